@@ -0,0 +1,146 @@
+// Cluster backend abstraction shared by the commands which operate on any scheduler
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logfilter"
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.backend")
+
+var (
+	ErrorUnsupported = errors.New("The operation is not supported by this backend")
+	ErrorNoInstances = errors.New("The workload has no running instances")
+	ErrorExists      = errors.New("The workload already exists.  Deploy with force to update it")
+)
+
+// NotConfiguredError is returned by factories when the environment doesn't configure the backend's
+// cluster and the backend wasn't selected explicitly
+type NotConfiguredError struct {
+	Backend string
+	EnvName string
+}
+
+func (e *NotConfiguredError) Error() string {
+	return fmt.Sprintf("Environment '%s' does not configure the '%s' backend", e.EnvName, e.Backend)
+}
+
+// ClusterBackend deploys and operates the workloads (eg. Marathon apps, Kubernetes deployments) of a
+// cluster.  Backends return ErrorUnsupported for operations they can't perform
+type ClusterBackend interface {
+
+	// Returns the name the backend is registered with
+	Name() string
+
+	// Creates the workload within the parsed document {doc} or updates it when it exists and
+	// {opts.Force} is true
+	Deploy(doc map[string]interface{}, opts *DeployOptions) (*Workload, error)
+
+	// Get a workload by ID
+	Get(id string) (*Workload, error)
+
+	// List all workloads
+	List() ([]*Workload, error)
+
+	// Scale a workload to {instances}
+	Scale(id string, instances int) error
+
+	// Removes a workload and its instances
+	Destroy(id string) error
+
+	// Waits until all instances of the workload are running (and healthy when checks are defined)
+	Wait(id string, timeout time.Duration) error
+
+	// Writes the logs of the workload's instances to {w}
+	Logs(id string, opts *LogOptions, w io.Writer) error
+}
+
+type DeployOptions struct {
+	// Update the workload when it already exists
+	Force bool
+	// Wait for the workload to become healthy
+	Wait bool
+	// Max time to wait.  The backend's default is used when zero
+	Timeout time.Duration
+}
+
+type LogOptions struct {
+	// Show the standard error rather than standard out
+	Stderr bool
+	// Tail the log until interrupted
+	Follow bool
+	// Time between polls when following
+	Poll time.Duration
+	// Optional selection of the entries shown (eg. the last 10 minutes, errors only)
+	Filter *logfilter.Filter
+	// Prefixes each entry with its timestamp in UTC and task interleaving the logs of the tasks by time
+	Timestamps bool
+}
+
+// Workload is the backend neutral view of a deployed application
+type Workload struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Image     string `json:"image,omitempty"`
+	Instances int    `json:"instances"`
+	Running   int    `json:"running"`
+	Healthy   int    `json:"healthy"`
+	Version   string `json:"version,omitempty"`
+}
+
+// Context carries the settings of the invocation a backend is created for
+type Context struct {
+	EnvName    string
+	Insecure   bool
+	AllowWrite bool
+	// The backend was chosen explicitly (eg. --backend) rather than detected.  Backends which may fall
+	// back to settings outside the environment (eg. $KUBECONFIG) only do so when selected
+	Selected bool
+}
+
+// Factory creates a backend for {ctx}
+type Factory func(ctx *Context) (ClusterBackend, error)
+
+var (
+	factories = map[string]Factory{}
+	lock      sync.RWMutex
+)
+
+// Register makes a backend available under {name}.  Registering a name twice replaces the factory
+func Register(name string, factory Factory) {
+	lock.Lock()
+	defer lock.Unlock()
+	factories[name] = factory
+}
+
+// New creates the backend registered under {name}
+func New(name string, ctx *Context) (ClusterBackend, error) {
+	lock.RLock()
+	factory, ok := factories[name]
+	lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown backend '%s' (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(ctx)
+}
+
+// Names returns the names of the registered backends
+func Names() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	names := []string{}
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
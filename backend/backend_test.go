@@ -0,0 +1,264 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/kubernetes"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/nomad"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logfilter"
+	"github.com/ContainX/depcon/swarm"
+	ml "github.com/ContainX/go-mesoslog/mesoslog"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMarathon records the calls of the MarathonBackend.  Calls it doesn't implement panic
+type fakeMarathon struct {
+	marathon.Marathon
+	created *marathon.Application
+	force   bool
+	scaled  int
+	waited  time.Duration
+}
+
+func (f *fakeMarathon) CreateApplication(app *marathon.Application, wait, force bool) (*marathon.Application, error) {
+	f.created, f.force = app, force
+	return app, nil
+}
+
+func (f *fakeMarathon) GetApplication(id string) (*marathon.Application, error) {
+	return &marathon.Application{ID: id, Instances: 2, TasksRunning: 2, TasksHealthy: 1, Version: "v1"}, nil
+}
+
+func (f *fakeMarathon) ScaleApplication(id string, instances int) (*marathon.DeploymentID, error) {
+	f.scaled = instances
+	return &marathon.DeploymentID{}, nil
+}
+
+func (f *fakeMarathon) WaitForApplication(id string, timeout time.Duration) error {
+	f.waited = timeout
+	return nil
+}
+
+func (f *fakeMarathon) GetTasks(id string) ([]*marathon.Task, error) {
+	return []*marathon.Task{}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	defer func() { factories = map[string]Factory{} }()
+
+	Register("b", func(ctx *Context) (ClusterBackend, error) { return nil, errors.New(ctx.EnvName) })
+	Register("a", func(ctx *Context) (ClusterBackend, error) { return NewMarathonBackend(&fakeMarathon{}, ""), nil })
+	assert.Equal(t, []string{"a", "b"}, Names())
+
+	b, err := New("a", &Context{})
+	assert.Nil(t, err)
+	assert.Equal(t, "marathon", b.Name())
+
+	_, err = New("b", &Context{EnvName: "prod"})
+	assert.EqualError(t, err, "prod")
+
+	_, err = New("c", &Context{})
+	assert.EqualError(t, err, "Unknown backend 'c' (available: a, b)")
+}
+
+func TestMarathonBackendDeploy(t *testing.T) {
+	f := &fakeMarathon{}
+	b := NewMarathonBackend(f, "")
+
+	doc := map[string]interface{}{
+		"id":        "/web",
+		"instances": 2,
+		"container": map[string]interface{}{"docker": map[string]interface{}{"image": "nginx"}},
+	}
+	w, err := b.Deploy(doc, &DeployOptions{Force: true})
+	assert.Nil(t, err)
+	assert.True(t, f.force)
+	assert.Equal(t, &Workload{ID: "/web", Kind: KIND_APP, Image: "nginx", Instances: 2}, w)
+
+	w, err = b.Deploy(doc, &DeployOptions{Wait: true})
+	assert.Nil(t, err)
+	assert.Equal(t, marathon.DefaultTimeout, f.waited)
+	assert.Equal(t, 1, w.Healthy)
+}
+
+func TestMarathonBackendScaleAndLogs(t *testing.T) {
+	f := &fakeMarathon{}
+	b := NewMarathonBackend(f, "")
+
+	assert.Nil(t, b.Scale("/web", 3))
+	assert.Equal(t, 3, f.scaled)
+	assert.Equal(t, ErrorNoInstances, b.Logs("/web", nil, nil))
+}
+
+func TestWriteEntries(t *testing.T) {
+	logs := []*ml.LogOut{
+		{TaskID: "web.4c5d7e0a-1111", Log: "2024-05-01T10:00:01Z INFO started\n2024-05-01T10:00:03Z ERROR failed\n\tat main\n"},
+		{TaskID: "web.9f8e7d6c-2222", Log: "2024-05-01T10:00:02Z ERROR refused\n"},
+	}
+	f, err := logfilter.New(0, 0, "", "error", time.Now())
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	writeEntries(&buf, "/web", logs, &LogOptions{Filter: f, Timestamps: true})
+	assert.Equal(t, "2024-05-01T10:00:02.000Z web.9f8e7d6c ERROR refused\n2024-05-01T10:00:03.000Z web.4c5d7e0a ERROR failed\n\tat main\n", buf.String())
+
+	buf.Reset()
+	writeEntries(&buf, "/web", logs[:1], &LogOptions{Filter: f})
+	assert.Equal(t, "2024-05-01T10:00:03Z ERROR failed\n\tat main\n", buf.String())
+}
+
+func TestShortTaskID(t *testing.T) {
+	assert.Equal(t, "web.4c5d7e0a", shortTaskID("web.4c5d7e0a-1111-2222"))
+	assert.Equal(t, "web.abcdef01", shortTaskID("web.instance-abcdef0123"))
+	assert.Equal(t, "task", shortTaskID("task"))
+}
+
+// fakeKubernetes holds the deployments of a namespace.  Calls it doesn't implement panic
+type fakeKubernetes struct {
+	kubernetes.Kubernetes
+	deployments map[string]*kubernetes.Deployment
+	applied     int
+}
+
+func (f *fakeKubernetes) GetDeployment(name string) (*kubernetes.Deployment, error) {
+	if d, ok := f.deployments[name]; ok {
+		return d, nil
+	}
+	return nil, httpclient.ErrorNotFound
+}
+
+func (f *fakeKubernetes) Apply(doc map[string]interface{}) (*kubernetes.AppliedResource, error) {
+	f.applied++
+	name := doc["metadata"].(map[string]interface{})["name"].(string)
+	replicas := 2
+	d := &kubernetes.Deployment{Metadata: kubernetes.ObjectMeta{Name: name, ResourceVersion: "7"}}
+	d.Spec.Replicas = &replicas
+	d.Spec.Template.Spec.Containers = []*kubernetes.Container{{Name: "web", Image: "nginx"}}
+	d.Status.ReadyReplicas, d.Status.AvailableReplicas = 2, 1
+	f.deployments[name] = d
+	return &kubernetes.AppliedResource{Kind: doc["kind"].(string), Name: name}, nil
+}
+
+func TestKubernetesBackendDeploy(t *testing.T) {
+	f := &fakeKubernetes{deployments: map[string]*kubernetes.Deployment{}}
+	b := NewKubernetesBackend(f)
+
+	doc := map[string]interface{}{"kind": "Deployment", "metadata": map[string]interface{}{"name": "web"}}
+	w, err := b.Deploy(doc, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, &Workload{ID: "web", Kind: KIND_DEPLOYMENT, Image: "nginx", Instances: 2, Running: 2, Healthy: 1, Version: "7"}, w)
+
+	_, err = b.Deploy(doc, nil)
+	assert.Equal(t, ErrorExists, err)
+	assert.Equal(t, 1, f.applied)
+
+	_, err = b.Deploy(doc, &DeployOptions{Force: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, f.applied)
+}
+
+// fakeNomad returns the status of a single job.  Calls it doesn't implement panic
+type fakeNomad struct {
+	nomad.Nomad
+	statuses []*nomad.JobStatus
+	checks   int
+}
+
+func (f *fakeNomad) JobStatus(id string) (*nomad.JobStatus, error) {
+	s := f.statuses[f.checks]
+	if f.checks < len(f.statuses)-1 {
+		f.checks++
+	}
+	return s, nil
+}
+
+func TestNomadBackendWait(t *testing.T) {
+	defer func(d time.Duration) { nomadWaitInterval = d }(nomadWaitInterval)
+	nomadWaitInterval = time.Millisecond
+
+	job := &nomad.Job{ID: "web", Version: 3, TaskGroups: []*nomad.TaskGroup{{Name: "web", Count: 2}}}
+	status := func(state string, healthy int) *nomad.JobStatus {
+		return &nomad.JobStatus{
+			Job:     job,
+			Summary: map[string]*nomad.TaskGroupSummary{"web": {Running: 2}},
+			Deployment: &nomad.Deployment{JobVersion: 3, Status: state,
+				TaskGroups: map[string]*nomad.DeploymentGroupState{"web": {HealthyAllocs: healthy}}},
+		}
+	}
+	f := &fakeNomad{statuses: []*nomad.JobStatus{status("running", 1), status("successful", 2)}}
+	b := NewNomadBackend(f)
+
+	assert.Nil(t, b.Wait("web", time.Second))
+	assert.Equal(t, 1, f.checks)
+	w, err := b.Get("web")
+	assert.Nil(t, err)
+	assert.Equal(t, &Workload{ID: "web", Kind: KIND_JOB, Instances: 2, Running: 2, Healthy: 2, Version: "3"}, w)
+
+	f = &fakeNomad{statuses: []*nomad.JobStatus{status("failed", 0)}}
+	assert.Error(t, NewNomadBackend(f).Wait("web", time.Second))
+	assert.Equal(t, ErrorUnsupported, b.Scale("web", 3))
+}
+
+// fakeSwarm holds the services of a single stack.  Calls it doesn't implement panic
+type fakeSwarm struct {
+	swarm.Swarm
+	deployed *swarm.Stack
+}
+
+func (f *fakeSwarm) ListServices(stack string) ([]*swarm.Service, error) {
+	if f.deployed == nil {
+		return []*swarm.Service{}, nil
+	}
+	return []*swarm.Service{f.service()}, nil
+}
+
+func (f *fakeSwarm) DeployStack(stack *swarm.Stack) ([]*swarm.DeployResult, error) {
+	f.deployed = stack
+	return []*swarm.DeployResult{{Service: "shop_web", ID: "s1", Action: "created"}}, nil
+}
+
+func (f *fakeSwarm) GetService(name string) (*swarm.Service, error) {
+	return f.service(), nil
+}
+
+func (f *fakeSwarm) ListTasks(name string) ([]*swarm.Task, error) {
+	task := &swarm.Task{DesiredState: "running"}
+	task.Status.State = "running"
+	return []*swarm.Task{task}, nil
+}
+
+func (f *fakeSwarm) service() *swarm.Service {
+	s := &swarm.Service{}
+	json.Unmarshal([]byte(`{"ID": "s1", "Spec": {"Name": "shop_web", "Mode": {"Replicated": {"Replicas": 2}}}}`), s)
+	return s
+}
+
+func TestSwarmBackendDeploy(t *testing.T) {
+	f := &fakeSwarm{}
+	b := NewSwarmBackend(f)
+
+	doc := map[string]interface{}{"services": map[string]interface{}{"web": map[string]interface{}{"image": "nginx"}}}
+	_, err := b.Deploy(doc, nil)
+	assert.Equal(t, ErrorMissingStackName, err)
+
+	doc["name"] = "shop"
+	w, err := b.Deploy(doc, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "shop", f.deployed.Name)
+	assert.Equal(t, &Workload{ID: "shop", Kind: KIND_STACK, Instances: 2, Running: 1, Healthy: 1}, w)
+
+	_, err = b.Deploy(doc, nil)
+	assert.Equal(t, ErrorExists, err)
+}
+
+func TestECSBackendDestroyUnsupported(t *testing.T) {
+	b := NewECSBackend(nil)
+	assert.Equal(t, ErrorUnsupported, b.Destroy("web"))
+	assert.Equal(t, "web:3", revision("arn:aws:ecs:us-east-1:123456789012:task-definition/web:3"))
+}
@@ -0,0 +1,118 @@
+package backend
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/ecs"
+)
+
+const (
+	KIND_SERVICE         = "service"
+	KIND_TASK_DEFINITION = "taskdefinition"
+)
+
+// ECSBackend operates the services of an ECS cluster.  The ECS client has no means of deleting services
+// so they can't be destroyed
+type ECSBackend struct {
+	client ecs.ECS
+}
+
+// NewECSBackend creates a backend operating the services of {client}
+func NewECSBackend(client ecs.ECS) *ECSBackend {
+	return &ECSBackend{client: client}
+}
+
+func (b *ECSBackend) Name() string {
+	return "ecs"
+}
+
+// Deploy registers the task definition and creates or updates the service of {doc} (see ecs.ECS.Deploy).
+// Documents registering only a task definition return a workload without instances
+func (b *ECSBackend) Deploy(doc map[string]interface{}, opts *DeployOptions) (*Workload, error) {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+	service, _ := doc["service"].(map[string]interface{})
+	if name, _ := service["serviceName"].(string); name != "" && !opts.Force {
+		switch _, err := b.client.GetService(name); err {
+		case nil:
+			return nil, ErrorExists
+		case ecs.ErrorServiceNotFound:
+		default:
+			return nil, err
+		}
+	}
+
+	r, err := b.client.Deploy(doc)
+	if err != nil {
+		return nil, err
+	}
+	if r.Service == "" {
+		return &Workload{ID: r.TaskDefinition, Kind: KIND_TASK_DEFINITION, Version: revision(r.TaskDefinition)}, nil
+	}
+	if opts.Wait {
+		if err := b.Wait(r.Service, opts.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	return b.Get(r.Service)
+}
+
+func (b *ECSBackend) Get(id string) (*Workload, error) {
+	s, err := b.client.GetService(id)
+	if err != nil {
+		return nil, err
+	}
+	return serviceWorkload(s), nil
+}
+
+func (b *ECSBackend) List() ([]*Workload, error) {
+	services, err := b.client.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	workloads := []*Workload{}
+	for _, s := range services {
+		workloads = append(workloads, serviceWorkload(s))
+	}
+	return workloads, nil
+}
+
+func (b *ECSBackend) Scale(id string, instances int) error {
+	_, err := b.client.ScaleService(id, instances)
+	return err
+}
+
+func (b *ECSBackend) Destroy(id string) error {
+	return ErrorUnsupported
+}
+
+func (b *ECSBackend) Wait(id string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = ecs.DefaultTimeout
+	}
+	return b.client.WaitForSteadyState(id, timeout)
+}
+
+func (b *ECSBackend) Logs(id string, opts *LogOptions, w io.Writer) error {
+	return ErrorUnsupported
+}
+
+// Returns the family and revision (eg. web:3) of the task definition {arn}
+func revision(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// Services report no healthy count so their running tasks are counted as healthy
+func serviceWorkload(s *ecs.Service) *Workload {
+	return &Workload{
+		ID:        s.ServiceName,
+		Kind:      KIND_SERVICE,
+		Instances: s.DesiredCount,
+		Running:   s.RunningCount,
+		Healthy:   s.RunningCount,
+		Version:   revision(s.TaskDefinition),
+	}
+}
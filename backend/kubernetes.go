@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/kubernetes"
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+const KIND_DEPLOYMENT = "deployment"
+
+// KubernetesBackend operates the Deployments of a Kubernetes namespace.  Services may be deployed and
+// destroyed alongside them but have no instances
+type KubernetesBackend struct {
+	client kubernetes.Kubernetes
+}
+
+// NewKubernetesBackend creates a backend operating the deployments of {client}
+func NewKubernetesBackend(client kubernetes.Kubernetes) *KubernetesBackend {
+	return &KubernetesBackend{client: client}
+}
+
+func (b *KubernetesBackend) Name() string {
+	return "kubernetes"
+}
+
+func (b *KubernetesBackend) Deploy(doc map[string]interface{}, opts *DeployOptions) (*Workload, error) {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+	kind, _ := doc["kind"].(string)
+	metadata, _ := doc["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if !opts.Force && name != "" {
+		// apply always updates so existing resources are refused here.  Unsupported kinds are left to Apply
+		var err error
+		switch kind {
+		case kubernetes.KIND_DEPLOYMENT:
+			_, err = b.client.GetDeployment(name)
+		case kubernetes.KIND_SERVICE:
+			_, err = b.client.GetService(name)
+		default:
+			err = httpclient.ErrorNotFound
+		}
+		switch err {
+		case nil:
+			return nil, ErrorExists
+		case httpclient.ErrorNotFound:
+		default:
+			return nil, err
+		}
+	}
+
+	r, err := b.client.Apply(doc)
+	if err != nil {
+		return nil, err
+	}
+	if r.Kind != kubernetes.KIND_DEPLOYMENT {
+		return &Workload{ID: r.Name, Kind: strings.ToLower(r.Kind)}, nil
+	}
+	if opts.Wait {
+		if err := b.Wait(r.Name, opts.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	return b.Get(r.Name)
+}
+
+func (b *KubernetesBackend) Get(id string) (*Workload, error) {
+	d, err := b.client.GetDeployment(id)
+	if err != nil {
+		return nil, err
+	}
+	return deploymentWorkload(d), nil
+}
+
+func (b *KubernetesBackend) List() ([]*Workload, error) {
+	list, err := b.client.ListDeployments()
+	if err != nil {
+		return nil, err
+	}
+	workloads := []*Workload{}
+	for _, d := range list.Items {
+		workloads = append(workloads, deploymentWorkload(d))
+	}
+	return workloads, nil
+}
+
+func (b *KubernetesBackend) Scale(id string, instances int) error {
+	_, err := b.client.ScaleDeployment(id, instances)
+	return err
+}
+
+// Destroy removes the Deployment {id} or, when there's no such Deployment, the Service {id}
+func (b *KubernetesBackend) Destroy(id string) error {
+	err := b.client.DeleteDeployment(id)
+	if err == httpclient.ErrorNotFound {
+		return b.client.DeleteService(id)
+	}
+	return err
+}
+
+func (b *KubernetesBackend) Wait(id string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = kubernetes.DefaultTimeout
+	}
+	return b.client.WaitForDeployment(id, timeout)
+}
+
+func (b *KubernetesBackend) Logs(id string, opts *LogOptions, w io.Writer) error {
+	return ErrorUnsupported
+}
+
+func deploymentWorkload(d *kubernetes.Deployment) *Workload {
+	w := &Workload{
+		ID:      d.Metadata.Name,
+		Kind:    KIND_DEPLOYMENT,
+		Running: d.Status.ReadyReplicas,
+		Healthy: d.Status.AvailableReplicas,
+		Version: d.Metadata.ResourceVersion,
+	}
+	if d.Spec.Replicas != nil {
+		w.Instances = *d.Spec.Replicas
+	}
+	if len(d.Spec.Template.Spec.Containers) > 0 {
+		w.Image = d.Spec.Template.Spec.Containers[0].Image
+	}
+	return w
+}
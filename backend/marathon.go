@@ -0,0 +1,255 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/logfilter"
+	ml "github.com/ContainX/go-mesoslog/mesoslog"
+)
+
+const (
+	KIND_APP = "app"
+
+	// Port of the Mesos master serving the task sandboxes
+	MesosPort = 5050
+)
+
+// MarathonBackend operates Marathon applications
+type MarathonBackend struct {
+	client    marathon.Marathon
+	mesosHost string
+}
+
+// NewMarathonBackend creates a backend operating the apps of {client}.  Logs are read from the sandboxes
+// of the Mesos master at {mesosHost}
+func NewMarathonBackend(client marathon.Marathon, mesosHost string) *MarathonBackend {
+	return &MarathonBackend{client: client, mesosHost: mesosHost}
+}
+
+func (b *MarathonBackend) Name() string {
+	return "marathon"
+}
+
+func (b *MarathonBackend) Deploy(doc map[string]interface{}, opts *DeployOptions) (*Workload, error) {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+	app := &marathon.Application{}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, app); err != nil {
+		return nil, err
+	}
+
+	result, err := b.client.CreateApplication(app, false, opts.Force)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Wait {
+		if err := b.Wait(result.ID, opts.Timeout); err != nil {
+			return nil, err
+		}
+		return b.Get(result.ID)
+	}
+	return appWorkload(result), nil
+}
+
+func (b *MarathonBackend) Get(id string) (*Workload, error) {
+	app, err := b.client.GetApplication(id)
+	if err != nil {
+		return nil, err
+	}
+	return appWorkload(app), nil
+}
+
+func (b *MarathonBackend) List() ([]*Workload, error) {
+	apps, err := b.client.ListApplications()
+	if err != nil {
+		return nil, err
+	}
+	workloads := []*Workload{}
+	for i := range apps.Apps {
+		workloads = append(workloads, appWorkload(&apps.Apps[i]))
+	}
+	return workloads, nil
+}
+
+func (b *MarathonBackend) Scale(id string, instances int) error {
+	_, err := b.client.ScaleApplication(id, instances)
+	return err
+}
+
+func (b *MarathonBackend) Destroy(id string) error {
+	_, err := b.client.DestroyApplication(id)
+	return err
+}
+
+func (b *MarathonBackend) Wait(id string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = marathon.DefaultTimeout
+	}
+	return b.client.WaitForApplication(id, timeout)
+}
+
+// Logs reads the sandbox logs of the app's tasks from Mesos.  When following without a filter or timestamps
+// only the first task is tailed and the log is written to stdout
+func (b *MarathonBackend) Logs(id string, opts *LogOptions, w io.Writer) error {
+	if opts == nil {
+		opts = &LogOptions{}
+	}
+	tasks, err := b.client.GetTasks(id)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return ErrorNoInstances
+	}
+
+	c, err := ml.NewMesosClient(b.mesosHost, MesosPort)
+	if err != nil {
+		return err
+	}
+	name, err := c.GetAppNameForTaskID(tasks[0].ID)
+	if err != nil {
+		return err
+	}
+	logType := ml.STDOUT
+	if opts.Stderr {
+		logType = ml.STDERR
+	}
+
+	filtered := !opts.Filter.IsZero() || opts.Timestamps
+	if opts.Follow {
+		poll := int(opts.Poll / time.Second)
+		if poll < 1 {
+			poll = 5
+		}
+		if filtered {
+			return followLogs(c, name, logType, time.Duration(poll)*time.Second, opts, w)
+		}
+		return c.TailLog(name, logType, poll)
+	}
+
+	logs, err := c.GetLog(name, logType, "")
+	if err != nil {
+		return err
+	}
+	if filtered {
+		writeEntries(w, id, logs, opts)
+		return nil
+	}
+	showBreaks := len(logs) > 1
+	for _, l := range logs {
+		if showBreaks {
+			fmt.Fprintf(w, "\n::: [ %s - Logs For: %s ] ::: \n", id, l.TaskID)
+		}
+		fmt.Fprintf(w, "%s\n", l.Log)
+		if showBreaks {
+			fmt.Fprintf(w, "\n!!! [ %s - End Logs For: %s ] !!! \n", id, l.TaskID)
+		}
+	}
+	return nil
+}
+
+// Writes the entries of the task {logs} of app {id} selected by the filter of {opts}
+func writeEntries(w io.Writer, id string, logs []*ml.LogOut, opts *LogOptions) {
+	tasks := [][]*logfilter.Entry{}
+	for _, l := range logs {
+		tasks = append(tasks, opts.Filter.Apply(logfilter.Parse(shortTaskID(l.TaskID), l.Log)))
+	}
+	if opts.Timestamps {
+		writeMerged(w, tasks)
+		return
+	}
+	showBreaks := len(logs) > 1
+	for i, entries := range tasks {
+		if showBreaks {
+			fmt.Fprintf(w, "\n::: [ %s - Logs For: %s ] ::: \n", id, logs[i].TaskID)
+		}
+		for _, e := range entries {
+			fmt.Fprintln(w, logfilter.Format(e, false))
+		}
+		if showBreaks {
+			fmt.Fprintf(w, "\n!!! [ %s - End Logs For: %s ] !!! \n", id, logs[i].TaskID)
+		}
+	}
+}
+
+// Writes the entries of {tasks} interleaved by time with normalized timestamps
+func writeMerged(w io.Writer, tasks [][]*logfilter.Entry) {
+	for _, e := range logfilter.Merge(tasks...) {
+		fmt.Fprintln(w, logfilter.Format(e, true))
+	}
+}
+
+// Follows the logs of every task of app {name} polling them every {poll} and writing the entries selected
+// by the filter of {opts} as they're logged
+func followLogs(c *ml.MesosClient, name string, logType ml.LogType, poll time.Duration, opts *LogOptions, w io.Writer) error {
+	streams := map[string]*logfilter.Stream{}
+	read := map[string]int{}
+	for {
+		logs, err := c.GetLog(name, logType, "")
+		if err != nil {
+			return err
+		}
+		tasks := [][]*logfilter.Entry{}
+		for _, l := range logs {
+			s, ok := streams[l.TaskID]
+			if !ok {
+				s = &logfilter.Stream{Source: shortTaskID(l.TaskID)}
+				streams[l.TaskID] = s
+			}
+			if len(l.Log) < read[l.TaskID] {
+				// the log was rotated
+				read[l.TaskID] = 0
+			}
+			tasks = append(tasks, s.Read(l.Log[read[l.TaskID]:], opts.Filter))
+			read[l.TaskID] = len(l.Log)
+		}
+		if opts.Timestamps {
+			writeMerged(w, tasks)
+		} else {
+			for _, entries := range tasks {
+				for _, e := range entries {
+					fmt.Fprintln(w, logfilter.Format(e, false))
+				}
+			}
+		}
+		time.Sleep(poll)
+	}
+}
+
+// Shortens task {id} (eg. web.4c5d7e0a-...) to the app and the start of its unique part
+func shortTaskID(id string) string {
+	parts := strings.SplitN(id, ".", 2)
+	if len(parts) < 2 {
+		return id
+	}
+	unique := strings.TrimPrefix(parts[1], "instance-")
+	if len(unique) > 8 {
+		unique = unique[:8]
+	}
+	return parts[0] + "." + unique
+}
+
+func appWorkload(app *marathon.Application) *Workload {
+	w := &Workload{
+		ID:        app.ID,
+		Kind:      KIND_APP,
+		Instances: app.Instances,
+		Running:   app.TasksRunning,
+		Healthy:   app.TasksHealthy,
+		Version:   app.Version,
+	}
+	if app.Container != nil && app.Container.Docker != nil {
+		w.Image = app.Container.Docker.Image
+	}
+	return w
+}
@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ContainX/depcon/nomad"
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+const KIND_JOB = "job"
+
+// interval between checks of a job while waiting on it
+var nomadWaitInterval = time.Duration(2) * time.Second
+
+// NomadBackend operates the jobs of a Nomad namespace.  The Nomad client has no means of scaling task
+// groups so jobs are scaled by deploying them with a new count
+type NomadBackend struct {
+	client nomad.Nomad
+}
+
+// NewNomadBackend creates a backend operating the jobs of {client}
+func NewNomadBackend(client nomad.Nomad) *NomadBackend {
+	return &NomadBackend{client: client}
+}
+
+func (b *NomadBackend) Name() string {
+	return "nomad"
+}
+
+// Deploy runs the job of {doc} (see nomad.Nomad.Run) waiting on its evaluation and deployment when
+// {opts.Wait} is true
+func (b *NomadBackend) Deploy(doc map[string]interface{}, opts *DeployOptions) (*Workload, error) {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+	if !opts.Force {
+		job := doc
+		if wrapped, ok := doc["Job"].(map[string]interface{}); ok {
+			job = wrapped
+		}
+		id, _ := job["ID"].(string)
+		if id == "" {
+			id, _ = job["Name"].(string)
+		}
+		if id != "" {
+			switch _, err := b.client.GetJob(id); err {
+			case nil:
+				return nil, ErrorExists
+			case httpclient.ErrorNotFound:
+			default:
+				return nil, err
+			}
+		}
+	}
+
+	r, err := b.client.Run(doc)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Wait {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = nomad.DefaultTimeout
+		}
+		if err := b.client.WaitForEvaluation(r.EvalID, timeout); err != nil {
+			return nil, err
+		}
+	}
+	return b.Get(r.JobID)
+}
+
+func (b *NomadBackend) Get(id string) (*Workload, error) {
+	s, err := b.client.JobStatus(id)
+	if err != nil {
+		return nil, err
+	}
+	return jobWorkload(s), nil
+}
+
+// List returns the jobs of the namespace.  Job stubs carry no task group counts so the instances are
+// the allocations which are queued, starting or running
+func (b *NomadBackend) List() ([]*Workload, error) {
+	jobs, err := b.client.ListJobs()
+	if err != nil {
+		return nil, err
+	}
+	workloads := []*Workload{}
+	for _, j := range jobs {
+		w := &Workload{ID: j.ID, Kind: KIND_JOB}
+		if j.JobSummary != nil {
+			for _, g := range j.JobSummary.Summary {
+				w.Instances += g.Queued + g.Starting + g.Running
+				w.Running += g.Running
+			}
+		}
+		w.Healthy = w.Running
+		workloads = append(workloads, w)
+	}
+	return workloads, nil
+}
+
+func (b *NomadBackend) Scale(id string, instances int) error {
+	return ErrorUnsupported
+}
+
+// Destroy stops the job leaving it to be garbage collected
+func (b *NomadBackend) Destroy(id string) error {
+	_, err := b.client.Stop(id, false)
+	return err
+}
+
+// Wait polls the job until its latest deployment is successful or, for jobs without deployments, every
+// allocation is running
+func (b *NomadBackend) Wait(id string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = nomad.DefaultTimeout
+	}
+	t_stop := time.Now().Add(timeout)
+	for {
+		s, err := b.client.JobStatus(id)
+		if err != nil {
+			return err
+		}
+		if d := s.Deployment; d != nil && d.JobVersion == s.Version {
+			switch d.Status {
+			case "successful":
+				return nil
+			case "failed", "cancelled":
+				return fmt.Errorf("%s: %s", nomad.ErrorDeploymentFailed.Error(), d.StatusDescription)
+			}
+		} else if w := jobWorkload(s); w.Running >= w.Instances {
+			return nil
+		}
+		if time.Now().After(t_stop) {
+			return nomad.ErrorTimeout
+		}
+		time.Sleep(nomadWaitInterval)
+	}
+}
+
+func (b *NomadBackend) Logs(id string, opts *LogOptions, w io.Writer) error {
+	return ErrorUnsupported
+}
+
+// Allocations are healthy once the latest deployment reports them so or, for jobs without a deployment,
+// once they're running
+func jobWorkload(s *nomad.JobStatus) *Workload {
+	w := &Workload{ID: s.ID, Kind: KIND_JOB, Version: strconv.Itoa(s.Version)}
+	for _, g := range s.TaskGroups {
+		w.Instances += g.Count
+	}
+	for _, g := range s.Summary {
+		w.Running += g.Running
+	}
+	w.Healthy = w.Running
+	if d := s.Deployment; d != nil && d.JobVersion == s.Version {
+		w.Healthy = 0
+		for _, g := range d.TaskGroups {
+			w.Healthy += g.HealthyAllocs
+		}
+	}
+	return w
+}
@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ContainX/depcon/swarm"
+)
+
+const KIND_STACK = "stack"
+
+var ErrorMissingStackName = errors.New("The compose file does not specify the stack name (top-level 'name')")
+
+// SwarmBackend deploys compose files as stacks and operates the services of a swarm
+type SwarmBackend struct {
+	client swarm.Swarm
+}
+
+// NewSwarmBackend creates a backend operating the services of {client}
+func NewSwarmBackend(client swarm.Swarm) *SwarmBackend {
+	return &SwarmBackend{client: client}
+}
+
+func (b *SwarmBackend) Name() string {
+	return "swarm"
+}
+
+// Deploy deploys the compose file {doc} as the stack named by its top-level 'name' returning the stack
+// with the instances of all of its services
+func (b *SwarmBackend) Deploy(doc map[string]interface{}, opts *DeployOptions) (*Workload, error) {
+	if opts == nil {
+		opts = &DeployOptions{}
+	}
+	name, _ := doc["name"].(string)
+	if name == "" {
+		return nil, ErrorMissingStackName
+	}
+	if !opts.Force {
+		services, err := b.client.ListServices(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(services) > 0 {
+			return nil, ErrorExists
+		}
+	}
+
+	stack, err := swarm.ConvertCompose(name, doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range stack.Warnings {
+		log.Warning(w)
+	}
+	results, err := b.client.DeployStack(stack)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Wait {
+		for _, r := range results {
+			if err := b.Wait(r.Service, opts.Timeout); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w := &Workload{ID: name, Kind: KIND_STACK}
+	for _, r := range results {
+		s, err := b.Get(r.Service)
+		if err != nil {
+			return nil, err
+		}
+		w.Instances += s.Instances
+		w.Running += s.Running
+		w.Healthy += s.Healthy
+	}
+	return w, nil
+}
+
+func (b *SwarmBackend) Get(id string) (*Workload, error) {
+	s, err := b.client.GetService(id)
+	if err != nil {
+		return nil, err
+	}
+	return b.serviceWorkload(s)
+}
+
+func (b *SwarmBackend) List() ([]*Workload, error) {
+	services, err := b.client.ListServices("")
+	if err != nil {
+		return nil, err
+	}
+	workloads := []*Workload{}
+	for _, s := range services {
+		w, err := b.serviceWorkload(s)
+		if err != nil {
+			return nil, err
+		}
+		workloads = append(workloads, w)
+	}
+	return workloads, nil
+}
+
+func (b *SwarmBackend) Scale(id string, instances int) error {
+	_, err := b.client.ScaleService(id, instances)
+	return err
+}
+
+func (b *SwarmBackend) Destroy(id string) error {
+	return b.client.RemoveService(id)
+}
+
+func (b *SwarmBackend) Wait(id string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = swarm.DefaultTimeout
+	}
+	return b.client.WaitForService(id, timeout)
+}
+
+func (b *SwarmBackend) Logs(id string, opts *LogOptions, w io.Writer) error {
+	return ErrorUnsupported
+}
+
+// Returns service {s} with the counts of its tasks.  Tasks carry no separate health so the running tasks
+// are counted as healthy
+func (b *SwarmBackend) serviceWorkload(s *swarm.Service) (*Workload, error) {
+	tasks, err := b.client.ListTasks(s.ID)
+	if err != nil {
+		return nil, err
+	}
+	running, desired := s.TaskCounts(tasks)
+	return &Workload{
+		ID:        s.Spec.Name,
+		Kind:      KIND_SERVICE,
+		Image:     s.Spec.TaskTemplate.ContainerSpec.Image,
+		Instances: desired,
+		Running:   running,
+		Healthy:   running,
+		Version:   strconv.FormatUint(s.Version.Index, 10),
+	}, nil
+}
@@ -0,0 +1,211 @@
+// Exports the definitions of an environment's applications, groups and pods to a directory of YAML files
+// described by a manifest of their versions and checksums (eg. before upgrading Marathon)
+package backup
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	// name of the manifest written to the root of a backup
+	ManifestFilename = "backup.yaml"
+
+	KindApp   = "app"
+	KindGroup = "group"
+	KindPod   = "pod"
+	KindQueue = "queue"
+)
+
+var log = logger.GetLogger("depcon.backup")
+
+var ErrorBackupExists = errors.New("The directory already contains a backup")
+
+// Options select what is exported besides applications and groups
+type Options struct {
+	// Exports the definition of every pod
+	Pods bool
+	// Exports the launch queue (the tasks waiting to be launched and why)
+	Queue bool
+}
+
+// Manifest describes the files of a backup
+type Manifest struct {
+	Environment string    `json:"environment"`
+	Created     time.Time `json:"created"`
+	Files       []*File   `json:"files"`
+}
+
+// File is a definition exported by a backup
+type File struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id,omitempty"`
+	// location relative to the root of the backup
+	Path string `json:"path"`
+	// version of the application, group or pod when it was exported
+	Version string `json:"version,omitempty"`
+	// hex encoded SHA-256 of the file's content
+	Checksum string `json:"sha256"`
+}
+
+// Create exports the definitions of the environment {env} reached by {client} to the directory {dir}.  The
+// applications, groups and pods are written to apps/, groups/ and pods/ following their ids (eg.
+// apps/prod/web.yaml) along with the manifest.  Groups hold their id and dependencies while their
+// applications and sub groups have files of their own
+func Create(client marathon.Marathon, env, dir string, opts *Options) (*Manifest, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if _, err := os.Stat(filepath.Join(dir, ManifestFilename)); err == nil {
+		return nil, fmt.Errorf("%s: %w", dir, ErrorBackupExists)
+	}
+	root, err := client.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &writer{dir: dir, enc: encoding.DefaultYAMLEncoder(), manifest: &Manifest{Environment: env, Created: time.Now().UTC()}}
+	for _, app := range root.Apps {
+		if err := w.app(app); err != nil {
+			return nil, err
+		}
+	}
+	for _, g := range root.Groups {
+		if err := w.group(g); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Pods {
+		pods, err := client.ListPods()
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range pods {
+			version := pod.Version
+			pod.Version = ""
+			if err := w.write(KindPod, pod.ID, version, pod); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if opts.Queue {
+		queue, err := client.ListQueue()
+		if err != nil {
+			return nil, err
+		}
+		if err := w.write(KindQueue, "", "", queue); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := w.enc.Marshal(w.manifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ManifestFilename), []byte(data), 0644); err != nil {
+		return nil, err
+	}
+	log.Info("Exported %d file(s) of environment '%s' to %s", len(w.manifest.Files), env, dir)
+	return w.manifest, nil
+}
+
+// Verify returns the files of the backup within {dir} which are missing or whose content no longer matches
+// the checksum of the manifest
+func Verify(dir string) ([]*File, error) {
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	return verify(dir, manifest), nil
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestFilename))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := encoding.DefaultYAMLEncoder().UnMarshalStr(string(data), manifest); err != nil {
+		return nil, fmt.Errorf("%s: %s", ManifestFilename, err.Error())
+	}
+	return manifest, nil
+}
+
+func verify(dir string, manifest *Manifest) []*File {
+	invalid := []*File{}
+	for _, f := range manifest.Files {
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil || checksum(content) != f.Checksum {
+			invalid = append(invalid, f)
+		}
+	}
+	return invalid
+}
+
+type writer struct {
+	dir      string
+	enc      encoding.Encoder
+	manifest *Manifest
+}
+
+func (w *writer) app(app *marathon.Application) error {
+	definition, err := marathon.Definition(app)
+	if err != nil {
+		return err
+	}
+	return w.write(KindApp, app.ID, app.Version, definition)
+}
+
+// Writes the group {g} followed by its applications and sub groups
+func (w *writer) group(g *marathon.Group) error {
+	definition := &marathon.Group{GroupID: g.GroupID, Dependencies: g.Dependencies}
+	if err := w.write(KindGroup, g.GroupID, g.Version, definition); err != nil {
+		return err
+	}
+	for _, app := range g.Apps {
+		if err := w.app(app); err != nil {
+			return err
+		}
+	}
+	for _, sub := range g.Groups {
+		if err := w.group(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writes {v} to the file of {kind} named after {id} adding it to the manifest
+func (w *writer) write(kind, id, version string, v interface{}) error {
+	data, err := w.enc.Marshal(v)
+	if err != nil {
+		return err
+	}
+	name := kind + ".yaml"
+	if id != "" {
+		name = filepath.Join(kind+"s", filepath.FromSlash(strings.TrimPrefix(id, "/"))+".yaml")
+	}
+	filename := filepath.Join(w.dir, name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		return err
+	}
+	w.manifest.Files = append(w.manifest.Files, &File{Kind: kind, ID: id, Path: filepath.ToSlash(name), Version: version, Checksum: checksum([]byte(data))})
+	return nil
+}
+
+func checksum(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
@@ -0,0 +1,88 @@
+package backup
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathontest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "backup")
+	defer os.RemoveAll(dir)
+	fake := marathontest.New().WithApps(&marathon.Application{ID: "/proxy", Instances: 1},
+		&marathon.Application{ID: "/prod/web", Instances: 2, Env: map[string]string{"A": "1"}})
+	fake.Groups["/prod"] = true
+	fake.Pods["/prod/cache"] = &marathon.Pod{ID: "/prod/cache", Version: "v1"}
+
+	manifest, err := Create(fake, "prod", dir, &Options{Pods: true, Queue: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", manifest.Environment)
+	paths := []string{}
+	for _, f := range manifest.Files {
+		paths = append(paths, f.Path)
+		assert.Len(t, f.Checksum, 64)
+	}
+	assert.Equal(t, []string{"apps/proxy.yaml", "groups/prod.yaml", "apps/prod/web.yaml", "pods/prod/cache.yaml", "queue.yaml"}, paths)
+	assert.Equal(t, "v1", manifest.Files[3].Version)
+
+	web, _ := ioutil.ReadFile(filepath.Join(dir, "apps", "prod", "web.yaml"))
+	assert.Contains(t, string(web), "instances: 2")
+	assert.NotContains(t, string(web), "tasksRunning")
+	assert.NotContains(t, string(web), "version")
+
+	_, err = Create(fake, "prod", dir, nil)
+	assert.True(t, errors.Is(err, ErrorBackupExists))
+
+	invalid, err := Verify(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, invalid)
+	ioutil.WriteFile(filepath.Join(dir, "apps", "proxy.yaml"), []byte("id: /changed\n"), 0644)
+	os.Remove(filepath.Join(dir, "queue.yaml"))
+	invalid, _ = Verify(dir)
+	assert.Equal(t, []*File{manifest.Files[0], manifest.Files[4]}, invalid)
+}
+
+func TestRestore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "backup")
+	defer os.RemoveAll(dir)
+	prod := marathontest.New().WithApps(&marathon.Application{ID: "/prod/web", Instances: 3, Dependencies: []string{"/prod/db"}},
+		&marathon.Application{ID: "/prod/db", Instances: 1})
+	prod.Groups["/prod"] = true
+	_, err := Create(prod, "prod", dir, nil)
+	assert.Nil(t, err)
+
+	dr := marathontest.New().WithApps(&marathon.Application{ID: "/dr/db", Instances: 2})
+	dr.Groups["/dr"] = true
+	steps, err := Plan(dr, dir, &RestoreOptions{Remap: map[string]string{"/prod": "/dr"}, SkipInstances: true})
+	assert.Nil(t, err)
+	actions := map[string]string{}
+	for _, s := range steps {
+		actions[s.ID] = s.Action
+	}
+	assert.Equal(t, map[string]string{"/dr": "none", "/dr/web": "create", "/dr/db": "none"}, actions)
+
+	assert.Equal(t, 0, Restore(dr, steps, false, 0))
+	web, err := dr.GetApplication("/dr/web")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/dr/db"}, web.Dependencies)
+	db, _ := dr.GetApplication("/dr/db")
+	assert.Equal(t, 2, db.Instances)
+
+	ioutil.WriteFile(filepath.Join(dir, "apps", "prod", "web.yaml"), []byte("id: /changed\n"), 0644)
+	_, err = Plan(dr, dir, nil)
+	assert.NotNil(t, err)
+}
+
+func TestRemapID(t *testing.T) {
+	remap := map[string]string{"/prod": "/dr", "/prod/shop/": "/shop"}
+	assert.Equal(t, "/dr/web", remapID("/prod/web", remap))
+	assert.Equal(t, "/dr", remapID("/prod", remap))
+	assert.Equal(t, "/shop/cart", remapID("/prod/shop/cart", remap))
+	assert.Equal(t, "/production/web", remapID("/production/web", remap))
+}
@@ -0,0 +1,224 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/reconcile"
+)
+
+// RestoreOptions change the definitions of a backup as they are restored
+type RestoreOptions struct {
+	// Replaces the prefix of ids (and dependencies) keyed by the prefix (eg. /prod -> /dr).  The longest
+	// matching prefix wins
+	Remap map[string]string
+	// Leaves the instance counts of the backup out.  Applications and pods already in the environment keep
+	// their instances while the others are created with one
+	SkipInstances bool
+}
+
+// Step restores a file of a backup to an environment
+type Step struct {
+	Kind string `json:"kind"`
+	// id within the environment once remapped
+	ID     string `json:"id"`
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+	app    *marathon.Application
+	group  *marathon.Group
+	pod    *marathon.Pod
+}
+
+// Plan returns the steps restoring the backup within {dir} to the environment reached by {client}.  Every
+// file must match the checksum of the manifest.  Missing applications, groups and pods are created and
+// applications and pods which differ are updated.  Groups which exist are left as is (restoring them would
+// replace their applications) and the launch queue isn't restored
+func Plan(client marathon.Marathon, dir string, opts *RestoreOptions) ([]*Step, error) {
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+	manifest, err := readManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	if invalid := verify(dir, manifest); len(invalid) > 0 {
+		return nil, fmt.Errorf("%s: %d file(s) of the backup are missing or have changed (eg. %s)", dir, len(invalid), invalid[0].Path)
+	}
+
+	enc := encoding.DefaultYAMLEncoder()
+	steps := []*Step{}
+	for _, f := range manifest.Files {
+		if f.Kind == KindQueue {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return nil, err
+		}
+		s := &Step{Kind: f.Kind, Path: f.Path, Action: reconcile.ActionNone}
+		switch f.Kind {
+		case KindApp:
+			s.app = &marathon.Application{}
+			err = enc.UnMarshalStr(string(data), s.app)
+		case KindGroup:
+			s.group = &marathon.Group{}
+			err = enc.UnMarshalStr(string(data), s.group)
+		case KindPod:
+			s.pod = &marathon.Pod{}
+			err = enc.UnMarshalStr(string(data), s.pod)
+		default:
+			err = fmt.Errorf("unknown kind '%s'", f.Kind)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", f.Path, err.Error())
+		}
+		if err := s.plan(client, opts); err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+	return steps, nil
+}
+
+// Restore makes the creates and updates of {steps} in order waiting for each when {wait} is true.  Every
+// step is attempted and the number which failed is returned
+func Restore(client marathon.Marathon, steps []*Step, wait bool, timeout time.Duration) int {
+	failed := 0
+	for _, s := range steps {
+		if s.Action == reconcile.ActionNone {
+			continue
+		}
+		if err := s.restore(client, wait, timeout); err != nil {
+			s.Result, s.Error = reconcile.ResultFailed, err.Error()
+			failed++
+			continue
+		}
+		s.Result = reconcile.ResultApplied
+	}
+	return failed
+}
+
+// Remaps the ids of the step's definition and compares it with the environment
+func (s *Step) plan(client marathon.Marathon, opts *RestoreOptions) error {
+	var err error
+	switch {
+	case s.app != nil:
+		s.app.ID = remapID(s.app.ID, opts.Remap)
+		s.app.Dependencies = remapIDs(s.app.Dependencies, opts.Remap)
+		s.ID = s.app.ID
+		var live *marathon.Application
+		if live, err = client.GetApplication(s.ID); err == nil {
+			if opts.SkipInstances {
+				s.app.Instances = live.Instances
+			}
+			if len(marathon.DiffApplication(s.app, live)) > 0 {
+				s.Action = reconcile.ActionUpdate
+			}
+		}
+		if opts.SkipInstances {
+			s.app.Instances = 0
+		}
+	case s.group != nil:
+		s.group.GroupID = remapID(s.group.GroupID, opts.Remap)
+		s.group.Dependencies = remapIDs(s.group.Dependencies, opts.Remap)
+		s.ID = s.group.GroupID
+		_, err = client.GetGroup(s.ID)
+	default:
+		s.pod.ID = remapID(s.pod.ID, opts.Remap)
+		s.ID = s.pod.ID
+		var live *marathon.Pod
+		if live, err = client.GetPod(s.ID); err == nil {
+			if opts.SkipInstances && s.pod.Scaling != nil && live.Scaling != nil {
+				s.pod.Scaling.Instances = live.Scaling.Instances
+			}
+			if !samePod(s.pod, live) {
+				s.Action = reconcile.ActionUpdate
+			}
+		} else if opts.SkipInstances && s.pod.Scaling != nil {
+			s.pod.Scaling.Instances = 1
+		}
+	}
+	if marathon.ErrorCodeOf(err) == marathon.CodeNotFound {
+		s.Action = reconcile.ActionCreate
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to get '%s': %s", s.ID, err.Error())
+	}
+	return nil
+}
+
+func (s *Step) restore(client marathon.Marathon, wait bool, timeout time.Duration) error {
+	logger.With(log, logger.Fields{logger.FieldApp: s.ID}).Info("Restoring %s of %s '%s' (%s)", s.Action, s.Kind, s.ID, s.Path)
+	update := s.Action == reconcile.ActionUpdate
+	switch {
+	case s.app != nil:
+		if _, err := client.CreateApplication(s.app, false, update); err != nil {
+			return err
+		}
+		if wait {
+			return client.WaitForApplication(s.ID, timeout)
+		}
+	case s.group != nil:
+		_, err := client.CreateGroup(s.group, false, false)
+		return err
+	default:
+		var err error
+		if update {
+			_, err = client.UpdatePod(s.pod, false, true)
+		} else {
+			_, err = client.CreatePod(s.pod, false, false)
+		}
+		if err == nil && wait {
+			err = client.WaitForPod(s.ID, timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+// Returns {id} with the longest prefix of {remap} it starts with replaced
+func remapID(id string, remap map[string]string) string {
+	match := ""
+	for prefix := range remap {
+		p := strings.TrimSuffix(prefix, "/")
+		if (id == p || strings.HasPrefix(id, p+"/")) && len(p) >= len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return id
+	}
+	rest := strings.TrimPrefix(id, strings.TrimSuffix(match, "/"))
+	return strings.TrimSuffix(remap[match], "/") + rest
+}
+
+func remapIDs(ids []string, remap map[string]string) []string {
+	if ids == nil {
+		return nil
+	}
+	remapped := make([]string, len(ids))
+	for i, id := range ids {
+		remapped[i] = remapID(id, remap)
+	}
+	return remapped
+}
+
+// Returns true if the pod {desired} matches the {live} pod ignoring its version
+func samePod(desired, live *marathon.Pod) bool {
+	version := live.Version
+	live.Version = ""
+	a, _ := json.Marshal(desired)
+	b, _ := json.Marshal(live)
+	live.Version = version
+	return string(a) == string(b)
+}
@@ -0,0 +1,204 @@
+// Mesos Chronos scheduler API
+package chronos
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	/* --- api related constants --- */
+	API_JOBS       = "v1/scheduler/jobs"
+	API_JOB        = "v1/scheduler/job"
+	API_SEARCH     = "v1/scheduler/jobs/search"
+	API_ISO8601    = "v1/scheduler/iso8601"
+	API_DEPENDENCY = "v1/scheduler/dependency"
+	API_KILL       = "v1/scheduler/task/kill"
+
+	TypeScheduled = "scheduled"
+	TypeDependent = "dependent"
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.chronos")
+
+var (
+	ErrorMissingName = errors.New("Document does not specify a job name")
+	ErrorJobExists   = errors.New("The job already exists - specify --force to update it")
+	ErrorNoSchedule  = errors.New("Document must specify either a schedule or parents")
+)
+
+type Chronos interface {
+
+	// Creates or updates the job within {doc}.  Jobs with 'parents' are registered as dependent jobs and all
+	// others must specify a 'schedule'
+	// {doc} - the job descriptor
+	// {force} - if false an existing job is not updated and ErrorJobExists is returned
+	ApplyJob(doc map[string]interface{}, force bool) (*ApplyResult, error)
+
+	// List all jobs
+	ListJobs() ([]*Job, error)
+
+	// Get a Job by name
+	// {name} - job name
+	GetJob(name string) (*Job, error)
+
+	// Removes a job
+	// {name} - job name
+	DeleteJob(name string) error
+
+	// Runs a job now regardless of its schedule
+	// {name} - job name
+	// {arguments} - optional arguments appended to the command
+	StartJob(name string, arguments string) error
+
+	// Kills the running tasks of a job
+	// {name} - job name
+	KillTasks(name string) error
+}
+
+type ChronosClient struct {
+	http httpclient.HttpClient
+	host string
+}
+
+type ChronosOptions struct {
+	TLSAllowInsecure bool
+	// Optional token based authentication (eg. DC/OS) used in place of basic auth
+	Authenticator httpclient.Authenticator
+	// Optional proxies used in place of the proxy environment variables
+	Proxy *httpclient.ProxyConfig
+	// Optional client certificate and CA bundle for mutual TLS
+	TLS *httpclient.TLSConfig
+	// Refuses applying, starting and deleting jobs and killing their tasks (every request other than a GET)
+	// with httpclient.ErrorReadOnly
+	ReadOnly bool
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Static headers added to every request
+	Headers map[string]string
+}
+
+// NewChronosClient creates a client for the Chronos at {host}
+func NewChronosClient(host, username, password string, opts *ChronosOptions) Chronos {
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.HttpUser = username
+	httpConfig.HttpPass = password
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	if opts != nil {
+		httpConfig.TLSInsecureSkipVerify = opts.TLSAllowInsecure
+		httpConfig.Authenticator = opts.Authenticator
+		httpConfig.Proxy = opts.Proxy
+		httpConfig.TLS = opts.TLS
+		httpConfig.ReadOnly = opts.ReadOnly
+		httpConfig.Timeouts = opts.Timeouts
+		httpConfig.Headers = opts.Headers
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+	}
+
+	c := new(ChronosClient)
+	c.http = *httpclient.NewHttpClient(*httpConfig)
+	c.host = host
+	return c
+}
+
+// Returns the URL of {api} followed by {elements} with the additional query {params}
+func (c *ChronosClient) chronosUrl(params url.Values, api string, elements ...string) string {
+	for i, e := range elements {
+		elements[i] = url.PathEscape(e)
+	}
+	uri := utils.BuildPath(c.host, append([]string{api}, elements...))
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+	return uri
+}
+
+func (c *ChronosClient) ApplyJob(doc map[string]interface{}, force bool) (*ApplyResult, error) {
+	name, _ := doc["name"].(string)
+	if name == "" {
+		return nil, ErrorMissingName
+	}
+
+	result := &ApplyResult{Name: name, Type: TypeScheduled}
+	api := API_ISO8601
+	if parents, ok := doc["parents"].([]interface{}); ok && len(parents) > 0 {
+		result.Type = TypeDependent
+		api = API_DEPENDENCY
+	} else if schedule, _ := doc["schedule"].(string); schedule == "" {
+		return nil, ErrorNoSchedule
+	}
+
+	// Chronos replaces existing jobs on create so existence is checked first
+	existing, err := c.GetJob(name)
+	switch {
+	case err == httpclient.ErrorNotFound:
+		result.Created = true
+	case err != nil:
+		return nil, err
+	case existing != nil && !force:
+		return nil, ErrorJobExists
+	default:
+		log.Info("Job '%s' exists - updating", name)
+	}
+
+	if resp := c.http.HttpPost(c.chronosUrl(nil, api), doc, nil); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return result, nil
+}
+
+func (c *ChronosClient) ListJobs() ([]*Job, error) {
+	jobs := []*Job{}
+	if resp := c.http.HttpGet(c.chronosUrl(nil, API_JOBS), &jobs); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return jobs, nil
+}
+
+func (c *ChronosClient) GetJob(name string) (*Job, error) {
+	jobs := []*Job{}
+	if resp := c.http.HttpGet(c.chronosUrl(url.Values{"name": []string{name}}, API_SEARCH), &jobs); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	// the search matches names containing {name}
+	for _, j := range jobs {
+		if j.Name == name {
+			return j, nil
+		}
+	}
+	return nil, httpclient.ErrorNotFound
+}
+
+func (c *ChronosClient) DeleteJob(name string) error {
+	if resp := c.http.HttpDelete(c.chronosUrl(nil, API_JOB, name), nil, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *ChronosClient) StartJob(name string, arguments string) error {
+	var params url.Values
+	if arguments != "" {
+		params = url.Values{"arguments": []string{arguments}}
+	}
+	if resp := c.http.HttpPut(c.chronosUrl(params, API_JOB, name), nil, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *ChronosClient) KillTasks(name string) error {
+	if resp := c.http.HttpDelete(c.chronosUrl(nil, API_KILL, name), nil, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package chronos
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/mockrest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(handler http.HandlerFunc) (c Chronos, requests *[]mockrest.RecordedRequest, stop func()) {
+	requests, stop = mockrest.RecordClient(handler, func(url string, retry *httpclient.RetryPolicy) {
+		c = NewChronosClient(url, "", "", &ChronosOptions{Retry: retry})
+	})
+	return
+}
+
+func TestApplyScheduledJob(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/scheduler/jobs/search" {
+			fmt.Fprint(w, `[{"name": "backup-old"}]`)
+		}
+	})
+	defer stop()
+
+	doc := map[string]interface{}{"name": "backup", "command": "backup.sh", "schedule": "R/2016-01-01T02:00:00Z/P1D"}
+	result, err := c.ApplyJob(doc, false)
+	assert.Nil(t, err)
+	assert.Equal(t, &ApplyResult{Name: "backup", Type: TypeScheduled, Created: true}, result)
+	assert.Equal(t, "name=backup", (*requests)[0].Query)
+	assert.Equal(t, "POST", (*requests)[1].Method)
+	assert.Equal(t, "/v1/scheduler/iso8601", (*requests)[1].Path)
+	assert.Equal(t, "backup.sh", (*requests)[1].Body["command"])
+}
+
+func TestApplyDependentJobRequiresForceWhenExists(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/scheduler/jobs/search" {
+			fmt.Fprint(w, `[{"name": "report", "parents": ["backup"]}]`)
+		}
+	})
+	defer stop()
+
+	doc := map[string]interface{}{"name": "report", "command": "report.sh", "parents": []interface{}{"backup"}}
+	_, err := c.ApplyJob(doc, false)
+	assert.Equal(t, ErrorJobExists, err)
+
+	result, err := c.ApplyJob(doc, true)
+	assert.Nil(t, err)
+	assert.Equal(t, TypeDependent, result.Type)
+	assert.False(t, result.Created)
+	assert.Equal(t, "/v1/scheduler/dependency", (*requests)[len(*requests)-1].Path)
+
+	_, err = c.ApplyJob(map[string]interface{}{"name": "orphan"}, true)
+	assert.Equal(t, ErrorNoSchedule, err)
+}
+
+func TestStartJobWithArguments(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {})
+	defer stop()
+
+	assert.Nil(t, c.StartJob("backup", "--full"))
+	assert.Equal(t, "PUT", (*requests)[0].Method)
+	assert.Equal(t, "/v1/scheduler/job/backup", (*requests)[0].Path)
+	assert.Equal(t, "arguments=--full", (*requests)[0].Query)
+}
@@ -0,0 +1,41 @@
+package chronos
+
+// The subset of the Chronos API objects displayed by depcon.  Jobs are created from the descriptor
+// as written so fields not listed here are preserved
+
+type Job struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+	Command     string `json:"command"`
+	// ISO 8601 repeating interval (eg. R/2016-01-01T02:00:00Z/P1D).  Empty for dependent jobs
+	Schedule         string   `json:"schedule,omitempty"`
+	ScheduleTimeZone string   `json:"scheduleTimeZone,omitempty"`
+	Parents          []string `json:"parents,omitempty"`
+	Disabled         bool     `json:"disabled"`
+	Cpus             float64  `json:"cpus"`
+	Mem              float64  `json:"mem"`
+	Disk             float64  `json:"disk"`
+	Retries          int      `json:"retries"`
+	SuccessCount     int      `json:"successCount"`
+	ErrorCount       int      `json:"errorCount"`
+	LastSuccess      string   `json:"lastSuccess,omitempty"`
+	LastError        string   `json:"lastError,omitempty"`
+	Container        *struct {
+		Type  string `json:"type"`
+		Image string `json:"image"`
+	} `json:"container,omitempty"`
+}
+
+// ApplyResult is the outcome of creating or updating a job from a descriptor
+type ApplyResult struct {
+	Name string `json:"name"`
+	// scheduled or dependent
+	Type    string `json:"type"`
+	Created bool   `json:"created"`
+}
+
+// IsDependent returns true if the job is run when its parents complete rather than on a schedule
+func (j *Job) IsDependent() bool {
+	return len(j.Parents) > 0
+}
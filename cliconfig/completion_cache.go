@@ -0,0 +1,54 @@
+package cliconfig
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const completionDir = "completion"
+
+// CompletionCache keeps the identifiers (eg. application IDs) used for shell completion by environment
+// for {TTL} so each key press doesn't query the cluster.  Entries are kept within the config directory
+type CompletionCache struct {
+	TTL time.Duration
+}
+
+// Load returns the cached identifiers of {kind} for environment {env} if they haven't expired
+func (c CompletionCache) Load(env, kind string) ([]string, bool) {
+	filename := c.filename(env, kind)
+	fi, err := os.Stat(filename)
+	if err != nil || time.Since(fi.ModTime()) > c.TTL {
+		return nil, false
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, false
+	}
+	var ids []string
+	if err := json.Unmarshal(b, &ids); err != nil {
+		return nil, false
+	}
+	return ids, true
+}
+
+// Store caches the identifiers {ids} of {kind} for environment {env}
+func (c CompletionCache) Store(env, kind string, ids []string) error {
+	dir := filepath.Join(ConfigDir(), completionDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.filename(env, kind), b, 0600)
+}
+
+func (c CompletionCache) filename(env, kind string) string {
+	name := strings.NewReplacer("/", "_", string(filepath.Separator), "_").Replace(env + "-" + kind)
+	return filepath.Join(ConfigDir(), completionDir, name)
+}
@@ -0,0 +1,35 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletionCache(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon-completion")
+	defer os.RemoveAll(dir)
+	defer SetConfigDir(ConfigDir())
+	SetConfigDir(dir)
+
+	cache := CompletionCache{TTL: time.Minute}
+	_, ok := cache.Load("prod", "apps")
+	assert.False(t, ok)
+
+	assert.NoError(t, cache.Store("prod", "apps", []string{"/web", "/worker"}))
+	ids, ok := cache.Load("prod", "apps")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"/web", "/worker"}, ids)
+
+	_, ok = cache.Load("stage", "apps")
+	assert.False(t, ok)
+
+	expired := time.Now().Add(-2 * time.Minute)
+	os.Chtimes(filepath.Join(dir, completionDir, "prod-apps"), expired, expired)
+	_, ok = cache.Load("prod", "apps")
+	assert.False(t, ok)
+}
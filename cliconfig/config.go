@@ -8,7 +8,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/ContainX/depcon/cost"
+	"github.com/ContainX/depcon/pkg/access"
+	"github.com/ContainX/depcon/pkg/freeze"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/login"
+	"github.com/ContainX/depcon/pkg/remote"
+	"github.com/ContainX/depcon/pkg/secrets"
 	"github.com/ContainX/depcon/pkg/userdir"
+	"github.com/ContainX/depcon/registry"
 	"io"
 	"os"
 	"path/filepath"
@@ -21,8 +29,17 @@ const (
 	TypeMarathon   = "marathon"
 	TypeKubernetes = "kubernetes"
 	TypeECS        = "ecs"
+	TypeNomad      = "nomad"
+	TypeSwarm      = "swarm"
+	AuthBasic      = "basic"
+	AuthDCOS       = "dcos"
+	AuthOIDC       = login.MethodOIDC
+	AuthLDAP       = login.MethodLDAP
 )
 
+// AuthModes are the valid authentication modes of an environment
+var AuthModes = []string{AuthBasic, AuthDCOS, AuthOIDC, AuthLDAP}
+
 var (
 	configDir      = os.Getenv("DEPCON_CONFIG")
 	ErrEnvNotFound = errors.New("Specified environment was not found")
@@ -45,16 +62,101 @@ func SetConfigDir(dir string) {
 }
 
 type ConfigFile struct {
-	Format       string                        `json:"format,omitempty"`
-	RootService  bool                          `json:"rootservice"`
-	Environments map[string]*ConfigEnvironment `json:"environments,omitempty"`
-	DefaultEnv   string                        `json:"default,omitempty"`
-	filename     string                        // not serialized
+	Format         string                        `json:"format,omitempty"`
+	Color          string                        `json:"color,omitempty"`  // auto (default) | always | never
+	Theme          string                        `json:"theme,omitempty"`  // built-in output theme (default | bright | mono)
+	Colors         map[string]string             `json:"colors,omitempty"` // theme overrides (eg. {"healthy": "1;32"})
+	RootService    bool                          `json:"rootservice"`
+	Environments   map[string]*ConfigEnvironment `json:"environments,omitempty"`
+	DefaultEnv     string                        `json:"default,omitempty"`
+	Groups         map[string][]string           `json:"groups,omitempty"`         // environment groups (eg. prod = [prod-us, prod-eu])
+	SecretPatterns []string                      `json:"secretPatterns,omitempty"` // names of secrets masked in output in addition to password, token etc (eg. ["dsn"])
+	Access         *access.Policy                `json:"access,omitempty"`         // roles restricting which users may change which environments
+	Formats        map[string]string             `json:"formats,omitempty"`        // output templates referenced by --format @name (eg. {"images": "{{ .ID }}"})
+	filename       string                        // not serialized
 }
 
 type ConfigEnvironment struct {
 	// currently only supporting marathon as initial release
 	Marathon *ServiceConfig `json:"marathon,omitempty"`
+	// Default flag values (flag name to value) applied to commands run against this environment
+	// unless specified on the command line (eg. {"wait": "true", "timeout": "5m"})
+	Flags map[string]string `json:"flags,omitempty"`
+	// Optional Kubernetes cluster used by the k8s commands while migrating from Marathon
+	Kubernetes *KubernetesConfig `json:"kubernetes,omitempty"`
+	// Optional ECS cluster used by the ecs commands.  An environment may define only an ECS cluster
+	// in place of a Marathon service
+	ECS *ECSConfig `json:"ecs,omitempty"`
+	// Optional Nomad cluster used by the nomad commands.  An environment may define only a Nomad cluster
+	// in place of a Marathon service
+	Nomad *NomadConfig `json:"nomad,omitempty"`
+	// Optional Docker Swarm used by the swarm commands.  An environment may define only a swarm in place
+	// of a Marathon service
+	Swarm *SwarmConfig `json:"swarm,omitempty"`
+	// Optional secrets providers keyed by the name used within secret://name/path references
+	// (eg. {"vault": {"address": "https://vault:8200"}}).  The env and file providers need no configuration
+	Secrets map[string]*secrets.Config `json:"secrets,omitempty"`
+	// Optional unit prices of resources used by the cost command (eg. {"cpu": 25, "mem": 4})
+	Cost *cost.Prices `json:"cost,omitempty"`
+	// Optional windows changes are refused unless --break-glass is specified (eg. [{"name": "weekend",
+	// "start": "Fri 16:00", "end": "Mon 08:00", "timezone": "Europe/London"}])
+	Freeze []*freeze.Window `json:"freeze,omitempty"`
+	// Optional recurring windows changes are allowed in, refused (or warned about with the warn policy)
+	// outside them unless --break-glass is specified (eg. {"windows": [{"cron": "* 9-16 * * mon-fri"}]})
+	ChangeWindows *freeze.ChangeWindows `json:"changeWindows,omitempty"`
+	// Optional registries whose credentials are packaged into a docker.tar.gz added to the fetch URIs of the
+	// applications pulling from them (eg. [{"registry": "registry.example.com", "credentials":
+	// "secret://vault/registry/creds/deploy"}])
+	Registries []*registry.Config `json:"registries,omitempty"`
+	// Optional credentials sent when fetching descriptors from URLs with these prefixes (eg. [{"url":
+	// "https://artifacts.example.com/", "username": "ci", "password": "secret://vault/ci/artifacts#password"}])
+	Remotes []*remote.Source `json:"remotes,omitempty"`
+	// Optional public key files (created by depcon sign keygen) one of which must have signed every descriptor
+	// deployed to this environment.  Unlike the verify-signature flag it can't be defaulted away by a project
+	TrustedKeys []string `json:"trustedKeys,omitempty"`
+}
+
+// SwarmConfig is the Docker engine of a swarm manager used by the swarm commands.  Empty values fall back to
+// the variables used by the docker CLI ($DOCKER_HOST, $DOCKER_CERT_PATH, $DOCKER_TLS_VERIFY)
+type SwarmConfig struct {
+	// unix:///var/run/docker.sock, tcp://host:2376 or ssh+http:// tunnels
+	Host string `json:"host,omitempty"`
+	// Optional client certificate and CA bundle
+	TLS *httpclient.TLSConfig `json:"tls,omitempty"`
+	// Mutating commands are refused unless --allow-write is specified
+	ReadOnly bool `json:"readonly,omitempty"`
+}
+
+// NomadConfig is the Nomad cluster used by the nomad commands.  Empty values fall back to the variables
+// used by the nomad CLI ($NOMAD_ADDR, $NOMAD_NAMESPACE, $NOMAD_REGION).  The ACL token is read from $NOMAD_TOKEN
+type NomadConfig struct {
+	Address   string `json:"address,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Region    string `json:"region,omitempty"`
+	// Mutating commands are refused unless --allow-write is specified
+	ReadOnly bool `json:"readonly,omitempty"`
+}
+
+// ECSConfig is the ECS cluster and region used by the ecs commands.  Credentials are read from the AWS
+// environment variables or the shared credentials file (~/.aws/credentials)
+type ECSConfig struct {
+	Cluster string `json:"cluster,omitempty"`
+	// Defaults to $AWS_REGION
+	Region string `json:"region,omitempty"`
+	// Named profile within the shared credentials file
+	Profile string `json:"profile,omitempty"`
+	// Optional endpoint used in place of the regional endpoint (eg. a VPC endpoint)
+	Endpoint string `json:"endpoint,omitempty"`
+	// Mutating commands are refused unless --allow-write is specified
+	ReadOnly bool `json:"readonly,omitempty"`
+}
+
+// KubernetesConfig selects the kubeconfig, context and namespace used by the k8s commands.  Empty values
+// fall back to $KUBECONFIG (or ~/.kube/config), its current context and the context's namespace
+type KubernetesConfig struct {
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	Context    string `json:"context,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
 }
 
 type ServiceConfig struct {
@@ -62,7 +164,60 @@ type ServiceConfig struct {
 	Password string            `json:"password,omitempty"`
 	HostUrl  string            `json:"serveraddress,omitempty"`
 	Features map[string]string `json:"features,omitempty"`
-	Name     string            `json:"-"`
+	// If true the password is stored within the OS keyring rather than this file
+	Keyring bool `json:"keyring,omitempty"`
+	// Authentication mode [ basic (default) | dcos | oidc | ldap ]
+	Auth string `json:"auth,omitempty"`
+	// Identity provider of the oidc and ldap modes whose tokens are obtained with 'depcon login'
+	Login *login.Config `json:"login,omitempty"`
+	// DC/OS service account secret or private key file.  When set the environment logs in as the
+	// service account (Username is the service account uid) instead of using a password
+	ServiceAccount string `json:"serviceaccount,omitempty"`
+	// Proxies used for this environment in place of the proxy environment variables
+	Proxy *httpclient.ProxyConfig `json:"proxy,omitempty"`
+	// Client certificate and CA bundle for clusters requiring mutual TLS
+	TLS *httpclient.TLSConfig `json:"tls,omitempty"`
+	// Connect, TLS handshake, response header and overall request timeouts (eg. {"request": "2m"})
+	Timeouts *httpclient.Timeouts `json:"timeouts,omitempty"`
+	// Maximum requests per second sent to this environment (eg. 5 for a small master).  Zero is unlimited
+	RateLimit float64 `json:"ratelimit,omitempty"`
+	// Compresses large request bodies with gzip.  Requires a Marathon (or gateway) which accepts
+	// gzip encoded requests
+	Compress bool `json:"compress,omitempty"`
+	// Static headers attached to every request (eg. {"X-Tenant": "payments"}) for installs behind gateways
+	Headers map[string]string `json:"headers,omitempty"`
+	// Metronome URL used by the job commands.  DC/OS environments default to the cluster's /service/metronome
+	MetronomeUrl string `json:"metronome,omitempty"`
+	// Chronos URL used by the chronos commands.  DC/OS environments default to the cluster's /service/chronos
+	ChronosUrl string `json:"chronos,omitempty"`
+	// Mesos master URL used by the mesos commands.  Defaults to the cluster's /mesos for DC/OS environments
+	// and port 5050 of the Marathon host otherwise
+	MesosUrl string `json:"mesos,omitempty"`
+	// Marathon-LB admin URL (eg. http://lb.example.com:9090) used by the lb and bluegreen commands
+	MarathonLBUrl string `json:"marathon_lb,omitempty"`
+	// Mutating commands are refused unless --allow-write is specified
+	ReadOnly bool   `json:"readonly,omitempty"`
+	Name     string `json:"-"`
+}
+
+// Determines if the environment authenticates against DC/OS in which case HostUrl is the cluster URL
+func (service *ServiceConfig) IsDCOS() bool {
+	return service.Auth == AuthDCOS
+}
+
+// Determines if the environment sends the token of a 'depcon login' (oidc or ldap)
+func (service *ServiceConfig) UsesLogin() bool {
+	return service.Auth == AuthOIDC || service.Auth == AuthLDAP
+}
+
+// ValidAuth returns true if {auth} is one of the AuthModes
+func ValidAuth(auth string) bool {
+	for _, mode := range AuthModes {
+		if auth == mode {
+			return true
+		}
+	}
+	return false
 }
 
 func HasExistingConfig() (*ConfigFile, bool) {
@@ -75,7 +230,14 @@ func (configFile *ConfigFile) LoadFromReader(configData io.Reader) error {
 		return err
 	}
 	var err error
-	for _, configEnv := range configFile.Environments {
+	for name, configEnv := range configFile.Environments {
+		if configEnv.Marathon == nil {
+			continue
+		}
+		if configEnv.Marathon.Keyring {
+			configEnv.Marathon.Password = keyringPassword(name)
+			continue
+		}
 		configEnv.Marathon.Password, err = DecodePassword(configEnv.Marathon.Password)
 		if err != nil {
 			return err
@@ -120,8 +282,19 @@ func (configFile *ConfigFile) DetermineIfServiceIsRooted() (string, bool) {
 	return TypeMarathon, configFile.RootService
 }
 
+// EnvironmentType returns TypeECS, TypeNomad or TypeSwarm for environments defining only an ECS cluster,
+// Nomad cluster or swarm and TypeMarathon otherwise
 func (configEnv *ConfigEnvironment) EnvironmentType() string {
-	// FIXME: Once we support more than Marathon (post initial release) - determine type
+	switch {
+	case configEnv.Marathon != nil:
+		return TypeMarathon
+	case configEnv.ECS != nil:
+		return TypeECS
+	case configEnv.Nomad != nil:
+		return TypeNomad
+	case configEnv.Swarm != nil:
+		return TypeSwarm
+	}
 	return TypeMarathon
 }
 
@@ -152,6 +325,33 @@ func (configFile *ConfigFile) AddMarathonEnvironment(name, host, user, pass stri
 	configFile.Save()
 }
 
+// Adds an environment named {name} for the ECS {cluster} within {region}
+func (configFile *ConfigFile) AddECSEnvironment(name string, ecs *ECSConfig) {
+	if len(configFile.Environments) == 0 {
+		configFile.DefaultEnv = name
+	}
+	configFile.Environments[name] = &ConfigEnvironment{ECS: ecs}
+	configFile.Save()
+}
+
+// Adds an environment named {name} for the Nomad cluster {nomad}
+func (configFile *ConfigFile) AddNomadEnvironment(name string, nomad *NomadConfig) {
+	if len(configFile.Environments) == 0 {
+		configFile.DefaultEnv = name
+	}
+	configFile.Environments[name] = &ConfigEnvironment{Nomad: nomad}
+	configFile.Save()
+}
+
+// Adds an environment named {name} for the swarm {swarm}
+func (configFile *ConfigFile) AddSwarmEnvironment(name string, swarm *SwarmConfig) {
+	if len(configFile.Environments) == 0 {
+		configFile.DefaultEnv = name
+	}
+	configFile.Environments[name] = &ConfigEnvironment{Swarm: swarm}
+	configFile.Save()
+}
+
 // Removes the specified environment from the configuration
 // {name}  - name of the environment
 // {force} - if true will not prompt for confirmation
@@ -170,7 +370,11 @@ func (configFile *ConfigFile) RemoveEnvironment(name string, force bool) error {
 	if configFile.DefaultEnv == name {
 		configFile.DefaultEnv = ""
 	}
+	if configEnv.Marathon != nil && configEnv.Marathon.Keyring {
+		deleteKeyringPassword(name)
+	}
 	delete(configFile.Environments, name)
+	configFile.updateGroupMembers(name, "")
 	configFile.Save()
 
 	return nil
@@ -204,9 +408,15 @@ func (configFile *ConfigFile) RenameEnvironment(oldName, newName string) error {
 	delete(configFile.Environments, oldName)
 	configFile.Environments[newName] = configEnv
 
+	// the keyring entry is re-created under the new name when saved
+	if configEnv.Marathon != nil && configEnv.Marathon.Keyring {
+		deleteKeyringPassword(oldName)
+	}
+
 	if configFile.DefaultEnv == oldName {
 		configFile.DefaultEnv = newName
 	}
+	configFile.updateGroupMembers(oldName, newName)
 	configFile.Save()
 	return nil
 }
@@ -232,13 +442,28 @@ func (configFile *ConfigFile) GetEnvironments() []string {
 func (configFile *ConfigFile) SaveToWriter(writer io.Writer) error {
 	tmpEnvConfigs := make(map[string]*ConfigEnvironment, len(configFile.Environments))
 	for k, configEnv := range configFile.Environments {
-		configEnvCopy := configEnv
-
-		if configEnvCopy.Marathon != nil {
-			configEnvCopy.Marathon.Password = EncodePassword(configEnvCopy.Marathon)
-			configEnvCopy.Marathon.Name = ""
+		configEnvCopy := *configEnv
+
+		if configEnv.Marathon != nil {
+			service := *configEnv.Marathon
+			service.Name = ""
+
+			// passwords are placed in the OS keyring when available falling back to this file
+			switch {
+			case service.Password != "" && storeKeyringPassword(k, service.Password):
+				configEnv.Marathon.Keyring = true
+				service.Keyring = true
+				service.Password = ""
+			case service.Password != "":
+				configEnv.Marathon.Keyring = false
+				service.Keyring = false
+				service.Password = EncodePassword(&service)
+			case !service.Keyring:
+				service.Password = EncodePassword(&service)
+			}
+			configEnvCopy.Marathon = &service
 		}
-		tmpEnvConfigs[k] = configEnvCopy
+		tmpEnvConfigs[k] = &configEnvCopy
 	}
 	saveEnvConfigs := configFile.Environments
 	configFile.Environments = tmpEnvConfigs
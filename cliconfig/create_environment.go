@@ -2,11 +2,13 @@ package cliconfig
 
 import (
 	"fmt"
+	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/ContainX/depcon/utils"
 	"github.com/bgentry/speakeasy"
 	"net/url"
 	"os"
 	"regexp"
+	"strings"
 )
 
 const (
@@ -54,7 +56,7 @@ func getPasswordWithVerify() string {
 func getMarathonURL(count int) string {
 	if count > 5 {
 		fmt.Printf("Too many retries obtaining Marathon URL.  If depcon is running within docker please insure 'docker run -it' is set.\n")
-		os.Exit(1)
+		os.Exit(cli.ExitError)
 	}
 	var response string
 	fmt.Print("Marathon URL (eg. http://hostname:8080)  : ")
@@ -69,10 +71,14 @@ func getMarathonURL(count int) string {
 	return getMarathonURL(count + 1)
 }
 
+// ValidateMarathonURL verifies {marathonURL} is a valid URL or a comma separated list of URLs (each
+// node of an HA cluster)
 func ValidateMarathonURL(marathonURL string) error {
-	_, err := url.ParseRequestURI(marathonURL)
-	if err != nil || !utils.HasURLScheme(marathonURL) {
-		return fmt.Errorf("ERROR: '%s' must be a valid URL", marathonURL)
+	for _, u := range strings.Split(marathonURL, ",") {
+		_, err := url.ParseRequestURI(u)
+		if err != nil || !utils.HasURLScheme(u) {
+			return fmt.Errorf("ERROR: '%s' must be a valid URL", marathonURL)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,134 @@
+package cliconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/ContainX/depcon/pkg/envcrypt"
+)
+
+const exportVersion = 2
+
+var (
+	ErrExportPassword = errors.New("A password is required to export or import environments")
+	ErrDecryptFailed  = errors.New("Unable to decrypt environments - the password is incorrect or the file has been modified")
+)
+
+// Environments carried within the file written by ExportEnvironments (see envcrypt.Encrypt).  Passwords
+// are stored as plaintext within the payload since the payload itself is encrypted
+type exportPayload struct {
+	Version      int                           `json:"version"`
+	Environments map[string]*ConfigEnvironment `json:"environments"`
+	// default environment of the exporting configuration when it is one of the exported environments
+	Default string `json:"default,omitempty"`
+}
+
+// Writes the specified environments (or all when {names} is empty) encrypted with a key derived
+// from {password} to {w}
+func (configFile *ConfigFile) ExportEnvironments(w io.Writer, names []string, password string) error {
+	if password == "" {
+		return ErrExportPassword
+	}
+	if len(names) == 0 {
+		names = configFile.GetEnvironments()
+	}
+
+	payload := exportPayload{Version: exportVersion, Environments: make(map[string]*ConfigEnvironment)}
+	for _, name := range names {
+		configEnv, err := configFile.GetEnvironment(name)
+		if err != nil {
+			return fmt.Errorf("%s: '%s'", err.Error(), name)
+		}
+		env := *configEnv
+		if configEnv.Marathon != nil {
+			// the password travels within the payload so where the importer stores it is its own choice
+			service := *configEnv.Marathon
+			service.Keyring = false
+			env.Marathon = &service
+		}
+		payload.Environments[name] = &env
+		if name == configFile.DefaultEnv {
+			payload.Default = name
+		}
+	}
+
+	plain, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	data, err := envcrypt.Encrypt(plain, password)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Reads environments previously written by ExportEnvironments and adds them to the configuration.
+// Environments which already exist are skipped unless {overwrite} is true.  Returns the names of
+// the imported environments
+func (configFile *ConfigFile) ImportEnvironments(r io.Reader, password string, overwrite bool) ([]string, error) {
+	if password == "" {
+		return nil, ErrExportPassword
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := envcrypt.Decrypt(data, password)
+	switch {
+	case err == envcrypt.ErrorDecryptFailed:
+		return nil, ErrDecryptFailed
+	case err != nil:
+		return nil, fmt.Errorf("Invalid environment export file: %s", err.Error())
+	}
+
+	payload := &exportPayload{}
+	if err := json.Unmarshal(plain, payload); err != nil {
+		return nil, fmt.Errorf("Invalid environment export file: %s", err.Error())
+	}
+	if payload.Version != exportVersion {
+		return nil, fmt.Errorf("Unsupported environment export file (version %d)", payload.Version)
+	}
+
+	names := make([]string, 0, len(payload.Environments))
+	for name := range payload.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	imported := []string{}
+	for _, name := range names {
+		if _, exists := configFile.Environments[name]; exists && !overwrite {
+			continue
+		}
+		configEnv := payload.Environments[name]
+		if configEnv == nil {
+			continue
+		}
+		if configEnv.Marathon != nil {
+			configEnv.Marathon.Name = name
+		}
+		configFile.Environments[name] = configEnv
+		imported = append(imported, name)
+	}
+
+	if len(imported) > 0 {
+		if configFile.DefaultEnv == "" && len(configFile.Environments) == len(imported) {
+			configFile.DefaultEnv = imported[0]
+			if _, ok := configFile.Environments[payload.Default]; ok {
+				configFile.DefaultEnv = payload.Default
+			}
+		}
+		if err := configFile.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return imported, nil
+}
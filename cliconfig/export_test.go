@@ -0,0 +1,144 @@
+package cliconfig
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/cost"
+	"github.com/ContainX/depcon/pkg/freeze"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/login"
+	"github.com/ContainX/depcon/pkg/remote"
+	"github.com/ContainX/depcon/pkg/secrets"
+	"github.com/ContainX/depcon/registry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImportEnvironments(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon")
+	defer os.RemoveAll(dir)
+
+	src := &ConfigFile{Environments: map[string]*ConfigEnvironment{
+		"prod": {Marathon: &ServiceConfig{HostUrl: "http://prod:8080", Username: "admin", Password: "secret"}},
+		"qa":   {Marathon: &ServiceConfig{HostUrl: "http://qa:8080"}},
+	}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, src.ExportEnvironments(&buf, []string{"prod"}, "pw"))
+	assert.NotContains(t, buf.String(), "secret")
+
+	dest, _ := Load(dir)
+	_, err := dest.ImportEnvironments(bytes.NewReader(buf.Bytes()), "wrong", false)
+	assert.Equal(t, ErrDecryptFailed, err)
+
+	imported, err := dest.ImportEnvironments(bytes.NewReader(buf.Bytes()), "pw", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod"}, imported)
+
+	reloaded, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://prod:8080", reloaded.Environments["prod"].Marathon.HostUrl)
+	assert.Equal(t, "secret", reloaded.Environments["prod"].Marathon.Password)
+	assert.Equal(t, "prod", reloaded.DefaultEnv)
+}
+
+func TestExportImportEveryField(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon")
+	defer os.RemoveAll(dir)
+
+	env := &ConfigEnvironment{
+		Marathon: &ServiceConfig{
+			Username:       "admin",
+			Password:       "secret",
+			HostUrl:        "http://prod:8080",
+			Features:       map[string]string{"pods": "true"},
+			Auth:           AuthOIDC,
+			Login:          &login.Config{Issuer: "https://login.example.com", ClientID: "depcon", Scopes: []string{"openid"}},
+			ServiceAccount: "/etc/depcon/sa.json",
+			Proxy:          &httpclient.ProxyConfig{HTTP: "http://proxy:3128", NoProxy: ".example.com"},
+			TLS:            &httpclient.TLSConfig{CAFile: "/etc/depcon/ca.pem"},
+			Timeouts:       &httpclient.Timeouts{Request: httpclient.Duration(2 * time.Minute)},
+			RateLimit:      5,
+			Compress:       true,
+			Headers:        map[string]string{"X-Tenant": "payments"},
+			MetronomeUrl:   "http://metronome:9000",
+			ChronosUrl:     "http://chronos:4400",
+			MesosUrl:       "http://mesos:5050",
+			MarathonLBUrl:  "http://lb:9090",
+			ReadOnly:       true,
+		},
+		Flags:         map[string]string{"wait": "true"},
+		Kubernetes:    &KubernetesConfig{Context: "prod", Namespace: "web"},
+		ECS:           &ECSConfig{Cluster: "prod", Region: "eu-west-1"},
+		Nomad:         &NomadConfig{Address: "http://nomad:4646"},
+		Swarm:         &SwarmConfig{Host: "tcp://swarm:2376"},
+		Secrets:       map[string]*secrets.Config{"vault": {Address: "https://vault:8200"}},
+		Cost:          &cost.Prices{CPU: 25, Mem: 4},
+		Freeze:        []*freeze.Window{{Name: "weekend", Start: "Fri 16:00", End: "Mon 08:00"}},
+		ChangeWindows: &freeze.ChangeWindows{Windows: []*freeze.Schedule{{Cron: "* 9-16 * * mon-fri"}}},
+		Registries:    []*registry.Config{{Registry: "registry.example.com", Credentials: "secret://vault/registry"}},
+		Remotes:       []*remote.Source{{URL: "https://artifacts.example.com/", Token: "secret://vault/ci#token"}},
+		TrustedKeys:   []string{"/etc/depcon/release.pub"},
+	}
+	// fields added later must be set above so they're known to survive an export
+	assertFieldsSet(t, *env)
+	assertFieldsSet(t, *env.Marathon, "Keyring", "Name")
+
+	src := &ConfigFile{DefaultEnv: "prod", Environments: map[string]*ConfigEnvironment{"prod": env, "qa": {Flags: map[string]string{"wait": "false"}}}}
+	var buf bytes.Buffer
+	assert.NoError(t, src.ExportEnvironments(&buf, nil, "pw"))
+
+	dest, _ := Load(dir)
+	imported, err := dest.ImportEnvironments(bytes.NewReader(buf.Bytes()), "pw", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod", "qa"}, imported)
+
+	reloaded, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", reloaded.DefaultEnv, "the exported default is kept")
+
+	got := reloaded.Environments["prod"]
+	got.Marathon.Keyring, got.Marathon.Name = false, ""
+	assert.Equal(t, env, got)
+}
+
+func TestImportRejectsTamperedFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon")
+	defer os.RemoveAll(dir)
+
+	src := &ConfigFile{Environments: map[string]*ConfigEnvironment{"prod": {Flags: map[string]string{"wait": "true"}}}}
+	var buf bytes.Buffer
+	assert.NoError(t, src.ExportEnvironments(&buf, nil, "pw"))
+	lines := strings.Split(buf.String(), "\n")
+
+	dest, _ := Load(dir)
+	for _, data := range []string{lines[0] + "\n" + lines[1][:28], lines[0] + "\n", `{"version": 1}`} {
+		_, err := dest.ImportEnvironments(strings.NewReader(data), "pw", false)
+		assert.Error(t, err)
+	}
+}
+
+// Asserts every field of struct {v} other than {skip} has a value
+func assertFieldsSet(t *testing.T, v interface{}, skip ...string) {
+	rv := reflect.ValueOf(v)
+	for i := 0; i < rv.NumField(); i++ {
+		name := rv.Type().Field(i).Name
+		if !contains(skip, name) {
+			assert.False(t, rv.Field(i).IsZero(), "%s.%s isn't set", rv.Type().Name(), name)
+		}
+	}
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package cliconfig
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FormatPrefix marks a --format value naming a format defined within the config (eg. @images)
+const FormatPrefix = "@"
+
+var (
+	ErrFormatNotFound = errors.New("Specified output format was not found")
+	ErrFormatEmpty    = errors.New("An output format requires a template")
+)
+
+// Adds or replaces the output format {name} rendering {template}
+func (configFile *ConfigFile) AddFormat(name, template string) error {
+	if strings.TrimSpace(template) == "" {
+		return ErrFormatEmpty
+	}
+	if configFile.Formats == nil {
+		configFile.Formats = make(map[string]string)
+	}
+	configFile.Formats[name] = template
+	return configFile.Save()
+}
+
+// Removes the output format {name}
+func (configFile *ConfigFile) RemoveFormat(name string) error {
+	if _, exists := configFile.Formats[name]; !exists {
+		return ErrFormatNotFound
+	}
+	delete(configFile.Formats, name)
+	return configFile.Save()
+}
+
+// Returns the sorted names of all output formats
+func (configFile *ConfigFile) GetFormats() []string {
+	keys := make([]string, 0, len(configFile.Formats))
+	for k := range configFile.Formats {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ResolveFormat returns the template of the --format value {format}: the format defined within the config
+// when it names one (eg. @images) otherwise {format} itself
+func (configFile *ConfigFile) ResolveFormat(format string) (string, error) {
+	if !strings.HasPrefix(format, FormatPrefix) {
+		return format, nil
+	}
+	name := strings.TrimPrefix(format, FormatPrefix)
+	if configFile != nil {
+		if template, exists := configFile.Formats[name]; exists {
+			return template, nil
+		}
+	}
+	if configFile == nil || len(configFile.Formats) == 0 {
+		return "", fmt.Errorf("Output format '%s' was not found - no formats are defined, add one with 'depcon config format add %s [template]'", name, name)
+	}
+	return "", fmt.Errorf("Output format '%s' was not found - must be one of %s", name, strings.Join(configFile.GetFormats(), ", "))
+}
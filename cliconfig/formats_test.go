@@ -0,0 +1,40 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormats(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon")
+	defer os.RemoveAll(dir)
+
+	configFile, _ := Load(dir)
+	_, err := configFile.ResolveFormat("@images")
+	assert.EqualError(t, err, "Output format 'images' was not found - no formats are defined, add one with 'depcon config format add images [template]'")
+
+	assert.Equal(t, ErrFormatEmpty, configFile.AddFormat("images", " "))
+	assert.NoError(t, configFile.AddFormat("images", "{{range .Apps}}{{ .ID }}{{end}}"))
+	assert.NoError(t, configFile.AddFormat("ids", "{{ .ID }}"))
+
+	reloaded, _ := Load(dir)
+	assert.Equal(t, []string{"ids", "images"}, reloaded.GetFormats())
+	template, err := reloaded.ResolveFormat("@images")
+	assert.NoError(t, err)
+	assert.Equal(t, "{{range .Apps}}{{ .ID }}{{end}}", template)
+
+	template, err = reloaded.ResolveFormat("{{ .Version }}")
+	assert.NoError(t, err)
+	assert.Equal(t, "{{ .Version }}", template, "templates are used as is")
+
+	_, err = reloaded.ResolveFormat("@tags")
+	assert.EqualError(t, err, "Output format 'tags' was not found - must be one of ids, images")
+
+	assert.Equal(t, ErrFormatNotFound, reloaded.RemoveFormat("tags"))
+	assert.NoError(t, reloaded.RemoveFormat("ids"))
+	reloaded, _ = Load(dir)
+	assert.Equal(t, []string{"images"}, reloaded.GetFormats())
+}
@@ -0,0 +1,79 @@
+package cliconfig
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrGroupNotFound = errors.New("Specified environment group was not found")
+	ErrGroupNameUsed = errors.New("An environment with the same name already exists")
+	ErrGroupEmpty    = errors.New("An environment group requires at least one environment")
+)
+
+// Adds or replaces the environment group {name} containing {members}.  Every member must be an existing
+// environment (groups cannot be nested)
+func (configFile *ConfigFile) AddGroup(name string, members []string) error {
+	if _, exists := configFile.Environments[name]; exists {
+		return ErrGroupNameUsed
+	}
+	if len(members) == 0 {
+		return ErrGroupEmpty
+	}
+	for _, m := range members {
+		if _, exists := configFile.Environments[m]; !exists {
+			return fmt.Errorf("%s: '%s'", ErrEnvNotFound.Error(), m)
+		}
+	}
+	if configFile.Groups == nil {
+		configFile.Groups = make(map[string][]string)
+	}
+	configFile.Groups[name] = members
+	return configFile.Save()
+}
+
+// Removes the environment group {name}.  The member environments are not affected
+func (configFile *ConfigFile) RemoveGroup(name string) error {
+	if _, exists := configFile.Groups[name]; !exists {
+		return ErrGroupNotFound
+	}
+	delete(configFile.Groups, name)
+	return configFile.Save()
+}
+
+// Returns the members of the environment group {name} and true if {name} is a group
+func (configFile *ConfigFile) GetGroup(name string) ([]string, bool) {
+	members, found := configFile.Groups[name]
+	return members, found
+}
+
+// Returns the sorted names of all environment groups
+func (configFile *ConfigFile) GetGroups() []string {
+	keys := make([]string, 0, len(configFile.Groups))
+	for k := range configFile.Groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Updates group membership when an environment is renamed or removed (empty {newName})
+func (configFile *ConfigFile) updateGroupMembers(oldName, newName string) {
+	for group, members := range configFile.Groups {
+		updated := make([]string, 0, len(members))
+		for _, m := range members {
+			switch {
+			case m != oldName:
+				updated = append(updated, m)
+			case newName != "":
+				updated = append(updated, newName)
+			}
+		}
+		if len(updated) == 0 {
+			delete(configFile.Groups, group)
+			continue
+		}
+		configFile.Groups[group] = updated
+	}
+}
@@ -0,0 +1,33 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupMembership(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon")
+	defer os.RemoveAll(dir)
+
+	configFile, _ := Load(dir)
+	configFile.AddMarathonEnvironment("prod-us", "http://prod-us:8080", "", "")
+	configFile.AddMarathonEnvironment("prod-eu", "http://prod-eu:8080", "", "")
+
+	assert.Equal(t, ErrGroupNameUsed, configFile.AddGroup("prod-us", []string{"prod-eu"}))
+	assert.Error(t, configFile.AddGroup("prod", []string{"prod-us", "prod-ap"}))
+	assert.NoError(t, configFile.AddGroup("prod", []string{"prod-us", "prod-eu"}))
+
+	configFile.RenameEnvironment("prod-eu", "prod-emea")
+	reloaded, _ := Load(dir)
+	members, isGroup := reloaded.GetGroup("prod")
+	assert.True(t, isGroup)
+	assert.Equal(t, []string{"prod-us", "prod-emea"}, members)
+
+	reloaded.RemoveEnvironment("prod-us", true)
+	reloaded.RemoveEnvironment("prod-emea", true)
+	_, isGroup = reloaded.GetGroup("prod")
+	assert.False(t, isGroup)
+}
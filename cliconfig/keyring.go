@@ -0,0 +1,89 @@
+package cliconfig
+
+import (
+	"os"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// Service name passwords are stored under within the OS keyring
+	KeyringService = "depcon"
+	// Disables keyring storage when set (eg. headless CI hosts without a keyring)
+	EnvNoKeyring = "DEPCON_NO_KEYRING"
+)
+
+var keyringEnabled = os.Getenv(EnvNoKeyring) == ""
+
+// DisableKeyring stores passwords within the configuration file rather than the OS keyring
+func DisableKeyring() {
+	keyringEnabled = false
+}
+
+// KeyringEnabled returns true if passwords are stored in the OS keyring (macOS Keychain,
+// Windows Credential Manager or libsecret)
+func KeyringEnabled() bool {
+	return keyringEnabled
+}
+
+// Moves every password currently stored within the configuration file into the OS keyring.
+// Returns the names of the environments migrated
+func (configFile *ConfigFile) MigrateToKeyring() ([]string, error) {
+	migrated := []string{}
+	for name, configEnv := range configFile.Environments {
+		if m := configEnv.Marathon; m != nil && !m.Keyring && m.Password != "" {
+			if err := keyring.Set(KeyringService, name, m.Password); err != nil {
+				return nil, err
+			}
+			m.Keyring = true
+			migrated = append(migrated, name)
+		}
+	}
+	return migrated, configFile.Save()
+}
+
+// Moves every password stored within the OS keyring back into the configuration file and removes
+// the keyring entries.  Keyring storage is disabled for the remainder of the process.  Returns the
+// names of the environments migrated
+func (configFile *ConfigFile) MigrateFromKeyring() ([]string, error) {
+	migrated := []string{}
+	for name, configEnv := range configFile.Environments {
+		if m := configEnv.Marathon; m != nil && m.Keyring {
+			if err := keyring.Delete(KeyringService, name); err != nil && err != keyring.ErrNotFound {
+				return nil, err
+			}
+			m.Keyring = false
+			migrated = append(migrated, name)
+		}
+	}
+	DisableKeyring()
+	return migrated, configFile.Save()
+}
+
+// Resolves the password for an environment which stores it within the keyring.  If the keyring
+// is disabled or the entry is missing an empty password is returned
+func keyringPassword(name string) string {
+	if !keyringEnabled {
+		return ""
+	}
+	password, err := keyring.Get(KeyringService, name)
+	if err != nil {
+		return ""
+	}
+	return password
+}
+
+// Stores the password for an environment in the keyring.  Returns false if the keyring is disabled or
+// unavailable in which case the caller should fall back to the configuration file
+func storeKeyringPassword(name, password string) bool {
+	if !keyringEnabled {
+		return false
+	}
+	return keyring.Set(KeyringService, name, password) == nil
+}
+
+func deleteKeyringPassword(name string) {
+	if keyringEnabled {
+		keyring.Delete(KeyringService, name)
+	}
+}
@@ -0,0 +1,48 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zalando/go-keyring"
+)
+
+func TestMain(m *testing.M) {
+	// never touch the real OS keyring from tests
+	keyring.MockInit()
+	os.Exit(m.Run())
+}
+
+func TestKeyringStorageAndMigration(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon")
+	defer os.RemoveAll(dir)
+
+	configFile, _ := Load(dir)
+	configFile.AddMarathonEnvironment("prod", "http://prod:8080", "admin", "secret")
+
+	data, _ := ioutil.ReadFile(configFile.Filename())
+	assert.False(t, strings.Contains(string(data), EncodePassword(&ServiceConfig{Username: "admin", Password: "secret"})))
+	assert.Contains(t, string(data), `"keyring": true`)
+
+	stored, err := keyring.Get(KeyringService, "prod")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", stored)
+
+	reloaded, _ := Load(dir)
+	assert.Equal(t, "secret", reloaded.Environments["prod"].Marathon.Password)
+
+	migrated, err := reloaded.MigrateFromKeyring()
+	defer func() { keyringEnabled = true }()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod"}, migrated)
+
+	_, err = keyring.Get(KeyringService, "prod")
+	assert.Equal(t, keyring.ErrNotFound, err)
+
+	reloaded, _ = Load(dir)
+	assert.False(t, reloaded.Environments["prod"].Marathon.Keyring)
+	assert.Equal(t, "secret", reloaded.Environments["prod"].Marathon.Password)
+}
@@ -0,0 +1,188 @@
+package cliconfig
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/userdir"
+)
+
+const (
+	ImportFromDCOS        = "dcos"
+	ImportFromMarathonctl = "marathonctl"
+)
+
+var invalidEnvChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ExternalEnvironment is an environment read from another tool's configuration
+type ExternalEnvironment struct {
+	Name    string
+	Service *ServiceConfig
+	// DC/OS ACS token found in the configuration which is seeded into the token cache
+	Token string
+}
+
+// Subset of the dcos CLI configuration (dcos.toml)
+type dcosConfig struct {
+	Core struct {
+		URL       string `json:"dcos_url"`
+		Token     string `json:"dcos_acs_token"`
+		Username  string `json:"dcos_username"`
+		SSLVerify string `json:"ssl_verify"`
+	} `json:"core"`
+	Cluster struct {
+		Name string `json:"name"`
+	} `json:"cluster"`
+	Marathon struct {
+		URL string `json:"url"`
+	} `json:"marathon"`
+}
+
+// DefaultDCOSDir returns the dcos CLI configuration directory (DCOS_DIR or ~/.dcos)
+func DefaultDCOSDir() string {
+	if dir := os.Getenv("DCOS_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(userdir.Get(), ".dcos")
+}
+
+// DefaultMarathonctlConfig returns the marathonctl configuration file location
+func DefaultMarathonctlConfig() string {
+	return filepath.Join(userdir.Get(), ".config", "marathonctl", "config")
+}
+
+// ReadDCOSConfig reads every cluster configured within the dcos CLI directory {dir}.  Both the single
+// cluster layout (dcos.toml) and the multi-cluster layout (clusters/<id>/dcos.toml) are supported
+func ReadDCOSConfig(dir string) ([]*ExternalEnvironment, error) {
+	files, _ := filepath.Glob(filepath.Join(dir, "clusters", "*", "dcos.toml"))
+	if _, err := os.Stat(filepath.Join(dir, "dcos.toml")); err == nil {
+		files = append(files, filepath.Join(dir, "dcos.toml"))
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("No dcos CLI configuration was found within %s", dir)
+	}
+
+	envs := []*ExternalEnvironment{}
+	for _, file := range files {
+		config := &dcosConfig{}
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		err = encoding.DefaultTOMLEncoder().UnMarshal(f, config)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err.Error())
+		}
+
+		service := &ServiceConfig{Username: config.Core.Username}
+		switch {
+		case config.Core.URL != "":
+			service.HostUrl = config.Core.URL
+			service.Auth = AuthDCOS
+		case config.Marathon.URL != "":
+			service.HostUrl = config.Marathon.URL
+		default:
+			continue
+		}
+
+		// ssl_verify is either true/false or the path to a CA bundle
+		if v := config.Core.SSLVerify; v != "" && v != "true" && v != "false" {
+			service.TLS = &httpclient.TLSConfig{CAFile: v}
+		}
+
+		name := config.Cluster.Name
+		if name == "" {
+			name = hostName(service.HostUrl)
+		}
+		envs = append(envs, &ExternalEnvironment{Name: envName(name), Service: service, Token: config.Core.Token})
+	}
+	return envs, nil
+}
+
+// ReadMarathonctlConfig reads the marathonctl configuration file {filename} which contains
+// marathon.host, marathon.user and marathon.password properties
+func ReadMarathonctlConfig(filename string) ([]*ExternalEnvironment, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexAny(line, ":="); i > 0 {
+			props[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// multiple hosts may be specified separated by commas
+	host := strings.TrimSpace(strings.Split(props["marathon.host"], ",")[0])
+	if host == "" {
+		return nil, fmt.Errorf("marathon.host is not defined within %s", filename)
+	}
+	service := &ServiceConfig{HostUrl: host, Username: props["marathon.user"], Password: props["marathon.password"]}
+	return []*ExternalEnvironment{{Name: envName(hostName(host)), Service: service}}, nil
+}
+
+// Adds environments read from another tool.  Environments which already exist are skipped unless
+// {overwrite} is true.  Returns the names of the added environments
+func (configFile *ConfigFile) AddExternalEnvironments(envs []*ExternalEnvironment, overwrite bool) ([]string, error) {
+	added := []string{}
+	for _, env := range envs {
+		if _, exists := configFile.Environments[env.Name]; exists && !overwrite {
+			continue
+		}
+		env.Service.Name = env.Name
+		configFile.Environments[env.Name] = &ConfigEnvironment{Marathon: env.Service}
+		if env.Token != "" {
+			if err := (TokenStore{}).Store(env.Name, env.Token); err != nil {
+				return nil, err
+			}
+		}
+		added = append(added, env.Name)
+	}
+
+	if len(added) > 0 {
+		if configFile.DefaultEnv == "" && len(configFile.Environments) == len(added) {
+			configFile.DefaultEnv = added[0]
+		}
+		if err := configFile.Save(); err != nil {
+			return nil, err
+		}
+	}
+	return added, nil
+}
+
+// Returns the first label of the host within {rawurl} (eg. marathon for http://marathon.example.com:8080)
+func hostName(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Hostname() == "" {
+		return "imported"
+	}
+	return strings.Split(u.Hostname(), ".")[0]
+}
+
+// Converts {name} into a valid environment name
+func envName(name string) string {
+	name = strings.Trim(invalidEnvChars.ReplaceAllString(name, "-"), "-")
+	if name == "" {
+		return "imported"
+	}
+	return name
+}
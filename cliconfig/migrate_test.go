@@ -0,0 +1,58 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportFromDCOS(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon-dcos")
+	defer os.RemoveAll(dir)
+	defer SetConfigDir(ConfigDir())
+	SetConfigDir(dir)
+
+	clusterDir := filepath.Join(dir, ".dcos", "clusters", "7c2d4b1a")
+	os.MkdirAll(clusterDir, 0700)
+	ioutil.WriteFile(filepath.Join(clusterDir, "dcos.toml"), []byte(`
+[core]
+dcos_url = "https://dcos.example.com"
+dcos_acs_token = "cached-token"
+dcos_username = "ops"
+ssl_verify = "/etc/ssl/dcos-ca.pem"
+
+[cluster]
+name = "prod west"
+`), 0600)
+
+	envs, err := ReadDCOSConfig(filepath.Join(dir, ".dcos"))
+	assert.NoError(t, err)
+	assert.Len(t, envs, 1)
+	assert.Equal(t, "prod-west", envs[0].Name)
+	assert.Equal(t, AuthDCOS, envs[0].Service.Auth)
+	assert.Equal(t, "/etc/ssl/dcos-ca.pem", envs[0].Service.TLS.CAFile)
+
+	configFile, _ := Load(dir)
+	added, err := configFile.AddExternalEnvironments(envs, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod-west"}, added)
+	assert.Equal(t, "https://dcos.example.com", configFile.Environments["prod-west"].Marathon.HostUrl)
+	assert.Equal(t, "cached-token", TokenStore{}.Load("prod-west"))
+}
+
+func TestImportFromMarathonctl(t *testing.T) {
+	f, _ := ioutil.TempFile("", "marathonctl")
+	defer os.Remove(f.Name())
+	f.WriteString("# marathonctl\nmarathon.host: http://marathon.example.com:8080,http://backup:8080\nmarathon.user: admin\nmarathon.password: secret\n")
+	f.Close()
+
+	envs, err := ReadMarathonctlConfig(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "marathon", envs[0].Name)
+	assert.Equal(t, "http://marathon.example.com:8080", envs[0].Service.HostUrl)
+	assert.Equal(t, "admin", envs[0].Service.Username)
+	assert.Equal(t, "secret", envs[0].Service.Password)
+}
@@ -0,0 +1,82 @@
+package cliconfig
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+)
+
+// Project configuration file discovered by walking up from the working directory
+const ProjectFileName = ".depcon.yaml"
+
+// ProjectConfig holds per-repository settings which override the global configuration
+type ProjectConfig struct {
+	// Default environment (or environment group) when -e is not specified
+	Environment string `json:"environment,omitempty"`
+	// Template context file used by create and deploy commands
+	TemplateContext string `json:"tempctx,omitempty"`
+	// Files looked for (in order) when no template context is given where {env} is replaced by the selected
+	// environment (eg. contexts/{env}.yaml).  Default: the built-in conventions.  An empty list disables them
+	TemplateContextPatterns []string `json:"tempctxPatterns,omitempty"`
+	// Param files used for substitution.  Later files override values from earlier files
+	ParamFiles []string `json:"params,omitempty"`
+	// Default flag values applied unless specified on the command line
+	Flags    map[string]string `json:"flags,omitempty"`
+	filename string
+}
+
+// FindProjectConfig walks up from {dir} looking for a ProjectFileName.  Returns nil if one was not found.
+// Relative paths within the project file are resolved against the directory containing it
+func FindProjectConfig(dir string) (*ProjectConfig, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		filename := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(filename); err == nil {
+			return loadProjectConfig(filename)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Filename returns the location of the project file
+func (project *ProjectConfig) Filename() string {
+	return project.filename
+}
+
+func loadProjectConfig(filename string) (*ProjectConfig, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	project := &ProjectConfig{filename: filename}
+	if err := encoding.DefaultYAMLEncoder().UnMarshal(f, project); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(filename)
+	project.TemplateContext = resolvePath(dir, project.TemplateContext)
+	for i, p := range project.ParamFiles {
+		project.ParamFiles[i] = resolvePath(dir, p)
+	}
+	for i, p := range project.TemplateContextPatterns {
+		project.TemplateContextPatterns[i] = resolvePath(dir, p)
+	}
+	return project, nil
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
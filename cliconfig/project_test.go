@@ -0,0 +1,42 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindProjectConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon-project")
+	defer os.RemoveAll(dir)
+
+	nested := filepath.Join(dir, "services", "api")
+	os.MkdirAll(nested, 0755)
+
+	project, err := FindProjectConfig(nested)
+	assert.NoError(t, err)
+	assert.Nil(t, project)
+
+	ioutil.WriteFile(filepath.Join(dir, ProjectFileName), []byte(`
+environment: prod
+tempctx: deploy/context.json
+tempctxPatterns:
+  - deploy/{env}.yaml
+params:
+  - deploy/common.env
+  - /etc/depcon/secrets.env
+flags:
+  wait: "true"
+`), 0644)
+
+	project, err = FindProjectConfig(nested)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", project.Environment)
+	assert.Equal(t, filepath.Join(dir, "deploy/context.json"), project.TemplateContext)
+	assert.Equal(t, []string{filepath.Join(dir, "deploy/common.env"), "/etc/depcon/secrets.env"}, project.ParamFiles)
+	assert.Equal(t, "true", project.Flags["wait"])
+	assert.Equal(t, []string{filepath.Join(dir, "deploy/{env}.yaml")}, project.TemplateContextPatterns)
+}
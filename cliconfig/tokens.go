@@ -0,0 +1,74 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	tokensDir = "tokens"
+	// suffix of the files recording when tokens expire.  Expiries aren't secret so they're always kept in
+	// the config directory
+	expirySuffix = ".expiry"
+)
+
+// TokenStore caches authentication tokens (eg. DC/OS) by environment name.  Tokens are kept in the OS
+// keyring when enabled otherwise within the config directory (mode 0600)
+type TokenStore struct{}
+
+func (t TokenStore) Load(key string) string {
+	if keyringEnabled {
+		if token, err := keyring.Get(KeyringService, tokenKey(key)); err == nil {
+			return token
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(ConfigDir(), tokensDir, key))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+func (t TokenStore) Store(key, token string) error {
+	// the expiry of a previous token no longer applies
+	os.Remove(filepath.Join(ConfigDir(), tokensDir, key+expirySuffix))
+	if keyringEnabled && keyring.Set(KeyringService, tokenKey(key), token) == nil {
+		return nil
+	}
+	dir := filepath.Join(ConfigDir(), tokensDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, key), []byte(token), 0600)
+}
+
+// LoadExpiry returns the time the token cached for {key} expires or the zero time when unknown
+func (t TokenStore) LoadExpiry(key string) time.Time {
+	b, err := ioutil.ReadFile(filepath.Join(ConfigDir(), tokensDir, key+expirySuffix))
+	if err != nil {
+		return time.Time{}
+	}
+	expires, err := time.Parse(time.RFC3339, strings.TrimSpace(string(b)))
+	if err != nil {
+		return time.Time{}
+	}
+	return expires
+}
+
+// StoreExpiry records that the token cached for {key} expires at {expires}
+func (t TokenStore) StoreExpiry(key string, expires time.Time) error {
+	dir := filepath.Join(ConfigDir(), tokensDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, key+expirySuffix), []byte(expires.UTC().Format(time.RFC3339)), 0600)
+}
+
+func tokenKey(key string) string {
+	return "token:" + key
+}
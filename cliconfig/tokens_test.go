@@ -0,0 +1,29 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenExpiry(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon-tokens")
+	defer os.RemoveAll(dir)
+	defer SetConfigDir(ConfigDir())
+	SetConfigDir(dir)
+
+	store := TokenStore{}
+	assert.True(t, store.LoadExpiry("prod").IsZero())
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	assert.Nil(t, store.Store("prod", "token"))
+	assert.Nil(t, store.StoreExpiry("prod", expires))
+	assert.True(t, expires.Equal(store.LoadExpiry("prod")))
+
+	// a new token discards the expiry of the previous one
+	assert.Nil(t, store.Store("prod", "other"))
+	assert.True(t, store.LoadExpiry("prod").IsZero())
+}
@@ -0,0 +1,546 @@
+package cliconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+
+	// Time allowed to connect to each environment when checking hosts
+	hostCheckTimeout = 3 * time.Second
+)
+
+// Valid HTTP header field name (RFC 7230 token)
+var headerName = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// ValidHeaderName returns true if {name} is a valid HTTP header name
+func ValidHeaderName(name string) bool {
+	return headerName.MatchString(name)
+}
+
+// Keys which are no longer used and the action the user should take
+var deprecatedKeys = map[string]string{
+	"email": "no longer used and can be removed",
+}
+
+// ConfigIssue is a problem found within the configuration file
+type ConfigIssue struct {
+	Level   string
+	Path    string
+	Message string
+}
+
+// ValidateFile checks the configuration file {filename} for syntax errors, unknown, duplicate and deprecated
+// keys and invalid settings.  If {checkHosts} is true each environment's host is connected to.  An error is
+// only returned if the file could not be read
+func ValidateFile(filename string, checkHosts bool) ([]*ConfigIssue, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		if serr, ok := err.(*json.SyntaxError); ok {
+			return []*ConfigIssue{{IssueError, position(data, serr.Offset), serr.Error()}}, nil
+		}
+		return []*ConfigIssue{{IssueError, "", err.Error()}}, nil
+	}
+
+	issues := findDuplicateKeys(data)
+	checkKeys(raw, reflect.TypeOf(ConfigFile{}), "", &issues)
+
+	configFile := &ConfigFile{}
+	if err := json.Unmarshal(data, configFile); err != nil {
+		if terr, ok := err.(*json.UnmarshalTypeError); ok {
+			issues = append(issues, &ConfigIssue{IssueError, terr.Field, fmt.Sprintf("expected %s but found %s", terr.Type, terr.Value)})
+		} else {
+			issues = append(issues, &ConfigIssue{IssueError, "", err.Error()})
+		}
+	} else {
+		issues = append(issues, configFile.validateSettings()...)
+		if checkHosts {
+			issues = append(issues, configFile.checkHosts()...)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Path < issues[j].Path })
+	return issues, nil
+}
+
+// HasErrors returns true if any of the {issues} is an error rather than a warning
+func HasErrors(issues []*ConfigIssue) bool {
+	for _, issue := range issues {
+		if issue.Level == IssueError {
+			return true
+		}
+	}
+	return false
+}
+
+func (configFile *ConfigFile) validateSettings() []*ConfigIssue {
+	issues := []*ConfigIssue{}
+	add := func(level, path, format string, args ...interface{}) {
+		issues = append(issues, &ConfigIssue{level, path, fmt.Sprintf(format, args...)})
+	}
+
+	if configFile.Format != "" && !cli.IsValidFormat(configFile.Format) {
+		add(IssueError, "format", "'%s' is not a valid output - must be one of %s", configFile.Format, strings.Join(cli.ValidFormats, ", "))
+	}
+	if configFile.Color != "" && !cli.IsValidColorMode(configFile.Color) {
+		add(IssueError, "color", "'%s' is not a valid color mode - must be one of %s", configFile.Color, strings.Join(cli.ColorModes, ", "))
+	}
+	if _, err := cli.LookupTheme(configFile.Theme); err != nil {
+		add(IssueError, "theme", "%s", err.Error())
+	}
+	for role, code := range configFile.Colors {
+		if !cli.IsValidRole(role) {
+			add(IssueWarning, "colors."+role, "unknown role - must be one of %s", strings.Join(cli.Roles, ", "))
+		} else if !cli.IsValidSGR(code) {
+			add(IssueError, "colors."+role, "'%s' is not a valid color - expected ANSI SGR parameters such as 1;32", code)
+		}
+	}
+
+	if configFile.DefaultEnv != "" {
+		_, isEnv := configFile.Environments[configFile.DefaultEnv]
+		_, isGroup := configFile.Groups[configFile.DefaultEnv]
+		if !isEnv && !isGroup {
+			add(IssueError, "default", "environment '%s' does not exist - run 'depcon config env default [name]'", configFile.DefaultEnv)
+		}
+	}
+
+	if configFile.Access != nil {
+		if err := configFile.Access.Validate(); err != nil {
+			add(IssueError, "access", "%s", err.Error())
+		}
+	}
+
+	for name, template := range configFile.Formats {
+		if !RegExAlphaNumDash.MatchString(name) {
+			add(IssueError, "formats."+name, "format names may only contain %s", AlphaNumDash)
+		}
+		if strings.TrimSpace(template) == "" {
+			add(IssueError, "formats."+name, "%s", ErrFormatEmpty.Error())
+		}
+	}
+
+	for name, configEnv := range configFile.Environments {
+		path := "environments." + name
+		if !RegExAlphaNumDash.MatchString(name) {
+			add(IssueError, path, "environment names may only contain %s", AlphaNumDash)
+		}
+		if configEnv != nil {
+			for provider, c := range configEnv.Secrets {
+				if c == nil {
+					continue
+				}
+				if err := c.Validate(provider); err != nil {
+					add(IssueError, path+".secrets."+provider+".type", "%s", err.Error())
+				}
+			}
+			for i, w := range configEnv.Freeze {
+				if w == nil {
+					continue
+				}
+				if err := w.Validate(); err != nil {
+					add(IssueError, fmt.Sprintf("%s.freeze[%d]", path, i), "%s", err.Error())
+				}
+			}
+			if w := configEnv.ChangeWindows; w != nil {
+				if err := w.Validate(); err != nil {
+					add(IssueError, path+".changeWindows", "%s", err.Error())
+				}
+			}
+			for i, r := range configEnv.Registries {
+				if r == nil {
+					continue
+				}
+				if err := r.Validate(); err != nil {
+					add(IssueError, fmt.Sprintf("%s.registries[%d]", path, i), "%s", err.Error())
+				}
+			}
+			for i, r := range configEnv.Remotes {
+				if r == nil {
+					continue
+				}
+				if err := r.Validate(); err != nil {
+					add(IssueError, fmt.Sprintf("%s.remotes[%d]", path, i), "%s", err.Error())
+				}
+			}
+		}
+		if configEnv != nil && configEnv.ECS != nil {
+			if configEnv.ECS.Cluster == "" {
+				add(IssueError, path+".ecs.cluster", "the ECS cluster must be specified")
+			}
+			if configEnv.ECS.Endpoint != "" {
+				if u, err := url.Parse(configEnv.ECS.Endpoint); err != nil || u.Host == "" {
+					add(IssueError, path+".ecs.endpoint", "'%s' must be a valid URL", configEnv.ECS.Endpoint)
+				}
+			}
+			if configEnv.Marathon == nil {
+				continue
+			}
+		}
+		if configEnv != nil && configEnv.Nomad != nil {
+			if configEnv.Nomad.Address != "" {
+				if u, err := url.Parse(configEnv.Nomad.Address); err != nil || u.Host == "" {
+					add(IssueError, path+".nomad.address", "'%s' must be a valid URL (eg. http://host:4646)", configEnv.Nomad.Address)
+				}
+			}
+			if configEnv.Marathon == nil {
+				continue
+			}
+		}
+		if configEnv != nil && configEnv.Swarm != nil {
+			if configEnv.Swarm.Host != "" {
+				if u, err := url.Parse(configEnv.Swarm.Host); err != nil || u.Scheme == "" {
+					add(IssueError, path+".swarm.host", "'%s' must be a valid URL (eg. tcp://host:2376)", configEnv.Swarm.Host)
+				}
+			}
+			if configEnv.Marathon == nil {
+				continue
+			}
+		}
+		if configEnv == nil || configEnv.Marathon == nil {
+			add(IssueError, path, "no marathon service, ecs cluster, nomad cluster or swarm is defined")
+			continue
+		}
+
+		m := configEnv.Marathon
+		path += ".marathon"
+		if err := ValidateMarathonURL(m.HostUrl); err != nil {
+			add(IssueError, path+".serveraddress", "'%s' must be a valid URL (eg. http://host:8080)", m.HostUrl)
+		}
+		if m.Auth != "" && !ValidAuth(m.Auth) {
+			add(IssueError, path+".auth", "'%s' is not valid - must be one of %s", m.Auth, strings.Join(AuthModes, ", "))
+		}
+		if m.UsesLogin() {
+			if err := m.Login.Validate(m.Auth); err != nil {
+				add(IssueError, path+".login", "%s", err.Error())
+			}
+		}
+		if m.ServiceAccount != "" {
+			if !m.IsDCOS() {
+				add(IssueWarning, path+".serviceaccount", "ignored unless auth is '%s'", AuthDCOS)
+			}
+			checkFileExists(m.ServiceAccount, path+".serviceaccount", add)
+		}
+		if m.Keyring && !KeyringEnabled() {
+			add(IssueWarning, path+".keyring", "the password is stored in the OS keyring but the keyring is disabled")
+		}
+		if p := m.Proxy; p != nil {
+			for key, proxy := range map[string]string{"http": p.HTTP, "https": p.HTTPS} {
+				if proxy != "" && httpclient.ValidateProxyURL(proxy) != nil {
+					add(IssueError, path+".proxy."+key, "'%s' must be an http, https or socks5 URL", proxy)
+				}
+			}
+		}
+		if t := m.TLS; t != nil {
+			if (t.CertFile == "") != (t.KeyFile == "") {
+				add(IssueError, path+".tls", "both cert and key must be specified")
+			}
+			for key, file := range map[string]string{"cert": t.CertFile, "key": t.KeyFile, "ca": t.CAFile} {
+				if file != "" {
+					checkFileExists(file, path+".tls."+key, add)
+				}
+			}
+			if t.InsecureSkipVerify {
+				add(IssueWarning, path+".tls.insecure_skip_verify", "certificate verification is disabled - prefer the cluster's CA bundle (ca)")
+			}
+		}
+		for name := range m.Headers {
+			if !ValidHeaderName(name) {
+				add(IssueError, path+".headers", "'%s' is not a valid header name", name)
+			}
+		}
+		for key, service := range map[string]string{"metronome": m.MetronomeUrl, "chronos": m.ChronosUrl, "mesos": m.MesosUrl, "marathon_lb": m.MarathonLBUrl} {
+			if service == "" {
+				continue
+			}
+			if u, err := url.Parse(service); err != nil || u.Host == "" {
+				add(IssueError, path+"."+key, "'%s' must be a valid URL (eg. http://host:4400)", service)
+			}
+		}
+		if m.RateLimit < 0 {
+			add(IssueError, path+".ratelimit", "must not be negative - 0 is unlimited")
+		}
+		if t := m.Timeouts; t != nil {
+			for key, d := range map[string]httpclient.Duration{"connect": t.Connect, "tlshandshake": t.TLSHandshake, "responseheader": t.ResponseHeader} {
+				if d < 0 {
+					add(IssueError, path+".timeouts."+key, "must not be negative - only the request timeout may be disabled")
+				}
+			}
+		}
+	}
+
+	for group, members := range configFile.Groups {
+		path := "groups." + group
+		if _, exists := configFile.Environments[group]; exists {
+			add(IssueError, path, "an environment with the same name exists - the environment is always used")
+		}
+		if len(members) == 0 {
+			add(IssueError, path, "the group has no environments")
+		}
+		for _, m := range members {
+			if _, exists := configFile.Environments[m]; !exists {
+				add(IssueError, path, "environment '%s' does not exist", m)
+			}
+		}
+	}
+	return issues
+}
+
+func checkFileExists(filename, path string, add func(level, path, format string, args ...interface{})) {
+	if _, err := os.Stat(filename); err != nil {
+		add(IssueError, path, "'%s' could not be read: %s", filename, err.Error())
+	}
+}
+
+// Connects to each environment's host (or proxy when one is defined) concurrently
+func (configFile *ConfigFile) checkHosts() []*ConfigIssue {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		issues = []*ConfigIssue{}
+	)
+	for name, configEnv := range configFile.Environments {
+		if configEnv == nil || configEnv.Marathon == nil {
+			continue
+		}
+		target, via := strings.Split(configEnv.Marathon.HostUrl, ",")[0], "host"
+		if p := configEnv.Marathon.Proxy; !p.IsEmpty() {
+			target, via = p.HTTP, "proxy"
+			if target == "" {
+				target = p.HTTPS
+			}
+		}
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		wg.Add(1)
+		go func(path, via string, u *url.URL) {
+			defer wg.Done()
+			conn, err := net.DialTimeout("tcp", hostPort(u), hostCheckTimeout)
+			if err != nil {
+				mu.Lock()
+				issues = append(issues, &ConfigIssue{IssueWarning, path, fmt.Sprintf("%s %s is unreachable: %s", via, u.Host, err.Error())})
+				mu.Unlock()
+				return
+			}
+			conn.Close()
+		}("environments."+name, via, u)
+	}
+	wg.Wait()
+	return issues
+}
+
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	switch u.Scheme {
+	case "https":
+		return net.JoinHostPort(u.Hostname(), "443")
+	case "socks5":
+		return net.JoinHostPort(u.Hostname(), "1080")
+	case httpclient.SchemeSSHHTTP, httpclient.SchemeSSHHTTPS:
+		// the bastion
+		return net.JoinHostPort(u.Hostname(), "22")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// Walks the JSON tokens within {data} reporting keys which are defined more than once within the same
+// object (eg. two environments with the same name) since only the last definition is used
+func findDuplicateKeys(data []byte) []*ConfigIssue {
+	issues := []*ConfigIssue{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := t.(json.Delim)
+		if !ok {
+			return nil
+		}
+		switch delim {
+		case '{':
+			seen := make(map[string]bool)
+			for dec.More() {
+				t, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key, _ := t.(string)
+				keyPath := joinPath(path, key)
+				if seen[key] {
+					issues = append(issues, &ConfigIssue{IssueError, keyPath, fmt.Sprintf("'%s' is defined more than once - only the last definition is used", key)})
+				}
+				seen[key] = true
+				if err := walk(keyPath); err != nil {
+					return err
+				}
+			}
+		case '[':
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+		// closing delimiter
+		_, err = dec.Token()
+		return err
+	}
+	walk("")
+	return issues
+}
+
+// Compares the keys within the decoded JSON {v} against the json tags of {t} reporting unknown and
+// deprecated keys
+func checkKeys(v interface{}, t reflect.Type, path string, issues *[]*ConfigIssue) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key, value := range obj {
+			keyPath := joinPath(path, key)
+			if field, known := fields[key]; known {
+				checkKeys(value, field, keyPath, issues)
+				continue
+			}
+			if action, deprecated := deprecatedKeys[key]; deprecated {
+				*issues = append(*issues, &ConfigIssue{IssueWarning, keyPath, "deprecated - " + action})
+				continue
+			}
+			msg := "unknown key - it is ignored"
+			if suggestion := closestKey(key, fields); suggestion != "" {
+				msg = fmt.Sprintf("unknown key - did you mean '%s'?", suggestion)
+			}
+			*issues = append(*issues, &ConfigIssue{IssueWarning, keyPath, msg})
+		}
+	case reflect.Map:
+		if obj, ok := v.(map[string]interface{}); ok {
+			for key, value := range obj {
+				checkKeys(value, t.Elem(), joinPath(path, key), issues)
+			}
+		}
+	case reflect.Slice:
+		if arr, ok := v.([]interface{}); ok {
+			for i, value := range arr {
+				checkKeys(value, t.Elem(), fmt.Sprintf("%s[%d]", path, i), issues)
+			}
+		}
+	}
+}
+
+// Returns the serialized field names of struct {t} mapped to their types
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// Returns the known key closest to {key} when it is likely a typo
+func closestKey(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// Returns the line and column of the byte preceding {offset} within {data} (the offending character
+// of a json.SyntaxError)
+func position(data []byte, offset int64) string {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset > 0 {
+		offset--
+	}
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	col := offset - int64(bytes.LastIndexByte(data[:offset], '\n'))
+	return fmt.Sprintf("line %d, column %d", line, col)
+}
@@ -0,0 +1,83 @@
+package cliconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validateConfig(t *testing.T, config string) map[string]*ConfigIssue {
+	dir, _ := ioutil.TempDir("", "depcon-validate")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, ConfigFileName)
+	ioutil.WriteFile(filename, []byte(config), 0600)
+
+	issues, err := ValidateFile(filename, false)
+	assert.NoError(t, err)
+
+	byPath := make(map[string]*ConfigIssue)
+	for _, issue := range issues {
+		byPath[issue.Path] = issue
+	}
+	return byPath
+}
+
+func TestValidateFile(t *testing.T) {
+	issues := validateConfig(t, `{
+	"format": "column",
+	"default": "stage",
+	"environments": {
+		"prod": { "marathon": { "serveraddress": "http://prod:8080" } },
+		"prod": { "marathon": { "serveradress": "http://prod:8080", "email": "ops@example.com" } },
+		"qa": { "marathon": { "serveraddress": "qa:8080", "auth": "oauth" } },
+		"lab": { "marathon": { "serveraddress": "https://lab:8443", "tls": { "insecure_skip_verify": true } },
+			"remotes": [{ "url": "https://artifacts.example.com/" }, { "url": "artifacts.example.com" }] }
+	},
+	"groups": { "all": ["prod", "dev"] },
+	"formats": { "ids": "{{ .ID }}", "images": " " }
+}`)
+
+	assert.Equal(t, IssueError, issues["environments.prod"].Level)
+	assert.Contains(t, issues["environments.prod.marathon.serveradress"].Message, "did you mean 'serveraddress'")
+	assert.Equal(t, IssueWarning, issues["environments.prod.marathon.email"].Level)
+	assert.Equal(t, IssueError, issues["environments.qa.marathon.serveraddress"].Level)
+	assert.Equal(t, IssueError, issues["environments.qa.marathon.auth"].Level)
+	assert.Equal(t, IssueWarning, issues["environments.lab.marathon.tls.insecure_skip_verify"].Level)
+	assert.Nil(t, issues["environments.lab.marathon.tls"])
+	assert.Nil(t, issues["environments.lab.remotes[0]"])
+	assert.Equal(t, IssueError, issues["environments.lab.remotes[1]"].Level)
+	assert.Equal(t, IssueError, issues["default"].Level)
+	assert.Contains(t, issues["groups.all"].Message, "'dev' does not exist")
+	assert.Nil(t, issues["formats.ids"])
+	assert.Equal(t, ErrFormatEmpty.Error(), issues["formats.images"].Message)
+}
+
+func TestValidateFileSyntaxError(t *testing.T) {
+	issues := validateConfig(t, "{\n\t\"format\": \"column\",\n\t\"environments\": {,\n}")
+	assert.Len(t, issues, 1)
+	for path := range issues {
+		assert.Equal(t, "line 3, column 19", path)
+	}
+}
+
+func TestValidateFileClusterEnvironments(t *testing.T) {
+	issues := validateConfig(t, `{
+	"environments": {
+		"aws": { "ecs": { "cluster": "web", "region": "us-east-1" } },
+		"hashi": { "nomad": { "address": "http://nomad:4646" } },
+		"broken": { "nomad": { "address": "nomad" } },
+		"docker": { "swarm": { "host": "tcp://manager:2376" } },
+		"empty": {}
+	}
+}`)
+
+	assert.Nil(t, issues["environments.aws"])
+	assert.Nil(t, issues["environments.hashi"])
+	assert.Nil(t, issues["environments.docker"])
+	assert.Equal(t, IssueError, issues["environments.broken.nomad.address"].Level)
+	assert.Contains(t, issues["environments.empty"].Message, "no marathon service")
+}
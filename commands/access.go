@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/access"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// Refuses changes the roles of the current user don't permit.  Like freezes, changes are checked as they're
+// sent so commands touching several environments are checked against the environment each change is sent
+// to.  The server enforces its own policy against the users of its tokens rather than the user running it
+func configureAccess(cmd *cobra.Command) {
+	if configFile == nil || configFile.Access == nil || cmd == serverCmd {
+		return
+	}
+	policy := configFile.Access
+	current := viper.GetString(ViperEnv)
+	username := access.CurrentUser()
+	command := commandName(cmd)
+	httpclient.AddWriteGuard(func(method, rawurl string) error {
+		return policy.Check(username, environmentForURL(rawurl, current), command)
+	})
+}
+
+// Returns the path of {cmd} without the program name (eg. app scale).  Marathon's commands are named the
+// same whether or not they're beneath the marathon command
+func commandName(cmd *cobra.Command) string {
+	name := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	return strings.TrimPrefix(name, "marathon ")
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagFile  = "file"
+	FlagPrune = "prune"
+
+	ActionApply = "apply"
+
+	T_APPLY = `
+{{ "ID" | header }}	{{ "KIND" | header }}	{{ "ACTION" | header }}	{{ "RESULT" | header }}	{{ "FIELDS" | header }}	{{ "FILE" | header }}
+{{ range . }}{{ .ID }}	{{ .Kind }}	{{ .Action }}	{{ .Result }}	{{ len .Fields | intToString }}	{{ .File }}
+{{end}}`
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply -f manifest.yaml",
+	Short: "Creates and updates the apps and groups declared by a manifest, pruning those no longer declared",
+	Long: `Renders the descriptors listed by the manifest, labels their applications as managed by the manifest
+(DEPCON_MANIFEST=<name>) and creates or updates those which differ from the environment.
+
+With --prune the applications labeled with the manifest's name which it no longer declares are destroyed.
+Groups declared by the manifest are destroyed whole.
+
+    name: shop
+    tempctx: template-context.json    # optional, relative to the manifest
+    params:                           # optional, overridden by -p
+      TAG: "1.2"
+    resources:
+      - file: apps/web.json
+      - file: groups/backend.yaml
+        params:
+          TAG: "1.3"
+
+    eg. depcon apply -f manifest.yaml -e prod --dry-run
+        depcon apply -f manifest.yaml -e prod --prune -w`,
+	Run: applyManifest,
+}
+
+func init() {
+	applyCmd.Flags().StringP(FlagFile, "f", "", "Manifest (.yaml, .yml or .json) declaring the apps and groups to apply")
+	applyCmd.Flags().Bool(FlagPrune, false, "Destroy the applications created by the manifest which it no longer declares")
+	applyCmd.Flags().Bool(cmdmarathon.DRYRUN_FLAG, false, "Report the changes without applying them")
+	applyCmd.Flags().BoolP(cmdmarathon.WAIT_FLAG, "w", false, "Wait for each change to complete before the next")
+	applyCmd.Flags().DurationP(cmdmarathon.TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for each change (ex. 90s | 2m).  0 waits forever")
+	cmdmarathon.ApplyCancelFlags(applyCmd.Flags())
+	cmdmarathon.ApplyReadyFlags(applyCmd.Flags())
+	cmdmarathon.ApplySignatureFlags(applyCmd)
+	applyCmd.Flags().Int(cmdmarathon.WORKERS_FLAG, 1, "Number of changes made at once.  Every change is attempted and failures are summarized")
+	applyCmd.Flags().String(cmdmarathon.TEMPLATE_CTX_FLAG, cmdmarathon.DEFAULT_CTX, "Template context the descriptors are rendered with.  Default: the manifest's tempctx")
+	applyCmd.Flags().StringSliceP(cmdmarathon.PARAMS_FLAG, "p", nil, "Adds a param(s) that can be used for substitution (eg. -p TAG=1.2)")
+	applyCmd.Flags().BoolP(cmdmarathon.IGNORE_MISSING, "i", false, "Ignore missing ${PARAMS} and template fields rather than failing")
+	applyCmd.Flags().String(FlagAuditLog, "", "Audit log file.  Default: ~/.depcon/"+audit.DefaultFilename)
+	applyCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+}
+
+func applyManifest(cmd *cobra.Command, args []string) {
+	filename, _ := cmd.Flags().GetString(FlagFile)
+	if filename == "" {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s is required", FlagFile)))
+	}
+	manifest, err := reconcile.LoadManifest(filename)
+	if err != nil {
+		exitWithError(err)
+	}
+	files := []string{}
+	for _, r := range manifest.Resources {
+		files = append(files, manifest.Path(r.File))
+	}
+	cmdmarathon.VerifySignatures(cmd, files...)
+	envName := viper.GetString(ViperEnv)
+	client, err := environmentClients(cmd)(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	tempctx, _ := cmd.Flags().GetString(cmdmarathon.TEMPLATE_CTX_FLAG)
+	if !cmd.Flags().Changed(cmdmarathon.TEMPLATE_CTX_FLAG) && manifest.TemplateContext != "" {
+		tempctx = manifest.Path(manifest.TemplateContext)
+	}
+	descs, err := manifest.Descriptors(descriptorLoadOptions(cmd, envName, tempctx))
+	if err != nil {
+		exitWithError(err)
+	}
+	apps, err := client.ListApplications()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	changes := reconcile.Plan(descs, apps.Apps)
+	if prune, _ := cmd.Flags().GetBool(FlagPrune); prune {
+		changes = append(changes, reconcile.PlanPrune(manifest.Name, descs, apps.Apps)...)
+	}
+	changed := reconcile.Changed(changes)
+	if len(changed) == 0 {
+		log.Info("%d resource(s) of manifest '%s' match environment '%s'", len(descs), manifest.Name, envName)
+		return
+	}
+	if dryRun, _ := cmd.Flags().GetBool(cmdmarathon.DRYRUN_FLAG); dryRun {
+		cli.Output(templateFor(T_APPLY, changed), nil)
+		return
+	}
+	if deletes := countDeletes(changed); deletes > 0 {
+		if err := cli.Confirm(fmt.Sprintf("Prune %d resource(s) no longer declared by manifest '%s' in environment '%s'", deletes, manifest.Name, envName)); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	wait, _ := cmd.Flags().GetBool(cmdmarathon.WAIT_FLAG)
+	timeout := cmdmarathon.WaitTimeout(cmd, marathon.DefaultTimeout)
+	workers, _ := cmd.Flags().GetInt(cmdmarathon.WORKERS_FLAG)
+	failed := reconcile.ApplyParallel(client, changed, wait, timeout, workers)
+	recordApply(cmd, envName, filename, manifest.Name, changed, failed)
+	cli.Output(templateFor(T_APPLY, changed), nil)
+	if failed > 0 {
+		exitWithError(fmt.Errorf("%d of %d change(s) of manifest '%s' failed", failed, len(changed), manifest.Name))
+	}
+}
+
+func countDeletes(changes []*reconcile.Change) int {
+	deletes := 0
+	for _, c := range changes {
+		if c.Action == reconcile.ActionDelete {
+			deletes++
+		}
+	}
+	return deletes
+}
+
+// Records each change applied by the manifest followed by a summary in the audit log
+func recordApply(cmd *cobra.Command, envName, filename, name string, changes []*reconcile.Change, failed int) {
+	auditLog, _ := cmd.Flags().GetString(FlagAuditLog)
+	if auditLog == "" {
+		auditLog = filepath.Join(cliconfig.ConfigDir(), audit.DefaultFilename)
+	}
+	l := audit.New(auditLog)
+
+	details := map[string]string{"manifest": filename}
+	summary := &audit.Entry{Environment: envName, Action: ActionApply, Target: name, Result: audit.ResultSuccess, Details: details,
+		Message: fmt.Sprintf("%d change(s), %d failed", len(changes), failed)}
+	if failed > 0 {
+		summary.Result = audit.ResultFailed
+	}
+	for _, e := range append(reconcile.AuditEntries(envName, changes, details), summary) {
+		if err := l.Record(e); err != nil {
+			log.Error("Unable to write the audit log %s: %s", l.Filename(), err.Error())
+			return
+		}
+	}
+}
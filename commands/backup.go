@@ -0,0 +1,145 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ContainX/depcon/backup"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/gitrepo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagPods    = "pods"
+	FlagQueue   = "queue"
+	FlagVerify  = "verify"
+	FlagGit     = "git"
+	FlagMessage = "message"
+
+	T_BACKUP = `
+{{ "KIND" | header }}	{{ "ID" | header }}	{{ "PATH" | header }}	{{ "VERSION" | header }}
+{{ range . }}{{ .Kind }}	{{ .ID }}	{{ .Path }}	{{ .Version }}
+{{end}}`
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Exports every application and group definition of the environment to a directory",
+	Long: `Exports the definition of every application and group of the environment (and optionally its pods and
+launch queue) to a directory tree of YAML files following their ids along with backup.yaml, a manifest
+of the exported versions and the checksums of the files.  Applications are restored with app create.
+
+    eg. depcon backup -e prod --out backups/prod-2024-05-01/ --pods
+        depcon backup --verify backups/prod-2024-05-01/
+
+With --git the backup is committed to a branch of a git repository (replacing the previous one under --path)
+and pushed, giving a history of the environment's configuration.  Nothing is committed when no definition
+changed.
+
+    eg. depcon backup -e prod --git git@github.com:acme/cluster-state.git --branch main --message "nightly"`,
+	Run: runBackup,
+}
+
+func init() {
+	backupCmd.Flags().String(OUT_FLAG, "", "Directory the backup is written to (required)")
+	backupCmd.Flags().Bool(FlagPods, false, "Also exports the definition of every pod")
+	backupCmd.Flags().Bool(FlagQueue, false, "Also exports the launch queue")
+	backupCmd.Flags().String(FlagVerify, "", "Checks the files of the backup within this directory against its manifest rather than creating one")
+	backupCmd.Flags().String(FlagGit, "", "URL of a git repository the backup is committed and pushed to rather than --out")
+	backupCmd.Flags().String(FlagMessage, "", "Commit message with --git.  Default: depcon backup of <env>")
+	backupCmd.Flags().String(FlagBranch, "", "Branch the backup is committed to with --git.  Default: the repository's default branch")
+	backupCmd.Flags().String(FlagPath, "", "Directory of the backup within the --git repository.  Default: the environment's name")
+	backupCmd.Flags().String(FlagCheckout, "", "Directory the --git repository is checked out to.  Default: within ~/.depcon/backup")
+	backupCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+}
+
+func runBackup(cmd *cobra.Command, args []string) {
+	if dir, _ := cmd.Flags().GetString(FlagVerify); dir != "" {
+		verifyBackup(dir)
+		return
+	}
+	dir, _ := cmd.Flags().GetString(OUT_FLAG)
+	repo, _ := cmd.Flags().GetString(FlagGit)
+	if (dir == "") == (repo == "") {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("one of --%s or --%s is required", OUT_FLAG, FlagGit)))
+	}
+	envName := viper.GetString(ViperEnv)
+	client, err := environmentClients(cmd)(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+	if repo != "" {
+		backupToGit(cmd, client, envName, repo)
+		return
+	}
+
+	manifest, err := backup.Create(client, envName, dir, backupOptions(cmd))
+	if err != nil {
+		exitWithError(err)
+	}
+	cli.Output(templateFor(T_BACKUP, manifest.Files), nil)
+}
+
+func backupOptions(cmd *cobra.Command) *backup.Options {
+	opts := &backup.Options{}
+	opts.Pods, _ = cmd.Flags().GetBool(FlagPods)
+	opts.Queue, _ = cmd.Flags().GetBool(FlagQueue)
+	return opts
+}
+
+// Replaces the backup under --path of the repository {url} and commits and pushes it unless nothing changed
+func backupToGit(cmd *cobra.Command, client marathon.Marathon, envName, url string) {
+	path, _ := cmd.Flags().GetString(FlagPath)
+	if path == "" {
+		path = envName
+	}
+	if path = filepath.Clean(path); path == "." || filepath.IsAbs(path) || strings.HasPrefix(path, "..") {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s must be a directory within the repository", FlagPath)))
+	}
+	message, _ := cmd.Flags().GetString(FlagMessage)
+	if message == "" {
+		message = "depcon backup of " + envName
+	}
+
+	repo := &gitrepo.Repo{URL: url, Dir: checkoutDir(cmd, "backup", url)}
+	repo.Branch, _ = cmd.Flags().GetString(FlagBranch)
+	if _, err := repo.Sync(); err != nil {
+		exitWithError(err)
+	}
+	dir := filepath.Join(repo.Dir, path)
+	if err := os.RemoveAll(dir); err != nil {
+		exitWithError(err)
+	}
+	manifest, err := backup.Create(client, envName, dir, backupOptions(cmd))
+	if err != nil {
+		exitWithError(err)
+	}
+	rev, committed, err := repo.Commit(message, filepath.Join(path, backup.ManifestFilename))
+	if err != nil {
+		exitWithError(err)
+	}
+	if !committed {
+		log.Info("No definition of environment '%s' changed since revision %s", envName, rev)
+		return
+	}
+	cli.Output(templateFor(T_BACKUP, manifest.Files), nil)
+	log.Info("Committed the backup of environment '%s' to %s as revision %s", envName, url, rev)
+}
+
+func verifyBackup(dir string) {
+	invalid, err := backup.Verify(dir)
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(invalid) > 0 {
+		cli.Output(templateFor(T_BACKUP, invalid), nil)
+		exitWithError(fmt.Errorf("%d file(s) of the backup %s are missing or have changed", len(invalid), dir))
+	}
+	log.Info("Every file of the backup %s matches its checksum", dir)
+}
@@ -0,0 +1,116 @@
+package chronos
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ContainX/depcon/chronos"
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/dcos"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	ENV_NAME         string = "env_name"
+)
+
+var (
+	ErrorNoChronos = errors.New("No Chronos URL is configured for this environment.  Add one with 'depcon config env update [name] --chronos URL'")
+
+	chronosCmd = &cobra.Command{
+		Use:   "chronos",
+		Short: "Manage Chronos scheduled jobs",
+		Long: `Manage Chronos scheduled and dependent jobs
+
+    Chronos is reached with the credentials of the environment's Marathon service.  DC/OS
+    environments use the cluster's /service/chronos unless the environment specifies a
+    Chronos URL (depcon config env update [name] --chronos URL)
+
+    See chronos's subcommands for available choices`,
+	}
+	chronosClient chronos.Chronos
+	configFile    *cliconfig.ConfigFile
+)
+
+// Associates the chronos commands to the given command
+func AddChronosToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
+	rc.AddCommand(chronosCmd)
+}
+
+func init() {
+	chronosCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	chronosCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	chronosCmd.AddCommand(chronosListCmd, chronosGetCmd, chronosCreateCmd, chronosUpdateCmd, chronosDeleteCmd, chronosRunCmd, chronosKillCmd)
+}
+
+func client(cmd *cobra.Command) chronos.Chronos {
+	if chronosClient == nil {
+		envName := viper.GetString(ENV_NAME)
+		service := environmentService(envName)
+		host, err := chronosHost(service)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		insecure, _ := cmd.Flags().GetBool(INSECURE_FLAG)
+		allowWrite, _ := cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+		mopts := &marathon.MarathonOptions{TLSAllowInsecure: insecure}
+		if _, err := cmdmarathon.ApplyConnection(envName, service, mopts); err != nil {
+			exitWithError(err)
+		}
+
+		chronosClient = chronos.NewChronosClient(host, service.Username, service.Password, &chronos.ChronosOptions{
+			TLSAllowInsecure: insecure,
+			Authenticator:    mopts.Authenticator,
+			Proxy:            mopts.Proxy,
+			TLS:              mopts.TLS,
+			ReadOnly:         service.ReadOnly && !allowWrite,
+			Retry:            httpclient.DefaultRetryPolicy(),
+			Timeouts:         mopts.Timeouts,
+			Headers:          mopts.Headers,
+		})
+	}
+	return chronosClient
+}
+
+// Returns the Marathon service of environment {envName} whose credentials are used for Chronos
+func environmentService(envName string) *cliconfig.ServiceConfig {
+	env, err := configFile.GetEnvironment(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+	if env.Marathon == nil {
+		exitWithError(fmt.Errorf("Environment '%s' does not define a Marathon service", envName))
+	}
+	return env.Marathon
+}
+
+// Returns the Chronos URL of {service} which defaults to /service/chronos of DC/OS clusters
+func chronosHost(service *cliconfig.ServiceConfig) (string, error) {
+	switch {
+	case service.ChronosUrl != "":
+		return httpclient.ResolveEndpoint(service.ChronosUrl)
+	case service.IsDCOS():
+		return dcos.ChronosURL(marathon.SplitHosts(service.HostUrl)[0]), nil
+	}
+	return "", ErrorNoChronos
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
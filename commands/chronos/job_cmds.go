@@ -0,0 +1,141 @@
+package chronos
+
+import (
+	"fmt"
+
+	"github.com/ContainX/depcon/chronos"
+	"github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FORCE_FLAG     string = "force"
+	ARGUMENTS_FLAG string = "arguments"
+)
+
+var log = logger.GetLogger("depcon.chronos")
+
+const descriptorHelp = `
+
+    The descriptor is rendered with the template context and ${PARAMS} exactly as Marathon
+    descriptors are.  Each document is a Chronos job.  Jobs listing 'parents' are registered
+    as dependent jobs and all others must specify an ISO 8601 'schedule'.  YAML descriptors
+    may contain multiple documents separated by '---' which are processed in order`
+
+var (
+	chronosListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists all jobs",
+		Run: func(cmd *cobra.Command, args []string) {
+			v, e := client(cmd).ListJobs()
+			cli.Output(templateFor(T_JOBS, v), e)
+		},
+	}
+
+	chronosGetCmd = &cobra.Command{
+		Use:   "get [name]",
+		Short: "Gets a job by name",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).GetJob(args[0])
+			cli.Output(templateFor(T_JOB, v), e)
+		},
+	}
+
+	chronosCreateCmd = &cobra.Command{
+		Use:   "create [file(.json | .yaml)]",
+		Short: "Creates the jobs within a descriptor",
+		Long:  "Creates the jobs within a descriptor.  With --force jobs which exist are updated" + descriptorHelp,
+		Run: func(cmd *cobra.Command, args []string) {
+			force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+			applyJobs(cmd, args, force)
+		},
+	}
+
+	chronosUpdateCmd = &cobra.Command{
+		Use:   "update [file(.json | .yaml)]",
+		Short: "Creates or updates the jobs within a descriptor",
+		Long:  "Creates or updates the jobs within a descriptor" + descriptorHelp,
+		Run: func(cmd *cobra.Command, args []string) {
+			applyJobs(cmd, args, true)
+		},
+	}
+
+	chronosDeleteCmd = &cobra.Command{
+		Use:   "delete [name]",
+		Short: "Removes a job",
+		Run:   deleteJob,
+	}
+
+	chronosRunCmd = &cobra.Command{
+		Use:   "run [name]",
+		Short: "Runs a job now regardless of its schedule",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			arguments, _ := cmd.Flags().GetString(ARGUMENTS_FLAG)
+			if err := client(cmd).StartJob(args[0], arguments); err != nil {
+				exitWithError(err)
+			}
+			log.Info("Job '%s' was started", args[0])
+		},
+	}
+
+	chronosKillCmd = &cobra.Command{
+		Use:   "kill [name]",
+		Short: "Kills the running tasks of a job",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			cli.ConfirmOrExit(fmt.Sprintf("Kill the tasks of job '%s'", args[0]), "environment", viper.GetString(ENV_NAME))
+			if err := client(cmd).KillTasks(args[0]); err != nil {
+				exitWithError(err)
+			}
+			log.Info("Tasks of job '%s' were killed", args[0])
+		},
+	}
+)
+
+func init() {
+	chronosCreateCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Updates jobs which already exist")
+	marathon.ApplyDescriptorFlags(chronosCreateCmd)
+	marathon.ApplyDescriptorFlags(chronosUpdateCmd)
+	chronosRunCmd.Flags().String(ARGUMENTS_FLAG, "", "Optional: arguments appended to the command of this run")
+}
+
+func applyJobs(cmd *cobra.Command, args []string, force bool) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	results := []*chronos.ApplyResult{}
+	for _, doc := range marathon.RenderDocuments(cmd, args[0]) {
+		result, err := client(cmd).ApplyJob(doc, force)
+		if err != nil {
+			name, _ := doc["name"].(string)
+			log.Error("Unable to apply job '%s'", name)
+			exitWithError(err)
+		}
+		results = append(results, result)
+	}
+	cli.Output(templateFor(T_APPLY, results), nil)
+}
+
+func deleteJob(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	cli.ConfirmOrExit(fmt.Sprintf("Delete job '%s'", args[0]), "environment", viper.GetString(ENV_NAME))
+	if err := client(cmd).DeleteJob(args[0]); err != nil {
+		exitWithError(err)
+	}
+	log.Info("Job '%s' was deleted", args[0])
+}
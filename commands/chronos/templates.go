@@ -0,0 +1,82 @@
+package chronos
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/ContainX/depcon/chronos"
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_JOBS = `
+{{ "NAME" | header }}	{{ "SCHEDULE" | header }}	{{ "DISABLED" | header }}	{{ "SUCCESS" | header }}	{{ "ERRORS" | header }}	{{ "LAST RUN" | header }}
+{{ range . }}{{ .Name }}	{{ . | schedule }}	{{ .Disabled | boolToYesNo }}	{{ .SuccessCount | intToString }}	{{ .ErrorCount | intToString }}	{{ . | lastRun }}
+{{end}}`
+
+	T_JOB = `
+{{ "Name:" }}	{{ .Name }}
+{{ "Description:" }}	{{ .Description }}
+{{ "Owner:" }}	{{ .Owner }}
+{{ "Command:" }}	{{ .Command }}
+{{ with .Container }}{{ "Image:" }}	{{ .Image }}
+{{end}}{{ "Schedule:" }}	{{ . | schedule }}
+{{ "Time Zone:" }}	{{ .ScheduleTimeZone }}
+{{ "Disabled:" }}	{{ .Disabled | boolToYesNo }}
+{{ "CPUs:" }}	{{ .Cpus | floatToString }}
+{{ "Memory:" }}	{{ .Mem | floatToString }}
+{{ "Retries:" }}	{{ .Retries | intToString }}
+{{ "Successful Runs:" }}	{{ .SuccessCount | intToString }}
+{{ "Failed Runs:" }}	{{ .ErrorCount | intToString }}
+{{ "Last Success:" }}	{{ .LastSuccess }}
+{{ "Last Error:" }}	{{ .LastError }}
+`
+
+	T_APPLY = `
+{{ "NAME" | header }}	{{ "TYPE" | header }}	{{ "CREATED" | header }}
+{{ range . }}{{ .Name }}	{{ .Type }}	{{ .Created | boolToYesNo }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data, Funcs: buildFuncMap()}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
+
+func buildFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"schedule": schedule,
+		"lastRun":  lastRun,
+	}
+}
+
+// Returns the schedule of the job or the parents of dependent jobs
+func schedule(j *chronos.Job) string {
+	if j.IsDependent() {
+		return "after " + strings.Join(j.Parents, ",")
+	}
+	return j.Schedule
+}
+
+// Returns the outcome of the most recent run
+func lastRun(j *chronos.Job) string {
+	switch {
+	case j.LastSuccess == "" && j.LastError == "":
+		return ""
+	case j.LastSuccess > j.LastError:
+		return cli.Status("success") + " " + j.LastSuccess
+	}
+	return cli.Status("failed") + " " + j.LastError
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+const completionHelp = `Generates the shell completion script for depcon.  Commands, flags and flag values such as
+output formats and environment names are completed.
+
+  bash:        source <(depcon completion bash)
+               or add to ~/.bashrc (requires the bash-completion package)
+  zsh:         depcon completion zsh > "${fpath[1]}/_depcon"
+  fish:        depcon completion fish > ~/.config/fish/completions/depcon.fish
+  powershell:  depcon completion powershell | Out-String | Invoke-Expression`
+
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+var completionCmd = &cobra.Command{
+	Use:       "completion [bash | zsh | fish | powershell]",
+	Short:     "Generates shell completion scripts",
+	Long:      completionHelp,
+	ValidArgs: completionShells,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(os.Stdout, true)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+		default:
+			err = cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Unknown shell '%s', must be one of %v", args[0], completionShells))
+		}
+		if err != nil {
+			cli.Output(nil, err)
+		}
+	},
+}
+
+// Registers completion of flag values and environment name arguments.  Called once all flags have been
+// defined
+func registerCompletions() {
+	rootCmd.RegisterFlagCompletionFunc(FlagEnv, completeEnvironmentsAndGroups)
+	rootCmd.RegisterFlagCompletionFunc(FLAG_FORMAT, completeValues(cli.ValidFormats))
+
+	for _, c := range []*cobra.Command{configRemoveCmd, configUpdateCmd, configRenameCmd, configVerifyCmd} {
+		c.ValidArgsFunction = completeFirstArg(completeEnvironments)
+	}
+	configDefaultCmd.ValidArgsFunction = completeFirstArg(completeEnvironmentsAndGroups)
+	configFlagsCmd.ValidArgsFunction = completeFirstArg(completeEnvironmentsAndGroups)
+	configGroupRemoveCmd.ValidArgsFunction = completeFirstArg(completeGroups)
+	configExportCmd.ValidArgsFunction = completeEnvironments
+	configOutputCmd.ValidArgsFunction = completeFirstArg(completeValues(cli.ValidFormats))
+	configGroupAddCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeEnvironments(cmd, args, toComplete)
+	}
+}
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+func completeValues(values []string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// Completes only the first argument with {fn}
+func completeFirstArg(fn completionFunc) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return fn(cmd, args, toComplete)
+	}
+}
+
+func completeEnvironments(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if configFile == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return configFile.GetEnvironments(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeGroups(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if configFile == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	groups := []string{}
+	for name := range configFile.Groups {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	return groups, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeEnvironmentsAndGroups(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	envs, _ := completeEnvironments(cmd, args, toComplete)
+	groups, _ := completeGroups(cmd, args, toComplete)
+	return append(envs, groups...), cobra.ShellCompDirectiveNoFileComp
+}
+
+// Determines if depcon was invoked to generate a completion script or by the shell to complete the
+// command line (cobra's hidden __complete command)
+func isCompletionCommand() bool {
+	switch firstCommand() {
+	case "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return false
+}
+
+// Returns a valid environment for completion so the command tree can be built even when the -e value is
+// still being typed or no default environment has been set
+func completionEnvironment(envName string) string {
+	if _, err := configFile.GetEnvironment(envName); err == nil {
+		return envName
+	}
+	if _, isGroup := configFile.GetGroup(envName); isGroup {
+		return envName
+	}
+	if _, err := configFile.GetEnvironment(configFile.DefaultEnv); err == nil {
+		return configFile.DefaultEnv
+	}
+	if envs := configFile.GetEnvironments(); len(envs) > 0 {
+		return envs[0]
+	}
+	return envName
+}
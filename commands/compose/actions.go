@@ -34,16 +34,26 @@ func execActionWithPreHook(action ComposeAction, preHook ComposePreHook) func(cm
 }
 
 func defaultCompose(composeFile, projName string, cmd *cobra.Command) compose.Compose {
+	return compose.NewCompose(composeContext(cmd))
+}
+
+// Returns the compose file, project name and params specified by the flags of {cmd}
+func composeContext(cmd *cobra.Command) *compose.Context {
+	composeFile, _ := cmd.Flags().GetString(COMPOSE_FILE_FLAG)
+	projName, _ := cmd.Flags().GetString(PROJECT_NAME_FLAG)
 	params, _ := cmd.Flags().GetStringSlice(PARAMS_FLAG)
 	ignore, _ := cmd.Flags().GetBool(IGNORE_MISSING)
+	envFile, _ := cmd.Flags().GetString(ENV_FILE_FLAG)
+	profiles, _ := cmd.Flags().GetStringSlice(PROFILE_FLAG)
 
-	context := &compose.Context{
+	return &compose.Context{
 		ComposeFile:          composeFile,
 		ProjectName:          projName,
 		EnvParams:            cli.NameValueSliceToMap(params),
+		EnvFile:              envFile,
+		Profiles:             profiles,
 		ErrorOnMissingParams: !ignore,
 	}
-	return compose.NewCompose(context)
 }
 
 func logs(c compose.Compose, cmd *cobra.Command, args []string) error {
@@ -96,3 +106,11 @@ func port(c compose.Compose, cmd *cobra.Command, args []string) error {
 func up(c compose.Compose, cmd *cobra.Command, args []string) error {
 	return c.Up(args...)
 }
+
+func scale(c compose.Compose, cmd *cobra.Command, args []string) error {
+	servicesScale, err := compose.ParseScale(args)
+	if err != nil {
+		return err
+	}
+	return c.Scale(servicesScale)
+}
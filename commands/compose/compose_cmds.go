@@ -14,6 +14,8 @@ const (
 	INDEX_FLAG        string = "index"
 	PROTO_FLAG        string = "protocol"
 	IGNORE_MISSING    string = "ignore"
+	ENV_FILE_FLAG     string = "env-file"
+	PROFILE_FLAG      string = "profile"
 )
 
 var (
@@ -28,7 +30,11 @@ var (
 	upCmd = &cobra.Command{
 		Use:   "up [services ...]",
 		Short: "Create and start containers",
-		Run:   execAction(up),
+		Long: `Create and start containers
+
+    With --marathon the project is converted to a Marathon group (see convert) and deployed to the
+    environment.  Services which are specified are deployed along with the services they depend on`,
+		Run: upProject,
 	}
 
 	killCmd = &cobra.Command{
@@ -85,6 +91,15 @@ var (
 		Run:   execAction(stop),
 	}
 
+	scaleCmd = &cobra.Command{
+		Use:   "scale [SERVICE=NUM ...]",
+		Short: "Set number of containers for a service",
+		Long: `Set number of containers (or Marathon app instances with --marathon) for a service
+
+    eg. depcon compose scale web=5 worker=2`,
+		Run: scaleProject,
+	}
+
 	portCmd = &cobra.Command{
 		Use:   "port [service] [private_port]",
 		Short: "Stops services",
@@ -94,6 +109,7 @@ var (
 
 // Associates the compose service to the given command
 func AddComposeToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
 	rc.AddCommand(composeCmd)
 }
 
@@ -102,13 +118,15 @@ func init() {
                         CAUTION: This can be dangerous if some params define versions or other required information.`)
 	composeCmd.PersistentFlags().StringSliceP(PARAMS_FLAG, "p", nil, `Adds a param(s) that can be used for substitution.
                   eg. -p MYVAR=value would replace ${MYVAR} with "value" in the compose file.
-                  These take precidence over env vars`)
+                  These take precidence over env vars and the env file`)
+	composeCmd.PersistentFlags().String(ENV_FILE_FLAG, "", "File of KEY=VALUE params used for substitution.  Default: .env beside the compose file")
+	composeCmd.PersistentFlags().StringSlice(PROFILE_FLAG, nil, "Enables the services of a profile (along with those of $COMPOSE_PROFILES)")
 
 	psCmd.Flags().BoolP(QUIET_FLAG, "q", false, "Only display IDs")
 	portCmd.Flags().Int(INDEX_FLAG, 1, "index of the container if there are multiple instances of a service [default: 1]")
 	portCmd.Flags().String(PROTO_FLAG, "tcp", "tcp or udp [default: tcp]")
 
-	composeCmd.PersistentFlags().String(COMPOSE_FILE_FLAG, "docker-compose.yml", "Docker compose file")
+	composeCmd.PersistentFlags().StringP(COMPOSE_FILE_FLAG, "f", "docker-compose.yml", "Docker compose file(s).  Files separated by commas are merged in order")
 	composeCmd.PersistentFlags().String(PROJECT_NAME_FLAG, compose.DEFAULT_PROJECT, "Project name for this composition")
-	composeCmd.AddCommand(buildCmd, killCmd, logCmd, portCmd, psCmd, upCmd, pullCmd, restartCmd, rmCmd, startCmd, stopCmd, upCmd)
+	composeCmd.AddCommand(buildCmd, killCmd, logCmd, portCmd, psCmd, upCmd, pullCmd, restartCmd, rmCmd, scaleCmd, startCmd, stopCmd, upCmd)
 }
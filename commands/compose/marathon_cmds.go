@@ -0,0 +1,330 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/compose"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	ROOT_GROUP_FLAG  string = "root"
+	MARATHON_FLAG    string = "marathon"
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	WAIT_FLAG        string = "wait"
+	TIMEOUT_FLAG     string = "wait-timeout"
+	DRYRUN_FLAG      string = "dry-run"
+	ENV_NAME         string = "env_name"
+)
+
+var log = logger.GetLogger("depcon.compose")
+
+// scaleResult is a Marathon app of a service which was scaled
+type scaleResult struct {
+	Service      string `json:"service"`
+	AppID        string `json:"appId"`
+	Instances    int    `json:"instances"`
+	DeploymentID string `json:"deploymentId"`
+}
+
+var (
+	convertCmd = &cobra.Command{
+		Use:   "convert [compose-file]",
+		Short: "Converts a compose project to a Marathon group",
+		Long: `Converts a compose project (version 2 or 3) to a Marathon group with an app per service
+
+    The group is named after the project (--name) beneath --root.  Ports, environment, labels,
+    volumes, resources, replicas and depends_on are converted.  Keys Marathon has no equivalent
+    for are reported and ignored.  Use -o json|yaml or --out to write the group definition
+
+    eg. depcon compose convert docker-compose.yml --name shop -o json > shop.json`,
+		Run: convertProject,
+	}
+
+	downCmd = &cobra.Command{
+		Use:   "down",
+		Short: "Stop and remove the project's containers or Marathon apps",
+		Long: `Stop and remove the project's containers or Marathon apps
+
+    With --marathon the apps labeled with the project (--name) are removed from the environment.
+    Groups holding nothing but the project's apps are removed whole.  Use --dry-run to preview
+    what will be removed`,
+		Run: downProject,
+	}
+
+	marathonClient marathon.Marathon
+	configFile     *cliconfig.ConfigFile
+)
+
+func init() {
+	composeCmd.PersistentFlags().Bool(MARATHON_FLAG, false, "Operates on the project's Marathon group within the environment rather than local containers")
+	composeCmd.PersistentFlags().String(ROOT_GROUP_FLAG, "/", "Marathon group the project's group is created beneath")
+	composeCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks (--marathon)")
+	composeCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only (--marathon)")
+
+	for _, c := range []*cobra.Command{upCmd, downCmd, scaleCmd} {
+		c.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the deployments to complete (--marathon)")
+		c.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait (ex. 90s | 2m).  Default: the environment's timeout")
+	}
+	downCmd.Flags().Bool(DRYRUN_FLAG, false, "Preview the groups and apps which would be removed (--marathon)")
+	composeCmd.AddCommand(convertCmd, downCmd)
+}
+
+func convertProject(cmd *cobra.Command, args []string) {
+	c, err := loadGroup(cmd, composeContext(cmd), args)
+	if err != nil {
+		cli.Output(nil, err)
+		return
+	}
+	cli.Output(templateFor(T_GROUP, c.Group), nil)
+}
+
+// Runs 'up' against Marathon when --marathon is specified and local containers otherwise
+func upProject(cmd *cobra.Command, args []string) {
+	if useMarathon, _ := cmd.Flags().GetBool(MARATHON_FLAG); !useMarathon {
+		execAction(up)(cmd, args)
+		return
+	}
+
+	c, err := loadGroup(cmd, composeContext(cmd), nil)
+	if err != nil {
+		exitWithError(err)
+	}
+	apps, err := c.Select(args...)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if len(args) == 0 {
+		// the whole project replaces the group so removed services are removed from Marathon
+		if _, err := client(cmd).CreateGroup(c.Group, false, true); err != nil {
+			exitWithError(err)
+		}
+	} else {
+		// selected services are updated individually leaving the other apps of the group untouched
+		for _, app := range apps {
+			if _, err := client(cmd).CreateApplication(app, false, true); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+
+	if wait, _ := cmd.Flags().GetBool(WAIT_FLAG); wait {
+		for _, app := range apps {
+			if err := client(cmd).WaitForApplication(app.ID, waitTimeout(cmd)); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+	cli.Output(templateFor(T_GROUP, &marathon.Group{GroupID: c.Group.GroupID, Apps: apps}), nil)
+}
+
+// Runs 'down' against Marathon when --marathon is specified and stops local containers otherwise
+func downProject(cmd *cobra.Command, args []string) {
+	if useMarathon, _ := cmd.Flags().GetBool(MARATHON_FLAG); !useMarathon {
+		execAction(stop)(cmd, args)
+		return
+	}
+
+	removals := projectRemovals(cmd)
+	if len(removals) == 0 {
+		exitWithError(cli.WithExitCode(cli.ExitNotFound, fmt.Errorf("No apps of project '%s' were found", projectName(cmd))))
+	}
+	if dryRun, _ := cmd.Flags().GetBool(DRYRUN_FLAG); dryRun {
+		cli.Output(templateFor(T_REMOVALS, removals), nil)
+		return
+	}
+
+	if err := cli.Confirm(fmt.Sprintf("Remove project '%s' (%d app(s)) in environment '%s'", projectName(cmd), countApps(removals), viper.GetString(ENV_NAME))); err != nil {
+		exitWithError(err)
+	}
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	for _, r := range removals {
+		var deployment *marathon.DeploymentID
+		var err error
+		if r.Kind == "group" {
+			deployment, err = client(cmd).DestroyGroup(r.ID)
+		} else {
+			deployment, err = client(cmd).DestroyApplication(r.ID)
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+		if wait {
+			if err := client(cmd).WaitForDeployment(deployment.DeploymentID, waitTimeout(cmd)); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+	cli.Output(templateFor(T_REMOVALS, removals), nil)
+}
+
+// Runs 'scale' against the project's Marathon apps when --marathon is specified and local containers otherwise
+func scaleProject(cmd *cobra.Command, args []string) {
+	if useMarathon, _ := cmd.Flags().GetBool(MARATHON_FLAG); !useMarathon {
+		execAction(scale)(cmd, args)
+		return
+	}
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	servicesScale, err := compose.ParseScale(args)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	services := compose.ServiceApps(projectName(cmd), projectApps(cmd))
+	names := []string{}
+	for svc, n := range servicesScale {
+		if len(services[svc]) == 0 {
+			exitWithError(cli.WithExitCode(cli.ExitNotFound, fmt.Errorf("No app of service '%s' within project '%s' was found", svc, projectName(cmd))))
+		}
+		if n == 0 {
+			if err := cli.Confirm(fmt.Sprintf("Scale service '%s' to 0 instances in environment '%s'", svc, viper.GetString(ENV_NAME))); err != nil {
+				exitWithError(err)
+			}
+		}
+		names = append(names, svc)
+	}
+	sort.Strings(names)
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	results := []*scaleResult{}
+	for _, svc := range names {
+		for _, id := range services[svc] {
+			deployment, err := client(cmd).ScaleApplication(id, servicesScale[svc])
+			if err != nil {
+				exitWithError(err)
+			}
+			if wait {
+				if err := client(cmd).WaitForDeployment(deployment.DeploymentID, waitTimeout(cmd)); err != nil {
+					exitWithError(err)
+				}
+			}
+			results = append(results, &scaleResult{Service: svc, AppID: id, Instances: servicesScale[svc], DeploymentID: deployment.DeploymentID})
+		}
+	}
+	cli.Output(templateFor(T_SCALED, results), nil)
+}
+
+// Returns the apps labeled with the project restricted to the project's group when --root is specified
+func projectApps(cmd *cobra.Command) []marathon.Application {
+	project := projectName(cmd)
+	apps, err := client(cmd).ListApplicationsWithFilters(fmt.Sprintf("label=%s==%s", compose.LabelProject, project))
+	if err != nil {
+		exitWithError(err)
+	}
+	if !cmd.Flags().Changed(ROOT_GROUP_FLAG) {
+		return apps.Apps
+	}
+
+	root, _ := cmd.Flags().GetString(ROOT_GROUP_FLAG)
+	prefix := compose.GroupID(root, project) + "/"
+	filtered := []marathon.Application{}
+	for _, app := range apps.Apps {
+		if strings.HasPrefix(app.ID, prefix) {
+			filtered = append(filtered, app)
+		}
+	}
+	return filtered
+}
+
+// Returns the groups and apps removed to tear down the project
+func projectRemovals(cmd *cobra.Command) []*compose.Removal {
+	removals, err := compose.PlanRemoval(projectName(cmd), projectApps(cmd), client(cmd).GetGroup)
+	if err != nil {
+		exitWithError(err)
+	}
+	return removals
+}
+
+func projectName(cmd *cobra.Command) string {
+	name, _ := cmd.Flags().GetString(PROJECT_NAME_FLAG)
+	return name
+}
+
+func countApps(removals []*compose.Removal) int {
+	count := 0
+	for _, r := range removals {
+		count += len(r.Apps)
+	}
+	return count
+}
+
+// Returns --wait-timeout or marathon.DefaultTimeout when unspecified
+func waitTimeout(cmd *cobra.Command) time.Duration {
+	if timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG); timeout > 0 {
+		return timeout
+	}
+	return marathon.DefaultTimeout
+}
+
+// Loads the compose project (the file in {args} or --compose-file) and converts it to a Marathon group
+// logging the keys which were ignored
+func loadGroup(cmd *cobra.Command, context *compose.Context, args []string) (*compose.GroupConversion, error) {
+	if len(args) > 0 {
+		context.ComposeFile = args[0]
+	}
+	root, _ := cmd.Flags().GetString(ROOT_GROUP_FLAG)
+
+	doc, err := compose.LoadProject(context)
+	if err != nil {
+		return nil, err
+	}
+	c, err := compose.ConvertToGroup(compose.GroupID(root, context.ProjectName), context.ProjectName, doc)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range c.Warnings {
+		log.Warning(w)
+	}
+	return c, nil
+}
+
+// Returns the Marathon client of the environment the project is deployed to
+func client(cmd *cobra.Command) marathon.Marathon {
+	if marathonClient == nil {
+		envName := viper.GetString(ENV_NAME)
+		env, err := configFile.GetEnvironment(envName)
+		if err != nil {
+			exitWithError(err)
+		}
+		if env.Marathon == nil {
+			exitWithError(fmt.Errorf("Environment '%s' does not define a Marathon service", envName))
+		}
+
+		insecure, _ := cmd.Flags().GetBool(INSECURE_FLAG)
+		allowWrite, _ := cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+		opts := &marathon.MarathonOptions{TLSAllowInsecure: insecure, ReadOnly: env.Marathon.ReadOnly && !allowWrite}
+		if progress := cli.ActiveProgress(); progress != nil {
+			opts.Progress = progress
+		}
+		m, err := cmdmarathon.NewClient(envName, env.Marathon, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+		marathonClient = m
+	}
+	return marathonClient
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
@@ -0,0 +1,66 @@
+package compose
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_GROUP = `
+{{ "GROUP:" }}	{{ .GroupID }}
+
+{{ "ID" | header }}	{{ "IMAGE" | header }}	{{ "INSTANCES" | header }}	{{ "CPU" | header }}	{{ "MEM" | header }}	{{ "PORTS" | header }}	{{ "DEPENDS ON" | header }}
+{{ range .Apps }}{{ .ID }}	{{ .Container.Docker.Image }}	{{ .Instances | intToString }}	{{ .CPUs | floatToString }}	{{ .Mem | floatToString }}	{{ .Container.Docker.PortMappings | ports }}	{{ .Dependencies | join }}
+{{end}}`
+
+	T_REMOVALS = `
+{{ "ID" | header }}	{{ "KIND" | header }}	{{ "APPS" | header }}
+{{ range . }}{{ .ID }}	{{ .Kind }}	{{ .Apps | join }}
+{{end}}`
+
+	T_SCALED = `
+{{ "SERVICE" | header }}	{{ "APP ID" | header }}	{{ "INSTANCES" | header }}	{{ "DEPLOYMENT" | header }}
+{{ range . }}{{ .Service }}	{{ .AppID }}	{{ .Instances | intToString }}	{{ .DeploymentID }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data, Funcs: buildFuncMap()}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
+
+func buildFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"ports": ports,
+		"join":  func(s []string) string { return strings.Join(s, ",") },
+	}
+}
+
+// Returns the port mappings as host:container/protocol
+func ports(mappings []*marathon.PortMapping) string {
+	p := []string{}
+	for _, m := range mappings {
+		host := "*"
+		if m.HostPort > 0 {
+			host = strconv.Itoa(m.HostPort)
+		}
+		p = append(p, host+":"+strconv.Itoa(m.ContainerPort)+"/"+m.Protocol)
+	}
+	return strings.Join(p, ",")
+}
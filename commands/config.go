@@ -5,24 +5,102 @@ import (
 	"fmt"
 	"github.com/ContainX/depcon/cliconfig"
 	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/login"
 	"github.com/ContainX/depcon/utils"
+	"github.com/bgentry/speakeasy"
 	"github.com/spf13/cobra"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"text/template"
 )
 
 const (
 	T_CONFIG_ENV = `
-{{ "NAME" }}	{{ "TYPE" }}	{{ "ENDPOINT" }}	{{ "AUTH" }}	{{ "DEFAULT" }}
+{{ "NAME" | header }}	{{ "TYPE" | header }}	{{ "ENDPOINT" | header }}	{{ "AUTH" | header }}	{{ "DEFAULT" | header }}
 {{ range . }}{{ .Name }}	{{ .EnvType }}	{{ .HostURL }}	{{ .Auth | boolToYesNo }}	{{ .Default | defaultEnvToStr }}
 {{end}}`
 
-	NAME_FLAG     = "name"
-	URL_FLAG      = "url"
-	USER_FLAG     = "user"
-	PASSWORD_FLAG = "pass"
+	T_CONFIG_ISSUES = `
+{{ "LEVEL" | header }}	{{ "PATH" | header }}	{{ "MESSAGE" | header }}
+{{ range . }}{{ .Level | status }}	{{ .Path }}	{{ .Message }}
+{{end}}`
+
+	T_CONFIG_FLAGS = `
+{{ "FLAG" | header }}	{{ "VALUE" | header }}
+{{ range . }}{{ .Name }}	{{ .Value }}
+{{end}}`
+
+	T_CONFIG_GROUPS = `
+{{ "GROUP" | header }}	{{ "ENVIRONMENTS" | header }}
+{{ range . }}{{ .Name }}	{{ .Members }}
+{{end}}`
+
+	T_CONFIG_FORMATS = `
+{{ "FORMAT" | header }}	{{ "TEMPLATE" | header }}
+{{ range . }}@{{ .Name }}	{{ .Template }}
+{{end}}`
+
+	NAME_FLAG            = "name"
+	URL_FLAG             = "url"
+	USER_FLAG            = "user"
+	PASSWORD_FLAG        = "pass"
+	OUT_FLAG             = "out"
+	EXPORT_PASSWORD_FLAG = "password"
+	FORCE_FLAG           = "force"
+	AUTH_FLAG            = "auth"
+	SERVICE_ACCOUNT_FLAG = "service-account"
+	PROXY_FLAG           = "proxy"
+	HTTPS_PROXY_FLAG     = "https-proxy"
+	NO_PROXY_FLAG        = "no-proxy"
+	CERT_FLAG            = "cert"
+	KEY_FLAG             = "key"
+	CA_FLAG              = "ca"
+	INSECURE_VERIFY_FLAG = "insecure-skip-verify"
+	OFFLINE_FLAG         = "offline"
+	READONLY_FLAG        = "readonly"
+	FROM_FLAG            = "from"
+	CONNECT_TIMEOUT_FLAG = "connect-timeout"
+	TLS_TIMEOUT_FLAG     = "tls-timeout"
+	HEADER_TIMEOUT_FLAG  = "header-timeout"
+	REQUEST_TIMEOUT_FLAG = "request-timeout"
+	RATE_LIMIT_FLAG      = "rate-limit"
+	COMPRESS_FLAG        = "compress"
+	HEADER_FLAG          = "header"
+	CLUSTER_FLAG         = "cluster"
+	REGION_FLAG          = "region"
+	PROFILE_FLAG         = "profile"
+	ENDPOINT_FLAG        = "endpoint"
+	ADDRESS_FLAG         = "address"
+	NAMESPACE_FLAG       = "namespace"
+	HOST_FLAG            = "host"
+	METRONOME_FLAG       = "metronome"
+	CHRONOS_FLAG         = "chronos"
+	MESOS_FLAG           = "mesos"
+	MARATHON_LB_FLAG     = "marathon-lb"
+	ISSUER_FLAG          = "issuer"
+	CLIENT_ID_FLAG       = "client-id"
+	LOGIN_URL_FLAG       = "login-url"
 )
 
+type FlagSummary struct {
+	Name  string
+	Value string
+}
+
+type GroupSummary struct {
+	Name    string
+	Members string
+}
+
+type FormatSummary struct {
+	Name     string
+	Template string
+}
+
 type ConfigEnvironments struct {
 	DefaultEnv string
 	Envs       map[string]*cliconfig.ConfigEnvironment
@@ -36,8 +114,8 @@ type EnvironmentSummary struct {
 	Default bool
 }
 
-var ValidOutputs []string = []string{"json", "yaml", "column"}
-var ErrInvalidOutputFormat = errors.New("Invalid Output specified. Must be 'json','yaml' or 'column'")
+var ValidOutputs []string = cli.ValidFormats
+var ErrInvalidOutputFormat = errors.New("Invalid Output specified. Must be 'json', 'yaml', 'column', 'table' or 'csv'")
 var ErrInvalidRootOption = errors.New("Invalid chroot option specified. Must be 'true' or 'false'")
 
 var configCmd = &cobra.Command{
@@ -108,7 +186,165 @@ NOTE: If this is the first environment then chrooting and column output are the
 			cli.Output(nil, err)
 		}
 
+		auth, _ := cmd.Flags().GetString(AUTH_FLAG)
+		if err := validateAuth(auth); err != nil {
+			cli.Output(nil, err)
+		}
+
+		serviceAccount, _ := cmd.Flags().GetString(SERVICE_ACCOUNT_FLAG)
+		if serviceAccount != "" && auth != cliconfig.AuthDCOS {
+			cli.Output(nil, errors.New("--service-account requires --auth dcos"))
+		}
+
+		proxy := &httpclient.ProxyConfig{}
+		updateProxy(cmd, proxy)
+		certs := &httpclient.TLSConfig{}
+		updateTLS(cmd, certs)
+		timeouts := &httpclient.Timeouts{}
+		updateTimeouts(cmd, timeouts)
+
+		service := &cliconfig.ServiceConfig{Auth: auth}
+		if err := updateLogin(cmd, service); err != nil {
+			cli.Output(nil, err)
+		}
+
 		configFile.AddMarathonEnvironment(name, url, user, pass)
+		readonly, _ := cmd.Flags().GetBool(READONLY_FLAG)
+		rateLimit := rateLimitFlag(cmd)
+		compress, _ := cmd.Flags().GetBool(COMPRESS_FLAG)
+		headers := updateHeaders(cmd, nil)
+		metronome, _ := cmd.Flags().GetString(METRONOME_FLAG)
+		chronos, _ := cmd.Flags().GetString(CHRONOS_FLAG)
+		mesos, _ := cmd.Flags().GetString(MESOS_FLAG)
+		marathonLB, _ := cmd.Flags().GetString(MARATHON_LB_FLAG)
+		for _, service := range []string{metronome, chronos, mesos, marathonLB} {
+			if service == "" {
+				continue
+			}
+			if err := cliconfig.ValidateMarathonURL(service); err != nil {
+				cli.Output(nil, err)
+			}
+		}
+
+		if auth == cliconfig.AuthDCOS || service.UsesLogin() || !proxy.IsEmpty() || !certs.IsEmpty() || !timeouts.IsEmpty() || readonly || rateLimit > 0 || compress || len(headers) > 0 || metronome != "" || chronos != "" || mesos != "" || marathonLB != "" {
+			if auth == cliconfig.AuthDCOS {
+				configFile.Environments[name].Marathon.Auth = auth
+				configFile.Environments[name].Marathon.ServiceAccount = serviceAccount
+			}
+			if service.UsesLogin() {
+				configFile.Environments[name].Marathon.Auth = auth
+				configFile.Environments[name].Marathon.Login = service.Login
+			}
+			if !proxy.IsEmpty() {
+				configFile.Environments[name].Marathon.Proxy = proxy
+			}
+			if !certs.IsEmpty() {
+				configFile.Environments[name].Marathon.TLS = certs
+			}
+			if !timeouts.IsEmpty() {
+				configFile.Environments[name].Marathon.Timeouts = timeouts
+			}
+			configFile.Environments[name].Marathon.ReadOnly = readonly
+			configFile.Environments[name].Marathon.RateLimit = rateLimit
+			configFile.Environments[name].Marathon.Compress = compress
+			configFile.Environments[name].Marathon.Headers = headers
+			configFile.Environments[name].Marathon.MetronomeUrl = metronome
+			configFile.Environments[name].Marathon.ChronosUrl = chronos
+			configFile.Environments[name].Marathon.MesosUrl = mesos
+			configFile.Environments[name].Marathon.MarathonLBUrl = marathonLB
+			configFile.Save()
+		}
+		fmt.Printf("\nEnvironment: %s - was added successfully\n", name)
+	},
+}
+
+var configAddECSCmd = &cobra.Command{
+	Use:   "add-ecs [name]",
+	Short: "Adds a new Amazon ECS environment using flags",
+	Long: `Adds a new environment for an Amazon ECS cluster with given name.  The ecs commands operate on the cluster
+and the marathon commands are unavailable within the environment.  Name argument only accepts: ^[a-zA-Z0-9_-]*$
+
+Credentials are read from $AWS_ACCESS_KEY_ID/$AWS_SECRET_ACCESS_KEY or the shared credentials file (~/.aws/credentials)`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		name := args[0]
+
+		if name == "" || !cliconfig.RegExAlphaNumDash.MatchString(name) {
+			cli.Output(nil, fmt.Errorf("'%s' must contain valid characters within %s\n", name, cliconfig.AlphaNumDash))
+		}
+
+		ecs := &cliconfig.ECSConfig{}
+		ecs.Cluster, _ = cmd.Flags().GetString(CLUSTER_FLAG)
+		ecs.Region, _ = cmd.Flags().GetString(REGION_FLAG)
+		ecs.Profile, _ = cmd.Flags().GetString(PROFILE_FLAG)
+		ecs.Endpoint, _ = cmd.Flags().GetString(ENDPOINT_FLAG)
+		ecs.ReadOnly, _ = cmd.Flags().GetBool(READONLY_FLAG)
+		if ecs.Cluster == "" {
+			cli.Output(nil, errors.New("--cluster must be specified"))
+		}
+
+		configFile.AddECSEnvironment(name, ecs)
+		fmt.Printf("\nEnvironment: %s - was added successfully\n", name)
+	},
+}
+
+var configAddNomadCmd = &cobra.Command{
+	Use:   "add-nomad [name]",
+	Short: "Adds a new HashiCorp Nomad environment using flags",
+	Long: `Adds a new environment for a Nomad cluster with given name.  The nomad commands operate on the cluster
+and the marathon commands are unavailable within the environment.  Name argument only accepts: ^[a-zA-Z0-9_-]*$
+
+The ACL token is read from $NOMAD_TOKEN`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		name := args[0]
+
+		if name == "" || !cliconfig.RegExAlphaNumDash.MatchString(name) {
+			cli.Output(nil, fmt.Errorf("'%s' must contain valid characters within %s\n", name, cliconfig.AlphaNumDash))
+		}
+
+		nomad := &cliconfig.NomadConfig{}
+		nomad.Address, _ = cmd.Flags().GetString(ADDRESS_FLAG)
+		nomad.Namespace, _ = cmd.Flags().GetString(NAMESPACE_FLAG)
+		nomad.Region, _ = cmd.Flags().GetString(REGION_FLAG)
+		nomad.ReadOnly, _ = cmd.Flags().GetBool(READONLY_FLAG)
+		if err := cliconfig.ValidateMarathonURL(nomad.Address); err != nil {
+			cli.Output(nil, err)
+		}
+
+		configFile.AddNomadEnvironment(name, nomad)
+		fmt.Printf("\nEnvironment: %s - was added successfully\n", name)
+	},
+}
+
+var configAddSwarmCmd = &cobra.Command{
+	Use:   "add-swarm [name]",
+	Short: "Adds a new Docker Swarm environment using flags",
+	Long: `Adds a new environment for a Docker Swarm with given name.  The swarm commands operate on the swarm
+and the marathon commands are unavailable within the environment.  Name argument only accepts: ^[a-zA-Z0-9_-]*$`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		name := args[0]
+
+		if name == "" || !cliconfig.RegExAlphaNumDash.MatchString(name) {
+			cli.Output(nil, fmt.Errorf("'%s' must contain valid characters within %s\n", name, cliconfig.AlphaNumDash))
+		}
+
+		swarm := &cliconfig.SwarmConfig{TLS: &httpclient.TLSConfig{}}
+		swarm.Host, _ = cmd.Flags().GetString(HOST_FLAG)
+		swarm.ReadOnly, _ = cmd.Flags().GetBool(READONLY_FLAG)
+		updateTLS(cmd, swarm.TLS)
+		if swarm.TLS.IsEmpty() {
+			swarm.TLS = nil
+		}
+
+		configFile.AddSwarmEnvironment(name, swarm)
 		fmt.Printf("\nEnvironment: %s - was added successfully\n", name)
 	},
 }
@@ -126,11 +362,31 @@ var configUpdateCmd = &cobra.Command{
 		if err != nil {
 			cli.Output(nil, err)
 		}
+		if ce.Marathon == nil {
+			cli.Output(nil, fmt.Errorf("'%s' is not a Marathon environment", args[0]))
+		}
 
 		url, _ := cmd.Flags().GetString(URL_FLAG)
 		user, _ := cmd.Flags().GetString(USER_FLAG)
 		pass, _ := cmd.Flags().GetString(PASSWORD_FLAG)
 
+		if cmd.Flags().Changed(AUTH_FLAG) {
+			auth, _ := cmd.Flags().GetString(AUTH_FLAG)
+			if err := validateAuth(auth); err != nil {
+				cli.Output(nil, err)
+			}
+			ce.Marathon.Auth = auth
+			if auth == cliconfig.AuthBasic {
+				ce.Marathon.Auth = ""
+			}
+		}
+		if err := updateLogin(cmd, ce.Marathon); err != nil {
+			cli.Output(nil, err)
+		}
+		if !ce.Marathon.UsesLogin() {
+			ce.Marathon.Login = nil
+		}
+
 		if url != "" {
 			if err := cliconfig.ValidateMarathonURL(url); err != nil {
 				cli.Output(nil, err)
@@ -140,9 +396,78 @@ var configUpdateCmd = &cobra.Command{
 		if user != "" {
 			ce.Marathon.Username = user
 		}
+		if cmd.Flags().Changed(SERVICE_ACCOUNT_FLAG) {
+			ce.Marathon.ServiceAccount, _ = cmd.Flags().GetString(SERVICE_ACCOUNT_FLAG)
+			if ce.Marathon.ServiceAccount != "" && !ce.Marathon.IsDCOS() {
+				cli.Output(nil, errors.New("--service-account requires --auth dcos"))
+			}
+		}
 		if pass != "" {
 			ce.Marathon.Password = pass
 		}
+		if ce.Marathon.Proxy == nil {
+			ce.Marathon.Proxy = &httpclient.ProxyConfig{}
+		}
+		updateProxy(cmd, ce.Marathon.Proxy)
+		if ce.Marathon.Proxy.IsEmpty() {
+			ce.Marathon.Proxy = nil
+		}
+		if cmd.Flags().Changed(READONLY_FLAG) {
+			ce.Marathon.ReadOnly, _ = cmd.Flags().GetBool(READONLY_FLAG)
+		}
+		if cmd.Flags().Changed(RATE_LIMIT_FLAG) {
+			ce.Marathon.RateLimit = rateLimitFlag(cmd)
+		}
+		if cmd.Flags().Changed(COMPRESS_FLAG) {
+			ce.Marathon.Compress, _ = cmd.Flags().GetBool(COMPRESS_FLAG)
+		}
+		ce.Marathon.Headers = updateHeaders(cmd, ce.Marathon.Headers)
+		if cmd.Flags().Changed(METRONOME_FLAG) {
+			ce.Marathon.MetronomeUrl, _ = cmd.Flags().GetString(METRONOME_FLAG)
+			if ce.Marathon.MetronomeUrl != "" {
+				if err := cliconfig.ValidateMarathonURL(ce.Marathon.MetronomeUrl); err != nil {
+					cli.Output(nil, err)
+				}
+			}
+		}
+		if cmd.Flags().Changed(CHRONOS_FLAG) {
+			ce.Marathon.ChronosUrl, _ = cmd.Flags().GetString(CHRONOS_FLAG)
+			if ce.Marathon.ChronosUrl != "" {
+				if err := cliconfig.ValidateMarathonURL(ce.Marathon.ChronosUrl); err != nil {
+					cli.Output(nil, err)
+				}
+			}
+		}
+		if cmd.Flags().Changed(MESOS_FLAG) {
+			ce.Marathon.MesosUrl, _ = cmd.Flags().GetString(MESOS_FLAG)
+			if ce.Marathon.MesosUrl != "" {
+				if err := cliconfig.ValidateMarathonURL(ce.Marathon.MesosUrl); err != nil {
+					cli.Output(nil, err)
+				}
+			}
+		}
+		if cmd.Flags().Changed(MARATHON_LB_FLAG) {
+			ce.Marathon.MarathonLBUrl, _ = cmd.Flags().GetString(MARATHON_LB_FLAG)
+			if ce.Marathon.MarathonLBUrl != "" {
+				if err := cliconfig.ValidateMarathonURL(ce.Marathon.MarathonLBUrl); err != nil {
+					cli.Output(nil, err)
+				}
+			}
+		}
+		if ce.Marathon.TLS == nil {
+			ce.Marathon.TLS = &httpclient.TLSConfig{}
+		}
+		updateTLS(cmd, ce.Marathon.TLS)
+		if ce.Marathon.TLS.IsEmpty() {
+			ce.Marathon.TLS = nil
+		}
+		if ce.Marathon.Timeouts == nil {
+			ce.Marathon.Timeouts = &httpclient.Timeouts{}
+		}
+		updateTimeouts(cmd, ce.Marathon.Timeouts)
+		if ce.Marathon.Timeouts.IsEmpty() {
+			ce.Marathon.Timeouts = nil
+		}
 		if err := configFile.Save(); err != nil {
 			cli.Output(nil, err)
 		}
@@ -161,6 +486,193 @@ var configListCmd = &cobra.Command{
 	},
 }
 
+var configGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Environment groups allow a single -e [group] to run a command against each member environment",
+	Long: `Manage environment groups (eg. prod = [prod-us, prod-eu]).  Specifying a group with -e runs the command
+against each member sequentially (or concurrently with --parallel) followed by a per-environment result table
+
+See group's subcommands for available choices`,
+}
+
+var configGroupAddCmd = &cobra.Command{
+	Use:   "add [name] [environments...]",
+	Short: "Adds (or replaces) a group [name] containing the specified environments",
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+			return
+		}
+		name := args[0]
+		if !cliconfig.RegExAlphaNumDash.MatchString(name) {
+			cli.Output(nil, fmt.Errorf("'%s' must contain valid characters within %s\n", name, cliconfig.AlphaNumDash))
+		}
+		if err := configFile.AddGroup(name, args[1:]); err != nil {
+			cli.Output(nil, err)
+		}
+		fmt.Printf("\nGroup: %s - was added successfully\n", name)
+	},
+}
+
+var configGroupRemoveCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Removes the group [name].  Member environments are not removed",
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		if err := configFile.RemoveGroup(args[0]); err != nil {
+			cli.Output(nil, err)
+		}
+	},
+}
+
+var configGroupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List current environment groups",
+	Run: func(cmd *cobra.Command, args []string) {
+		groups := []*GroupSummary{}
+		for _, name := range configFile.GetGroups() {
+			members, _ := configFile.GetGroup(name)
+			groups = append(groups, &GroupSummary{Name: name, Members: strings.Join(members, ", ")})
+		}
+		cli.Output(templateFor(T_CONFIG_GROUPS, groups), nil)
+	},
+}
+
+var configFormatCmd = &cobra.Command{
+	Use:   "format",
+	Short: "Named output formats referenced by --format @name in place of a template",
+	Long: `Manage named output formats (Go templates) so long templates aren't repeated on every invocation.  A format
+is referenced with --format @name by any command accepting --format
+
+Examples:
+    depcon config format add images '{{range .Apps}}{{ .ID }} {{ .Container.Docker.Image }}{{"\n"}}{{end}}'
+    depcon app list --format @images
+
+See format's subcommands for available choices`,
+}
+
+var configFormatAddCmd = &cobra.Command{
+	Use:   "add [name] [template]",
+	Short: "Adds (or replaces) the format [name] rendering [template]",
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+			return
+		}
+		name := strings.TrimPrefix(args[0], cliconfig.FormatPrefix)
+		if !cliconfig.RegExAlphaNumDash.MatchString(name) {
+			cli.Output(nil, fmt.Errorf("'%s' must contain valid characters within %s\n", name, cliconfig.AlphaNumDash))
+		}
+		if err := configFile.AddFormat(name, args[1]); err != nil {
+			cli.Output(nil, err)
+		}
+		fmt.Printf("\nFormat: @%s - was added successfully\n", name)
+	},
+}
+
+var configFormatRemoveCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Removes the format [name]",
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		if err := configFile.RemoveFormat(strings.TrimPrefix(args[0], cliconfig.FormatPrefix)); err != nil {
+			cli.Output(nil, err)
+		}
+	},
+}
+
+var configFormatListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the named output formats",
+	Run: func(cmd *cobra.Command, args []string) {
+		formats := []*FormatSummary{}
+		escape := strings.NewReplacer("\n", `\n`, "\t", `\t`)
+		for _, name := range configFile.GetFormats() {
+			formats = append(formats, &FormatSummary{Name: name, Template: escape.Replace(configFile.Formats[name])})
+		}
+		cli.Output(templateFor(T_CONFIG_FORMATS, formats), nil)
+	},
+}
+
+var configFlagsCmd = &cobra.Command{
+	Use:   "flags [name] [flag=value ...]",
+	Short: "Lists or sets default flag values applied to commands run against environment [name]",
+	Long: `Default flags are applied to every command run against the environment which defines the flag unless
+the flag is specified on the command line.  An empty value removes the default
+
+Examples:
+    depcon config env flags prod wait=true timeout=5m
+    depcon config env flags dev force=true
+    depcon config env flags prod timeout=`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		ce, err := configFile.GetEnvironment(args[0])
+		if err != nil {
+			cli.Output(nil, err)
+		}
+
+		if len(args) == 1 {
+			flags := []*FlagSummary{}
+			for name, value := range ce.Flags {
+				flags = append(flags, &FlagSummary{Name: name, Value: value})
+			}
+			sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+			cli.Output(templateFor(T_CONFIG_FLAGS, flags), nil)
+			return
+		}
+
+		for _, kv := range args[1:] {
+			pair := strings.SplitN(kv, "=", 2)
+			if len(pair) != 2 || pair[0] == "" {
+				cli.Output(nil, fmt.Errorf("Invalid flag '%s', must be in the form flag=value", kv))
+				return
+			}
+			name := strings.TrimLeft(pair[0], "-")
+			if pair[1] == "" {
+				delete(ce.Flags, name)
+				continue
+			}
+			if ce.Flags == nil {
+				ce.Flags = make(map[string]string)
+			}
+			ce.Flags[name] = pair[1]
+		}
+		if err := configFile.Save(); err != nil {
+			cli.Output(nil, err)
+		}
+		fmt.Printf("\nEnvironment: %s - default flags were updated\n", args[0])
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Checks the configuration file for errors",
+	Long: `Checks the configuration file for syntax errors, unknown, duplicate and deprecated keys, invalid settings
+and unreachable hosts.  Exits with a non-zero status if any errors are found`,
+	Run: func(cmd *cobra.Command, args []string) {
+		offline, _ := cmd.Flags().GetBool(OFFLINE_FLAG)
+		filename := filepath.Join(cliconfig.ConfigDir(), cliconfig.ConfigFileName)
+
+		issues, err := cliconfig.ValidateFile(filename, !offline)
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		if len(issues) == 0 {
+			fmt.Printf("\n%s is valid\n\n", filename)
+			return
+		}
+		cli.Output(templateFor(T_CONFIG_ISSUES, issues), nil)
+		if cliconfig.HasErrors(issues) {
+			cli.ExitWithCode(cli.ExitError)
+		}
+	},
+}
+
 var configDefaultCmd = &cobra.Command{
 	Use:   "default [name]",
 	Short: "Sets the default environment [name] to use (eg. -e envname can be eliminated when set and using default)",
@@ -235,17 +747,363 @@ var configRenameCmd = &cobra.Command{
 	},
 }
 
+var configExportCmd = &cobra.Command{
+	Use:   "export (names...)",
+	Short: "Exports environments (all if no names are specified) into an encrypted file which can be shared and imported",
+	Run: func(cmd *cobra.Command, args []string) {
+		out, _ := cmd.Flags().GetString(OUT_FLAG)
+		if out == "" {
+			cli.Output(nil, fmt.Errorf("--%s is required", OUT_FLAG))
+			return
+		}
+
+		// the password is checked before the file is created so a mismatch leaves an existing file intact
+		pass, err := exportPassword(cmd, true)
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+
+		f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		defer f.Close()
+
+		if err := configFile.ExportEnvironments(f, args, pass); err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		fmt.Printf("\nEnvironments have been exported to %s\n\n", out)
+	},
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Imports environments from a file created by 'config export' or from another tool (--from)",
+	Long: `Imports environments from a file created by 'config export'.
+
+With --from the environments are read from another tool's configuration.  When [file] is omitted the
+tool's default location is used:
+    dcos         ~/.dcos (or DCOS_DIR) - every configured cluster is imported
+    marathonctl  ~/.config/marathonctl/config`,
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+
+		if from, _ := cmd.Flags().GetString(FROM_FLAG); from != "" {
+			importFromTool(from, args, force)
+			return
+		}
+
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+
+		f, err := os.Open(args[0])
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		defer f.Close()
+
+		pass, err := exportPassword(cmd, false)
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		imported, err := configFile.ImportEnvironments(f, pass, force)
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		printImported(imported)
+	},
+}
+
+func importFromTool(from string, args []string, force bool) {
+	var (
+		envs []*cliconfig.ExternalEnvironment
+		err  error
+	)
+	switch from {
+	case cliconfig.ImportFromDCOS:
+		dir := cliconfig.DefaultDCOSDir()
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		envs, err = cliconfig.ReadDCOSConfig(dir)
+	case cliconfig.ImportFromMarathonctl:
+		file := cliconfig.DefaultMarathonctlConfig()
+		if len(args) > 0 {
+			file = args[0]
+		}
+		envs, err = cliconfig.ReadMarathonctlConfig(file)
+	default:
+		err = fmt.Errorf("Invalid --%s '%s'. Must be '%s' or '%s'", FROM_FLAG, from, cliconfig.ImportFromDCOS, cliconfig.ImportFromMarathonctl)
+	}
+	if err != nil {
+		cli.Output(nil, err)
+		return
+	}
+
+	imported, err := configFile.AddExternalEnvironments(envs, force)
+	if err != nil {
+		cli.Output(nil, err)
+		return
+	}
+	printImported(imported)
+}
+
+func printImported(imported []string) {
+	if len(imported) == 0 {
+		fmt.Printf("\nNo environments were imported. Use --%s to overwrite existing environments\n\n", FORCE_FLAG)
+		return
+	}
+	fmt.Printf("\nImported environments: %s\n\n", strings.Join(imported, ", "))
+}
+
+var configKeyringCmd = &cobra.Command{
+	Use:   "keyring",
+	Short: "Manage storage of environment passwords within the OS keyring",
+}
+
+var configKeyringMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Moves passwords stored in the config file into the OS keyring",
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cliconfig.KeyringEnabled() {
+			cli.Output(nil, errors.New("The OS keyring has been disabled (--no-keyring)"))
+			return
+		}
+		migrated, err := configFile.MigrateToKeyring()
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		fmt.Printf("\nPasswords moved into the OS keyring: %s\n\n", strings.Join(migrated, ", "))
+	},
+}
+
+var configKeyringDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Moves passwords stored in the OS keyring back into the config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		migrated, err := configFile.MigrateFromKeyring()
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		fmt.Printf("\nPasswords moved into the config file: %s\nUse --no-keyring (or %s=1) to prevent them from being moved back on the next change\n\n",
+			strings.Join(migrated, ", "), cliconfig.EnvNoKeyring)
+	},
+}
+
+func validateAuth(auth string) error {
+	if !cliconfig.ValidAuth(auth) {
+		return fmt.Errorf("Invalid auth '%s'. Must be one of %s", auth, strings.Join(cliconfig.AuthModes, ", "))
+	}
+	return nil
+}
+
+// Updates the identity provider of {service} with any login flags which were specified returning an error
+// when the provider lacks a setting required by its auth mode
+func updateLogin(cmd *cobra.Command, service *cliconfig.ServiceConfig) error {
+	if !service.UsesLogin() {
+		return nil
+	}
+	if service.Login == nil {
+		service.Login = &login.Config{}
+	}
+	for flag, field := range map[string]*string{ISSUER_FLAG: &service.Login.Issuer, CLIENT_ID_FLAG: &service.Login.ClientID, LOGIN_URL_FLAG: &service.Login.URL} {
+		if cmd.Flags().Changed(flag) {
+			*field, _ = cmd.Flags().GetString(flag)
+		}
+	}
+	return service.Login.Validate(service.Auth)
+}
+
+// Updates {proxy} with any proxy flags which were specified.  An empty value removes the setting
+func updateProxy(cmd *cobra.Command, proxy *httpclient.ProxyConfig) {
+	for flag, field := range map[string]*string{PROXY_FLAG: &proxy.HTTP, HTTPS_PROXY_FLAG: &proxy.HTTPS, NO_PROXY_FLAG: &proxy.NoProxy} {
+		if !cmd.Flags().Changed(flag) {
+			continue
+		}
+		value, _ := cmd.Flags().GetString(flag)
+		if value != "" && flag != NO_PROXY_FLAG {
+			if err := httpclient.ValidateProxyURL(value); err != nil {
+				cli.Output(nil, fmt.Errorf("--%s %s", flag, err.Error()))
+			}
+		}
+		*field = value
+	}
+}
+
+// Updates {certs} with any certificate flags which were specified and verifies the certificates can be
+// loaded.  An empty value removes the setting
+func updateTLS(cmd *cobra.Command, certs *httpclient.TLSConfig) {
+	changed := false
+	for flag, field := range map[string]*string{CERT_FLAG: &certs.CertFile, KEY_FLAG: &certs.KeyFile, CA_FLAG: &certs.CAFile} {
+		if cmd.Flags().Changed(flag) {
+			*field, _ = cmd.Flags().GetString(flag)
+			if *field != "" {
+				// commands may be run from any directory
+				*field, _ = filepath.Abs(*field)
+			}
+			changed = true
+		}
+	}
+	if cmd.Flags().Changed(INSECURE_VERIFY_FLAG) {
+		certs.InsecureSkipVerify, _ = cmd.Flags().GetBool(INSECURE_VERIFY_FLAG)
+		if certs.InsecureSkipVerify {
+			log.Warning("!!! TLS certificate verification is DISABLED for this environment - prefer --%s with the cluster's CA bundle", CA_FLAG)
+		}
+	}
+	if changed {
+		if _, err := certs.Load(false); err != nil {
+			cli.Output(nil, err)
+		}
+	}
+}
+
+// Updates {timeouts} with any timeout flags which were specified.  A zero value removes the setting
+func updateTimeouts(cmd *cobra.Command, timeouts *httpclient.Timeouts) {
+	fields := map[string]*httpclient.Duration{CONNECT_TIMEOUT_FLAG: &timeouts.Connect, TLS_TIMEOUT_FLAG: &timeouts.TLSHandshake,
+		HEADER_TIMEOUT_FLAG: &timeouts.ResponseHeader, REQUEST_TIMEOUT_FLAG: &timeouts.Request}
+	for flag, field := range fields {
+		if cmd.Flags().Changed(flag) {
+			value, _ := cmd.Flags().GetDuration(flag)
+			*field = httpclient.Duration(value)
+		}
+	}
+}
+
+// Applies the --header flags (Name=Value) to {headers} returning the updated headers or nil when none
+// remain.  An empty value removes the header
+func updateHeaders(cmd *cobra.Command, headers map[string]string) map[string]string {
+	values, _ := cmd.Flags().GetStringArray(HEADER_FLAG)
+	for _, h := range values {
+		kv := strings.SplitN(h, "=", 2)
+		name := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || !cliconfig.ValidHeaderName(name) {
+			cli.Output(nil, fmt.Errorf("Invalid header '%s' - must be Name=Value", h))
+		}
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		if value := strings.TrimSpace(kv[1]); value != "" {
+			headers[name] = value
+		} else {
+			delete(headers, name)
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+// Returns the --rate-limit flag exiting if it is negative
+func rateLimitFlag(cmd *cobra.Command) float64 {
+	rateLimit, _ := cmd.Flags().GetFloat64(RATE_LIMIT_FLAG)
+	if rateLimit < 0 {
+		cli.Output(nil, errors.New("--rate-limit must not be negative"))
+	}
+	return rateLimit
+}
+
+// Returns the password flag or prompts for it when not specified.  A password which is empty or, when
+// {verify}, doesn't match its verification is an error
+func exportPassword(cmd *cobra.Command, verify bool) (string, error) {
+	if pass, _ := cmd.Flags().GetString(EXPORT_PASSWORD_FLAG); pass != "" {
+		return pass, nil
+	}
+	pass, _ := speakeasy.Ask("Export Password: ")
+	if pass == "" {
+		return "", cliconfig.ErrExportPassword
+	}
+	if verify {
+		if again, _ := speakeasy.Ask("Verify Password: "); again != pass {
+			return "", errors.New("Password and Verify Password don't match")
+		}
+	}
+	return pass, nil
+}
+
 func init() {
-	configAddMarathonCmd.Flags().String(URL_FLAG, "http://localhost:8080", "Marathon URL (eg. http://host:port)")
+	configAddMarathonCmd.Flags().String(URL_FLAG, "http://localhost:8080", `Marathon URL (eg. http://host:port).  Separate multiple URLs with commas for an HA cluster.
+                  unix:///path/to.sock and ssh+http://[user@]bastion/host:port tunnels are also supported`)
 	configAddMarathonCmd.Flags().String(USER_FLAG, "", "Optional: username if authentication is enabled")
 	configAddMarathonCmd.Flags().String(PASSWORD_FLAG, "", "Optional: password if authentication is enabled")
 
-	configUpdateCmd.Flags().String(URL_FLAG, "", "Marathon URL (eg. http://host:port)")
+	configAddMarathonCmd.Flags().String(AUTH_FLAG, cliconfig.AuthBasic, `Authentication [ basic | dcos | oidc | ldap ].  With dcos the url is the DC/OS cluster URL, a token is
+                  obtained via the ACS login endpoint (refreshed automatically) and Marathon is accessed via /service/marathon.
+                  With oidc and ldap the token of 'depcon login' is sent to an identity-aware proxy`)
+	configAddMarathonCmd.Flags().String(SERVICE_ACCOUNT_FLAG, "", `Optional: DC/OS service account secret (or PEM private key) file.  Logs in as the service account
+                  instead of using a password.  --user is the service account uid when a PEM key is used`)
+
+	for _, c := range []*cobra.Command{configAddMarathonCmd, configUpdateCmd} {
+		c.Flags().String(PROXY_FLAG, "", "Optional: proxy for this environment (eg. http://proxy:3128 or socks5://proxy:1080).  Overrides HTTP_PROXY")
+		c.Flags().String(HTTPS_PROXY_FLAG, "", "Optional: proxy for https requests.  Defaults to --proxy")
+		c.Flags().String(NO_PROXY_FLAG, "", "Optional: comma separated hosts, domains (.example.com) or CIDRs which bypass the proxy")
+		c.Flags().String(CERT_FLAG, "", "Optional: PEM client certificate for clusters requiring mutual TLS")
+		c.Flags().String(KEY_FLAG, "", "Optional: PEM client private key for --cert")
+		c.Flags().Bool(READONLY_FLAG, false, "Refuses commands which modify the cluster unless --allow-write is specified (--readonly=false to clear)")
+		c.Flags().String(CA_FLAG, "", "Optional: PEM CA bundle used to verify the cluster in place of the system roots")
+		c.Flags().Bool(INSECURE_VERIFY_FLAG, false, "Skips TLS certificate checks for this environment only (--insecure-skip-verify=false to clear).  Prefer --ca")
+		c.Flags().Duration(CONNECT_TIMEOUT_FLAG, 0, "Optional: timeout establishing a connection (default 30s)")
+		c.Flags().Duration(TLS_TIMEOUT_FLAG, 0, "Optional: timeout for the TLS handshake (default 10s)")
+		c.Flags().Duration(HEADER_TIMEOUT_FLAG, 0, "Optional: timeout waiting for response headers after the request is sent (default none)")
+		c.Flags().Duration(REQUEST_TIMEOUT_FLAG, 0, "Optional: overall timeout for each request including the body (default 30s, negative disables)")
+		c.Flags().Float64(RATE_LIMIT_FLAG, 0, "Optional: maximum requests per second sent to the environment during bulk operations (0 is unlimited)")
+		c.Flags().Bool(COMPRESS_FLAG, false, "Compresses request bodies of 8KB or more with gzip (--compress=false to clear)")
+		c.Flags().StringArray(HEADER_FLAG, []string{}, "Optional: header sent with every request as Name=Value (eg. X-Tenant=payments).  May be repeated, an empty value removes the header")
+		c.Flags().String(METRONOME_FLAG, "", "Optional: Metronome URL used by the job commands (default /service/metronome of DC/OS clusters)")
+		c.Flags().String(CHRONOS_FLAG, "", "Optional: Chronos URL used by the chronos commands (default /service/chronos of DC/OS clusters)")
+		c.Flags().String(MESOS_FLAG, "", "Optional: Mesos master URL used by the mesos commands (default /mesos of DC/OS clusters or port 5050 of the Marathon host)")
+		c.Flags().String(MARATHON_LB_FLAG, "", "Optional: Marathon-LB admin URL used by the lb and bluegreen commands (eg. http://lb.example.com:9090)")
+		c.Flags().String(ISSUER_FLAG, "", "OIDC issuer URL of --auth oidc (eg. https://login.example.com/realms/ops)")
+		c.Flags().String(CLIENT_ID_FLAG, "", "OIDC client id of --auth oidc allowing the device code grant")
+		c.Flags().String(LOGIN_URL_FLAG, "", "URL LDAP credentials are exchanged for a token at with --auth ldap")
+	}
+
+	configUpdateCmd.Flags().String(URL_FLAG, "", `Marathon URL (eg. http://host:port).  Separate multiple URLs with commas for an HA cluster.
+                  unix:///path/to.sock and ssh+http://[user@]bastion/host:port tunnels are also supported`)
 	configUpdateCmd.Flags().String(USER_FLAG, "", "Optional: username if authentication is enabled")
 	configUpdateCmd.Flags().String(PASSWORD_FLAG, "", "Optional: password if authentication is enabled")
+	configUpdateCmd.Flags().String(AUTH_FLAG, cliconfig.AuthBasic, "Authentication [ basic | dcos | oidc | ldap ]")
+	configUpdateCmd.Flags().String(SERVICE_ACCOUNT_FLAG, "", "DC/OS service account secret (or PEM private key) file.  Empty removes it")
+
+	configAddECSCmd.Flags().String(CLUSTER_FLAG, "", "Name or ARN of the ECS cluster")
+	configAddECSCmd.Flags().String(REGION_FLAG, "", "Optional: AWS region of the cluster (default $AWS_REGION)")
+	configAddECSCmd.Flags().String(PROFILE_FLAG, "", "Optional: named profile within the shared credentials file (default $AWS_PROFILE)")
+	configAddECSCmd.Flags().String(ENDPOINT_FLAG, "", "Optional: endpoint used in place of the regional endpoint (eg. a VPC endpoint)")
+	configAddECSCmd.Flags().Bool(READONLY_FLAG, false, "Refuses commands which modify the cluster unless --allow-write is specified")
+
+	configAddNomadCmd.Flags().String(ADDRESS_FLAG, "http://127.0.0.1:4646", "Nomad address (eg. https://nomad:4646)")
+	configAddNomadCmd.Flags().String(NAMESPACE_FLAG, "", "Optional: namespace of the jobs (default $NOMAD_NAMESPACE or the agent's default)")
+	configAddNomadCmd.Flags().String(REGION_FLAG, "", "Optional: region of the jobs (default $NOMAD_REGION or the agent's region)")
+	configAddNomadCmd.Flags().Bool(READONLY_FLAG, false, "Refuses commands which modify the cluster unless --allow-write is specified")
+
+	configAddSwarmCmd.Flags().String(HOST_FLAG, "", "Docker engine of a swarm manager (eg. tcp://manager:2376 | unix:///var/run/docker.sock).  Defaults to $DOCKER_HOST")
+	configAddSwarmCmd.Flags().String(CERT_FLAG, "", "Optional: PEM client certificate for engines requiring mutual TLS")
+	configAddSwarmCmd.Flags().String(KEY_FLAG, "", "Optional: PEM client private key for --cert")
+	configAddSwarmCmd.Flags().String(CA_FLAG, "", "Optional: PEM CA bundle used to verify the engine")
+	configAddSwarmCmd.Flags().Bool(READONLY_FLAG, false, "Refuses commands which modify the swarm unless --allow-write is specified")
+
+	configValidateCmd.Flags().Bool(OFFLINE_FLAG, false, "Skips connecting to each environment's host")
+
+	configExportCmd.Flags().String(OUT_FLAG, "", "File to write the encrypted environments to")
+	configExportCmd.Flags().String(EXPORT_PASSWORD_FLAG, "", "Password used to encrypt the environments (prompted if omitted)")
+	configImportCmd.Flags().String(EXPORT_PASSWORD_FLAG, "", "Password used to decrypt the environments (prompted if omitted)")
+	configImportCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Overwrite environments which already exist")
+	configImportCmd.Flags().String(FROM_FLAG, "", "Imports from another tool's configuration [ dcos | marathonctl ]")
 
-	configEnvCmd.AddCommand(configAddCmd, configAddMarathonCmd, configListCmd, configDefaultCmd, configRenameCmd, configUpdateCmd, configRemoveCmd)
-	configCmd.AddCommand(configEnvCmd, configOutputCmd, configRootServiceCmd)
+	configEnvCmd.AddCommand(configAddCmd, configAddMarathonCmd, configAddECSCmd, configAddNomadCmd, configAddSwarmCmd, configListCmd, configDefaultCmd, configRenameCmd, configUpdateCmd, configRemoveCmd, configFlagsCmd, configVerifyCmd)
+	configKeyringCmd.AddCommand(configKeyringMigrateCmd, configKeyringDisableCmd)
+	configGroupCmd.AddCommand(configGroupAddCmd, configGroupRemoveCmd, configGroupListCmd)
+	configFormatCmd.AddCommand(configFormatAddCmd, configFormatRemoveCmd, configFormatListCmd)
+	configCmd.AddCommand(configEnvCmd, configGroupCmd, configFormatCmd, configValidateCmd, configOutputCmd, configRootServiceCmd, configExportCmd, configImportCmd, configKeyringCmd)
 }
 
 type ConfigTemplate struct {
@@ -273,6 +1131,12 @@ func (e ConfigEnvironments) toEnvironmentMap() []*EnvironmentSummary {
 		switch v.EnvironmentType() {
 		case cliconfig.TypeMarathon:
 			sc = *v.Marathon
+		case cliconfig.TypeECS:
+			sc.HostUrl = fmt.Sprintf("%s (%s)", v.ECS.Cluster, v.ECS.Region)
+		case cliconfig.TypeNomad:
+			sc.HostUrl = v.Nomad.Address
+		case cliconfig.TypeSwarm:
+			sc.HostUrl = v.Swarm.Host
 		}
 		arr = append(arr, &EnvironmentSummary{
 			Name:    k,
@@ -288,6 +1152,9 @@ func (e ConfigEnvironments) toEnvironmentMap() []*EnvironmentSummary {
 func buildFuncMap() template.FuncMap {
 	funcMap := template.FuncMap{
 		"defaultEnvToStr": defaultEnvToStr,
+		"warning": func(s string) string {
+			return cli.Colorize(cli.RoleWarning, s)
+		},
 	}
 	return funcMap
 }
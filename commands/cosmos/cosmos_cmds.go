@@ -0,0 +1,130 @@
+package cosmos
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/cosmos"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/dcos"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	ENV_NAME         string = "env_name"
+)
+
+var log = logger.GetLogger("depcon.cosmos")
+
+var (
+	ErrorNotDCOS = errors.New("Packages require a DC/OS environment.  Set the auth of the environment with 'depcon config env update [name] --auth dcos'")
+
+	packageCmd = &cobra.Command{
+		Use:   "package",
+		Short: "Install and uninstall DC/OS Universe packages",
+		Long: `Install and uninstall DC/OS Universe packages
+
+    Packages are resolved and rendered by the cluster's Cosmos (/package) and their Marathon
+    apps are deployed through depcon like any other app.  Cosmos and Marathon are reached with
+    the credentials of the environment's Marathon service
+
+    See package's subcommands for available choices`,
+	}
+	cosmosClient   cosmos.Cosmos
+	marathonClient marathon.Marathon
+	configFile     *cliconfig.ConfigFile
+)
+
+// Associates the package commands to the given command
+func AddCosmosToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
+	rc.AddCommand(packageCmd)
+}
+
+func init() {
+	packageCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	packageCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	packageCmd.AddCommand(packageListCmd, packageInstallCmd, packageUninstallCmd)
+}
+
+func client(cmd *cobra.Command) cosmos.Cosmos {
+	if cosmosClient == nil {
+		envName := viper.GetString(ENV_NAME)
+		service := environmentService(envName)
+		if !service.IsDCOS() {
+			exitWithError(ErrorNotDCOS)
+		}
+
+		insecure, _ := cmd.Flags().GetBool(INSECURE_FLAG)
+		mopts := &marathon.MarathonOptions{TLSAllowInsecure: insecure}
+		if _, err := cmdmarathon.ApplyConnection(envName, service, mopts); err != nil {
+			exitWithError(err)
+		}
+
+		opts := &cosmos.CosmosOptions{
+			TLSAllowInsecure: insecure,
+			Authenticator:    mopts.Authenticator,
+			Proxy:            mopts.Proxy,
+			TLS:              mopts.TLS,
+			Retry:            httpclient.DefaultRetryPolicy(),
+			Timeouts:         mopts.Timeouts,
+			Headers:          mopts.Headers,
+		}
+		host := dcos.CosmosURL(marathon.SplitHosts(service.HostUrl)[0])
+		cosmosClient = cosmos.NewCosmosClient(host, service.Username, service.Password, opts)
+	}
+	return cosmosClient
+}
+
+// Returns the Marathon client of the environment which deploys the apps of packages
+func marathonFor(cmd *cobra.Command) marathon.Marathon {
+	if marathonClient == nil {
+		envName := viper.GetString(ENV_NAME)
+		service := environmentService(envName)
+		insecure, _ := cmd.Flags().GetBool(INSECURE_FLAG)
+		allowWrite, _ := cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+
+		opts := &marathon.MarathonOptions{TLSAllowInsecure: insecure, ReadOnly: service.ReadOnly && !allowWrite}
+		opts.WaitTimeout, _ = cmd.Flags().GetDuration(TIMEOUT_FLAG)
+		if progress := cli.ActiveProgress(); progress != nil {
+			opts.Progress = progress
+		}
+		m, err := cmdmarathon.NewClient(envName, service, opts)
+		if err != nil {
+			exitWithError(err)
+		}
+		marathonClient = m
+	}
+	return marathonClient
+}
+
+// Returns the Marathon service of environment {envName} whose credentials are used for Cosmos
+func environmentService(envName string) *cliconfig.ServiceConfig {
+	env, err := configFile.GetEnvironment(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+	if env.Marathon == nil {
+		exitWithError(fmt.Errorf("Environment '%s' does not define a Marathon service", envName))
+	}
+	return env.Marathon
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
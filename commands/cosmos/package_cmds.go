@@ -0,0 +1,207 @@
+package cosmos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/cosmos"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	VERSION_FLAG string = "package-version"
+	OPTIONS_FLAG string = "options"
+	APP_ID_FLAG  string = "app-id"
+	WAIT_FLAG    string = "wait"
+	TIMEOUT_FLAG string = "wait-timeout"
+	FORCE_FLAG   string = "force"
+	RENDER_FLAG  string = "render"
+)
+
+var ErrorNotInstalled = errors.New("No apps of the package are installed")
+
+var (
+	packageListCmd = &cobra.Command{
+		Use:   "list [name]",
+		Short: "Lists the installed packages or the apps of a single package",
+		Run:   listPackages,
+	}
+
+	packageInstallCmd = &cobra.Command{
+		Use:   "install [name]",
+		Short: "Renders the Marathon app of a package and deploys it",
+		Long: `Renders the Marathon app of a package with the given options and deploys it
+
+    The options file is rendered with the template context and ${PARAMS} exactly as Marathon
+    descriptors are and is merged by Cosmos with the defaults of the package.  --dry-run
+    previews the rendered options while --render prints the Marathon app without deploying it
+
+    eg. depcon package install kafka --package-version 1.1.9 --options kafka.json -p BROKERS=5`,
+		Run: installPackage,
+	}
+
+	packageUninstallCmd = &cobra.Command{
+		Use:   "uninstall [name]",
+		Short: "Destroys the Marathon apps of an installed package",
+		Run:   uninstallPackage,
+	}
+)
+
+func init() {
+	packageInstallCmd.Flags().String(VERSION_FLAG, "", "Version of the package.  Default: latest")
+	packageInstallCmd.Flags().String(OPTIONS_FLAG, "", "Options file (.json | .yaml) of the package")
+	packageInstallCmd.Flags().Bool(FORCE_FLAG, false, "Updates the app when it's already installed")
+	packageInstallCmd.Flags().Bool(RENDER_FLAG, false, "Prints the rendered Marathon app and exits")
+	cmdmarathon.ApplyDescriptorFlags(packageInstallCmd)
+	for _, c := range []*cobra.Command{packageInstallCmd, packageUninstallCmd} {
+		c.Flags().String(APP_ID_FLAG, "", "Optional: app ID in place of the package's default (allows multiple installs)")
+		c.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the deployment to complete")
+		c.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the deployment (ex. 90s | 2m)")
+	}
+}
+
+// PackageApp is a Marathon app deployed from a package
+type PackageApp struct {
+	AppID     string
+	Name      string
+	Version   string
+	Framework bool
+	Instances int
+	Running   int
+}
+
+func listPackages(cmd *cobra.Command, args []string) {
+	filter := "label=" + cosmos.LabelPackageName
+	if len(args) > 0 {
+		filter = fmt.Sprintf("%s==%s", filter, args[0])
+	}
+	apps, err := marathonFor(cmd).ListApplicationsWithFilters(filter)
+	if err != nil {
+		exitWithError(err)
+	}
+	results := []*PackageApp{}
+	for _, app := range apps.Apps {
+		results = append(results, &PackageApp{
+			AppID:     app.ID,
+			Name:      app.Labels[cosmos.LabelPackageName],
+			Version:   app.Labels[cosmos.LabelPackageVersion],
+			Framework: app.Labels[cosmos.LabelFrameworkName] != "",
+			Instances: app.Instances,
+			Running:   app.TasksRunning,
+		})
+	}
+	cli.Output(templateFor(T_PACKAGES, results), nil)
+}
+
+func installPackage(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	options := map[string]interface{}{}
+	if file, _ := cmd.Flags().GetString(OPTIONS_FLAG); file != "" {
+		docs := cmdmarathon.RenderDocuments(cmd, file)
+		if len(docs) != 1 {
+			exitWithError(fmt.Errorf("%s must contain a single document of options", file))
+		}
+		options = docs[0]
+	}
+
+	version, _ := cmd.Flags().GetString(VERSION_FLAG)
+	pkg, err := client(cmd).Describe(args[0], version)
+	if err != nil {
+		exitWithError(err)
+	}
+	appID, _ := cmd.Flags().GetString(APP_ID_FLAG)
+	doc, err := client(cmd).Render(pkg.Name, pkg.Version, options, appID)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if render, _ := cmd.Flags().GetBool(RENDER_FLAG); render {
+		enc, _ := encoding.NewEncoder(encoding.JSON)
+		out, err := enc.MarshalIndent(doc)
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Println(out)
+		return
+	}
+
+	app := &marathon.Application{}
+	if b, err := json.Marshal(doc); err != nil || json.Unmarshal(b, app) != nil {
+		exitWithError(fmt.Errorf("The app rendered for package '%s' is not a valid Marathon app", pkg.Name))
+	}
+	if app.Labels == nil {
+		app.Labels = map[string]string{}
+	}
+	app.Labels[cosmos.LabelPackageName] = pkg.Name
+	app.Labels[cosmos.LabelPackageVersion] = pkg.Version
+
+	if pkg.PreInstallNotes != "" {
+		fmt.Printf("%s\n\n", pkg.PreInstallNotes)
+	}
+	cli.ConfirmOrExit(fmt.Sprintf("Install package '%s' version %s as %s", pkg.Name, pkg.Version, app.ID), "environment", viper.GetString(ENV_NAME))
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+	if _, err := marathonFor(cmd).CreateApplication(app, wait, force); err != nil {
+		exitWithError(err)
+	}
+	log.Info("Package '%s' version %s was installed as %s", pkg.Name, pkg.Version, app.ID)
+	if pkg.PostInstallNotes != "" {
+		fmt.Printf("\n%s\n", pkg.PostInstallNotes)
+	}
+}
+
+func uninstallPackage(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	apps, err := marathonFor(cmd).ListApplicationsWithFilters(fmt.Sprintf("label=%s==%s", cosmos.LabelPackageName, args[0]))
+	if err != nil {
+		exitWithError(err)
+	}
+	appID, _ := cmd.Flags().GetString(APP_ID_FLAG)
+	ids := []string{}
+	frameworks := false
+	for _, app := range apps.Apps {
+		if appID == "" || app.ID == appID || app.ID == "/"+appID {
+			ids = append(ids, app.ID)
+			frameworks = frameworks || app.Labels[cosmos.LabelFrameworkName] != ""
+		}
+	}
+	if len(ids) == 0 {
+		exitWithError(cli.WithExitCode(cli.ExitNotFound, ErrorNotInstalled))
+	}
+
+	cli.ConfirmOrExit(fmt.Sprintf("Uninstall package '%s' (%d app(s))", args[0], len(ids)), "environment", viper.GetString(ENV_NAME))
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG)
+	if timeout == 0 {
+		timeout = marathon.DefaultTimeout
+	}
+	for _, id := range ids {
+		dep, err := marathonFor(cmd).DestroyApplication(id)
+		if err != nil {
+			exitWithError(err)
+		}
+		if wait {
+			if err := marathonFor(cmd).WaitForDeployment(dep.DeploymentID, timeout); err != nil {
+				exitWithError(err)
+			}
+		}
+		log.Info("App %s of package '%s' was destroyed", id, args[0])
+	}
+	if frameworks {
+		log.Warning("Frameworks may leave reserved resources and state behind which must be cleaned up separately")
+	}
+}
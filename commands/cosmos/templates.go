@@ -0,0 +1,30 @@
+package cosmos
+
+import (
+	"io"
+
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_PACKAGES = `
+{{ "APP ID" | header }}	{{ "PACKAGE" | header }}	{{ "VERSION" | header }}	{{ "FRAMEWORK" | header }}	{{ "RUNNING" | header }}
+{{ range . }}{{ .AppID }}	{{ .Name }}	{{ .Version }}	{{ .Framework | boolToYesNo }}	{{ .Running | intToString }}/{{ .Instances | intToString }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
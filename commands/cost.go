@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"os"
+	"strings"
+
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/cost"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagAll       = "all"
+	FlagBy        = "by"
+	FlagLabel     = "label"
+	FlagDiff      = "diff"
+	FlagCPUPrice  = "cpu-price"
+	FlagMemPrice  = "mem-price"
+	FlagDiskPrice = "disk-price"
+
+	T_COST = `
+{{ .By | header }}	{{ "APPS" | header }}	{{ "INSTANCES" | header }}	{{ "CPUS" | header }}	{{ "MEM" | header }}	{{ "DISK" | header }}	{{ printf "COST (%s/%s)" .Currency .Period | header }}
+{{ range .Items }}{{ .Name }}	{{ .Apps | intToString }}	{{ .Instances | intToString }}	{{ printf "%g" .CPUs }}	{{ printf "%g" .Mem }}	{{ printf "%g" .Disk }}	{{ printf "%.2f" .Cost }}
+{{end}}{{ with .Total }}{{ .Name }}	{{ .Apps | intToString }}	{{ .Instances | intToString }}	{{ printf "%g" .CPUs }}	{{ printf "%g" .Mem }}	{{ printf "%g" .Disk }}	{{ printf "%.2f" .Cost }}
+{{ end }}`
+
+	T_COST_DIFF = `
+{{ "ID" | header }}	{{ "BEFORE" | header }}	{{ "AFTER" | header }}	{{ "CHANGE" | header }}
+{{ range . }}{{ .ID }}	{{ printf "%.2f" .Before }}	{{ printf "%.2f" .After }}	{{ printf "%+.2f" .Change }}
+{{end}}`
+)
+
+var costCmd = &cobra.Command{
+	Use:   "cost [groupId | --all]",
+	Short: "Estimates the cost of the resources allocated to applications",
+	Long: `Multiplies the cpus, memory and disk allocated to every instance of the applications within [groupId] (or
+every application with --all) by the unit prices of the environment and reports the cost per app, group
+or label value (eg. the team label).
+
+Prices are set per environment in the config file and may be overridden by flags.  Memory and disk are
+priced per GiB:
+
+    "prod": {
+      "marathon": { ... },
+      "cost": { "cpu": 25, "mem": 4, "disk": 0.1, "currency": "USD", "period": "month" }
+    }
+
+With --diff the cost of the apps of a pending descriptor (a file or directory) is compared with the apps
+running in the environment.
+
+    eg. depcon cost --all --by label --label team
+        depcon cost /shop --by group
+        depcon cost --diff app.json -p TAG=1.4`,
+	Run: estimateCost,
+}
+
+func init() {
+	costCmd.Flags().Bool(FlagAll, false, "Include every application of the environment")
+	costCmd.Flags().String(FlagBy, cost.ByApp, "Summarize the cost by app, group or label")
+	costCmd.Flags().String(FlagLabel, cost.DefaultLabel, "Label the cost is summarized by with --by label")
+	costCmd.Flags().String(FlagDiff, "", "Descriptor (file or directory) whose cost change against the environment is reported")
+	costCmd.Flags().Float64(FlagCPUPrice, 0, "Price of a cpu for the period.  Default: the environment's cost prices")
+	costCmd.Flags().Float64(FlagMemPrice, 0, "Price of a GiB of memory for the period.  Default: the environment's cost prices")
+	costCmd.Flags().Float64(FlagDiskPrice, 0, "Price of a GiB of disk for the period.  Default: the environment's cost prices")
+	costCmd.Flags().String(cmdmarathon.TEMPLATE_CTX_FLAG, cmdmarathon.DEFAULT_CTX, "Template context the --diff descriptor is rendered with")
+	costCmd.Flags().StringSliceP(cmdmarathon.PARAMS_FLAG, "p", nil, "Adds a param(s) used to render the --diff descriptor (eg. -p TAG=1.2)")
+	costCmd.Flags().BoolP(cmdmarathon.IGNORE_MISSING, "i", false, "Ignore missing ${PARAMS} and template fields rather than failing")
+	costCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+}
+
+func estimateCost(cmd *cobra.Command, args []string) {
+	all, _ := cmd.Flags().GetBool(FlagAll)
+	diff, _ := cmd.Flags().GetString(FlagDiff)
+	if len(args) == 0 && !all && diff == "" {
+		cli.EvalPrintUsage(Usage(cmd), args, 1)
+		return
+	}
+
+	envName := viper.GetString(ViperEnv)
+	prices := costPrices(cmd, envName)
+	client, err := environmentClients(cmd)(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+	live, err := client.ListApplications()
+	if err != nil {
+		exitWithError(err)
+	}
+	apps := []*marathon.Application{}
+	for i := range live.Apps {
+		apps = append(apps, &live.Apps[i])
+	}
+
+	if diff != "" {
+		pending := pendingApps(cmd, envName, diff)
+		cli.Output(templateFor(T_COST_DIFF, cost.Diff(apps, pending, prices)), nil)
+		return
+	}
+
+	if len(args) > 0 {
+		group := "/" + strings.Trim(args[0], "/")
+		within := []*marathon.Application{}
+		for _, app := range apps {
+			if group == "/" || app.ID == group || strings.HasPrefix(app.ID, group+"/") {
+				within = append(within, app)
+			}
+		}
+		apps = within
+	}
+
+	by, _ := cmd.Flags().GetString(FlagBy)
+	label, _ := cmd.Flags().GetString(FlagLabel)
+	report, err := cost.Summarize(apps, prices, by, label)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	cli.Output(templateFor(T_COST, report), nil)
+}
+
+// Returns the prices of the environment {envName} overridden by the price flags of {cmd}
+func costPrices(cmd *cobra.Command, envName string) *cost.Prices {
+	var prices *cost.Prices
+	if env, ok := configFile.Environments[envName]; ok {
+		prices = env.Cost
+	}
+	flags := &cost.Prices{}
+	flags.CPU, _ = cmd.Flags().GetFloat64(FlagCPUPrice)
+	flags.Mem, _ = cmd.Flags().GetFloat64(FlagMemPrice)
+	flags.Disk, _ = cmd.Flags().GetFloat64(FlagDiskPrice)
+	prices = prices.Merge(flags)
+	if err := prices.Validate(); err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	return prices
+}
+
+// Renders the apps (including those of groups) of the descriptor file or directory {filename}
+func pendingApps(cmd *cobra.Command, envName, filename string) []*marathon.Application {
+	tempctx, _ := cmd.Flags().GetString(cmdmarathon.TEMPLATE_CTX_FLAG)
+	opts := descriptorLoadOptions(cmd, envName, tempctx)
+
+	var descs []*reconcile.Descriptor
+	var err error
+	if fi, serr := os.Stat(filename); serr == nil && fi.IsDir() {
+		descs, err = reconcile.Load(filename, opts)
+	} else {
+		descs, err = reconcile.LoadFile(filename, opts)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+
+	apps := []*marathon.Application{}
+	for _, d := range descs {
+		if d.App != nil {
+			a := *d.App
+			a.ID = d.ID
+			apps = append(apps, &a)
+			continue
+		}
+		for id, app := range reconcile.GroupApps(d.Group) {
+			a := *app
+			a.ID = id
+			apps = append(apps, &a)
+		}
+	}
+	return apps
+}
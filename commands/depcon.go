@@ -4,26 +4,49 @@ package commands
 import (
 	"fmt"
 	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/commands/chronos"
 	"github.com/ContainX/depcon/commands/compose"
+	"github.com/ContainX/depcon/commands/cosmos"
+	"github.com/ContainX/depcon/commands/ecs"
+	"github.com/ContainX/depcon/commands/kubernetes"
 	"github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/commands/mesos"
+	"github.com/ContainX/depcon/commands/metronome"
+	"github.com/ContainX/depcon/commands/nomad"
+	"github.com/ContainX/depcon/commands/swarm"
+	"github.com/ContainX/depcon/commands/workload"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/httpclient"
 	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/secrets"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"io"
 	"os"
 	"strings"
 )
 
 const (
 	FlagVerbose     = "verbose"
+	FlagNoKeyring   = "no-keyring"
+	FlagDebugHTTP   = "debug-http"
+	FlagLogFormat   = "log-format"
+	FlagLogFile     = "log-file"
+	FlagYes         = "yes"
 	EnvDepconMode   = "DEPCON_MODE"
 	ModeMarathon    = "marathon"
 	EnvMarathonHost = "MARATHON_HOST"
 	EnvMarathonUser = "MARATHON_USER"
 	EnvMarathonPass = "MARATHON_PASS"
-	FlagEnv         = "env"
-	ViperEnv        = "env_name"
-	EnvHelp         = `Specifies the Environment name to use (eg. test | prod | etc). This can be omitted if only a single environment has been defined`
-	DepConHelp      = `
+	// DC/OS cluster URL and service account secret file used in place of MARATHON_HOST for CI
+	EnvDCOSURL            = "DCOS_URL"
+	EnvDCOSServiceAccount = "DCOS_SERVICE_ACCOUNT"
+	FlagEnv               = "env"
+	ViperEnv              = "env_name"
+	EnvHelp               = `Specifies the Environment name to use (eg. test | prod | etc). This can be omitted if only a single environment has been defined or DEPCON_ENV is set`
+	DepConHelp            = `
 DEPCON (Deploy Containers)
 
 == Version: %s - Built: %s ==
@@ -37,12 +60,14 @@ Provides management and deployment aid across user-defined clusters such as
 
 var (
 	configFile *cliconfig.ConfigFile
+	// project-local configuration (.depcon.yaml) or nil if the working directory is not within a project
+	project *cliconfig.ProjectConfig
 
 	// Root command for CLI command hierarchy
 	rootCmd = &cobra.Command{
 		Use:              "depcon",
 		Short:            "Manage container clusters and deployments",
-		PersistentPreRun: configureLogging,
+		PersistentPreRun: preRun,
 	}
 
 	// Default logging levels
@@ -53,6 +78,21 @@ var (
 		"depcon.marathon":    logger.WARNING,
 		"depcon.marshal":     logger.WARNING,
 		"depcon.compose":     logger.WARNING,
+		"depcon.kubernetes":  logger.WARNING,
+		"depcon.ecs":         logger.WARNING,
+		"depcon.nomad":       logger.WARNING,
+		"depcon.swarm":       logger.WARNING,
+		"depcon.metronome":   logger.WARNING,
+		"depcon.chronos":     logger.WARNING,
+		"depcon.mesos":       logger.WARNING,
+		"depcon.cosmos":      logger.INFO,
+		"depcon.workload":    logger.INFO,
+		"depcon.server":      logger.INFO,
+		"depcon.reconcile":   logger.INFO,
+		"depcon.release":     logger.INFO,
+		"depcon.pipeline":    logger.INFO,
+		"depcon.secrets":     logger.WARNING,
+		"depcon.marathonlb":  logger.INFO,
 		"depcon.marathon.bg": logger.INFO,
 	}
 
@@ -63,7 +103,13 @@ var (
 func init() {
 	logger.InitWithDefaultLogger("depcon")
 	rootCmd.PersistentFlags().StringP(FlagEnv, "e", "", EnvHelp)
-	rootCmd.PersistentFlags().Bool(FlagVerbose, false, "Enables debug/verbose logging")
+	rootCmd.PersistentFlags().CountP(FlagVerbose, "v", "Increases logging: -v actions taken, -vv API calls with timing, -vvv debug logging with full request and response tracing")
+	rootCmd.PersistentFlags().Bool(FlagDebugHTTP, false, "Writes every API request and response (secrets redacted) to stderr")
+	rootCmd.PersistentFlags().String(FlagLogFormat, logger.FormatText, "Format of log messages: text or json (one object per line with env, app, deployment and duration fields)")
+	rootCmd.PersistentFlags().String(FlagLogFile, "", "Appends log messages to the file rather than writing them to stdout")
+	rootCmd.PersistentFlags().String(FlagBreakGlass, "", "Makes changes during a freeze window of the environment recording REASON in the audit log")
+	rootCmd.PersistentFlags().Bool(FlagNoKeyring, false, "Stores passwords in the config file rather than the OS keyring")
+	rootCmd.PersistentFlags().BoolP(FlagYes, "y", false, "Answers yes to confirmations of destructive commands (destroy, scale to zero, deployment cancel)")
 	viper.BindPFlag(FlagEnv, rootCmd.PersistentFlags().Lookup(FlagEnv))
 }
 
@@ -71,6 +117,14 @@ func init() {
 // to force initial setup
 func Execute() {
 	rootCmd.Long = fmt.Sprintf(DepConHelp, Version, BuildDate)
+	registerCompletions()
+	// the config is loaded prior to flag parsing
+	for _, arg := range os.Args {
+		if arg == "--"+FlagNoKeyring {
+			cliconfig.DisableKeyring()
+		}
+	}
+	loadProjectConfig()
 	file, found := cliconfig.HasExistingConfig()
 	if found {
 		configFile = file
@@ -80,25 +134,52 @@ func Execute() {
 			configFile = marathonConfigFromEnv()
 			executeWithExistingConfig()
 		} else {
-			if len(os.Args) >= 4 && os.Args[1] == "config" && os.Args[2] == "env" && os.Args[3] == "add-marathon" {
+			if isConfigBootstrap() || isCompletionCommand() {
 				configFile, _ = cliconfig.Load("")
-				rootCmd.AddCommand(configCmd)
-				rootCmd.Execute()
+				rootCmd.AddCommand(configCmd, completionCmd)
+				execute()
 				return
 			}
+			if _, err := os.Stat(file.Filename()); err == nil {
+				logger.Logger().Error("%s could not be loaded.  Run 'depcon config validate' for details", file.Filename())
+				os.Exit(cli.ExitError)
+			}
 			logger.Logger().Error("%s file not found.  Generating initial configuration", file.Filename())
 			configFile = cliconfig.CreateNewConfigFromUserInput()
 		}
 	}
 }
 
+// Determines if the command can run without an existing configuration (adding an initial environment
+// or importing shared environments)
+func isConfigBootstrap() bool {
+	if len(os.Args) >= 4 && os.Args[1] == "config" && os.Args[2] == "env" && (os.Args[3] == "add-marathon" || os.Args[3] == "add-ecs" || os.Args[3] == "add-nomad" || os.Args[3] == "add-swarm") {
+		return true
+	}
+	return len(os.Args) >= 3 && os.Args[1] == "config" && (os.Args[2] == "import" || os.Args[2] == "validate")
+}
+
 func marathonConfigFromEnv() *cliconfig.ConfigFile {
+	if url := os.Getenv(EnvDCOSURL); url != "" {
+		cf := cliconfig.CreateMemoryMarathonConfig(url, os.Getenv(EnvMarathonUser), os.Getenv(EnvMarathonPass))
+		mc := cf.Environments[cf.DefaultEnv].Marathon
+		mc.Auth = cliconfig.AuthDCOS
+		mc.ServiceAccount = os.Getenv(EnvDCOSServiceAccount)
+		return cf
+	}
 	return cliconfig.CreateMemoryMarathonConfig(os.Getenv(EnvMarathonHost), os.Getenv(EnvMarathonUser), os.Getenv(EnvMarathonPass))
 }
 
 func determineEnvironment() string {
 	envName := findEnvNameFromArgs()
 
+	if envName == "" {
+		envName = os.Getenv(EnvDepconEnv)
+	}
+	if envName == "" && project != nil {
+		envName = project.Environment
+	}
+
 	if envName == "" {
 		if _, single := configFile.DetermineIfServiceIsRooted(); single {
 			envName = configFile.GetEnvironments()[0]
@@ -106,7 +187,7 @@ func determineEnvironment() string {
 			if configFile.DefaultEnv != "" {
 				envName = configFile.DefaultEnv
 			} else {
-				rootCmd.Execute()
+				execute()
 				logger.Logger().Error("Multiple environments are defined in config.  You must execute with -e envname.")
 				printValidEnvironments()
 				return ""
@@ -117,25 +198,66 @@ func determineEnvironment() string {
 }
 
 func executeWithExistingConfig() {
-	envName := determineEnvironment()
+	var envName string
+	if isCompletionCommand() {
+		envName = completionEnvironment(findEnvNameFromArgs())
+	} else {
+		envName = determineEnvironment()
+	}
 	if envName == "" {
-		os.Exit(1)
+		os.Exit(cli.ExitUsage)
 	}
-	if _, err := configFile.GetEnvironment(envName); err != nil {
+	if members, isGroup := configFile.GetGroup(envName); isGroup {
+		if firstCommand() == "shell" {
+			logger.Logger().Error("'%s' is a group of environments, open a session with one of them: %s", envName, strings.Join(members, ", "))
+			os.Exit(cli.ExitUsage)
+		}
+		if !isLocalCommand() {
+			os.Exit(fanOut(envName, members, hasArg("--"+FlagParallel)))
+		}
+		// local commands run once against the first member
+		envName = members[0]
+	}
+	if _, err := configFile.GetEnvironment(envName); err == nil && !isLocalCommand() {
+		applyConnectionOverrides(envName)
+	}
+	if configEnv, err := configFile.GetEnvironment(envName); err != nil {
 		logger.Logger().Error("'%s' environment could not be found in config (%s)\n\n", envName, configFile.Filename())
 		printValidEnvironments()
-		os.Exit(1)
+		os.Exit(cli.ExitNotFound)
 	} else {
 		viper.Set(ViperEnv, envName)
-		if configFile.RootService {
+		switch {
+		case configEnv.Marathon == nil:
+			// environments defining only an ECS cluster, Nomad cluster or swarm have no Marathon commands
+		case configFile.RootService:
 			marathon.AddJailedMarathonToCmd(rootCmd, configFile)
-		} else {
+		default:
 			marathon.AddMarathonToCmd(rootCmd, configFile)
 		}
+		if configEnv.Marathon != nil {
+			metronome.AddMetronomeToCmd(rootCmd, configFile)
+			chronos.AddChronosToCmd(rootCmd, configFile)
+			mesos.AddMesosToCmd(rootCmd, configFile)
+			cosmos.AddCosmosToCmd(rootCmd, configFile)
+		}
+	}
+	compose.AddComposeToCmd(rootCmd, configFile)
+	kubernetes.AddKubernetesToCmd(rootCmd, configFile)
+	ecs.AddECSToCmd(rootCmd, configFile)
+	nomad.AddNomadToCmd(rootCmd, configFile)
+	swarm.AddSwarmToCmd(rootCmd, configFile)
+	workload.AddWorkloadToCmd(rootCmd)
+	rootCmd.AddCommand(configCmd, schemaCmd, completionCmd, pluginCmd, serverCmd, syncCmd, driftCmd, applyCmd, releaseCmd, pipelineCmd, costCmd, backupCmd, restoreCmd, signCmd, loginCmd, envFileCmd, reportCmd, shellCmd)
+	addPluginCommands()
+	execute()
+}
+
+// Executes the root command exiting with cli.ExitUsage when the command or flags are invalid
+func execute() {
+	if err := rootCmd.Execute(); err != nil {
+		os.Exit(cli.ExitUsage)
 	}
-	compose.AddComposeToCmd(rootCmd, nil)
-	rootCmd.AddCommand(configCmd)
-	rootCmd.Execute()
 }
 
 // Profiles the user with a list of current environments found within the config.json based on
@@ -146,34 +268,208 @@ func printValidEnvironments() {
 	for _, env := range envs {
 		fmt.Printf("-  %s\n", env)
 	}
+	for _, group := range configFile.GetGroups() {
+		members, _ := configFile.GetGroup(group)
+		fmt.Printf("-  %s (%s)\n", group, strings.Join(members, ", "))
+	}
 	fmt.Println("")
 }
 
 func findEnvNameFromArgs() string {
-	if len(os.Args) < 2 {
+	args := os.Args[1:]
+	// the shell requests completions with the command line being completed following __complete
+	if len(args) > 0 && (args[0] == cobra.ShellCompRequestCmd || args[0] == cobra.ShellCompNoDescRequestCmd) {
+		args = args[1:]
+	}
+	if len(args) < 1 {
 		return ""
 	}
-	f := os.Args[1]
-	if f == "-e" && len(os.Args) > 2 {
-		return os.Args[2]
+	f := args[0]
+	if f == "-e" && len(args) > 1 {
+		return args[1]
 	}
-	if strings.HasPrefix(os.Args[1], "--env=") {
-		split := strings.Split(os.Args[1], "=")
+	if strings.HasPrefix(f, "--env=") {
+		split := strings.Split(f, "=")
 		return split[1]
 	}
 	return ""
 }
 
-// Configures the logging levels based on the logLevels map.  If --verbose is flagged
-// then all categories defined in the map become DEBUG
+func preRun(cmd *cobra.Command, args []string) {
+	applyEnvironmentFlags(cmd)
+	configureMasking(cmd)
+	configureSecrets()
+	configureLogging(cmd, args)
+	configureFreeze(cmd)
+	configureAccess(cmd)
+	configureColor(cmd)
+	configurePager(cmd)
+	configureOutputFile(cmd)
+	quiet, _ := cmd.Flags().GetBool(FLAG_QUIET)
+	cli.EnableProgress(!quiet)
+	yes, _ := cmd.Flags().GetBool(FlagYes)
+	cli.AssumeYes(yes)
+}
+
+// Resolves secret:// references within params and templates with the secrets providers of the current
+// environment
+func configureSecrets() {
+	var configs map[string]*secrets.Config
+	if configFile != nil {
+		if configEnv, err := configFile.GetEnvironment(viper.GetString(ViperEnv)); err == nil {
+			configs = configEnv.Secrets
+		}
+	}
+	secrets.SetDefault(secrets.NewResolver(configs))
+	envsubst.SecretResolver = secrets.Default().ResolveValue
+}
+
+func loadProjectConfig() {
+	wd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	if project, err = cliconfig.FindProjectConfig(wd); err != nil {
+		logger.Logger().Warning("Ignoring project configuration: %s", err.Error())
+	}
+}
+
+// Applies DEPCON_* environment variables, then the default flag values defined by the project and then the
+// current environment to any flags the command defines which were not specified on the command line.  The
+// first source to set a flag wins
+func applyEnvironmentFlags(cmd *cobra.Command) {
+	setDefaultFlags(cmd, flagsFromEnv(cmd), "environment variable")
+
+	if project != nil {
+		setDefaultFlags(cmd, project.Flags, "project")
+		if project.TemplateContext != "" {
+			setDefaultFlags(cmd, map[string]string{marathon.TEMPLATE_CTX_FLAG: project.TemplateContext}, "project")
+		}
+		if len(project.ParamFiles) > 0 {
+			setDefaultFlags(cmd, map[string]string{marathon.ENV_FILE_FLAG: strings.Join(project.ParamFiles, ",")}, "project")
+		}
+	}
+
+	if configFile != nil {
+		if configEnv, err := configFile.GetEnvironment(viper.GetString(ViperEnv)); err == nil {
+			setDefaultFlags(cmd, configEnv.Flags, "environment")
+		}
+	}
+	applyContextConvention(cmd)
+}
+
+// Selects the template context of the current environment by convention (eg. template-context-prod.json)
+// when --tempctx wasn't given.  The flag is left unchanged so contexts named by manifests, releases and
+// pipelines still take precedence
+func applyContextConvention(cmd *cobra.Command) {
+	f := cmd.Flags().Lookup(marathon.TEMPLATE_CTX_FLAG)
+	if f == nil || f.Changed || defaulted(f) {
+		return
+	}
+	patterns := marathon.DefaultContextPatterns
+	if project != nil && project.TemplateContextPatterns != nil {
+		patterns = project.TemplateContextPatterns
+	}
+	if tempctx := marathon.ContextForEnv(patterns, viper.GetString(ViperEnv)); tempctx != "" {
+		log.Debug("Using template context %s for environment '%s'", tempctx, viper.GetString(ViperEnv))
+		f.Value.Set(tempctx)
+	}
+}
+
+// flag annotation recording the source (eg. project) which defaulted the flag
+const annotationDefaultSource = "depcon_default_source"
+
+// Flags overriding the safeguards of an environment (read-only, freeze windows and confirmations).  They are
+// never defaulted so a checked out .depcon.yaml or an inherited DEPCON_* variable can't silently disable them
+var guardrailFlags = map[string]bool{
+	marathon.ALLOW_WRITE_FLAG: true,
+	FlagBreakGlass:            true,
+	FlagYes:                   true,
+}
+
+// Sets the {flags} the command defines which were neither specified on the command line nor defaulted by an
+// earlier source.  The value becomes the flag's default rather than marking it changed so commands still
+// tell the flags given on the command line from those defaulted by the project or environment.  The
+// guardrailFlags are ignored as they may only be given on the command line
+func setDefaultFlags(cmd *cobra.Command, flags map[string]string, source string) {
+	for name, value := range flags {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Changed || defaulted(f) || name == FlagEnv {
+			continue
+		}
+		if guardrailFlags[name] {
+			log.Warning("Ignoring %s default for --%s which may only be given on the command line", source, name)
+			continue
+		}
+		if err := f.Value.Set(value); err != nil {
+			log.Warning("Ignoring %s default for --%s: %s", source, name, err.Error())
+			continue
+		}
+		f.DefValue = f.Value.String()
+		cmd.Flags().SetAnnotation(name, annotationDefaultSource, []string{source})
+	}
+}
+
+// Returns true when flag {f} was defaulted by setDefaultFlags
+func defaulted(f *pflag.Flag) bool {
+	_, ok := f.Annotations[annotationDefaultSource]
+	return ok
+}
+
+// Configures the logging levels based on the logLevels map raised by the verbosity (-v, -vv, -vvv).  HTTP
+// tracing is enabled with -vvv, --debug-http or DEPCON_DEBUG
 func configureLogging(cmd *cobra.Command, args []string) {
-	verbose, _ := cmd.Flags().GetBool(FlagVerbose)
+	verbosity, _ := cmd.Flags().GetCount(FlagVerbose)
+	debug, _ := cmd.Flags().GetBool(FlagDebugHTTP)
+	if debug || verbosity >= verbosityTrace || os.Getenv(EnvDepconDebug) != "" {
+		httpclient.EnableTracing(os.Stderr)
+	}
 
-	for category, level := range logLevels {
-		if verbose {
-			logger.SetLevel(logger.DEBUG, category)
-		} else {
-			logger.SetLevel(level, category)
+	format, _ := cmd.Flags().GetString(FlagLogFormat)
+	filename, _ := cmd.Flags().GetString(FlagLogFile)
+	// configured even for the default text on stdout so secrets are masked within log messages
+	var w io.Writer = os.Stdout
+	if filename != "" {
+		f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			exitWithError(err)
 		}
+		w = f
+	}
+	if err := logger.Configure(format, w); err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	if envName := viper.GetString(ViperEnv); envName != "" {
+		logger.SetGlobalFields(logger.Fields{logger.FieldEnv: envName})
+	}
+
+	for category, level := range logLevels {
+		logger.SetLevel(levelForVerbosity(verbosity, category, level), category)
+	}
+}
+
+const (
+	// actions taken (deployments, scaling etc)
+	verbosityInfo = 1
+	// every API call with its status and timing
+	verbosityAPI = 2
+	// debug logging and full request/response tracing
+	verbosityTrace = 3
+)
+
+// Returns the level of log {category} for {verbosity} where {level} is the default
+func levelForVerbosity(verbosity int, category string, level logger.LogLevel) logger.LogLevel {
+	raised := level
+	switch {
+	case verbosity >= verbosityTrace:
+		raised = logger.DEBUG
+	case verbosity >= verbosityAPI:
+		raised = logger.INFO
+	case verbosity >= verbosityInfo && category != httpclient.LogCategory:
+		raised = logger.INFO
+	}
+	if raised > level {
+		return raised
 	}
+	return level
 }
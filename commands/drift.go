@@ -0,0 +1,78 @@
+package commands
+
+import (
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagPrefix          = "prefix"
+	FlagIgnoreUnmanaged = "ignore-unmanaged"
+
+	T_DRIFT = `
+{{ "ID" | header }}	{{ "DRIFT" | header }}	{{ "FIELD" | header }}	{{ "LIVE" | header }}	{{ "DESIRED" | header }}
+{{ range . }}{{ $d := . }}{{ if .Fields }}{{ range .Fields }}{{ $d.ID }}	{{ $d.Type }}	{{ .Path }}	{{ mask .Path .LiveValue }}	{{ mask .Path .DesiredValue }}
+{{end}}{{ else }}{{ .ID }}	{{ .Type }}
+{{end}}{{end}}`
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift [dir]",
+	Short: "Reports the differences between the descriptors in [dir] and the environment",
+	Long: `Renders every descriptor (.json, .yaml, .yml) within [dir] and its sub directories and reports how the
+environment differs from them:
+
+    changed    fields the descriptor defines have another value (and env or labels keys it doesn't define)
+    missing    the application of a descriptor isn't running
+    unmanaged  an application beneath --prefix is running which no descriptor defines
+
+Exits with 1 when drift is found so it may run as a scheduled compliance check.
+
+    eg. depcon drift deploy/prod -e prod --prefix /prod`,
+	Run: detectDrift,
+}
+
+func init() {
+	driftCmd.Flags().String(cmdmarathon.TEMPLATE_CTX_FLAG, cmdmarathon.DEFAULT_CTX, "Template context the descriptors are rendered with")
+	driftCmd.Flags().StringSliceP(cmdmarathon.PARAMS_FLAG, "p", nil, "Adds a param(s) that can be used for substitution (eg. -p TAG=1.2)")
+	driftCmd.Flags().BoolP(cmdmarathon.IGNORE_MISSING, "i", false, "Ignore missing ${PARAMS} and template fields rather than failing")
+	driftCmd.Flags().String(FlagPrefix, "/", "Group the environment's applications are compared beneath when reporting unmanaged apps")
+	driftCmd.Flags().Bool(FlagIgnoreUnmanaged, false, "Don't report unmanaged applications")
+	driftCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+}
+
+func detectDrift(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	envName := viper.GetString(ViperEnv)
+	client, err := environmentClients(cmd)(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	tempctx, _ := cmd.Flags().GetString(cmdmarathon.TEMPLATE_CTX_FLAG)
+	descs, err := reconcile.Load(args[0], descriptorLoadOptions(cmd, envName, tempctx))
+	if err != nil {
+		exitWithError(err)
+	}
+	apps, err := client.ListApplications()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	prefix, _ := cmd.Flags().GetString(FlagPrefix)
+	if ignore, _ := cmd.Flags().GetBool(FlagIgnoreUnmanaged); ignore {
+		prefix = ""
+	}
+	drift := reconcile.Detect(descs, apps.Apps, prefix)
+	if len(drift) == 0 {
+		log.Info("%d descriptor(s) match environment '%s'", len(descs), envName)
+		return
+	}
+	cli.Output(templateFor(T_DRIFT, drift), nil)
+	cli.ExitWithCode(cli.ExitError)
+}
@@ -0,0 +1,142 @@
+package ecs
+
+import (
+	"time"
+
+	"github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/ecs"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+const (
+	WAIT_FLAG    string = "wait"
+	TIMEOUT_FLAG string = "wait-timeout"
+)
+
+var log = logger.GetLogger("depcon.ecs")
+
+var (
+	ecsDeployCmd = &cobra.Command{
+		Use:   "deploy [file(.json | .yaml)]",
+		Short: "Registers the task definitions and creates or updates the services within a descriptor",
+		Long: `Registers the task definitions and creates or updates the services within a descriptor
+
+    The descriptor is rendered with the template context and ${PARAMS} exactly as Marathon
+    descriptors are.  Each document holds a RegisterTaskDefinition request under 'taskDefinition'
+    and a CreateService request under 'service'.  The service runs the task definition registered
+    by the document.  YAML descriptors may contain multiple documents separated by '---' which
+    are deployed in order`,
+		Run: deployDocuments,
+	}
+
+	ecsTaskDefCmd = &cobra.Command{
+		Use:     "taskdef",
+		Aliases: []string{"task-definition"},
+		Short:   "Manage ECS task definitions",
+		Long: `Manage ECS task definitions
+
+    See taskdef's subcommands for available choices`,
+	}
+
+	ecsTaskDefRegisterCmd = &cobra.Command{
+		Use:   "register [file(.json | .yaml)]",
+		Short: "Registers a new revision of the task definitions within a descriptor",
+		Long: `Registers a new revision of the task definitions within a descriptor
+
+    Each document is a RegisterTaskDefinition request or holds one under 'taskDefinition'`,
+		Run: registerTaskDefinitions,
+	}
+)
+
+func init() {
+	ecsDeployCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the services to reach a steady state")
+	ecsDeployCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the services to reach a steady state (ex. 90s | 2m)")
+	marathon.ApplyDescriptorFlags(ecsDeployCmd)
+	marathon.ApplyDescriptorFlags(ecsTaskDefRegisterCmd)
+	ecsTaskDefCmd.AddCommand(ecsTaskDefRegisterCmd)
+}
+
+func deployDocuments(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	docs := marathon.RenderDocuments(cmd, args[0])
+
+	results := []*ecs.DeployResult{}
+	for i, doc := range docs {
+		reportBulkStep(i+1, len(docs), documentLabel(doc))
+		result, err := client(cmd).Deploy(doc)
+		if err != nil {
+			cli.StopProgress()
+			log.Error("Unable to deploy %s", documentLabel(doc))
+			exitWithError(err)
+		}
+		results = append(results, result)
+	}
+	cli.StopProgress()
+
+	if wait {
+		for _, r := range results {
+			if r.Service == "" {
+				continue
+			}
+			if err := client(cmd).WaitForSteadyState(r.Service, waitTimeout(cmd)); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+	cli.Output(templateFor(T_DEPLOYED, results), nil)
+}
+
+func registerTaskDefinitions(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	registered := []*ecs.TaskDefinition{}
+	for _, doc := range marathon.RenderDocuments(cmd, args[0]) {
+		if def, ok := doc["taskDefinition"].(map[string]interface{}); ok {
+			doc = def
+		}
+		td, err := client(cmd).RegisterTaskDefinition(doc)
+		if err != nil {
+			exitWithError(err)
+		}
+		registered = append(registered, td)
+	}
+	cli.Output(templateFor(T_TASK_DEFINITIONS, registered), nil)
+}
+
+// Returns the service or task definition family named by {doc}
+func documentLabel(doc map[string]interface{}) string {
+	if service, ok := doc["service"].(map[string]interface{}); ok {
+		name, _ := service["serviceName"].(string)
+		return "service/" + name
+	}
+	def, _ := doc["taskDefinition"].(map[string]interface{})
+	family, _ := def["family"].(string)
+	return "taskdef/" + family
+}
+
+// Reports {step} of {total} of a bulk deployment which is drawn with the progress on a terminal and
+// logged otherwise
+func reportBulkStep(step, total int, label string) {
+	if total < 2 {
+		return
+	}
+	if !cli.ActiveProgress().Step(step, total, label) {
+		log.Info("[%d/%d] %s", step, total, label)
+	}
+}
+
+// Returns --wait-timeout or ecs.DefaultTimeout when unspecified
+func waitTimeout(cmd *cobra.Command) time.Duration {
+	if timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG); timeout > 0 {
+		return timeout
+	}
+	return ecs.DefaultTimeout
+}
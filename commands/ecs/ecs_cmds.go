@@ -0,0 +1,149 @@
+package ecs
+
+import (
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/ecs"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	CLUSTER_FLAG     string = "cluster"
+	REGION_FLAG      string = "region"
+	PROFILE_FLAG     string = "profile"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	ENV_NAME         string = "env_name"
+)
+
+var (
+	ecsCmd = &cobra.Command{
+		Use:   "ecs",
+		Short: "Manage Amazon ECS task definitions and services",
+		Long: `Manage Amazon ECS task definitions and services using the same templated descriptors,
+params and template contexts as Marathon
+
+    The cluster is selected by the 'ecs' block of the environment or the --cluster, --region
+    and --profile flags.  Credentials are read from $AWS_ACCESS_KEY_ID/$AWS_SECRET_ACCESS_KEY
+    or the shared credentials file (~/.aws/credentials)
+
+    See ecs's subcommands for available choices`,
+	}
+	ecsClient  ecs.ECS
+	configFile *cliconfig.ConfigFile
+)
+
+// Associates the ecs commands to the given command
+func AddECSToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
+	backend.Register(cliconfig.TypeECS, newBackend)
+	rc.AddCommand(ecsCmd)
+}
+
+func init() {
+	ecsCmd.PersistentFlags().String(CLUSTER_FLAG, "", "ECS cluster overriding the environment")
+	ecsCmd.PersistentFlags().String(REGION_FLAG, "", "AWS region overriding the environment and $AWS_REGION")
+	ecsCmd.PersistentFlags().String(PROFILE_FLAG, "", "Shared credentials profile overriding the environment and $AWS_PROFILE")
+	ecsCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	ecsCmd.AddCommand(ecsDeployCmd, ecsTaskDefCmd, ecsServiceCmd)
+}
+
+func client(cmd *cobra.Command) ecs.ECS {
+	if ecsClient == nil {
+		o := overrides{}
+		o.cluster, _ = cmd.Flags().GetString(CLUSTER_FLAG)
+		o.region, _ = cmd.Flags().GetString(REGION_FLAG)
+		o.profile, _ = cmd.Flags().GetString(PROFILE_FLAG)
+		o.allowWrite, _ = cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+		c, err := newClient(viper.GetString(ENV_NAME), o)
+		if err != nil {
+			exitWithError(err)
+		}
+		ecsClient = c
+	}
+	return ecsClient
+}
+
+// Settings of the invocation overriding those of the environment
+type overrides struct {
+	cluster, region, profile string
+	allowWrite               bool
+}
+
+// Creates the client of environment {envName} falling back to $AWS_REGION and the default cluster for
+// settings neither the environment nor {o} specify
+func newClient(envName string, o overrides) (ecs.ECS, error) {
+	settings := &cliconfig.ECSConfig{}
+	if configFile != nil {
+		if env, err := configFile.GetEnvironment(envName); err == nil {
+			if env.ECS != nil {
+				*settings = *env.ECS
+			}
+			if env.Marathon != nil && env.Marathon.ReadOnly {
+				settings.ReadOnly = true
+			}
+		}
+	}
+	if o.cluster != "" {
+		settings.Cluster = o.cluster
+	}
+	if o.region != "" {
+		settings.Region = o.region
+	}
+	if o.profile != "" {
+		settings.Profile = o.profile
+	}
+	if settings.Region == "" {
+		settings.Region = ecs.DefaultRegion()
+	}
+	if settings.Cluster == "" {
+		settings.Cluster = "default"
+	}
+
+	creds, err := ecs.LoadCredentials(settings.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &ecs.ECSOptions{Endpoint: settings.Endpoint, Retry: httpclient.DefaultRetryPolicy()}
+	opts.ReadOnly = settings.ReadOnly && !o.allowWrite
+	if progress := cli.ActiveProgress(); progress != nil {
+		opts.Progress = progress
+	}
+	return ecs.NewECSClient(settings.Cluster, settings.Region, creds, opts)
+}
+
+// Creates the backend of the workload commands.  The default cluster is only fallen back to when the
+// backend is selected explicitly
+func newBackend(ctx *backend.Context) (backend.ClusterBackend, error) {
+	if !ctx.Selected && !configured(ctx.EnvName) {
+		return nil, &backend.NotConfiguredError{Backend: cliconfig.TypeECS, EnvName: ctx.EnvName}
+	}
+	c, err := newClient(ctx.EnvName, overrides{allowWrite: ctx.AllowWrite})
+	if err != nil {
+		return nil, err
+	}
+	return backend.NewECSBackend(c), nil
+}
+
+// Returns true if environment {envName} has an 'ecs' block
+func configured(envName string) bool {
+	if configFile == nil {
+		return false
+	}
+	env, err := configFile.GetEnvironment(envName)
+	return err == nil && env.ECS != nil
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
@@ -0,0 +1,97 @@
+package ecs
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ecsServiceCmd = &cobra.Command{
+		Use:     "service",
+		Aliases: []string{"services"},
+		Short:   "Manage ECS services",
+		Long: `Manage ECS services within the cluster
+
+    See service's subcommands for available choices`,
+	}
+
+	ecsServiceListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all services within the cluster",
+		Run: func(cmd *cobra.Command, args []string) {
+			v, e := client(cmd).ListServices()
+			cli.Output(templateFor(T_SERVICES, v), e)
+		},
+	}
+
+	ecsServiceGetCmd = &cobra.Command{
+		Use:   "get [name]",
+		Short: "Gets a service details by name",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).GetService(args[0])
+			cli.Output(templateFor(T_SERVICE, v), e)
+		},
+	}
+
+	ecsServiceScaleCmd = &cobra.Command{
+		Use:   "scale [name] [count]",
+		Short: "Scales a service to the desired count of tasks",
+		Run:   scaleService,
+	}
+
+	ecsServiceWaitCmd = &cobra.Command{
+		Use:   "wait [name]",
+		Short: "Waits for a service to reach a steady state",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			if err := client(cmd).WaitForSteadyState(args[0], waitTimeout(cmd)); err != nil {
+				exitWithError(err)
+			}
+			v, e := client(cmd).GetService(args[0])
+			cli.Output(templateFor(T_SCALE, v), e)
+		},
+	}
+)
+
+func init() {
+	ecsServiceScaleCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the service to reach a steady state")
+	ecsServiceScaleCmd.Flags().DurationP(TIMEOUT_FLAG, "t", 0, "Max duration to wait for the service to reach a steady state (ex. 90s | 2m)")
+	ecsServiceWaitCmd.Flags().DurationP(TIMEOUT_FLAG, "t", 0, "Max duration to wait for the service to reach a steady state (ex. 90s | 2m)")
+	ecsServiceCmd.AddCommand(ecsServiceListCmd, ecsServiceGetCmd, ecsServiceScaleCmd, ecsServiceWaitCmd)
+}
+
+func scaleService(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+		return
+	}
+
+	count, err := strconv.Atoi(args[1])
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	if count == 0 {
+		cli.ConfirmOrExit(fmt.Sprintf("Scale service '%s' to 0 tasks", args[0]), "cluster", client(cmd).Cluster())
+	}
+
+	v, e := client(cmd).ScaleService(args[0], count)
+	if e != nil {
+		exitWithError(e)
+	}
+	if wait, _ := cmd.Flags().GetBool(WAIT_FLAG); wait {
+		if err := client(cmd).WaitForSteadyState(args[0], waitTimeout(cmd)); err != nil {
+			exitWithError(err)
+		}
+		if v, e = client(cmd).GetService(args[0]); e != nil {
+			exitWithError(e)
+		}
+	}
+	cli.Output(templateFor(T_SCALE, v), nil)
+}
@@ -0,0 +1,85 @@
+package ecs
+
+import (
+	"io"
+	"path"
+	"text/template"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_SERVICES = `
+{{ "NAME" | header }}	{{ "STATUS" | header }}	{{ "DESIRED" | header }}	{{ "RUNNING" | header }}	{{ "PENDING" | header }}	{{ "TASK DEFINITION" | header }}	{{ "LAUNCH TYPE" | header }}
+{{ range . }}{{ .ServiceName }}	{{ .Status }}	{{ .DesiredCount | intToString }}	{{ .RunningCount | intToString }}	{{ .PendingCount | intToString }}	{{ .TaskDefinition | base }}	{{ .LaunchType }}
+{{end}}`
+
+	T_SERVICE = `
+{{ "Name:" }}	{{ .ServiceName }}
+{{ "ARN:" }}	{{ .ServiceArn }}
+{{ "Status:" }}	{{ .Status }}
+{{ "Launch Type:" }}	{{ .LaunchType }}
+{{ "Task Definition:" }}	{{ .TaskDefinition | base }}
+{{ "Created:" }}	{{ .CreatedAt | epoch }}
+{{ "Tasks:" }}	{{ "Desired" | pad }} {{ .DesiredCount | intToString }}
+	{{ "Running" | pad }} {{ .RunningCount | intToString }}
+	{{ "Pending" | pad }} {{ .PendingCount | intToString }}
+{{ "Deployments:" }}
+{{ range .Deployments }}		{{ .Status | pad }} {{ .TaskDefinition | base }} {{ .RunningCount | intToString }}/{{ .DesiredCount | intToString }} {{ .RolloutState }}
+{{end}}`
+
+	T_SCALE = `
+{{ "NAME" | header }}	{{ "DESIRED" | header }}	{{ "RUNNING" | header }}
+{{ .ServiceName }}	{{ .DesiredCount | intToString }}	{{ .RunningCount | intToString }}`
+
+	T_DEPLOYED = `
+{{ "SERVICE" | header }}	{{ "TASK DEFINITION" | header }}	{{ "ACTION" | header }}
+{{ range . }}{{ .Service | dash }}	{{ .TaskDefinition | base }}	{{ .Action }}
+{{end}}`
+
+	T_TASK_DEFINITIONS = `
+{{ "FAMILY" | header }}	{{ "REVISION" | header }}	{{ "ARN" | header }}
+{{ range . }}{{ .Family }}	{{ .Revision | intToString }}	{{ .TaskDefinitionArn }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data, Funcs: buildFuncMap()}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
+
+func buildFuncMap() template.FuncMap {
+	return template.FuncMap{
+		// family:revision of a task definition ARN (eg. arn:aws:ecs:...:task-definition/api:3)
+		"base":  path.Base,
+		"epoch": epoch,
+		"dash":  dash,
+	}
+}
+
+// Formats seconds since the epoch as RFC3339
+func epoch(secs float64) string {
+	if secs == 0 {
+		return ""
+	}
+	return time.Unix(int64(secs), 0).UTC().Format(time.RFC3339)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
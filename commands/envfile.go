@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/envcrypt"
+	"github.com/bgentry/speakeasy"
+	"github.com/spf13/cobra"
+)
+
+var envFileCmd = &cobra.Command{
+	Use:   "env-file",
+	Short: "Encrypts params/env files so secret values never sit in plaintext on disk",
+	Long: `Encrypted params/env files (--env-file) are decrypted in memory as they're read.  Files encrypted with
+'env-file encrypt' use the passphrase within $DEPCON_ENV_PASSPHRASE (or prompt for it).  Files encrypted
+by sops (eg. with a KMS key: sops --encrypt --kms ARN --input-type dotenv) are decrypted by sops.
+
+    eg. depcon env-file encrypt secrets.env          # writes secrets.env.enc
+        DEPCON_ENV_PASSPHRASE=... depcon app create app.json -c params.env,secrets.env.enc`,
+}
+
+var envFileEncryptCmd = &cobra.Command{
+	Use:   "encrypt [file]",
+	Short: "Encrypts [file] with a passphrase writing [file].enc (or --out)",
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		plain, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		if envcrypt.IsEncrypted(plain) {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("%s is already encrypted", args[0])))
+		}
+		data, err := envcrypt.Encrypt(plain, envFilePassphrase(true))
+		if err != nil {
+			exitWithError(err)
+		}
+		out, _ := cmd.Flags().GetString(OUT_FLAG)
+		if out == "" {
+			out = args[0] + ".enc"
+		}
+		if err := ioutil.WriteFile(out, data, 0600); err != nil {
+			exitWithError(err)
+		}
+		log.Info("Encrypted %s to %s.  Remove the plaintext file once it's no longer needed", args[0], out)
+	},
+}
+
+var envFileDecryptCmd = &cobra.Command{
+	Use:   "decrypt [file]",
+	Short: "Decrypts [file] printing it (or writing it to --out) eg. to edit its values",
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		data, err := envcrypt.ReadFile(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		if out, _ := cmd.Flags().GetString(OUT_FLAG); out != "" {
+			if err := ioutil.WriteFile(out, data, 0600); err != nil {
+				exitWithError(err)
+			}
+			return
+		}
+		os.Stdout.Write(data)
+	},
+}
+
+func init() {
+	envFileEncryptCmd.Flags().String(OUT_FLAG, "", "File the encrypted content is written to.  Default: [file].enc")
+	envFileDecryptCmd.Flags().String(OUT_FLAG, "", "File the decrypted content is written to.  Default: stdout")
+	envFileCmd.AddCommand(envFileEncryptCmd, envFileDecryptCmd)
+
+	envcrypt.Prompt = func() (string, error) {
+		return speakeasy.Ask("Env File Passphrase: ")
+	}
+}
+
+// Returns the passphrase from $DEPCON_ENV_PASSPHRASE or prompts for it
+func envFilePassphrase(verify bool) string {
+	if pass := os.Getenv(envcrypt.PassphraseEnv); pass != "" {
+		return pass
+	}
+	pass, _ := speakeasy.Ask("Env File Passphrase: ")
+	if verify {
+		if again, _ := speakeasy.Ask("Verify Passphrase: "); again != pass {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, errors.New("Passphrase and Verify Passphrase don't match")))
+		}
+	}
+	return pass
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Flag values are resolved in the following order (first wins):
+//
+//  1. command line flags
+//  2. DEPCON_* environment variables
+//  3. project configuration (.depcon.yaml)
+//  4. environment default flags from the config file
+//  5. flag defaults
+//
+// Every flag may be set using DEPCON_ followed by the upper-cased flag name with dashes replaced by
+// underscores (eg. --stop-deploys is DEPCON_STOP_DEPLOYS) except the environment and credential flags
+// which are settings rather than flags and the guardrail flags (--allow-write, --break-glass and --yes)
+// which may only be given on the command line
+const (
+	EnvPrefix = "DEPCON_"
+	// Selects the environment when -e is not specified
+	EnvDepconEnv = "DEPCON_ENV"
+	// Override the host and credentials of the selected environment without modifying the config file
+	EnvDepconHost     = "DEPCON_HOST"
+	EnvDepconUser     = "DEPCON_USER"
+	EnvDepconPassword = "DEPCON_PASSWORD"
+	// Enables tracing of API requests (same as --debug-http)
+	EnvDepconDebug = "DEPCON_DEBUG"
+)
+
+// Flags which share a name with a setting and therefore are never set from the environment
+var reservedEnvFlags = map[string]bool{
+	FlagEnv:    true,
+	"host":     true,
+	"user":     true,
+	"pass":     true,
+	"password": true,
+}
+
+// Returns the environment variable which sets flag {name}
+func envVarForFlag(name string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// Returns the flag values for {cmd} defined by DEPCON_* environment variables
+func flagsFromEnv(cmd *cobra.Command) map[string]string {
+	flags := make(map[string]string)
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if reservedEnvFlags[f.Name] {
+			return
+		}
+		if value, ok := os.LookupEnv(envVarForFlag(f.Name)); ok {
+			flags[f.Name] = value
+		}
+	})
+	return flags
+}
+
+// Applies DEPCON_HOST, DEPCON_USER and DEPCON_PASSWORD to environment {name}.  Only applied for commands
+// which contact a cluster so the overrides are never saved to the config file
+func applyConnectionOverrides(name string) {
+	configEnv, err := configFile.GetEnvironment(name)
+	if err != nil || configEnv.Marathon == nil {
+		return
+	}
+	if host := os.Getenv(EnvDepconHost); host != "" {
+		configEnv.Marathon.HostUrl = host
+	}
+	if user := os.Getenv(EnvDepconUser); user != "" {
+		configEnv.Marathon.Username = user
+	}
+	if pass := os.Getenv(EnvDepconPassword); pass != "" {
+		configEnv.Marathon.Password = pass
+	}
+}
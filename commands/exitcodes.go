@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/ecs"
+	"github.com/ContainX/depcon/kubernetes"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/metronome"
+	"github.com/ContainX/depcon/nomad"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/dcos"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/server"
+	"github.com/ContainX/depcon/swarm"
+)
+
+// Associates the errors returned by the clients and config with the documented exit codes
+func init() {
+	cli.RegisterExitCode(cli.ExitNotFound,
+		httpclient.ErrorNotFound,
+		marathon.ErrorNoAppExists,
+		marathon.ErrorGropAppExists,
+		cliconfig.ErrEnvNotFound,
+		cliconfig.ErrGroupNotFound,
+		ecs.ErrorServiceNotFound,
+		metronome.ErrorNoTasks,
+		mesos.ErrorFrameworkNotFound,
+		backend.ErrorNoInstances,
+	)
+	cli.RegisterExitCode(cli.ExitUsage, cli.ErrConfirmationRequired, server.ErrorNoTokens, server.ErrorNoEnvironments)
+	cli.RegisterExitCode(cli.ExitDeployTimeout, marathon.ErrorTimeout, marathon.ErrorDeploymentNotfound, kubernetes.ErrorTimeout, ecs.ErrorTimeout, nomad.ErrorTimeout, swarm.ErrorTimeout,
+		metronome.ErrorTimeout)
+	cli.RegisterExitCode(cli.ExitDeployFailed, marathon.ErrorDeploymentFailed, ecs.ErrorDeploymentFailed,
+		nomad.ErrorDeploymentFailed, nomad.ErrorEvaluationFailed, nomad.ErrorPlacementFailed,
+		swarm.ErrorUpdateFailed, metronome.ErrorRunFailed)
+	cli.RegisterExitCode(cli.ExitAuth,
+		httpclient.ErrorNotAuthenticated,
+		httpclient.ErrorNotAuthorized,
+		dcos.ErrLoginFailed,
+	)
+}
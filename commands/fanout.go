@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	FlagParallel = "parallel"
+
+	T_FANOUT_RESULTS = `
+{{ "ENVIRONMENT" | header }}	{{ "STATUS" | header }}	{{ "DURATION" | header }}
+{{ range . }}{{ .Env }}	{{ .Status | status }}	{{ .Duration }}
+{{end}}`
+)
+
+// Result of running a command against a single member of an environment group
+type FanOutResult struct {
+	Env      string
+	Status   string
+	Duration string
+	exitCode int
+	output   bytes.Buffer
+}
+
+func init() {
+	rootCmd.PersistentFlags().Bool(FlagParallel, false, "Runs the command against each member of an environment group concurrently")
+}
+
+// Runs the current command once for each environment within {members} by re-invoking depcon with the
+// environment replaced.  Members run sequentially with output streamed unless {parallel} is true in which
+// case output is collected and printed per environment once all have completed.  A result table is printed
+// last and the returned exit code is that of the first member which failed
+func fanOut(group string, members []string, parallel bool) int {
+	exe, err := os.Executable()
+	if err != nil {
+		log.Error("Unable to run against environment group '%s': %s", group, err.Error())
+		return cli.ExitError
+	}
+
+	results := make([]*FanOutResult, len(members))
+	for i, env := range members {
+		results[i] = &FanOutResult{Env: env}
+	}
+
+	if parallel {
+		var wg sync.WaitGroup
+		for _, r := range results {
+			wg.Add(1)
+			go func(r *FanOutResult) {
+				defer wg.Done()
+				runMember(exe, r, &r.output, nil)
+			}(r)
+		}
+		wg.Wait()
+		for _, r := range results {
+			fmt.Printf("\n==> %s\n", r.Env)
+			io.Copy(os.Stdout, &r.output)
+		}
+	} else {
+		for _, r := range results {
+			fmt.Printf("\n==> %s\n", r.Env)
+			runMember(exe, r, os.Stdout, os.Stdin)
+		}
+	}
+
+	cli.Output(templateFor(T_FANOUT_RESULTS, results), nil)
+
+	for _, r := range results {
+		if r.exitCode != cli.ExitSuccess {
+			return r.exitCode
+		}
+	}
+	return cli.ExitSuccess
+}
+
+func runMember(exe string, r *FanOutResult, out io.Writer, in io.Reader) {
+	cmd := exec.Command(exe, argsForEnvironment(os.Args[1:], r.Env)...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Stdin = in
+
+	started := time.Now()
+	err := cmd.Run()
+	r.Duration = time.Since(started).Round(time.Millisecond).String()
+	r.Status = "OK"
+	if err != nil {
+		r.Status = "FAILED"
+		if ee, exited := err.(*exec.ExitError); exited {
+			r.exitCode = ee.ExitCode()
+		} else {
+			r.exitCode = cli.ExitError
+			r.Status = "FAILED: " + err.Error()
+		}
+	}
+}
+
+// Returns {args} with the environment flag replaced by (or set to) {env}
+func argsForEnvironment(args []string, env string) []string {
+	updated := make([]string, 0, len(args)+2)
+	replaced := false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case !replaced && (args[i] == "-e" || args[i] == "--"+FlagEnv) && i+1 < len(args):
+			updated = append(updated, args[i], env)
+			replaced = true
+			i++
+		case !replaced && strings.HasPrefix(args[i], "--"+FlagEnv+"="):
+			updated = append(updated, "--"+FlagEnv+"="+env)
+			replaced = true
+		default:
+			updated = append(updated, args[i])
+		}
+	}
+	if !replaced {
+		updated = append([]string{"-e", env}, updated...)
+	}
+	return updated
+}
+
+// Returns the first command name within os.Args (skipping the environment flag and other flags)
+func firstCommand() string {
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		if arg == "-e" || arg == "--"+FlagEnv {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return arg
+		}
+	}
+	return ""
+}
+
+// Determines if the command only operates on the local configuration (or prints help) and never contacts
+// a cluster.  Local commands are run once when an environment group is targeted
+func isLocalCommand() bool {
+	switch firstCommand() {
+	case "", "config", "schema", "help":
+		return true
+	}
+	return isCompletionCommand()
+}
+
+func hasArg(name string) bool {
+	for _, arg := range os.Args[1:] {
+		if arg == name {
+			return true
+		}
+	}
+	return false
+}
@@ -1,25 +1,84 @@
 package commands
 
 import (
-	"fmt"
+	"bytes"
 	"github.com/ContainX/depcon/pkg/cli"
-	"github.com/ContainX/depcon/pkg/encoding"
 	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/spf13/cobra"
+	"io"
 	"os"
 )
 
 const (
-	FLAG_FORMAT string = "output"
-	TypeJSON    string = "json"
-	TypeYAML    string = "yaml"
-	TypeColumn  string = "column"
+	FLAG_FORMAT  string = "output"
+	FLAG_QUERY   string = "query"
+	FLAG_QUIET   string = "quiet"
+	FLAG_COLOR   string = "no-color"
+	FLAG_PAGER   string = "no-pager"
+	FLAG_SECRETS string = "show-secrets"
+	TypeJSON     string = cli.FormatJSON
+	TypeYAML     string = cli.FormatYAML
+	TypeColumn   string = cli.FormatColumn
+	TypeTable    string = cli.FormatTable
+	TypeCSV      string = cli.FormatCSV
 )
 
 var log = logger.GetLogger("depcon")
 
 func init() {
 	cli.Register(&cli.CLIWriter{FormatWriter: PrintFormat, ErrorWriter: PrintError})
-	rootCmd.PersistentFlags().StringP(FLAG_FORMAT, "o", "column", "Specifies the output format [column | json | yaml]")
+	rootCmd.PersistentFlags().StringP(FLAG_FORMAT, "o", "column", "Specifies the output format [column | table | json | yaml | csv]")
+	rootCmd.PersistentFlags().String(FLAG_QUERY, "", "JSONPath expression applied to the result (eg. '{.tasks[*].host}').  Values are printed one per line unless -o json|yaml")
+	rootCmd.PersistentFlags().BoolP(FLAG_QUIET, "q", false, "Only display identifiers (app, deployment and task IDs) one per line")
+	rootCmd.PersistentFlags().Bool(FLAG_COLOR, false, "Disables colored output.  Output is colored by default when written to a terminal")
+	rootCmd.PersistentFlags().Bool(FLAG_PAGER, false, "Disables paging long output (app, task and group lists, logs) through $PAGER")
+	rootCmd.PersistentFlags().Bool(FLAG_SECRETS, false, "Shows the values of secrets (env vars and fields named like a secret and secret params) which are masked by default")
+	rootCmd.PersistentFlags().String(OUT_FLAG, "", "Saves the result (eg. the application or deployment) to a file as JSON or YAML (.yaml/.yml) while printing the usual output")
+}
+
+// Saves results to the file specified by --out.  The global flag is used since commands such as
+// config export define their own --out
+func configureOutputFile(cmd *cobra.Command) {
+	out, _ := cmd.Root().PersistentFlags().GetString(OUT_FLAG)
+	cli.SetOutputFile(out)
+}
+
+// Masks secrets within output and logs unless --show-secrets is specified.  The config file's secretPatterns
+// add to the names of env vars, fields and params considered secrets
+func configureMasking(cmd *cobra.Command) {
+	if configFile != nil {
+		mask.AddPatterns(configFile.SecretPatterns...)
+	}
+	show, _ := cmd.Flags().GetBool(FLAG_SECRETS)
+	mask.SetEnabled(!show)
+}
+
+// Enables colored output based on the --no-color flag and the config file's color mode and theme
+func configureColor(cmd *cobra.Command) {
+	mode := cli.ColorAuto
+	if configFile != nil && configFile.Color != "" {
+		mode = configFile.Color
+	}
+	if noColor, _ := cmd.Flags().GetBool(FLAG_COLOR); noColor {
+		mode = cli.ColorNever
+	}
+
+	if configFile != nil {
+		theme, err := cli.LookupTheme(configFile.Theme)
+		if err != nil {
+			log.Warning("%s.  Using the default theme", err.Error())
+			theme, _ = cli.LookupTheme("")
+		}
+		cli.SetTheme(theme.With(configFile.Colors))
+	}
+	cli.EnableColor(cli.ShouldColor(mode, os.Stdout))
+}
+
+// Enables paging for commands annotated with cli.AnnotationPaged unless --no-pager is specified
+func configurePager(cmd *cobra.Command) {
+	noPager, _ := cmd.Flags().GetBool(FLAG_PAGER)
+	cli.EnablePager(!noPager && cmd.Annotations[cli.AnnotationPaged] == "true")
 }
 
 func getFormatType() string {
@@ -36,30 +95,52 @@ func getFormatType() string {
 }
 
 func PrintError(err error) {
-	log.Error("%v", err.Error())
-	os.Exit(1)
+	cli.StopProgress()
+	if getFormatType() == TypeJSON {
+		cli.WriteErrorReport(os.Stderr, err)
+	} else {
+		log.Error("%v", err.Error())
+	}
+	cli.Exit(err)
 }
 
 func PrintFormat(formatter cli.Formatter) {
-	switch getFormatType() {
-	case TypeJSON:
-		printEncodedType(formatter, encoding.JSON)
-	case TypeYAML:
-		printEncodedType(formatter, encoding.YAML)
-	default:
-		printColumn(formatter)
+	cli.StopProgress()
+	if err := cli.SaveOutput(formatter.Data().Data); err != nil {
+		PrintError(err)
 	}
+	if expr, _ := rootCmd.PersistentFlags().GetString(FLAG_QUERY); expr != "" {
+		printQuery(expr, formatter.Data().Data)
+		return
+	}
+	if quiet, _ := rootCmd.PersistentFlags().GetBool(FLAG_QUIET); quiet {
+		if err := cli.WriteIdentifiers(os.Stdout, formatter.Data().Data); err != nil {
+			log.Error("Error: %s", err.Error())
+		}
+		return
+	}
+	out, done := cli.StartPager(os.Stdout)
+	defer done()
+	buf := &bytes.Buffer{}
+	if err := cli.Write(buf, formatter, getFormatType()); err != nil {
+		log.Error("Error: %s", err.Error())
+	}
+	io.WriteString(out, mask.Text(buf.String()))
 }
 
-func printEncodedType(formatter cli.Formatter, encoder encoding.EncoderType) {
-	e, _ := encoding.NewEncoder(encoder)
-	str, _ := e.MarshalIndent(formatter.Data().Data)
-	fmt.Println(str)
-}
-
-func printColumn(formatter cli.Formatter) {
-	err := formatter.ToColumns(os.Stdout)
+// Prints the values within {data} selected by the JSONPath expression {expr}
+func printQuery(expr string, data interface{}) {
+	q, err := cli.ParseQuery(expr)
 	if err != nil {
+		PrintError(err)
+	}
+	results, err := q.Apply(data)
+	if err != nil {
+		PrintError(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := cli.WriteQueryResults(buf, results, getFormatType()); err != nil {
 		log.Error("Error: %s", err.Error())
 	}
+	os.Stdout.WriteString(mask.Values(buf.String()))
 }
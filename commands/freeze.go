@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/freeze"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagBreakGlass    = "break-glass"
+	ActionBreakGlass  = "break-glass"
+	breakGlassDetails = "command"
+)
+
+// Refuses changes to environments during their freeze windows or outside their change windows.  Changes
+// are checked as they're sent so commands touching several environments (eg. pipelines) are checked against
+// the environment each change is sent to.  With --break-glass the changes are made and the first change to
+// each frozen environment is recorded in the audit log along with the reason
+func configureFreeze(cmd *cobra.Command) {
+	httpclient.SetWriteGuard(nil)
+	if configFile == nil || !hasChangeRestrictions() {
+		return
+	}
+	reason, _ := cmd.Flags().GetString(FlagBreakGlass)
+	current := viper.GetString(ViperEnv)
+
+	var mu sync.Mutex
+	recorded := map[string]bool{}
+	httpclient.SetWriteGuard(func(method, rawurl string) error {
+		envName := environmentForURL(rawurl, current)
+		configEnv, err := configFile.GetEnvironment(envName)
+		if err != nil {
+			return nil
+		}
+		now := time.Now()
+		err = freeze.Check(envName, configEnv.Freeze, now)
+		if err == nil {
+			err = configEnv.ChangeWindows.Check(envName, now)
+		}
+
+		var window string
+		switch e := err.(type) {
+		case *freeze.FrozenError:
+			window = e.Window.String()
+		case *freeze.OutsideWindowError:
+			window = e.Windows.String()
+			if configEnv.ChangeWindows.Warns() {
+				mu.Lock()
+				defer mu.Unlock()
+				if !recorded[envName] {
+					recorded[envName] = true
+					log.Warning("%s", e.Error())
+				}
+				return nil
+			}
+		}
+		if window == "" || strings.TrimSpace(reason) == "" {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if !recorded[envName] {
+			recorded[envName] = true
+			recordBreakGlass(cmd, envName, window, reason)
+		}
+		return nil
+	})
+}
+
+func hasChangeRestrictions() bool {
+	for _, configEnv := range configFile.Environments {
+		if configEnv != nil && (len(configEnv.Freeze) > 0 || configEnv.ChangeWindows != nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the environment with a service at the host of {rawurl} or {current} when none match
+func environmentForURL(rawurl, current string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		return current
+	}
+	if configEnv, ok := configFile.Environments[current]; ok && hasHost(configEnv, u.Host) {
+		return current
+	}
+	for name, configEnv := range configFile.Environments {
+		if hasHost(configEnv, u.Host) {
+			return name
+		}
+	}
+	return current
+}
+
+func hasHost(configEnv *cliconfig.ConfigEnvironment, host string) bool {
+	if configEnv == nil {
+		return false
+	}
+	urls := []string{}
+	if s := configEnv.Marathon; s != nil {
+		urls = append(urls, marathon.SplitHosts(s.HostUrl)...)
+		urls = append(urls, s.MetronomeUrl, s.ChronosUrl, s.MesosUrl, s.MarathonLBUrl)
+	}
+	if configEnv.Nomad != nil {
+		urls = append(urls, configEnv.Nomad.Address)
+	}
+	if configEnv.ECS != nil {
+		urls = append(urls, configEnv.ECS.Endpoint)
+	}
+	for _, s := range urls {
+		if u, err := url.Parse(s); err == nil && u.Host != "" && strings.EqualFold(u.Host, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Records a change made to an environment during a freeze (or outside its change windows) in the audit log
+func recordBreakGlass(cmd *cobra.Command, envName, window, reason string) {
+	log.Warning("Breaking the freeze of environment '%s' (%s): %s", envName, window, reason)
+
+	auditLog, _ := cmd.Flags().GetString(FlagAuditLog)
+	if auditLog == "" {
+		auditLog = filepath.Join(cliconfig.ConfigDir(), audit.DefaultFilename)
+	}
+	l := audit.New(auditLog)
+	err := l.Record(&audit.Entry{
+		Environment: envName,
+		Action:      ActionBreakGlass,
+		Target:      window,
+		Result:      audit.ResultSuccess,
+		Message:     reason,
+		Details:     map[string]string{breakGlassDetails: cmd.CommandPath()},
+	})
+	if err != nil {
+		log.Error("Unable to write the audit log %s: %s", l.Filename(), err.Error())
+	}
+}
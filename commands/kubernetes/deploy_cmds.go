@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/kubernetes"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+const (
+	WAIT_FLAG    string = "wait"
+	TIMEOUT_FLAG string = "wait-timeout"
+)
+
+var log = logger.GetLogger("depcon.kubernetes")
+
+var k8sDeployCmd = &cobra.Command{
+	Use:   "deploy [file(.json | .yaml)]",
+	Short: "Creates or updates the Deployments and Services within a descriptor",
+	Long: `Creates or updates the Deployments and Services within a descriptor
+
+    The descriptor is rendered with the template context and ${PARAMS} exactly as Marathon
+    descriptors are.  YAML descriptors may contain multiple documents separated by '---' which
+    are applied in order.  Existing resources are replaced by the document`,
+	Run: deployResources,
+}
+
+func init() {
+	k8sDeployCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the deployments to roll out and become available")
+	k8sDeployCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the deployments to become available (ex. 90s | 2m)")
+	marathon.ApplyDescriptorFlags(k8sDeployCmd)
+}
+
+func deployResources(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	docs := marathon.RenderDocuments(cmd, args[0])
+
+	applied := []*kubernetes.AppliedResource{}
+	for i, doc := range docs {
+		reportBulkStep(i+1, len(docs), documentLabel(doc))
+		result, err := client(cmd).Apply(doc)
+		if err != nil {
+			cli.StopProgress()
+			log.Error("Unable to apply %s", documentLabel(doc))
+			exitWithError(err)
+		}
+		applied = append(applied, result)
+	}
+	cli.StopProgress()
+
+	if wait {
+		for _, r := range applied {
+			if r.Kind != kubernetes.KIND_DEPLOYMENT {
+				continue
+			}
+			if err := client(cmd).WaitForDeployment(r.Name, waitTimeout(cmd)); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+	cli.Output(templateFor(T_APPLIED, applied), nil)
+}
+
+// Returns kind/name of the document {doc} (eg. deployment/api)
+func documentLabel(doc map[string]interface{}) string {
+	kind, _ := doc["kind"].(string)
+	metadata, _ := doc["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	return strings.ToLower(kind) + "/" + name
+}
+
+// Reports {step} of {total} of a bulk deployment which is drawn with the progress on a terminal and
+// logged otherwise
+func reportBulkStep(step, total int, label string) {
+	if total < 2 {
+		return
+	}
+	if !cli.ActiveProgress().Step(step, total, label) {
+		log.Info("[%d/%d] %s", step, total, label)
+	}
+}
+
+// Returns --wait-timeout or kubernetes.DefaultTimeout when unspecified
+func waitTimeout(cmd *cobra.Command) time.Duration {
+	if timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG); timeout > 0 {
+		return timeout
+	}
+	return kubernetes.DefaultTimeout
+}
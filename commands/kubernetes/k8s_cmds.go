@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/kubernetes"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	KUBECONFIG_FLAG  string = "kubeconfig"
+	CONTEXT_FLAG     string = "context"
+	NAMESPACE_FLAG   string = "namespace"
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	ENV_NAME         string = "env_name"
+)
+
+var (
+	k8sCmd = &cobra.Command{
+		Use:   "k8s",
+		Short: "Manage Kubernetes deployments and services",
+		Long: `Manage Kubernetes deployments and services using the same templated descriptors,
+params and template contexts as Marathon
+
+    The cluster is selected by the kubeconfig ($KUBECONFIG or ~/.kube/config), the 'kubernetes'
+    block of the environment or the --kubeconfig, --context and --namespace flags
+
+    See k8s's subcommands for available choices`,
+	}
+	kubeClient kubernetes.Kubernetes
+	configFile *cliconfig.ConfigFile
+)
+
+// Associates the kubernetes commands to the given command
+func AddKubernetesToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
+	backend.Register(cliconfig.TypeKubernetes, newBackend)
+	rc.AddCommand(k8sCmd)
+}
+
+func init() {
+	k8sCmd.PersistentFlags().String(KUBECONFIG_FLAG, "", "Path to the kubeconfig overriding the environment and $KUBECONFIG")
+	k8sCmd.PersistentFlags().String(CONTEXT_FLAG, "", "Kubeconfig context overriding the environment and the current-context")
+	k8sCmd.PersistentFlags().StringP(NAMESPACE_FLAG, "n", "", "Namespace overriding the environment and the context")
+	k8sCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	k8sCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	k8sCmd.AddCommand(k8sDeployCmd, k8sDeploymentCmd, k8sServiceCmd)
+}
+
+func client(cmd *cobra.Command) kubernetes.Kubernetes {
+	if kubeClient == nil {
+		o := overrides{}
+		o.kubeconfig, _ = cmd.Flags().GetString(KUBECONFIG_FLAG)
+		o.context, _ = cmd.Flags().GetString(CONTEXT_FLAG)
+		o.namespace, _ = cmd.Flags().GetString(NAMESPACE_FLAG)
+		o.insecure, _ = cmd.Flags().GetBool(INSECURE_FLAG)
+		o.allowWrite, _ = cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+		c, err := newClient(viper.GetString(ENV_NAME), o)
+		if err != nil {
+			exitWithError(err)
+		}
+		kubeClient = c
+	}
+	return kubeClient
+}
+
+// Settings of the invocation overriding those of the environment
+type overrides struct {
+	kubeconfig, context, namespace string
+	insecure, allowWrite           bool
+}
+
+// Creates the client of environment {envName} falling back to the kubeconfig for settings neither the
+// environment nor {o} specify
+func newClient(envName string, o overrides) (kubernetes.Kubernetes, error) {
+	settings := &cliconfig.KubernetesConfig{}
+	readOnly := false
+	if configFile != nil {
+		if env, err := configFile.GetEnvironment(envName); err == nil {
+			if env.Kubernetes != nil {
+				*settings = *env.Kubernetes
+			}
+			readOnly = env.Marathon != nil && env.Marathon.ReadOnly
+		}
+	}
+	if o.kubeconfig != "" {
+		settings.Kubeconfig = o.kubeconfig
+	}
+	if o.context != "" {
+		settings.Context = o.context
+	}
+	if o.namespace != "" {
+		settings.Namespace = o.namespace
+	}
+
+	config, err := kubernetes.LoadKubeConfig(settings.Kubeconfig, settings.Context)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Namespace != "" {
+		config.Namespace = settings.Namespace
+	}
+	if o.insecure {
+		config.Insecure = true
+	}
+
+	opts := &kubernetes.KubernetesOptions{Retry: httpclient.DefaultRetryPolicy()}
+	opts.ReadOnly = readOnly && !o.allowWrite
+	if progress := cli.ActiveProgress(); progress != nil {
+		opts.Progress = progress
+	}
+	return kubernetes.NewKubernetesClient(config, opts), nil
+}
+
+// Creates the backend of the workload commands.  The kubeconfig is only fallen back to when the backend
+// is selected explicitly
+func newBackend(ctx *backend.Context) (backend.ClusterBackend, error) {
+	if !ctx.Selected && !configured(ctx.EnvName) {
+		return nil, &backend.NotConfiguredError{Backend: cliconfig.TypeKubernetes, EnvName: ctx.EnvName}
+	}
+	c, err := newClient(ctx.EnvName, overrides{insecure: ctx.Insecure, allowWrite: ctx.AllowWrite})
+	if err != nil {
+		return nil, err
+	}
+	return backend.NewKubernetesBackend(c), nil
+}
+
+// Returns true if environment {envName} has a 'kubernetes' block
+func configured(envName string) bool {
+	if configFile == nil {
+		return false
+	}
+	env, err := configFile.GetEnvironment(envName)
+	return err == nil && env.Kubernetes != nil
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
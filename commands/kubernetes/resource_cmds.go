@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var (
+	k8sDeploymentCmd = &cobra.Command{
+		Use:     "deployment",
+		Aliases: []string{"deployments"},
+		Short:   "Manage Kubernetes deployments",
+		Long: `Manage Kubernetes deployments within the namespace
+
+    See deployment's subcommands for available choices`,
+	}
+
+	k8sDeploymentListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all deployments within the namespace",
+		Run: func(cmd *cobra.Command, args []string) {
+			v, e := client(cmd).ListDeployments()
+			cli.Output(templateFor(T_DEPLOYMENTS, v), e)
+		},
+	}
+
+	k8sDeploymentGetCmd = &cobra.Command{
+		Use:   "get [name]",
+		Short: "Gets a deployment details by name",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).GetDeployment(args[0])
+			cli.Output(templateFor(T_DEPLOYMENT, v), e)
+		},
+	}
+
+	k8sDeploymentScaleCmd = &cobra.Command{
+		Use:   "scale [name] [replicas]",
+		Short: "Scales a deployment to the number of replicas",
+		Run:   scaleDeployment,
+	}
+
+	k8sDeploymentDestroyCmd = &cobra.Command{
+		Use:   "destroy [name]",
+		Short: "Removes a deployment and its pods",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			cli.ConfirmOrExit(fmt.Sprintf("Destroy deployment '%s' and its pods", args[0]), "namespace", client(cmd).Namespace())
+			if err := client(cmd).DeleteDeployment(args[0]); err != nil {
+				exitWithError(err)
+			}
+			fmt.Printf("deployment/%s deleted\n", args[0])
+		},
+	}
+
+	k8sServiceCmd = &cobra.Command{
+		Use:     "service",
+		Aliases: []string{"services", "svc"},
+		Short:   "Manage Kubernetes services",
+		Long: `Manage Kubernetes services within the namespace
+
+    See service's subcommands for available choices`,
+	}
+
+	k8sServiceListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all services within the namespace",
+		Run: func(cmd *cobra.Command, args []string) {
+			v, e := client(cmd).ListServices()
+			cli.Output(templateFor(T_SERVICES, v), e)
+		},
+	}
+
+	k8sServiceGetCmd = &cobra.Command{
+		Use:   "get [name]",
+		Short: "Gets a service details by name",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).GetService(args[0])
+			cli.Output(templateFor(T_SERVICE, v), e)
+		},
+	}
+
+	k8sServiceDestroyCmd = &cobra.Command{
+		Use:   "destroy [name]",
+		Short: "Removes a service",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			cli.ConfirmOrExit(fmt.Sprintf("Destroy service '%s'", args[0]), "namespace", client(cmd).Namespace())
+			if err := client(cmd).DeleteService(args[0]); err != nil {
+				exitWithError(err)
+			}
+			fmt.Printf("service/%s deleted\n", args[0])
+		},
+	}
+)
+
+func init() {
+	k8sDeploymentScaleCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the deployment to become available")
+	k8sDeploymentScaleCmd.Flags().DurationP(TIMEOUT_FLAG, "t", 0, "Max duration to wait for the deployment to become available (ex. 90s | 2m)")
+	k8sDeploymentCmd.AddCommand(k8sDeploymentListCmd, k8sDeploymentGetCmd, k8sDeploymentScaleCmd, k8sDeploymentDestroyCmd)
+	k8sServiceCmd.AddCommand(k8sServiceListCmd, k8sServiceGetCmd, k8sServiceDestroyCmd)
+}
+
+func scaleDeployment(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+		return
+	}
+
+	replicas, err := strconv.Atoi(args[1])
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	if replicas == 0 {
+		cli.ConfirmOrExit(fmt.Sprintf("Scale deployment '%s' to 0 replicas", args[0]), "namespace", client(cmd).Namespace())
+	}
+
+	v, e := client(cmd).ScaleDeployment(args[0], replicas)
+	if e != nil {
+		exitWithError(e)
+	}
+	if wait, _ := cmd.Flags().GetBool(WAIT_FLAG); wait {
+		if err := client(cmd).WaitForDeployment(args[0], waitTimeout(cmd)); err != nil {
+			exitWithError(err)
+		}
+	}
+	cli.Output(templateFor(T_SCALE, v), nil)
+}
@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/ContainX/depcon/kubernetes"
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_DEPLOYMENTS = `
+{{ "NAME" | header }}	{{ "READY" | header }}	{{ "UP-TO-DATE" | header }}	{{ "AVAILABLE" | header }}	{{ "IMAGES" | header }}	{{ "CREATED" | header }}
+{{ range .Items }}{{ .Metadata.Name }}	{{ . | ready }}	{{ .Status.UpdatedReplicas | intToString }}	{{ .Status.AvailableReplicas | intToString }}	{{ . | images }}	{{ .Metadata.CreationTimestamp }}
+{{end}}`
+
+	T_DEPLOYMENT = `
+{{ "Name:" }}	{{ .Metadata.Name }}
+{{ "Namespace:" }}	{{ .Metadata.Namespace }}
+{{ "Created:" }}	{{ .Metadata.CreationTimestamp }}
+{{ "Images:" }}	{{ . | images }}
+{{ "Replicas:" }}	{{ "Desired" | pad }} {{ .DesiredReplicas | intToString }}
+	{{ "Updated" | pad }} {{ .Status.UpdatedReplicas | intToString }}
+	{{ "Ready" | pad }} {{ .Status.ReadyReplicas | intToString }}
+	{{ "Available" | pad }} {{ .Status.AvailableReplicas | intToString }}
+	{{ "Unavailable" | pad }} {{ .Status.UnavailableReplicas | intToString }}
+{{ "Labels:" }}
+{{ range $key, $value := .Metadata.Labels }}		{{ $key | pad }} {{ $value }}
+{{end}}`
+
+	T_SERVICES = `
+{{ "NAME" | header }}	{{ "TYPE" | header }}	{{ "CLUSTER-IP" | header }}	{{ "PORTS" | header }}	{{ "CREATED" | header }}
+{{ range .Items }}{{ .Metadata.Name }}	{{ .Spec.Type }}	{{ .Spec.ClusterIP }}	{{ .Spec.Ports | ports }}	{{ .Metadata.CreationTimestamp }}
+{{end}}`
+
+	T_SERVICE = `
+{{ "Name:" }}	{{ .Metadata.Name }}
+{{ "Namespace:" }}	{{ .Metadata.Namespace }}
+{{ "Type:" }}	{{ .Spec.Type }}
+{{ "Cluster IP:" }}	{{ .Spec.ClusterIP }}
+{{ "Ports:" }}	{{ .Spec.Ports | ports }}
+{{ "Selector:" }}
+{{ range $key, $value := .Spec.Selector }}		{{ $key | pad }} {{ $value }}
+{{end}}`
+
+	T_SCALE = `
+{{ "NAME" | header }}	{{ "DESIRED" | header }}	{{ "CURRENT" | header }}
+{{ .Metadata.Name }}	{{ .Spec.Replicas | intToString }}	{{ .Status.Replicas | intToString }}`
+
+	T_APPLIED = `
+{{ "RESOURCE" | header }}	{{ "NAMESPACE" | header }}	{{ "ACTION" | header }}
+{{ range . }}{{ .Kind | lower }}/{{ .Name }}	{{ .Namespace }}	{{ .Action }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data, Funcs: buildFuncMap()}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
+
+func buildFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"ready":  ready,
+		"images": images,
+		"ports":  ports,
+		"lower":  strings.ToLower,
+	}
+}
+
+func ready(d *kubernetes.Deployment) string {
+	return fmt.Sprintf("%d/%d", d.Status.ReadyReplicas, d.DesiredReplicas())
+}
+
+func images(d *kubernetes.Deployment) string {
+	list := []string{}
+	for _, c := range d.Spec.Template.Spec.Containers {
+		list = append(list, c.Image)
+	}
+	return strings.Join(list, ",")
+}
+
+// Formats ports as kubectl does (eg. 80:30080/TCP)
+func ports(ports []*kubernetes.ServicePort) string {
+	list := []string{}
+	for _, p := range ports {
+		port := fmt.Sprintf("%d", p.Port)
+		if p.NodePort > 0 {
+			port = fmt.Sprintf("%s:%d", port, p.NodePort)
+		}
+		if p.Protocol != "" {
+			port = port + "/" + p.Protocol
+		}
+		list = append(list, port)
+	}
+	return strings.Join(list, ",")
+}
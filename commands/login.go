@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/login"
+	"github.com/bgentry/speakeasy"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagLoginUser     = "user"
+	FlagPasswordStdin = "password-stdin"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Logs into an environment fronted by an identity-aware proxy",
+	Long: `Logs into the environment (-e) storing the token sent to its identity-aware proxy in the OS keyring (or
+the config directory when the keyring is disabled).
+
+Environments with auth oidc use the device code flow: open the URL shown, enter the code and approve the
+login.  Their tokens are refreshed automatically until the refresh token expires.  Environments with auth
+ldap exchange a username and password for a token which lasts until it expires.
+
+    eg. depcon login -e prod
+        echo "$PASSWORD" | depcon login -e stage --user ci --password-stdin`,
+	Run: runLogin,
+}
+
+func init() {
+	loginCmd.Flags().String(FlagLoginUser, "", "Username of an ldap login.  Default: the environment's username")
+	loginCmd.Flags().Bool(FlagPasswordStdin, false, "Reads the password of an ldap login from stdin rather than prompting")
+	loginCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks against the identity provider")
+}
+
+func runLogin(cmd *cobra.Command, args []string) {
+	name := viper.GetString(ViperEnv)
+	env, err := configFile.GetEnvironment(name)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	service := env.Marathon
+	if service == nil || !service.UsesLogin() {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Environment '%s' does not log in with %s or %s (see config env update --auth)", name, cliconfig.AuthOIDC, cliconfig.AuthLDAP)))
+	}
+	if err := service.Login.Validate(service.Auth); err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	insecure, _ := cmd.Flags().GetBool(cmdmarathon.INSECURE_FLAG)
+	client := cmdmarathon.LoginClient(service, insecure)
+
+	var token *login.Token
+	if service.Auth == cliconfig.AuthOIDC {
+		oidc := &login.OIDC{Config: service.Login, Client: client, Prompt: printDeviceCode}
+		token, err = oidc.Login(cli.Context())
+	} else {
+		username, password := loginCredentials(cmd, service)
+		token, err = (&login.LDAP{Config: service.Login, Client: client}).Login(username, password)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+	if err := login.Save(cliconfig.TokenStore{}, name, token); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("\nLogged into environment '%s' until %s\n", name, token.Expires.Local().Format("2006-01-02 15:04:05 MST"))
+}
+
+func printDeviceCode(code *login.DeviceCode) {
+	if code.VerificationURIComplete != "" {
+		fmt.Printf("Open %s to approve the login (code %s)\n", code.VerificationURIComplete, code.UserCode)
+	} else {
+		fmt.Printf("Open %s and enter the code %s to approve the login\n", code.VerificationURI, code.UserCode)
+	}
+	fmt.Println("Waiting for the login to be approved...")
+}
+
+// Returns the username and password of an ldap login prompting for those not given
+func loginCredentials(cmd *cobra.Command, service *cliconfig.ServiceConfig) (string, string) {
+	username, _ := cmd.Flags().GetString(FlagLoginUser)
+	if username == "" {
+		username = service.Username
+	}
+	if username == "" {
+		fmt.Print("Username: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		username = strings.TrimSpace(line)
+	}
+
+	if stdin, _ := cmd.Flags().GetBool(FlagPasswordStdin); stdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s requires the password on stdin", FlagPasswordStdin)))
+		}
+		return username, strings.TrimRight(line, "\r\n")
+	}
+	password, err := speakeasy.Ask("Password: ")
+	if err != nil {
+		exitWithError(err)
+	}
+	return username, password
+}
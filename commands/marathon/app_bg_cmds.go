@@ -4,7 +4,6 @@ import (
 	"github.com/ContainX/depcon/marathon/bluegreen"
 	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/spf13/cobra"
-	"os"
 	"strings"
 	"time"
 )
@@ -28,7 +27,7 @@ var bgCmd = &cobra.Command{
 }
 
 func init() {
-	bgCmd.Flags().String(LB_FLAG, "http://localhost:9090", "HAProxy URL and Stats Port")
+	bgCmd.Flags().String(LB_FLAG, DEFAULT_LB, "HAProxy URL and Stats Port.  Defaults to the environment's Marathon-LB URL")
 	bgCmd.Flags().Int(LB_TIMEOUT_FLAG, 300, "HAProxy timeout - default 300 seconds")
 	bgCmd.Flags().Int(INSTANCES_FLAG, 1, "Initial intances of the app to create")
 	bgCmd.Flags().Int(STEP_DELAY_FLAG, 6, "Delay (in seconds) to wait between successive deployment steps. ")
@@ -41,6 +40,7 @@ func init() {
                   eg. -p MYVAR=value would replace ${MYVAR} with "value" in the application file.
                   These take precidence over env vars`)
 	bgCmd.Flags().Bool(BG_DRYRUN_FLAG, false, "Dry run (no deployment or scaling)")
+	ApplySignatureFlags(bgCmd)
 
 }
 
@@ -48,10 +48,11 @@ func deployBlueGreenCmd(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
 		return
 	}
+	VerifySignatures(cmd, args[0])
 	a, err := bgc(cmd).DeployBlueGreenFromFile(args[0])
 	if err != nil {
 		cli.Output(nil, err)
-		os.Exit(1)
+		return
 	}
 	cli.Output(templateFor(T_APPLICATION, a), err)
 }
@@ -67,7 +68,7 @@ func bgc(c *cobra.Command) bluegreen.BlueGreen {
 	// Create Options
 	opts := bluegreen.NewBlueGreenOptions()
 	opts.Resume, _ = c.Flags().GetBool(RESUME_FLAG)
-	opts.LoadBalancer, _ = c.Flags().GetString(LB_FLAG)
+	opts.LoadBalancer = lbURL(c)
 	opts.InitialInstances, _ = c.Flags().GetInt(INSTANCES_FLAG)
 	opts.ErrorOnMissingParams = !ignore
 	opts.StepDelay = time.Duration(sd) * time.Second
@@ -75,7 +76,10 @@ func bgc(c *cobra.Command) bluegreen.BlueGreen {
 	opts.DryRun, _ = c.Flags().GetBool(BG_DRYRUN_FLAG)
 
 	if paramsFile != "" {
-		envParams, _ := parseParamsFile(paramsFile)
+		envParams, err := ParseParamsFile(paramsFile)
+		if err != nil {
+			exitWithError(err)
+		}
 		opts.EnvParams = envParams
 	} else {
 		opts.EnvParams = make(map[string]string)
@@ -84,7 +88,7 @@ func bgc(c *cobra.Command) bluegreen.BlueGreen {
 	if params != nil {
 		for _, p := range params {
 			if strings.Contains(p, "=") {
-				v := strings.Split(p, "=")
+				v := strings.SplitN(p, "=", 2)
 				opts.EnvParams[v[0]] = v[1]
 			}
 		}
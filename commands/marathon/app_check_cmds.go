@@ -0,0 +1,128 @@
+package marathon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/spf13/cobra"
+)
+
+const (
+	TARGET_FLAG    = "target"
+	CONTAINER_FLAG = "container"
+
+	T_HEALTH_PROBES = `
+{{ "#" | header }}	{{ "PROTOCOL" | header }}	{{ "TARGET" | header }}	{{ "HEALTHY" | header }}	{{ "LATENCY" | header }}	{{ "MESSAGE" | header }}
+{{ range $i, $r := . }}{{ $i | intToString }}	{{ $r.Protocol }}	{{ $r.Target }}	{{ $r.Healthy }}	{{ $r.Latency }}	{{ $r.Message }}
+{{end}}`
+)
+
+var appCheckCmd = &cobra.Command{
+	Use:   "check [file(.json | .yaml) | applicationId]",
+	Short: "Runs the health checks of an application against a locally running container",
+	Long: `Runs the HTTP, TCP and COMMAND health checks declared by the application descriptor (or the deployed
+application) against a container running locally so the checks can be confirmed before the descriptor is
+deployed.  Exits with a non-zero status if any check fails.
+
+    eg. depcon app check app.json --target http://localhost:8080
+        depcon app check app.json --target localhost:8080 --target localhost:9090 --container web`,
+	Run: checkAppHealth,
+}
+
+func init() {
+	appCheckCmd.Flags().StringSlice(TARGET_FLAG, nil, `Address the container listens on (eg. http://localhost:8080).  Repeat for each port
+                  of the application: checks use the target at their portIndex`)
+	appCheckCmd.Flags().String(CONTAINER_FLAG, "", "Docker container COMMAND checks are run within (with docker exec).  They run on the local shell when unset")
+	appCheckCmd.Flags().String(TEMPLATE_CTX_FLAG, "", "Provides data per environment in JSON form to do a first pass parse of descriptor as template")
+	appCheckCmd.Flags().StringSliceP(PARAMS_FLAG, "p", nil, `Adds a param(s) that can be used for substitution.
+                  eg. -p MYVAR=value would replace ${MYVAR} with "value" in the application file.`)
+}
+
+func checkAppHealth(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	targets, _ := cmd.Flags().GetStringSlice(TARGET_FLAG)
+	container, _ := cmd.Flags().GetString(CONTAINER_FLAG)
+
+	app := loadCheckedApp(cmd, args[0])
+	if len(app.HealthChecks) == 0 {
+		exitWithError(fmt.Errorf("Application '%s' declares no health checks", app.ID))
+	}
+	opts := marathon.ProbeOptions{}
+	if container != "" {
+		opts.Exec = []string{"docker", "exec", container}
+	}
+
+	results := []*marathon.ProbeResult{}
+	failed := 0
+	for _, hc := range app.HealthChecks {
+		if hc.Protocol != "COMMAND" && len(targets) == 0 {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s is required by %s health checks", TARGET_FLAG, hc.Protocol)))
+		}
+		opts.Target = probeTarget(targets, hc.PortIndex)
+		result := hc.Probe(opts)
+		if !result.Healthy {
+			failed++
+		}
+		results = append(results, result)
+	}
+	cli.Output(templateFor(T_HEALTH_PROBES, results), nil)
+	if failed > 0 {
+		exitWithError(fmt.Errorf("%d of %d health check(s) of '%s' failed", failed, len(results), app.ID))
+	}
+}
+
+// Returns the target of the port {index} falling back to the first target
+func probeTarget(targets []string, index int) string {
+	if index < len(targets) {
+		return targets[index]
+	}
+	if len(targets) > 0 {
+		return targets[0]
+	}
+	return ""
+}
+
+// Loads the application descriptor {arg} or the deployed application {arg} when no such file exists
+func loadCheckedApp(cmd *cobra.Command, arg string) *marathon.Application {
+	if _, err := os.Stat(arg); err != nil {
+		app, err := client(cmd).GetApplication(arg)
+		if err != nil {
+			exitWithError(err)
+		}
+		return app
+	}
+
+	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
+	params, _ := cmd.Flags().GetStringSlice(PARAMS_FLAG)
+	envParams := make(map[string]string)
+	for _, p := range params {
+		if strings.Contains(p, "=") {
+			v := strings.SplitN(p, "=", 2)
+			envParams[v[0]] = v[1]
+		}
+	}
+
+	et, err := encoding.EncoderTypeFromExt(arg)
+	if err != nil {
+		exitWithError(err)
+	}
+	enc, _ := encoding.NewEncoder(et)
+	docs := encoding.SplitDocuments(et, parseDescriptor(tempctx, arg, nil, true))
+	if len(docs) == 0 {
+		exitWithError(fmt.Errorf("%s contains no application", arg))
+	}
+	parsed, _ := envsubst.SubstTokens(strings.NewReader(docs[0]), envParams)
+
+	app := new(marathon.Application)
+	if err := enc.UnMarshalStr(parsed, app); err != nil {
+		exitWithError(err)
+	}
+	return app
+}
@@ -1,28 +1,41 @@
 package marathon
 
 import (
-	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ContainX/depcon/marathon"
 	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/dotenv"
 	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/ContainX/depcon/pkg/policy"
+	"github.com/ContainX/depcon/pkg/schema"
+	"github.com/ContainX/depcon/pkg/workpool"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 const (
 	HOST_FLAG         = "host"
 	SCALE_FLAG        = "scale"
 	FORMAT_FLAG       = "format"
+	STREAM_FLAG       = "stream"
+	BY_GROUP_FLAG     = "by-group"
 	TEMPLATE_CTX_FLAG = "tempctx"
 	DEFAULT_CTX       = "template-context.json"
 	STOP_DEPLOYS_FLAG = "stop-deploys"
+	EACH_FLAG         = "each"
+	EXPECT_VERSION    = "expect-version"
+	GROUP_BY_FLAG     = "group-by"
 )
 
 var appCmd = &cobra.Command{
@@ -50,6 +63,24 @@ var appUpdateCPUCmd = &cobra.Command{
 	Run:   updateAppCPU,
 }
 
+var appUpdatePatchCmd = &cobra.Command{
+	Use:   "patch [applicationId] [patch | @file | -]",
+	Short: "Changes the fields of [applicationId] within a JSON merge patch",
+	Long: `Applies a JSON merge patch to [applicationId] (eg. '{"instances": 3, "env": {"DEBUG": null}}').  Objects are
+merged with the application, null removes a field and other values replace it.  The patch is read from a
+file with @file or from stdin with -.
+
+With --expect-version the patch is only applied if the application is still at that version so changes
+made by others since it was read aren't overwritten`,
+	Run: patchApp,
+}
+
+var appUpdateImageCmd = &cobra.Command{
+	Use:   "image [applicationId | --selector selector] [image]",
+	Short: "Updates the container image of [applicationId] or of every application matching --selector",
+	Run:   updateAppImage,
+}
+
 var appUpdateMemoryCmd = &cobra.Command{
 	Use:   "mem [applicationId] [amount]",
 	Short: "Updates [applicationId] to have [amount] of memory in MB",
@@ -64,6 +95,26 @@ var appListCmd = &cobra.Command{
 		if len(args) > 0 {
 			filter = args[0]
 		}
+		if byGroup, _ := cmd.Flags().GetBool(BY_GROUP_FLAG); byGroup {
+			if strings.Contains(filter, "=") {
+				exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s accepts a group id rather than a filter", BY_GROUP_FLAG)))
+			}
+			streamApplications(cmd, func(fn func(app *marathon.Application) error) error {
+				return client(cmd).ListApplicationsByGroup(filter, fn)
+			})
+			return
+		}
+		if groupBy, _ := cmd.Flags().GetString(GROUP_BY_FLAG); groupBy != "" {
+			listGroupedApps(cmd, filter, groupBy)
+			return
+		}
+		// a query needs the complete result so streaming is bypassed
+		if stream, _ := cmd.Flags().GetBool(STREAM_FLAG); stream && !queried(cmd) && !savesOutput(cmd) {
+			streamApplications(cmd, func(fn func(app *marathon.Application) error) error {
+				return client(cmd).ListApplicationsStream(filter, fn)
+			})
+			return
+		}
 		v, e := client(cmd).ListApplicationsWithFilters(filter)
 
 		cli.Output(templateFor(templateFormat(T_APPLICATIONS, cmd), v), e)
@@ -78,8 +129,10 @@ var appGetCmd = &cobra.Command{
 		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
 			return
 		}
-		v, e := client(cmd).GetApplication(args[0])
-		cli.Output(templateFor(templateFormat(T_APPLICATION, cmd), v), e)
+		outputOrWatch(cmd, marathon.EventIDApplications|marathon.EventIDDeployments, func() (cli.Formatter, error) {
+			v, e := client(cmd).GetApplication(args[0])
+			return templateFor(templateFormat(T_APPLICATION, cmd), v), e
+		})
 	},
 }
 
@@ -97,22 +150,24 @@ var appVersionsCmd = &cobra.Command{
 }
 
 var appDestroyCmd = &cobra.Command{
-	Use:   "destroy [applicationId]",
+	Use:   "destroy [applicationId | --selector selector]",
 	Short: "Remove an application [applicationId] and all of it's instances",
-	Long:  `Removes the specified [appliationId] application`,
-	Run:   destroyApp,
+	Long: `Removes the specified [appliationId] application or every application matching the --selector
+(eg. label=team=payments) once confirmed`,
+	Run: destroyApp,
 }
 
 var appRestartCmd = &cobra.Command{
-	Use:   "restart [applicationId]",
+	Use:   "restart [applicationId | --selector selector]",
 	Short: "Restarts an application by Id",
-	Long:  `Restarts the specified [appliationId] application`,
-	Run:   restartApp,
+	Long: `Restarts the specified [appliationId] application or every application matching the --selector
+(eg. label=team=payments) using up to --workers at once`,
+	Run: restartApp,
 }
 
 var appScaleCmd = &cobra.Command{
-	Use:   "scale [applicationId] [instances]",
-	Short: "Scales [appliationId] to total [instances]",
+	Use:   "scale [applicationId | --selector selector] [instances]",
+	Short: "Scales [appliationId] (or every application matching --selector) to total [instances]",
 	Run:   scaleApp,
 }
 
@@ -124,14 +179,22 @@ var appRollbackCmd = &cobra.Command{
 }
 
 var appConvertFileCmd = &cobra.Command{
-	Use:   "convert [from.(json | yaml)] [to.(json | yaml)]",
-	Short: "Utilty to convert an application file from json to yaml or yaml to json.",
+	Use:   "convert [from.(json | yaml | toml)] [to.(json | yaml | toml)]",
+	Short: "Utilty to convert an application file between json, yaml and toml.",
 	Run:   convertFile,
 }
 
+var appValidateCmd = &cobra.Command{
+	Use:   "validate [file.(json | yaml | toml)]",
+	Short: "Validates an application file against the application schema reporting the path of each error",
+	Long: `Validates an application file against the application schema reporting the path of each error.  Properties
+the schema doesn't know (eg. misspelt fields or Marathon fields depcon doesn't use) are reported as warnings`,
+	Run: validateAppFile,
+}
+
 func init() {
-	appUpdateCmd.AddCommand(appUpdateCPUCmd, appUpdateMemoryCmd)
-	appCmd.AddCommand(appListCmd, appGetCmd, logCmd, appCreateCmd, appUpdateCmd, appDestroyCmd, appRollbackCmd, bgCmd, appRestartCmd, appScaleCmd, appVersionsCmd, appConvertFileCmd)
+	appUpdateCmd.AddCommand(appUpdateCPUCmd, appUpdateMemoryCmd, appUpdatePatchCmd, appUpdateImageCmd)
+	appCmd.AddCommand(appListCmd, appGetCmd, logCmd, appCreateCmd, appUpdateCmd, appDestroyCmd, appRollbackCmd, bgCmd, appRestartCmd, appScaleCmd, appVersionsCmd, appConvertFileCmd, appValidateCmd, appCheckCmd, appPromoteCmd, appDiffCmd, appDiffEnvCmd, appSnapshotCmd, appRestoreCmd, appWatchCmd, appDeployStackCmd)
 
 	// Create Flags
 	appCreateCmd.Flags().String(TEMPLATE_CTX_FLAG, "", "Provides data per environment in JSON form to do a first pass parse of descriptor as template")
@@ -146,15 +209,51 @@ func init() {
                   These take precidence over env vars`)
 
 	appCreateCmd.Flags().Bool(DRYRUN_FLAG, false, "Preview the parsed template - don't actually deploy")
-	appListCmd.Flags().String(FORMAT_FLAG, "", "Custom output format. Example: '{{range .Apps}}{{ .Container.Docker.Image }}{{end}}'")
-	appGetCmd.Flags().String(FORMAT_FLAG, "", "Custom output format. Example: '{{ .ID }}'")
-	applyCommonAppFlags(appCreateCmd, appUpdateCPUCmd, appUpdateMemoryCmd, appRollbackCmd, appDestroyCmd, appRestartCmd, appScaleCmd)
+	appCreateCmd.Flags().String(EACH_FLAG, "", `Renders and deploys an application for every element in the template context list at this path (eg. .tenants).
+                  The current element is available within the descriptor as {{ .item }} and it's position as {{ .index }}.
+                  The applications deploy as a batch: each is waited on and once one fails those already deployed are rolled back`)
+	applyPolicyFlags(appCreateCmd)
+	ApplySignatureFlags(appCreateCmd)
+	ApplyRemoteFlags(appCreateCmd)
+	applyPreflightFlags(appCreateCmd, appScaleCmd)
+	applyWorkersFlags(appCreateCmd, appRestartCmd, appDestroyCmd, appScaleCmd, appUpdateImageCmd)
+	applyPostDeployFlags(appCreateCmd)
+	applyEnvironmentFlags(appCreateCmd)
+	applyScheduleFlags(appCreateCmd)
+	appRestartCmd.Flags().String(LABEL_FLAG, "", "Restarts every application matching the label selector (eg. team==web) in place of [applicationId]")
+	appRestartCmd.Flags().MarkDeprecated(LABEL_FLAG, "use --"+SELECTOR_FLAG+" label=...")
+	applySelectorFlags("Restarts", appRestartCmd)
+	applySelectorFlags("Destroys", appDestroyCmd)
+	applySelectorFlags("Scales", appScaleCmd)
+	applySelectorFlags("Updates the image of", appUpdateImageCmd)
+	appValidateCmd.Flags().String(TEMPLATE_CTX_FLAG, "", "Provides data per environment in JSON form to do a first pass parse of descriptor as template")
+	appValidateCmd.Flags().StringSliceP(PARAMS_FLAG, "p", nil, `Adds a param(s) that can be used for substitution.
+                  eg. -p MYVAR=value would replace ${MYVAR} with "value" in the application file.`)
+	applyFormatFlag(appListCmd, "{{range .Apps}}{{ .Container.Docker.Image }}{{end}}")
+	appListCmd.Flags().Bool(STREAM_FLAG, false, `Render applications as they are received rather than after the entire list has been read.
+                  Useful for very large clusters. When combined with --format the template is applied to each application`)
+	appListCmd.Flags().Bool(BY_GROUP_FLAG, false, `Streams the applications one group at a time (implies --stream) so clusters with thousands of applications
+                  are listed without reading them in a single response.  The optional argument is the group to list`)
+	appListCmd.Flags().String(GROUP_BY_FLAG, "", `Lists the applications under a heading per label value (eg. label:team), group (group) or group at a
+                  depth of the id (eg. group:1) with the instances, cpus and memory allocated to each`)
+	applyFormatFlag(appGetCmd, "{{ .ID }}")
+	appUpdatePatchCmd.Flags().String(EXPECT_VERSION, "", "Refuses the patch if the application is no longer at this version")
+	appUpdatePatchCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Applies the patch even if the application is locked by a deployment")
+	for _, c := range []*cobra.Command{appCreateCmd, appRestartCmd, appScaleCmd} {
+		c.Flags().String(WAIT_HEALTHY, "", `Waits until this number (eg. 3) or percentage (eg. 75%) of the instances are healthy
+                  rather than for the entire deployment to complete.  Implies --wait`)
+	}
+	applyCommonAppFlags(appCreateCmd, appUpdateCPUCmd, appUpdateMemoryCmd, appUpdatePatchCmd, appUpdateImageCmd, appRollbackCmd, appDestroyCmd, appRestartCmd, appScaleCmd)
 }
 
 func createApp(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
 		return
 	}
+	filename, cleanup := FetchDescriptor(cmd, args[0])
+	defer cleanup()
+	args[0] = filename
+	VerifySignatures(cmd, args[0])
 
 	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
 	force, _ := cmd.Flags().GetBool(FORCE_FLAG)
@@ -164,11 +263,20 @@ func createApp(cmd *cobra.Command, args []string) {
 	stop_deploy, _ := cmd.Flags().GetBool(STOP_DEPLOYS_FLAG)
 	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
 	dryrun, _ := cmd.Flags().GetBool(DRYRUN_FLAG)
+	each, _ := cmd.Flags().GetString(EACH_FLAG)
 
 	options := &marathon.CreateOptions{Wait: wait, Force: force, ErrorOnMissingParams: !ignore, StopDeploy: stop_deploy, DryRun: dryrun}
+	options.WaitHealthy = waitQuorum(cmd)
+	checker := policyChecker(cmd)
+	defer checker.Close()
+	options.Prepare = registryCredentials(cmd)
+	options.Validate = createChecks(cmd, checker)
 
 	if paramsFile != "" {
-		envParams, _ := parseParamsFile(paramsFile)
+		envParams, err := ParseParamsFile(paramsFile)
+		if err != nil {
+			exitWithError(err)
+		}
 		options.EnvParams = envParams
 	} else {
 		options.EnvParams = make(map[string]string)
@@ -177,110 +285,425 @@ func createApp(cmd *cobra.Command, args []string) {
 	if params != nil {
 		for _, p := range params {
 			if strings.Contains(p, "=") {
-				v := strings.Split(p, "=")
+				v := strings.SplitN(p, "=", 2)
 				options.EnvParams[v[0]] = v[1]
 			}
 		}
 	}
 
+	envs, _ := cmd.Flags().GetStringSlice(ENVS_FLAG)
+	if !scheduledTime(cmd).IsZero() && (len(envs) > 0 || each != "") {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s and --%s can't be combined with --%s or --%s", AT_FLAG, IN_FLAG, ENVS_FLAG, EACH_FLAG)))
+	}
+
+	if len(envs) > 0 {
+		createAppInEnvironments(cmd, args[0], tempctx, options)
+		return
+	}
+
+	if each != "" {
+		createAppForEach(cmd, args[0], tempctx, each, options)
+		return
+	}
+
+	// the descriptor is rendered once so a deferred deployment deploys what was validated
+	descriptor := parseDescriptor(tempctx, args[0], options.EnvParams, ignore)
+	docs := []string{descriptor}
+	if et, err := encoding.EncoderTypeFromExt(args[0]); err == nil && et == encoding.YAML {
+		docs = encoding.SplitDocuments(et, descriptor)
+	}
+	deferDeployment(cmd, args[0], docs, options)
+	if len(docs) > 1 {
+		createApps(cmd, args[0], docs, options, false)
+		return
+	}
+
 	var result *marathon.Application = nil
 	var e error
 
-	if TemplateExists(tempctx) {
-		b := &bytes.Buffer{}
-
-		r, err := LoadTemplateContext(tempctx)
-		if err != nil {
-			exitWithError(err)
-		}
+	post := postDeployOf(cmd)
+	var deployed *deployment
+	if post != nil {
+		options, deployed = post.options(client(cmd), options)
+	}
 
-		if err := r.Transform(b, args[0]); err != nil {
-			exitWithError(err)
-		}
-		result, e = client(cmd).CreateApplicationFromString(args[0], b.String(), options)
-	} else {
-		result, e = client(cmd).CreateApplicationFromFile(args[0], options)
+	result, e = client(cmd).CreateApplicationFromString(args[0], descriptor, options)
+	if perr, ok := e.(*envsubst.MissingParamsError); ok {
+		perr.Filename = args[0]
 	}
-	if e != nil && e == marathon.ErrorAppExists {
+	if errors.Is(e, marathon.ErrorAppExists) {
 		exitWithError(errors.New(fmt.Sprintf("%s, consider using the --force flag to update when an application exists", e.Error())))
 	}
+	if post != nil {
+		e = post.finish(client(cmd), deployed, result, e)
+		post.report()
+		if e != nil {
+			exitWithError(e)
+		}
+	}
 
 	if result == nil {
 		if e != nil {
-			fmt.Printf("[ERROR] %s\n", e.Error())
+			exitWithError(e)
 		}
-		os.Exit(1)
+		cli.ExitWithCode(cli.ExitError)
 	}
 	cli.Output(templateFor(T_APPLICATION, result), e)
 }
 
+// Renders the descriptor for every element found at the {each} path within the template context and
+// deploys the resulting applications as a batch.  Every application is parsed before any is deployed and
+// once one fails those already deployed are rolled back
+func createAppForEach(cmd *cobra.Command, filename, tempctx, each string, options *marathon.CreateOptions) {
+	if !TemplateExists(tempctx) {
+		exitWithError(fmt.Errorf("--%s requires a template context (--%s) containing the list '%s'", EACH_FLAG, TEMPLATE_CTX_FLAG, each))
+	}
+
+	r, err := LoadTemplateContext(tempctx)
+	if err != nil {
+		exitWithError(err)
+	}
+	r.ErrorOnMissing, r.Params = options.ErrorOnMissingParams, options.EnvParams
+
+	descriptors, err := r.TransformEach(filename, each)
+	if err != nil {
+		exitWithError(err)
+	}
+	if err := parseEach(filename, each, descriptors, options.EnvParams); err != nil {
+		exitWithError(err)
+	}
+	createApps(cmd, filename, descriptors, options, true)
+}
+
+// Parses the application rendered for every element of {each} so a descriptor which doesn't parse, or two
+// elements deploying the same application, fail the batch before any application is deployed
+func parseEach(filename, each string, descriptors []string, params map[string]string) error {
+	et, err := encoding.EncoderTypeFromExt(filename)
+	if err != nil {
+		return err
+	}
+	encoder, err := encoding.NewEncoder(et)
+	if err != nil {
+		return err
+	}
+
+	// values aren't needed to find the ids so secrets aren't resolved here
+	resolver := func(s string) string {
+		if v := params[s]; v != "" {
+			return v
+		}
+		return os.Getenv(s)
+	}
+	ids := map[string]int{}
+	for idx, descriptor := range descriptors {
+		app := new(marathon.Application)
+		if err := encoder.UnMarshalStr(envsubst.Substitute(strings.NewReader(descriptor), true, resolver), app); err != nil {
+			return fmt.Errorf("Element %d of '%s': %s", idx, each, err.Error())
+		}
+		if prev, exists := ids[app.ID]; exists {
+			return fmt.Errorf("Elements %d and %d of '%s' both deploy the application '%s'", prev, idx, each, app.ID)
+		}
+		ids[app.ID] = idx
+	}
+	return nil
+}
+
+// Returns the checks (policies and the capacity preflight) enabled by the flags of {cmd} which rendered
+// applications must pass before they're created or nil when there are none
+func createChecks(cmd *cobra.Command, checker *policy.Checker) func(app *marathon.Application) error {
+	checks := []func(app *marathon.Application) error{}
+	if checker.Enabled() {
+		checks = append(checks, validateWithPolicies(checker))
+	}
+	if mode := preflightMode(cmd); mode != "" {
+		checks = append(checks, validateWithPreflight(cmd, mode))
+	}
+	if len(checks) == 0 {
+		return nil
+	}
+	return func(app *marathon.Application) error {
+		for _, check := range checks {
+			if err := check(app); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Deploys the applications of {descriptors}.  A {batch} deploys as a whole: once an application fails the
+// rest are skipped and those already deployed are restored to their previous version (or removed)
+func createApps(cmd *cobra.Command, filename string, descriptors []string, options *marathon.CreateOptions, batch bool) {
+	if options.DryRun {
+		for idx, descriptor := range descriptors {
+			parsed, _ := envsubst.SubstFileTokens(strings.NewReader(descriptor), options.EnvParams)
+			fmt.Printf("Create Application :: DryRun :: Template Output [%d]\n\n%s\n", idx, mask.Text(parsed))
+		}
+		return
+	}
+
+	c := client(cmd)
+	post := postDeployOf(cmd)
+	if batch {
+		post = batchPostDeployOf(cmd)
+	}
+	created := make([]*marathon.Application, len(descriptors))
+	deployments := make([]*deployment, len(descriptors))
+	var failed int32
+	tasks := []*workpool.Task{}
+	for idx, descriptor := range descriptors {
+		idx, descriptor := idx, descriptor
+		tasks = append(tasks, &workpool.Task{Name: fmt.Sprintf("%s[%d]", filename, idx), Run: func() error {
+			if batch && atomic.LoadInt32(&failed) != 0 {
+				return ErrorBatchSkipped
+			}
+			opts, deployed := options, (*deployment)(nil)
+			if post != nil {
+				opts, deployed = post.options(c, options)
+			}
+			result, e := c.CreateApplicationFromString(filename, descriptor, opts)
+			if errors.Is(e, marathon.ErrorAppExists) {
+				e = fmt.Errorf("%s, consider using the --force flag to update when an application exists", e.Error())
+			}
+			if post != nil {
+				e = post.finish(c, deployed, result, e)
+			}
+			if e != nil {
+				atomic.StoreInt32(&failed, 1)
+			}
+			created[idx], deployments[idx] = result, deployed
+			return e
+		}})
+	}
+	results := runBulk(cmd, "Deploying applications from "+filename, tasks)
+	if batch && workpool.Err(results) != nil {
+		post.restoreBatch(c, deployments, results)
+	}
+	if post != nil {
+		post.report()
+	}
+
+	apps := &marathon.Applications{Apps: []marathon.Application{}}
+	for idx, app := range created {
+		if app != nil {
+			results[idx].Name = app.ID
+			apps.Apps = append(apps.Apps, *app)
+		}
+	}
+	if err := workpool.Err(results); err != nil {
+		cli.Output(templateFor(T_BULK_RESULTS, results), nil)
+		exitWithError(err)
+	}
+	cli.Output(templateFor(T_APPLICATIONS, apps), nil)
+}
+
+// Runs {tasks} on the number of workers set by --workers reporting the progress as {label}
+func runBulk(cmd *cobra.Command, label string, tasks []*workpool.Task) []*workpool.Result {
+	workers, _ := cmd.Flags().GetInt(WORKERS_FLAG)
+	return workpool.Run(workers, tasks, func(completed, total int, r *workpool.Result) {
+		reportBulkStep(completed, total, label)
+	})
+}
+
+func applyWorkersFlags(cmd ...*cobra.Command) {
+	for _, c := range cmd {
+		c.Flags().Int(WORKERS_FLAG, 1, "Number of applications operated on at once.  Every application is attempted and failures are summarized")
+	}
+}
+
 func exitWithError(err error) {
 	cli.Output(nil, err)
-	os.Exit(1)
+	cli.Exit(err)
 }
 
-func parseParamsFile(filename string) (map[string]string, error) {
-	paramsFile, err := os.Open(filename)
-	if err != nil {
-		return nil, err
+// Reports {step} of {total} of a bulk deployment which is drawn with the progress on a terminal and
+// logged otherwise
+func reportBulkStep(step, total int, label string) {
+	if total < 2 {
+		return
+	}
+	if !cli.ActiveProgress().Step(step, total, label) {
+		log.Info("[%d/%d] %s", step, total, label)
+	}
+}
+
+// Asks the user to confirm a destructive action against the current environment exiting unless confirmed.
+// {describe} returns the action (eg. "Destroy application '/web'") and is only called when prompting
+// so --yes doesn't incur the lookups it may require
+func confirmOrExit(describe func() (string, error)) {
+	if !cli.ConfirmationsEnabled() {
+		return
 	}
-	bytes, err := ioutil.ReadAll(paramsFile)
+	action, err := describe()
 	if err != nil {
-		return nil, err
+		exitWithError(err)
 	}
-	data := string(bytes)
-	params := strings.Split(data, "\n")
+	cli.ConfirmOrExit(action, "environment", viper.GetString(ENV_NAME))
+}
 
+// Parses the params file {filename} (a .env style file or a YAML or JSON document of params).  A comma
+// separated list of files may be specified in which case values from later files override earlier ones.
+// Encrypted files are decrypted in memory
+func ParseParamsFile(filename string) (map[string]string, error) {
 	envmap := make(map[string]string)
-	for _, p := range params {
-		if strings.Contains(p, "=") {
-			v := strings.Split(p, "=")
-			envmap[v[0]] = v[1]
+	for _, name := range strings.Split(filename, ",") {
+		params, err := dotenv.ParseFile(name)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range params {
+			envmap[k] = v
 		}
 	}
 	return envmap, nil
 }
 
 func restartApp(cmd *cobra.Command, args []string) {
+	if selector := selectorOf(cmd); selector != "" && len(args) == 0 {
+		force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+		apps := selectApps(cmd, selector, "Restart")
+		runSelected(cmd, "Restarting applications matching "+selector, apps, func(app *marathon.Application) (string, error) {
+			v, err := client(cmd).RestartApplication(app.ID, force)
+			if err != nil {
+				return "", err
+			}
+			return v.DeploymentID, nil
+		})
+		return
+	}
 	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
-		os.Exit(1)
+		return
 	}
 
 	force, _ := cmd.Flags().GetBool(FORCE_FLAG)
 
 	v, e := client(cmd).RestartApplication(args[0], force)
 	cli.Output(templateFor(T_DEPLOYMENT_ID, v), e)
-	waitForDeploymentIfFlagged(cmd, v.DeploymentID)
+	waitForDeploymentIfFlagged(cmd, args[0], v.DeploymentID)
 }
 
 func destroyApp(cmd *cobra.Command, args []string) {
+	if selector := selectorOf(cmd); selector != "" && len(args) == 0 {
+		apps := selectApps(cmd, selector, "Destroy")
+		runSelected(cmd, "Destroying applications matching "+selector, apps, func(app *marathon.Application) (string, error) {
+			v, err := client(cmd).DestroyApplication(app.ID)
+			if err != nil {
+				return "", err
+			}
+			return v.DeploymentID, nil
+		})
+		return
+	}
 	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
-		os.Exit(1)
+		return
 	}
 
+	confirmOrExit(func() (string, error) {
+		app, err := client(cmd).GetApplication(args[0])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Destroy application '%s' (%d instances)", app.ID, app.Instances), nil
+	})
 	v, e := client(cmd).DestroyApplication(args[0])
 	cli.Output(templateFor(T_DEPLOYMENT_ID, v), e)
-	waitForDeploymentIfFlagged(cmd, v.DeploymentID)
+	waitForDeploymentIfFlagged(cmd, args[0], v.DeploymentID)
 }
 
 func scaleApp(cmd *cobra.Command, args []string) {
+	if selector := selectorOf(cmd); selector != "" && len(args) == 1 {
+		scaleApps(cmd, selector, args[0])
+		return
+	}
 	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
-		os.Exit(1)
+		return
 	}
 
 	instances, err := strconv.Atoi(args[1])
 	if err != nil {
 		cli.Output(nil, err)
-		os.Exit(1)
+		return
+	}
+	if instances == 0 {
+		confirmOrExit(func() (string, error) {
+			app, err := client(cmd).GetApplication(args[0])
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Scale application '%s' from %d to 0 instances", app.ID, app.Instances), nil
+		})
+	}
+	if mode := preflightMode(cmd); mode != "" {
+		app, err := client(cmd).GetApplication(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		if err := preflight(cmd, mode, app, instances-app.Instances); err != nil {
+			exitWithError(err)
+		}
 	}
 	v, e := client(cmd).ScaleApplication(args[0], instances)
 	cli.Output(templateFor(T_DEPLOYMENT_ID, v), e)
-	waitForDeploymentIfFlagged(cmd, v.DeploymentID)
+	waitForDeploymentIfFlagged(cmd, args[0], v.DeploymentID)
+}
+
+// Scales every application matching {selector} to {count} instances
+func scaleApps(cmd *cobra.Command, selector, count string) {
+	instances, err := strconv.Atoi(count)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("'%s' is not a number of instances", count)))
+	}
+	mode := preflightMode(cmd)
+	apps := selectApps(cmd, selector, fmt.Sprintf("Scale to %d instances", instances))
+	runSelected(cmd, "Scaling applications matching "+selector, apps, func(app *marathon.Application) (string, error) {
+		if err := preflight(cmd, mode, app, instances-app.Instances); err != nil {
+			return "", err
+		}
+		v, err := client(cmd).ScaleApplication(app.ID, instances)
+		if err != nil {
+			return "", err
+		}
+		return v.DeploymentID, nil
+	})
+}
+
+func updateAppImage(cmd *cobra.Command, args []string) {
+	if selector := selectorOf(cmd); selector != "" && len(args) == 1 {
+		apps := selectApps(cmd, selector, fmt.Sprintf("Update the image to %s of", args[0]))
+		runSelected(cmd, "Updating the image of applications matching "+selector, apps, func(app *marathon.Application) (string, error) {
+			v, err := updateImage(cmd, app, args[0], false)
+			if err != nil || len(v.DeploymentID) == 0 {
+				return "", err
+			}
+			return v.DeploymentID[0]["id"], nil
+		})
+		return
+	}
+	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+		return
+	}
+	app, err := client(cmd).GetApplication(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	v, e := updateImage(cmd, app, args[1], wait)
+	cli.Output(templateFor(T_APPLICATION, v), e)
+}
+
+// Patches the container image of {app} to {image}.  Only the image is changed so the rest of the
+// container (eg. port mappings and volumes) is kept
+func updateImage(cmd *cobra.Command, app *marathon.Application, image string, wait bool) (*marathon.Application, error) {
+	if app.Container == nil || app.Container.Docker == nil {
+		return nil, fmt.Errorf("Application '%s' does not run a container image", app.ID)
+	}
+	patch := map[string]interface{}{"container": map[string]interface{}{"docker": map[string]interface{}{"image": image}}}
+	return client(cmd).UpdateApplicationPartial(app.ID, patch, &marathon.PatchOptions{Wait: wait})
 }
 
 func updateAppCPU(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
-		os.Exit(1)
+		return
 	}
 
 	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
@@ -288,7 +711,7 @@ func updateAppCPU(cmd *cobra.Command, args []string) {
 
 	if err != nil {
 		cli.Output(nil, err)
-		os.Exit(1)
+		return
 	}
 	update := marathon.NewApplication(args[0]).CPU(cpu)
 	v, e := client(cmd).UpdateApplication(update, wait)
@@ -297,7 +720,7 @@ func updateAppCPU(cmd *cobra.Command, args []string) {
 
 func updateAppMemory(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
-		os.Exit(1)
+		return
 	}
 
 	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
@@ -305,16 +728,56 @@ func updateAppMemory(cmd *cobra.Command, args []string) {
 
 	if err != nil {
 		cli.Output(nil, err)
-		os.Exit(1)
+		return
 	}
 	update := marathon.NewApplication(args[0]).Memory(mem)
 	v, e := client(cmd).UpdateApplication(update, wait)
 	cli.Output(templateFor(T_APPLICATION, v), e)
 }
 
+func patchApp(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+		return
+	}
+
+	patch, err := readPatch(args[1])
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	opts := &marathon.PatchOptions{}
+	opts.Wait, _ = cmd.Flags().GetBool(WAIT_FLAG)
+	opts.Force, _ = cmd.Flags().GetBool(FORCE_FLAG)
+	opts.Version, _ = cmd.Flags().GetString(EXPECT_VERSION)
+
+	v, e := client(cmd).UpdateApplicationPartial(args[0], patch, opts)
+	cli.Output(templateFor(T_APPLICATION, v), e)
+}
+
+// Reads the JSON merge patch {arg} which is the patch itself, @file or - for stdin
+func readPatch(arg string) (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	switch {
+	case arg == "-":
+		data, err = ioutil.ReadAll(os.Stdin)
+	case strings.HasPrefix(arg, "@"):
+		data, err = ioutil.ReadFile(strings.TrimPrefix(arg, "@"))
+	default:
+		data = []byte(arg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, fmt.Errorf("The patch must be a JSON object: %s", err.Error())
+	}
+	return patch, nil
+}
+
 func rollbackAppVersion(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
-		os.Exit(1)
+		return
 	}
 
 	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
@@ -335,33 +798,226 @@ func rollbackAppVersion(cmd *cobra.Command, args []string) {
 
 func convertFile(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
-		os.Exit(1)
+		return
 	}
 	if err := encoding.ConvertFile(args[0], args[1], &marathon.Application{}); err != nil {
 		cli.Output(nil, err)
-		os.Exit(1)
+		return
 	}
 	fmt.Printf("Source file %s has been re-written into new format in %s\n\n", args[0], args[1])
 }
 
-func waitForDeploymentIfFlagged(cmd *cobra.Command, depId string) {
+// Lists the applications matching {filter} grouped by {groupBy} (eg. label:team) with the totals of each group
+func listGroupedApps(cmd *cobra.Command, filter, groupBy string) {
+	apps, err := client(cmd).ListApplicationsWithFilters(filter)
+	if err != nil {
+		exitWithError(err)
+	}
+	grouped, err := apps.GroupBy(groupBy)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	cli.Output(templateFor(templateFormat(T_APPLICATIONS_GROUPED, cmd), grouped), nil)
+}
+
+// Number of rows written between flushes when streaming column output
+const streamFlushRows = 50
+
+// Lists applications with {list} rendering each as it is decoded from the response.  Column output is
+// flushed every streamFlushRows rows so alignment is maintained within each batch
+func streamApplications(cmd *cobra.Command, list func(fn func(app *marathon.Application) error) error) {
+	var err error
+
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		err = list(func(app *marathon.Application) error {
+			_, err := fmt.Fprintln(os.Stdout, app.ID)
+			return err
+		})
+		if err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	out, done := cli.StartPager(os.Stdout)
+
+	switch outputFormat(cmd) {
+	case "json", "yaml":
+		et := encoding.JSON
+		if outputFormat(cmd) == "yaml" {
+			et = encoding.YAML
+		}
+		enc := encoding.NewStreamEncoder(et, out)
+		err = list(func(app *marathon.Application) error {
+			return enc.Encode(app)
+		})
+	case cli.FormatCSV:
+		enc := cli.NewCSVEncoder(out)
+		err = list(func(app *marathon.Application) error {
+			return enc.Encode(app)
+		})
+		enc.Flush()
+		if err == nil {
+			err = enc.Error()
+		}
+	default:
+		row := templateFormat(T_APPLICATION_ROW, cmd)
+		t, terr := cli.NewTemplate(row+"\n", buildFuncMap())
+		if terr != nil {
+			done()
+			exitWithError(terr)
+		}
+
+		w := cli.NewTabWriter(out)
+		if row == T_APPLICATION_ROW {
+			header, _ := cli.NewTemplate("\n"+T_APPLICATIONS_HEADER+"\n", nil)
+			header.Execute(w, nil)
+		}
+		count := 0
+		err = list(func(app *marathon.Application) error {
+			if err := t.Execute(w, app); err != nil {
+				return err
+			}
+			if count++; count%streamFlushRows == 0 {
+				w.Flush()
+			}
+			return nil
+		})
+		cli.FlushWriter(w)
+	}
+
+	done()
+	if err != nil {
+		exitWithError(err)
+	}
+}
+
+func queried(cmd *cobra.Command) bool {
+	f := cmd.Flags().Lookup("query")
+	return f != nil && f.Value.String() != ""
+}
+
+// Determines if the result is saved to a file (--out) and must be output as a whole
+func savesOutput(cmd *cobra.Command) bool {
+	f := cmd.Flags().Lookup("out")
+	return f != nil && f.Value.String() != ""
+}
+
+func outputFormat(cmd *cobra.Command) string {
+	if f := cmd.Flags().Lookup("output"); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	if configFile != nil && configFile.Format != "" {
+		return configFile.Format
+	}
+	return "column"
+}
+
+func validateAppFile(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
+	params, _ := cmd.Flags().GetStringSlice(PARAMS_FLAG)
+
+	envParams := make(map[string]string)
+	for _, p := range params {
+		if strings.Contains(p, "=") {
+			v := strings.SplitN(p, "=", 2)
+			envParams[v[0]] = v[1]
+		}
+	}
+
+	et, err := encoding.EncoderTypeFromExt(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	enc, _ := encoding.NewEncoder(et)
+	s := schema.Generate(&marathon.Application{})
+
+	failed := false
+	docs := encoding.SplitDocuments(et, parseDescriptor(tempctx, args[0], nil, true))
+	for idx, doc := range docs {
+		parsed, _ := envsubst.SubstTokens(strings.NewReader(doc), envParams)
+
+		var data interface{}
+		if err := enc.UnMarshalStr(parsed, &data); err != nil {
+			exitWithError(err)
+		}
+
+		errs := s.Validate(data)
+		if len(schema.Errors(errs)) == 0 {
+			// the schema only checks the shape of the descriptor so apply the checks Marathon performs
+			app := new(marathon.Application)
+			if err := enc.UnMarshalStr(parsed, app); err != nil {
+				exitWithError(err)
+			}
+			if err := app.Validate(); err != nil {
+				for _, d := range err.(*marathon.ValidationError).Details {
+					errs = append(errs, schema.ValidationError{Path: d.Path, Message: strings.Join(d.Errors, ", ")})
+				}
+			}
+		}
+
+		for _, verr := range errs {
+			name := args[0]
+			if len(docs) > 1 {
+				name = fmt.Sprintf("%s [%d]", args[0], idx)
+			}
+			if verr.Warning {
+				fmt.Printf("%s: warning: %s\n", name, verr.Error())
+				continue
+			}
+			failed = true
+			fmt.Printf("%s: %s\n", name, verr.Error())
+		}
+	}
+
+	if failed {
+		cli.ExitWithCode(cli.ExitError)
+	}
+	fmt.Printf("%s is valid\n", args[0])
+}
+
+// Waits for deployment {depId} of application {appID} when --wait is given or, with --wait-healthy, for
+// the number of healthy instances of the application
+func waitForDeploymentIfFlagged(cmd *cobra.Command, appID, depId string) {
+	if quorum := waitQuorum(cmd); quorum != nil {
+		if err := client(cmd).WaitForHealthyTasks(appID, *quorum, WaitTimeout(cmd, marathon.DefaultTimeout)); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
 	if found, err := cmd.Flags().GetBool(WAIT_FLAG); err == nil && found {
-		client(cmd).WaitForDeployment(depId, time.Duration(80)*time.Second)
+		if result := client(cmd).WaitForDeploymentResult(depId, WaitTimeout(cmd, marathon.DefaultTimeout)); !result.Converged() {
+			exitWithWaitResult(result)
+		}
 	}
 }
 
 func applyCommonAppFlags(cmd ...*cobra.Command) {
 	for _, c := range cmd {
 		c.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for application to become healthy")
-		c.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for application health (ex. 90s | 2m). See docs for ordering")
+		c.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for application health (ex. 90s | 2m).  0 waits forever. See docs for ordering")
 	}
 }
 
+// Adds --format to {cmd} rendering its output with a custom template such as {example}
+func applyFormatFlag(cmd *cobra.Command, example string) {
+	cmd.Flags().String(FORMAT_FLAG, "", fmt.Sprintf("Custom output format (eg. '%s') or @name of a format defined in the config (see 'config format')", example))
+	cmd.RegisterFlagCompletionFunc(FORMAT_FLAG, completeFormats)
+}
+
+// Returns the template of --format or {template} when it isn't specified.  Formats defined in the config
+// are referenced by name (eg. --format @images)
 func templateFormat(template string, cmd *cobra.Command) string {
-	t := template
 	tv, _ := cmd.Flags().GetString(FORMAT_FLAG)
-	if len(tv) > 0 {
-		t = tv
+	if len(tv) == 0 {
+		return template
+	}
+	t, err := configFile.ResolveFormat(tv)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
 	}
 	return t
 }
@@ -1,12 +1,29 @@
 package marathon
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
 	l "log"
+	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathontest"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/workpool"
+	"github.com/ContainX/depcon/registry"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestParseParamFile(t *testing.T) {
-	envParams, _ := parseParamsFile("resources/test.env")
+	envParams, _ := ParseParamsFile("resources/test.env")
 	el, ok := envParams["APP1_VERSION"]
 	if !ok && el != "3" {
 		l.Printf("Actual envParams %v", envParams)
@@ -18,3 +35,270 @@ func TestParseParamFile(t *testing.T) {
 		l.Panic("Expected envFile parsed correctly")
 	}
 }
+
+func TestDescriptorParamsKeepEqualsInValues(t *testing.T) {
+	cmd := &cobra.Command{}
+	ApplyDescriptorFlags(cmd)
+	cmd.Flags().Set(PARAMS_FLAG, "DB=postgres://h/db?sslmode=require")
+	assert.Equal(t, map[string]string{"DB": "postgres://h/db?sslmode=require"}, descriptorParams(cmd))
+}
+
+func TestScaleApp(t *testing.T) {
+	fake := marathontest.New().WithApps(&marathon.Application{ID: "/web", Instances: 1})
+	marathonClient = fake
+	defer func() { marathonClient = nil }()
+	var output cli.Formatter
+	cli.Register(&cli.CLIWriter{
+		FormatWriter: func(f cli.Formatter) { output = f },
+		ErrorWriter:  func(err error) { t.Fatal(err) },
+	})
+
+	scaleApp(appScaleCmd, []string{"/web", "3"})
+
+	app, _ := fake.GetApplication("/web")
+	assert.Equal(t, 3, app.Instances)
+	assert.Equal(t, "deployment-2", output.Data().Data.(*marathon.DeploymentID).DeploymentID)
+}
+
+func TestPromoteDiff(t *testing.T) {
+	promoted := &marathon.Application{ID: "/web", Instances: 5}
+	d, err := promoteDiff(promoted, &marathon.Application{ID: "/web", Instances: 2, Version: "v1"}, "staging", "prod")
+	assert.Nil(t, err)
+	assert.Contains(t, d, "--- /web (prod)\n+++ /web (staging)\n")
+	assert.Contains(t, d, "-   \"instances\": 2,\n+   \"instances\": 5,")
+	assert.NotContains(t, d, "v1", "state of the live application isn't compared")
+
+	d, _ = promoteDiff(promoted, nil, "staging", "prod")
+	assert.Contains(t, d, "+   \"id\": \"/web\",")
+
+	d, _ = promoteDiff(promoted, &marathon.Application{ID: "/web", Instances: 5, TasksRunning: 5}, "staging", "prod")
+	assert.Equal(t, "", d)
+}
+
+func TestParseEachRejectsDuplicateApps(t *testing.T) {
+	descriptors := []string{`{"id": "/tenants/acme"}`, `{"id": "/tenants/${TENANT}"}`}
+	assert.Nil(t, parseEach("each-app.json", ".tenants", descriptors, map[string]string{"TENANT": "globex"}))
+
+	err := parseEach("each-app.json", ".tenants", descriptors, map[string]string{"TENANT": "acme"})
+	assert.EqualError(t, err, "Elements 0 and 1 of '.tenants' both deploy the application '/tenants/acme'")
+
+	err = parseEach("each-app.json", ".tenants", []string{`{"id": `}, nil)
+	assert.Contains(t, err.Error(), "Element 0 of '.tenants'")
+}
+
+func TestRestoreBatch(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "batch")
+	defer os.RemoveAll(dir)
+	cliconfig.SetConfigDir(dir)
+
+	fake := marathontest.New().WithApps(&marathon.Application{ID: "/tenants/acme"}, &marathon.Application{ID: "/tenants/globex"})
+	deployments := []*deployment{{id: "/tenants/acme"}, {id: "/tenants/globex"}, nil}
+	results := []*workpool.Result{
+		{Name: "each-app.json[0]", Result: workpool.ResultSucceeded},
+		{Name: "each-app.json[1]", Result: workpool.ResultFailed, Err: errors.New("unhealthy")},
+		{Name: "each-app.json[2]", Result: workpool.ResultFailed, Err: ErrorBatchSkipped},
+	}
+
+	p := &postDeploy{rollback: true, env: "prod"}
+	p.restoreBatch(fake, deployments, results)
+
+	_, err := fake.GetApplication("/tenants/acme")
+	assert.Error(t, err, "the new application which succeeded is removed")
+	_, err = fake.GetApplication("/tenants/globex")
+	assert.Nil(t, err, "the application which failed was restored as it failed")
+	assert.True(t, errors.Is(results[0].Err, ErrorBatchFailed))
+	assert.Equal(t, workpool.ResultFailed, results[0].Result)
+	assert.Equal(t, 1, len(p.steps))
+}
+
+func TestInitApp(t *testing.T) {
+	answers := "web\nnginx:1.25\n2\n\n512\n80,abc\n80,443\n/ping\nMODE=prod\nbad\nDB_URL=postgres://db?ssl=on\n\ny\n\nweb.example.com\n"
+	a, err := askApp(bufio.NewReader(strings.NewReader(answers)), ioutil.Discard)
+	assert.Nil(t, err)
+	assert.Equal(t, &appAnswers{ID: "/web", Image: "nginx:1.25", Instances: 2, CPUs: 0.5, Mem: 512, Ports: []int{80, 443},
+		HealthPath: "/ping", Env: [][2]string{{"MODE", "prod"}, {"DB_URL", "postgres://db?ssl=on"}}, LBGroup: "external", LBVHost: "web.example.com"}, a)
+
+	descriptor, params, err := renderDescriptor(a, "web.yaml", false)
+	assert.Nil(t, err)
+	assert.Equal(t, "", params)
+	app := &marathon.Application{}
+	assert.Nil(t, encoding.DefaultYAMLEncoder().UnMarshalStr(descriptor, app))
+	assert.Equal(t, "nginx:1.25", app.Container.Docker.Image)
+	assert.Equal(t, 443, app.Container.Docker.PortMappings[1].ContainerPort)
+	assert.Equal(t, "/ping", app.HealthChecks[0].Path)
+	assert.Equal(t, "postgres://db?ssl=on", app.Env["DB_URL"])
+	assert.Equal(t, "web.example.com", app.Labels["HAPROXY_0_VHOST"])
+
+	descriptor, params, err = renderDescriptor(a, "web.yaml", true)
+	assert.Nil(t, err)
+	assert.Contains(t, descriptor, `image: "nginx:${TAG}"`)
+	assert.Contains(t, descriptor, "instances: ${INSTANCES}")
+	assert.Contains(t, descriptor, `MODE: "${MODE}"`)
+	assert.Equal(t, "TAG=1.25\nINSTANCES=2\nMODE=prod\nDB_URL=postgres://db?ssl=on\n", params)
+
+	_, err = askApp(bufio.NewReader(strings.NewReader("web\n")), ioutil.Discard)
+	assert.EqualError(t, err, "A Docker image is required")
+}
+
+func TestAnswersFromImage(t *testing.T) {
+	config := &registry.ImageConfig{
+		ExposedPorts: map[string]struct{}{"80/tcp": {}, "53/udp": {}, "53/tcp": {}},
+		Entrypoint:   []string{"/docker-entrypoint.sh"},
+		Cmd:          []string{"nginx", "-g", "daemon off;"},
+		Env:          []string{"PATH=/usr/bin", "NGINX_VERSION=1.25.3"},
+		Labels:       map[string]string{"maintainer": "NGINX", "org.opencontainers.image.version": "1.25"},
+	}
+	a := answersFromImage("nginx:1.25", config)
+	assert.Equal(t, "/nginx", a.ID)
+	assert.Equal(t, []int{53, 80}, a.Ports)
+	assert.Equal(t, map[int]string{53: "udp,tcp"}, a.Protocols)
+	assert.Equal(t, [][2]string{{"NGINX_VERSION", "1.25.3"}}, a.Env)
+	assert.Equal(t, map[string]string{"maintainer": "NGINX"}, a.Labels)
+	assert.True(t, a.HealthTCP)
+
+	descriptor, _, err := renderDescriptor(a, "nginx.yaml", false)
+	assert.Nil(t, err)
+	assert.Contains(t, descriptor, `# entrypoint of the image (args are passed to it): ["/docker-entrypoint.sh"]`)
+	app := &marathon.Application{}
+	assert.Nil(t, encoding.DefaultYAMLEncoder().UnMarshalStr(descriptor, app))
+	assert.Equal(t, []string{"nginx", "-g", "daemon off;"}, app.Args)
+	assert.Equal(t, "udp,tcp", app.Container.Docker.PortMappings[0].Protocol)
+	assert.Equal(t, "TCP", app.HealthChecks[0].Protocol)
+
+	config.Healthcheck = &struct {
+		Test []string `json:"Test"`
+	}{[]string{"CMD-SHELL", "curl -f http://localhost/ || exit 1"}}
+	a = answersFromImage("nginx:1.25", config)
+	descriptor, _, _ = renderDescriptor(a, "nginx.yaml", false)
+	app = &marathon.Application{}
+	assert.Nil(t, encoding.DefaultYAMLEncoder().UnMarshalStr(descriptor, app))
+	assert.Equal(t, "curl -f http://localhost/ || exit 1", app.HealthChecks[0].Command.Value)
+}
+
+func TestNormalizeSelector(t *testing.T) {
+	assert.Equal(t, "", normalizeSelector(" "))
+	assert.Equal(t, "label=team==payments", normalizeSelector("label=team=payments"))
+	assert.Equal(t, "label=team==payments", normalizeSelector("team=payments"))
+	assert.Equal(t, "label=team==payments,tier!=db", normalizeSelector("label=team==payments,tier!=db"))
+	assert.Equal(t, "label=team in (web,api)", normalizeSelector("team in (web,api)"))
+	assert.Equal(t, "label=canary", normalizeSelector("canary"))
+	assert.Equal(t, "id=/payments", normalizeSelector("id=/payments"))
+}
+
+func TestSelectedApps(t *testing.T) {
+	docker := func(image string) *marathon.Container {
+		return &marathon.Container{Type: "DOCKER", Docker: &marathon.Docker{Image: image}}
+	}
+	fake := marathontest.New().WithApps(
+		&marathon.Application{ID: "/payments/api", Instances: 1, Labels: map[string]string{"team": "payments"}, Container: docker("api:1")},
+		&marathon.Application{ID: "/payments/worker", Instances: 2, Labels: map[string]string{"team": "payments"}, Container: docker("worker:1")},
+		&marathon.Application{ID: "/web", Instances: 1, Labels: map[string]string{"team": "web"}},
+	)
+	marathonClient = fake
+	defer func() { marathonClient = nil }()
+	cli.AssumeYes(true)
+	defer cli.AssumeYes(false)
+	outputs := []cli.Formatter{}
+	cli.Register(&cli.CLIWriter{
+		FormatWriter: func(f cli.Formatter) { outputs = append(outputs, f) },
+		ErrorWriter:  func(err error) { t.Fatal(err) },
+	})
+
+	appScaleCmd.Flags().Set(SELECTOR_FLAG, "label=team=payments")
+	defer appScaleCmd.Flags().Set(SELECTOR_FLAG, "")
+	scaleApp(appScaleCmd, []string{"4"})
+	for _, id := range []string{"/payments/api", "/payments/worker"} {
+		app, _ := fake.GetApplication(id)
+		assert.Equal(t, 4, app.Instances)
+	}
+	app, _ := fake.GetApplication("/web")
+	assert.Equal(t, 1, app.Instances)
+	assert.Equal(t, 2, len(outputs))
+	assert.Equal(t, 2, len(outputs[0].Data().Data.(*marathon.Applications).Apps))
+
+	appUpdateImageCmd.Flags().Set(SELECTOR_FLAG, "team=payments")
+	defer appUpdateImageCmd.Flags().Set(SELECTOR_FLAG, "")
+	updateAppImage(appUpdateImageCmd, []string{"registry.example.com/payments:2"})
+	app, _ = fake.GetApplication("/payments/worker")
+	assert.Equal(t, "registry.example.com/payments:2", app.Container.Docker.Image)
+	assert.Equal(t, "DOCKER", app.Container.Type)
+}
+
+func TestListGroupedApps(t *testing.T) {
+	marathonClient = marathontest.New().WithApps(
+		&marathon.Application{ID: "/payments/api", Instances: 2, CPUs: 0.5, Mem: 256, Labels: map[string]string{"team": "payments"}},
+		&marathon.Application{ID: "/web", Instances: 1, CPUs: 1, Mem: 512},
+	)
+	defer func() { marathonClient = nil }()
+	var output cli.Formatter
+	cli.Register(&cli.CLIWriter{
+		FormatWriter: func(f cli.Formatter) { output = f },
+		ErrorWriter:  func(err error) { t.Fatal(err) },
+	})
+
+	listGroupedApps(appListCmd, "", "label:team")
+	b := &bytes.Buffer{}
+	assert.Nil(t, output.ToColumns(b))
+	assert.Contains(t, b.String(), "payments - 1 app(s), 2 instance(s), 1.00 cpus, 512.00 MB mem")
+	assert.Contains(t, b.String(), "(none) - 1 app(s), 1 instance(s), 1.00 cpus, 512.00 MB mem")
+	assert.Contains(t, b.String(), "TOTAL - 2 app(s), 3 instance(s), 2.00 cpus, 1024.00 MB mem")
+}
+
+func TestTemplateFormat(t *testing.T) {
+	configFile = &cliconfig.ConfigFile{Formats: map[string]string{"ids": "{{ .ID }}"}}
+	defer func() { configFile = nil }()
+	defer appGetCmd.Flags().Set(FORMAT_FLAG, "")
+
+	assert.Equal(t, T_APPLICATION, templateFormat(T_APPLICATION, appGetCmd))
+	appGetCmd.Flags().Set(FORMAT_FLAG, "@ids")
+	assert.Equal(t, "{{ .ID }}", templateFormat(T_APPLICATION, appGetCmd))
+	appGetCmd.Flags().Set(FORMAT_FLAG, "{{ .Version }}")
+	assert.Equal(t, "{{ .Version }}", templateFormat(T_APPLICATION, appGetCmd))
+
+	names, _ := completeFormats(appGetCmd, nil, "")
+	assert.Equal(t, []string{"@ids"}, names)
+}
+
+func TestDiffApplications(t *testing.T) {
+	fake := marathontest.New().WithApps(&marathon.Application{ID: "/web", Instances: 2, CPUs: 0.5,
+		Env: map[string]string{"MODE": "prod", "OLD": "1"}, TasksRunning: 2, Version: "v1"})
+
+	desired := []map[string]interface{}{
+		{"id": "/web", "instances": 2, "cpus": 0.5, "env": map[string]interface{}{"MODE": "debug"}},
+		{"id": "/api", "instances": 1},
+	}
+	diffs, err := diffApplications(fake, desired, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, DiffChanged, diffs[0].Status)
+	assert.Equal(t, []string{"env.MODE: prod -> debug", "env.OLD: 1 -> <none>"}, []string{diffs[0].Fields[0].String(), diffs[0].Fields[1].String()})
+	assert.Len(t, diffs[0].Fields, 2)
+	assert.Equal(t, DiffNew, diffs[1].Status)
+	assert.Equal(t, "id", diffs[1].Fields[0].Path)
+
+	diffs, err = diffApplications(fake, desired[:1], []string{"env"})
+	assert.Nil(t, err)
+	assert.Equal(t, DiffUnchanged, diffs[0].Status)
+	assert.Empty(t, diffs[0].Fields)
+
+	scaled, err := docDefinition(map[string]interface{}{"id": "/web", "instances": 0, "networks": []interface{}{map[string]interface{}{"mode": "host"}}})
+	assert.Nil(t, err)
+	diffs, err = diffApplications(fake, []map[string]interface{}{scaled}, []string{"env"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"instances: 2 -> 0", `networks: <none> -> [{"mode":"host"}]`}, []string{diffs[0].Fields[0].String(), diffs[0].Fields[1].String()},
+		"fields Application omits or doesn't model are compared")
+
+	_, err = docDefinition(map[string]interface{}{"instances": 1})
+	assert.EqualError(t, err, "the application has no id")
+}
+
+func TestWaitTimeout(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().Duration(TIMEOUT_FLAG, 0, "")
+	assert.Equal(t, marathon.DefaultTimeout, WaitTimeout(cmd, marathon.DefaultTimeout))
+
+	cmd.Flags().Set(TIMEOUT_FLAG, "2m")
+	assert.Equal(t, 2*time.Minute, WaitTimeout(cmd, marathon.DefaultTimeout))
+
+	cmd.Flags().Set(TIMEOUT_FLAG, "0")
+	assert.Equal(t, marathon.WaitForever, WaitTimeout(cmd, marathon.DefaultTimeout))
+}
@@ -0,0 +1,65 @@
+package marathon
+
+import (
+	"fmt"
+
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const T_CONSTRAINTS = `
+{{ "CONSTRAINT" | header }}	{{ "MATCHING" | header }}	{{ "LIMIT" | header }}	{{ "REASON" | header }}
+{{ range .Constraints }}{{ .Constraint }}	{{ .Matching | intToString }}	{{ if lt .Limit 0 }}-{{ else }}{{ .Limit | intToString }}{{ end }}	{{ .Reason }}
+{{end}}
+{{ .ID }}: {{ .Eligible | intToString }} of {{ .Active | intToString }} active agent(s) eligible, {{ .Placeable | intToString }} of {{ .Required | intToString }} instance(s) placeable
+`
+
+var appCheckConstraintsCmd = &cobra.Command{
+	Use:   "check-constraints [file(.json | .yaml) | applicationId]",
+	Short: "Reports how many Mesos agents satisfy the constraints of an application",
+	Long: `Evaluates the constraints of the application descriptor (or the deployed application) against the
+hostnames, attributes and fault domains of the active Mesos agents and reports how many agents satisfy each
+constraint and whether the requested instances can be placed within the agents' free resources, before a
+deployment waits in the queue for offers which never match.  Exits with a non-zero status if they can't.
+
+    eg. depcon app check-constraints app.json
+        depcon app check-constraints /product/web --instances 6`,
+	Run: checkAppConstraints,
+}
+
+func init() {
+	appCmd.AddCommand(appCheckConstraintsCmd)
+	appCheckConstraintsCmd.Flags().Int(INSTANCES_FLAG, 0, "Instances to place.  Default: the instances of the application")
+	appCheckConstraintsCmd.Flags().String(TEMPLATE_CTX_FLAG, "", "Provides data per environment in JSON form to do a first pass parse of descriptor as template")
+	appCheckConstraintsCmd.Flags().StringSliceP(PARAMS_FLAG, "p", nil, `Adds a param(s) that can be used for substitution.
+                  eg. -p MYVAR=value would replace ${MYVAR} with "value" in the application file.`)
+}
+
+func checkAppConstraints(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	app := loadCheckedApp(cmd, args[0])
+	instances := app.Instances
+	if n, _ := cmd.Flags().GetInt(INSTANCES_FLAG); n > 0 {
+		instances = n
+	}
+
+	envName := viper.GetString(ENV_NAME)
+	m, err := NewMesosClient(envName, configFile.Environments[envName].Marathon, viper.GetBool(INSECURE_FLAG), false)
+	if err != nil {
+		exitWithError(err)
+	}
+	agents, err := m.ListAgents()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	report := mesos.CheckConstraints(agents, app.Constraints, requirementOf(cmd, app, instances))
+	cli.Output(templateFor(T_CONSTRAINTS, report), nil)
+	if !report.Satisfied() {
+		exitWithError(fmt.Errorf("Only %d of %d instance(s) of '%s' can be placed", report.Placeable, report.Required, app.ID))
+	}
+}
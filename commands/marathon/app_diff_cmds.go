@@ -0,0 +1,131 @@
+package marathon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+)
+
+const (
+	DiffChanged   = "changed"
+	DiffNew       = "new"
+	DiffUnchanged = "unchanged"
+
+	T_APP_DIFF = `
+{{ "ID" | header }}	{{ "CHANGE" | header }}	{{ "FIELD" | header }}	{{ "LIVE" | header }}	{{ "DESIRED" | header }}
+{{ range . }}{{ $d := . }}{{ range .Fields }}{{ $d.ID }}	{{ $d.Status }}	{{ .Path }}	{{ mask .Path .LiveValue }}	{{ mask .Path .DesiredValue }}
+{{end}}{{end}}`
+)
+
+// AppDiff is the difference between an application descriptor and the application deployed
+type AppDiff struct {
+	ID string `json:"id"`
+	// changed, new (not deployed) or unchanged
+	Status string                 `json:"status"`
+	Fields []marathon.FieldChange `json:"fields"`
+}
+
+var appDiffCmd = &cobra.Command{
+	Use:   "diff [file(.json | .yaml)]",
+	Short: "Shows the fields 'app create --force' would change in the deployed application",
+	Long: `Renders the descriptor [file] with the same template context and params as 'app create', fetches the
+deployed application and prints the fields whose values differ (eg. env vars, the image, ports, instances
+and health checks).  Fields the descriptor doesn't define are left out since Marathon fills in defaults,
+except env and labels keys which an update removes.  Exits with a non-zero status when there are
+differences so a pipeline can skip deploying an unchanged application.
+
+    eg. depcon app diff app.json -p TAG=1.4.2 -e prod
+        depcon app diff app.yaml --ignore-field instances -o json`,
+	Run: diffApp,
+}
+
+func init() {
+	ApplyDescriptorFlags(appDiffCmd)
+	appDiffCmd.Flags().StringSlice(IGNORE_FIELD_FLAG, nil, "Field left out of the comparison along with the fields below it (eg. instances or env.BUILD_ID)")
+	applyFormatFlag(appDiffCmd, "{{range .}}{{ .ID }} {{ .Status }}{{end}}")
+}
+
+func diffApp(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	ignored, _ := cmd.Flags().GetStringSlice(IGNORE_FIELD_FLAG)
+
+	definitions := []map[string]interface{}{}
+	for i, doc := range RenderDocuments(cmd, args[0]) {
+		definition, err := docDefinition(doc)
+		if err != nil {
+			exitWithError(fmt.Errorf("Document %d of %s: %s", i+1, args[0], err.Error()))
+		}
+		definitions = append(definitions, definition)
+	}
+
+	diffs, err := diffApplications(client(cmd), definitions, ignored)
+	if err != nil {
+		exitWithError(err)
+	}
+	changed := 0
+	for _, d := range diffs {
+		if d.Status != DiffUnchanged {
+			changed++
+		}
+	}
+	if changed == 0 {
+		fmt.Printf("%s matches the deployed application(s)\n", args[0])
+		return
+	}
+	cli.Output(templateFor(templateFormat(T_APP_DIFF, cmd), diffs), nil)
+	cli.ExitWithCode(cli.ExitError)
+}
+
+// Returns the rendered descriptor document {doc} as JSON values.  The document isn't decoded into an
+// Application so the fields it doesn't model (eg. networks) and zero values (eg. instances: 0) are compared
+func docDefinition(doc map[string]interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	definition := map[string]interface{}{}
+	if err := json.Unmarshal(b, &definition); err != nil {
+		return nil, err
+	}
+	if id, _ := definition["id"].(string); id == "" {
+		return nil, errors.New("the application has no id")
+	}
+	return definition, nil
+}
+
+// Compares each of the desired {definitions} with the definition of the application deployed leaving out
+// the {ignored} fields.  Apps which aren't deployed are compared against an empty definition
+func diffApplications(c marathon.Marathon, definitions []map[string]interface{}, ignored []string) ([]*AppDiff, error) {
+	diffs := []*AppDiff{}
+	for _, desired := range definitions {
+		id := desired["id"].(string)
+		d := &AppDiff{ID: id, Status: DiffChanged, Fields: []marathon.FieldChange{}}
+		live, err := c.GetApplicationDefinition(id)
+		switch {
+		case errors.Is(err, httpclient.ErrorNotFound):
+			d.Status, live = DiffNew, map[string]interface{}{}
+		case err != nil:
+			return nil, err
+		}
+		for _, f := range marathon.DiffDefinition(desired, live) {
+			if !ignoredField(f.Path, ignored) {
+				d.Fields = append(d.Fields, f)
+			}
+		}
+		if d.Status == DiffNew {
+			// the id isn't compared as it's how applications are matched
+			d.Fields = append([]marathon.FieldChange{{Path: "id", Desired: id}}, d.Fields...)
+		} else if len(d.Fields) == 0 {
+			d.Status = DiffUnchanged
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
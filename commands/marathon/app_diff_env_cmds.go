@@ -0,0 +1,93 @@
+package marathon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ENVS_FLAG         = "envs"
+	IGNORE_FIELD_FLAG = "ignore-field"
+
+	T_ENV_DIFF = `
+{{ "FIELD" | header }}{{ range .Environments }}	{{ . | header }}{{ end }}
+{{ range $f := .Fields }}{{ $f.Path }}{{ range $f.FormattedValues }}	{{ mask $f.Path . }}{{ end }}
+{{end}}`
+)
+
+// EnvironmentDiff is the difference between the definitions of an application deployed to several
+// environments
+type EnvironmentDiff struct {
+	ID           string                    `json:"id"`
+	Environments []string                  `json:"environments"`
+	Fields       []marathon.DefinitionDiff `json:"fields"`
+}
+
+var appDiffEnvCmd = &cobra.Command{
+	Use:   "diff-env [applicationId]",
+	Short: "Shows the fields of an application which differ between environments",
+	Long: `Fetches the definition of [applicationId] from each of --envs and prints the fields whose values differ
+(eg. the image, env vars, resources and instances) with a column per environment.  Exits with a non-zero
+status when the definitions differ so unexpected drift between environments can fail a build.  Fields
+which are expected to differ are left out with --ignore-field.
+
+    eg. depcon app diff-env /web --envs staging,prod
+        depcon app diff-env /web --envs dev,staging,prod --ignore-field instances --ignore-field env.DB_HOST`,
+	Run: diffAppEnvironments,
+}
+
+func init() {
+	appDiffEnvCmd.Flags().StringSlice(ENVS_FLAG, nil, "Environments to compare (at least two).  The first is compared against the others")
+	appDiffEnvCmd.Flags().StringSlice(IGNORE_FIELD_FLAG, nil, "Field left out of the comparison along with the fields below it (eg. instances or env.DB_HOST)")
+}
+
+func diffAppEnvironments(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	envs, _ := cmd.Flags().GetStringSlice(ENVS_FLAG)
+	ignored, _ := cmd.Flags().GetStringSlice(IGNORE_FIELD_FLAG)
+	if len(envs) < 2 {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s requires at least two environments (eg. staging,prod)", ENVS_FLAG)))
+	}
+
+	apps := []*marathon.Application{}
+	for _, env := range envs {
+		c, err := environmentClient(cmd, env)
+		if err != nil {
+			exitWithError(err)
+		}
+		app, err := c.GetApplication(args[0])
+		if err != nil {
+			exitWithError(fmt.Errorf("Unable to get '%s' from environment '%s': %s", args[0], env, err.Error()))
+		}
+		apps = append(apps, app)
+	}
+
+	result := &EnvironmentDiff{ID: apps[0].ID, Environments: envs, Fields: []marathon.DefinitionDiff{}}
+	for _, d := range marathon.DiffDefinitions(apps...) {
+		if !ignoredField(d.Path, ignored) {
+			result.Fields = append(result.Fields, d)
+		}
+	}
+	if len(result.Fields) == 0 {
+		fmt.Printf("Application '%s' is defined the same in %s\n", result.ID, strings.Join(envs, ", "))
+		return
+	}
+	cli.Output(templateFor(T_ENV_DIFF, result), nil)
+	exitWithError(fmt.Errorf("%d field(s) of '%s' differ between %s", len(result.Fields), result.ID, strings.Join(envs, ", ")))
+}
+
+// Returns true if {path} is one of the {ignored} fields or a field below one of them
+func ignoredField(path string, ignored []string) bool {
+	for _, field := range ignored {
+		if path == field || strings.HasPrefix(path, field+".") {
+			return true
+		}
+	}
+	return false
+}
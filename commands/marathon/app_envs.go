@@ -0,0 +1,72 @@
+package marathon
+
+import (
+	"fmt"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pipeline"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/spf13/cobra"
+)
+
+const (
+	GATE_FLAG       = "gate"
+	VERIFY_CMD_FLAG = "verify-cmd"
+
+	T_ENV_DEPLOYS = `
+{{ "ENVIRONMENT" | header }}	{{ "ACTION" | header }}	{{ "VERIFIED" | header }}	{{ "RESULT" | header }}	{{ "DURATION" | header }}
+{{ range . }}{{ .Environment }}	{{ .Action }}	{{ .Verified | intToString }}	{{ .Result }}	{{ .Duration }}
+{{end}}`
+)
+
+// Adds the flags deploying a descriptor to several environments in order to {cmd}
+func applyEnvironmentFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice(ENVS_FLAG, nil, `Deploys to each environment in order (eg. dev,staging,prod) waiting for it to become healthy
+                  (and pass --verify-cmd) before the next.  Stops at the first environment which fails`)
+	cmd.Flags().String(GATE_FLAG, pipeline.GateAuto, "Gate of the environments after the first with --envs: auto or manual (confirmed on the terminal or with --yes)")
+	cmd.Flags().String(VERIFY_CMD_FLAG, "", "Command which must succeed once each environment of --envs is healthy.  DEPCON_ENV is set to the environment")
+}
+
+// Deploys the descriptor {filename} to each environment of --envs in order
+func createAppInEnvironments(cmd *cobra.Command, filename, tempctx string, options *marathon.CreateOptions) {
+	envs, _ := cmd.Flags().GetStringSlice(ENVS_FLAG)
+	gate, _ := cmd.Flags().GetString(GATE_FLAG)
+	command, _ := cmd.Flags().GetString(VERIFY_CMD_FLAG)
+	if options.DryRun || cmd.Flags().Changed(EACH_FLAG) {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s can't be combined with --%s or --%s", ENVS_FLAG, DRYRUN_FLAG, EACH_FLAG)))
+	}
+
+	verify := []*pipeline.Verification{}
+	if command != "" {
+		verify = append(verify, &pipeline.Verification{Command: command})
+	}
+	p, err := pipeline.ForEnvironments(filename, envs, gate, WaitTimeout(cmd, 0), verify)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+
+	runner := &pipeline.Runner{
+		Client: func(env string) (marathon.Marathon, error) {
+			return environmentClient(cmd, env)
+		},
+		Load: func(env string) *reconcile.LoadOptions {
+			opts := &reconcile.LoadOptions{Params: options.EnvParams, IgnoreMissing: !options.ErrorOnMissingParams}
+			opts.Render = func(filename string) (string, error) {
+				return RenderDescriptor(filename, tempctx, env, opts.Params, opts.IgnoreMissing)
+			}
+			opts.Verify = SignatureVerifier(cmd, env)
+			return opts
+		},
+		Approve: func(p *pipeline.Pipeline, s *pipeline.Stage) error {
+			return cli.Confirm(fmt.Sprintf("Deploy '%s' to environment '%s'", filename, s.Env()))
+		},
+	}
+	results, err := runner.Run(p, "")
+	if len(results) > 0 {
+		cli.Output(templateFor(T_ENV_DEPLOYS, results), nil)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+}
@@ -0,0 +1,155 @@
+package marathon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/registry"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	PLAIN_HTTP_FLAG = "plain-http"
+	APP_ID_FLAG     = "id"
+)
+
+var appFromImageCmd = &cobra.Command{
+	Use:   "from-image [image]",
+	Short: "Generates a descriptor from the exposed ports, entrypoint, env, labels and health check of [image]",
+	Long: `Reads the configuration of [image] from its registry (no docker daemon is needed) and prints a YAML
+descriptor skeleton (or writes it to --out) running the image with matching port mappings, args, env vars and
+health check.  Labels other than namespaced metadata (eg. org.opencontainers.image.*) are carried over.
+
+Private registries are authenticated with the credentials of the docker config (--config).
+
+    eg. depcon app from-image nginx:1.25
+        depcon app from-image registry.example.com/shop/web:1.2 --out web.yaml`,
+	Run: appFromImage,
+}
+
+func init() {
+	appCmd.AddCommand(appFromImageCmd)
+	appFromImageCmd.Flags().String(OUT_FLAG, "", "File the descriptor is written to.  Default: stdout")
+	appFromImageCmd.Flags().String(APP_ID_FLAG, "", "Application id.  Default: the name of the image")
+	appFromImageCmd.Flags().String(DOCKER_CONFIG_FLAG, registry.DefaultDockerConfig(), "Docker config holding the registry credentials")
+	appFromImageCmd.Flags().Bool(PLAIN_HTTP_FLAG, false, "Reaches the registry over http rather than https (eg. a local registry)")
+	appFromImageCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Overwrites --out when it exists")
+}
+
+func appFromImage(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	image := args[0]
+	out, _ := cmd.Flags().GetString(OUT_FLAG)
+	if force, _ := cmd.Flags().GetBool(FORCE_FLAG); out != "" && !force {
+		if _, err := os.Stat(out); err == nil {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("%s already exists (--%s to overwrite)", out, FORCE_FLAG)))
+		}
+	}
+
+	config := httpclient.NewDefaultConfig()
+	config.TLSInsecureSkipVerify = viper.GetBool(INSECURE_FLAG)
+	inspector := &registry.Inspector{Client: httpclient.NewHttpClient(*config).Unwrap()}
+	inspector.PlainHTTP, _ = cmd.Flags().GetBool(PLAIN_HTTP_FLAG)
+	if filename, _ := cmd.Flags().GetString(DOCKER_CONFIG_FLAG); filename != "" {
+		if _, err := os.Stat(filename); err == nil {
+			dockerConfig, err := registry.LoadDockerConfig(filename)
+			if err != nil {
+				exitWithError(err)
+			}
+			inspector.Credentials = dockerConfig.Credentials
+		}
+	}
+
+	imageConfig, err := inspector.Inspect(image)
+	if err != nil {
+		exitWithError(err)
+	}
+	answers := answersFromImage(image, imageConfig)
+	if id, _ := cmd.Flags().GetString(APP_ID_FLAG); id != "" {
+		answers.ID = "/" + strings.Trim(id, "/")
+	}
+	file := out
+	if file == "" {
+		file = "app.yaml"
+	}
+	descriptor, _, err := renderDescriptor(answers, file, false)
+	if err != nil {
+		exitWithError(err)
+	}
+	if out == "" {
+		fmt.Print(descriptor)
+		return
+	}
+	if err := ioutil.WriteFile(out, []byte(descriptor), 0644); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("Wrote %s\n", out)
+}
+
+// Returns the descriptor answers running {image} configured by {config}
+func answersFromImage(image string, config *registry.ImageConfig) *appAnswers {
+	a := &appAnswers{
+		Image:      image,
+		Instances:  1,
+		CPUs:       0.5,
+		Mem:        256,
+		Ports:      []int{},
+		Protocols:  map[int]string{},
+		Entrypoint: config.Entrypoint,
+		Args:       config.Cmd,
+		Labels:     map[string]string{},
+		Header:     fmt.Sprintf("Generated from %s by 'depcon app from-image'", image),
+	}
+	if ref, err := registry.ParseReference(image); err == nil {
+		a.ID = "/" + path.Base(ref.Repository)
+	}
+
+	seen := map[int]bool{}
+	for _, p := range config.Ports() {
+		// a port exposed for both tcp and udp is mapped once with protocol udp,tcp
+		if seen[p.Port] {
+			a.Protocols[p.Port] = "udp,tcp"
+			continue
+		}
+		seen[p.Port] = true
+		a.Ports = append(a.Ports, p.Port)
+		if p.Protocol != "tcp" {
+			a.Protocols[p.Port] = p.Protocol
+		}
+	}
+
+	for _, kv := range config.Env {
+		parts := strings.SplitN(kv, "=", 2)
+		// the PATH of the image is kept by the container
+		if len(parts) != 2 || parts[0] == "PATH" {
+			continue
+		}
+		a.Env = append(a.Env, [2]string{parts[0], parts[1]})
+	}
+	for key, value := range config.Labels {
+		// namespaced labels describe the image rather than configure the application
+		if !strings.Contains(key, ".") {
+			a.Labels[key] = value
+		}
+	}
+
+	if hc := config.Healthcheck; hc != nil && len(hc.Test) > 1 {
+		switch hc.Test[0] {
+		case "CMD-SHELL":
+			a.HealthCommand = hc.Test[1]
+		case "CMD":
+			a.HealthCommand = strings.Join(hc.Test[1:], " ")
+		}
+	} else if hc == nil || len(hc.Test) == 0 || hc.Test[0] != "NONE" {
+		a.HealthTCP = true
+	}
+	return a
+}
@@ -0,0 +1,399 @@
+package marathon
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathonlb"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/spf13/cobra"
+)
+
+const (
+	TEMPLATED_FLAG = "templated"
+
+	defaultInitFile = "app.yaml"
+)
+
+// names of env vars which can be written as ${PARAMS}
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// descriptor written by 'app init' and 'app from-image'.  Values are pre-quoted by renderDescriptor
+const initTemplate = `# {{ .Header }}.  Validate with: depcon app validate {{ .File }}
+id: {{ .ID }}
+instances: {{ .Instances }}
+cpus: {{ .CPUs }}
+mem: {{ .Mem }}
+{{- if .Entrypoint }}
+# entrypoint of the image (args are passed to it): {{ .Entrypoint }}
+{{- end }}
+{{- if .Args }}
+args:
+{{- range .Args }}
+  - {{ . }}
+{{- end }}
+{{- end }}
+
+container:
+  type: DOCKER
+  docker:
+    image: {{ .Image }}
+    network: {{ if .Ports }}BRIDGE{{ else }}HOST{{ end }}
+{{- if .Ports }}
+    portMappings:
+{{- range .Ports }}
+      - containerPort: {{ .Port }}
+        hostPort: 0
+        protocol: {{ .Protocol }}
+{{- end }}
+{{- end }}
+{{ if .Env }}
+env:
+{{- range .Env }}
+  {{ .Key }}: {{ .Value }}
+{{- end }}
+{{ end }}
+{{- if .Labels }}
+labels:
+{{- range .Labels }}
+  {{ .Key }}: {{ .Value }}
+{{- end }}
+{{ end }}
+{{- if or .HealthPath .HealthCommand .HealthTCP }}
+healthChecks:
+{{- if .HealthPath }}
+  - protocol: HTTP
+    path: {{ .HealthPath }}
+    portIndex: 0
+{{- else if .HealthCommand }}
+  - protocol: COMMAND
+    command:
+      value: {{ .HealthCommand }}
+{{- else }}
+  - protocol: TCP
+    portIndex: 0
+{{- end }}
+    gracePeriodSeconds: 300
+    intervalSeconds: 30
+    timeoutSeconds: 20
+    maxConsecutiveFailures: 3
+{{ end }}
+upgradeStrategy:
+  minimumHealthCapacity: 1
+  maximumOverCapacity: 1
+`
+
+var appInitCmd = &cobra.Command{
+	Use:   "init (file)",
+	Short: "Interactively generates an application descriptor (default: app.yaml)",
+	Long: `Asks for the image, ports, resources, health check, env vars and Marathon-LB exposure of an application
+and writes a YAML descriptor which can be deployed with 'app create'.
+
+With --templated the image tag, instances and env values are written as ${PARAMS} along with a params file
+(<file>.env) holding the answers so the descriptor can be deployed to each environment with its own values.
+
+    eg. depcon app init web.yaml --templated
+        depcon app create web.yaml -c web.yaml.env -e stage`,
+	Run: initApp,
+}
+
+func init() {
+	appCmd.AddCommand(appInitCmd)
+	appInitCmd.Flags().Bool(TEMPLATED_FLAG, false, "Writes the image tag, instances and env values as ${PARAMS} with a params file holding the answers")
+	appInitCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Overwrites existing files")
+}
+
+// Answers of the descriptor wizard (or the metadata of an image)
+type appAnswers struct {
+	ID        string
+	Image     string
+	Instances int
+	CPUs      float64
+	Mem       float64
+	Ports     []int
+	// protocol of the ports which aren't tcp (eg. udp)
+	Protocols  map[int]string
+	HealthPath string
+	// command of a COMMAND health check (eg. the HEALTHCHECK of an image)
+	HealthCommand string
+	// checks the first port accepts connections when there's no other health check
+	HealthTCP  bool
+	Env        [][2]string
+	LBGroup    string
+	LBVHost    string
+	Entrypoint []string
+	Args       []string
+	Labels     map[string]string
+	// first comment line of the descriptor.  Default: generated by 'depcon app init'
+	Header string
+}
+
+// descriptor entry rendered by initTemplate
+type initEntry struct {
+	Key, Value string
+}
+
+// port mapping rendered by initTemplate
+type initPort struct {
+	Port     int
+	Protocol string
+}
+
+func initApp(cmd *cobra.Command, args []string) {
+	file := defaultInitFile
+	if len(args) > 0 {
+		file = args[0]
+	}
+	templated, _ := cmd.Flags().GetBool(TEMPLATED_FLAG)
+	paramsFile := file + ".env"
+	if force, _ := cmd.Flags().GetBool(FORCE_FLAG); !force {
+		for _, f := range []string{file, paramsFile} {
+			if _, err := os.Stat(f); err == nil && (f == file || templated) {
+				exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("%s already exists (--%s to overwrite)", f, FORCE_FLAG)))
+			}
+		}
+	}
+
+	answers, err := askApp(bufio.NewReader(os.Stdin), os.Stderr)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	descriptor, params, err := renderDescriptor(answers, file, templated)
+	if err != nil {
+		exitWithError(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(descriptor), 0644); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("\nWrote %s\n", file)
+	if templated {
+		// params may hold secret env values
+		if err := ioutil.WriteFile(paramsFile, []byte(params), 0600); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("Wrote %s\n\nDeploy with: depcon app create %s -c %s\n", paramsFile, file, paramsFile)
+		return
+	}
+	fmt.Printf("\nDeploy with: depcon app create %s\n", file)
+}
+
+// Asks the questions of the wizard on {out} reading the answers from {in}.  Blank answers (or the end of
+// {in}) accept the default shown
+func askApp(in *bufio.Reader, out io.Writer) (*appAnswers, error) {
+	eof := false
+	ask := func(question, def string) string {
+		if def != "" {
+			fmt.Fprintf(out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", question)
+		}
+		line, err := in.ReadString('\n')
+		eof = err != nil
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+		return def
+	}
+	askNumber := func(question, def string) (float64, error) {
+		for {
+			v, err := strconv.ParseFloat(ask(question, def), 64)
+			if err == nil && v >= 0 {
+				return v, nil
+			}
+			if eof {
+				return 0, fmt.Errorf("%s must be a positive number", question)
+			}
+			fmt.Fprintln(out, "  a positive number is required")
+		}
+	}
+
+	a := &appAnswers{}
+	a.ID = "/" + strings.Trim(ask("Application id", "/my-app"), "/")
+	for a.Image == "" {
+		if a.Image = ask("Docker image (eg. nginx:1.25)", ""); a.Image == "" {
+			if eof {
+				return nil, fmt.Errorf("A Docker image is required")
+			}
+			fmt.Fprintln(out, "  an image is required")
+		}
+	}
+	instances, err := askNumber("Instances", "1")
+	if err != nil {
+		return nil, err
+	}
+	a.Instances = int(instances)
+	if a.CPUs, err = askNumber("CPUs", "0.5"); err != nil {
+		return nil, err
+	}
+	if a.Mem, err = askNumber("Memory (MB)", "256"); err != nil {
+		return nil, err
+	}
+
+	for a.Ports == nil {
+		a.Ports = []int{}
+		for _, p := range strings.Split(ask("Container ports (comma separated, 'none' for none)", "8080"), ",") {
+			if p = strings.TrimSpace(p); p == "" || p == "none" {
+				continue
+			}
+			port, err := strconv.Atoi(p)
+			if err != nil || port <= 0 || port > 65535 {
+				if eof {
+					return nil, fmt.Errorf("'%s' is not a valid port", p)
+				}
+				fmt.Fprintf(out, "  '%s' is not a valid port\n", p)
+				a.Ports = nil
+				break
+			}
+			a.Ports = append(a.Ports, port)
+		}
+	}
+
+	if len(a.Ports) > 0 {
+		if path := ask("HTTP health check path of the first port ('none' for none)", "/health"); path != "none" {
+			a.HealthPath = "/" + strings.TrimLeft(path, "/")
+		}
+	}
+
+	fmt.Fprintln(out, "Env vars as KEY=VALUE, one per line (blank line to finish)")
+	for !eof {
+		kv := strings.SplitN(ask("  env", ""), "=", 2)
+		if kv[0] == "" {
+			break
+		}
+		if len(kv) != 2 || !envName.MatchString(strings.TrimSpace(kv[0])) {
+			fmt.Fprintln(out, "  must be KEY=VALUE where KEY is letters, digits and underscores")
+			continue
+		}
+		a.Env = append(a.Env, [2]string{strings.TrimSpace(kv[0]), kv[1]})
+	}
+
+	if len(a.Ports) > 0 && isYes(ask("Expose through Marathon-LB (y/N)", "n")) {
+		a.LBGroup = ask("Marathon-LB group", "external")
+		a.LBVHost = ask("Virtual host (eg. app.example.com, blank for none)", "")
+	}
+	return a, nil
+}
+
+func quoteAll(values []string) []string {
+	quoted := []string{}
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return quoted
+}
+
+func isYes(answer string) bool {
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// Renders the descriptor of {a} written to {file}.  When {templated} the image tag, instances and env
+// values are ${PARAMS} whose values are returned as the content of a params file
+func renderDescriptor(a *appAnswers, file string, templated bool) (string, string, error) {
+	header := a.Header
+	if header == "" {
+		header = "Generated by 'depcon app init'"
+	}
+	data := map[string]interface{}{
+		"Header":        header,
+		"File":          file,
+		"ID":            a.ID,
+		"Instances":     strconv.Itoa(a.Instances),
+		"CPUs":          strconv.FormatFloat(a.CPUs, 'f', -1, 64),
+		"Mem":           strconv.FormatFloat(a.Mem, 'f', -1, 64),
+		"Image":         strconv.Quote(a.Image),
+		"HealthPath":    "",
+		"HealthCommand": "",
+		"HealthTCP":     a.HealthTCP && len(a.Ports) > 0,
+		"Entrypoint":    "",
+		"Args":          quoteAll(a.Args),
+	}
+	ports := []initPort{}
+	for _, p := range a.Ports {
+		protocol := a.Protocols[p]
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		ports = append(ports, initPort{p, protocol})
+	}
+	data["Ports"] = ports
+	if a.HealthPath != "" {
+		data["HealthPath"] = strconv.Quote(a.HealthPath)
+	}
+	if a.HealthCommand != "" {
+		data["HealthCommand"] = strconv.Quote(a.HealthCommand)
+	}
+	if len(a.Entrypoint) > 0 {
+		data["Entrypoint"] = "[" + strings.Join(quoteAll(a.Entrypoint), ", ") + "]"
+	}
+	params := []string{}
+
+	env := []initEntry{}
+	for _, kv := range a.Env {
+		value := strconv.Quote(kv[1])
+		if templated {
+			value = strconv.Quote("${" + kv[0] + "}")
+			params = append(params, kv[0]+"="+kv[1])
+		}
+		env = append(env, initEntry{kv[0], value})
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i].Key < env[j].Key })
+	data["Env"] = env
+
+	if templated {
+		image, tag := a.Image, "latest"
+		if i := strings.LastIndex(a.Image, ":"); i > strings.LastIndex(a.Image, "/") {
+			image, tag = a.Image[:i], a.Image[i+1:]
+		}
+		data["Image"] = strconv.Quote(image + ":${TAG}")
+		data["Instances"] = "${INSTANCES}"
+		params = append([]string{"TAG=" + tag, "INSTANCES=" + strconv.Itoa(a.Instances)}, params...)
+	}
+
+	labels := []initEntry{}
+	for key, value := range a.Labels {
+		labels = append(labels, initEntry{strconv.Quote(key), strconv.Quote(value)})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Key < labels[j].Key })
+	if a.LBGroup != "" {
+		labels = append(labels, initEntry{marathonlb.LabelGroup, strconv.Quote(a.LBGroup)})
+		if a.LBVHost != "" {
+			labels = append(labels, initEntry{"HAPROXY_0_VHOST", strconv.Quote(a.LBVHost)})
+		}
+	}
+	data["Labels"] = labels
+
+	var buf bytes.Buffer
+	if err := template.Must(template.New("init").Parse(initTemplate)).Execute(&buf, data); err != nil {
+		return "", "", err
+	}
+
+	if templated {
+		// the descriptor with the answers in place of the params must be valid
+		if _, _, err := renderDescriptor(a, file, false); err != nil {
+			return "", "", err
+		}
+	} else if err := encoding.DefaultYAMLEncoder().UnMarshalStr(buf.String(), &marathon.Application{}); err != nil {
+		return "", "", fmt.Errorf("Unable to generate a valid descriptor: %s", err.Error())
+	}
+
+	paramsFile := ""
+	if len(params) > 0 {
+		paramsFile = strings.Join(params, "\n") + "\n"
+	}
+	return buf.String(), paramsFile, nil
+}
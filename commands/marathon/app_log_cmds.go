@@ -1,20 +1,30 @@
 package marathon
 
 import (
-	"fmt"
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
 	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logfilter"
 	"github.com/ContainX/depcon/pkg/logger"
-	ml "github.com/ContainX/go-mesoslog/mesoslog"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"net/url"
+	"os"
 	"strings"
+	"time"
 )
 
 const (
 	STDERR_FLAG = "stderr"
 	FOLLOW_FLAG = "follow"
 	POLL_FLAG   = "poll"
+	SINCE_FLAG  = "since"
+	TAIL_FLAG   = "tail"
+	GREP_FLAG   = "grep"
+	LEVEL_FLAG  = "level"
+	TS_FLAG     = "timestamps"
 )
 
 var logCmd = &cobra.Command{
@@ -30,80 +40,75 @@ func init() {
 	logCmd.Flags().BoolP(STDERR_FLAG, "s", false, "Show StdErr vs default StdOut log")
 	logCmd.Flags().BoolP(FOLLOW_FLAG, "f", false, "Tail/Follow log")
 	logCmd.Flags().IntP(POLL_FLAG, "p", 5, "Log poll time (duration) in seconds")
+	ApplyLogFilterFlags(logCmd.Flags())
 }
 
-func showLogCmd(cmd *cobra.Command, args []string) {
-	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
-		return
-	}
-
-	host := getMesosHost()
-	logType := ml.STDOUT
+// ApplyLogFilterFlags adds the flags selecting the entries of the logs shown
+func ApplyLogFilterFlags(flags *pflag.FlagSet) {
+	flags.Duration(SINCE_FLAG, 0, "Only show entries logged within this duration (eg. 10m, 2h)")
+	flags.Int(TAIL_FLAG, 0, "Only show the last N entries of each task")
+	flags.String(GREP_FLAG, "", "Only show entries matching this regular expression")
+	flags.String(LEVEL_FLAG, "", "Only show entries at this level or more severe (eg. WARN, ERROR)")
+	flags.Bool(TS_FLAG, false, "Prefix entries with their timestamp in UTC and task, interleaving the tasks by time")
+}
 
-	if stderr, _ := cmd.Flags().GetBool(STDERR_FLAG); stderr {
-		logType = ml.STDERR
+// LogFilterOptions sets the filter and timestamps of {opts} from the flags of {cmd}.  Exits with a usage
+// error when the flags are invalid
+func LogFilterOptions(cmd *cobra.Command, opts *backend.LogOptions) {
+	since, _ := cmd.Flags().GetDuration(SINCE_FLAG)
+	tail, _ := cmd.Flags().GetInt(TAIL_FLAG)
+	grep, _ := cmd.Flags().GetString(GREP_FLAG)
+	level, _ := cmd.Flags().GetString(LEVEL_FLAG)
+	f, err := logfilter.New(since, tail, grep, level, time.Now())
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
 	}
+	opts.Filter = f
+	opts.Timestamps, _ = cmd.Flags().GetBool(TS_FLAG)
+}
 
-	c, _ := ml.NewMesosClient(host, 5050)
-	appId := getMesosAppIdentifier(cmd, c, args[0])
-
-	if follow, _ := cmd.Flags().GetBool(FOLLOW_FLAG); follow {
-		duration, _ := cmd.Flags().GetInt(POLL_FLAG)
-		if duration < 1 {
-			duration = 5
-
-		}
-		if err := c.TailLog(appId, logType, duration); err != nil {
-			log.Fatal(err)
-		}
+func showLogCmd(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
 		return
 	}
 
-	logs, err := c.GetLog(appId, logType, "")
-	if err != nil {
-		log.Fatal(err)
+	opts := &backend.LogOptions{}
+	opts.Stderr, _ = cmd.Flags().GetBool(STDERR_FLAG)
+	opts.Follow, _ = cmd.Flags().GetBool(FOLLOW_FLAG)
+	if poll, _ := cmd.Flags().GetInt(POLL_FLAG); poll > 0 {
+		opts.Poll = time.Duration(poll) * time.Second
 	}
+	LogFilterOptions(cmd, opts)
 
-	showBreaks := len(logs) > 1
-	for _, log := range logs {
-		if showBreaks {
-			fmt.Printf("\n::: [ %s - Logs For: %s ] ::: \n", args[0], log.TaskID)
-		}
-		fmt.Printf("%s\n", log.Log)
-		if showBreaks {
-			fmt.Printf("\n!!! [ %s - End Logs For: %s ] !!! \n", args[0], log.TaskID)
-		}
-	}
-}
-
-func getMesosAppIdentifier(cmd *cobra.Command, c *ml.MesosClient, appId string) string {
-	tasks, err := client(cmd).GetTasks(appId)
+	host, err := mesosHost(configFile.Environments[viper.GetString(ENV_NAME)].Marathon)
 	if err != nil {
-		log.Fatal(err)
+		exitWithError(err)
 	}
 
-	if len(tasks) > 0 {
-		name, err := c.GetAppNameForTaskID(tasks[0].ID)
-		if err != nil {
-			log.Fatal(err)
+	mb := backend.NewMarathonBackend(client(cmd), host)
+	if opts.Follow {
+		if err := mb.Logs(args[0], opts, os.Stdout); err != nil {
+			exitWithError(err)
 		}
-		return name
+		return
 	}
 
-	log.Fatal("Currently no tasks found for application: %s\n", appId)
-	return ""
+	out, done := cli.StartPager(os.Stdout)
+	defer done()
+	if err := mb.Logs(args[0], opts, out); err != nil {
+		done()
+		exitWithError(err)
+	}
 }
 
-func getMesosHost() string {
-	envName := viper.GetString("env_name")
-	mc := *configFile.Environments[envName].Marathon
-
-	u, err := url.Parse(mc.HostUrl)
+// Returns the host of the Mesos master which is assumed to share the host of Marathon
+func mesosHost(service *cliconfig.ServiceConfig) (string, error) {
+	u, err := url.Parse(marathon.SplitHosts(service.HostUrl)[0])
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 	if strings.Index(u.Host, ":") > 0 {
-		return strings.Split(u.Host, ":")[0]
+		return strings.Split(u.Host, ":")[0], nil
 	}
-	return u.Host
+	return u.Host, nil
 }
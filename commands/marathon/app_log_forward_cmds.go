@@ -0,0 +1,107 @@
+package marathon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logforward"
+	ml "github.com/ContainX/go-mesoslog/mesoslog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	TO_FLAG         = "to"
+	FROM_START_FLAG = "from-start"
+)
+
+var logForwardCmd = &cobra.Command{
+	Use:   "forward [appId]",
+	Short: "Continuously ship the stdout and stderr of an application's tasks to syslog or Elasticsearch",
+	Long: `Continuously ship the stdout and stderr of an application's tasks to a central sink until interrupted.
+Each entry carries the application, task and stream it was logged by.  Sinks:
+
+  syslog://host[:514]         RFC 5424 over UDP
+  syslog+tcp://host[:514]     RFC 5424 over TCP
+  es://[user:pass@]host:9200/index         Elasticsearch bulk API (default index depcon-logs)
+  es+https://[user:pass@]host:9200/index   Elasticsearch over https`,
+	Example: `  depcon app log forward /web --to syslog://logs.example.com:514
+  depcon app log forward /web --to es://es.example.com:9200/web-logs --from-start`,
+	Run: forwardLogs,
+}
+
+func init() {
+	logForwardCmd.Flags().String(TO_FLAG, "", "URL of the sink the logs are shipped to (required)")
+	logForwardCmd.Flags().Bool(FROM_START_FLAG, false, "Ship the logs written before forwarding started")
+	logForwardCmd.Flags().IntP(POLL_FLAG, "p", 5, "Log poll time (duration) in seconds")
+	logCmd.AddCommand(logForwardCmd)
+}
+
+func forwardLogs(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	to, _ := cmd.Flags().GetString(TO_FLAG)
+	if to == "" {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s is required", TO_FLAG)))
+	}
+	sink, err := logforward.New(to)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	defer sink.Close()
+
+	fetch, err := taskLogs(cmd, args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	poll, _ := cmd.Flags().GetInt(POLL_FLAG)
+	if poll < 1 {
+		poll = 5
+	}
+	f := &logforward.Forwarder{App: args[0], Sink: sink, Fetch: fetch}
+	f.FromStart, _ = cmd.Flags().GetBool(FROM_START_FLAG)
+
+	log.Info("Forwarding the logs of '%s' to %s (interrupt to stop)", args[0], to)
+	f.Run(cli.Context(), time.Duration(poll)*time.Second)
+}
+
+// Returns a function reading the stdout and stderr sandbox logs of the tasks of application {id} from Mesos
+func taskLogs(cmd *cobra.Command, id string) (func() ([]*logforward.TaskLog, error), error) {
+	tasks, err := client(cmd).GetTasks(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, backend.ErrorNoInstances
+	}
+	host, err := mesosHost(configFile.Environments[viper.GetString(ENV_NAME)].Marathon)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ml.NewMesosClient(host, backend.MesosPort)
+	if err != nil {
+		return nil, err
+	}
+	name, err := c.GetAppNameForTaskID(tasks[0].ID)
+	if err != nil {
+		return nil, err
+	}
+
+	streams := map[string]ml.LogType{"stdout": ml.STDOUT, "stderr": ml.STDERR}
+	return func() ([]*logforward.TaskLog, error) {
+		logs := []*logforward.TaskLog{}
+		for stream, t := range streams {
+			out, err := c.GetLog(name, t, "")
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range out {
+				logs = append(logs, &logforward.TaskLog{Task: l.TaskID, Stream: stream, Log: l.Log})
+			}
+		}
+		return logs, nil
+	}, nil
+}
@@ -0,0 +1,178 @@
+package marathon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/diff"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/spf13/cobra"
+)
+
+const (
+	FROM_ENV_FLAG = "from"
+	TO_ENV_FLAG   = "to"
+	OVERRIDE_FLAG = "override"
+)
+
+var appPromoteCmd = &cobra.Command{
+	Use:   "promote [applicationId]",
+	Short: "Deploys an application as it runs in one environment to another environment",
+	Long: `Exports the definition of [applicationId] from the --from environment, applies the --override file for
+the --to environment, shows the difference with the application deployed to --to and deploys it once
+confirmed (or with --yes).  What was tested is promoted rather than a descriptor rendered again.
+
+The override file is a JSON merge patch (JSON or YAML) of the fields which differ between environments.
+It is rendered with --tempctx for the --to environment and -p params so one file serves every environment.
+
+    instances: {{ .instances }}
+    env:
+      DB_HOST: ${DB_HOST}
+      DEBUG: null              # removes DEBUG
+
+    eg. depcon app promote /web --from staging --to prod --override promote.yaml --tempctx ctx.json -w`,
+	Run: promoteApp,
+}
+
+func init() {
+	appPromoteCmd.Flags().String(FROM_ENV_FLAG, "", "Environment the application is exported from (required)")
+	appPromoteCmd.Flags().String(TO_ENV_FLAG, "", "Environment the application is deployed to (required)")
+	appPromoteCmd.Flags().String(OVERRIDE_FLAG, "", "JSON or YAML merge patch applied to the exported application")
+	appPromoteCmd.Flags().String(TEMPLATE_CTX_FLAG, "", "Template context the override file is rendered with for the --to environment")
+	appPromoteCmd.Flags().StringSliceP(PARAMS_FLAG, "p", nil, `Adds a param(s) that can be used for substitution within the override file.
+                  eg. -p MYVAR=value would replace ${MYVAR} with "value"`)
+	appPromoteCmd.Flags().BoolP(IGNORE_MISSING, "i", false, "Ignore missing ${PARAMS} and template fields rather than failing")
+	appPromoteCmd.Flags().Bool(DRYRUN_FLAG, false, "Show the difference without deploying")
+	appPromoteCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the promoted application to become healthy")
+	appPromoteCmd.Flags().Duration(TIMEOUT_FLAG, 0, "Max duration to wait for the application to become healthy.  Default: derived from the health checks")
+	ApplySignatureFlags(appPromoteCmd)
+}
+
+func promoteApp(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	from, _ := cmd.Flags().GetString(FROM_ENV_FLAG)
+	to, _ := cmd.Flags().GetString(TO_ENV_FLAG)
+	if from == "" || to == "" {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s and --%s are required", FROM_ENV_FLAG, TO_ENV_FLAG)))
+	}
+	if from == to {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s and --%s must be different environments", FROM_ENV_FLAG, TO_ENV_FLAG)))
+	}
+
+	source, err := environmentClient(cmd, from)
+	if err != nil {
+		exitWithError(err)
+	}
+	target, err := environmentClient(cmd, to)
+	if err != nil {
+		exitWithError(err)
+	}
+	app, err := source.GetApplication(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	overrides, err := promoteOverrides(cmd, to)
+	if err != nil {
+		exitWithError(err)
+	}
+	promoted, err := marathon.Promote(app, overrides)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	live, err := target.GetApplication(args[0])
+	if err != nil {
+		if marathon.ErrorCodeOf(err) != marathon.CodeNotFound {
+			exitWithError(err)
+		}
+		live = nil
+	}
+	d, err := promoteDiff(promoted, live, from, to)
+	if err != nil {
+		exitWithError(err)
+	}
+	if d == "" {
+		fmt.Printf("Application '%s' in '%s' already matches '%s'\n", promoted.ID, to, from)
+		return
+	}
+	fmt.Print(d)
+	if dryrun, _ := cmd.Flags().GetBool(DRYRUN_FLAG); dryrun {
+		return
+	}
+	if err := cli.Confirm(fmt.Sprintf("Promote application '%s' from '%s' to environment '%s'", promoted.ID, from, to)); err != nil {
+		exitWithError(err)
+	}
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	v, e := target.CreateApplication(promoted, wait, true)
+	cli.Output(templateFor(T_APPLICATION, v), e)
+}
+
+// Reads the --override file rendered for the environment {env} returning nil when there is none
+func promoteOverrides(cmd *cobra.Command, env string) (map[string]interface{}, error) {
+	filename, _ := cmd.Flags().GetString(OVERRIDE_FLAG)
+	if filename == "" {
+		return nil, nil
+	}
+	if verify := SignatureVerifier(cmd, env); verify != nil {
+		if err := verify(filename); err != nil {
+			return nil, err
+		}
+	}
+	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
+	ignore, _ := cmd.Flags().GetBool(IGNORE_MISSING)
+	params, _ := cmd.Flags().GetStringSlice(PARAMS_FLAG)
+	envParams := make(map[string]string)
+	for _, p := range params {
+		if strings.Contains(p, "=") {
+			v := strings.SplitN(p, "=", 2)
+			envParams[v[0]] = v[1]
+		}
+	}
+
+	rendered, err := RenderDescriptor(filename, tempctx, env, envParams, ignore)
+	if err != nil {
+		return nil, err
+	}
+	parsed, missing := envsubst.SubstTokens(strings.NewReader(rendered), envParams)
+	if len(missing) > 0 && !ignore {
+		return nil, &envsubst.MissingParamsError{Filename: filename, Params: missing}
+	}
+	enc, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	overrides := map[string]interface{}{}
+	if err := enc.UnMarshalStr(parsed, &overrides); err != nil {
+		return nil, fmt.Errorf("The override file %s must be an object: %s", filename, err.Error())
+	}
+	return overrides, nil
+}
+
+// Returns the unified difference between the {live} application of environment {to} (nil when it doesn't
+// exist) and the application {promoted} from environment {from}
+func promoteDiff(promoted, live *marathon.Application, from, to string) (string, error) {
+	enc := encoding.DefaultJSONEncoder()
+	current := ""
+	if live != nil {
+		definition, err := marathon.Definition(live)
+		if err != nil {
+			return "", err
+		}
+		if current, err = enc.MarshalIndent(definition); err != nil {
+			return "", err
+		}
+	}
+	desired, err := enc.MarshalIndent(promoted)
+	if err != nil {
+		return "", err
+	}
+	// secrets are masked after diffing so a changed secret is still shown as a change
+	return mask.Text(diff.Unified(current, desired, fmt.Sprintf("%s (%s)", promoted.ID, to), fmt.Sprintf("%s (%s)", promoted.ID, from), diff.DefaultContext)), nil
+}
@@ -0,0 +1,143 @@
+package marathon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const T_APP_SNAPSHOT = `
+{{ "ID" | header }}	{{ "ENVIRONMENT" | header }}	{{ "VERSION" | header }}	{{ "INSTANCES" | header }}	{{ "CREATED" | header }}
+{{ .ID }}	{{ .Environment }}	{{ .Version }}	{{ .Instances | intToString }}	{{ .Created.Format "2006-01-02 15:04:05" }}
+`
+
+var appSnapshotCmd = &cobra.Command{
+	Use:   "snapshot [applicationId] [file(.json | .yaml)]",
+	Short: "Saves the definition, version and instances of an application to a file",
+	Long: `Saves the full definition of [applicationId] along with its current version and instances to [file]
+(default: <name>.snapshot.yaml) so the application can later be restored to this known-good state with
+app restore, independent of the versions Marathon keeps.
+
+    eg. depcon app snapshot /shop/web web-before-tuning.yaml`,
+	Run: snapshotApp,
+}
+
+var appRestoreCmd = &cobra.Command{
+	Use:   "restore [snapshot-file]",
+	Short: "Restores an application to the definition and instances saved by app snapshot",
+	Long: `Shows the difference between the application running in the environment and [snapshot-file] and
+deploys the snapshot's definition and instances once confirmed (or with --yes).  The application is
+created when it no longer exists.
+
+    eg. depcon app restore web-before-tuning.yaml --dry-run
+        depcon app restore web-before-tuning.yaml -w`,
+	Run: restoreAppSnapshot,
+}
+
+func init() {
+	appRestoreCmd.Flags().Bool(DRYRUN_FLAG, false, "Show the difference without restoring")
+	appRestoreCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the restored application to become healthy")
+	ApplySignatureFlags(appRestoreCmd)
+}
+
+func snapshotApp(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	app, err := client(cmd).GetApplication(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	snapshot, err := marathon.Snapshot(app, viper.GetString(ENV_NAME))
+	if err != nil {
+		exitWithError(err)
+	}
+
+	filename := app.ID[strings.LastIndex(app.ID, "/")+1:] + ".snapshot.yaml"
+	if len(args) > 1 {
+		filename = args[1]
+	}
+	enc, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	var data string
+	if json, ok := enc.(*encoding.JSONEncoder); ok {
+		data, err = json.MarshalIndent(snapshot)
+	} else {
+		data, err = enc.Marshal(snapshot)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		exitWithError(err)
+	}
+	cli.Output(templateFor(T_APP_SNAPSHOT, snapshot), nil)
+	log.Info("Saved the snapshot of '%s' to %s", app.ID, filename)
+}
+
+func restoreAppSnapshot(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	VerifySignatures(cmd, args[0])
+	enc, err := encoding.NewEncoderFromFileExt(args[0])
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	snapshot := &marathon.AppSnapshot{}
+	if err := enc.UnMarshalStr(string(data), snapshot); err != nil {
+		exitWithError(fmt.Errorf("%s: %s", args[0], err.Error()))
+	}
+	if snapshot.App == nil || snapshot.ID == "" {
+		exitWithError(fmt.Errorf("%s is not a snapshot of an application", args[0]))
+	}
+	env := viper.GetString(ENV_NAME)
+	if snapshot.Environment != "" && snapshot.Environment != env {
+		log.Warning("The snapshot of '%s' was taken in environment '%s' and is restored to '%s'", snapshot.ID, snapshot.Environment, env)
+	}
+
+	c := client(cmd)
+	app := snapshot.Definition()
+	live, err := c.GetApplication(snapshot.ID)
+	if err != nil {
+		if marathon.ErrorCodeOf(err) != marathon.CodeNotFound {
+			exitWithError(err)
+		}
+		live = nil
+	}
+	d, err := promoteDiff(app, live, "snapshot "+snapshot.Version, env)
+	if err != nil {
+		exitWithError(err)
+	}
+	if d == "" {
+		fmt.Printf("Application '%s' in '%s' already matches the snapshot\n", snapshot.ID, env)
+		return
+	}
+	fmt.Print(d)
+	if dryrun, _ := cmd.Flags().GetBool(DRYRUN_FLAG); dryrun {
+		return
+	}
+	confirmOrExit(func() (string, error) {
+		return fmt.Sprintf("Restore application '%s' to its snapshot of version %s (%d instances)", snapshot.ID, snapshot.Version, snapshot.Instances), nil
+	})
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	v, e := c.CreateApplication(app, wait && snapshot.Instances > 0, true)
+	if e == nil && snapshot.Instances == 0 {
+		// instances of 0 are left out of a definition so the restored application is scaled down after
+		v, e = c.UpdateApplicationPartial(snapshot.ID, map[string]interface{}{"instances": 0}, &marathon.PatchOptions{Wait: wait, Force: true})
+	}
+	cli.Output(templateFor(T_APPLICATION, v), e)
+}
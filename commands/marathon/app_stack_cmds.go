@@ -0,0 +1,181 @@
+package marathon
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/ContainX/depcon/stack"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	NO_ROLLBACK_FLAG = "no-rollback"
+	ActionStack      = "deploy-stack"
+
+	T_STACK = `
+{{ "ID" | header }}	{{ "ACTION" | header }}	{{ "RESULT" | header }}	{{ "FIELDS" | header }}	{{ "DEPENDS ON" | header }}	{{ "FILE" | header }}
+{{ range . }}{{ .ID }}	{{ .Action }}	{{ .Result }}	{{ len .Fields | intToString }}	{{ join .DependsOn }}	{{ .File }}
+{{end}}`
+)
+
+var appDeployStackCmd = &cobra.Command{
+	Use:   "deploy-stack [dir | stack.yaml]",
+	Short: "Deploys a set of application descriptors in dependency order rolling them back when one fails",
+	Long: `Deploys the application descriptors of a stack one at a time, waiting for each to be healthy before the
+next.  The descriptors share the template context and params.  A stack is either a directory whose
+descriptors are deployed or a manifest listing them:
+
+    name: shop
+    tempctx: template-context.json    # optional, relative to the manifest
+    params:                           # optional, overridden by -c and -p
+      TAG: "1.4"
+    apps:
+      - file: db.json
+      - file: api.yaml
+        dependsOn: [/shop/db]
+        timeout: 5m
+      - file: web.yaml
+        dependsOn: [api]              # relative to the application's group
+
+An application is deployed after those listed by its dependsOn, its DEPCON_DEPENDS_ON label (ids separated
+by commas) and its Marathon dependencies.  Other applications keep the order they're declared in (or the
+order of their files within a directory).  Unchanged applications are skipped.  When an application fails
+to deploy or become healthy the rest aren't deployed and the applications updated by the run are rolled
+back to their previous versions (and those it created are removed) unless --no-rollback is set.
+
+    eg. depcon app deploy-stack stack.yaml -e prod -p TAG=1.4
+        depcon app deploy-stack ./apps --dry-run`,
+	Run: deployStack,
+}
+
+func init() {
+	ApplyDescriptorFlags(appDeployStackCmd)
+	appDeployStackCmd.Flags().Lookup(DRYRUN_FLAG).Usage = "Prints the deployment plan and the rendered descriptors without deploying"
+	appDeployStackCmd.Flags().DurationP(TIMEOUT_FLAG, "t", 0, "Max duration to wait for each application to be healthy unless the manifest sets its timeout (ex. 90s | 2m).  0 waits forever")
+	appDeployStackCmd.Flags().Bool(NO_ROLLBACK_FLAG, false, "Leaves the applications changed by a failed deploy in place rather than rolling them back")
+	applyPolicyFlags(appDeployStackCmd)
+	ApplySignatureFlags(appDeployStackCmd)
+}
+
+func deployStack(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	s, err := stack.Load(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	VerifySignatures(cmd, s.Files()...)
+
+	envName := viper.GetString(ENV_NAME)
+	ignore, _ := cmd.Flags().GetBool(IGNORE_MISSING)
+	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
+	if !cmd.Flags().Changed(TEMPLATE_CTX_FLAG) && s.TemplateContext != "" {
+		tempctx = s.Path(s.TemplateContext)
+	}
+	opts := &reconcile.LoadOptions{Params: descriptorParams(cmd), IgnoreMissing: ignore}
+	opts.Render = func(filename string) (string, error) {
+		return RenderDescriptor(filename, tempctx, envName, opts.Params, ignore)
+	}
+	if tempctx != "" {
+		opts.Exclude = []string{tempctx}
+	}
+	apps, err := s.Load(opts)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	noRollback, _ := cmd.Flags().GetBool(NO_ROLLBACK_FLAG)
+	deployer := &stack.Deployer{Marathon: client(cmd), Timeout: WaitTimeout(cmd, marathon.DefaultTimeout), NoRollback: noRollback}
+	if dryrun, _ := cmd.Flags().GetBool(DRYRUN_FLAG); dryrun {
+		printStackPlan(cmd, deployer, apps)
+		return
+	}
+
+	// every application is checked before any is deployed so a violation doesn't leave the stack half deployed
+	checker := policyChecker(cmd)
+	defer checker.Close()
+	if checker.Enabled() {
+		check := validateWithPolicies(checker)
+		for _, a := range apps {
+			if err := check(a.App); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+
+	deployer.Report = func(r *stack.Result) {
+		if r.Result == stack.ResultFailed || r.Result == stack.ResultRollbackFailed {
+			log.Error("'%s' %s: %s", r.ID, r.Result, r.Error)
+		} else {
+			log.Info("'%s' %s", r.ID, r.Result)
+		}
+	}
+	log.Info("Deploying %d application(s) of stack '%s' to '%s'", len(apps), s.Name, envName)
+	results, err := deployer.Deploy(apps)
+	recordStack(envName, args[0], s.Name, results, err)
+	if results != nil {
+		cli.Output(templateFor(T_STACK, results), nil)
+	}
+	if err != nil {
+		exitWithError(fmt.Errorf("Stack '%s': %w", s.Name, err))
+	}
+}
+
+// Prints the changes {deployer} would make followed by each rendered descriptor in the order they're deployed
+func printStackPlan(cmd *cobra.Command, deployer *stack.Deployer, apps []*stack.App) {
+	plan, err := deployer.Plan(apps)
+	if err != nil {
+		exitWithError(err)
+	}
+	cli.Output(templateFor(T_STACK, plan), nil)
+	if outputFormat(cmd) == "json" {
+		return
+	}
+	for i, a := range apps {
+		b, err := json.MarshalIndent(a.App, "", "  ")
+		if err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("\nDeploy Stack :: DryRun :: %d. %s (%s)\n\n%s\n", i+1, a.ID, a.File, mask.Text(string(b)))
+	}
+}
+
+// Records each application changed by the stack {name} followed by a summary in the audit log
+func recordStack(envName, filename, name string, results []*stack.Result, err error) {
+	l := audit.New(filepath.Join(cliconfig.ConfigDir(), audit.DefaultFilename))
+	details := map[string]string{"stack": filename}
+	entries := []*audit.Entry{}
+	changed := []string{}
+	for _, r := range results {
+		if r.Action == reconcile.ActionNone || r.Result == stack.ResultSkipped {
+			continue
+		}
+		changed = append(changed, r.ID)
+		e := &audit.Entry{Environment: envName, Action: r.Action, Target: r.ID, Result: audit.ResultSuccess, Details: details, Message: r.Result}
+		if r.Result != stack.ResultDeployed {
+			e.Result, e.Message = audit.ResultFailed, strings.TrimSpace(r.Result+" "+r.Error)
+		}
+		entries = append(entries, e)
+	}
+	summary := &audit.Entry{Environment: envName, Action: ActionStack, Target: name, Result: audit.ResultSuccess, Details: details,
+		Message: fmt.Sprintf("%d application(s) changed: %s", len(changed), strings.Join(changed, ", "))}
+	if err != nil {
+		summary.Result, summary.Message = audit.ResultFailed, err.Error()
+	}
+	for _, e := range append(entries, summary) {
+		if err := l.Record(e); err != nil {
+			log.Error("Unable to write the audit log %s: %s", l.Filename(), err.Error())
+			return
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package marathon
+
+import (
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var appWatchCmd = &cobra.Command{
+	Use:   "watch [appId]",
+	Short: "Prints the events of an application as Marathon reports them until interrupted (Ctrl-C)",
+	Long: `Tails the deployment steps, health checks and task status changes of an application (or the applications
+within a group) from Marathon's event stream until interrupted, eg. while a rollout started elsewhere is in
+progress.  The success or failure of the deployments changing the application is printed too.  With -o json
+each event is written as a JSON object per line
+
+    eg. depcon app watch /web
+        depcon app watch /prod --type deployment_step_success,deployment_step_failure,failed_health_check_event`,
+	Run: watchApp,
+}
+
+func init() {
+	applyEventTypeFlag(appWatchCmd)
+}
+
+func watchApp(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	types := eventTypeFilter(cmd)
+	if types == 0 {
+		types = marathon.EventIDDeployments | marathon.EventIDApplications | marathon.EventIDAddHealthCheck | marathon.EventIDRemoveHealthCheck
+	}
+	printEvents(cmd, types, marathon.NewEventFilter(args[0]), true)
+}
@@ -0,0 +1,100 @@
+package marathon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+var artifactCmd = &cobra.Command{
+	Use:   "artifact",
+	Short: "Marathon artifact store management",
+	Long: `Manage the artifacts (eg. docker.tar.gz credential bundles) served by the artifact store of Marathon.  Marathon
+must be started with --artifact_store
+
+    See artifact's subcommands for available choices`,
+}
+
+var artifactPushCmd = &cobra.Command{
+	Use:   "push [file] [path]",
+	Short: "Uploads the [file] to the artifact store at [path].  Default path: the name of the file",
+	Run:   pushArtifact,
+}
+
+var artifactGetCmd = &cobra.Command{
+	Use:   "get [path] [file]",
+	Short: "Downloads the artifact at [path] to [file] or stdout when [file] is omitted",
+	Run:   getArtifact,
+}
+
+var artifactDeleteCmd = &cobra.Command{
+	Use:   "delete [path]",
+	Short: "Deletes the artifact at [path]",
+	Run:   deleteArtifact,
+}
+
+func init() {
+	artifactCmd.AddCommand(artifactPushCmd, artifactGetCmd, artifactDeleteCmd)
+}
+
+func pushArtifact(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	artifactPath := filepath.Base(args[0])
+	if len(args) > 1 {
+		artifactPath = args[1]
+	}
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	defer file.Close()
+
+	if err := client(cmd).UploadArtifact(artifactPath, file); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("%s has been uploaded to %s\n", args[0], artifactPath)
+}
+
+func getArtifact(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	if len(args) == 1 {
+		if err := client(cmd).FetchArtifact(args[0], os.Stdout); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	file, err := os.Create(args[1])
+	if err != nil {
+		exitWithError(err)
+	}
+	err = client(cmd).FetchArtifact(args[0], file)
+	file.Close()
+	if err != nil {
+		os.Remove(args[1])
+		exitWithError(err)
+	}
+	fmt.Printf("%s has been downloaded to %s\n", args[0], args[1])
+}
+
+func deleteArtifact(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	confirmOrExit(func() (string, error) {
+		return fmt.Sprintf("Delete artifact '%s'", args[0]), nil
+	})
+	if err := client(cmd).DeleteArtifact(args[0]); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("Artifact %s has been deleted\n", args[0])
+}
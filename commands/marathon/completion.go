@@ -0,0 +1,185 @@
+package marathon
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// identifiers are cached briefly so repeated tab presses don't query the cluster
+	completionCacheTTL = 30 * time.Second
+	// completion gives up quickly rather than stalling the shell
+	completionTimeout = 5 * time.Second
+)
+
+var completionCache = cliconfig.CompletionCache{TTL: completionCacheTTL}
+
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// Completes the first argument of app, group, pod, deployment and task commands with identifiers from the
+// current environment
+func registerCompletions() {
+	apps := completeFirstArg(completeIdentifiers("apps", listAppIDs))
+	for _, c := range []*cobra.Command{appGetCmd, appVersionsCmd, appDestroyCmd, appRestartCmd, appScaleCmd,
+		appUpdateCPUCmd, appUpdateMemoryCmd, logCmd, appTaskGetCmd, appTaskKillallCmd, deleteIfDeployingCmd} {
+		c.ValidArgsFunction = apps
+	}
+	appRollbackCmd.ValidArgsFunction = completeRollback
+
+	groups := completeFirstArg(completeIdentifiers("groups", listGroupIDs))
+	groupGetCmd.ValidArgsFunction = groups
+	groupDestroyCmd.ValidArgsFunction = groups
+
+	pods := completeFirstArg(completeIdentifiers("pods", listPodIDs))
+	for _, c := range []*cobra.Command{podGetCmd, podDestroyCmd, podVersionsCmd} {
+		c.ValidArgsFunction = pods
+	}
+
+	deployDeleteCmd.ValidArgsFunction = completeFirstArg(completeIdentifiers("deployments", listDeploymentIDs))
+	appTaskKillCmd.ValidArgsFunction = completeFirstArg(completeIdentifiers("tasks", listTaskIDs))
+}
+
+// Completes --format with the names of the formats defined in the config (eg. @images)
+func completeFormats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := []string{}
+	if configFile != nil {
+		for _, name := range configFile.GetFormats() {
+			names = append(names, cliconfig.FormatPrefix+name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// Completes only the first argument with {fn}
+func completeFirstArg(fn completionFunc) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return fn(cmd, args, toComplete)
+	}
+}
+
+// Completes the application ID followed by the versions of that application
+func completeRollback(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeIdentifiers("apps", listAppIDs)(cmd, args, toComplete)
+	case 1:
+		id := args[0]
+		return completeIdentifiers("versions"+id, func(c marathon.Marathon) ([]string, error) {
+			v, err := c.ListVersions(id)
+			if err != nil {
+				return nil, err
+			}
+			return v.Versions, nil
+		})(cmd, args, toComplete)
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// Returns a completion func listing identifiers of {kind} with {list}.  Results are cached per
+// environment and failures complete nothing
+func completeIdentifiers(kind string, list func(c marathon.Marathon) ([]string, error)) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		envName := viper.GetString(ENV_NAME)
+		if ids, ok := completionCache.Load(envName, kind); ok {
+			return ids, cobra.ShellCompDirectiveNoFileComp
+		}
+		c, err := completionClient(envName)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		ids, err := list(c)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		sort.Strings(ids)
+		completionCache.Store(envName, kind, ids)
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// Creates a client for completion which doesn't retry and times out quickly
+func completionClient(envName string) (marathon.Marathon, error) {
+	env, err := configFile.GetEnvironment(envName)
+	if err != nil {
+		return nil, err
+	}
+	opts := &marathon.MarathonOptions{TLSAllowInsecure: viper.GetBool(INSECURE_FLAG)}
+	opts.Retry = httpclient.DefaultRetryPolicy()
+	opts.Retry.MaxAttempts = 1
+	opts.Timeouts = &httpclient.Timeouts{Request: httpclient.Duration(completionTimeout)}
+	opts.Cache = responseCache()
+	return NewClient(envName, env.Marathon, opts)
+}
+
+func listAppIDs(c marathon.Marathon) ([]string, error) {
+	apps, err := c.ListApplications()
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, app := range apps.Apps {
+		ids = append(ids, app.ID)
+	}
+	return ids, nil
+}
+
+func listPodIDs(c marathon.Marathon) ([]string, error) {
+	pods, err := c.ListPods()
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, p := range pods {
+		ids = append(ids, p.ID)
+	}
+	return ids, nil
+}
+
+func listGroupIDs(c marathon.Marathon) ([]string, error) {
+	groups, err := c.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+	arr := []*marathon.Group{}
+	for _, g := range groups.Groups {
+		arr = flattenGroup(g, arr)
+	}
+	ids := []string{}
+	for _, g := range arr {
+		ids = append(ids, g.GroupID)
+	}
+	return ids, nil
+}
+
+func listDeploymentIDs(c marathon.Marathon) ([]string, error) {
+	deploys, err := c.ListDeployments()
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, d := range deploys {
+		ids = append(ids, d.DeployID)
+	}
+	return ids, nil
+}
+
+func listTaskIDs(c marathon.Marathon) ([]string, error) {
+	tasks, err := c.ListTasks()
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, t := range tasks {
+		ids = append(ids, t.ID)
+	}
+	return ids, nil
+}
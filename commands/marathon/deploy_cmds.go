@@ -7,9 +7,11 @@ import (
 	"github.com/ContainX/depcon/marathon"
 	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/ContainX/depcon/pkg/sops"
+	"github.com/ContainX/depcon/utils"
 	"github.com/spf13/cobra"
-	"io/ioutil"
-	"os"
 	"strings"
 	"time"
 )
@@ -26,8 +28,10 @@ var deployListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all deployments",
 	Run: func(cmd *cobra.Command, args []string) {
-		v, e := client(cmd).ListDeployments()
-		cli.Output(templateFor(T_DEPLOYMENTS, v), e)
+		outputOrWatch(cmd, marathon.EventIDDeployments, func() (cli.Formatter, error) {
+			v, e := client(cmd).ListDeployments()
+			return templateFor(templateFormat(T_DEPLOYMENTS, cmd), v), e
+		})
 	},
 }
 
@@ -40,6 +44,18 @@ var deployDeleteCmd = &cobra.Command{
 		}
 		force, _ := cmd.Flags().GetBool(FORCE_FLAG)
 
+		confirmOrExit(func() (string, error) {
+			deployments, err := client(cmd).ListDeployments()
+			if err != nil {
+				return "", err
+			}
+			for _, d := range deployments {
+				if d.DeployID == args[0] {
+					return fmt.Sprintf("Cancel deployment '%s' of %s", d.DeployID, utils.ConcatIdentifiers(d.AffectedApps)), nil
+				}
+			}
+			return fmt.Sprintf("Cancel deployment '%s'", args[0]), nil
+		})
 		v, e := client(cmd).DeleteDeployment(args[0], force)
 		cli.Output(templateFor(T_DEPLOYMENT_ID, v), e)
 	},
@@ -52,6 +68,9 @@ var deleteIfDeployingCmd = &cobra.Command{
 		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
 			return
 		}
+		confirmOrExit(func() (string, error) {
+			return fmt.Sprintf("Cancel the deployment of application '%s'", args[0]), nil
+		})
 		v, e := client(cmd).CancelAppDeployment(args[0], false)
 		if v != nil || e != nil {
 			cli.Output(templateFor(T_DEPLOYMENT_ID, v), e)
@@ -64,6 +83,31 @@ var deleteIfDeployingCmd = &cobra.Command{
 	},
 }
 
+var deployWaitCmd = &cobra.Command{
+	Use:   "wait [deploymentId]",
+	Short: "Waits for a deployment reporting whether it converged, timed out, got stuck waiting for offers or failed",
+	Long: `Waits for the deployment [deploymentId] to complete.  When it doesn't the state of its applications is
+reported along with the reason (eg. the resources the offers declined for queued tasks lacked) and the
+command exits with a non-zero status (4 when the wait timed out)`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+		result := client(cmd).WaitForDeploymentResult(args[0], WaitTimeout(cmd, marathon.DefaultTimeout))
+		if result.Converged() {
+			cli.Output(templateFor(T_WAIT_RESULT, result), nil)
+			return
+		}
+		exitWithWaitResult(result)
+	},
+}
+
+// Outputs the state and reason of a deployment which didn't converge and exits with its error
+func exitWithWaitResult(result *marathon.WaitResult) {
+	cli.Output(templateFor(T_WAIT_RESULT, result), nil)
+	exitWithError(result.Err())
+}
+
 var deployCreateCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Creates a new app or group by introspecting the incoming descriptor.  Useful for deployment pipelines",
@@ -78,6 +122,7 @@ the other for delegation to the origin (app or group)
 }
 
 func init() {
+	applyFormatFlag(deployListCmd, "{{range .}}{{ .DeployID }} {{end}}")
 
 	deployCreateCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for group to become healthy")
 	deployCreateCmd.Flags().String(TEMPLATE_CTX_FLAG, DEFAULT_CTX, "Provides data per environment in JSON form to do a first pass parse of descriptor as template")
@@ -92,10 +137,15 @@ func init() {
                   These take precidence over env vars`)
 
 	deployCreateCmd.Flags().Bool(DRYRUN_FLAG, false, "Preview the parsed template - don't actually deploy")
+	applyPreflightFlags(deployCreateCmd)
+	ApplySignatureFlags(deployCreateCmd)
+	ApplyRemoteFlags(deployCreateCmd)
+	applyScheduleFlags(deployCreateCmd)
 
-	deployCreateCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for application health (ex. 90s | 2m). See docs for ordering")
+	deployCreateCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for application health (ex. 90s | 2m).  0 waits forever. See docs for ordering")
 	deployDeleteCmd.Flags().BoolP(FORCE_FLAG, "f", false, "If set to true, then the deployment is still canceled but no rollback deployment is created.")
-	deployCmd.AddCommand(deployCreateCmd, deployListCmd, deployDeleteCmd, deleteIfDeployingCmd)
+	deployWaitCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the deployment (ex. 90s | 2m).  0 waits forever")
+	deployCmd.AddCommand(deployCreateCmd, deployListCmd, deployDeleteCmd, deleteIfDeployingCmd, deployWaitCmd)
 }
 
 func deployAppOrGroup(cmd *cobra.Command, args []string) {
@@ -104,7 +154,9 @@ func deployAppOrGroup(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	filename := args[0]
+	filename, cleanup := FetchDescriptor(cmd, args[0])
+	defer cleanup()
+	VerifySignatures(cmd, filename)
 	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
 	force, _ := cmd.Flags().GetBool(FORCE_FLAG)
 	paramsFile, _ := cmd.Flags().GetString(ENV_FILE_FLAG)
@@ -114,20 +166,19 @@ func deployAppOrGroup(cmd *cobra.Command, args []string) {
 	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
 	dryrun, _ := cmd.Flags().GetBool(DRYRUN_FLAG)
 	options := &marathon.CreateOptions{Wait: wait, Force: force, ErrorOnMissingParams: !ignore, StopDeploy: stop_deploy, DryRun: dryrun}
+	options.Prepare = registryCredentials(cmd)
+	options.Validate = createChecks(cmd, policyChecker(cmd))
 
-	descriptor := parseDescriptor(tempctx, filename)
 	et, err := encoding.NewEncoderFromFileExt(filename)
 	if err != nil {
 		exitWithError(err)
 	}
 
-	ag := &marathon.AppOrGroup{}
-	if err := et.UnMarshalStr(descriptor, ag); err != nil {
-		exitWithError(err)
-	}
-
 	if paramsFile != "" {
-		envParams, _ := parseParamsFile(paramsFile)
+		envParams, err := ParseParamsFile(paramsFile)
+		if err != nil {
+			exitWithError(err)
+		}
 		options.EnvParams = envParams
 	} else {
 		options.EnvParams = make(map[string]string)
@@ -136,12 +187,38 @@ func deployAppOrGroup(cmd *cobra.Command, args []string) {
 	if params != nil {
 		for _, p := range params {
 			if strings.Contains(p, "=") {
-				v := strings.Split(p, "=")
+				v := strings.SplitN(p, "=", 2)
 				options.EnvParams[v[0]] = v[1]
 			}
 		}
 	}
 
+	descriptor := parseDescriptor(tempctx, filename, options.EnvParams, ignore)
+	fileType, _ := encoding.EncoderTypeFromExt(filename)
+	docs := encoding.SplitDocuments(fileType, descriptor)
+
+	if options.DryRun && len(docs) > 1 {
+		for idx, doc := range docs {
+			parsed, _ := envsubst.SubstFileTokens(strings.NewReader(doc), options.EnvParams)
+			fmt.Printf("Deploy :: DryRun :: Template Output [%d]\n\n%s\n", idx, mask.Text(parsed))
+		}
+		return
+	}
+
+	deferDeployment(cmd, filename, docs, options)
+
+	// multi-document descriptors are deployed in order and may mix apps and groups
+	for idx, doc := range docs {
+		ag := &marathon.AppOrGroup{}
+		if err := et.UnMarshalStr(doc, ag); err != nil {
+			exitWithError(err)
+		}
+		reportBulkStep(idx+1, len(docs), "Deploying "+ag.ID)
+		deployDocument(cmd, filename, doc, ag, options)
+	}
+}
+
+func deployDocument(cmd *cobra.Command, filename, descriptor string, ag *marathon.AppOrGroup, options *marathon.CreateOptions) {
 	if ag.IsApplication() {
 		result, e := client(cmd).CreateApplicationFromString(filename, descriptor, options)
 		outputDeployment(result, e)
@@ -160,7 +237,7 @@ func deployAppOrGroup(cmd *cobra.Command, args []string) {
 }
 
 func outputDeployment(result interface{}, e error) {
-	if e != nil && e == marathon.ErrorAppExists {
+	if errors.Is(e, marathon.ErrorAppExists) {
 		exitWithError(errors.New(fmt.Sprintf("%s, consider using the --force flag to update when an application exists", e.Error())))
 	}
 
@@ -168,11 +245,11 @@ func outputDeployment(result interface{}, e error) {
 		if e != nil {
 			exitWithError(e)
 		}
-		os.Exit(1)
+		cli.ExitWithCode(cli.ExitError)
 	}
 }
 
-func parseDescriptor(tempctx, filename string) string {
+func parseDescriptor(tempctx, filename string, params map[string]string, ignore bool) string {
 	if TemplateExists(tempctx) {
 		b := &bytes.Buffer{}
 
@@ -180,13 +257,14 @@ func parseDescriptor(tempctx, filename string) string {
 		if err != nil {
 			exitWithError(err)
 		}
+		r.ErrorOnMissing, r.Params = !ignore, params
 
 		if err := r.Transform(b, filename); err != nil {
 			exitWithError(err)
 		}
 		return b.String()
 	} else {
-		if b, err := ioutil.ReadFile(filename); err != nil {
+		if b, err := sops.ReadFile(filename); err != nil {
 			exitWithError(err)
 		} else {
 			return string(b)
@@ -195,3 +273,12 @@ func parseDescriptor(tempctx, filename string) string {
 	}
 	return ""
 }
+
+// Returns the documents within a multi-document YAML descriptor.  Descriptors which are not YAML
+// return nil
+func parseDocuments(tempctx, filename string, params map[string]string, ignore bool) []string {
+	if et, err := encoding.EncoderTypeFromExt(filename); err != nil || et != encoding.YAML {
+		return nil
+	}
+	return encoding.SplitDocuments(encoding.YAML, parseDescriptor(tempctx, filename, params, ignore))
+}
@@ -0,0 +1,88 @@
+package marathon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/spf13/cobra"
+)
+
+// ApplyDescriptorFlags adds the template context, params and dry-run flags used by RenderDocuments to {cmd}.
+// Other backends (eg. k8s) render their descriptors through the same pipeline as Marathon
+func ApplyDescriptorFlags(cmd *cobra.Command) {
+	cmd.Flags().String(TEMPLATE_CTX_FLAG, DEFAULT_CTX, "Provides data per environment in JSON form to do a first pass parse of descriptor as template")
+	cmd.Flags().BoolP(IGNORE_MISSING, "i", false, `Ignore missing ${PARAMS} that are declared in the descriptor that could not be resolved
+                        CAUTION: This can be dangerous if some params define versions or other required information.`)
+	cmd.Flags().StringP(ENV_FILE_FLAG, "c", "", `Adds a file with a param(s) that can be used for substitution.
+						These take precidence over env vars`)
+	cmd.Flags().StringSliceP(PARAMS_FLAG, "p", nil, `Adds a param(s) that can be used for substitution.
+                  eg. -p MYVAR=value would replace ${MYVAR} with "value" in the descriptor.
+                  These take precidence over env vars`)
+	cmd.Flags().Bool(DRYRUN_FLAG, false, "Preview the parsed template - don't actually deploy")
+}
+
+// RenderDocuments renders the descriptor {filename} with the template context and ${PARAMS} of the flags
+// added by ApplyDescriptorFlags and returns each document it contains.  With --dry-run the rendered
+// descriptor is printed and the command exits.  Every document is parsed before returning so a malformed
+// descriptor is reported before anything is deployed
+func RenderDocuments(cmd *cobra.Command, filename string) []map[string]interface{} {
+	ignore, _ := cmd.Flags().GetBool(IGNORE_MISSING)
+	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
+	dryrun, _ := cmd.Flags().GetBool(DRYRUN_FLAG)
+
+	et, err := encoding.EncoderTypeFromExt(filename)
+	if err != nil {
+		exitWithError(err)
+	}
+	encoder, err := encoding.NewEncoder(et)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	params := descriptorParams(cmd)
+	parsed, missing := envsubst.SubstTokens(strings.NewReader(parseDescriptor(tempctx, filename, params, ignore)), params)
+	if !ignore && len(missing) > 0 {
+		exitWithError(&envsubst.MissingParamsError{Filename: filename, Params: missing})
+	}
+
+	if dryrun {
+		fmt.Printf("Deploy :: DryRun :: Template Output\n\n%s", mask.Text(parsed))
+		cli.ExitWithCode(cli.ExitSuccess)
+	}
+
+	docs := []map[string]interface{}{}
+	for i, doc := range encoding.SplitDocuments(et, parsed) {
+		m := map[string]interface{}{}
+		if err := encoder.UnMarshalStr(doc, &m); err != nil {
+			exitWithError(fmt.Errorf("Document %d of %s: %s", i+1, filename, err.Error()))
+		}
+		docs = append(docs, m)
+	}
+	return docs
+}
+
+// Returns the ${PARAMS} of the params file (-c) and -p flags added by ApplyDescriptorFlags.  Params of -p take
+// precedence.  Exits if the params file can't be parsed
+func descriptorParams(cmd *cobra.Command) map[string]string {
+	paramsFile, _ := cmd.Flags().GetString(ENV_FILE_FLAG)
+	params, _ := cmd.Flags().GetStringSlice(PARAMS_FLAG)
+
+	envParams := make(map[string]string)
+	if paramsFile != "" {
+		var err error
+		if envParams, err = ParseParamsFile(paramsFile); err != nil {
+			exitWithError(err)
+		}
+	}
+	for _, p := range params {
+		if strings.Contains(p, "=") {
+			v := strings.SplitN(p, "=", 2)
+			envParams[v[0]] = v[1]
+		}
+	}
+	return envParams
+}
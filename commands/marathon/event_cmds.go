@@ -1,9 +1,20 @@
 package marathon
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/spf13/cobra"
 )
 
+const (
+	EVENT_TYPE_FLAG = "type"
+	EVENT_APP_FLAG  = "app"
+)
+
 var eventCmd = &cobra.Command{
 	Use:   "event",
 	Short: "Marathon event streaming and subscription management",
@@ -12,5 +23,70 @@ var eventCmd = &cobra.Command{
     See events's subcommands for available choices`,
 }
 
+var eventStreamCmd = &cobra.Command{
+	Use:   "stream",
+	Short: "Prints events as Marathon reports them until interrupted (Ctrl-C)",
+	Long: `Prints events as Marathon reports them until interrupted (Ctrl-C).  A dropped stream is reconnected
+automatically.  With -o json each event is written as a JSON object per line
+
+    eg. depcon mar event stream --type deployment_step_success,deployment_failed
+        depcon mar event stream --app /prod -o json`,
+	Run: streamEvents,
+}
+
 func init() {
+	eventCmd.AddCommand(eventStreamCmd)
+	applyEventTypeFlag(eventStreamCmd)
+	eventStreamCmd.Flags().StringSlice(EVENT_APP_FLAG, nil, "Only prints events of these applications or groups (eg. /prod/web,/staging)")
+}
+
+// Adds --type filtering the events printed by {cmd}
+func applyEventTypeFlag(cmd *cobra.Command) {
+	cmd.Flags().StringSlice(EVENT_TYPE_FLAG, nil, "Only prints events of these types (eg. deployment_success,deployment_failed)")
+}
+
+// Returns the event types of --type or zero (every event) when it isn't specified.  Exits on unknown types
+func eventTypeFilter(cmd *cobra.Command) int {
+	filter := 0
+	types, _ := cmd.Flags().GetStringSlice(EVENT_TYPE_FLAG)
+	for _, t := range types {
+		id, ok := marathon.EventTypeID(t)
+		if !ok {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Unknown event type '%s'", t)))
+		}
+		filter |= id
+	}
+	return filter
+}
+
+func streamEvents(cmd *cobra.Command, args []string) {
+	apps, _ := cmd.Flags().GetStringSlice(EVENT_APP_FLAG)
+	printEvents(cmd, eventTypeFilter(cmd), marathon.NewEventFilter(apps...), false)
+}
+
+// Prints the events of the types within {types} matched by {filter} until interrupted.  Text output is the
+// event's JSON or with {summarize} a line describing it
+func printEvents(cmd *cobra.Command, types int, filter *marathon.EventFilter, summarize bool) {
+	events, err := client(cmd).EventStream(cli.Context(), types)
+	if err != nil {
+		exitWithError(err)
+	}
+	asJSON := outputFormat(cmd) == "json"
+	for event := range events {
+		if !filter.Match(event) {
+			continue
+		}
+		b, err := json.Marshal(event.Event)
+		if err != nil {
+			continue
+		}
+		switch {
+		case asJSON:
+			fmt.Printf("{\"type\":%q,\"event\":%s}\n", event.Name, b)
+		case summarize:
+			fmt.Printf("%s  %-28s  %s\n", time.Now().Format("15:04:05"), event.Name, marathon.EventSummary(event))
+		default:
+			fmt.Printf("%s  %-28s  %s\n", time.Now().Format("15:04:05"), event.Name, b)
+		}
+	}
 }
@@ -2,12 +2,14 @@ package marathon
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"github.com/ContainX/depcon/marathon"
 	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/mask"
 	"github.com/spf13/cobra"
-	"os"
 	"strings"
 	"time"
 )
@@ -45,13 +47,15 @@ var groupCreateCmd = &cobra.Command{
 }
 
 var groupConvertFileCmd = &cobra.Command{
-	Use:   "convert [from.(json | yaml)] [to.(json | yaml)]",
-	Short: "Utilty to convert an group file from json to yaml or yaml to json.",
+	Use:   "convert [from.(json | yaml | toml)] [to.(json | yaml | toml)]",
+	Short: "Utilty to convert an group file between json, yaml and toml.",
 	Run:   convertGroupFile,
 }
 
 func init() {
-	groupCmd.AddCommand(groupListCmd, groupGetCmd, groupCreateCmd, groupDestroyCmd, groupConvertFileCmd)
+	groupCmd.AddCommand(groupListCmd, groupGetCmd, groupCreateCmd, groupDestroyCmd, groupConvertFileCmd, groupMaintenanceCmd)
+	applyFormatFlag(groupListCmd, "{{range .}}{{ .ID }} {{end}}")
+	applyFormatFlag(groupGetCmd, "{{range .}}{{ .ID }} {{end}}")
 
 	// Destroy Flags
 	groupDestroyCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for destroy to complete")
@@ -60,7 +64,7 @@ func init() {
 	groupCreateCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for group to become healthy")
 	groupCreateCmd.Flags().Bool(STOP_DEPLOYS_FLAG, false, "Stop an existing deployment for this group (if exists) and use this revision")
 	groupCreateCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Force deployment (updates group if it already exists)")
-	groupCreateCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for application health (ex. 90s | 2m). See docs for ordering")
+	groupCreateCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for application health (ex. 90s | 2m).  0 waits forever. See docs for ordering")
 
 	groupCreateCmd.Flags().BoolP(IGNORE_MISSING, "i", false, `Ignore missing ${PARAMS} that are declared in app config that could not be resolved
                         CAUTION: This can be dangerous if some params define versions or other required information.`)
@@ -69,6 +73,8 @@ func init() {
                   These take precidence over env vars and params in file`)
 
 	groupCreateCmd.Flags().Bool(DRYRUN_FLAG, false, "Preview the parsed template - don't actually deploy")
+	ApplySignatureFlags(groupCreateCmd)
+	ApplyRemoteFlags(groupCreateCmd)
 
 }
 
@@ -80,7 +86,7 @@ func listGroups(cmd *cobra.Command, args []string) {
 		arr = flattenGroup(group, arr)
 	}
 
-	cli.Output(templateFor(T_GROUPS, arr), e)
+	cli.Output(templateFor(templateFormat(T_GROUPS, cmd), arr), e)
 }
 
 func getGroup(cmd *cobra.Command, args []string) {
@@ -88,15 +94,34 @@ func getGroup(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	v, e := client(cmd).GetGroup(args[0])
-	arr := flattenGroup(v, []*marathon.Group{})
-	cli.Output(templateFor(T_GROUPS, arr), e)
+	filter := marathon.EventIDGroupChangeSuccess | marathon.EventIDGroupChangeFailed | marathon.EventIDDeployments
+	outputOrWatch(cmd, filter, func() (cli.Formatter, error) {
+		v, e := client(cmd).GetGroup(args[0])
+		if e != nil {
+			return nil, e
+		}
+		return templateFor(templateFormat(T_GROUPS, cmd), flattenGroup(v, []*marathon.Group{})), nil
+	})
 }
 
 func destroyGroup(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
 		return
 	}
+	confirmOrExit(func() (string, error) {
+		g, err := client(cmd).GetGroup(args[0])
+		if err != nil {
+			return "", err
+		}
+		apps, instances := 0, 0
+		for _, cg := range flattenGroup(g, []*marathon.Group{}) {
+			for _, app := range cg.Apps {
+				apps++
+				instances += app.Instances
+			}
+		}
+		return fmt.Sprintf("Destroy group '%s' with %d applications (%d instances)", g.GroupID, apps, instances), nil
+	})
 	v, e := client(cmd).DestroyGroup(args[0])
 	cli.Output(templateFor(T_DEPLOYMENT_ID, v), e)
 }
@@ -105,6 +130,10 @@ func createGroup(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
 		return
 	}
+	filename, cleanup := FetchDescriptor(cmd, args[0])
+	defer cleanup()
+	args[0] = filename
+	VerifySignatures(cmd, args[0])
 
 	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
 	force, _ := cmd.Flags().GetBool(FORCE_FLAG)
@@ -115,18 +144,24 @@ func createGroup(cmd *cobra.Command, args []string) {
 
 	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
 	options := &marathon.CreateOptions{Wait: wait, Force: force, ErrorOnMissingParams: !ignore, StopDeploy: stop_deploy, DryRun: dryrun}
+	options.Prepare = registryCredentials(cmd)
 
 	if params != nil {
 		envmap := make(map[string]string)
 		for _, p := range params {
 			if strings.Contains(p, "=") {
-				v := strings.Split(p, "=")
+				v := strings.SplitN(p, "=", 2)
 				envmap[v[0]] = v[1]
 			}
 		}
 		options.EnvParams = envmap
 	}
 
+	if docs := parseDocuments(tempctx, args[0], options.EnvParams, ignore); len(docs) > 1 {
+		createGroups(cmd, args[0], docs, options)
+		return
+	}
+
 	var result *marathon.Group = nil
 	var e error
 
@@ -137,6 +172,7 @@ func createGroup(cmd *cobra.Command, args []string) {
 		if err != nil {
 			exitWithError(err)
 		}
+		r.ErrorOnMissing, r.Params = !ignore, options.EnvParams
 
 		if err := r.Transform(b, args[0]); err != nil {
 			exitWithError(err)
@@ -147,7 +183,7 @@ func createGroup(cmd *cobra.Command, args []string) {
 	}
 
 	if e != nil {
-		if e == marathon.ErrorGroupExists {
+		if errors.Is(e, marathon.ErrorGroupExists) {
 			cli.Output(nil, fmt.Errorf("%s, consider using the --force flag to update when group exists", e.Error()))
 		} else {
 			cli.Output(nil, e)
@@ -159,6 +195,31 @@ func createGroup(cmd *cobra.Command, args []string) {
 	cli.Output(templateFor(T_GROUPS, arr), e)
 }
 
+// Creates a group for each of the {descriptors} in order and outputs the results
+func createGroups(cmd *cobra.Command, filename string, descriptors []string, options *marathon.CreateOptions) {
+	if options.DryRun {
+		for idx, descriptor := range descriptors {
+			parsed, _ := envsubst.SubstFileTokens(strings.NewReader(descriptor), options.EnvParams)
+			fmt.Printf("Create Group :: DryRun :: Template Output [%d]\n\n%s\n", idx, mask.Text(parsed))
+		}
+		return
+	}
+
+	arr := []*marathon.Group{}
+	for idx, descriptor := range descriptors {
+		reportBulkStep(idx+1, len(descriptors), "Deploying groups from "+filename)
+		result, e := client(cmd).CreateGroupFromString(filename, descriptor, options)
+		if e != nil {
+			if errors.Is(e, marathon.ErrorGroupExists) {
+				e = fmt.Errorf("%s, consider using the --force flag to update when group exists", e.Error())
+			}
+			exitWithError(e)
+		}
+		arr = flattenGroup(result, arr)
+	}
+	cli.Output(templateFor(T_GROUPS, arr), nil)
+}
+
 func flattenGroup(g *marathon.Group, arr []*marathon.Group) []*marathon.Group {
 	arr = append(arr, g)
 	for _, cg := range g.Groups {
@@ -169,11 +230,11 @@ func flattenGroup(g *marathon.Group, arr []*marathon.Group) []*marathon.Group {
 
 func convertGroupFile(cmd *cobra.Command, args []string) {
 	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
-		os.Exit(1)
+		return
 	}
 	if err := encoding.ConvertFile(args[0], args[1], &marathon.Groups{}); err != nil {
 		cli.Output(nil, err)
-		os.Exit(1)
+		return
 	}
 	fmt.Printf("Source file %s has been re-written into new format in %s\n\n", args[0], args[1])
 }
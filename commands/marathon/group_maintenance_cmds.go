@@ -0,0 +1,97 @@
+package marathon
+
+import (
+	"fmt"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/workpool"
+	"github.com/spf13/cobra"
+)
+
+const (
+	EXCLUDE_LABEL_FLAG = "exclude-label"
+
+	MAINTENANCE_ON  = "on"
+	MAINTENANCE_OFF = "off"
+
+	T_MAINTENANCE = `
+{{ "ID" | header }}	{{ "FROM" | header }}	{{ "TO" | header }}
+{{ range . }}{{ .ID }}	{{ .From | intToString }}	{{ .To | intToString }}
+{{end}}`
+)
+
+var groupMaintenanceCmd = &cobra.Command{
+	Use:   "maintenance [on|off] [groupId]",
+	Short: "Scales every application of a group to 0 for maintenance and back to its previous instances",
+	Long: `With on, records the instances of each application of [groupId] (and its sub groups) in the
+DEPCON_MAINTENANCE_INSTANCES label and scales it to 0.  Applications matching an --exclude-label selector
+(key or key==value) keep running.  With off, the applications in maintenance are scaled back to the exact
+instances recorded and the label is removed.
+
+    eg. depcon group maintenance on /shop --exclude-label tier==db --dry-run
+        depcon group maintenance off /shop -w`,
+	Run: groupMaintenance,
+}
+
+func init() {
+	groupMaintenanceCmd.Flags().StringSlice(EXCLUDE_LABEL_FLAG, nil, "Leaves the applications matching the label selector (eg. tier==db or critical) running with on")
+	groupMaintenanceCmd.Flags().Bool(DRYRUN_FLAG, false, "Report the applications which would be scaled without scaling them")
+	groupMaintenanceCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for each application to be scaled")
+	groupMaintenanceCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Scales applications even if they're locked by a deployment in progress")
+	applyWorkersFlags(groupMaintenanceCmd)
+}
+
+func groupMaintenance(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+		return
+	}
+	mode := args[0]
+	if mode != MAINTENANCE_ON && mode != MAINTENANCE_OFF {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Unknown mode '%s': must be %s or %s", mode, MAINTENANCE_ON, MAINTENANCE_OFF)))
+	}
+	c := client(cmd)
+	g, err := c.GetGroup(args[1])
+	if err != nil {
+		exitWithError(err)
+	}
+	apps := []*marathon.Application{}
+	for _, cg := range flattenGroup(g, []*marathon.Group{}) {
+		apps = append(apps, cg.Apps...)
+	}
+
+	var updates []*marathon.MaintenanceUpdate
+	if mode == MAINTENANCE_ON {
+		exclude, _ := cmd.Flags().GetStringSlice(EXCLUDE_LABEL_FLAG)
+		updates = marathon.MaintenanceOn(apps, exclude)
+	} else if updates, err = marathon.MaintenanceOff(apps); err != nil {
+		exitWithError(err)
+	}
+	if len(updates) == 0 {
+		fmt.Printf("No application of group '%s' to scale for maintenance %s\n", g.GroupID, mode)
+		return
+	}
+	if dryRun, _ := cmd.Flags().GetBool(DRYRUN_FLAG); dryRun {
+		cli.Output(templateFor(T_MAINTENANCE, updates), nil)
+		return
+	}
+	confirmOrExit(func() (string, error) {
+		return fmt.Sprintf("Turn maintenance %s for %d application(s) of group '%s'", mode, len(updates), g.GroupID), nil
+	})
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+	tasks := []*workpool.Task{}
+	for _, u := range updates {
+		u := u
+		tasks = append(tasks, &workpool.Task{Name: u.ID, Run: func() error {
+			_, err := c.UpdateApplicationPartial(u.ID, u.Patch, &marathon.PatchOptions{Version: u.Version, Wait: wait, Force: force})
+			return err
+		}})
+	}
+	results := runBulk(cmd, fmt.Sprintf("Turning maintenance %s for %s", mode, g.GroupID), tasks)
+	cli.Output(templateFor(T_BULK_RESULTS, results), nil)
+	if err := workpool.Err(results); err != nil {
+		exitWithError(err)
+	}
+}
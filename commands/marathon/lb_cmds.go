@@ -0,0 +1,190 @@
+package marathon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathonlb"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	LB_GROUP_FLAG = "group"
+	LB_LIVE_FLAG  = "live"
+	DEFAULT_LB    = "http://localhost:9090"
+)
+
+var lbCmd = &cobra.Command{
+	Use:   "lb",
+	Short: "Manage Marathon-LB (HAProxy) load balancers",
+	Long: `Manage Marathon-LB (HAProxy) load balancers through their admin endpoints
+
+    The admin URL is taken from --lb, the environment (depcon config env update [name] --marathon-lb URL)
+    or defaults to http://localhost:9090.  When the host resolves to multiple addresses each address is
+    treated as a Marathon-LB instance
+
+    See lb's subcommands for available choices`,
+}
+
+var lbStatusCmd = &cobra.Command{
+	Use:   "status [applicationId]",
+	Short: "Shows the status of HAProxy backend servers of all apps or a single app",
+	Run:   lbStatus,
+}
+
+var lbReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Regenerates the HAProxy configuration from Marathon and reloads each instance",
+	Run:   lbReload,
+}
+
+var lbValidateCmd = &cobra.Command{
+	Use:   "validate [applicationId | file(.json | .yaml)]",
+	Short: "Validates the HAPROXY_* labels of an app and shows the frontends they produce",
+	Long: `Validates the HAPROXY_* labels of a deployed app or of the apps within a descriptor and shows
+    the frontends and backends Marathon-LB will generate.  Exits with a failure when errors are found
+
+    With --live the backends are also checked against the stats of the running instances`,
+	Run: lbValidate,
+}
+
+func init() {
+	lbCmd.PersistentFlags().String(LB_FLAG, DEFAULT_LB, "Marathon-LB admin URL (HAProxy stats port)")
+	lbValidateCmd.Flags().String(LB_GROUP_FLAG, "external", "HAPROXY_GROUP of the Marathon-LB instances the app should be exposed by.  Empty skips the check")
+	lbValidateCmd.Flags().Bool(LB_LIVE_FLAG, false, "Checks the backends exist within the running instances")
+	ApplyDescriptorFlags(lbValidateCmd)
+	lbCmd.AddCommand(lbStatusCmd, lbReloadCmd, lbValidateCmd)
+}
+
+// Returns the Marathon-LB URL from --lb when specified, the environment or the default
+func lbURL(cmd *cobra.Command) string {
+	if f := cmd.Flags().Lookup(LB_FLAG); f != nil && f.Changed {
+		return f.Value.String()
+	}
+	if env, ok := configFile.Environments[viper.GetString(ENV_NAME)]; ok && env.Marathon != nil && env.Marathon.MarathonLBUrl != "" {
+		return env.Marathon.MarathonLBUrl
+	}
+	return DEFAULT_LB
+}
+
+func lbClient(cmd *cobra.Command) marathonlb.MarathonLB {
+	config := httpclient.NewDefaultConfig()
+	config.TLSInsecureSkipVerify = viper.GetBool(INSECURE_FLAG)
+	lb, err := marathonlb.NewMarathonLBClient(lbURL(cmd), config)
+	if err != nil {
+		exitWithError(err)
+	}
+	return lb
+}
+
+func lbStatus(cmd *cobra.Command, args []string) {
+	if len(args) > 0 {
+		app, err := client(cmd).GetApplication(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		v, e := lbClient(cmd).AppBackends(app)
+		cli.Output(templateFor(T_LB_SERVERS, v), e)
+		return
+	}
+
+	stats, err := lbClient(cmd).Stats()
+	if err != nil {
+		exitWithError(err)
+	}
+	servers := []*marathonlb.Stat{}
+	for _, s := range stats {
+		if s.IsServer() {
+			servers = append(servers, s)
+		}
+	}
+	cli.Output(templateFor(T_LB_SERVERS, servers), nil)
+}
+
+func lbReload(cmd *cobra.Command, args []string) {
+	if service := configFile.Environments[viper.GetString(ENV_NAME)].Marathon; service.ReadOnly && !viper.GetBool(ALLOW_WRITE_FLAG) {
+		exitWithError(httpclient.ErrorReadOnly)
+	}
+	lb := lbClient(cmd)
+	confirmOrExit(func() (string, error) {
+		return fmt.Sprintf("Reload %d Marathon-LB instance(s)", len(lb.Instances())), nil
+	})
+	if err := lb.Reload(); err != nil {
+		exitWithError(err)
+	}
+}
+
+// LBValidation is the frontends and label issues of an app
+type LBValidation struct {
+	AppID     string
+	Frontends []*marathonlb.Frontend
+	Issues    []*marathonlb.LabelIssue
+}
+
+func lbValidate(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	apps := []*marathon.Application{}
+	if _, err := os.Stat(args[0]); err == nil {
+		for _, doc := range RenderDocuments(cmd, args[0]) {
+			app := &marathon.Application{}
+			if b, err := json.Marshal(doc); err != nil || json.Unmarshal(b, app) != nil {
+				exitWithError(fmt.Errorf("%s does not contain Marathon applications", args[0]))
+			}
+			apps = append(apps, app)
+		}
+	} else {
+		app, err := client(cmd).GetApplication(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		apps = append(apps, app)
+	}
+
+	group, _ := cmd.Flags().GetString(LB_GROUP_FLAG)
+	live, _ := cmd.Flags().GetBool(LB_LIVE_FLAG)
+	var stats []*marathonlb.Stat
+	if live {
+		var err error
+		if stats, err = lbClient(cmd).Stats(); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	results := []*LBValidation{}
+	failed := false
+	for _, app := range apps {
+		v := &LBValidation{AppID: app.ID, Frontends: marathonlb.Frontends(app), Issues: marathonlb.ValidateLabels(app, group)}
+		if live {
+			for _, f := range v.Frontends {
+				if !hasProxy(stats, f.Backend) {
+					v.Issues = append(v.Issues, &marathonlb.LabelIssue{Level: marathonlb.IssueError, Label: fmt.Sprintf("HAPROXY_%d_*", f.Index),
+						Message: fmt.Sprintf("backend %s does not exist within the running instances", f.Backend)})
+				}
+			}
+		}
+		failed = failed || marathonlb.HasErrors(v.Issues)
+		results = append(results, v)
+	}
+
+	cli.Output(templateFor(T_LB_VALIDATION, results), nil)
+	if failed {
+		cli.ExitWithCode(cli.ExitError)
+	}
+}
+
+func hasProxy(stats []*marathonlb.Stat, name string) bool {
+	for _, s := range stats {
+		if s.Proxy == name {
+			return true
+		}
+	}
+	return false
+}
@@ -1,23 +1,55 @@
 package marathon
 
 import (
+	"fmt"
+	"github.com/ContainX/depcon/backend"
 	"github.com/ContainX/depcon/cliconfig"
 	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/dcos"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/login"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 const (
-	WAIT_FLAG      string = "wait"
-	TIMEOUT_FLAG   string = "wait-timeout"
-	FORCE_FLAG     string = "force"
-	DETAIL_FLAG    string = "detail"
-	PARAMS_FLAG    string = "param"
-	ENV_FILE_FLAG  string = "env-file"
-	IGNORE_MISSING string = "ignore"
-	INSECURE_FLAG  string = "insecure"
-	ENV_NAME       string = "env_name"
-	DRYRUN_FLAG    string = "dry-run"
+	WAIT_FLAG        string = "wait"
+	TIMEOUT_FLAG     string = "wait-timeout"
+	POLL_INTERVAL    string = "poll-interval"
+	CANCEL_TIMEOUT   string = "cancel-on-timeout"
+	FORCE_CANCEL     string = "force-cancel"
+	READY_URL        string = "ready-url"
+	READY_STATUS     string = "ready-status"
+	WAIT_HEALTHY     string = "wait-healthy"
+	FORCE_FLAG       string = "force"
+	DETAIL_FLAG      string = "detail"
+	PARAMS_FLAG      string = "param"
+	ENV_FILE_FLAG    string = "env-file"
+	IGNORE_MISSING   string = "ignore"
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	RETRIES_FLAG     string = "retries"
+	BACKOFF_FLAG     string = "retry-backoff"
+	RETRY_STATUS     string = "retry-status"
+	REQ_TIMEOUT_FLAG string = "request-timeout"
+	RATE_LIMIT_FLAG  string = "rate-limit"
+	NO_CACHE_FLAG    string = "no-cache"
+	NO_EVENTS_FLAG   string = "no-events"
+	ENV_NAME         string = "env_name"
+	DRYRUN_FLAG      string = "dry-run"
+	POLICY_FLAG      string = "policy"
+	POLICY_BUNDLE    string = "policy-bundle"
+	POLICY_QUERY     string = "policy-query"
+	WORKERS_FLAG     string = "workers"
+	LABEL_FLAG       string = "label"
 )
 
 var (
@@ -35,6 +67,7 @@ var (
 // Associates the marathon service to the given command
 func AddMarathonToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
 	configFile = c
+	backend.Register("marathon", newBackend)
 	associateServiceCommands(marathonCmd)
 	rc.AddCommand(marathonCmd)
 }
@@ -43,6 +76,7 @@ func AddMarathonToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
 // when we only have a single environment declared and already know the cluster type
 func AddJailedMarathonToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
 	configFile = c
+	backend.Register("marathon", newBackend)
 	associateServiceCommands(rc)
 }
 
@@ -50,25 +84,333 @@ func AddJailedMarathonToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
 func associateServiceCommands(parent *cobra.Command) {
 	parent.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
 	viper.BindPFlag(INSECURE_FLAG, parent.PersistentFlags().Lookup(INSECURE_FLAG))
+	parent.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	viper.BindPFlag(ALLOW_WRITE_FLAG, parent.PersistentFlags().Lookup(ALLOW_WRITE_FLAG))
+	parent.PersistentFlags().Int(RETRIES_FLAG, httpclient.DefaultRetryPolicy().MaxAttempts, "Attempts made for requests failing with connection errors or 5xx responses (1 disables retries)")
+	viper.BindPFlag(RETRIES_FLAG, parent.PersistentFlags().Lookup(RETRIES_FLAG))
+	parent.PersistentFlags().Duration(BACKOFF_FLAG, httpclient.DefaultRetryPolicy().BaseDelay, "Delay before the first retry which doubles for each subsequent retry")
+	viper.BindPFlag(BACKOFF_FLAG, parent.PersistentFlags().Lookup(BACKOFF_FLAG))
+	parent.PersistentFlags().String(RETRY_STATUS, "", "Response statuses which are retried (eg. 502,503,504).  Default: 429 and 5xx other than 501")
+	viper.BindPFlag(RETRY_STATUS, parent.PersistentFlags().Lookup(RETRY_STATUS))
+	parent.PersistentFlags().Duration(REQ_TIMEOUT_FLAG, 0, "Overall timeout for each request overriding the environment (eg. 2m).  A negative value disables the timeout")
+	viper.BindPFlag(REQ_TIMEOUT_FLAG, parent.PersistentFlags().Lookup(REQ_TIMEOUT_FLAG))
+	parent.PersistentFlags().Duration(POLL_INTERVAL, marathon.DefaultPollInterval, "Delay between checks while waiting on deployments (eg. 5s).  Failing checks back off exponentially")
+	viper.BindPFlag(POLL_INTERVAL, parent.PersistentFlags().Lookup(POLL_INTERVAL))
+	ApplyCancelFlags(parent.PersistentFlags())
+	viper.BindPFlag(CANCEL_TIMEOUT, parent.PersistentFlags().Lookup(CANCEL_TIMEOUT))
+	viper.BindPFlag(FORCE_CANCEL, parent.PersistentFlags().Lookup(FORCE_CANCEL))
+	ApplyReadyFlags(parent.PersistentFlags())
+	viper.BindPFlag(READY_URL, parent.PersistentFlags().Lookup(READY_URL))
+	viper.BindPFlag(READY_STATUS, parent.PersistentFlags().Lookup(READY_STATUS))
+	parent.PersistentFlags().Float64(RATE_LIMIT_FLAG, 0, "Maximum requests per second sent to Marathon overriding the environment (eg. 5).  0 uses the environment setting")
+	viper.BindPFlag(RATE_LIMIT_FLAG, parent.PersistentFlags().Lookup(RATE_LIMIT_FLAG))
+	parent.PersistentFlags().Bool(NO_CACHE_FLAG, false, "Always query Marathon rather than using recently cached responses")
+	viper.BindPFlag(NO_CACHE_FLAG, parent.PersistentFlags().Lookup(NO_CACHE_FLAG))
+	parent.PersistentFlags().Bool(NO_EVENTS_FLAG, false, "Only polls while waiting rather than following Marathon's event stream to report deployment steps, failed health checks and task changes")
+	viper.BindPFlag(NO_EVENTS_FLAG, parent.PersistentFlags().Lookup(NO_EVENTS_FLAG))
 
-	parent.AddCommand(appCmd, groupCmd, deployCmd, taskCmd, eventCmd, serverCmd)
+	parent.AddCommand(appCmd, groupCmd, podCmd, deployCmd, taskCmd, eventCmd, serverCmd, templateCmd, lbCmd, portsCmd, artifactCmd, registryCmd, topCmd)
+	markPaged(appListCmd, appVersionsCmd, logCmd, groupListCmd, groupGetCmd, podListCmd, taskListCmd, appTaskGetCmd, deployListCmd)
+	registerCompletions()
+}
+
+// ApplyCancelFlags adds the flags cancelling deployments which don't converge within the wait timeout
+func ApplyCancelFlags(flags *pflag.FlagSet) {
+	flags.Bool(CANCEL_TIMEOUT, false, "Cancels a deployment which hasn't converged within the wait timeout (Marathon rolls it back) before exiting")
+	flags.Bool(FORCE_CANCEL, false, "Deletes timed out deployments without rolling them back (with --cancel-on-timeout)")
+}
+
+// CancelOnTimeout returns whether the flags of {cmd} cancel (and force delete) timed out deployments
+func CancelOnTimeout(cmd *cobra.Command) (cancel, force bool) {
+	cancel, _ = cmd.Flags().GetBool(CANCEL_TIMEOUT)
+	force, _ = cmd.Flags().GetBool(FORCE_CANCEL)
+	return cancel, force
+}
+
+// ApplyReadyFlags adds the flags probing the tasks of deployed applications after they become healthy
+func ApplyReadyFlags(flags *pflag.FlagSet) {
+	flags.String(READY_URL, "", "Path requested on each task's host:port once healthy (eg. /healthz).  Waits until every task responds")
+	flags.Int(READY_STATUS, 200, "Status the tasks must respond to --ready-url with")
+}
+
+// ReadyCheck returns the check of the flags of {cmd} probed against each task or nil when --ready-url isn't set
+func ReadyCheck(cmd *cobra.Command) *marathon.ReadyCheck {
+	path, _ := cmd.Flags().GetString(READY_URL)
+	status, _ := cmd.Flags().GetInt(READY_STATUS)
+	return readyCheck(path, status)
+}
+
+func readyCheck(path string, status int) *marathon.ReadyCheck {
+	if path == "" {
+		return nil
+	}
+	return &marathon.ReadyCheck{Path: path, Status: status}
+}
+
+// Marks commands with potentially long output to be paged when written to a terminal
+func markPaged(cmds ...*cobra.Command) {
+	for _, c := range cmds {
+		if c.Annotations == nil {
+			c.Annotations = map[string]string{}
+		}
+		c.Annotations[cli.AnnotationPaged] = "true"
+	}
+}
+
+// WaitTimeout returns the duration given by --wait-timeout or {def} when it isn't set.  An explicit
+// zero is mapped to marathon.WaitForever since the client gives up at once on a zero timeout
+func WaitTimeout(cmd *cobra.Command, def time.Duration) time.Duration {
+	f := cmd.Flags().Lookup(TIMEOUT_FLAG)
+	if f == nil || !f.Changed {
+		return def
+	}
+	if timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG); timeout != 0 {
+		return timeout
+	}
+	return marathon.WaitForever
+}
+
+// Returns the quorum given by --wait-healthy or nil when the entire deployment is waited on
+func waitQuorum(cmd *cobra.Command) *marathon.Quorum {
+	value, _ := cmd.Flags().GetString(WAIT_HEALTHY)
+	if value == "" {
+		return nil
+	}
+	quorum, err := marathon.ParseQuorum(value)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	return &quorum
 }
 
 func client(c *cobra.Command) marathon.Marathon {
 	if marathonClient == nil {
-		envName := viper.GetString(ENV_NAME)
-		insecure := viper.GetBool(INSECURE_FLAG)
-		mc := *configFile.Environments[envName].Marathon
-		opts := &marathon.MarathonOptions{}
-		if timeout, err := c.Flags().GetDuration(TIMEOUT_FLAG); err == nil {
-			opts.WaitTimeout = timeout
+		m, err := environmentClient(c, viper.GetString(ENV_NAME))
+		if err != nil {
+			exitWithError(err)
 		}
-		opts.TLSAllowInsecure = insecure
+		marathonClient = m
+	}
+	return marathonClient
+}
 
-		marathonClient = marathon.NewMarathonClientWithOpts(mc.HostUrl, mc.Username, mc.Password, opts)
+// Creates the client of the environment {envName} with the connection and wait settings of the flags
+func environmentClient(c *cobra.Command, envName string) (marathon.Marathon, error) {
+	env, err := configFile.GetEnvironment(envName)
+	if err != nil {
+		return nil, err
+	}
+	if env.Marathon == nil {
+		return nil, fmt.Errorf("Environment '%s' does not define a Marathon service", envName)
+	}
+	opts := &marathon.MarathonOptions{}
+	opts.WaitTimeout = WaitTimeout(c, 0)
+	opts.PollInterval = viper.GetDuration(POLL_INTERVAL)
+	opts.WaitEvents = !viper.GetBool(NO_EVENTS_FLAG)
+	opts.CancelOnTimeout, opts.ForceCancel = viper.GetBool(CANCEL_TIMEOUT), viper.GetBool(FORCE_CANCEL)
+	opts.Ready = readyCheck(viper.GetString(READY_URL), viper.GetInt(READY_STATUS))
+	opts.TLSAllowInsecure = viper.GetBool(INSECURE_FLAG)
+	opts.Retry = httpclient.DefaultRetryPolicy()
+	opts.Retry.MaxAttempts = viper.GetInt(RETRIES_FLAG)
+	opts.Retry.BaseDelay = viper.GetDuration(BACKOFF_FLAG)
+	statuses, err := retryStatuses(viper.GetString(RETRY_STATUS))
+	if err != nil {
+		return nil, cli.WithExitCode(cli.ExitUsage, err)
+	}
+	opts.Retry.Statuses = statuses
+	if timeout := viper.GetDuration(REQ_TIMEOUT_FLAG); timeout != 0 {
+		opts.Timeouts = &httpclient.Timeouts{Request: httpclient.Duration(timeout)}
+	}
 
+	opts.RateLimit = viper.GetFloat64(RATE_LIMIT_FLAG)
+	opts.Cache = responseCache()
+	cli.CancelOnInterrupt()
+	opts.Context = cli.Context()
+	if progress := cli.ActiveProgress(); progress != nil {
+		opts.Progress = progress
 	}
-	return marathonClient
+
+	opts.ReadOnly = env.Marathon.ReadOnly && !viper.GetBool(ALLOW_WRITE_FLAG)
+	return NewClient(envName, env.Marathon, opts)
+}
+
+// Parses the comma separated response statuses {s} returning nil when empty so the default statuses
+// are retried
+func retryStatuses(s string) ([]int, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	statuses := []int{}
+	for _, v := range strings.Split(s, ",") {
+		status, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || status < 100 || status > 599 {
+			return nil, fmt.Errorf("Invalid --%s '%s' - must be HTTP statuses separated by commas (eg. 502,503)", RETRY_STATUS, s)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Returns the cache of GET responses kept within the config directory or nil with --no-cache
+func responseCache() *httpclient.CacheConfig {
+	if viper.GetBool(NO_CACHE_FLAG) {
+		return nil
+	}
+	return &httpclient.CacheConfig{Dir: filepath.Join(cliconfig.ConfigDir(), "cache")}
+}
+
+// Creates the Marathon backend of the environment used by the backend neutral commands
+func newBackend(ctx *backend.Context) (backend.ClusterBackend, error) {
+	env, err := configFile.GetEnvironment(ctx.EnvName)
+	if err != nil {
+		return nil, err
+	}
+	if env.Marathon == nil {
+		return nil, &backend.NotConfiguredError{Backend: cliconfig.TypeMarathon, EnvName: ctx.EnvName}
+	}
+
+	opts := &marathon.MarathonOptions{TLSAllowInsecure: ctx.Insecure, Retry: httpclient.DefaultRetryPolicy()}
+	opts.ReadOnly = env.Marathon.ReadOnly && !ctx.AllowWrite
+	opts.PollInterval = viper.GetDuration(POLL_INTERVAL)
+	opts.WaitEvents = !viper.GetBool(NO_EVENTS_FLAG)
+	opts.CancelOnTimeout, opts.ForceCancel = viper.GetBool(CANCEL_TIMEOUT), viper.GetBool(FORCE_CANCEL)
+	opts.Ready = readyCheck(viper.GetString(READY_URL), viper.GetInt(READY_STATUS))
+	if progress := cli.ActiveProgress(); progress != nil {
+		opts.Progress = progress
+	}
+	m, err := NewClient(ctx.EnvName, env.Marathon, opts)
+	if err != nil {
+		return nil, err
+	}
+	host, err := mesosHost(env.Marathon)
+	if err != nil {
+		return nil, err
+	}
+	return backend.NewMarathonBackend(m, host), nil
+}
+
+// NewClient creates a Marathon client for the environment {envName} configured by {service} applying
+// the authentication, proxy and TLS settings of the environment to {opts}
+func NewClient(envName string, service *cliconfig.ServiceConfig, opts *marathon.MarathonOptions) (marathon.Marathon, error) {
+	if opts == nil {
+		opts = &marathon.MarathonOptions{}
+	}
+	host, err := ApplyConnection(envName, service, opts)
+	if err != nil {
+		return nil, err
+	}
+	return marathon.NewMarathonClientWithOpts(host, service.Username, service.Password, opts), nil
+}
+
+// ApplyConnection applies the authentication, proxy and TLS settings of the environment {envName} configured
+// by {service} to {opts} and returns the Marathon URLs of the environment.  Other services of the cluster
+// (eg. Metronome) are reached with the same settings
+func ApplyConnection(envName string, service *cliconfig.ServiceConfig, opts *marathon.MarathonOptions) (string, error) {
+	mc := *service
+	insecure := opts.TLSAllowInsecure
+	warnInsecure(envName, insecure, mc.TLS.SkipsVerify())
+	opts.Proxy = mc.Proxy
+	opts.TLS = mc.TLS
+	opts.Timeouts = mc.Timeouts.Merge(opts.Timeouts)
+	if opts.RateLimit == 0 {
+		opts.RateLimit = mc.RateLimit
+	}
+	opts.Compress = mc.Compress
+	opts.Headers = mc.Headers
+
+	host := mc.HostUrl
+	if mc.IsDCOS() {
+		// each master of the cluster may be listed in which case the first is used to login
+		clusters := marathon.SplitHosts(mc.HostUrl)
+		hosts := []string{}
+		for _, cluster := range clusters {
+			hosts = append(hosts, dcos.MarathonURL(cluster))
+		}
+		host = strings.Join(hosts, ",")
+		mc.HostUrl = clusters[0]
+
+		var acs *dcos.ACSAuthenticator
+		if mc.ServiceAccount != "" {
+			account, err := dcos.LoadServiceAccount(mc.ServiceAccount, mc.Username)
+			if err != nil {
+				return "", err
+			}
+			acs = dcos.NewServiceAccountAuthenticator(mc.HostUrl, account, envName, cliconfig.TokenStore{}, insecure)
+		} else {
+			acs = dcos.NewACSAuthenticator(mc.HostUrl, mc.Username, mc.Password, envName, cliconfig.TokenStore{}, insecure)
+		}
+		acs.Proxy = mc.Proxy
+		acs.TLS = mc.TLS
+		acs.Timeouts = opts.Timeouts
+		acs.Headers = mc.Headers
+		opts.Authenticator = acs
+		renewExpiringToken(envName, acs)
+	}
+	if mc.UsesLogin() {
+		a := &login.Authenticator{Environment: envName, Cache: cliconfig.TokenStore{}}
+		if mc.Auth == cliconfig.AuthOIDC {
+			a.OIDC = &login.OIDC{Config: mc.Login, Client: LoginClient(&mc, insecure)}
+		}
+		opts.Authenticator = a
+	}
+
+	// unix sockets and ssh tunnels are converted into http(s) URLs
+	hosts := []string{}
+	for _, h := range marathon.SplitHosts(host) {
+		resolved, err := httpclient.ResolveEndpoint(h)
+		if err != nil {
+			return "", err
+		}
+		hosts = append(hosts, resolved)
+	}
+	return strings.Join(hosts, ","), nil
+}
+
+// environments already warned about skipping certificate verification
+var insecureWarned sync.Map
+
+// Warns (once per environment) that the server certificate of environment {envName} isn't verified either
+// because of --insecure {flag} or the insecure_skip_verify setting of the environment {configured}
+func warnInsecure(envName string, flag, configured bool) {
+	if !flag && !configured {
+		return
+	}
+	if _, warned := insecureWarned.LoadOrStore(envName, true); warned {
+		return
+	}
+	source := "--insecure"
+	if configured {
+		source = "tls.insecure_skip_verify"
+	}
+	log.Warning("!!! TLS certificate verification is DISABLED for environment '%s' (%s) - credentials and deployments "+
+		"can be intercepted.  Configure the environment's CA bundle instead: depcon config env update %s --ca <bundle.pem>", envName, source, envName)
+}
+
+// LoginClient returns the client reaching the identity provider of {service} with the proxies and TLS
+// settings of the environment
+func LoginClient(service *cliconfig.ServiceConfig, insecure bool) *http.Client {
+	config := httpclient.NewDefaultConfig()
+	config.TLSInsecureSkipVerify = insecure
+	config.Proxy = service.Proxy
+	config.TLS = service.TLS
+	config.Timeouts = service.Timeouts
+	return httpclient.NewHttpClient(*config).Unwrap()
+}
+
+// Logs in again when the cached token of {envName} is about to expire so long running commands (eg. waiting
+// on deployments in CI) aren't cut short by a 401.  A failed login is only warned about since the current
+// token remains valid for now
+func renewExpiringToken(envName string, acs *dcos.ACSAuthenticator) {
+	expires := acs.Expires()
+	if expires.IsZero() || time.Until(expires) > dcos.TokenExpiryWarning {
+		return
+	}
+	if _, err := acs.Token(true); err != nil {
+		log.Warning("The token of environment '%s' expires in %s and could not be refreshed (%s) - run 'depcon config env token refresh %s'",
+			envName, time.Until(expires).Round(time.Second), err.Error(), envName)
+		return
+	}
+	log.Debug("Refreshed the token of environment '%s' which was about to expire", envName)
 }
 
 func Usage(c *cobra.Command) func() error {
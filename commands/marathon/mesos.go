@@ -0,0 +1,59 @@
+package marathon
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/pkg/dcos"
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+// NewMesosClient creates a client for the Mesos master of the environment {envName} which is reached with
+// the credentials and connection settings of the environment's Marathon {service}
+func NewMesosClient(envName string, service *cliconfig.ServiceConfig, insecure, allowWrite bool) (mesos.Mesos, error) {
+	host, err := MesosURL(service)
+	if err != nil {
+		return nil, err
+	}
+	mopts := &marathon.MarathonOptions{TLSAllowInsecure: insecure}
+	if _, err := ApplyConnection(envName, service, mopts); err != nil {
+		return nil, err
+	}
+
+	opts := &mesos.MesosOptions{
+		TLSAllowInsecure: insecure,
+		Authenticator:    mopts.Authenticator,
+		Proxy:            mopts.Proxy,
+		TLS:              mopts.TLS,
+		ReadOnly:         service.ReadOnly && !allowWrite,
+		Retry:            httpclient.DefaultRetryPolicy(),
+		Timeouts:         mopts.Timeouts,
+		Headers:          mopts.Headers,
+	}
+	return mesos.NewMesosClient(host, service.Username, service.Password, opts), nil
+}
+
+// MesosURL returns the Mesos master URL of {service}.  DC/OS clusters expose the master at /mesos while
+// other clusters are assumed to run the master on the host of Marathon
+func MesosURL(service *cliconfig.ServiceConfig) (string, error) {
+	cluster := marathon.SplitHosts(service.HostUrl)[0]
+	switch {
+	case service.MesosUrl != "":
+		return httpclient.ResolveEndpoint(service.MesosUrl)
+	case service.IsDCOS():
+		return dcos.MesosURL(cluster), nil
+	}
+	u, err := url.Parse(cluster)
+	if err != nil {
+		return "", err
+	}
+	host := u.Host
+	if strings.Index(host, ":") > 0 {
+		host = strings.Split(host, ":")[0]
+	}
+	return fmt.Sprintf("%s://%s:%d", u.Scheme, host, mesos.DefaultPort), nil
+}
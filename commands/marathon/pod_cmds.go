@@ -0,0 +1,137 @@
+package marathon
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/sops"
+	"github.com/spf13/cobra"
+)
+
+var podCmd = &cobra.Command{
+	Use:   "pod",
+	Short: "Marathon pod management",
+	Long: `Manage pods (containers scheduled together on the same agent) in a marathon cluster
+
+    See pod's subcommands for available choices`,
+}
+
+var podListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all pods",
+	Run: func(cmd *cobra.Command, args []string) {
+		v, e := client(cmd).ListPods()
+		cli.Output(templateFor(templateFormat(T_PODS, cmd), v), e)
+	},
+}
+
+var podGetCmd = &cobra.Command{
+	Use:   "get [podId]",
+	Short: "Gets the status of the pod [podId] and its instances",
+	Run:   getPod,
+}
+
+var podCreateCmd = &cobra.Command{
+	Use:   "create [file(.json | .yaml)]",
+	Short: "Create a new pod with the [file(.json | .yaml)]",
+	Run:   createPod,
+}
+
+var podDestroyCmd = &cobra.Command{
+	Use:   "destroy [podId]",
+	Short: "Removes the pod [podId] and all of its instances",
+	Run:   destroyPod,
+}
+
+var podVersionsCmd = &cobra.Command{
+	Use:   "versions [podId]",
+	Short: "List the versions of the pod [podId]",
+	Run:   podVersions,
+}
+
+func init() {
+	podCmd.AddCommand(podListCmd, podGetCmd, podCreateCmd, podDestroyCmd, podVersionsCmd)
+	applyFormatFlag(podListCmd, "{{range .}}{{ .ID }} {{end}}")
+
+	podCreateCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the pod to become stable")
+	podCreateCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the pod to become stable (ex. 90s | 2m).  0 waits forever")
+	podCreateCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Force deployment (updates the pod if it already exists)")
+	ApplySignatureFlags(podCreateCmd)
+	podDestroyCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Destroys the pod even if it's locked by a deployment")
+}
+
+func getPod(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	v, e := client(cmd).GetPodStatus(args[0])
+	cli.Output(templateFor(T_POD_STATUS, v), e)
+}
+
+func createPod(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	VerifySignatures(cmd, args[0])
+	pod, err := parsePodFile(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+
+	c := client(cmd)
+	v, e := c.CreatePod(pod, false, force)
+	if e == nil && wait {
+		e = c.WaitForPod(v.ID, WaitTimeout(cmd, marathon.DefaultTimeout))
+	}
+	cli.Output(templateFor(T_PODS, []*marathon.Pod{v}), e)
+}
+
+func parsePodFile(filename string) (*marathon.Pod, error) {
+	data, err := sops.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening filename %s, %s", filename, err.Error())
+	}
+
+	enc, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	pod := new(marathon.Pod)
+	if err := enc.UnMarshalStr(string(data), pod); err != nil {
+		return nil, fmt.Errorf("Error parsing pod %s, %s", filename, err.Error())
+	}
+	return pod, nil
+}
+
+func destroyPod(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	confirmOrExit(func() (string, error) {
+		status, err := client(cmd).GetPodStatus(args[0])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Destroy pod '%s' (%d instances)", status.ID, len(status.Instances)), nil
+	})
+
+	force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+	if err := client(cmd).DeletePod(args[0], force); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("Pod %s has been destroyed\n", args[0])
+}
+
+func podVersions(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	v, e := client(cmd).PodVersions(args[0])
+	cli.Output(templateFor(T_VERSIONS, &marathon.Versions{Versions: v}), e)
+}
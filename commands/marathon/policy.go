@@ -0,0 +1,33 @@
+package marathon
+
+import (
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/policy"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func applyPolicyFlags(cmd ...*cobra.Command) {
+	for _, c := range cmd {
+		c.Flags().StringSlice(POLICY_FLAG, nil, `Rego policy file(s) or directories the rendered application must satisfy before it is deployed.
+                  Requires opa on the PATH.  Defaults may be set per environment within the environment's flags`)
+		c.Flags().String(POLICY_BUNDLE, "", "Path or URL of a policy bundle (tar.gz) the rendered application must satisfy")
+		c.Flags().String(POLICY_QUERY, policy.DefaultQuery, "Rego query returning the violations (deny messages)")
+	}
+}
+
+// Returns the checker configured by the policy flags of {cmd}
+func policyChecker(cmd *cobra.Command) *policy.Checker {
+	policies, _ := cmd.Flags().GetStringSlice(POLICY_FLAG)
+	bundle, _ := cmd.Flags().GetString(POLICY_BUNDLE)
+	query, _ := cmd.Flags().GetString(POLICY_QUERY)
+	return &policy.Checker{Policies: policies, Bundle: bundle, Query: query}
+}
+
+// Returns a marathon.CreateOptions Validate func refusing applications which violate {checker}'s policies
+func validateWithPolicies(checker *policy.Checker) func(app *marathon.Application) error {
+	return func(app *marathon.Application) error {
+		log.Info("Checking application '%s' against policies", app.ID)
+		return checker.Check(&policy.Input{Kind: policy.KindApp, Environment: viper.GetString(ENV_NAME), App: app})
+	}
+}
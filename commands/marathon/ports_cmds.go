@@ -0,0 +1,110 @@
+package marathon
+
+import (
+	"fmt"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathonlb"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+const (
+	RESERVED_FLAG = "reserved"
+
+	T_PORT_CONFLICTS = `
+{{ "APP" | header }}	{{ "PORT" | header }}	{{ "KIND" | header }}	{{ "WITH" | header }}	{{ "MESSAGE" | header }}
+{{ range . }}{{ .App }}	{{ .Port | intToString }}	{{ .Kind }}	{{ .With }}	{{ .Message }}
+{{end}}`
+)
+
+var portsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "Detect servicePort and Marathon-LB frontend collisions",
+	Long: `Detect servicePort and Marathon-LB frontend collisions
+
+    See ports's subcommands for available choices`,
+}
+
+var portsAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Reports the servicePort and frontend collisions between the deployed applications",
+	Long: `Reports servicePorts used by more than one application, Marathon-LB frontends (HAPROXY_{n}_PORT) bound
+by more than one application of the same HAPROXY_GROUP and ports within the ranges Marathon-LB reserves
+(80, 443, 9090-9091 and --reserved).  Exits with a non-zero status when collisions are found.
+
+    eg. depcon ports audit --reserved 10000-10100`,
+	Run: auditPorts,
+}
+
+var appCheckPortsCmd = &cobra.Command{
+	Use:   "check-ports [file(.json | .yaml) | applicationId]",
+	Short: "Checks the servicePorts of an application don't collide with the deployed applications",
+	Long: `Checks the servicePorts and Marathon-LB frontends of the application descriptor (or the deployed
+application) against the deployed applications and the ranges Marathon-LB reserves (80, 443, 9090-9091 and
+--reserved) before a deployment fails on a port conflict.  Previous versions of the application (the same
+id or HAPROXY_DEPLOYMENT_GROUP) are ignored.  Exits with a non-zero status when collisions are found.
+
+    eg. depcon app check-ports app.json`,
+	Run: checkAppPorts,
+}
+
+func init() {
+	portsCmd.AddCommand(portsAuditCmd)
+	appCmd.AddCommand(appCheckPortsCmd)
+	for _, c := range []*cobra.Command{portsAuditCmd, appCheckPortsCmd} {
+		c.Flags().StringSlice(RESERVED_FLAG, nil, "Additional port or range of ports reserved on the Marathon-LB instances (eg. 10000-10100)")
+	}
+	appCheckPortsCmd.Flags().String(TEMPLATE_CTX_FLAG, "", "Provides data per environment in JSON form to do a first pass parse of descriptor as template")
+	appCheckPortsCmd.Flags().StringSliceP(PARAMS_FLAG, "p", nil, `Adds a param(s) that can be used for substitution.
+                  eg. -p MYVAR=value would replace ${MYVAR} with "value" in the application file.`)
+}
+
+func checkAppPorts(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	reserved := reservedPorts(cmd)
+	app := loadCheckedApp(cmd, args[0])
+	outputPortConflicts(marathonlb.CheckPorts(app, deployedApps(cmd), reserved))
+}
+
+func auditPorts(cmd *cobra.Command, args []string) {
+	reserved := reservedPorts(cmd)
+	outputPortConflicts(marathonlb.AuditPorts(deployedApps(cmd), reserved))
+}
+
+func deployedApps(cmd *cobra.Command) []*marathon.Application {
+	apps, err := client(cmd).ListApplications()
+	if err != nil {
+		exitWithError(err)
+	}
+	results := []*marathon.Application{}
+	for i := range apps.Apps {
+		results = append(results, &apps.Apps[i])
+	}
+	return results
+}
+
+// Returns the ports Marathon-LB reserves and the ranges of --reserved exiting when one is invalid
+func reservedPorts(cmd *cobra.Command) []marathonlb.PortRange {
+	reserved := append([]marathonlb.PortRange{}, marathonlb.ReservedPorts...)
+	values, _ := cmd.Flags().GetStringSlice(RESERVED_FLAG)
+	for _, v := range values {
+		r, err := marathonlb.ParsePortRange(v)
+		if err != nil {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+		}
+		reserved = append(reserved, r)
+	}
+	return reserved
+}
+
+func outputPortConflicts(conflicts []*marathonlb.PortConflict) {
+	if len(conflicts) == 0 {
+		fmt.Println("No port collisions found")
+		return
+	}
+	cli.Output(templateFor(T_PORT_CONFLICTS, conflicts), nil)
+	exitWithError(fmt.Errorf("%d port collision(s) found", len(conflicts)))
+}
@@ -0,0 +1,249 @@
+package marathon
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/workpool"
+	"github.com/ContainX/depcon/postdeploy"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	POST_DEPLOY_CMD     string = "post-deploy-cmd"
+	POST_DEPLOY_URL     string = "post-deploy-url"
+	POST_DEPLOY_RETRIES string = "post-deploy-retries"
+	ROLLBACK_FLAG       string = "rollback-on-failure"
+	ROLLBACK_VERIFY     string = "rollback-on-verify-failure"
+
+	ActionPostDeploy = "post-deploy"
+	ActionRollback   = "rollback"
+	ActionDeploy     = "deploy"
+
+	T_POST_DEPLOY = `
+{{ "STEP" | header }}	{{ "APP" | header }}	{{ "VERSION" | header }}	{{ "RESULT" | header }}
+{{ range . }}{{ .Step }}	{{ .App }}	{{ .Version }}	{{ .Result }}
+{{end}}`
+)
+
+var (
+	ErrorBatchFailed  = errors.New("Another application of the batch failed")
+	ErrorBatchSkipped = errors.New("Skipped since another application of the batch failed")
+)
+
+// PostDeployStep is a deployment, verification or rollback made by a command verifying its deployments
+type PostDeployStep struct {
+	Step    string `json:"step"`
+	App     string `json:"app"`
+	Version string `json:"version,omitempty"`
+	Result  string `json:"result"`
+	// Output of the verification
+	Output string `json:"output,omitempty"`
+}
+
+func applyPostDeployFlags(cmd ...*cobra.Command) {
+	for _, c := range cmd {
+		c.Flags().String(POST_DEPLOY_CMD, "", `Shell command run once the deployment has converged (eg. "./smoke.sh {{.Endpoint}}").  Implies --wait.
+                  {{.ID}}, {{.Version}}, {{.Environment}}, {{.Endpoint}} (host:port of the first task) and {{.Endpoints}} are available`)
+		c.Flags().String(POST_DEPLOY_URL, "", "URL which must respond with a 2xx status once the deployment has converged (eg. http://{{.Endpoint}}/health).  Implies --wait")
+		c.Flags().Int(POST_DEPLOY_RETRIES, postdeploy.DefaultRetries, "Attempts made by the post-deploy checks before the deployment is marked as failed")
+		c.Flags().Bool(ROLLBACK_FLAG, false, "Restores the previous version (or removes a new application) when the deployment or its post-deploy checks fail.  Implies --wait")
+		c.Flags().Bool(ROLLBACK_VERIFY, false, "Re-deploys the previous version only when the post-deploy checks fail, reporting both deployments and the check's output")
+	}
+}
+
+// postDeploy verifies the applications deployed by a command and rolls back those which fail when
+// --rollback-on-failure is set.  Checks and rollbacks are recorded in the audit log
+type postDeploy struct {
+	check    *postdeploy.Check
+	rollback bool
+	// roll back only when the checks fail
+	rollbackVerify bool
+	env            string
+	mu             sync.Mutex
+	// steps of the deployments which were rolled back
+	steps []*PostDeployStep
+}
+
+// deployment is an application deployed by a command
+type deployment struct {
+	id string
+	// version prior to the deployment or empty for a new application
+	previous string
+}
+
+// Returns the post-deploy steps enabled by the flags of {cmd} or nil when there are none
+func postDeployOf(cmd *cobra.Command) *postDeploy {
+	command, _ := cmd.Flags().GetString(POST_DEPLOY_CMD)
+	url, _ := cmd.Flags().GetString(POST_DEPLOY_URL)
+	retries, _ := cmd.Flags().GetInt(POST_DEPLOY_RETRIES)
+	rollback, _ := cmd.Flags().GetBool(ROLLBACK_FLAG)
+	rollbackVerify, _ := cmd.Flags().GetBool(ROLLBACK_VERIFY)
+
+	p := &postDeploy{
+		check:          &postdeploy.Check{Command: command, URL: url, Retries: retries},
+		rollback:       rollback,
+		rollbackVerify: rollbackVerify,
+		env:            viper.GetString(ENV_NAME),
+	}
+	if rollbackVerify && !p.check.Enabled() {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s requires --%s or --%s", ROLLBACK_VERIFY, POST_DEPLOY_CMD, POST_DEPLOY_URL)))
+	}
+	if !p.check.Enabled() && !rollback {
+		return nil
+	}
+	return p
+}
+
+// Returns the post-deploy steps of {cmd} which also roll back the applications of a batch which fails
+func batchPostDeployOf(cmd *cobra.Command) *postDeploy {
+	p := postDeployOf(cmd)
+	if p == nil {
+		p = &postDeploy{check: &postdeploy.Check{}, env: viper.GetString(ENV_NAME)}
+	}
+	p.rollback = true
+	return p
+}
+
+// Returns a copy of {options} which waits for the deployment and records the application deployed (and its
+// version beforehand) within the returned deployment
+func (p *postDeploy) options(c marathon.Marathon, options *marathon.CreateOptions) (*marathon.CreateOptions, *deployment) {
+	d := &deployment{}
+	opts := *options
+	opts.Wait = true
+	validate := options.Validate
+	opts.Validate = func(app *marathon.Application) error {
+		if validate != nil {
+			if err := validate(app); err != nil {
+				return err
+			}
+		}
+		d.id = app.ID
+		if current, err := c.GetApplication(app.ID); err == nil {
+			d.previous = current.Version
+		}
+		return nil
+	}
+	return &opts, d
+}
+
+// Runs the checks against deployment {d} once {result} is deployed without error and restores the previous
+// version when either the deployment (failing with {err}) or the checks fail
+func (p *postDeploy) finish(c marathon.Marathon, d *deployment, result *marathon.Application, err error) error {
+	deployed := result != nil || errors.Is(err, marathon.ErrorTimeout) || errors.Is(err, marathon.ErrorDeploymentFailed)
+	if err == nil && p.check.Enabled() {
+		steps, verr := p.verify(c, d.id)
+		if verr != nil && (p.rollback || p.rollbackVerify) {
+			return p.restore(c, d, verr, steps)
+		}
+		return verr
+	}
+	if err != nil && deployed && p.rollback && d.id != "" {
+		return p.restore(c, d, err, nil)
+	}
+	return err
+}
+
+// Runs the checks against the application {id} returning the steps of its deployment and verification
+func (p *postDeploy) verify(c marathon.Marathon, id string) ([]*PostDeployStep, error) {
+	app, err := c.GetApplication(id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.check.Run(postdeploy.TargetOf(app, p.env))
+	details := map[string]string{"version": app.Version}
+	if p.check.Command != "" {
+		details["command"] = p.check.Command
+	}
+	if p.check.URL != "" {
+		details["url"] = p.check.URL
+	}
+	p.record(ActionPostDeploy, id, details, err)
+
+	verified := &PostDeployStep{Step: ActionPostDeploy, App: id, Version: app.Version, Result: audit.ResultSuccess}
+	if result != nil {
+		verified.Output = result.Output
+	}
+	if err != nil {
+		verified.Result = audit.ResultFailed
+	}
+	return []*PostDeployStep{{Step: ActionDeploy, App: id, Version: app.Version, Result: audit.ResultSuccess}, verified}, err
+}
+
+// Restores the version of deployment {d} prior to the deployment which failed with {cause}.  The {steps} of
+// the failed deployment are reported along with the rollback
+func (p *postDeploy) restore(c marathon.Marathon, d *deployment, cause error, steps []*PostDeployStep) error {
+	var err error
+	rollback := &PostDeployStep{Step: ActionRollback, App: d.id, Version: d.previous, Result: audit.ResultSuccess}
+	if d.previous == "" {
+		log.Warning("Deployment of '%s' failed, removing the application", d.id)
+		_, err = c.DestroyApplication(d.id)
+	} else {
+		log.Warning("Deployment of '%s' failed, rolling back to %s", d.id, d.previous)
+		update := marathon.NewApplication(d.id).RollbackVersion(d.previous)
+		if _, err = c.UpdateApplication(update, false); err == nil {
+			err = c.WaitForApplication(d.id, marathon.DefaultTimeout)
+		}
+	}
+	p.record(ActionRollback, d.id, map[string]string{"version": d.previous, "cause": cause.Error()}, err)
+	if err != nil {
+		rollback.Result = audit.ResultFailed
+	}
+	p.mu.Lock()
+	p.steps = append(append(p.steps, steps...), rollback)
+	p.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("%w (rollback failed: %s)", cause, err.Error())
+	}
+	if d.previous == "" {
+		return fmt.Errorf("%w (application removed)", cause)
+	}
+	return fmt.Errorf("%w (rolled back to %s)", cause, d.previous)
+}
+
+// Restores the {deployments} of a batch which succeeded since another application of the batch failed.  Their
+// {results} report the rollback.  Those which failed were restored as they failed
+func (p *postDeploy) restoreBatch(c marathon.Marathon, deployments []*deployment, results []*workpool.Result) {
+	for idx, d := range deployments {
+		if d == nil || d.id == "" || results[idx].Err != nil {
+			continue
+		}
+		err := p.restore(c, d, ErrorBatchFailed, nil)
+		results[idx].Result, results[idx].Error, results[idx].Err = workpool.ResultFailed, err.Error(), err
+	}
+}
+
+// Outputs the deployments which were rolled back along with the output of their verification
+func (p *postDeploy) report() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.steps) == 0 {
+		return
+	}
+	cli.Output(templateFor(T_POST_DEPLOY, p.steps), nil)
+	for _, s := range p.steps {
+		if s.Step == ActionPostDeploy && s.Output != "" {
+			fmt.Printf("\nPost-deploy output of %s (%s):\n%s\n", s.App, s.Version, s.Output)
+		}
+	}
+}
+
+// Records the outcome {err} of {action} against the application {id} in the audit log
+func (p *postDeploy) record(action, id string, details map[string]string, err error) {
+	l := audit.New(filepath.Join(cliconfig.ConfigDir(), audit.DefaultFilename))
+	e := &audit.Entry{Environment: p.env, Action: action, Target: id, Result: audit.ResultSuccess, Details: details}
+	if err != nil {
+		e.Result, e.Message = audit.ResultFailed, err.Error()
+	}
+	if err := l.Record(e); err != nil {
+		log.Error("Unable to write the audit log %s: %s", l.Filename(), err.Error())
+	}
+}
@@ -0,0 +1,113 @@
+package marathon
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/mesos"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	PREFLIGHT_FLAG string = "preflight"
+
+	PreflightWarn = "warn"
+	PreflightFail = "fail"
+)
+
+var ErrorInsufficientCapacity = errors.New("The cluster does not have the capacity for the deployment")
+
+func applyPreflightFlags(cmd ...*cobra.Command) {
+	for _, c := range cmd {
+		c.Flags().String(PREFLIGHT_FLAG, "", `Checks the free resources of the Mesos agents and the quota of Marathon's role before deploying.
+                  'warn' reports a shortfall and continues while 'fail' (the default when no value is given) refuses to deploy`)
+		c.Flags().Lookup(PREFLIGHT_FLAG).NoOptDefVal = PreflightFail
+	}
+}
+
+// Returns the preflight mode of {cmd} exiting when it's invalid.  An empty mode disables the check
+func preflightMode(cmd *cobra.Command) string {
+	mode, _ := cmd.Flags().GetString(PREFLIGHT_FLAG)
+	switch mode {
+	case "", PreflightWarn, PreflightFail:
+		return mode
+	}
+	exitWithError(fmt.Errorf("--%s must be '%s' or '%s'", PREFLIGHT_FLAG, PreflightWarn, PreflightFail))
+	return ""
+}
+
+// Returns a marathon.CreateOptions Validate func checking the cluster can place the application's instances
+func validateWithPreflight(cmd *cobra.Command, mode string) func(app *marathon.Application) error {
+	return func(app *marathon.Application) error {
+		instances := app.Instances
+		if current, err := client(cmd).GetApplication(app.ID); err == nil && sameResources(current, app) {
+			instances -= current.Instances
+		}
+		return preflight(cmd, mode, app, instances)
+	}
+}
+
+// Checks the cluster can place {instances} additional instances of {app}.  A shortfall is returned as an
+// error in fail mode and logged in warn mode
+func preflight(cmd *cobra.Command, mode string, app *marathon.Application, instances int) error {
+	if mode == "" || instances <= 0 {
+		return nil
+	}
+	report, err := checkCapacity(cmd, app, instances)
+	if err != nil {
+		if mode == PreflightFail {
+			return fmt.Errorf("Unable to check the capacity of the cluster: %s", err.Error())
+		}
+		log.Warning("Unable to check the capacity of the cluster: %s", err.Error())
+		return nil
+	}
+	if report.Satisfied() {
+		log.Info("Preflight: the cluster can place %d instance(s) of '%s'", instances, app.ID)
+		return nil
+	}
+	shortfall := strings.Join(report.Shortfall(), "\n")
+	if mode == PreflightWarn {
+		log.Warning("Preflight: %s\n%s", ErrorInsufficientCapacity.Error(), shortfall)
+		return nil
+	}
+	return fmt.Errorf("%s:\n%s", ErrorInsufficientCapacity.Error(), shortfall)
+}
+
+func checkCapacity(cmd *cobra.Command, app *marathon.Application, instances int) (*mesos.CapacityReport, error) {
+	envName := viper.GetString(ENV_NAME)
+	m, err := NewMesosClient(envName, configFile.Environments[envName].Marathon, viper.GetBool(INSECURE_FLAG), false)
+	if err != nil {
+		return nil, err
+	}
+	agents, err := m.ListAgents()
+	if err != nil {
+		return nil, err
+	}
+	roles, err := m.ListRoles()
+	if err != nil {
+		log.Debug("Unable to list the roles of the cluster, skipping the quota check: %s", err.Error())
+	}
+
+	return mesos.CheckCapacity(agents, roles, requirementOf(cmd, app, instances)), nil
+}
+
+// Returns the resources {instances} instances of {app} need from the roles Marathon offers them
+func requirementOf(cmd *cobra.Command, app *marathon.Application, instances int) *mesos.Requirement {
+	req := &mesos.Requirement{ID: app.ID, Instances: instances, CPUs: app.CPUs, Mem: app.Mem, Disk: app.Disk, Roles: app.AcceptedResourceRoles}
+	if info, err := client(cmd).GetMarathonInfo(); err == nil {
+		req.QuotaRole = info.MarathonConfig.MesosRole
+		// Marathon offers its instances the resources of its role unless the app restricts them
+		if len(req.Roles) == 0 && req.QuotaRole != "" {
+			req.Roles = []string{mesos.RoleAny, req.QuotaRole}
+		}
+	}
+	return req
+}
+
+// Returns true if the instances of {a} and {b} need the same resources
+func sameResources(a, b *marathon.Application) bool {
+	return a.CPUs == b.CPUs && a.Mem == b.Mem && a.Disk == b.Disk
+}
@@ -0,0 +1,216 @@
+package marathon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/dcos"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/secrets"
+	"github.com/ContainX/depcon/registry"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	DOCKER_CONFIG_FLAG = "config"
+	OUT_FLAG           = "out"
+	UPLOAD_FLAG        = "upload"
+	REGISTRY_FLAG      = "registry"
+)
+
+const T_REGISTRY_CREDENTIALS = `
+{{ "REGISTRY" | header }}	{{ "ARTIFACT" | header }}	{{ "URI" | header }}
+{{ range . }}{{ .Registry }}	{{ .Artifact }}	{{ .URI }}
+{{end}}`
+
+// RefreshedCredentials is the bundle of a registry's credentials uploaded to the artifact store
+type RefreshedCredentials struct {
+	Registry string `json:"registry"`
+	Artifact string `json:"artifact"`
+	URI      string `json:"uri"`
+}
+
+var artifactRefreshCredentialsCmd = &cobra.Command{
+	Use:   "refresh-credentials [registry...]",
+	Short: "Uploads fresh docker.tar.gz bundles of the credentials of the environment's registries",
+	Long: `Fetches the current credentials of the registries configured by the environment (or just [registry...])
+from Vault or their credential helper and replaces their docker.tar.gz bundles in the artifact store.  Applications
+fetch the new credentials the next time their tasks start.
+
+Bundles are also refreshed by app create, group create and deploy create, which add the bundle to the fetch URIs
+of the applications pulling from a configured registry.
+
+    eg. depcon artifact refresh-credentials -e prod
+        depcon artifact refresh-credentials registry.example.com`,
+	Run: refreshRegistryCredentials,
+}
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "Docker registry credentials for private image pulls",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var registryBundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Builds the docker.tar.gz agents fetch to pull from private registries",
+	Long: `Builds the docker.tar.gz of a docker config (default: ~/.docker/config.json) which applications fetch to
+pull images from private registries, optionally uploads it and prints the fetch block to add to descriptors.
+
+Credentials held by a credential helper or credsStore are asked of the helper since the agents don't have it.
+--upload takes an http(s) URL the bundle is PUT to or a path within Marathon's artifact store.
+
+    eg. depcon registry bundle --config ~/.docker/config.json --out docker.tar.gz
+        depcon registry bundle --registry registry.example.com --upload /credentials/docker.tar.gz
+        depcon registry bundle --upload http://artifacts.example.com/credentials/docker.tar.gz`,
+	Run: bundleRegistryCredentials,
+}
+
+func init() {
+	artifactCmd.AddCommand(artifactRefreshCredentialsCmd)
+	registryCmd.AddCommand(registryBundleCmd)
+
+	registryBundleCmd.Flags().String(DOCKER_CONFIG_FLAG, registry.DefaultDockerConfig(), "Docker config holding the registry credentials")
+	registryBundleCmd.Flags().String(OUT_FLAG, "docker.tar.gz", "File the bundle is written to")
+	registryBundleCmd.Flags().String(UPLOAD_FLAG, "", "http(s) URL or artifact store path the bundle is uploaded to")
+	registryBundleCmd.Flags().StringSlice(REGISTRY_FLAG, []string{}, "Bundle just these registries (default: every registry of the config)")
+}
+
+func bundleRegistryCredentials(cmd *cobra.Command, args []string) {
+	filename, _ := cmd.Flags().GetString(DOCKER_CONFIG_FLAG)
+	out, _ := cmd.Flags().GetString(OUT_FLAG)
+	upload, _ := cmd.Flags().GetString(UPLOAD_FLAG)
+	registries, _ := cmd.Flags().GetStringSlice(REGISTRY_FLAG)
+
+	config, err := registry.LoadDockerConfig(filename)
+	if err != nil {
+		exitWithError(err)
+	}
+	portable, err := config.Portable(registries...)
+	if err != nil {
+		exitWithError(err)
+	}
+	bundle, err := registry.BundleConfig(portable)
+	if err != nil {
+		exitWithError(err)
+	}
+	if out != "" {
+		if err := ioutil.WriteFile(out, bundle, 0600); err != nil {
+			exitWithError(err)
+		}
+		fmt.Printf("The credentials of %s have been written to %s\n", strings.Join(portable.Registries(), ", "), out)
+	}
+	if upload == "" {
+		return
+	}
+
+	uri, err := uploadBundle(cmd, upload, bundle)
+	if err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("The bundle has been uploaded to %s.  Add it to the fetch URIs of the applications:\n\n", uri)
+	block, _ := json.MarshalIndent(map[string][]*marathon.Fetch{"fetch": {{URI: uri, Extract: true}}}, "", "  ")
+	fmt.Println(string(block))
+}
+
+// Uploads {bundle} to the http(s) URL or artifact store path {target} returning the URI agents fetch it from
+func uploadBundle(cmd *cobra.Command, target string, bundle []byte) (string, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		headers := map[string]string{"Content-Type": "application/gzip"}
+		resp := httpclient.DefaultHttpClient().HttpPutRawCtx(context.Background(), target, headers, string(bundle), nil)
+		if resp.Error != nil {
+			return "", fmt.Errorf("Unable to upload the bundle to %s: %s", target, resp.Error.Error())
+		}
+		return target, nil
+	}
+
+	if err := client(cmd).UploadArtifact(target, bytes.NewReader(bundle)); err != nil {
+		return "", err
+	}
+	env, err := configFile.GetEnvironment(viper.GetString(ENV_NAME))
+	if err != nil || env.Marathon == nil {
+		return "", fmt.Errorf("Unable to determine the URL of the artifact store: %v", err)
+	}
+	return artifactURL(env.Marathon) + "/" + strings.TrimLeft(target, "/"), nil
+}
+
+func refreshRegistryCredentials(cmd *cobra.Command, args []string) {
+	envName := viper.GetString(ENV_NAME)
+	packager := registryPackager(cmd, envName)
+	if packager == nil {
+		exitWithError(fmt.Errorf("Environment '%s' does not configure any registries", envName))
+	}
+	configs := packager.Registries
+	if len(args) > 0 {
+		configs = []*registry.Config{}
+		for _, name := range args {
+			c := findRegistry(packager.Registries, name)
+			if c == nil {
+				exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Environment '%s' does not configure the registry '%s'", envName, name)))
+			}
+			configs = append(configs, c)
+		}
+	}
+
+	results := []*RefreshedCredentials{}
+	for _, c := range configs {
+		uri, err := packager.Refresh(c)
+		if err != nil {
+			exitWithError(err)
+		}
+		results = append(results, &RefreshedCredentials{Registry: c.Registry, Artifact: c.ArtifactPath(), URI: uri})
+	}
+	cli.Output(templateFor(T_REGISTRY_CREDENTIALS, results), nil)
+}
+
+func findRegistry(configs []*registry.Config, name string) *registry.Config {
+	for _, c := range configs {
+		if c != nil && c.Registry == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// Returns a marathon.CreateOptions Prepare func adding the credentials of the registries of the current
+// environment to the applications pulling from them or nil when the environment configures no registries
+func registryCredentials(cmd *cobra.Command) func(app *marathon.Application) error {
+	packager := registryPackager(cmd, viper.GetString(ENV_NAME))
+	if packager == nil {
+		return nil
+	}
+	return packager.Prepare
+}
+
+// Returns the packager of the registries of {envName} or nil when it configures none
+func registryPackager(cmd *cobra.Command, envName string) *registry.Packager {
+	env, err := configFile.GetEnvironment(envName)
+	if err != nil || env.Marathon == nil || len(env.Registries) == 0 {
+		return nil
+	}
+	return &registry.Packager{
+		Registries:  env.Registries,
+		Secrets:     secrets.Default(),
+		Client:      client(cmd),
+		ArtifactURL: artifactURL(env.Marathon),
+	}
+}
+
+// Returns the URL of Marathon's artifact store the agents fetch artifacts from
+func artifactURL(service *cliconfig.ServiceConfig) string {
+	host := marathon.SplitHosts(service.HostUrl)[0]
+	if service.IsDCOS() {
+		host = dcos.MarathonURL(host)
+	}
+	return strings.TrimRight(host, "/") + "/" + marathon.API_ARTIFACTS
+}
@@ -0,0 +1,69 @@
+package marathon
+
+import (
+	"fmt"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/remote"
+	"github.com/ContainX/depcon/pkg/signature"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const SHA256_FLAG string = "sha256"
+
+// ApplyRemoteFlags adds --sha256 to the commands deploying descriptors which may be fetched from a URL
+func ApplyRemoteFlags(cmd ...*cobra.Command) {
+	for _, c := range cmd {
+		c.Flags().String(SHA256_FLAG, "", `SHA-256 digest the descriptor must match or nothing is deployed.  Descriptors may be http(s) URLs which
+                  are fetched with the credentials of the environment's remotes`)
+	}
+}
+
+// FetchDescriptor returns the file of descriptor {filename} along with a function removing it once deployed.
+// URLs are downloaded (with their detached signature when --verify-signature is set) to a temporary directory.
+// Exits unless the descriptor matches --sha256
+func FetchDescriptor(cmd *cobra.Command, filename string) (string, func()) {
+	digest, _ := cmd.Flags().GetString(SHA256_FLAG)
+	if !remote.IsURL(filename) {
+		if digest != "" {
+			if err := remote.VerifyFile(filename, digest); err != nil {
+				exitWithError(err)
+			}
+			log.Info("Verified the checksum of %s", filename)
+		}
+		return filename, func() {}
+	}
+	if digest == "" {
+		log.Warning("%s is deployed without verifying its checksum - specify --%s to deploy an immutable artifact", filename, SHA256_FLAG)
+	}
+
+	fetcher := &remote.Fetcher{}
+	config := httpclient.NewDefaultConfig()
+	if env := configFile.Environments[viper.GetString(ENV_NAME)]; env != nil {
+		fetcher.Sources = env.Remotes
+		if env.Marathon != nil {
+			config.Proxy = env.Marathon.Proxy
+		}
+	}
+	fetcher.Client = httpclient.NewHttpClient(*config).Unwrap()
+
+	dir, cleanup, err := remote.TempDir()
+	if err != nil {
+		exitWithError(err)
+	}
+	local, err := fetcher.Download(filename, digest, dir)
+	if err != nil {
+		cleanup()
+		exitWithError(err)
+	}
+	if digest != "" {
+		log.Info("Verified the checksum of %s", filename)
+	}
+	// the signature is fetched when present since --envs may deploy to environments trusting keys
+	if _, err := fetcher.Download(filename+signature.Extension, "", dir); err != nil && len(trustedKeys(cmd, viper.GetString(ENV_NAME))) > 0 {
+		cleanup()
+		exitWithError(fmt.Errorf("Fetching the signature of %s: %s", filename, err.Error()))
+	}
+	return local, cleanup
+}
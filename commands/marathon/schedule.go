@@ -0,0 +1,132 @@
+package marathon
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	AT_FLAG = "at"
+	IN_FLAG = "in"
+
+	ActionSchedule = "schedule"
+)
+
+// Adds the flags deferring the deployment of {cmds} to a planned time
+func applyScheduleFlags(cmds ...*cobra.Command) {
+	for _, cmd := range cmds {
+		cmd.Flags().String(AT_FLAG, "", "Deploys at this time (eg. 2024-06-01T02:00Z) after rendering and validating the descriptor now")
+		cmd.Flags().String(IN_FLAG, "", "Deploys after this delay (eg. 30m, 4h) after rendering and validating the descriptor now")
+	}
+}
+
+// Returns the time --at or --in plan the deployment for or the zero time when it isn't deferred.  Exits
+// on an invalid or past time
+func scheduledTime(cmd *cobra.Command) time.Time {
+	at, _ := cmd.Flags().GetString(AT_FLAG)
+	in, _ := cmd.Flags().GetString(IN_FLAG)
+	when, err := utils.ScheduledTime(at, in, time.Now())
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	return when
+}
+
+// Defers the deployment of the rendered {docs} of {filename} until the time of --at or --in once they are
+// validated.  Returns immediately when the deployment isn't deferred or is a dry run
+func deferDeployment(cmd *cobra.Command, filename string, docs []string, options *marathon.CreateOptions) {
+	when := scheduledTime(cmd)
+	if when.IsZero() || options.DryRun {
+		return
+	}
+	ids, err := validateScheduled(filename, docs, options)
+	if err != nil {
+		exitWithError(err)
+	}
+	waitForSchedule(strings.Join(ids, ", "), when)
+}
+
+// Validates the rendered {docs} of {filename} as they will be deployed so problems surface before waiting:
+// unresolved ${PARAMS}, invalid applications or groups and the policy and preflight checks of {options}.
+// Returns the ids of the applications and groups
+func validateScheduled(filename string, docs []string, options *marathon.CreateOptions) ([]string, error) {
+	et, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for _, doc := range docs {
+		parsed, missing := envsubst.SubstTokens(strings.NewReader(doc), options.EnvParams)
+		if options.ErrorOnMissingParams && len(missing) > 0 {
+			return nil, &envsubst.MissingParamsError{Filename: filename, Params: missing}
+		}
+		ag := &marathon.AppOrGroup{}
+		if err := et.UnMarshalStr(parsed, ag); err != nil {
+			return nil, err
+		}
+		ids = append(ids, ag.ID)
+		if !ag.IsApplication() {
+			group := &marathon.Group{}
+			if err := et.UnMarshalStr(parsed, group); err != nil {
+				return nil, err
+			}
+			if err := group.Validate(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		app := &marathon.Application{}
+		if err := et.UnMarshalStr(parsed, app); err != nil {
+			return nil, err
+		}
+		if err := app.Validate(); err != nil {
+			return nil, err
+		}
+		if options.Validate != nil {
+			if err := options.Validate(app); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ids, nil
+}
+
+// Waits until {when} before {target} is deployed recording the schedule in the audit log.  Exits when
+// interrupted (Ctrl-C) in the meantime
+func waitForSchedule(target string, when time.Time) {
+	env := viper.GetString(ENV_NAME)
+	details := map[string]string{"scheduledAt": when.UTC().Format(time.RFC3339)}
+	recordSchedule(env, target, audit.ResultScheduled, details)
+
+	wait := time.Until(when)
+	log.Info("Deployment of '%s' to '%s' scheduled at %s (in %s) - interrupt to cancel", target, env, when.Local().Format(time.RFC3339), wait.Round(time.Second))
+	cli.CancelOnInterrupt()
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		log.Info("Deploying '%s' as scheduled", target)
+	case <-cli.Context().Done():
+		recordSchedule(env, target, audit.ResultCancelled, details)
+		exitWithError(cli.WithExitCode(cli.ExitCancelled, fmt.Errorf("Scheduled deployment of '%s' cancelled", target)))
+	}
+}
+
+func recordSchedule(env, target, result string, details map[string]string) {
+	l := audit.New(filepath.Join(cliconfig.ConfigDir(), audit.DefaultFilename))
+	if err := l.Record(&audit.Entry{Environment: env, Action: ActionSchedule, Target: target, Result: result, Details: details}); err != nil {
+		log.Error("Unable to write the audit log %s: %s", l.Filename(), err.Error())
+	}
+}
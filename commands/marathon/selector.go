@@ -0,0 +1,108 @@
+package marathon
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/workpool"
+	"github.com/spf13/cobra"
+)
+
+const SELECTOR_FLAG = "selector"
+
+// a label requirement written with a single = (eg. team=payments)
+var singleEquals = regexp.MustCompile(`^\s*([A-Za-z0-9_./-]+)=([^=]*)$`)
+
+func applySelectorFlags(verb string, cmd ...*cobra.Command) {
+	for _, c := range cmd {
+		c.Flags().String(SELECTOR_FLAG, "", verb+` every application matching the selector in place of [applicationId]
+                  eg. label=team=payments, label=tier!=db or id=/payments.  The matching applications are listed first`)
+		c.Flags().Bool(DRYRUN_FLAG, false, "Lists the applications matching --"+SELECTOR_FLAG+" without changing them")
+	}
+}
+
+// Returns the Marathon filter of --selector (or the legacy --label of restart) or an empty string when the
+// command targets a single application
+func selectorOf(cmd *cobra.Command) string {
+	selector, _ := cmd.Flags().GetString(SELECTOR_FLAG)
+	if selector == "" && cmd.Flags().Lookup(LABEL_FLAG) != nil {
+		if label, _ := cmd.Flags().GetString(LABEL_FLAG); label != "" {
+			selector = "label=" + label
+		}
+	}
+	return normalizeSelector(selector)
+}
+
+// Returns {selector} as a filter of the Marathon apps API.  Selectors without a filter are label selectors
+// and label requirements written with a single = (eg. label=team=payments) are rewritten as equality
+// requirements (label=team==payments)
+func normalizeSelector(selector string) string {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return ""
+	}
+	if strings.HasPrefix(selector, "id=") || strings.HasPrefix(selector, "cmd=") {
+		return selector
+	}
+	requirements := strings.Split(strings.TrimPrefix(selector, "label="), ",")
+	for i, r := range requirements {
+		if m := singleEquals.FindStringSubmatch(r); m != nil {
+			requirements[i] = m[1] + "==" + m[2]
+		}
+	}
+	return "label=" + strings.Join(requirements, ",")
+}
+
+// Lists the applications matching {selector}, outputs them as a preview and asks to confirm {action} (eg.
+// "Destroy") exiting when none match, --dry-run is set or the action is declined
+func selectApps(cmd *cobra.Command, selector, action string) []marathon.Application {
+	apps, err := client(cmd).ListApplicationsWithFilters(selector)
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(apps.Apps) == 0 {
+		exitWithError(fmt.Errorf("No applications match the selector '%s'", selector))
+	}
+	cli.Output(templateFor(T_APPLICATIONS, apps), nil)
+	if dryrun, _ := cmd.Flags().GetBool(DRYRUN_FLAG); dryrun {
+		cli.Exit(nil)
+	}
+	confirmOrExit(func() (string, error) {
+		return fmt.Sprintf("%s %d application(s) matching '%s'", action, len(apps.Apps), selector), nil
+	})
+	return apps.Apps
+}
+
+// Runs {op} against every application of {apps} on up to --workers at once, waiting for the deployment it
+// returns as requested by --wait and --wait-healthy, and outputs a summary exiting when any failed
+func runSelected(cmd *cobra.Command, label string, apps []marathon.Application, op func(app *marathon.Application) (string, error)) {
+	c := client(cmd)
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	quorum := waitQuorum(cmd)
+	timeout := WaitTimeout(cmd, marathon.DefaultTimeout)
+	tasks := []*workpool.Task{}
+	for i := range apps {
+		app := &apps[i]
+		tasks = append(tasks, &workpool.Task{Name: app.ID, Run: func() error {
+			deploymentID, err := op(app)
+			if err != nil {
+				return err
+			}
+			if quorum != nil {
+				return c.WaitForHealthyTasks(app.ID, *quorum, timeout)
+			}
+			if !wait || deploymentID == "" {
+				return nil
+			}
+			return c.WaitForDeployment(deploymentID, timeout)
+		}})
+	}
+	results := runBulk(cmd, label, tasks)
+	cli.Output(templateFor(T_BULK_RESULTS, results), nil)
+	if err := workpool.Err(results); err != nil {
+		exitWithError(err)
+	}
+}
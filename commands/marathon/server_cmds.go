@@ -1,10 +1,16 @@
 package marathon
 
 import (
+	"github.com/ContainX/depcon/marathon"
 	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/spf13/cobra"
 )
 
+const (
+	BACKUP_FLAG  = "backup"
+	RESTORE_FLAG = "restore"
+)
+
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Marathon server information",
@@ -49,12 +55,16 @@ var serverLeaderAbdicateCmd = &cobra.Command{
 	Use:   "abdicate",
 	Short: "Force the current leader to relinquish control (elect a new leader)",
 	Run: func(cmd *cobra.Command, args []string) {
-		v, e := client(cmd).AbdicateLeader()
+		backup, _ := cmd.Flags().GetString(BACKUP_FLAG)
+		restore, _ := cmd.Flags().GetString(RESTORE_FLAG)
+		v, e := client(cmd).AbdicateLeaderWithOpts(&marathon.AbdicateOptions{Backup: backup, Restore: restore})
 		cli.Output(templateFor(T_MESSAGE, v), e)
 	},
 }
 
 func init() {
+	serverLeaderAbdicateCmd.Flags().String(BACKUP_FLAG, "", "URL the state is backed up to before abdicating (eg. file:///var/backups/marathon.zip)")
+	serverLeaderAbdicateCmd.Flags().String(RESTORE_FLAG, "", "URL of a backup the next leader restores the state from")
 	serverLeaderCmd.AddCommand(serverLeaderGetCmd, serverLeaderAbdicateCmd)
 	serverCmd.AddCommand(serverInfoCmd, serverLeaderCmd, serverPingCmd)
 }
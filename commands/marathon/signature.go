@@ -0,0 +1,79 @@
+package marathon
+
+import (
+	"crypto/ed25519"
+
+	"github.com/ContainX/depcon/pkg/signature"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const VERIFY_SIGNATURE_FLAG string = "verify-signature"
+
+// ApplySignatureFlags adds --verify-signature to the commands deploying descriptors
+func ApplySignatureFlags(cmd ...*cobra.Command) {
+	for _, c := range cmd {
+		c.Flags().StringSlice(VERIFY_SIGNATURE_FLAG, nil, `Public key file(s) trusted to sign descriptors.  Descriptors are only deployed when their detached
+                  signature (<file>.sig created by depcon sign) is by one of these keys.  Ignored for environments
+                  configuring trustedKeys which are always enforced`)
+	}
+}
+
+// Returns the public key files trusted to sign the descriptors deployed to environment {envName}.  The
+// environment's trustedKeys take precedence so they can't be cleared or replaced by --verify-signature, which
+// may be defaulted by a project or DEPCON_* variables
+func trustedKeys(cmd *cobra.Command, envName string) []string {
+	if configFile != nil {
+		if env, err := configFile.GetEnvironment(envName); err == nil && len(env.TrustedKeys) > 0 {
+			return env.TrustedKeys
+		}
+	}
+	if cmd == nil {
+		return nil
+	}
+	keys, _ := cmd.Flags().GetStringSlice(VERIFY_SIGNATURE_FLAG)
+	return keys
+}
+
+// TrustedPublicKeys returns the keys of the trustedKeys of environment {envName} or nil when it configures none
+func TrustedPublicKeys(envName string) ([]ed25519.PublicKey, error) {
+	keys := trustedKeys(nil, envName)
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return signature.LoadPublicKeys(keys)
+}
+
+// SignatureVerifier returns a check that a descriptor deployed to environment {envName} is signed by a trusted
+// key (see reconcile.LoadOptions.Verify) or nil when no keys are trusted
+func SignatureVerifier(cmd *cobra.Command, envName string) func(filename string) error {
+	keys := trustedKeys(cmd, envName)
+	if len(keys) == 0 {
+		return nil
+	}
+	return func(filename string) error {
+		trusted, err := signature.LoadPublicKeys(keys)
+		if err != nil {
+			return err
+		}
+		if err := signature.VerifyFile(filename, trusted); err != nil {
+			return err
+		}
+		log.Info("Verified the signature of %s", filename)
+		return nil
+	}
+}
+
+// VerifySignatures exits unless each of {filenames} is signed by a key trusted by the current environment (see
+// trustedKeys).  Nothing is checked when no keys are trusted
+func VerifySignatures(cmd *cobra.Command, filenames ...string) {
+	verify := SignatureVerifier(cmd, viper.GetString(ENV_NAME))
+	if verify == nil {
+		return
+	}
+	for _, f := range filenames {
+		if err := verify(f); err != nil {
+			exitWithError(err)
+		}
+	}
+}
@@ -19,7 +19,7 @@ var taskListCmd = &cobra.Command{
 	Short: "List all tasks",
 	Run: func(cmd *cobra.Command, args []string) {
 		v, e := client(cmd).ListTasks()
-		cli.Output(templateFor(T_TASKS, v), e)
+		cli.Output(templateFor(templateFormat(T_TASKS, cmd), v), e)
 	},
 }
 
@@ -28,10 +28,16 @@ var taskQueueCmd = &cobra.Command{
 	Short: "List all queued tasks",
 	Run: func(cmd *cobra.Command, args []string) {
 		v, e := client(cmd).ListQueue()
-		cli.Output(templateFor(T_QUEUED_TASKS, v), e)
+		cli.Output(templateFor(templateFormat(T_QUEUED_TASKS, cmd), v), e)
 	},
 }
 
+var taskResetDelayCmd = &cobra.Command{
+	Use:   "reset-delay [applicationId]",
+	Short: "Resets the launch delay of the queued application [applicationId] so its tasks are launched immediately",
+	Run:   resetDelay,
+}
+
 var appTaskGetCmd = &cobra.Command{
 	Use:   "get [applicationId]",
 	Short: "List tasks for the application [applicationId]",
@@ -52,6 +58,9 @@ var appTaskKillCmd = &cobra.Command{
 
 func init() {
 	taskCmd.AddCommand(taskListCmd, appTaskGetCmd, appTaskKillCmd, appTaskKillallCmd, taskQueueCmd)
+	taskQueueCmd.AddCommand(taskResetDelayCmd)
+	applyFormatFlag(taskListCmd, "{{range .}}{{ .Host }} {{end}}")
+	applyFormatFlag(taskQueueCmd, "{{range .Queue}}{{ .App.ID }} {{end}}")
 
 	// Task List Flags
 	appTaskGetCmd.Flags().BoolP(DETAIL_FLAG, "d", false, "Prints each task instance in detailed form vs. table summary")
@@ -102,3 +111,13 @@ func appKillTask(cmd *cobra.Command, args []string) {
 	v, e := client(cmd).KillAppTask(args[0], scale)
 	cli.Output(templateFor(T_TASK, v), e)
 }
+
+func resetDelay(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	if err := client(cmd).ResetDelay(args[0]); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("Launch delay of %s has been reset\n", args[0])
+}
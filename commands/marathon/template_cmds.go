@@ -0,0 +1,99 @@
+package marathon
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/diff"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	AGAINST_FLAG     = "against"
+	AGAINST_ENV_FLAG = "against-env"
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Descriptor template utilities",
+	Long: `Render and compare templated descriptors (eg. diffing rendered output across environments)
+
+    See template's subcommands for available choices`,
+}
+
+var templateDiffCmd = &cobra.Command{
+	Use:   "diff [file(.json | .yaml)]",
+	Short: "Renders [file] under two template contexts and prints a unified diff of the output",
+	Long: `Renders the descriptor [file] with the context specified by --tempctx and again with the context
+specified by --against and prints a unified diff of the results.
+
+When --against is omitted the same context is used and --against-env selects the environment to compare with.
+
+Examples:
+  depcon template diff app.json --tempctx prod.json --against staging.json
+  depcon template diff app.json --against-env staging -e prod`,
+	Run: templateDiff,
+}
+
+func init() {
+	templateDiffCmd.Flags().String(TEMPLATE_CTX_FLAG, DEFAULT_CTX, "Template context used to render the original output")
+	templateDiffCmd.Flags().String(AGAINST_FLAG, "", "Template context to compare against (default: the --tempctx context)")
+	templateDiffCmd.Flags().String(AGAINST_ENV_FLAG, "", "Environment to render the compared context with (default: the current environment)")
+	templateCmd.AddCommand(templateDiffCmd)
+}
+
+func templateDiff(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	tempctx, _ := cmd.Flags().GetString(TEMPLATE_CTX_FLAG)
+	against, _ := cmd.Flags().GetString(AGAINST_FLAG)
+	againstEnv, _ := cmd.Flags().GetString(AGAINST_ENV_FLAG)
+
+	env := viper.GetString(ENV_NAME)
+	if against == "" {
+		against = tempctx
+	}
+	if againstEnv == "" {
+		againstEnv = env
+	}
+
+	original, err := renderWithContext(args[0], tempctx, env)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	compared, err := renderWithContext(args[0], against, againstEnv)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	from := fmt.Sprintf("%s (%s)", tempctx, env)
+	to := fmt.Sprintf("%s (%s)", against, againstEnv)
+
+	if d := diff.Unified(original, compared, from, to, diff.DefaultContext); d != "" {
+		fmt.Print(mask.Text(d))
+		cli.ExitWithCode(cli.ExitError)
+	}
+	fmt.Println("No differences found")
+}
+
+func renderWithContext(filename, tempctx, env string) (string, error) {
+	if !TemplateExists(tempctx) {
+		return "", fmt.Errorf("Template context '%s' could not be found", tempctx)
+	}
+
+	ctx, err := LoadTemplateContext(tempctx)
+	if err != nil {
+		return "", err
+	}
+
+	b := &bytes.Buffer{}
+	if err := ctx.TransformForEnv(b, filename, env); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
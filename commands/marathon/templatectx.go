@@ -1,13 +1,17 @@
 package marathon
 
 import (
+	"bytes"
+	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"reflect"
 	"text/template"
 
 	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/secrets"
+	"github.com/ContainX/depcon/pkg/sops"
 	"github.com/spf13/viper"
 	"path/filepath"
 	"strings"
@@ -15,6 +19,10 @@ import (
 
 const (
 	DefaultEnv = "-"
+	// Key within the template data referencing the current element when rendering with --each
+	EachItemKey = "item"
+	// Key within the template data referencing the current element index when rendering with --each
+	EachIndexKey = "index"
 )
 
 // Templated based Functions
@@ -45,42 +53,78 @@ var Funcs = template.FuncMap{
 		return value
 	},
 	"isEnv": func(value string) bool {
-		if len(value) > 0 {
-			current := strings.ToLower(viper.GetString(ENV_NAME))
-			return current == strings.ToLower(value)
-		}
-		return false
+		return isEnv(viper.GetString(ENV_NAME), value)
+	},
+	"toJson": envsubst.ToJSON,
+	"jsonEscape": func(value interface{}) string {
+		return envsubst.JSONEscape(fmt.Sprint(value))
+	},
+	"b64enc": func(value interface{}) string {
+		return envsubst.Base64Encode(fmt.Sprint(value))
+	},
+	"quote": func(value interface{}) string {
+		return envsubst.Quote(fmt.Sprint(value))
+	},
+	"indent": func(spaces int, value interface{}) string {
+		return envsubst.Indent(spaces, fmt.Sprint(value))
+	},
+	// resolves a secret reference (eg. {{ secret "secret://vault/secret/data/db#password" }})
+	"secret": func(ref string) (string, error) {
+		return secrets.Default().Resolve(ref)
 	},
 }
 
+// Returns the template functions with isEnv bound to the specified environment
+func funcsForEnv(env string) template.FuncMap {
+	funcs := template.FuncMap{}
+	for k, v := range Funcs {
+		funcs[k] = v
+	}
+	funcs["isEnv"] = func(value string) bool {
+		return isEnv(env, value)
+	}
+	return funcs
+}
+
+func isEnv(current, value string) bool {
+	if len(value) > 0 {
+		return strings.ToLower(current) == strings.ToLower(value)
+	}
+	return false
+}
+
 type TemplateContext struct {
 	Environments map[string]*TemplateEnvironment `json:"environments,omitempty"`
+	// If true template fields referencing values not defined in the context result in an error
+	ErrorOnMissing bool `json:"-"`
+	// ${PARAMS} the rendered descriptor is resolved with.  With ErrorOnMissing the descriptor's unresolved
+	// ${PARAMS} are reported along with its missing template fields
+	Params map[string]string `json:"-"`
 }
 
 type TemplateEnvironment struct {
 	Apps map[string]map[string]interface{} `json:"apps,omitempty"`
+	// Free form values available at the root of the template data (eg. lists used with --each)
+	Values map[string]interface{} `json:"values,omitempty"`
 }
 
 func (ctx *TemplateContext) Transform(writer io.Writer, descriptor string) error {
-	var t *template.Template
+	return ctx.TransformForEnv(writer, descriptor, viper.GetString(ENV_NAME))
+}
 
-	if b, err := ioutil.ReadFile(descriptor); err != nil {
+// Transforms the descriptor using the values for the specified environment {env} rather than the
+// currently selected environment
+func (ctx *TemplateContext) TransformForEnv(writer io.Writer, descriptor, env string) error {
+	t, err := parseTemplate(descriptor, env)
+	if err != nil {
+		return err
+	}
+
+	m := ctx.templateData(strings.ToLower(env))
+
+	if err := ctx.checkMissingFields(t, descriptor, m); err != nil {
 		return err
-	} else {
-		var e error
-		t = template.New(descriptor).Funcs(Funcs)
-		t, e = t.Parse(string(b))
-		if e != nil {
-			return e
-		}
-		if matches, err := filepath.Glob("./**/*.tmpl"); err == nil && len(matches) > 0 {
-			if t, e = t.ParseFiles(matches...); err != nil {
-				return err
-			}
-		}
 	}
-	environment := viper.GetString(ENV_NAME)
-	m := ctx.mergeAppWithDefault(strings.ToLower(environment))
 
 	if err := t.Execute(writer, m); err != nil {
 		return err
@@ -88,6 +132,120 @@ func (ctx *TemplateContext) Transform(writer io.Writer, descriptor string) error
 	return nil
 }
 
+// Renders the descriptor once for every element found at the specified path (eg. .tenants) within the
+// template data.  The current element and its index are available as {{ .item }} and {{ .index }}
+func (ctx *TemplateContext) TransformEach(descriptor, path string) ([]string, error) {
+	environment := viper.GetString(ENV_NAME)
+	t, err := parseTemplate(descriptor, environment)
+	if err != nil {
+		return nil, err
+	}
+
+	m := ctx.templateData(strings.ToLower(environment))
+	for _, key := range []string{EachItemKey, EachIndexKey} {
+		if _, exists := m[key]; exists {
+			return nil, fmt.Errorf("The template context defines '%s' which is reserved for the current element when rendering for each element", key)
+		}
+	}
+
+	v, err := resolvePath(m, path)
+	if err != nil {
+		return nil, err
+	}
+
+	items := reflect.ValueOf(v)
+	if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+		return nil, fmt.Errorf("Each path '%s' must reference a list within the template context", path)
+	}
+
+	results := []string{}
+	for i := 0; i < items.Len(); i++ {
+		data := make(map[string]interface{}, len(m)+2)
+		for k, v := range m {
+			data[k] = v
+		}
+		data[EachItemKey] = items.Index(i).Interface()
+		data[EachIndexKey] = i
+
+		if i == 0 {
+			if err := ctx.checkMissingFields(t, descriptor, data); err != nil {
+				return nil, err
+			}
+		}
+
+		b := &bytes.Buffer{}
+		if err := t.Execute(b, data); err != nil {
+			return nil, err
+		}
+		results = append(results, b.String())
+	}
+	return results, nil
+}
+
+func parseTemplate(descriptor, env string) (*template.Template, error) {
+	b, err := sops.ReadFile(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := template.New(descriptor).Funcs(funcsForEnv(env)).Parse(string(b))
+	if err != nil {
+		return nil, err
+	}
+	if matches, err := filepath.Glob("./**/*.tmpl"); err == nil && len(matches) > 0 {
+		if t, err = t.ParseFiles(matches...); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Builds the root data handed to the template which is comprised of the merged apps and any
+// free form values defined for the environment
+func (ctx *TemplateContext) templateData(env string) map[string]interface{} {
+	data := make(map[string]interface{})
+	for k, v := range ctx.mergeValuesWithDefault(env) {
+		data[k] = v
+	}
+	for app, props := range ctx.mergeAppWithDefault(env) {
+		data[app] = props
+	}
+	return data
+}
+
+// Returns the free form values for the environment with any values missing propagated from the
+// default environment
+func (ctx *TemplateContext) mergeValuesWithDefault(env string) map[string]interface{} {
+	merged := make(map[string]interface{})
+
+	if def, exists := ctx.Environments[DefaultEnv]; exists && def != nil {
+		for k, v := range def.Values {
+			merged[k] = v
+		}
+	}
+	if e, exists := ctx.Environments[env]; exists && e != nil && env != DefaultEnv {
+		for k, v := range e.Values {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Resolves a dotted path such as .tenants or .regions.us against the template data
+func resolvePath(data map[string]interface{}, path string) (interface{}, error) {
+	var current interface{} = data
+	for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Each path '%s' could not be resolved at '%s'", path, key)
+		}
+		if current, ok = m[key]; !ok {
+			return nil, fmt.Errorf("Each path '%s' could not be resolved at '%s'", path, key)
+		}
+	}
+	return current, nil
+}
+
 // Validates the specified app is declared within the current envirnoment. If it is any values missing from
 // specific environment are propagated from the default environment
 func (ctx *TemplateContext) mergeAppWithDefault(env string) map[string]map[string]interface{} {
@@ -131,6 +289,23 @@ func recovery() {
 	recover()
 }
 
+// Template context files looked for when none is given where {env} is replaced by the selected environment
+var DefaultContextPatterns = []string{"template-context-{env}.json", "template-context-{env}.yaml", "contexts/{env}.yaml", "contexts/{env}.json"}
+
+// ContextForEnv returns the first file of {patterns} which exists for the environment {env} or an empty
+// string when there is none
+func ContextForEnv(patterns []string, env string) string {
+	if env == "" {
+		return ""
+	}
+	for _, pattern := range patterns {
+		if filename := strings.Replace(pattern, "{env}", env, -1); TemplateExists(filename) {
+			return filename
+		}
+	}
+	return ""
+}
+
 func TemplateExists(filename string) bool {
 
 	if len(filename) > 0 {
@@ -142,20 +317,50 @@ func TemplateExists(filename string) bool {
 }
 
 func LoadTemplateContext(filename string) (*TemplateContext, error) {
-	ctx, err := os.Open(filename)
+	b, err := sops.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
 
-	encoder, err := encoding.NewEncoder(encoding.JSON)
+	// context files may be json, yaml or toml. Unknown extensions are treated as json
+	et, _ := encoding.EncoderTypeFromExt(filename)
+	encoder, err := encoding.NewEncoder(et)
 	if err != nil {
 		return nil, err
 	}
-
 	result := &TemplateContext{Environments: make(map[string]*TemplateEnvironment)}
-
-	if err := encoder.UnMarshal(ctx, result); err != nil {
+	if err := encoder.UnMarshalStr(string(b), result); err != nil {
 		return nil, err
 	}
+
+	// a context without environments (eg. template-context-prod.json) holds the values of every environment
+	if len(result.Environments) == 0 {
+		env := &TemplateEnvironment{}
+		if err := encoder.UnMarshalStr(string(b), env); err != nil {
+			return nil, err
+		}
+		result.Environments[DefaultEnv] = env
+	}
 	return result, nil
 }
+
+// RenderDescriptor renders the descriptor {filename} with the template context {tempctx} using the values of
+// environment {env}.  The descriptor is returned as is when the context doesn't exist.  Unless {ignoreMissing}
+// the ${PARAMS} of the descriptor which {params} don't resolve are reported with its missing template fields
+func RenderDescriptor(filename, tempctx, env string, params map[string]string, ignoreMissing bool) (string, error) {
+	if !TemplateExists(tempctx) {
+		b, err := sops.ReadFile(filename)
+		return string(b), err
+	}
+	ctx, err := LoadTemplateContext(tempctx)
+	if err != nil {
+		return "", err
+	}
+	ctx.ErrorOnMissing, ctx.Params = !ignoreMissing, params
+
+	b := &bytes.Buffer{}
+	if err := ctx.TransformForEnv(b, filename, env); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -1,7 +1,12 @@
 package marathon
 
 import (
+	"bytes"
+	"github.com/ContainX/depcon/pkg/envsubst"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -16,3 +21,74 @@ func TestMergeFunctionality(t *testing.T) {
 	assert.Equal(t, float64(300), m["appa"]["mem"])
 
 }
+
+func TestTransformEach(t *testing.T) {
+	tc, err := LoadTemplateContext("resources/testeach.json")
+	assert.NoError(t, err)
+
+	results, err := tc.TransformEach("resources/each-app.json", ".tenants")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(results))
+	assert.Contains(t, results[0], `"id": "/tenants/acme"`)
+	assert.Contains(t, results[0], `"mem": 200`)
+	assert.Contains(t, results[1], `"TENANT_INDEX": "1"`)
+
+	_, err = tc.TransformEach("resources/each-app.json", ".missing")
+	assert.Error(t, err)
+
+	tc.Environments[DefaultEnv].Values[EachIndexKey] = 3
+	_, err = tc.TransformEach("resources/each-app.json", ".tenants")
+	assert.EqualError(t, err, "The template context defines 'index' which is reserved for the current element when rendering for each element")
+}
+
+func TestMissingTemplateFieldsReported(t *testing.T) {
+	tc, err := LoadTemplateContext("resources/testcontext.json")
+	assert.NoError(t, err)
+	tc.ErrorOnMissing = true
+
+	b := &bytes.Buffer{}
+	err = tc.TransformForEnv(b, "resources/missing-app.json", "prod")
+	assert.Error(t, err)
+
+	perr, ok := err.(*envsubst.MissingParamsError)
+	assert.True(t, ok)
+	assert.Equal(t, 1, len(perr.Params))
+	assert.Equal(t, ".appb.disk", perr.Params[0].Name)
+	assert.Equal(t, 5, perr.Params[0].Line)
+	assert.Equal(t, 14, perr.Params[0].Column)
+}
+
+func TestMissingParamsReportedWithTemplateFields(t *testing.T) {
+	tc, err := LoadTemplateContext("resources/testcontext.json")
+	assert.NoError(t, err)
+	tc.ErrorOnMissing = true
+	tc.Params = map[string]string{"DB_PORT": "5432"}
+
+	b := &bytes.Buffer{}
+	err = tc.TransformForEnv(b, "resources/missing-params-app.json", "prod")
+	assert.Error(t, err)
+
+	perr, ok := err.(*envsubst.MissingParamsError)
+	assert.True(t, ok)
+	assert.Equal(t, []envsubst.MissingParam{
+		{Name: ".appb.disk", Line: 5, Column: 14, Template: true},
+		{Name: "DB_HOST", Line: 7, Column: 17},
+	}, perr.Params)
+}
+
+func TestContextForEnv(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tempctx")
+	defer os.RemoveAll(dir)
+	os.MkdirAll(filepath.Join(dir, "contexts"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "contexts", "prod.yaml"), []byte("values:\n  replicas: 3\n"), 0644)
+	patterns := []string{filepath.Join(dir, "template-context-{env}.json"), filepath.Join(dir, "contexts/{env}.yaml")}
+
+	filename := ContextForEnv(patterns, "prod")
+	assert.Equal(t, filepath.Join(dir, "contexts", "prod.yaml"), filename)
+	assert.Equal(t, "", ContextForEnv(patterns, "staging"))
+	assert.Equal(t, "", ContextForEnv(nil, "prod"))
+
+	ctx, err := LoadTemplateContext(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, float64(3), ctx.templateData("prod")["replicas"])
+}
@@ -0,0 +1,142 @@
+package marathon
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/sops"
+)
+
+// Reports every field reference (eg. {{ .appa.mem }}) in the descriptor that cannot be resolved against
+// the template data when ErrorOnMissing is enabled.  References within range/with blocks are skipped since
+// the dot is re-bound within them.  Optional lookups (if conditions and arguments to default) are allowed
+// to be missing.  The ${PARAMS} of the descriptor which ctx.Params don't resolve are reported alongside so
+// both are listed at once with their positions within the descriptor rather than the rendered output
+func (ctx *TemplateContext) checkMissingFields(t *template.Template, descriptor string, data map[string]interface{}) error {
+	if !ctx.ErrorOnMissing {
+		return nil
+	}
+
+	src, err := sops.ReadFile(descriptor)
+	if err != nil {
+		return err
+	}
+
+	missing := []envsubst.MissingParam{}
+	if t.Tree != nil {
+		walkFields(t.Tree.Root, func(n *parse.FieldNode) {
+			if !fieldResolves(data, n.Ident) {
+				// the node position references the final identifier in the chain
+				offset := int(n.Position())
+				for _, ident := range n.Ident[:len(n.Ident)-1] {
+					offset -= len(ident) + 1
+				}
+				line, col := position(string(src), offset)
+				missing = append(missing, envsubst.MissingParam{
+					Name:     "." + strings.Join(n.Ident, "."),
+					Line:     line,
+					Column:   col,
+					Template: true,
+				})
+			}
+		})
+	}
+	missing = append(missing, envsubst.FindMissing(bytes.NewReader(src), ctx.Params)...)
+
+	if len(missing) > 0 {
+		sort.SliceStable(missing, func(i, j int) bool {
+			if missing[i].Line != missing[j].Line {
+				return missing[i].Line < missing[j].Line
+			}
+			return missing[i].Column < missing[j].Column
+		})
+		return &envsubst.MissingParamsError{Filename: descriptor, Params: missing}
+	}
+	return nil
+}
+
+func walkFields(node parse.Node, fn func(n *parse.FieldNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkFields(c, fn)
+		}
+	case *parse.ActionNode:
+		walkFields(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		// commands piped into default are optional lookups
+		for i := len(n.Cmds) - 1; i >= 0; i-- {
+			if isDefaultCmd(n.Cmds[i]) {
+				return
+			}
+			walkFields(n.Cmds[i], fn)
+		}
+	case *parse.CommandNode:
+		if isDefaultCmd(n) {
+			return
+		}
+		for _, a := range n.Args {
+			walkFields(a, fn)
+		}
+	case *parse.FieldNode:
+		fn(n)
+	case *parse.IfNode:
+		walkFields(n.List, fn)
+		walkFields(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkFields(n.Pipe, fn)
+		walkFields(n.ElseList, fn)
+	case *parse.WithNode:
+		walkFields(n.Pipe, fn)
+		walkFields(n.ElseList, fn)
+	}
+}
+
+func isDefaultCmd(c *parse.CommandNode) bool {
+	if len(c.Args) > 0 {
+		if ident, ok := c.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "default" {
+			return true
+		}
+	}
+	return false
+}
+
+// Determines if the field chain resolves against nested maps within the data.  Chains passing through
+// values which are not maps cannot be evaluated statically and are treated as resolvable
+func fieldResolves(data map[string]interface{}, idents []string) bool {
+	var current interface{} = data
+	for _, ident := range idents {
+		switch m := current.(type) {
+		case map[string]interface{}:
+			v, ok := m[ident]
+			if !ok {
+				return false
+			}
+			current = v
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+// Converts a byte offset into a 1 based line and column
+func position(src string, offset int) (int, int) {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	before := src[:offset]
+	line := strings.Count(before, "\n") + 1
+	col := offset - strings.LastIndex(before, "\n")
+	return line, col
+}
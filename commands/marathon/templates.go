@@ -1,21 +1,26 @@
 package marathon
 
 import (
+	"fmt"
 	"github.com/ContainX/depcon/marathon"
 	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/ContainX/depcon/utils"
 	"io"
+	"strconv"
+	"strings"
 	"text/template"
 )
 
 const (
-	T_APPLICATIONS = `
-{{ "ID" }}	{{ "INSTANCES" }}	{{ "CPU" }}	{{ "MEM" }}	{{ "PORTS" }}	{{ "CONTAINER" }}	{{ "VERSION" }}
-{{range .Apps}}{{ .ID }}	{{ .Instances }}	{{ .CPUs | floatToString }}	{{ .Mem | floatToString }}	{{ .Ports | intConcat }}	{{ .Container | dockerImage }}	{{ .Version }}
-{{end}}`
+	T_APPLICATIONS_HEADER  = `{{ "ID" | header }}	{{ "INSTANCES" | header }}	{{ "CPU" | header }}	{{ "MEM" | header }}	{{ "PORTS" | header }}	{{ "CONTAINER" | header }}	{{ "VERSION" | header }}`
+	T_APPLICATION_ROW      = `{{ .ID }}	{{ . | instances }}	{{ .CPUs | floatToString }}	{{ .Mem | floatToString }}	{{ .Ports | intConcat }}	{{ .Container | dockerImage }}	{{ .Version }}`
+	T_APPLICATIONS         = "\n" + T_APPLICATIONS_HEADER + "\n{{range .Apps}}" + T_APPLICATION_ROW + "\n{{end}}"
+	T_GROUP_TOTALS         = `{{ len .Apps }} app(s), {{ .Instances }} instance(s), {{ .CPUs | floatToString }} cpus, {{ .Mem | floatToString }} MB mem`
+	T_APPLICATIONS_GROUPED = "{{range .Groups}}\n{{ .Name | header }} - " + T_GROUP_TOTALS + "\n" + T_APPLICATIONS_HEADER + "\n{{range .Apps}}" + T_APPLICATION_ROW + "\n{{end}}{{end}}" +
+		"\n{{ with .Total }}{{ \"TOTAL\" | header }} - " + T_GROUP_TOTALS + "{{end}}\n"
 
 	T_APPLICATION = `
-{{ "ID" }}	{{ .ID }}
+{{ "ID" | header }}	{{ .ID }}
 {{ "CPUs:" }}	{{ .CPUs | floatToString }}
 {{ "Memory:" }}	{{ .Mem | floatToString }}
 {{ "Ports:" }}	{{ .Ports | intConcat }}
@@ -32,7 +37,7 @@ const (
 	{{ "Network" | pad }} {{ .Container.Docker.Network }}
 {{- end}}
 {{ "Environment:" }}
-{{ range $key, $value := .Env }}		{{ $key | pad }} {{ $value }}
+{{ range $key, $value := .Env }}		{{ $key | pad }} {{ mask $key $value }}
 {{end}}
 {{ "Labels:" }}
 {{ range $key, $value := .Labels }}		{{ $key | pad }} {{ $value }}
@@ -40,17 +45,22 @@ const (
 `
 
 	T_VERSIONS = `
-{{ "VERSIONS" }}
+{{ "VERSIONS" | header }}
 {{ range .Versions }}{{ . }}
 {{end}}`
 
 	T_DEPLOYMENT_ID = `
-{{ "DEPLOYMENT_ID" }}	{{ "VERSION" }}
+{{ "DEPLOYMENT_ID" | header }}	{{ "VERSION" | header }}
 {{ .DeploymentID }}	{{ .Version }}`
 
+	T_BULK_RESULTS = `
+{{ "NAME" | header }}	{{ "RESULT" | header }}	{{ "DURATION" | header }}	{{ "ERROR" | header }}
+{{ range . }}{{ .Name }}	{{ .Result }}	{{ .Duration }}	{{ .Error }}
+{{end}}`
+
 	T_TASKS = `
-{{ "APP_ID" }}	{{ "HOST" }}	{{ "VERSION" }}	{{ "STARTED" }}	{{ "TASK_ID" }}
-{{ range . }}{{ .AppID }}	{{ .Host }}	{{ .Version }}	{{ .StartedAt | fdate }}	{{ .ID }}
+{{ "APP_ID" | header }}	{{ "HOST" | header }}	{{ "VERSION" | header }}	{{ "STARTED" | header }}	{{ "TASK_ID" | header }}
+{{ range . }}{{ .AppID }}	{{ .Host }}	{{ .Version }}	{{ .StartedAt | fdate }}	{{ . | taskID }}
 {{end}}`
 
 	T_TASK = `
@@ -62,30 +72,40 @@ const (
 {{ "Ports:" }}	{{ .Ports | intConcat }}
 `
 	T_DEPLOYMENTS = `
-{{ "DEPLOYMENT_ID" }}	{{ "VERSION" }} 	{{ "PROGRESS" }}	{{ "APPS" }}
-{{ range . }}{{ .DeployID }}	{{ .Version }}	{{ .CurrentStep | intToString }}/{{ .TotalSteps | intToString }}	{{ .AffectedApps | idConcat }}
+{{ "DEPLOYMENT_ID" | header }}	{{ "VERSION" | header }} 	{{ "PROGRESS" | header }}	{{ "APPS" | header }}	{{ "CURRENT" | header }}
+{{ range . }}{{ .DeployID }}	{{ .Version }}	{{ . | deployProgress }}	{{ .AffectedApps | idConcat }}	{{ .CurrentActions | deployActions }}
+{{end}}`
+	T_WAIT_RESULT = `
+{{ "Deployment:" }}	{{ .DeploymentID }}
+{{ "State:" }}	{{ .State }}
+{{ "Elapsed:" }}	{{ .Elapsed }}
+{{ "Reason:" }}	{{ .Reason }}
+{{ range .Apps }}{{ "App:" }}	{{ . }}
+{{end}}{{ if .More }}{{ "More:" }}	{{ .More }} application(s)
 {{end}}`
 	T_LEADER_INFO = `
 {{ "Leader:" }}	{{ .Leader }}
+{{ "Hostname:" }}	{{ .Hostname }}
+{{ "Port:" }}	{{ .Port }}
 `
 
 	T_PING = `
-{{ "HOST" }}	{{ "DURATION" }}
+{{ "HOST" | header }}	{{ "DURATION" | header }}
 {{ .Host }}	{{ .Elapsed | msDur }}
 `
 
 	T_MARATHON_INFO = `
-{{ "INFO" }}
+{{ "INFO" | header }}
 {{ "Name:" }}	{{ .Name }}
 {{ "Version:" }}	{{ .Version }}
 {{ "FrameworkId:" }}	{{ .FrameworkId }}
 {{ "Leader:" }}	{{ .Leader }}
 
-{{ "HTTP CONFIG" }}
+{{ "HTTP CONFIG" | header }}
 {{ "HTTP Port:" }}	{{ .HttpConfig.HttpPort | valString }}
 {{ "HTTPS Port:" }}	{{ .HttpConfig.HttpsPort | valString }}
 
-{{ "MARATHON CONFIG" }}
+{{ "MARATHON CONFIG" | header }}
 {{ "Checkpoint:" }}	{{ .MarathonConfig.Checkpoint | valString }}
 {{ "Executor:" }}	{{ .MarathonConfig.Executor }}
 {{ "HA:" }}	{{ .MarathonConfig.Ha | valString }}
@@ -94,22 +114,50 @@ const (
 {{ "Local Port (Min):" }}	{{ .MarathonConfig.LocalPortMin | valString }}
 {{ "Local Port (Max):" }}	{{ .MarathonConfig.LocalPortMax | valString }}
 
-{{ "ZOOKEEPER CONFIG" }}
+{{ "ZOOKEEPER CONFIG" | header }}
 {{ "ZK:" }}	{{ .ZookeeperConfig.Zk }}
 {{ "Timeout:" }}	{{ .ZookeeperConfig.ZkTimeout | valString }}
 `
 	T_QUEUED_TASKS = `
-{{ "APP_ID" }}	{{ "VERSION" }}	{{ "OVERDUE" }}
-{{ range .Queue }}{{ .App.ID }}	{{ .App.Version }}	{{ .Delay.overdue | valString }}
+{{ "APP_ID" | header }}	{{ "VERSION" | header }}	{{ "WAITING" | header }}	{{ "DELAY" | header }}	{{ "OVERDUE" | header }}
+{{ range .Queue }}{{ .App.ID }}	{{ .App.Version }}	{{ .Count | intToString }}	{{ .Delay.TimeLeftSeconds | intToString }}s	{{ .Delay.Overdue | overdue }}
 {{end}}`
 
 	T_MESSAGE = `
 {{ "Message:" }}	{{ .Message }}
 `
 	T_GROUPS = `
-{{ "ID" }}	{{ "VERSION" }}	{{ "GROUPS" }}	{{ "APPS" }}
+{{ "ID" | header }}	{{ "VERSION" | header }}	{{ "GROUPS" | header }}	{{ "APPS" | header }}
 {{ range . }}{{ .GroupID }}	{{ .Version }}	{{ .Groups | len | valString }}	{{ .Apps | len | valString }}
 {{end}}`
+
+	T_PODS = `
+{{ "ID" | header }}	{{ "INSTANCES" | header }}	{{ "CONTAINERS" | header }}	{{ "VERSION" | header }}
+{{ range . }}{{ .ID }}	{{ if .Scaling }}{{ .Scaling.Instances | intToString }}{{ end }}	{{ .Containers | len | valString }}	{{ .Version }}
+{{end}}`
+
+	T_POD_STATUS = `
+{{ "ID:" }}	{{ .ID }}
+{{ "Status:" }}	{{ .Status }}
+{{ "Since:" }}	{{ .StatusSince }}
+
+{{ "INSTANCE" | header }}	{{ "STATUS" | header }}	{{ "AGENT" | header }}	{{ "SINCE" | header }}
+{{ range .Instances }}{{ .ID }}	{{ .Status }}	{{ .AgentHostname }}	{{ .StatusSince }}
+{{end}}`
+
+	T_LB_SERVERS = `
+{{ "INSTANCE" | header }}	{{ "BACKEND" | header }}	{{ "SERVER" | header }}	{{ "STATUS" | header }}	{{ "SESSIONS" | header }}	{{ "QUEUED" | header }}
+{{ range . }}{{ .Instance }}	{{ .Proxy }}	{{ .Server }}	{{ .Status | serverStatus }}	{{ .Sessions | intToString }}	{{ .Queued | intToString }}
+{{end}}`
+
+	T_LB_VALIDATION = `{{ range . }}
+{{ "App:" }}	{{ .AppID }}
+{{ "BACKEND" | header }}	{{ "BIND" | header }}	{{ "MODE" | header }}	{{ "VHOSTS" | header }}	{{ "PATH" | header }}	{{ "GROUPS" | header }}
+{{ range .Frontends }}{{ .Backend }}	{{ .BindAddr }}:{{ .Port | intToString }}	{{ .Mode }}	{{ .VHosts | join }}	{{ .Path }}	{{ .Groups | join }}
+{{end}}{{ if .Issues }}
+{{ "LEVEL" | header }}	{{ "LABEL" | header }}	{{ "MESSAGE" | header }}
+{{ range .Issues }}{{ .Level | status }}	{{ .Label }}	{{ .Message }}
+{{end}}{{end}}{{end}}`
 )
 
 type Templated struct {
@@ -130,14 +178,85 @@ func (d Templated) Data() cli.FormatData {
 
 func buildFuncMap() template.FuncMap {
 	funcMap := template.FuncMap{
-		"intConcat":   utils.ConcatInts,
-		"idConcat":    utils.ConcatIdentifiers,
-		"dockerImage": dockerImageOrEmpty,
-		"hasDocker":   hasDocker,
+		"intConcat":      utils.ConcatInts,
+		"idConcat":       utils.ConcatIdentifiers,
+		"dockerImage":    dockerImageOrEmpty,
+		"hasDocker":      hasDocker,
+		"instances":      instances,
+		"taskID":         taskID,
+		"deployProgress": deployProgress,
+		"deployActions":  deployActions,
+		"overdue":        overdue,
+		"serverStatus":   serverStatus,
+		"join":           join,
 	}
 	return funcMap
 }
 
+// Colors the instance count of {app} by the health of its tasks
+func instances(app *marathon.Application) string {
+	count := strconv.Itoa(app.Instances)
+	switch {
+	case app.TasksUnHealthy > 0:
+		return cli.Colorize(cli.RoleUnhealthy, count)
+	case len(app.DeploymentID) > 0 || app.TasksStaged > 0:
+		return cli.Colorize(cli.RoleDeploying, count)
+	case app.TasksRunning < app.Instances:
+		return cli.Colorize(cli.RoleWarning, count)
+	}
+	return cli.Colorize(cli.RoleHealthy, count)
+}
+
+// Colors the ID of {task} by its health check results.  Tasks without health checks are not colored
+func taskID(task *marathon.Task) string {
+	if len(task.HealthCheckResult) == 0 {
+		return task.ID
+	}
+	for _, hc := range task.HealthCheckResult {
+		if hc == nil || !hc.Alive {
+			return cli.Colorize(cli.RoleUnhealthy, task.ID)
+		}
+	}
+	return cli.Colorize(cli.RoleHealthy, task.ID)
+}
+
+func deployProgress(d *marathon.Deploy) string {
+	return cli.Colorize(cli.RoleDeploying, fmt.Sprintf("%d/%d", d.CurrentStep, d.TotalSteps))
+}
+
+// Describes {actions} (eg. ScaleApplication /web)
+func deployActions(actions []*marathon.DeploymentAction) string {
+	arr := []string{}
+	for _, a := range actions {
+		arr = append(arr, a.Action+" "+a.Target())
+	}
+	return strings.Join(arr, ", ")
+}
+
+func overdue(o bool) string {
+	if o {
+		return cli.Colorize(cli.RoleWarning, "true")
+	}
+	return "false"
+}
+
+// Colors the HAProxy status of a backend server (eg. UP, DOWN, MAINT, DRAIN)
+func serverStatus(status string) string {
+	switch {
+	case strings.HasPrefix(status, "UP"), status == "OPEN":
+		return cli.Colorize(cli.RoleHealthy, status)
+	case strings.HasPrefix(status, "DOWN"):
+		return cli.Colorize(cli.RoleUnhealthy, status)
+	case status == "MAINT", status == "DRAIN", status == "NOLB":
+		return cli.Colorize(cli.RoleWarning, status)
+	}
+	return status
+}
+
+func join(values []string) string {
+	return strings.Join(values, ",")
+}
+
 func hasDocker(c *marathon.Container) bool {
 	return c != nil && c.Docker != nil
 }
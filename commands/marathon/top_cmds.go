@@ -0,0 +1,561 @@
+package marathon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/tui"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	TOP_INTERVAL_FLAG = "interval"
+
+	// recent events kept by the dashboard
+	topEvents = 50
+	// rows shown for each of the deployments, queue and events
+	topSectionRows = 5
+	// widest application id column
+	topMaxIDWidth = 48
+)
+
+// Views of the dashboard
+const (
+	topModeApps    = "apps"
+	topModeInput   = "input"
+	topModeConfirm = "confirm"
+	topModeLogs    = "logs"
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Full-screen dashboard of applications, deployments, the queue and recent events",
+	Long: `Full-screen dashboard of the applications with their live instance and health counts, the active
+deployments, the launch queue and the most recent events.  The dashboard refreshes every --interval and
+whenever the event stream reports a change.
+
+Keys:
+    Up/Down, j/k, PgUp/PgDn   select an application
+    s                         scale the selected application
+    r                         restart the selected application
+    l                         view the logs of the selected application
+    q, Ctrl-C                 quit`,
+	Run: runTop,
+}
+
+func init() {
+	topCmd.Flags().Duration(TOP_INTERVAL_FLAG, 5*time.Second, "Interval between refreshes.  Changes reported by the event stream refresh immediately")
+}
+
+// state of the cluster displayed by the dashboard
+type topSnapshot struct {
+	apps        []marathon.Application
+	deployments []*marathon.Deploy
+	queue       []marathon.QueuedTask
+	fetched     time.Time
+	err         error
+}
+
+// dashboard is the state of the full-screen view rendered by render and changed by the keys pressed
+type dashboard struct {
+	env    string
+	client marathon.Marathon
+	// reads the recent logs of an application
+	logs func(id string) (string, error)
+	// how changes are noticed (events or polling)
+	source string
+
+	snapshot *topSnapshot
+	events   []string
+	selected int
+	mode     string
+	message  string
+
+	// prompt of the input and confirm modes and the action run with the input
+	prompt string
+	input  string
+	submit func(input string)
+
+	logTitle  string
+	logLines  []string
+	logOffset int
+
+	// action run once the dashboard has been drawn (eg. reading logs)
+	pending func()
+}
+
+func newDashboard(env string, client marathon.Marathon, logs func(id string) (string, error)) *dashboard {
+	return &dashboard{env: env, client: client, logs: logs, snapshot: &topSnapshot{}, mode: topModeApps, events: []string{}}
+}
+
+func runTop(cmd *cobra.Command, args []string) {
+	interval, _ := cmd.Flags().GetDuration(TOP_INTERVAL_FLAG)
+	if interval <= 0 {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s must be greater than zero", TOP_INTERVAL_FLAG)))
+	}
+	env := viper.GetString(ENV_NAME)
+	c := client(cmd)
+	d := newDashboard(env, c, func(id string) (string, error) {
+		host, err := mesosHost(configFile.Environments[env].Marathon)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		err = backend.NewMarathonBackend(c, host).Logs(id, &backend.LogOptions{}, &buf)
+		return buf.String(), err
+	})
+
+	ctx, cancel := context.WithCancel(cli.Context())
+	defer cancel()
+	d.source = "events"
+	events, err := c.EventStream(ctx, marathon.EventIDApplications|marathon.EventIDDeployments)
+	if err != nil {
+		log.Debug("Event stream unavailable, polling every %s: %s", interval, err.Error())
+		d.source = "polling"
+	}
+
+	screen, err := tui.Open()
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	// exiting would leave the terminal in raw mode so errors from here on are displayed by the dashboard
+	defer screen.Close()
+
+	snapshots := make(chan *topSnapshot, 1)
+	fetching := false
+	refresh := func() {
+		if !fetching {
+			fetching = true
+			go func() { snapshots <- fetchTop(c) }()
+		}
+	}
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	// changes reported by events are refreshed once no further events arrive within the debounce
+	var debounce <-chan time.Time
+
+	for {
+		screen.Draw(d.render(screen.Size()))
+		if d.pending != nil {
+			d.pending()
+			d.pending = nil
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case k, ok := <-screen.Keys():
+			if !ok || d.handleKey(k) {
+				return
+			}
+		case s := <-snapshots:
+			fetching = false
+			d.update(s)
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+				continue
+			}
+			d.addEvent(e, time.Now())
+			debounce = time.After(watchDebounce)
+		case <-debounce:
+			debounce = nil
+			refresh()
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// Reads the applications, deployments and queue of the cluster
+func fetchTop(c marathon.Marathon) *topSnapshot {
+	s := &topSnapshot{fetched: time.Now()}
+	apps, err := c.ListApplications()
+	if err != nil {
+		s.err = err
+		return s
+	}
+	s.apps = apps.Apps
+	sort.Slice(s.apps, func(i, j int) bool { return s.apps[i].ID < s.apps[j].ID })
+	if s.deployments, err = c.ListDeployments(); err != nil {
+		s.err = err
+		return s
+	}
+	queue, err := c.ListQueue()
+	if err != nil {
+		s.err = err
+		return s
+	}
+	s.queue = queue.Queue
+	return s
+}
+
+// Replaces the state displayed with {s} keeping the selected application.  A failed refresh keeps the
+// previous state along with the error
+func (d *dashboard) update(s *topSnapshot) {
+	if s.err != nil {
+		d.snapshot.err, d.snapshot.fetched = s.err, s.fetched
+		return
+	}
+	id := d.selectedID()
+	d.snapshot = s
+	d.selected = 0
+	for i, app := range s.apps {
+		if app.ID == id {
+			d.selected = i
+		}
+	}
+}
+
+// Adds event {e} received at {at} to the recent events
+func (d *dashboard) addEvent(e *marathon.Event, at time.Time) {
+	d.events = append([]string{summarizeEvent(e, at)}, d.events...)
+	if len(d.events) > topEvents {
+		d.events = d.events[:topEvents]
+	}
+}
+
+// Returns a line describing event {e} with the application, task status or deployment it concerns
+func summarizeEvent(e *marathon.Event, at time.Time) string {
+	fields := map[string]interface{}{}
+	if b, err := json.Marshal(e.Event); err == nil {
+		json.Unmarshal(b, &fields)
+	}
+	parts := []string{at.Format("15:04:05"), e.Name}
+	for _, key := range []string{"appId", "id", "taskStatus", "alive", "host", "message"} {
+		if v, ok := fields[key]; ok && v != "" && v != nil {
+			parts = append(parts, fmt.Sprint(v))
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+func (d *dashboard) selectedID() string {
+	if d.selected < len(d.snapshot.apps) {
+		return d.snapshot.apps[d.selected].ID
+	}
+	return ""
+}
+
+// Handles key {k} returning true when the dashboard is quit
+func (d *dashboard) handleKey(k tui.Key) bool {
+	if k.Is(tui.KeyCtrlC) {
+		return true
+	}
+	switch d.mode {
+	case topModeInput:
+		d.handleInput(k)
+	case topModeConfirm:
+		switch {
+		case k.Is("y") || k.Is("Y"):
+			d.mode = topModeApps
+			d.submit("y")
+		case k.Is("n") || k.Is("N") || k.Is(tui.KeyEscape) || k.Is(tui.KeyEnter):
+			d.mode, d.message = topModeApps, "Cancelled"
+		}
+	case topModeLogs:
+		switch {
+		case k.Is("q") || k.Is(tui.KeyEscape):
+			d.mode, d.logLines = topModeApps, nil
+		default:
+			d.logOffset = scroll(k, d.logOffset, len(d.logLines))
+		}
+	default:
+		return d.handleAppKey(k)
+	}
+	return false
+}
+
+func (d *dashboard) handleAppKey(k tui.Key) bool {
+	id := d.selectedID()
+	switch {
+	case k.Is("q"):
+		return true
+	case k.Is("s") && id != "":
+		current := strconv.Itoa(d.snapshot.apps[d.selected].Instances)
+		d.ask(fmt.Sprintf("Scale %s to instances: ", id), current, func(input string) {
+			instances, err := strconv.Atoi(strings.TrimSpace(input))
+			if err != nil || instances < 0 {
+				d.message = fmt.Sprintf("'%s' is not a number of instances", input)
+				return
+			}
+			scale := func(string) {
+				v, err := d.client.ScaleApplication(id, instances)
+				if !d.failed(err) {
+					d.message = fmt.Sprintf("Scaling %s to %d instances (deployment %s)", id, instances, v.DeploymentID)
+				}
+			}
+			if instances == 0 {
+				d.confirm(fmt.Sprintf("Scale %s to 0 instances? (y/n)", id), scale)
+				return
+			}
+			scale(input)
+		})
+	case k.Is("r") && id != "":
+		d.confirm(fmt.Sprintf("Restart %s? (y/n)", id), func(string) {
+			v, err := d.client.RestartApplication(id, false)
+			if !d.failed(err) {
+				d.message = fmt.Sprintf("Restarting %s (deployment %s)", id, v.DeploymentID)
+			}
+		})
+	case k.Is("l") && id != "":
+		d.message = fmt.Sprintf("Reading the logs of %s...", id)
+		d.pending = func() {
+			logs, err := d.logs(id)
+			if err != nil {
+				d.message = fmt.Sprintf("Unable to read the logs of %s: %s", id, err.Error())
+				return
+			}
+			d.mode, d.message, d.logTitle = topModeLogs, "", "Logs of "+id
+			d.logLines = strings.Split(strings.TrimRight(logs, "\n"), "\n")
+			// the most recent lines are shown first
+			d.logOffset = len(d.logLines) - 1
+		}
+	default:
+		d.selected = scroll(k, d.selected, len(d.snapshot.apps))
+	}
+	return false
+}
+
+func (d *dashboard) handleInput(k tui.Key) {
+	switch {
+	case k.Is(tui.KeyEscape):
+		d.mode, d.message = topModeApps, "Cancelled"
+	case k.Is(tui.KeyEnter):
+		d.mode = topModeApps
+		d.submit(d.input)
+	case k.Is(tui.KeyBackspace):
+		if r := []rune(d.input); len(r) > 0 {
+			d.input = string(r[:len(r)-1])
+		}
+	case k.Name == "":
+		d.input += string(k.Rune)
+	}
+}
+
+// Prompts for input with {prompt} starting from {initial} running {submit} with the input once entered
+func (d *dashboard) ask(prompt, initial string, submit func(input string)) {
+	d.mode, d.prompt, d.input, d.submit, d.message = topModeInput, prompt, initial, submit, ""
+}
+
+// Asks for confirmation with {prompt} running {submit} once confirmed
+func (d *dashboard) confirm(prompt string, submit func(input string)) {
+	d.mode, d.prompt, d.submit, d.message = topModeConfirm, prompt, submit, ""
+}
+
+// Displays {err} of an action returning true if it failed
+func (d *dashboard) failed(err error) bool {
+	if err != nil {
+		d.message = "Error: " + err.Error()
+	}
+	return err != nil
+}
+
+// Returns position {pos} within {count} rows moved by key {k}
+func scroll(k tui.Key, pos, count int) int {
+	switch {
+	case k.Is(tui.KeyUp) || k.Is("k"):
+		pos--
+	case k.Is(tui.KeyDown) || k.Is("j"):
+		pos++
+	case k.Is(tui.KeyPageUp):
+		pos -= 10
+	case k.Is(tui.KeyPageDown):
+		pos += 10
+	case k.Is(tui.KeyHome) || k.Is("g"):
+		pos = 0
+	case k.Is(tui.KeyEnd) || k.Is("G"):
+		pos = count - 1
+	}
+	if pos >= count {
+		pos = count - 1
+	}
+	if pos < 0 {
+		pos = 0
+	}
+	return pos
+}
+
+// Returns the lines of the dashboard for a terminal {width} by {height}
+func (d *dashboard) render(width, height int) []string {
+	s := d.snapshot
+	deploying := 0
+	for _, app := range s.apps {
+		if len(app.DeploymentID) > 0 {
+			deploying++
+		}
+	}
+	updated := "loading"
+	if !s.fetched.IsZero() {
+		updated = s.fetched.Format("15:04:05")
+	}
+	lines := []string{
+		cli.Paint(cli.RoleHeader, fmt.Sprintf("depcon top - %s - %s (%s)   apps: %d  deploying: %d  deployments: %d  queued: %d",
+			d.env, updated, d.source, len(s.apps), deploying, len(s.deployments), len(s.queue))),
+	}
+	switch {
+	case d.message != "":
+		lines = append(lines, d.message)
+	case s.err != nil:
+		lines = append(lines, cli.Paint(cli.RoleUnhealthy, "Error: "+s.err.Error()))
+	default:
+		lines = append(lines, "")
+	}
+
+	if d.mode == topModeLogs {
+		return append(lines, d.renderLogs(height-len(lines)-1)...)
+	}
+
+	sections := [][]string{
+		section("DEPLOYMENTS", d.deploymentRows()),
+		section("QUEUE", d.queueRows()),
+		section("EVENTS", d.events),
+	}
+	rows := height - len(lines) - 2
+	for _, sec := range sections {
+		rows -= len(sec)
+	}
+	if rows < 3 {
+		rows = 3
+	}
+	lines = append(lines, d.appRows(rows)...)
+	for _, sec := range sections {
+		lines = append(lines, sec...)
+	}
+	return append(lines, d.footer())
+}
+
+// Returns the header and {rows} rows of applications scrolled so the selected application is shown
+func (d *dashboard) appRows(rows int) []string {
+	apps := d.snapshot.apps
+	idWidth := len("ID")
+	for _, app := range apps {
+		if len(app.ID) > idWidth {
+			idWidth = len(app.ID)
+		}
+	}
+	if idWidth > topMaxIDWidth {
+		idWidth = topMaxIDWidth
+	}
+	lines := []string{cli.Paint(cli.RoleHeader, fmt.Sprintf("%-*s  %9s  %7s  %6s  %9s  %s", idWidth, "ID", "INSTANCES", "HEALTHY", "STAGED", "UNHEALTHY", "STATUS"))}
+
+	first := 0
+	if d.selected >= rows {
+		first = d.selected - rows + 1
+	}
+	for i := first; i < len(apps) && i < first+rows; i++ {
+		app := apps[i]
+		status := appStatus(&app)
+		row := fmt.Sprintf("%-*s  %9s  %7d  %6d  %9d  ", idWidth, app.ID, fmt.Sprintf("%d/%d", app.TasksRunning, app.Instances),
+			app.TasksHealthy, app.TasksStaged, app.TasksUnHealthy)
+		if i == d.selected {
+			lines = append(lines, tui.Reverse(row+status))
+		} else {
+			lines = append(lines, row+cli.Paint(cli.StatusRole(status), status))
+		}
+	}
+	for len(lines) <= rows {
+		lines = append(lines, "")
+	}
+	return lines
+}
+
+// Returns the status of {app}: deploying, suspended, unhealthy, waiting (for instances) or healthy
+func appStatus(app *marathon.Application) string {
+	switch {
+	case len(app.DeploymentID) > 0:
+		return "deploying"
+	case app.Instances == 0:
+		return "suspended"
+	case app.TasksUnHealthy > 0:
+		return "unhealthy"
+	case app.TasksRunning < app.Instances:
+		return "waiting"
+	}
+	return "healthy"
+}
+
+func (d *dashboard) deploymentRows() []string {
+	rows := []string{}
+	for _, dep := range d.snapshot.deployments {
+		actions := []string{}
+		for _, a := range dep.CurrentActions {
+			actions = append(actions, strings.TrimSpace(a.Action+" "+a.App+a.Pod))
+		}
+		rows = append(rows, fmt.Sprintf("%s  step %d/%d  %s", dep.DeployID, dep.CurrentStep, dep.TotalSteps, strings.Join(actions, ", ")))
+	}
+	return rows
+}
+
+func (d *dashboard) queueRows() []string {
+	rows := []string{}
+	for _, q := range d.snapshot.queue {
+		id := ""
+		if q.App != nil {
+			id = q.App.ID
+		}
+		row := fmt.Sprintf("%s  waiting: %d", id, q.Count)
+		if q.Delay.Overdue {
+			row += "  " + cli.Paint(cli.RoleWarning, "overdue")
+		} else if q.Delay.TimeLeftSeconds > 0 {
+			row += fmt.Sprintf("  delayed %ds", q.Delay.TimeLeftSeconds)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// Returns the {title} followed by at most topSectionRows of {rows}
+func section(title string, rows []string) []string {
+	lines := []string{cli.Paint(cli.RoleHeader, fmt.Sprintf("%s (%d)", title, len(rows)))}
+	if len(rows) == 0 {
+		return append(lines, "  none")
+	}
+	for i, row := range rows {
+		if i == topSectionRows {
+			return append(lines, fmt.Sprintf("  ... %d more", len(rows)-i))
+		}
+		lines = append(lines, "  "+row)
+	}
+	return lines
+}
+
+// Returns {rows} lines of the logs ending at the scroll offset
+func (d *dashboard) renderLogs(rows int) []string {
+	lines := []string{cli.Paint(cli.RoleHeader, d.logTitle)}
+	rows--
+	end := d.logOffset + 1
+	if end > len(d.logLines) {
+		end = len(d.logLines)
+	}
+	start := end - rows
+	if start < 0 {
+		start = 0
+	}
+	lines = append(lines, d.logLines[start:end]...)
+	for len(lines) <= rows {
+		lines = append(lines, "")
+	}
+	return append(lines, "Up/Down PgUp/PgDn scroll   q/Esc back")
+}
+
+func (d *dashboard) footer() string {
+	switch d.mode {
+	case topModeInput:
+		return d.prompt + d.input + "_   (Enter to apply, Esc to cancel)"
+	case topModeConfirm:
+		return d.prompt
+	}
+	return "Up/Down select   s scale   r restart   l logs   q quit"
+}
@@ -0,0 +1,130 @@
+package marathon
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathontest"
+	"github.com/ContainX/depcon/pkg/tui"
+	"github.com/stretchr/testify/assert"
+)
+
+func testDashboard() (*dashboard, *marathontest.Fake) {
+	fake := marathontest.New().WithApps(
+		&marathon.Application{ID: "/web", Instances: 3},
+		&marathon.Application{ID: "/api", Instances: 2},
+		&marathon.Application{ID: "/batch", Instances: 0},
+	)
+	fake.Queue.Queue = append(fake.Queue.Queue, marathon.QueuedTask{App: &marathon.Application{ID: "/api"}, Count: 1, Delay: marathon.QueueDelay{Overdue: true}})
+	d := newDashboard("prod", fake, func(id string) (string, error) {
+		if id == "/batch" {
+			return "", errors.New("no instances")
+		}
+		return "line 1\nline 2\nline 3\n", nil
+	})
+	d.source = "events"
+	d.update(fetchTop(fake))
+	return d, fake
+}
+
+func keys(d *dashboard, input string) bool {
+	for _, k := range tui.ParseKeys([]byte(input)) {
+		if d.handleKey(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTopRender(t *testing.T) {
+	d, _ := testDashboard()
+	d.addEvent(&marathon.Event{Name: "status_update_event", Event: &marathon.EventStatusUpdate{AppID: "/web", TaskStatus: "TASK_RUNNING"}}, time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC))
+
+	lines := d.render(100, 30)
+	assert.Len(t, lines, 30)
+	assert.Contains(t, lines[0], "depcon top - prod")
+	assert.Contains(t, lines[0], "apps: 3")
+	assert.Contains(t, lines[0], "queued: 1")
+	screen := strings.Join(lines, "\n")
+	assert.Contains(t, screen, "/api    ")
+	assert.Contains(t, screen, "suspended")
+	assert.Contains(t, screen, "QUEUE (1)\n  /api  waiting: 1  overdue")
+	assert.Contains(t, screen, "DEPLOYMENTS (0)\n  none")
+	assert.Contains(t, screen, "EVENTS (1)\n  10:00:00  status_update_event  /web  TASK_RUNNING")
+	// apps are sorted and the first is selected
+	assert.Contains(t, lines[3], "\x1b[7m/api")
+	assert.Equal(t, "Up/Down select   s scale   r restart   l logs   q quit", lines[29])
+}
+
+func TestTopSelectionKeptOnRefresh(t *testing.T) {
+	d, fake := testDashboard()
+	keys(d, "jj")
+	assert.Equal(t, "/web", d.selectedID())
+	keys(d, "j")
+	assert.Equal(t, "/web", d.selectedID(), "selection stops at the last app")
+
+	fake.WithApps(&marathon.Application{ID: "/cache", Instances: 1})
+	d.update(fetchTop(fake))
+	assert.Equal(t, "/web", d.selectedID())
+
+	fake.Errors["ListApplications"] = errors.New("unreachable")
+	d.update(fetchTop(fake))
+	assert.Len(t, d.snapshot.apps, 4, "a failed refresh keeps the previous state")
+	assert.Contains(t, d.render(100, 30)[1], "Error: unreachable")
+}
+
+func TestTopScaleAndRestart(t *testing.T) {
+	d, fake := testDashboard()
+	keys(d, "s")
+	assert.Equal(t, topModeInput, d.mode)
+	assert.Contains(t, d.render(100, 30)[29], "Scale /api to instances: 2_")
+	keys(d, "\x7f5\r")
+	app, _ := fake.GetApplication("/api")
+	assert.Equal(t, 5, app.Instances)
+	assert.Contains(t, d.message, "Scaling /api to 5 instances")
+
+	keys(d, "s\x7f0\r")
+	assert.Equal(t, topModeConfirm, d.mode, "scaling to 0 is confirmed")
+	keys(d, "n")
+	app, _ = fake.GetApplication("/api")
+	assert.Equal(t, 5, app.Instances)
+	assert.Equal(t, "Cancelled", d.message)
+
+	// instances start from the last refresh
+	keys(d, "sx\r")
+	assert.Equal(t, "'2x' is not a number of instances", d.message)
+
+	keys(d, "r")
+	assert.Equal(t, "Restart /api? (y/n)", d.render(100, 30)[29])
+	keys(d, "y")
+	assert.True(t, fake.Called("RestartApplication"))
+	assert.Contains(t, d.message, "Restarting /api")
+
+	fake.Errors["RestartApplication"] = errors.New("frozen")
+	keys(d, "ry")
+	assert.Equal(t, "Error: frozen", d.message)
+}
+
+func TestTopLogs(t *testing.T) {
+	d, _ := testDashboard()
+	keys(d, "l")
+	assert.Equal(t, "Reading the logs of /api...", d.message)
+	d.pending()
+	assert.Equal(t, topModeLogs, d.mode)
+	lines := d.render(80, 6)
+	assert.Equal(t, []string{"Logs of /api", "line 2", "line 3", "Up/Down PgUp/PgDn scroll   q/Esc back"}, lines[2:])
+	keys(d, "k")
+	assert.Equal(t, "line 1", d.render(80, 6)[3])
+	keys(d, "q")
+	assert.Equal(t, topModeApps, d.mode)
+	assert.False(t, keys(d, "\x1b"), "escape doesn't quit the dashboard")
+
+	keys(d, "jl")
+	d.pending()
+	assert.Equal(t, topModeApps, d.mode)
+	assert.Equal(t, "Unable to read the logs of /batch: no instances", d.message)
+	assert.True(t, keys(d, "q"))
+}
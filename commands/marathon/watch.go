@@ -0,0 +1,91 @@
+package marathon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	WATCH_FLAG          = "watch"
+	WATCH_INTERVAL_FLAG = "watch-interval"
+	// events arriving within this window of each other result in a single refresh
+	watchDebounce = 250 * time.Millisecond
+)
+
+func init() {
+	applyWatchFlags(deployListCmd, appGetCmd, groupGetCmd)
+}
+
+// Adds the --watch flags to {cmds}
+func applyWatchFlags(cmds ...*cobra.Command) {
+	for _, c := range cmds {
+		c.Flags().Bool(WATCH_FLAG, false, "Re-renders the result as it changes until interrupted (Ctrl-C)")
+		c.Flags().Duration(WATCH_INTERVAL_FLAG, 5*time.Second, "Interval between refreshes with --watch.  Changes reported by the event stream refresh immediately")
+	}
+}
+
+// Outputs the result of {fetch}.  With --watch the result is re-rendered whenever an event matching
+// {filter} is received from the event stream and at least every --watch-interval.  Errors while
+// watching are displayed in place of the result rather than ending the watch
+func outputOrWatch(cmd *cobra.Command, filter int, fetch func() (cli.Formatter, error)) {
+	if watching, _ := cmd.Flags().GetBool(WATCH_FLAG); !watching {
+		cli.Output(fetch())
+		return
+	}
+	interval, _ := cmd.Flags().GetDuration(WATCH_INTERVAL_FLAG)
+	if interval <= 0 {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s must be greater than zero", WATCH_INTERVAL_FLAG)))
+	}
+
+	// each refresh replaces the screen so the output is never paged
+	cli.EnablePager(false)
+
+	ctx, cancel := context.WithCancel(cli.Context())
+	defer cancel()
+	mode := "events"
+	events, err := client(cmd).EventStream(ctx, filter)
+	if err != nil {
+		log.Debug("Event stream unavailable, polling every %s: %s", interval, err.Error())
+		mode = "polling"
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		f, err := fetch()
+		cli.ClearScreen(os.Stdout)
+		fmt.Printf("Watching %s every %s (%s) - %s\n\n", viper.GetString(ENV_NAME), interval, mode, time.Now().Format("15:04:05"))
+		if err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+		} else {
+			cli.Output(f, nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-events:
+			drainEvents(events, watchDebounce)
+		}
+	}
+}
+
+// Discards further events received within {window}
+func drainEvents(events <-chan *marathon.Event, window time.Duration) {
+	timeout := time.After(window)
+	for {
+		select {
+		case <-events:
+		case <-timeout:
+			return
+		}
+	}
+}
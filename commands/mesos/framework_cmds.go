@@ -0,0 +1,76 @@
+package mesos
+
+import (
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	COMPLETED_FLAG string = "completed"
+	FRAMEWORK_FLAG string = "framework"
+)
+
+var (
+	frameworksCmd = &cobra.Command{
+		Use:   "frameworks",
+		Short: "Lists the frameworks registered with the Mesos master",
+		Run: func(cmd *cobra.Command, args []string) {
+			completed, _ := cmd.Flags().GetBool(COMPLETED_FLAG)
+			v, e := client(cmd).ListFrameworks(completed)
+			cli.Output(templateFor(T_FRAMEWORKS, v), e)
+		},
+	}
+
+	reconcileCmd = &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compares the tasks Marathon knows with the tasks Mesos runs for Marathon",
+		Long: `Compares the tasks Marathon knows with the tasks Mesos runs for the Marathon framework
+
+    Tasks running in Mesos which Marathon does not know and tasks Marathon believes are
+    running which Mesos does not know are listed.  The command fails when any are found`,
+		Run: reconcile,
+	}
+)
+
+func init() {
+	frameworksCmd.Flags().Bool(COMPLETED_FLAG, false, "Includes completed frameworks")
+	reconcileCmd.Flags().String(FRAMEWORK_FLAG, "marathon", "Name of the Marathon framework within Mesos")
+}
+
+func reconcile(cmd *cobra.Command, args []string) {
+	name, _ := cmd.Flags().GetString(FRAMEWORK_FLAG)
+	framework, err := client(cmd).FindFramework(name)
+	if err != nil {
+		exitWithError(err)
+	}
+	tasks, err := client(cmd).ListTasks()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	insecure, _ := cmd.Flags().GetBool(INSECURE_FLAG)
+	envName := viper.GetString(ENV_NAME)
+	mc, err := cmdmarathon.NewClient(envName, environmentService(envName), &marathon.MarathonOptions{TLSAllowInsecure: insecure})
+	if err != nil {
+		exitWithError(err)
+	}
+	known, err := mc.ListTasks()
+	if err != nil {
+		exitWithError(err)
+	}
+	ids := []string{}
+	for _, t := range known {
+		ids = append(ids, t.ID)
+	}
+
+	discrepancies := mesos.Reconcile(tasks, framework.ID(), ids)
+	cli.Output(templateFor(T_DISCREPANCIES, discrepancies), nil)
+	if len(discrepancies) > 0 {
+		log.Warning("%d task(s) are not reconciled between Marathon and Mesos", len(discrepancies))
+		cli.Exit(mesos.ErrorNotReconciled)
+	}
+}
@@ -0,0 +1,149 @@
+package mesos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	START_FLAG    string = "start"
+	DURATION_FLAG string = "duration"
+)
+
+const machinesHelp = `
+
+    Machines are given by hostname or as hostname=ip when the agents register by IP address`
+
+var (
+	maintenanceCmd = &cobra.Command{
+		Use:   "maintenance",
+		Short: "Schedule and perform maintenance of agents",
+		Long: `Schedule and perform maintenance of agents
+
+    Machines are first scheduled for maintenance so frameworks may drain them.  'start' marks
+    the machines down (killing their remaining tasks) and 'stop' brings them back up`,
+	}
+
+	maintenanceStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Lists the maintenance schedule with the draining and down machines",
+		Run:   maintenanceStatus,
+	}
+
+	maintenanceScheduleCmd = &cobra.Command{
+		Use:   "schedule [machine] [machine...]",
+		Short: "Schedules maintenance of machines",
+		Long: "Schedules maintenance of machines replacing any maintenance they are scheduled for" + machinesHelp + `
+
+    eg. depcon mesos maintenance schedule agent1=10.0.0.1 --start 2017-06-01T02:00:00Z --duration 2h`,
+		Run: scheduleMaintenance,
+	}
+
+	maintenanceCancelCmd = &cobra.Command{
+		Use:   "cancel [machine] [machine...]",
+		Short: "Removes machines from the maintenance schedule",
+		Long:  "Removes machines from the maintenance schedule" + machinesHelp,
+		Run:   cancelMaintenance,
+	}
+
+	maintenanceStartCmd = &cobra.Command{
+		Use:   "start [machine] [machine...]",
+		Short: "Marks scheduled machines down killing their tasks",
+		Long:  "Marks scheduled machines down killing their tasks" + machinesHelp,
+		Run: func(cmd *cobra.Command, args []string) {
+			updateMachines(cmd, args, "Start maintenance of", "down", client(cmd).StartMaintenance)
+		},
+	}
+
+	maintenanceStopCmd = &cobra.Command{
+		Use:   "stop [machine] [machine...]",
+		Short: "Brings machines back up after maintenance",
+		Long:  "Brings machines back up after maintenance" + machinesHelp,
+		Run: func(cmd *cobra.Command, args []string) {
+			updateMachines(cmd, args, "Stop maintenance of", "up", client(cmd).StopMaintenance)
+		},
+	}
+)
+
+func init() {
+	maintenanceScheduleCmd.Flags().String(START_FLAG, "", "Optional: start of the maintenance as RFC3339 (eg. 2017-06-01T02:00:00Z). Default: now")
+	maintenanceScheduleCmd.Flags().Duration(DURATION_FLAG, time.Hour, "Expected duration of the maintenance (ex. 90m | 2h)")
+	maintenanceCmd.AddCommand(maintenanceStatusCmd, maintenanceScheduleCmd, maintenanceCancelCmd, maintenanceStartCmd, maintenanceStopCmd)
+}
+
+// Maintenance represents the schedule and status of the cluster's maintenance
+type Maintenance struct {
+	Schedule *mesos.Schedule
+	Status   *mesos.MaintenanceStatus
+}
+
+func maintenanceStatus(cmd *cobra.Command, args []string) {
+	schedule, err := client(cmd).GetMaintenanceSchedule()
+	if err != nil {
+		exitWithError(err)
+	}
+	status, err := client(cmd).GetMaintenanceStatus()
+	cli.Output(templateFor(T_MAINTENANCE, &Maintenance{Schedule: schedule, Status: status}), err)
+}
+
+func scheduleMaintenance(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	start := time.Now()
+	if s, _ := cmd.Flags().GetString(START_FLAG); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Invalid --%s '%s': %s", START_FLAG, s, err.Error())))
+		}
+		start = t
+	}
+	duration, _ := cmd.Flags().GetDuration(DURATION_FLAG)
+
+	schedule, err := client(cmd).ScheduleMaintenance(parseMachines(args), start, duration)
+	cli.Output(templateFor(T_SCHEDULE, schedule), err)
+}
+
+func cancelMaintenance(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	cli.ConfirmOrExit(fmt.Sprintf("Cancel maintenance of %s", strings.Join(args, ", ")), "environment", viper.GetString(ENV_NAME))
+	schedule, err := client(cmd).CancelMaintenance(parseMachines(args))
+	cli.Output(templateFor(T_SCHEDULE, schedule), err)
+}
+
+// Confirms {action} and applies {fn} to the machines within {args} which are then {state}
+func updateMachines(cmd *cobra.Command, args []string, action, state string, fn func([]*mesos.MachineID) error) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	cli.ConfirmOrExit(fmt.Sprintf("%s %s", action, strings.Join(args, ", ")), "environment", viper.GetString(ENV_NAME))
+	if err := fn(parseMachines(args)); err != nil {
+		exitWithError(err)
+	}
+	log.Info("Machine(s) %s are %s", strings.Join(args, ", "), state)
+}
+
+// Parses machines given as hostname or hostname=ip
+func parseMachines(args []string) []*mesos.MachineID {
+	machines := []*mesos.MachineID{}
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		m := &mesos.MachineID{Hostname: parts[0]}
+		if len(parts) > 1 {
+			m.IP = parts[1]
+		}
+		machines = append(machines, m)
+	}
+	return machines
+}
@@ -0,0 +1,87 @@
+package mesos
+
+import (
+	"fmt"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	ENV_NAME         string = "env_name"
+)
+
+var log = logger.GetLogger("depcon.mesos")
+
+var (
+	mesosCmd = &cobra.Command{
+		Use:   "mesos",
+		Short: "Query and operate the Mesos master of the cluster",
+		Long: `Query and operate the Mesos master of the cluster (eg. frameworks, agent maintenance and
+    task reconciliation) through the operator API
+
+    Mesos is reached with the credentials of the environment's Marathon service.  DC/OS
+    environments use the cluster's /mesos and other environments the Marathon host on port
+    5050 unless the environment specifies a Mesos URL (depcon config env update [name] --mesos URL)
+
+    See mesos's subcommands for available choices`,
+	}
+	mesosClient mesos.Mesos
+	configFile  *cliconfig.ConfigFile
+)
+
+// Associates the mesos commands to the given command
+func AddMesosToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
+	rc.AddCommand(mesosCmd)
+}
+
+func init() {
+	mesosCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	mesosCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	mesosCmd.AddCommand(frameworksCmd, maintenanceCmd, reconcileCmd)
+}
+
+func client(cmd *cobra.Command) mesos.Mesos {
+	if mesosClient == nil {
+		envName := viper.GetString(ENV_NAME)
+		insecure, _ := cmd.Flags().GetBool(INSECURE_FLAG)
+		allowWrite, _ := cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+		c, err := cmdmarathon.NewMesosClient(envName, environmentService(envName), insecure, allowWrite)
+		if err != nil {
+			exitWithError(err)
+		}
+		mesosClient = c
+	}
+	return mesosClient
+}
+
+// Returns the Marathon service of environment {envName} whose credentials are used for Mesos
+func environmentService(envName string) *cliconfig.ServiceConfig {
+	env, err := configFile.GetEnvironment(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+	if env.Marathon == nil {
+		exitWithError(fmt.Errorf("Environment '%s' does not define a Marathon service", envName))
+	}
+	return env.Marathon
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
@@ -0,0 +1,93 @@
+package mesos
+
+import (
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_FRAMEWORKS = `
+{{ "ID" | header }}	{{ "NAME" | header }}	{{ "ROLE" | header }}	{{ "ACTIVE" | header }}	{{ "CONNECTED" | header }}	{{ "COMPLETED" | header }}
+{{ range . }}{{ .ID }}	{{ .Info.Name }}	{{ .Info.Role }}	{{ .Active | boolToYesNo }}	{{ .Connected | boolToYesNo }}	{{ .Completed | boolToYesNo }}
+{{end}}`
+
+	T_SCHEDULE = `
+{{ "MACHINES" | header }}	{{ "START" | header }}	{{ "DURATION" | header }}
+{{ range .Windows }}{{ .MachineIDs | machines }}	{{ .Unavailability | start }}	{{ .Unavailability | duration }}
+{{end}}`
+
+	T_MAINTENANCE = `
+{{ "MACHINES" | header }}	{{ "START" | header }}	{{ "DURATION" | header }}
+{{ range .Schedule.Windows }}{{ .MachineIDs | machines }}	{{ .Unavailability | start }}	{{ .Unavailability | duration }}
+{{end}}{{ with .Status }}
+{{ "Draining:" }}	{{ .DrainingMachines | draining }}
+{{ "Down:" }}	{{ .DownMachines | machines }}
+{{end}}`
+
+	T_DISCREPANCIES = `
+{{ "TASK" | header }}	{{ "NAME" | header }}	{{ "STATE" | header }}	{{ "ISSUE" | header }}
+{{ range . }}{{ .TaskID }}	{{ .Name }}	{{ .State }}	{{ .Issue }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data, Funcs: buildFuncMap()}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
+
+func buildFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"machines": machines,
+		"draining": draining,
+		"start":    start,
+		"duration": duration,
+	}
+}
+
+func machines(ids []*mesos.MachineID) string {
+	names := []string{}
+	for _, id := range ids {
+		names = append(names, id.String())
+	}
+	return strings.Join(names, ",")
+}
+
+func draining(dms []*mesos.DrainingMachine) string {
+	ids := []*mesos.MachineID{}
+	for _, dm := range dms {
+		if dm.ID != nil {
+			ids = append(ids, dm.ID)
+		}
+	}
+	return machines(ids)
+}
+
+func start(u *mesos.Unavailability) string {
+	if u == nil || u.Start == nil {
+		return ""
+	}
+	return time.Unix(0, u.Start.Nanoseconds).UTC().Format(time.RFC3339)
+}
+
+func duration(u *mesos.Unavailability) string {
+	if u == nil || u.Duration == nil {
+		return ""
+	}
+	return time.Duration(u.Duration.Nanoseconds).String()
+}
@@ -0,0 +1,272 @@
+package metronome
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/metronome"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	ml "github.com/ContainX/go-mesoslog/mesoslog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	WAIT_FLAG      string = "wait"
+	TIMEOUT_FLAG   string = "wait-timeout"
+	FORCE_FLAG     string = "force"
+	STOP_RUNS_FLAG string = "stop-runs"
+	STDERR_FLAG    string = "stderr"
+	FOLLOW_FLAG    string = "follow"
+	POLL_FLAG      string = "poll"
+)
+
+var log = logger.GetLogger("depcon.metronome")
+
+const descriptorHelp = `
+
+    The descriptor is rendered with the template context and ${PARAMS} exactly as Marathon
+    descriptors are.  Each document is a Metronome job which may list its schedules under
+    'schedules'.  Schedules are created or replaced after the job.  YAML descriptors may
+    contain multiple documents separated by '---' which are processed in order`
+
+var (
+	jobListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists all jobs with their schedules and last run",
+		Run: func(cmd *cobra.Command, args []string) {
+			v, e := client(cmd).ListJobs()
+			cli.Output(templateFor(T_JOBS, v), e)
+		},
+	}
+
+	jobGetCmd = &cobra.Command{
+		Use:   "get [id]",
+		Short: "Gets a job by id with its schedules, active runs and history",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).GetJob(args[0])
+			cli.Output(templateFor(T_JOB, v), e)
+		},
+	}
+
+	jobCreateCmd = &cobra.Command{
+		Use:   "create [file(.json | .yaml)]",
+		Short: "Creates the jobs within a descriptor",
+		Long:  "Creates the jobs within a descriptor.  With --force jobs which exist are updated" + descriptorHelp,
+		Run: func(cmd *cobra.Command, args []string) {
+			force, _ := cmd.Flags().GetBool(FORCE_FLAG)
+			applyJobs(cmd, args, force)
+		},
+	}
+
+	jobUpdateCmd = &cobra.Command{
+		Use:   "update [file(.json | .yaml)]",
+		Short: "Creates or updates the jobs within a descriptor",
+		Long:  "Creates or updates the jobs within a descriptor" + descriptorHelp,
+		Run: func(cmd *cobra.Command, args []string) {
+			applyJobs(cmd, args, true)
+		},
+	}
+
+	jobDestroyCmd = &cobra.Command{
+		Use:   "destroy [id]",
+		Short: "Removes a job and its schedules",
+		Run:   destroyJob,
+	}
+
+	jobRunCmd = &cobra.Command{
+		Use:   "run [id]",
+		Short: "Starts a run of a job now",
+		Run:   startRun,
+	}
+
+	jobRunsCmd = &cobra.Command{
+		Use:   "runs [id]",
+		Short: "Lists the active and finished runs of a job",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).ListRuns(args[0])
+			cli.Output(templateFor(T_RUNS, v), e)
+		},
+	}
+
+	jobStopCmd = &cobra.Command{
+		Use:   "stop [id] [runId]",
+		Short: "Stops an active run of a job",
+		Run:   stopRun,
+	}
+
+	jobLogCmd = &cobra.Command{
+		Use:   "log [id] [runId]",
+		Short: "Log or Tail the Mesos logs of a run",
+		Long:  "Log or Tail the Mesos logs of an active run.  The latest active run is used when runId is omitted",
+		Run:   showRunLog,
+	}
+)
+
+func init() {
+	jobCreateCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Updates jobs which already exist")
+	marathon.ApplyDescriptorFlags(jobCreateCmd)
+	marathon.ApplyDescriptorFlags(jobUpdateCmd)
+	jobDestroyCmd.Flags().Bool(STOP_RUNS_FLAG, false, "Stops active runs rather than failing while runs are active")
+	jobRunCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the run to finish and fail if the run fails")
+	jobRunCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the run to finish (ex. 90s | 2m)")
+	jobLogCmd.Flags().BoolP(STDERR_FLAG, "s", false, "Show StdErr vs default StdOut log")
+	jobLogCmd.Flags().BoolP(FOLLOW_FLAG, "f", false, "Tail/Follow log")
+	jobLogCmd.Flags().IntP(POLL_FLAG, "p", 5, "Log poll time (duration) in seconds")
+}
+
+func applyJobs(cmd *cobra.Command, args []string, force bool) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	results := []*metronome.ApplyResult{}
+	for _, doc := range marathon.RenderDocuments(cmd, args[0]) {
+		result, err := client(cmd).ApplyJob(doc, force)
+		if err != nil {
+			id, _ := doc["id"].(string)
+			log.Error("Unable to apply job '%s'", id)
+			exitWithError(err)
+		}
+		results = append(results, result)
+	}
+	cli.Output(templateFor(T_APPLY, results), nil)
+}
+
+func destroyJob(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	stopRuns, _ := cmd.Flags().GetBool(STOP_RUNS_FLAG)
+	cli.ConfirmOrExit(fmt.Sprintf("Destroy job '%s'", args[0]), "environment", viper.GetString(ENV_NAME))
+	if err := client(cmd).DestroyJob(args[0], stopRuns); err != nil {
+		exitWithError(err)
+	}
+	log.Info("Job '%s' was destroyed", args[0])
+}
+
+func startRun(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	run, err := client(cmd).StartRun(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	if wait, _ := cmd.Flags().GetBool(WAIT_FLAG); wait {
+		if err := client(cmd).WaitForRun(args[0], run.ID, waitTimeout(cmd)); err != nil {
+			exitWithError(err)
+		}
+		if finished, err := client(cmd).ListRuns(args[0]); err == nil {
+			for _, r := range finished {
+				if r.ID == run.ID {
+					run = r
+				}
+			}
+		}
+	}
+	cli.Output(templateFor(T_RUNS, []*metronome.Run{run}), nil)
+}
+
+func stopRun(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+		return
+	}
+
+	cli.ConfirmOrExit(fmt.Sprintf("Stop run '%s' of job '%s'", args[1], args[0]), "environment", viper.GetString(ENV_NAME))
+	if err := client(cmd).StopRun(args[0], args[1]); err != nil {
+		exitWithError(err)
+	}
+	log.Info("Run '%s' of job '%s' was stopped", args[1], args[0])
+}
+
+func showRunLog(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	taskID := runTaskID(cmd, args)
+	logType := ml.STDOUT
+	if stderr, _ := cmd.Flags().GetBool(STDERR_FLAG); stderr {
+		logType = ml.STDERR
+	}
+
+	c, _ := ml.NewMesosClient(mesosHost(), 5050)
+	name, err := c.GetAppNameForTaskID(taskID)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if follow, _ := cmd.Flags().GetBool(FOLLOW_FLAG); follow {
+		duration, _ := cmd.Flags().GetInt(POLL_FLAG)
+		if duration < 1 {
+			duration = 5
+		}
+		if err := c.TailLog(name, logType, duration); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	logs, err := c.GetLog(name, logType, "")
+	if err != nil {
+		exitWithError(err)
+	}
+
+	out, done := cli.StartPager(os.Stdout)
+	defer done()
+	for _, l := range logs {
+		// tasks of earlier runs share the name of the job
+		if l.TaskID == taskID {
+			fmt.Fprintf(out, "%s\n", l.Log)
+		}
+	}
+}
+
+// Returns the Mesos task of the run within {args} or the latest active run of the job
+func runTaskID(cmd *cobra.Command, args []string) string {
+	var run *metronome.Run
+	if len(args) > 1 {
+		r, err := client(cmd).GetRun(args[0], args[1])
+		if err != nil {
+			exitWithError(err)
+		}
+		run = r
+	} else {
+		job, err := client(cmd).GetJob(args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+		for _, r := range job.ActiveRuns {
+			if run == nil || r.CreatedAt > run.CreatedAt {
+				run = r
+			}
+		}
+		if run == nil {
+			exitWithError(cli.WithExitCode(cli.ExitNotFound, fmt.Errorf("Currently no active runs found for job: %s", args[0])))
+		}
+	}
+	if len(run.Tasks) == 0 {
+		exitWithError(metronome.ErrorNoTasks)
+	}
+	return run.Tasks[len(run.Tasks)-1].ID
+}
+
+// Returns --wait-timeout or metronome.DefaultTimeout when unspecified
+func waitTimeout(cmd *cobra.Command) time.Duration {
+	if timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG); timeout > 0 {
+		return timeout
+	}
+	return metronome.DefaultTimeout
+}
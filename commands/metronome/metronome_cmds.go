@@ -0,0 +1,143 @@
+package metronome
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/metronome"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/dcos"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	ENV_NAME         string = "env_name"
+)
+
+var (
+	ErrorNoMetronome = errors.New("No Metronome URL is configured for this environment.  Add one with 'depcon config env update [name] --metronome URL'")
+
+	jobCmd = &cobra.Command{
+		Use:   "job",
+		Short: "Manage DC/OS Metronome batch jobs",
+		Long: `Manage DC/OS Metronome batch jobs (eg. job definitions, runs and schedules)
+
+    Metronome is reached with the credentials of the environment's Marathon service.  DC/OS
+    environments use the cluster's /service/metronome unless the environment specifies a
+    Metronome URL (depcon config env update [name] --metronome URL)
+
+    See job's subcommands for available choices`,
+	}
+	metronomeClient metronome.Metronome
+	configFile      *cliconfig.ConfigFile
+)
+
+// Associates the job commands to the given command
+func AddMetronomeToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
+	rc.AddCommand(jobCmd)
+}
+
+func init() {
+	jobCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	jobCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	jobCmd.AddCommand(jobListCmd, jobGetCmd, jobCreateCmd, jobUpdateCmd, jobDestroyCmd, jobRunCmd, jobRunsCmd, jobStopCmd, jobLogCmd, scheduleCmd)
+}
+
+func client(cmd *cobra.Command) metronome.Metronome {
+	if metronomeClient == nil {
+		envName := viper.GetString(ENV_NAME)
+		insecure, _ := cmd.Flags().GetBool(INSECURE_FLAG)
+		allowWrite, _ := cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+		c, err := NewClient(envName, environmentService(envName), insecure, allowWrite)
+		if err != nil {
+			exitWithError(err)
+		}
+		metronomeClient = c
+	}
+	return metronomeClient
+}
+
+// NewClient creates a Metronome client for the environment {envName} whose Marathon {service} supplies the
+// credentials and connection settings.  Changes are rejected when the service is read-only unless {allowWrite}
+func NewClient(envName string, service *cliconfig.ServiceConfig, insecure, allowWrite bool) (metronome.Metronome, error) {
+	host, err := metronomeHost(service)
+	if err != nil {
+		return nil, err
+	}
+	mopts := &marathon.MarathonOptions{TLSAllowInsecure: insecure}
+	if _, err := cmdmarathon.ApplyConnection(envName, service, mopts); err != nil {
+		return nil, err
+	}
+
+	opts := &metronome.MetronomeOptions{
+		TLSAllowInsecure: insecure,
+		Authenticator:    mopts.Authenticator,
+		Proxy:            mopts.Proxy,
+		TLS:              mopts.TLS,
+		ReadOnly:         service.ReadOnly && !allowWrite,
+		Retry:            httpclient.DefaultRetryPolicy(),
+		Timeouts:         mopts.Timeouts,
+		Headers:          mopts.Headers,
+	}
+	if progress := cli.ActiveProgress(); progress != nil {
+		opts.Progress = progress
+	}
+	return metronome.NewMetronomeClient(host, service.Username, service.Password, opts), nil
+}
+
+// Returns the Marathon service of environment {envName} whose credentials are used for Metronome
+func environmentService(envName string) *cliconfig.ServiceConfig {
+	env, err := configFile.GetEnvironment(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+	if env.Marathon == nil {
+		exitWithError(fmt.Errorf("Environment '%s' does not define a Marathon service", envName))
+	}
+	return env.Marathon
+}
+
+// Returns the Metronome URL of {service} which defaults to /service/metronome of DC/OS clusters
+func metronomeHost(service *cliconfig.ServiceConfig) (string, error) {
+	switch {
+	case service.MetronomeUrl != "":
+		return httpclient.ResolveEndpoint(service.MetronomeUrl)
+	case service.IsDCOS():
+		return dcos.MetronomeURL(marathon.SplitHosts(service.HostUrl)[0]), nil
+	}
+	return "", ErrorNoMetronome
+}
+
+// Returns the host of the Mesos master which is assumed to share the host of Marathon
+func mesosHost() string {
+	service := environmentService(viper.GetString(ENV_NAME))
+	u, err := url.Parse(marathon.SplitHosts(service.HostUrl)[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	if strings.Index(u.Host, ":") > 0 {
+		return strings.Split(u.Host, ":")[0]
+	}
+	return u.Host
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
@@ -0,0 +1,96 @@
+package metronome
+
+import (
+	"fmt"
+
+	"github.com/ContainX/depcon/metronome"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	CRON_FLAG        string = "cron"
+	TIMEZONE_FLAG    string = "timezone"
+	DEADLINE_FLAG    string = "starting-deadline"
+	DISABLED_FLAG    string = "disabled"
+	SCHEDULE_ID_FLAG string = "schedule"
+)
+
+var (
+	scheduleCmd = &cobra.Command{
+		Use:   "schedule",
+		Short: "Manage the cron schedules of a job",
+	}
+
+	scheduleListCmd = &cobra.Command{
+		Use:   "list [id]",
+		Short: "Lists the schedules of a job",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).ListSchedules(args[0])
+			cli.Output(templateFor(T_SCHEDULES, v), e)
+		},
+	}
+
+	scheduleSetCmd = &cobra.Command{
+		Use:   "set [id]",
+		Short: "Creates or replaces a schedule of a job",
+		Long: `Creates or replaces a schedule of a job
+
+    eg. depcon job schedule set nightly-backup --cron "0 2 * * *" --timezone America/New_York`,
+		Run: setSchedule,
+	}
+
+	scheduleRemoveCmd = &cobra.Command{
+		Use:   "remove [id]",
+		Short: "Removes a schedule of a job",
+		Run:   removeSchedule,
+	}
+)
+
+func init() {
+	scheduleSetCmd.Flags().String(CRON_FLAG, "", "Cron expression of the schedule (eg. \"0 2 * * *\")")
+	scheduleSetCmd.Flags().String(TIMEZONE_FLAG, "", "Optional: time zone of the cron expression (default UTC)")
+	scheduleSetCmd.Flags().Int(DEADLINE_FLAG, 0, "Optional: seconds after the scheduled time a missed run may still start")
+	scheduleSetCmd.Flags().Bool(DISABLED_FLAG, false, "Creates the schedule disabled")
+	for _, c := range []*cobra.Command{scheduleSetCmd, scheduleRemoveCmd} {
+		c.Flags().String(SCHEDULE_ID_FLAG, metronome.DefaultScheduleID, "ID of the schedule")
+	}
+	scheduleCmd.AddCommand(scheduleListCmd, scheduleSetCmd, scheduleRemoveCmd)
+}
+
+func setSchedule(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	schedule := &metronome.Schedule{}
+	schedule.ID, _ = cmd.Flags().GetString(SCHEDULE_ID_FLAG)
+	schedule.Cron, _ = cmd.Flags().GetString(CRON_FLAG)
+	schedule.TimeZone, _ = cmd.Flags().GetString(TIMEZONE_FLAG)
+	schedule.StartingDeadlineSeconds, _ = cmd.Flags().GetInt(DEADLINE_FLAG)
+	disabled, _ := cmd.Flags().GetBool(DISABLED_FLAG)
+	schedule.Enabled = !disabled
+	if schedule.Cron == "" {
+		exitWithError(fmt.Errorf("--%s is required", CRON_FLAG))
+	}
+
+	v, e := client(cmd).PutSchedule(args[0], schedule)
+	cli.Output(templateFor(T_SCHEDULES, []*metronome.Schedule{v}), e)
+}
+
+func removeSchedule(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	id, _ := cmd.Flags().GetString(SCHEDULE_ID_FLAG)
+	cli.ConfirmOrExit(fmt.Sprintf("Remove schedule '%s' of job '%s'", id, args[0]), "environment", viper.GetString(ENV_NAME))
+	if err := client(cmd).DeleteSchedule(args[0], id); err != nil {
+		exitWithError(err)
+	}
+	log.Info("Schedule '%s' of job '%s' was removed", id, args[0])
+}
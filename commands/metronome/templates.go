@@ -0,0 +1,94 @@
+package metronome
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/ContainX/depcon/metronome"
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_JOBS = `
+{{ "ID" | header }}	{{ "SCHEDULE" | header }}	{{ "ACTIVE RUNS" | header }}	{{ "LAST RUN" | header }}	{{ "DESCRIPTION" | header }}
+{{ range . }}{{ .ID }}	{{ .Schedules | cron }}	{{ len .ActiveRuns }}	{{ .History | lastRun }}	{{ .Description }}
+{{end}}`
+
+	T_JOB = `
+{{ "ID:" }}	{{ .ID }}
+{{ "Description:" }}	{{ .Description }}
+{{ with .Run }}{{ "Command:" }}	{{ .Cmd }}
+{{ with .Docker }}{{ "Image:" }}	{{ .Image }}
+{{end}}{{ "CPUs:" }}	{{ .Cpus | floatToString }}
+{{ "Memory:" }}	{{ .Mem | floatToString }}
+{{end}}{{ with .History }}{{ "Successful Runs:" }}	{{ .SuccessCount | intToString }}
+{{ "Failed Runs:" }}	{{ .FailureCount | intToString }}
+{{ "Last Success:" }}	{{ .LastSuccessAt }}
+{{ "Last Failure:" }}	{{ .LastFailureAt }}
+{{end}}{{ "Schedules:" }}
+{{ range .Schedules }}		{{ .ID | pad }} {{ .Cron }} {{ .TimeZone }} enabled: {{ .Enabled | boolToYesNo }}, next: {{ .NextRunAt }}
+{{end}}{{ "Active Runs:" }}
+{{ range .ActiveRuns }}		{{ .ID | pad }} {{ .Status | status }} since {{ .CreatedAt }}
+{{end}}`
+
+	T_RUNS = `
+{{ "JOB" | header }}	{{ "RUN" | header }}	{{ "STATUS" | header }}	{{ "CREATED" | header }}	{{ "COMPLETED" | header }}
+{{ range . }}{{ .JobID }}	{{ .ID }}	{{ .Status | status }}	{{ .CreatedAt }}	{{ .CompletedAt }}
+{{end}}`
+
+	T_APPLY = `
+{{ "JOB" | header }}	{{ "CREATED" | header }}	{{ "SCHEDULES" | header }}
+{{ range . }}{{ .JobID }}	{{ .Created | boolToYesNo }}	{{ .Schedules | intToString }}
+{{end}}`
+
+	T_SCHEDULES = `
+{{ "ID" | header }}	{{ "CRON" | header }}	{{ "TIMEZONE" | header }}	{{ "ENABLED" | header }}	{{ "NEXT RUN" | header }}
+{{ range . }}{{ .ID }}	{{ .Cron }}	{{ .TimeZone }}	{{ .Enabled | boolToYesNo }}	{{ .NextRunAt }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data, Funcs: buildFuncMap()}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
+
+func buildFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"cron":    cron,
+		"lastRun": lastRun,
+	}
+}
+
+// Returns the cron expressions of the enabled schedules
+func cron(schedules []*metronome.Schedule) string {
+	crons := []string{}
+	for _, s := range schedules {
+		if s.Enabled {
+			crons = append(crons, s.Cron)
+		}
+	}
+	return strings.Join(crons, ",")
+}
+
+// Returns the outcome of the most recent finished run
+func lastRun(h *metronome.JobHistory) string {
+	switch {
+	case h == nil || (h.LastSuccessAt == "" && h.LastFailureAt == ""):
+		return ""
+	case h.LastSuccessAt > h.LastFailureAt:
+		return "success " + h.LastSuccessAt
+	}
+	return "failed " + h.LastFailureAt
+}
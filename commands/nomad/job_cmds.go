@@ -0,0 +1,178 @@
+package nomad
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/nomad"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+const (
+	WAIT_FLAG    string = "wait"
+	TIMEOUT_FLAG string = "wait-timeout"
+	PURGE_FLAG   string = "purge"
+)
+
+var log = logger.GetLogger("depcon.nomad")
+
+const descriptorHelp = `
+
+    The descriptor is rendered with the template context and ${PARAMS} exactly as Marathon
+    descriptors are.  Each document is a JSON job (the output of 'nomad job run -output')
+    holding the job under 'Job' or the job itself.  YAML descriptors may contain multiple
+    documents separated by '---' which are processed in order`
+
+var (
+	nomadPlanCmd = &cobra.Command{
+		Use:   "plan [file(.json | .yaml)]",
+		Short: "Shows the changes the scheduler would make for the jobs within a descriptor",
+		Long:  "Shows the changes the scheduler would make for the jobs within a descriptor" + descriptorHelp,
+		Run:   planJobs,
+	}
+
+	nomadRunCmd = &cobra.Command{
+		Use:   "run [file(.json | .yaml)]",
+		Short: "Registers the jobs within a descriptor creating or updating them",
+		Long:  "Registers the jobs within a descriptor creating or updating them" + descriptorHelp,
+		Run:   runJobs,
+	}
+
+	nomadStatusCmd = &cobra.Command{
+		Use:   "status [id]",
+		Short: "Lists all jobs or gets the status of a job by id",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 0 {
+				v, e := client(cmd).ListJobs()
+				cli.Output(templateFor(T_JOBS, v), e)
+				return
+			}
+			v, e := client(cmd).JobStatus(args[0])
+			cli.Output(templateFor(T_JOB_STATUS, v), e)
+		},
+	}
+
+	nomadStopCmd = &cobra.Command{
+		Use:   "stop [id]",
+		Short: "Stops a job and its allocations",
+		Run:   stopJob,
+	}
+)
+
+func init() {
+	nomadRunCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the evaluations to complete and deployments to become healthy")
+	nomadRunCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the jobs to become healthy (ex. 90s | 2m)")
+	nomadStopCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the evaluation to complete")
+	nomadStopCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the evaluation to complete (ex. 90s | 2m)")
+	nomadStopCmd.Flags().Bool(PURGE_FLAG, false, "Removes the job rather than leaving it dead until garbage collected")
+	marathon.ApplyDescriptorFlags(nomadPlanCmd)
+	marathon.ApplyDescriptorFlags(nomadRunCmd)
+}
+
+func planJobs(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	plans := []*nomad.PlanResult{}
+	for _, doc := range marathon.RenderDocuments(cmd, args[0]) {
+		plan, err := client(cmd).Plan(doc)
+		if err != nil {
+			exitWithError(err)
+		}
+		for group, metrics := range plan.FailedGroups {
+			log.Warning("Task group '%s' of job '%s' could not be placed: %d of %d nodes evaluated were exhausted", group, plan.JobID, metrics.NodesExhausted, metrics.NodesEvaluated)
+		}
+		if plan.Warnings != "" {
+			log.Warning("Job '%s': %s", plan.JobID, plan.Warnings)
+		}
+		plans = append(plans, plan)
+	}
+	cli.Output(templateFor(T_PLAN, plans), nil)
+}
+
+func runJobs(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	docs := marathon.RenderDocuments(cmd, args[0])
+
+	results := []*nomad.RunResult{}
+	for i, doc := range docs {
+		label := documentLabel(doc)
+		reportBulkStep(i+1, len(docs), label)
+		result, err := client(cmd).Run(doc)
+		if err != nil {
+			cli.StopProgress()
+			log.Error("Unable to run %s", label)
+			exitWithError(err)
+		}
+		results = append(results, result)
+	}
+	cli.StopProgress()
+
+	if wait {
+		for _, r := range results {
+			if err := client(cmd).WaitForEvaluation(r.EvalID, waitTimeout(cmd)); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+	cli.Output(templateFor(T_RUN, results), nil)
+}
+
+func stopJob(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	purge, _ := cmd.Flags().GetBool(PURGE_FLAG)
+	cli.ConfirmOrExit(fmt.Sprintf("Stop job '%s'", args[0]), "namespace", namespace(cmd))
+	result, err := client(cmd).Stop(args[0], purge)
+	if err != nil {
+		exitWithError(err)
+	}
+	if wait, _ := cmd.Flags().GetBool(WAIT_FLAG); wait {
+		if err := client(cmd).WaitForEvaluation(result.EvalID, waitTimeout(cmd)); err != nil {
+			exitWithError(err)
+		}
+	}
+	cli.Output(templateFor(T_RUN, []*nomad.RunResult{result}), nil)
+}
+
+// Returns job/{id} of the job within {doc}
+func documentLabel(doc map[string]interface{}) string {
+	job := doc
+	if wrapped, ok := doc["Job"].(map[string]interface{}); ok {
+		job = wrapped
+	}
+	id, _ := job["ID"].(string)
+	if id == "" {
+		id, _ = job["Name"].(string)
+	}
+	return "job/" + id
+}
+
+// Reports {step} of {total} of a bulk deployment which is drawn with the progress on a terminal and
+// logged otherwise
+func reportBulkStep(step, total int, label string) {
+	if total < 2 {
+		return
+	}
+	if !cli.ActiveProgress().Step(step, total, label) {
+		log.Info("[%d/%d] %s", step, total, label)
+	}
+}
+
+// Returns --wait-timeout or nomad.DefaultTimeout when unspecified
+func waitTimeout(cmd *cobra.Command) time.Duration {
+	if timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG); timeout > 0 {
+		return timeout
+	}
+	return nomad.DefaultTimeout
+}
@@ -0,0 +1,154 @@
+package nomad
+
+import (
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/nomad"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	ADDRESS_FLAG     string = "address"
+	NAMESPACE_FLAG   string = "namespace"
+	REGION_FLAG      string = "region"
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	ENV_NAME         string = "env_name"
+)
+
+var (
+	nomadCmd = &cobra.Command{
+		Use:   "nomad",
+		Short: "Plan, run and manage HashiCorp Nomad jobs",
+		Long: `Plan, run and manage HashiCorp Nomad jobs using the same templated descriptors,
+params and template contexts as Marathon
+
+    The cluster is selected by the 'nomad' block of the environment, the variables used by
+    the nomad CLI ($NOMAD_ADDR, $NOMAD_TOKEN, $NOMAD_NAMESPACE, $NOMAD_REGION, $NOMAD_CACERT)
+    or the --address, --namespace and --region flags
+
+    See nomad's subcommands for available choices`,
+	}
+	nomadClient nomad.Nomad
+	configFile  *cliconfig.ConfigFile
+)
+
+// Associates the nomad commands to the given command
+func AddNomadToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
+	backend.Register(cliconfig.TypeNomad, newBackend)
+	rc.AddCommand(nomadCmd)
+}
+
+func init() {
+	nomadCmd.PersistentFlags().String(ADDRESS_FLAG, "", "Nomad address overriding the environment and $NOMAD_ADDR")
+	nomadCmd.PersistentFlags().StringP(NAMESPACE_FLAG, "n", "", "Namespace overriding the environment and $NOMAD_NAMESPACE")
+	nomadCmd.PersistentFlags().String(REGION_FLAG, "", "Region overriding the environment and $NOMAD_REGION")
+	nomadCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	nomadCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	nomadCmd.AddCommand(nomadPlanCmd, nomadRunCmd, nomadStatusCmd, nomadStopCmd)
+}
+
+func client(cmd *cobra.Command) nomad.Nomad {
+	if nomadClient == nil {
+		o := overrides{}
+		o.address, _ = cmd.Flags().GetString(ADDRESS_FLAG)
+		o.namespace, _ = cmd.Flags().GetString(NAMESPACE_FLAG)
+		o.region, _ = cmd.Flags().GetString(REGION_FLAG)
+		o.insecure, _ = cmd.Flags().GetBool(INSECURE_FLAG)
+		o.allowWrite, _ = cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+		nomadClient = newClient(viper.GetString(ENV_NAME), o)
+	}
+	return nomadClient
+}
+
+// Settings of the invocation overriding those of the environment
+type overrides struct {
+	address, namespace, region string
+	insecure, allowWrite       bool
+}
+
+// Creates the client of environment {envName} falling back to the variables of the nomad CLI for settings
+// neither the environment nor {o} specify
+func newClient(envName string, o overrides) nomad.Nomad {
+	config := nomad.DefaultConfig()
+	readOnly := false
+	if configFile != nil {
+		if env, err := configFile.GetEnvironment(envName); err == nil {
+			if env.Nomad != nil {
+				if env.Nomad.Address != "" {
+					config.Address = env.Nomad.Address
+				}
+				if env.Nomad.Namespace != "" {
+					config.Namespace = env.Nomad.Namespace
+				}
+				if env.Nomad.Region != "" {
+					config.Region = env.Nomad.Region
+				}
+				readOnly = env.Nomad.ReadOnly
+			}
+			if env.Marathon != nil && env.Marathon.ReadOnly {
+				readOnly = true
+			}
+		}
+	}
+	if o.address != "" {
+		config.Address = o.address
+	}
+	if o.namespace != "" {
+		config.Namespace = o.namespace
+	}
+	if o.region != "" {
+		config.Region = o.region
+	}
+	if o.insecure {
+		config.Insecure = true
+	}
+
+	opts := &nomad.NomadOptions{Retry: httpclient.DefaultRetryPolicy()}
+	opts.ReadOnly = readOnly && !o.allowWrite
+	if progress := cli.ActiveProgress(); progress != nil {
+		opts.Progress = progress
+	}
+	return nomad.NewNomadClient(config, opts)
+}
+
+// Creates the backend of the workload commands.  The variables of the nomad CLI are only fallen back to
+// when the backend is selected explicitly
+func newBackend(ctx *backend.Context) (backend.ClusterBackend, error) {
+	if !ctx.Selected && !configured(ctx.EnvName) {
+		return nil, &backend.NotConfiguredError{Backend: cliconfig.TypeNomad, EnvName: ctx.EnvName}
+	}
+	return backend.NewNomadBackend(newClient(ctx.EnvName, overrides{insecure: ctx.Insecure, allowWrite: ctx.AllowWrite})), nil
+}
+
+// Returns true if environment {envName} has a 'nomad' block
+func configured(envName string) bool {
+	if configFile == nil {
+		return false
+	}
+	env, err := configFile.GetEnvironment(envName)
+	return err == nil && env.Nomad != nil
+}
+
+// Returns the namespace of the client or 'default' when the agent's default is used
+func namespace(cmd *cobra.Command) string {
+	if ns := client(cmd).Namespace(); ns != "" {
+		return ns
+	}
+	return "default"
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
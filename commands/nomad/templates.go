@@ -0,0 +1,75 @@
+package nomad
+
+import (
+	"io"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_JOBS = `
+{{ "ID" | header }}	{{ "TYPE" | header }}	{{ "PRIORITY" | header }}	{{ "STATUS" | header }}	{{ "SUBMITTED" | header }}
+{{ range . }}{{ .ID }}	{{ .Type }}	{{ .Priority | intToString }}	{{ .Status }}	{{ .SubmitTime | submitted }}
+{{end}}`
+
+	T_JOB_STATUS = `
+{{ "ID:" }}	{{ .ID }}
+{{ "Name:" }}	{{ .Name }}
+{{ "Namespace:" }}	{{ .Namespace }}
+{{ "Type:" }}	{{ .Type }}
+{{ "Priority:" }}	{{ .Priority | intToString }}
+{{ "Status:" }}	{{ .Status }}
+{{ "Version:" }}	{{ .Version | intToString }}
+{{ "Datacenters:" }}	{{ .Datacenters | join }}
+{{ "Submitted:" }}	{{ .SubmitTime | submitted }}
+{{ "Summary:" }}
+{{ range $group, $s := .Summary }}		{{ $group | pad }} queued {{ $s.Queued }}, starting {{ $s.Starting }}, running {{ $s.Running }}, failed {{ $s.Failed }}, complete {{ $s.Complete }}, lost {{ $s.Lost }}
+{{end}}{{ with .Deployment }}{{ "Latest Deployment:" }}	{{ .ID }} {{ .Status }} - {{ .StatusDescription }}
+{{end}}`
+
+	T_PLAN = `
+{{ "JOB" | header }}	{{ "DIFF" | header }}	{{ "GROUP" | header }}	{{ "PLACE" | header }}	{{ "IN-PLACE" | header }}	{{ "DESTRUCTIVE" | header }}	{{ "STOP" | header }}	{{ "CANARY" | header }}	{{ "IGNORE" | header }}
+{{ range . }}{{ $job := .JobID }}{{ $diff := .DiffType }}{{ range $group, $u := .Updates }}{{ $job }}	{{ $diff }}	{{ $group }}	{{ $u.Place }}	{{ $u.InPlaceUpdate }}	{{ $u.DestructiveUpdate }}	{{ $u.Stop }}	{{ $u.Canary }}	{{ $u.Ignore }}
+{{end}}{{end}}`
+
+	T_RUN = `
+{{ "JOB" | header }}	{{ "EVALUATION" | header }}
+{{ range . }}{{ .JobID }}	{{ .EvalID }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data, Funcs: buildFuncMap()}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
+
+func buildFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"submitted": submitted,
+		"join": func(s []string) string {
+			return strings.Join(s, ",")
+		},
+	}
+}
+
+// Formats nanoseconds since the epoch as RFC3339
+func submitted(nanos int64) string {
+	if nanos == 0 {
+		return ""
+	}
+	return time.Unix(0, nanos).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/pipeline"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/spf13/cobra"
+)
+
+const (
+	FlagFrom = "from"
+
+	ActionPromote = "promote"
+
+	T_PIPELINE = `
+{{ "STAGE" | header }}	{{ "ENVIRONMENT" | header }}	{{ "ACTION" | header }}	{{ "VERIFIED" | header }}	{{ "RESULT" | header }}	{{ "DURATION" | header }}
+{{ range . }}{{ .Stage }}	{{ .Environment }}	{{ .Action }}	{{ .Verified | intToString }}	{{ .Result }}	{{ .Duration }}
+{{end}}`
+)
+
+var pipelineCmd = &cobra.Command{
+	Use:   "pipeline",
+	Short: "Promote a deployment through a pipeline of environments",
+}
+
+var pipelineRunCmd = &cobra.Command{
+	Use:   "run [pipeline.yaml]",
+	Short: "Deploys the pipeline's descriptor to each stage's environment in order",
+	Long: `Deploys the same descriptor with the same params (eg. the image tag) to each stage's environment in
+order.  A stage is deployed once the previous stage is healthy and its verifications pass.  Manual stages
+are confirmed first (or approved with --yes).  The run stops at the first stage which fails or is declined.
+
+    name: shop
+    app: app.json                     # app or group descriptor, relative to the pipeline
+    tempctx: template-context.json    # optional, rendered for each stage's environment
+    params:                           # optional, overridden by -p
+      TAG: "1.4"
+    stages:
+      - name: dev
+      - name: staging
+        verify:
+          - http: https://staging.example.com/health
+            status: 200
+          - command: ./smoke-test.sh  # DEPCON_ENV and DEPCON_STAGE are set
+            retries: 3
+            interval: 10s
+      - name: production
+        environment: prod             # default: the stage's name
+        gate: manual
+        timeout: 10m
+
+    eg. depcon pipeline run pipeline.yaml -p TAG=1.4
+        depcon pipeline run pipeline.yaml -p TAG=1.4 --from production`,
+	Run: runPipeline,
+}
+
+func init() {
+	pipelineRunCmd.Flags().String(FlagFrom, "", "Stage to start from skipping those before it")
+	pipelineRunCmd.Flags().String(cmdmarathon.TEMPLATE_CTX_FLAG, cmdmarathon.DEFAULT_CTX, "Template context the descriptor is rendered with.  Default: the pipeline's tempctx")
+	pipelineRunCmd.Flags().StringSliceP(cmdmarathon.PARAMS_FLAG, "p", nil, "Adds a param(s) that can be used for substitution (eg. -p TAG=1.2)")
+	pipelineRunCmd.Flags().BoolP(cmdmarathon.IGNORE_MISSING, "i", false, "Ignore missing ${PARAMS} and template fields rather than failing")
+	pipelineRunCmd.Flags().String(FlagAuditLog, "", "Audit log file.  Default: ~/.depcon/"+audit.DefaultFilename)
+	pipelineRunCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	cmdmarathon.ApplySignatureFlags(pipelineRunCmd)
+	pipelineCmd.AddCommand(pipelineRunCmd)
+}
+
+func runPipeline(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	p, err := pipeline.Load(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+
+	tempctx, _ := cmd.Flags().GetString(cmdmarathon.TEMPLATE_CTX_FLAG)
+	if !cmd.Flags().Changed(cmdmarathon.TEMPLATE_CTX_FLAG) && p.TemplateContext != "" {
+		tempctx = p.Path(p.TemplateContext)
+	}
+	auditLog, _ := cmd.Flags().GetString(FlagAuditLog)
+	if auditLog == "" {
+		auditLog = filepath.Join(cliconfig.ConfigDir(), audit.DefaultFilename)
+	}
+	l := audit.New(auditLog)
+
+	runner := &pipeline.Runner{
+		Client: pipeline.ClientFactory(environmentClients(cmd)),
+		Load: func(env string) *reconcile.LoadOptions {
+			return deployLoadOptions(cmd, env, tempctx)
+		},
+		Approve: func(p *pipeline.Pipeline, s *pipeline.Stage) error {
+			return cli.Confirm(fmt.Sprintf("Promote pipeline '%s' to stage '%s' (environment '%s')", p.Name, s.Name, s.Env()))
+		},
+		Report: func(r *pipeline.StageResult) {
+			if r.Result == pipeline.ResultSkipped {
+				return
+			}
+			e := &audit.Entry{Environment: r.Environment, Action: ActionPromote, Target: p.Name, Result: audit.ResultSuccess,
+				Message: r.Error, Details: map[string]string{"pipeline": args[0], "stage": r.Stage}}
+			if r.Result != pipeline.ResultPromoted {
+				e.Result = audit.ResultFailed
+			}
+			if err := l.Record(e); err != nil {
+				log.Error("Unable to write the audit log %s: %s", l.Filename(), err.Error())
+			}
+		},
+	}
+
+	from, _ := cmd.Flags().GetString(FlagFrom)
+	results, err := runner.Run(p, from)
+	if len(results) > 0 {
+		cli.Output(templateFor(T_PIPELINE, results), nil)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+}
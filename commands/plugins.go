@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/plugin"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// Settings passed to plugins in addition to DEPCON_ENV, DEPCON_HOST, DEPCON_USER and DEPCON_PASSWORD
+	EnvDepconConfig = "DEPCON_CONFIG"
+	EnvDepconAuth   = "DEPCON_AUTH"
+	EnvDepconOutput = "DEPCON_OUTPUT"
+
+	T_PLUGINS = `
+{{ "NAME" | header }}	{{ "PATH" | header }}	{{ "STATUS" | header }}
+{{ range . }}{{ .Name }}	{{ .Path }}	{{ .Status }}
+{{end}}`
+)
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Lists the plugins extending depcon",
+	Long: `Plugins are executables named depcon-<name> on the PATH which run as 'depcon <name>'
+
+    Plugins receive their arguments unchanged along with the selected environment through
+    DEPCON_ENV, DEPCON_CONFIG, DEPCON_HOST, DEPCON_USER, DEPCON_PASSWORD, DEPCON_AUTH and
+    DEPCON_OUTPUT.  Built-in commands take precedence over plugins of the same name
+
+    See plugin's subcommands for available choices`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the plugins found on the PATH",
+	Run: func(cmd *cobra.Command, args []string) {
+		cli.Output(templateFor(T_PLUGINS, pluginStatuses()), nil)
+	},
+}
+
+// pluginStatus is a plugin and whether a built-in command shadows it
+type pluginStatus struct {
+	*plugin.Plugin
+	Status string `json:"status"`
+}
+
+func init() {
+	pluginCmd.AddCommand(pluginListCmd)
+}
+
+// Adds a command for each plugin on the PATH which doesn't share its name with a built-in command
+func addPluginCommands() {
+	for _, p := range plugin.List() {
+		if isBuiltinCommand(p.Name) {
+			continue
+		}
+		rootCmd.AddCommand(&cobra.Command{
+			Use:                p.Name,
+			Short:              "Plugin " + p.Path,
+			DisableFlagParsing: true,
+			Run:                runPlugin(p),
+		})
+	}
+}
+
+func runPlugin(p *plugin.Plugin) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		err := p.Run(argsAfterCommand(), pluginEnv())
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			// the plugin reported its own failure so its exit code is kept
+			cli.ExitWithCode(exitErr.ExitCode())
+		}
+		if err != nil {
+			cli.Output(nil, err)
+		}
+	}
+}
+
+// Returns the settings of the selected environment passed to plugins
+func pluginEnv() []string {
+	envName := viper.GetString(ViperEnv)
+	env := []string{
+		EnvDepconEnv + "=" + envName,
+		EnvDepconConfig + "=" + configFile.Filename(),
+		EnvDepconOutput + "=" + getFormatType(),
+	}
+	if configEnv, err := configFile.GetEnvironment(envName); err == nil && configEnv.Marathon != nil {
+		service := configEnv.Marathon
+		env = append(env,
+			EnvDepconHost+"="+service.HostUrl,
+			EnvDepconUser+"="+service.Username,
+			EnvDepconPassword+"="+service.Password,
+			EnvDepconAuth+"="+service.Auth,
+		)
+	}
+	return env
+}
+
+// Returns os.Args following the first command (skipping the environment flag) which are passed to plugins
+// unchanged
+func argsAfterCommand() []string {
+	for i := 1; i < len(os.Args); i++ {
+		arg := os.Args[i]
+		if arg == "-e" || arg == "--"+FlagEnv {
+			i++
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			return os.Args[i+1:]
+		}
+	}
+	return nil
+}
+
+func isBuiltinCommand(name string) bool {
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return name == "help"
+}
+
+func pluginStatuses() []*pluginStatus {
+	statuses := []*pluginStatus{}
+	for _, p := range plugin.List() {
+		status := "ok"
+		if isBuiltinCommand(p.Name) {
+			status = "shadowed by built-in command"
+		}
+		statuses = append(statuses, &pluginStatus{Plugin: p, Status: status})
+	}
+	return statuses
+}
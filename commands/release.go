@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"path/filepath"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	cmdmetronome "github.com/ContainX/depcon/commands/metronome"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/release"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagRestart = "restart"
+
+	T_RELEASE = `
+{{ "STEP" | header }}	{{ "KIND" | header }}	{{ "TARGET" | header }}	{{ "ACTION" | header }}	{{ "RESULT" | header }}	{{ "DURATION" | header }}
+{{ range . }}{{ .Step }}	{{ .Kind }}	{{ .Target }}	{{ .Action }}	{{ .Result }}	{{ .Duration }}
+{{end}}`
+)
+
+var releaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Run ordered releases of multiple applications and jobs",
+}
+
+var releaseRunCmd = &cobra.Command{
+	Use:   "run [release.yaml]",
+	Short: "Runs the steps of a release in order, resuming a failed release after its completed steps",
+	Long: `Runs the steps of a release in order.  A step deploys an app or group descriptor (waiting for it to be
+healthy), creates or updates a Metronome job and runs it to completion, or runs its parallel steps
+concurrently.  The release stops at the first step which fails.  Running it again resumes from the failed
+step unless the release file has changed or --restart is given.
+
+    name: shop-1.4
+    tempctx: template-context.json    # optional, relative to the release
+    params:                           # optional, overridden by -p
+      TAG: "1.4"
+    steps:
+      - name: migrate
+        job: jobs/migrate.json
+        timeout: 10m
+      - name: api
+        app: apps/api.json
+      - name: workers
+        parallel:
+          - name: worker
+            app: apps/worker.json
+          - name: scheduler
+            app: apps/scheduler.json
+            wait: false
+
+    eg. depcon release run release.yaml -e prod -p TAG=1.4`,
+	Run: runRelease,
+}
+
+func init() {
+	releaseRunCmd.Flags().Bool(FlagRestart, false, "Run every step rather than resuming a failed release")
+	releaseRunCmd.Flags().String(cmdmarathon.TEMPLATE_CTX_FLAG, cmdmarathon.DEFAULT_CTX, "Template context the descriptors are rendered with.  Default: the release's tempctx")
+	releaseRunCmd.Flags().StringSliceP(cmdmarathon.PARAMS_FLAG, "p", nil, "Adds a param(s) that can be used for substitution (eg. -p TAG=1.2)")
+	releaseRunCmd.Flags().BoolP(cmdmarathon.IGNORE_MISSING, "i", false, "Ignore missing ${PARAMS} and template fields rather than failing")
+	releaseRunCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	cmdmarathon.ApplySignatureFlags(releaseRunCmd)
+	releaseCmd.AddCommand(releaseRunCmd)
+}
+
+func runRelease(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	rel, err := release.Load(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	envName := viper.GetString(ViperEnv)
+	client, err := environmentClients(cmd)(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	tempctx, _ := cmd.Flags().GetString(cmdmarathon.TEMPLATE_CTX_FLAG)
+	if !cmd.Flags().Changed(cmdmarathon.TEMPLATE_CTX_FLAG) && rel.TemplateContext != "" {
+		tempctx = rel.Path(rel.TemplateContext)
+	}
+	runner := &release.Runner{
+		Environment: envName,
+		Marathon:    client,
+		Load:        deployLoadOptions(cmd, envName, tempctx),
+		StateFile:   filepath.Join(cliconfig.ConfigDir(), "releases", envName, rel.Name+".json"),
+		Report: func(r *release.StepResult) {
+			if r.Result == release.ResultFailed {
+				log.Error("Step '%s' failed: %s", r.Step, r.Error)
+			} else {
+				log.Info("Step '%s' %s", r.Step, r.Result)
+			}
+		},
+	}
+	if rel.HasJobs() {
+		env, err := configFile.GetEnvironment(envName)
+		if err != nil {
+			exitWithError(err)
+		}
+		insecure, _ := cmd.Flags().GetBool(cmdmarathon.INSECURE_FLAG)
+		if runner.Metronome, err = cmdmetronome.NewClient(envName, env.Marathon, insecure, false); err != nil {
+			exitWithError(err)
+		}
+	}
+
+	restart, _ := cmd.Flags().GetBool(FlagRestart)
+	results, err := runner.Run(rel, restart)
+	cli.Output(templateFor(T_RELEASE, results), nil)
+	if err != nil {
+		exitWithError(err)
+	}
+}
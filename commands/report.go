@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"strings"
+
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/utilization"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagTop = "top"
+
+	T_RESOURCES = `
+{{ "ID" | header }}	{{ "INSTANCES" | header }}	{{ "SAMPLED" | header }}	{{ "CPUS" | header }}	{{ "CPUS USED" | header }}	{{ "CPU %" | header }}	{{ "MEM" | header }}	{{ "MEM USED" | header }}	{{ "MEM %" | header }}	{{ "UNUSED CPUS" | header }}	{{ "UNUSED MEM" | header }}
+{{ range .Apps }}{{ if .OverProvisioned }}{{ .ID | warning }}{{ else }}{{ .ID }}{{ end }}	{{ .Instances | intToString }}	{{ .Sampled | intToString }}	{{ printf "%g" .CPUsAllocated }}	{{ printf "%g" .CPUsUsed }}	{{ printf "%g" .CPUPercent }}	{{ printf "%g" .MemAllocated }}	{{ printf "%g" .MemUsed }}	{{ printf "%g" .MemPercent }}	{{ printf "%g" .UnusedCPUs }}	{{ printf "%g" .UnusedMem }}
+{{end}}{{ with .Total }}{{ .ID }}	{{ .Instances | intToString }}	{{ .Sampled | intToString }}	{{ printf "%g" .CPUsAllocated }}	{{ printf "%g" .CPUsUsed }}	{{ printf "%g" .CPUPercent }}	{{ printf "%g" .MemAllocated }}	{{ printf "%g" .MemUsed }}	{{ printf "%g" .MemPercent }}	{{ printf "%g" .UnusedCPUs }}	{{ printf "%g" .UnusedMem }}
+{{ end }}`
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Reports on the applications of an environment",
+	Long: `Reports on the applications of an environment
+
+    See report's subcommands for available choices`,
+}
+
+var reportResourcesCmd = &cobra.Command{
+	Use:   "resources [groupId]",
+	Short: "Compares the cpus and memory allocated to applications with what their containers use",
+	Long: `Aggregates the cpus and memory allocated to every application (or those within [groupId]) against the
+usage the Mesos agents report for their containers.  The cpus used are measured over --interval.  Apps are
+ordered by the share of the environment's allocation they leave unused, and the --top most over-provisioned
+apps are highlighted.  Use -o csv or -o json to feed capacity planning.
+
+Usage is only known for the instances the agents report (SAMPLED).  Unused resources of an app are
+projected from its sampled instances to all of its instances.
+
+    eg. depcon report resources -e prod
+        depcon report resources /shop --top 10 -o csv > usage.csv`,
+	Run: reportResources,
+}
+
+func init() {
+	reportResourcesCmd.Flags().Int(FlagTop, utilization.DefaultTop, "Number of the most over-provisioned apps to highlight")
+	reportResourcesCmd.Flags().Duration(FlagInterval, utilization.DefaultInterval, "Time between the two samples the cpus used are measured over")
+	reportResourcesCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	reportCmd.AddCommand(reportResourcesCmd)
+}
+
+func reportResources(cmd *cobra.Command, args []string) {
+	envName := viper.GetString(ViperEnv)
+	client, err := environmentClients(cmd)(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+	insecure, _ := cmd.Flags().GetBool(cmdmarathon.INSECURE_FLAG)
+	m, err := cmdmarathon.NewMesosClient(envName, configFile.Environments[envName].Marathon, insecure, false)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	live, err := client.ListApplications()
+	if err != nil {
+		exitWithError(err)
+	}
+	group := ""
+	if len(args) > 0 {
+		group = "/" + strings.Trim(args[0], "/")
+	}
+	apps := []*marathon.Application{}
+	for i := range live.Apps {
+		id := live.Apps[i].ID
+		if group == "" || group == "/" || id == group || strings.HasPrefix(id, group+"/") {
+			apps = append(apps, &live.Apps[i])
+		}
+	}
+	tasks, err := client.ListTasks()
+	if err != nil {
+		exitWithError(err)
+	}
+
+	interval, _ := cmd.Flags().GetDuration(FlagInterval)
+	usage, err := utilization.Sample(m, interval)
+	if err != nil {
+		exitWithError(err)
+	}
+	top, _ := cmd.Flags().GetInt(FlagTop)
+	cli.Output(templateFor(T_RESOURCES, utilization.Summarize(apps, tasks, usage, top)), nil)
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/backup"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagRemap         = "remap"
+	FlagSkipInstances = "skip-instances"
+
+	T_RESTORE = `
+{{ "KIND" | header }}	{{ "ID" | header }}	{{ "ACTION" | header }}	{{ "RESULT" | header }}	{{ "PATH" | header }}
+{{ range . }}{{ .Kind }}	{{ .ID }}	{{ .Action }}	{{ .Result }}	{{ .Path }}
+{{end}}`
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [backup-dir]",
+	Short: "Restores the applications, groups and pods of a backup to an environment",
+	Long: `Replays a backup made by depcon backup into the environment (which may differ from the one backed up).
+Missing groups, applications and pods are created and those which differ from the backup are updated.
+Groups which already exist are left as is and the launch queue isn't restored.  The backup is refused
+when a file no longer matches the checksum of its manifest.
+
+--remap replaces the prefix of ids (and dependencies) so a backup can be restored under another group.
+--skip-instances leaves the instance counts of the backup out: existing applications and pods keep
+theirs and the others are created with one instance (eg. for DR drills).
+
+    eg. depcon restore backups/prod-2024-05-01/ -e dr --remap /prod=/dr --dry-run
+        depcon restore backups/prod-2024-05-01/ -e prod --skip-instances -w`,
+	Run: runRestore,
+}
+
+func init() {
+	restoreCmd.Flags().StringSlice(FlagRemap, nil, "Replaces the prefix of ids: old=new (eg. /prod=/dr)")
+	restoreCmd.Flags().Bool(FlagSkipInstances, false, "Keeps the instances of existing apps and pods and creates the others with one")
+	restoreCmd.Flags().Bool(cmdmarathon.DRYRUN_FLAG, false, "Report the plan without restoring it")
+	restoreCmd.Flags().BoolP(cmdmarathon.WAIT_FLAG, "w", false, "Wait for each application and pod to become healthy before the next")
+	restoreCmd.Flags().DurationP(cmdmarathon.TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for each application or pod (ex. 90s | 2m).  0 waits forever")
+	restoreCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	cmdmarathon.ApplySignatureFlags(restoreCmd)
+}
+
+func runRestore(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	opts := &backup.RestoreOptions{Remap: map[string]string{}}
+	remaps, _ := cmd.Flags().GetStringSlice(FlagRemap)
+	for _, r := range remaps {
+		kv := strings.SplitN(r, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[0], "/") || !strings.HasPrefix(kv[1], "/") {
+			exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s '%s' must be old=new (eg. /prod=/dr)", FlagRemap, r)))
+		}
+		opts.Remap[kv[0]] = kv[1]
+	}
+	opts.SkipInstances, _ = cmd.Flags().GetBool(FlagSkipInstances)
+
+	// the manifest holds the checksum of every file of the backup so its signature covers them all
+	cmdmarathon.VerifySignatures(cmd, filepath.Join(args[0], backup.ManifestFilename))
+	envName := viper.GetString(ViperEnv)
+	client, err := environmentClients(cmd)(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+	steps, err := backup.Plan(client, args[0], opts)
+	if err != nil {
+		exitWithError(err)
+	}
+	changed := []*backup.Step{}
+	for _, s := range steps {
+		if s.Action != reconcile.ActionNone {
+			changed = append(changed, s)
+		}
+	}
+	if len(changed) == 0 {
+		log.Info("%d definition(s) of the backup match environment '%s'", len(steps), envName)
+		return
+	}
+	if dryRun, _ := cmd.Flags().GetBool(cmdmarathon.DRYRUN_FLAG); dryRun {
+		cli.Output(templateFor(T_RESTORE, changed), nil)
+		return
+	}
+	if err := cli.Confirm(fmt.Sprintf("Restore %d definition(s) of the backup %s to environment '%s'", len(changed), args[0], envName)); err != nil {
+		exitWithError(err)
+	}
+
+	wait, _ := cmd.Flags().GetBool(cmdmarathon.WAIT_FLAG)
+	failed := backup.Restore(client, changed, wait, cmdmarathon.WaitTimeout(cmd, marathon.DefaultTimeout))
+	cli.Output(templateFor(T_RESTORE, changed), nil)
+	if failed > 0 {
+		exitWithError(fmt.Errorf("%d of %d definition(s) of the backup failed to restore", failed, len(changed)))
+	}
+}
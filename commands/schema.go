@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"fmt"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "JSON Schema for descriptors",
+	Long: `Exports JSON Schema for application and group descriptors.  Editors can use the schema
+for validation and autocomplete
+
+See schema's subcommands for available choices`,
+}
+
+var schemaExportCmd = &cobra.Command{
+	Use:   "export [app | group]",
+	Short: "Exports the JSON Schema for an application or group descriptor",
+	Run: func(cmd *cobra.Command, args []string) {
+		if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+			return
+		}
+
+		var s *schema.Schema
+		switch args[0] {
+		case "app", "application":
+			s = schema.Generate(&marathon.Application{})
+		case "group":
+			s = schema.Generate(&marathon.Group{})
+		default:
+			cli.Output(nil, fmt.Errorf("Unknown schema '%s', must be [app | group]", args[0]))
+			return
+		}
+
+		data, err := encoding.DefaultJSONEncoder().MarshalIndent(s)
+		if err != nil {
+			cli.Output(nil, err)
+			return
+		}
+		fmt.Println(data)
+	},
+}
+
+func init() {
+	schemaCmd.AddCommand(schemaExportCmd)
+}
@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/access"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/server"
+	"github.com/spf13/cobra"
+)
+
+const (
+	FlagListen       = "listen"
+	FlagToken        = "token"
+	FlagTokenFile    = "token-file"
+	FlagEnvironments = "environments"
+	FlagTLSCert      = "tls-cert"
+	FlagTLSKey       = "tls-key"
+	FlagAccessPolicy = "access-policy"
+)
+
+var serverCmd = &cobra.Command{
+	// not 'server' which is Marathon's server commands when Marathon is rooted
+	Use:   "serve",
+	Short: "Serves the deployment pipeline over an authenticated REST API",
+	Long: `Serves depcon's deployment pipeline (render, validate, deploy, wait and rollback) over a REST API
+so CI systems and bots can deploy to the configured environments without holding their credentials.
+
+Requests require an 'Authorization: Bearer <token>' header matching one of the tokens given by
+--token, DEPCON_TOKEN (comma separated) or --token-file (one per line).  A line of --token-file may name the
+user of its token after a space ('TOKEN alice').  Deployments and rollbacks are refused (403) unless the roles of
+the token's user permit changes to the environment under --access-policy or the config's access policy
+
+    GET    /v1/health                               (unauthenticated)
+    GET    /v1/environments
+    POST   /v1/environments/{env}/deployments       {"descriptor": "...", "params": {}, "wait": true, "rollback": true}
+    GET    /v1/environments/{env}/deployments       (scheduled deployments)
+    DELETE /v1/environments/{env}/deployments/{id}  (cancels a scheduled deployment)
+    POST   /v1/environments/{env}/rollbacks         {"appId": "/web", "version": "..."}
+
+A deployment requested with "at" (eg. "2024-06-01T02:00Z") or "in" (eg. "4h") is rendered and validated at once
+and then scheduled.  Scheduled deployments and their outcome are recorded in the audit log (--audit-log)
+
+    eg. DEPCON_TOKEN=s3cret depcon serve --listen :8443 --environments test,prod --tls-cert cert.pem --tls-key key.pem`,
+	Run: runServer,
+}
+
+func init() {
+	serverCmd.Flags().String(FlagListen, server.DefaultAddress, "Address to listen on")
+	serverCmd.Flags().StringSlice(FlagToken, []string{}, "Bearer token accepted by the API (repeatable).  Prefer DEPCON_TOKEN or --token-file to keep tokens out of the process list")
+	serverCmd.Flags().String(FlagTokenFile, "", "File of bearer tokens accepted by the API, one per line")
+	serverCmd.Flags().StringSlice(FlagEnvironments, []string{}, "Environments deployments may target.  Default: every environment defining Marathon")
+	serverCmd.Flags().String(FlagTLSCert, "", "TLS certificate file.  Plain HTTP is served when unspecified")
+	serverCmd.Flags().String(FlagTLSKey, "", "TLS key file of --tls-cert")
+	serverCmd.Flags().String(FlagAccessPolicy, "", "Central access policy file restricting which users may deploy to which environments.  Default: the config's access policy")
+	serverCmd.Flags().String(FlagAuditLog, "", "Audit log file.  Default: ~/.depcon/"+audit.DefaultFilename)
+	serverCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks against the environments")
+}
+
+func runServer(cmd *cobra.Command, args []string) {
+	tokens, users, err := serverTokens(cmd)
+	if err != nil {
+		exitWithError(err)
+	}
+	policy, err := serverAccessPolicy(cmd)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	environments, err := servedEnvironments(cmd)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	config := &server.Config{Tokens: tokens, Users: users, Access: policy, Environments: environments, Client: environmentClients(cmd)}
+	config.Address, _ = cmd.Flags().GetString(FlagListen)
+	config.CertFile, _ = cmd.Flags().GetString(FlagTLSCert)
+	config.KeyFile, _ = cmd.Flags().GetString(FlagTLSKey)
+	auditLog, _ := cmd.Flags().GetString(FlagAuditLog)
+	if auditLog == "" {
+		auditLog = filepath.Join(cliconfig.ConfigDir(), audit.DefaultFilename)
+	}
+	config.Audit = audit.New(auditLog)
+	config.TrustedKeys = cmdmarathon.TrustedPublicKeys
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s and --%s must be specified together", FlagTLSCert, FlagTLSKey)))
+	}
+
+	s, err := server.New(config)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	if err := s.ListenAndServe(); err != nil {
+		exitWithError(err)
+	}
+}
+
+// Returns the tokens of --token and --token-file along with the users named by --token-file
+func serverTokens(cmd *cobra.Command) ([]string, map[string]string, error) {
+	tokens := []string{}
+	users := map[string]string{}
+	flagged, _ := cmd.Flags().GetStringSlice(FlagToken)
+	for _, t := range flagged {
+		if t = strings.TrimSpace(t); t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	if filename, _ := cmd.Flags().GetString(FlagTokenFile); filename != "" {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			tokens = append(tokens, fields[0])
+			if len(fields) > 1 {
+				users[fields[0]] = fields[1]
+			}
+		}
+	}
+	return tokens, users, nil
+}
+
+// Returns the policy of --access-policy or the config's access policy
+func serverAccessPolicy(cmd *cobra.Command) (*access.Policy, error) {
+	if filename, _ := cmd.Flags().GetString(FlagAccessPolicy); filename != "" {
+		return access.Load(filename)
+	}
+	return configFile.Access, nil
+}
+
+// Returns --environments or every environment which defines Marathon
+func servedEnvironments(cmd *cobra.Command) ([]string, error) {
+	names, _ := cmd.Flags().GetStringSlice(FlagEnvironments)
+	if len(names) == 0 {
+		for _, name := range configFile.GetEnvironments() {
+			if env, err := configFile.GetEnvironment(name); err == nil && env.Marathon != nil {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+	for _, name := range names {
+		env, err := configFile.GetEnvironment(name)
+		if err != nil {
+			return nil, err
+		}
+		if env.Marathon == nil {
+			return nil, fmt.Errorf("Environment '%s' does not define a Marathon service", name)
+		}
+	}
+	return names, nil
+}
+
+// Returns a factory creating the client of an environment once and sharing it between requests
+func environmentClients(cmd *cobra.Command) server.ClientFactory {
+	insecure, _ := cmd.Flags().GetBool(cmdmarathon.INSECURE_FLAG)
+	cancel, force := cmdmarathon.CancelOnTimeout(cmd)
+	ready := cmdmarathon.ReadyCheck(cmd)
+	clients := map[string]marathon.Marathon{}
+	var mu sync.Mutex
+
+	return func(name string) (marathon.Marathon, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if c, ok := clients[name]; ok {
+			return c, nil
+		}
+		env, err := configFile.GetEnvironment(name)
+		if err != nil {
+			return nil, err
+		}
+		if env.Marathon == nil {
+			return nil, fmt.Errorf("Environment '%s' does not define a Marathon service", name)
+		}
+		opts := &marathon.MarathonOptions{TLSAllowInsecure: insecure, ReadOnly: env.Marathon.ReadOnly, CancelOnTimeout: cancel, ForceCancel: force, Ready: ready}
+		c, err := cmdmarathon.NewClient(name, env.Marathon, opts)
+		if err != nil {
+			return nil, err
+		}
+		clients[name] = c
+		return c, nil
+	}
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/shell"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const ShellHistoryFile = "shell_history"
+
+var shellCmd = &cobra.Command{
+	Use:   "shell",
+	Short: "Opens an interactive session running depcon commands against the environment",
+	Long: `Opens an interactive session where each line is run as a depcon command (without 'depcon') against
+the environment chosen when the session is opened.  The configuration is loaded and the client authenticated
+once for the whole session.  Lines are kept in a history (~/.depcon/shell_history), Tab completes commands,
+flags and application, group and deployment ids, Ctrl-C cancels the running command and exit, quit or Ctrl-D
+ends the session.
+
+    eg. depcon -e prod shell
+        depcon:prod> app list
+        depcon:prod> app restart /web --wait`,
+	Run: func(cmd *cobra.Command, args []string) {
+		env := viper.GetString(ViperEnv)
+		s := &shell.Shell{
+			Root:        rootCmd,
+			Prompt:      fmt.Sprintf("depcon:%s> ", env),
+			HistoryFile: filepath.Join(cliconfig.ConfigDir(), ShellHistoryFile),
+			Check:       checkShellLine,
+		}
+		if err := s.Run(); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+// Refuses lines which can't run within a session: switching environments (the commands available depend
+// on the environment) and nested sessions
+func checkShellLine(args []string) error {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "-e" || arg == "--"+FlagEnv || strings.HasPrefix(arg, "--"+FlagEnv+"=") || strings.HasPrefix(arg, "-e=") {
+			return cli.WithExitCode(cli.ExitUsage, fmt.Errorf("The environment can't be changed within a session, open another with 'depcon -e [env] shell'"))
+		}
+	}
+	if args[0] == "shell" {
+		return cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Already within a session"))
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/signature"
+	"github.com/spf13/cobra"
+)
+
+const (
+	FlagKey         = "key"
+	FlagGenerateKey = "generate-key"
+)
+
+var signCmd = &cobra.Command{
+	Use:   "sign [file...]",
+	Short: "Signs descriptors so environments verifying signatures accept them",
+	Long: `Writes a detached ed25519 signature of each descriptor to <file>.sig using the private key --key.
+Deployments with --verify-signature (eg. set within the flags of a production environment) refuse
+descriptors which are unsigned, signed by an untrusted key or changed after signing.
+
+The signature covers the descriptor as stored (before templates and params are rendered) so the same
+signed descriptor can be deployed to every environment.
+
+    eg. depcon sign --generate-key release      # writes release.key and release.pub
+        depcon sign --key release.key app.json group.yaml
+        depcon app create app.json -e prod --verify-signature release.pub`,
+	Run: signDescriptors,
+}
+
+func init() {
+	signCmd.Flags().String(FlagKey, "", "PEM encoded ed25519 private key the descriptors are signed with")
+	signCmd.Flags().String(FlagGenerateKey, "", "Generates a key pair writing the private key to NAME.key and the public key to NAME.pub")
+}
+
+func signDescriptors(cmd *cobra.Command, args []string) {
+	if name, _ := cmd.Flags().GetString(FlagGenerateKey); name != "" {
+		generateSigningKey(name)
+		return
+	}
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	keyfile, _ := cmd.Flags().GetString(FlagKey)
+	if keyfile == "" {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s is required", FlagKey)))
+	}
+	data, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		exitWithError(err)
+	}
+	key, err := signature.ParsePrivateKey(data)
+	if err != nil {
+		exitWithError(fmt.Errorf("%s: %s", keyfile, err.Error()))
+	}
+	for _, f := range args {
+		sigfile, err := signature.SignFile(f, key)
+		if err != nil {
+			exitWithError(err)
+		}
+		log.Info("Signed %s (%s)", f, sigfile)
+	}
+}
+
+func generateSigningKey(name string) {
+	public, private, err := signature.GenerateKey()
+	if err != nil {
+		exitWithError(err)
+	}
+	if err := ioutil.WriteFile(name+".key", private, 0600); err != nil {
+		exitWithError(err)
+	}
+	if err := ioutil.WriteFile(name+".pub", public, 0644); err != nil {
+		exitWithError(err)
+	}
+	log.Info("Wrote the private key to %s.key and the public key to %s.pub", name, name)
+}
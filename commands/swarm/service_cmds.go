@@ -0,0 +1,109 @@
+package swarm
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/swarm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	swarmServiceCmd = &cobra.Command{
+		Use:     "service",
+		Aliases: []string{"services"},
+		Short:   "Manage Docker Swarm services",
+		Long: `Manage Docker Swarm services
+
+    See service's subcommands for available choices`,
+	}
+
+	swarmServiceListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all services or the services of a stack",
+		Run: func(cmd *cobra.Command, args []string) {
+			stack, _ := cmd.Flags().GetString(STACK_FLAG)
+			v, e := client(cmd).ListServices(stack)
+			cli.Output(templateFor(T_SERVICES, v), e)
+		},
+	}
+
+	swarmServiceGetCmd = &cobra.Command{
+		Use:   "get [name]",
+		Short: "Gets a service details by name",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).GetService(args[0])
+			cli.Output(templateFor(T_SERVICE, v), e)
+		},
+	}
+
+	swarmServiceTasksCmd = &cobra.Command{
+		Use:     "tasks [name]",
+		Aliases: []string{"ps"},
+		Short:   "Lists the tasks of a service",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := client(cmd).ListTasks(args[0])
+			cli.Output(templateFor(T_TASKS, v), e)
+		},
+	}
+
+	swarmServiceScaleCmd = &cobra.Command{
+		Use:   "scale [name] [replicas]",
+		Short: "Scales a replicated service to the number of replicas",
+		Run:   scaleService,
+	}
+
+	swarmServiceRemoveCmd = &cobra.Command{
+		Use:   "destroy [name]",
+		Short: "Removes a service and its tasks",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			cli.ConfirmOrExit(fmt.Sprintf("Destroy service '%s' and its tasks", args[0]), "", "")
+			if err := client(cmd).RemoveService(args[0]); err != nil {
+				exitWithError(err)
+			}
+			fmt.Printf("service/%s destroyed\n", args[0])
+		},
+	}
+)
+
+func init() {
+	swarmServiceListCmd.Flags().StringP(STACK_FLAG, "s", "", "Only list the services of the stack")
+	swarmServiceScaleCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the service to converge")
+	swarmServiceScaleCmd.Flags().DurationP(TIMEOUT_FLAG, "t", 0, "Max duration to wait for the service to converge (ex. 90s | 2m)")
+	swarmServiceCmd.AddCommand(swarmServiceListCmd, swarmServiceGetCmd, swarmServiceTasksCmd, swarmServiceScaleCmd, swarmServiceRemoveCmd)
+}
+
+func scaleService(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+		return
+	}
+
+	replicas, err := strconv.Atoi(args[1])
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	if replicas == 0 {
+		cli.ConfirmOrExit(fmt.Sprintf("Scale service '%s' to 0 replicas", args[0]), "", "")
+	}
+
+	v, e := client(cmd).ScaleService(args[0], replicas)
+	if e != nil {
+		exitWithError(e)
+	}
+	if wait, _ := cmd.Flags().GetBool(WAIT_FLAG); wait {
+		if err := client(cmd).WaitForService(args[0], waitTimeout(cmd)); err != nil {
+			exitWithError(err)
+		}
+	}
+	cli.Output(templateFor(T_SERVICES, []*swarm.Service{v}), nil)
+}
@@ -0,0 +1,119 @@
+package swarm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/swarm"
+	"github.com/spf13/cobra"
+)
+
+const (
+	STACK_FLAG   string = "stack"
+	WAIT_FLAG    string = "wait"
+	TIMEOUT_FLAG string = "wait-timeout"
+)
+
+var log = logger.GetLogger("depcon.swarm")
+
+var (
+	swarmDeployCmd = &cobra.Command{
+		Use:   "deploy [compose-file(.yml | .yaml | .json)]",
+		Short: "Deploys the services within a compose file as a stack",
+		Long: `Deploys the services within a compose file as a stack
+
+    The compose file is rendered with the template context and ${PARAMS} exactly as Marathon
+    descriptors are and deployed as 'docker stack deploy' would: services and networks are
+    named {stack}_{name} and existing services are updated.  Keys swarm services don't support
+    (eg. build, depends_on) are reported and ignored`,
+		Run: deployStack,
+	}
+
+	swarmStackCmd = &cobra.Command{
+		Use:     "stack",
+		Aliases: []string{"stacks"},
+		Short:   "Manage Docker Swarm stacks",
+		Long: `Manage stacks of services deployed from compose files
+
+    See stack's subcommands for available choices`,
+	}
+
+	swarmStackListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List all stacks",
+		Run: func(cmd *cobra.Command, args []string) {
+			v, e := client(cmd).ListStacks()
+			cli.Output(templateFor(T_STACKS, v), e)
+		},
+	}
+
+	swarmStackRemoveCmd = &cobra.Command{
+		Use:   "destroy [name]",
+		Short: "Removes the services and networks of a stack",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			cli.ConfirmOrExit(fmt.Sprintf("Destroy stack '%s' and its services", args[0]), "", "")
+			if err := client(cmd).RemoveStack(args[0]); err != nil {
+				exitWithError(err)
+			}
+			fmt.Printf("stack/%s destroyed\n", args[0])
+		},
+	}
+)
+
+func init() {
+	swarmDeployCmd.Flags().StringP(STACK_FLAG, "s", "", "Name of the stack (required)")
+	swarmDeployCmd.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the services to converge")
+	swarmDeployCmd.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for the services to converge (ex. 90s | 2m)")
+	marathon.ApplyDescriptorFlags(swarmDeployCmd)
+	swarmStackCmd.AddCommand(swarmStackListCmd, swarmStackRemoveCmd)
+}
+
+func deployStack(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+	name, _ := cmd.Flags().GetString(STACK_FLAG)
+	if name == "" {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s must be specified", STACK_FLAG)))
+	}
+
+	wait, _ := cmd.Flags().GetBool(WAIT_FLAG)
+	docs := marathon.RenderDocuments(cmd, args[0])
+	if len(docs) != 1 {
+		exitWithError(fmt.Errorf("%s must contain a single compose document", args[0]))
+	}
+	stack, err := swarm.ConvertCompose(name, docs[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	for _, w := range stack.Warnings {
+		log.Warning(w)
+	}
+
+	results, err := client(cmd).DeployStack(stack)
+	if err != nil {
+		exitWithError(err)
+	}
+	if wait {
+		for _, r := range results {
+			if err := client(cmd).WaitForService(r.Service, waitTimeout(cmd)); err != nil {
+				exitWithError(err)
+			}
+		}
+	}
+	cli.Output(templateFor(T_DEPLOYED, results), nil)
+}
+
+// Returns --wait-timeout or swarm.DefaultTimeout when unspecified
+func waitTimeout(cmd *cobra.Command) time.Duration {
+	if timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG); timeout > 0 {
+		return timeout
+	}
+	return swarm.DefaultTimeout
+}
@@ -0,0 +1,139 @@
+package swarm
+
+import (
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/cliconfig"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/swarm"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	HOST_FLAG        string = "host"
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	ENV_NAME         string = "env_name"
+)
+
+var (
+	swarmCmd = &cobra.Command{
+		Use:   "swarm",
+		Short: "Deploy and manage Docker Swarm stacks and services",
+		Long: `Deploy and manage Docker Swarm stacks and services from compose files using the same
+templating, params and template contexts as Marathon
+
+    The swarm manager is selected by the 'swarm' block of the environment, the variables used
+    by the docker CLI ($DOCKER_HOST, $DOCKER_CERT_PATH, $DOCKER_TLS_VERIFY) or the --host flag
+
+    See swarm's subcommands for available choices`,
+	}
+	swarmClient swarm.Swarm
+	configFile  *cliconfig.ConfigFile
+)
+
+// Associates the swarm commands to the given command
+func AddSwarmToCmd(rc *cobra.Command, c *cliconfig.ConfigFile) {
+	configFile = c
+	backend.Register(cliconfig.TypeSwarm, newBackend)
+	rc.AddCommand(swarmCmd)
+}
+
+func init() {
+	swarmCmd.PersistentFlags().String(HOST_FLAG, "", "Docker engine of a swarm manager overriding the environment and $DOCKER_HOST")
+	swarmCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	swarmCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+	swarmCmd.AddCommand(swarmDeployCmd, swarmStackCmd, swarmServiceCmd)
+}
+
+func client(cmd *cobra.Command) swarm.Swarm {
+	if swarmClient == nil {
+		o := overrides{}
+		o.host, _ = cmd.Flags().GetString(HOST_FLAG)
+		o.insecure, _ = cmd.Flags().GetBool(INSECURE_FLAG)
+		o.allowWrite, _ = cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+		c, err := newClient(viper.GetString(ENV_NAME), o)
+		if err != nil {
+			exitWithError(err)
+		}
+		swarmClient = c
+	}
+	return swarmClient
+}
+
+// Settings of the invocation overriding those of the environment
+type overrides struct {
+	host                 string
+	insecure, allowWrite bool
+}
+
+// Creates the client of environment {envName} falling back to the variables of the docker CLI for
+// settings neither the environment nor {o} specify
+func newClient(envName string, o overrides) (swarm.Swarm, error) {
+	config := swarm.DefaultConfig()
+	readOnly := false
+	if configFile != nil {
+		if env, err := configFile.GetEnvironment(envName); err == nil {
+			if env.Swarm != nil {
+				if env.Swarm.Host != "" {
+					config.Host = env.Swarm.Host
+				}
+				if !env.Swarm.TLS.IsEmpty() {
+					config.TLS = env.Swarm.TLS
+					config.Insecure = false
+				}
+				readOnly = env.Swarm.ReadOnly
+			}
+			if env.Marathon != nil && env.Marathon.ReadOnly {
+				readOnly = true
+			}
+		}
+	}
+	if o.host != "" {
+		config.Host = o.host
+	}
+	if o.insecure {
+		config.Insecure = true
+	}
+
+	opts := &swarm.SwarmOptions{Retry: httpclient.DefaultRetryPolicy()}
+	opts.ReadOnly = readOnly && !o.allowWrite
+	if progress := cli.ActiveProgress(); progress != nil {
+		opts.Progress = progress
+	}
+	return swarm.NewSwarmClient(config, opts)
+}
+
+// Creates the backend of the workload commands.  The variables of the docker CLI are only fallen back to
+// when the backend is selected explicitly
+func newBackend(ctx *backend.Context) (backend.ClusterBackend, error) {
+	if !ctx.Selected && !configured(ctx.EnvName) {
+		return nil, &backend.NotConfiguredError{Backend: cliconfig.TypeSwarm, EnvName: ctx.EnvName}
+	}
+	c, err := newClient(ctx.EnvName, overrides{insecure: ctx.Insecure, allowWrite: ctx.AllowWrite})
+	if err != nil {
+		return nil, err
+	}
+	return backend.NewSwarmBackend(c), nil
+}
+
+// Returns true if environment {envName} has a 'swarm' block
+func configured(envName string) bool {
+	if configFile == nil {
+		return false
+	}
+	env, err := configFile.GetEnvironment(envName)
+	return err == nil && env.Swarm != nil
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
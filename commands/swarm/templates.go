@@ -0,0 +1,105 @@
+package swarm
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/swarm"
+)
+
+const (
+	T_SERVICES = `
+{{ "NAME" | header }}	{{ "MODE" | header }}	{{ "REPLICAS" | header }}	{{ "IMAGE" | header }}	{{ "PORTS" | header }}
+{{ range . }}{{ .Spec.Name }}	{{ . | mode }}	{{ . | replicas }}	{{ .Spec.TaskTemplate.ContainerSpec.Image | image }}	{{ . | ports }}
+{{end}}`
+
+	T_SERVICE = `
+{{ "Name:" }}	{{ .Spec.Name }}
+{{ "ID:" }}	{{ .ID }}
+{{ "Mode:" }}	{{ . | mode }}
+{{ "Replicas:" }}	{{ . | replicas }}
+{{ "Image:" }}	{{ .Spec.TaskTemplate.ContainerSpec.Image | image }}
+{{ "Ports:" }}	{{ . | ports }}
+{{ "Created:" }}	{{ .CreatedAt }}
+{{ "Updated:" }}	{{ .UpdatedAt }}
+{{ with .UpdateStatus }}{{ "Update:" }}	{{ .State }} {{ .Message }}
+{{end}}{{ "Labels:" }}
+{{ range $key, $value := .Spec.Labels }}		{{ $key | pad }} {{ $value }}
+{{end}}`
+
+	T_TASKS = `
+{{ "ID" | header }}	{{ "SLOT" | header }}	{{ "NODE" | header }}	{{ "DESIRED" | header }}	{{ "STATE" | header }}	{{ "ERROR" | header }}
+{{ range . }}{{ .ID }}	{{ .Slot | intToString }}	{{ .NodeID }}	{{ .DesiredState }}	{{ .Status.State }}	{{ .Status.Err }}
+{{end}}`
+
+	T_STACKS = `
+{{ "NAME" | header }}	{{ "SERVICES" | header }}
+{{ range . }}{{ .Name }}	{{ .Services | intToString }}
+{{end}}`
+
+	T_DEPLOYED = `
+{{ "SERVICE" | header }}	{{ "ID" | header }}	{{ "ACTION" | header }}
+{{ range . }}{{ .Service }}	{{ .ID }}	{{ .Action }}
+{{end}}`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data, Funcs: buildFuncMap()}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
+
+func buildFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"mode":     mode,
+		"replicas": replicas,
+		"image":    image,
+		"ports":    ports,
+	}
+}
+
+func mode(s *swarm.Service) string {
+	if s.Spec.Mode.Global != nil {
+		return "global"
+	}
+	return "replicated"
+}
+
+func replicas(s *swarm.Service) string {
+	if s.Spec.Mode.Replicated == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d", s.Spec.Mode.Replicated.Replicas)
+}
+
+// Removes the digest the engine pins images to (eg. nginx:1.19@sha256:...)
+func image(image string) string {
+	if i := strings.Index(image, "@"); i > 0 {
+		return image[:i]
+	}
+	return image
+}
+
+// Formats ports as docker does (eg. *:8080->80/tcp)
+func ports(s *swarm.Service) string {
+	list := []string{}
+	if s.Endpoint != nil {
+		for _, p := range s.Endpoint.Ports {
+			list = append(list, fmt.Sprintf("*:%d->%d/%s", p.PublishedPort, p.TargetPort, p.Protocol))
+		}
+	}
+	return strings.Join(list, ",")
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/gitrepo"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	FlagRepo     = "repo"
+	FlagBranch   = "branch"
+	FlagPath     = "path"
+	FlagInterval = "interval"
+	FlagCheckout = "checkout"
+	FlagOnce     = "once"
+	FlagAuditLog = "audit-log"
+
+	T_SYNC = `
+{{ "FILE" | header }}	{{ "ID" | header }}	{{ "KIND" | header }}	{{ "ACTION" | header }}	{{ "RESULT" | header }}	{{ "FIELDS" | header }}
+{{ range . }}{{ .File }}	{{ .ID }}	{{ .Kind }}	{{ .Action }}	{{ .Result }}	{{ len .Fields | intToString }}
+{{end}}`
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Continuously applies the descriptors of a git repository to the environment",
+	Long: `Watches a git repository of descriptors, renders them with the template context, diffs them against the
+environment and applies the creates and updates which bring it in line.  Each sync making changes (or failing)
+is recorded in the audit log (~/.depcon/audit.log).  Applications removed from the repository are not removed.
+
+    eg. depcon sync -e prod --repo git@github.com:acme/deployments.git --path prod/ --interval 1m
+        depcon sync -e prod --repo git@github.com:acme/deployments.git --path prod/ --once --dry-run`,
+	Run: runSync,
+}
+
+func init() {
+	syncCmd.Flags().String(FlagRepo, "", "URL of the git repository of descriptors")
+	syncCmd.Flags().String(FlagBranch, "", "Branch to sync.  Default: the repository's default branch")
+	syncCmd.Flags().String(FlagPath, "", "Directory of the descriptors within the repository")
+	syncCmd.Flags().Duration(FlagInterval, time.Minute, "Time between syncs")
+	syncCmd.Flags().String(FlagCheckout, "", "Directory the repository is checked out to.  Default: within ~/.depcon/sync")
+	syncCmd.Flags().Bool(FlagOnce, false, "Sync once and exit, non-zero when a change fails")
+	syncCmd.Flags().Bool(cmdmarathon.DRYRUN_FLAG, false, "Report the changes without applying them")
+	syncCmd.Flags().BoolP(cmdmarathon.WAIT_FLAG, "w", false, "Wait for each change to complete before the next")
+	syncCmd.Flags().DurationP(cmdmarathon.TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait for each change (ex. 90s | 2m).  0 waits forever")
+	cmdmarathon.ApplyCancelFlags(syncCmd.Flags())
+	cmdmarathon.ApplyReadyFlags(syncCmd.Flags())
+	syncCmd.Flags().Int(cmdmarathon.WORKERS_FLAG, 1, "Number of changes made at once.  Every change is attempted and failures are summarized")
+	syncCmd.Flags().String(cmdmarathon.TEMPLATE_CTX_FLAG, cmdmarathon.DEFAULT_CTX, "Template context relative to the root of the repository")
+	syncCmd.Flags().StringSliceP(cmdmarathon.PARAMS_FLAG, "p", nil, "Adds a param(s) that can be used for substitution (eg. -p TAG=1.2)")
+	syncCmd.Flags().BoolP(cmdmarathon.IGNORE_MISSING, "i", false, "Ignore missing ${PARAMS} and template fields rather than failing the sync")
+	syncCmd.Flags().String(FlagAuditLog, "", "Audit log file.  Default: ~/.depcon/"+audit.DefaultFilename)
+	syncCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	cmdmarathon.ApplySignatureFlags(syncCmd)
+}
+
+func runSync(cmd *cobra.Command, args []string) {
+	repo, _ := cmd.Flags().GetString(FlagRepo)
+	if repo == "" {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("--%s is required", FlagRepo)))
+	}
+	envName := viper.GetString(ViperEnv)
+	client, err := environmentClients(cmd)(envName)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	tempctx, _ := cmd.Flags().GetString(cmdmarathon.TEMPLATE_CTX_FLAG)
+	s := &reconcile.Syncer{Environment: envName, Client: client, Load: deployLoadOptions(cmd, envName, checkoutPath(cmd, tempctx))}
+	s.Repo = &gitrepo.Repo{URL: repo}
+	s.Repo.Branch, _ = cmd.Flags().GetString(FlagBranch)
+	s.Repo.Dir = syncCheckout(cmd)
+	s.Path, _ = cmd.Flags().GetString(FlagPath)
+	s.DryRun, _ = cmd.Flags().GetBool(cmdmarathon.DRYRUN_FLAG)
+	s.Wait, _ = cmd.Flags().GetBool(cmdmarathon.WAIT_FLAG)
+	s.Timeout = cmdmarathon.WaitTimeout(cmd, 0)
+	s.Parallel, _ = cmd.Flags().GetInt(cmdmarathon.WORKERS_FLAG)
+	auditLog, _ := cmd.Flags().GetString(FlagAuditLog)
+	if auditLog == "" {
+		auditLog = filepath.Join(cliconfig.ConfigDir(), audit.DefaultFilename)
+	}
+	s.Audit = audit.New(auditLog)
+
+	if once, _ := cmd.Flags().GetBool(FlagOnce); once {
+		result, err := s.Sync()
+		if result.Changes != nil {
+			cli.Output(templateFor(T_SYNC, result.Changed()), nil)
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	interval, _ := cmd.Flags().GetDuration(FlagInterval)
+	log.Info("Syncing %s (%s) to '%s' every %s", repo, s.Path, envName, interval)
+	s.Run(interval, stop, func(result *reconcile.SyncResult, err error) {
+		if err != nil {
+			cli.Output(nil, err)
+		}
+		if changed := result.Changed(); len(changed) > 0 {
+			cli.Output(templateFor(T_SYNC, changed), nil)
+		} else if err == nil {
+			log.Info("Revision %s: %d descriptor(s) in sync", result.Revision, len(result.Changes))
+		}
+	})
+}
+
+// Returns the options rendering descriptors with the template context {tempctx} of environment {envName}
+// and the -p params
+func descriptorLoadOptions(cmd *cobra.Command, envName, tempctx string) *reconcile.LoadOptions {
+	ignore, _ := cmd.Flags().GetBool(cmdmarathon.IGNORE_MISSING)
+	params, _ := cmd.Flags().GetStringSlice(cmdmarathon.PARAMS_FLAG)
+
+	opts := &reconcile.LoadOptions{Params: map[string]string{}, IgnoreMissing: ignore}
+	for _, p := range params {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			opts.Params[kv[0]] = kv[1]
+		}
+	}
+	opts.Render = func(filename string) (string, error) {
+		return cmdmarathon.RenderDescriptor(filename, tempctx, envName, opts.Params, ignore)
+	}
+	if tempctx != "" {
+		opts.Exclude = []string{tempctx}
+	}
+	return opts
+}
+
+// Returns descriptorLoadOptions which also require the descriptors deployed to environment {envName} to be
+// signed by its trusted keys (see cmdmarathon.SignatureVerifier)
+func deployLoadOptions(cmd *cobra.Command, envName, tempctx string) *reconcile.LoadOptions {
+	opts := descriptorLoadOptions(cmd, envName, tempctx)
+	opts.Verify = cmdmarathon.SignatureVerifier(cmd, envName)
+	return opts
+}
+
+// Returns {name} relative to the root of the checkout unless it is absolute
+func checkoutPath(cmd *cobra.Command, name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(syncCheckout(cmd), name)
+}
+
+// Returns --checkout or a directory within the config directory named after the repository and branch
+func syncCheckout(cmd *cobra.Command) string {
+	repo, _ := cmd.Flags().GetString(FlagRepo)
+	return checkoutDir(cmd, "sync", repo)
+}
+
+// Returns --checkout or a directory within ~/.depcon/{kind} named after the repository {repo} and --branch
+func checkoutDir(cmd *cobra.Command, kind, repo string) string {
+	if checkout, _ := cmd.Flags().GetString(FlagCheckout); checkout != "" {
+		return checkout
+	}
+	branch, _ := cmd.Flags().GetString(FlagBranch)
+	return filepath.Join(cliconfig.ConfigDir(), kind, fmt.Sprintf("%x", sha1.Sum([]byte(repo+"#"+branch)))[:12])
+}
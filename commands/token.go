@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/dcos"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	T_TOKENS = `
+{{ "ENVIRONMENT" | header }}	{{ "STATUS" | header }}	{{ "EXPIRES" | header }}	{{ "REMAINING" | header }}
+{{ range . }}{{ .Environment }}	{{ .Status | status }}	{{ .ExpiresAt }}	{{ .Remaining }}
+{{end}}`
+
+	TokenOK       = "ok"
+	TokenExpiring = "expiring"
+	TokenExpired  = "expired"
+	TokenNone     = "none"
+	TokenUnknown  = "unknown expiry"
+)
+
+// TokenSummary is the cached token of an environment authenticating with tokens (DC/OS or depcon login)
+type TokenSummary struct {
+	Environment string     `json:"environment"`
+	Status      string     `json:"status"`
+	Expires     *time.Time `json:"expires,omitempty"`
+}
+
+func (t *TokenSummary) ExpiresAt() string {
+	if t.Expires == nil {
+		return "-"
+	}
+	return t.Expires.Local().Format("2006-01-02 15:04:05 MST")
+}
+
+func (t *TokenSummary) Remaining() string {
+	if t.Expires == nil || t.Status == TokenExpired {
+		return "-"
+	}
+	return time.Until(*t.Expires).Round(time.Second).String()
+}
+
+var configTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Tokens of environments authenticating with DC/OS or 'depcon login'",
+	Long: `Tokens are cached between commands along with the time they expire.  Tokens about to expire are renewed
+when a command starts so long running commands (eg. CI pipelines waiting on deployments) aren't cut short
+by a 401.  A warning is logged when the renewal fails.`,
+}
+
+var configTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the cached tokens of the environments and when they expire",
+	Run: func(cmd *cobra.Command, args []string) {
+		summaries := []*TokenSummary{}
+		for _, name := range tokenEnvironments() {
+			summaries = append(summaries, tokenSummary(name))
+		}
+		cli.Output(templateFor(T_TOKENS, summaries), nil)
+	},
+}
+
+var configTokenRefreshCmd = &cobra.Command{
+	Use:   "refresh [env]",
+	Short: "Logs into the environment [env] (default: the current environment) replacing its cached token",
+	Long: `Logs into the environment [env] replacing its cached token.  Run it before a long pipeline (or when a
+command warns that a token couldn't be renewed) to start with a token lasting its full lifetime.
+
+    eg. depcon config env token refresh prod`,
+	Run: refreshToken,
+}
+
+func init() {
+	configTokenCmd.AddCommand(configTokenListCmd, configTokenRefreshCmd)
+	configEnvCmd.AddCommand(configTokenCmd)
+}
+
+func refreshToken(cmd *cobra.Command, args []string) {
+	name := viper.GetString(ViperEnv)
+	if len(args) > 0 {
+		name = args[0]
+	}
+	env, err := configFile.GetEnvironment(name)
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, err))
+	}
+	if env.Marathon == nil || !usesTokens(env.Marathon) {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Environment '%s' does not authenticate with tokens (auth: %s, %s or %s)", name, cliconfig.AuthDCOS, cliconfig.AuthOIDC, cliconfig.AuthLDAP)))
+	}
+
+	opts := &marathon.MarathonOptions{}
+	if _, err := cmdmarathon.ApplyConnection(name, env.Marathon, opts); err != nil {
+		exitWithError(err)
+	}
+	if _, err := opts.Authenticator.Token(true); err != nil {
+		exitWithError(err)
+	}
+	cli.Output(templateFor(T_TOKENS, []*TokenSummary{tokenSummary(name)}), nil)
+}
+
+// Returns the sorted environments authenticating with tokens
+func tokenEnvironments() []string {
+	names := []string{}
+	for name, env := range configFile.Environments {
+		if env != nil && env.Marathon != nil && usesTokens(env.Marathon) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func usesTokens(service *cliconfig.ServiceConfig) bool {
+	return service.IsDCOS() || service.UsesLogin()
+}
+
+func tokenSummary(name string) *TokenSummary {
+	store := cliconfig.TokenStore{}
+	summary := &TokenSummary{Environment: name, Status: TokenNone}
+	token := store.Load(name)
+	if token == "" {
+		return summary
+	}
+	expires := store.LoadExpiry(name)
+	if expires.IsZero() {
+		expires = dcos.TokenExpiry(token)
+	}
+	switch remaining := time.Until(expires); {
+	case expires.IsZero():
+		summary.Status = TokenUnknown
+		return summary
+	case remaining <= 0:
+		summary.Status = TokenExpired
+	case remaining < dcos.TokenExpiryWarning:
+		summary.Status = TokenExpiring
+	default:
+		summary.Status = TokenOK
+	}
+	summary.Expires = &expires
+	return summary
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ContainX/depcon/cliconfig"
+	cmdmarathon "github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+)
+
+const (
+	T_ENV_VERIFY = `
+{{ "ENVIRONMENT" | header }}	{{ "VERSION" | header }}	{{ "LEADER" | header }}	{{ "LATENCY" | header }}	{{ "STATUS" | header }}
+{{ range . }}{{ .Name }}	{{ .Version }}	{{ .Leader }}	{{ .Latency }}	{{ .Status | status }}
+{{end}}`
+)
+
+// Result of verifying connectivity to an environment
+type EnvironmentVerification struct {
+	Name    string `json:"name"`
+	Host    string `json:"host"`
+	Status  string `json:"status"`
+	Version string `json:"version,omitempty"`
+	Leader  string `json:"leader,omitempty"`
+	Latency string `json:"latency,omitempty"`
+	err     error
+}
+
+var configVerifyCmd = &cobra.Command{
+	Use:   "verify [name]",
+	Short: "Verifies connectivity and credentials for environment [name] (or all environments)",
+	Long: `Authenticates against the environment and retrieves the Marathon server info reporting the version,
+current leader and request latency.  Exits with a non-zero status if any environment fails (the exit code
+of the first failure, eg. 6 for authentication or 7 for connection errors)`,
+	Run: func(cmd *cobra.Command, args []string) {
+		names := args
+		if len(names) == 0 {
+			names = configFile.GetEnvironments()
+			sort.Strings(names)
+		}
+
+		insecure, _ := cmd.Flags().GetBool(cmdmarathon.INSECURE_FLAG)
+		results := []*EnvironmentVerification{}
+		var failure error
+		for _, name := range names {
+			configEnv, err := configFile.GetEnvironment(name)
+			if err != nil {
+				cli.Output(nil, err)
+				return
+			}
+			// ECS environments have no server to verify
+			if configEnv.Marathon == nil {
+				continue
+			}
+			result := verifyEnvironment(name, configEnv.Marathon, insecure)
+			if failure == nil {
+				failure = result.err
+			}
+			results = append(results, result)
+		}
+
+		cli.Output(templateFor(T_ENV_VERIFY, results), nil)
+		if failure != nil {
+			cli.Exit(failure)
+		}
+	},
+}
+
+func verifyEnvironment(name string, service *cliconfig.ServiceConfig, insecure bool) *EnvironmentVerification {
+	result := &EnvironmentVerification{Name: name, Host: service.HostUrl}
+
+	client, err := cmdmarathon.NewClient(name, service, &marathon.MarathonOptions{TLSAllowInsecure: insecure})
+	if err != nil {
+		result.Status, result.err = "FAILED: "+err.Error(), err
+		return result
+	}
+
+	started := time.Now()
+	info, err := client.GetMarathonInfo()
+	result.Latency = time.Since(started).Round(time.Millisecond).String()
+	if err != nil {
+		result.Status, result.err = "FAILED: "+err.Error(), err
+		return result
+	}
+
+	result.Status = "OK"
+	result.Version = info.Version
+	result.Leader = info.Leader
+	return result
+}
+
+func init() {
+	configVerifyCmd.Flags().Bool(cmdmarathon.INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+}
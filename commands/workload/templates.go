@@ -0,0 +1,40 @@
+package workload
+
+import (
+	"io"
+
+	"github.com/ContainX/depcon/pkg/cli"
+)
+
+const (
+	T_WORKLOADS = `
+{{ "ID" | header }}	{{ "KIND" | header }}	{{ "INSTANCES" | header }}	{{ "RUNNING" | header }}	{{ "HEALTHY" | header }}	{{ "IMAGE" | header }}	{{ "VERSION" | header }}
+{{ range . }}{{ .ID }}	{{ .Kind }}	{{ .Instances | intToString }}	{{ .Running | intToString }}	{{ .Healthy | intToString }}	{{ .Image }}	{{ .Version }}
+{{end}}`
+
+	T_WORKLOAD = `
+{{ "ID:" }}	{{ .ID }}
+{{ "Kind:" }}	{{ .Kind }}
+{{ "Instances:" }}	{{ .Instances | intToString }}
+{{ "Running:" }}	{{ .Running | intToString }}
+{{ "Healthy:" }}	{{ .Healthy | intToString }}
+{{ "Image:" }}	{{ .Image }}
+{{ "Version:" }}	{{ .Version }}
+`
+)
+
+type Templated struct {
+	cli.FormatData
+}
+
+func templateFor(template string, data interface{}) Templated {
+	return Templated{cli.FormatData{Template: template, Data: data}}
+}
+
+func (d Templated) ToColumns(output io.Writer) error {
+	return d.FormatData.ToColumns(output)
+}
+
+func (d Templated) Data() cli.FormatData {
+	return d.FormatData
+}
@@ -0,0 +1,285 @@
+package workload
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/backend"
+	"github.com/ContainX/depcon/commands/marathon"
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	BACKEND_FLAG     string = "backend"
+	INSECURE_FLAG    string = "insecure"
+	ALLOW_WRITE_FLAG string = "allow-write"
+	FORCE_FLAG       string = "force"
+	WAIT_FLAG        string = "wait"
+	TIMEOUT_FLAG     string = "wait-timeout"
+	STDERR_FLAG      string = "stderr"
+	FOLLOW_FLAG      string = "follow"
+	POLL_FLAG        string = "poll"
+	ENV_NAME         string = "env_name"
+)
+
+var log = logger.GetLogger("depcon.workload")
+
+var ErrorNoBackend = errors.New("No backend is available for this environment")
+
+var (
+	workloadCmd = &cobra.Command{
+		Use:     "workload",
+		Aliases: []string{"wl"},
+		Short:   "Deploy and operate workloads on any supported backend",
+		Long: `Deploy and operate workloads (Marathon apps, Kubernetes deployments, ECS services, Nomad jobs
+and Swarm services) through the backend neutral commands
+
+    The backend is selected with --backend and defaults to the only backend the environment
+    configures.  Descriptors are the documents of the backend (eg. a Nomad job or a compose file
+    naming its stack) rendered with the template context and ${PARAMS} exactly as Marathon
+    descriptors are.  Operations a backend can't perform (eg. scaling Nomad jobs) are refused
+
+    See workload's subcommands for available choices`,
+	}
+
+	deployCmd = &cobra.Command{
+		Use:   "deploy [file(.json | .yaml)]",
+		Short: "Deploys the workloads within a descriptor",
+		Run:   deployWorkloads,
+	}
+
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "Lists all workloads",
+		Run: func(cmd *cobra.Command, args []string) {
+			v, e := clusterBackend(cmd).List()
+			cli.Output(templateFor(T_WORKLOADS, v), e)
+		},
+	}
+
+	getCmd = &cobra.Command{
+		Use:   "get [id]",
+		Short: "Gets a workload by id",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			v, e := clusterBackend(cmd).Get(args[0])
+			cli.Output(templateFor(T_WORKLOAD, v), e)
+		},
+	}
+
+	scaleCmd = &cobra.Command{
+		Use:   "scale [id] [instances]",
+		Short: "Scales a workload to the given number of instances",
+		Run:   scaleWorkload,
+	}
+
+	destroyCmd = &cobra.Command{
+		Use:   "destroy [id]",
+		Short: "Removes a workload and its instances",
+		Run:   destroyWorkload,
+	}
+
+	waitCmd = &cobra.Command{
+		Use:   "wait [id]",
+		Short: "Waits until all instances of a workload are running and healthy",
+		Run: func(cmd *cobra.Command, args []string) {
+			if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+				return
+			}
+			if err := clusterBackend(cmd).Wait(args[0], waitTimeout(cmd)); err != nil {
+				exitWithError(err)
+			}
+			v, e := clusterBackend(cmd).Get(args[0])
+			cli.Output(templateFor(T_WORKLOAD, v), e)
+		},
+	}
+
+	logsCmd = &cobra.Command{
+		Use:   "logs [id]",
+		Short: "Log or Tail the logs of a workload's instances",
+		Run:   showLogs,
+	}
+
+	instance backend.ClusterBackend
+)
+
+// Associates the workload commands to the given command
+func AddWorkloadToCmd(rc *cobra.Command) {
+	rc.AddCommand(workloadCmd)
+}
+
+func init() {
+	workloadCmd.PersistentFlags().String(BACKEND_FLAG, "", "Backend operating the workloads (marathon, kubernetes, ecs, nomad or swarm).  Default: the only backend the environment configures")
+	workloadCmd.PersistentFlags().Bool(INSECURE_FLAG, false, "Skips Insecure TLS/HTTPS Certificate checks")
+	workloadCmd.PersistentFlags().Bool(ALLOW_WRITE_FLAG, false, "Permits changes against an environment marked read-only")
+
+	deployCmd.Flags().BoolP(FORCE_FLAG, "f", false, "Updates workloads which already exist")
+	marathon.ApplyDescriptorFlags(deployCmd)
+	for _, c := range []*cobra.Command{deployCmd, scaleCmd, waitCmd} {
+		if c != waitCmd {
+			c.Flags().BoolP(WAIT_FLAG, "w", false, "Wait for the workloads to become healthy")
+		}
+		c.Flags().DurationP(TIMEOUT_FLAG, "t", time.Duration(0), "Max duration to wait (ex. 90s | 2m).  Default: the backend's timeout")
+	}
+	logsCmd.Flags().BoolP(STDERR_FLAG, "s", false, "Show StdErr vs default StdOut log")
+	logsCmd.Flags().BoolP(FOLLOW_FLAG, "f", false, "Tail/Follow log")
+	logsCmd.Flags().IntP(POLL_FLAG, "p", 5, "Log poll time (duration) in seconds")
+	marathon.ApplyLogFilterFlags(logsCmd.Flags())
+	workloadCmd.AddCommand(deployCmd, listCmd, getCmd, scaleCmd, destroyCmd, waitCmd, logsCmd)
+}
+
+// Returns the backend selected by --backend or the only backend the environment configures
+func clusterBackend(cmd *cobra.Command) backend.ClusterBackend {
+	if instance == nil {
+		ctx := &backend.Context{EnvName: viper.GetString(ENV_NAME)}
+		ctx.Insecure, _ = cmd.Flags().GetBool(INSECURE_FLAG)
+		ctx.AllowWrite, _ = cmd.Flags().GetBool(ALLOW_WRITE_FLAG)
+
+		if name, _ := cmd.Flags().GetString(BACKEND_FLAG); name != "" {
+			ctx.Selected = true
+			b, err := backend.New(name, ctx)
+			if err != nil {
+				exitWithError(err)
+			}
+			instance = b
+			return instance
+		}
+
+		configured := []backend.ClusterBackend{}
+		for _, name := range backend.Names() {
+			b, err := backend.New(name, ctx)
+			if _, ok := err.(*backend.NotConfiguredError); ok {
+				continue
+			}
+			if err != nil {
+				exitWithError(err)
+			}
+			configured = append(configured, b)
+		}
+		switch len(configured) {
+		case 0:
+			exitWithError(ErrorNoBackend)
+		case 1:
+			instance = configured[0]
+		default:
+			names := []string{}
+			for _, b := range configured {
+				names = append(names, b.Name())
+			}
+			exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Specify --%s (configured: %s)", BACKEND_FLAG, strings.Join(names, ", "))))
+		}
+	}
+	return instance
+}
+
+func deployWorkloads(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	opts := &backend.DeployOptions{Timeout: waitTimeout(cmd)}
+	opts.Force, _ = cmd.Flags().GetBool(FORCE_FLAG)
+	opts.Wait, _ = cmd.Flags().GetBool(WAIT_FLAG)
+
+	deployed := []*backend.Workload{}
+	for _, doc := range marathon.RenderDocuments(cmd, args[0]) {
+		w, err := clusterBackend(cmd).Deploy(doc, opts)
+		if err != nil {
+			if id, _ := doc["id"].(string); id != "" {
+				log.Error("Unable to deploy '%s'", id)
+			}
+			exitWithError(err)
+		}
+		deployed = append(deployed, w)
+	}
+	cli.Output(templateFor(T_WORKLOADS, deployed), nil)
+}
+
+func scaleWorkload(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 2) {
+		return
+	}
+
+	instances, err := strconv.Atoi(args[1])
+	if err != nil {
+		exitWithError(cli.WithExitCode(cli.ExitUsage, fmt.Errorf("Invalid instances '%s'", args[1])))
+	}
+	if instances == 0 {
+		cli.ConfirmOrExit(fmt.Sprintf("Scale '%s' to 0 instances", args[0]), "environment", viper.GetString(ENV_NAME))
+	}
+	if err := clusterBackend(cmd).Scale(args[0], instances); err != nil {
+		exitWithError(err)
+	}
+	if wait, _ := cmd.Flags().GetBool(WAIT_FLAG); wait {
+		if err := clusterBackend(cmd).Wait(args[0], waitTimeout(cmd)); err != nil {
+			exitWithError(err)
+		}
+	}
+	v, e := clusterBackend(cmd).Get(args[0])
+	cli.Output(templateFor(T_WORKLOAD, v), e)
+}
+
+func destroyWorkload(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	cli.ConfirmOrExit(fmt.Sprintf("Destroy '%s'", args[0]), "environment", viper.GetString(ENV_NAME))
+	if err := clusterBackend(cmd).Destroy(args[0]); err != nil {
+		exitWithError(err)
+	}
+	log.Info("'%s' was destroyed", args[0])
+}
+
+func showLogs(cmd *cobra.Command, args []string) {
+	if cli.EvalPrintUsage(Usage(cmd), args, 1) {
+		return
+	}
+
+	opts := &backend.LogOptions{}
+	opts.Stderr, _ = cmd.Flags().GetBool(STDERR_FLAG)
+	opts.Follow, _ = cmd.Flags().GetBool(FOLLOW_FLAG)
+	if poll, _ := cmd.Flags().GetInt(POLL_FLAG); poll > 0 {
+		opts.Poll = time.Duration(poll) * time.Second
+	}
+	marathon.LogFilterOptions(cmd, opts)
+
+	if opts.Follow {
+		if err := clusterBackend(cmd).Logs(args[0], opts, os.Stdout); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+	out, done := cli.StartPager(os.Stdout)
+	defer done()
+	if err := clusterBackend(cmd).Logs(args[0], opts, out); err != nil {
+		done()
+		exitWithError(err)
+	}
+}
+
+// Returns --wait-timeout which is zero (the backend's default) when unspecified
+func waitTimeout(cmd *cobra.Command) time.Duration {
+	timeout, _ := cmd.Flags().GetDuration(TIMEOUT_FLAG)
+	return timeout
+}
+
+func exitWithError(err error) {
+	cli.Output(nil, err)
+	cli.Exit(err)
+}
+
+func Usage(c *cobra.Command) func() error {
+	return func() error {
+		return c.UsageFunc()(c)
+	}
+}
@@ -26,4 +26,6 @@ type Compose interface {
 	Port(index int, proto, service, port string) error
 
 	PS(quiet bool) error
+
+	Scale(servicesScale map[string]int) error
 }
@@ -103,18 +103,27 @@ func (c *ComposeWrapper) PS(quiet bool) error {
 	return nil
 }
 
+func (c *ComposeWrapper) Scale(servicesScale map[string]int) error {
+	timeout := 10
+	return c.project.Scale(context.Background(), timeout, servicesScale)
+}
+
 func (c *ComposeWrapper) createDockerContext() (project.APIProject, error) {
 
-	if c.context.EnvParams != nil && len(c.context.EnvParams) > 0 {
+	params, err := InterpolationParams(c.context)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
 		file, err := os.Open(c.context.ComposeFile)
 		if err != nil {
 			return nil, fmt.Errorf("Error opening filename %s, %s", c.context.ComposeFile, err.Error())
 		}
-		parsed, missing := envsubst.SubstFileTokens(file, c.context.EnvParams)
-		log.Debug("Map: %v\nParsed: %s\n", c.context.EnvParams, parsed)
+		parsed, missing := envsubst.SubstTokens(file, params)
+		log.Debug("Map: %v\nParsed: %s\n", params, parsed)
 
-		if c.context.ErrorOnMissingParams && missing {
-			return nil, ErrorParamsMissing
+		if c.context.ErrorOnMissingParams && len(missing) > 0 {
+			return nil, &envsubst.MissingParamsError{Filename: c.context.ComposeFile, Params: missing}
 		}
 		file, err = ioutil.TempFile("", "depcon")
 		if err != nil {
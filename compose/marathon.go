@@ -0,0 +1,599 @@
+package compose
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/ContainX/depcon/marathon"
+)
+
+const (
+	// labels tracing the apps of a converted group back to the compose project and service
+	LabelProject = "DEPCON_COMPOSE_PROJECT"
+	LabelService = "DEPCON_COMPOSE_SERVICE"
+
+	// resources of services which don't declare limits
+	DefaultCPUs = 0.1
+	DefaultMem  = 128
+
+	// docker volume driver of named volumes which don't declare one
+	DefaultVolumeDriver = "rexray"
+)
+
+var (
+	ErrorNoServices   = errors.New("The compose file does not define any services")
+	ErrorMissingImage = errors.New("An image must be specified (build is not supported)")
+)
+
+// Service keys of the compose file which are converted to Marathon apps
+var marathonKeys = map[string]bool{
+	"image": true, "command": true, "entrypoint": true, "environment": true, "labels": true, "ports": true,
+	"volumes": true, "depends_on": true, "links": true, "deploy": true, "scale": true, "cpus": true,
+	"mem_limit": true, "privileged": true, "hostname": true, "user": true, "working_dir": true,
+	"network_mode": true, "container_name": true,
+}
+
+// GroupConversion is a compose project converted to a Marathon group with an app per service
+type GroupConversion struct {
+	Group *marathon.Group
+	// Marathon app IDs keyed by the compose service
+	Apps map[string]string
+	// keys of the compose file which were ignored
+	Warnings []string
+
+	project string
+	volumes map[string]interface{}
+}
+
+// GroupID returns the ID of the group project {project} is converted to beneath {root}
+func GroupID(root, project string) string {
+	return path.Join("/", root, marathonID(project))
+}
+
+// ConvertToGroup converts the compose file {doc} (version 2 or 3) of project {project} to a group {groupID}.
+// Each service becomes the app {groupID}/{service} labeled with the project and service
+func ConvertToGroup(groupID, project string, doc map[string]interface{}) (*GroupConversion, error) {
+	services, _ := doc["services"].(map[string]interface{})
+	if len(services) == 0 {
+		return nil, ErrorNoServices
+	}
+
+	c := &GroupConversion{
+		Group:   &marathon.Group{GroupID: groupID},
+		Apps:    map[string]string{},
+		project: project,
+	}
+	c.volumes, _ = doc["volumes"].(map[string]interface{})
+
+	names := []string{}
+	for svc := range services {
+		names = append(names, svc)
+		c.Apps[svc] = path.Join(groupID, marathonID(svc))
+	}
+	sort.Strings(names)
+	for _, svc := range names {
+		s, _ := services[svc].(map[string]interface{})
+		app, err := c.convertService(svc, s)
+		if err != nil {
+			return nil, fmt.Errorf("service '%s': %s", svc, err.Error())
+		}
+		c.Group.Apps = append(c.Group.Apps, app)
+	}
+	return c, nil
+}
+
+func (c *GroupConversion) convertService(name string, s map[string]interface{}) (*marathon.Application, error) {
+	keys := []string{}
+	for key := range s {
+		if !marathonKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		c.Warnings = append(c.Warnings, fmt.Sprintf("services.%s.%s is not supported and was ignored", name, key))
+	}
+
+	image, _ := s["image"].(string)
+	if image == "" {
+		return nil, ErrorMissingImage
+	}
+	deploy, _ := s["deploy"].(map[string]interface{})
+
+	docker := &marathon.Docker{Image: image, Network: "BRIDGE"}
+	app := &marathon.Application{
+		ID:        c.Apps[name],
+		Instances: 1,
+		CPUs:      DefaultCPUs,
+		Mem:       DefaultMem,
+		Env:       toEnv(s["environment"]),
+		Labels:    toStringMap(s["labels"]),
+		Container: &marathon.Container{Type: "DOCKER", Docker: docker},
+	}
+	for k, v := range toStringMap(deploy["labels"]) {
+		app.Labels[k] = v
+	}
+	app.Labels[LabelProject] = c.project
+	app.Labels[LabelService] = name
+
+	switch {
+	case deploy["replicas"] != nil:
+		app.Instances = toInt(deploy["replicas"])
+	case s["scale"] != nil:
+		app.Instances = toInt(s["scale"])
+	}
+	if deploy["mode"] == "global" {
+		app.Constraints = [][]string{{"hostname", "UNIQUE"}}
+		c.Warnings = append(c.Warnings, fmt.Sprintf("services.%s.deploy.mode global runs %d instance(s) on unique hosts", name, app.Instances))
+	}
+
+	limits := map[string]interface{}{}
+	if resources, ok := deploy["resources"].(map[string]interface{}); ok {
+		limits, _ = resources["limits"].(map[string]interface{})
+	}
+	if cpus := firstOf(limits["cpus"], s["cpus"]); cpus != nil {
+		n, err := strconv.ParseFloat(fmt.Sprint(cpus), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cpus '%v' must be a number", cpus)
+		}
+		app.CPUs = n
+	}
+	if memory := firstOf(limits["memory"], s["mem_limit"]); memory != nil {
+		b, err := toBytes(memory)
+		if err != nil {
+			return nil, err
+		}
+		app.Mem = float64(b) / (1 << 20)
+	}
+
+	args := toArgs(s["command"])
+	if entrypoint := toArgs(s["entrypoint"]); len(entrypoint) > 0 {
+		docker.Parameters = append(docker.Parameters, &marathon.Parameters{Key: "entrypoint", Value: entrypoint[0]})
+		args = append(entrypoint[1:], args...)
+	}
+	if len(args) > 0 {
+		app.Args = args
+	}
+	for key, param := range map[string]string{"hostname": "hostname", "user": "user", "working_dir": "workdir"} {
+		if v, _ := s[key].(string); v != "" {
+			docker.Parameters = append(docker.Parameters, &marathon.Parameters{Key: param, Value: v})
+		}
+	}
+	sort.Slice(docker.Parameters, func(i, j int) bool { return docker.Parameters[i].Key < docker.Parameters[j].Key })
+	if privileged, _ := s["privileged"].(bool); privileged {
+		docker.Privileged = true
+	}
+
+	switch s["network_mode"] {
+	case "host":
+		docker.Network = "HOST"
+	case "none":
+		docker.Network = "NONE"
+	}
+	ports, err := convertPorts(s["ports"])
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) > 0 {
+		if docker.Network != "BRIDGE" {
+			return nil, fmt.Errorf("ports can't be published with network_mode '%v'", s["network_mode"])
+		}
+		docker.PortMappings = ports
+	}
+
+	volumes, err := c.convertVolumes(name, s["volumes"])
+	if err != nil {
+		return nil, err
+	}
+	app.Container.Volumes = volumes
+
+	app.Dependencies, err = c.dependencies(s)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// Select returns the apps of {services} and the services they depend on ordered so dependencies precede
+// their dependents.  All apps are returned when no services are specified
+func (c *GroupConversion) Select(services ...string) ([]*marathon.Application, error) {
+	if len(services) == 0 {
+		return c.Group.Apps, nil
+	}
+	byID := map[string]*marathon.Application{}
+	for _, app := range c.Group.Apps {
+		byID[app.ID] = app
+	}
+
+	selected := []*marathon.Application{}
+	visited := map[string]bool{}
+	var visit func(id string)
+	visit = func(id string) {
+		if visited[id] {
+			return
+		}
+		visited[id] = true
+		app := byID[id]
+		for _, dep := range app.Dependencies {
+			visit(dep)
+		}
+		selected = append(selected, app)
+	}
+	for _, svc := range services {
+		id, ok := c.Apps[svc]
+		if !ok {
+			return nil, fmt.Errorf("No such service: %s", svc)
+		}
+		visit(id)
+	}
+	return selected, nil
+}
+
+// Removal is a group or app removed when a project is torn down
+type Removal struct {
+	ID   string   `json:"id"`
+	Kind string   `json:"kind"`
+	Apps []string `json:"apps"`
+}
+
+// PlanRemoval returns what is removed to tear down {apps}, the apps labeled with project {project}.  Groups
+// which contain nothing but the project's apps are removed whole while apps sharing a group with others
+// are removed individually.  Groups are looked up with {getGroup}
+func PlanRemoval(project string, apps []marathon.Application, getGroup func(id string) (*marathon.Group, error)) ([]*Removal, error) {
+	byGroup := map[string][]string{}
+	groupIDs := []string{}
+	for _, app := range apps {
+		if app.Labels[LabelProject] != project {
+			continue
+		}
+		id := path.Dir(app.ID)
+		if _, ok := byGroup[id]; !ok {
+			groupIDs = append(groupIDs, id)
+		}
+		byGroup[id] = append(byGroup[id], app.ID)
+	}
+	sort.Strings(groupIDs)
+
+	removals := []*Removal{}
+	for _, id := range groupIDs {
+		sort.Strings(byGroup[id])
+		group, err := getGroup(id)
+		if err != nil {
+			return nil, err
+		}
+		if id != "/" && len(group.Groups) == 0 && len(group.Apps) == len(byGroup[id]) {
+			removals = append(removals, &Removal{ID: id, Kind: "group", Apps: byGroup[id]})
+			continue
+		}
+		for _, appID := range byGroup[id] {
+			removals = append(removals, &Removal{ID: appID, Kind: "app", Apps: []string{appID}})
+		}
+	}
+	return removals, nil
+}
+
+// ServiceApps returns the IDs of {apps} labeled with project {project} keyed by their compose service
+func ServiceApps(project string, apps []marathon.Application) map[string][]string {
+	services := map[string][]string{}
+	for _, app := range apps {
+		if app.Labels[LabelProject] != project || app.Labels[LabelService] == "" {
+			continue
+		}
+		svc := app.Labels[LabelService]
+		services[svc] = append(services[svc], app.ID)
+	}
+	return services
+}
+
+// Returns the app IDs of the services within depends_on (a list or a map of conditions) and links
+func (c *GroupConversion) dependencies(s map[string]interface{}) ([]string, error) {
+	services := []string{}
+	switch d := s["depends_on"].(type) {
+	case []interface{}:
+		for _, svc := range d {
+			services = append(services, fmt.Sprint(svc))
+		}
+	case map[string]interface{}:
+		for svc := range d {
+			services = append(services, svc)
+		}
+	}
+	links, _ := s["links"].([]interface{})
+	for _, link := range links {
+		services = append(services, strings.SplitN(fmt.Sprint(link), ":", 2)[0])
+	}
+
+	deps := []string{}
+	for _, svc := range services {
+		id, ok := c.Apps[svc]
+		if !ok {
+			return nil, fmt.Errorf("depends on undefined service '%s'", svc)
+		}
+		if !contains(deps, id) {
+			deps = append(deps, id)
+		}
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
+
+// Converts volumes in the short (source:target[:ro]) or long syntax.  Sources which are paths are mounted
+// from the agent and named volumes become external volumes named within the project
+func (c *GroupConversion) convertVolumes(service string, v interface{}) ([]*marathon.Volume, error) {
+	list, _ := v.([]interface{})
+	volumes := []*marathon.Volume{}
+	for _, item := range list {
+		var source, target string
+		readOnly := false
+		switch m := item.(type) {
+		case map[string]interface{}:
+			source, _ = m["source"].(string)
+			target, _ = m["target"].(string)
+			readOnly, _ = m["read_only"].(bool)
+		default:
+			parts := strings.Split(fmt.Sprint(m), ":")
+			target = parts[0]
+			if len(parts) > 1 {
+				source, target = parts[0], parts[1]
+			}
+			readOnly = len(parts) > 2 && parts[2] == "ro"
+		}
+		if target == "" {
+			return nil, fmt.Errorf("volume '%v' must specify a target", item)
+		}
+		if source == "" {
+			c.Warnings = append(c.Warnings, fmt.Sprintf("services.%s.volumes '%s' is anonymous and was ignored", service, target))
+			continue
+		}
+
+		volume := &marathon.Volume{ContainerPath: target, Mode: "RW"}
+		if readOnly {
+			volume.Mode = "RO"
+		}
+		if isPath(source) {
+			if !strings.HasPrefix(source, "/") {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("services.%s.volumes '%s' is relative to the task's sandbox", service, source))
+			}
+			volume.HostPath = source
+		} else {
+			volume.External = c.externalVolume(source)
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes, nil
+}
+
+// Returns the external volume of the named volume {name} which is scoped to the project unless it is external
+func (c *GroupConversion) externalVolume(name string) *marathon.ExternalVolume {
+	spec, _ := c.volumes[name].(map[string]interface{})
+	volume := &marathon.ExternalVolume{
+		Name:     c.project + "_" + name,
+		Provider: "dvdi",
+		Options:  map[string]string{"dvdi/driver": stringOr(spec["driver"], DefaultVolumeDriver)},
+	}
+	switch external := spec["external"].(type) {
+	case bool:
+		if external {
+			volume.Name = stringOr(spec["name"], name)
+		}
+	case map[string]interface{}:
+		volume.Name = stringOr(external["name"], name)
+	}
+	for k, v := range toStringMap(spec["driver_opts"]) {
+		volume.Options["dvdi/"+k] = v
+	}
+	return volume
+}
+
+// Converts ports in the short ([ip:][published:]target[/protocol]) or long syntax.  Unpublished ports are
+// mapped to a random host port
+func convertPorts(v interface{}) ([]*marathon.PortMapping, error) {
+	list, _ := v.([]interface{})
+	ports := []*marathon.PortMapping{}
+	for _, item := range list {
+		port := &marathon.PortMapping{Protocol: "tcp"}
+		switch p := item.(type) {
+		case map[string]interface{}:
+			port.ContainerPort = toInt(p["target"])
+			port.HostPort = toInt(p["published"])
+			port.Protocol = stringOr(p["protocol"], "tcp")
+		default:
+			spec := fmt.Sprint(p)
+			if i := strings.Index(spec, "/"); i > 0 {
+				port.Protocol = spec[i+1:]
+				spec = spec[:i]
+			}
+			parts := strings.Split(spec, ":")
+			target, err := strconv.Atoi(parts[len(parts)-1])
+			if err != nil {
+				return nil, fmt.Errorf("port '%v' must be [published:]target[/protocol]", item)
+			}
+			port.ContainerPort = target
+			if len(parts) > 1 && parts[len(parts)-2] != "" {
+				published, err := strconv.Atoi(parts[len(parts)-2])
+				if err != nil {
+					return nil, fmt.Errorf("port '%v' must be [published:]target[/protocol]", item)
+				}
+				port.HostPort = published
+			}
+		}
+		if port.ContainerPort == 0 {
+			return nil, fmt.Errorf("port '%v' must specify a target", item)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// Returns {v} (a map or list of KEY=VALUE) as a map.  Keys without a value take the value of the local
+// environment as compose does and are omitted when it is unset
+func toEnv(v interface{}) map[string]string {
+	env := map[string]string{}
+	set := func(k string, value interface{}) {
+		if value != nil {
+			env[k] = fmt.Sprint(value)
+		} else if local, ok := os.LookupEnv(k); ok {
+			env[k] = local
+		}
+	}
+	switch e := v.(type) {
+	case []interface{}:
+		for _, item := range e {
+			kv := strings.SplitN(fmt.Sprint(item), "=", 2)
+			if len(kv) == 2 {
+				set(kv[0], kv[1])
+			} else {
+				set(kv[0], nil)
+			}
+		}
+	case map[string]interface{}:
+		for k, value := range e {
+			set(k, value)
+		}
+	}
+	return env
+}
+
+// Returns {v} (a map or list of key=value) as a map
+func toStringMap(v interface{}) map[string]string {
+	m := map[string]string{}
+	switch l := v.(type) {
+	case []interface{}:
+		for _, item := range l {
+			kv := strings.SplitN(fmt.Sprint(item), "=", 2)
+			if len(kv) == 2 {
+				m[kv[0]] = kv[1]
+			} else {
+				m[kv[0]] = ""
+			}
+		}
+	case map[string]interface{}:
+		for k, value := range l {
+			m[k] = fmt.Sprint(value)
+		}
+	}
+	return m
+}
+
+// Returns {v} (a list or a string split as the shell would) as a list of arguments
+func toArgs(v interface{}) []string {
+	switch a := v.(type) {
+	case string:
+		return splitArgs(a)
+	case []interface{}:
+		args := []string{}
+		for _, item := range a {
+			args = append(args, fmt.Sprint(item))
+		}
+		return args
+	}
+	return nil
+}
+
+// Splits {s} on whitespace outside of single or double quotes removing the quotes
+func splitArgs(s string) []string {
+	args := []string{}
+	var current bytes.Buffer
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// Converts a compose byte value (eg. 512M, 1g, 1024) to bytes
+func toBytes(v interface{}) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(fmt.Sprint(v)))
+	multiplier := int64(1)
+	for suffix, m := range map[string]int64{"k": 1 << 10, "m": 1 << 20, "g": 1 << 30} {
+		if strings.HasSuffix(s, suffix) || strings.HasSuffix(s, suffix+"b") {
+			s = strings.TrimSuffix(strings.TrimSuffix(s, "b"), suffix)
+			multiplier = m
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSuffix(s, "b"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%v' must be a size in bytes (eg. 512M, 1G)", v)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// Returns {name} lower cased with characters Marathon doesn't permit within IDs replaced by '-'
+func marathonID(name string) string {
+	id := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(name))
+	return strings.Trim(id, "-.")
+}
+
+func isPath(source string) bool {
+	return strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~")
+}
+
+func firstOf(values ...interface{}) interface{} {
+	for _, v := range values {
+		if v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	i, _ := strconv.Atoi(fmt.Sprint(v))
+	return i
+}
+
+func stringOr(v interface{}, def string) string {
+	if s, ok := v.(string); ok && s != "" {
+		return s
+	}
+	return def
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
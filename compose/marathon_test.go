@@ -0,0 +1,199 @@
+package compose
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCompose = `
+version: "3.8"
+services:
+  web:
+    image: nginx:1.19
+    command: nginx -g "daemon off;"
+    environment:
+      MODE: prod
+    labels: [tier=web]
+    ports:
+      - "8080:80"
+      - 443
+      - target: 53
+        published: 5353
+        protocol: udp
+    volumes:
+      - /etc/nginx:/etc/nginx/conf.d:ro
+      - data:/var/cache
+      - /tmp/scratch
+    depends_on: [api]
+    deploy:
+      replicas: 3
+      resources:
+        limits: { cpus: "0.5", memory: 512M }
+  api:
+    image: shop/api
+    entrypoint: [/bin/api, --verbose]
+    working_dir: /srv
+    mem_limit: 1g
+    scale: 2
+    healthcheck:
+      test: curl localhost
+    depends_on:
+      db:
+        condition: service_healthy
+  db:
+    image: postgres
+    network_mode: host
+volumes:
+  data:
+    driver: ebs
+`
+
+func convertTestCompose(t *testing.T) *GroupConversion {
+	doc := map[string]interface{}{}
+	assert.Nil(t, encoding.DefaultYAMLEncoder().UnMarshalStr(testCompose, &doc))
+	c, err := ConvertToGroup(GroupID("/apps", "Shop_Dev"), "shop", doc)
+	assert.Nil(t, err)
+	return c
+}
+
+func TestConvertToGroup(t *testing.T) {
+	c := convertTestCompose(t)
+	assert.Equal(t, "/apps/shop-dev", c.Group.GroupID)
+	assert.Equal(t, map[string]string{"api": "/apps/shop-dev/api", "db": "/apps/shop-dev/db", "web": "/apps/shop-dev/web"}, c.Apps)
+	assert.Equal(t, []string{
+		"services.api.healthcheck is not supported and was ignored",
+		"services.web.volumes '/tmp/scratch' is anonymous and was ignored",
+	}, c.Warnings)
+	assert.Len(t, c.Group.Apps, 3)
+
+	api := c.Group.Apps[0]
+	assert.Equal(t, 2, api.Instances)
+	assert.Equal(t, DefaultCPUs, api.CPUs)
+	assert.Equal(t, float64(1024), api.Mem)
+	assert.Equal(t, []string{"--verbose"}, api.Args)
+	assert.Equal(t, []*marathon.Parameters{{Key: "entrypoint", Value: "/bin/api"}, {Key: "workdir", Value: "/srv"}}, api.Container.Docker.Parameters)
+	assert.Equal(t, []string{"/apps/shop-dev/db"}, api.Dependencies)
+
+	assert.Equal(t, "HOST", c.Group.Apps[1].Container.Docker.Network)
+
+	web := c.Group.Apps[2]
+	assert.Equal(t, "/apps/shop-dev/web", web.ID)
+	assert.Equal(t, 3, web.Instances)
+	assert.Equal(t, 0.5, web.CPUs)
+	assert.Equal(t, float64(512), web.Mem)
+	assert.Equal(t, []string{"nginx", "-g", "daemon off;"}, web.Args)
+	assert.Equal(t, map[string]string{"MODE": "prod"}, web.Env)
+	assert.Equal(t, map[string]string{"tier": "web", LabelProject: "shop", LabelService: "web"}, web.Labels)
+	assert.Equal(t, []string{"/apps/shop-dev/api"}, web.Dependencies)
+	assert.Equal(t, "BRIDGE", web.Container.Docker.Network)
+	assert.Equal(t, []*marathon.PortMapping{
+		{ContainerPort: 80, HostPort: 8080, Protocol: "tcp"},
+		{ContainerPort: 443, Protocol: "tcp"},
+		{ContainerPort: 53, HostPort: 5353, Protocol: "udp"},
+	}, web.Container.Docker.PortMappings)
+	assert.Equal(t, []*marathon.Volume{
+		{ContainerPath: "/etc/nginx/conf.d", HostPath: "/etc/nginx", Mode: "RO"},
+		{ContainerPath: "/var/cache", Mode: "RW", External: &marathon.ExternalVolume{
+			Name: "shop_data", Provider: "dvdi", Options: map[string]string{"dvdi/driver": "ebs"}}},
+	}, web.Container.Volumes)
+}
+
+func TestConvertToGroupErrors(t *testing.T) {
+	_, err := ConvertToGroup("/shop", "shop", map[string]interface{}{"version": "3"})
+	assert.Equal(t, ErrorNoServices, err)
+
+	service := func(s map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"services": map[string]interface{}{"web": s}}
+	}
+	_, err = ConvertToGroup("/shop", "shop", service(map[string]interface{}{"build": "."}))
+	assert.EqualError(t, err, "service 'web': "+ErrorMissingImage.Error())
+
+	_, err = ConvertToGroup("/shop", "shop", service(map[string]interface{}{"image": "nginx", "depends_on": []interface{}{"db"}}))
+	assert.EqualError(t, err, "service 'web': depends on undefined service 'db'")
+
+	_, err = ConvertToGroup("/shop", "shop", service(map[string]interface{}{"image": "nginx", "ports": []interface{}{"http"}}))
+	assert.EqualError(t, err, "service 'web': port 'http' must be [published:]target[/protocol]")
+}
+
+func TestLoadProjectMergesFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "compose")
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "docker-compose.yml")
+	override := filepath.Join(dir, "docker-compose.prod.yml")
+	ioutil.WriteFile(base, []byte("services:\n  web:\n    image: nginx:${TAG}\n    scale: 1\n"), 0644)
+	ioutil.WriteFile(override, []byte("services:\n  web:\n    scale: 4\n  db:\n    image: postgres\n"), 0644)
+
+	doc, err := LoadProject(&Context{ComposeFile: base + "," + override, EnvParams: map[string]string{"TAG": "1.19"}, ErrorOnMissingParams: true})
+	assert.Nil(t, err)
+	services := doc["services"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"image": "nginx:1.19", "scale": float64(4)}, services["web"])
+	assert.NotNil(t, services["db"])
+
+	_, err = LoadProject(&Context{ComposeFile: base, ErrorOnMissingParams: true})
+	assert.NotNil(t, err)
+}
+
+func TestSelectIncludesDependencies(t *testing.T) {
+	c := convertTestCompose(t)
+
+	apps, err := c.Select("web")
+	assert.Nil(t, err)
+	ids := []string{}
+	for _, app := range apps {
+		ids = append(ids, app.ID)
+	}
+	assert.Equal(t, []string{"/apps/shop-dev/db", "/apps/shop-dev/api", "/apps/shop-dev/web"}, ids)
+
+	apps, _ = c.Select("db")
+	assert.Len(t, apps, 1)
+
+	_, err = c.Select("cache")
+	assert.EqualError(t, err, "No such service: cache")
+}
+
+func TestPlanRemoval(t *testing.T) {
+	labeled := func(id, project string) marathon.Application {
+		return marathon.Application{ID: id, Labels: map[string]string{LabelProject: project}}
+	}
+	groups := map[string]*marathon.Group{
+		"/shop":   {GroupID: "/shop", Apps: []*marathon.Application{{ID: "/shop/web"}, {ID: "/shop/db"}}},
+		"/shared": {GroupID: "/shared", Apps: []*marathon.Application{{ID: "/shared/web"}, {ID: "/shared/other"}}},
+	}
+	apps := []marathon.Application{
+		labeled("/shop/web", "shop"), labeled("/shop/db", "shop"), labeled("/shared/web", "shop"), labeled("/shared/other", "blog"),
+	}
+
+	removals, err := PlanRemoval("shop", apps, func(id string) (*marathon.Group, error) { return groups[id], nil })
+	assert.Nil(t, err)
+	assert.Equal(t, []*Removal{
+		{ID: "/shared/web", Kind: "app", Apps: []string{"/shared/web"}},
+		{ID: "/shop", Kind: "group", Apps: []string{"/shop/db", "/shop/web"}},
+	}, removals)
+}
+
+func TestServiceApps(t *testing.T) {
+	apps := []marathon.Application{
+		{ID: "/shop/web", Labels: map[string]string{LabelProject: "shop", LabelService: "web"}},
+		{ID: "/dev/shop/web", Labels: map[string]string{LabelProject: "shop", LabelService: "web"}},
+		{ID: "/blog/web", Labels: map[string]string{LabelProject: "blog", LabelService: "web"}},
+		{ID: "/shop/legacy", Labels: map[string]string{LabelProject: "shop"}},
+	}
+	assert.Equal(t, map[string][]string{"web": {"/shop/web", "/dev/shop/web"}}, ServiceApps("shop", apps))
+}
+
+func TestParseScale(t *testing.T) {
+	scale, err := ParseScale([]string{"web=5", "worker=0"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"web": 5, "worker": 0}, scale)
+
+	_, err = ParseScale([]string{"web"})
+	assert.EqualError(t, err, "Invalid scale 'web', must be SERVICE=NUM")
+	_, err = ParseScale([]string{"web=-1"})
+	assert.EqualError(t, err, "Invalid scale 'web=-1', NUM must be zero or more")
+}
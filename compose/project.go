@@ -0,0 +1,258 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envcrypt"
+	"github.com/ContainX/depcon/pkg/envsubst"
+)
+
+const (
+	// env file read beside the compose file when one isn't specified
+	DefaultEnvFile = ".env"
+	// comma separated profiles enabled in addition to those specified
+	EnvProfiles = "COMPOSE_PROFILES"
+)
+
+// LoadProject parses the compose files of {context} with ${PARAMS} resolved.  When several files are
+// specified (comma separated) the keys of a service within later files override those of earlier files.
+// Services whose profiles aren't enabled are removed and the env_file entries of services are merged
+// into their environment
+func LoadProject(context *Context) (map[string]interface{}, error) {
+	params, err := InterpolationParams(context)
+	if err != nil {
+		return nil, err
+	}
+
+	project := map[string]interface{}{}
+	for _, filename := range strings.Split(context.ComposeFile, ",") {
+		doc, err := loadFile(filename, params, context.ErrorOnMissingParams)
+		if err != nil {
+			return nil, err
+		}
+		mergeProject(project, doc)
+	}
+
+	services, _ := project["services"].(map[string]interface{})
+	profiles := enabledProfiles(context.Profiles)
+	for name, v := range services {
+		s, _ := v.(map[string]interface{})
+		if !isEnabled(s, profiles) {
+			delete(services, name)
+			continue
+		}
+		if err := mergeEnvFiles(s, filepath.Dir(composeFiles(context)[0])); err != nil {
+			return nil, fmt.Errorf("service '%s': %s", name, err.Error())
+		}
+	}
+	return project, nil
+}
+
+// InterpolationParams returns the params ${PARAMS} are resolved with.  The precedence is -p params, then
+// environment variables, then the env file (.env beside the compose file unless specified)
+func InterpolationParams(context *Context) (map[string]string, error) {
+	filename := context.EnvFile
+	if filename == "" {
+		filename = filepath.Join(filepath.Dir(composeFiles(context)[0]), DefaultEnvFile)
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			filename = ""
+		}
+	}
+
+	params := map[string]string{}
+	if filename != "" {
+		values, err := ParseEnvFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range values {
+			// params are resolved before the environment so values of the environment are left unset
+			if _, ok := os.LookupEnv(k); !ok {
+				params[k] = v
+			}
+		}
+	}
+	for k, v := range context.EnvParams {
+		params[k] = v
+	}
+	return params, nil
+}
+
+// ParseEnvFile parses the KEY=VALUE lines of the env file {filename}.  Blank lines and comments (#) are
+// skipped, an 'export ' prefix is permitted and quotes surrounding values are removed.  Encrypted files are
+// decrypted in memory
+func ParseEnvFile(filename string) (map[string]string, error) {
+	data, err := envcrypt.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(line, "export "), "=", 2)
+		key := strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			return nil, fmt.Errorf("%s: line %d must be KEY=VALUE", filename, i+1)
+		}
+		value := strings.TrimSpace(kv[1])
+		if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func composeFiles(context *Context) []string {
+	return strings.Split(context.ComposeFile, ",")
+}
+
+// Returns {profiles} along with those of $COMPOSE_PROFILES
+func enabledProfiles(profiles []string) map[string]bool {
+	enabled := map[string]bool{}
+	for _, p := range append(profiles, strings.Split(os.Getenv(EnvProfiles), ",")...) {
+		if p = strings.TrimSpace(p); p != "" {
+			enabled[p] = true
+		}
+	}
+	return enabled
+}
+
+// Services without profiles are always enabled while others require one of their profiles be enabled
+func isEnabled(s map[string]interface{}, enabled map[string]bool) bool {
+	profiles, _ := s["profiles"].([]interface{})
+	if len(profiles) == 0 {
+		return true
+	}
+	for _, p := range profiles {
+		if enabled[fmt.Sprint(p)] || enabled["*"] {
+			return true
+		}
+	}
+	return false
+}
+
+// Merges the env_file entries (relative to {dir}) of service {s} into its environment.  Later files override
+// earlier files and the environment overrides them all as compose does
+func mergeEnvFiles(s map[string]interface{}, dir string) error {
+	var files []string
+	switch f := s["env_file"].(type) {
+	case nil:
+		return nil
+	case string:
+		files = []string{f}
+	case []interface{}:
+		for _, item := range f {
+			files = append(files, fmt.Sprint(item))
+		}
+	}
+	delete(s, "env_file")
+
+	env := map[string]interface{}{}
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		values, err := ParseEnvFile(file)
+		if err != nil {
+			return err
+		}
+		for k, v := range values {
+			env[k] = v
+		}
+	}
+	for k, v := range toEnvMap(s["environment"]) {
+		env[k] = v
+	}
+	s["environment"] = env
+	return nil
+}
+
+// Returns the environment {v} (a map or list of KEY=VALUE) as a map.  Keys without a value are nil
+func toEnvMap(v interface{}) map[string]interface{} {
+	env := map[string]interface{}{}
+	switch e := v.(type) {
+	case []interface{}:
+		for _, item := range e {
+			kv := strings.SplitN(fmt.Sprint(item), "=", 2)
+			if len(kv) == 2 {
+				env[kv[0]] = kv[1]
+			} else {
+				env[kv[0]] = nil
+			}
+		}
+	case map[string]interface{}:
+		for k, value := range e {
+			env[k] = value
+		}
+	}
+	return env
+}
+
+func loadFile(filename string, params map[string]string, errorOnMissing bool) (map[string]interface{}, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening filename %s, %s", filename, err.Error())
+	}
+	defer file.Close()
+
+	parsed, missing := envsubst.SubstTokens(file, params)
+	if errorOnMissing && len(missing) > 0 {
+		return nil, &envsubst.MissingParamsError{Filename: filename, Params: missing}
+	}
+
+	doc := map[string]interface{}{}
+	if err := encoding.DefaultYAMLEncoder().UnMarshalStr(parsed, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return doc, nil
+}
+
+// Merges the compose file {doc} into {project}
+func mergeProject(project, doc map[string]interface{}) {
+	for key, v := range doc {
+		section, ok := v.(map[string]interface{})
+		existing, _ := project[key].(map[string]interface{})
+		if !ok || existing == nil {
+			project[key] = v
+			continue
+		}
+		for name, item := range section {
+			overrides, ok := item.(map[string]interface{})
+			base, _ := existing[name].(map[string]interface{})
+			if !ok || base == nil {
+				existing[name] = item
+				continue
+			}
+			for k, value := range overrides {
+				base[k] = value
+			}
+		}
+	}
+}
+
+// ParseScale parses the SERVICE=NUM arguments of a scale command
+func ParseScale(args []string) (map[string]int, error) {
+	scale := map[string]int{}
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("Invalid scale '%s', must be SERVICE=NUM", arg)
+		}
+		n, err := strconv.Atoi(kv[1])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("Invalid scale '%s', NUM must be zero or more", arg)
+		}
+		scale[kv[0]] = n
+	}
+	return scale, nil
+}
@@ -0,0 +1,88 @@
+package compose
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeProject(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "compose")
+	assert.Nil(t, err)
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestParseEnvFile(t *testing.T) {
+	dir := writeProject(t, map[string]string{".env": "# comment\n\nTAG=1.19\nexport MODE=\"prod\"\nGREETING='hello world'\nEMPTY=\n"})
+	defer os.RemoveAll(dir)
+
+	values, err := ParseEnvFile(filepath.Join(dir, ".env"))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"TAG": "1.19", "MODE": "prod", "GREETING": "hello world", "EMPTY": ""}, values)
+
+	ioutil.WriteFile(filepath.Join(dir, "bad.env"), []byte("TAG\n"), 0644)
+	_, err = ParseEnvFile(filepath.Join(dir, "bad.env"))
+	assert.EqualError(t, err, filepath.Join(dir, "bad.env")+": line 1 must be KEY=VALUE")
+}
+
+func TestInterpolationPrecedence(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		".env":               "TAG=from-env-file\nREGISTRY=docker.io\nDEPCON_TEST_MODE=from-env-file\n",
+		"docker-compose.yml": "services:\n  web:\n    image: ${REGISTRY}/nginx:${TAG}\n    environment:\n      MODE: ${DEPCON_TEST_MODE}\n",
+	})
+	defer os.RemoveAll(dir)
+	os.Setenv("DEPCON_TEST_MODE", "from-shell")
+	defer os.Unsetenv("DEPCON_TEST_MODE")
+
+	context := &Context{ComposeFile: filepath.Join(dir, "docker-compose.yml"), EnvParams: map[string]string{"TAG": "from-param"}, ErrorOnMissingParams: true}
+	doc, err := LoadProject(context)
+	assert.Nil(t, err)
+	web := doc["services"].(map[string]interface{})["web"].(map[string]interface{})
+	assert.Equal(t, "docker.io/nginx:from-param", web["image"])
+	assert.Equal(t, map[string]interface{}{"MODE": "from-shell"}, web["environment"])
+
+	context.EnvFile = filepath.Join(dir, "missing.env")
+	_, err = LoadProject(context)
+	assert.NotNil(t, err)
+}
+
+func TestLoadProjectEnvFilesAndProfiles(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"common.env": "MODE=dev\nLEVEL=info\n",
+		"web.env":    "LEVEL=debug\n",
+		"docker-compose.yml": `
+services:
+  web:
+    image: nginx
+    env_file: [common.env, web.env]
+    environment: [MODE=prod]
+  debug:
+    image: busybox
+    profiles: [debug]
+  tools:
+    image: tools
+    profiles: [ops, debug]
+`,
+	})
+	defer os.RemoveAll(dir)
+	context := &Context{ComposeFile: filepath.Join(dir, "docker-compose.yml")}
+
+	doc, err := LoadProject(context)
+	assert.Nil(t, err)
+	services := doc["services"].(map[string]interface{})
+	assert.Len(t, services, 1)
+	web := services["web"].(map[string]interface{})
+	assert.Nil(t, web["env_file"])
+	assert.Equal(t, map[string]interface{}{"MODE": "prod", "LEVEL": "debug"}, web["environment"])
+
+	context.Profiles = []string{"ops"}
+	doc, _ = LoadProject(context)
+	assert.Len(t, doc["services"], 2)
+	assert.NotNil(t, doc["services"].(map[string]interface{})["tools"])
+}
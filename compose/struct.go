@@ -1,8 +1,13 @@
 package compose
 
 type Context struct {
-	ComposeFile          string
-	ProjectName          string
-	EnvParams            map[string]string
+	ComposeFile string
+	ProjectName string
+	// params specified with -p which take precedence over the environment and the env file
+	EnvParams map[string]string
+	// file of KEY=VALUE used for interpolation.  Defaults to the .env beside the compose file
+	EnvFile string
+	// enabled profiles in addition to those of $COMPOSE_PROFILES
+	Profiles             []string
 	ErrorOnMissingParams bool
 }
@@ -0,0 +1,144 @@
+// DC/OS Cosmos (Universe package) API
+package cosmos
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	/* --- api related constants --- */
+	API_SEARCH   = "search"
+	API_DESCRIBE = "describe"
+	API_RENDER   = "render"
+
+	// Labels Cosmos adds to the Marathon apps of packages
+	LabelPackageName    = "DCOS_PACKAGE_NAME"
+	LabelPackageVersion = "DCOS_PACKAGE_VERSION"
+	LabelFrameworkName  = "DCOS_PACKAGE_FRAMEWORK_NAME"
+
+	mediaTypeFormat = "application/vnd.dcos.package.%s+json;charset=utf-8;version=%s"
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.cosmos")
+
+var (
+	ErrorNoMarathonApp = errors.New("The package does not define a Marathon app")
+)
+
+type Cosmos interface {
+
+	// Searches the package repositories for packages matching {query}.  All packages are returned when
+	// {query} is empty
+	Search(query string) ([]*PackageSummary, error)
+
+	// Returns the definition of a package
+	// {name} - package name
+	// {version} - package version or empty for the latest
+	Describe(name, version string) (*Package, error)
+
+	// Renders the Marathon app of a package
+	// {name} - package name
+	// {version} - package version or empty for the latest
+	// {options} - options merged with the defaults of the package's config schema
+	// {appID} - optional app ID used in place of the package's default
+	Render(name, version string, options map[string]interface{}, appID string) (map[string]interface{}, error)
+}
+
+type CosmosClient struct {
+	http httpclient.HttpClient
+	host string
+}
+
+type CosmosOptions struct {
+	TLSAllowInsecure bool
+	// Optional token based authentication (eg. DC/OS) used in place of basic auth
+	Authenticator httpclient.Authenticator
+	// Optional proxies used in place of the proxy environment variables
+	Proxy *httpclient.ProxyConfig
+	// Optional client certificate and CA bundle for mutual TLS
+	TLS *httpclient.TLSConfig
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Static headers added to every request
+	Headers map[string]string
+}
+
+// NewCosmosClient creates a client for the Cosmos at {host} (eg. https://cluster/package).  Cosmos only
+// renders packages, apps are deployed through Marathon so read-only environments are enforced there
+func NewCosmosClient(host, username, password string, opts *CosmosOptions) Cosmos {
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.HttpUser = username
+	httpConfig.HttpPass = password
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	if opts != nil {
+		httpConfig.TLSInsecureSkipVerify = opts.TLSAllowInsecure
+		httpConfig.Authenticator = opts.Authenticator
+		httpConfig.Proxy = opts.Proxy
+		httpConfig.TLS = opts.TLS
+		httpConfig.Timeouts = opts.Timeouts
+		httpConfig.Headers = opts.Headers
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+	}
+
+	c := new(CosmosClient)
+	c.http = *httpclient.NewHttpClient(*httpConfig)
+	c.host = host
+	return c
+}
+
+// Posts {data} to the Cosmos {action} using its versioned media types
+func (c *CosmosClient) post(action, requestVersion, responseVersion string, data, result interface{}) error {
+	headers := map[string]string{
+		"Content-Type": mediaType(action+"-request", requestVersion),
+		"Accept":       mediaType(action+"-response", responseVersion),
+	}
+	resp := c.http.HttpPostWithHeaders(utils.BuildPath(c.host, []string{action}), headers, data, result)
+	if resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *CosmosClient) Search(query string) ([]*PackageSummary, error) {
+	result := new(searchResponse)
+	if err := c.post(API_SEARCH, "v1", "v1", &searchRequest{Query: query}, result); err != nil {
+		return nil, err
+	}
+	return result.Packages, nil
+}
+
+func (c *CosmosClient) Describe(name, version string) (*Package, error) {
+	result := new(describeResponse)
+	if err := c.post(API_DESCRIBE, "v1", "v2", &packageRequest{PackageName: name, PackageVersion: version}, result); err != nil {
+		return nil, err
+	}
+	return result.Package, nil
+}
+
+func (c *CosmosClient) Render(name, version string, options map[string]interface{}, appID string) (map[string]interface{}, error) {
+	log.Debug("Rendering package '%s' version '%s'", name, version)
+	req := &renderRequest{packageRequest: packageRequest{PackageName: name, PackageVersion: version}, Options: options, AppID: appID}
+	result := new(renderResponse)
+	if err := c.post(API_RENDER, "v1", "v1", req, result); err != nil {
+		return nil, err
+	}
+	if len(result.MarathonJson) == 0 {
+		return nil, ErrorNoMarathonApp
+	}
+	return result.MarathonJson, nil
+}
+
+// Returns the versioned Cosmos media type of {name} (eg. render-request)
+func mediaType(name, version string) string {
+	return fmt.Sprintf(mediaTypeFormat, name, version)
+}
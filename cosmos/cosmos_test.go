@@ -0,0 +1,57 @@
+package cosmos
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/mockrest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(body string) (c Cosmos, requests *[]mockrest.RecordedRequest, stop func()) {
+	requests, stop = mockrest.RecordClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	}, func(url string, retry *httpclient.RetryPolicy) {
+		c = NewCosmosClient(url+"/package", "", "", &CosmosOptions{Retry: retry})
+	})
+	return
+}
+
+func TestRender(t *testing.T) {
+	c, requests, stop := newTestClient(`{"marathonJson": {"id": "/kafka", "labels": {"DCOS_PACKAGE_NAME": "kafka"}}}`)
+	defer stop()
+
+	app, err := c.Render("kafka", "1.1.9", map[string]interface{}{"service": map[string]interface{}{"name": "kafka"}}, "/kafka")
+	assert.Nil(t, err)
+	assert.Equal(t, "/kafka", app["id"])
+
+	req := (*requests)[0]
+	assert.Equal(t, "/package/render", req.Path)
+	assert.Equal(t, "application/vnd.dcos.package.render-request+json;charset=utf-8;version=v1", req.Header.Get("Content-Type"))
+	assert.Equal(t, "application/vnd.dcos.package.render-response+json;charset=utf-8;version=v1", req.Header.Get("Accept"))
+	assert.Equal(t, "kafka", req.Body["packageName"])
+	assert.Equal(t, "1.1.9", req.Body["packageVersion"])
+	assert.Equal(t, "/kafka", req.Body["appId"])
+	assert.NotNil(t, req.Body["options"])
+}
+
+func TestRenderWithoutMarathonApp(t *testing.T) {
+	c, _, stop := newTestClient(`{}`)
+	defer stop()
+
+	_, err := c.Render("cli-only", "", nil, "")
+	assert.Equal(t, ErrorNoMarathonApp, err)
+}
+
+func TestDescribe(t *testing.T) {
+	c, requests, stop := newTestClient(`{"package": {"name": "kafka", "version": "1.1.9", "framework": true, "postInstallNotes": "done"}}`)
+	defer stop()
+
+	pkg, err := c.Describe("kafka", "")
+	assert.Nil(t, err)
+	assert.Equal(t, &Package{Name: "kafka", Version: "1.1.9", Framework: true, PostInstallNotes: "done"}, pkg)
+	assert.Equal(t, "application/vnd.dcos.package.describe-response+json;charset=utf-8;version=v2", (*requests)[0].Header.Get("Accept"))
+	assert.Nil(t, (*requests)[0].Body["packageVersion"])
+}
@@ -0,0 +1,51 @@
+package cosmos
+
+type searchRequest struct {
+	Query string `json:"query,omitempty"`
+}
+
+type searchResponse struct {
+	Packages []*PackageSummary `json:"packages"`
+}
+
+type packageRequest struct {
+	PackageName    string `json:"packageName"`
+	PackageVersion string `json:"packageVersion,omitempty"`
+}
+
+type renderRequest struct {
+	packageRequest
+	Options map[string]interface{} `json:"options,omitempty"`
+	AppID   string                 `json:"appId,omitempty"`
+}
+
+type describeResponse struct {
+	Package *Package `json:"package"`
+}
+
+type renderResponse struct {
+	MarathonJson map[string]interface{} `json:"marathonJson"`
+}
+
+// PackageSummary is a package within the search results
+type PackageSummary struct {
+	Name           string   `json:"name"`
+	CurrentVersion string   `json:"currentVersion"`
+	Description    string   `json:"description"`
+	Framework      bool     `json:"framework"`
+	Selected       bool     `json:"selected"`
+	Tags           []string `json:"tags"`
+}
+
+// Package is the definition of a package version
+type Package struct {
+	Name             string   `json:"name"`
+	Version          string   `json:"version"`
+	PackagingVersion string   `json:"packagingVersion"`
+	Description      string   `json:"description"`
+	Maintainer       string   `json:"maintainer"`
+	Framework        bool     `json:"framework"`
+	Tags             []string `json:"tags"`
+	PreInstallNotes  string   `json:"preInstallNotes,omitempty"`
+	PostInstallNotes string   `json:"postInstallNotes,omitempty"`
+}
@@ -0,0 +1,210 @@
+// Estimates the cost of the resources allocated to applications from configurable unit prices
+package cost
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+
+	"github.com/ContainX/depcon/marathon"
+)
+
+const (
+	ByApp   = "app"
+	ByGroup = "group"
+	ByLabel = "label"
+
+	DefaultCurrency = "USD"
+	DefaultPeriod   = "month"
+	DefaultLabel    = "team"
+
+	// Name the apps without the label being summarized by are grouped under
+	NoLabel = "(none)"
+)
+
+var ErrorNoPrices = errors.New("No unit prices are configured - set the environment's cost prices or the --cpu-price, --mem-price and --disk-price flags")
+
+// Prices are the unit prices of resources for a period (eg. a month)
+type Prices struct {
+	// Price of a cpu
+	CPU float64 `json:"cpu,omitempty"`
+	// Price of a GiB of memory
+	Mem float64 `json:"mem,omitempty"`
+	// Price of a GiB of disk
+	Disk float64 `json:"disk,omitempty"`
+	// Default: USD
+	Currency string `json:"currency,omitempty"`
+	// Default: month
+	Period string `json:"period,omitempty"`
+}
+
+// Item is the resources allocated to the apps of an app, group or label value and their cost
+type Item struct {
+	Name      string  `json:"name"`
+	Apps      int     `json:"apps"`
+	Instances int     `json:"instances"`
+	CPUs      float64 `json:"cpus"`
+	Mem       float64 `json:"mem"`
+	Disk      float64 `json:"disk"`
+	Cost      float64 `json:"cost"`
+}
+
+// Report is the cost of a set of applications summarized by app, group or label
+type Report struct {
+	By       string  `json:"by"`
+	Currency string  `json:"currency"`
+	Period   string  `json:"period"`
+	Items    []*Item `json:"items"`
+	Total    *Item   `json:"total"`
+}
+
+// Change is the cost of an application before and after a pending descriptor is deployed
+type Change struct {
+	ID     string  `json:"id"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Change float64 `json:"change"`
+}
+
+// Validate returns ErrorNoPrices if no prices are set
+func (p *Prices) Validate() error {
+	if p == nil || (p.CPU == 0 && p.Mem == 0 && p.Disk == 0) {
+		return ErrorNoPrices
+	}
+	return nil
+}
+
+// Merge returns the prices with the non zero values of {o} taking precedence
+func (p *Prices) Merge(o *Prices) *Prices {
+	result := &Prices{}
+	if p != nil {
+		*result = *p
+	}
+	if o == nil {
+		return result
+	}
+	if o.CPU != 0 {
+		result.CPU = o.CPU
+	}
+	if o.Mem != 0 {
+		result.Mem = o.Mem
+	}
+	if o.Disk != 0 {
+		result.Disk = o.Disk
+	}
+	if o.Currency != "" {
+		result.Currency = o.Currency
+	}
+	if o.Period != "" {
+		result.Period = o.Period
+	}
+	return result
+}
+
+// App returns the cost of the instances of {app}.  Memory and disk are allocated in MiB
+func (p *Prices) App(app *marathon.Application) float64 {
+	per := app.CPUs*p.CPU + app.Mem/1024*p.Mem + app.Disk/1024*p.Disk
+	return round(per * float64(app.Instances))
+}
+
+// Summarize returns the cost of {apps} grouped by app, group (the app's parent) or the value of the label
+// {label}.  Items are ordered by cost, most expensive first
+func Summarize(apps []*marathon.Application, prices *Prices, by, label string) (*Report, error) {
+	if label == "" {
+		label = DefaultLabel
+	}
+	key, err := keyFunc(by, label)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{By: by, Currency: prices.Currency, Period: prices.Period, Items: []*Item{}, Total: &Item{Name: "TOTAL"}}
+	if report.Currency == "" {
+		report.Currency = DefaultCurrency
+	}
+	if report.Period == "" {
+		report.Period = DefaultPeriod
+	}
+	switch by {
+	case "":
+		report.By = ByApp
+	case ByLabel:
+		report.By = label
+	}
+
+	items := map[string]*Item{}
+	for _, app := range apps {
+		name := key(app)
+		item, ok := items[name]
+		if !ok {
+			item = &Item{Name: name}
+			items[name] = item
+			report.Items = append(report.Items, item)
+		}
+		for _, i := range []*Item{item, report.Total} {
+			i.add(app, prices)
+		}
+	}
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		if report.Items[i].Cost != report.Items[j].Cost {
+			return report.Items[i].Cost > report.Items[j].Cost
+		}
+		return report.Items[i].Name < report.Items[j].Name
+	})
+	return report, nil
+}
+
+// Diff returns the cost change of each app when the {live} applications are replaced by the {pending}
+// applications.  Pending apps which aren't running are new and cost nothing before
+func Diff(live, pending []*marathon.Application, prices *Prices) []*Change {
+	running := map[string]*marathon.Application{}
+	for _, app := range live {
+		running[app.ID] = app
+	}
+	changes := []*Change{}
+	for _, app := range pending {
+		c := &Change{ID: app.ID, After: prices.App(app)}
+		if current, ok := running[app.ID]; ok {
+			c.Before = prices.App(current)
+		}
+		c.Change = round(c.After - c.Before)
+		changes = append(changes, c)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].ID < changes[j].ID
+	})
+	return changes
+}
+
+func (i *Item) add(app *marathon.Application, prices *Prices) {
+	n := float64(app.Instances)
+	i.Apps++
+	i.Instances += app.Instances
+	i.CPUs += app.CPUs * n
+	i.Mem += app.Mem * n
+	i.Disk += app.Disk * n
+	i.Cost = round(i.Cost + prices.App(app))
+}
+
+func keyFunc(by, label string) (func(app *marathon.Application) string, error) {
+	switch by {
+	case ByApp, "":
+		return func(app *marathon.Application) string { return app.ID }, nil
+	case ByGroup:
+		return func(app *marathon.Application) string { return path.Dir(app.ID) }, nil
+	case ByLabel:
+		return func(app *marathon.Application) string {
+			if v := app.Labels[label]; v != "" {
+				return v
+			}
+			return NoLabel
+		}, nil
+	}
+	return nil, fmt.Errorf("'%s' is not a summary - must be %s, %s or %s", by, ByApp, ByGroup, ByLabel)
+}
+
+// Rounds {v} to cents
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
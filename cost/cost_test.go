@@ -0,0 +1,64 @@
+package cost
+
+import (
+	"testing"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+var prices = &Prices{CPU: 20, Mem: 4, Disk: 0.5}
+
+func apps() []*marathon.Application {
+	return []*marathon.Application{
+		{ID: "/shop/web", Instances: 3, CPUs: 0.5, Mem: 1024, Labels: map[string]string{"team": "storefront"}},
+		{ID: "/shop/api", Instances: 2, CPUs: 1, Mem: 2048, Disk: 1024, Labels: map[string]string{"team": "storefront"}},
+		{ID: "/ops/metrics", Instances: 1, CPUs: 2, Mem: 512},
+	}
+}
+
+func TestAppCost(t *testing.T) {
+	// 3 x (0.5 x 20 + 1 GiB x 4)
+	assert.Equal(t, 42.0, prices.App(apps()[0]))
+	// 2 x (20 + 2 x 4 + 1 x 0.5)
+	assert.Equal(t, 57.0, prices.App(apps()[1]))
+}
+
+func TestSummarize(t *testing.T) {
+	report, err := Summarize(apps(), prices, ByGroup, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "group", report.By)
+	assert.Equal(t, DefaultCurrency, report.Currency)
+	assert.Equal(t, "/shop", report.Items[0].Name)
+	assert.Equal(t, 99.0, report.Items[0].Cost)
+	assert.Equal(t, 5, report.Items[0].Instances)
+	assert.Equal(t, 141.0, report.Total.Cost)
+	assert.Equal(t, 3, report.Total.Apps)
+
+	report, _ = Summarize(apps(), prices, ByLabel, "")
+	assert.Equal(t, "team", report.By)
+	assert.Equal(t, "storefront", report.Items[0].Name)
+	assert.Equal(t, NoLabel, report.Items[1].Name)
+	assert.Equal(t, 42.0, report.Items[1].Cost)
+
+	_, err = Summarize(apps(), prices, "cluster", "")
+	assert.NotNil(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	live := apps()
+	web := *live[0]
+	web.Instances = 5
+	changes := Diff(live, []*marathon.Application{&web, {ID: "/shop/new", Instances: 1, CPUs: 1}}, prices)
+	assert.Equal(t, []*Change{
+		{ID: "/shop/new", Before: 0, After: 20, Change: 20},
+		{ID: "/shop/web", Before: 42, After: 70, Change: 28},
+	}, changes)
+}
+
+func TestPrices(t *testing.T) {
+	assert.Equal(t, ErrorNoPrices, (*Prices)(nil).Validate())
+	p := (&Prices{CPU: 10, Currency: "EUR"}).Merge(&Prices{CPU: 12, Mem: 3})
+	assert.Equal(t, &Prices{CPU: 12, Mem: 3, Currency: "EUR"}, p)
+	assert.Nil(t, p.Validate())
+}
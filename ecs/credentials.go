@@ -0,0 +1,107 @@
+package ecs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/userdir"
+)
+
+const (
+	EnvAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	EnvSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	EnvSessionToken    = "AWS_SESSION_TOKEN"
+	EnvProfile         = "AWS_PROFILE"
+	EnvCredentialsFile = "AWS_SHARED_CREDENTIALS_FILE"
+	EnvRegion          = "AWS_REGION"
+	EnvDefaultRegion   = "AWS_DEFAULT_REGION"
+
+	DefaultProfile = "default"
+)
+
+var ErrNoCredentials = errors.New("No AWS credentials found - set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or configure ~/.aws/credentials")
+
+// Credentials are the AWS access keys used to sign requests
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// Optional token of temporary credentials
+	SessionToken string
+}
+
+// LoadCredentials returns the credentials of {profile} within the shared credentials file.  When {profile}
+// is empty the credentials within the environment are used if set followed by $AWS_PROFILE or the default
+// profile.  Credential processes and SSO profiles are not supported
+func LoadCredentials(profile string) (*Credentials, error) {
+	if profile == "" {
+		if id := os.Getenv(EnvAccessKeyID); id != "" {
+			return &Credentials{AccessKeyID: id, SecretAccessKey: os.Getenv(EnvSecretAccessKey), SessionToken: os.Getenv(EnvSessionToken)}, nil
+		}
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	filename := os.Getenv(EnvCredentialsFile)
+	if filename == "" {
+		filename = filepath.Join(userdir.Get(), ".aws", "credentials")
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoCredentials
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	values, found := map[string]string{}, false
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			found = found || section == profile
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			values[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("Profile '%s' could not be found in %s", profile, filename)
+	}
+
+	creds := &Credentials{
+		AccessKeyID:     values["aws_access_key_id"],
+		SecretAccessKey: values["aws_secret_access_key"],
+		SessionToken:    values["aws_session_token"],
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("Profile '%s' does not define aws_access_key_id and aws_secret_access_key", profile)
+	}
+	return creds, nil
+}
+
+// DefaultRegion returns the region from $AWS_REGION or $AWS_DEFAULT_REGION
+func DefaultRegion() string {
+	if region := os.Getenv(EnvRegion); region != "" {
+		return region
+	}
+	return os.Getenv(EnvDefaultRegion)
+}
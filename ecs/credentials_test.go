@@ -0,0 +1,79 @@
+package ecs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCredentials = `
+[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = defaultsecret
+
+# temporary credentials
+[deploy]
+aws_access_key_id=AKIDDEPLOY
+aws_secret_access_key=deploysecret
+aws_session_token=token
+
+[broken]
+aws_access_key_id = AKIDBROKEN
+`
+
+func withCredentialsFile(t *testing.T) func() {
+	dir, _ := ioutil.TempDir("", "depcon-aws")
+	filename := filepath.Join(dir, "credentials")
+	ioutil.WriteFile(filename, []byte(testCredentials), 0600)
+
+	saved := map[string]string{}
+	for _, k := range []string{EnvCredentialsFile, EnvAccessKeyID, EnvSecretAccessKey, EnvSessionToken, EnvProfile} {
+		saved[k] = os.Getenv(k)
+		os.Unsetenv(k)
+	}
+	os.Setenv(EnvCredentialsFile, filename)
+	return func() {
+		for k, v := range saved {
+			os.Setenv(k, v)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLoadCredentialsProfiles(t *testing.T) {
+	defer withCredentialsFile(t)()
+
+	creds, err := LoadCredentials("")
+	assert.Nil(t, err)
+	assert.Equal(t, &Credentials{AccessKeyID: "AKIDDEFAULT", SecretAccessKey: "defaultsecret"}, creds)
+
+	creds, err = LoadCredentials("deploy")
+	assert.Nil(t, err)
+	assert.Equal(t, &Credentials{AccessKeyID: "AKIDDEPLOY", SecretAccessKey: "deploysecret", SessionToken: "token"}, creds)
+
+	os.Setenv(EnvProfile, "deploy")
+	creds, _ = LoadCredentials("")
+	assert.Equal(t, "AKIDDEPLOY", creds.AccessKeyID)
+
+	_, err = LoadCredentials("broken")
+	assert.EqualError(t, err, "Profile 'broken' does not define aws_access_key_id and aws_secret_access_key")
+	_, err = LoadCredentials("missing")
+	assert.Contains(t, err.Error(), "Profile 'missing' could not be found")
+}
+
+func TestLoadCredentialsEnvironment(t *testing.T) {
+	defer withCredentialsFile(t)()
+	os.Setenv(EnvAccessKeyID, "AKIDENV")
+	os.Setenv(EnvSecretAccessKey, "envsecret")
+
+	creds, err := LoadCredentials("")
+	assert.Nil(t, err)
+	assert.Equal(t, "AKIDENV", creds.AccessKeyID)
+
+	// an explicit profile takes precedence over the environment
+	creds, _ = LoadCredentials("deploy")
+	assert.Equal(t, "AKIDDEPLOY", creds.AccessKeyID)
+}
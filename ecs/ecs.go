@@ -0,0 +1,311 @@
+// Amazon ECS API
+package ecs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+)
+
+const (
+	/* --- api related constants --- */
+	targetPrefix = "AmazonEC2ContainerServiceV20141113."
+	contentType  = "application/x-amz-json-1.1"
+	signingName  = "ecs"
+
+	// services are described in batches of at most this size
+	describeBatchSize = 10
+
+	DefaultTimeout = time.Duration(10) * time.Minute
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.ecs")
+
+var (
+	ErrorTimeout            = errors.New("The operation has timed out")
+	ErrorServiceNotFound    = errors.New("The service does not exist")
+	ErrorDeploymentFailed   = errors.New("The deployment failed and was rolled back")
+	ErrorNoRegion           = errors.New("No region specified - set the region of the environment, use --region or set $AWS_REGION")
+	ErrorEmptyDocument      = errors.New("Document defines neither a taskDefinition nor a service")
+	ErrorMissingServiceName = errors.New("Document service does not specify a serviceName")
+)
+
+// Fields of CreateService which can't be sent when updating a service
+var createOnlyFields = []string{"serviceName", "launchType", "schedulingStrategy", "role", "clientToken", "tags", "deploymentController"}
+
+type ECS interface {
+
+	// Returns the cluster the client operates on
+	Cluster() string
+
+	// Registers the task definition and creates or updates the service within {doc}.  The document
+	// holds a RegisterTaskDefinition request under 'taskDefinition' and a CreateService request under
+	// 'service'.  Either may be omitted; a service without a task definition uses the task definition
+	// named within the service
+	Deploy(doc map[string]interface{}) (*DeployResult, error)
+
+	/** Task Definition API */
+
+	// Registers a new revision of a task definition
+	// {def} - the RegisterTaskDefinition request
+	RegisterTaskDefinition(def map[string]interface{}) (*TaskDefinition, error)
+
+	/** Service API */
+
+	// List all services within the cluster
+	ListServices() ([]*Service, error)
+
+	// Get a Service by name
+	// {name} - service name
+	GetService(name string) (*Service, error)
+
+	// Creates a Service within the cluster
+	// {input} - the CreateService request
+	CreateService(input map[string]interface{}) (*Service, error)
+
+	// Updates a Service within the cluster
+	// {input} - the UpdateService request
+	UpdateService(input map[string]interface{}) (*Service, error)
+
+	// Scale a Service
+	// {name} - service name
+	// {count} - desired count of tasks
+	ScaleService(name string, count int) (*Service, error)
+
+	// Waits until the service has a single deployment with all desired tasks running
+	// {name} - service name
+	// {timeout} - the max time to wait
+	WaitForSteadyState(name string, timeout time.Duration) error
+}
+
+type ECSClient struct {
+	http    httpclient.HttpClient
+	host    string
+	cluster string
+	opts    *ECSOptions
+	// every action is a POST so read-only is enforced by call rather than the http client
+	readOnly bool
+}
+
+type ECSOptions struct {
+	// Optional endpoint used in place of https://ecs.{region}.amazonaws.com (eg. a VPC endpoint)
+	Endpoint string
+	// Refuses the actions other than Describe* and List* (eg. registering task definitions and updating
+	// services) with httpclient.ErrorReadOnly
+	ReadOnly bool
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Optional reporter drawing the status (running tasks of the total) while waiting on deployments.  Status is logged when nil
+	Progress progress.Reporter
+}
+
+// NewECSClient creates a client for {cluster} within {region} signing requests with {creds}
+func NewECSClient(cluster, region string, creds *Credentials, opts *ECSOptions) (ECS, error) {
+	if region == "" {
+		return nil, ErrorNoRegion
+	}
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.Authenticator = &SigV4Signer{Credentials: creds, Region: region, Service: signingName}
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	httpConfig.RPC = true
+
+	c := new(ECSClient)
+	c.host = fmt.Sprintf("https://ecs.%s.amazonaws.com", region)
+	if opts != nil {
+		httpConfig.Timeouts = opts.Timeouts
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+		if opts.Endpoint != "" {
+			c.host = strings.TrimRight(opts.Endpoint, "/")
+		}
+	}
+	c.http = *httpclient.NewHttpClient(*httpConfig)
+	c.cluster = cluster
+	c.opts = opts
+	c.readOnly = opts != nil && opts.ReadOnly
+	return c, nil
+}
+
+func (c *ECSClient) Cluster() string {
+	return c.cluster
+}
+
+// Invokes the API {action} with {input} decoding the response into {result}.  Every action is a POST so
+// read-only environments and the write guard are enforced here: only Describe* and List* actions are reads
+func (c *ECSClient) call(action string, input, result interface{}) error {
+	if !strings.HasPrefix(action, "Describe") && !strings.HasPrefix(action, "List") {
+		if c.readOnly {
+			return httpclient.ErrorReadOnly
+		}
+		if err := httpclient.CheckWrite("POST", c.host+"/"); err != nil {
+			return err
+		}
+	}
+	headers := map[string]string{"X-Amz-Target": targetPrefix + action, "Content-Type": contentType}
+	if resp := c.http.HttpPostWithHeaders(c.host+"/", headers, input, result); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *ECSClient) Deploy(doc map[string]interface{}) (*DeployResult, error) {
+	def, _ := doc["taskDefinition"].(map[string]interface{})
+	service, _ := doc["service"].(map[string]interface{})
+	if def == nil && service == nil {
+		return nil, ErrorEmptyDocument
+	}
+
+	result := &DeployResult{}
+	if def != nil {
+		td, err := c.RegisterTaskDefinition(def)
+		if err != nil {
+			return nil, err
+		}
+		result.TaskDefinition = td.TaskDefinitionArn
+		result.Action = "registered"
+	}
+	if service == nil {
+		return result, nil
+	}
+
+	name, _ := service["serviceName"].(string)
+	if name == "" {
+		return nil, ErrorMissingServiceName
+	}
+	result.Service = name
+	if result.TaskDefinition == "" {
+		result.TaskDefinition, _ = service["taskDefinition"].(string)
+	}
+
+	_, err := c.GetService(name)
+	switch err {
+	case ErrorServiceNotFound:
+		input := copyMap(service)
+		input["cluster"] = c.cluster
+		input["taskDefinition"] = result.TaskDefinition
+		if _, err := c.CreateService(input); err != nil {
+			return nil, err
+		}
+		result.Action = "created"
+	case nil:
+		input := copyMap(service)
+		for _, f := range createOnlyFields {
+			delete(input, f)
+		}
+		input["cluster"] = c.cluster
+		input["service"] = name
+		input["taskDefinition"] = result.TaskDefinition
+		if _, err := c.UpdateService(input); err != nil {
+			return nil, err
+		}
+		result.Action = "updated"
+	default:
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *ECSClient) RegisterTaskDefinition(def map[string]interface{}) (*TaskDefinition, error) {
+	log.Info("Registering task definition '%v'", def["family"])
+	resp := new(registerTaskDefinitionResponse)
+	if err := c.call("RegisterTaskDefinition", def, resp); err != nil {
+		return nil, err
+	}
+	return resp.TaskDefinition, nil
+}
+
+func (c *ECSClient) ListServices() ([]*Service, error) {
+	arns := []string{}
+	req := &listServicesRequest{Cluster: c.cluster, MaxResults: 100}
+	for {
+		resp := new(listServicesResponse)
+		if err := c.call("ListServices", req, resp); err != nil {
+			return nil, err
+		}
+		arns = append(arns, resp.ServiceArns...)
+		if resp.NextToken == "" {
+			break
+		}
+		req.NextToken = resp.NextToken
+	}
+
+	services := []*Service{}
+	for start := 0; start < len(arns); start += describeBatchSize {
+		end := start + describeBatchSize
+		if end > len(arns) {
+			end = len(arns)
+		}
+		batch, err := c.describeServices(arns[start:end])
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, batch.Services...)
+	}
+	return services, nil
+}
+
+func (c *ECSClient) GetService(name string) (*Service, error) {
+	resp, err := c.describeServices([]string{name})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range resp.Services {
+		// deleted services are reported as INACTIVE until they are purged
+		if s.Status != "INACTIVE" {
+			return s, nil
+		}
+	}
+	return nil, ErrorServiceNotFound
+}
+
+func (c *ECSClient) describeServices(services []string) (*describeServicesResponse, error) {
+	resp := new(describeServicesResponse)
+	if err := c.call("DescribeServices", &describeServicesRequest{Cluster: c.cluster, Services: services}, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *ECSClient) CreateService(input map[string]interface{}) (*Service, error) {
+	log.Info("Creating service '%v' in cluster '%s'", input["serviceName"], c.cluster)
+	resp := new(serviceResponse)
+	if err := c.call("CreateService", input, resp); err != nil {
+		return nil, err
+	}
+	return resp.Service, nil
+}
+
+func (c *ECSClient) UpdateService(input map[string]interface{}) (*Service, error) {
+	log.Info("Updating service '%v' in cluster '%s'", input["service"], c.cluster)
+	resp := new(serviceResponse)
+	if err := c.call("UpdateService", input, resp); err != nil {
+		return nil, err
+	}
+	return resp.Service, nil
+}
+
+func (c *ECSClient) ScaleService(name string, count int) (*Service, error) {
+	log.Info("Scaling service '%s' to %d tasks", name, count)
+	resp := new(serviceResponse)
+	if err := c.call("UpdateService", &updateServiceRequest{Cluster: c.cluster, Service: name, DesiredCount: count}, resp); err != nil {
+		return nil, err
+	}
+	return resp.Service, nil
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,196 @@
+package ecs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedCall struct {
+	Action string
+	Input  map[string]interface{}
+}
+
+// Starts a server answering each action with {responses} and recording every call
+func newTestClient(t *testing.T, responses func(action string, input map[string]interface{}) (int, string)) (ECS, *[]recordedCall, func()) {
+	calls := []recordedCall{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, contentType, r.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), sigV4Algorithm))
+		call := recordedCall{Action: strings.TrimPrefix(r.Header.Get("X-Amz-Target"), targetPrefix)}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &call.Input)
+		calls = append(calls, call)
+		status, body := responses(call.Action, call.Input)
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	retry := httpclient.DefaultRetryPolicy()
+	retry.MaxAttempts = 1
+	c, err := NewECSClient("web", "us-east-1", &Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, &ECSOptions{Endpoint: s.URL, Retry: retry})
+	assert.Nil(t, err)
+	return c, &calls, s.Close
+}
+
+func testDocument() map[string]interface{} {
+	return map[string]interface{}{
+		"taskDefinition": map[string]interface{}{"family": "api"},
+		"service":        map[string]interface{}{"serviceName": "api", "desiredCount": 2, "launchType": "FARGATE"},
+	}
+}
+
+func TestDeployCreatesService(t *testing.T) {
+	c, calls, stop := newTestClient(t, func(action string, input map[string]interface{}) (int, string) {
+		switch action {
+		case "RegisterTaskDefinition":
+			return 200, `{"taskDefinition": {"taskDefinitionArn": "arn:td/api:3", "family": "api", "revision": 3}}`
+		case "DescribeServices":
+			return 200, `{"services": [], "failures": [{"arn": "api", "reason": "MISSING"}]}`
+		}
+		return 200, `{"service": {"serviceName": "api"}}`
+	})
+	defer stop()
+
+	result, err := c.Deploy(testDocument())
+	assert.Nil(t, err)
+	assert.Equal(t, &DeployResult{TaskDefinition: "arn:td/api:3", Service: "api", Action: "created"}, result)
+	assert.Equal(t, "CreateService", (*calls)[2].Action)
+	assert.Equal(t, "web", (*calls)[2].Input["cluster"])
+	assert.Equal(t, "arn:td/api:3", (*calls)[2].Input["taskDefinition"])
+	assert.Equal(t, "FARGATE", (*calls)[2].Input["launchType"])
+}
+
+func TestDeployUpdatesService(t *testing.T) {
+	c, calls, stop := newTestClient(t, func(action string, input map[string]interface{}) (int, string) {
+		switch action {
+		case "RegisterTaskDefinition":
+			return 200, `{"taskDefinition": {"taskDefinitionArn": "arn:td/api:4"}}`
+		case "DescribeServices":
+			return 200, `{"services": [{"serviceName": "api", "status": "ACTIVE"}]}`
+		}
+		return 200, `{"service": {"serviceName": "api"}}`
+	})
+	defer stop()
+
+	result, err := c.Deploy(testDocument())
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", result.Action)
+
+	update := (*calls)[2]
+	assert.Equal(t, "UpdateService", update.Action)
+	assert.Equal(t, "api", update.Input["service"])
+	assert.Equal(t, float64(2), update.Input["desiredCount"])
+	assert.Nil(t, update.Input["serviceName"])
+	assert.Nil(t, update.Input["launchType"])
+}
+
+func TestDeployInactiveServiceIsRecreated(t *testing.T) {
+	c, calls, stop := newTestClient(t, func(action string, input map[string]interface{}) (int, string) {
+		if action == "DescribeServices" {
+			return 200, `{"services": [{"serviceName": "api", "status": "INACTIVE"}]}`
+		}
+		return 200, `{}`
+	})
+	defer stop()
+
+	doc := map[string]interface{}{"service": map[string]interface{}{"serviceName": "api", "taskDefinition": "api:2"}}
+	result, err := c.Deploy(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, &DeployResult{TaskDefinition: "api:2", Service: "api", Action: "created"}, result)
+	assert.Len(t, *calls, 2)
+}
+
+func TestDeployReportsAPIErrors(t *testing.T) {
+	c, _, stop := newTestClient(t, func(action string, input map[string]interface{}) (int, string) {
+		return 400, `{"__type": "ClientException", "message": "Container.image should not be null or empty."}`
+	})
+	defer stop()
+
+	_, err := c.Deploy(testDocument())
+	assert.EqualError(t, err, "Container.image should not be null or empty. (Status 400)")
+
+	_, err = c.Deploy(map[string]interface{}{})
+	assert.Equal(t, ErrorEmptyDocument, err)
+}
+
+func TestListServicesPagesAndBatches(t *testing.T) {
+	c, calls, stop := newTestClient(t, func(action string, input map[string]interface{}) (int, string) {
+		if action == "ListServices" {
+			if input["nextToken"] == nil {
+				arns := []string{}
+				for i := 0; i < 12; i++ {
+					arns = append(arns, fmt.Sprintf(`"arn:svc/%d"`, i))
+				}
+				return 200, fmt.Sprintf(`{"serviceArns": [%s], "nextToken": "next"}`, strings.Join(arns, ","))
+			}
+			return 200, `{"serviceArns": ["arn:svc/12"]}`
+		}
+		services := []string{}
+		for _, arn := range input["services"].([]interface{}) {
+			services = append(services, fmt.Sprintf(`{"serviceArn": "%s"}`, arn))
+		}
+		return 200, fmt.Sprintf(`{"services": [%s]}`, strings.Join(services, ","))
+	})
+	defer stop()
+
+	services, err := c.ListServices()
+	assert.Nil(t, err)
+	assert.Len(t, services, 13)
+	assert.Len(t, *calls, 4)
+	assert.Len(t, (*calls)[2].Input["services"], describeBatchSize)
+}
+
+func TestReadOnlyPermitsReads(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"serviceArns": []}`)
+	}))
+	defer s.Close()
+	c, _ := NewECSClient("web", "us-east-1", &Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, &ECSOptions{Endpoint: s.URL, ReadOnly: true})
+
+	_, err := c.ListServices()
+	assert.Nil(t, err, "List* and Describe* actions are reads")
+	_, err = c.Deploy(testDocument())
+	assert.Equal(t, httpclient.ErrorReadOnly, err)
+}
+
+func TestWaitForSteadyState(t *testing.T) {
+	defer func(i time.Duration) { waitInterval = i }(waitInterval)
+	waitInterval = time.Millisecond
+
+	polls := 0
+	c, _, stop := newTestClient(t, func(action string, input map[string]interface{}) (int, string) {
+		polls++
+		if polls < 3 {
+			return 200, `{"services": [{"status": "ACTIVE", "desiredCount": 2, "runningCount": 1,
+				"deployments": [{"status": "PRIMARY"}, {"status": "ACTIVE"}]}]}`
+		}
+		return 200, `{"services": [{"status": "ACTIVE", "desiredCount": 2, "runningCount": 2, "deployments": [{"status": "PRIMARY"}]}]}`
+	})
+	defer stop()
+
+	assert.Nil(t, c.WaitForSteadyState("api", time.Minute))
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, ErrorTimeout, c.WaitForSteadyState("api", 0))
+}
+
+func TestWaitForSteadyStateFailedRollout(t *testing.T) {
+	c, _, stop := newTestClient(t, func(action string, input map[string]interface{}) (int, string) {
+		return 200, `{"services": [{"status": "ACTIVE", "deployments": [{"status": "PRIMARY", "rolloutState": "FAILED"}]}]}`
+	})
+	defer stop()
+
+	assert.Equal(t, ErrorDeploymentFailed, c.WaitForSteadyState("api", time.Minute))
+}
+
+func TestNewECSClientRequiresRegion(t *testing.T) {
+	_, err := NewECSClient("web", "", &Credentials{}, nil)
+	assert.Equal(t, ErrorNoRegion, err)
+}
@@ -0,0 +1,41 @@
+package ecs
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The get-vanilla case of the AWS Signature Version 4 test suite
+func TestSigV4Vanilla(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	s := &SigV4Signer{
+		Credentials: &Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+		Region:      "us-east-1",
+		Service:     "service",
+	}
+	s.Sign(req, nil, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestSigV4SignsTargetAndSessionToken(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://ecs.us-west-2.amazonaws.com/", nil)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Target", targetPrefix+"ListServices")
+	s := &SigV4Signer{Credentials: &Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}, Region: "us-west-2", Service: "ecs"}
+	s.Sign(req, []byte(`{}`), time.Now())
+
+	assert.Equal(t, "session", req.Header.Get("X-Amz-Security-Token"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token;x-amz-target,")
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/?b=2&a=x%20y&a=1", nil)
+	assert.Equal(t, "a=1&a=x%20y&b=2", canonicalQuery(req))
+}
@@ -0,0 +1,98 @@
+package ecs
+
+// The subset of the ECS API objects displayed and managed by depcon
+
+type Service struct {
+	ServiceName    string        `json:"serviceName"`
+	ServiceArn     string        `json:"serviceArn"`
+	ClusterArn     string        `json:"clusterArn,omitempty"`
+	Status         string        `json:"status"`
+	LaunchType     string        `json:"launchType,omitempty"`
+	TaskDefinition string        `json:"taskDefinition"`
+	DesiredCount   int           `json:"desiredCount"`
+	RunningCount   int           `json:"runningCount"`
+	PendingCount   int           `json:"pendingCount"`
+	Deployments    []*Deployment `json:"deployments,omitempty"`
+	Events         []*Event      `json:"events,omitempty"`
+	// seconds since the epoch
+	CreatedAt float64 `json:"createdAt,omitempty"`
+}
+
+// Deployment is a rollout of a task definition within a service.  The PRIMARY deployment is the latest
+type Deployment struct {
+	ID                 string  `json:"id"`
+	Status             string  `json:"status"`
+	TaskDefinition     string  `json:"taskDefinition"`
+	DesiredCount       int     `json:"desiredCount"`
+	RunningCount       int     `json:"runningCount"`
+	PendingCount       int     `json:"pendingCount"`
+	FailedTasks        int     `json:"failedTasks,omitempty"`
+	RolloutState       string  `json:"rolloutState,omitempty"`
+	RolloutStateReason string  `json:"rolloutStateReason,omitempty"`
+	CreatedAt          float64 `json:"createdAt,omitempty"`
+	UpdatedAt          float64 `json:"updatedAt,omitempty"`
+}
+
+type Event struct {
+	ID        string  `json:"id"`
+	Message   string  `json:"message"`
+	CreatedAt float64 `json:"createdAt"`
+}
+
+type TaskDefinition struct {
+	TaskDefinitionArn string `json:"taskDefinitionArn"`
+	Family            string `json:"family"`
+	Revision          int    `json:"revision"`
+	Status            string `json:"status"`
+}
+
+type Failure struct {
+	Arn    string `json:"arn"`
+	Reason string `json:"reason"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// DeployResult is the outcome of deploying a document
+type DeployResult struct {
+	// ARN of the registered task definition
+	TaskDefinition string `json:"taskDefinition"`
+	// Name of the service or empty when the document only registers a task definition
+	Service string `json:"service,omitempty"`
+	// registered, created or updated
+	Action string `json:"action"`
+}
+
+type registerTaskDefinitionResponse struct {
+	TaskDefinition *TaskDefinition `json:"taskDefinition"`
+}
+
+type serviceResponse struct {
+	Service *Service `json:"service"`
+}
+
+type describeServicesRequest struct {
+	Cluster  string   `json:"cluster"`
+	Services []string `json:"services"`
+}
+
+type describeServicesResponse struct {
+	Services []*Service `json:"services"`
+	Failures []*Failure `json:"failures"`
+}
+
+type listServicesRequest struct {
+	Cluster    string `json:"cluster"`
+	NextToken  string `json:"nextToken,omitempty"`
+	MaxResults int    `json:"maxResults"`
+}
+
+type listServicesResponse struct {
+	ServiceArns []string `json:"serviceArns"`
+	NextToken   string   `json:"nextToken"`
+}
+
+type updateServiceRequest struct {
+	Cluster      string `json:"cluster"`
+	Service      string `json:"service"`
+	DesiredCount int    `json:"desiredCount"`
+}
@@ -0,0 +1,71 @@
+package ecs
+
+import (
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+var logWait = logger.GetLogger("depcon.deploy.wait")
+
+// interval between checks of the service
+var waitInterval = time.Duration(5) * time.Second
+
+func (c *ECSClient) WaitForSteadyState(name string, timeout time.Duration) error {
+	t_now := time.Now()
+	t_stop := t_now.Add(timeout)
+
+	for {
+		if time.Now().After(t_stop) {
+			c.clearWaitStatus()
+			return ErrorTimeout
+		}
+		s, err := c.GetService(name)
+		if err != nil {
+			c.clearWaitStatus()
+			return err
+		}
+		if primary := s.PrimaryDeployment(); primary != nil && primary.RolloutState == "FAILED" {
+			c.clearWaitStatus()
+			logWait.Error("Deployment of '%s' failed: %s", name, primary.RolloutStateReason)
+			return ErrorDeploymentFailed
+		}
+		if s.IsSteady() {
+			c.clearWaitStatus()
+			logWait.Info("Service '%s' has reached a steady state, elapsed time %s", name, utils.ElapsedStr(time.Since(t_now)))
+			return nil
+		}
+		if !c.reportWaitStatus("Waiting for service "+name+" to reach a steady state", s.RunningCount, s.DesiredCount) {
+			logWait.Info("%v of %v tasks of '%s' are running (%v deployments).  Retrying check in %v", s.RunningCount, s.DesiredCount, name, len(s.Deployments), waitInterval)
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// PrimaryDeployment returns the latest deployment of the service or nil when there isn't one
+func (s *Service) PrimaryDeployment() *Deployment {
+	for _, d := range s.Deployments {
+		if d.Status == "PRIMARY" {
+			return d
+		}
+	}
+	return nil
+}
+
+// IsSteady determines if the service has a single deployment with every desired task running (the
+// condition of 'aws ecs wait services-stable')
+func (s *Service) IsSteady() bool {
+	return len(s.Deployments) == 1 && s.RunningCount == s.DesiredCount
+}
+
+func (c *ECSClient) reportWaitStatus(message string, done, total int) bool {
+	return c.opts != nil && progress.Report(c.opts.Progress, message, done, total)
+}
+
+func (c *ECSClient) clearWaitStatus() {
+	if c.opts != nil {
+		progress.Clear(c.opts.Progress)
+	}
+}
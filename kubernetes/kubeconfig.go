@@ -0,0 +1,186 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/userdir"
+)
+
+const (
+	// Environment variable holding the kubeconfig path(s)
+	KubeConfigEnv = "KUBECONFIG"
+	// Namespace used when neither the context nor the command specify one
+	DefaultNamespace = "default"
+)
+
+var (
+	ErrNoKubeConfig = errors.New("No kubeconfig found - set $KUBECONFIG, use --kubeconfig or create ~/.kube/config")
+	ErrNoContext    = errors.New("No context specified and the kubeconfig does not define a current-context")
+)
+
+// KubeConfig is the cluster, credentials and namespace resolved from a context within a kubeconfig
+type KubeConfig struct {
+	// Name of the context the configuration was resolved from
+	Context string
+	// API server URL (eg. https://10.0.0.1:6443)
+	Server    string
+	Namespace string
+	// Bearer token used in place of basic auth
+	Token    string
+	Username string
+	Password string
+	// Client certificate and CA used for mutual TLS
+	TLS      *httpclient.TLSConfig
+	Insecure bool
+}
+
+type kubeConfigFile struct {
+	CurrentContext string         `json:"current-context"`
+	Clusters       []namedCluster `json:"clusters"`
+	Contexts       []namedContext `json:"contexts"`
+	Users          []namedUser    `json:"users"`
+}
+
+type namedCluster struct {
+	Name    string `json:"name"`
+	Cluster struct {
+		Server                   string `json:"server"`
+		CertificateAuthority     string `json:"certificate-authority"`
+		CertificateAuthorityData []byte `json:"certificate-authority-data"`
+		InsecureSkipTLSVerify    bool   `json:"insecure-skip-tls-verify"`
+	} `json:"cluster"`
+}
+
+type namedContext struct {
+	Name    string `json:"name"`
+	Context struct {
+		Cluster   string `json:"cluster"`
+		User      string `json:"user"`
+		Namespace string `json:"namespace"`
+	} `json:"context"`
+}
+
+type namedUser struct {
+	Name string `json:"name"`
+	User struct {
+		Token                 string      `json:"token"`
+		TokenFile             string      `json:"tokenFile"`
+		Username              string      `json:"username"`
+		Password              string      `json:"password"`
+		ClientCertificate     string      `json:"client-certificate"`
+		ClientCertificateData []byte      `json:"client-certificate-data"`
+		ClientKey             string      `json:"client-key"`
+		ClientKeyData         []byte      `json:"client-key-data"`
+		Exec                  interface{} `json:"exec"`
+		AuthProvider          interface{} `json:"auth-provider"`
+	} `json:"user"`
+}
+
+// DefaultKubeConfigPath returns the first existing file listed within $KUBECONFIG or ~/.kube/config.
+// Multiple files within $KUBECONFIG are not merged
+func DefaultKubeConfigPath() string {
+	for _, path := range filepath.SplitList(os.Getenv(KubeConfigEnv)) {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(userdir.Get(), ".kube", "config")
+}
+
+// LoadKubeConfig resolves {context} (or the current-context when empty) from the kubeconfig {filename}
+// (or DefaultKubeConfigPath when empty).  Relative certificate and token files are resolved against the
+// directory of the kubeconfig.  Exec and auth-provider credential plugins are not supported
+func LoadKubeConfig(filename, context string) (*KubeConfig, error) {
+	if filename == "" {
+		filename = DefaultKubeConfigPath()
+	}
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoKubeConfig
+		}
+		return nil, err
+	}
+
+	file := &kubeConfigFile{}
+	if err := encoding.DefaultYAMLEncoder().UnMarshalStr(string(b), file); err != nil {
+		return nil, fmt.Errorf("Unable to parse kubeconfig '%s': %s", filename, err.Error())
+	}
+	return file.resolve(context, filepath.Dir(filename))
+}
+
+func (f *kubeConfigFile) resolve(context, dir string) (*KubeConfig, error) {
+	if context == "" {
+		context = f.CurrentContext
+	}
+	if context == "" {
+		return nil, ErrNoContext
+	}
+
+	var ctx *namedContext
+	for i := range f.Contexts {
+		if f.Contexts[i].Name == context {
+			ctx = &f.Contexts[i]
+		}
+	}
+	if ctx == nil {
+		return nil, fmt.Errorf("Context '%s' could not be found in the kubeconfig", context)
+	}
+
+	config := &KubeConfig{Context: context, Namespace: ctx.Context.Namespace, TLS: &httpclient.TLSConfig{}}
+	if config.Namespace == "" {
+		config.Namespace = DefaultNamespace
+	}
+
+	found := false
+	for _, c := range f.Clusters {
+		if c.Name == ctx.Context.Cluster {
+			found = true
+			config.Server = strings.TrimRight(c.Cluster.Server, "/")
+			config.Insecure = c.Cluster.InsecureSkipTLSVerify
+			config.TLS.CAData = c.Cluster.CertificateAuthorityData
+			config.TLS.CAFile = resolvePath(dir, c.Cluster.CertificateAuthority)
+		}
+	}
+	if !found || config.Server == "" {
+		return nil, fmt.Errorf("Cluster '%s' of context '%s' could not be found in the kubeconfig", ctx.Context.Cluster, context)
+	}
+
+	for _, u := range f.Users {
+		if u.Name != ctx.Context.User {
+			continue
+		}
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("User '%s' authenticates with a credential plugin (exec/auth-provider) which is not supported - use a token or client certificate", u.Name)
+		}
+		config.Token = u.User.Token
+		if config.Token == "" && u.User.TokenFile != "" {
+			token, err := ioutil.ReadFile(resolvePath(dir, u.User.TokenFile))
+			if err != nil {
+				return nil, err
+			}
+			config.Token = strings.TrimSpace(string(token))
+		}
+		config.Username = u.User.Username
+		config.Password = u.User.Password
+		config.TLS.CertData = u.User.ClientCertificateData
+		config.TLS.KeyData = u.User.ClientKeyData
+		config.TLS.CertFile = resolvePath(dir, u.User.ClientCertificate)
+		config.TLS.KeyFile = resolvePath(dir, u.User.ClientKey)
+	}
+	return config, nil
+}
+
+func resolvePath(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
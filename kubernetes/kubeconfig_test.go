@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKubeConfig = `
+apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev-cluster
+  cluster:
+    server: https://dev.example.com:6443/
+    certificate-authority: certs/ca.pem
+- name: prod-cluster
+  cluster:
+    server: https://prod.example.com
+    certificate-authority-data: Y2EtZGF0YQ==
+    insecure-skip-tls-verify: true
+contexts:
+- name: dev
+  context:
+    cluster: dev-cluster
+    user: dev-user
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: prod-user
+    namespace: payments
+- name: sso
+  context:
+    cluster: prod-cluster
+    user: sso-user
+users:
+- name: dev-user
+  user:
+    tokenFile: token
+- name: prod-user
+  user:
+    client-certificate-data: Y2VydA==
+    client-key-data: a2V5
+- name: sso-user
+  user:
+    exec:
+      command: aws
+`
+
+func writeKubeConfig(t *testing.T) (string, func()) {
+	dir, _ := ioutil.TempDir("", "depcon-kube")
+	filename := filepath.Join(dir, "config")
+	ioutil.WriteFile(filename, []byte(testKubeConfig), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "token"), []byte("secret-token\n"), 0600)
+	return filename, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadKubeConfigCurrentContext(t *testing.T) {
+	filename, cleanup := writeKubeConfig(t)
+	defer cleanup()
+
+	config, err := LoadKubeConfig(filename, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "dev", config.Context)
+	assert.Equal(t, "https://dev.example.com:6443", config.Server)
+	assert.Equal(t, DefaultNamespace, config.Namespace)
+	assert.Equal(t, "secret-token", config.Token)
+	assert.Equal(t, filepath.Join(filepath.Dir(filename), "certs", "ca.pem"), config.TLS.CAFile)
+	assert.False(t, config.Insecure)
+}
+
+func TestLoadKubeConfigEmbeddedCertificates(t *testing.T) {
+	filename, cleanup := writeKubeConfig(t)
+	defer cleanup()
+
+	config, err := LoadKubeConfig(filename, "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, "payments", config.Namespace)
+	assert.Equal(t, []byte("ca-data"), config.TLS.CAData)
+	assert.Equal(t, []byte("cert"), config.TLS.CertData)
+	assert.Equal(t, []byte("key"), config.TLS.KeyData)
+	assert.True(t, config.Insecure)
+	assert.Empty(t, config.Token)
+}
+
+func TestLoadKubeConfigErrors(t *testing.T) {
+	filename, cleanup := writeKubeConfig(t)
+	defer cleanup()
+
+	_, err := LoadKubeConfig(filename, "missing")
+	assert.EqualError(t, err, "Context 'missing' could not be found in the kubeconfig")
+
+	_, err = LoadKubeConfig(filename, "sso")
+	assert.Contains(t, err.Error(), "credential plugin")
+
+	_, err = LoadKubeConfig(filepath.Join(filepath.Dir(filename), "absent"), "")
+	assert.Equal(t, ErrNoKubeConfig, err)
+}
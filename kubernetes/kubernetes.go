@@ -0,0 +1,286 @@
+// Kubernetes API
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	/* --- api related constants --- */
+	API_CORE = "api/v1"
+	API_APPS = "apis/apps/v1"
+
+	KIND_DEPLOYMENT = "Deployment"
+	KIND_SERVICE    = "Service"
+
+	DefaultTimeout = time.Duration(5) * time.Minute
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.kubernetes")
+
+var (
+	ErrorTimeout         = errors.New("The operation has timed out")
+	ErrorMissingKind     = errors.New("Document does not specify a kind")
+	ErrorMissingName     = errors.New("Document does not specify metadata.name")
+	ErrorUnsupportedKind = errors.New("Only Deployment and Service documents are supported")
+)
+
+// Resource paths of the supported kinds relative to the namespace
+var kindPaths = map[string][]string{
+	KIND_DEPLOYMENT: {API_APPS, "deployments"},
+	KIND_SERVICE:    {API_CORE, "services"},
+}
+
+type Kubernetes interface {
+
+	// Returns the namespace the client operates within
+	Namespace() string
+
+	// Creates the Deployment or Service {doc} or updates it when it already exists
+	// {doc} - the parsed document
+	Apply(doc map[string]interface{}) (*AppliedResource, error)
+
+	/** Deployment API */
+
+	// List all deployments within the namespace
+	ListDeployments() (*DeploymentList, error)
+
+	// Get a Deployment by name
+	// {name} - deployment name
+	GetDeployment(name string) (*Deployment, error)
+
+	// Scale a Deployment
+	// {name} - deployment name
+	// {replicas} - replicas to scale to
+	ScaleDeployment(name string, replicas int) (*Scale, error)
+
+	// Removes a Deployment and its pods
+	// {name} - deployment name
+	DeleteDeployment(name string) error
+
+	// Waits until the latest revision of a Deployment is rolled out and all replicas are available
+	// {name} - deployment name
+	// {timeout} - the max time to wait
+	WaitForDeployment(name string, timeout time.Duration) error
+
+	/** Service API */
+
+	// List all services within the namespace
+	ListServices() (*ServiceList, error)
+
+	// Get a Service by name
+	// {name} - service name
+	GetService(name string) (*Service, error)
+
+	// Removes a Service
+	// {name} - service name
+	DeleteService(name string) error
+}
+
+type KubernetesClient struct {
+	http      httpclient.HttpClient
+	host      string
+	namespace string
+	opts      *KubernetesOptions
+}
+
+type KubernetesOptions struct {
+	// Refuses applying and deleting deployments and services (every request other than a GET) with
+	// httpclient.ErrorReadOnly
+	ReadOnly bool
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Optional reporter drawing the status (available replicas of the total) while waiting on deployments.  Status is logged when nil
+	Progress progress.Reporter
+}
+
+// Bearer token authentication from the kubeconfig
+type tokenAuthenticator string
+
+func (t tokenAuthenticator) Token(refresh bool) (string, error) {
+	return string(t), nil
+}
+
+func (t tokenAuthenticator) Apply(req *http.Request, token string) {
+	req.Header.Set("Authorization", "Bearer "+token)
+}
+
+// NewKubernetesClient creates a client for the cluster and namespace of {config}
+func NewKubernetesClient(config *KubeConfig, opts *KubernetesOptions) Kubernetes {
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.HttpUser = config.Username
+	httpConfig.HttpPass = config.Password
+	httpConfig.TLSInsecureSkipVerify = config.Insecure
+	httpConfig.TLS = config.TLS
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	if config.Token != "" {
+		httpConfig.Authenticator = tokenAuthenticator(config.Token)
+	}
+	if opts != nil {
+		httpConfig.ReadOnly = opts.ReadOnly
+		httpConfig.Timeouts = opts.Timeouts
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+	}
+
+	c := new(KubernetesClient)
+	c.http = *httpclient.NewHttpClient(*httpConfig)
+	c.host = config.Server
+	c.namespace = config.Namespace
+	if c.namespace == "" {
+		c.namespace = DefaultNamespace
+	}
+	c.opts = opts
+	return c
+}
+
+func (c *KubernetesClient) Namespace() string {
+	return c.namespace
+}
+
+// Returns the URL of the {resource} collection (eg. deployments) of {api} followed by {elements}
+func (c *KubernetesClient) namespacedUrl(api, resource string, elements ...string) string {
+	return utils.BuildPath(c.host, append([]string{api, "namespaces", c.namespace, resource}, elements...))
+}
+
+func (c *KubernetesClient) Apply(doc map[string]interface{}) (*AppliedResource, error) {
+	kind, _ := doc["kind"].(string)
+	if kind == "" {
+		return nil, ErrorMissingKind
+	}
+	path, ok := kindPaths[kind]
+	if !ok {
+		return nil, ErrorUnsupportedKind
+	}
+	metadata, _ := doc["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		return nil, ErrorMissingName
+	}
+	if ns, _ := metadata["namespace"].(string); ns != "" && ns != c.namespace {
+		return nil, fmt.Errorf("%s '%s' specifies namespace '%s' but the target namespace is '%s'", kind, name, ns, c.namespace)
+	}
+
+	result := &AppliedResource{Kind: kind, Name: name, Namespace: c.namespace}
+	existing := map[string]interface{}{}
+	resp := c.http.HttpGet(c.namespacedUrl(path[0], path[1], name), &existing)
+	switch resp.Error {
+	case httpclient.ErrorNotFound:
+		log.Info("Creating %s '%s' in namespace '%s'", kind, name, c.namespace)
+		if resp := c.http.HttpPost(c.namespacedUrl(path[0], path[1]), doc, nil); resp.Error != nil {
+			return nil, resp.Err()
+		}
+		result.Action = "created"
+	case nil:
+		log.Info("Updating %s '%s' in namespace '%s'", kind, name, c.namespace)
+		mergeServerFields(kind, doc, existing)
+		if resp := c.http.HttpPut(c.namespacedUrl(path[0], path[1], name), doc, nil); resp.Error != nil {
+			return nil, resp.Err()
+		}
+		result.Action = "configured"
+	default:
+		return nil, resp.Err()
+	}
+	return result, nil
+}
+
+// Copies the fields of the {existing} object which must be carried by a replacement {doc}: the
+// resourceVersion guarding against concurrent updates and the immutable cluster IP of a Service
+func mergeServerFields(kind string, doc, existing map[string]interface{}) {
+	if m, ok := existing["metadata"].(map[string]interface{}); ok {
+		doc["metadata"].(map[string]interface{})["resourceVersion"] = m["resourceVersion"]
+	}
+	if kind != KIND_SERVICE {
+		return
+	}
+	es, _ := existing["spec"].(map[string]interface{})
+	if es == nil || es["clusterIP"] == nil {
+		return
+	}
+	spec, ok := doc["spec"].(map[string]interface{})
+	if !ok {
+		spec = map[string]interface{}{}
+		doc["spec"] = spec
+	}
+	if _, set := spec["clusterIP"]; !set {
+		spec["clusterIP"] = es["clusterIP"]
+	}
+}
+
+func (c *KubernetesClient) ListDeployments() (*DeploymentList, error) {
+	list := new(DeploymentList)
+	if resp := c.http.HttpGet(c.namespacedUrl(API_APPS, "deployments"), list); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return list, nil
+}
+
+func (c *KubernetesClient) GetDeployment(name string) (*Deployment, error) {
+	d := new(Deployment)
+	if resp := c.http.HttpGet(c.namespacedUrl(API_APPS, "deployments", name), d); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return d, nil
+}
+
+func (c *KubernetesClient) ScaleDeployment(name string, replicas int) (*Scale, error) {
+	log.Info("Scaling deployment '%s' to %d replicas", name, replicas)
+	scale := &Scale{
+		APIVersion: "autoscaling/v1",
+		Kind:       "Scale",
+		Metadata:   ObjectMeta{Name: name, Namespace: c.namespace},
+		Spec:       ScaleSpec{Replicas: replicas},
+	}
+	result := new(Scale)
+	if resp := c.http.HttpPut(c.namespacedUrl(API_APPS, "deployments", name, "scale"), scale, result); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return result, nil
+}
+
+func (c *KubernetesClient) DeleteDeployment(name string) error {
+	log.Info("Deleting deployment '%s'", name)
+	return c.delete(API_APPS, "deployments", name)
+}
+
+func (c *KubernetesClient) ListServices() (*ServiceList, error) {
+	list := new(ServiceList)
+	if resp := c.http.HttpGet(c.namespacedUrl(API_CORE, "services"), list); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return list, nil
+}
+
+func (c *KubernetesClient) GetService(name string) (*Service, error) {
+	s := new(Service)
+	if resp := c.http.HttpGet(c.namespacedUrl(API_CORE, "services", name), s); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return s, nil
+}
+
+func (c *KubernetesClient) DeleteService(name string) error {
+	log.Info("Deleting service '%s'", name)
+	return c.delete(API_CORE, "services", name)
+}
+
+// Deletes the named resource along with its dependents (eg. the replica sets and pods of a Deployment)
+func (c *KubernetesClient) delete(api, resource, name string) error {
+	opts := &DeleteOptions{APIVersion: "v1", Kind: "DeleteOptions", PropagationPolicy: "Background"}
+	if resp := c.http.HttpDelete(c.namespacedUrl(api, resource, name), opts, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/mockrest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(handler http.HandlerFunc) (c Kubernetes, requests *[]mockrest.RecordedRequest, stop func()) {
+	requests, stop = mockrest.RecordClient(handler, func(url string, retry *httpclient.RetryPolicy) {
+		c = NewKubernetesClient(&KubeConfig{Server: url, Namespace: "web", Token: "abc"}, &KubernetesOptions{Retry: retry})
+	})
+	return
+}
+
+func TestApplyCreates(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"kind": "Status", "message": "not found"}`)
+			return
+		}
+		w.WriteHeader(201)
+		fmt.Fprint(w, `{}`)
+	})
+	defer stop()
+
+	doc := map[string]interface{}{"kind": "Deployment", "metadata": map[string]interface{}{"name": "api"}}
+	result, err := c.Apply(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, &AppliedResource{Kind: "Deployment", Name: "api", Namespace: "web", Action: "created"}, result)
+	assert.Len(t, *requests, 2)
+	assert.Equal(t, "/apis/apps/v1/namespaces/web/deployments/api", (*requests)[0].Path)
+	assert.Equal(t, "POST", (*requests)[1].Method)
+	assert.Equal(t, "/apis/apps/v1/namespaces/web/deployments", (*requests)[1].Path)
+	assert.Equal(t, "Bearer abc", (*requests)[1].Header.Get("Authorization"))
+}
+
+func TestApplyUpdatesService(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"metadata": {"name": "api", "resourceVersion": "42"}, "spec": {"clusterIP": "10.0.0.7"}}`)
+	})
+	defer stop()
+
+	doc := map[string]interface{}{"kind": "Service", "metadata": map[string]interface{}{"name": "api"},
+		"spec": map[string]interface{}{"ports": []interface{}{}}}
+	result, err := c.Apply(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, "configured", result.Action)
+
+	put := (*requests)[1]
+	assert.Equal(t, "PUT", put.Method)
+	assert.Equal(t, "/api/v1/namespaces/web/services/api", put.Path)
+	assert.Equal(t, "42", put.Body["metadata"].(map[string]interface{})["resourceVersion"])
+	assert.Equal(t, "10.0.0.7", put.Body["spec"].(map[string]interface{})["clusterIP"])
+}
+
+func TestApplyRejectsInvalidDocuments(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {})
+	defer stop()
+
+	_, err := c.Apply(map[string]interface{}{"metadata": map[string]interface{}{"name": "api"}})
+	assert.Equal(t, ErrorMissingKind, err)
+	_, err = c.Apply(map[string]interface{}{"kind": "ConfigMap", "metadata": map[string]interface{}{"name": "api"}})
+	assert.Equal(t, ErrorUnsupportedKind, err)
+	_, err = c.Apply(map[string]interface{}{"kind": "Service"})
+	assert.Equal(t, ErrorMissingName, err)
+	_, err = c.Apply(map[string]interface{}{"kind": "Service", "metadata": map[string]interface{}{"name": "api", "namespace": "other"}})
+	assert.EqualError(t, err, "Service 'api' specifies namespace 'other' but the target namespace is 'web'")
+	assert.Empty(t, *requests)
+}
+
+func TestApplyReportsAPIErrors(t *testing.T) {
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.WriteHeader(404)
+			return
+		}
+		w.WriteHeader(422)
+		fmt.Fprint(w, `{"kind": "Status", "message": "Deployment.apps \"api\" is invalid"}`)
+	})
+	defer stop()
+
+	_, err := c.Apply(map[string]interface{}{"kind": "Deployment", "metadata": map[string]interface{}{"name": "api"}})
+	assert.EqualError(t, err, `Deployment.apps "api" is invalid (Status 422)`)
+}
+
+func TestScaleDeployment(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"spec": {"replicas": 3}, "status": {"replicas": 1}}`)
+	})
+	defer stop()
+
+	scale, err := c.ScaleDeployment("api", 3)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, scale.Spec.Replicas)
+	assert.Equal(t, "/apis/apps/v1/namespaces/web/deployments/api/scale", (*requests)[0].Path)
+	assert.Equal(t, float64(3), (*requests)[0].Body["spec"].(map[string]interface{})["replicas"])
+}
+
+func TestDeleteService(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	defer stop()
+
+	assert.Nil(t, c.DeleteService("api"))
+	assert.Equal(t, "DELETE", (*requests)[0].Method)
+	assert.Equal(t, "/api/v1/namespaces/web/services/api", (*requests)[0].Path)
+	assert.Equal(t, "Background", (*requests)[0].Body["propagationPolicy"])
+}
+
+func TestWaitForDeployment(t *testing.T) {
+	defer func(i time.Duration) { waitInterval = i }(waitInterval)
+	waitInterval = time.Millisecond
+
+	polls := 0
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		available := 1
+		if polls > 2 {
+			available = 2
+		}
+		fmt.Fprintf(w, `{"metadata": {"generation": 2}, "spec": {"replicas": 2},
+			"status": {"observedGeneration": 2, "replicas": 2, "updatedReplicas": 2, "availableReplicas": %d}}`, available)
+	})
+	defer stop()
+
+	assert.Nil(t, c.WaitForDeployment("api", time.Minute))
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, ErrorTimeout, c.WaitForDeployment("api", 0))
+}
+
+func TestDeploymentIsReady(t *testing.T) {
+	d := &Deployment{Metadata: ObjectMeta{Generation: 3}}
+	d.Status = DeploymentStatus{ObservedGeneration: 2, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1}
+	assert.False(t, d.IsReady(), "generation not yet observed")
+	d.Status.ObservedGeneration = 3
+	assert.True(t, d.IsReady(), "replicas default to 1")
+	d.Status.Replicas = 2
+	assert.False(t, d.IsReady(), "old replicas remain")
+}
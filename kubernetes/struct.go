@@ -0,0 +1,117 @@
+package kubernetes
+
+// The subset of the Kubernetes API objects displayed and managed by depcon
+
+type ObjectMeta struct {
+	Name              string            `json:"name"`
+	Namespace         string            `json:"namespace,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	ResourceVersion   string            `json:"resourceVersion,omitempty"`
+	Generation        int64             `json:"generation,omitempty"`
+	CreationTimestamp string            `json:"creationTimestamp,omitempty"`
+}
+
+type ListMeta struct {
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type Deployment struct {
+	APIVersion string           `json:"apiVersion,omitempty"`
+	Kind       string           `json:"kind,omitempty"`
+	Metadata   ObjectMeta       `json:"metadata"`
+	Spec       DeploymentSpec   `json:"spec"`
+	Status     DeploymentStatus `json:"status"`
+}
+
+type DeploymentSpec struct {
+	Replicas *int            `json:"replicas,omitempty"`
+	Template PodTemplateSpec `json:"template"`
+}
+
+type DeploymentStatus struct {
+	ObservedGeneration  int64 `json:"observedGeneration,omitempty"`
+	Replicas            int   `json:"replicas,omitempty"`
+	UpdatedReplicas     int   `json:"updatedReplicas,omitempty"`
+	ReadyReplicas       int   `json:"readyReplicas,omitempty"`
+	AvailableReplicas   int   `json:"availableReplicas,omitempty"`
+	UnavailableReplicas int   `json:"unavailableReplicas,omitempty"`
+}
+
+type DeploymentList struct {
+	Metadata ListMeta      `json:"metadata"`
+	Items    []*Deployment `json:"items"`
+}
+
+type PodTemplateSpec struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     PodSpec    `json:"spec"`
+}
+
+type PodSpec struct {
+	Containers []*Container `json:"containers"`
+}
+
+type Container struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+}
+
+type Service struct {
+	APIVersion string      `json:"apiVersion,omitempty"`
+	Kind       string      `json:"kind,omitempty"`
+	Metadata   ObjectMeta  `json:"metadata"`
+	Spec       ServiceSpec `json:"spec"`
+}
+
+type ServiceSpec struct {
+	Type      string            `json:"type,omitempty"`
+	ClusterIP string            `json:"clusterIP,omitempty"`
+	Ports     []*ServicePort    `json:"ports,omitempty"`
+	Selector  map[string]string `json:"selector,omitempty"`
+}
+
+type ServicePort struct {
+	Name     string `json:"name,omitempty"`
+	Protocol string `json:"protocol,omitempty"`
+	Port     int    `json:"port"`
+	// port number or name of the container port
+	TargetPort interface{} `json:"targetPort,omitempty"`
+	NodePort   int         `json:"nodePort,omitempty"`
+}
+
+type ServiceList struct {
+	Metadata ListMeta   `json:"metadata"`
+	Items    []*Service `json:"items"`
+}
+
+// Scale is the scale subresource of a Deployment
+type Scale struct {
+	APIVersion string      `json:"apiVersion,omitempty"`
+	Kind       string      `json:"kind,omitempty"`
+	Metadata   ObjectMeta  `json:"metadata"`
+	Spec       ScaleSpec   `json:"spec"`
+	Status     ScaleStatus `json:"status"`
+}
+
+type ScaleSpec struct {
+	Replicas int `json:"replicas"`
+}
+
+type ScaleStatus struct {
+	Replicas int `json:"replicas"`
+}
+
+type DeleteOptions struct {
+	APIVersion        string `json:"apiVersion"`
+	Kind              string `json:"kind"`
+	PropagationPolicy string `json:"propagationPolicy,omitempty"`
+}
+
+// AppliedResource is the result of applying a document
+type AppliedResource struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// created or configured
+	Action string `json:"action"`
+}
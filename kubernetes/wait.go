@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+var logWait = logger.GetLogger("depcon.deploy.wait")
+
+// interval between checks of the rollout status
+var waitInterval = time.Duration(2) * time.Second
+
+func (c *KubernetesClient) WaitForDeployment(name string, timeout time.Duration) error {
+	t_now := time.Now()
+	t_stop := t_now.Add(timeout)
+
+	for {
+		if time.Now().After(t_stop) {
+			c.clearWaitStatus()
+			return ErrorTimeout
+		}
+		d, err := c.GetDeployment(name)
+		if err != nil {
+			c.clearWaitStatus()
+			return err
+		}
+		if d.IsReady() {
+			c.clearWaitStatus()
+			logWait.Info("Deployment '%s' has rolled out, elapsed time %s", name, utils.ElapsedStr(time.Since(t_now)))
+			return nil
+		}
+		desired := d.DesiredReplicas()
+		if !c.reportWaitStatus("Waiting for deployment "+name+" to become available", d.Status.AvailableReplicas, desired) {
+			logWait.Info("%v of %v replicas of '%s' are available (%v updated).  Retrying check in %v", d.Status.AvailableReplicas, desired, name, d.Status.UpdatedReplicas, waitInterval)
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// DesiredReplicas returns the replicas requested by the spec which default to 1 when unspecified
+func (d *Deployment) DesiredReplicas() int {
+	if d.Spec.Replicas == nil {
+		return 1
+	}
+	return *d.Spec.Replicas
+}
+
+// IsReady determines if the latest revision has been observed and every desired replica is updated
+// and available with no replicas of older revisions remaining
+func (d *Deployment) IsReady() bool {
+	desired := d.DesiredReplicas()
+	return d.Status.ObservedGeneration >= d.Metadata.Generation &&
+		d.Status.UpdatedReplicas == desired &&
+		d.Status.AvailableReplicas == desired &&
+		d.Status.Replicas == desired
+}
+
+func (c *KubernetesClient) reportWaitStatus(message string, done, total int) bool {
+	return c.opts != nil && progress.Report(c.opts.Progress, message, done, total)
+}
+
+func (c *KubernetesClient) clearWaitStatus() {
+	if c.opts != nil {
+		progress.Clear(c.opts.Progress)
+	}
+}
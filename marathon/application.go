@@ -1,14 +1,20 @@
 package marathon
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/ContainX/depcon/pkg/encoding"
 	"github.com/ContainX/depcon/pkg/envsubst"
 	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/ContainX/depcon/pkg/sops"
 	"github.com/ContainX/depcon/utils"
 	"io"
-	"os"
 	"strings"
 	"time"
 )
@@ -17,6 +23,7 @@ const (
 	ActionRestart  = "restart"
 	ActionVersions = "versions"
 	PathTasks      = "tasks"
+	PathDelay      = "delay"
 )
 
 var (
@@ -29,22 +36,41 @@ var (
 )
 
 func (c *MarathonClient) CreateApplicationFromFile(filename string, opts *CreateOptions) (*Application, error) {
+	return c.CreateApplicationFromFileCtx(c.context(), filename, opts)
+}
+
+func (c *MarathonClient) CreateApplicationFromFileCtx(ctx context.Context, filename string, opts *CreateOptions) (*Application, error) {
 	app, err := c.ParseApplicationFromFile(filename, opts)
 	if err != nil {
 		return app, err
 	}
 
+	if opts.Prepare != nil {
+		if err := opts.Prepare(app); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Validate != nil {
+		if err := opts.Validate(app); err != nil {
+			return nil, err
+		}
+	}
+
 	if opts.StopDeploy {
-		if deployment, err := c.CancelAppDeployment(app.ID, false); err == nil && deployment != nil {
+		if deployment, err := c.CancelAppDeploymentCtx(ctx, app.ID, false); err == nil && deployment != nil {
 			log.Info("Previous deployment found..  cancelling and waiting until complete.")
-			c.WaitForDeployment(deployment.DeploymentID, time.Second*30)
+			c.WaitForDeploymentCtx(ctx, deployment.DeploymentID, time.Second*30)
 		}
 	}
 
-	return c.CreateApplication(app, opts.Wait, opts.Force)
+	return c.createApplicationWithOpts(ctx, app, opts)
 }
 
 func (c *MarathonClient) CreateApplicationFromString(filename string, appstr string, opts *CreateOptions) (*Application, error) {
+	return c.CreateApplicationFromStringCtx(c.context(), filename, appstr, opts)
+}
+
+func (c *MarathonClient) CreateApplicationFromStringCtx(ctx context.Context, filename string, appstr string, opts *CreateOptions) (*Application, error) {
 	et, err := encoding.EncoderTypeFromExt(filename)
 	if err != nil {
 		return nil, err
@@ -55,30 +81,61 @@ func (c *MarathonClient) CreateApplicationFromString(filename string, appstr str
 		return app, err
 	}
 
+	if opts.Prepare != nil {
+		if err := opts.Prepare(app); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Validate != nil {
+		if err := opts.Validate(app); err != nil {
+			return nil, err
+		}
+	}
+
 	if opts.StopDeploy {
-		if deployment, err := c.CancelAppDeployment(app.ID, false); err == nil && deployment != nil {
+		if deployment, err := c.CancelAppDeploymentCtx(ctx, app.ID, false); err == nil && deployment != nil {
 			log.Info("Previous deployment found..  cancelling and waiting until complete.")
-			c.WaitForDeployment(deployment.DeploymentID, time.Second*30)
+			c.WaitForDeploymentCtx(ctx, deployment.DeploymentID, time.Second*30)
 		}
 	}
 
-	return c.CreateApplication(app, opts.Wait, opts.Force)
+	return c.createApplicationWithOpts(ctx, app, opts)
+}
 
+// Creates {app} waiting for the deployment or the healthy instances given by {opts}
+func (c *MarathonClient) createApplicationWithOpts(ctx context.Context, app *Application, opts *CreateOptions) (*Application, error) {
+	if opts.WaitHealthy == nil {
+		return c.CreateApplicationCtx(ctx, app, opts.Wait, opts.Force)
+	}
+	result, err := c.CreateApplicationCtx(ctx, app, false, opts.Force)
+	if err != nil {
+		return result, err
+	}
+	if err := c.WaitForHealthyTasksCtx(ctx, result.ID, *opts.WaitHealthy, c.determineTimeout(app)); err != nil {
+		return result, err
+	}
+	return c.GetApplicationCtx(ctx, result.ID)
 }
 
 func (c *MarathonClient) ParseApplicationFromFile(filename string, opts *CreateOptions) (*Application, error) {
 	log.Info("Creating Application from file: %s", filename)
 
-	file, err := os.Open(filename)
+	data, err := sops.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("Error opening filename %s, %s", filename, err.Error())
 	}
+	file := bytes.NewReader(data)
 
-	if et, err := encoding.EncoderTypeFromExt(filename); err != nil {
+	et, err := encoding.EncoderTypeFromExt(filename)
+	if err != nil {
 		return nil, err
-	} else {
-		return c.ParseApplicationFromString(file, et, opts)
 	}
+
+	result, err := c.ParseApplicationFromString(file, et, opts)
+	if perr, ok := err.(*envsubst.MissingParamsError); ok {
+		perr.Filename = filename
+	}
+	return result, err
 }
 
 func (c *MarathonClient) ParseApplicationFromString(r io.Reader, et encoding.EncoderType, opts *CreateOptions) (*Application, error) {
@@ -93,15 +150,15 @@ func (c *MarathonClient) ParseApplicationFromString(r io.Reader, et encoding.Enc
 		return nil, err
 	}
 
-	parsed, missing := envsubst.SubstFileTokens(r, options.EnvParams)
+	parsed, missing := envsubst.SubstTokens(r, options.EnvParams)
 
-	if opts.ErrorOnMissingParams && missing {
-		return nil, ErrorAppParamsMissing
+	if options.ErrorOnMissingParams && len(missing) > 0 {
+		return nil, &envsubst.MissingParamsError{Params: missing}
 	}
 
-	if opts.DryRun {
-		fmt.Printf("Create Application :: DryRun :: Template Output\n\n%s", parsed)
-		os.Exit(0)
+	if options.DryRun {
+		fmt.Printf("Create Application :: DryRun :: Template Output\n\n%s", mask.Text(parsed))
+		cli.ExitWithCode(cli.ExitSuccess)
 	}
 
 	app := new(Application)
@@ -113,32 +170,36 @@ func (c *MarathonClient) ParseApplicationFromString(r io.Reader, et encoding.Enc
 }
 
 func (c *MarathonClient) CreateApplication(app *Application, wait, force bool) (*Application, error) {
-	log.Info("Creating Application '%s', wait: %v, force: %v", app.ID, wait, force)
+	return c.CreateApplicationCtx(c.context(), app, wait, force)
+}
+
+func (c *MarathonClient) CreateApplicationCtx(ctx context.Context, app *Application, wait, force bool) (*Application, error) {
+	logger.With(log, logger.Fields{logger.FieldApp: app.ID}).Info("Creating Application '%s', wait: %v, force: %v", app.ID, wait, force)
+	if err := app.Validate(); err != nil {
+		return nil, err
+	}
 
 	result := new(Application)
-	resp := c.http.HttpPost(c.marathonUrl(API_APPS), app, result)
+	resp := c.http.HttpPostCtx(ctx, c.marathonUrl(API_APPS), app, result)
 	if resp.Error != nil {
 		if resp.Error == httpclient.ErrorMessage {
 			if resp.Status == 409 {
 				if force {
-					return c.UpdateApplication(app, wait)
+					return c.UpdateApplicationCtx(ctx, app, wait)
 				}
-				return nil, ErrorAppExists
-			}
-			if resp.Status == 422 {
-				return nil, fmt.Errorf("Error occurred: %s", resp.Content)
+				return nil, newMarathonError(resp, CodeConflict, ErrorAppExists)
 			}
-			return nil, fmt.Errorf("Error occurred (Status %v) Body -> %s", resp.Status, resp.Content)
+			return nil, responseError(resp)
 		}
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	if wait {
-		err := c.WaitForApplication(result.ID, c.determineTimeout(app))
+		err := c.WaitForApplicationCtx(ctx, result.ID, c.determineTimeout(app))
 		if err != nil {
 			return result, err
 		}
 	}
-	app, err := c.GetApplication(result.ID)
+	app, err := c.GetApplicationCtx(ctx, result.ID)
 	if err == nil {
 		return app, nil
 	}
@@ -146,43 +207,130 @@ func (c *MarathonClient) CreateApplication(app *Application, wait, force bool) (
 }
 
 func (c *MarathonClient) UpdateApplication(app *Application, wait bool) (*Application, error) {
-	log.Info("Update Application '%s', wait = %v", app.ID, wait)
+	return c.UpdateApplicationCtx(c.context(), app, wait)
+}
+
+func (c *MarathonClient) UpdateApplicationCtx(ctx context.Context, app *Application, wait bool) (*Application, error) {
+	logger.With(log, logger.Fields{logger.FieldApp: app.ID}).Info("Update Application '%s', wait = %v", app.ID, wait)
+	if err := app.Validate(); err != nil {
+		return nil, err
+	}
 	result := new(DeploymentID)
 	id := utils.TrimRootPath(app.ID)
 	app.ID = ""
-	resp := c.http.HttpPut(c.marathonUrl(API_APPS, id), app, result)
+	resp := c.http.HttpPutCtx(ctx, c.marathonUrl(API_APPS, id), app, result)
 
 	if resp.Error != nil {
 		if resp.Error == httpclient.ErrorMessage {
 			if resp.Status == 422 {
-				return nil, ErrorNoAppExists
+				return nil, newMarathonError(resp, CodeNotFound, ErrorNoAppExists)
 			}
 		}
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	if wait {
-		if err := c.WaitForDeployment(result.DeploymentID, c.determineTimeout(app)); err != nil {
+		if err := c.WaitForDeploymentCtx(ctx, result.DeploymentID, c.determineTimeout(app)); err != nil {
 			return nil, err
 		}
-		err := c.WaitForApplication(id, c.determineTimeout(app))
+		err := c.WaitForApplicationCtx(ctx, id, c.determineTimeout(app))
 		if err != nil {
 			return nil, err
 		}
 	}
 	// Get the latest version of the application to return
-	app, err := c.GetApplication(id)
+	app, err := c.GetApplicationCtx(ctx, id)
 	return app, err
 }
 
 func (c *MarathonClient) ListApplications() (*Applications, error) {
-	return c.ListApplicationsWithFilters("")
+	return c.ListApplicationsCtx(c.context())
+}
+
+func (c *MarathonClient) ListApplicationsCtx(ctx context.Context) (*Applications, error) {
+	return c.ListApplicationsWithFiltersCtx(ctx, "")
 }
 
 func (c *MarathonClient) ListApplicationsWithFilters(filter string) (*Applications, error) {
+	return c.ListApplicationsWithFiltersCtx(c.context(), filter)
+}
+
+func (c *MarathonClient) ListApplicationsWithFiltersCtx(ctx context.Context, filter string) (*Applications, error) {
 	log.Debug("Enter: ListApplications")
 
 	apps := new(Applications)
 
+	resp := c.http.HttpGetCtx(ctx, c.applicationsUrl(filter), apps)
+	if resp.Error != nil {
+		return nil, responseError(resp)
+	}
+	return apps, nil
+}
+
+func (c *MarathonClient) ListApplicationsStream(filter string, fn func(app *Application) error) error {
+	return c.ListApplicationsStreamCtx(c.context(), filter, fn)
+}
+
+func (c *MarathonClient) ListApplicationsStreamCtx(ctx context.Context, filter string, fn func(app *Application) error) error {
+	log.Debug("Enter: ListApplicationsStream")
+
+	resp := c.http.HttpGetStreamCtx(ctx, c.applicationsUrl(filter), func(body io.Reader) error {
+		return encoding.DecodeArray(body, "apps", func(dec *json.Decoder) error {
+			app := new(Application)
+			if err := dec.Decode(app); err != nil {
+				return err
+			}
+			return fn(app)
+		})
+	})
+	return responseError(resp)
+}
+
+func (c *MarathonClient) ListApplicationsByGroup(groupID string, fn func(app *Application) error) error {
+	return c.ListApplicationsByGroupCtx(c.context(), groupID, fn)
+}
+
+// ListApplicationsByGroupCtx lists the applications under {groupID} (the root group when empty) one
+// group at a time.  The group tree is read without applications and each group's own applications are
+// then streamed from a separate request so the response of a single group is the most held in memory
+func (c *MarathonClient) ListApplicationsByGroupCtx(ctx context.Context, groupID string, fn func(app *Application) error) error {
+	log.Debug("Enter: ListApplicationsByGroup: %s", groupID)
+
+	tree := new(Group)
+	resp := c.http.HttpGetCtx(ctx, c.groupEmbedUrl(groupID, "group.groups"), tree)
+	if resp.Error != nil {
+		return responseError(resp)
+	}
+	return c.streamGroupApplications(ctx, tree, fn)
+}
+
+// Streams the applications of {group} followed by those of its nested groups
+func (c *MarathonClient) streamGroupApplications(ctx context.Context, group *Group, fn func(app *Application) error) error {
+	resp := c.http.HttpGetStreamCtx(ctx, c.groupEmbedUrl(group.GroupID, "group.apps"), func(body io.Reader) error {
+		return encoding.DecodeArray(body, "apps", func(dec *json.Decoder) error {
+			app := new(Application)
+			if err := dec.Decode(app); err != nil {
+				return err
+			}
+			return fn(app)
+		})
+	})
+	if err := responseError(resp); err != nil {
+		return err
+	}
+	for _, g := range group.Groups {
+		if err := c.streamGroupApplications(ctx, g, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the URL of group {id} embedding only {embed} (eg. group.apps) rather than the entire tree
+func (c *MarathonClient) groupEmbedUrl(id, embed string) string {
+	return fmt.Sprintf("%s?embed=%s", c.marathonUrl(API_GROUPS, utils.TrimRootPath(id)), embed)
+}
+
+func (c *MarathonClient) applicationsUrl(filter string) string {
 	url := c.marathonUrl(API_APPS)
 	if len(filter) > 0 {
 		if strings.Contains(filter, "=") == false {
@@ -190,28 +338,47 @@ func (c *MarathonClient) ListApplicationsWithFilters(filter string) (*Applicatio
 		}
 		url = fmt.Sprintf("%s?%s", url, filter)
 	}
-	resp := c.http.HttpGet(url, apps)
-	if resp.Error != nil {
-		return nil, resp.Error
-	}
-	return apps, nil
+	return url
 }
 
 func (c *MarathonClient) GetApplication(id string) (*Application, error) {
+	return c.GetApplicationCtx(c.context(), id)
+}
+
+func (c *MarathonClient) GetApplicationCtx(ctx context.Context, id string) (*Application, error) {
 	log.Debug("Enter: GetApplication: %s", id)
 	app := new(AppById)
-	resp := c.http.HttpGet(c.marathonUrl(API_APPS, id), app)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_APPS, id), app)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return &app.App, nil
 }
 
+func (c *MarathonClient) GetApplicationDefinition(id string) (map[string]interface{}, error) {
+	return c.GetApplicationDefinitionCtx(c.context(), id)
+}
+
+func (c *MarathonClient) GetApplicationDefinitionCtx(ctx context.Context, id string) (map[string]interface{}, error) {
+	live := struct {
+		App map[string]interface{} `json:"app"`
+	}{}
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_APPS, id), &live)
+	if resp.Error != nil {
+		return nil, responseError(resp)
+	}
+	return live.App, nil
+}
+
 func (c *MarathonClient) HasApplication(id string) (bool, error) {
-	app, err := c.GetApplication(id)
+	return c.HasApplicationCtx(c.context(), id)
+}
+
+func (c *MarathonClient) HasApplicationCtx(ctx context.Context, id string) (bool, error) {
+	app, err := c.GetApplicationCtx(ctx, id)
 
 	if err != nil {
-		if err == httpclient.ErrorNotFound {
+		if errors.Is(err, httpclient.ErrorNotFound) {
 			return false, nil
 		}
 		return false, err
@@ -220,48 +387,64 @@ func (c *MarathonClient) HasApplication(id string) (bool, error) {
 }
 
 func (c *MarathonClient) DestroyApplication(id string) (*DeploymentID, error) {
-	log.Info("Deleting Application '%s'", id)
+	return c.DestroyApplicationCtx(c.context(), id)
+}
+
+func (c *MarathonClient) DestroyApplicationCtx(ctx context.Context, id string) (*DeploymentID, error) {
+	logger.With(log, logger.Fields{logger.FieldApp: id}).Info("Deleting Application '%s'", id)
 	deploymentId := new(DeploymentID)
 
-	resp := c.http.HttpDelete(c.marathonUrl(API_APPS, id), nil, deploymentId)
+	resp := c.http.HttpDeleteCtx(ctx, c.marathonUrl(API_APPS, id), nil, deploymentId)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return deploymentId, nil
 }
 
 func (c *MarathonClient) RestartApplication(id string, force bool) (*DeploymentID, error) {
-	log.Info("Restarting Application '%s', force: %v", id, force)
+	return c.RestartApplicationCtx(c.context(), id, force)
+}
+
+func (c *MarathonClient) RestartApplicationCtx(ctx context.Context, id string, force bool) (*DeploymentID, error) {
+	logger.With(log, logger.Fields{logger.FieldApp: id}).Info("Restarting Application '%s', force: %v", id, force)
 
 	deploymentId := new(DeploymentID)
 
 	uri := fmt.Sprintf("%s?force=%v", c.marathonUrl(API_APPS, id, ActionRestart), force)
-	resp := c.http.HttpPost(uri, nil, deploymentId)
+	resp := c.http.HttpPostCtx(ctx, uri, nil, deploymentId)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return deploymentId, nil
 }
 
 func (c *MarathonClient) ScaleApplication(id string, instances int) (*DeploymentID, error) {
-	log.Info("Scale Application '%s' to %v instances", id, instances)
+	return c.ScaleApplicationCtx(c.context(), id, instances)
+}
+
+func (c *MarathonClient) ScaleApplicationCtx(ctx context.Context, id string, instances int) (*DeploymentID, error) {
+	logger.With(log, logger.Fields{logger.FieldApp: id}).Info("Scale Application '%s' to %v instances", id, instances)
 
 	update := new(Application)
 	update.ID = id
 	update.Instances = instances
 	deploymentID := new(DeploymentID)
-	resp := c.http.HttpPut(c.marathonUrl(API_APPS, id), &update, deploymentID)
+	resp := c.http.HttpPutCtx(ctx, c.marathonUrl(API_APPS, id), &update, deploymentID)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return deploymentID, nil
 }
 
 func (c *MarathonClient) ListVersions(id string) (*Versions, error) {
+	return c.ListVersionsCtx(c.context(), id)
+}
+
+func (c *MarathonClient) ListVersionsCtx(ctx context.Context, id string) (*Versions, error) {
 	versions := new(Versions)
-	resp := c.http.HttpGet(c.marathonUrl(API_APPS, id, ActionVersions), versions)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_APPS, id, ActionVersions), versions)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return versions, nil
 
@@ -301,8 +484,208 @@ func (app *Application) RollbackVersion(version string) *Application {
 	return app
 }
 
+// The command run by each instance
+// {cmd} - shell command
+func (app *Application) Command(cmd string) *Application {
+	app.Cmd = cmd
+	return app
+}
+
+// The arguments run by each instance in place of a shell command
+// {args} - the arguments.  The first is the executable unless the docker image has an entrypoint
+func (app *Application) Arguments(args ...string) *Application {
+	app.Args = append(app.Args, args...)
+	return app
+}
+
+// Sets an environment variable of each instance
+// {name}  - the variable name
+// {value} - the value of the variable
+func (app *Application) EnvVar(name, value string) *Application {
+	if app.Env == nil {
+		app.Env = map[string]string{}
+	}
+	app.Env[name] = value
+	return app
+}
+
+// Adds a label to the application
+// {name}  - the label name (eg. HAPROXY_GROUP)
+// {value} - the value of the label
+func (app *Application) Label(name, value string) *Application {
+	if app.Labels == nil {
+		app.Labels = map[string]string{}
+	}
+	app.Labels[name] = value
+	return app
+}
+
+// Adds a placement constraint
+// {field}    - the agent field or attribute (eg. hostname)
+// {operator} - the constraint operator (eg. UNIQUE, CLUSTER, GROUP_BY, LIKE, UNLIKE, MAX_PER)
+// {value}    - optional value of the operator
+func (app *Application) Constraint(field, operator string, value ...string) *Application {
+	app.Constraints = append(app.Constraints, append([]string{field, operator}, value...))
+	return app
+}
+
+// Runs the application as the docker {image}
+// {image} - the docker image (eg. nginx:1.25)
+func (app *Application) DockerImage(image string) *Application {
+	app.docker().Image = image
+	return app
+}
+
+// Sets the docker network mode
+// {network} - the network mode (BRIDGE, HOST, USER or NONE)
+func (app *Application) DockerNetwork(network string) *Application {
+	app.docker().Network = network
+	return app
+}
+
+// Adds a parameter passed to docker run
+// {key}   - the parameter name without dashes (eg. log-driver)
+// {value} - the value of the parameter
+func (app *Application) DockerParameter(key, value string) *Application {
+	d := app.docker()
+	d.Parameters = append(d.Parameters, &Parameters{Key: key, Value: value})
+	return app
+}
+
+// Always pulls the docker image before starting an instance
+func (app *Application) ForcePullImage() *Application {
+	app.docker().ForcePullImage = true
+	return app
+}
+
+// Runs the docker container in privileged mode
+func (app *Application) Privileged() *Application {
+	app.docker().Privileged = true
+	return app
+}
+
+// Maps a port of the docker container when using bridge networking
+// {containerPort} - the port within the container
+// {hostPort}      - the port on the agent.  Zero assigns a random port
+// {protocol}      - tcp, udp or udp,tcp.  Default: tcp
+func (app *Application) PortMapping(containerPort, hostPort int, protocol string) *Application {
+	if protocol == "" {
+		protocol = "tcp"
+	}
+	d := app.docker()
+	d.PortMappings = append(d.PortMappings, &PortMapping{ContainerPort: containerPort, HostPort: hostPort, Protocol: protocol})
+	return app
+}
+
+// Mounts a volume within the container
+// {containerPath} - the path within the container
+// {hostPath}      - the path on the agent
+// {mode}          - RO or RW
+func (app *Application) Volume(containerPath, hostPath, mode string) *Application {
+	c := app.container()
+	c.Volumes = append(c.Volumes, &Volume{ContainerPath: containerPath, HostPath: hostPath, Mode: mode})
+	return app
+}
+
+// Adds a health check
+// {check} - the health check (eg. NewHTTPHealthCheck("/health"))
+func (app *Application) HealthCheck(check *HealthCheck) *Application {
+	app.HealthChecks = append(app.HealthChecks, check)
+	return app
+}
+
+// Adds a readiness check which must pass before a deployment continues to the next instance
+// {check} - the readiness check
+func (app *Application) ReadinessCheck(check *ReadinessCheck) *Application {
+	app.ReadinessChecks = append(app.ReadinessChecks, check)
+	return app
+}
+
+// Adds a URI fetched into the sandbox of each instance before it starts.  Archives are extracted
+// {uri} - the URI to fetch
+func (app *Application) FetchURI(uri string) *Application {
+	return app.AddFetch(Fetch{URI: uri, Extract: true})
+}
+
+// Adds a URI fetched into the sandbox of each instance before it starts
+// {fetch} - the URI and whether it's extracted, executable or cached
+func (app *Application) AddFetch(fetch Fetch) *Application {
+	app.Fetch = append(app.Fetch, fetch)
+	return app
+}
+
+// Adds a secret from the cluster's secret store.  Mount it with SecretVolume
+// {name}   - the name referenced by the application (eg. db-password)
+// {source} - the path of the secret in the secret store
+func (app *Application) Secret(name, source string) *Application {
+	if app.Secrets == nil {
+		app.Secrets = map[string]*Secret{}
+	}
+	app.Secrets[name] = &Secret{Source: source}
+	return app
+}
+
+// Mounts the secret {name} as a file within the container
+// {containerPath} - the path of the file within the container
+// {name}          - the name of a secret added with Secret
+func (app *Application) SecretVolume(containerPath, name string) *Application {
+	c := app.container()
+	c.Volumes = append(c.Volumes, &Volume{ContainerPath: containerPath, Secret: name})
+	return app
+}
+
+// Sets how instances are replaced during a deployment
+// {minimumHealthCapacity} - fraction (0 - 1) of instances which must remain healthy
+// {maximumOverCapacity}   - fraction (0 - 1) of additional instances which may be started
+func (app *Application) Upgrade(minimumHealthCapacity, maximumOverCapacity float64) *Application {
+	app.UpgradeStrategy = &UpgradeStrategy{MinimumHealthCapacity: minimumHealthCapacity, MaximumOverCapacity: maximumOverCapacity}
+	return app
+}
+
+// Returns the container of the application creating a docker container when there is none
+func (app *Application) container() *Container {
+	if app.Container == nil {
+		app.Container = &Container{Type: "DOCKER"}
+	}
+	return app.Container
+}
+
+func (app *Application) docker() *Docker {
+	c := app.container()
+	if c.Docker == nil {
+		c.Docker = &Docker{}
+	}
+	return c.Docker
+}
+
+// NewHTTPHealthCheck returns a health check requesting {path} on the first port of each instance using
+// Marathon's defaults
+func NewHTTPHealthCheck(path string) *HealthCheck {
+	return &HealthCheck{
+		Protocol:               "HTTP",
+		Path:                   path,
+		GracePeriodSeconds:     300,
+		IntervalSeconds:        60,
+		TimeoutSeconds:         20,
+		MaxConsecutiveFailures: 3,
+	}
+}
+
+// NewTCPHealthCheck returns a health check connecting to the port at {portIndex} of each instance using
+// Marathon's defaults
+func NewTCPHealthCheck(portIndex int) *HealthCheck {
+	return &HealthCheck{
+		Protocol:               "TCP",
+		PortIndex:              portIndex,
+		GracePeriodSeconds:     300,
+		IntervalSeconds:        60,
+		TimeoutSeconds:         20,
+		MaxConsecutiveFailures: 3,
+	}
+}
+
 func (c *MarathonClient) determineTimeout(app *Application) time.Duration {
-	if c.opts != nil && c.opts.WaitTimeout > 0 {
+	if c.opts != nil && c.opts.WaitTimeout != 0 {
 		return c.opts.WaitTimeout
 	}
 
@@ -1,9 +1,14 @@
 package marathon
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
 	"github.com/ContainX/depcon/pkg/mockrest"
 	"github.com/stretchr/testify/assert"
-	"testing"
 )
 
 const (
@@ -43,6 +48,21 @@ func TestListApplications(t *testing.T) {
 	assert.Equal(t, "/myapp", apps.Apps[0].ID)
 }
 
+func TestListApplicationsStream(t *testing.T) {
+	s := mockrest.StartNewWithFile(AppsFolder + "list_apps_response.json")
+	defer s.Stop()
+
+	c := NewMarathonClient(s.URL, "", "")
+	ids := []string{}
+	err := c.ListApplicationsStream("", func(app *Application) error {
+		ids = append(ids, app.ID)
+		return nil
+	})
+
+	assert.Nil(t, err, "Error response was not expected")
+	assert.Equal(t, "/myapp", ids[0])
+}
+
 func TestGetApplication(t *testing.T) {
 	s := mockrest.StartNewWithFile(AppsFolder + "get_app_response.json")
 	defer s.Stop()
@@ -122,3 +142,69 @@ func TestNewApplication(t *testing.T) {
 	app := NewApplication("/some/application")
 	assert.Equal(t, "/some/application", app.ID)
 }
+
+func TestApplicationBuilder(t *testing.T) {
+	app := NewApplication("/web").
+		Count(2).
+		CPU(0.5).
+		Memory(256).
+		DockerImage("nginx:1.25").
+		DockerNetwork("BRIDGE").
+		PortMapping(80, 0, "").
+		EnvVar("MODE", "production").
+		Label("HAPROXY_GROUP", "external").
+		Constraint("hostname", "UNIQUE").
+		Constraint("rack", "GROUP_BY", "2").
+		HealthCheck(NewHTTPHealthCheck("/health")).
+		FetchURI("https://example.com/config.tgz").
+		Secret("db-password", "/prod/db/password").
+		SecretVolume("/run/secrets/db", "db-password").
+		Upgrade(1, 0.5)
+
+	assert.Equal(t, "DOCKER", app.Container.Type)
+	assert.Equal(t, "nginx:1.25", app.Container.Docker.Image)
+	assert.Equal(t, "tcp", app.Container.Docker.PortMappings[0].Protocol)
+	assert.Equal(t, "production", app.Env["MODE"])
+	assert.Equal(t, "external", app.Labels["HAPROXY_GROUP"])
+	assert.Equal(t, [][]string{{"hostname", "UNIQUE"}, {"rack", "GROUP_BY", "2"}}, app.Constraints)
+	assert.Equal(t, "/health", app.HealthChecks[0].Path)
+	assert.True(t, app.Fetch[0].Extract)
+	assert.Equal(t, "/prod/db/password", app.Secrets["db-password"].Source)
+	assert.Equal(t, "db-password", app.Container.Volumes[0].Secret)
+	assert.Equal(t, 0.5, app.UpgradeStrategy.MaximumOverCapacity)
+}
+
+func TestListApplicationsByGroup(t *testing.T) {
+	requests := []string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, path.Clean(r.URL.Path)+"?"+r.URL.RawQuery)
+		switch path.Clean(r.URL.Path) + "?" + r.URL.RawQuery {
+		case "/v2/groups?embed=group.groups":
+			fmt.Fprint(w, `{"id": "/", "groups": [{"id": "/web", "groups": [{"id": "/web/api", "groups": []}]}, {"id": "/db", "groups": []}]}`)
+		case "/v2/groups?embed=group.apps":
+			fmt.Fprint(w, `{"id": "/", "apps": []}`)
+		case "/v2/groups/web?embed=group.apps":
+			fmt.Fprint(w, `{"id": "/web", "apps": [{"id": "/web/nginx"}]}`)
+		case "/v2/groups/web/api?embed=group.apps":
+			fmt.Fprint(w, `{"id": "/web/api", "apps": [{"id": "/web/api/v1"}, {"id": "/web/api/v2"}]}`)
+		case "/v2/groups/db?embed=group.apps":
+			fmt.Fprint(w, `{"id": "/db", "apps": [{"id": "/db/pg"}]}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	ids := []string{}
+	err := c.ListApplicationsByGroup("", func(app *Application) error {
+		ids = append(ids, app.ID)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/web/nginx", "/web/api/v1", "/web/api/v2", "/db/pg"}, ids)
+	assert.Len(t, requests, 5)
+
+	err = c.ListApplicationsByGroup("/missing", func(app *Application) error { return nil })
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(err))
+}
@@ -0,0 +1,76 @@
+package marathon
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"path"
+	"strings"
+)
+
+// UploadArtifact stores {content} in the artifact store of Marathon at {artifactPath} (eg.
+// /credentials/docker.tar.gz) replacing any artifact already stored there.  Marathon must be started
+// with --artifact_store
+func (c *MarathonClient) UploadArtifact(artifactPath string, content io.Reader) error {
+	return c.UploadArtifactCtx(c.context(), artifactPath, content)
+}
+
+func (c *MarathonClient) UploadArtifactCtx(ctx context.Context, artifactPath string, content io.Reader) error {
+	log.Info("Uploading artifact '%s'", artifactPath)
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	part, err := w.CreateFormFile("file", path.Base(artifactPath))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	headers := map[string]string{"Content-Type": w.FormDataContentType()}
+	resp := c.http.HttpPutRawCtx(ctx, c.artifactUrl(artifactPath), headers, body.String(), nil)
+	if resp.Error != nil {
+		return responseError(resp)
+	}
+	return nil
+}
+
+// FetchArtifact copies the artifact stored at {artifactPath} to {w}
+func (c *MarathonClient) FetchArtifact(artifactPath string, w io.Writer) error {
+	return c.FetchArtifactCtx(c.context(), artifactPath, w)
+}
+
+func (c *MarathonClient) FetchArtifactCtx(ctx context.Context, artifactPath string, w io.Writer) error {
+	headers := map[string]string{"Accept": "*/*"}
+	resp := c.http.HttpGetStreamWithHeadersCtx(ctx, c.artifactUrl(artifactPath), headers, func(body io.Reader) error {
+		_, err := io.Copy(w, body)
+		return err
+	})
+	if resp.Error != nil {
+		return responseError(resp)
+	}
+	return nil
+}
+
+func (c *MarathonClient) DeleteArtifact(artifactPath string) error {
+	return c.DeleteArtifactCtx(c.context(), artifactPath)
+}
+
+func (c *MarathonClient) DeleteArtifactCtx(ctx context.Context, artifactPath string) error {
+	log.Info("Deleting artifact '%s'", artifactPath)
+
+	resp := c.http.HttpDeleteCtx(ctx, c.artifactUrl(artifactPath), nil, nil)
+	if resp.Error != nil {
+		return responseError(resp)
+	}
+	return nil
+}
+
+func (c *MarathonClient) artifactUrl(artifactPath string) string {
+	return c.marathonUrl(API_ARTIFACTS, strings.TrimPrefix(path.Clean("/"+artifactPath), "/"))
+}
@@ -0,0 +1,54 @@
+package marathon
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtifacts(t *testing.T) {
+	stored := map[string]string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := strings.TrimPrefix(path.Clean(r.URL.Path), "/v2/artifacts")
+		switch r.Method {
+		case "PUT":
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			b, _ := ioutil.ReadAll(file)
+			stored[p] = header.Filename + ":" + string(b)
+			w.WriteHeader(http.StatusCreated)
+		case "GET":
+			content, ok := stored[p]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte(content))
+		case "DELETE":
+			delete(stored, p)
+		}
+	}))
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	assert.Nil(t, c.UploadArtifact("/credentials/docker.tar.gz", strings.NewReader("bundle")))
+	assert.Equal(t, "docker.tar.gz:bundle", stored["/credentials/docker.tar.gz"])
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, c.FetchArtifact("credentials/docker.tar.gz", buf))
+	assert.Equal(t, "docker.tar.gz:bundle", buf.String())
+
+	assert.Nil(t, c.DeleteArtifact("/credentials/docker.tar.gz"))
+	err := c.FetchArtifact("/credentials/docker.tar.gz", buf)
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(err))
+}
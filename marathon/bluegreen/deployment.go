@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/ContainX/depcon/marathon"
 	"github.com/ContainX/depcon/pkg/logger"
-	"os"
 	"strconv"
 	"time"
 )
@@ -100,7 +99,9 @@ func (c *BGClient) DeployBlueGreen(app *marathon.Application) (*marathon.Applica
 		return app, nil
 	}
 
-	c.startDeployment(app, state)
+	if _, err := c.startDeployment(app, state); err != nil {
+		return nil, err
+	}
 
 	return c.marathon.GetApplication(app.ID)
 }
@@ -118,20 +119,20 @@ func (c *BGClient) updateServicePort(app *marathon.Application, port int) *marat
 	return app
 }
 
-func (c *BGClient) startDeployment(app *marathon.Application, state *appState) bool {
+func (c *BGClient) startDeployment(app *marathon.Application, state *appState) (bool, error) {
 	log.Debug("startDeployment: resuming: %v", state.resuming)
 	if !state.resuming {
 		a, err := c.marathon.CreateApplication(app, true, false)
 		if err != nil {
 			log.Error("Unable to create application: %s", err.Error())
-			os.Exit(1)
+			return false, err
 		}
 		app = a
 	}
 	if state.existingApp != nil {
-		return c.checkIfTasksDrained(app, state.existingApp, time.Now())
+		return c.checkIfTasksDrained(app, state.existingApp, time.Now()), nil
 	}
-	return false
+	return false, nil
 }
 
 func (c *BGClient) bgAppInfo(deployGroup string, deployGroupAltPort int) (*appState, error) {
@@ -4,11 +4,10 @@ import (
 	"encoding/csv"
 	"fmt"
 	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathonlb"
 	"github.com/ContainX/depcon/utils"
 	"io"
 	"math"
-	"net"
-	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -39,7 +38,7 @@ func (c *BGClient) checkIfTasksDrained(app, existingApp *marathon.Application, s
 	targetInstances, _ := strconv.Atoi(app.Labels[DeployTargetInstances])
 	log.Info("Existing app running %d instance, new app running %d instances", existingApp.Instances, app.Instances)
 
-	hosts, err := proxiesFromURI(c.opts.LoadBalancer)
+	hosts, err := marathonlb.Instances(c.opts.LoadBalancer)
 	if err != nil {
 		log.Error("Error with HAProxy Stats URL: %s", err.Error())
 	}
@@ -250,26 +249,3 @@ func (c *BGClient) refreshAppOrPanic(id string) *marathon.Application {
 	}
 	panic("Failure to refresh application " + id)
 }
-
-func proxiesFromURI(uri string) ([]string, error) {
-	url, err := url.Parse(uri)
-	if err != nil {
-		return nil, err
-	}
-	hp := strings.Split(url.Host, ":")
-	host := hp[0]
-	port := hp[1]
-
-	ips, err := net.LookupIP(host)
-	if err != nil {
-		log.Debug("Lookup IP failed for: %s, error: %s", host, err.Error())
-		return []string{url.String()}, nil
-	}
-
-	results := []string{}
-	for _, ip := range ips {
-		url.Host = ip.String() + ":" + port
-		results = append(results, url.String())
-	}
-	return results, nil
-}
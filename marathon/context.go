@@ -0,0 +1,73 @@
+package marathon
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// MarathonContext is implemented by clients accepting a context for every request.  Each method behaves as
+// the Marathon method of the same name without the Ctx suffix except that requests (including retries) are
+// abandoned and waits return the error of {ctx} once it's cancelled or its deadline passes
+type MarathonContext interface {
+	CreateApplicationFromFileCtx(ctx context.Context, filename string, opts *CreateOptions) (*Application, error)
+	CreateApplicationFromStringCtx(ctx context.Context, filename string, appstr string, opts *CreateOptions) (*Application, error)
+	CreateApplicationCtx(ctx context.Context, app *Application, wait, force bool) (*Application, error)
+	UpdateApplicationCtx(ctx context.Context, app *Application, wait bool) (*Application, error)
+	UpdateApplicationPartialCtx(ctx context.Context, id string, patch map[string]interface{}, opts *PatchOptions) (*Application, error)
+	ListApplicationsCtx(ctx context.Context) (*Applications, error)
+	ListApplicationsWithFiltersCtx(ctx context.Context, filter string) (*Applications, error)
+	ListApplicationsStreamCtx(ctx context.Context, filter string, fn func(app *Application) error) error
+	ListApplicationsByGroupCtx(ctx context.Context, groupID string, fn func(app *Application) error) error
+	GetApplicationCtx(ctx context.Context, id string) (*Application, error)
+	GetApplicationDefinitionCtx(ctx context.Context, id string) (map[string]interface{}, error)
+	HasApplicationCtx(ctx context.Context, id string) (bool, error)
+	DestroyApplicationCtx(ctx context.Context, id string) (*DeploymentID, error)
+	RestartApplicationCtx(ctx context.Context, id string, force bool) (*DeploymentID, error)
+	ScaleApplicationCtx(ctx context.Context, id string, instances int) (*DeploymentID, error)
+	ListVersionsCtx(ctx context.Context, id string) (*Versions, error)
+	WaitForApplicationCtx(ctx context.Context, id string, timeout time.Duration) error
+	WaitForApplicationHealthyCtx(ctx context.Context, id string, timeout time.Duration) error
+	WaitForHealthyTasksCtx(ctx context.Context, id string, quorum Quorum, timeout time.Duration) error
+
+	HasDeploymentCtx(ctx context.Context, id string) (bool, error)
+	ListDeploymentsCtx(ctx context.Context) ([]*Deploy, error)
+	DeleteDeploymentCtx(ctx context.Context, id string, force bool) (*DeploymentID, error)
+	CancelAppDeploymentCtx(ctx context.Context, appId string, matchPrefix bool) (*DeploymentID, error)
+	WaitForDeploymentCtx(ctx context.Context, id string, timeout time.Duration) error
+	WaitForDeploymentResultCtx(ctx context.Context, id string, timeout time.Duration) *WaitResult
+
+	CreateGroupFromFileCtx(ctx context.Context, filename string, opts *CreateOptions) (*Group, error)
+	CreateGroupFromStringCtx(ctx context.Context, filename string, grpstr string, opts *CreateOptions) (*Group, error)
+	CreateGroupCtx(ctx context.Context, group *Group, wait, force bool) (*Group, error)
+	ListGroupsCtx(ctx context.Context) (*Groups, error)
+	GetGroupCtx(ctx context.Context, id string) (*Group, error)
+	DestroyGroupCtx(ctx context.Context, id string) (*DeploymentID, error)
+
+	CreatePodCtx(ctx context.Context, pod *Pod, wait, force bool) (*Pod, error)
+	UpdatePodCtx(ctx context.Context, pod *Pod, wait, force bool) (*Pod, error)
+	ListPodsCtx(ctx context.Context) ([]*Pod, error)
+	GetPodCtx(ctx context.Context, id string) (*Pod, error)
+	GetPodStatusCtx(ctx context.Context, id string) (*PodStatus, error)
+	DeletePodCtx(ctx context.Context, id string, force bool) error
+	PodVersionsCtx(ctx context.Context, id string) ([]string, error)
+	WaitForPodCtx(ctx context.Context, id string, timeout time.Duration) error
+
+	UploadArtifactCtx(ctx context.Context, path string, content io.Reader) error
+	FetchArtifactCtx(ctx context.Context, path string, w io.Writer) error
+	DeleteArtifactCtx(ctx context.Context, path string) error
+
+	ListTasksCtx(ctx context.Context) ([]*Task, error)
+	GetTasksCtx(ctx context.Context, id string) ([]*Task, error)
+	KillAppTasksCtx(ctx context.Context, id string, host string, scale bool) ([]*Task, error)
+	KillAppTaskCtx(ctx context.Context, taskId string, scale bool) (*Task, error)
+	KillTasksAndScaleCtx(ctx context.Context, ids ...string) error
+	ListQueueCtx(ctx context.Context) (*Queue, error)
+	ResetDelayCtx(ctx context.Context, id string) error
+
+	PingCtx(ctx context.Context) (*MarathonPing, error)
+	GetMarathonInfoCtx(ctx context.Context) (*MarathonInfo, error)
+	GetCurrentLeaderCtx(ctx context.Context) (*LeaderInfo, error)
+	AbdicateLeaderCtx(ctx context.Context) (*Message, error)
+	AbdicateLeaderWithOptsCtx(ctx context.Context, opts *AbdicateOptions) (*Message, error)
+}
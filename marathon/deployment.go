@@ -1,23 +1,31 @@
 package marathon
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"github.com/ContainX/depcon/pkg/httpclient"
 	"strings"
 )
 
 func (c *MarathonClient) ListDeployments() ([]*Deploy, error) {
+	return c.ListDeploymentsCtx(c.context())
+}
+
+func (c *MarathonClient) ListDeploymentsCtx(ctx context.Context) ([]*Deploy, error) {
 	var deploys []*Deploy
-	resp := c.http.HttpGet(c.marathonUrl(API_DEPLOYMENTS), &deploys)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_DEPLOYMENTS), &deploys)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return deploys, nil
 }
 
 func (c *MarathonClient) HasDeployment(id string) (bool, error) {
-	deployments, err := c.ListDeployments()
+	return c.HasDeploymentCtx(c.context(), id)
+}
+
+func (c *MarathonClient) HasDeploymentCtx(ctx context.Context, id string) (bool, error) {
+	deployments, err := c.ListDeploymentsCtx(ctx)
 	if err != nil {
 		return false, err
 	}
@@ -30,25 +38,33 @@ func (c *MarathonClient) HasDeployment(id string) (bool, error) {
 }
 
 func (c *MarathonClient) DeleteDeployment(id string, force bool) (*DeploymentID, error) {
+	return c.DeleteDeploymentCtx(c.context(), id, force)
+}
+
+func (c *MarathonClient) DeleteDeploymentCtx(ctx context.Context, id string, force bool) (*DeploymentID, error) {
 	deploymentID := new(DeploymentID)
 	uri := fmt.Sprintf("%s?force=%v", c.marathonUrl(API_DEPLOYMENTS, id), force)
-	resp := c.http.HttpDelete(uri, nil, deploymentID)
+	resp := c.http.HttpDeleteCtx(ctx, uri, nil, deploymentID)
 	if resp.Error != nil {
 		if resp.Error == httpclient.ErrorNotFound {
-			return nil, errors.New(fmt.Sprintf("Deployment '%s' was not found", id))
+			return nil, newMarathonError(resp, CodeNotFound, fmt.Errorf("Deployment '%s' was not found", id))
 		}
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return deploymentID, nil
 }
 
 func (c *MarathonClient) CancelAppDeployment(appId string, matchPrefix bool) (*DeploymentID, error) {
-	if deployments, err := c.ListDeployments(); err == nil {
+	return c.CancelAppDeploymentCtx(c.context(), appId, matchPrefix)
+}
+
+func (c *MarathonClient) CancelAppDeploymentCtx(ctx context.Context, appId string, matchPrefix bool) (*DeploymentID, error) {
+	if deployments, err := c.ListDeploymentsCtx(ctx); err == nil {
 		for _, value := range deployments {
 			for _, id := range value.AffectedApps {
 				if doesIDMatch(appId, id, matchPrefix) {
 					log.Info("Removing matched deployment: %s for app: %s", value.DeployID, id)
-					return c.DeleteDeployment(value.DeployID, true)
+					return c.DeleteDeploymentCtx(ctx, value.DeployID, true)
 				}
 			}
 		}
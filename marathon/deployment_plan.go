@@ -0,0 +1,121 @@
+package marathon
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// Actions performed by deployment steps
+const (
+	DeployStartApplication   = "StartApplication"
+	DeployStopApplication    = "StopApplication"
+	DeployScaleApplication   = "ScaleApplication"
+	DeployRestartApplication = "RestartApplication"
+	DeployResolveArtifacts   = "ResolveArtifacts"
+	DeployStartPod           = "StartPod"
+	DeployStopPod            = "StopPod"
+	DeployScalePod           = "ScalePod"
+	DeployRestartPod         = "RestartPod"
+)
+
+// Steps are objects with actions although Marathon versions before 1.0 send them as an array of actions
+func (s *DeploymentStep) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		return json.Unmarshal(b, &s.Actions)
+	}
+	type plain DeploymentStep
+	return json.Unmarshal(b, (*plain)(s))
+}
+
+// Events of Marathon versions before 1.0 name the action "type"
+func (a *DeploymentAction) UnmarshalJSON(b []byte) error {
+	type plain DeploymentAction
+	aux := struct {
+		*plain
+		Type string `json:"type"`
+	}{plain: (*plain)(a)}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	if a.Action == "" {
+		a.Action = aux.Type
+	}
+	return nil
+}
+
+// Target returns the id of the application or pod the action is performed on
+func (a *DeploymentAction) Target() string {
+	if a.App != "" {
+		return a.App
+	}
+	return a.Pod
+}
+
+// CurrentStepIndex returns the 0-based index within Steps of the step in progress or -1 if the
+// deployment hasn't started a step
+func (d *Deploy) CurrentStepIndex() int {
+	if d.CurrentStep < 1 || d.CurrentStep > len(d.Steps) {
+		return -1
+	}
+	return d.CurrentStep - 1
+}
+
+// ActionsFor returns the actions of every step performed on the application or pod {id}
+func (d *Deploy) ActionsFor(id string) []*DeploymentAction {
+	actions := []*DeploymentAction{}
+	for _, step := range d.Steps {
+		for _, a := range step.Actions {
+			if a.Target() == id {
+				actions = append(actions, a)
+			}
+		}
+	}
+	return actions
+}
+
+// AffectedApps returns the sorted ids of the applications and pods changed by the steps of the plan
+func (p *DeploymentPlan) AffectedApps() []string {
+	return affectedIDs(p.Steps)
+}
+
+// IndexIn returns the 0-based index of the step within the steps of {plan} (eg. for the current step of a
+// deployment event) or -1 if it isn't one of them
+func (s *DeploymentStep) IndexIn(plan *DeploymentPlan) int {
+	if plan == nil {
+		return -1
+	}
+	for i, step := range plan.Steps {
+		if sameActions(step, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+func sameActions(a, b *DeploymentStep) bool {
+	if a == nil || b == nil || len(a.Actions) != len(b.Actions) {
+		return false
+	}
+	for i := range a.Actions {
+		if a.Actions[i].Action != b.Actions[i].Action || a.Actions[i].Target() != b.Actions[i].Target() {
+			return false
+		}
+	}
+	return true
+}
+
+func affectedIDs(steps []*DeploymentStep) []string {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, step := range steps {
+		for _, a := range step.Actions {
+			if id := a.Target(); id != "" && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
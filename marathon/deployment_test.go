@@ -0,0 +1,229 @@
+package marathon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+const deploymentsResponse = `[{"id": "d1", "version": "2026-01-01T00:00:00.000Z", "affectedApps": ["/web", "/api"],
+	"steps": [{"actions": [{"action": "StartApplication", "app": "/api"}]},
+		{"actions": [{"action": "ScaleApplication", "app": "/api"}, {"action": "RestartApplication", "app": "/web"}]}],
+	"currentActions": [{"action": "ScaleApplication", "app": "/api",
+		"readinessCheckResults": [{"name": "ready", "taskId": "api.1", "ready": false}]}],
+	"currentStep": 2, "totalSteps": 2}]`
+
+func TestDeploymentSteps(t *testing.T) {
+	deploys := []*Deploy{}
+	assert.Nil(t, json.Unmarshal([]byte(deploymentsResponse), &deploys))
+
+	d := deploys[0]
+	assert.Equal(t, 1, d.CurrentStepIndex())
+	assert.Equal(t, DeployRestartApplication, d.Steps[d.CurrentStepIndex()].Actions[1].Action)
+	assert.Equal(t, DeployScaleApplication, d.CurrentActions[0].Action)
+	assert.False(t, d.CurrentActions[0].ReadinessCheckResults[0].Ready)
+	assert.Len(t, d.ActionsFor("/api"), 2)
+
+	d.CurrentStep = 0
+	assert.Equal(t, -1, d.CurrentStepIndex())
+}
+
+func TestDeploymentPlanFormats(t *testing.T) {
+	// Marathon before 1.0 sends steps as arrays of actions and names the action type in events
+	event := new(EventDeploymentInfo)
+	data := `{"eventType": "deployment_info", "currentStep": {"actions": [{"type": "StopApplication", "app": "/old"}]},
+		"plan": {"id": "d1", "steps": [[{"action": "StartApplication", "app": "/web"}], [{"action": "StopApplication", "app": "/old"}]]}}`
+	assert.Nil(t, json.Unmarshal([]byte(data), event))
+
+	assert.Equal(t, DeployStopApplication, event.CurrentStep.Actions[0].Action)
+	assert.Equal(t, []string{"/old", "/web"}, event.Plan.AffectedApps())
+	assert.Equal(t, 1, event.CurrentStep.IndexIn(event.Plan))
+}
+
+func TestWaitDeadline(t *testing.T) {
+	start := time.Now()
+	assert.True(t, waitDeadline(start, WaitForever).IsZero())
+	assert.Equal(t, start, waitDeadline(start, 0))
+	assert.Equal(t, start.Add(time.Minute), waitDeadline(start, time.Minute))
+
+	assert.False(t, expired(time.Time{}))
+	assert.True(t, expired(start.Add(-time.Second)))
+	assert.False(t, expired(start.Add(time.Minute)))
+}
+
+func TestWaitForDeploymentForever(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[]`)
+	}))
+	defer s.Close()
+
+	c := NewMarathonClientWithOpts(s.URL, "", "", &MarathonOptions{WaitTimeout: WaitForever})
+	assert.Nil(t, c.WaitForDeployment("d1", WaitForever))
+	assert.Equal(t, WaitForever, c.(*MarathonClient).determineTimeout(&Application{}))
+}
+
+func TestPollBackoff(t *testing.T) {
+	interval := 2 * time.Second
+	assert.Equal(t, 4*time.Second, pollBackoff(interval, interval))
+	assert.Equal(t, maxPollBackoff, pollBackoff(20*time.Second, interval))
+	assert.Equal(t, time.Minute, pollBackoff(time.Minute, time.Minute), "never shorter than the interval")
+}
+
+func TestWaitForDeploymentRetriesErrors(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer s.Close()
+
+	retry := httpclient.DefaultRetryPolicy()
+	retry.MaxAttempts = 1
+	c := NewMarathonClientWithOpts(s.URL, "", "", &MarathonOptions{PollInterval: time.Millisecond, Retry: retry})
+	assert.Nil(t, c.WaitForDeployment("d1", time.Minute))
+	assert.Equal(t, 3, requests)
+}
+
+func TestParseQuorum(t *testing.T) {
+	q, err := ParseQuorum("3")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, q.Of(10))
+	assert.Equal(t, 2, q.Of(2), "never more than the instances")
+
+	q, err = ParseQuorum("75%")
+	assert.Nil(t, err)
+	assert.Equal(t, 8, q.Of(10), "percentages round up")
+	assert.Equal(t, 0, q.Of(0))
+
+	for _, invalid := range []string{"", "0", "-1", "abc", "0%", "150%"} {
+		_, err = ParseQuorum(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestWaitForHealthyTasks(t *testing.T) {
+	alive := `"healthCheckResults": [{"alive": true}]`
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"app": {"id": "/web", "instances": 4, "healthChecks": [{"protocol": "HTTP"}],
+			"versionInfo": {"lastConfigChangeAt": "2026-01-02T00:00:00.000Z"},
+			"tasks": [
+				{"id": "old", "version": "2026-01-01T00:00:00.000Z", "startedAt": "x", %s},
+				{"id": "new1", "version": "2026-01-02T00:00:00.000Z", "startedAt": "x", %s},
+				{"id": "new2", "version": "2026-01-02T00:00:00.000Z", "startedAt": "x", %s},
+				{"id": "unhealthy", "version": "2026-01-02T00:00:00.000Z", "startedAt": "x", "healthCheckResults": [{"alive": false}]},
+				{"id": "staged", "version": "2026-01-02T00:00:00.000Z"}]}}`, alive, alive, alive)
+	}))
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	assert.Nil(t, c.WaitForHealthyTasks("/web", Quorum{Percent: 50}, time.Minute))
+
+	app, err := c.GetApplication("/web")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, currentHealthyTasks(app))
+}
+
+func TestAppProgress(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/apps/web":
+			fmt.Fprint(w, `{"app": {"id": "/web", "instances": 3, "tasksRunning": 1, "tasksHealthy": 1, "healthChecks": [{}]}}`)
+		case "/v2/apps/worker":
+			fmt.Fprint(w, `{"app": {"id": "/worker", "instances": 2, "tasksRunning": 2}}`)
+		case "/v2/queue":
+			fmt.Fprint(w, `{"queue": [{"app": {"id": "/web"}, "count": 2, "delay": {"timeLeftSeconds": 30, "overdue": false}}]}`)
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "").(*MarathonClient)
+	progress, more := c.appProgress(context.Background(), []string{"web", "worker", "missing"})
+	assert.Equal(t, 0, more)
+	assert.Len(t, progress, 2)
+	assert.Equal(t, "/web 1/3 healthy, 1 running (launch delayed 30s)", progress[0].String())
+	assert.Equal(t, "/worker 2/2 running", progress[1].String())
+	assert.Equal(t, 2, progress[1].Ready())
+
+	err := timeoutError(progress, 4)
+	assert.True(t, errors.Is(err, ErrorTimeout))
+	assert.Equal(t, "The operation has timed out\n  /web 1/3 healthy, 1 running (launch delayed 30s)\n  /worker 2/2 running\n  and 4 more application(s)", err.Error())
+	assert.Equal(t, ErrorTimeout, timeoutError(nil, 0))
+}
+
+func TestWaitForDeploymentResult(t *testing.T) {
+	unauthorized := false
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch p := path.Clean(r.URL.Path); {
+		case unauthorized:
+			w.WriteHeader(http.StatusUnauthorized)
+		case p == "/v2/deployments":
+			fmt.Fprint(w, `[{"id": "d1", "affectedApps": ["/web"]}]`)
+		case p == "/v2/apps/web":
+			fmt.Fprint(w, `{"app": {"id": "/web", "instances": 2}}`)
+		case p == "/v2/queue":
+			fmt.Fprint(w, `{"queue": [{"app": {"id": "/web"}, "count": 2, "delay": {"overdue": true},
+				"processedOffersSummary": {"rejectSummaryLastOffers": [
+					{"reason": "InsufficientMemory", "declined": 3, "processed": 3},
+					{"reason": "InsufficientCpus", "declined": 0, "processed": 3}]}}]}`)
+		}
+	}))
+	defer s.Close()
+
+	c := NewMarathonClientWithOpts(s.URL, "", "", &MarathonOptions{PollInterval: time.Millisecond})
+	result := c.WaitForDeploymentResult("d1", 10*time.Millisecond)
+	assert.Equal(t, WaitStuck, result.State)
+	assert.Equal(t, "stuck in queue: /web 0/2 running (waiting for offers: InsufficientMemory declined 3 of 3)", result.Reason())
+	assert.True(t, errors.Is(result.Err(), ErrorTimeout))
+
+	assert.Nil(t, c.WaitForDeploymentResult("d2", time.Minute).Err())
+
+	unauthorized = true
+	result = c.WaitForDeploymentResult("d1", time.Minute)
+	assert.Equal(t, WaitFailed, result.State)
+	assert.Equal(t, result.Cause, result.Err())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = c.(*MarathonClient).WaitForDeploymentResultCtx(ctx, "d1", time.Minute)
+	assert.Equal(t, WaitCancelled, result.State)
+}
+
+func TestWaitForDeploymentCancelOnTimeout(t *testing.T) {
+	cancelled := ""
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch p := path.Clean(r.URL.Path); {
+		case r.Method == http.MethodDelete:
+			cancelled = p + "?" + r.URL.RawQuery
+			fmt.Fprint(w, `{"deploymentId": "d2", "version": "v2"}`)
+		case p == "/v2/deployments":
+			fmt.Fprint(w, `[{"id": "d1", "affectedApps": ["/web"]}]`)
+		case p == "/v2/apps/web":
+			fmt.Fprint(w, `{"app": {"id": "/web", "instances": 1}}`)
+		case p == "/v2/queue":
+			fmt.Fprint(w, `{"queue": []}`)
+		}
+	}))
+	defer s.Close()
+
+	c := NewMarathonClientWithOpts(s.URL, "", "", &MarathonOptions{PollInterval: time.Millisecond, CancelOnTimeout: true, ForceCancel: true})
+	result := c.WaitForDeploymentResult("d1", 10*time.Millisecond)
+	assert.Equal(t, WaitTimedOut, result.State)
+	assert.True(t, result.DeploymentCancelled)
+	assert.Equal(t, "timed out (deployment cancelled)", result.Reason())
+	assert.Equal(t, "/v2/deployments/d1?force=true", cancelled)
+}
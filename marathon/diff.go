@@ -0,0 +1,202 @@
+package marathon
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// FieldChange is a field of an application whose desired value differs from the live value.  A nil
+// Desired is a field which only exists within the live application
+type FieldChange struct {
+	Path    string      `json:"path"`
+	Desired interface{} `json:"desired"`
+	Live    interface{} `json:"live"`
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.LiveValue(), c.DesiredValue())
+}
+
+// Returns the live value formatted for display
+func (c FieldChange) LiveValue() string {
+	return formatValue(c.Live)
+}
+
+// Returns the desired value formatted for display
+func (c FieldChange) DesiredValue() string {
+	return formatValue(c.Desired)
+}
+
+var (
+	// fields reported by Marathon describing the state of an application rather than its definition
+	statusFields = map[string]bool{
+		"id": true, "version": true, "versionInfo": true, "tasks": true, "deployments": true, "tasksRunning": true,
+		"tasksStaged": true, "tasksHealthy": true, "tasksUnHealthy": true, "lastTaskFailure": true,
+	}
+	// fields replaced as a whole by an update so keys missing from the desired value are changes
+	replacedFields = map[string]bool{"env": true, "labels": true}
+)
+
+// DiffApplication returns the fields of {desired} which differ from the {live} application.  Fields which
+// {desired} doesn't define are ignored since Marathon fills them with defaults, except the keys of env and
+// labels which are replaced as a whole
+func DiffApplication(desired, live *Application) []FieldChange {
+	return DiffDefinition(toGeneric(desired), toGeneric(live))
+}
+
+// DiffDefinition compares application definitions held as JSON values (eg. a rendered descriptor and the
+// definition Marathon returns) as DiffApplication does so fields Application doesn't model are compared too
+func DiffDefinition(desired, live map[string]interface{}) []FieldChange {
+	d, l := toGeneric(desired), toGeneric(live)
+	changes := []FieldChange{}
+	for _, key := range sortedKeys(d) {
+		if statusFields[key] {
+			continue
+		}
+		changes = diffValue(key, d[key], l[key], changes)
+		if replacedFields[key] {
+			dm, _ := d[key].(map[string]interface{})
+			lm, _ := l[key].(map[string]interface{})
+			for _, k := range sortedKeys(lm) {
+				if _, ok := dm[k]; !ok {
+					changes = append(changes, FieldChange{Path: key + "." + k, Live: lm[k]})
+				}
+			}
+		}
+	}
+	for _, key := range []string{"env", "labels"} {
+		if _, ok := d[key]; !ok && l[key] != nil {
+			if lm, _ := l[key].(map[string]interface{}); len(lm) > 0 {
+				changes = append(changes, FieldChange{Path: key, Live: l[key]})
+			}
+		}
+	}
+	return changes
+}
+
+func diffValue(path string, desired, live interface{}, changes []FieldChange) []FieldChange {
+	switch d := desired.(type) {
+	case nil:
+		return changes
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return append(changes, FieldChange{Path: path, Desired: desired, Live: live})
+		}
+		for _, k := range sortedKeys(d) {
+			changes = diffValue(path+"."+k, d[k], l[k], changes)
+		}
+		return changes
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return append(changes, FieldChange{Path: path, Desired: desired, Live: live})
+		}
+		for i := range d {
+			changes = diffValue(fmt.Sprintf("%s[%d]", path, i), d[i], l[i], changes)
+		}
+		return changes
+	}
+	if !reflect.DeepEqual(desired, live) {
+		changes = append(changes, FieldChange{Path: path, Desired: desired, Live: live})
+	}
+	return changes
+}
+
+// Returns {v} as the maps, slices and values it encodes to in JSON
+func toGeneric(v interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	if b, err := json.Marshal(v); err == nil {
+		json.Unmarshal(b, &m)
+	}
+	return m
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatValue(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "<none>"
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// DefinitionDiff is a field of an application whose value differs between the applications compared by
+// DiffDefinitions.  Values holds the value of each application in order (nil when it's undefined)
+type DefinitionDiff struct {
+	Path   string        `json:"path"`
+	Values []interface{} `json:"values"`
+}
+
+// FormattedValues returns the values formatted for display
+func (d DefinitionDiff) FormattedValues() []string {
+	values := make([]string, len(d.Values))
+	for i, v := range d.Values {
+		values[i] = formatValue(v)
+	}
+	return values
+}
+
+// DiffDefinitions returns the fields whose values differ between the definitions of {apps} (eg. the same
+// application deployed to several environments).  Objects are compared field by field (eg. env.DB_HOST or
+// container.docker.image) while arrays are compared as a whole.  The fields describing the state of the
+// applications (tasks, versions, ...) are ignored
+func DiffDefinitions(apps ...*Application) []DefinitionDiff {
+	flattened := make([]map[string]interface{}, len(apps))
+	paths := map[string]interface{}{}
+	for i, app := range apps {
+		generic := toGeneric(app)
+		for field := range statusFields {
+			delete(generic, field)
+		}
+		flattened[i] = map[string]interface{}{}
+		flatten("", generic, flattened[i])
+		for path := range flattened[i] {
+			paths[path] = true
+		}
+	}
+
+	diffs := []DefinitionDiff{}
+	for _, path := range sortedKeys(paths) {
+		d := DefinitionDiff{Path: path, Values: make([]interface{}, len(apps))}
+		differs := false
+		for i := range apps {
+			d.Values[i] = flattened[i][path]
+			if !reflect.DeepEqual(d.Values[i], d.Values[0]) {
+				differs = true
+			}
+		}
+		if differs {
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs
+}
+
+// Adds the values of the object {m} to {values} keyed by their path below {prefix}
+func flatten(prefix string, m map[string]interface{}, values map[string]interface{}) {
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok && len(child) > 0 {
+			flatten(path, child, values)
+			continue
+		}
+		values[path] = v
+	}
+}
@@ -0,0 +1,59 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffApplicationIgnoresDefaults(t *testing.T) {
+	desired := &Application{ID: "web", Instances: 2, Mem: 128, Env: map[string]string{"A": "1"}}
+	live := &Application{ID: "/web", Instances: 2, Mem: 128, CPUs: 0.1, Env: map[string]string{"A": "1"}, Version: "v1", TasksRunning: 2}
+
+	assert.Empty(t, DiffApplication(desired, live))
+}
+
+func TestDiffApplication(t *testing.T) {
+	desired := &Application{
+		ID:        "/web",
+		Instances: 3,
+		Env:       map[string]string{"A": "2"},
+		Container: &Container{Docker: &Docker{Image: "web:2"}},
+	}
+	live := &Application{
+		ID:        "/web",
+		Instances: 2,
+		Env:       map[string]string{"A": "1", "B": "1"},
+		Labels:    map[string]string{"team": "x"},
+		Container: &Container{Type: "DOCKER", Docker: &Docker{Image: "web:1"}},
+	}
+
+	changes := DiffApplication(desired, live)
+	paths := []string{}
+	for _, c := range changes {
+		paths = append(paths, c.Path)
+	}
+	assert.Equal(t, []string{"container.docker.image", "env.A", "env.B", "instances", "labels"}, paths)
+	assert.Equal(t, "container.docker.image: web:1 -> web:2", changes[0].String())
+	assert.Equal(t, "env.B: 1 -> <none>", changes[2].String())
+}
+
+func TestDiffDefinitions(t *testing.T) {
+	staging := &Application{ID: "/web", Instances: 1, Mem: 128, Env: map[string]string{"DB_HOST": "db.staging", "A": "1"},
+		Container: &Container{Docker: &Docker{Image: "web:2"}}, Version: "v2"}
+	prod := &Application{ID: "/web", Instances: 3, Mem: 128, Env: map[string]string{"DB_HOST": "db.prod", "A": "1"},
+		Container: &Container{Docker: &Docker{Image: "web:1"}}, Version: "v1"}
+
+	diffs := DiffDefinitions(staging, prod, prod)
+	assert.Equal(t, []DefinitionDiff{
+		{Path: "container.docker.image", Values: []interface{}{"web:2", "web:1", "web:1"}},
+		{Path: "env.DB_HOST", Values: []interface{}{"db.staging", "db.prod", "db.prod"}},
+		{Path: "instances", Values: []interface{}{float64(1), float64(3), float64(3)}},
+	}, diffs)
+	assert.Equal(t, []string{"1", "3", "3"}, diffs[2].FormattedValues())
+
+	prod.Env = nil
+	diffs = DiffDefinitions(staging, prod)
+	assert.Equal(t, []string{"1", "<none>"}, diffs[1].FormattedValues())
+	assert.Empty(t, DiffDefinitions(prod, prod))
+}
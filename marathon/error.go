@@ -2,9 +2,114 @@ package marathon
 
 import (
 	"errors"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
 )
 
 var (
 	ErrorTimeout            = errors.New("The operation has timed out")
 	ErrorDeploymentNotfound = errors.New("Failed to get deployment in allocated time")
+	ErrorDeploymentFailed   = errors.New("The deployment completed but the application did not become healthy")
+)
+
+// ErrorCode classifies why Marathon rejected a request
+type ErrorCode int
+
+const (
+	CodeUnknown ErrorCode = iota
+	// the application, group, deployment or task does not exist
+	CodeNotFound
+	// the resource already exists or is locked by a deployment
+	CodeConflict
+	// the credentials are missing, invalid or not permitted to perform the request
+	CodeUnauthorized
+	// the request was invalid (eg. an application failing Marathon's validation)
+	CodeValidation
 )
+
+var errorCodeNames = map[ErrorCode]string{
+	CodeUnknown:      "Unknown",
+	CodeNotFound:     "NotFound",
+	CodeConflict:     "Conflict",
+	CodeUnauthorized: "Unauthorized",
+	CodeValidation:   "Validation",
+}
+
+func (c ErrorCode) String() string {
+	return errorCodeNames[c]
+}
+
+// MarathonError is a request rejected by Marathon.  It carries the HTTP status along with Marathon's
+// message and the offending fields so callers can branch on the Code rather than the message.
+// errors.Is matches the error it replaced (eg. ErrorAppExists) and httpclient.ErrorNotFound for every
+// CodeNotFound error
+type MarathonError struct {
+	Code    ErrorCode
+	Status  int
+	Message string
+	Details []httpclient.FieldError
+	// error describing the failure to the user
+	err error
+}
+
+// Returns the error of the failed response {resp}.  Responses from Marathon become a MarathonError with
+// the code for the status while failures without a response (eg. connection errors) are returned as is
+func responseError(resp *httpclient.Response) error {
+	if resp.Status < 400 {
+		return resp.Err()
+	}
+	return newMarathonError(resp, codeForStatus(resp.Status), resp.Err())
+}
+
+// Returns a MarathonError for {resp} described by {err}
+func newMarathonError(resp *httpclient.Response, code ErrorCode, err error) *MarathonError {
+	api := httpclient.NewAPIError(resp.Status, resp.Content)
+	return &MarathonError{Code: code, Status: resp.Status, Message: api.Message, Details: api.Details, err: err}
+}
+
+func codeForStatus(status int) ErrorCode {
+	switch status {
+	case 404:
+		return CodeNotFound
+	case 409:
+		return CodeConflict
+	case 401, 403:
+		return CodeUnauthorized
+	case 400, 422:
+		return CodeValidation
+	}
+	return CodeUnknown
+}
+
+func (e *MarathonError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the error describing the failure and the APIError with Marathon's response
+func (e *MarathonError) Unwrap() []error {
+	return []error{e.err, &httpclient.APIError{Status: e.Status, Message: e.Message, Details: e.Details}}
+}
+
+func (e *MarathonError) Is(target error) bool {
+	return e.Code == CodeNotFound && target == httpclient.ErrorNotFound
+}
+
+// ErrorCodeOf returns the code of {err} or CodeUnknown when it isn't a MarathonError.  Descriptors
+// rejected before they were sent (ValidationError) are CodeValidation
+func ErrorCodeOf(err error) ErrorCode {
+	var e *MarathonError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	var v *ValidationError
+	if errors.As(err, &v) {
+		return CodeValidation
+	}
+	return CodeUnknown
+}
+
+// NewError returns a MarathonError with {code} and HTTP {status} described by {err}.  Used by
+// implementations of Marathon other than the client (eg. marathontest.Fake)
+func NewError(code ErrorCode, status int, err error) *MarathonError {
+	return &MarathonError{Code: code, Status: status, Message: err.Error(), err: err}
+}
@@ -0,0 +1,67 @@
+package marathon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/mockrest"
+	"github.com/stretchr/testify/assert"
+)
+
+func startWithError(status int, body string) *mockrest.Server {
+	s := mockrest.New()
+	s.URL = s.Start()
+	s.Enqueue(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	})
+	return s
+}
+
+func TestMarathonErrorValidation(t *testing.T) {
+	s := startWithError(422, `{"message":"Object is not valid","details":[{"path":"/cpus","errors":["error.min"]}]}`)
+	defer s.Stop()
+
+	c := NewMarathonClient(s.URL, "", "")
+	_, err := c.ScaleApplication("/someapp", 5)
+
+	var e *MarathonError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, CodeValidation, e.Code)
+	assert.Equal(t, 422, e.Status)
+	assert.Equal(t, "Object is not valid", e.Message)
+	assert.Equal(t, "/cpus", e.Details[0].Path)
+
+	var api *httpclient.APIError
+	assert.True(t, errors.As(err, &api))
+	assert.Equal(t, 422, api.Status)
+}
+
+func TestMarathonErrorNotFound(t *testing.T) {
+	s := startWithError(404, `{"message":"App '/someapp' does not exist"}`)
+	defer s.Stop()
+
+	c := NewMarathonClient(s.URL, "", "")
+	_, err := c.GetApplication("/someapp")
+
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(err))
+	assert.True(t, errors.Is(err, httpclient.ErrorNotFound))
+	assert.Equal(t, "App '/someapp' does not exist", err.(*MarathonError).Message)
+}
+
+func TestMarathonErrorConflict(t *testing.T) {
+	s := startWithError(409, `{"message":"An app with id [/someapp] already exists."}`)
+	defer s.Stop()
+
+	c := NewMarathonClient(s.URL, "", "")
+	_, err := c.CreateApplication(NewApplication("/someapp"), false, false)
+
+	assert.Equal(t, CodeConflict, ErrorCodeOf(err))
+	assert.True(t, errors.Is(err, ErrorAppExists))
+	assert.Equal(t, ErrorAppExists.Error(), err.Error())
+	assert.Equal(t, CodeUnknown, ErrorCodeOf(errors.New("other")))
+}
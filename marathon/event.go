@@ -1,10 +1,26 @@
 package marathon
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/ContainX/depcon/pkg/encoding"
-	"github.com/donovanhide/eventsource"
-	"net/http"
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+const (
+	// delay before reconnecting the event stream which doubles after each failed attempt
+	eventStreamBaseDelay = time.Second
+	eventStreamMaxDelay  = 30 * time.Second
+	// largest event accepted (eg. an api_post_event containing a large application)
+	eventStreamMaxEvent = 4 * 1024 * 1024
 )
 
 func (c *MarathonClient) CreateEventStreamListener(channel EventsChannel, filter int) error {
@@ -16,15 +32,28 @@ func (c *MarathonClient) CreateEventStreamListener(channel EventsChannel, filter
 		return nil
 	}
 
-	if err := c.setupSSEStream(); err != nil {
+	ctx, cancel := context.WithCancel(c.context())
+	events, err := c.EventStream(ctx, filter)
+	if err != nil {
+		cancel()
 		return err
 	}
 
 	c.eventStreamState = &EventStreamState{
 		channel: channel,
 		filter:  filter,
+		cancel:  cancel,
 	}
 
+	go func() {
+		for event := range events {
+			select {
+			case channel <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
 	return nil
 }
 
@@ -32,59 +61,172 @@ func (c *MarathonClient) CloseEventStreamListener(channel EventsChannel) {
 	c.Lock()
 	defer c.Unlock()
 
+	if c.eventStreamState != nil {
+		c.eventStreamState.cancel()
+	}
 	c.eventStreamState = nil
 }
 
-func (c *MarathonClient) setupSSEStream() error {
-	request, err := c.http.CreateHttpRequest(http.MethodGet, c.marathonUrl(API_EVENTS), nil)
-	if err != nil {
-		return err
+// EventStream streams the events of the types within {filters} (eg. EventIDDeployments) or every event
+// when no filters are given.  The channel is closed once {ctx} is done.  A dropped stream is reconnected
+// with an increasing delay, resuming after the last event received when Marathon supports it.  An error
+// is returned if the stream can't be opened initially
+func (c *MarathonClient) EventStream(ctx context.Context, filters ...int) (<-chan *Event, error) {
+	filter := 0
+	for _, f := range filters {
+		filter |= f
+	}
+	if filter == 0 {
+		filter = -1
 	}
 
-	stream, err := eventsource.SubscribeWith("", http.DefaultClient, request)
-	if err != nil {
-		return err
+	events := make(chan *Event, 16)
+	opened := make(chan error, 1)
+	go c.streamEvents(ctx, filter, events, opened)
+	if err := <-opened; err != nil {
+		return nil, err
 	}
+	return events, nil
+}
 
-	go func() {
-		for {
-			select {
-			case ev := <-stream.Events:
-				if err := c.handleStreamEvent(ev.Data()); err != nil {
-					log.Error("Error setting up SSE Stream: %v", err)
+// Reads the event stream into {events} until {ctx} is done reconnecting when the stream is dropped.  The
+// result of the first connection attempt is sent to {opened}
+func (c *MarathonClient) streamEvents(ctx context.Context, filter int, events chan<- *Event, opened chan<- error) {
+	defer close(events)
+
+	uri := eventStreamUrl(c.marathonUrl(API_EVENTS), filter)
+	lastEventID := ""
+	delay := eventStreamBaseDelay
+	for {
+		resp := c.http.HttpGetEventStreamCtx(ctx, uri, lastEventID, func(body io.Reader) error {
+			if opened != nil {
+				opened <- nil
+				opened = nil
+			}
+			delay = eventStreamBaseDelay
+			return readEventStream(body, func(field, value string) error {
+				switch field {
+				case "id":
+					lastEventID = value
+				case "retry":
+					if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+						delay = time.Duration(ms) * time.Millisecond
+					}
+				case "data":
+					return c.dispatchEvent(ctx, value, filter, events)
 				}
-			case err := <-stream.Errors:
-				log.Error("Error setting up SSE Stream: %v", err)
+				return nil
+			})
+		})
+		err := resp.Err()
+		if opened != nil {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
 			}
+			opened <- err
+			return
 		}
-	}()
-	return nil
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		log.Warning("Event stream disconnected (%s) - reconnecting in %s", err.Error(), delay)
+		if httpclient.Sleep(ctx, delay) != nil {
+			return
+		}
+		if delay *= 2; delay > eventStreamMaxDelay {
+			delay = eventStreamMaxDelay
+		}
+	}
+}
+
+// Returns the events URL asking Marathon for only the event types within {filter}.  Versions of
+// Marathon without event_type support send every event which is filtered on receipt
+func eventStreamUrl(base string, filter int) string {
+	if filter == -1 {
+		return base
+	}
+	names := []string{}
+	for name, id := range eventTypesMap {
+		if id&filter != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	params := url.Values{"event_type": names}
+	return base + "?" + params.Encode()
+}
+
+// Reads the server-sent events within {r} invoking {fn} with the id and retry fields as they're read
+// and the data of each event once complete
+func readEventStream(r io.Reader, fn func(field, value string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), eventStreamMaxEvent)
+	data := []string{}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				if err := fn("data", strings.Join(data, "\n")); err != nil {
+					return err
+				}
+				data = data[:0]
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		if field == "data" {
+			data = append(data, value)
+		} else if err := fn(field, value); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
 }
 
-func (c *MarathonClient) handleStreamEvent(data string) error {
-	if data == "" {
+// Decodes the event {data} sending it to {events} if its type is within {filter}
+func (c *MarathonClient) dispatchEvent(ctx context.Context, data string, filter int, events chan<- *Event) error {
+	event, err := c.decodeEvent(data)
+	if err != nil {
+		log.Debug(err.Error())
+		return nil
+	}
+	if event.ID&filter == 0 {
 		return nil
 	}
+	select {
+	case events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
+func (c *MarathonClient) decodeEvent(data string) (*Event, error) {
 	eventType := new(EventType)
 
 	if err := encoding.DefaultJSONEncoder().UnMarshalStr(data, eventType); err != nil {
-		return fmt.Errorf("Failed to decode event, content: %s, error: %s", data, err)
+		return nil, fmt.Errorf("Failed to decode event, content: %s, error: %s", data, err)
 	}
 
 	event, err := c.GetEvent(eventType.EventType)
 	if err != nil {
-		return fmt.Errorf("Unable to handle event type, type: %s, error: %s", eventType.EventType, err)
+		return nil, fmt.Errorf("Unable to handle event type, type: %s, error: %s", eventType.EventType, err)
 	}
 
 	if err := encoding.DefaultJSONEncoder().UnMarshalStr(data, event.Event); err != nil {
-		return fmt.Errorf("Failed to decode event, id: %s, error: %s", event.ID, err)
+		return nil, fmt.Errorf("Failed to decode event, id: %d, error: %s", event.ID, err)
 	}
-
-	if event.ID&c.eventStreamState.filter != 0 {
-		go func(ch EventsChannel, e *Event) {
-			ch <- e
-		}(c.eventStreamState.channel, event)
-	}
-	return nil
+	return event, nil
 }
@@ -0,0 +1,295 @@
+package marathon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Events received within this window of the one ending a pause between checks are reported before the
+// next check so a burst of task updates results in a single check
+const eventWakeDebounce = 250 * time.Millisecond
+
+// EventFilter matches the events concerning a set of applications along with the deployments changing them
+type EventFilter struct {
+	// Application, pod or group ids (eg. /prod matching /prod/web).  A filter without applications matches
+	// every event
+	Apps []string
+	// deployments seen changing the applications whose completion events name no applications
+	deployments map[string]bool
+}
+
+// NewEventFilter returns a filter matching the events of the applications, pods or groups {apps}
+func NewEventFilter(apps ...string) *EventFilter {
+	return &EventFilter{Apps: apps, deployments: map[string]bool{}}
+}
+
+// Returns a filter matching the events of deployment {id}.  The applications it changes are added to Apps
+// once known
+func deploymentEventFilter(id string) *EventFilter {
+	f := NewEventFilter()
+	f.deployments[id] = true
+	return f
+}
+
+// Match returns whether {e} concerns the applications of the filter.  The success or failure of a
+// deployment is matched once one of its steps has been
+func (f *EventFilter) Match(e *Event) bool {
+	deployment := EventDeploymentID(e)
+	if deployment != "" && f.deployments[deployment] {
+		return true
+	}
+	if len(f.Apps) == 0 && len(f.deployments) == 0 {
+		return true
+	}
+	for _, id := range EventAppIDs(e) {
+		if f.matchApp(id) {
+			if deployment != "" {
+				f.deployments[deployment] = true
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func (f *EventFilter) matchApp(id string) bool {
+	id = "/" + strings.Trim(id, "/")
+	for _, app := range f.Apps {
+		app = "/" + strings.Trim(app, "/")
+		if id == app || app == "/" || strings.HasPrefix(id, app+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// EventAppIDs returns the ids of the applications or pods {e} concerns.  Deployment events return those
+// changed by the deployment's plan and deployment_success / deployment_failed return none
+func EventAppIDs(e *Event) []string {
+	switch ev := e.Event.(type) {
+	case *EventStatusUpdate:
+		return []string{ev.AppID}
+	case *EventFailedHealthCheck:
+		return []string{ev.AppID}
+	case *EventHealthCheckChanged:
+		return []string{ev.AppID}
+	case *EventAddHealthCheck:
+		return []string{ev.AppID}
+	case *EventRemoveHealthCheck:
+		return []string{ev.AppID}
+	case *EventAppTerminated:
+		return []string{ev.AppID}
+	case *EventAPIRequest:
+		if ev.AppDefinition != nil {
+			return []string{ev.AppDefinition.ID}
+		}
+	case *EventGroupChangeSuccess:
+		return []string{ev.GroupID}
+	case *EventGroupChangeFailed:
+		return []string{ev.GroupID}
+	case *EventDeploymentInfo:
+		return planApps(ev.Plan, ev.CurrentStep)
+	case *EventDeploymentStepSuccess:
+		return planApps(ev.Plan, ev.CurrentStep)
+	case *EventDeploymentStepFailure:
+		return planApps(ev.Plan, ev.CurrentStep)
+	}
+	return nil
+}
+
+// EventDeploymentID returns the id of the deployment {e} reports on or an empty string for other events
+func EventDeploymentID(e *Event) string {
+	var plan *DeploymentPlan
+	switch ev := e.Event.(type) {
+	case *EventDeploymentSuccess:
+		return ev.ID
+	case *EventDeploymentFailed:
+		return ev.ID
+	case *EventDeploymentInfo:
+		plan = ev.Plan
+	case *EventDeploymentStepSuccess:
+		plan = ev.Plan
+	case *EventDeploymentStepFailure:
+		plan = ev.Plan
+	}
+	if plan == nil {
+		return ""
+	}
+	return plan.ID
+}
+
+// EventSummary returns a line describing {e} (eg. "Deployment d1 completed step 2 of 3: ScaleApplication /web")
+func EventSummary(e *Event) string {
+	switch ev := e.Event.(type) {
+	case *EventStatusUpdate:
+		s := fmt.Sprintf("Task %s of %s is %s", ev.TaskID, ev.AppID, ev.TaskStatus)
+		if ev.Host != "" {
+			s += " on " + ev.Host
+		}
+		if ev.Message != "" {
+			s += ": " + ev.Message
+		}
+		return s
+	case *EventFailedHealthCheck:
+		check := strings.TrimSpace(ev.HealthCheck.Protocol + " " + ev.HealthCheck.Path)
+		if check == "" {
+			check = "Health check"
+		} else {
+			check = "Health check " + check
+		}
+		if ev.TaskID != "" {
+			return fmt.Sprintf("%s failed for task %s of %s", check, ev.TaskID, ev.AppID)
+		}
+		return fmt.Sprintf("%s failed for %s", check, ev.AppID)
+	case *EventHealthCheckChanged:
+		state := "unhealthy"
+		if ev.Alive {
+			state = "healthy"
+		}
+		return fmt.Sprintf("Task %s of %s is %s", ev.TaskID, ev.AppID, state)
+	case *EventAddHealthCheck:
+		return fmt.Sprintf("Health check added to %s", ev.AppID)
+	case *EventRemoveHealthCheck:
+		return fmt.Sprintf("Health check removed from %s", ev.AppID)
+	case *EventAppTerminated:
+		return fmt.Sprintf("Application %s terminated", ev.AppID)
+	case *EventAPIRequest:
+		if ev.AppDefinition != nil {
+			return fmt.Sprintf("Application %s updated through %s", ev.AppDefinition.ID, ev.URI)
+		}
+	case *EventGroupChangeSuccess:
+		return fmt.Sprintf("Group %s changed to version %s", ev.GroupID, ev.Version)
+	case *EventGroupChangeFailed:
+		return fmt.Sprintf("Group %s failed to change: %s", ev.GroupID, ev.Reason)
+	case *EventDeploymentSuccess:
+		return fmt.Sprintf("Deployment %s succeeded", ev.ID)
+	case *EventDeploymentFailed:
+		return fmt.Sprintf("Deployment %s failed", ev.ID)
+	case *EventDeploymentInfo:
+		return fmt.Sprintf("Deployment %s started %s", EventDeploymentID(e), describeStep(ev.Plan, ev.CurrentStep))
+	case *EventDeploymentStepSuccess:
+		return fmt.Sprintf("Deployment %s completed %s", EventDeploymentID(e), describeStep(ev.Plan, ev.CurrentStep))
+	case *EventDeploymentStepFailure:
+		return fmt.Sprintf("Deployment %s failed %s", EventDeploymentID(e), describeStep(ev.Plan, ev.CurrentStep))
+	}
+	return e.Name
+}
+
+// Returns whether {e} reports a failure (eg. a failed step or health check or a task which was killed)
+func eventFailed(e *Event) bool {
+	switch ev := e.Event.(type) {
+	case *EventStatusUpdate:
+		switch ev.TaskStatus {
+		case "TASK_FAILED", "TASK_ERROR", "TASK_LOST", "TASK_DROPPED", "TASK_GONE", "TASK_UNREACHABLE":
+			return true
+		}
+	case *EventHealthCheckChanged:
+		return !ev.Alive
+	case *EventFailedHealthCheck, *EventDeploymentFailed, *EventDeploymentStepFailure, *EventGroupChangeFailed:
+		return true
+	}
+	return false
+}
+
+// Describes the deployment {step} of {plan} (eg. "step 2 of 3: ScaleApplication /web")
+func describeStep(plan *DeploymentPlan, step *DeploymentStep) string {
+	s := "a step"
+	if i := step.IndexIn(plan); i >= 0 {
+		s = fmt.Sprintf("step %d of %d", i+1, len(plan.Steps))
+	}
+	if step == nil || len(step.Actions) == 0 {
+		return s
+	}
+	actions := make([]string, len(step.Actions))
+	for i, a := range step.Actions {
+		actions[i] = strings.TrimSpace(a.Action + " " + a.Target())
+	}
+	return s + ": " + strings.Join(actions, ", ")
+}
+
+// Returns the applications changed by {plan} or by {step} when the plan isn't known
+func planApps(plan *DeploymentPlan, step *DeploymentStep) []string {
+	if plan != nil {
+		return plan.AffectedApps()
+	}
+	if step == nil {
+		return nil
+	}
+	return affectedIDs([]*DeploymentStep{step})
+}
+
+// waitEvents follows the event stream while waiting so progress is reported as Marathon reports it
+type waitEvents struct {
+	events <-chan *Event
+	filter *EventFilter
+	cancel context.CancelFunc
+}
+
+// Subscribes to the deployment and application events matched by {filter} when MarathonOptions.WaitEvents
+// is set.  Nil is returned when the stream is unavailable in which case waits only poll
+func (c *MarathonClient) subscribeWait(ctx context.Context, filter *EventFilter) *waitEvents {
+	if filter == nil || c.opts == nil || !c.opts.WaitEvents {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	events, err := c.EventStream(ctx, EventIDDeployments|EventIDApplications)
+	if err != nil {
+		cancel()
+		logWait.Debug("Event stream unavailable, polling every %s: %s", c.pollInterval(), err.Error())
+		return nil
+	}
+	return &waitEvents{events: events, filter: filter, cancel: cancel}
+}
+
+func (w *waitEvents) close() {
+	if w != nil {
+		w.cancel()
+	}
+}
+
+// Pauses for {d} like sleep reporting the events matched by {w} as they arrive.  The pause ends early once
+// a matching event is received so the next check sees the change it reports
+func (c *MarathonClient) sleepOrEvent(ctx context.Context, d time.Duration, w *waitEvents) error {
+	if w == nil {
+		return c.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	var wake <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			c.clearWaitStatus()
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case <-wake:
+			return nil
+		case e, ok := <-w.events:
+			if !ok {
+				// the stream only closes once the context is done
+				w.events = nil
+				continue
+			}
+			if !w.filter.Match(e) {
+				continue
+			}
+			c.reportEvent(e)
+			if wake == nil {
+				wake = time.After(eventWakeDebounce)
+			}
+		}
+	}
+}
+
+// Logs {e} clearing the wait status so it's redrawn below the event by the next check
+func (c *MarathonClient) reportEvent(e *Event) {
+	c.clearWaitStatus()
+	if eventFailed(e) {
+		logWait.Warning("%s", EventSummary(e))
+	} else {
+		logWait.Info("%s", EventSummary(e))
+	}
+}
@@ -0,0 +1,146 @@
+package marathon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const stepSuccessEvent = `{"eventType": "deployment_step_success",
+	"currentStep": {"actions": [{"action": "ScaleApplication", "app": "/prod/web"}]},
+	"plan": {"id": "d1", "steps": [{"actions": [{"action": "StartApplication", "app": "/prod/web"}]},
+		{"actions": [{"action": "ScaleApplication", "app": "/prod/web"}]}]}}`
+
+func decodeTestEvent(t *testing.T, data string) *Event {
+	e, err := (&MarathonClient{}).decodeEvent(data)
+	assert.Nil(t, err)
+	return e
+}
+
+func TestEventFilter(t *testing.T) {
+	step := decodeTestEvent(t, stepSuccessEvent)
+	success := decodeTestEvent(t, `{"eventType": "deployment_success", "id": "d1"}`)
+	other := decodeTestEvent(t, `{"eventType": "deployment_success", "id": "d2"}`)
+	status := decodeTestEvent(t, `{"eventType": "status_update_event", "appId": "/prod/web", "taskId": "web.1", "taskStatus": "TASK_RUNNING"}`)
+	api := decodeTestEvent(t, `{"eventType": "status_update_event", "appId": "/prod/web-api", "taskId": "api.1", "taskStatus": "TASK_RUNNING"}`)
+
+	f := NewEventFilter("/prod/web")
+	assert.False(t, f.Match(success), "deployments are matched once a step changing the app is")
+	assert.True(t, f.Match(step))
+	assert.True(t, f.Match(success))
+	assert.False(t, f.Match(other))
+	assert.True(t, f.Match(status))
+	assert.False(t, f.Match(api), "ids are matched by path segment")
+
+	assert.True(t, NewEventFilter("prod").Match(api), "groups match the applications within them")
+	assert.True(t, NewEventFilter().Match(other))
+
+	f = deploymentEventFilter("d1")
+	assert.True(t, f.Match(success))
+	assert.False(t, f.Match(status))
+	f.Apps = []string{"/prod/web"}
+	assert.True(t, f.Match(status))
+}
+
+func TestEventSummary(t *testing.T) {
+	tests := map[string]string{
+		stepSuccessEvent: "Deployment d1 completed step 2 of 2: ScaleApplication /prod/web",
+		`{"eventType": "deployment_step_failure", "currentStep": {"actions": [{"action": "StopApplication", "app": "/old"}]}, "plan": {"id": "d2"}}`: "Deployment d2 failed a step: StopApplication /old",
+		`{"eventType": "deployment_failed", "id": "d3"}`: "Deployment d3 failed",
+		`{"eventType": "status_update_event", "appId": "/web", "taskId": "web.1", "taskStatus": "TASK_FAILED", "host": "agent-1", "message": "OOM"}`: "Task web.1 of /web is TASK_FAILED on agent-1: OOM",
+		`{"eventType": "failed_health_check_event", "appId": "/web", "taskId": "web.1", "healthCheck": {"protocol": "HTTP", "path": "/health"}}`:     "Health check HTTP /health failed for task web.1 of /web",
+		`{"eventType": "health_status_changed_event", "appId": "/web", "taskId": "web.1", "alive": true}`:                                            "Task web.1 of /web is healthy",
+		`{"eventType": "framework_message_event", "message": "x"}`:                                                                                   "framework_message_event",
+	}
+	for data, expected := range tests {
+		assert.Equal(t, expected, EventSummary(decodeTestEvent(t, data)))
+	}
+
+	assert.True(t, eventFailed(decodeTestEvent(t, `{"eventType": "health_status_changed_event", "alive": false}`)))
+	assert.False(t, eventFailed(decodeTestEvent(t, `{"eventType": "status_update_event", "taskStatus": "TASK_RUNNING"}`)))
+}
+
+type recordingProgress struct {
+	sync.Mutex
+	cleared int
+}
+
+func (p *recordingProgress) Status(message string, done, total int) bool { return true }
+
+func (p *recordingProgress) Clear() {
+	p.Lock()
+	defer p.Unlock()
+	p.cleared++
+}
+
+func TestWaitForDeploymentEvents(t *testing.T) {
+	var mu sync.Mutex
+	completed := false
+	checked := make(chan struct{})
+	var once sync.Once
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path.Clean(r.URL.Path) {
+		case "/v2/events":
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.(http.Flusher).Flush()
+			<-checked
+			mu.Lock()
+			completed = true
+			mu.Unlock()
+			fmt.Fprintf(w, "data: %s\n\n", compactJSON(t, stepSuccessEvent))
+			fmt.Fprint(w, "data: {\"eventType\": \"deployment_success\", \"id\": \"d1\"}\n\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		case "/v2/deployments":
+			mu.Lock()
+			defer mu.Unlock()
+			if completed {
+				fmt.Fprint(w, `[]`)
+				return
+			}
+			fmt.Fprint(w, `[{"id": "d1", "affectedApps": ["/prod/web"]}]`)
+			once.Do(func() { close(checked) })
+		default:
+			fmt.Fprint(w, `{"app": {"id": "/prod/web", "instances": 1}}`)
+		}
+	}))
+	defer s.Close()
+
+	// the deployment completes long before the next poll so only the events end the wait promptly
+	progress := &recordingProgress{}
+	c := NewMarathonClientWithOpts(s.URL, "", "", &MarathonOptions{PollInterval: time.Minute, WaitEvents: true, Progress: progress})
+	start := time.Now()
+	assert.Nil(t, c.WaitForDeployment("d1", 2*time.Minute))
+	assert.True(t, time.Since(start) < 30*time.Second)
+	progress.Lock()
+	defer progress.Unlock()
+	assert.True(t, progress.cleared >= 2, "the status should be cleared before each event is reported")
+}
+
+func TestWaitEventsUnavailable(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Clean(r.URL.Path) == "/v2/events" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `[]`)
+	}))
+	defer s.Close()
+
+	c := NewMarathonClientWithOpts(s.URL, "", "", &MarathonOptions{PollInterval: time.Millisecond, WaitEvents: true})
+	assert.Nil(t, c.WaitForDeployment("d1", time.Minute), "waits should fall back to polling")
+}
+
+func compactJSON(t *testing.T, s string) string {
+	var v interface{}
+	assert.Nil(t, json.Unmarshal([]byte(s), &v))
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,77 @@
+package marathon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventStreamReconnects(t *testing.T) {
+	var connections int32
+	lastEventIDs := make(chan string, 2)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs <- r.Header.Get("Last-Event-ID")
+		w.Header().Set("Content-Type", "text/event-stream")
+		if atomic.AddInt32(&connections, 1) == 1 {
+			fmt.Fprint(w, "retry: 10\nid: 1\nevent: deployment_info\ndata: {\"eventType\": \"app_terminated_event\", \"appId\": \"/ignored\"}\n\n")
+			fmt.Fprint(w, "id: 2\ndata: {\"eventType\": \"deployment_success\",\n")
+			fmt.Fprint(w, "data: \"id\": \"d1\"}\n\n")
+			return
+		}
+		fmt.Fprint(w, ": keep-alive\n\ndata: {\"eventType\": \"deployment_failed\", \"id\": \"d2\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewMarathonClient(s.URL, "", "")
+	events, err := c.EventStream(ctx, EventIDDeploymentSuccess, EventIDDeploymentFailed)
+	assert.Nil(t, err)
+
+	e := receiveEvent(t, events)
+	assert.Equal(t, "deployment_success", e.Name)
+	assert.Equal(t, "d1", e.Event.(*EventDeploymentSuccess).ID)
+
+	e = receiveEvent(t, events)
+	assert.Equal(t, "deployment_failed", e.Name)
+	assert.Equal(t, "", <-lastEventIDs)
+	assert.Equal(t, "2", <-lastEventIDs, "should resume after the last event")
+
+	cancel()
+	_, open := <-events
+	assert.False(t, open, "channel should close once the context is done")
+}
+
+func TestEventStreamUnavailable(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	_, err := NewMarathonClient(s.URL, "", "").EventStream(context.Background())
+	assert.NotNil(t, err)
+}
+
+func TestEventStreamUrl(t *testing.T) {
+	assert.Equal(t, "http://m/v2/events", eventStreamUrl("http://m/v2/events", -1))
+	assert.Equal(t, "http://m/v2/events?event_type=deployment_failed&event_type=deployment_success",
+		eventStreamUrl("http://m/v2/events", EventIDDeploymentSuccess|EventIDDeploymentFailed))
+}
+
+func receiveEvent(t *testing.T, events <-chan *Event) *Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return nil
+}
@@ -217,6 +217,7 @@ type EventRemoveHealthCheck struct {
 // EventFailedHealthCheck describes a 'failed_health_check_event' event.
 type EventFailedHealthCheck struct {
 	AppID       string `json:"appId"`
+	TaskID      string `json:"taskId,omitempty"`
 	EventType   string `json:"eventType"`
 	HealthCheck struct {
 		GracePeriodSeconds     float64 `json:"gracePeriodSeconds"`
@@ -351,3 +352,9 @@ func (c *MarathonClient) GetEvent(eventType string) (*Event, error) {
 
 	return nil, fmt.Errorf("the event type: %s was not found or supported", eventType)
 }
+
+// EventTypeID returns the listener ID of the event type {name} (eg. deployment_success)
+func EventTypeID(name string) (int, bool) {
+	id, ok := eventTypesMap[name]
+	return id, ok
+}
@@ -1,17 +1,25 @@
 package marathon
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"github.com/ContainX/depcon/pkg/cli"
 	"github.com/ContainX/depcon/pkg/encoding"
 	"github.com/ContainX/depcon/pkg/envsubst"
 	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/ContainX/depcon/pkg/sops"
 	"io"
-	"os"
 	"strings"
 	"time"
 )
 
 func (c *MarathonClient) CreateGroupFromString(filename string, grpstr string, opts *CreateOptions) (*Group, error) {
+	return c.CreateGroupFromStringCtx(c.context(), filename, grpstr, opts)
+}
+
+func (c *MarathonClient) CreateGroupFromStringCtx(ctx context.Context, filename string, grpstr string, opts *CreateOptions) (*Group, error) {
 	et, err := encoding.EncoderTypeFromExt(filename)
 	if err != nil {
 		return nil, err
@@ -21,48 +29,82 @@ func (c *MarathonClient) CreateGroupFromString(filename string, grpstr string, o
 	if err != nil {
 		return group, err
 	}
+	if err := prepareGroup(group, opts.Prepare); err != nil {
+		return nil, err
+	}
 
 	if opts.StopDeploy {
-		if deployment, err := c.CancelAppDeployment(group.GroupID, true); err == nil && deployment != nil {
+		if deployment, err := c.CancelAppDeploymentCtx(ctx, group.GroupID, true); err == nil && deployment != nil {
 			log.Info("Previous deployment found..  cancelling and waiting until complete.")
-			c.WaitForDeployment(deployment.DeploymentID, time.Second*30)
+			c.WaitForDeploymentCtx(ctx, deployment.DeploymentID, time.Second*30)
 		}
 	}
 
-	return c.CreateGroup(group, opts.Wait, opts.Force)
+	return c.CreateGroupCtx(ctx, group, opts.Wait, opts.Force)
 }
 
 func (c *MarathonClient) CreateGroupFromFile(filename string, opts *CreateOptions) (*Group, error) {
+	return c.CreateGroupFromFileCtx(c.context(), filename, opts)
+}
+
+func (c *MarathonClient) CreateGroupFromFileCtx(ctx context.Context, filename string, opts *CreateOptions) (*Group, error) {
 	log.Info("Creating Group from file: %s", filename)
 
 	group, err := c.ParseGroupFromFile(filename, opts)
 	if err != nil {
 		return group, err
 	}
+	if err := prepareGroup(group, opts.Prepare); err != nil {
+		return nil, err
+	}
 
 	if opts.StopDeploy {
-		if deployment, err := c.CancelAppDeployment(group.GroupID, true); err == nil && deployment != nil {
+		if deployment, err := c.CancelAppDeploymentCtx(ctx, group.GroupID, true); err == nil && deployment != nil {
 			log.Info("Previous deployment found..  cancelling and waiting until complete.")
-			c.WaitForDeployment(deployment.DeploymentID, time.Second*30)
+			c.WaitForDeploymentCtx(ctx, deployment.DeploymentID, time.Second*30)
 		}
 	}
 
-	return c.CreateGroup(group, opts.Wait, opts.Force)
+	return c.CreateGroupCtx(ctx, group, opts.Wait, opts.Force)
+}
+
+// Applies {prepare} to the applications of {group} and its subgroups
+func prepareGroup(group *Group, prepare func(app *Application) error) error {
+	if prepare == nil {
+		return nil
+	}
+	for _, app := range group.Apps {
+		if err := prepare(app); err != nil {
+			return err
+		}
+	}
+	for _, g := range group.Groups {
+		if err := prepareGroup(g, prepare); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (c *MarathonClient) ParseGroupFromFile(filename string, opts *CreateOptions) (*Group, error) {
 	log.Info("Creating Group from file: %s", filename)
 
-	file, err := os.Open(filename)
+	data, err := sops.ReadFile(filename)
 	if err != nil {
 		return nil, fmt.Errorf("Error opening filename %s, %s", filename, err.Error())
 	}
+	file := bytes.NewReader(data)
 
-	if et, err := encoding.EncoderTypeFromExt(filename); err != nil {
+	et, err := encoding.EncoderTypeFromExt(filename)
+	if err != nil {
 		return nil, err
-	} else {
-		return c.ParseGroupFromString(file, et, opts)
 	}
+
+	result, err := c.ParseGroupFromString(file, et, opts)
+	if perr, ok := err.(*envsubst.MissingParamsError); ok {
+		perr.Filename = filename
+	}
+	return result, err
 }
 
 func (c *MarathonClient) ParseGroupFromString(r io.Reader, et encoding.EncoderType, opts *CreateOptions) (*Group, error) {
@@ -77,15 +119,15 @@ func (c *MarathonClient) ParseGroupFromString(r io.Reader, et encoding.EncoderTy
 		return nil, err
 	}
 
-	parsed, missing := envsubst.SubstFileTokens(r, options.EnvParams)
+	parsed, missing := envsubst.SubstTokens(r, options.EnvParams)
 
-	if opts.ErrorOnMissingParams && missing {
-		return nil, ErrorAppParamsMissing
+	if options.ErrorOnMissingParams && len(missing) > 0 {
+		return nil, &envsubst.MissingParamsError{Params: missing}
 	}
 
-	if opts.DryRun {
-		fmt.Printf("Create Group :: DryRun :: Template Output\n\n%s", parsed)
-		os.Exit(0)
+	if options.DryRun {
+		fmt.Printf("Create Group :: DryRun :: Template Output\n\n%s", mask.Text(parsed))
+		cli.ExitWithCode(cli.ExitSuccess)
 	}
 
 	group := new(Group)
@@ -97,26 +139,37 @@ func (c *MarathonClient) ParseGroupFromString(r io.Reader, et encoding.EncoderTy
 }
 
 func (c *MarathonClient) CreateGroup(group *Group, wait, force bool) (*Group, error) {
+	return c.CreateGroupCtx(c.context(), group, wait, force)
+}
+
+func (c *MarathonClient) CreateGroupCtx(ctx context.Context, group *Group, wait, force bool) (*Group, error) {
 	log.Info("Creating Group '%s', wait: %v, force: %v", group.GroupID, wait, force)
+	if err := group.Validate(); err != nil {
+		return nil, err
+	}
 	result := new(DeploymentID)
-	resp := c.http.HttpPost(c.marathonUrl(API_GROUPS), group, result)
+	resp := c.http.HttpPostCtx(ctx, c.marathonUrl(API_GROUPS), group, result)
 	if resp.Error != nil {
 		if resp.Error == httpclient.ErrorMessage {
 			if resp.Status == 409 {
 				if force {
-					return c.UpdateGroup(group, wait)
+					return c.UpdateGroupCtx(ctx, group, wait)
 				}
-				return nil, ErrorGroupExists
+				return nil, newMarathonError(resp, CodeConflict, ErrorGroupExists)
 			}
 			if resp.Status == 422 {
-				return nil, ErrorInvalidGroupId
+				return nil, newMarathonError(resp, CodeValidation, ErrorInvalidGroupId)
 			}
-			return nil, fmt.Errorf("Error occurred (Status %v) Body -> %s", resp.Status, resp.Content)
+			return nil, responseError(resp)
 		}
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	if wait {
-		if err := c.WaitForDeployment(result.DeploymentID, time.Duration(500)*time.Second); err != nil {
+		timeout := time.Duration(500) * time.Second
+		if c.opts != nil && c.opts.WaitTimeout != 0 {
+			timeout = c.opts.WaitTimeout
+		}
+		if err := c.WaitForDeploymentCtx(ctx, result.DeploymentID, timeout); err != nil {
 			return nil, err
 		}
 	}
@@ -124,51 +177,70 @@ func (c *MarathonClient) CreateGroup(group *Group, wait, force bool) (*Group, er
 }
 
 func (c *MarathonClient) UpdateGroup(group *Group, wait bool) (*Group, error) {
+	return c.UpdateGroupCtx(c.context(), group, wait)
+}
+
+func (c *MarathonClient) UpdateGroupCtx(ctx context.Context, group *Group, wait bool) (*Group, error) {
 	log.Info("Update Group '%s', wait = %v", group.GroupID, wait)
+	if err := group.Validate(); err != nil {
+		return nil, err
+	}
 	result := new(DeploymentID)
-	resp := c.http.HttpPut(c.marathonUrl(API_GROUPS), group, result)
+	resp := c.http.HttpPutCtx(ctx, c.marathonUrl(API_GROUPS), group, result)
 
 	if resp.Error != nil {
 		if resp.Error == httpclient.ErrorMessage {
 			if resp.Status == 422 {
-				return nil, ErrorGropAppExists
+				return nil, newMarathonError(resp, CodeNotFound, ErrorGropAppExists)
 			}
 		}
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	if wait {
-		if err := c.WaitForDeployment(result.DeploymentID, c.determineTimeout(nil)); err != nil {
+		if err := c.WaitForDeploymentCtx(ctx, result.DeploymentID, c.determineTimeout(nil)); err != nil {
 			return nil, err
 		}
 	}
 	// Get the latest version of the application to return
-	return c.GetGroup(group.GroupID)
+	return c.GetGroupCtx(ctx, group.GroupID)
 }
 
 func (c *MarathonClient) ListGroups() (*Groups, error) {
+	return c.ListGroupsCtx(c.context())
+}
+
+func (c *MarathonClient) ListGroupsCtx(ctx context.Context) (*Groups, error) {
 	groups := new(Groups)
 
-	resp := c.http.HttpGet(c.marathonUrl(API_GROUPS), groups)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_GROUPS), groups)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return groups, nil
 }
 
 func (c *MarathonClient) GetGroup(id string) (*Group, error) {
+	return c.GetGroupCtx(c.context(), id)
+}
+
+func (c *MarathonClient) GetGroupCtx(ctx context.Context, id string) (*Group, error) {
 	group := new(Group)
-	resp := c.http.HttpGet(c.marathonUrl(API_GROUPS, id), group)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_GROUPS, id), group)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return group, nil
 }
 
 func (c *MarathonClient) DestroyGroup(id string) (*DeploymentID, error) {
+	return c.DestroyGroupCtx(c.context(), id)
+}
+
+func (c *MarathonClient) DestroyGroupCtx(ctx context.Context, id string) (*DeploymentID, error) {
 	deploymentId := new(DeploymentID)
-	resp := c.http.HttpDelete(fmt.Sprintf("%s?force=true", c.marathonUrl(API_GROUPS, id)), nil, deploymentId)
+	resp := c.http.HttpDeleteCtx(ctx, fmt.Sprintf("%s?force=true", c.marathonUrl(API_GROUPS, id)), nil, deploymentId)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return deploymentId, nil
 }
@@ -0,0 +1,114 @@
+package marathon
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Groups applications by the value of a label (eg. label:team)
+	GroupByLabel = "label"
+	// Groups applications by their group (eg. group) or the group at a depth (eg. group:1)
+	GroupByGroup = "group"
+
+	// Name of the group holding the applications without the label being grouped by
+	NoLabelGroup = "(none)"
+)
+
+// ApplicationGroup is a set of applications sharing a label value or group along with the resources
+// allocated to their instances
+type ApplicationGroup struct {
+	Name      string        `json:"name"`
+	Apps      []Application `json:"apps"`
+	Instances int           `json:"instances"`
+	CPUs      float64       `json:"cpus"`
+	Mem       float64       `json:"mem"`
+	Disk      float64       `json:"disk"`
+}
+
+// GroupedApplications are applications grouped by a label or group with the totals of every group
+type GroupedApplications struct {
+	By     string              `json:"by"`
+	Groups []*ApplicationGroup `json:"groups"`
+	Total  *ApplicationGroup   `json:"total"`
+}
+
+// GroupBy groups the applications by {key}: label:<name> groups by the value of a label, group by the
+// group of each app and group:<depth> by the group at a depth of the id (eg. group:1 groups /shop/web/api
+// under /shop).  Groups are ordered by name with applications lacking the label last
+func (a *Applications) GroupBy(key string) (*GroupedApplications, error) {
+	nameOf, err := groupKey(key)
+	if err != nil {
+		return nil, err
+	}
+	result := &GroupedApplications{By: key, Groups: []*ApplicationGroup{}, Total: &ApplicationGroup{Name: "TOTAL", Apps: []Application{}}}
+	groups := map[string]*ApplicationGroup{}
+	for _, app := range a.Apps {
+		name := nameOf(&app)
+		g, ok := groups[name]
+		if !ok {
+			g = &ApplicationGroup{Name: name, Apps: []Application{}}
+			groups[name] = g
+			result.Groups = append(result.Groups, g)
+		}
+		g.add(app)
+		result.Total.add(app)
+	}
+	sort.SliceStable(result.Groups, func(i, j int) bool {
+		a, b := result.Groups[i].Name, result.Groups[j].Name
+		if (a == NoLabelGroup) != (b == NoLabelGroup) {
+			return b == NoLabelGroup
+		}
+		return a < b
+	})
+	return result, nil
+}
+
+func (g *ApplicationGroup) add(app Application) {
+	n := float64(app.Instances)
+	g.Apps = append(g.Apps, app)
+	g.Instances += app.Instances
+	g.CPUs += app.CPUs * n
+	g.Mem += app.Mem * n
+	g.Disk += app.Disk * n
+}
+
+func groupKey(key string) (func(app *Application) string, error) {
+	parts := strings.SplitN(key, ":", 2)
+	switch {
+	case parts[0] == GroupByLabel && len(parts) == 2 && parts[1] != "":
+		label := parts[1]
+		return func(app *Application) string {
+			if v := app.Labels[label]; v != "" {
+				return v
+			}
+			return NoLabelGroup
+		}, nil
+	case parts[0] == GroupByGroup && len(parts) == 1:
+		return func(app *Application) string {
+			return groupPath(app.ID, -1)
+		}, nil
+	case parts[0] == GroupByGroup:
+		depth, err := strconv.Atoi(parts[1])
+		if err != nil || depth < 1 {
+			return nil, fmt.Errorf("'%s' requires a positive depth (eg. group:1)", key)
+		}
+		return func(app *Application) string {
+			return groupPath(app.ID, depth)
+		}, nil
+	}
+	return nil, fmt.Errorf("'%s' is not a grouping - expected label:<name>, group or group:<depth>", key)
+}
+
+// Returns the group of application {id} or the group at {depth} when the app is nested deeper.  A
+// negative depth returns the group the app belongs to
+func groupPath(id string, depth int) string {
+	segments := strings.Split(strings.Trim(id, "/"), "/")
+	segments = segments[:len(segments)-1]
+	if depth >= 0 && len(segments) > depth {
+		segments = segments[:depth]
+	}
+	return "/" + strings.Join(segments, "/")
+}
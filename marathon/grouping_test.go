@@ -0,0 +1,45 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupBy(t *testing.T) {
+	apps := &Applications{Apps: []Application{
+		{ID: "/shop/web/frontend", Instances: 2, CPUs: 0.5, Mem: 256, Labels: map[string]string{"team": "web"}},
+		{ID: "/shop/api", Instances: 3, CPUs: 1, Mem: 512, Labels: map[string]string{"team": "payments"}},
+		{ID: "/batch", Instances: 1, CPUs: 2, Mem: 1024},
+		{ID: "/shop/web/backend", Instances: 1, CPUs: 0.25, Mem: 128, Labels: map[string]string{"team": "web"}},
+	}}
+
+	grouped, err := apps.GroupBy("label:team")
+	assert.Nil(t, err)
+	names := []string{}
+	for _, g := range grouped.Groups {
+		names = append(names, g.Name)
+	}
+	assert.Equal(t, []string{"payments", "web", NoLabelGroup}, names)
+	web := grouped.Groups[1]
+	assert.Equal(t, 2, len(web.Apps))
+	assert.Equal(t, 3, web.Instances)
+	assert.Equal(t, 1.25, web.CPUs)
+	assert.Equal(t, float64(640), web.Mem)
+	assert.Equal(t, 7, grouped.Total.Instances)
+	assert.Equal(t, 4, len(grouped.Total.Apps))
+
+	grouped, _ = apps.GroupBy("group")
+	assert.Equal(t, "/", grouped.Groups[0].Name)
+	assert.Equal(t, "/shop", grouped.Groups[1].Name)
+	assert.Equal(t, "/shop/web", grouped.Groups[2].Name)
+
+	grouped, _ = apps.GroupBy("group:1")
+	assert.Equal(t, 2, len(grouped.Groups))
+	assert.Equal(t, 3, len(grouped.Groups[1].Apps))
+
+	for _, invalid := range []string{"team", "label:", "group:0", "group:x"} {
+		_, err = apps.GroupBy(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
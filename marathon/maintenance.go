@@ -0,0 +1,81 @@
+package marathon
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LabelMaintenanceInstances records the instances an application ran before it was scaled to 0 for
+// maintenance
+const LabelMaintenanceInstances = "DEPCON_MAINTENANCE_INSTANCES"
+
+// MaintenanceUpdate is the partial update (see UpdateApplicationPartial) putting an application in or out
+// of maintenance
+type MaintenanceUpdate struct {
+	ID string `json:"id"`
+	// version of the application the update is based on
+	Version string                 `json:"version"`
+	From    int                    `json:"from"`
+	To      int                    `json:"to"`
+	Patch   map[string]interface{} `json:"patch"`
+}
+
+// MaintenanceOn returns the updates scaling each of {apps} to 0 while recording its instances in the
+// LabelMaintenanceInstances label, ordered by id.  Applications already in maintenance or matching one of
+// the label selectors {exclude} (key or key==value) are left out
+func MaintenanceOn(apps []*Application, exclude []string) []*MaintenanceUpdate {
+	updates := []*MaintenanceUpdate{}
+	for _, app := range apps {
+		if _, ok := app.Labels[LabelMaintenanceInstances]; ok || matchesAnyLabel(app, exclude) {
+			continue
+		}
+		updates = append(updates, &MaintenanceUpdate{ID: app.ID, Version: app.Version, From: app.Instances, To: 0,
+			Patch: map[string]interface{}{
+				"instances": 0,
+				"labels":    map[string]interface{}{LabelMaintenanceInstances: strconv.Itoa(app.Instances)},
+			}})
+	}
+	sortUpdates(updates)
+	return updates
+}
+
+// MaintenanceOff returns the updates scaling each of {apps} in maintenance back to the instances recorded
+// by MaintenanceOn and removing the label, ordered by id
+func MaintenanceOff(apps []*Application) ([]*MaintenanceUpdate, error) {
+	updates := []*MaintenanceUpdate{}
+	for _, app := range apps {
+		recorded, ok := app.Labels[LabelMaintenanceInstances]
+		if !ok {
+			continue
+		}
+		instances, err := strconv.Atoi(recorded)
+		if err != nil || instances < 0 {
+			return nil, fmt.Errorf("%s: invalid %s label '%s'", app.ID, LabelMaintenanceInstances, recorded)
+		}
+		updates = append(updates, &MaintenanceUpdate{ID: app.ID, Version: app.Version, From: app.Instances, To: instances,
+			Patch: map[string]interface{}{
+				"instances": instances,
+				"labels":    map[string]interface{}{LabelMaintenanceInstances: nil},
+			}})
+	}
+	sortUpdates(updates)
+	return updates, nil
+}
+
+// Returns true if {app} matches one of the label {selectors}: key (the label is set) or key==value
+func matchesAnyLabel(app *Application, selectors []string) bool {
+	for _, selector := range selectors {
+		kv := strings.SplitN(selector, "==", 2)
+		value, ok := app.Labels[kv[0]]
+		if ok && (len(kv) == 1 || value == kv[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortUpdates(updates []*MaintenanceUpdate) {
+	sort.Slice(updates, func(i, j int) bool { return updates[i].ID < updates[j].ID })
+}
@@ -0,0 +1,32 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenance(t *testing.T) {
+	apps := []*Application{
+		{ID: "/shop/web", Version: "v1", Instances: 3},
+		{ID: "/shop/db", Instances: 2, Labels: map[string]string{"tier": "db"}},
+		{ID: "/shop/cache", Instances: 1, Labels: map[string]string{"critical": "true"}},
+		{ID: "/shop/api", Labels: map[string]string{LabelMaintenanceInstances: "4"}},
+	}
+
+	on := MaintenanceOn(apps, []string{"tier==db", "critical"})
+	assert.Len(t, on, 1)
+	assert.Equal(t, &MaintenanceUpdate{ID: "/shop/web", Version: "v1", From: 3, To: 0, Patch: map[string]interface{}{
+		"instances": 0, "labels": map[string]interface{}{LabelMaintenanceInstances: "3"}}}, on[0])
+
+	off, err := MaintenanceOff(apps)
+	assert.Nil(t, err)
+	assert.Len(t, off, 1)
+	assert.Equal(t, "/shop/api", off[0].ID)
+	assert.Equal(t, 4, off[0].To)
+	assert.Equal(t, map[string]interface{}{"instances": 4, "labels": map[string]interface{}{LabelMaintenanceInstances: nil}}, off[0].Patch)
+
+	apps[3].Labels[LabelMaintenanceInstances] = "many"
+	_, err = MaintenanceOff(apps)
+	assert.NotNil(t, err)
+}
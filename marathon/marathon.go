@@ -2,9 +2,13 @@
 package marathon
 
 import (
+	"context"
 	"github.com/ContainX/depcon/pkg/httpclient"
 	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
 	"github.com/ContainX/depcon/utils"
+	"io"
+	"strings"
 	"sync"
 	"time"
 )
@@ -20,12 +24,16 @@ const (
 	API_DEPLOYMENTS  = API_VERSION + "/deployments"
 	API_GROUPS       = API_VERSION + "/groups"
 	API_QUEUE        = API_VERSION + "/queue"
+	API_PODS         = API_VERSION + "/pods"
+	API_ARTIFACTS    = API_VERSION + "/artifacts"
 	API_INFO         = API_VERSION + "/info"
 	API_LEADER       = API_VERSION + "/leader"
 	API_EVENTS       = API_VERSION + "/events"
 	API_PING         = "ping"
 
 	DefaultTimeout = time.Duration(90) * time.Second
+	// Timeout (or MarathonOptions.WaitTimeout) waiting until the operation completes or the context is done
+	WaitForever = time.Duration(-1)
 )
 
 // Common package logger
@@ -52,9 +60,23 @@ type CreateOptions struct {
 
 	// Do not actually create - output final parsed payload which would be POSTED and then exit
 	DryRun bool
+
+	// If set waiting ends once this number of the application's instances are healthy rather than when
+	// the deployment completes.  Implies Wait.  Ignored by groups
+	WaitHealthy *Quorum
+
+	// Optional change of each parsed application (including the applications of a group) before it is
+	// validated and created (eg. adding registry credentials).  Nothing is created when it returns an error
+	Prepare func(app *Application) error
+
+	// Optional check of the parsed application before it is created (eg. policies).  The application
+	// isn't created when it returns an error
+	Validate func(app *Application) error
 }
 
 type Marathon interface {
+	// Variants of the methods below accepting a context
+	MarathonContext
 
 	/** Application API */
 
@@ -89,16 +111,36 @@ type Marathon interface {
 	// {wait} - if true will attempt to wait until the application updated is running
 	UpdateApplication(app *Application, wait bool) (*Application, error)
 
+	// Applies a JSON merge patch to an Application
+	// {id}    - the application identifier
+	// {patch} - the fields to change.  Objects are merged and null removes a field
+	// {opts}  - optional expected version, wait and force
+	UpdateApplicationPartial(id string, patch map[string]interface{}, opts *PatchOptions) (*Application, error)
+
 	// List all applications on a Marathon cluster
 	ListApplications() (*Applications, error)
 
 	// List all applications on a Marathon cluster with filtering Options
 	ListApplicationsWithFilters(filter string) (*Applications, error)
 
+	// Streams all applications matching the optional {filter} invoking {fn} as each application
+	// is read from the response rather than buffering the entire list
+	ListApplicationsStream(filter string, fn func(app *Application) error) error
+
+	// Lists the applications under a group one group at a time invoking {fn} with each so clusters with
+	// thousands of applications are never held in memory at once
+	// {groupID} - group to list.  Default: the root group
+	ListApplicationsByGroup(groupID string, fn func(app *Application) error) error
+
 	// Get an Application by Id
 	// {id} - application identifier
 	GetApplication(id string) (*Application, error)
 
+	// Get the definition of an Application as the JSON values Marathon returns including the fields
+	// Application doesn't model
+	// {id} - application identifier
+	GetApplicationDefinition(id string) (map[string]interface{}, error)
+
 	// Determines if the application exists
 	// {id} - the application identifier
 	HasApplication(id string) (bool, error)
@@ -131,6 +173,13 @@ type Marathon interface {
 	// {timeout} - the max time to wait
 	WaitForApplicationHealthy(id string, timeout time.Duration) error
 
+	// Waits for a number of healthy tasks running the application's current configuration rather than
+	// for the entire deployment to complete
+	// {id} - the application id
+	// {quorum} - the number or percentage of the instances required
+	// {timeout} - the max time to wait
+	WaitForHealthyTasks(id string, quorum Quorum, timeout time.Duration) error
+
 	/** Deployment API */
 
 	// Determines whether a deployment for the specified Id exists
@@ -153,6 +202,10 @@ type Marathon interface {
 	// Waits for a deployment to finish for max timeout duration
 	WaitForDeployment(id string, timeout time.Duration) error
 
+	// Waits for a deployment to finish for max timeout duration returning whether it converged, timed out,
+	// got stuck waiting for offers, failed or was cancelled along with the state of its applications
+	WaitForDeploymentResult(id string, timeout time.Duration) *WaitResult
+
 	/** Group API */
 
 	// Creates a new group from a file and replaces tokenized variables
@@ -188,6 +241,61 @@ type Marathon interface {
 	// {id} - group identifier
 	DestroyGroup(id string) (*DeploymentID, error)
 
+	/** Pod API */
+
+	// Creates a new pod
+	// {pod}   - the pod to create
+	// {wait}  - if true will block until the pod is stable
+	// {force} - if true and the pod exists it will be updated instead
+	CreatePod(pod *Pod, wait, force bool) (*Pod, error)
+
+	// Updates an existing pod
+	// {pod}   - the pod to update
+	// {wait}  - if true will block until the pod is stable
+	// {force} - if true a current deployment of the pod is overridden
+	UpdatePod(pod *Pod, wait, force bool) (*Pod, error)
+
+	// List all pods
+	ListPods() ([]*Pod, error)
+
+	// Gets the pod by its identifier
+	// {id} - the pod id
+	GetPod(id string) (*Pod, error)
+
+	// Gets the status of the pod and its instances
+	// {id} - the pod id
+	GetPodStatus(id string) (*PodStatus, error)
+
+	// Deletes a pod and kills its instances
+	// {id}    - the pod id
+	// {force} - if true a current deployment of the pod is overridden
+	DeletePod(id string, force bool) error
+
+	// Lists the versions of a pod
+	// {id} - the pod id
+	PodVersions(id string) ([]string, error)
+
+	// Waits for the pod to become stable
+	// {id}      - the pod id
+	// {timeout} - the max time to wait
+	WaitForPod(id string, timeout time.Duration) error
+
+	/** Artifact API */
+
+	// Stores an artifact (eg. a docker.tar.gz credential bundle) replacing any existing artifact
+	// {path}    - the path of the artifact within the store (eg. /credentials/docker.tar.gz)
+	// {content} - the content of the artifact
+	UploadArtifact(path string, content io.Reader) error
+
+	// Copies the content of an artifact
+	// {path} - the path of the artifact within the store
+	// {w}    - the writer receiving the content
+	FetchArtifact(path string, w io.Writer) error
+
+	// Deletes an artifact
+	// {path} - the path of the artifact within the store
+	DeleteArtifact(path string) error
+
 	/** Task API */
 
 	// List all running tasks
@@ -215,6 +323,11 @@ type Marathon interface {
 	// List Queue - tasks currently pending
 	ListQueue() (*Queue, error)
 
+	// Resets the launch delay of a queued application so Marathon immediately attempts to launch its
+	// tasks again (eg. after fixing the cause of repeated failures)
+	// {id} - the application id
+	ResetDelay(id string) error
+
 	/** Event API */
 
 	// Creates an event stream listener which will filter based on the specified
@@ -224,6 +337,10 @@ type Marathon interface {
 	// Removes the channel from the event stream listener
 	CloseEventStreamListener(channel EventsChannel)
 
+	// Streams events until {ctx} is done reconnecting when the stream is dropped
+	// {filters} - event types to receive (eg. EventIDDeployments).  Default: all events
+	EventStream(ctx context.Context, filters ...int) (<-chan *Event, error)
+
 	/** Marathon Server Info API */
 
 	// Pings the Marathon host via the /ping endpoint
@@ -237,6 +354,10 @@ type Marathon interface {
 
 	// Abdicates the current leader
 	AbdicateLeader() (*Message, error)
+
+	// Abdicates the current leader optionally backing up or restoring the state
+	// {opts} - backup and restore locations.  Optional
+	AbdicateLeaderWithOpts(opts *AbdicateOptions) (*Message, error)
 }
 
 type MarathonClient struct {
@@ -245,40 +366,160 @@ type MarathonClient struct {
 	host             string
 	opts             *MarathonOptions
 	eventStreamState *EventStreamState
+	// every host when an HA cluster is configured and the detection of its leader
+	hosts      []string
+	leaderOnce sync.Once
 }
 
 type EventStreamState struct {
 	channel EventsChannel
 	filter  int
+	cancel  context.CancelFunc
 }
 
 type MarathonOptions struct {
-	WaitTimeout      time.Duration
+	// Maximum time waited on deployments by methods with a wait parameter.  WaitForever disables the limit.
+	// Default: derived from the application's health checks
+	WaitTimeout time.Duration
+	// Delay between checks while waiting on deployments, applications and pods.  Checks failing with
+	// transient errors back off exponentially from it.  Default: DefaultPollInterval
+	PollInterval time.Duration
+	// Cancels deployments which haven't converged when a wait times out so they don't linger after the
+	// caller gives up.  Marathon rolls the cancelled deployment back unless ForceCancel is set
+	CancelOnTimeout bool
+	// Deletes timed out deployments without rolling them back (requires CancelOnTimeout)
+	ForceCancel bool
+	// Optional check probed against every task once Marathon reports an application healthy.  Waits on
+	// applications and deployments return once all the tasks respond to it
+	Ready            *ReadyCheck
 	TLSAllowInsecure bool
+	// Optional token based authentication (eg. DC/OS) used in place of basic auth
+	Authenticator httpclient.Authenticator
+	// Optional proxies used in place of the proxy environment variables
+	Proxy *httpclient.ProxyConfig
+	// Optional client certificate and CA bundle for mutual TLS
+	TLS *httpclient.TLSConfig
+	// Refuses deploying, scaling, restarting and destroying (every request other than a GET) with
+	// httpclient.ErrorReadOnly
+	ReadOnly bool
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil.  Single calls
+	// may use another policy by passing a context from httpclient.WithRetryPolicy to the Ctx methods
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Maximum requests per second sent to Marathon.  Zero is unlimited
+	RateLimit float64
+	// Compresses large request bodies with gzip
+	Compress bool
+	// Static headers added to every request
+	Headers map[string]string
+	// Optional local cache of GET responses
+	Cache *httpclient.CacheConfig
+	// Optional reporter drawing the status (healthy instances of the total) while waiting on deployments and applications.  Status is logged when nil
+	Progress progress.Reporter
+	// Follows Marathon's event stream while waiting on deployments and applications, logging their steps,
+	// failed health checks and task status changes as they happen and checking again as soon as they're
+	// reported.  Waits poll every PollInterval when the stream is unavailable
+	WaitEvents bool
+	// Optional callbacks receiving the method, path, status and latency of every request made to Marathon
+	// (eg. to export metrics or traces)
+	Hooks *httpclient.Hooks
+	// Optional context of the methods without a Ctx suffix (eg. one cancelled on Ctrl-C).  Default:
+	// context.Background()
+	Context context.Context
 }
 
 func NewMarathonClient(host, username, password string) Marathon {
 	return NewMarathonClientWithOpts(host, username, password, nil)
 }
 
+// NewMarathonClientWithOpts creates a client for {host} which may be a comma separated list of the
+// Marathon nodes within an HA cluster.  Requests are sent to the leader and fail over to the other
+// nodes when a connection can't be established
 func NewMarathonClientWithOpts(host, username, password string, opts *MarathonOptions) Marathon {
+	hosts := SplitHosts(host)
 	httpConfig := httpclient.NewDefaultConfig()
 	httpConfig.HttpUser = username
 	httpConfig.HttpPass = password
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	httpConfig.Endpoints = hosts
+	if opts != nil {
+		httpConfig.TLSInsecureSkipVerify = opts.TLSAllowInsecure
+		httpConfig.Authenticator = opts.Authenticator
+		httpConfig.Proxy = opts.Proxy
+		httpConfig.TLS = opts.TLS
+		httpConfig.ReadOnly = opts.ReadOnly
+		httpConfig.Timeouts = opts.Timeouts
+		httpConfig.RateLimit = opts.RateLimit
+		httpConfig.Compress = opts.Compress
+		httpConfig.Headers = opts.Headers
+		httpConfig.Cache = opts.Cache
+		httpConfig.Hooks = opts.Hooks
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+	}
 
 	httpClient := httpclient.NewHttpClient(*httpConfig)
 
 	c := new(MarathonClient)
 	c.http = *httpClient
-	c.host = host
+	c.host = hosts[0]
+	c.hosts = hosts
 	c.opts = opts
 	return c
 }
 
+// Returns the context of requests made by methods without a Ctx suffix
+func (c *MarathonClient) context() context.Context {
+	if c.opts != nil && c.opts.Context != nil {
+		return c.opts.Context
+	}
+	return context.Background()
+}
+
+// SplitHosts returns the hosts within the comma separated list {hosts}
+func SplitHosts(hosts string) []string {
+	list := []string{}
+	for _, h := range strings.Split(hosts, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			list = append(list, h)
+		}
+	}
+	if len(list) == 0 {
+		list = append(list, hosts)
+	}
+	return list
+}
+
 func (c *MarathonClient) marathonUrl(elements ...string) string {
+	c.leaderOnce.Do(c.detectLeader)
+	if active := c.http.ActiveEndpoint(); active != "" {
+		return utils.BuildPath(active, elements)
+	}
 	return utils.BuildPath(c.host, elements)
 }
 
+// When multiple hosts are configured the leader is made the active host so requests aren't proxied
+// through a follower
+func (c *MarathonClient) detectLeader() {
+	if len(c.hosts) < 2 {
+		return
+	}
+	info := new(LeaderInfo)
+	if resp := c.http.HttpGet(utils.BuildPath(c.host, []string{API_LEADER}), info); resp.Error != nil {
+		log.Debug("Unable to determine the leader: %s", resp.Error.Error())
+		return
+	}
+	for _, host := range c.hosts {
+		if info.IsHost(host) {
+			log.Debug("Leader: %s", host)
+			c.http.SetActiveEndpoint(host)
+			return
+		}
+	}
+}
+
 func initCreateOptions(opts *CreateOptions) *CreateOptions {
 	if opts == nil {
 		return &CreateOptions{}
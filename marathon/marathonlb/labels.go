@@ -0,0 +1,251 @@
+package marathonlb
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+)
+
+const (
+	IssueError   = "error"
+	IssueWarning = "warning"
+
+	ModeHTTP = "http"
+	ModeTCP  = "tcp"
+
+	LabelGroup           = "HAPROXY_GROUP"
+	LabelDeploymentGroup = "HAPROXY_DEPLOYMENT_GROUP"
+)
+
+// Per port labels (HAPROXY_{n}_{KEY})
+var portLabel = regexp.MustCompile(`^HAPROXY_(\d+)_(.+)$`)
+
+// Application wide labels other than the per port labels
+var appLabels = map[string]bool{
+	LabelGroup:                            true,
+	LabelDeploymentGroup:                  true,
+	"HAPROXY_DEPLOYMENT_ALT_PORT":         true,
+	"HAPROXY_DEPLOYMENT_COLOUR":           true,
+	"HAPROXY_DEPLOYMENT_STARTED_AT":       true,
+	"HAPROXY_DEPLOYMENT_TARGET_INSTANCES": true,
+	"HAPROXY_DEPLOYMENT_NEW_INSTANCES":    true,
+}
+
+// Per port settings understood by Marathon-LB.  Keys with one of the template prefixes override the
+// templates of the generated configuration
+var portKeys = map[string]bool{
+	"VHOST": true, "GROUP": true, "PORT": true, "MODE": true, "BIND_ADDR": true, "BIND_OPTIONS": true,
+	"PATH": true, "ENABLED": true, "STICKY": true, "REDIRECT_TO_HTTPS": true, "USE_HSTS": true,
+	"SSL_CERT": true, "BALANCE": true, "AUTH": true, "BACKEND_WEIGHT": true,
+	"HTTP_BACKEND_PROXYPASS_PATH": true, "HTTP_BACKEND_REVMAP_PATH": true, "HTTP_BACKEND_PROXYPASS_HOSTNAME": true,
+}
+
+var templatePrefixes = []string{"FRONTEND_", "BACKEND_", "HTTP_FRONTEND_", "HTTPS_FRONTEND_", "HTTP_BACKEND_", "TCP_BACKEND_", "USERLIST_"}
+
+var boolKeys = []string{"ENABLED", "STICKY", "REDIRECT_TO_HTTPS", "USE_HSTS"}
+
+// Frontend is the frontend and backend Marathon-LB generates for a port of an application
+type Frontend struct {
+	Index       int      `json:"index"`
+	Backend     string   `json:"backend"`
+	ServicePort int      `json:"servicePort"`
+	Port        int      `json:"port"`
+	BindAddr    string   `json:"bindAddr"`
+	Mode        string   `json:"mode"`
+	VHosts      []string `json:"vhosts,omitempty"`
+	Path        string   `json:"path,omitempty"`
+	Groups      []string `json:"groups"`
+}
+
+// LabelIssue is a problem with the HAPROXY_* labels of an application
+type LabelIssue struct {
+	Level   string `json:"level"`
+	Label   string `json:"label"`
+	Message string `json:"message"`
+}
+
+// ServicePorts returns the service ports of {app}.  Ports assigned by Marathon are preferred over the ports
+// requested within descriptors
+func ServicePorts(app *marathon.Application) []int {
+	if len(app.ServicePorts) > 0 {
+		return app.ServicePorts
+	}
+	if app.Container != nil && app.Container.Docker != nil && len(app.Container.Docker.PortMappings) > 0 {
+		ports := []int{}
+		for _, pm := range app.Container.Docker.PortMappings {
+			ports = append(ports, pm.ServicePort)
+		}
+		return ports
+	}
+	return app.Ports
+}
+
+// BackendName returns the name of the frontend and backend Marathon-LB generates for {app} on {port}.  Apps
+// of a blue/green deployment group share the backend named after the group
+func BackendName(app *marathon.Application, port int) string {
+	name := strings.Replace(strings.TrimPrefix(app.ID, "/"), "/", "_", -1)
+	if group := app.Labels[LabelDeploymentGroup]; group != "" {
+		name = group
+	}
+	return fmt.Sprintf("%s_%d", name, port)
+}
+
+// Frontends returns the frontends Marathon-LB generates for the ports of {app} which have not been disabled
+func Frontends(app *marathon.Application) []*Frontend {
+	frontends := []*Frontend{}
+	for i, servicePort := range ServicePorts(app) {
+		label := func(key string) string {
+			return app.Labels[fmt.Sprintf("HAPROXY_%d_%s", i, key)]
+		}
+		if strings.EqualFold(label("ENABLED"), "false") {
+			continue
+		}
+
+		f := &Frontend{Index: i, ServicePort: servicePort, Port: servicePort, BindAddr: "*", Mode: ModeTCP}
+		if p, err := strconv.Atoi(label("PORT")); err == nil {
+			f.Port = p
+		}
+		if addr := label("BIND_ADDR"); addr != "" {
+			f.BindAddr = addr
+		}
+		f.VHosts = splitList(label("VHOST"))
+		if len(f.VHosts) > 0 {
+			f.Mode = ModeHTTP
+		}
+		if mode := label("MODE"); mode != "" {
+			f.Mode = strings.ToLower(mode)
+		}
+		f.Path = label("PATH")
+		f.Groups = splitList(app.Labels[LabelGroup])
+		if groups := label("GROUP"); groups != "" {
+			f.Groups = splitList(groups)
+		}
+		f.Backend = BackendName(app, f.Port)
+		frontends = append(frontends, f)
+	}
+	return frontends
+}
+
+// ValidateLabels checks the HAPROXY_* labels of {app} returning the issues found.  If {group} is not empty
+// frontends which are not exposed by Marathon-LB instances of the group are reported
+func ValidateLabels(app *marathon.Application, group string) []*LabelIssue {
+	issues := []*LabelIssue{}
+	add := func(level, label, format string, args ...interface{}) {
+		issues = append(issues, &LabelIssue{Level: level, Label: label, Message: fmt.Sprintf(format, args...)})
+	}
+
+	ports := ServicePorts(app)
+	names := []string{}
+	for name := range app.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, "HAPROXY_") || appLabels[name] {
+			continue
+		}
+		m := portLabel.FindStringSubmatch(name)
+		if m == nil {
+			add(IssueWarning, name, "is not a label Marathon-LB recognizes")
+			continue
+		}
+		index, _ := strconv.Atoi(m[1])
+		key, value := m[2], app.Labels[name]
+		if index >= len(ports) {
+			add(IssueError, name, "refers to port index %d but the app defines %d port(s)", index, len(ports))
+			continue
+		}
+		switch {
+		case key == "MODE" && value != ModeHTTP && value != ModeTCP:
+			add(IssueError, name, "'%s' is not a valid mode (http | tcp)", value)
+		case key == "PORT":
+			if p, err := strconv.Atoi(value); err != nil || p < 1 || p > 65535 {
+				add(IssueError, name, "'%s' is not a valid port", value)
+			}
+		case isBoolKey(key) && !strings.EqualFold(value, "true") && !strings.EqualFold(value, "false"):
+			add(IssueError, name, "'%s' is not a boolean (true | false)", value)
+		case !portKeys[key] && !isTemplateKey(key):
+			add(IssueWarning, name, "'%s' is not a setting Marathon-LB recognizes", key)
+		}
+	}
+
+	bound := map[string]string{}
+	for _, f := range Frontends(app) {
+		prefix := fmt.Sprintf("HAPROXY_%d_", f.Index)
+		if f.Port == 0 {
+			add(IssueWarning, prefix+"PORT", "service port %d is assigned by Marathon so the frontend port is not known until deployed", f.Index)
+		} else {
+			bind := fmt.Sprintf("%s:%d", f.BindAddr, f.Port)
+			if other, ok := bound[bind]; ok {
+				add(IssueError, prefix+"PORT", "frontend %s binds %s which is already bound by %s", f.Backend, bind, other)
+			}
+			bound[bind] = f.Backend
+		}
+		if f.Mode == ModeTCP && len(f.VHosts) > 0 {
+			add(IssueWarning, prefix+"VHOST", "virtual hosts require http mode and are ignored in tcp mode")
+		}
+		if f.Path != "" && len(f.VHosts) == 0 {
+			add(IssueWarning, prefix+"PATH", "path based routing requires %sVHOST", prefix)
+		}
+		switch {
+		case group == "" || exposedTo(f.Groups, group):
+		case len(f.Groups) == 0:
+			add(IssueWarning, prefix+"GROUP", "frontend %s has no %s so it is not exposed by Marathon-LB instances of group '%s'", f.Backend, LabelGroup, group)
+		default:
+			add(IssueWarning, prefix+"GROUP", "frontend %s is not exposed by Marathon-LB instances of group '%s' (groups: %s)", f.Backend, group, strings.Join(f.Groups, ","))
+		}
+	}
+	return issues
+}
+
+// HasErrors returns true if any of the {issues} are errors
+func HasErrors(issues []*LabelIssue) bool {
+	for _, i := range issues {
+		if i.Level == IssueError {
+			return true
+		}
+	}
+	return false
+}
+
+func exposedTo(groups []string, group string) bool {
+	for _, g := range groups {
+		if g == group || g == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func isBoolKey(key string) bool {
+	for _, k := range boolKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func isTemplateKey(key string) bool {
+	for _, p := range templatePrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	results := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			results = append(results, v)
+		}
+	}
+	return results
+}
@@ -0,0 +1,243 @@
+// Client for the admin endpoints of Marathon-LB (HAProxy stats, pids and reload signals)
+package marathonlb
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	StatsPath  = "/haproxy?stats;csv"
+	PidsPath   = "/_haproxy_getpids"
+	ConfigPath = "/_haproxy_getconfig"
+	ReloadPath = "/_mlb_signal/hup"
+
+	// Default admin port of Marathon-LB
+	DefaultPort = 9090
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.marathonlb")
+
+var (
+	ErrorNoInstances = errors.New("No Marathon-LB instances could be resolved from the URL")
+)
+
+type MarathonLB interface {
+
+	// Returns the admin URLs of the Marathon-LB instances behind the configured URL
+	Instances() []string
+
+	// Returns the HAProxy stats of every frontend, backend and server of each instance
+	Stats() ([]*Stat, error)
+
+	// Returns the stats of the servers within the backends HAProxy generates for {app}
+	AppBackends(app *marathon.Application) ([]*Stat, error)
+
+	// Returns the pids of HAProxy for each instance.  More than one pid means a reload is still draining
+	Pids() (map[string][]string, error)
+
+	// Returns the generated HAProxy configuration of the first instance
+	Config() (string, error)
+
+	// Signals each instance to regenerate the HAProxy configuration from Marathon and reload
+	Reload() error
+}
+
+// Stat is a row of the HAProxy stats of a single instance
+type Stat struct {
+	Instance string `json:"instance"`
+	Proxy    string `json:"proxy"`
+	Server   string `json:"server"`
+	Status   string `json:"status"`
+	Sessions int    `json:"sessions"`
+	Queued   int    `json:"queued"`
+	Weight   int    `json:"weight"`
+}
+
+type LBClient struct {
+	http      *httpclient.HttpClient
+	instances []string
+}
+
+// NewMarathonLBClient creates a client for the Marathon-LB admin URL {uri} (eg. http://lb:9090).  When the host
+// resolves to multiple addresses each address is treated as an instance
+func NewMarathonLBClient(uri string, config *httpclient.HttpClientConfig) (MarathonLB, error) {
+	instances, err := Instances(uri)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = httpclient.NewDefaultConfig()
+	}
+	c := new(LBClient)
+	c.http = httpclient.NewHttpClient(*config)
+	c.instances = instances
+	return c, nil
+}
+
+// Instances resolves the host of {uri} returning a URL for each of its addresses.  If the host can't be
+// resolved {uri} is returned as is
+func Instances(uri string) ([]string, error) {
+	u, err := url.Parse(strings.TrimRight(uri, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, ErrorNoInstances
+	}
+	host, port := u.Host, strconv.Itoa(DefaultPort)
+	if h, p, err := net.SplitHostPort(u.Host); err == nil {
+		host, port = h, p
+	}
+
+	ips, err := net.LookupIP(host)
+	if err != nil || len(ips) == 0 {
+		log.Debug("Lookup IP failed for: %s, error: %v", host, err)
+		return []string{u.String()}, nil
+	}
+
+	results := []string{}
+	for _, ip := range ips {
+		u.Host = net.JoinHostPort(ip.String(), port)
+		results = append(results, u.String())
+	}
+	return results, nil
+}
+
+func (c *LBClient) Instances() []string {
+	return c.instances
+}
+
+func (c *LBClient) Stats() ([]*Stat, error) {
+	stats := []*Stat{}
+	for _, instance := range c.instances {
+		resp := c.http.HttpGet(instance+StatsPath, nil)
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s: %s", instance, resp.Err().Error())
+		}
+		parsed, err := ParseStats(instance, resp.Content)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, parsed...)
+	}
+	return stats, nil
+}
+
+func (c *LBClient) AppBackends(app *marathon.Application) ([]*Stat, error) {
+	stats, err := c.Stats()
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, f := range Frontends(app) {
+		names[f.Backend] = true
+	}
+	results := []*Stat{}
+	for _, s := range stats {
+		if names[s.Proxy] && s.IsServer() {
+			results = append(results, s)
+		}
+	}
+	return results, nil
+}
+
+func (c *LBClient) Pids() (map[string][]string, error) {
+	pids := map[string][]string{}
+	for _, instance := range c.instances {
+		resp := c.http.HttpGet(instance+PidsPath, nil)
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%s: %s", instance, resp.Err().Error())
+		}
+		pids[instance] = strings.Fields(resp.Content)
+	}
+	return pids, nil
+}
+
+func (c *LBClient) Config() (string, error) {
+	resp := c.http.HttpGet(c.instances[0]+ConfigPath, nil)
+	if resp.Error != nil {
+		return "", resp.Err()
+	}
+	return resp.Content, nil
+}
+
+func (c *LBClient) Reload() error {
+	for _, instance := range c.instances {
+		resp := c.http.HttpGet(instance+ReloadPath, nil)
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s", instance, resp.Err().Error())
+		}
+		log.Info("Reload of %s was signaled", instance)
+	}
+	return nil
+}
+
+// ParseStats parses the HAProxy stats CSV {data} of {instance}
+func ParseStats(instance, data string) ([]*Stat, error) {
+	stats := []*Stat{}
+	hmap := map[string]int{}
+
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = -1
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+		if strings.HasPrefix(row[0], "#") {
+			row[0] = strings.TrimSpace(strings.TrimPrefix(row[0], "#"))
+			for i, h := range row {
+				hmap[h] = i
+			}
+			continue
+		}
+
+		col := func(name string) string {
+			if i, ok := hmap[name]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		stats = append(stats, &Stat{
+			Instance: instance,
+			Proxy:    col("pxname"),
+			Server:   col("svname"),
+			Status:   col("status"),
+			Sessions: intOrZero(col("scur")),
+			Queued:   intOrZero(col("qcur")),
+			Weight:   intOrZero(col("weight")),
+		})
+	}
+	return stats, nil
+}
+
+// IsServer returns true if the stat is of a server rather than the frontend or backend summary
+func (s *Stat) IsServer() bool {
+	return s.Server != "BACKEND" && s.Server != "FRONTEND"
+}
+
+func intOrZero(s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return i
+}
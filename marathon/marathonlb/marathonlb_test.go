@@ -0,0 +1,110 @@
+package marathonlb
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+const statsCSV = `# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight
+web_10000,FRONTEND,,,2,5,,10,,,,,,,,,,OPEN,
+web_10000,10_0_0_1_31001,0,0,1,3,,5,,,,,,,,,,UP,1
+web_10000,10_0_0_2_31002,1,1,1,2,,5,,,,,,,,,,MAINT,1
+web_10000,BACKEND,1,1,2,5,,10,,,,,,,,,,UP,2
+api_10001,10_0_0_3_31003,0,0,0,0,,0,,,,,,,,,,DOWN,1
+`
+
+func TestParseStats(t *testing.T) {
+	stats, err := ParseStats("http://lb:9090", statsCSV)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, len(stats))
+	assert.Equal(t, &Stat{Instance: "http://lb:9090", Proxy: "web_10000", Server: "10_0_0_2_31002", Status: "MAINT", Sessions: 1, Queued: 1, Weight: 1}, stats[2])
+	assert.False(t, stats[0].IsServer())
+	assert.True(t, stats[1].IsServer())
+}
+
+func TestAppBackendsAndReload(t *testing.T) {
+	paths := []string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		if r.URL.Path == "/haproxy" {
+			fmt.Fprint(w, statsCSV)
+		}
+	}))
+	defer s.Close()
+
+	c, err := NewMarathonLBClient(s.URL, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{s.URL}, c.Instances())
+
+	stats, err := c.AppBackends(&marathon.Application{ID: "/web", ServicePorts: []int{10000}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, "/haproxy?stats;csv", paths[0])
+
+	assert.Nil(t, c.Reload())
+	assert.Equal(t, ReloadPath, paths[1])
+}
+
+func TestFrontends(t *testing.T) {
+	app := &marathon.Application{
+		ID:    "/prod/web",
+		Ports: []int{10000, 10001, 10002},
+		Labels: map[string]string{
+			"HAPROXY_GROUP":     "external",
+			"HAPROXY_0_VHOST":   "web.example.com, www.example.com",
+			"HAPROXY_1_PORT":    "8443",
+			"HAPROXY_1_GROUP":   "internal",
+			"HAPROXY_2_ENABLED": "false",
+		},
+	}
+	frontends := Frontends(app)
+	assert.Equal(t, 2, len(frontends))
+	assert.Equal(t, &Frontend{Index: 0, Backend: "prod_web_10000", ServicePort: 10000, Port: 10000, BindAddr: "*", Mode: ModeHTTP,
+		VHosts: []string{"web.example.com", "www.example.com"}, Groups: []string{"external"}}, frontends[0])
+	assert.Equal(t, "prod_web_8443", frontends[1].Backend)
+	assert.Equal(t, ModeTCP, frontends[1].Mode)
+	assert.Equal(t, []string{"internal"}, frontends[1].Groups)
+
+	app.Labels[LabelDeploymentGroup] = "web"
+	assert.Equal(t, "web_10000", Frontends(app)[0].Backend)
+}
+
+func TestValidateLabels(t *testing.T) {
+	app := &marathon.Application{
+		ID:    "/web",
+		Ports: []int{10000, 10001},
+		Labels: map[string]string{
+			"HAPROXY_GROUP":            "internal",
+			"HAPROXY_0_MODE":           "tcp",
+			"HAPROXY_0_VHOST":          "web.example.com",
+			"HAPROXY_0_STICKY":         "yes",
+			"HAPROXY_0_FRONTEND_HEAD":  "...",
+			"HAPROXY_1_PORT":           "10000",
+			"HAPROXY_1_VHOSTS":         "typo.example.com",
+			"HAPROXY_2_VHOST":          "missing.example.com",
+			"HAPROXY_REDIRECT_TO_HTTP": "true",
+		},
+	}
+	issues := ValidateLabels(app, "external")
+	assert.True(t, HasErrors(issues))
+
+	byLabel := map[string]string{}
+	for _, i := range issues {
+		byLabel[i.Label] = byLabel[i.Label] + i.Level
+	}
+	assert.Equal(t, IssueError, byLabel["HAPROXY_0_STICKY"])
+	assert.Equal(t, IssueWarning, byLabel["HAPROXY_1_VHOSTS"])
+	assert.Equal(t, IssueError, byLabel["HAPROXY_2_VHOST"])
+	assert.Equal(t, IssueWarning, byLabel["HAPROXY_REDIRECT_TO_HTTP"])
+	assert.Equal(t, IssueWarning, byLabel["HAPROXY_0_VHOST"])
+	assert.Equal(t, IssueError, byLabel["HAPROXY_1_PORT"])
+	assert.Equal(t, IssueWarning, byLabel["HAPROXY_0_GROUP"])
+	assert.Equal(t, "", byLabel["HAPROXY_0_FRONTEND_HEAD"])
+
+	assert.Equal(t, 0, len(ValidateLabels(&marathon.Application{ID: "/web", Ports: []int{10000}, Labels: map[string]string{"HAPROXY_GROUP": "external"}}, "external")))
+}
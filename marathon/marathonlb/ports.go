@@ -0,0 +1,150 @@
+package marathonlb
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+)
+
+const (
+	ConflictServicePort = "servicePort"
+	ConflictFrontend    = "frontend"
+	ConflictReserved    = "reserved"
+)
+
+// PortRange is an inclusive range of ports
+type PortRange struct {
+	From   int    `json:"from"`
+	To     int    `json:"to"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ReservedPorts are the ports Marathon-LB binds itself on every instance
+var ReservedPorts = []PortRange{
+	{From: 80, To: 80, Reason: "Marathon-LB http frontend"},
+	{From: 443, To: 443, Reason: "Marathon-LB https frontend"},
+	{From: 9090, To: 9091, Reason: "Marathon-LB stats and health endpoints"},
+}
+
+// PortConflict is a port of an application which collides with another application or a reserved range
+type PortConflict struct {
+	App     string `json:"app"`
+	Port    int    `json:"port"`
+	Kind    string `json:"kind"`
+	With    string `json:"with"`
+	Message string `json:"message"`
+}
+
+// ParsePortRange parses a port (eg. 8080) or a range of ports (eg. 10000-10100)
+func ParsePortRange(value string) (PortRange, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "-", 2)
+	from, err := strconv.Atoi(parts[0])
+	to := from
+	if err == nil && len(parts) == 2 {
+		to, err = strconv.Atoi(parts[1])
+	}
+	if err != nil || from < 1 || to > 65535 || from > to {
+		return PortRange{}, fmt.Errorf("'%s' is not a valid port or port range (eg. 8080 or 10000-10100)", value)
+	}
+	return PortRange{From: from, To: to, Reason: "reserved range " + value}, nil
+}
+
+// Contains returns true if {port} is within the range
+func (r PortRange) Contains(port int) bool {
+	return port >= r.From && port <= r.To
+}
+
+// CheckPorts returns the service ports and Marathon-LB frontends of {app} which collide with those of the
+// {others} apps or fall within the {reserved} ranges.  Other versions of the app itself (the same id or the
+// same blue/green deployment group) are ignored
+func CheckPorts(app *marathon.Application, others []*marathon.Application, reserved []PortRange) []*PortConflict {
+	conflicts := []*PortConflict{}
+	add := func(port int, kind, with, format string, args ...interface{}) {
+		conflicts = append(conflicts, &PortConflict{App: app.ID, Port: port, Kind: kind, With: with, Message: fmt.Sprintf(format, args...)})
+	}
+
+	frontends := Frontends(app)
+	for _, port := range ServicePorts(app) {
+		for _, r := range reserved {
+			if port != 0 && r.Contains(port) {
+				add(port, ConflictReserved, r.Reason, "servicePort %d is within %s", port, describeRange(r))
+			}
+		}
+	}
+	for _, f := range frontends {
+		for _, r := range reserved {
+			if f.Port != f.ServicePort && f.Port != 0 && r.Contains(f.Port) {
+				add(f.Port, ConflictReserved, r.Reason, "frontend %s binds %d within %s", f.Backend, f.Port, describeRange(r))
+			}
+		}
+	}
+
+	for _, other := range others {
+		if sameApp(app, other) {
+			continue
+		}
+		used := map[int]bool{}
+		for _, port := range ServicePorts(other) {
+			used[port] = true
+		}
+		for _, port := range ServicePorts(app) {
+			if port != 0 && used[port] {
+				add(port, ConflictServicePort, other.ID, "servicePort %d is already used by %s", port, other.ID)
+			}
+		}
+		for _, f := range frontends {
+			for _, o := range Frontends(other) {
+				if f.Port != 0 && f.Port == o.Port && f.ServicePort != o.ServicePort && sharesBind(f, o) && sharesGroup(f.Groups, o.Groups) {
+					add(f.Port, ConflictFrontend, other.ID, "frontend %s binds %d which is already bound by %s", f.Backend, f.Port, o.Backend)
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+// AuditPorts returns the port conflicts between the {apps} of a cluster, reporting each pair of apps once
+func AuditPorts(apps []*marathon.Application, reserved []PortRange) []*PortConflict {
+	sorted := append([]*marathon.Application{}, apps...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	conflicts := []*PortConflict{}
+	for i, app := range sorted {
+		conflicts = append(conflicts, CheckPorts(app, sorted[i+1:], reserved)...)
+	}
+	return conflicts
+}
+
+// Apps with the same id or blue/green deployment group are versions of one application
+func sameApp(a, b *marathon.Application) bool {
+	if a.ID == b.ID {
+		return true
+	}
+	group := a.Labels[LabelDeploymentGroup]
+	return group != "" && group == b.Labels[LabelDeploymentGroup]
+}
+
+func sharesBind(a, b *Frontend) bool {
+	return a.BindAddr == b.BindAddr || a.BindAddr == "*" || b.BindAddr == "*"
+}
+
+// Frontends of apps without a group are not exposed by any Marathon-LB instance
+func sharesGroup(a, b []string) bool {
+	for _, g := range a {
+		if exposedTo(b, g) || (g == "*" && len(b) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func describeRange(r PortRange) string {
+	ports := strconv.Itoa(r.From)
+	if r.To != r.From {
+		ports += "-" + strconv.Itoa(r.To)
+	}
+	return fmt.Sprintf("%s (%s)", ports, r.Reason)
+}
@@ -0,0 +1,73 @@
+package marathonlb
+
+import (
+	"testing"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePortRange(t *testing.T) {
+	r, err := ParsePortRange("10000-10100")
+	assert.Nil(t, err)
+	assert.True(t, r.Contains(10000))
+	assert.True(t, r.Contains(10100))
+	assert.False(t, r.Contains(10101))
+
+	r, err = ParsePortRange("8080")
+	assert.Nil(t, err)
+	assert.Equal(t, 8080, r.To)
+
+	for _, invalid := range []string{"", "http", "100-10", "0", "70000"} {
+		_, err = ParsePortRange(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestCheckPorts(t *testing.T) {
+	others := []*marathon.Application{
+		{ID: "/api", ServicePorts: []int{10000, 10001}, Labels: map[string]string{"HAPROXY_GROUP": "external"}},
+		{ID: "/admin", ServicePorts: []int{10005}, Labels: map[string]string{"HAPROXY_GROUP": "external", "HAPROXY_0_PORT": "8443"}},
+		{ID: "/web-blue", ServicePorts: []int{10010}, Labels: map[string]string{"HAPROXY_DEPLOYMENT_GROUP": "web"}},
+	}
+	app := &marathon.Application{
+		ID:    "/web-green",
+		Ports: []int{10001, 10010, 10020, 9090},
+		Labels: map[string]string{
+			"HAPROXY_GROUP":            "external",
+			"HAPROXY_DEPLOYMENT_GROUP": "web",
+			"HAPROXY_2_PORT":           "8443",
+		},
+	}
+
+	conflicts := CheckPorts(app, others, ReservedPorts)
+	assert.Equal(t, 3, len(conflicts))
+	byKind := map[string]*PortConflict{}
+	for _, c := range conflicts {
+		byKind[c.Kind] = c
+	}
+	assert.Equal(t, 9090, byKind[ConflictReserved].Port)
+	assert.Equal(t, &PortConflict{App: "/web-green", Port: 10001, Kind: ConflictServicePort, With: "/api",
+		Message: "servicePort 10001 is already used by /api"}, byKind[ConflictServicePort])
+	assert.Equal(t, "/admin", byKind[ConflictFrontend].With)
+
+	// the frontend of an app exposed to another group doesn't collide
+	app.Labels["HAPROXY_2_GROUP"] = "internal"
+	app.Ports = []int{10030, 0, 10020}
+	assert.Equal(t, 0, len(CheckPorts(app, others, ReservedPorts)))
+}
+
+func TestAuditPorts(t *testing.T) {
+	apps := []*marathon.Application{
+		{ID: "/b", ServicePorts: []int{10000}},
+		{ID: "/a", ServicePorts: []int{10000, 443}},
+		{ID: "/c", ServicePorts: []int{10002}},
+	}
+	reserved := append(ReservedPorts, PortRange{From: 10002, To: 10002, Reason: "reserved range 10002"})
+	conflicts := AuditPorts(apps, reserved)
+	assert.Equal(t, 3, len(conflicts))
+	assert.Equal(t, "/a", conflicts[0].App)
+	assert.Equal(t, ConflictReserved, conflicts[0].Kind)
+	assert.Equal(t, "/b", conflicts[1].With)
+	assert.Equal(t, "servicePort 10002 is within 10002 (reserved range 10002)", conflicts[2].Message)
+}
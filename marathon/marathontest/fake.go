@@ -0,0 +1,1381 @@
+// An in-memory implementation of marathon.Marathon so programs using the client (including depcon's own
+// commands) can be tested without a live cluster
+package marathontest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/encoding"
+)
+
+// versions are assigned from this time advancing a second per change so results are repeatable
+var epoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Fake is a Marathon cluster held in memory.  Deployments complete as soon as they're started so
+// applications are immediately running with all their instances healthy.  Applications, tasks and
+// deployments may be seeded directly (eg. a deployment in progress which WaitForDeployment times out on)
+type Fake struct {
+	sync.Mutex
+	// Applications keyed by their absolute id (eg. /web/api)
+	Apps map[string]*marathon.Application
+	// Identifiers of the groups that were created
+	Groups map[string]bool
+	Tasks  []*marathon.Task
+	// Deployments in progress
+	Deployments []*marathon.Deploy
+	Queue       *marathon.Queue
+	// Pods keyed by their absolute id
+	Pods map[string]*marathon.Pod
+	// Content of the artifacts keyed by their path (eg. /credentials/docker.tar.gz)
+	Artifacts map[string][]byte
+	Info      *marathon.MarathonInfo
+	// Errors returned by the named methods (eg. CreateApplication) in place of their result.  Methods with
+	// a Ctx suffix share the error of the method without it
+	Errors map[string]error
+	// Methods invoked in order
+	Calls []string
+
+	versions    map[string][]string
+	podVersions map[string][]string
+	listeners   map[marathon.EventsChannel]int
+	streams     []*eventStream
+	seq         int
+}
+
+type eventStream struct {
+	ctx    context.Context
+	filter int
+	events chan *marathon.Event
+}
+
+var _ marathon.Marathon = &Fake{}
+
+// New returns an empty cluster
+func New() *Fake {
+	f := &Fake{
+		Apps:        map[string]*marathon.Application{},
+		Groups:      map[string]bool{},
+		Queue:       &marathon.Queue{Queue: []marathon.QueuedTask{}},
+		Info:        &marathon.MarathonInfo{Name: "marathon", Version: "1.5.0", Leader: "localhost:8080"},
+		Errors:      map[string]error{},
+		Pods:        map[string]*marathon.Pod{},
+		Artifacts:   map[string][]byte{},
+		versions:    map[string][]string{},
+		podVersions: map[string][]string{},
+		listeners:   map[marathon.EventsChannel]int{},
+	}
+	return f
+}
+
+// WithApps adds {apps} to the cluster as if they had been created
+func (f *Fake) WithApps(apps ...*marathon.Application) *Fake {
+	f.Lock()
+	defer f.Unlock()
+	for _, app := range apps {
+		f.putApp(app)
+	}
+	return f
+}
+
+// Called reports whether {method} was invoked
+func (f *Fake) Called(method string) bool {
+	f.Lock()
+	defer f.Unlock()
+	for _, c := range f.Calls {
+		if c == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Records the call of {method} returning the error of {ctx} or the error configured for the method
+func (f *Fake) call(ctx context.Context, method string) error {
+	f.Calls = append(f.Calls, method)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.Errors[method]
+}
+
+func notFound(kind, id string) error {
+	return marathon.NewError(marathon.CodeNotFound, 404, fmt.Errorf("%s '%s' does not exist", kind, id))
+}
+
+// Returns {id} as an absolute id resolving relative ids against {parent}
+func absID(parent, id string) string {
+	if !strings.HasPrefix(id, "/") {
+		id = parent + "/" + id
+	}
+	return path.Clean("/" + id)
+}
+
+func (f *Fake) nextVersion() string {
+	f.seq++
+	return f.versionAt(f.seq)
+}
+
+func (f *Fake) deployment() *marathon.DeploymentID {
+	return &marathon.DeploymentID{DeploymentID: fmt.Sprintf("deployment-%d", f.seq), Version: f.versionAt(f.seq)}
+}
+
+func (f *Fake) versionAt(seq int) string {
+	return epoch.Add(time.Duration(seq) * time.Second).Format("2006-01-02T15:04:05.000Z")
+}
+
+// Stores a copy of {app} as a new version running all of its instances
+func (f *Fake) putApp(app *marathon.Application) *marathon.Application {
+	stored := copyApp(app)
+	stored.ID = absID("", stored.ID)
+	stored.Version = f.nextVersion()
+	stored.TasksRunning = stored.Instances
+	stored.TasksStaged = 0
+	stored.TasksUnHealthy = 0
+	stored.TasksHealthy = 0
+	if len(stored.HealthChecks) > 0 {
+		stored.TasksHealthy = stored.Instances
+	}
+	f.Apps[stored.ID] = stored
+	f.versions[stored.ID] = append([]string{stored.Version}, f.versions[stored.ID]...)
+	f.syncTasks(stored)
+	return copyApp(stored)
+}
+
+// Starts or kills tasks so {app} has a task per instance
+func (f *Fake) syncTasks(app *marathon.Application) {
+	tasks := []*marathon.Task{}
+	running := 0
+	for _, t := range f.Tasks {
+		if t.AppID != app.ID {
+			tasks = append(tasks, t)
+		} else if running < app.Instances {
+			tasks = append(tasks, t)
+			running++
+		}
+	}
+	name := strings.Replace(strings.TrimPrefix(app.ID, "/"), "/", "_", -1)
+	for ; running < app.Instances; running++ {
+		tasks = append(tasks, &marathon.Task{
+			AppID:     app.ID,
+			Host:      "localhost",
+			ID:        fmt.Sprintf("%s.%d-%d", name, f.seq, running),
+			StagedAt:  app.Version,
+			StartedAt: app.Version,
+			Version:   app.Version,
+		})
+	}
+	f.Tasks = tasks
+}
+
+func (f *Fake) removeApp(id string) {
+	delete(f.Apps, id)
+	delete(f.versions, id)
+	tasks := []*marathon.Task{}
+	for _, t := range f.Tasks {
+		if t.AppID != id {
+			tasks = append(tasks, t)
+		}
+	}
+	f.Tasks = tasks
+}
+
+func copyApp(app *marathon.Application) *marathon.Application {
+	c := new(marathon.Application)
+	b, _ := json.Marshal(app)
+	json.Unmarshal(b, c)
+	return c
+}
+
+/** Application API */
+
+func (f *Fake) CreateApplicationFromFile(filename string, opts *marathon.CreateOptions) (*marathon.Application, error) {
+	return f.CreateApplicationFromFileCtx(context.Background(), filename, opts)
+}
+
+func (f *Fake) CreateApplicationFromFileCtx(ctx context.Context, filename string, opts *marathon.CreateOptions) (*marathon.Application, error) {
+	app, err := f.ParseApplicationFromFile(filename, opts)
+	if err != nil {
+		return app, err
+	}
+	return f.createParsedApplication(ctx, app, opts)
+}
+
+func (f *Fake) CreateApplicationFromString(filename string, appstr string, opts *marathon.CreateOptions) (*marathon.Application, error) {
+	return f.CreateApplicationFromStringCtx(context.Background(), filename, appstr, opts)
+}
+
+func (f *Fake) CreateApplicationFromStringCtx(ctx context.Context, filename string, appstr string, opts *marathon.CreateOptions) (*marathon.Application, error) {
+	et, err := encoding.EncoderTypeFromExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	app, err := (&marathon.MarathonClient{}).ParseApplicationFromString(strings.NewReader(appstr), et, opts)
+	if err != nil {
+		return app, err
+	}
+	return f.createParsedApplication(ctx, app, opts)
+}
+
+func (f *Fake) createParsedApplication(ctx context.Context, app *marathon.Application, opts *marathon.CreateOptions) (*marathon.Application, error) {
+	if opts.Validate != nil {
+		if err := opts.Validate(app); err != nil {
+			return nil, err
+		}
+	}
+	if opts.StopDeploy {
+		f.CancelAppDeploymentCtx(ctx, app.ID, false)
+	}
+	return f.CreateApplicationCtx(ctx, app, opts.Wait, opts.Force)
+}
+
+func (f *Fake) ParseApplicationFromFile(filename string, opts *marathon.CreateOptions) (*marathon.Application, error) {
+	return (&marathon.MarathonClient{}).ParseApplicationFromFile(filename, opts)
+}
+
+func (f *Fake) CreateApplication(app *marathon.Application, wait, force bool) (*marathon.Application, error) {
+	return f.CreateApplicationCtx(context.Background(), app, wait, force)
+}
+
+func (f *Fake) CreateApplicationCtx(ctx context.Context, app *marathon.Application, wait, force bool) (*marathon.Application, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "CreateApplication"); err != nil {
+		return nil, err
+	}
+	if err := app.Validate(); err != nil {
+		return nil, err
+	}
+	if _, ok := f.Apps[absID("", app.ID)]; ok && !force {
+		return nil, marathon.NewError(marathon.CodeConflict, 409, marathon.ErrorAppExists)
+	}
+	return f.putApp(app), nil
+}
+
+func (f *Fake) UpdateApplication(app *marathon.Application, wait bool) (*marathon.Application, error) {
+	return f.UpdateApplicationCtx(context.Background(), app, wait)
+}
+
+func (f *Fake) UpdateApplicationCtx(ctx context.Context, app *marathon.Application, wait bool) (*marathon.Application, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "UpdateApplication"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.Apps[absID("", app.ID)]; !ok {
+		return nil, marathon.NewError(marathon.CodeNotFound, 422, marathon.ErrorNoAppExists)
+	}
+	return f.putApp(app), nil
+}
+
+func (f *Fake) UpdateApplicationPartial(id string, patch map[string]interface{}, opts *marathon.PatchOptions) (*marathon.Application, error) {
+	return f.UpdateApplicationPartialCtx(context.Background(), id, patch, opts)
+}
+
+func (f *Fake) UpdateApplicationPartialCtx(ctx context.Context, id string, patch map[string]interface{}, opts *marathon.PatchOptions) (*marathon.Application, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "UpdateApplicationPartial"); err != nil {
+		return nil, err
+	}
+	app, ok := f.Apps[absID("", id)]
+	if !ok {
+		return nil, notFound("App", id)
+	}
+	live := map[string]interface{}{}
+	b, _ := json.Marshal(app)
+	json.Unmarshal(b, &live)
+	if opts != nil {
+		if err := marathon.CheckVersion(live, opts.Version); err != nil {
+			return nil, err
+		}
+	}
+	patched := new(marathon.Application)
+	b, _ = json.Marshal(marathon.MergePatch(live, patch))
+	if err := json.Unmarshal(b, patched); err != nil {
+		return nil, marathon.NewError(marathon.CodeValidation, 422, err)
+	}
+	patched.ID = app.ID
+	return f.putApp(patched), nil
+}
+
+func (f *Fake) ListApplications() (*marathon.Applications, error) {
+	return f.ListApplicationsCtx(context.Background())
+}
+
+func (f *Fake) ListApplicationsCtx(ctx context.Context) (*marathon.Applications, error) {
+	return f.listApplications(ctx, "ListApplications", "")
+}
+
+func (f *Fake) ListApplicationsWithFilters(filter string) (*marathon.Applications, error) {
+	return f.ListApplicationsWithFiltersCtx(context.Background(), filter)
+}
+
+// Filters are matched as Marathon does for the id (a substring of the id) and label (label=key or
+// label=key==value) filters.  Other filters match every application
+func (f *Fake) ListApplicationsWithFiltersCtx(ctx context.Context, filter string) (*marathon.Applications, error) {
+	return f.listApplications(ctx, "ListApplicationsWithFilters", filter)
+}
+
+func (f *Fake) listApplications(ctx context.Context, method, filter string) (*marathon.Applications, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, method); err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for id := range f.Apps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	apps := &marathon.Applications{Apps: []marathon.Application{}}
+	for _, id := range ids {
+		if matchesFilter(f.Apps[id], filter) {
+			apps.Apps = append(apps.Apps, *copyApp(f.Apps[id]))
+		}
+	}
+	return apps, nil
+}
+
+func matchesFilter(app *marathon.Application, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	if !strings.Contains(filter, "=") {
+		filter = "id=" + filter
+	}
+	for _, param := range strings.Split(filter, "&") {
+		kv := strings.SplitN(param, "=", 2)
+		switch kv[0] {
+		case "id":
+			if !strings.Contains(app.ID, kv[1]) {
+				return false
+			}
+		case "label":
+			label := strings.SplitN(kv[1], "==", 2)
+			value, ok := app.Labels[label[0]]
+			if !ok || (len(label) == 2 && value != label[1]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (f *Fake) ListApplicationsStream(filter string, fn func(app *marathon.Application) error) error {
+	return f.ListApplicationsStreamCtx(context.Background(), filter, fn)
+}
+
+func (f *Fake) ListApplicationsStreamCtx(ctx context.Context, filter string, fn func(app *marathon.Application) error) error {
+	apps, err := f.listApplications(ctx, "ListApplicationsStream", filter)
+	if err != nil {
+		return err
+	}
+	for i := range apps.Apps {
+		if err := fn(&apps.Apps[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fake) ListApplicationsByGroup(groupID string, fn func(app *marathon.Application) error) error {
+	return f.ListApplicationsByGroupCtx(context.Background(), groupID, fn)
+}
+
+func (f *Fake) ListApplicationsByGroupCtx(ctx context.Context, groupID string, fn func(app *marathon.Application) error) error {
+	apps, err := f.listApplications(ctx, "ListApplicationsByGroup", "")
+	if err != nil {
+		return err
+	}
+	prefix := strings.TrimSuffix(absID("", groupID), "/") + "/"
+	for i := range apps.Apps {
+		if !strings.HasPrefix(apps.Apps[i].ID, prefix) {
+			continue
+		}
+		if err := fn(&apps.Apps[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fake) GetApplication(id string) (*marathon.Application, error) {
+	return f.GetApplicationCtx(context.Background(), id)
+}
+
+func (f *Fake) GetApplicationCtx(ctx context.Context, id string) (*marathon.Application, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "GetApplication"); err != nil {
+		return nil, err
+	}
+	app, ok := f.Apps[absID("", id)]
+	if !ok {
+		return nil, notFound("App", id)
+	}
+	return copyApp(app), nil
+}
+
+func (f *Fake) GetApplicationDefinition(id string) (map[string]interface{}, error) {
+	return f.GetApplicationDefinitionCtx(context.Background(), id)
+}
+
+// The definition holds the fields of the stored Application as the fake only keeps those it models
+func (f *Fake) GetApplicationDefinitionCtx(ctx context.Context, id string) (map[string]interface{}, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "GetApplicationDefinition"); err != nil {
+		return nil, err
+	}
+	app, ok := f.Apps[absID("", id)]
+	if !ok {
+		return nil, notFound("App", id)
+	}
+	definition := map[string]interface{}{}
+	b, _ := json.Marshal(app)
+	json.Unmarshal(b, &definition)
+	return definition, nil
+}
+
+func (f *Fake) HasApplication(id string) (bool, error) {
+	return f.HasApplicationCtx(context.Background(), id)
+}
+
+func (f *Fake) HasApplicationCtx(ctx context.Context, id string) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "HasApplication"); err != nil {
+		return false, err
+	}
+	_, ok := f.Apps[absID("", id)]
+	return ok, nil
+}
+
+func (f *Fake) DestroyApplication(id string) (*marathon.DeploymentID, error) {
+	return f.DestroyApplicationCtx(context.Background(), id)
+}
+
+func (f *Fake) DestroyApplicationCtx(ctx context.Context, id string) (*marathon.DeploymentID, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "DestroyApplication"); err != nil {
+		return nil, err
+	}
+	id = absID("", id)
+	if _, ok := f.Apps[id]; !ok {
+		return nil, notFound("App", id)
+	}
+	f.removeApp(id)
+	f.nextVersion()
+	return f.deployment(), nil
+}
+
+func (f *Fake) RestartApplication(id string, force bool) (*marathon.DeploymentID, error) {
+	return f.RestartApplicationCtx(context.Background(), id, force)
+}
+
+// Restarts replace every task of the application with a new one
+func (f *Fake) RestartApplicationCtx(ctx context.Context, id string, force bool) (*marathon.DeploymentID, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "RestartApplication"); err != nil {
+		return nil, err
+	}
+	app, ok := f.Apps[absID("", id)]
+	if !ok {
+		return nil, notFound("App", id)
+	}
+	f.removeTasks(func(t *marathon.Task) bool { return t.AppID == app.ID })
+	f.putApp(app)
+	return f.deployment(), nil
+}
+
+func (f *Fake) ScaleApplication(id string, instances int) (*marathon.DeploymentID, error) {
+	return f.ScaleApplicationCtx(context.Background(), id, instances)
+}
+
+func (f *Fake) ScaleApplicationCtx(ctx context.Context, id string, instances int) (*marathon.DeploymentID, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "ScaleApplication"); err != nil {
+		return nil, err
+	}
+	app, ok := f.Apps[absID("", id)]
+	if !ok {
+		return nil, notFound("App", id)
+	}
+	scaled := copyApp(app)
+	scaled.Instances = instances
+	f.putApp(scaled)
+	return f.deployment(), nil
+}
+
+func (f *Fake) ListVersions(id string) (*marathon.Versions, error) {
+	return f.ListVersionsCtx(context.Background(), id)
+}
+
+func (f *Fake) ListVersionsCtx(ctx context.Context, id string) (*marathon.Versions, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "ListVersions"); err != nil {
+		return nil, err
+	}
+	versions, ok := f.versions[absID("", id)]
+	if !ok {
+		return nil, notFound("App", id)
+	}
+	return &marathon.Versions{Versions: append([]string{}, versions...)}, nil
+}
+
+func (f *Fake) WaitForApplication(id string, timeout time.Duration) error {
+	return f.WaitForApplicationCtx(context.Background(), id, timeout)
+}
+
+func (f *Fake) WaitForApplicationCtx(ctx context.Context, id string, timeout time.Duration) error {
+	return f.waitForApplication(ctx, "WaitForApplication", id)
+}
+
+func (f *Fake) WaitForApplicationHealthy(id string, timeout time.Duration) error {
+	return f.WaitForApplicationHealthyCtx(context.Background(), id, timeout)
+}
+
+func (f *Fake) WaitForApplicationHealthyCtx(ctx context.Context, id string, timeout time.Duration) error {
+	return f.waitForApplication(ctx, "WaitForApplicationHealthy", id)
+}
+
+func (f *Fake) WaitForHealthyTasks(id string, quorum marathon.Quorum, timeout time.Duration) error {
+	return f.WaitForHealthyTasksCtx(context.Background(), id, quorum, timeout)
+}
+
+func (f *Fake) WaitForHealthyTasksCtx(ctx context.Context, id string, quorum marathon.Quorum, timeout time.Duration) error {
+	return f.waitForApplication(ctx, "WaitForHealthyTasks", id)
+}
+
+func (f *Fake) waitForApplication(ctx context.Context, method, id string) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, method); err != nil {
+		return err
+	}
+	if _, ok := f.Apps[absID("", id)]; !ok {
+		return notFound("App", id)
+	}
+	return nil
+}
+
+/** Deployment API */
+
+func (f *Fake) HasDeployment(id string) (bool, error) {
+	return f.HasDeploymentCtx(context.Background(), id)
+}
+
+func (f *Fake) HasDeploymentCtx(ctx context.Context, id string) (bool, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "HasDeployment"); err != nil {
+		return false, err
+	}
+	return f.findDeployment(id) >= 0, nil
+}
+
+func (f *Fake) findDeployment(id string) int {
+	for i, d := range f.Deployments {
+		if d.DeployID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (f *Fake) ListDeployments() ([]*marathon.Deploy, error) {
+	return f.ListDeploymentsCtx(context.Background())
+}
+
+func (f *Fake) ListDeploymentsCtx(ctx context.Context) ([]*marathon.Deploy, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "ListDeployments"); err != nil {
+		return nil, err
+	}
+	return append([]*marathon.Deploy{}, f.Deployments...), nil
+}
+
+func (f *Fake) DeleteDeployment(id string, force bool) (*marathon.DeploymentID, error) {
+	return f.DeleteDeploymentCtx(context.Background(), id, force)
+}
+
+func (f *Fake) DeleteDeploymentCtx(ctx context.Context, id string, force bool) (*marathon.DeploymentID, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "DeleteDeployment"); err != nil {
+		return nil, err
+	}
+	return f.deleteDeployment(id)
+}
+
+func (f *Fake) deleteDeployment(id string) (*marathon.DeploymentID, error) {
+	i := f.findDeployment(id)
+	if i < 0 {
+		return nil, marathon.NewError(marathon.CodeNotFound, 404, fmt.Errorf("Deployment '%s' was not found", id))
+	}
+	f.Deployments = append(f.Deployments[:i], f.Deployments[i+1:]...)
+	f.nextVersion()
+	return f.deployment(), nil
+}
+
+func (f *Fake) CancelAppDeployment(appId string, matchPrefix bool) (*marathon.DeploymentID, error) {
+	return f.CancelAppDeploymentCtx(context.Background(), appId, matchPrefix)
+}
+
+func (f *Fake) CancelAppDeploymentCtx(ctx context.Context, appId string, matchPrefix bool) (*marathon.DeploymentID, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "CancelAppDeployment"); err != nil {
+		return nil, err
+	}
+	for _, d := range f.Deployments {
+		for _, id := range d.AffectedApps {
+			if id == appId || (matchPrefix && strings.HasPrefix(id, appId)) {
+				return f.deleteDeployment(d.DeployID)
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (f *Fake) WaitForDeployment(id string, timeout time.Duration) error {
+	return f.WaitForDeploymentCtx(context.Background(), id, timeout)
+}
+
+// Deployments complete immediately so waiting times out only for the deployments in progress
+func (f *Fake) WaitForDeploymentCtx(ctx context.Context, id string, timeout time.Duration) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "WaitForDeployment"); err != nil {
+		return err
+	}
+	if f.findDeployment(id) >= 0 {
+		return marathon.ErrorTimeout
+	}
+	return nil
+}
+
+func (f *Fake) WaitForDeploymentResult(id string, timeout time.Duration) *marathon.WaitResult {
+	return f.WaitForDeploymentResultCtx(context.Background(), id, timeout)
+}
+
+// Deployments in progress time out.  Errors configured for WaitForDeploymentResult fail the wait
+func (f *Fake) WaitForDeploymentResultCtx(ctx context.Context, id string, timeout time.Duration) *marathon.WaitResult {
+	f.Lock()
+	defer f.Unlock()
+	result := &marathon.WaitResult{DeploymentID: id, State: marathon.WaitConverged}
+	if err := f.call(ctx, "WaitForDeploymentResult"); err != nil {
+		result.State, result.Cause = marathon.WaitFailed, err
+		if ctx.Err() != nil {
+			result.State = marathon.WaitCancelled
+		}
+		return result
+	}
+	if f.findDeployment(id) >= 0 {
+		result.State = marathon.WaitTimedOut
+	}
+	return result
+}
+
+/** Group API */
+
+func (f *Fake) CreateGroupFromFile(filename string, opts *marathon.CreateOptions) (*marathon.Group, error) {
+	return f.CreateGroupFromFileCtx(context.Background(), filename, opts)
+}
+
+func (f *Fake) CreateGroupFromFileCtx(ctx context.Context, filename string, opts *marathon.CreateOptions) (*marathon.Group, error) {
+	group, err := (&marathon.MarathonClient{}).ParseGroupFromFile(filename, opts)
+	if err != nil {
+		return group, err
+	}
+	return f.createParsedGroup(ctx, group, opts)
+}
+
+func (f *Fake) CreateGroupFromString(filename string, grpstr string, opts *marathon.CreateOptions) (*marathon.Group, error) {
+	return f.CreateGroupFromStringCtx(context.Background(), filename, grpstr, opts)
+}
+
+func (f *Fake) CreateGroupFromStringCtx(ctx context.Context, filename string, grpstr string, opts *marathon.CreateOptions) (*marathon.Group, error) {
+	et, err := encoding.EncoderTypeFromExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	group, err := (&marathon.MarathonClient{}).ParseGroupFromString(strings.NewReader(grpstr), et, opts)
+	if err != nil {
+		return group, err
+	}
+	return f.createParsedGroup(ctx, group, opts)
+}
+
+func (f *Fake) createParsedGroup(ctx context.Context, group *marathon.Group, opts *marathon.CreateOptions) (*marathon.Group, error) {
+	if opts.StopDeploy {
+		f.CancelAppDeploymentCtx(ctx, group.GroupID, true)
+	}
+	return f.CreateGroupCtx(ctx, group, opts.Wait, opts.Force)
+}
+
+func (f *Fake) CreateGroup(group *marathon.Group, wait, force bool) (*marathon.Group, error) {
+	return f.CreateGroupCtx(context.Background(), group, wait, force)
+}
+
+// The group is stored as its applications and the identifiers of it and its nested groups
+func (f *Fake) CreateGroupCtx(ctx context.Context, group *marathon.Group, wait, force bool) (*marathon.Group, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "CreateGroup"); err != nil {
+		return nil, err
+	}
+	if err := group.Validate(); err != nil {
+		return nil, err
+	}
+	if f.Groups[absID("", group.GroupID)] && !force {
+		return nil, marathon.NewError(marathon.CodeConflict, 409, marathon.ErrorGroupExists)
+	}
+	f.putGroup("", group)
+	return group, nil
+}
+
+func (f *Fake) putGroup(parent string, group *marathon.Group) {
+	id := absID(parent, group.GroupID)
+	f.Groups[id] = true
+	for _, app := range group.Apps {
+		resolved := copyApp(app)
+		resolved.ID = absID(id, app.ID)
+		f.putApp(resolved)
+	}
+	for _, g := range group.Groups {
+		f.putGroup(id, g)
+	}
+}
+
+func (f *Fake) ListGroups() (*marathon.Groups, error) {
+	return f.ListGroupsCtx(context.Background())
+}
+
+func (f *Fake) ListGroupsCtx(ctx context.Context) (*marathon.Groups, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "ListGroups"); err != nil {
+		return nil, err
+	}
+	root := f.group("/")
+	return &marathon.Groups{GroupID: root.GroupID, Version: root.Version, Apps: root.Apps, Groups: root.Groups}, nil
+}
+
+func (f *Fake) GetGroup(id string) (*marathon.Group, error) {
+	return f.GetGroupCtx(context.Background(), id)
+}
+
+func (f *Fake) GetGroupCtx(ctx context.Context, id string) (*marathon.Group, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "GetGroup"); err != nil {
+		return nil, err
+	}
+	id = absID("", id)
+	if !f.Groups[id] && id != "/" {
+		return nil, notFound("Group", id)
+	}
+	return f.group(id), nil
+}
+
+// Assembles the group {id} from the applications and groups directly within it
+func (f *Fake) group(id string) *marathon.Group {
+	group := &marathon.Group{GroupID: id, Apps: []*marathon.Application{}, Groups: []*marathon.Group{}}
+	appIds := []string{}
+	for appId := range f.Apps {
+		if path.Dir(appId) == id {
+			appIds = append(appIds, appId)
+		}
+	}
+	sort.Strings(appIds)
+	for _, appId := range appIds {
+		group.Apps = append(group.Apps, copyApp(f.Apps[appId]))
+	}
+
+	groupIds := []string{}
+	for groupId := range f.Groups {
+		if groupId != id && path.Dir(groupId) == id {
+			groupIds = append(groupIds, groupId)
+		}
+	}
+	sort.Strings(groupIds)
+	for _, groupId := range groupIds {
+		group.Groups = append(group.Groups, f.group(groupId))
+	}
+	return group
+}
+
+func (f *Fake) DestroyGroup(id string) (*marathon.DeploymentID, error) {
+	return f.DestroyGroupCtx(context.Background(), id)
+}
+
+func (f *Fake) DestroyGroupCtx(ctx context.Context, id string) (*marathon.DeploymentID, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "DestroyGroup"); err != nil {
+		return nil, err
+	}
+	id = absID("", id)
+	if !f.Groups[id] {
+		return nil, notFound("Group", id)
+	}
+	for groupId := range f.Groups {
+		if groupId == id || strings.HasPrefix(groupId, id+"/") {
+			delete(f.Groups, groupId)
+		}
+	}
+	for appId := range f.Apps {
+		if strings.HasPrefix(appId, id+"/") {
+			f.removeApp(appId)
+		}
+	}
+	f.nextVersion()
+	return f.deployment(), nil
+}
+
+/** Pod API */
+
+// Stores a copy of {pod} as a new version
+func (f *Fake) putPod(pod *marathon.Pod) *marathon.Pod {
+	stored := copyPod(pod)
+	stored.ID = absID("", stored.ID)
+	stored.Version = f.nextVersion()
+	f.Pods[stored.ID] = stored
+	f.podVersions[stored.ID] = append([]string{stored.Version}, f.podVersions[stored.ID]...)
+	return copyPod(stored)
+}
+
+func copyPod(pod *marathon.Pod) *marathon.Pod {
+	c := new(marathon.Pod)
+	b, _ := json.Marshal(pod)
+	json.Unmarshal(b, c)
+	return c
+}
+
+func (f *Fake) CreatePod(pod *marathon.Pod, wait, force bool) (*marathon.Pod, error) {
+	return f.CreatePodCtx(context.Background(), pod, wait, force)
+}
+
+func (f *Fake) CreatePodCtx(ctx context.Context, pod *marathon.Pod, wait, force bool) (*marathon.Pod, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "CreatePod"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.Pods[absID("", pod.ID)]; ok && !force {
+		return nil, marathon.NewError(marathon.CodeConflict, 409, marathon.ErrorPodExists)
+	}
+	return f.putPod(pod), nil
+}
+
+func (f *Fake) UpdatePod(pod *marathon.Pod, wait, force bool) (*marathon.Pod, error) {
+	return f.UpdatePodCtx(context.Background(), pod, wait, force)
+}
+
+func (f *Fake) UpdatePodCtx(ctx context.Context, pod *marathon.Pod, wait, force bool) (*marathon.Pod, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "UpdatePod"); err != nil {
+		return nil, err
+	}
+	if _, ok := f.Pods[absID("", pod.ID)]; !ok {
+		return nil, marathon.NewError(marathon.CodeNotFound, 404, marathon.ErrorNoPodExists)
+	}
+	return f.putPod(pod), nil
+}
+
+func (f *Fake) ListPods() ([]*marathon.Pod, error) {
+	return f.ListPodsCtx(context.Background())
+}
+
+func (f *Fake) ListPodsCtx(ctx context.Context) ([]*marathon.Pod, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "ListPods"); err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for id := range f.Pods {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	pods := []*marathon.Pod{}
+	for _, id := range ids {
+		pods = append(pods, copyPod(f.Pods[id]))
+	}
+	return pods, nil
+}
+
+func (f *Fake) GetPod(id string) (*marathon.Pod, error) {
+	return f.GetPodCtx(context.Background(), id)
+}
+
+func (f *Fake) GetPodCtx(ctx context.Context, id string) (*marathon.Pod, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "GetPod"); err != nil {
+		return nil, err
+	}
+	pod, ok := f.Pods[absID("", id)]
+	if !ok {
+		return nil, notFound("Pod", id)
+	}
+	return copyPod(pod), nil
+}
+
+func (f *Fake) GetPodStatus(id string) (*marathon.PodStatus, error) {
+	return f.GetPodStatusCtx(context.Background(), id)
+}
+
+// Pods are always stable with an instance per the scaling of the pod
+func (f *Fake) GetPodStatusCtx(ctx context.Context, id string) (*marathon.PodStatus, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "GetPodStatus"); err != nil {
+		return nil, err
+	}
+	pod, ok := f.Pods[absID("", id)]
+	if !ok {
+		return nil, notFound("Pod", id)
+	}
+	status := &marathon.PodStatus{ID: pod.ID, Spec: copyPod(pod), Status: marathon.PodStatusStable, StatusSince: pod.Version,
+		Instances: []*marathon.PodInstanceStatus{}}
+	instances := 1
+	if pod.Scaling != nil {
+		instances = pod.Scaling.Instances
+	}
+	for i := 0; i < instances; i++ {
+		status.Instances = append(status.Instances, &marathon.PodInstanceStatus{
+			ID:            fmt.Sprintf("%s.instance-%d", strings.Replace(strings.TrimPrefix(pod.ID, "/"), "/", "_", -1), i),
+			Status:        marathon.PodStatusStable,
+			StatusSince:   pod.Version,
+			AgentHostname: "localhost",
+		})
+	}
+	return status, nil
+}
+
+func (f *Fake) DeletePod(id string, force bool) error {
+	return f.DeletePodCtx(context.Background(), id, force)
+}
+
+func (f *Fake) DeletePodCtx(ctx context.Context, id string, force bool) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "DeletePod"); err != nil {
+		return err
+	}
+	id = absID("", id)
+	if _, ok := f.Pods[id]; !ok {
+		return notFound("Pod", id)
+	}
+	delete(f.Pods, id)
+	delete(f.podVersions, id)
+	return nil
+}
+
+func (f *Fake) PodVersions(id string) ([]string, error) {
+	return f.PodVersionsCtx(context.Background(), id)
+}
+
+func (f *Fake) PodVersionsCtx(ctx context.Context, id string) ([]string, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "PodVersions"); err != nil {
+		return nil, err
+	}
+	id = absID("", id)
+	if _, ok := f.Pods[id]; !ok {
+		return nil, notFound("Pod", id)
+	}
+	return append([]string{}, f.podVersions[id]...), nil
+}
+
+func (f *Fake) WaitForPod(id string, timeout time.Duration) error {
+	return f.WaitForPodCtx(context.Background(), id, timeout)
+}
+
+func (f *Fake) WaitForPodCtx(ctx context.Context, id string, timeout time.Duration) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "WaitForPod"); err != nil {
+		return err
+	}
+	if _, ok := f.Pods[absID("", id)]; !ok {
+		return notFound("Pod", id)
+	}
+	return nil
+}
+
+/** Artifact API */
+
+func (f *Fake) UploadArtifact(path string, content io.Reader) error {
+	return f.UploadArtifactCtx(context.Background(), path, content)
+}
+
+func (f *Fake) UploadArtifactCtx(ctx context.Context, path string, content io.Reader) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "UploadArtifact"); err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	f.Artifacts[absID("", path)] = b
+	return nil
+}
+
+func (f *Fake) FetchArtifact(path string, w io.Writer) error {
+	return f.FetchArtifactCtx(context.Background(), path, w)
+}
+
+func (f *Fake) FetchArtifactCtx(ctx context.Context, path string, w io.Writer) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "FetchArtifact"); err != nil {
+		return err
+	}
+	b, ok := f.Artifacts[absID("", path)]
+	if !ok {
+		return notFound("Artifact", path)
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func (f *Fake) DeleteArtifact(path string) error {
+	return f.DeleteArtifactCtx(context.Background(), path)
+}
+
+func (f *Fake) DeleteArtifactCtx(ctx context.Context, path string) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "DeleteArtifact"); err != nil {
+		return err
+	}
+	if _, ok := f.Artifacts[absID("", path)]; !ok {
+		return notFound("Artifact", path)
+	}
+	delete(f.Artifacts, absID("", path))
+	return nil
+}
+
+/** Task API */
+
+func (f *Fake) ListTasks() ([]*marathon.Task, error) {
+	return f.ListTasksCtx(context.Background())
+}
+
+func (f *Fake) ListTasksCtx(ctx context.Context) ([]*marathon.Task, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "ListTasks"); err != nil {
+		return nil, err
+	}
+	return append([]*marathon.Task{}, f.Tasks...), nil
+}
+
+func (f *Fake) GetTasks(id string) ([]*marathon.Task, error) {
+	return f.GetTasksCtx(context.Background(), id)
+}
+
+func (f *Fake) GetTasksCtx(ctx context.Context, id string) ([]*marathon.Task, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "GetTasks"); err != nil {
+		return nil, err
+	}
+	id = absID("", id)
+	if _, ok := f.Apps[id]; !ok {
+		return nil, notFound("App", id)
+	}
+	tasks := []*marathon.Task{}
+	for _, t := range f.Tasks {
+		if t.AppID == id {
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+// Removes the tasks matching {fn} returning them
+func (f *Fake) removeTasks(fn func(t *marathon.Task) bool) []*marathon.Task {
+	kept, removed := []*marathon.Task{}, []*marathon.Task{}
+	for _, t := range f.Tasks {
+		if fn(t) {
+			removed = append(removed, t)
+		} else {
+			kept = append(kept, t)
+		}
+	}
+	f.Tasks = kept
+	return removed
+}
+
+// Kills the tasks matching {fn}.  Unless scaling, the applications replace the killed tasks
+func (f *Fake) killTasks(scale bool, fn func(t *marathon.Task) bool) []*marathon.Task {
+	killed := f.removeTasks(fn)
+	for _, t := range killed {
+		app, ok := f.Apps[t.AppID]
+		if !ok {
+			continue
+		}
+		if scale {
+			app.Instances--
+			app.TasksRunning = app.Instances
+			if len(app.HealthChecks) > 0 {
+				app.TasksHealthy = app.Instances
+			}
+		}
+	}
+	if !scale {
+		for _, t := range killed {
+			if app, ok := f.Apps[t.AppID]; ok {
+				f.syncTasks(app)
+			}
+		}
+	}
+	return killed
+}
+
+func (f *Fake) KillAppTasks(id string, host string, scale bool) ([]*marathon.Task, error) {
+	return f.KillAppTasksCtx(context.Background(), id, host, scale)
+}
+
+func (f *Fake) KillAppTasksCtx(ctx context.Context, id string, host string, scale bool) ([]*marathon.Task, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "KillAppTasks"); err != nil {
+		return nil, err
+	}
+	id = absID("", id)
+	if _, ok := f.Apps[id]; !ok {
+		return nil, notFound("App", id)
+	}
+	return f.killTasks(scale, func(t *marathon.Task) bool {
+		return t.AppID == id && (host == "" || t.Host == host)
+	}), nil
+}
+
+func (f *Fake) KillAppTask(taskId string, scale bool) (*marathon.Task, error) {
+	return f.KillAppTaskCtx(context.Background(), taskId, scale)
+}
+
+func (f *Fake) KillAppTaskCtx(ctx context.Context, taskId string, scale bool) (*marathon.Task, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "KillAppTask"); err != nil {
+		return nil, err
+	}
+	killed := f.killTasks(scale, func(t *marathon.Task) bool { return t.ID == taskId })
+	if len(killed) == 0 {
+		return nil, notFound("Task", taskId)
+	}
+	return killed[0], nil
+}
+
+func (f *Fake) KillTasksAndScale(ids ...string) error {
+	return f.KillTasksAndScaleCtx(context.Background(), ids...)
+}
+
+func (f *Fake) KillTasksAndScaleCtx(ctx context.Context, ids ...string) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "KillTasksAndScale"); err != nil {
+		return err
+	}
+	f.killTasks(true, func(t *marathon.Task) bool {
+		for _, id := range ids {
+			if t.ID == id {
+				return true
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+func (f *Fake) ListQueue() (*marathon.Queue, error) {
+	return f.ListQueueCtx(context.Background())
+}
+
+func (f *Fake) ListQueueCtx(ctx context.Context) (*marathon.Queue, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "ListQueue"); err != nil {
+		return nil, err
+	}
+	return f.Queue, nil
+}
+
+func (f *Fake) ResetDelay(id string) error {
+	return f.ResetDelayCtx(context.Background(), id)
+}
+
+// Clears the delay of the queued application {id}
+func (f *Fake) ResetDelayCtx(ctx context.Context, id string) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "ResetDelay"); err != nil {
+		return err
+	}
+	id = absID("", id)
+	for i := range f.Queue.Queue {
+		if q := &f.Queue.Queue[i]; q.App != nil && q.App.ID == id {
+			q.Delay = marathon.QueueDelay{}
+			return nil
+		}
+	}
+	return notFound("Queued app", id)
+}
+
+/** Event API */
+
+// Listeners are registered but no events are sent to them
+func (f *Fake) CreateEventStreamListener(channel marathon.EventsChannel, filter int) error {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(context.Background(), "CreateEventStreamListener"); err != nil {
+		return err
+	}
+	f.listeners[channel] = filter
+	return nil
+}
+
+func (f *Fake) CloseEventStreamListener(channel marathon.EventsChannel) {
+	f.Lock()
+	defer f.Unlock()
+	f.Calls = append(f.Calls, "CloseEventStreamListener")
+	delete(f.listeners, channel)
+}
+
+// EventStream sends the events added with SendEvent which match {filters} until {ctx} is done
+func (f *Fake) EventStream(ctx context.Context, filters ...int) (<-chan *marathon.Event, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "EventStream"); err != nil {
+		return nil, err
+	}
+	filter := 0
+	for _, fl := range filters {
+		filter |= fl
+	}
+	if filter == 0 {
+		filter = -1
+	}
+	events := make(chan *marathon.Event, 16)
+	f.streams = append(f.streams, &eventStream{ctx: ctx, filter: filter, events: events})
+	go func() {
+		<-ctx.Done()
+		f.Lock()
+		defer f.Unlock()
+		for i, s := range f.streams {
+			if s.events == events {
+				f.streams = append(f.streams[:i], f.streams[i+1:]...)
+				break
+			}
+		}
+		close(events)
+	}()
+	return events, nil
+}
+
+// SendEvent sends {event} to the open event streams and listeners whose filters match its type
+func (f *Fake) SendEvent(event *marathon.Event) {
+	f.Lock()
+	defer f.Unlock()
+	for _, s := range f.streams {
+		if event.ID&s.filter != 0 {
+			select {
+			case s.events <- event:
+			case <-s.ctx.Done():
+			}
+		}
+	}
+	for ch, filter := range f.listeners {
+		if event.ID&filter != 0 {
+			go func(ch marathon.EventsChannel) { ch <- event }(ch)
+		}
+	}
+}
+
+/** Server API */
+
+func (f *Fake) Ping() (*marathon.MarathonPing, error) {
+	return f.PingCtx(context.Background())
+}
+
+func (f *Fake) PingCtx(ctx context.Context) (*marathon.MarathonPing, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "Ping"); err != nil {
+		return nil, err
+	}
+	return &marathon.MarathonPing{Host: f.Info.Leader}, nil
+}
+
+func (f *Fake) GetMarathonInfo() (*marathon.MarathonInfo, error) {
+	return f.GetMarathonInfoCtx(context.Background())
+}
+
+func (f *Fake) GetMarathonInfoCtx(ctx context.Context) (*marathon.MarathonInfo, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "GetMarathonInfo"); err != nil {
+		return nil, err
+	}
+	return f.Info, nil
+}
+
+func (f *Fake) GetCurrentLeader() (*marathon.LeaderInfo, error) {
+	return f.GetCurrentLeaderCtx(context.Background())
+}
+
+func (f *Fake) GetCurrentLeaderCtx(ctx context.Context) (*marathon.LeaderInfo, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "GetCurrentLeader"); err != nil {
+		return nil, err
+	}
+	return &marathon.LeaderInfo{Leader: f.Info.Leader}, nil
+}
+
+func (f *Fake) AbdicateLeader() (*marathon.Message, error) {
+	return f.AbdicateLeaderCtx(context.Background())
+}
+
+func (f *Fake) AbdicateLeaderCtx(ctx context.Context) (*marathon.Message, error) {
+	return f.AbdicateLeaderWithOptsCtx(ctx, nil)
+}
+
+func (f *Fake) AbdicateLeaderWithOpts(opts *marathon.AbdicateOptions) (*marathon.Message, error) {
+	return f.AbdicateLeaderWithOptsCtx(context.Background(), opts)
+}
+
+func (f *Fake) AbdicateLeaderWithOptsCtx(ctx context.Context, opts *marathon.AbdicateOptions) (*marathon.Message, error) {
+	f.Lock()
+	defer f.Unlock()
+	if err := f.call(ctx, "AbdicateLeader"); err != nil {
+		return nil, err
+	}
+	return &marathon.Message{Message: "Leadership abdicated"}, nil
+}
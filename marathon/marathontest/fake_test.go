@@ -0,0 +1,105 @@
+package marathontest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeApplications(t *testing.T) {
+	f := New()
+	app, err := f.CreateApplication(&marathon.Application{ID: "web", Instances: 2}, true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "/web", app.ID)
+	assert.Equal(t, 2, app.TasksRunning)
+
+	_, err = f.CreateApplication(&marathon.Application{ID: "/web"}, false, false)
+	assert.True(t, errors.Is(err, marathon.ErrorAppExists))
+	assert.Equal(t, marathon.CodeConflict, marathon.ErrorCodeOf(err))
+
+	_, err = f.ScaleApplication("/web", 3)
+	assert.Nil(t, err)
+	tasks, _ := f.GetTasks("/web")
+	assert.Len(t, tasks, 3)
+	versions, _ := f.ListVersions("/web")
+	assert.Len(t, versions.Versions, 2)
+
+	_, err = f.KillAppTask(tasks[0].ID, true)
+	assert.Nil(t, err)
+	app, _ = f.GetApplication("/web")
+	assert.Equal(t, 2, app.Instances)
+
+	_, err = f.DestroyApplication("/web")
+	assert.Nil(t, err)
+	exists, err := f.HasApplication("/web")
+	assert.False(t, exists)
+	_, err = f.GetApplication("/web")
+	assert.True(t, errors.Is(err, httpclient.ErrorNotFound))
+}
+
+func TestFakeGroups(t *testing.T) {
+	f := New()
+	_, err := f.CreateGroupFromString("group.json", `{"id": "/site", "apps": [{"id": "web", "instances": 1}],
+		"groups": [{"id": "backend", "apps": [{"id": "api", "instances": 1}]}]}`, &marathon.CreateOptions{})
+	assert.Nil(t, err)
+
+	group, err := f.GetGroup("/site")
+	assert.Nil(t, err)
+	assert.Equal(t, "/site/web", group.Apps[0].ID)
+	assert.Equal(t, "/site/backend/api", group.Groups[0].Apps[0].ID)
+
+	apps, _ := f.ListApplicationsWithFilters("backend")
+	assert.Len(t, apps.Apps, 1)
+
+	_, err = f.DestroyGroup("/site")
+	assert.Nil(t, err)
+	assert.Empty(t, f.Apps)
+}
+
+func TestFakeErrorsAndDeployments(t *testing.T) {
+	f := New()
+	unavailable := errors.New("unavailable")
+	f.Errors["ListApplications"] = unavailable
+	_, err := f.ListApplicationsCtx(context.Background())
+	assert.Equal(t, unavailable, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = f.GetMarathonInfoCtx(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	f.Deployments = []*marathon.Deploy{{DeployID: "1", AffectedApps: []string{"/web"}}}
+	assert.Equal(t, marathon.ErrorTimeout, f.WaitForDeployment("1", 0))
+	_, err = f.CancelAppDeployment("/web", false)
+	assert.Nil(t, err)
+	assert.Nil(t, f.WaitForDeployment("1", 0))
+	assert.True(t, f.Called("CancelAppDeployment"))
+}
+
+func TestFakePods(t *testing.T) {
+	f := New()
+	pod := marathon.NewPod("web")
+	pod.Scaling.Instances = 2
+	created, err := f.CreatePod(pod, true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "/web", created.ID)
+
+	_, err = f.CreatePod(pod, false, false)
+	assert.True(t, errors.Is(err, marathon.ErrorPodExists))
+	_, err = f.CreatePod(pod, false, true)
+	assert.Nil(t, err)
+
+	status, _ := f.GetPodStatus("/web")
+	assert.Equal(t, marathon.PodStatusStable, status.Status)
+	assert.Len(t, status.Instances, 2)
+	versions, _ := f.PodVersions("/web")
+	assert.Len(t, versions, 2)
+
+	assert.Nil(t, f.DeletePod("/web", false))
+	_, err = f.GetPod("/web")
+	assert.True(t, errors.Is(err, httpclient.ErrorNotFound))
+}
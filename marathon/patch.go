@@ -0,0 +1,96 @@
+package marathon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/utils"
+)
+
+var ErrorVersionConflict = errors.New("The application has changed since it was read - fetch it again and reapply the change")
+
+// PatchOptions control how a partial update is applied
+type PatchOptions struct {
+	// Optional version the application must still be at (eg. the version the change was based on).  The
+	// update is refused with ErrorVersionConflict when the application has since been changed
+	Version string
+	// Waits until the deployment completes and the application is running
+	Wait bool
+	// Updates the application even if it's locked by a deployment in progress
+	Force bool
+}
+
+// UpdateApplicationPartial applies {patch} to the application {id}.  The patch is a JSON merge patch
+// (RFC 7386): objects are merged, null removes a field and other values replace it (eg.
+// {"instances": 3, "env": {"DEBUG": null}}).  The application is fetched, merged and PUT as a whole so
+// fields this client doesn't model are preserved
+func (c *MarathonClient) UpdateApplicationPartial(id string, patch map[string]interface{}, opts *PatchOptions) (*Application, error) {
+	return c.UpdateApplicationPartialCtx(c.context(), id, patch, opts)
+}
+
+func (c *MarathonClient) UpdateApplicationPartialCtx(ctx context.Context, id string, patch map[string]interface{}, opts *PatchOptions) (*Application, error) {
+	if opts == nil {
+		opts = &PatchOptions{}
+	}
+	logger.With(log, logger.Fields{logger.FieldApp: id}).Info("Patch Application '%s', wait = %v", id, opts.Wait)
+	id = utils.TrimRootPath(id)
+
+	live, err := c.GetApplicationDefinitionCtx(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckVersion(live, opts.Version); err != nil {
+		return nil, err
+	}
+
+	merged := MergePatch(live, patch)
+	for field := range statusFields {
+		delete(merged, field)
+	}
+	result := new(DeploymentID)
+	resp := c.http.HttpPutCtx(ctx, fmt.Sprintf("%s?force=%v", c.marathonUrl(API_APPS, id), opts.Force), merged, result)
+	if resp.Error != nil {
+		return nil, responseError(resp)
+	}
+	if opts.Wait {
+		if err := c.WaitForDeploymentCtx(ctx, result.DeploymentID, c.determineTimeout(nil)); err != nil {
+			return nil, err
+		}
+		if err := c.WaitForApplicationCtx(ctx, id, c.determineTimeout(nil)); err != nil {
+			return nil, err
+		}
+	}
+	return c.GetApplicationCtx(ctx, id)
+}
+
+// CheckVersion returns a CodeConflict MarathonError when {version} is set and differs from the version of
+// the application {app} (as JSON fields)
+func CheckVersion(app map[string]interface{}, version string) error {
+	if version == "" || app["version"] == version {
+		return nil
+	}
+	return NewError(CodeConflict, 409, fmt.Errorf("%w (expected version %s, current version %v)", ErrorVersionConflict, version, app["version"]))
+}
+
+// MergePatch returns {target} with the JSON merge patch (RFC 7386) {patch} applied.  {target} is not
+// modified
+func MergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for k, v := range target {
+		merged[k] = v
+	}
+	for k, v := range patch {
+		switch p := v.(type) {
+		case nil:
+			delete(merged, k)
+		case map[string]interface{}:
+			t, _ := merged[k].(map[string]interface{})
+			merged[k] = MergePatch(t, p)
+		default:
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,64 @@
+package marathon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const patchLiveApp = `{"app": {"id": "/web", "version": "2026-01-01T00:00:00.000Z", "instances": 1, "tasksRunning": 1,
+	"env": {"MODE": "production", "DEBUG": "1"}, "unknownField": {"kept": true}}}`
+
+func patchServer(put *map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "PUT" {
+			json.NewDecoder(r.Body).Decode(put)
+			fmt.Fprint(w, `{"deploymentId": "1", "version": "2026-01-02T00:00:00.000Z"}`)
+			return
+		}
+		fmt.Fprint(w, patchLiveApp)
+	}))
+}
+
+func TestMergePatch(t *testing.T) {
+	target := map[string]interface{}{"a": 1.0, "env": map[string]interface{}{"X": "1", "Y": "2"}}
+	merged := MergePatch(target, map[string]interface{}{"a": nil, "b": 2.0, "env": map[string]interface{}{"X": nil, "Z": "3"}})
+	assert.Equal(t, map[string]interface{}{"b": 2.0, "env": map[string]interface{}{"Y": "2", "Z": "3"}}, merged)
+	assert.Equal(t, 1.0, target["a"], "target should not be modified")
+}
+
+func TestUpdateApplicationPartial(t *testing.T) {
+	put := map[string]interface{}{}
+	s := patchServer(&put)
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	patch := map[string]interface{}{"instances": 3, "env": map[string]interface{}{"DEBUG": nil}}
+	_, err := c.UpdateApplicationPartial("/web", patch, &PatchOptions{Version: "2026-01-01T00:00:00.000Z"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3.0, put["instances"])
+	assert.Equal(t, map[string]interface{}{"MODE": "production"}, put["env"])
+	assert.Equal(t, map[string]interface{}{"kept": true}, put["unknownField"])
+	assert.NotContains(t, put, "version")
+	assert.NotContains(t, put, "tasksRunning")
+}
+
+func TestUpdateApplicationPartialConflict(t *testing.T) {
+	put := map[string]interface{}{}
+	s := patchServer(&put)
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	_, err := c.UpdateApplicationPartial("/web", map[string]interface{}{"instances": 3}, &PatchOptions{Version: "2025-12-31T00:00:00.000Z"})
+
+	assert.True(t, errors.Is(err, ErrorVersionConflict))
+	assert.Equal(t, CodeConflict, ErrorCodeOf(err))
+	assert.Empty(t, put, "a conflicting patch should not be sent")
+}
@@ -0,0 +1,370 @@
+package marathon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	// pod statuses reported by Marathon
+	PodStatusDeploying = "DEPLOYING"
+	PodStatusStable    = "STABLE"
+	PodStatusDegraded  = "DEGRADED"
+	PodStatusTerminal  = "TERMINAL"
+
+	podStatusSuffix   = "::status"
+	podVersionsSuffix = "::versions"
+)
+
+var (
+	ErrorPodExists   = errors.New("The pod already exists")
+	ErrorNoPodExists = errors.New("The pod does not exist.  Create a pod before updating")
+)
+
+// Pod is a group of containers scheduled together on the same agent (Marathon 1.4+)
+type Pod struct {
+	ID          string                 `json:"id"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Version     string                 `json:"version,omitempty"`
+	User        string                 `json:"user,omitempty"`
+	Environment map[string]interface{} `json:"environment,omitempty"`
+	Containers  []*PodContainer        `json:"containers"`
+	Secrets     map[string]*Secret     `json:"secrets,omitempty"`
+	Volumes     []*PodVolume           `json:"volumes,omitempty"`
+	Networks    []*PodNetwork          `json:"networks,omitempty"`
+	Scaling     *PodScaling            `json:"scaling,omitempty"`
+	Scheduling  *PodScheduling         `json:"scheduling,omitempty"`
+	// resources of the executor running the containers
+	ExecutorResources *PodResources `json:"executorResources,omitempty"`
+}
+
+type PodContainer struct {
+	Name         string                 `json:"name"`
+	Exec         *PodExec               `json:"exec,omitempty"`
+	Resources    *PodResources          `json:"resources"`
+	Endpoints    []*PodEndpoint         `json:"endpoints,omitempty"`
+	Image        *PodImage              `json:"image,omitempty"`
+	Environment  map[string]interface{} `json:"environment,omitempty"`
+	User         string                 `json:"user,omitempty"`
+	HealthCheck  *PodHealthCheck        `json:"healthCheck,omitempty"`
+	VolumeMounts []*PodVolumeMount      `json:"volumeMounts,omitempty"`
+	Artifacts    []*PodArtifact         `json:"artifacts,omitempty"`
+	Labels       map[string]string      `json:"labels,omitempty"`
+}
+
+type PodExec struct {
+	Command PodCommand `json:"command"`
+}
+
+// PodCommand is either a shell command or the arguments of a command run directly
+type PodCommand struct {
+	Shell string   `json:"shell,omitempty"`
+	Argv  []string `json:"argv,omitempty"`
+}
+
+type PodResources struct {
+	Cpus float64 `json:"cpus"`
+	Mem  float64 `json:"mem"`
+	Disk float64 `json:"disk,omitempty"`
+	Gpus int     `json:"gpus,omitempty"`
+}
+
+type PodEndpoint struct {
+	Name          string            `json:"name"`
+	ContainerPort int               `json:"containerPort,omitempty"`
+	HostPort      int               `json:"hostPort,omitempty"`
+	Protocol      []string          `json:"protocol,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+// PodImage is the image of a container where Kind is DOCKER or APPC
+type PodImage struct {
+	Kind      string `json:"kind"`
+	ID        string `json:"id"`
+	ForcePull bool   `json:"forcePull,omitempty"`
+}
+
+// PodHealthCheck checks a container with one of HTTP, TCP or Exec
+type PodHealthCheck struct {
+	HTTP                   *PodHTTPHealthCheck `json:"http,omitempty"`
+	TCP                    *PodTCPHealthCheck  `json:"tcp,omitempty"`
+	Exec                   *PodExec            `json:"exec,omitempty"`
+	GracePeriodSeconds     int                 `json:"gracePeriodSeconds,omitempty"`
+	IntervalSeconds        int                 `json:"intervalSeconds,omitempty"`
+	MaxConsecutiveFailures int                 `json:"maxConsecutiveFailures,omitempty"`
+	TimeoutSeconds         int                 `json:"timeoutSeconds,omitempty"`
+	DelaySeconds           int                 `json:"delaySeconds,omitempty"`
+}
+
+type PodHTTPHealthCheck struct {
+	Endpoint string `json:"endpoint"`
+	Path     string `json:"path,omitempty"`
+	Scheme   string `json:"scheme,omitempty"`
+}
+
+type PodTCPHealthCheck struct {
+	Endpoint string `json:"endpoint"`
+}
+
+type PodVolumeMount struct {
+	Name      string `json:"name"`
+	MountPath string `json:"mountPath"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
+}
+
+type PodArtifact struct {
+	URI        string `json:"uri"`
+	Extract    bool   `json:"extract,omitempty"`
+	Executable bool   `json:"executable,omitempty"`
+	Cache      bool   `json:"cache,omitempty"`
+	DestPath   string `json:"destPath,omitempty"`
+}
+
+// PodVolume is an ephemeral volume when Host and Secret are empty, otherwise a host path or a secret
+type PodVolume struct {
+	Name   string `json:"name"`
+	Host   string `json:"host,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+// PodNetwork is a network the pod joins where Mode is host, container or container/bridge
+type PodNetwork struct {
+	Name   string            `json:"name,omitempty"`
+	Mode   string            `json:"mode"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type PodScaling struct {
+	Kind         string `json:"kind"`
+	Instances    int    `json:"instances"`
+	MaxInstances int    `json:"maxInstances,omitempty"`
+}
+
+type PodScheduling struct {
+	Backoff       *PodBackoff   `json:"backoff,omitempty"`
+	Upgrade       *PodUpgrade   `json:"upgrade,omitempty"`
+	Placement     *PodPlacement `json:"placement,omitempty"`
+	KillSelection string        `json:"killSelection,omitempty"`
+}
+
+type PodBackoff struct {
+	Backoff        float64 `json:"backoff,omitempty"`
+	BackoffFactor  float64 `json:"backoffFactor,omitempty"`
+	MaxLaunchDelay float64 `json:"maxLaunchDelay,omitempty"`
+}
+
+type PodUpgrade struct {
+	MinimumHealthCapacity float64 `json:"minimumHealthCapacity"`
+	MaximumOverCapacity   float64 `json:"maximumOverCapacity"`
+}
+
+type PodPlacement struct {
+	Constraints           []*PodConstraint `json:"constraints,omitempty"`
+	AcceptedResourceRoles []string         `json:"acceptedResourceRoles,omitempty"`
+}
+
+type PodConstraint struct {
+	FieldName string `json:"fieldName"`
+	Operator  string `json:"operator"`
+	Value     string `json:"value,omitempty"`
+}
+
+// PodStatus is the state of a pod and its running instances
+type PodStatus struct {
+	ID          string               `json:"id"`
+	Spec        *Pod                 `json:"spec"`
+	Status      string               `json:"status"`
+	StatusSince string               `json:"statusSince"`
+	Message     string               `json:"message,omitempty"`
+	Instances   []*PodInstanceStatus `json:"instances"`
+	LastUpdated string               `json:"lastUpdated"`
+	LastChanged string               `json:"lastChanged"`
+}
+
+type PodInstanceStatus struct {
+	ID            string                `json:"id"`
+	Status        string                `json:"status"`
+	StatusSince   string                `json:"statusSince"`
+	Message       string                `json:"message,omitempty"`
+	AgentHostname string                `json:"agentHostname,omitempty"`
+	Containers    []*PodContainerStatus `json:"containers,omitempty"`
+	LastUpdated   string                `json:"lastUpdated"`
+	LastChanged   string                `json:"lastChanged"`
+}
+
+type PodContainerStatus struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	StatusSince string `json:"statusSince"`
+	ContainerID string `json:"containerId,omitempty"`
+	Message     string `json:"message,omitempty"`
+}
+
+func NewPod(id string) *Pod {
+	return &Pod{ID: id, Containers: []*PodContainer{}, Scaling: &PodScaling{Kind: "fixed", Instances: 1}}
+}
+
+func (c *MarathonClient) CreatePod(pod *Pod, wait, force bool) (*Pod, error) {
+	return c.CreatePodCtx(c.context(), pod, wait, force)
+}
+
+func (c *MarathonClient) CreatePodCtx(ctx context.Context, pod *Pod, wait, force bool) (*Pod, error) {
+	logger.With(log, logger.Fields{logger.FieldApp: pod.ID}).Info("Creating Pod '%s', wait: %v, force: %v", pod.ID, wait, force)
+
+	result := new(Pod)
+	resp := c.http.HttpPostCtx(ctx, c.marathonUrl(API_PODS), pod, result)
+	if resp.Error != nil {
+		if resp.Status == 409 {
+			if force {
+				return c.UpdatePodCtx(ctx, pod, wait, false)
+			}
+			return nil, newMarathonError(resp, CodeConflict, ErrorPodExists)
+		}
+		return nil, responseError(resp)
+	}
+	if wait {
+		if err := c.waitForPodVersion(ctx, result.ID, result.Version, c.determineTimeout(nil)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (c *MarathonClient) UpdatePod(pod *Pod, wait, force bool) (*Pod, error) {
+	return c.UpdatePodCtx(c.context(), pod, wait, force)
+}
+
+func (c *MarathonClient) UpdatePodCtx(ctx context.Context, pod *Pod, wait, force bool) (*Pod, error) {
+	logger.With(log, logger.Fields{logger.FieldApp: pod.ID}).Info("Update Pod '%s', wait: %v, force: %v", pod.ID, wait, force)
+
+	result := new(Pod)
+	uri := fmt.Sprintf("%s?force=%v", c.podUrl(pod.ID, ""), force)
+	resp := c.http.HttpPutCtx(ctx, uri, pod, result)
+	if resp.Error != nil {
+		if resp.Status == 404 {
+			return nil, newMarathonError(resp, CodeNotFound, ErrorNoPodExists)
+		}
+		return nil, responseError(resp)
+	}
+	if wait {
+		if err := c.waitForPodVersion(ctx, result.ID, result.Version, c.determineTimeout(nil)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (c *MarathonClient) ListPods() ([]*Pod, error) {
+	return c.ListPodsCtx(c.context())
+}
+
+func (c *MarathonClient) ListPodsCtx(ctx context.Context) ([]*Pod, error) {
+	pods := []*Pod{}
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_PODS), &pods)
+	if resp.Error != nil {
+		return nil, responseError(resp)
+	}
+	return pods, nil
+}
+
+func (c *MarathonClient) GetPod(id string) (*Pod, error) {
+	return c.GetPodCtx(c.context(), id)
+}
+
+func (c *MarathonClient) GetPodCtx(ctx context.Context, id string) (*Pod, error) {
+	log.Debug("Enter: GetPod: %s", id)
+	pod := new(Pod)
+	resp := c.http.HttpGetCtx(ctx, c.podUrl(id, ""), pod)
+	if resp.Error != nil {
+		return nil, responseError(resp)
+	}
+	return pod, nil
+}
+
+func (c *MarathonClient) GetPodStatus(id string) (*PodStatus, error) {
+	return c.GetPodStatusCtx(c.context(), id)
+}
+
+func (c *MarathonClient) GetPodStatusCtx(ctx context.Context, id string) (*PodStatus, error) {
+	status := new(PodStatus)
+	resp := c.http.HttpGetCtx(ctx, c.podUrl(id, podStatusSuffix), status)
+	if resp.Error != nil {
+		return nil, responseError(resp)
+	}
+	return status, nil
+}
+
+func (c *MarathonClient) DeletePod(id string, force bool) error {
+	return c.DeletePodCtx(c.context(), id, force)
+}
+
+func (c *MarathonClient) DeletePodCtx(ctx context.Context, id string, force bool) error {
+	logger.With(log, logger.Fields{logger.FieldApp: id}).Info("Deleting Pod '%s', force: %v", id, force)
+
+	uri := fmt.Sprintf("%s?force=%v", c.podUrl(id, ""), force)
+	resp := c.http.HttpDeleteCtx(ctx, uri, nil, nil)
+	if resp.Error != nil {
+		return responseError(resp)
+	}
+	return nil
+}
+
+func (c *MarathonClient) PodVersions(id string) ([]string, error) {
+	return c.PodVersionsCtx(c.context(), id)
+}
+
+func (c *MarathonClient) PodVersionsCtx(ctx context.Context, id string) ([]string, error) {
+	versions := []string{}
+	resp := c.http.HttpGetCtx(ctx, c.podUrl(id, podVersionsSuffix), &versions)
+	if resp.Error != nil {
+		return nil, responseError(resp)
+	}
+	return versions, nil
+}
+
+func (c *MarathonClient) WaitForPod(id string, timeout time.Duration) error {
+	return c.WaitForPodCtx(c.context(), id, timeout)
+}
+
+func (c *MarathonClient) WaitForPodCtx(ctx context.Context, id string, timeout time.Duration) error {
+	return c.waitForPodVersion(ctx, id, "", timeout)
+}
+
+// Waits until the pod {id} is stable and when {version} is set running that version
+func (c *MarathonClient) waitForPodVersion(ctx context.Context, id, version string, timeout time.Duration) error {
+	t_now := time.Now()
+	log := logger.With(logWait, logger.Fields{logger.FieldApp: id})
+
+	stable := func() (bool, error) {
+		status, err := c.GetPodStatusCtx(ctx, id)
+		if err != nil && !errors.Is(err, httpclient.ErrorNotFound) {
+			return false, err
+		}
+		if err == nil {
+			current := status.Status == PodStatusStable && (version == "" || status.Spec == nil || status.Spec.Version == version)
+			if current {
+				c.clearWaitStatus()
+				elapsed := time.Since(t_now)
+				log.With(logger.Fields{logger.FieldDuration: elapsed}).Info("Pod %s is stable, elapsed time %s", id, utils.ElapsedStr(elapsed))
+				return true, nil
+			}
+			if status.Status == PodStatusTerminal {
+				return false, fmt.Errorf("Pod %s has terminated: %s", id, status.Message)
+			}
+		}
+		c.waitStatus(0, 0, "Waiting for pod %s to become stable", id)
+		return false, nil
+	}
+	return c.poll(ctx, timeout, stable, func() error { return ErrorTimeout })
+}
+
+// Returns the URL of the pod {id} with the {suffix} (eg. ::status)
+func (c *MarathonClient) podUrl(id, suffix string) string {
+	return c.marathonUrl(API_PODS, utils.TrimRootPath(id)+suffix)
+}
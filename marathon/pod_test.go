@@ -0,0 +1,84 @@
+package marathon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const podStatusResponse = `{"id": "/web", "status": "STABLE", "statusSince": "2026-01-01T00:00:00.000Z",
+	"spec": {"id": "/web", "version": "2026-01-01T00:00:00.000Z", "containers": [{"name": "nginx", "resources": {"cpus": 0.1, "mem": 32}}]},
+	"instances": [{"id": "web.instance-1", "status": "STABLE", "agentHostname": "agent-1",
+		"containers": [{"name": "nginx", "status": "TASK_RUNNING"}]}]}`
+
+func podServer(created *Pod) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + path.Clean(r.URL.Path) {
+		case "POST /v2/pods":
+			if created.ID != "" {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, `{"message": "Pod /web already exists"}`)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(created)
+			created.Version = "2026-01-01T00:00:00.000Z"
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(created)
+		case "GET /v2/pods/web::status":
+			fmt.Fprint(w, podStatusResponse)
+		case "GET /v2/pods/web::versions":
+			fmt.Fprint(w, `["2026-01-01T00:00:00.000Z", "2025-12-01T00:00:00.000Z"]`)
+		case "DELETE /v2/pods/web":
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "not found"}`)
+		}
+	}))
+}
+
+func TestCreatePod(t *testing.T) {
+	created := new(Pod)
+	s := podServer(created)
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	pod := NewPod("/web")
+	pod.Containers = append(pod.Containers, &PodContainer{Name: "nginx", Resources: &PodResources{Cpus: 0.1, Mem: 32},
+		Image: &PodImage{Kind: "DOCKER", ID: "nginx"}})
+	result, err := c.CreatePod(pod, true, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "2026-01-01T00:00:00.000Z", result.Version)
+	assert.Equal(t, "nginx", created.Containers[0].Image.ID)
+
+	_, err = c.CreatePod(pod, false, false)
+	assert.True(t, errors.Is(err, ErrorPodExists))
+	assert.Equal(t, CodeConflict, ErrorCodeOf(err))
+}
+
+func TestPodStatusAndVersions(t *testing.T) {
+	s := podServer(new(Pod))
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	status, err := c.GetPodStatus("/web")
+	assert.Nil(t, err)
+	assert.Equal(t, PodStatusStable, status.Status)
+	assert.Equal(t, "agent-1", status.Instances[0].AgentHostname)
+	assert.Equal(t, "TASK_RUNNING", status.Instances[0].Containers[0].Status)
+
+	versions, err := c.PodVersions("web")
+	assert.Nil(t, err)
+	assert.Len(t, versions, 2)
+
+	assert.Nil(t, c.DeletePod("/web", true))
+	_, err = c.GetPod("/other")
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(err))
+}
@@ -0,0 +1,152 @@
+package marathon
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Timeout of a health check which doesn't declare timeoutSeconds (Marathon's default)
+const DefaultHealthCheckTimeout = 20 * time.Second
+
+var ErrorNoHealthCheckCommand = errors.New("COMMAND health check has no command")
+
+// ProbeResult is the outcome of running a health check locally
+type ProbeResult struct {
+	Protocol string        `json:"protocol"`
+	Target   string        `json:"target"`
+	Healthy  bool          `json:"healthy"`
+	Latency  time.Duration `json:"latency"`
+	Message  string        `json:"message,omitempty"`
+}
+
+// ProbeOptions configure how health checks are run locally
+type ProbeOptions struct {
+	// Address the application listens on (eg. http://localhost:8080 or localhost:8080)
+	Target string
+	// Prefix of the command run by COMMAND health checks (eg. docker exec web).  The command is run by the
+	// local shell when empty
+	Exec []string
+}
+
+// Probe runs the health check against the application listening at the target of {opts} the way Marathon
+// would: HTTP checks require a status between 200 and 399, TCP checks a connection and COMMAND checks a
+// zero exit status
+func (hc *HealthCheck) Probe(opts ProbeOptions) *ProbeResult {
+	protocol := hc.Protocol
+	if protocol == "" {
+		protocol = "HTTP"
+	}
+	timeout := DefaultHealthCheckTimeout
+	if hc.TimeoutSeconds > 0 {
+		timeout = time.Duration(hc.TimeoutSeconds) * time.Second
+	}
+	result := &ProbeResult{Protocol: protocol}
+
+	start := time.Now()
+	var err error
+	switch protocol {
+	case "HTTP", "HTTPS", "MESOS_HTTP", "MESOS_HTTPS":
+		err = hc.probeHTTP(result, opts.Target, strings.HasSuffix(protocol, "HTTPS"), timeout)
+	case "TCP", "MESOS_TCP":
+		err = probeTCP(result, opts.Target, timeout)
+	case "COMMAND":
+		err = hc.probeCommand(result, opts.Exec, timeout)
+	default:
+		err = fmt.Errorf("'%s' is not a valid health check protocol", hc.Protocol)
+	}
+	result.Latency = time.Since(start)
+	result.Healthy = err == nil
+	if err != nil {
+		result.Message = err.Error()
+	}
+	return result
+}
+
+func (hc *HealthCheck) probeHTTP(result *ProbeResult, target string, secure bool, timeout time.Duration) error {
+	u, err := probeUrl(target)
+	if err != nil {
+		return err
+	}
+	if secure {
+		u.Scheme = "https"
+	}
+	u.Path = hc.Path
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	result.Target = u.String()
+
+	client := &http.Client{
+		Timeout: timeout,
+		// Marathon doesn't verify the certificates of health checks
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		// redirects are reported as healthy rather than followed
+		CheckRedirect: func(req *http.Request, via []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(result.Target)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	result.Message = resp.Status
+	if resp.StatusCode < 200 || resp.StatusCode > 399 {
+		return fmt.Errorf("GET %s returned %s, expected 200-399", hc.Path, resp.Status)
+	}
+	return nil
+}
+
+func probeTCP(result *ProbeResult, target string, timeout time.Duration) error {
+	u, err := probeUrl(target)
+	if err != nil {
+		return err
+	}
+	result.Target = u.Host
+	conn, err := net.DialTimeout("tcp", u.Host, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (hc *HealthCheck) probeCommand(result *ProbeResult, prefix []string, timeout time.Duration) error {
+	if hc.Command == nil || hc.Command.Value == "" {
+		return ErrorNoHealthCheckCommand
+	}
+	result.Target = hc.Command.Value
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	args := append(append([]string{}, prefix...), "sh", "-c", hc.Command.Value)
+	out, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s", err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Returns the URL of {target} which may omit the scheme (eg. localhost:8080)
+func probeUrl(target string) (*url.URL, error) {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("Invalid target '%s' - expected a URL (eg. http://localhost:8080)", target)
+	}
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u, nil
+}
@@ -0,0 +1,69 @@
+package marathon
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeHTTP(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+		case "/moved":
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+	opts := ProbeOptions{Target: strings.TrimPrefix(s.URL, "http://")}
+
+	result := (&HealthCheck{Protocol: "HTTP", Path: "/health"}).Probe(opts)
+	assert.True(t, result.Healthy)
+	assert.Equal(t, s.URL+"/health", result.Target)
+
+	assert.True(t, (&HealthCheck{Path: "/moved"}).Probe(opts).Healthy, "redirects are healthy")
+
+	result = (&HealthCheck{Protocol: "MESOS_HTTP", Path: "/down"}).Probe(opts)
+	assert.False(t, result.Healthy)
+	assert.Equal(t, "GET /down returned 503 Service Unavailable, expected 200-399", result.Message)
+}
+
+func TestProbeTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := l.Addr().String()
+
+	assert.True(t, (&HealthCheck{Protocol: "TCP"}).Probe(ProbeOptions{Target: "http://" + addr}).Healthy)
+	l.Close()
+	assert.False(t, (&HealthCheck{Protocol: "TCP"}).Probe(ProbeOptions{Target: addr}).Healthy)
+}
+
+func TestProbeCommand(t *testing.T) {
+	hc := &HealthCheck{Protocol: "COMMAND", Command: &HealthCheckCommand{Value: "test -n \"$HOME\""}}
+	assert.True(t, hc.Probe(ProbeOptions{}).Healthy)
+
+	hc.Command.Value = "echo failing && exit 1"
+	result := hc.Probe(ProbeOptions{Exec: []string{"env"}})
+	assert.False(t, result.Healthy)
+	assert.Equal(t, "exit status 1 failing", result.Message)
+
+	assert.Equal(t, ErrorNoHealthCheckCommand.Error(), (&HealthCheck{Protocol: "COMMAND"}).Probe(ProbeOptions{}).Message)
+}
+
+func TestProbeUrl(t *testing.T) {
+	u, err := probeUrl("localhost")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:80", u.Host)
+
+	u, _ = probeUrl("https://localhost")
+	assert.Equal(t, "localhost:443", u.Host)
+
+	_, err = probeUrl("http://")
+	assert.NotNil(t, err)
+}
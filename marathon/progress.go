@@ -0,0 +1,180 @@
+package marathon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Most applications of a deployment whose state is read on each poll while waiting
+const maxProgressApps = 10
+
+// AppProgress is the state of an application being waited on
+type AppProgress struct {
+	ID           string `json:"id"`
+	Instances    int    `json:"instances"`
+	TasksStaged  int    `json:"tasksStaged"`
+	TasksRunning int    `json:"tasksRunning"`
+	TasksHealthy int    `json:"tasksHealthy"`
+	// false if the application has no health checks in which case running tasks are considered healthy
+	HealthChecked bool `json:"healthChecked"`
+	// Seconds before Marathon next attempts to launch the tasks of a queued application (eg. after
+	// failures)
+	LaunchDelay int `json:"launchDelay,omitempty"`
+	// true if tasks are queued and waiting for offers matching the application's requirements
+	WaitingForOffers bool `json:"waitingForOffers,omitempty"`
+	// Requirements the last offers declined for the queued tasks didn't meet (eg. InsufficientMemory)
+	Unmet []*OfferRejection `json:"unmet,omitempty"`
+}
+
+func (p *AppProgress) String() string {
+	s := fmt.Sprintf("%s %d/%d running", p.ID, p.TasksRunning, p.Instances)
+	if p.HealthChecked {
+		s = fmt.Sprintf("%s %d/%d healthy, %d running", p.ID, p.TasksHealthy, p.Instances, p.TasksRunning)
+	}
+	if p.LaunchDelay > 0 {
+		s += fmt.Sprintf(" (launch delayed %ds)", p.LaunchDelay)
+	} else if p.WaitingForOffers {
+		s += " (waiting for offers"
+		for i, r := range p.Unmet {
+			sep := ", "
+			if i == 0 {
+				sep = ": "
+			}
+			s += fmt.Sprintf("%s%s declined %d of %d", sep, r.Reason, r.Declined, r.Processed)
+		}
+		s += ")"
+	}
+	return s
+}
+
+// Ready returns the tasks which count towards completion: healthy tasks or running tasks of applications
+// without health checks
+func (p *AppProgress) Ready() int {
+	if p.HealthChecked {
+		return p.TasksHealthy
+	}
+	return p.TasksRunning
+}
+
+// TimeoutError is returned when waiting on a deployment or application times out.  It describes the state
+// of the applications when waiting gave up and matches ErrorTimeout with errors.Is
+type TimeoutError struct {
+	Apps []*AppProgress
+	// applications of the deployment which weren't read
+	More int
+}
+
+func (e *TimeoutError) Error() string {
+	msg := ErrorTimeout.Error()
+	for _, app := range e.Apps {
+		msg = fmt.Sprintf("%s\n  %s", msg, app)
+	}
+	if e.More > 0 {
+		msg = fmt.Sprintf("%s\n  and %d more application(s)", msg, e.More)
+	}
+	return msg
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return ErrorTimeout
+}
+
+// Returns the timeout error for the last {progress} read or ErrorTimeout when nothing was read
+func timeoutError(progress []*AppProgress, more int) error {
+	if len(progress) == 0 {
+		return ErrorTimeout
+	}
+	return &TimeoutError{Apps: progress, More: more}
+}
+
+// Reads the state of the applications {ids} (at most maxProgressApps) returning it along with the
+// number of applications left out.  Applications which can't be read are skipped
+func (c *MarathonClient) appProgress(ctx context.Context, ids []string) ([]*AppProgress, int) {
+	more := 0
+	if len(ids) > maxProgressApps {
+		more = len(ids) - maxProgressApps
+		ids = ids[:maxProgressApps]
+	}
+
+	progress := []*AppProgress{}
+	for _, id := range ids {
+		if app, err := c.GetApplicationCtx(ctx, id); err == nil {
+			progress = append(progress, progressOf(app))
+		}
+	}
+	c.applyQueue(ctx, progress)
+	return progress, more
+}
+
+// Sets the launch delay of the applications within {progress} which haven't launched every task.  The
+// queue explains why tasks aren't launching
+func (c *MarathonClient) applyQueue(ctx context.Context, progress []*AppProgress) {
+	launching := false
+	for _, p := range progress {
+		launching = launching || p.TasksRunning < p.Instances
+	}
+	if !launching {
+		return
+	}
+	queue, err := c.ListQueueCtx(ctx)
+	if err != nil {
+		return
+	}
+	for _, q := range queue.Queue {
+		if q.App == nil {
+			continue
+		}
+		for _, p := range progress {
+			if p.ID == q.App.ID {
+				p.LaunchDelay = q.Delay.TimeLeftSeconds
+				p.WaitingForOffers = q.Delay.Overdue && q.Count > 0
+				p.Unmet = unmetRequirements(q.ProcessedOffersSummary)
+			}
+		}
+	}
+}
+
+// Returns the rejections of {summary} which declined offers
+func unmetRequirements(summary *ProcessedOffersSummary) []*OfferRejection {
+	if summary == nil {
+		return nil
+	}
+	unmet := []*OfferRejection{}
+	for _, r := range summary.RejectSummaryLastOffers {
+		if r != nil && r.Declined > 0 {
+			unmet = append(unmet, r)
+		}
+	}
+	return unmet
+}
+
+func progressOf(app *Application) *AppProgress {
+	return &AppProgress{
+		ID:            app.ID,
+		Instances:     app.Instances,
+		TasksStaged:   app.TasksStaged,
+		TasksRunning:  app.TasksRunning,
+		TasksHealthy:  app.TasksHealthy,
+		HealthChecked: len(app.HealthChecks) > 0,
+	}
+}
+
+// Reports {progress} as the wait status prefixed by {message}
+func (c *MarathonClient) progressStatus(message string, progress []*AppProgress, more int) {
+	if len(progress) == 0 {
+		c.waitStatus(0, 0, "%s", message)
+		return
+	}
+	ready, total := 0, 0
+	parts := []string{}
+	for _, p := range progress {
+		ready += p.Ready()
+		total += p.Instances
+		parts = append(parts, p.String())
+	}
+	if more > 0 {
+		parts = append(parts, fmt.Sprintf("%d more", more))
+	}
+	c.waitStatus(ready, total, "%s: %s", message, strings.Join(parts, ", "))
+}
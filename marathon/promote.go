@@ -0,0 +1,33 @@
+package marathon
+
+import (
+	"encoding/json"
+)
+
+// Promote returns the definition of {app} (as deployed to another environment) to deploy elsewhere.  The
+// fields describing the state of the application (tasks, deployments, versions and task counts) are removed
+// and the JSON merge patch {overrides} is applied (eg. {"instances": 5, "env": {"DB_HOST": "db.prod"}}).
+// {app} is not modified
+func Promote(app *Application, overrides map[string]interface{}) (*Application, error) {
+	definition := MergePatch(toGeneric(app), overrides)
+	for field := range statusFields {
+		if field != "id" {
+			delete(definition, field)
+		}
+	}
+	b, err := json.Marshal(definition)
+	if err != nil {
+		return nil, err
+	}
+	promoted := new(Application)
+	if err := json.Unmarshal(b, promoted); err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// Definition returns {app} without the fields describing its state (tasks, deployments, versions and task
+// counts)
+func Definition(app *Application) (*Application, error) {
+	return Promote(app, nil)
+}
@@ -0,0 +1,18 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromote(t *testing.T) {
+	app := &Application{ID: "/web", Instances: 2, Version: "v3", TasksRunning: 2, Env: map[string]string{"DB_HOST": "db.staging", "DEBUG": "1"},
+		Tasks: []*Task{{ID: "web.1"}}, VersionInfo: &VersionInfo{LastConfigChangeAt: "v3"}, DeploymentID: []map[string]string{{"id": "d1"}}}
+
+	promoted, err := Promote(app, map[string]interface{}{"instances": 5, "env": map[string]interface{}{"DB_HOST": "db.prod", "DEBUG": nil}})
+	assert.Nil(t, err)
+	assert.Equal(t, &Application{ID: "/web", Instances: 5, Env: map[string]string{"DB_HOST": "db.prod"}}, promoted)
+	assert.Equal(t, 2, app.Instances, "the exported application is not modified")
+	assert.Equal(t, "db.staging", app.Env["DB_HOST"])
+}
@@ -0,0 +1,131 @@
+package marathon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/utils"
+)
+
+// Timeout of a single readiness request made to a task
+const readyRequestTimeout = time.Duration(5) * time.Second
+
+// ReadyCheck is probed against the advertised host:port of every task once Marathon reports an application
+// healthy.  Waits return once all the tasks respond, covering the gap between Marathon's health checks and
+// the tasks receiving traffic (eg. marathon-lb propagation)
+type ReadyCheck struct {
+	// Path requested on each task (eg. /healthz)
+	Path string
+	// Status the tasks must respond with.  Default: 200
+	Status int
+}
+
+// NotReadyError is returned when tasks of an application didn't respond to the ReadyCheck before the wait
+// timed out.  It matches ErrorTimeout with errors.Is
+type NotReadyError struct {
+	AppID string
+	// task endpoints (host:port) which weren't ready and why
+	Endpoints map[string]string
+}
+
+func (e *NotReadyError) Error() string {
+	msg := fmt.Sprintf("%s - %d task(s) of '%s' not ready", ErrorTimeout.Error(), len(e.Endpoints), e.AppID)
+	endpoints := utils.MapStringKeysToSlice(e.Endpoints)
+	sort.Strings(endpoints)
+	for _, endpoint := range endpoints {
+		msg = fmt.Sprintf("%s\n  %s: %s", msg, endpoint, e.Endpoints[endpoint])
+	}
+	return msg
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return ErrorTimeout
+}
+
+// Waits until every task of application {id} running its current configuration responds to the ready
+// check of the options.  Returns immediately when there is no ready check
+func (c *MarathonClient) waitForReady(ctx context.Context, id string, timeout time.Duration) error {
+	if c.opts == nil || c.opts.Ready == nil {
+		return nil
+	}
+	check := c.opts.Ready
+	t_now := time.Now()
+	log := logger.With(logWait, logger.Fields{logger.FieldApp: id})
+	client := &http.Client{Timeout: readyRequestTimeout}
+
+	failing := map[string]string{}
+	ready := func() (bool, error) {
+		app, err := c.GetApplicationCtx(ctx, id)
+		if err != nil {
+			return false, retryPoll(err)
+		}
+		endpoints := taskEndpoints(app)
+		failing = map[string]string{}
+		for _, endpoint := range endpoints {
+			if err := check.probe(ctx, client, endpoint); err != nil {
+				failing[endpoint] = err.Error()
+			}
+		}
+		if len(failing) == 0 {
+			c.clearWaitStatus()
+			elapsed := time.Since(t_now)
+			log.With(logger.Fields{logger.FieldDuration: elapsed}).Info("%d task(s) of %s respond to %s, elapsed time %s", len(endpoints), id, check.Path, utils.ElapsedStr(elapsed))
+			return true, nil
+		}
+		ready := len(endpoints) - len(failing)
+		if !c.reportWaitStatus(fmt.Sprintf("Waiting for the tasks of %s to respond to %s", id, check.Path), ready, len(endpoints)) {
+			log.Info("%d of %d task(s) of %s respond to %s. Retrying check in %v", ready, len(endpoints), id, check.Path, c.pollInterval())
+		}
+		return false, nil
+	}
+	return c.poll(ctx, timeout, ready, func() error { return &NotReadyError{AppID: id, Endpoints: failing} })
+}
+
+// Requests the check's path on {endpoint} returning an error unless it responds with the expected status
+func (r *ReadyCheck) probe(ctx context.Context, client *http.Client, endpoint string) error {
+	path := r.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	req, err := http.NewRequest("GET", "http://"+endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if resp.StatusCode != status {
+		return fmt.Errorf("GET %s returned %s, expected %d", path, resp.Status, status)
+	}
+	return nil
+}
+
+// Returns the advertised endpoints (host:first port) of the started tasks of {app} running its current
+// configuration
+func taskEndpoints(app *Application) []string {
+	since := ""
+	if app.VersionInfo != nil {
+		since = app.VersionInfo.LastConfigChangeAt
+	}
+	endpoints := []string{}
+	for _, t := range app.Tasks {
+		if t.Version < since || t.StartedAt == "" || len(t.Ports) == 0 {
+			continue
+		}
+		endpoints = append(endpoints, net.JoinHostPort(t.Host, strconv.Itoa(t.Ports[0])))
+	}
+	return endpoints
+}
@@ -0,0 +1,58 @@
+package marathon
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForDeploymentReady(t *testing.T) {
+	probes := 0
+	task := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+		assert.Equal(t, "/healthz", r.URL.Path)
+		if probes == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer task.Close()
+	u, _ := url.Parse(task.URL)
+
+	listed := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch p := path.Clean(r.URL.Path); p {
+		case "/v2/deployments":
+			if listed++; listed == 1 {
+				fmt.Fprint(w, `[{"id": "d1", "affectedApps": ["/web"]}]`)
+				return
+			}
+			fmt.Fprint(w, `[]`)
+		case "/v2/apps/web":
+			fmt.Fprintf(w, `{"app": {"id": "/web", "instances": 2, "versionInfo": {"lastConfigChangeAt": "v2"}, "tasks": [
+				{"host": "%s", "ports": [%s], "startedAt": "x", "version": "v2"},
+				{"host": "old", "ports": [1], "startedAt": "x", "version": "v1"}]}}`, u.Hostname(), u.Port())
+		case "/v2/queue":
+			fmt.Fprint(w, `{"queue": []}`)
+		}
+	}))
+	defer s.Close()
+
+	opts := &MarathonOptions{PollInterval: time.Millisecond, Ready: &ReadyCheck{Path: "healthz"}}
+	c := NewMarathonClientWithOpts(s.URL, "", "", opts)
+	result := c.WaitForDeploymentResult("d1", time.Minute)
+	assert.Nil(t, result.Err())
+	assert.Equal(t, 2, probes)
+
+	listed, opts.Ready.Status = 0, http.StatusNoContent
+	result = c.WaitForDeploymentResult("d1", 50*time.Millisecond)
+	assert.Equal(t, WaitNotReady, result.State)
+	assert.True(t, errors.Is(result.Err(), ErrorTimeout))
+	assert.Contains(t, result.Reason(), "1 task(s) of '/web' not ready\n  "+u.Host+": GET /healthz returned 200 OK, expected 204")
+}
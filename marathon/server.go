@@ -1,40 +1,107 @@
 package marathon
 
-import "net/url"
+import (
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+)
 
 func (c *MarathonClient) GetMarathonInfo() (*MarathonInfo, error) {
+	return c.GetMarathonInfoCtx(c.context())
+}
+
+func (c *MarathonClient) GetMarathonInfoCtx(ctx context.Context) (*MarathonInfo, error) {
 	info := new(MarathonInfo)
 
-	resp := c.http.HttpGet(c.marathonUrl(API_INFO), info)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_INFO), info)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return info, nil
 }
 
 func (c *MarathonClient) GetCurrentLeader() (*LeaderInfo, error) {
+	return c.GetCurrentLeaderCtx(c.context())
+}
+
+func (c *MarathonClient) GetCurrentLeaderCtx(ctx context.Context) (*LeaderInfo, error) {
 	info := new(LeaderInfo)
 
-	resp := c.http.HttpGet(c.marathonUrl(API_LEADER), info)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_LEADER), info)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return info, nil
 }
 
 func (c *MarathonClient) AbdicateLeader() (*Message, error) {
+	return c.AbdicateLeaderCtx(c.context())
+}
+
+func (c *MarathonClient) AbdicateLeaderCtx(ctx context.Context) (*Message, error) {
+	return c.AbdicateLeaderWithOptsCtx(ctx, nil)
+}
+
+func (c *MarathonClient) AbdicateLeaderWithOpts(opts *AbdicateOptions) (*Message, error) {
+	return c.AbdicateLeaderWithOptsCtx(c.context(), opts)
+}
+
+func (c *MarathonClient) AbdicateLeaderWithOptsCtx(ctx context.Context, opts *AbdicateOptions) (*Message, error) {
+	uri := c.marathonUrl(API_LEADER)
+	if opts != nil {
+		params := url.Values{}
+		if opts.Backup != "" {
+			params.Set("backup", opts.Backup)
+		}
+		if opts.Restore != "" {
+			params.Set("restore", opts.Restore)
+		}
+		if len(params) > 0 {
+			uri = uri + "?" + params.Encode()
+		}
+	}
+	log.Info("Abdicating the current leader")
+
 	msg := new(Message)
-	resp := c.http.HttpDelete(c.marathonUrl(API_LEADER), nil, msg)
+	resp := c.http.HttpDeleteCtx(ctx, uri, nil, msg)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return msg, nil
 }
 
+// Hostname returns the host name of the leader without the port
+func (l *LeaderInfo) Hostname() string {
+	if host, _, err := net.SplitHostPort(l.Leader); err == nil {
+		return host
+	}
+	return l.Leader
+}
+
+// Port returns the HTTP port of the leader or 0 if it isn't known
+func (l *LeaderInfo) Port() int {
+	if _, port, err := net.SplitHostPort(l.Leader); err == nil {
+		p, _ := strconv.Atoi(port)
+		return p
+	}
+	return 0
+}
+
+// IsHost returns true if the Marathon URL {host} (eg. http://m1:8080) is the leader
+func (l *LeaderInfo) IsHost(host string) bool {
+	u, err := url.Parse(host)
+	return err == nil && l.Leader != "" && u.Host == l.Leader
+}
+
 func (c *MarathonClient) Ping() (*MarathonPing, error) {
-	resp := c.http.HttpGet(c.marathonUrl(API_PING), nil)
+	return c.PingCtx(c.context())
+}
+
+func (c *MarathonClient) PingCtx(ctx context.Context) (*MarathonPing, error) {
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_PING), nil)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	host := c.host
 	if u, err := url.Parse(c.host); err == nil {
@@ -0,0 +1,74 @@
+package marathon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitHosts(t *testing.T) {
+	assert.Equal(t, []string{"http://m1:8080", "http://m2:8080"}, SplitHosts("http://m1:8080, http://m2:8080,"))
+	assert.Equal(t, []string{"http://m1:8080"}, SplitHosts("http://m1:8080"))
+}
+
+func TestLeaderDetection(t *testing.T) {
+	var leaderHits int
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaderHits++
+		fmt.Fprint(w, `{"apps": []}`)
+	}))
+	defer leader.Close()
+	u, _ := url.Parse(leader.URL)
+
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+API_LEADER {
+			fmt.Fprintf(w, `{"leader": "%s"}`, u.Host)
+			return
+		}
+		t.Errorf("request sent to follower: %s", r.URL.Path)
+	}))
+	defer follower.Close()
+
+	c := NewMarathonClient(follower.URL+","+leader.URL, "", "")
+	_, err := c.ListApplications()
+	assert.Nil(t, err)
+	_, err = c.ListApplications()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, leaderHits)
+}
+
+func TestLeaderInfo(t *testing.T) {
+	info := &LeaderInfo{Leader: "m1.local:8080"}
+	assert.Equal(t, "m1.local", info.Hostname())
+	assert.Equal(t, 8080, info.Port())
+	assert.True(t, info.IsHost("http://m1.local:8080"))
+	assert.False(t, info.IsHost("http://m2.local:8080"))
+
+	info = &LeaderInfo{Leader: "m1.local"}
+	assert.Equal(t, "m1.local", info.Hostname())
+	assert.Equal(t, 0, info.Port())
+}
+
+func TestAbdicateLeaderWithOpts(t *testing.T) {
+	var query url.Values
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		query = r.URL.Query()
+		fmt.Fprint(w, `{"message": "Leadership abdicated"}`)
+	}))
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	msg, err := c.AbdicateLeaderWithOpts(&AbdicateOptions{Backup: "file:///tmp/backup.zip"})
+	assert.Nil(t, err)
+	assert.Equal(t, "Leadership abdicated", msg.Message)
+	assert.Equal(t, url.Values{"backup": {"file:///tmp/backup.zip"}}, query)
+
+	_, err = c.AbdicateLeader()
+	assert.Nil(t, err)
+	assert.Empty(t, query)
+}
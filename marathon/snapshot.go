@@ -0,0 +1,35 @@
+package marathon
+
+import (
+	"time"
+)
+
+// AppSnapshot is the definition of an application along with the version and instances it ran, restorable
+// independent of Marathon's version history
+type AppSnapshot struct {
+	ID          string    `json:"id"`
+	Environment string    `json:"environment,omitempty"`
+	Created     time.Time `json:"created"`
+	// version of the application when the snapshot was taken
+	Version string `json:"version"`
+	// instances of the application when the snapshot was taken
+	Instances int          `json:"instances"`
+	App       *Application `json:"app"`
+}
+
+// Snapshot returns the snapshot of {app} running in environment {env}
+func Snapshot(app *Application, env string) (*AppSnapshot, error) {
+	definition, err := Definition(app)
+	if err != nil {
+		return nil, err
+	}
+	return &AppSnapshot{ID: app.ID, Environment: env, Created: time.Now().UTC(), Version: app.Version, Instances: app.Instances, App: definition}, nil
+}
+
+// Definition returns the definition of the application restoring the snapshot
+func (s *AppSnapshot) Definition() *Application {
+	app := *s.App
+	app.ID = s.ID
+	app.Instances = s.Instances
+	return &app
+}
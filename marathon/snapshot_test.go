@@ -0,0 +1,25 @@
+package marathon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	app := &Application{ID: "/web", Version: "v2", Instances: 3, TasksRunning: 3, Env: map[string]string{"MODE": "tuned"}}
+	snapshot, err := Snapshot(app, "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", snapshot.Environment)
+	assert.Equal(t, "v2", snapshot.Version)
+	assert.Equal(t, 3, snapshot.Instances)
+	assert.Equal(t, "", snapshot.App.Version)
+	assert.Equal(t, 0, snapshot.App.TasksRunning)
+
+	snapshot.Instances = 1
+	restored := snapshot.Definition()
+	assert.Equal(t, "/web", restored.ID)
+	assert.Equal(t, 1, restored.Instances)
+	assert.Equal(t, 3, snapshot.App.Instances)
+	assert.Equal(t, "tuned", restored.Env["MODE"])
+}
@@ -52,6 +52,7 @@ type Application struct {
 	Fetch                 []Fetch             `json:"fetch"`
 	Residency             *Residency          `json:"residency,omitempty"`
 	StoreURLs             []string            `json:"storeUrls,omitempty"`
+	Secrets               map[string]*Secret  `json:"secrets,omitempty"`
 }
 
 type KillTasksScale struct {
@@ -78,7 +79,7 @@ type DeploymentID struct {
 }
 
 type Container struct {
-	Type    string    `json:"type,omitempty"`
+	Type    string    `json:"type,omitempty" enum:"DOCKER|MESOS"`
 	Docker  *Docker   `json:"docker,omitempty"`
 	Volumes []*Volume `json:"volumes,omitempty"`
 }
@@ -105,7 +106,7 @@ type PortMapping struct {
 	ContainerPort int               `json:"containerPort,omitempty"`
 	HostPort      int               `json:"hostPort"`
 	ServicePort   int               `json:"servicePort,omitempty"`
-	Protocol      string            `json:"protocol"`
+	Protocol      string            `json:"protocol" enum:"tcp|udp|udp,tcp"`
 	Labels        map[string]string `json:"labels,omitempty"`
 }
 
@@ -117,9 +118,16 @@ type Parameters struct {
 type Volume struct {
 	ContainerPath string            `json:"containerPath,omitempty"`
 	HostPath      string            `json:"hostPath,omitempty"`
-	Mode          string            `json:"mode,omitempty"`
+	Mode          string            `json:"mode,omitempty" enum:"RO|RW"`
 	Persistent    *PersistentVolume `json:"persistent,omitempty"`
 	External      *ExternalVolume   `json:"external,omitempty"`
+	// Name of the secret (within the application's secrets) mounted as a file at ContainerPath
+	Secret string `json:"secret,omitempty"`
+}
+
+// Secret is a value from the cluster's secret store (eg. DC/OS secrets) made available to the application
+type Secret struct {
+	Source string `json:"source"`
 }
 
 type PersistentVolume struct {
@@ -136,7 +144,7 @@ type ExternalVolume struct {
 type Docker struct {
 	ForcePullImage bool           `json:"forcePullImage,omitempty"`
 	Image          string         `json:"image,omitempty"`
-	Network        string         `json:"network,omitempty"`
+	Network        string         `json:"network,omitempty" enum:"BRIDGE|HOST|USER|NONE"`
 	Parameters     []*Parameters  `json:"parameters,omitempty"`
 	PortMappings   []*PortMapping `json:"portMappings,omitempty"`
 	Privileged     bool           `json:"privileged,omitempty"`
@@ -148,13 +156,19 @@ type UpgradeStrategy struct {
 }
 
 type HealthCheck struct {
-	Protocol               string `json:"protocol,omitempty"`
+	Protocol               string `json:"protocol,omitempty" enum:"HTTP|HTTPS|TCP|COMMAND|MESOS_HTTP|MESOS_HTTPS|MESOS_TCP"`
 	Path                   string `json:"path,omitempty"`
 	GracePeriodSeconds     int    `json:"gracePeriodSeconds,omitempty"`
 	IntervalSeconds        int    `json:"intervalSeconds,omitempty"`
 	PortIndex              int    `json:"portIndex,omitempty"`
 	MaxConsecutiveFailures int    `json:"maxConsecutiveFailures,omitempty"`
 	TimeoutSeconds         int    `json:"timeoutSeconds,omitempty"`
+	// Command run by COMMAND health checks
+	Command *HealthCheckCommand `json:"command,omitempty"`
+}
+
+type HealthCheckCommand struct {
+	Value string `json:"value"`
 }
 
 type TaskIPAddress struct {
@@ -215,9 +229,38 @@ type Task struct {
 	Version           string               `json:"version"`
 }
 
+// QueuedTask is an application with tasks waiting to be launched
 type QueuedTask struct {
-	App   *Application    `json:"app"`
-	Delay map[string]bool `json:"delay"`
+	App *Application `json:"app"`
+	// number of tasks waiting to be launched
+	Count int        `json:"count"`
+	Delay QueueDelay `json:"delay"`
+	// time the application was first queued
+	Since                  string                  `json:"since,omitempty"`
+	ProcessedOffersSummary *ProcessedOffersSummary `json:"processedOffersSummary,omitempty"`
+}
+
+// QueueDelay is the backoff before Marathon next attempts to launch the tasks of a queued application
+type QueueDelay struct {
+	TimeLeftSeconds int  `json:"timeLeftSeconds"`
+	Overdue         bool `json:"overdue"`
+}
+
+// ProcessedOffersSummary describes the Mesos offers considered for a queued application and why they
+// were declined
+type ProcessedOffersSummary struct {
+	ProcessedOffersCount    int               `json:"processedOffersCount"`
+	UnusedOffersCount       int               `json:"unusedOffersCount"`
+	LastUnusedOfferAt       string            `json:"lastUnusedOfferAt,omitempty"`
+	LastUsedOfferAt         string            `json:"lastUsedOfferAt,omitempty"`
+	RejectSummaryLastOffers []*OfferRejection `json:"rejectSummaryLastOffers,omitempty"`
+}
+
+// OfferRejection is the number of offers declined for {Reason} (eg. InsufficientMemory)
+type OfferRejection struct {
+	Reason    string `json:"reason"`
+	Declined  int    `json:"declined"`
+	Processed int    `json:"processed"`
 }
 
 type Queue struct {
@@ -235,33 +278,49 @@ type Message struct {
 type Deploys []Deploy
 
 type Deploy struct {
-	AffectedApps   []string `json:"affectedApps"`
-	DeployID       string   `json:"id"`
-	Steps          [][]Step `json:"steps"`
-	CurrentActions []Step   `json:"currentActions"`
-	Version        string   `json:"version"`
-	CurrentStep    int      `json:"currentStep"`
-	TotalSteps     int      `json:"totalSteps"`
+	AffectedApps   []string            `json:"affectedApps"`
+	AffectedPods   []string            `json:"affectedPods,omitempty"`
+	DeployID       string              `json:"id"`
+	Steps          []*DeploymentStep   `json:"steps"`
+	CurrentActions []*DeploymentAction `json:"currentActions"`
+	Version        string              `json:"version"`
+	// 1-based index of the step in progress
+	CurrentStep int `json:"currentStep"`
+	TotalSteps  int `json:"totalSteps"`
 }
 
-type StepActions struct {
-	Actions []struct {
-		Type string `json:"type"`
-		App  string `json:"app"`
-	}
-}
+// StepActions is the step of deployment events
+type StepActions = DeploymentStep
 
 type DeploymentPlan struct {
-	ID       string  `json:"id"`
-	Version  string  `json:"version"`
-	Original *Group  `json:"original"`
-	Target   *Group  `json:"target"`
-	Steps    []*Step `json:"steps"`
+	ID       string            `json:"id"`
+	Version  string            `json:"version"`
+	Original *Group            `json:"original"`
+	Target   *Group            `json:"target"`
+	Steps    []*DeploymentStep `json:"steps"`
 }
 
-type Step struct {
-	Action string `json:"action"`
-	App    string `json:"app"`
+// DeploymentStep is a set of actions performed concurrently.  The steps of a deployment run in order
+type DeploymentStep struct {
+	Actions []*DeploymentAction `json:"actions"`
+}
+
+// DeploymentAction is an action (eg. ScaleApplication) performed on an application or pod by a step
+type DeploymentAction struct {
+	Action                string                  `json:"action"`
+	App                   string                  `json:"app,omitempty"`
+	Pod                   string                  `json:"pod,omitempty"`
+	ReadinessCheckResults []*ReadinessCheckResult `json:"readinessCheckResults,omitempty"`
+}
+
+// Step is the former name of DeploymentAction
+type Step = DeploymentAction
+
+// ReadinessCheckResult is the latest result of a readiness check of a task started by a deployment
+type ReadinessCheckResult struct {
+	Name   string `json:"name"`
+	TaskID string `json:"taskId"`
+	Ready  bool   `json:"ready"`
 }
 
 type AppOrGroup struct {
@@ -340,6 +399,16 @@ type MarathonInfo struct {
 	} `json:"zookeeper_config"`
 }
 
+// LeaderInfo identifies the Marathon instance currently elected leader
 type LeaderInfo struct {
+	// host:port of the leader (eg. m1.local:8080)
 	Leader string `json:"leader"`
 }
+
+// AbdicateOptions are the optional parameters of leader abdication (Marathon 1.4+)
+type AbdicateOptions struct {
+	// URL the state is backed up to before abdicating (eg. file:///var/backups/marathon.zip)
+	Backup string
+	// URL of a backup the next leader restores the state from
+	Restore string
+}
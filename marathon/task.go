@@ -1,20 +1,29 @@
 package marathon
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
 
 func (c *MarathonClient) ListTasks() ([]*Task, error) {
+	return c.ListTasksCtx(c.context())
+}
+
+func (c *MarathonClient) ListTasksCtx(ctx context.Context) ([]*Task, error) {
 	tasks := new(Tasks)
-	resp := c.http.HttpGet(c.marathonUrl(API_TASKS), &tasks)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_TASKS), &tasks)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return tasks.Tasks, nil
 }
 
 func (c *MarathonClient) KillAppTasks(id string, host string, scale bool) ([]*Task, error) {
+	return c.KillAppTasksCtx(c.context(), id, host, scale)
+}
+
+func (c *MarathonClient) KillAppTasksCtx(ctx context.Context, id string, host string, scale bool) ([]*Task, error) {
 	tasks := new(Tasks)
 
 	url := c.marathonUrl(API_APPS, id, PathTasks)
@@ -25,14 +34,18 @@ func (c *MarathonClient) KillAppTasks(id string, host string, scale bool) ([]*Ta
 			url = fmt.Sprintf("%s?host=%s&scale=%v", url, host, scale)
 		}
 	}
-	resp := c.http.HttpDelete(url, nil, tasks)
+	resp := c.http.HttpDeleteCtx(ctx, url, nil, tasks)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return tasks.Tasks, nil
 }
 
 func (c *MarathonClient) KillAppTask(taskId string, scale bool) (*Task, error) {
+	return c.KillAppTaskCtx(c.context(), taskId, scale)
+}
+
+func (c *MarathonClient) KillAppTaskCtx(ctx context.Context, taskId string, scale bool) (*Task, error) {
 	task := new(Task)
 	app := taskId[0:strings.LastIndex(taskId, ".")]
 	url := c.marathonUrl(API_APPS, app, PathTasks, taskId)
@@ -40,42 +53,66 @@ func (c *MarathonClient) KillAppTask(taskId string, scale bool) (*Task, error) {
 	if scale {
 		url = fmt.Sprintf("%s?scale=%v", url, scale)
 	}
-	resp := c.http.HttpDelete(url, nil, task)
+	resp := c.http.HttpDeleteCtx(ctx, url, nil, task)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return task, nil
 }
 
 func (c *MarathonClient) KillTasksAndScale(ids ...string) error {
+	return c.KillTasksAndScaleCtx(c.context(), ids...)
+}
+
+func (c *MarathonClient) KillTasksAndScaleCtx(ctx context.Context, ids ...string) error {
 	tasks := new(KillTasksScale)
 	tasks.IDs = ids
 
 	url := c.marathonUrl(API_TASKS_DELETE)
 	url = fmt.Sprintf("%s?scale=true", url)
-	resp := c.http.HttpPost(url, tasks, &Tasks{})
+	resp := c.http.HttpPostCtx(ctx, url, tasks, &Tasks{})
 
 	if resp.Error != nil {
 		log.Error(resp.Error.Error())
-		return resp.Error
+		return responseError(resp)
 	}
 	return nil
 }
 
 func (c *MarathonClient) GetTasks(id string) ([]*Task, error) {
+	return c.GetTasksCtx(c.context(), id)
+}
+
+func (c *MarathonClient) GetTasksCtx(ctx context.Context, id string) ([]*Task, error) {
 	tasks := new(Tasks)
-	resp := c.http.HttpGet(c.marathonUrl(API_APPS, id, PathTasks), &tasks)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_APPS, id, PathTasks), &tasks)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return tasks.Tasks, nil
 }
 
 func (c *MarathonClient) ListQueue() (*Queue, error) {
+	return c.ListQueueCtx(c.context())
+}
+
+func (c *MarathonClient) ListQueueCtx(ctx context.Context) (*Queue, error) {
 	q := new(Queue)
-	resp := c.http.HttpGet(c.marathonUrl(API_QUEUE), &q)
+	resp := c.http.HttpGetCtx(ctx, c.marathonUrl(API_QUEUE), &q)
 	if resp.Error != nil {
-		return nil, resp.Error
+		return nil, responseError(resp)
 	}
 	return q, nil
 }
+
+func (c *MarathonClient) ResetDelay(id string) error {
+	return c.ResetDelayCtx(c.context(), id)
+}
+
+func (c *MarathonClient) ResetDelayCtx(ctx context.Context, id string) error {
+	resp := c.http.HttpDeleteCtx(ctx, c.marathonUrl(API_QUEUE, id, PathDelay), nil, nil)
+	if resp.Error != nil {
+		return responseError(resp)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package marathon
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+const queueResponse = `{"queue": [{"count": 2, "delay": {"timeLeftSeconds": 120, "overdue": false},
+	"since": "2026-01-01T00:00:00.000Z", "app": {"id": "/web", "version": "2026-01-01T00:00:00.000Z"},
+	"processedOffersSummary": {"processedOffersCount": 10, "unusedOffersCount": 10,
+		"rejectSummaryLastOffers": [{"reason": "InsufficientMemory", "declined": 10, "processed": 10}]}}]}`
+
+func TestListQueue(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, queueResponse)
+	}))
+	defer s.Close()
+
+	q, err := NewMarathonClient(s.URL, "", "").ListQueue()
+	assert.Nil(t, err)
+	assert.Len(t, q.Queue, 1)
+	assert.Equal(t, "/web", q.Queue[0].App.ID)
+	assert.Equal(t, 2, q.Queue[0].Count)
+	assert.Equal(t, QueueDelay{TimeLeftSeconds: 120}, q.Queue[0].Delay)
+	assert.Equal(t, "InsufficientMemory", q.Queue[0].ProcessedOffersSummary.RejectSummaryLastOffers[0].Reason)
+}
+
+func TestResetDelay(t *testing.T) {
+	requests := []string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+path.Clean(r.URL.Path))
+		if path.Clean(r.URL.Path) != "/v2/queue/web/delay" {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Application /other not found in tasks queue."}`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	assert.Nil(t, c.ResetDelay("/web"))
+	assert.Equal(t, "DELETE /v2/queue/web/delay", requests[len(requests)-1])
+
+	err := c.ResetDelay("/other")
+	assert.Equal(t, CodeNotFound, ErrorCodeOf(err))
+	assert.ErrorIs(t, err, httpclient.ErrorNotFound)
+}
@@ -0,0 +1,231 @@
+package marathon
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+const maxPort = 65535
+
+var (
+	// segment of an application or group identifier accepted by Marathon
+	idSegmentPattern = regexp.MustCompile(`^(([a-z0-9]|[a-z0-9][a-z0-9\-]*[a-z0-9])\.)*([a-z0-9]|[a-z0-9][a-z0-9\-]*[a-z0-9])$|^\.$|^\.\.$`)
+
+	healthCheckProtocols = map[string]bool{
+		"HTTP": true, "HTTPS": true, "TCP": true, "COMMAND": true, "MESOS_HTTP": true, "MESOS_HTTPS": true, "MESOS_TCP": true,
+	}
+	constraintOperators = map[string]bool{
+		"UNIQUE": true, "CLUSTER": true, "GROUP_BY": true, "LIKE": true, "UNLIKE": true, "MAX_PER": true, "IS": true,
+	}
+	portProtocols = map[string]bool{"": true, "tcp": true, "udp": true, "udp,tcp": true, "tcp,udp": true}
+)
+
+// ValidationError is a descriptor rejected before it was sent to Marathon.  Details holds the offending
+// fields using the paths Marathon reports (eg. /healthChecks(0)/protocol)
+type ValidationError struct {
+	Details []httpclient.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msg := "Object is not valid"
+	for _, d := range e.Details {
+		msg = fmt.Sprintf("%s\n  %s: %s", msg, d.Path, strings.Join(d.Errors, ", "))
+	}
+	return msg
+}
+
+// validator collects the field errors of a descriptor
+type validator struct {
+	details []httpclient.FieldError
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	for i := range v.details {
+		if v.details[i].Path == path {
+			v.details[i].Errors = append(v.details[i].Errors, msg)
+			return
+		}
+	}
+	v.details = append(v.details, httpclient.FieldError{Path: path, Errors: []string{msg}})
+}
+
+func (v *validator) err() error {
+	if len(v.details) == 0 {
+		return nil
+	}
+	return &ValidationError{Details: v.details}
+}
+
+// Validate performs the checks Marathon would reject the application for (identifier format, port
+// ranges, health checks and resources) returning a ValidationError listing every offending field.  Only
+// fields which are set are checked so partial updates (eg. a new instance count) may be validated
+func (a *Application) Validate() error {
+	v := &validator{}
+	a.validate(v, "")
+	return v.err()
+}
+
+// Validate checks the group identifier and every application and group within it
+func (g *Group) Validate() error {
+	v := &validator{}
+	g.validate(v, "")
+	return v.err()
+}
+
+func (g *Group) validate(v *validator, prefix string) {
+	validateID(v, prefix+"/id", g.GroupID)
+	for i, app := range g.Apps {
+		if app != nil {
+			app.validate(v, fmt.Sprintf("%s/apps(%d)", prefix, i))
+		}
+	}
+	for i, group := range g.Groups {
+		if group != nil {
+			group.validate(v, fmt.Sprintf("%s/groups(%d)", prefix, i))
+		}
+	}
+}
+
+func (a *Application) validate(v *validator, prefix string) {
+	validateID(v, prefix+"/id", a.ID)
+
+	if a.Cmd != "" && len(a.Args) > 0 {
+		v.fail(prefix+"/cmd", "AppDefinition must not contain both cmd and args")
+	}
+
+	resources := []struct {
+		field string
+		value float64
+	}{{"cpus", a.CPUs}, {"mem", a.Mem}, {"disk", a.Disk}}
+	for _, r := range resources {
+		if r.value < 0 {
+			v.fail(prefix+"/"+r.field, "got %v, expected 0.0 or more", r.value)
+		}
+	}
+	if a.Instances < 0 {
+		v.fail(prefix+"/instances", "got %d, expected 0 or more", a.Instances)
+	}
+
+	for i, port := range a.Ports {
+		validatePort(v, fmt.Sprintf("%s/ports(%d)", prefix, i), port)
+	}
+
+	portCount := len(a.Ports)
+	if a.Container != nil && a.Container.Docker != nil {
+		docker := a.Container.Docker
+		if strings.TrimSpace(docker.Image) == "" {
+			v.fail(prefix+"/container/docker/image", "must not be empty")
+		}
+		for i, pm := range docker.PortMappings {
+			if pm == nil {
+				continue
+			}
+			path := fmt.Sprintf("%s/container/docker/portMappings(%d)", prefix, i)
+			validatePort(v, path+"/containerPort", pm.ContainerPort)
+			validatePort(v, path+"/hostPort", pm.HostPort)
+			validatePort(v, path+"/servicePort", pm.ServicePort)
+			if !portProtocols[pm.Protocol] {
+				v.fail(path+"/protocol", "'%s' is not one of tcp, udp, udp,tcp", pm.Protocol)
+			}
+		}
+		if len(docker.PortMappings) > 0 {
+			portCount = len(docker.PortMappings)
+		}
+	}
+
+	for i, hc := range a.HealthChecks {
+		if hc != nil {
+			hc.validate(v, fmt.Sprintf("%s/healthChecks(%d)", prefix, i), portCount)
+		}
+	}
+
+	if us := a.UpgradeStrategy; us != nil {
+		if us.MinimumHealthCapacity < 0 || us.MinimumHealthCapacity > 1 {
+			v.fail(prefix+"/upgradeStrategy/minimumHealthCapacity", "got %v, expected a value between 0.0 and 1.0", us.MinimumHealthCapacity)
+		}
+		if us.MaximumOverCapacity < 0 || us.MaximumOverCapacity > 1 {
+			v.fail(prefix+"/upgradeStrategy/maximumOverCapacity", "got %v, expected a value between 0.0 and 1.0", us.MaximumOverCapacity)
+		}
+	}
+
+	for i, c := range a.Constraints {
+		path := fmt.Sprintf("%s/constraints(%d)", prefix, i)
+		if len(c) < 2 || len(c) > 3 {
+			v.fail(path, "expected a field, an operator and an optional value")
+			continue
+		}
+		if !constraintOperators[strings.ToUpper(c[1])] {
+			v.fail(path, "'%s' is not a valid constraint operator", c[1])
+		}
+	}
+
+	if a.BackoffFactor < 0 {
+		v.fail(prefix+"/backoffFactor", "got %v, expected 0.0 or more", a.BackoffFactor)
+	}
+	if a.BackoffSeconds < 0 {
+		v.fail(prefix+"/backoffSeconds", "got %d, expected 0 or more", a.BackoffSeconds)
+	}
+}
+
+// Checks the health check at {path} against the {ports} of the application
+func (hc *HealthCheck) validate(v *validator, path string, ports int) {
+	protocol := hc.Protocol
+	if protocol == "" {
+		protocol = "HTTP"
+	}
+	if !healthCheckProtocols[protocol] {
+		v.fail(path+"/protocol", "'%s' is not a valid health check protocol", hc.Protocol)
+	}
+
+	switch protocol {
+	case "HTTP", "HTTPS", "MESOS_HTTP", "MESOS_HTTPS":
+	default:
+		if hc.Path != "" {
+			v.fail(path+"/path", "is only supported by HTTP health checks")
+		}
+	}
+
+	fields := []struct {
+		field string
+		value int
+	}{
+		{"gracePeriodSeconds", hc.GracePeriodSeconds},
+		{"intervalSeconds", hc.IntervalSeconds},
+		{"timeoutSeconds", hc.TimeoutSeconds},
+		{"maxConsecutiveFailures", hc.MaxConsecutiveFailures},
+		{"portIndex", hc.PortIndex},
+	}
+	for _, f := range fields {
+		if f.value < 0 {
+			v.fail(path+"/"+f.field, "got %d, expected 0 or more", f.value)
+		}
+	}
+	if hc.IntervalSeconds > 0 && hc.TimeoutSeconds > 0 && hc.TimeoutSeconds >= hc.IntervalSeconds {
+		v.fail(path+"/timeoutSeconds", "must be less than intervalSeconds")
+	}
+	if protocol != "COMMAND" && ports > 0 && hc.PortIndex >= ports {
+		v.fail(path+"/portIndex", "index %d is out of range for %d port(s)", hc.PortIndex, ports)
+	}
+}
+
+func validateID(v *validator, path, id string) {
+	for _, segment := range strings.Split(strings.Trim(id, "/"), "/") {
+		if segment != "" && !idSegmentPattern.MatchString(segment) {
+			v.fail(path, "'%s' is not a valid identifier - segments may contain lowercase letters, digits, hyphens and dots", id)
+			return
+		}
+	}
+	if strings.Contains(strings.Trim(id, "/"), "//") {
+		v.fail(path, "'%s' is not a valid identifier - empty path segment", id)
+	}
+}
+
+func validatePort(v *validator, path string, port int) {
+	if port < 0 || port > maxPort {
+		v.fail(path, "got %d, expected a port between 0 and %d", port, maxPort)
+	}
+}
@@ -0,0 +1,96 @@
+package marathon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func validApp() *Application {
+	return &Application{
+		ID:        "/web/nginx-1.10",
+		CPUs:      0.5,
+		Mem:       128,
+		Instances: 2,
+		Container: &Container{
+			Type: "DOCKER",
+			Docker: &Docker{
+				Image:        "nginx",
+				Network:      "BRIDGE",
+				PortMappings: []*PortMapping{{ContainerPort: 80, HostPort: 0, Protocol: "tcp"}},
+			},
+		},
+		HealthChecks:    []*HealthCheck{{Protocol: "HTTP", Path: "/", IntervalSeconds: 10, TimeoutSeconds: 5}},
+		UpgradeStrategy: &UpgradeStrategy{MinimumHealthCapacity: 1, MaximumOverCapacity: 0.5},
+		Constraints:     [][]string{{"hostname", "UNIQUE"}},
+	}
+}
+
+func TestApplicationValidate(t *testing.T) {
+	assert.NoError(t, validApp().Validate())
+	assert.NoError(t, (&Application{Instances: 3}).Validate(), "partial updates are valid")
+
+	app := validApp()
+	app.ID = "/web/Nginx_1"
+	app.Mem = -1
+	app.Cmd, app.Args = "nginx", []string{"nginx"}
+	app.Container.Docker.PortMappings[0].ContainerPort = 70000
+	app.HealthChecks = append(app.HealthChecks, &HealthCheck{Protocol: "TCP", Path: "/health", PortIndex: 1})
+	app.HealthChecks[0].TimeoutSeconds = 10
+	app.UpgradeStrategy.MaximumOverCapacity = 2
+	app.Constraints = [][]string{{"hostname", "SOMETIMES"}}
+
+	err := app.Validate()
+	assert.Equal(t, CodeValidation, ErrorCodeOf(err))
+
+	paths := []string{}
+	for _, d := range err.(*ValidationError).Details {
+		paths = append(paths, d.Path)
+	}
+	assert.Equal(t, []string{
+		"/id",
+		"/cmd",
+		"/mem",
+		"/container/docker/portMappings(0)/containerPort",
+		"/healthChecks(0)/timeoutSeconds",
+		"/healthChecks(1)/path",
+		"/healthChecks(1)/portIndex",
+		"/upgradeStrategy/maximumOverCapacity",
+		"/constraints(0)",
+	}, paths)
+	assert.Contains(t, err.Error(), "Object is not valid\n  /id: '/web/Nginx_1' is not a valid identifier")
+}
+
+func TestGroupValidate(t *testing.T) {
+	group := &Group{
+		GroupID: "/sites",
+		Apps:    []*Application{validApp()},
+		Groups:  []*Group{{GroupID: "/sites/db", Apps: []*Application{{ID: "pg", Disk: -5}}}},
+	}
+	err := group.Validate()
+	assert.Equal(t, &ValidationError{Details: []httpclient.FieldError{
+		{Path: "/groups(0)/apps(0)/disk", Errors: []string{"got -5, expected 0.0 or more"}},
+	}}, err)
+
+	group.Groups[0].Apps[0].Disk = 0
+	assert.NoError(t, group.Validate())
+}
+
+func TestCreateApplicationValidatesBeforeRequest(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer s.Close()
+
+	c := NewMarathonClient(s.URL, "", "")
+	_, err := c.CreateApplication(&Application{ID: "/Invalid"}, false, false)
+	assert.IsType(t, &ValidationError{}, err)
+
+	_, err = c.CreateGroup(&Group{GroupID: "/sites", Apps: []*Application{{ID: "web", Ports: []int{-1}}}}, false, false)
+	assert.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, 0, requests)
+}
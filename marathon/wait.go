@@ -1,90 +1,427 @@
 package marathon
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ContainX/depcon/pkg/httpclient"
 	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
 	"github.com/ContainX/depcon/utils"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 )
 
 var logWait = logger.GetLogger("depcon.deploy.wait")
 
+const (
+	// DefaultPollInterval is the delay between checks while waiting on deployments, applications and pods
+	DefaultPollInterval = time.Duration(2) * time.Second
+	// Longest delay between checks failing with transient errors
+	maxPollBackoff = time.Duration(30) * time.Second
+)
+
 func (c *MarathonClient) WaitForApplication(id string, timeout time.Duration) error {
+	return c.WaitForApplicationCtx(c.context(), id, timeout)
+}
+
+func (c *MarathonClient) WaitForApplicationCtx(ctx context.Context, id string, timeout time.Duration) error {
 	t_now := time.Now()
-	t_stop := t_now.Add(timeout)
+	t_stop := waitDeadline(t_now, timeout)
+	log := logger.With(logWait, logger.Fields{logger.FieldApp: id})
 
-	logWaitApplication(id)
-	for {
-		if time.Now().After(t_stop) {
-			return ErrorTimeout
-		}
-
-		app, err := c.GetApplication(id)
-		if err == nil {
-			if app.DeploymentID == nil || len(app.DeploymentID) <= 0 {
-				logWait.Info("Application deployment has completed for %s, elapsed time %s", id, utils.ElapsedStr(time.Since(t_now)))
-				if app.HealthChecks != nil && len(app.HealthChecks) > 0 {
-					err := c.WaitForApplicationHealthy(id, timeout)
-					if err != nil {
-						logWait.Error("Error waiting for application '%s' to become healthy: %s", id, err.Error())
-					}
-				} else {
-					logWait.Warning("No health checks defined for '%s', skipping waiting for healthy state", id)
-				}
-				return nil
-			}
+	c.waitStatus(0, 0, "Waiting for application deployment to complete for %s", id)
+	var progress []*AppProgress
+	var deployments []map[string]string
+	deployed := func() (bool, error) {
+		app, err := c.GetApplicationCtx(ctx, id)
+		if err != nil {
+			c.waitStatus(0, 0, "Waiting for application deployment to complete for %s", id)
+			return false, retryPoll(err)
+		}
+		deployments = app.DeploymentID
+		if len(app.DeploymentID) > 0 {
+			progress = []*AppProgress{progressOf(app)}
+			c.applyQueue(ctx, progress)
+			c.progressStatus("Waiting for application deployment to complete", progress, 0)
+			return false, nil
+		}
+		c.clearWaitStatus()
+		elapsed := time.Since(t_now)
+		log.With(logger.Fields{logger.FieldDuration: elapsed}).Info("Application deployment has completed for %s, elapsed time %s", id, utils.ElapsedStr(elapsed))
+		if len(app.HealthChecks) == 0 {
+			log.Warning("No health checks defined for '%s', skipping waiting for healthy state", id)
+			return true, c.waitForReady(ctx, id, remaining(t_stop))
+		}
+		err = c.WaitForApplicationHealthyCtx(ctx, id, timeout)
+		if errors.Is(err, ErrorTimeout) {
+			log.Error("%s", err.Error())
+			return false, ErrorDeploymentFailed
+		}
+		if err != nil {
+			log.Error("Error waiting for application '%s' to become healthy: %s", id, err.Error())
+			return false, err
+		}
+		return true, c.waitForReady(ctx, id, remaining(t_stop))
+	}
+	timedOut := func() error {
+		for _, d := range deployments {
+			c.cancelOnTimeout(ctx, d["id"])
 		}
-		logWaitApplication(id)
-		time.Sleep(time.Duration(2) * time.Second)
+		return timeoutError(progress, 0)
 	}
+	return c.pollEvents(ctx, timeout, NewEventFilter(id), deployed, timedOut)
 }
 
 func (c *MarathonClient) WaitForApplicationHealthy(id string, timeout time.Duration) error {
+	return c.WaitForApplicationHealthyCtx(c.context(), id, timeout)
+}
+
+func (c *MarathonClient) WaitForApplicationHealthyCtx(ctx context.Context, id string, timeout time.Duration) error {
 	t_now := time.Now()
-	t_stop := t_now.Add(timeout)
-	duration := time.Duration(2) * time.Second
-	for {
-		if time.Now().After(t_stop) {
-			return ErrorTimeout
-		}
-		app, err := c.GetApplication(id)
+	log := logger.With(logWait, logger.Fields{logger.FieldApp: id})
+	var progress []*AppProgress
+	healthy := func() (bool, error) {
+		app, err := c.GetApplicationCtx(ctx, id)
 		if err != nil {
-			return err
+			return false, err
 		}
+		progress = []*AppProgress{progressOf(app)}
 		total := app.TasksStaged + app.TasksRunning
 		diff := total - app.TasksHealthy
 		if diff == 0 {
-			logWait.Info("%v of %v expected instances are healthy.  Elapsed health check time of %s", app.TasksHealthy, total, utils.ElapsedStr(time.Since(t_now)))
-			return nil
+			c.clearWaitStatus()
+			elapsed := time.Since(t_now)
+			log.With(logger.Fields{logger.FieldDuration: elapsed}).Info("%v of %v expected instances are healthy.  Elapsed health check time of %s", app.TasksHealthy, total, utils.ElapsedStr(elapsed))
+			return true, nil
+		}
+		c.applyQueue(ctx, progress)
+		if !c.reportWaitStatus(fmt.Sprintf("Waiting to become healthy: %s", progress[0]), app.TasksHealthy, total) {
+			log.Info("%v healthy instances.  Waiting for %v total instances. Retrying check in %v", app.TasksHealthy, total, c.pollInterval())
 		}
-		logWait.Info("%v healthy instances.  Waiting for %v total instances. Retrying check in %v seconds", app.TasksHealthy, total, duration)
-		time.Sleep(duration)
+		return false, nil
 	}
+	return c.pollEvents(ctx, timeout, NewEventFilter(id), healthy, func() error { return timeoutError(progress, 0) })
+}
+
+func (c *MarathonClient) WaitForHealthyTasks(id string, quorum Quorum, timeout time.Duration) error {
+	return c.WaitForHealthyTasksCtx(c.context(), id, quorum, timeout)
+}
+
+// WaitForHealthyTasksCtx waits until {quorum} of the instances of application {id} are healthy tasks
+// running its current configuration rather than for the entire deployment to complete.  Tasks of
+// applications without health checks count once started
+func (c *MarathonClient) WaitForHealthyTasksCtx(ctx context.Context, id string, quorum Quorum, timeout time.Duration) error {
+	t_now := time.Now()
+	log := logger.With(logWait, logger.Fields{logger.FieldApp: id})
+	var progress []*AppProgress
+	reached := func() (bool, error) {
+		app, err := c.GetApplicationCtx(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		progress = []*AppProgress{progressOf(app)}
+		healthy, needed := currentHealthyTasks(app), quorum.Of(app.Instances)
+		if healthy >= needed {
+			c.clearWaitStatus()
+			elapsed := time.Since(t_now)
+			log.With(logger.Fields{logger.FieldDuration: elapsed}).Info("%v of %v required instances are healthy.  Elapsed time %s", healthy, needed, utils.ElapsedStr(elapsed))
+			return true, nil
+		}
+		c.applyQueue(ctx, progress)
+		if !c.reportWaitStatus(fmt.Sprintf("Waiting for %d healthy instances: %s", needed, progress[0]), healthy, needed) {
+			log.Info("%v of %v required instances are healthy. Retrying check in %v", healthy, needed, c.pollInterval())
+		}
+		return false, nil
+	}
+	return c.pollEvents(ctx, timeout, NewEventFilter(id), reached, func() error { return timeoutError(progress, 0) })
+}
+
+// Quorum is the number of an application's instances required to be healthy, either a count or a
+// percentage of the instances
+type Quorum struct {
+	Count   int
+	Percent float64
+}
+
+// ParseQuorum parses a count (eg. 3) or a percentage of the instances (eg. 75%)
+func ParseQuorum(s string) (Quorum, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || p <= 0 || p > 100 {
+			return Quorum{}, fmt.Errorf("Invalid percentage '%s' - expected a value between 0%% and 100%%", s)
+		}
+		return Quorum{Percent: p}, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return Quorum{}, fmt.Errorf("Invalid number of instances '%s' - expected a count (eg. 3) or a percentage (eg. 75%%)", s)
+	}
+	return Quorum{Count: n}, nil
+}
+
+// Of returns the number of {instances} required.  Percentages are rounded up and the result never
+// exceeds {instances}
+func (q Quorum) Of(instances int) int {
+	needed := q.Count
+	if q.Percent > 0 {
+		needed = int(math.Ceil(float64(instances) * q.Percent / 100))
+	}
+	if needed > instances {
+		return instances
+	}
+	return needed
+}
+
+// Returns the number of healthy tasks of {app} launched since its configuration last changed
+func currentHealthyTasks(app *Application) int {
+	since := ""
+	if app.VersionInfo != nil {
+		since = app.VersionInfo.LastConfigChangeAt
+	}
+	healthy := 0
+	for _, t := range app.Tasks {
+		if t.Version < since || t.StartedAt == "" {
+			continue
+		}
+		if len(app.HealthChecks) > 0 && !taskAlive(t) {
+			continue
+		}
+		healthy++
+	}
+	return healthy
+}
+
+func taskAlive(t *Task) bool {
+	if len(t.HealthCheckResult) == 0 {
+		return false
+	}
+	for _, r := range t.HealthCheckResult {
+		if r == nil || !r.Alive {
+			return false
+		}
+	}
+	return true
 }
 
 func (c *MarathonClient) WaitForDeployment(id string, timeout time.Duration) error {
+	return c.WaitForDeploymentCtx(c.context(), id, timeout)
+}
+
+func (c *MarathonClient) WaitForDeploymentCtx(ctx context.Context, id string, timeout time.Duration) error {
+	return c.WaitForDeploymentResultCtx(ctx, id, timeout).Err()
+}
 
+func (c *MarathonClient) WaitForDeploymentResult(id string, timeout time.Duration) *WaitResult {
+	return c.WaitForDeploymentResultCtx(c.context(), id, timeout)
+}
+
+// WaitForDeploymentResultCtx waits for the deployment {id} returning how the wait ended along with the
+// state of the deployment's applications when it didn't converge
+func (c *MarathonClient) WaitForDeploymentResultCtx(ctx context.Context, id string, timeout time.Duration) *WaitResult {
 	t_now := time.Now()
-	t_stop := t_now.Add(timeout)
+	c.waitStatus(0, 0, "Waiting for deployment %s", id)
+
+	t_stop := waitDeadline(t_now, timeout)
+	var progress []*AppProgress
+	var apps []string
+	more := 0
+	events := deploymentEventFilter(id)
+	completed := func() (bool, error) {
+		deployment, err := c.findDeployment(ctx, id)
+		if err != nil {
+			return false, retryPoll(err)
+		}
+		if deployment == nil {
+			c.clearWaitStatus()
+			elapsed := time.Since(t_now)
+			logger.With(logWait, logger.Fields{logger.FieldDeployment: id, logger.FieldDuration: elapsed}).Info("Deployment has completed for %s, elapsed time %s", id, utils.ElapsedStr(elapsed))
+			for _, app := range apps {
+				if err := c.waitForReady(ctx, app, remaining(t_stop)); err != nil {
+					return false, err
+				}
+			}
+			return true, nil
+		}
+		apps = deployment.AffectedApps
+		events.Apps = deployment.AffectedApps
+		progress, more = c.appProgress(ctx, deployment.AffectedApps)
+		c.progressStatus("Waiting for deployment "+id, progress, more)
+		return false, nil
+	}
+	err := c.pollEvents(ctx, timeout, events, completed, func() error { return ErrorTimeout })
+	result := waitResult(id, t_now, progress, more, err)
+	if result.State == WaitTimedOut || result.State == WaitStuck {
+		result.DeploymentCancelled = c.cancelOnTimeout(ctx, id)
+	}
+	return result
+}
+
+// Cancels the deployment {id} which timed out when MarathonOptions.CancelOnTimeout is set returning true
+// if it was cancelled
+func (c *MarathonClient) cancelOnTimeout(ctx context.Context, id string) bool {
+	if c.opts == nil || !c.opts.CancelOnTimeout {
+		return false
+	}
+	log := logger.With(logWait, logger.Fields{logger.FieldDeployment: id})
+	log.Warning("Deployment %s did not converge within the timeout, cancelling it (force: %v)", id, c.opts.ForceCancel)
+	if _, err := c.DeleteDeploymentCtx(ctx, id, c.opts.ForceCancel); err != nil {
+		log.Error("Unable to cancel deployment %s: %s", id, err.Error())
+		return false
+	}
+	return true
+}
+
+// Returns the deployment {id} or nil once it has completed
+func (c *MarathonClient) findDeployment(ctx context.Context, id string) (*Deploy, error) {
+	deployments, err := c.ListDeploymentsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments {
+		if deployment.DeployID == id {
+			return deployment, nil
+		}
+	}
+	return nil, nil
+}
+
+// retryableError marks an error of a poll which is retried after a backoff rather than ending the wait
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+// Marks {err} as transient so poll retries the check after a backoff.  Responses other than 429 and 5xx
+// (eg. unauthorized) are returned as is and end the wait
+func retryPoll(err error) error {
+	var e *MarathonError
+	if errors.As(err, &e) && e.Status != 429 && e.Status < 500 {
+		return err
+	}
+	return &retryableError{err: err}
+}
 
-	logWaitDeployment(id)
+// Calls {check} every poll interval until it's done, fails or {timeout} expires in which case the error
+// returned by {onTimeout} is returned.  Checks failing with a retryPoll error are retried with a delay
+// doubling from the poll interval up to maxPollBackoff which resets once a check succeeds
+func (c *MarathonClient) poll(ctx context.Context, timeout time.Duration, check func() (bool, error), onTimeout func() error) error {
+	return c.pollEvents(ctx, timeout, nil, check, onTimeout)
+}
 
+// Polls like poll while following the events matched by {filter} with MarathonOptions.WaitEvents.  Matching
+// events are reported as they arrive and end the pause before the next check
+func (c *MarathonClient) pollEvents(ctx context.Context, timeout time.Duration, filter *EventFilter, check func() (bool, error), onTimeout func() error) error {
+	t_stop := waitDeadline(time.Now(), timeout)
+	w := c.subscribeWait(ctx, filter)
+	defer w.close()
+	interval := c.pollInterval()
+	delay := interval
 	for {
-		if time.Now().After(t_stop) {
-			return ErrorTimeout
+		if expired(t_stop) {
+			c.clearWaitStatus()
+			return onTimeout()
 		}
-		if found, _ := c.HasDeployment(id); !found {
-			logWait.Info("Deployment has completed for %s, elapsed time %s", id, utils.ElapsedStr(time.Since(t_now)))
+		done, err := check()
+		var retry *retryableError
+		switch {
+		case errors.As(err, &retry):
+			delay = pollBackoff(delay, interval)
+			logWait.Debug("Check failed, retrying in %v: %s", delay, retry.err.Error())
+		case err != nil:
+			c.clearWaitStatus()
+			return err
+		case done:
+			c.clearWaitStatus()
 			return nil
+		default:
+			delay = interval
 		}
-		logWaitDeployment(id)
-		time.Sleep(time.Duration(2) * time.Second)
+		if err := c.sleepOrEvent(ctx, delay, w); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns the delay following {delay} after another failed check: double the previous delay, capped at
+// maxPollBackoff but never shorter than the poll {interval}
+func pollBackoff(delay, interval time.Duration) time.Duration {
+	delay *= 2
+	if delay > maxPollBackoff {
+		delay = maxPollBackoff
+	}
+	if delay < interval {
+		delay = interval
+	}
+	return delay
+}
+
+// Returns the delay between the checks made while waiting
+func (c *MarathonClient) pollInterval() time.Duration {
+	if c.opts != nil && c.opts.PollInterval > 0 {
+		return c.opts.PollInterval
+	}
+	return DefaultPollInterval
+}
+
+// Pauses between polls returning the error of {ctx} (clearing the status) if it's done first
+func (c *MarathonClient) sleep(ctx context.Context, d time.Duration) error {
+	if err := httpclient.Sleep(ctx, d); err != nil {
+		c.clearWaitStatus()
+		return err
+	}
+	return nil
+}
+
+// Reports the wait status through the progress reporter or logs it when there isn't one
+func (c *MarathonClient) waitStatus(done, total int, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if !c.reportWaitStatus(message, done, total) {
+		logWait.Info(message)
+	}
+}
+
+func (c *MarathonClient) reportWaitStatus(message string, done, total int) bool {
+	return c.opts != nil && progress.Report(c.opts.Progress, message, done, total)
+}
+
+func (c *MarathonClient) clearWaitStatus() {
+	if c.opts != nil {
+		progress.Clear(c.opts.Progress)
 	}
 }
 
-func logWaitDeployment(id string) {
-	logWait.Info("Waiting for deployment %s", id)
+// Returns the time waiting started at {start} gives up after {timeout} or the zero time when WaitForever
+// waits until the context is done
+func waitDeadline(start time.Time, timeout time.Duration) time.Time {
+	if timeout == WaitForever {
+		return time.Time{}
+	}
+	return start.Add(timeout)
+}
+
+// Returns the time left until {deadline} (at least a nanosecond so it doesn't wait forever) or zero when
+// there is no deadline
+func remaining(deadline time.Time) time.Duration {
+	if deadline.IsZero() {
+		return 0
+	}
+	if left := time.Until(deadline); left > 0 {
+		return left
+	}
+	return time.Nanosecond
 }
 
-func logWaitApplication(id string) {
-	logWait.Info("Waiting for application deployment to complete for %s", id)
+func expired(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().After(deadline)
 }
@@ -0,0 +1,112 @@
+package marathon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WaitState is how waiting on a deployment ended
+type WaitState string
+
+const (
+	// every step of the deployment completed
+	WaitConverged WaitState = "converged"
+	// the deployment was still running when the timeout expired
+	WaitTimedOut WaitState = "timed out"
+	// the timeout expired with tasks queued because no offer satisfies their requirements
+	WaitStuck WaitState = "stuck in queue"
+	// Marathon couldn't be queried (eg. the request was unauthorized)
+	WaitFailed WaitState = "failed"
+	// the context of the wait was cancelled (eg. Ctrl-C)
+	WaitCancelled WaitState = "cancelled"
+	// the deployment completed but its tasks didn't respond to the ready check (MarathonOptions.Ready)
+	WaitNotReady WaitState = "not ready"
+)
+
+// WaitResult is the outcome of waiting on a deployment
+type WaitResult struct {
+	DeploymentID string        `json:"deploymentId"`
+	State        WaitState     `json:"state"`
+	Elapsed      time.Duration `json:"elapsed"`
+	// State of the deployment's applications when waiting ended.  Empty once converged
+	Apps []*AppProgress `json:"apps,omitempty"`
+	// applications of the deployment which weren't read
+	More int `json:"more,omitempty"`
+	// true if the deployment was cancelled after timing out (MarathonOptions.CancelOnTimeout)
+	DeploymentCancelled bool `json:"deploymentCancelled,omitempty"`
+	// Error which failed or cancelled the wait
+	Cause error `json:"-"`
+}
+
+// Converged returns true if the deployment completed
+func (r *WaitResult) Converged() bool {
+	return r.State == WaitConverged
+}
+
+// Err returns nil once converged, a TimeoutError describing the applications when the wait timed out or
+// got stuck and the cause of a failed or cancelled wait
+func (r *WaitResult) Err() error {
+	switch r.State {
+	case WaitConverged:
+		return nil
+	case WaitTimedOut, WaitStuck:
+		return timeoutError(r.Apps, r.More)
+	default:
+		return r.Cause
+	}
+}
+
+// Reason describes why the deployment didn't converge (eg. the offers declined for the queued tasks)
+func (r *WaitResult) Reason() string {
+	if r.DeploymentCancelled {
+		return r.reason() + " (deployment cancelled)"
+	}
+	return r.reason()
+}
+
+func (r *WaitResult) reason() string {
+	switch r.State {
+	case WaitConverged:
+		return ""
+	case WaitStuck:
+		for _, app := range r.Apps {
+			if app.WaitingForOffers {
+				return fmt.Sprintf("%s: %s", r.State, app)
+			}
+		}
+	case WaitFailed, WaitCancelled, WaitNotReady:
+		if r.Cause != nil {
+			return fmt.Sprintf("%s: %s", r.State, r.Cause.Error())
+		}
+	}
+	return string(r.State)
+}
+
+// Returns the result of a wait on deployment {id} started at {start} which ended with {err} when the
+// applications were last seen in the state {progress}
+func waitResult(id string, start time.Time, progress []*AppProgress, more int, err error) *WaitResult {
+	r := &WaitResult{DeploymentID: id, State: WaitConverged, Elapsed: time.Since(start), Cause: err}
+	var notReady *NotReadyError
+	switch {
+	case err == nil:
+		return r
+	case errors.As(err, &notReady):
+		r.State = WaitNotReady
+		return r
+	case errors.Is(err, ErrorTimeout):
+		r.State = WaitTimedOut
+		for _, app := range progress {
+			if app.WaitingForOffers {
+				r.State = WaitStuck
+			}
+		}
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		r.State = WaitCancelled
+	default:
+		r.State = WaitFailed
+	}
+	r.Apps, r.More = progress, more
+	return r
+}
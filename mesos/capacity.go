@@ -0,0 +1,181 @@
+package mesos
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	ResourceCPUs = "cpus"
+	ResourceMem  = "mem"
+	ResourceDisk = "disk"
+
+	// Role of unreserved resources
+	RoleAny = "*"
+)
+
+// Requirement describes the instances of a deployment which must be placed on the cluster
+type Requirement struct {
+	ID        string
+	Instances int
+	CPUs      float64
+	Mem       float64
+	Disk      float64
+	// Roles whose resources the instances may use.  Default: unreserved resources only
+	Roles []string
+	// Role whose quota limits the resources consumed.  No quota is checked when empty
+	QuotaRole string
+}
+
+// AgentCapacity is the free resources of an agent usable by a requirement and the number of its
+// instances which fit
+type AgentCapacity struct {
+	Hostname  string  `json:"hostname"`
+	CPUs      float64 `json:"cpus"`
+	Mem       float64 `json:"mem"`
+	Disk      float64 `json:"disk"`
+	Instances int     `json:"instances"`
+	// Why more instances don't fit (eg. the resource which runs out)
+	Reason string `json:"reason,omitempty"`
+}
+
+// CapacityReport is the outcome of checking a requirement against the cluster
+type CapacityReport struct {
+	ID       string           `json:"id"`
+	Required int              `json:"required"`
+	Fits     int              `json:"fits"`
+	Agents   []*AgentCapacity `json:"agents"`
+	// Instances the quota of QuotaRole permits or -1 when unlimited
+	QuotaRole      string `json:"quotaRole,omitempty"`
+	QuotaInstances int    `json:"quotaInstances"`
+	QuotaReason    string `json:"quotaReason,omitempty"`
+}
+
+// Satisfied returns true if the required instances can be placed within the quota
+func (r *CapacityReport) Satisfied() bool {
+	return r.Fits >= r.Required && (r.QuotaInstances < 0 || r.QuotaInstances >= r.Required)
+}
+
+// Shortfall explains why the required instances can't be placed listing the limit of each agent and of
+// the quota.  It's empty when the report is satisfied
+func (r *CapacityReport) Shortfall() []string {
+	if r.Satisfied() {
+		return nil
+	}
+	lines := []string{}
+	if r.Fits < r.Required {
+		lines = append(lines, fmt.Sprintf("%s: %d instance(s) required but the agents can place %d", r.ID, r.Required, r.Fits))
+		for _, a := range r.Agents {
+			lines = append(lines, fmt.Sprintf("  %s: %d instance(s) - %s", a.Hostname, a.Instances, a.Reason))
+		}
+	}
+	if r.QuotaInstances >= 0 && r.QuotaInstances < r.Required {
+		lines = append(lines, fmt.Sprintf("%s: %d instance(s) required but the quota of role '%s' permits %d - %s",
+			r.ID, r.Required, r.QuotaRole, r.QuotaInstances, r.QuotaReason))
+	}
+	return lines
+}
+
+// CheckCapacity determines how many instances of {req} fit on the free resources of the active {agents}
+// and within the quota of the requirement's role found in {roles}
+func CheckCapacity(agents []*Agent, roles []*Role, req *Requirement) *CapacityReport {
+	report := &CapacityReport{ID: req.ID, Required: req.Instances, Agents: []*AgentCapacity{}, QuotaInstances: -1}
+	usable := req.Roles
+	if len(usable) == 0 {
+		usable = []string{RoleAny}
+	}
+
+	for _, a := range agents {
+		c := &AgentCapacity{Hostname: a.Hostname()}
+		report.Agents = append(report.Agents, c)
+		if !a.Active {
+			c.Reason = "agent is inactive"
+			continue
+		}
+		free := freeResources(a, usable)
+		c.CPUs, c.Mem, c.Disk = free[ResourceCPUs], free[ResourceMem], free[ResourceDisk]
+		c.Instances, c.Reason = fit(req, free, "free")
+		report.Fits += c.Instances
+	}
+	sort.SliceStable(report.Agents, func(i, j int) bool {
+		return report.Agents[i].Instances > report.Agents[j].Instances
+	})
+
+	if req.QuotaRole == "" {
+		return report
+	}
+	for _, r := range roles {
+		if r.Name != req.QuotaRole || r.Quota == nil || len(r.Quota.Limit) == 0 {
+			continue
+		}
+		remaining := map[string]float64{}
+		for name, limit := range r.Quota.Limit {
+			remaining[name] = math.Max(limit-r.Quota.Consumed[name], 0)
+		}
+		if limitingResource(req, remaining) != "" {
+			report.QuotaRole = r.Name
+			report.QuotaInstances, report.QuotaReason = fit(req, remaining, "remaining")
+		}
+	}
+	return report
+}
+
+// Returns the unreserved resources of {a} and those reserved for {roles} which aren't allocated
+func freeResources(a *Agent, roles []string) map[string]float64 {
+	usable := func(r *Resource) bool {
+		role := r.ReservedRole()
+		if role == "" {
+			return utils.StringInSlice(RoleAny, roles)
+		}
+		return utils.StringInSlice(role, roles)
+	}
+	free := map[string]float64{ResourceCPUs: 0, ResourceMem: 0, ResourceDisk: 0}
+	for _, r := range a.TotalResources {
+		if r.Scalar != nil && usable(r) {
+			free[r.Name] += r.Scalar.Value
+		}
+	}
+	for _, r := range a.AllocatedResources {
+		if r.Scalar != nil && usable(r) {
+			free[r.Name] -= r.Scalar.Value
+		}
+	}
+	for name, v := range free {
+		free[name] = math.Max(v, 0)
+	}
+	return free
+}
+
+// Returns the number of instances of {req} which fit within {available} and the resource limiting them
+func fit(req *Requirement, available map[string]float64, label string) (int, string) {
+	name := limitingResource(req, available)
+	if name == "" {
+		return req.Instances, ""
+	}
+	need := needed(req)[name]
+	n := int(math.Floor(available[name]/need + 1e-9))
+	return n, fmt.Sprintf("%s %g %s, %g per instance", name, available[name], label, need)
+}
+
+// Returns the resource of {available} which permits the fewest instances of {req} or an empty string
+// when the requirement needs none of the resources.  Resources missing from {available} are unlimited
+func limitingResource(req *Requirement, available map[string]float64) string {
+	limiting, fewest := "", math.MaxFloat64
+	for _, name := range []string{ResourceCPUs, ResourceMem, ResourceDisk} {
+		need := needed(req)[name]
+		if _, ok := available[name]; !ok || need <= 0 {
+			continue
+		}
+		if n := available[name] / need; n < fewest {
+			limiting, fewest = name, n
+		}
+	}
+	return limiting
+}
+
+func needed(req *Requirement) map[string]float64 {
+	return map[string]float64{ResourceCPUs: req.CPUs, ResourceMem: req.Mem, ResourceDisk: req.Disk}
+}
@@ -0,0 +1,83 @@
+package mesos
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const capacityAgents = `[
+	{"agent_info": {"hostname": "a1"}, "active": true,
+	 "total_resources": [
+		{"name": "cpus", "type": "SCALAR", "scalar": {"value": 4}},
+		{"name": "mem", "type": "SCALAR", "scalar": {"value": 8192}},
+		{"name": "cpus", "type": "SCALAR", "scalar": {"value": 2}, "reservations": [{"role": "marathon"}]}],
+	 "allocated_resources": [
+		{"name": "cpus", "type": "SCALAR", "scalar": {"value": 3}},
+		{"name": "mem", "type": "SCALAR", "scalar": {"value": 1024}}]},
+	{"agent_info": {"hostname": "a2"}, "active": true,
+	 "total_resources": [
+		{"name": "cpus", "type": "SCALAR", "scalar": {"value": 8}},
+		{"name": "mem", "type": "SCALAR", "scalar": {"value": 1024}}]},
+	{"agent_info": {"hostname": "a3"}, "active": false,
+	 "total_resources": [{"name": "cpus", "type": "SCALAR", "scalar": {"value": 16}}]}
+]`
+
+func agents(t *testing.T) []*Agent {
+	agents := []*Agent{}
+	assert.Nil(t, json.Unmarshal([]byte(capacityAgents), &agents))
+	return agents
+}
+
+func TestCheckCapacity(t *testing.T) {
+	req := &Requirement{ID: "/web", Instances: 4, CPUs: 1, Mem: 512}
+
+	report := CheckCapacity(agents(t), nil, req)
+	assert.False(t, report.Satisfied())
+	// a1: 1 unreserved cpu free, a2: 1024 mem fits 2, a3: inactive
+	assert.Equal(t, 3, report.Fits)
+	assert.Equal(t, "a2", report.Agents[0].Hostname)
+	assert.Equal(t, "mem 1024 free, 512 per instance", report.Agents[0].Reason)
+	assert.Equal(t, "agent is inactive", report.Agents[2].Reason)
+	assert.Len(t, report.Shortfall(), 4)
+
+	req.Roles = []string{RoleAny, "marathon"}
+	report = CheckCapacity(agents(t), nil, req)
+	assert.True(t, report.Satisfied())
+	assert.Equal(t, 5, report.Fits)
+	assert.Nil(t, report.Shortfall())
+}
+
+func TestCheckCapacityQuota(t *testing.T) {
+	roles := []*Role{}
+	assert.Nil(t, json.Unmarshal([]byte(`[
+		{"name": "marathon", "quota": {"role": "marathon", "limits": {"cpus": {"value": 10}}, "consumed": {"cpus": {"value": 9}}}},
+		{"name": "spark", "quota": {"limit": {"mem": 1}}}]`), &roles))
+	assert.Equal(t, 10.0, roles[0].Quota.Limit["cpus"])
+	assert.Equal(t, 1.0, roles[1].Quota.Limit["mem"])
+
+	req := &Requirement{ID: "/web", Instances: 2, CPUs: 1, Mem: 128, Roles: []string{RoleAny, "marathon"}, QuotaRole: "marathon"}
+	report := CheckCapacity(agents(t), roles, req)
+	assert.Equal(t, 1, report.QuotaInstances)
+	assert.False(t, report.Satisfied())
+	assert.Contains(t, report.Shortfall()[0], "quota of role 'marathon' permits 1 - cpus 1 remaining")
+
+	// the quota of spark doesn't limit cpus or disk
+	req.Mem, req.QuotaRole = 0, "spark"
+	report = CheckCapacity(agents(t), roles, req)
+	assert.Equal(t, -1, report.QuotaInstances)
+	assert.True(t, report.Satisfied())
+}
+
+func TestListRoles(t *testing.T) {
+	c, _, stop := newTestClient(map[string]string{
+		"GET_ROLES": `{"type": "GET_ROLES", "get_roles": {"roles": [{"name": "marathon", "quota": {"limits": {"mem": {"value": 2048}}}}]}}`,
+	}, true)
+	defer stop()
+
+	roles, err := c.ListRoles()
+	assert.Nil(t, err)
+	assert.Equal(t, "marathon", roles[0].Name)
+	assert.Equal(t, 2048.0, roles[0].Quota.Limit["mem"])
+}
@@ -0,0 +1,240 @@
+package mesos
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Constraint operators of Marathon
+const (
+	OperatorUnique  = "UNIQUE"
+	OperatorCluster = "CLUSTER"
+	OperatorGroupBy = "GROUP_BY"
+	OperatorLike    = "LIKE"
+	OperatorUnlike  = "UNLIKE"
+	OperatorMaxPer  = "MAX_PER"
+	OperatorIs      = "IS"
+)
+
+// ConstraintResult is the number of active agents a constraint admits and the instances it permits
+type ConstraintResult struct {
+	Constraint string `json:"constraint"`
+	Matching   int    `json:"matching"`
+	// Instances the constraint permits on the eligible agents or -1 when it doesn't limit them
+	Limit int `json:"limit"`
+	// Why the constraint couldn't be evaluated or limits the instances
+	Reason string `json:"reason,omitempty"`
+}
+
+// ConstraintReport is the outcome of checking the constraints of an application against the agents
+type ConstraintReport struct {
+	ID       string `json:"id"`
+	Required int    `json:"required"`
+	Active   int    `json:"active"`
+	// Active agents satisfying every constraint
+	Eligible int `json:"eligible"`
+	// Instances which can be placed on the eligible agents within their free resources and the constraints
+	Placeable   int                 `json:"placeable"`
+	Constraints []*ConstraintResult `json:"constraints"`
+	Agents      []string            `json:"agents"`
+}
+
+// Satisfied returns true if the required instances can be placed
+func (r *ConstraintReport) Satisfied() bool {
+	return r.Placeable >= r.Required
+}
+
+// compiled constraint of an application
+type constraint struct {
+	field    string
+	operator string
+	value    string
+	like     *regexp.Regexp
+	result   *ConstraintResult
+}
+
+// CheckConstraints determines which active {agents} satisfy {constraints} (eg. [["hostname", "UNIQUE"]]) and
+// how many instances of {req} can be placed on them within their free resources.  Constraints which limit
+// instances per value (UNIQUE, MAX_PER, CLUSTER) are applied to the instances each group of agents can hold
+func CheckConstraints(agents []*Agent, constraints [][]string, req *Requirement) *ConstraintReport {
+	report := &ConstraintReport{ID: req.ID, Required: req.Instances, Constraints: []*ConstraintResult{}, Agents: []string{}}
+	usable := req.Roles
+	if len(usable) == 0 {
+		usable = []string{RoleAny}
+	}
+
+	compiled := []*constraint{}
+	for _, c := range constraints {
+		cc, err := compileConstraint(c)
+		report.Constraints = append(report.Constraints, cc.result)
+		if err != nil {
+			cc.result.Reason = err.Error()
+			continue
+		}
+		compiled = append(compiled, cc)
+	}
+
+	eligible := []*Agent{}
+	fits := map[*Agent]int{}
+	for _, a := range agents {
+		if !a.Active {
+			continue
+		}
+		report.Active++
+		admitted := true
+		for _, c := range compiled {
+			if c.admits(a) {
+				c.result.Matching++
+			} else {
+				admitted = false
+			}
+		}
+		if admitted {
+			eligible = append(eligible, a)
+			report.Agents = append(report.Agents, a.Hostname())
+			fits[a], _ = fit(req, freeResources(a, usable), "free")
+		}
+	}
+	report.Eligible = len(eligible)
+	sort.Strings(report.Agents)
+
+	total := 0
+	for _, a := range eligible {
+		total += fits[a]
+	}
+	report.Placeable = total
+	for _, c := range compiled {
+		if limit, ok := c.limit(eligible, fits); ok {
+			c.result.Limit = limit
+			if limit < report.Placeable {
+				report.Placeable = limit
+			}
+		}
+	}
+	return report
+}
+
+func compileConstraint(c []string) (*constraint, error) {
+	cc := &constraint{result: &ConstraintResult{Constraint: strings.Join(c, ":"), Limit: -1}}
+	if len(c) < 2 || len(c) > 3 {
+		return cc, fmt.Errorf("expected a field, an operator and an optional value")
+	}
+	cc.field, cc.operator = c[0], strings.ToUpper(c[1])
+	if len(c) == 3 {
+		cc.value = c[2]
+	}
+	switch cc.operator {
+	case OperatorLike, OperatorUnlike:
+		re, err := regexp.Compile("^(?:" + cc.value + ")$")
+		if err != nil {
+			return cc, fmt.Errorf("invalid regular expression: %s", err.Error())
+		}
+		cc.like = re
+	case OperatorMaxPer:
+		if n, err := strconv.Atoi(cc.value); err != nil || n < 1 {
+			return cc, fmt.Errorf("MAX_PER requires a positive number")
+		}
+	case OperatorIs:
+		if len(c) != 3 {
+			return cc, fmt.Errorf("IS requires a value")
+		}
+	case OperatorUnique, OperatorCluster, OperatorGroupBy:
+	default:
+		return cc, fmt.Errorf("'%s' is not a valid constraint operator", c[1])
+	}
+	return cc, nil
+}
+
+// Returns true if agent {a} may run instances under the constraint
+func (c *constraint) admits(a *Agent) bool {
+	value, ok := fieldOf(a, c.field)
+	switch c.operator {
+	case OperatorUnlike:
+		return !ok || !c.like.MatchString(value)
+	case OperatorLike:
+		return ok && c.like.MatchString(value)
+	case OperatorIs:
+		return ok && value == c.value
+	case OperatorCluster:
+		return ok && (c.value == "" || value == c.value)
+	}
+	return ok
+}
+
+// Returns the instances the constraint permits on {agents} each holding {fits} instances and false when the
+// constraint doesn't limit the instances
+func (c *constraint) limit(agents []*Agent, fits map[*Agent]int) (int, bool) {
+	perValue := 0
+	switch c.operator {
+	case OperatorUnique:
+		perValue = 1
+	case OperatorMaxPer:
+		perValue, _ = strconv.Atoi(c.value)
+	case OperatorCluster:
+		if c.value != "" {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+
+	groups := map[string]int{}
+	for _, a := range agents {
+		value, _ := fieldOf(a, c.field)
+		groups[value] += fits[a]
+	}
+	limit := 0
+	for _, n := range groups {
+		switch {
+		case c.operator == OperatorCluster:
+			// every instance runs on agents sharing the value of the first instance
+			if n > limit {
+				limit = n
+			}
+		case n < perValue:
+			limit += n
+		default:
+			limit += perValue
+		}
+	}
+	switch c.operator {
+	case OperatorCluster:
+		c.result.Reason = fmt.Sprintf("instances share one of %d value(s)", len(groups))
+	default:
+		c.result.Reason = fmt.Sprintf("%d per value, %d value(s)", perValue, len(groups))
+	}
+	return limit, true
+}
+
+// Returns the value of {field} of agent {a}: its hostname (hostname or @hostname), fault domain (@region or
+// @zone) or attribute.  Returns false when the agent has no such value
+func fieldOf(a *Agent, field string) (string, bool) {
+	switch field {
+	case "hostname", "@hostname":
+		return a.Hostname(), a.Hostname() != ""
+	case "@region", "@zone":
+		if a.Info == nil || a.Info.Domain == nil || a.Info.Domain.FaultDomain == nil {
+			return "", false
+		}
+		fd := a.Info.Domain.FaultDomain
+		if field == "@region" && fd.Region != nil {
+			return fd.Region.Name, true
+		}
+		if field == "@zone" && fd.Zone != nil {
+			return fd.Zone.Name, true
+		}
+		return "", false
+	}
+	if a.Info == nil {
+		return "", false
+	}
+	for _, attr := range a.Info.Attributes {
+		if attr.Name == field {
+			return attr.Value(), true
+		}
+	}
+	return "", false
+}
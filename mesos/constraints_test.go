@@ -0,0 +1,63 @@
+package mesos
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const constraintAgents = `[
+	{"agent_info": {"hostname": "a1", "attributes": [{"name": "rack", "type": "TEXT", "text": {"value": "r1"}}, {"name": "gpu", "type": "SCALAR", "scalar": {"value": 1}}],
+	 "domain": {"fault_domain": {"region": {"name": "us-east"}, "zone": {"name": "us-east-1a"}}}},
+	 "active": true, "total_resources": [{"name": "cpus", "type": "SCALAR", "scalar": {"value": 4}}]},
+	{"agent_info": {"hostname": "a2", "attributes": [{"name": "rack", "type": "TEXT", "text": {"value": "r1"}}],
+	 "domain": {"fault_domain": {"region": {"name": "us-east"}, "zone": {"name": "us-east-1b"}}}},
+	 "active": true, "total_resources": [{"name": "cpus", "type": "SCALAR", "scalar": {"value": 4}}]},
+	{"agent_info": {"hostname": "a3", "attributes": [{"name": "rack", "type": "TEXT", "text": {"value": "r2"}}]},
+	 "active": true, "total_resources": [{"name": "cpus", "type": "SCALAR", "scalar": {"value": 1}}]},
+	{"agent_info": {"hostname": "a4", "attributes": [{"name": "rack", "type": "TEXT", "text": {"value": "r3"}}]},
+	 "active": false, "total_resources": [{"name": "cpus", "type": "SCALAR", "scalar": {"value": 16}}]}
+]`
+
+func TestCheckConstraints(t *testing.T) {
+	agents := []*Agent{}
+	assert.Nil(t, json.Unmarshal([]byte(constraintAgents), &agents))
+	assert.Equal(t, "1", agents[0].Info.Attributes[1].Value())
+	req := &Requirement{ID: "/web", Instances: 3, CPUs: 1}
+
+	report := CheckConstraints(agents, nil, req)
+	assert.Equal(t, 3, report.Active)
+	assert.Equal(t, 3, report.Eligible)
+	assert.Equal(t, 9, report.Placeable)
+	assert.True(t, report.Satisfied())
+
+	// one instance per rack on the two active racks
+	report = CheckConstraints(agents, [][]string{{"rack", "UNIQUE"}}, req)
+	assert.Equal(t, 3, report.Eligible)
+	assert.Equal(t, 2, report.Placeable)
+	assert.Equal(t, 2, report.Constraints[0].Limit)
+	assert.False(t, report.Satisfied())
+
+	report = CheckConstraints(agents, [][]string{{"rack", "LIKE", "r1"}, {"hostname", "MAX_PER", "1"}}, req)
+	assert.Equal(t, 2, report.Constraints[0].Matching)
+	assert.Equal(t, []string{"a1", "a2"}, report.Agents)
+	assert.Equal(t, 2, report.Placeable)
+
+	report = CheckConstraints(agents, [][]string{{"gpu", "IS", "1"}}, req)
+	assert.Equal(t, []string{"a1"}, report.Agents)
+	assert.Equal(t, 4, report.Placeable)
+
+	report = CheckConstraints(agents, [][]string{{"@zone", "GROUP_BY"}, {"rack", "UNLIKE", "r2"}}, req)
+	assert.Equal(t, []string{"a1", "a2"}, report.Agents)
+	assert.Equal(t, -1, report.Constraints[0].Limit)
+
+	// instances of CLUSTER without a value share the rack of the first instance
+	report = CheckConstraints(agents, [][]string{{"rack", "CLUSTER"}}, &Requirement{ID: "/web", Instances: 10, CPUs: 1})
+	assert.Equal(t, 8, report.Placeable)
+
+	report = CheckConstraints(agents, [][]string{{"rack", "LIKE", "r[1"}, {"rack", "NEAR"}}, req)
+	assert.Contains(t, report.Constraints[0].Reason, "invalid regular expression")
+	assert.Contains(t, report.Constraints[1].Reason, "not a valid constraint operator")
+	assert.Equal(t, 3, report.Eligible)
+}
@@ -0,0 +1,398 @@
+// Mesos master operator API (v1)
+package mesos
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	/* --- api related constants --- */
+	API_OPERATOR = "api/v1"
+
+	DefaultPort = 5050
+
+	// Tasks running in Mesos which the framework does not know
+	IssueUnknown = "unknown to framework"
+	// Tasks the framework believes are running which Mesos does not know
+	IssueMissing = "missing from mesos"
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.mesos")
+
+var (
+	ErrorFrameworkNotFound = errors.New("The framework is not registered with the Mesos master")
+	ErrorNoMachines        = errors.New("At least one machine must be specified")
+	ErrorNotReconciled     = errors.New("The tasks of the framework are not reconciled with Mesos")
+)
+
+type Mesos interface {
+
+	// List the active frameworks and, if {completed} is true, the completed frameworks
+	ListFrameworks(completed bool) ([]*Framework, error)
+
+	// Returns the active framework named {name}
+	FindFramework(name string) (*Framework, error)
+
+	// List all tasks known to the master
+	ListTasks() ([]*Task, error)
+
+	// List the registered agents along with their total and allocated resources
+	ListAgents() ([]*Agent, error)
+
+	// List the roles of the cluster along with their quotas
+	ListRoles() ([]*Role, error)
+
+	// List the containers running on {agent} along with their resource usage
+	ListContainers(agent *Agent) ([]*Container, error)
+
+	/** Maintenance */
+
+	// Returns the maintenance schedule of the cluster
+	GetMaintenanceSchedule() (*Schedule, error)
+
+	// Replaces the maintenance schedule of the cluster
+	UpdateMaintenanceSchedule(schedule *Schedule) error
+
+	// Adds a window to the maintenance schedule for {machines} starting at {start} for {duration}
+	ScheduleMaintenance(machines []*MachineID, start time.Time, duration time.Duration) (*Schedule, error)
+
+	// Removes {machines} from the windows of the maintenance schedule dropping windows left empty
+	CancelMaintenance(machines []*MachineID) (*Schedule, error)
+
+	// Returns the machines being drained and the machines which are down
+	GetMaintenanceStatus() (*MaintenanceStatus, error)
+
+	// Marks scheduled {machines} down killing their tasks
+	StartMaintenance(machines []*MachineID) error
+
+	// Brings {machines} back up after maintenance
+	StopMaintenance(machines []*MachineID) error
+}
+
+type MesosClient struct {
+	http     httpclient.HttpClient
+	host     string
+	readOnly bool
+}
+
+type MesosOptions struct {
+	TLSAllowInsecure bool
+	// Optional token based authentication (eg. DC/OS) used in place of basic auth
+	Authenticator httpclient.Authenticator
+	// Optional proxies used in place of the proxy environment variables
+	Proxy *httpclient.ProxyConfig
+	// Optional client certificate and CA bundle for mutual TLS
+	TLS *httpclient.TLSConfig
+	// Refuses the operator calls other than GET_* (eg. maintenance schedules) with httpclient.ErrorReadOnly
+	ReadOnly bool
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Static headers added to every request
+	Headers map[string]string
+}
+
+// NewMesosClient creates a client for the Mesos master at {host}
+func NewMesosClient(host, username, password string, opts *MesosOptions) Mesos {
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.HttpUser = username
+	httpConfig.HttpPass = password
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	httpConfig.RPC = true
+	if opts != nil {
+		httpConfig.TLSInsecureSkipVerify = opts.TLSAllowInsecure
+		httpConfig.Authenticator = opts.Authenticator
+		httpConfig.Proxy = opts.Proxy
+		httpConfig.TLS = opts.TLS
+		httpConfig.Timeouts = opts.Timeouts
+		httpConfig.Headers = opts.Headers
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+	}
+
+	c := new(MesosClient)
+	c.http = *httpclient.NewHttpClient(*httpConfig)
+	c.host = host
+	c.readOnly = opts != nil && opts.ReadOnly
+	return c
+}
+
+// Sends the operator API {call} decoding the response into {result} when not nil.  Every call is a
+// POST so read-only environments and the write guard are enforced here: only GET_* calls are reads
+func (c *MesosClient) call(req *call, result *response) error {
+	if c.readOnly && !strings.HasPrefix(req.Type, "GET_") {
+		return httpclient.ErrorReadOnly
+	}
+	uri := utils.BuildPath(c.host, []string{API_OPERATOR})
+	if !strings.HasPrefix(req.Type, "GET_") {
+		if err := httpclient.CheckWrite("POST", uri); err != nil {
+			return err
+		}
+	}
+	var resp *httpclient.Response
+	if result != nil {
+		resp = c.http.HttpPost(uri, req, result)
+	} else {
+		resp = c.http.HttpPost(uri, req, nil)
+	}
+	if resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *MesosClient) ListFrameworks(completed bool) ([]*Framework, error) {
+	resp := new(response)
+	if err := c.call(&call{Type: "GET_FRAMEWORKS"}, resp); err != nil {
+		return nil, err
+	}
+	frameworks := []*Framework{}
+	if resp.GetFrameworks == nil {
+		return frameworks, nil
+	}
+	frameworks = append(frameworks, resp.GetFrameworks.Frameworks...)
+	if completed {
+		for _, f := range resp.GetFrameworks.CompletedFrameworks {
+			f.Completed = true
+			frameworks = append(frameworks, f)
+		}
+	}
+	return frameworks, nil
+}
+
+func (c *MesosClient) FindFramework(name string) (*Framework, error) {
+	frameworks, err := c.ListFrameworks(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range frameworks {
+		if f.Info != nil && f.Info.Name == name {
+			return f, nil
+		}
+	}
+	return nil, ErrorFrameworkNotFound
+}
+
+func (c *MesosClient) ListTasks() ([]*Task, error) {
+	resp := new(response)
+	if err := c.call(&call{Type: "GET_TASKS"}, resp); err != nil {
+		return nil, err
+	}
+	if resp.GetTasks == nil {
+		return []*Task{}, nil
+	}
+	return resp.GetTasks.Tasks, nil
+}
+
+func (c *MesosClient) ListAgents() ([]*Agent, error) {
+	resp := new(response)
+	if err := c.call(&call{Type: "GET_AGENTS"}, resp); err != nil {
+		return nil, err
+	}
+	if resp.GetAgents == nil {
+		return []*Agent{}, nil
+	}
+	return resp.GetAgents.Agents, nil
+}
+
+func (c *MesosClient) ListRoles() ([]*Role, error) {
+	resp := new(response)
+	if err := c.call(&call{Type: "GET_ROLES"}, resp); err != nil {
+		return nil, err
+	}
+	if resp.GetRoles == nil {
+		return []*Role{}, nil
+	}
+	return resp.GetRoles.Roles, nil
+}
+
+func (c *MesosClient) ListContainers(agent *Agent) ([]*Container, error) {
+	uri, err := c.agentURL(agent)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(response)
+	if r := c.http.HttpPost(uri, &call{Type: "GET_CONTAINERS"}, resp); r.Error != nil {
+		return nil, r.Err()
+	}
+	if resp.GetContainers == nil {
+		return []*Container{}, nil
+	}
+	return resp.GetContainers.Containers, nil
+}
+
+// Returns the operator API URL of {agent}.  DC/OS masters (/mesos) proxy the agents at /agent/{id} while
+// other agents are reached at the address of their PID (eg. slave(1)@10.0.0.1:5051) with the scheme
+// of the master
+func (c *MesosClient) agentURL(agent *Agent) (string, error) {
+	host := strings.TrimRight(c.host, "/")
+	if strings.HasSuffix(host, "/mesos") && agent.ID() != "" {
+		return utils.BuildPath(strings.TrimSuffix(host, "/mesos"), []string{"agent", agent.ID(), API_OPERATOR}), nil
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return "", err
+	}
+	i := strings.LastIndex(agent.PID, "@")
+	if i < 0 {
+		return "", fmt.Errorf("The address of agent %s is unknown (pid '%s')", agent.Hostname(), agent.PID)
+	}
+	return utils.BuildPath(fmt.Sprintf("%s://%s", u.Scheme, agent.PID[i+1:]), []string{API_OPERATOR}), nil
+}
+
+// CPUsUsed returns the cpus a container used between the samples {before} and {after} of its statistics
+func CPUsUsed(before, after *ResourceStatistics) float64 {
+	if before == nil || after == nil || after.Timestamp <= before.Timestamp {
+		return 0
+	}
+	elapsed := after.Timestamp - before.Timestamp
+	used := (after.CPUsUserTimeSecs + after.CPUsSystemTimeSecs) - (before.CPUsUserTimeSecs + before.CPUsSystemTimeSecs)
+	if used < 0 {
+		return 0
+	}
+	return used / elapsed
+}
+
+func (c *MesosClient) GetMaintenanceSchedule() (*Schedule, error) {
+	resp := new(response)
+	if err := c.call(&call{Type: "GET_MAINTENANCE_SCHEDULE"}, resp); err != nil {
+		return nil, err
+	}
+	if resp.GetMaintenanceSchedule == nil || resp.GetMaintenanceSchedule.Schedule == nil {
+		return &Schedule{Windows: []*Window{}}, nil
+	}
+	return resp.GetMaintenanceSchedule.Schedule, nil
+}
+
+func (c *MesosClient) UpdateMaintenanceSchedule(schedule *Schedule) error {
+	return c.call(&call{Type: "UPDATE_MAINTENANCE_SCHEDULE", UpdateSchedule: &updateSchedule{Schedule: schedule}}, nil)
+}
+
+func (c *MesosClient) ScheduleMaintenance(machines []*MachineID, start time.Time, duration time.Duration) (*Schedule, error) {
+	if len(machines) == 0 {
+		return nil, ErrorNoMachines
+	}
+	schedule, err := c.GetMaintenanceSchedule()
+	if err != nil {
+		return nil, err
+	}
+	window := &Window{MachineIDs: machines, Unavailability: &Unavailability{Start: &TimeInfo{Nanoseconds: start.UnixNano()}}}
+	if duration > 0 {
+		window.Unavailability.Duration = &DurationInfo{Nanoseconds: duration.Nanoseconds()}
+	}
+	// machines may only appear within a single window
+	schedule = withoutMachines(schedule, machines)
+	schedule.Windows = append(schedule.Windows, window)
+	if err := c.UpdateMaintenanceSchedule(schedule); err != nil {
+		return nil, err
+	}
+	log.Info("Scheduled maintenance of %d machine(s) at %s", len(machines), start.UTC().Format(time.RFC3339))
+	return schedule, nil
+}
+
+func (c *MesosClient) CancelMaintenance(machines []*MachineID) (*Schedule, error) {
+	if len(machines) == 0 {
+		return nil, ErrorNoMachines
+	}
+	schedule, err := c.GetMaintenanceSchedule()
+	if err != nil {
+		return nil, err
+	}
+	schedule = withoutMachines(schedule, machines)
+	if err := c.UpdateMaintenanceSchedule(schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Returns {schedule} without {machines} dropping windows left without machines
+func withoutMachines(schedule *Schedule, machines []*MachineID) *Schedule {
+	result := &Schedule{Windows: []*Window{}}
+	for _, w := range schedule.Windows {
+		ids := []*MachineID{}
+		for _, id := range w.MachineIDs {
+			if !containsMachine(machines, id) {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > 0 {
+			result.Windows = append(result.Windows, &Window{MachineIDs: ids, Unavailability: w.Unavailability})
+		}
+	}
+	return result
+}
+
+func containsMachine(machines []*MachineID, m *MachineID) bool {
+	for _, id := range machines {
+		if id.Hostname == m.Hostname && (id.IP == "" || m.IP == "" || id.IP == m.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *MesosClient) GetMaintenanceStatus() (*MaintenanceStatus, error) {
+	resp := new(response)
+	if err := c.call(&call{Type: "GET_MAINTENANCE_STATUS"}, resp); err != nil {
+		return nil, err
+	}
+	if resp.GetMaintenanceStatus == nil || resp.GetMaintenanceStatus.Status == nil {
+		return &MaintenanceStatus{}, nil
+	}
+	return resp.GetMaintenanceStatus.Status, nil
+}
+
+func (c *MesosClient) StartMaintenance(machines []*MachineID) error {
+	if len(machines) == 0 {
+		return ErrorNoMachines
+	}
+	return c.call(&call{Type: "START_MAINTENANCE", StartMaintenance: &machinesMessage{Machines: machines}}, nil)
+}
+
+func (c *MesosClient) StopMaintenance(machines []*MachineID) error {
+	if len(machines) == 0 {
+		return ErrorNoMachines
+	}
+	return c.call(&call{Type: "STOP_MAINTENANCE", StopMaintenance: &machinesMessage{Machines: machines}}, nil)
+}
+
+// Reconcile compares the {tasks} Mesos runs for the framework {frameworkID} with the IDs of the tasks
+// the framework knows {known} returning the tasks known to only one of them.  Terminal tasks are ignored
+func Reconcile(tasks []*Task, frameworkID string, known []string) []*Discrepancy {
+	discrepancies := []*Discrepancy{}
+	inMesos := map[string]bool{}
+	for _, t := range tasks {
+		if t.FrameworkID == nil || t.FrameworkID.Value != frameworkID || t.TaskID == nil || isTerminal(t.State) {
+			continue
+		}
+		inMesos[t.TaskID.Value] = true
+		if !utils.StringInSlice(t.TaskID.Value, known) {
+			discrepancies = append(discrepancies, &Discrepancy{TaskID: t.TaskID.Value, Name: t.Name, State: t.State, Issue: IssueUnknown})
+		}
+	}
+	for _, id := range known {
+		if !inMesos[id] {
+			discrepancies = append(discrepancies, &Discrepancy{TaskID: id, Issue: IssueMissing})
+		}
+	}
+	return discrepancies
+}
+
+func isTerminal(state string) bool {
+	switch state {
+	case "TASK_FINISHED", "TASK_FAILED", "TASK_KILLED", "TASK_ERROR", "TASK_DROPPED", "TASK_GONE", "TASK_GONE_BY_OPERATOR":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package mesos
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+// Starts a server answering each operator call type with {responses} and recording every call
+func newTestClient(responses map[string]string, readOnly bool) (Mesos, *[]map[string]interface{}, func()) {
+	calls := []map[string]interface{}{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := map[string]interface{}{}
+		b, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(b, &call)
+		call["path"] = r.URL.Path
+		calls = append(calls, call)
+		if body, ok := responses[call["type"].(string)]; ok {
+			fmt.Fprint(w, body)
+		}
+	}))
+	retry := httpclient.DefaultRetryPolicy()
+	retry.MaxAttempts = 1
+	c := NewMesosClient(s.URL, "", "", &MesosOptions{Retry: retry, ReadOnly: readOnly})
+	return c, &calls, s.Close
+}
+
+func TestListFrameworks(t *testing.T) {
+	c, calls, stop := newTestClient(map[string]string{
+		"GET_FRAMEWORKS": `{"type": "GET_FRAMEWORKS", "get_frameworks": {
+			"frameworks": [{"framework_info": {"id": {"value": "f1"}, "name": "marathon"}, "active": true}],
+			"completed_frameworks": [{"framework_info": {"id": {"value": "f0"}, "name": "spark"}}]}}`,
+	}, false)
+	defer stop()
+
+	frameworks, err := c.ListFrameworks(false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(frameworks))
+	assert.Equal(t, "/api/v1", (*calls)[0]["path"])
+
+	frameworks, err = c.ListFrameworks(true)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(frameworks))
+	assert.True(t, frameworks[1].Completed)
+
+	f, err := c.FindFramework("marathon")
+	assert.Nil(t, err)
+	assert.Equal(t, "f1", f.ID())
+	_, err = c.FindFramework("spark")
+	assert.Equal(t, ErrorFrameworkNotFound, err)
+}
+
+func TestScheduleMaintenanceReplacesMachineWindows(t *testing.T) {
+	c, calls, stop := newTestClient(map[string]string{
+		"GET_MAINTENANCE_SCHEDULE": `{"get_maintenance_schedule": {"schedule": {"windows": [
+			{"machine_ids": [{"hostname": "a1"}, {"hostname": "a2"}], "unavailability": {"start": {"nanoseconds": 1}}}]}}}`,
+	}, false)
+	defer stop()
+
+	start := time.Unix(100, 0)
+	schedule, err := c.ScheduleMaintenance([]*MachineID{{Hostname: "a2", IP: "10.0.0.2"}}, start, time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(schedule.Windows))
+	assert.Equal(t, []*MachineID{{Hostname: "a1"}}, schedule.Windows[0].MachineIDs)
+	assert.Equal(t, start.UnixNano(), schedule.Windows[1].Unavailability.Start.Nanoseconds)
+	assert.Equal(t, time.Hour.Nanoseconds(), schedule.Windows[1].Unavailability.Duration.Nanoseconds)
+	assert.Equal(t, "UPDATE_MAINTENANCE_SCHEDULE", (*calls)[1]["type"])
+
+	schedule, err = c.CancelMaintenance([]*MachineID{{Hostname: "a1"}, {Hostname: "a2"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(schedule.Windows))
+
+	_, err = c.ScheduleMaintenance(nil, start, 0)
+	assert.Equal(t, ErrorNoMachines, err)
+}
+
+func TestReadOnlyPermitsOnlyQueries(t *testing.T) {
+	c, calls, stop := newTestClient(map[string]string{"GET_TASKS": `{"get_tasks": {"tasks": []}}`}, true)
+	defer stop()
+
+	_, err := c.ListTasks()
+	assert.Nil(t, err)
+	assert.Equal(t, httpclient.ErrorReadOnly, c.StartMaintenance([]*MachineID{{Hostname: "a1"}}))
+	assert.Equal(t, 1, len(*calls))
+}
+
+func TestReconcile(t *testing.T) {
+	tasks := []*Task{
+		{Name: "web", TaskID: &Value{"web.1"}, FrameworkID: &Value{"f1"}, State: "TASK_RUNNING"},
+		{Name: "web", TaskID: &Value{"web.2"}, FrameworkID: &Value{"f1"}, State: "TASK_RUNNING"},
+		{Name: "old", TaskID: &Value{"old.1"}, FrameworkID: &Value{"f1"}, State: "TASK_KILLED"},
+		{Name: "spark", TaskID: &Value{"spark.1"}, FrameworkID: &Value{"f2"}, State: "TASK_RUNNING"},
+	}
+	discrepancies := Reconcile(tasks, "f1", []string{"web.1", "api.1"})
+	assert.Equal(t, []*Discrepancy{
+		{TaskID: "web.2", Name: "web", State: "TASK_RUNNING", Issue: IssueUnknown},
+		{TaskID: "api.1", Issue: IssueMissing},
+	}, discrepancies)
+}
+
+func TestListContainersThroughDCOSProxy(t *testing.T) {
+	paths := []string{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		fmt.Fprint(w, `{"type": "GET_CONTAINERS", "get_containers": {"containers": [
+			{"executor_id": {"value": "web.1"}, "resource_statistics": {"timestamp": 10, "cpus_user_time_secs": 2, "cpus_system_time_secs": 1}}]}}`)
+	}))
+	defer s.Close()
+	retry := httpclient.DefaultRetryPolicy()
+	retry.MaxAttempts = 1
+	c := NewMesosClient(s.URL+"/mesos", "", "", &MesosOptions{Retry: retry})
+
+	containers, err := c.ListContainers(&Agent{Info: &AgentInfo{ID: &Value{Value: "a1"}}})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"/agent/a1/api/v1"}, paths)
+	assert.Equal(t, "web.1", containers[0].ExecutorID.Value)
+	assert.Equal(t, 0.5, CPUsUsed(&ResourceStatistics{Timestamp: 4}, containers[0].Statistics))
+	assert.Equal(t, 0.0, CPUsUsed(nil, containers[0].Statistics))
+
+	_, err = NewMesosClient(s.URL, "", "", nil).ListContainers(&Agent{PID: "slave(1)"})
+	assert.NotNil(t, err)
+}
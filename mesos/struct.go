@@ -0,0 +1,317 @@
+package mesos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The subset of the Mesos operator API (v1) messages used by depcon
+
+type call struct {
+	Type             string           `json:"type"`
+	UpdateSchedule   *updateSchedule  `json:"update_maintenance_schedule,omitempty"`
+	StartMaintenance *machinesMessage `json:"start_maintenance,omitempty"`
+	StopMaintenance  *machinesMessage `json:"stop_maintenance,omitempty"`
+}
+
+type response struct {
+	Type          string `json:"type"`
+	GetFrameworks *struct {
+		Frameworks          []*Framework `json:"frameworks"`
+		CompletedFrameworks []*Framework `json:"completed_frameworks"`
+	} `json:"get_frameworks,omitempty"`
+	GetTasks *struct {
+		Tasks []*Task `json:"tasks"`
+	} `json:"get_tasks,omitempty"`
+	GetAgents *struct {
+		Agents []*Agent `json:"agents"`
+	} `json:"get_agents,omitempty"`
+	GetMaintenanceSchedule *struct {
+		Schedule *Schedule `json:"schedule"`
+	} `json:"get_maintenance_schedule,omitempty"`
+	GetMaintenanceStatus *struct {
+		Status *MaintenanceStatus `json:"status"`
+	} `json:"get_maintenance_status,omitempty"`
+	GetRoles *struct {
+		Roles []*Role `json:"roles"`
+	} `json:"get_roles,omitempty"`
+	GetContainers *struct {
+		Containers []*Container `json:"containers"`
+	} `json:"get_containers,omitempty"`
+}
+
+type updateSchedule struct {
+	Schedule *Schedule `json:"schedule"`
+}
+
+type machinesMessage struct {
+	Machines []*MachineID `json:"machines"`
+}
+
+// Value wraps the IDs of the operator API (eg. {"value": "..."})
+type Value struct {
+	Value string `json:"value"`
+}
+
+type Framework struct {
+	Info      *FrameworkInfo `json:"framework_info"`
+	Active    bool           `json:"active"`
+	Connected bool           `json:"connected"`
+	Recovered bool           `json:"recovered"`
+	// Set on completed frameworks
+	Completed bool `json:"-"`
+}
+
+type FrameworkInfo struct {
+	ID       *Value `json:"id"`
+	Name     string `json:"name"`
+	User     string `json:"user"`
+	Role     string `json:"role,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	WebUIURL string `json:"webui_url,omitempty"`
+}
+
+type Task struct {
+	Name        string `json:"name"`
+	TaskID      *Value `json:"task_id"`
+	FrameworkID *Value `json:"framework_id"`
+	AgentID     *Value `json:"agent_id"`
+	State       string `json:"state"`
+}
+
+type Agent struct {
+	Info               *AgentInfo  `json:"agent_info"`
+	Active             bool        `json:"active"`
+	PID                string      `json:"pid"`
+	TotalResources     []*Resource `json:"total_resources,omitempty"`
+	AllocatedResources []*Resource `json:"allocated_resources,omitempty"`
+}
+
+// Container is a container running on an agent along with its resource usage
+type Container struct {
+	FrameworkID *Value `json:"framework_id"`
+	ExecutorID  *Value `json:"executor_id"`
+	ContainerID *Value `json:"container_id"`
+	// Resource usage of the container.  Absent when the agent can't collect it
+	Statistics *ResourceStatistics `json:"resource_statistics,omitempty"`
+}
+
+// ResourceStatistics is a sample of the resource usage of a container.  CPU times are cumulative so the
+// cpus used are derived from two samples
+type ResourceStatistics struct {
+	Timestamp          float64 `json:"timestamp"`
+	CPUsUserTimeSecs   float64 `json:"cpus_user_time_secs"`
+	CPUsSystemTimeSecs float64 `json:"cpus_system_time_secs"`
+	CPUsLimit          float64 `json:"cpus_limit"`
+	MemRSSBytes        uint64  `json:"mem_rss_bytes"`
+	MemLimitBytes      uint64  `json:"mem_limit_bytes"`
+}
+
+type AgentInfo struct {
+	ID         *Value       `json:"id"`
+	Hostname   string       `json:"hostname"`
+	Attributes []*Attribute `json:"attributes,omitempty"`
+	Domain     *DomainInfo  `json:"domain,omitempty"`
+}
+
+// Attribute is an attribute of an agent (eg. rack_id:r1) matched by the constraints of applications
+type Attribute struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Text   *Value  `json:"text,omitempty"`
+	Scalar *Scalar `json:"scalar,omitempty"`
+	Ranges *struct {
+		Range []struct {
+			Begin int64 `json:"begin"`
+			End   int64 `json:"end"`
+		} `json:"range"`
+	} `json:"ranges,omitempty"`
+	Set *struct {
+		Item []string `json:"item"`
+	} `json:"set,omitempty"`
+}
+
+// DomainInfo is the fault domain (region and zone) of an agent
+type DomainInfo struct {
+	FaultDomain *struct {
+		Region *struct {
+			Name string `json:"name"`
+		} `json:"region"`
+		Zone *struct {
+			Name string `json:"name"`
+		} `json:"zone"`
+	} `json:"fault_domain,omitempty"`
+}
+
+// Resource is a resource of an agent (eg. cpus) which may be reserved for a role
+type Resource struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Scalar *Scalar `json:"scalar,omitempty"`
+	// Static reservation of agents without reservation refinement
+	Role         string             `json:"role,omitempty"`
+	Reservations []*ReservationInfo `json:"reservations,omitempty"`
+}
+
+type Scalar struct {
+	Value float64 `json:"value"`
+}
+
+type ReservationInfo struct {
+	Role string `json:"role"`
+}
+
+// Role is a role of the cluster along with its quota (Mesos 1.9 and later)
+type Role struct {
+	Name  string     `json:"name"`
+	Quota *RoleQuota `json:"quota,omitempty"`
+}
+
+// RoleQuota holds the scalar resources (eg. cpus) of the role's quota keyed by name
+type RoleQuota struct {
+	Guarantee map[string]float64 `json:"guarantee,omitempty"`
+	Limit     map[string]float64 `json:"limit,omitempty"`
+	Consumed  map[string]float64 `json:"consumed,omitempty"`
+}
+
+// UnmarshalJSON accepts the quota of both the operator API ({"limits": {"cpus": {"value": 2}}}) and the
+// /roles endpoint ({"limit": {"cpus": 2}})
+func (q *RoleQuota) UnmarshalJSON(b []byte) error {
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	scalars := func(names ...string) map[string]float64 {
+		result := map[string]float64{}
+		for _, name := range names {
+			values := map[string]json.RawMessage{}
+			json.Unmarshal(raw[name], &values)
+			for resource, v := range values {
+				var f float64
+				if json.Unmarshal(v, &f) != nil {
+					s := &Scalar{}
+					json.Unmarshal(v, s)
+					f = s.Value
+				}
+				result[resource] = f
+			}
+		}
+		return result
+	}
+	q.Guarantee = scalars("guarantee", "guarantees")
+	q.Limit = scalars("limit", "limits")
+	q.Consumed = scalars("consumed")
+	return nil
+}
+
+// MachineID identifies a machine by hostname and optionally IP address
+type MachineID struct {
+	Hostname string `json:"hostname,omitempty"`
+	IP       string `json:"ip,omitempty"`
+}
+
+// Schedule is the maintenance schedule of the cluster
+type Schedule struct {
+	Windows []*Window `json:"windows"`
+}
+
+// Window is a period the listed machines are unavailable
+type Window struct {
+	MachineIDs     []*MachineID    `json:"machine_ids"`
+	Unavailability *Unavailability `json:"unavailability"`
+}
+
+type Unavailability struct {
+	Start    *TimeInfo     `json:"start"`
+	Duration *DurationInfo `json:"duration,omitempty"`
+}
+
+type TimeInfo struct {
+	Nanoseconds int64 `json:"nanoseconds"`
+}
+
+type DurationInfo struct {
+	Nanoseconds int64 `json:"nanoseconds"`
+}
+
+// MaintenanceStatus lists the machines being drained and the machines which are down
+type MaintenanceStatus struct {
+	DrainingMachines []*DrainingMachine `json:"draining_machines,omitempty"`
+	DownMachines     []*MachineID       `json:"down_machines,omitempty"`
+}
+
+type DrainingMachine struct {
+	ID *MachineID `json:"id"`
+}
+
+// Discrepancy is a task known to only one of Mesos and the framework
+type Discrepancy struct {
+	TaskID string `json:"taskId"`
+	Name   string `json:"name,omitempty"`
+	State  string `json:"state,omitempty"`
+	// IssueUnknown or IssueMissing
+	Issue string `json:"issue"`
+}
+
+// ID returns the ID of the framework
+func (f *Framework) ID() string {
+	if f.Info == nil || f.Info.ID == nil {
+		return ""
+	}
+	return f.Info.ID.Value
+}
+
+// ReservedRole returns the role the resource is reserved for or an empty string when it's unreserved
+func (r *Resource) ReservedRole() string {
+	if n := len(r.Reservations); n > 0 {
+		return r.Reservations[n-1].Role
+	}
+	if r.Role != "*" {
+		return r.Role
+	}
+	return ""
+}
+
+// Hostname returns the hostname of the agent
+func (a *Agent) Hostname() string {
+	if a.Info == nil {
+		return ""
+	}
+	return a.Info.Hostname
+}
+
+// ID returns the id of the agent
+func (a *Agent) ID() string {
+	if a.Info == nil || a.Info.ID == nil {
+		return ""
+	}
+	return a.Info.ID.Value
+}
+
+// Value returns the value of the attribute as Marathon matches it (eg. 1.5, [1000-2000] or {a,b})
+func (a *Attribute) Value() string {
+	switch {
+	case a.Text != nil:
+		return a.Text.Value
+	case a.Scalar != nil:
+		return strconv.FormatFloat(a.Scalar.Value, 'f', -1, 64)
+	case a.Ranges != nil:
+		ranges := []string{}
+		for _, r := range a.Ranges.Range {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", r.Begin, r.End))
+		}
+		return "[" + strings.Join(ranges, ",") + "]"
+	case a.Set != nil:
+		return "{" + strings.Join(a.Set.Item, ",") + "}"
+	}
+	return ""
+}
+
+func (m *MachineID) String() string {
+	if m.IP != "" {
+		return m.Hostname + "=" + m.IP
+	}
+	return m.Hostname
+}
@@ -0,0 +1,332 @@
+// DC/OS Metronome (batch job) API
+package metronome
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	/* --- api related constants --- */
+	API_JOBS = "v1/jobs"
+
+	RunSuccess = "SUCCESS"
+	RunFailed  = "FAILED"
+
+	// ID of schedules which don't specify one
+	DefaultScheduleID = "default"
+
+	DefaultTimeout = time.Duration(10) * time.Minute
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.metronome")
+
+var (
+	ErrorTimeout      = errors.New("The operation has timed out")
+	ErrorMissingJobID = errors.New("Document does not specify a job id")
+	ErrorRunFailed    = errors.New("The job run failed")
+	ErrorNoTasks      = errors.New("The run has not launched any tasks")
+)
+
+type Metronome interface {
+
+	/** Job API */
+
+	// Creates the job within {doc} or updates it when it exists and {force} is true.  Schedules listed
+	// under 'schedules' are created or replaced after the job
+	// {doc} - the job descriptor
+	ApplyJob(doc map[string]interface{}, force bool) (*ApplyResult, error)
+
+	// List all jobs with their active runs, schedules and history
+	ListJobs() ([]*Job, error)
+
+	// Get a Job by ID with its active runs, schedules and history
+	// {id} - job ID
+	GetJob(id string) (*Job, error)
+
+	// Removes a job
+	// {id} - job ID
+	// {stopRuns} - if true active runs are stopped otherwise the request fails while runs are active
+	DestroyJob(id string, stopRuns bool) error
+
+	/** Run API */
+
+	// Starts a run of the job now regardless of its schedules
+	// {id} - job ID
+	StartRun(id string) (*Run, error)
+
+	// List the active runs followed by the finished runs of a job
+	// {id} - job ID
+	ListRuns(id string) ([]*Run, error)
+
+	// Get an active run
+	// {id} - job ID
+	// {runID} - run ID
+	GetRun(id, runID string) (*Run, error)
+
+	// Stops an active run
+	// {id} - job ID
+	// {runID} - run ID
+	StopRun(id, runID string) error
+
+	// Waits until the run has finished returning ErrorRunFailed if it failed
+	// {id} - job ID
+	// {runID} - run ID
+	// {timeout} - the max time to wait
+	WaitForRun(id, runID string, timeout time.Duration) error
+
+	/** Schedule API */
+
+	// List the schedules of a job
+	// {id} - job ID
+	ListSchedules(id string) ([]*Schedule, error)
+
+	// Creates the schedule or replaces it when it exists
+	// {id} - job ID
+	PutSchedule(id string, schedule *Schedule) (*Schedule, error)
+
+	// Removes a schedule
+	// {id} - job ID
+	// {scheduleID} - schedule ID
+	DeleteSchedule(id, scheduleID string) error
+}
+
+type MetronomeClient struct {
+	http httpclient.HttpClient
+	host string
+	opts *MetronomeOptions
+}
+
+type MetronomeOptions struct {
+	TLSAllowInsecure bool
+	// Optional token based authentication (eg. DC/OS) used in place of basic auth
+	Authenticator httpclient.Authenticator
+	// Optional proxies used in place of the proxy environment variables
+	Proxy *httpclient.ProxyConfig
+	// Optional client certificate and CA bundle for mutual TLS
+	TLS *httpclient.TLSConfig
+	// Refuses applying and destroying jobs, starting and stopping runs and changing schedules (every request
+	// other than a GET) with httpclient.ErrorReadOnly
+	ReadOnly bool
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Static headers added to every request
+	Headers map[string]string
+	// Optional reporter drawing the status while waiting on runs.  Status is logged when nil
+	Progress progress.Reporter
+}
+
+// NewMetronomeClient creates a client for the Metronome at {host}
+func NewMetronomeClient(host, username, password string, opts *MetronomeOptions) Metronome {
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.HttpUser = username
+	httpConfig.HttpPass = password
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	if opts != nil {
+		httpConfig.TLSInsecureSkipVerify = opts.TLSAllowInsecure
+		httpConfig.Authenticator = opts.Authenticator
+		httpConfig.Proxy = opts.Proxy
+		httpConfig.TLS = opts.TLS
+		httpConfig.ReadOnly = opts.ReadOnly
+		httpConfig.Timeouts = opts.Timeouts
+		httpConfig.Headers = opts.Headers
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+	}
+
+	c := new(MetronomeClient)
+	c.http = *httpclient.NewHttpClient(*httpConfig)
+	c.host = host
+	c.opts = opts
+	return c
+}
+
+// Returns the URL of the jobs API followed by {elements} with the additional query {params}
+func (c *MetronomeClient) metronomeUrl(params url.Values, elements ...string) string {
+	for i, e := range elements {
+		elements[i] = url.PathEscape(e)
+	}
+	uri := utils.BuildPath(c.host, append([]string{API_JOBS}, elements...))
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+	return uri
+}
+
+// Query embedding the active runs, schedules and history within jobs
+func embedAll() url.Values {
+	return url.Values{"embed": []string{"activeRuns", "schedules", "history"}}
+}
+
+// Returns the job within {doc} without its schedules, the schedules and the job ID
+func jobFromDocument(doc map[string]interface{}) (map[string]interface{}, []*Schedule, string, error) {
+	id, _ := doc["id"].(string)
+	if id == "" {
+		return nil, nil, "", ErrorMissingJobID
+	}
+	job := map[string]interface{}{}
+	schedules := []*Schedule{}
+	for k, v := range doc {
+		if k != "schedules" {
+			job[k] = v
+			continue
+		}
+		list, _ := v.([]interface{})
+		for _, s := range list {
+			m, _ := s.(map[string]interface{})
+			schedules = append(schedules, scheduleFromMap(m))
+		}
+	}
+	return job, schedules, id, nil
+}
+
+func scheduleFromMap(m map[string]interface{}) *Schedule {
+	s := &Schedule{Enabled: true}
+	s.ID, _ = m["id"].(string)
+	if s.ID == "" {
+		s.ID = DefaultScheduleID
+	}
+	s.Cron, _ = m["cron"].(string)
+	s.TimeZone, _ = m["timezone"].(string)
+	s.ConcurrencyPolicy, _ = m["concurrencyPolicy"].(string)
+	if enabled, ok := m["enabled"].(bool); ok {
+		s.Enabled = enabled
+	}
+	if deadline, ok := m["startingDeadlineSeconds"].(float64); ok {
+		s.StartingDeadlineSeconds = int(deadline)
+	}
+	return s
+}
+
+func (c *MetronomeClient) ApplyJob(doc map[string]interface{}, force bool) (*ApplyResult, error) {
+	job, schedules, id, err := jobFromDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ApplyResult{JobID: id, Schedules: len(schedules)}
+	resp := c.http.HttpPost(c.metronomeUrl(nil), job, nil)
+	switch {
+	case resp.Status == 409 && force:
+		log.Info("Job '%s' exists - updating", id)
+		if r := c.http.HttpPut(c.metronomeUrl(nil, id), job, nil); r.Error != nil {
+			return nil, r.Err()
+		}
+	case resp.Error != nil:
+		return nil, resp.Err()
+	default:
+		result.Created = true
+	}
+
+	for _, s := range schedules {
+		if _, err := c.PutSchedule(id, s); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (c *MetronomeClient) ListJobs() ([]*Job, error) {
+	jobs := []*Job{}
+	if resp := c.http.HttpGet(c.metronomeUrl(embedAll()), &jobs); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return jobs, nil
+}
+
+func (c *MetronomeClient) GetJob(id string) (*Job, error) {
+	job := new(Job)
+	if resp := c.http.HttpGet(c.metronomeUrl(embedAll(), id), job); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return job, nil
+}
+
+func (c *MetronomeClient) DestroyJob(id string, stopRuns bool) error {
+	var params url.Values
+	if stopRuns {
+		params = url.Values{"stopCurrentJobRuns": []string{"true"}}
+	}
+	if resp := c.http.HttpDelete(c.metronomeUrl(params, id), nil, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *MetronomeClient) StartRun(id string) (*Run, error) {
+	run := new(Run)
+	if resp := c.http.HttpPost(c.metronomeUrl(nil, id, "runs"), nil, run); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return run, nil
+}
+
+func (c *MetronomeClient) ListRuns(id string) ([]*Run, error) {
+	job, err := c.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	runs := append([]*Run{}, job.ActiveRuns...)
+	if h := job.History; h != nil {
+		for _, r := range h.SuccessfulFinishedRuns {
+			runs = append(runs, &Run{ID: r.ID, JobID: id, Status: RunSuccess, CreatedAt: r.CreatedAt, CompletedAt: r.FinishedAt})
+		}
+		for _, r := range h.FailedFinishedRuns {
+			runs = append(runs, &Run{ID: r.ID, JobID: id, Status: RunFailed, CreatedAt: r.CreatedAt, CompletedAt: r.FinishedAt})
+		}
+	}
+	return runs, nil
+}
+
+func (c *MetronomeClient) GetRun(id, runID string) (*Run, error) {
+	run := new(Run)
+	if resp := c.http.HttpGet(c.metronomeUrl(nil, id, "runs", runID), run); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return run, nil
+}
+
+func (c *MetronomeClient) StopRun(id, runID string) error {
+	if resp := c.http.HttpPost(c.metronomeUrl(nil, id, "runs", runID, "actions", "stop"), nil, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *MetronomeClient) ListSchedules(id string) ([]*Schedule, error) {
+	schedules := []*Schedule{}
+	if resp := c.http.HttpGet(c.metronomeUrl(nil, id, "schedules"), &schedules); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return schedules, nil
+}
+
+func (c *MetronomeClient) PutSchedule(id string, schedule *Schedule) (*Schedule, error) {
+	result := new(Schedule)
+	resp := c.http.HttpPut(c.metronomeUrl(nil, id, "schedules", schedule.ID), schedule, result)
+	if resp.Error == httpclient.ErrorNotFound {
+		// the schedule doesn't exist yet
+		resp = c.http.HttpPost(c.metronomeUrl(nil, id, "schedules"), schedule, result)
+	}
+	if resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return result, nil
+}
+
+func (c *MetronomeClient) DeleteSchedule(id, scheduleID string) error {
+	if resp := c.http.HttpDelete(c.metronomeUrl(nil, id, "schedules", scheduleID), nil, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
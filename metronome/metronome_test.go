@@ -0,0 +1,129 @@
+package metronome
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/mockrest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(handler http.HandlerFunc) (c Metronome, requests *[]mockrest.RecordedRequest, stop func()) {
+	requests, stop = mockrest.RecordClient(handler, func(url string, retry *httpclient.RetryPolicy) {
+		c = NewMetronomeClient(url, "", "", &MetronomeOptions{Retry: retry})
+	})
+	return
+}
+
+func TestApplyJobCreatesJobAndSchedules(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	defer stop()
+
+	doc := map[string]interface{}{
+		"id":        "nightly",
+		"run":       map[string]interface{}{"cmd": "backup.sh", "cpus": 0.5},
+		"schedules": []interface{}{map[string]interface{}{"cron": "0 2 * * *", "timezone": "UTC"}},
+	}
+	result, err := c.ApplyJob(doc, false)
+	assert.Nil(t, err)
+	assert.Equal(t, &ApplyResult{JobID: "nightly", Created: true, Schedules: 1}, result)
+
+	assert.Equal(t, 3, len(*requests))
+	create := (*requests)[0]
+	assert.Equal(t, "POST", create.Method)
+	assert.Equal(t, "/v1/jobs", create.Path)
+	assert.Nil(t, create.Body["schedules"])
+	assert.Equal(t, "/v1/jobs/nightly/schedules/default", (*requests)[1].Path)
+	assert.Equal(t, "POST", (*requests)[2].Method)
+	assert.Equal(t, "/v1/jobs/nightly/schedules", (*requests)[2].Path)
+	assert.Equal(t, "0 2 * * *", (*requests)[2].Body["cron"])
+	assert.Equal(t, true, (*requests)[2].Body["enabled"])
+
+	_, err = c.ApplyJob(map[string]interface{}{"run": map[string]interface{}{}}, false)
+	assert.Equal(t, ErrorMissingJobID, err)
+}
+
+func TestApplyJobUpdatesExisting(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	defer stop()
+
+	doc := map[string]interface{}{"id": "nightly"}
+	_, err := c.ApplyJob(doc, false)
+	assert.NotNil(t, err)
+
+	result, err := c.ApplyJob(doc, true)
+	assert.Nil(t, err)
+	assert.False(t, result.Created)
+	last := (*requests)[len(*requests)-1]
+	assert.Equal(t, "PUT", last.Method)
+	assert.Equal(t, "/v1/jobs/nightly", last.Path)
+}
+
+func TestListRunsIncludesHistory(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "nightly",
+			"activeRuns": [{"id": "r3", "jobId": "nightly", "status": "ACTIVE"}],
+			"history": {"successfulFinishedRuns": [{"id": "r2", "createdAt": "a", "finishedAt": "b"}],
+				"failedFinishedRuns": [{"id": "r1"}]}}`)
+	})
+	defer stop()
+
+	runs, err := c.ListRuns("nightly")
+	assert.Nil(t, err)
+	assert.Equal(t, "embed=activeRuns&embed=schedules&embed=history", (*requests)[0].Query)
+	assert.Equal(t, 3, len(runs))
+	assert.Equal(t, "ACTIVE", runs[0].Status)
+	assert.Equal(t, &Run{ID: "r2", JobID: "nightly", Status: RunSuccess, CreatedAt: "a", CompletedAt: "b"}, runs[1])
+	assert.Equal(t, RunFailed, runs[2].Status)
+}
+
+func TestDestroyJobStopsRuns(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {})
+	defer stop()
+
+	assert.Nil(t, c.DestroyJob("nightly", true))
+	assert.Equal(t, "DELETE", (*requests)[0].Method)
+	assert.Equal(t, "stopCurrentJobRuns=true", (*requests)[0].Query)
+}
+
+func TestWaitForRun(t *testing.T) {
+	defer func(i time.Duration) { waitInterval = i }(waitInterval)
+	waitInterval = time.Millisecond
+
+	polls := 0
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/jobs/nightly/runs/r1":
+			if polls++; polls < 3 {
+				fmt.Fprint(w, `{"id": "r1", "status": "ACTIVE"}`)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case "/v1/jobs/nightly/runs/r2":
+			w.WriteHeader(http.StatusNotFound)
+		case "/v1/jobs/nightly":
+			fmt.Fprint(w, `{"id": "nightly", "history": {"successfulFinishedRuns": [{"id": "r1"}], "failedFinishedRuns": [{"id": "r2"}]}}`)
+		}
+	})
+	defer stop()
+
+	assert.Nil(t, c.WaitForRun("nightly", "r1", time.Minute))
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, ErrorRunFailed, c.WaitForRun("nightly", "r2", time.Minute))
+	assert.Equal(t, ErrorTimeout, c.WaitForRun("nightly", "r1", 0))
+}
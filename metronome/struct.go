@@ -0,0 +1,90 @@
+package metronome
+
+// The subset of the Metronome API objects displayed and managed by depcon
+
+type Job struct {
+	ID          string            `json:"id"`
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Run         *JobRunSpec       `json:"run,omitempty"`
+	// embedded when requested
+	ActiveRuns []*Run      `json:"activeRuns,omitempty"`
+	Schedules  []*Schedule `json:"schedules,omitempty"`
+	History    *JobHistory `json:"history,omitempty"`
+}
+
+// JobRunSpec is the subset of the run specification of a job displayed by depcon.  Jobs are created
+// from the descriptor as written so fields not listed here are preserved
+type JobRunSpec struct {
+	Cmd            string  `json:"cmd,omitempty"`
+	Cpus           float64 `json:"cpus"`
+	Mem            float64 `json:"mem"`
+	Disk           float64 `json:"disk"`
+	MaxLaunchDelay int     `json:"maxLaunchDelay,omitempty"`
+	Docker         *struct {
+		Image string `json:"image"`
+	} `json:"docker,omitempty"`
+	Restart *struct {
+		Policy                string `json:"policy"`
+		ActiveDeadlineSeconds int    `json:"activeDeadlineSeconds,omitempty"`
+	} `json:"restart,omitempty"`
+}
+
+type JobHistory struct {
+	SuccessCount           int            `json:"successCount"`
+	FailureCount           int            `json:"failureCount"`
+	LastSuccessAt          string         `json:"lastSuccessAt,omitempty"`
+	LastFailureAt          string         `json:"lastFailureAt,omitempty"`
+	SuccessfulFinishedRuns []*FinishedRun `json:"successfulFinishedRuns,omitempty"`
+	FailedFinishedRuns     []*FinishedRun `json:"failedFinishedRuns,omitempty"`
+}
+
+// FinishedRun is a completed run of the job history
+type FinishedRun struct {
+	ID         string `json:"id"`
+	CreatedAt  string `json:"createdAt"`
+	FinishedAt string `json:"finishedAt"`
+}
+
+// Run is a single execution of a job
+type Run struct {
+	ID    string `json:"id"`
+	JobID string `json:"jobId"`
+	// INITIAL, STARTING, ACTIVE, SUCCESS or FAILED
+	Status      string     `json:"status"`
+	CreatedAt   string     `json:"createdAt"`
+	CompletedAt string     `json:"completedAt,omitempty"`
+	Tasks       []*RunTask `json:"tasks,omitempty"`
+}
+
+// RunTask is a Mesos task launched by a run
+type RunTask struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	StartedAt   string `json:"startedAt,omitempty"`
+	CompletedAt string `json:"completedAt,omitempty"`
+}
+
+// Schedule runs a job by cron expression
+type Schedule struct {
+	ID       string `json:"id"`
+	Cron     string `json:"cron"`
+	TimeZone string `json:"timezone,omitempty"`
+	// ALLOW is the only policy currently supported by Metronome
+	ConcurrencyPolicy       string `json:"concurrencyPolicy,omitempty"`
+	Enabled                 bool   `json:"enabled"`
+	StartingDeadlineSeconds int    `json:"startingDeadlineSeconds,omitempty"`
+	NextRunAt               string `json:"nextRunAt,omitempty"`
+}
+
+// ApplyResult is the outcome of creating or updating a job from a descriptor
+type ApplyResult struct {
+	JobID     string `json:"jobId"`
+	Created   bool   `json:"created"`
+	Schedules int    `json:"schedules"`
+}
+
+// IsFinished returns true if the run has succeeded or failed
+func (r *Run) IsFinished() bool {
+	return r.Status == RunSuccess || r.Status == RunFailed
+}
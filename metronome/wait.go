@@ -0,0 +1,78 @@
+package metronome
+
+import (
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+var logWait = logger.GetLogger("depcon.deploy.wait")
+
+// interval between checks of the run
+var waitInterval = time.Duration(2) * time.Second
+
+func (c *MetronomeClient) WaitForRun(id, runID string, timeout time.Duration) error {
+	t_now := time.Now()
+	t_stop := t_now.Add(timeout)
+	defer c.clearWaitStatus()
+
+	for {
+		if time.Now().After(t_stop) {
+			return ErrorTimeout
+		}
+		var status string
+		run, err := c.GetRun(id, runID)
+		switch {
+		case err == nil:
+			status = run.Status
+		case err == httpclient.ErrorNotFound:
+			// finished runs are removed from the active runs and recorded within the job history
+			if status, err = c.finishedRunStatus(id, runID); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		switch status {
+		case RunSuccess:
+			logWait.Info("Run '%s' of job '%s' succeeded, elapsed time %s", runID, id, utils.ElapsedStr(time.Since(t_now)))
+			return nil
+		case RunFailed:
+			logWait.Error("Run '%s' of job '%s' failed, elapsed time %s", runID, id, utils.ElapsedStr(time.Since(t_now)))
+			return ErrorRunFailed
+		}
+		if !c.reportWaitStatus("Waiting for run "+runID+" of job "+id, 0, 0) {
+			logWait.Info("Run '%s' of job '%s' is %s.  Retrying check in %v", runID, id, status, waitInterval)
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// Returns the status of run {runID} recorded within the history of job {id} or empty if the history
+// has not been updated yet
+func (c *MetronomeClient) finishedRunStatus(id, runID string) (string, error) {
+	runs, err := c.ListRuns(id)
+	if err != nil {
+		return "", err
+	}
+	for _, r := range runs {
+		if r.ID == runID {
+			return r.Status, nil
+		}
+	}
+	return "", nil
+}
+
+func (c *MetronomeClient) reportWaitStatus(message string, done, total int) bool {
+	return c.opts != nil && progress.Report(c.opts.Progress, message, done, total)
+}
+
+func (c *MetronomeClient) clearWaitStatus() {
+	if c.opts != nil {
+		progress.Clear(c.opts.Progress)
+	}
+}
@@ -0,0 +1,59 @@
+package nomad
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+// Environment variables read by the nomad CLI which are honoured so both tools target the same cluster
+const (
+	EnvAddress    = "NOMAD_ADDR"
+	EnvToken      = "NOMAD_TOKEN"
+	EnvNamespace  = "NOMAD_NAMESPACE"
+	EnvRegion     = "NOMAD_REGION"
+	EnvCACert     = "NOMAD_CACERT"
+	EnvClientCert = "NOMAD_CLIENT_CERT"
+	EnvClientKey  = "NOMAD_CLIENT_KEY"
+	EnvSkipVerify = "NOMAD_SKIP_VERIFY"
+
+	DefaultAddress = "http://127.0.0.1:4646"
+)
+
+// Config is the address, credentials and scope of the Nomad cluster
+type Config struct {
+	Address string
+	// Optional ACL token sent as X-Nomad-Token
+	Token string
+	// Optional namespace and region of the jobs.  The agent's defaults are used when empty
+	Namespace string
+	Region    string
+	// Optional client certificate and CA bundle
+	TLS      *httpclient.TLSConfig
+	Insecure bool
+}
+
+// DefaultConfig returns the cluster described by the nomad CLI environment variables falling back to
+// the local agent
+func DefaultConfig() *Config {
+	config := &Config{
+		Address:   os.Getenv(EnvAddress),
+		Token:     os.Getenv(EnvToken),
+		Namespace: os.Getenv(EnvNamespace),
+		Region:    os.Getenv(EnvRegion),
+		TLS: &httpclient.TLSConfig{
+			CAFile:   os.Getenv(EnvCACert),
+			CertFile: os.Getenv(EnvClientCert),
+			KeyFile:  os.Getenv(EnvClientKey),
+		},
+	}
+	if config.Address == "" {
+		config.Address = DefaultAddress
+	}
+	if config.TLS.IsEmpty() {
+		config.TLS = nil
+	}
+	config.Insecure, _ = strconv.ParseBool(os.Getenv(EnvSkipVerify))
+	return config
+}
@@ -0,0 +1,257 @@
+// HashiCorp Nomad API
+package nomad
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	/* --- api related constants --- */
+	API_JOBS       = "v1/jobs"
+	API_JOB        = "v1/job"
+	API_EVALUATION = "v1/evaluation"
+	API_DEPLOYMENT = "v1/deployment"
+
+	tokenHeader = "X-Nomad-Token"
+
+	DefaultTimeout = time.Duration(5) * time.Minute
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.nomad")
+
+var (
+	ErrorTimeout          = errors.New("The operation has timed out")
+	ErrorMissingJobID     = errors.New("Document does not specify a job ID")
+	ErrorPlacementFailed  = errors.New("The scheduler was unable to place all allocations")
+	ErrorEvaluationFailed = errors.New("The evaluation of the job failed")
+	ErrorDeploymentFailed = errors.New("The deployment failed")
+)
+
+type Nomad interface {
+
+	// Returns the namespace the client operates within or empty for the agent's default
+	Namespace() string
+
+	/** Job API */
+
+	// Dry-runs the scheduler for the job within {doc} returning the changes it would make
+	// {doc} - the job or a document holding the job under 'Job' (nomad job run -output)
+	Plan(doc map[string]interface{}) (*PlanResult, error)
+
+	// Registers the job within {doc} creating or updating it
+	// {doc} - the job or a document holding the job under 'Job' (nomad job run -output)
+	Run(doc map[string]interface{}) (*RunResult, error)
+
+	// List all jobs within the namespace
+	ListJobs() ([]*JobListStub, error)
+
+	// Get a Job by ID
+	// {id} - job ID
+	GetJob(id string) (*Job, error)
+
+	// Get a Job with the state of its allocations and latest deployment
+	// {id} - job ID
+	JobStatus(id string) (*JobStatus, error)
+
+	// Stops a job
+	// {id} - job ID
+	// {purge} - if true the job is removed rather than remaining as dead until garbage collected
+	Stop(id string, purge bool) (*RunResult, error)
+
+	// Waits until the evaluation is complete and any deployment it created is successful
+	// {evalID} - the evaluation returned by Run or Stop
+	// {timeout} - the max time to wait
+	WaitForEvaluation(evalID string, timeout time.Duration) error
+}
+
+type NomadClient struct {
+	http      httpclient.HttpClient
+	host      string
+	namespace string
+	region    string
+	opts      *NomadOptions
+}
+
+type NomadOptions struct {
+	// Refuses planning, registering and stopping jobs (every request other than a GET) with
+	// httpclient.ErrorReadOnly
+	ReadOnly bool
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Optional reporter drawing the status (healthy allocations of the total) while waiting on deployments.  Status is logged when nil
+	Progress progress.Reporter
+}
+
+// NewNomadClient creates a client for the cluster of {config}
+func NewNomadClient(config *Config, opts *NomadOptions) Nomad {
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.TLSInsecureSkipVerify = config.Insecure
+	httpConfig.TLS = config.TLS
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	if config.Token != "" {
+		httpConfig.Headers = map[string]string{tokenHeader: config.Token}
+	}
+	if opts != nil {
+		httpConfig.ReadOnly = opts.ReadOnly
+		httpConfig.Timeouts = opts.Timeouts
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+	}
+
+	c := new(NomadClient)
+	c.http = *httpclient.NewHttpClient(*httpConfig)
+	c.host = config.Address
+	c.namespace = config.Namespace
+	c.region = config.Region
+	c.opts = opts
+	return c
+}
+
+func (c *NomadClient) Namespace() string {
+	return c.namespace
+}
+
+// Returns the URL of {api} followed by {elements} scoped to the namespace and region with the
+// additional query {params}
+func (c *NomadClient) nomadUrl(params url.Values, api string, elements ...string) string {
+	for i, e := range elements {
+		elements[i] = url.PathEscape(e)
+	}
+	if params == nil {
+		params = url.Values{}
+	}
+	if c.namespace != "" {
+		params.Set("namespace", c.namespace)
+	}
+	if c.region != "" {
+		params.Set("region", c.region)
+	}
+	uri := utils.BuildPath(c.host, append([]string{api}, elements...))
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+	return uri
+}
+
+// Returns the job within {doc} and its ID
+func jobFromDocument(doc map[string]interface{}) (map[string]interface{}, string, error) {
+	job := doc
+	if wrapped, ok := doc["Job"].(map[string]interface{}); ok {
+		job = wrapped
+	}
+	id, _ := job["ID"].(string)
+	if id == "" {
+		// the ID defaults to the name as it does for HCL jobs
+		if id, _ = job["Name"].(string); id == "" {
+			return nil, "", ErrorMissingJobID
+		}
+		job["ID"] = id
+	}
+	return job, id, nil
+}
+
+func (c *NomadClient) Plan(doc map[string]interface{}) (*PlanResult, error) {
+	job, id, err := jobFromDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(planResponse)
+	if r := c.http.HttpPost(c.nomadUrl(nil, API_JOB, id, "plan"), &planRequest{Job: job, Diff: true}, resp); r.Error != nil {
+		return nil, r.Err()
+	}
+
+	result := &PlanResult{JobID: id, JobModifyIndex: resp.JobModifyIndex, FailedGroups: resp.FailedTGAllocs, Warnings: resp.Warnings}
+	if resp.Diff != nil {
+		result.DiffType = resp.Diff.Type
+	}
+	if resp.Annotations != nil {
+		result.Updates = resp.Annotations.DesiredTGUpdates
+	}
+	return result, nil
+}
+
+func (c *NomadClient) Run(doc map[string]interface{}) (*RunResult, error) {
+	job, id, err := jobFromDocument(doc)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Registering job '%s'", id)
+	result := &RunResult{JobID: id}
+	if resp := c.http.HttpPost(c.nomadUrl(nil, API_JOBS), &registerRequest{Job: job}, result); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return result, nil
+}
+
+func (c *NomadClient) ListJobs() ([]*JobListStub, error) {
+	jobs := []*JobListStub{}
+	if resp := c.http.HttpGet(c.nomadUrl(nil, API_JOBS), &jobs); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return jobs, nil
+}
+
+func (c *NomadClient) GetJob(id string) (*Job, error) {
+	job := new(Job)
+	if resp := c.http.HttpGet(c.nomadUrl(nil, API_JOB, id), job); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return job, nil
+}
+
+func (c *NomadClient) JobStatus(id string) (*JobStatus, error) {
+	job, err := c.GetJob(id)
+	if err != nil {
+		return nil, err
+	}
+	summary := new(JobSummary)
+	if resp := c.http.HttpGet(c.nomadUrl(nil, API_JOB, id, "summary"), summary); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	// the latest deployment is null for jobs which have never been deployed
+	var deployment *Deployment
+	if resp := c.http.HttpGet(c.nomadUrl(nil, API_JOB, id, "deployment"), &deployment); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return &JobStatus{Job: job, Summary: summary.Summary, Deployment: deployment}, nil
+}
+
+func (c *NomadClient) Stop(id string, purge bool) (*RunResult, error) {
+	log.Info("Stopping job '%s'", id)
+	params := url.Values{}
+	if purge {
+		params.Set("purge", "true")
+	}
+	result := &RunResult{JobID: id}
+	if resp := c.http.HttpDelete(c.nomadUrl(params, API_JOB, id), nil, result); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return result, nil
+}
+
+func (c *NomadClient) getEvaluation(id string) (*Evaluation, error) {
+	eval := new(Evaluation)
+	if resp := c.http.HttpGet(c.nomadUrl(nil, API_EVALUATION, id), eval); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return eval, nil
+}
+
+func (c *NomadClient) getDeployment(id string) (*Deployment, error) {
+	deployment := new(Deployment)
+	if resp := c.http.HttpGet(c.nomadUrl(nil, API_DEPLOYMENT, id), deployment); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return deployment, nil
+}
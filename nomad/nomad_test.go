@@ -0,0 +1,150 @@
+package nomad
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/mockrest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(handler http.HandlerFunc) (c Nomad, requests *[]mockrest.RecordedRequest, stop func()) {
+	requests, stop = mockrest.RecordClient(handler, func(url string, retry *httpclient.RetryPolicy) {
+		c = NewNomadClient(&Config{Address: url, Namespace: "web", Token: "secret"}, &NomadOptions{Retry: retry})
+	})
+	return
+}
+
+func TestRunRegistersWrappedJob(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"EvalID": "e1", "JobModifyIndex": 42}`)
+	})
+	defer stop()
+
+	doc := map[string]interface{}{"Job": map[string]interface{}{"Name": "api", "Type": "service"}}
+	result, err := c.Run(doc)
+	assert.Nil(t, err)
+	assert.Equal(t, &RunResult{JobID: "api", EvalID: "e1", JobModifyIndex: 42}, result)
+
+	req := (*requests)[0]
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "/v1/jobs", req.Path)
+	assert.Equal(t, "namespace=web", req.Query)
+	assert.Equal(t, "secret", req.Header.Get(tokenHeader))
+	assert.Equal(t, "api", req.Body["Job"].(map[string]interface{})["ID"])
+
+	_, err = c.Run(map[string]interface{}{"Type": "batch"})
+	assert.Equal(t, ErrorMissingJobID, err)
+}
+
+func TestPlan(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Annotations": {"DesiredTGUpdates": {"web": {"Place": 2, "DestructiveUpdate": 1}}},
+			"Diff": {"Type": "Edited"}, "JobModifyIndex": 7, "FailedTGAllocs": {"cache": {"NodesEvaluated": 3, "NodesExhausted": 3}}}`)
+	})
+	defer stop()
+
+	result, err := c.Plan(map[string]interface{}{"ID": "api"})
+	assert.Nil(t, err)
+	assert.Equal(t, "/v1/job/api/plan", (*requests)[0].Path)
+	assert.Equal(t, true, (*requests)[0].Body["Diff"])
+	assert.Equal(t, "Edited", result.DiffType)
+	assert.Equal(t, uint64(7), result.JobModifyIndex)
+	assert.Equal(t, &GroupUpdate{Place: 2, DestructiveUpdate: 1}, result.Updates["web"])
+	assert.Equal(t, 3, result.FailedGroups["cache"].NodesExhausted)
+}
+
+func TestJobStatus(t *testing.T) {
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/job/api":
+			fmt.Fprint(w, `{"ID": "api", "Status": "running", "TaskGroups": [{"Name": "web", "Count": 2}]}`)
+		case "/v1/job/api/summary":
+			fmt.Fprint(w, `{"JobID": "api", "Summary": {"web": {"Running": 2}}}`)
+		default:
+			fmt.Fprint(w, `null`)
+		}
+	})
+	defer stop()
+
+	status, err := c.JobStatus("api")
+	assert.Nil(t, err)
+	assert.Equal(t, "running", status.Status)
+	assert.Equal(t, 2, status.Summary["web"].Running)
+	assert.Nil(t, status.Deployment)
+}
+
+func TestStopPurge(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"EvalID": "e2"}`)
+	})
+	defer stop()
+
+	result, err := c.Stop("my api", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "e2", result.EvalID)
+	assert.Equal(t, "DELETE", (*requests)[0].Method)
+	assert.Equal(t, "/v1/job/my api", (*requests)[0].Path)
+	assert.Equal(t, "namespace=web&purge=true", (*requests)[0].Query)
+}
+
+func TestWaitForEvaluation(t *testing.T) {
+	defer func(i time.Duration) { waitInterval = i }(waitInterval)
+	waitInterval = time.Millisecond
+
+	polls := 0
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/evaluation/e1":
+			fmt.Fprint(w, `{"ID": "e1", "Status": "complete", "JobID": "api", "DeploymentID": "d1"}`)
+		case "/v1/deployment/d1":
+			polls++
+			status := "running"
+			if polls == 3 {
+				status = "successful"
+			}
+			fmt.Fprintf(w, `{"ID": "d1", "JobID": "api", "Status": "%s", "TaskGroups": {"web": {"DesiredTotal": 2, "HealthyAllocs": 1}}}`, status)
+		case "/v1/evaluation/e2":
+			fmt.Fprint(w, `{"ID": "e2", "Status": "complete", "FailedTGAllocs": {"web": {"NodesEvaluated": 1}}}`)
+		}
+	})
+	defer stop()
+
+	assert.Nil(t, c.WaitForEvaluation("e1", time.Minute))
+	assert.Equal(t, 3, polls)
+	assert.Equal(t, ErrorPlacementFailed, c.WaitForEvaluation("e2", time.Minute))
+	assert.Equal(t, ErrorTimeout, c.WaitForEvaluation("e1", 0))
+}
+
+func TestWaitForEvaluationFailedDeployment(t *testing.T) {
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/evaluation/e1" {
+			fmt.Fprint(w, `{"ID": "e1", "Status": "complete", "DeploymentID": "d1"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ID": "d1", "Status": "failed", "StatusDescription": "Failed due to unhealthy allocations"}`)
+	})
+	defer stop()
+
+	assert.Equal(t, ErrorDeploymentFailed, c.WaitForEvaluation("e1", time.Minute))
+}
+
+func TestDefaultConfig(t *testing.T) {
+	for _, k := range []string{EnvAddress, EnvToken, EnvCACert, EnvSkipVerify} {
+		defer os.Setenv(k, os.Getenv(k))
+	}
+	os.Unsetenv(EnvAddress)
+	os.Unsetenv(EnvCACert)
+	os.Setenv(EnvToken, "abc")
+	os.Setenv(EnvSkipVerify, "true")
+
+	config := DefaultConfig()
+	assert.Equal(t, DefaultAddress, config.Address)
+	assert.Equal(t, "abc", config.Token)
+	assert.Nil(t, config.TLS)
+	assert.True(t, config.Insecure)
+}
@@ -0,0 +1,149 @@
+package nomad
+
+// The subset of the Nomad API objects displayed and managed by depcon
+
+type Job struct {
+	ID          string       `json:"ID"`
+	Name        string       `json:"Name"`
+	Namespace   string       `json:"Namespace,omitempty"`
+	Region      string       `json:"Region,omitempty"`
+	Type        string       `json:"Type"`
+	Priority    int          `json:"Priority"`
+	Status      string       `json:"Status"`
+	Stop        bool         `json:"Stop"`
+	Version     int          `json:"Version"`
+	Datacenters []string     `json:"Datacenters"`
+	TaskGroups  []*TaskGroup `json:"TaskGroups"`
+	// nanoseconds since the epoch
+	SubmitTime int64 `json:"SubmitTime,omitempty"`
+}
+
+type TaskGroup struct {
+	Name  string `json:"Name"`
+	Count int    `json:"Count"`
+}
+
+// JobListStub is the summary of a job returned when listing jobs
+type JobListStub struct {
+	ID         string      `json:"ID"`
+	Name       string      `json:"Name"`
+	Type       string      `json:"Type"`
+	Priority   int         `json:"Priority"`
+	Status     string      `json:"Status"`
+	Stop       bool        `json:"Stop"`
+	JobSummary *JobSummary `json:"JobSummary"`
+	SubmitTime int64       `json:"SubmitTime,omitempty"`
+}
+
+type JobSummary struct {
+	JobID   string                       `json:"JobID"`
+	Summary map[string]*TaskGroupSummary `json:"Summary"`
+}
+
+// TaskGroupSummary is the count of the group's allocations in each state
+type TaskGroupSummary struct {
+	Queued   int `json:"Queued"`
+	Complete int `json:"Complete"`
+	Failed   int `json:"Failed"`
+	Running  int `json:"Running"`
+	Starting int `json:"Starting"`
+	Lost     int `json:"Lost"`
+}
+
+type Evaluation struct {
+	ID                string                        `json:"ID"`
+	Status            string                        `json:"Status"`
+	StatusDescription string                        `json:"StatusDescription,omitempty"`
+	JobID             string                        `json:"JobID"`
+	DeploymentID      string                        `json:"DeploymentID,omitempty"`
+	BlockedEval       string                        `json:"BlockedEval,omitempty"`
+	FailedTGAllocs    map[string]*AllocationMetrics `json:"FailedTGAllocs,omitempty"`
+}
+
+// AllocationMetrics describes why allocations of a group could not be placed
+type AllocationMetrics struct {
+	NodesEvaluated     int            `json:"NodesEvaluated"`
+	NodesFiltered      int            `json:"NodesFiltered"`
+	NodesExhausted     int            `json:"NodesExhausted"`
+	DimensionExhausted map[string]int `json:"DimensionExhausted,omitempty"`
+	ConstraintFiltered map[string]int `json:"ConstraintFiltered,omitempty"`
+	CoalescedFailures  int            `json:"CoalescedFailures"`
+}
+
+type Deployment struct {
+	ID                string                           `json:"ID"`
+	JobID             string                           `json:"JobID"`
+	JobVersion        int                              `json:"JobVersion"`
+	Status            string                           `json:"Status"`
+	StatusDescription string                           `json:"StatusDescription"`
+	TaskGroups        map[string]*DeploymentGroupState `json:"TaskGroups"`
+}
+
+type DeploymentGroupState struct {
+	DesiredTotal    int `json:"DesiredTotal"`
+	DesiredCanaries int `json:"DesiredCanaries"`
+	PlacedAllocs    int `json:"PlacedAllocs"`
+	HealthyAllocs   int `json:"HealthyAllocs"`
+	UnhealthyAllocs int `json:"UnhealthyAllocs"`
+}
+
+// JobStatus is a job with the state of its allocations and latest deployment
+type JobStatus struct {
+	*Job
+	Summary map[string]*TaskGroupSummary `json:"Summary"`
+	// nil when the job has never been deployed (eg. batch jobs)
+	Deployment *Deployment `json:"Deployment,omitempty"`
+}
+
+// PlanResult is the outcome of a dry-run of the scheduler for a job
+type PlanResult struct {
+	JobID string `json:"JobID"`
+	// Added, Edited, Deleted or None
+	DiffType string `json:"DiffType"`
+	// Modify index of the job when planned (zero for a new job)
+	JobModifyIndex uint64                  `json:"JobModifyIndex"`
+	Updates        map[string]*GroupUpdate `json:"Updates"`
+	// Groups which can't be placed and the reason
+	FailedGroups map[string]*AllocationMetrics `json:"FailedGroups,omitempty"`
+	Warnings     string                        `json:"Warnings,omitempty"`
+}
+
+// GroupUpdate is the count of allocations of a group the scheduler would change
+type GroupUpdate struct {
+	Place             int `json:"Place"`
+	Stop              int `json:"Stop"`
+	Migrate           int `json:"Migrate"`
+	InPlaceUpdate     int `json:"InPlaceUpdate"`
+	DestructiveUpdate int `json:"DestructiveUpdate"`
+	Canary            int `json:"Canary"`
+	Ignore            int `json:"Ignore"`
+}
+
+// RunResult is the evaluation created by registering or stopping a job
+type RunResult struct {
+	JobID          string `json:"JobID"`
+	EvalID         string `json:"EvalID"`
+	JobModifyIndex uint64 `json:"JobModifyIndex,omitempty"`
+	Warnings       string `json:"Warnings,omitempty"`
+}
+
+type planRequest struct {
+	Job  map[string]interface{} `json:"Job"`
+	Diff bool                   `json:"Diff"`
+}
+
+type planResponse struct {
+	Annotations *struct {
+		DesiredTGUpdates map[string]*GroupUpdate `json:"DesiredTGUpdates"`
+	} `json:"Annotations"`
+	Diff *struct {
+		Type string `json:"Type"`
+	} `json:"Diff"`
+	FailedTGAllocs map[string]*AllocationMetrics `json:"FailedTGAllocs"`
+	JobModifyIndex uint64                        `json:"JobModifyIndex"`
+	Warnings       string                        `json:"Warnings"`
+}
+
+type registerRequest struct {
+	Job map[string]interface{} `json:"Job"`
+}
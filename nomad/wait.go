@@ -0,0 +1,97 @@
+package nomad
+
+import (
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+var logWait = logger.GetLogger("depcon.deploy.wait")
+
+// interval between checks of the evaluation and deployment
+var waitInterval = time.Duration(2) * time.Second
+
+func (c *NomadClient) WaitForEvaluation(evalID string, timeout time.Duration) error {
+	t_now := time.Now()
+	t_stop := t_now.Add(timeout)
+
+	var eval *Evaluation
+	for {
+		if time.Now().After(t_stop) {
+			return ErrorTimeout
+		}
+		var err error
+		if eval, err = c.getEvaluation(evalID); err != nil {
+			return err
+		}
+		if eval.Status != "pending" {
+			break
+		}
+		logWait.Info("Evaluation '%s' is pending.  Retrying check in %v", evalID, waitInterval)
+		time.Sleep(waitInterval)
+	}
+
+	if eval.Status != "complete" {
+		logWait.Error("Evaluation '%s' is %s: %s", evalID, eval.Status, eval.StatusDescription)
+		return ErrorEvaluationFailed
+	}
+	if len(eval.FailedTGAllocs) > 0 {
+		for group, metrics := range eval.FailedTGAllocs {
+			logWait.Error("Task group '%s' could not be placed: %d of %d nodes evaluated were exhausted %v", group, metrics.NodesExhausted, metrics.NodesEvaluated, metrics.DimensionExhausted)
+		}
+		return ErrorPlacementFailed
+	}
+	// batch and system jobs don't create deployments
+	if eval.DeploymentID == "" {
+		logWait.Info("Evaluation of job '%s' is complete, elapsed time %s", eval.JobID, utils.ElapsedStr(time.Since(t_now)))
+		return nil
+	}
+
+	for {
+		if time.Now().After(t_stop) {
+			c.clearWaitStatus()
+			return ErrorTimeout
+		}
+		d, err := c.getDeployment(eval.DeploymentID)
+		if err != nil {
+			c.clearWaitStatus()
+			return err
+		}
+		switch d.Status {
+		case "successful":
+			c.clearWaitStatus()
+			logWait.Info("Deployment of job '%s' is successful, elapsed time %s", d.JobID, utils.ElapsedStr(time.Since(t_now)))
+			return nil
+		case "failed", "cancelled":
+			c.clearWaitStatus()
+			logWait.Error("Deployment of job '%s' is %s: %s", d.JobID, d.Status, d.StatusDescription)
+			return ErrorDeploymentFailed
+		}
+		healthy, desired := d.Allocations()
+		if !c.reportWaitStatus("Waiting for job "+d.JobID+" to become healthy", healthy, desired) {
+			logWait.Info("%v of %v allocations of '%s' are healthy.  Retrying check in %v", healthy, desired, d.JobID, waitInterval)
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// Allocations returns the healthy and desired allocations across the task groups of the deployment
+func (d *Deployment) Allocations() (healthy, desired int) {
+	for _, g := range d.TaskGroups {
+		healthy += g.HealthyAllocs
+		desired += g.DesiredTotal
+	}
+	return
+}
+
+func (c *NomadClient) reportWaitStatus(message string, done, total int) bool {
+	return c.opts != nil && progress.Report(c.opts.Progress, message, done, total)
+}
+
+func (c *NomadClient) clearWaitStatus() {
+	if c.opts != nil {
+		progress.Clear(c.opts.Progress)
+	}
+}
@@ -0,0 +1,198 @@
+// Promotes a descriptor through a pipeline of environments (eg. dev -> staging -> prod) where each stage
+// is gated by an approval and verified before the next
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	// the stage is deployed as soon as the previous stage is verified
+	GateAuto = "auto"
+	// the stage is deployed once approved (eg. confirmed on the terminal)
+	GateManual = "manual"
+
+	DefaultVerifyRetries  = 10
+	DefaultVerifyInterval = 5 * time.Second
+)
+
+var log = logger.GetLogger("depcon.pipeline")
+
+var (
+	ErrorName     = errors.New("The pipeline requires a name")
+	ErrorApp      = errors.New("The pipeline requires the app (or group) descriptor it deploys")
+	ErrorNoStages = errors.New("The pipeline declares no stages")
+)
+
+// Pipeline deploys the same descriptor and params to each stage in order
+type Pipeline struct {
+	Name string `json:"name"`
+	// App or group descriptor relative to the pipeline
+	App string `json:"app"`
+	// Template context the descriptor is rendered with for each stage's environment relative to the pipeline
+	TemplateContext string `json:"tempctx,omitempty"`
+	// Values of the ${PARAMS} within the descriptor (eg. the image tag) which are the same for every stage
+	Params map[string]string `json:"params,omitempty"`
+	Stages []*Stage          `json:"stages"`
+	dir    string
+}
+
+// Stage deploys the descriptor to an environment
+type Stage struct {
+	Name string `json:"name"`
+	// Environment of the depcon config deployed to.  Default: the stage's name
+	Environment string `json:"environment,omitempty"`
+	// auto or manual.  Default: auto
+	Gate string `json:"gate,omitempty"`
+	// Max duration to wait for the deployment to become healthy (eg. 5m)
+	Timeout string `json:"timeout,omitempty"`
+	// Checks which must pass before the pipeline moves on to the next stage
+	Verify  []*Verification `json:"verify,omitempty"`
+	timeout time.Duration
+}
+
+// Verification is an HTTP request or command which must succeed after a stage is deployed.  It is retried
+// until it succeeds or runs out of retries
+type Verification struct {
+	// URL requested with GET
+	HTTP string `json:"http,omitempty"`
+	// Expected status of the HTTP request.  Default: 200
+	Status int `json:"status,omitempty"`
+	// Command run by the shell.  DEPCON_ENV and DEPCON_STAGE are set to the stage's environment and name
+	Command string `json:"command,omitempty"`
+	// Attempts before the verification fails.  Default: 10
+	Retries int `json:"retries,omitempty"`
+	// Time between attempts (eg. 5s).  Default: 5s
+	Interval string `json:"interval,omitempty"`
+	interval time.Duration
+}
+
+// Env returns the environment the stage deploys to
+func (s *Stage) Env() string {
+	if s.Environment != "" {
+		return s.Environment
+	}
+	return s.Name
+}
+
+// Load parses and validates the pipeline {filename} (.yaml, .yml or .json)
+func Load(filename string) (*Pipeline, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := &Pipeline{dir: filepath.Dir(filename)}
+	if err := enc.UnMarshalStr(string(b), p); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return p, nil
+}
+
+// ForEnvironments returns a pipeline deploying the descriptor {app} to the environments {envs} in order.
+// Every environment after the first is gated by {gate}, waited on for up to {timeout} (the default timeout
+// when zero) and verified by {verify}
+func ForEnvironments(app string, envs []string, gate string, timeout time.Duration, verify []*Verification) (*Pipeline, error) {
+	p := &Pipeline{Name: filepath.Base(app), App: app}
+	for i, env := range envs {
+		s := &Stage{Name: env, Verify: verify}
+		if i > 0 {
+			s.Gate = gate
+		}
+		if timeout > 0 {
+			s.Timeout = timeout.String()
+		}
+		p.Stages = append(p.Stages, s)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Pipeline) validate() error {
+	switch {
+	case p.Name == "":
+		return ErrorName
+	case p.App == "":
+		return ErrorApp
+	case len(p.Stages) == 0:
+		return ErrorNoStages
+	}
+
+	names := map[string]bool{}
+	for _, s := range p.Stages {
+		if s.Name == "" {
+			return errors.New("every stage requires a name")
+		}
+		if names[s.Name] {
+			return fmt.Errorf("stage '%s' is declared more than once", s.Name)
+		}
+		names[s.Name] = true
+
+		if s.Gate == "" {
+			s.Gate = GateAuto
+		}
+		if s.Gate != GateAuto && s.Gate != GateManual {
+			return fmt.Errorf("stage '%s': gate must be %s or %s", s.Name, GateAuto, GateManual)
+		}
+		if s.Timeout != "" {
+			d, err := time.ParseDuration(s.Timeout)
+			if err != nil {
+				return fmt.Errorf("stage '%s': invalid timeout '%s'", s.Name, s.Timeout)
+			}
+			s.timeout = d
+		}
+		for _, v := range s.Verify {
+			if (v.HTTP == "") == (v.Command == "") {
+				return fmt.Errorf("stage '%s': a verification requires exactly one of http or command", s.Name)
+			}
+			if v.Status == 0 {
+				v.Status = 200
+			}
+			if v.Retries <= 0 {
+				v.Retries = DefaultVerifyRetries
+			}
+			v.interval = DefaultVerifyInterval
+			if v.Interval != "" {
+				d, err := time.ParseDuration(v.Interval)
+				if err != nil {
+					return fmt.Errorf("stage '%s': invalid interval '%s'", s.Name, v.Interval)
+				}
+				v.interval = d
+			}
+		}
+	}
+	return nil
+}
+
+// Path returns {name} relative to the pipeline unless it is absolute
+func (p *Pipeline) Path(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(p.dir, name)
+}
+
+// Stage returns the stage named {name} or nil
+func (p *Pipeline) Stage(name string) *Stage {
+	for _, s := range p.Stages {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMarathon records the apps deployed to an environment.  Calls it doesn't implement panic
+type fakeMarathon struct {
+	marathon.Marathon
+	env      string
+	deployed map[string][]string
+}
+
+func (f *fakeMarathon) ListApplications() (*marathon.Applications, error) {
+	return &marathon.Applications{}, nil
+}
+
+func (f *fakeMarathon) CreateApplication(app *marathon.Application, wait, force bool) (*marathon.Application, error) {
+	f.deployed[f.env] = append(f.deployed[f.env], app.Container.Docker.Image)
+	return app, nil
+}
+
+func (f *fakeMarathon) WaitForApplication(id string, timeout time.Duration) error {
+	return nil
+}
+
+func writePipeline(t *testing.T, dir, verifyURL string) string {
+	files := map[string]string{
+		"pipeline.yaml": `
+name: shop
+app: app.json
+params:
+  TAG: "1.4"
+stages:
+  - name: dev
+  - name: staging
+    verify:
+      - http: ` + verifyURL + `
+        retries: 2
+        interval: 1ms
+  - name: production
+    environment: prod
+    gate: manual
+`,
+		"app.json": `{"id": "/shop", "container": {"docker": {"image": "shop:${TAG}"}}}`,
+	}
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return filepath.Join(dir, "pipeline.yaml")
+}
+
+func newRunner(deployed map[string][]string, approve func(*Pipeline, *Stage) error) *Runner {
+	return &Runner{
+		Client: func(env string) (marathon.Marathon, error) {
+			return &fakeMarathon{env: env, deployed: deployed}, nil
+		},
+		Approve: approve,
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pipeline")
+	defer os.RemoveAll(dir)
+
+	p, err := Load(writePipeline(t, dir, "http://localhost"))
+	assert.Nil(t, err)
+	assert.Len(t, p.Stages, 3)
+	assert.Equal(t, GateAuto, p.Stages[0].Gate)
+	assert.Equal(t, "dev", p.Stages[0].Env())
+	assert.Equal(t, 200, p.Stages[1].Verify[0].Status)
+	assert.Equal(t, "prod", p.Stages[2].Env())
+
+	invalid := map[string]string{
+		"noapp.yaml":  "name: p\nstages:\n  - name: dev\n",
+		"gate.yaml":   "name: p\napp: app.json\nstages:\n  - name: dev\n    gate: later\n",
+		"verify.yaml": "name: p\napp: app.json\nstages:\n  - name: dev\n    verify:\n      - retries: 1\n",
+	}
+	for name, content := range invalid {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		_, err := Load(filepath.Join(dir, name))
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestRunPromotes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	dir, _ := ioutil.TempDir("", "pipeline")
+	defer os.RemoveAll(dir)
+	p, _ := Load(writePipeline(t, dir, server.URL))
+
+	deployed := map[string][]string{}
+	approved := []string{}
+	results, err := newRunner(deployed, func(p *Pipeline, s *Stage) error {
+		approved = append(approved, s.Name)
+		return nil
+	}).Run(p, "")
+
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, ResultPromoted, results[2].Result)
+	assert.Equal(t, 1, results[1].Verified)
+	assert.Equal(t, []string{"production"}, approved)
+	assert.Equal(t, map[string][]string{"dev": {"shop:1.4"}, "staging": {"shop:1.4"}, "prod": {"shop:1.4"}}, deployed)
+}
+
+func TestRunStopsAtFailedVerification(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	dir, _ := ioutil.TempDir("", "pipeline")
+	defer os.RemoveAll(dir)
+	p, _ := Load(writePipeline(t, dir, server.URL))
+
+	deployed := map[string][]string{}
+	results, err := newRunner(deployed, nil).Run(p, "")
+	assert.NotNil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, ResultFailed, results[1].Result)
+	assert.NotContains(t, deployed, "prod")
+}
+
+func TestRunFromStage(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pipeline")
+	defer os.RemoveAll(dir)
+	p, _ := Load(writePipeline(t, dir, "http://localhost"))
+
+	deployed := map[string][]string{}
+	results, err := newRunner(deployed, func(p *Pipeline, s *Stage) error {
+		return errors.New("declined")
+	}).Run(p, "production")
+	assert.NotNil(t, err)
+	assert.Equal(t, ResultSkipped, results[0].Result)
+	assert.Equal(t, ResultSkipped, results[1].Result)
+	assert.Equal(t, ResultRejected, results[2].Result)
+	assert.Empty(t, deployed)
+
+	_, err = newRunner(deployed, nil).Run(p, "qa")
+	assert.NotNil(t, err)
+}
+
+func TestForEnvironments(t *testing.T) {
+	p, err := ForEnvironments("apps/web.yaml", []string{"dev", "staging", "prod"}, GateManual, time.Minute, []*Verification{{Command: "./smoke.sh"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "web.yaml", p.Name)
+	assert.Equal(t, "apps/web.yaml", p.Path(p.App))
+	assert.Equal(t, []string{GateAuto, GateManual, GateManual}, []string{p.Stages[0].Gate, p.Stages[1].Gate, p.Stages[2].Gate})
+	assert.Equal(t, time.Minute, p.Stages[2].timeout)
+	assert.Equal(t, DefaultVerifyRetries, p.Stages[1].Verify[0].Retries)
+
+	_, err = ForEnvironments("web.yaml", []string{"dev", "dev"}, GateAuto, 0, nil)
+	assert.EqualError(t, err, "stage 'dev' is declared more than once")
+	_, err = ForEnvironments("web.yaml", []string{"dev", "prod"}, "sometimes", 0, nil)
+	assert.NotNil(t, err)
+}
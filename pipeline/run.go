@@ -0,0 +1,205 @@
+package pipeline
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/reconcile"
+)
+
+const (
+	ResultPromoted = "promoted"
+	ResultFailed   = "failed"
+	ResultRejected = "rejected"
+	// the stage precedes the stage the run started from
+	ResultSkipped = "skipped"
+)
+
+// ClientFactory returns the Marathon client of the environment {env}
+type ClientFactory func(env string) (marathon.Marathon, error)
+
+// StageResult is the outcome of a stage
+type StageResult struct {
+	Stage       string `json:"stage"`
+	Environment string `json:"environment"`
+	// The changes made to the app or group (eg. create, update, none)
+	Action   string        `json:"action,omitempty"`
+	Verified int           `json:"verified"`
+	Result   string        `json:"result"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Runner runs pipelines
+type Runner struct {
+	Client ClientFactory
+	// Returns the options rendering the descriptor for the environment {env}.  The params of the pipeline are
+	// added to (and overridden by) those of the options
+	Load func(env string) *reconcile.LoadOptions
+	// Approves a manual stage returning an error when it's rejected.  Manual stages are rejected when nil
+	Approve func(p *Pipeline, s *Stage) error
+	// Receives the result of each stage as it completes.  Optional
+	Report func(*StageResult)
+	// Client used by HTTP verifications.  http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Run deploys the pipeline's descriptor to each stage in order starting at stage {from} (the first stage
+// when empty).  The run stops at the first stage which is rejected, fails to deploy or fails a verification
+func (r *Runner) Run(p *Pipeline, from string) ([]*StageResult, error) {
+	if from != "" && p.Stage(from) == nil {
+		return nil, fmt.Errorf("Pipeline '%s' has no stage '%s'", p.Name, from)
+	}
+
+	results := []*StageResult{}
+	started := from == ""
+	for _, s := range p.Stages {
+		result := &StageResult{Stage: s.Name, Environment: s.Env()}
+		results = append(results, result)
+		if !started && s.Name != from {
+			result.Result = ResultSkipped
+			r.report(result)
+			continue
+		}
+		started = true
+
+		if s.Gate == GateManual {
+			if r.Approve == nil {
+				result.Result, result.Error = ResultRejected, "manual approval is required"
+			} else if err := r.Approve(p, s); err != nil {
+				result.Result, result.Error = ResultRejected, err.Error()
+			}
+			if result.Result == ResultRejected {
+				r.report(result)
+				return results, fmt.Errorf("Stage '%s' of pipeline '%s' was not approved", s.Name, p.Name)
+			}
+		}
+
+		begin := time.Now()
+		err := r.runStage(p, s, result)
+		result.Duration = time.Since(begin).Round(time.Millisecond)
+		if err != nil {
+			result.Result, result.Error = ResultFailed, err.Error()
+			r.report(result)
+			return results, fmt.Errorf("Stage '%s' of pipeline '%s' failed: %s", s.Name, p.Name, err.Error())
+		}
+		result.Result = ResultPromoted
+		r.report(result)
+	}
+	return results, nil
+}
+
+// Deploys the descriptor to the stage's environment, waits for it to be healthy and verifies it
+func (r *Runner) runStage(p *Pipeline, s *Stage, result *StageResult) error {
+	log.Info("Deploying stage '%s' of pipeline '%s' to environment '%s'", s.Name, p.Name, s.Env())
+	client, err := r.Client(s.Env())
+	if err != nil {
+		return err
+	}
+	descs, err := reconcile.LoadFile(p.Path(p.App), r.loadOptions(p, s))
+	if err != nil {
+		return err
+	}
+	apps, err := client.ListApplications()
+	if err != nil {
+		return err
+	}
+
+	changes := reconcile.Plan(descs, apps.Apps)
+	actions := []string{}
+	for _, c := range changes {
+		actions = append(actions, c.Action)
+	}
+	result.Action = strings.Join(actions, ",")
+
+	timeout := s.timeout
+	if timeout == 0 {
+		timeout = marathon.DefaultTimeout
+	}
+	if reconcile.Apply(client, changes, true, timeout) > 0 {
+		for _, c := range changes {
+			if c.Result == reconcile.ResultFailed {
+				return fmt.Errorf("%s: %s", c.ID, c.Error)
+			}
+		}
+	}
+
+	for _, v := range s.Verify {
+		if err := r.verify(s, v); err != nil {
+			return err
+		}
+		result.Verified++
+	}
+	return nil
+}
+
+func (r *Runner) loadOptions(p *Pipeline, s *Stage) *reconcile.LoadOptions {
+	o := reconcile.LoadOptions{}
+	if r.Load != nil {
+		o = *r.Load(s.Env())
+	}
+	params := map[string]string{}
+	for _, m := range []map[string]string{p.Params, o.Params} {
+		for k, v := range m {
+			params[k] = v
+		}
+	}
+	o.Params = params
+	return &o
+}
+
+// Attempts verification {v} until it passes or runs out of retries
+func (r *Runner) verify(s *Stage, v *Verification) error {
+	var err error
+	for attempt := 1; attempt <= v.Retries; attempt++ {
+		if v.HTTP != "" {
+			err = r.verifyHTTP(v)
+		} else {
+			err = verifyCommand(s, v)
+		}
+		if err == nil {
+			return nil
+		}
+		log.Debug("Verification of stage '%s' failed (attempt %d of %d): %s", s.Name, attempt, v.Retries, err.Error())
+		if attempt < v.Retries {
+			time.Sleep(v.interval)
+		}
+	}
+	return fmt.Errorf("verification failed after %d attempt(s): %s", v.Retries, err.Error())
+}
+
+func (r *Runner) verifyHTTP(v *Verification) error {
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(v.HTTP)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != v.Status {
+		return fmt.Errorf("GET %s returned %d, expected %d", v.HTTP, resp.StatusCode, v.Status)
+	}
+	return nil
+}
+
+func verifyCommand(s *Stage, v *Verification) error {
+	cmd := exec.Command("sh", "-c", v.Command)
+	cmd.Env = append(os.Environ(), "DEPCON_ENV="+s.Env(), "DEPCON_STAGE="+s.Name)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("'%s' failed: %s %s", v.Command, err.Error(), strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (r *Runner) report(result *StageResult) {
+	if r.Report != nil {
+		r.Report(result)
+	}
+}
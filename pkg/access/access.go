@@ -0,0 +1,163 @@
+// Role-based restrictions on which users may make changes to which environments and with which commands
+// (eg. developers may deploy to dev but only read prod).  Reads are never restricted
+package access
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Wildcard matching every environment or command
+const Wildcard = "*"
+
+var ErrorNoRoles = errors.New("A user must be assigned at least one role")
+
+// Policy assigns users roles permitting changes to environments
+type Policy struct {
+	// Roles keyed by name (eg. {"developer": {"environments": ["dev", "test-*"]}})
+	Roles map[string]*Role `json:"roles"`
+	// Roles of each user (eg. {"alice": ["developer"], "bob": ["sre"]})
+	Users map[string][]string `json:"users,omitempty"`
+	// Roles of users who aren't listed.  Default: none so they may only read
+	DefaultRoles []string `json:"defaultRoles,omitempty"`
+}
+
+// Role permits changes to Environments with Commands
+type Role struct {
+	// Environments the role may change (eg. dev or test-*).  * permits every environment
+	Environments []string `json:"environments"`
+	// Commands the role may make changes with (eg. "app scale" or "deploy *").  A command permits its
+	// subcommands.  Default: every command
+	Commands []string `json:"commands,omitempty"`
+}
+
+// DeniedError is returned for changes a user isn't permitted to make
+type DeniedError struct {
+	User        string
+	Environment string
+	Command     string
+	Roles       []string
+}
+
+func (e *DeniedError) Error() string {
+	roles := "no roles"
+	if len(e.Roles) > 0 {
+		roles = "roles " + strings.Join(e.Roles, ", ")
+	}
+	return fmt.Sprintf("User '%s' (%s) may not make changes to environment '%s' with '%s'", e.User, roles, e.Environment, e.Command)
+}
+
+// Load reads the policy file {filename}
+func Load(filename string) (*Policy, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	p := &Policy{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("%s is not a valid access policy: %s", filename, err.Error())
+	}
+	return p, p.Validate()
+}
+
+// Validate returns an error if a user is assigned a role which doesn't exist
+func (p *Policy) Validate() error {
+	for name, r := range p.Roles {
+		if r == nil || len(r.Environments) == 0 {
+			return fmt.Errorf("role '%s' must list the environments it may change", name)
+		}
+		for _, pattern := range append(append([]string{}, r.Environments...), r.Commands...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("role '%s': '%s' is not a valid pattern", name, pattern)
+			}
+		}
+	}
+	users := []string{}
+	for u := range p.Users {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	for _, u := range users {
+		if len(p.Users[u]) == 0 {
+			return fmt.Errorf("user '%s': %s", u, ErrorNoRoles.Error())
+		}
+		if err := p.checkRoles(p.Users[u]); err != nil {
+			return fmt.Errorf("user '%s': %s", u, err.Error())
+		}
+	}
+	if err := p.checkRoles(p.DefaultRoles); err != nil {
+		return fmt.Errorf("defaultRoles: %s", err.Error())
+	}
+	return nil
+}
+
+func (p *Policy) checkRoles(roles []string) error {
+	for _, name := range roles {
+		if _, ok := p.Roles[name]; !ok {
+			return fmt.Errorf("role '%s' does not exist", name)
+		}
+	}
+	return nil
+}
+
+// RolesOf returns the roles of {user}
+func (p *Policy) RolesOf(user string) []string {
+	if roles, ok := p.Users[user]; ok {
+		return roles
+	}
+	return p.DefaultRoles
+}
+
+// Check returns a *DeniedError unless a role of {user} permits changes to environment {env} with {command}
+// (the command's path without the program name, eg. app scale).  A nil policy permits everything
+func (p *Policy) Check(user, env, command string) error {
+	if p == nil {
+		return nil
+	}
+	roles := p.RolesOf(user)
+	for _, name := range roles {
+		if r := p.Roles[name]; r != nil && r.permits(env, command) {
+			return nil
+		}
+	}
+	return &DeniedError{User: user, Environment: env, Command: command, Roles: roles}
+}
+
+func (r *Role) permits(env, command string) bool {
+	if !matchAny(r.Environments, env, false) {
+		return false
+	}
+	return len(r.Commands) == 0 || matchAny(r.Commands, command, true)
+}
+
+// Returns true if {value} matches any of the {patterns}.  With {prefix} a pattern also matches the words
+// following it (eg. app matches app scale)
+func matchAny(patterns []string, value string, prefix bool) bool {
+	for _, pattern := range patterns {
+		if pattern == Wildcard {
+			return true
+		}
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+		if prefix && strings.HasPrefix(value, pattern+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentUser returns the name of the user running depcon
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
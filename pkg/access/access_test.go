@@ -0,0 +1,64 @@
+package access
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testPolicy() *Policy {
+	return &Policy{
+		Roles: map[string]*Role{
+			"developer": {Environments: []string{"dev", "test-*"}},
+			"operator":  {Environments: []string{Wildcard}, Commands: []string{"app scale", "app restart"}},
+			"sre":       {Environments: []string{Wildcard}},
+		},
+		Users:        map[string][]string{"alice": {"developer"}, "bob": {"developer", "operator"}, "carol": {"sre"}},
+		DefaultRoles: []string{"developer"},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	p := testPolicy()
+	assert.Nil(t, p.Check("alice", "dev", "deploy create"))
+	assert.Nil(t, p.Check("alice", "test-eu", "app create"))
+	assert.Nil(t, p.Check("bob", "prod", "app scale"))
+	assert.Nil(t, p.Check("carol", "prod", "deploy create"))
+	// unlisted users have the default roles
+	assert.Nil(t, p.Check("dave", "dev", "app destroy"))
+
+	err := p.Check("alice", "prod", "app scale")
+	assert.IsType(t, &DeniedError{}, err)
+	assert.Equal(t, "User 'alice' (roles developer) may not make changes to environment 'prod' with 'app scale'", err.Error())
+	assert.NotNil(t, p.Check("bob", "prod", "app destroy"))
+
+	var none *Policy
+	assert.Nil(t, none.Check("alice", "prod", "app destroy"))
+}
+
+func TestCommandPatterns(t *testing.T) {
+	r := &Role{Environments: []string{"prod"}, Commands: []string{"app", "deploy *"}}
+	assert.True(t, r.permits("prod", "app scale"))
+	assert.True(t, r.permits("prod", "deploy create"))
+	assert.False(t, r.permits("prod", "group create"))
+	assert.False(t, r.permits("dev", "app scale"))
+}
+
+func TestLoadValidates(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "access")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "access.json")
+
+	ioutil.WriteFile(filename, []byte(`{"roles": {"developer": {"environments": ["dev"]}}, "users": {"alice": ["developer"]}}`), 0644)
+	p, err := Load(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"developer"}, p.RolesOf("alice"))
+	assert.Empty(t, p.RolesOf("bob"))
+
+	ioutil.WriteFile(filename, []byte(`{"roles": {"developer": {"environments": ["dev"]}}, "users": {"alice": ["admin"]}}`), 0644)
+	_, err = Load(filename)
+	assert.EqualError(t, err, "user 'alice': role 'admin' does not exist")
+}
@@ -0,0 +1,128 @@
+// Append-only log of the changes depcon makes to clusters
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/mask"
+)
+
+const (
+	// file within the depcon config directory the log is written to
+	DefaultFilename = "audit.log"
+	ResultSuccess   = "success"
+	ResultFailed    = "failed"
+	// results of a deployment deferred to a planned time which is yet to happen
+	ResultScheduled = "scheduled"
+	ResultCancelled = "cancelled"
+)
+
+// Entry is a change made (or attempted) by depcon
+type Entry struct {
+	Time        time.Time `json:"time"`
+	User        string    `json:"user"`
+	Environment string    `json:"environment,omitempty"`
+	// What was done (eg. sync, create, update)
+	Action string `json:"action"`
+	// What it was done to (eg. an application id)
+	Target  string `json:"target,omitempty"`
+	Result  string `json:"result"`
+	Message string `json:"message,omitempty"`
+	// Free form context of the change (eg. the git revision which was synced)
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// Log writes entries as JSON lines to a file
+type Log struct {
+	filename string
+	mu       sync.Mutex
+}
+
+func New(filename string) *Log {
+	return &Log{filename: filename}
+}
+
+func (l *Log) Filename() string {
+	return l.filename
+}
+
+// Record appends {e} to the log.  The time and user are filled in when unset.  Secrets within the message and
+// details are masked
+func (l *Log) Record(e *Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.User == "" {
+		e.User = currentUser()
+	}
+	b, err := json.Marshal(redact(e))
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.filename), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// Returns a copy of {e} with the secrets within the message and details masked
+func redact(e *Entry) *Entry {
+	c := *e
+	c.Message = mask.Redact(e.Message)
+	if e.Details != nil {
+		c.Details = make(map[string]string, len(e.Details))
+		for k, v := range e.Details {
+			if mask.IsSecretName(k) {
+				v = mask.Placeholder
+			}
+			c.Details[k] = mask.Redact(v)
+		}
+	}
+	return &c
+}
+
+// Entries returns the entries of the log oldest first.  A log which doesn't exist has no entries
+func (l *Log) Entries() ([]*Entry, error) {
+	entries := []*Entry{}
+	f, err := os.Open(l.filename)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		e := &Entry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecord(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "audit")
+	defer os.RemoveAll(dir)
+
+	l := New(filepath.Join(dir, "logs", DefaultFilename))
+	entries, err := l.Entries()
+	assert.Nil(t, err)
+	assert.Empty(t, entries)
+
+	assert.Nil(t, l.Record(&Entry{Action: "sync", Target: "/web", Result: ResultSuccess, Details: map[string]string{"revision": "abc"}}))
+	assert.Nil(t, l.Record(&Entry{User: "ci", Action: "sync", Result: ResultFailed}))
+
+	entries, err = l.Entries()
+	assert.Nil(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "/web", entries[0].Target)
+	assert.Equal(t, "abc", entries[0].Details["revision"])
+	assert.False(t, entries[0].Time.IsZero())
+	assert.NotEmpty(t, entries[0].User)
+	assert.Equal(t, "ci", entries[1].User)
+
+	info, _ := os.Stat(l.Filename())
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestRecordMasksSecrets(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "audit")
+	defer os.RemoveAll(dir)
+
+	l := New(filepath.Join(dir, DefaultFilename))
+	details := map[string]string{"api_token": "abc123", "params": "DB_PASSWORD=hunter22 TAG=1.2"}
+	assert.Nil(t, l.Record(&Entry{Action: "create", Result: ResultSuccess, Details: details}))
+
+	entries, _ := l.Entries()
+	assert.Equal(t, mask.Placeholder, entries[0].Details["api_token"])
+	assert.Equal(t, "DB_PASSWORD=[REDACTED] TAG=1.2", entries[0].Details["params"])
+	// the caller's entry is left as is
+	assert.Equal(t, "abc123", details["api_token"])
+}
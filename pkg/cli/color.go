@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Color modes
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// Roles which are colored by a Theme
+const (
+	RoleHeader    = "header"
+	RoleHealthy   = "healthy"
+	RoleUnhealthy = "unhealthy"
+	RoleWarning   = "warning"
+	RoleDeploying = "deploying"
+)
+
+// ThemeDefault is the theme used unless the config specifies another
+const ThemeDefault = "default"
+
+var (
+	ColorModes = []string{ColorAuto, ColorAlways, ColorNever}
+	Roles      = []string{RoleHeader, RoleHealthy, RoleUnhealthy, RoleWarning, RoleDeploying}
+
+	// Themes are the built-in themes by name
+	Themes = map[string]Theme{
+		ThemeDefault: {RoleHeader: "1", RoleHealthy: "32", RoleUnhealthy: "31", RoleWarning: "33", RoleDeploying: "36"},
+		"bright":     {RoleHeader: "1;4", RoleHealthy: "1;92", RoleUnhealthy: "1;91", RoleWarning: "1;93", RoleDeploying: "1;96"},
+		"mono":       {RoleHeader: "1", RoleUnhealthy: "1;7", RoleWarning: "4"},
+	}
+
+	colorEnabled = false
+	theme        = Themes[ThemeDefault]
+)
+
+// Theme maps a role to the ANSI SGR parameters (eg. "1;32" for bold green) used to render it.  Roles
+// without parameters are not colored
+type Theme map[string]string
+
+// With returns a copy of the theme with the role parameters in {overrides} applied
+func (t Theme) With(overrides map[string]string) Theme {
+	c := Theme{}
+	for role, code := range t {
+		c[role] = code
+	}
+	for role, code := range overrides {
+		c[role] = code
+	}
+	return c
+}
+
+// LookupTheme returns the built-in theme {name}, an empty name is the default theme
+func LookupTheme(name string) (Theme, error) {
+	if name == "" {
+		name = ThemeDefault
+	}
+	if t, ok := Themes[name]; ok {
+		return t, nil
+	}
+	return nil, fmt.Errorf("Unknown theme '%s' - must be one of %s", name, strings.Join(ThemeNames(), ", "))
+}
+
+// ThemeNames returns the names of the built-in themes in sorted order
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsValidColorMode returns true if {mode} is one of ColorModes
+func IsValidColorMode(mode string) bool {
+	for _, m := range ColorModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole returns true if {role} is one of Roles
+func IsValidRole(role string) bool {
+	return roleIndex(role) >= 0
+}
+
+// IsValidSGR returns true if {code} is a list of ANSI SGR parameters separated by ';' (eg. 1;32)
+func IsValidSGR(code string) bool {
+	if code == "" {
+		return true
+	}
+	for _, p := range strings.Split(code, ";") {
+		if p == "" || strings.Trim(p, "0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ShouldColor resolves the color {mode} for output written to {f}.  Auto colors terminals unless the
+// NO_COLOR environment variable is set or TERM is dumb
+func ShouldColor(mode string, f *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	// the null device is also a character device
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(fi, null)
+}
+
+// EnableColor toggles colored template output
+func EnableColor(enabled bool) {
+	colorEnabled = enabled
+}
+
+// ColorEnabled returns true if template output is colored
+func ColorEnabled() bool {
+	return colorEnabled
+}
+
+// SetTheme sets the theme used to color template output
+func SetTheme(t Theme) {
+	theme = t
+}
+
+// Colors rendered through a tab writer are written as markers (private use runes) which are replaced
+// with the escape sequences by colorWriter once the columns have been aligned.  The end marker is
+// followed by two spaces standing in for the width of both markers so alignment is kept
+const (
+	markerStart rune = '\uE000'
+	markerEnd   rune = '\uE0FF'
+	resetSGR         = "\x1b[0m"
+)
+
+// Colorize renders {text} in the theme's color for {role} when color is enabled.  The result must be
+// written through a writer from NewTabWriter
+func Colorize(role, text string) string {
+	if !colorEnabled || text == "" || theme[role] == "" {
+		return text
+	}
+	idx := roleIndex(role)
+	if idx < 0 {
+		return text
+	}
+	return string(markerStart+rune(idx)) + text + string(markerEnd)
+}
+
+// Paint renders {text} in the theme's color for {role} with escape sequences when color is enabled.  Unlike
+// Colorize the result is written as is (eg. to a full-screen view) rather than through a tab writer
+func Paint(role, text string) string {
+	if !colorEnabled || text == "" || theme[role] == "" {
+		return text
+	}
+	return "\x1b[" + theme[role] + "m" + text + resetSGR
+}
+
+// Status colors {status} by its meaning (eg. OK, healthy, FAILED, warning, deploying)
+func Status(status string) string {
+	return Colorize(StatusRole(status), status)
+}
+
+// StatusRole returns the role used to color {status}, or an empty role if the status isn't known
+func StatusRole(status string) string {
+	s := strings.ToLower(status)
+	switch {
+	case hasAnyPrefix(s, "ok", "healthy", "running", "task_running", "success", "pass"):
+		return RoleHealthy
+	case hasAnyPrefix(s, "fail", "error", "unhealthy", "task_failed", "task_lost", "task_killed", "task_error", "down", "lost", "timeout"):
+		return RoleUnhealthy
+	case hasAnyPrefix(s, "warn", "overdue", "delayed", "waiting", "suspended"):
+		return RoleWarning
+	case hasAnyPrefix(s, "deploy", "staging", "staged", "task_staging", "task_starting", "starting", "scaling", "pending", "restarting"):
+		return RoleDeploying
+	}
+	return ""
+}
+
+func hasAnyPrefix(s string, prefixes ...string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func roleIndex(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// Replaces color markers with escape sequences
+type colorWriter struct {
+	w io.Writer
+	// an incomplete rune held back until the next write
+	pending []byte
+}
+
+func (c *colorWriter) Write(p []byte) (int, error) {
+	buf := append(c.pending, p...)
+	n := len(buf)
+	for i := n - 1; i >= 0 && i >= n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if !utf8.FullRune(buf[i:]) {
+				n = i
+			}
+			break
+		}
+	}
+	c.pending = append([]byte(nil), buf[n:]...)
+	if _, err := c.w.Write(renderColors(buf[:n])); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func renderColors(p []byte) []byte {
+	// every marker is encoded with the lead byte 0xEE
+	if bytes.IndexByte(p, 0xEE) < 0 {
+		return p
+	}
+	var out bytes.Buffer
+	for len(p) > 0 {
+		r, size := utf8.DecodeRune(p)
+		switch {
+		case r == markerEnd:
+			out.WriteString(resetSGR + "  ")
+		case r >= markerStart && r < markerStart+rune(len(Roles)):
+			out.WriteString("\x1b[" + theme[Roles[r-markerStart]] + "m")
+		default:
+			out.Write(p[:size])
+		}
+		p = p[size:]
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const colorTemplate = `{{ "ID" | header }}	{{ "STATUS" | header }}	{{ "HOST" | header }}
+{{ range . }}{{ .ID }}	{{ .State | status }}	{{ .Host }}
+{{end}}`
+
+var sgr = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func renderColumns(t *testing.T, color bool) string {
+	defer EnableColor(false)
+	EnableColor(color)
+
+	data := []*queryTask{
+		{ID: "web.1", Host: "agent-1", State: "TASK_RUNNING"},
+		{ID: "web.20", Host: "agent-2", State: "TASK_FAILED"},
+		{ID: "web.300", Host: "agent-3", State: "UNKNOWN"},
+	}
+	var buf bytes.Buffer
+	assert.Nil(t, FormatData{Template: colorTemplate, Data: data}.ToColumns(&buf))
+	return buf.String()
+}
+
+// Returns {s} as displayed by a terminal: escape sequences removed, tabs expanded and trailing
+// spaces trimmed
+func displayed(s string) string {
+	lines := strings.Split(sgr.ReplaceAllString(s, ""), "\n")
+	for i, line := range lines {
+		var b strings.Builder
+		for _, r := range line {
+			if r == '\t' {
+				b.WriteString(strings.Repeat(" ", 8-b.Len()%8))
+			} else {
+				b.WriteRune(r)
+			}
+		}
+		lines[i] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestColorDisabled(t *testing.T) {
+	out := renderColumns(t, false)
+	assert.NotContains(t, out, "\x1b[")
+	assert.Contains(t, out, "web.20\t\tTASK_FAILED\tagent-2")
+}
+
+func TestColorKeepsAlignment(t *testing.T) {
+	plain := renderColumns(t, false)
+	colored := renderColumns(t, true)
+
+	assert.Contains(t, colored, "\x1b[1mID\x1b[0m")
+	assert.Contains(t, colored, "\x1b[32mTASK_RUNNING\x1b[0m")
+	assert.Contains(t, colored, "\x1b[31mTASK_FAILED\x1b[0m")
+	assert.NotContains(t, colored, "\x1b[0mUNKNOWN")
+	assert.Equal(t, displayed(plain), displayed(colored))
+}
+
+func TestColorTheme(t *testing.T) {
+	defer SetTheme(Themes[ThemeDefault])
+	theme, err := LookupTheme("mono")
+	assert.Nil(t, err)
+	SetTheme(theme.With(map[string]string{RoleHealthy: "4"}))
+
+	colored := renderColumns(t, true)
+	assert.Contains(t, colored, "\x1b[4mTASK_RUNNING\x1b[0m")
+	assert.Contains(t, colored, "\x1b[1;7mTASK_FAILED\x1b[0m")
+
+	_, err = LookupTheme("neon")
+	assert.NotNil(t, err)
+}
+
+func TestStatusRole(t *testing.T) {
+	tests := map[string]string{
+		"OK":             RoleHealthy,
+		"TASK_RUNNING":   RoleHealthy,
+		"FAILED: 401":    RoleUnhealthy,
+		"error":          RoleUnhealthy,
+		"warning":        RoleWarning,
+		"TASK_STAGING":   RoleDeploying,
+		"something else": "",
+	}
+	for status, role := range tests {
+		assert.Equal(t, role, StatusRole(status), status)
+	}
+}
+
+func TestColorWriterSplitRune(t *testing.T) {
+	defer EnableColor(false)
+	EnableColor(true)
+
+	var buf bytes.Buffer
+	w := &colorWriter{w: &buf}
+	b := []byte(Colorize(RoleHealthy, "ok"))
+	// split within the 3 byte start marker
+	w.Write(b[:1])
+	w.Write(b[1:])
+	assert.Equal(t, "\x1b[32mok\x1b[0m  ", buf.String())
+}
+
+func TestIsValidSGR(t *testing.T) {
+	assert.True(t, IsValidSGR("1;32"))
+	assert.True(t, IsValidSGR(""))
+	assert.False(t, IsValidSGR("green"))
+	assert.False(t, IsValidSGR("1;"))
+}
+
+func TestPaint(t *testing.T) {
+	assert.Equal(t, "healthy", Paint(RoleHealthy, "healthy"), "color disabled")
+
+	defer EnableColor(false)
+	EnableColor(true)
+	assert.Equal(t, "\x1b[32mhealthy\x1b[0m", Paint(RoleHealthy, "healthy"))
+	assert.Equal(t, "plain", Paint("", "plain"))
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	// ErrConfirmationRequired is returned when a confirmation is needed but stdin isn't a terminal
+	ErrConfirmationRequired = errors.New("Confirmation required, use --yes to proceed when not running interactively")
+	// ErrAborted is returned when the user declines a confirmation
+	ErrAborted = errors.New("Aborted")
+)
+
+var assumeYes = false
+
+// AssumeYes toggles answering yes to all confirmations (--yes)
+func AssumeYes(yes bool) {
+	assumeYes = yes
+}
+
+// ConfirmationsEnabled returns false when --yes was specified and confirmations are skipped
+func ConfirmationsEnabled() bool {
+	return !assumeYes
+}
+
+// Confirm asks the user to confirm {question} on the terminal.  Returns nil if confirmed or --yes was
+// specified, ErrAborted if declined and ErrConfirmationRequired when stdin isn't a terminal
+func Confirm(question string) error {
+	if assumeYes {
+		return nil
+	}
+	if !isTerminal(os.Stdin) {
+		return ErrConfirmationRequired
+	}
+	return confirm(os.Stdin, os.Stderr, question)
+}
+
+// ConfirmOrExit asks the user to confirm {action} within the {scope} named {name} (eg. environment 'prod')
+// exiting with the error when declined.  {action} is asked alone when {scope} is empty
+func ConfirmOrExit(action, scope, name string) {
+	if err := Confirm(scopedQuestion(action, scope, name)); err != nil {
+		Output(nil, err)
+		Exit(err)
+	}
+}
+
+func scopedQuestion(action, scope, name string) string {
+	if scope == "" {
+		return action
+	}
+	return fmt.Sprintf("%s in %s '%s'", action, scope, name)
+}
+
+// Prompts {question} on {out} reading the answer from {in}.  Anything other than y or yes declines
+func confirm(in io.Reader, out io.Writer, question string) error {
+	fmt.Fprintf(out, "%s [y/N]? ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return ErrAborted
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirm(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, confirm(strings.NewReader("y\n"), out, "Destroy application '/web'"))
+	assert.Equal(t, "Destroy application '/web' [y/N]? ", out.String())
+
+	assert.NoError(t, confirm(strings.NewReader(" YES \n"), out, "Destroy"))
+	assert.Equal(t, ErrAborted, confirm(strings.NewReader("n\n"), out, "Destroy"))
+	assert.Equal(t, ErrAborted, confirm(strings.NewReader("\n"), out, "Destroy"))
+	assert.Equal(t, ErrAborted, confirm(strings.NewReader(""), out, "Destroy"))
+}
+
+func TestConfirmAssumeYes(t *testing.T) {
+	defer AssumeYes(false)
+	AssumeYes(true)
+	assert.NoError(t, Confirm("Destroy"))
+}
+
+func TestConfirmNotTerminal(t *testing.T) {
+	null, err := os.Open(os.DevNull)
+	assert.NoError(t, err)
+	defer null.Close()
+	defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+	os.Stdin = null
+
+	assert.Equal(t, ErrConfirmationRequired, Confirm("Destroy"))
+}
+
+func TestScopedQuestion(t *testing.T) {
+	assert.Equal(t, "Destroy '/web' in environment 'prod'", scopedQuestion("Destroy '/web'", "environment", "prod"))
+	assert.Equal(t, "Destroy stack 'shop'", scopedQuestion("Destroy stack 'shop'", "", ""))
+}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+// ErrorReport is the machine readable form of an error written in place of the free text error when
+// the output format is JSON
+type ErrorReport struct {
+	// Exit code depcon terminates with
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	// HTTP status and offending fields when the remote rejected the request
+	Status  int                     `json:"status,omitempty"`
+	Details []httpclient.FieldError `json:"details,omitempty"`
+}
+
+// NewErrorReport returns the report describing {err}
+func NewErrorReport(err error) *ErrorReport {
+	r := &ErrorReport{Code: ExitCode(err), Message: err.Error()}
+	var e *httpclient.APIError
+	if errors.As(err, &e) {
+		if e.Message != "" {
+			r.Message = e.Message
+		}
+		r.Status = e.Status
+		r.Details = e.Details
+	}
+	return r
+}
+
+// WriteErrorReport writes the report describing {err} to {w} as JSON
+func WriteErrorReport(w io.Writer, err error) error {
+	b, e := json.MarshalIndent(NewErrorReport(err), "", "  ")
+	if e != nil {
+		return e
+	}
+	_, e = w.Write(append(b, '\n'))
+	return e
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewErrorReport(t *testing.T) {
+	r := NewErrorReport(WithExitCode(ExitNotFound, errors.New("App '/web' does not exist")))
+	assert.Equal(t, &ErrorReport{Code: ExitNotFound, Message: "App '/web' does not exist"}, r)
+
+	apiErr := httpclient.NewAPIError(422, `{"message":"Object is not valid","details":[{"path":"/cpus","errors":["error.min"]}]}`)
+	r = NewErrorReport(apiErr)
+	assert.Equal(t, ExitError, r.Code)
+	assert.Equal(t, "Object is not valid", r.Message)
+	assert.Equal(t, 422, r.Status)
+	assert.Equal(t, []httpclient.FieldError{{Path: "/cpus", Errors: []string{"error.min"}}}, r.Details)
+}
+
+func TestWriteErrorReport(t *testing.T) {
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteErrorReport(out, httpclient.NewAPIError(409, "")))
+	assert.JSONEq(t, `{"code": 1, "message": "Unknown error message was captured (Status 409)", "status": 409}`, out.String())
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+)
+
+// Exit codes returned by depcon.  These are a stable contract allowing scripts and CI pipelines to
+// branch on the type of failure
+const (
+	ExitSuccess       = 0
+	ExitError         = 1   // a failure not covered by one of the codes below
+	ExitUsage         = 2   // invalid arguments or flags
+	ExitNotFound      = 3   // the application, group, deployment or environment does not exist
+	ExitDeployTimeout = 4   // the deployment did not complete within the wait timeout
+	ExitDeployFailed  = 5   // the deployment failed (eg. never became healthy) or was rolled back
+	ExitAuth          = 6   // authentication or authorization failed
+	ExitConnection    = 7   // the cluster could not be reached
+	ExitCancelled     = 130 // interrupted (Ctrl-C) before completing
+)
+
+type registeredExitCode struct {
+	err  error
+	code int
+}
+
+var exitCodes []registeredExitCode
+
+// ExitCodeError associates an exit code with an error
+type ExitCodeError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitCodeError) Error() string {
+	return e.Err.Error()
+}
+
+// WithExitCode returns {err} associated with the exit {code}
+func WithExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitCodeError{Code: code, Err: err}
+}
+
+// RegisterExitCode associates the sentinel errors {errs} with the exit {code}
+func RegisterExitCode(code int, errs ...error) {
+	for _, err := range errs {
+		exitCodes = append(exitCodes, registeredExitCode{err, code})
+	}
+}
+
+// ExitCode returns the exit code for {err}.  Errors which have not been associated with a code
+// result in ExitError unless they're a failure connecting to the remote
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+	if e, ok := err.(*ExitCodeError); ok {
+		return e.Code
+	}
+	if errors.Is(err, context.Canceled) {
+		return ExitCancelled
+	}
+	for _, r := range exitCodes {
+		if errors.Is(err, r.err) {
+			return r.code
+		}
+	}
+	if isConnectionError(err) {
+		return ExitConnection
+	}
+	return ExitError
+}
+
+// exits the process unless replaced (eg. by an interactive shell ending only the current command)
+var exitHandler = os.Exit
+
+// SetExitHandler replaces how depcon exits with {fn}.  nil restores os.Exit
+func SetExitHandler(fn func(code int)) {
+	if fn == nil {
+		fn = os.Exit
+	}
+	exitHandler = fn
+}
+
+// Exit terminates depcon with the exit code for {err}
+func Exit(err error) {
+	ExitWithCode(ExitCode(err))
+}
+
+// ExitWithCode terminates depcon with {code}
+func ExitWithCode(code int) {
+	exitHandler(code)
+}
+
+func isConnectionError(err error) bool {
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	switch err.(type) {
+	case *net.OpError, *net.DNSError:
+		return true
+	}
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
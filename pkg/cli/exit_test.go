@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	errMissing := errors.New("missing")
+	defer func(codes []registeredExitCode) { exitCodes = codes }(exitCodes)
+	RegisterExitCode(ExitNotFound, errMissing)
+
+	refused := &url.Error{Op: "Get", URL: "http://localhost:1", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+
+	assert.Equal(t, ExitSuccess, ExitCode(nil))
+	assert.Equal(t, ExitError, ExitCode(errors.New("other")))
+	assert.Equal(t, ExitNotFound, ExitCode(errMissing))
+	assert.Equal(t, ExitNotFound, ExitCode(fmt.Errorf("app: %w", errMissing)))
+	assert.Equal(t, ExitDeployFailed, ExitCode(WithExitCode(ExitDeployFailed, errMissing)))
+	assert.Equal(t, ExitConnection, ExitCode(refused))
+	assert.Equal(t, ExitConnection, ExitCode(&net.DNSError{Err: "no such host", Name: "marathon"}))
+	assert.Nil(t, WithExitCode(ExitUsage, nil))
+	assert.Equal(t, "missing", WithExitCode(ExitNotFound, errMissing).Error())
+}
+
+func TestExitHandler(t *testing.T) {
+	defer SetExitHandler(nil)
+	codes := []int{}
+	SetExitHandler(func(code int) { codes = append(codes, code) })
+
+	Exit(WithExitCode(ExitNotFound, errors.New("missing")))
+	ExitWithCode(ExitUsage)
+	assert.Equal(t, []int{ExitNotFound, ExitUsage}, codes)
+}
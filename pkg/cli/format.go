@@ -0,0 +1,220 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+)
+
+// Output formats supported by every command
+const (
+	FormatColumn = "column"
+	// alias of column
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatCSV   = "csv"
+)
+
+var ValidFormats = []string{FormatColumn, FormatTable, FormatJSON, FormatYAML, FormatCSV}
+
+// IsValidFormat returns true if {format} is one of the ValidFormats
+func IsValidFormat(format string) bool {
+	for _, f := range ValidFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Write renders {f} to {w} in the output {format}.  JSON, YAML and CSV use the field names of the
+// underlying API types (json tags) so they remain stable for scripting
+func Write(w io.Writer, f Formatter, format string) error {
+	switch format {
+	case FormatJSON:
+		return encoding.NewStreamEncoder(encoding.JSON, w).Encode(f.Data().Data)
+	case FormatYAML:
+		return encoding.NewStreamEncoder(encoding.YAML, w).Encode(f.Data().Data)
+	case FormatCSV:
+		return WriteCSV(w, f.Data().Data)
+	case FormatColumn, FormatTable, "":
+		return f.ToColumns(w)
+	}
+	return fmt.Errorf("Invalid output '%s' - must be one of %s", format, strings.Join(ValidFormats, ", "))
+}
+
+// WriteCSV writes {data} as CSV with a header row.  Each element is a row when {data} is a slice or has a
+// single slice field (eg. Applications.Apps), otherwise {data} is written as a single row.  Columns are the
+// json field names and nested values are written as JSON
+func WriteCSV(w io.Writer, data interface{}) error {
+	enc := NewCSVEncoder(w)
+	for _, row := range csvRows(reflect.ValueOf(data)) {
+		if err := enc.Encode(row.Interface()); err != nil {
+			return err
+		}
+	}
+	enc.Flush()
+	return enc.Error()
+}
+
+// CSVEncoder writes values as CSV rows.  The header is written using the type of the first value
+type CSVEncoder struct {
+	w       *csv.Writer
+	columns []csvColumn
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+func NewCSVEncoder(w io.Writer) *CSVEncoder {
+	return &CSVEncoder{w: csv.NewWriter(w)}
+}
+
+// Encode writes {v} as a row writing the header first if this is the first row
+func (e *CSVEncoder) Encode(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if e.columns == nil {
+		e.columns = csvColumns(rv.Type())
+		if err := e.w.Write(columnNames(e.columns)); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		if c.index == nil {
+			record[i] = csvValue(rv)
+		} else if f, ok := fieldByIndex(rv, c.index); ok {
+			record[i] = csvValue(f)
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *CSVEncoder) Flush() {
+	e.w.Flush()
+}
+
+func (e *CSVEncoder) Error() error {
+	return e.w.Error()
+}
+
+func csvRows(v reflect.Value) []reflect.Value {
+	v = reflect.Indirect(v)
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Struct {
+		var slice reflect.Value
+		count := 0
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath == "" && v.Field(i).Kind() == reflect.Slice {
+				slice = v.Field(i)
+				count++
+			}
+		}
+		if count == 1 {
+			v = slice
+		}
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []reflect.Value{v}
+	}
+	rows := make([]reflect.Value, v.Len())
+	for i := range rows {
+		rows[i] = v.Index(i)
+	}
+	return rows
+}
+
+// Returns the columns for rows of type {t}.  Scalars have a single "value" column
+func csvColumns(t reflect.Type) []csvColumn {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == reflect.TypeOf(time.Time{}) {
+		return []csvColumn{{name: "value"}}
+	}
+
+	columns := []csvColumn{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct {
+			for _, c := range csvColumns(ft) {
+				columns = append(columns, csvColumn{name: c.name, index: append([]int{i}, c.index...)})
+			}
+			continue
+		}
+		columns = append(columns, csvColumn{name: name, index: []int{i}})
+	}
+	return columns
+}
+
+func columnNames(columns []csvColumn) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Returns the field at {index} following pointers.  False is returned if a nil pointer is encountered
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		v = reflect.Indirect(v)
+		if !v.IsValid() {
+			return v, false
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.Len() == 0 {
+			return ""
+		}
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
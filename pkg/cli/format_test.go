@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type csvTask struct {
+	ID    string            `json:"id"`
+	Host  string            `json:"host"`
+	Ports []int             `json:"ports,omitempty"`
+	Meta  *csvMeta          `json:"meta,omitempty"`
+	Tags  map[string]string `json:"-"`
+}
+
+type csvMeta struct {
+	Version string `json:"version"`
+}
+
+type csvTasks struct {
+	Tasks []*csvTask `json:"tasks"`
+}
+
+func TestWriteCSV(t *testing.T) {
+	data := &csvTasks{Tasks: []*csvTask{
+		{ID: "app.1", Host: "agent-1", Ports: []int{31000, 31001}, Meta: &csvMeta{Version: "v1"}},
+		{ID: "app.2", Host: "agent,2"},
+	}}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, WriteCSV(buf, data))
+	assert.Equal(t, "id,host,ports,meta\n"+
+		"app.1,agent-1,\"[31000,31001]\",\"{\"\"version\"\":\"\"v1\"\"}\"\n"+
+		"app.2,\"agent,2\",,\n", buf.String())
+
+	buf.Reset()
+	assert.Nil(t, WriteCSV(buf, []string{"a", "b"}))
+	assert.Equal(t, "value\na\nb\n", buf.String())
+}
+
+type testFormatter struct {
+	FormatData
+}
+
+func (f testFormatter) Data() FormatData {
+	return f.FormatData
+}
+
+func TestWriteFormats(t *testing.T) {
+	f := testFormatter{FormatData{Data: map[string]string{"id": "/app"}, Template: "{{ .id }}"}}
+
+	buf := &bytes.Buffer{}
+	assert.Nil(t, Write(buf, f, FormatJSON))
+	assert.Contains(t, buf.String(), `"id": "/app"`)
+
+	buf.Reset()
+	assert.Nil(t, Write(buf, f, FormatTable))
+	assert.Contains(t, buf.String(), "/app")
+
+	assert.NotNil(t, Write(buf, f, "xml"))
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// identifierKeys are the fields, in order of preference, holding a result's primary identifier
+var identifierKeys = []string{"id", "deploymentid", "taskid", "name"}
+
+// Identifiers returns the primary identifiers (app, deployment, task IDs etc) within {data}.  Lists
+// yield the identifier of each element and a result wrapping a single list (eg. {"apps": [...]})
+// yields the identifiers of that list
+func Identifiers(data interface{}) ([]string, error) {
+	v, err := normalize(data)
+	if err != nil {
+		return nil, err
+	}
+	return identifiers(v), nil
+}
+
+// WriteIdentifiers writes the identifiers within {data} to {w} one per line
+func WriteIdentifiers(w io.Writer, data interface{}) error {
+	ids, err := Identifiers(data)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := fmt.Fprintln(w, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func identifiers(v interface{}) []string {
+	switch t := v.(type) {
+	case []interface{}:
+		ids := []string{}
+		for _, e := range t {
+			ids = append(ids, identifiers(e)...)
+		}
+		return ids
+	case map[string]interface{}:
+		if id, ok := identifierOf(t); ok {
+			return []string{id}
+		}
+		if list, ok := singleList(t); ok {
+			return identifiers(list)
+		}
+		return []string{}
+	case nil:
+		return []string{}
+	default:
+		return []string{queryValueString(t)}
+	}
+}
+
+// Returns the value of the preferred identifier field of {m}, field names are matched ignoring case
+func identifierOf(m map[string]interface{}) (string, bool) {
+	keys := make(map[string]string, len(m))
+	for k := range m {
+		keys[strings.ToLower(k)] = k
+	}
+	for _, name := range identifierKeys {
+		if k, ok := keys[name]; ok {
+			if s, ok := m[k].(string); ok && s != "" {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Returns the only list valued member of {m}
+func singleList(m map[string]interface{}) ([]interface{}, bool) {
+	var found []interface{}
+	for _, v := range m {
+		if l, ok := v.([]interface{}); ok {
+			if found != nil {
+				return nil, false
+			}
+			found = l
+		}
+	}
+	return found, found != nil
+}
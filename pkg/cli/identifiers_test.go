@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type identifierDeploy struct {
+	DeploymentID string `json:"deploymentId"`
+	Version      string `json:"version"`
+}
+
+type identifierEnv struct {
+	Name    string
+	HostURL string
+}
+
+func TestIdentifiers(t *testing.T) {
+	tests := []struct {
+		data     interface{}
+		expected []string
+	}{
+		{queryData, []string{"web.1", "web.2", "web.3"}},
+		{queryData["tasks"], []string{"web.1", "web.2", "web.3"}},
+		{&queryTask{ID: "web.1", Host: "agent-1"}, []string{"web.1"}},
+		{&identifierDeploy{DeploymentID: "5ed4c0c5", Version: "v1"}, []string{"5ed4c0c5"}},
+		{[]identifierEnv{{"prod", "http://a"}, {"test", "http://b"}}, []string{"prod", "test"}},
+		{[]string{"/app1", "/app2"}, []string{"/app1", "/app2"}},
+		{map[string]interface{}{"a": []int{1}, "b": []int{2}}, []string{}},
+		{nil, []string{}},
+	}
+
+	for _, test := range tests {
+		ids, err := Identifiers(test.data)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, ids)
+	}
+}
+
+func TestWriteIdentifiers(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Nil(t, WriteIdentifiers(&buf, queryData))
+	assert.Equal(t, "web.1\nweb.2\nweb.3\n", buf.String())
+}
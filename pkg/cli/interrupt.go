@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	interruptOnce sync.Once
+	interruptCtx  context.Context = context.Background()
+	interrupts                    = &interruptContext{}
+)
+
+// CancelOnInterrupt makes the context returned by Context cancelled by the first interrupt (Ctrl-C) or
+// SIGTERM so requests are abandoned and waits stop.  A second interrupt exits immediately
+func CancelOnInterrupt() {
+	interruptOnce.Do(func() {
+		interrupts.arm()
+		interruptCtx = interrupts
+
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			for range signals {
+				if !interrupts.cancel() {
+					os.Exit(ExitCancelled)
+				}
+				fmt.Fprintln(os.Stderr, "Cancelling... (interrupt again to exit immediately)")
+			}
+		}()
+	})
+}
+
+// ResetInterrupt makes the context returned by Context usable again after an interrupt so an interactive
+// session can run further commands
+func ResetInterrupt() {
+	interrupts.arm()
+}
+
+// Context returns the context commands make requests with.  It's cancelled on interrupt once
+// CancelOnInterrupt has been called
+func Context() context.Context {
+	return interruptCtx
+}
+
+// interruptContext is a context cancelled by an interrupt which can be re-armed afterwards
+type interruptContext struct {
+	mu   sync.Mutex
+	done chan struct{}
+	err  error
+}
+
+// Replaces the done channel unless the context is armed and hasn't been cancelled
+func (c *interruptContext) arm() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done == nil || c.err != nil {
+		c.done, c.err = make(chan struct{}), nil
+	}
+}
+
+// Cancels the context returning false if it had already been cancelled
+func (c *interruptContext) cancel() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return false
+	}
+	c.err = context.Canceled
+	close(c.done)
+	return true
+}
+
+func (c *interruptContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (c *interruptContext) Done() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.done
+}
+
+func (c *interruptContext) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+func (c *interruptContext) Value(key interface{}) interface{} {
+	return nil
+}
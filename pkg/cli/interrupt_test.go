@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterruptContextRearms(t *testing.T) {
+	c := &interruptContext{}
+	c.arm()
+	derived, cancel := context.WithCancel(c)
+	defer cancel()
+	assert.Nil(t, c.Err())
+
+	assert.True(t, c.cancel())
+	assert.False(t, c.cancel())
+	<-derived.Done()
+	assert.Equal(t, context.Canceled, c.Err())
+
+	c.arm()
+	assert.Nil(t, c.Err())
+	select {
+	case <-c.Done():
+		t.Fatal("a re-armed context isn't done")
+	default:
+	}
+	assert.True(t, c.cancel())
+}
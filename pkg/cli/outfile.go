@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+)
+
+var (
+	outputFile    string
+	outputEncoder encoding.StreamEncoder
+)
+
+// SetOutputFile saves the results written through SaveOutput to {filename} (--out) in the format of its
+// extension.  Files without a .yaml, .yml or .toml extension are written as JSON
+func SetOutputFile(filename string) {
+	outputFile = filename
+	outputEncoder = nil
+}
+
+// SaveOutput writes the result {data} to the output file if one has been set.  The file is replaced by
+// the first result of a command and any further results are added as separate documents
+func SaveOutput(data interface{}) error {
+	if outputFile == "" {
+		return nil
+	}
+	if outputEncoder == nil {
+		f, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		et, _ := encoding.EncoderTypeFromExt(outputFile)
+		outputEncoder = encoding.NewStreamEncoder(et, f)
+	}
+	return outputEncoder.Encode(data)
+}
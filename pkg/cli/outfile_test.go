@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveOutput(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon-out")
+	defer os.RemoveAll(dir)
+	defer SetOutputFile("")
+
+	assert.NoError(t, SaveOutput(map[string]string{"id": "/ignored"}))
+
+	filename := filepath.Join(dir, "result.yaml")
+	SetOutputFile(filename)
+	assert.NoError(t, SaveOutput(map[string]string{"id": "/web"}))
+	assert.NoError(t, SaveOutput(map[string]string{"id": "/worker"}))
+
+	b, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "id: /web\n---\nid: /worker\n", string(b))
+
+	filename = filepath.Join(dir, "result")
+	SetOutputFile(filename)
+	assert.NoError(t, SaveOutput(map[string]string{"id": "/web"}))
+	b, _ = ioutil.ReadFile(filename)
+	assert.JSONEq(t, `{"id": "/web"}`, string(b))
+}
@@ -7,6 +7,8 @@ import (
 	"text/tabwriter"
 	"text/template"
 	"time"
+
+	"github.com/ContainX/depcon/pkg/mask"
 )
 
 // Specializes in formatting a type into Column format
@@ -47,15 +49,22 @@ func FlushWriter(w *tabwriter.Writer) {
 }
 
 func NewTabWriter(output io.Writer) *tabwriter.Writer {
+	if colorEnabled {
+		output = &colorWriter{w: output}
+	}
 	w := new(tabwriter.Writer)
 	w.Init(output, 0, 8, 2, '\t', 0)
 	return w
 }
 
+// Parses the output template {text} making the standard output functions along with {funcs} available
+func NewTemplate(text string, funcs template.FuncMap) (*template.Template, error) {
+	return template.New("output").Funcs(buildFuncMap(funcs)).Parse(text)
+}
+
 func (d FormatData) ToColumns(output io.Writer) error {
 	w := NewTabWriter(output)
-	t := template.New("output").Funcs(buildFuncMap(d.Funcs))
-	t, _ = t.Parse(d.Template)
+	t, _ := NewTemplate(d.Template, d.Funcs)
 	if err := t.Execute(w, d.Data); err != nil {
 		return err
 	}
@@ -72,6 +81,10 @@ func buildFuncMap(userFuncs template.FuncMap) template.FuncMap {
 		"fdate":         FormatDate,
 		"msDur":         durationToMilliseconds,
 		"boolToYesNo":   boolToYesNo,
+		"header":        header,
+		"color":         Colorize,
+		"status":        Status,
+		"mask":          mask.Value,
 	}
 
 	if userFuncs != nil {
@@ -83,6 +96,10 @@ func buildFuncMap(userFuncs template.FuncMap) template.FuncMap {
 	return funcMap
 }
 
+func header(s string) string {
+	return Colorize(RoleHeader, s)
+}
+
 func durationToMilliseconds(t time.Duration) string {
 	return fmt.Sprintf("%d ms", (t.Nanoseconds() / int64(time.Millisecond)))
 }
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// AnnotationPaged marks a command (cobra annotation) whose output is paged when written to a terminal
+	AnnotationPaged = "depcon.paged"
+	// EnvPager is the pager used in place of $PAGER
+	EnvPager     = "DEPCON_PAGER"
+	defaultPager = "less"
+)
+
+var pagerEnabled = false
+
+// EnablePager toggles paging of output written through StartPager
+func EnablePager(enabled bool) {
+	pagerEnabled = enabled
+}
+
+// PagerCommand returns the pager command line from DEPCON_PAGER or PAGER (default: less).  An empty
+// result or cat disables paging
+func PagerCommand() string {
+	for _, env := range []string{EnvPager, "PAGER"} {
+		if v, ok := os.LookupEnv(env); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return defaultPager
+}
+
+// StartPager returns the writer output should be written to along with a func which must be called
+// once the output is complete.  When paging is enabled and {out} is a terminal the writer is the
+// pager's input, otherwise it is {out}.  Like git, less is run with LESS=FRX unless LESS is set so
+// output fitting on one screen is written directly and colors are kept
+func StartPager(out *os.File) (io.Writer, func()) {
+	done := func() {}
+	if !pagerEnabled || !isTerminal(out) {
+		return out, done
+	}
+	args := strings.Fields(PagerCommand())
+	if len(args) == 0 || args[0] == "cat" {
+		return out, done
+	}
+
+	c := exec.Command(args[0], args[1:]...)
+	c.Stdout = out
+	c.Stderr = os.Stderr
+	c.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		c.Env = append(c.Env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		c.Env = append(c.Env, "LV=-c")
+	}
+
+	in, err := c.StdinPipe()
+	if err != nil {
+		return out, done
+	}
+	if err := c.Start(); err != nil {
+		return out, done
+	}
+	return in, func() {
+		in.Close()
+		c.Wait()
+	}
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPagerCommand(t *testing.T) {
+	os.Unsetenv(EnvPager)
+	os.Unsetenv("PAGER")
+	defer os.Unsetenv(EnvPager)
+	defer os.Unsetenv("PAGER")
+
+	assert.Equal(t, "less", PagerCommand())
+
+	os.Setenv("PAGER", "more")
+	assert.Equal(t, "more", PagerCommand())
+
+	os.Setenv(EnvPager, "")
+	assert.Equal(t, "", PagerCommand())
+}
+
+func TestStartPagerNotTerminal(t *testing.T) {
+	defer EnablePager(false)
+	EnablePager(true)
+
+	f, err := ioutil.TempFile("", "pager")
+	assert.Nil(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	out, done := StartPager(f)
+	assert.Equal(t, f, out)
+	done()
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressInterval = 100 * time.Millisecond
+	progressBarWidth = 20
+)
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+var activeProgress *Progress
+
+// Progress draws a spinner, the step of a bulk operation (N/M) and an optional progress bar on a single
+// terminal line which is redrawn as the status changes.  All methods are safe to call on a nil Progress
+// in which case they do nothing and Status/Step return false so callers can log plain lines instead
+type Progress struct {
+	mu      sync.Mutex
+	out     io.Writer
+	frame   int
+	step    string
+	message string
+	bar     string
+	drawn   bool
+	stop    chan struct{}
+}
+
+// NewProgress returns a Progress drawn on {out}
+func NewProgress(out io.Writer) *Progress {
+	return &Progress{out: out}
+}
+
+// EnableProgress enables drawing progress on stderr when both stdout and stderr are terminals.  When
+// disabled ActiveProgress returns nil
+func EnableProgress(enabled bool) {
+	StopProgress()
+	if enabled && isTerminal(os.Stdout) && isTerminal(os.Stderr) {
+		activeProgress = NewProgress(os.Stderr)
+	}
+}
+
+// ActiveProgress returns the progress of the current command or nil when progress isn't drawn
+func ActiveProgress() *Progress {
+	return activeProgress
+}
+
+// StopProgress clears any drawn progress so other output can be written
+func StopProgress() {
+	activeProgress.Stop()
+}
+
+// Status sets the status {message}.  When {total} is greater than zero a bar of {done} of {total} is drawn
+func (p *Progress) Status(message string, done, total int) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.message = message
+	p.bar = ""
+	if total > 0 {
+		p.bar = progressBar(done, total)
+	}
+	p.start()
+	return true
+}
+
+// Step sets the current {step} of {total} of a bulk operation described by {label}
+func (p *Progress) Step(step, total int, label string) bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.step = fmt.Sprintf("[%d/%d]", step, total)
+	p.message = label
+	p.bar = ""
+	p.start()
+	return true
+}
+
+// Clear erases the drawn line.  It's redrawn on the next tick so Clear must be followed by output
+// written immediately or by Stop
+func (p *Progress) Clear() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
+
+// Stop stops redrawing and clears the line
+func (p *Progress) Stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+	p.clear()
+	p.step = ""
+}
+
+// Starts redrawing if it isn't already.  Must be called while holding the lock
+func (p *Progress) start() {
+	p.draw()
+	if p.stop != nil {
+		return
+	}
+	p.stop = make(chan struct{})
+	go func(stop chan struct{}) {
+		t := time.NewTicker(progressInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				p.mu.Lock()
+				p.frame++
+				p.draw()
+				p.mu.Unlock()
+			}
+		}
+	}(p.stop)
+}
+
+func (p *Progress) draw() {
+	fmt.Fprintf(p.out, "\r\033[K%s", p.line())
+	p.drawn = true
+}
+
+func (p *Progress) clear() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\033[K")
+		p.drawn = false
+	}
+}
+
+func (p *Progress) line() string {
+	parts := []string{spinnerFrames[p.frame%len(spinnerFrames)]}
+	for _, s := range []string{p.step, p.message, p.bar} {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Returns a bar such as [#####-----] 5/10
+func progressBar(done, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * progressBarWidth / total
+	}
+	if filled < 0 {
+		filled = 0
+	} else if filled > progressBarWidth {
+		filled = progressBarWidth
+	}
+	return fmt.Sprintf("[%s%s] %d/%d", strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled), done, total)
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressBar(t *testing.T) {
+	assert.Equal(t, "[--------------------] 0/4", progressBar(0, 4))
+	assert.Equal(t, "[##########----------] 2/4", progressBar(2, 4))
+	assert.Equal(t, "[####################] 4/4", progressBar(4, 4))
+	assert.Equal(t, "[####################] 5/4", progressBar(5, 4))
+}
+
+func TestProgress(t *testing.T) {
+	out := &bytes.Buffer{}
+	p := NewProgress(out)
+
+	assert.True(t, p.Step(2, 5, "/web"))
+	assert.Equal(t, "\r\033[K| [2/5] /web", out.String())
+
+	out.Reset()
+	assert.True(t, p.Status("Waiting for /web to become healthy", 1, 2))
+	assert.Equal(t, "\r\033[K| [2/5] Waiting for /web to become healthy [##########----------] 1/2", out.String())
+
+	out.Reset()
+	p.Stop()
+	assert.Equal(t, "\r\033[K", out.String())
+
+	out.Reset()
+	p.Stop()
+	assert.Empty(t, out.String())
+}
+
+func TestProgressNil(t *testing.T) {
+	var p *Progress
+	assert.False(t, p.Status("Waiting", 0, 0))
+	assert.False(t, p.Step(1, 2, "/web"))
+	p.Clear()
+	p.Stop()
+}
@@ -0,0 +1,467 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+)
+
+// Query is a parsed JSONPath expression (eg. {.tasks[*].host}) which is applied to a command's result.
+// Supported: .name, ['name'], [n], [-n], [start:end], [a,b], *, .. (recursive descent) and filters such as
+// [?(@.state=='TASK_RUNNING')] or [?(@.instances>1)]
+type Query struct {
+	expr  string
+	steps []queryStep
+}
+
+type queryStep struct {
+	recursive bool
+	sel       func(v interface{}) []interface{}
+}
+
+// ParseQuery parses the JSONPath expression {expr}.  The surrounding braces and leading $ are optional
+func ParseQuery(expr string) (*Query, error) {
+	q := &Query{expr: expr}
+	s := strings.TrimSpace(expr)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	s = strings.TrimPrefix(s, "$")
+
+	// set by '..' and applied to the following selector
+	recursive := false
+	for i := 0; i < len(s); {
+		step := queryStep{recursive: recursive}
+		switch s[i] {
+		case '.':
+			i++
+			if i < len(s) && s[i] == '.' {
+				step.recursive = true
+				i++
+			}
+			if i >= len(s) {
+				return nil, q.errorf("expected a field name after '.'")
+			}
+			switch {
+			case s[i] == '*':
+				step.sel = selectAll
+				i++
+			case s[i] == '[':
+				if !step.recursive {
+					return nil, q.errorf("unexpected '[' after '.'")
+				}
+				// eg. ..[0]
+				recursive = true
+				continue
+			default:
+				end := i
+				for end < len(s) && s[end] != '.' && s[end] != '[' {
+					end++
+				}
+				step.sel = selectNames([]string{s[i:end]})
+				i = end
+			}
+		case '[':
+			end, err := closingBracket(s, i)
+			if err != nil {
+				return nil, q.errorf("%s", err)
+			}
+			if step.sel, err = parseBracket(strings.TrimSpace(s[i+1 : end])); err != nil {
+				return nil, q.errorf("%s", err)
+			}
+			i = end + 1
+		default:
+			return nil, q.errorf("unexpected '%c' at position %d", s[i], i)
+		}
+		recursive = false
+		q.steps = append(q.steps, step)
+	}
+	return q, nil
+}
+
+func (q *Query) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Invalid query '%s': %s", q.expr, fmt.Sprintf(format, args...))
+}
+
+// Apply evaluates the query against {data} returning every matching value
+func (q *Query) Apply(data interface{}) ([]interface{}, error) {
+	root, err := normalize(data)
+	if err != nil {
+		return nil, err
+	}
+	return q.applyNormalized(root), nil
+}
+
+// WriteQueryResults writes {results} to {w}.  For json and yaml {format} the results are encoded (a single
+// result is encoded on its own), otherwise each result is written on its own line with objects and
+// arrays written as JSON
+func WriteQueryResults(w io.Writer, results []interface{}, format string) error {
+	switch format {
+	case FormatJSON, FormatYAML:
+		et := encoding.JSON
+		if format == FormatYAML {
+			et = encoding.YAML
+		}
+		var v interface{} = results
+		if len(results) == 1 {
+			v = results[0]
+		}
+		return encoding.NewStreamEncoder(et, w).Encode(v)
+	}
+	for _, r := range results {
+		if _, err := fmt.Fprintln(w, queryValueString(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queryValueString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// Converts {data} into the generic JSON representation (maps, slices and scalars) so queries use the
+// json field names
+func normalize(data interface{}) (interface{}, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	err = dec.Decode(&v)
+	return v, err
+}
+
+// Returns the index of the ']' closing the '[' at {start} ignoring brackets within quotes and filters
+func closingBracket(s string, start int) (int, error) {
+	var quote byte
+	depth := 0
+	for i := start + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')':
+			depth--
+		case c == ']':
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+		}
+	}
+	return 0, fmt.Errorf("missing ']'")
+}
+
+func parseBracket(content string) (func(v interface{}) []interface{}, error) {
+	switch {
+	case content == "*":
+		return selectAll, nil
+	case strings.HasPrefix(content, "?(") && strings.HasSuffix(content, ")"):
+		return parseFilter(strings.TrimSpace(content[2 : len(content)-1]))
+	case strings.HasPrefix(content, "'") || strings.HasPrefix(content, "\""):
+		names := []string{}
+		for _, n := range strings.Split(content, ",") {
+			n = strings.TrimSpace(n)
+			if len(n) < 2 || n[0] != n[len(n)-1] {
+				return nil, fmt.Errorf("invalid name %s", n)
+			}
+			names = append(names, n[1:len(n)-1])
+		}
+		return selectNames(names), nil
+	case strings.Contains(content, ":"):
+		parts := strings.SplitN(content, ":", 2)
+		start, end, err := sliceBound(parts[0]), sliceBound(parts[1]), error(nil)
+		if start == nil && strings.TrimSpace(parts[0]) != "" || end == nil && strings.TrimSpace(parts[1]) != "" {
+			err = fmt.Errorf("invalid slice [%s]", content)
+		}
+		return selectSlice(start, end), err
+	}
+	indexes := []int{}
+	for _, p := range strings.Split(content, ",") {
+		idx, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid index [%s]", content)
+		}
+		indexes = append(indexes, idx)
+	}
+	return selectIndexes(indexes), nil
+}
+
+func sliceBound(s string) *int {
+	if i, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+		return &i
+	}
+	return nil
+}
+
+var filterOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// Parses a filter such as @.state=='TASK_RUNNING'.  Without an operator the filter matches elements
+// where the path exists and isn't false or null
+func parseFilter(filter string) (func(v interface{}) []interface{}, error) {
+	lhs, op, rhs := filter, "", ""
+	for i := 0; i < len(filter) && op == ""; i++ {
+		if filter[i] == '\'' || filter[i] == '"' {
+			break
+		}
+		for _, o := range filterOps {
+			if strings.HasPrefix(filter[i:], o) {
+				lhs, op, rhs = strings.TrimSpace(filter[:i]), o, strings.TrimSpace(filter[i+len(o):])
+				break
+			}
+		}
+	}
+	if !strings.HasPrefix(lhs, "@") {
+		return nil, fmt.Errorf("filters must start with @ (eg. ?(@.state=='TASK_RUNNING'))")
+	}
+	path, err := ParseQuery("$" + lhs[1:])
+	if err != nil {
+		return nil, err
+	}
+	var literal interface{}
+	if op != "" {
+		if literal, err = parseLiteral(rhs); err != nil {
+			return nil, err
+		}
+	}
+
+	matches := func(v interface{}) bool {
+		values := path.applyNormalized(v)
+		if len(values) == 0 {
+			return false
+		}
+		if op == "" {
+			b, isBool := values[0].(bool)
+			return values[0] != nil && (!isBool || b)
+		}
+		return compare(values[0], op, literal)
+	}
+
+	return func(v interface{}) []interface{} {
+		result := []interface{}{}
+		for _, child := range children(v) {
+			if matches(child) {
+				result = append(result, child)
+			}
+		}
+		return result
+	}, nil
+}
+
+// Applies the query to data which has already been normalized
+func (q *Query) applyNormalized(v interface{}) []interface{} {
+	current := []interface{}{v}
+	for _, step := range q.steps {
+		next := []interface{}{}
+		for _, c := range current {
+			if step.recursive {
+				for _, d := range descendants(c) {
+					next = append(next, step.sel(d)...)
+				}
+			} else {
+				next = append(next, step.sel(c)...)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+func parseLiteral(s string) (interface{}, error) {
+	switch {
+	case len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0]:
+		return s[1 : len(s)-1], nil
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	case s == "null":
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %s - strings must be quoted", s)
+	}
+	return f, nil
+}
+
+func compare(v interface{}, op string, literal interface{}) bool {
+	if n, ok := v.(json.Number); ok {
+		f, _ := n.Float64()
+		v = f
+	}
+	switch l := literal.(type) {
+	case float64:
+		f, ok := v.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "==":
+			return f == l
+		case "!=":
+			return f != l
+		case "<":
+			return f < l
+		case "<=":
+			return f <= l
+		case ">":
+			return f > l
+		case ">=":
+			return f >= l
+		}
+	case string:
+		s, ok := v.(string)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "==":
+			return s == l
+		case "!=":
+			return s != l
+		case "<":
+			return s < l
+		case "<=":
+			return s <= l
+		case ">":
+			return s > l
+		case ">=":
+			return s >= l
+		}
+	default:
+		switch op {
+		case "==":
+			return v == literal
+		case "!=":
+			return v != literal
+		}
+	}
+	return false
+}
+
+// Returns the elements of an array or the values of an object ordered by key
+func children(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		return t
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, len(keys))
+		for i, k := range keys {
+			values[i] = t[k]
+		}
+		return values
+	}
+	return nil
+}
+
+// Returns {v} and every value nested within it
+func descendants(v interface{}) []interface{} {
+	result := []interface{}{v}
+	for _, c := range children(v) {
+		result = append(result, descendants(c)...)
+	}
+	return result
+}
+
+func selectAll(v interface{}) []interface{} {
+	return children(v)
+}
+
+func selectNames(names []string) func(v interface{}) []interface{} {
+	return func(v interface{}) []interface{} {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		result := []interface{}{}
+		for _, n := range names {
+			if value, exists := m[n]; exists {
+				result = append(result, value)
+			}
+		}
+		return result
+	}
+}
+
+func selectIndexes(indexes []int) func(v interface{}) []interface{} {
+	return func(v interface{}) []interface{} {
+		a, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		result := []interface{}{}
+		for _, i := range indexes {
+			if i < 0 {
+				i += len(a)
+			}
+			if i >= 0 && i < len(a) {
+				result = append(result, a[i])
+			}
+		}
+		return result
+	}
+}
+
+func selectSlice(start, end *int) func(v interface{}) []interface{} {
+	return func(v interface{}) []interface{} {
+		a, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		from, to := 0, len(a)
+		if start != nil {
+			from = *start
+		}
+		if end != nil {
+			to = *end
+		}
+		if from < 0 {
+			from += len(a)
+		}
+		if to < 0 {
+			to += len(a)
+		}
+		if from < 0 {
+			from = 0
+		}
+		if to > len(a) {
+			to = len(a)
+		}
+		if from >= to {
+			return []interface{}{}
+		}
+		return a[from:to]
+	}
+}
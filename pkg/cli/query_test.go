@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type queryTask struct {
+	ID    string `json:"id"`
+	Host  string `json:"host"`
+	State string `json:"state"`
+	Ports []int  `json:"ports"`
+}
+
+var queryData = map[string]interface{}{
+	"tasks": []*queryTask{
+		{ID: "web.1", Host: "agent-1", State: "TASK_RUNNING", Ports: []int{31000}},
+		{ID: "web.2", Host: "agent-2", State: "TASK_STAGING", Ports: []int{31001, 31002}},
+		{ID: "web.3", Host: "agent-3", State: "TASK_RUNNING"},
+	},
+}
+
+func query(t *testing.T, expr string) []string {
+	q, err := ParseQuery(expr)
+	if !assert.Nil(t, err) {
+		return nil
+	}
+	results, err := q.Apply(queryData)
+	assert.Nil(t, err)
+	values := []string{}
+	for _, r := range results {
+		values = append(values, queryValueString(r))
+	}
+	return values
+}
+
+func TestQuery(t *testing.T) {
+	assert.Equal(t, []string{"agent-1", "agent-2", "agent-3"}, query(t, "{.tasks[*].host}"))
+	assert.Equal(t, []string{"web.2"}, query(t, "$.tasks[1].id"))
+	assert.Equal(t, []string{"web.3"}, query(t, ".tasks[-1].id"))
+	assert.Equal(t, []string{"web.1", "web.2"}, query(t, ".tasks[0:2].id"))
+	assert.Equal(t, []string{"web.1", "web.3"}, query(t, ".tasks[?(@.state=='TASK_RUNNING')].id"))
+	assert.Equal(t, []string{"web.2"}, query(t, ".tasks[?(@.ports[1])].id"))
+	assert.Equal(t, []string{"31000", "31001", "31002"}, query(t, "..ports[*]"))
+	assert.Equal(t, []string{"31000", "31001"}, query(t, "..ports[0]"))
+	assert.Equal(t, []string{"web.1", "agent-1"}, query(t, ".tasks[0]['id','host']"))
+	assert.Equal(t, []string{"31001", "31002"}, query(t, ".tasks[?(@.host>'agent-1')].ports[?(@>31000)]"))
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, expr := range []string{".tasks[", ".tasks[?(state=='x')]", "tasks", ".tasks[a]", ".tasks[?(@.x==y)]"} {
+		_, err := ParseQuery(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestWriteQueryResults(t *testing.T) {
+	buf := &bytes.Buffer{}
+	WriteQueryResults(buf, []interface{}{"a", map[string]interface{}{"b": true}}, FormatColumn)
+	assert.Equal(t, "a\n{\"b\":true}\n", buf.String())
+}
@@ -1,18 +1,28 @@
 package cli
 
 import (
+	"os"
 	"strings"
 	"time"
 )
 
+// Prints the usage and exits with ExitUsage if fewer than {minlen} arguments are specified
 func EvalPrintUsage(usage_func func() error, args []string, minlen int) bool {
 	if len(args) < minlen {
 		usage_func()
-		return true
+		ExitWithCode(ExitUsage)
 	}
 	return false
 }
 
+// ClearScreen clears the terminal {out} and moves the cursor to the top.  Does nothing unless {out} is
+// a terminal
+func ClearScreen(out *os.File) {
+	if isTerminal(out) {
+		out.WriteString("\033[H\033[2J")
+	}
+}
+
 func FormatDate(date string) string {
 	t, err := time.Parse(time.RFC3339, date)
 	if err != nil {
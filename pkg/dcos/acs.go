@@ -0,0 +1,206 @@
+// DC/OS authentication (ACS) and service routing
+package dcos
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	// Login endpoint relative to the cluster URL
+	LoginPath = "/acs/api/v1/auth/login"
+	// Marathon is routed through the admin router at this path
+	MarathonServicePath = "/service/marathon"
+	// Metronome is routed through the admin router at this path
+	MetronomeServicePath = "/service/metronome"
+	// Chronos is routed through the admin router at this path when installed from the Universe
+	ChronosServicePath = "/service/chronos"
+	// The leading Mesos master is routed through the admin router at this path
+	MesosServicePath = "/mesos"
+	// Cosmos (the Universe package service) is routed through the admin router at this path
+	CosmosServicePath = "/package"
+)
+
+var (
+	log = logger.GetLogger("client")
+
+	ErrLoginFailed = errors.New("DC/OS login failed - verify the credentials for this environment")
+)
+
+// Returns the Marathon URL for the DC/OS cluster at {clusterURL}
+func MarathonURL(clusterURL string) string {
+	return strings.TrimRight(clusterURL, "/") + MarathonServicePath
+}
+
+// Returns the Metronome URL for the DC/OS cluster at {clusterURL}
+func MetronomeURL(clusterURL string) string {
+	return strings.TrimRight(clusterURL, "/") + MetronomeServicePath
+}
+
+// Returns the Chronos URL for the DC/OS cluster at {clusterURL}
+func ChronosURL(clusterURL string) string {
+	return strings.TrimRight(clusterURL, "/") + ChronosServicePath
+}
+
+// Returns the Mesos master URL for the DC/OS cluster at {clusterURL}
+func MesosURL(clusterURL string) string {
+	return strings.TrimRight(clusterURL, "/") + MesosServicePath
+}
+
+// Returns the Cosmos URL for the DC/OS cluster at {clusterURL}
+func CosmosURL(clusterURL string) string {
+	return strings.TrimRight(clusterURL, "/") + CosmosServicePath
+}
+
+// TokenCache persists tokens between invocations so a login is not required for every command
+type TokenCache interface {
+	Load(key string) string
+	Store(key, token string) error
+}
+
+type loginRequest struct {
+	UID      string `json:"uid"`
+	Password string `json:"password,omitempty"`
+	// signed JWT used by service accounts in place of a password
+	Token string `json:"token,omitempty"`
+}
+
+type loginResponse struct {
+	Token string `json:"token"`
+}
+
+// ACSAuthenticator logs in against the DC/OS ACS endpoint using a username and password (or a service
+// account private key) and implements httpclient.Authenticator
+type ACSAuthenticator struct {
+	sync.Mutex
+	// DC/OS cluster URL (eg. https://dcos.example.com)
+	URL      string
+	Username string
+	Password string
+	// Service account private key.  When set a signed JWT is exchanged for the token rather than a password
+	PrivateKey *rsa.PrivateKey
+	// Key used to cache the token (typically the environment name)
+	CacheKey string
+	Cache    TokenCache
+	Insecure bool
+	// Optional proxies and mutual TLS configuration used for the login request
+	Proxy *httpclient.ProxyConfig
+	TLS   *httpclient.TLSConfig
+	// Optional timeouts used for the login request
+	Timeouts *httpclient.Timeouts
+	// Optional static headers sent with the login request
+	Headers map[string]string
+	token   string
+	// when the token expires or zero when unknown
+	expires time.Time
+}
+
+func NewACSAuthenticator(url, username, password, cacheKey string, cache TokenCache, insecure bool) *ACSAuthenticator {
+	return &ACSAuthenticator{URL: url, Username: username, Password: password, CacheKey: cacheKey, Cache: cache, Insecure: insecure}
+}
+
+func (a *ACSAuthenticator) Token(refresh bool) (string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	// tokens about to expire are replaced rather than failing part way through a command
+	if !refresh && a.loadToken() != "" && !a.expiresWithin(TokenRefreshMargin) {
+		return a.token, nil
+	}
+
+	token, err := a.login()
+	if err != nil {
+		return "", err
+	}
+	a.token, a.expires = token, TokenExpiry(token)
+	if a.Cache != nil {
+		if err := a.Cache.Store(a.CacheKey, token); err != nil {
+			log.Warning("Unable to cache DC/OS token: %s", err.Error())
+		}
+		if ec, ok := a.Cache.(ExpiryCache); ok && !a.expires.IsZero() {
+			if err := ec.StoreExpiry(a.CacheKey, a.expires); err != nil {
+				log.Warning("Unable to record the expiry of the DC/OS token: %s", err.Error())
+			}
+		}
+	}
+	return token, nil
+}
+
+// Expires returns the time the current (or cached) token expires.  The time is zero when there's no token
+// or its expiry is unknown
+func (a *ACSAuthenticator) Expires() time.Time {
+	a.Lock()
+	defer a.Unlock()
+	if a.loadToken() == "" {
+		return time.Time{}
+	}
+	return a.expires
+}
+
+// Returns the current token loading it from the cache when there's none
+func (a *ACSAuthenticator) loadToken() string {
+	if a.token != "" || a.Cache == nil {
+		return a.token
+	}
+	if a.token = a.Cache.Load(a.CacheKey); a.token != "" {
+		if ec, ok := a.Cache.(ExpiryCache); ok {
+			a.expires = ec.LoadExpiry(a.CacheKey)
+		}
+		if a.expires.IsZero() {
+			a.expires = TokenExpiry(a.token)
+		}
+	}
+	return a.token
+}
+
+func (a *ACSAuthenticator) expiresWithin(d time.Duration) bool {
+	return !a.expires.IsZero() && time.Until(a.expires) < d
+}
+
+func (a *ACSAuthenticator) Apply(req *http.Request, token string) {
+	req.Header.Set("Authorization", "token="+token)
+}
+
+func (a *ACSAuthenticator) login() (string, error) {
+	log.Debug("Logging into DC/OS: %s", a.URL)
+
+	body := &loginRequest{UID: a.Username, Password: a.Password}
+	if a.PrivateKey != nil {
+		jwt, err := serviceAccountJWT(a.Username, a.PrivateKey)
+		if err != nil {
+			return "", err
+		}
+		body = &loginRequest{UID: a.Username, Token: jwt}
+	}
+
+	config := httpclient.NewDefaultConfig()
+	config.TLSInsecureSkipVerify = a.Insecure
+	config.Proxy = a.Proxy
+	config.TLS = a.TLS
+	config.Timeouts = a.Timeouts
+	config.Headers = a.Headers
+	// logging in makes no change to the cluster so it isn't refused by freezes or read-only environments
+	config.RPC = true
+	client := httpclient.NewHttpClient(*config)
+
+	result := &loginResponse{}
+	resp := client.HttpPost(strings.TrimRight(a.URL, "/")+LoginPath, body, result)
+	if resp.Error != nil {
+		if resp.Status == 401 || resp.Status == 403 {
+			return "", ErrLoginFailed
+		}
+		return "", fmt.Errorf("DC/OS login failed: %s", resp.Error.Error())
+	}
+	if result.Token == "" {
+		return "", ErrLoginFailed
+	}
+	return result.Token, nil
+}
@@ -0,0 +1,129 @@
+package dcos
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryCache map[string]string
+
+func (m memoryCache) Load(key string) string { return m[key] }
+func (m memoryCache) Store(key, token string) error {
+	m[key] = token
+	return nil
+}
+
+func TestTokenRefreshedOn401(t *testing.T) {
+	logins := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case LoginPath:
+			req := &loginRequest{}
+			json.NewDecoder(r.Body).Decode(req)
+			if req.UID != "admin" || req.Password != "secret" {
+				w.WriteHeader(401)
+				return
+			}
+			logins++
+			fmt.Fprintf(w, `{"token": "fresh-%d"}`, logins)
+		case MarathonServicePath + "/v2/info":
+			if r.Header.Get("Authorization") != "token=fresh-1" {
+				w.WriteHeader(401)
+				return
+			}
+			fmt.Fprint(w, `{"name": "marathon"}`)
+		}
+	}))
+	defer s.Close()
+
+	cache := memoryCache{"prod": "expired"}
+	config := httpclient.NewDefaultConfig()
+	config.Authenticator = NewACSAuthenticator(s.URL, "admin", "secret", "prod", cache, false)
+	client := httpclient.NewHttpClient(*config)
+
+	result := map[string]string{}
+	resp := client.HttpGet(MarathonURL(s.URL)+"/v2/info", &result)
+
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, "marathon", result["name"])
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, "fresh-1", cache["prod"])
+}
+
+func TestLoginFailure(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(401)
+	}))
+	defer s.Close()
+
+	_, err := NewACSAuthenticator(s.URL, "admin", "wrong", "prod", nil, false).Token(false)
+	assert.Equal(t, ErrLoginFailed, err)
+}
+
+func TestLoginIgnoresWriteGuard(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"token": "t"}`)
+	}))
+	defer s.Close()
+	httpclient.SetWriteGuard(func(method, url string) error { return errors.New("frozen") })
+	defer httpclient.SetWriteGuard(nil)
+
+	token, err := NewACSAuthenticator(s.URL, "admin", "secret", "prod", nil, false).Token(false)
+	assert.Nil(t, err, "logging in during a freeze makes no change")
+	assert.Equal(t, "t", token)
+}
+
+func TestServiceAccountLogin(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	secret, _ := json.Marshal(map[string]string{"uid": "ci-deployer", "private_key": string(keyPEM), "scheme": "RS256"})
+
+	f, _ := ioutil.TempFile("", "sa-secret")
+	defer os.Remove(f.Name())
+	f.Write(secret)
+	f.Close()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &loginRequest{}
+		json.NewDecoder(r.Body).Decode(req)
+
+		parts := strings.Split(req.Token, ".")
+		if req.UID != "ci-deployer" || req.Password != "" || len(parts) != 3 {
+			w.WriteHeader(401)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig) != nil {
+			w.WriteHeader(401)
+			return
+		}
+		fmt.Fprint(w, `{"token": "sa-token"}`)
+	}))
+	defer s.Close()
+
+	account, err := LoadServiceAccount(f.Name(), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "ci-deployer", account.UID)
+
+	token, err := NewServiceAccountAuthenticator(s.URL, account, "ci", nil, false).Token(false)
+	assert.Nil(t, err)
+	assert.Equal(t, "sa-token", token)
+}
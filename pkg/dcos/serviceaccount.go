@@ -0,0 +1,115 @@
+package dcos
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// Lifetime of the JWT exchanged for a token during a service account login
+const serviceAccountJWTExpiry = 5 * time.Minute
+
+var ErrInvalidPrivateKey = errors.New("Service account private key must be an RSA key in PEM form")
+
+// ServiceAccount holds the credentials of a DC/OS service account
+type ServiceAccount struct {
+	UID        string
+	PrivateKey *rsa.PrivateKey
+}
+
+// Service account secret as produced by 'dcos security secrets create-sa-secret'
+type serviceAccountSecret struct {
+	UID        string `json:"uid"`
+	PrivateKey string `json:"private_key"`
+	Scheme     string `json:"scheme"`
+}
+
+// NewServiceAccountAuthenticator returns an ACSAuthenticator which logs in using the service account
+func NewServiceAccountAuthenticator(url string, account *ServiceAccount, cacheKey string, cache TokenCache, insecure bool) *ACSAuthenticator {
+	a := NewACSAuthenticator(url, account.UID, "", cacheKey, cache, insecure)
+	a.PrivateKey = account.PrivateKey
+	return a
+}
+
+// LoadServiceAccount reads a service account from {filename} which may either be a service account
+// secret (JSON containing uid and private_key) or a PEM encoded private key.  When a PEM key is used
+// the {uid} must be supplied
+func LoadServiceAccount(filename, uid string) (*ServiceAccount, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPEM := b
+	if strings.HasPrefix(strings.TrimSpace(string(b)), "{") {
+		secret := &serviceAccountSecret{}
+		if err := json.Unmarshal(b, secret); err != nil {
+			return nil, fmt.Errorf("Invalid service account secret %s: %s", filename, err.Error())
+		}
+		if secret.Scheme != "" && secret.Scheme != "RS256" {
+			return nil, fmt.Errorf("Unsupported service account scheme '%s'", secret.Scheme)
+		}
+		if uid == "" {
+			uid = secret.UID
+		}
+		keyPEM = []byte(secret.PrivateKey)
+	}
+
+	if uid == "" {
+		return nil, errors.New("A service account uid is required")
+	}
+
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &ServiceAccount{UID: uid, PrivateKey: key}, nil
+}
+
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidPrivateKey
+	}
+	return key, nil
+}
+
+// Creates the RS256 signed JWT presented to the ACS login endpoint
+func serviceAccountJWT(uid string, key *rsa.PrivateKey) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"uid": uid,
+		"exp": time.Now().Add(serviceAccountJWTExpiry).Unix(),
+	})
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + enc.EncodeToString(sig), nil
+}
@@ -0,0 +1,42 @@
+package dcos
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const (
+	// Cached tokens expiring within this margin are replaced by a new login before they're used
+	TokenRefreshMargin = time.Minute
+	// Tokens expiring within this duration are refreshed (or warned about) when a client is created so long
+	// running commands don't fail part way through
+	TokenExpiryWarning = 15 * time.Minute
+)
+
+// ExpiryCache is implemented by TokenCaches which also record when the tokens they cache expire
+type ExpiryCache interface {
+	LoadExpiry(key string) time.Time
+	StoreExpiry(key string, expires time.Time) error
+}
+
+// TokenExpiry returns the time the JWT {token} expires (its exp claim) or the zero time when it isn't a JWT
+// with an expiry
+func TokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
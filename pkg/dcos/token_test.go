@@ -0,0 +1,51 @@
+package dcos
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns an unsigned JWT expiring at {exp}
+func testJWT(exp time.Time) string {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString([]byte(fmt.Sprintf(`{"uid":"admin","exp":%d}`, exp.Unix()))) + ".sig"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	assert.True(t, exp.Equal(TokenExpiry(testJWT(exp))))
+	assert.True(t, TokenExpiry("opaque").IsZero())
+}
+
+func TestExpiringTokenIsRenewed(t *testing.T) {
+	fresh := testJWT(time.Now().Add(5 * 24 * time.Hour))
+	logins := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logins++
+		fmt.Fprintf(w, `{"token": "%s"}`, fresh)
+	}))
+	defer s.Close()
+
+	// a cached token still valid for longer than the margin is used as is
+	valid := testJWT(time.Now().Add(time.Hour))
+	a := NewACSAuthenticator(s.URL, "admin", "secret", "prod", memoryCache{"prod": valid}, false)
+	token, err := a.Token(false)
+	assert.Nil(t, err)
+	assert.Equal(t, valid, token)
+	assert.Equal(t, 0, logins)
+
+	cache := memoryCache{"prod": testJWT(time.Now().Add(30 * time.Second))}
+	a = NewACSAuthenticator(s.URL, "admin", "secret", "prod", cache, false)
+	token, err = a.Token(false)
+	assert.Nil(t, err)
+	assert.Equal(t, fresh, token)
+	assert.Equal(t, 1, logins)
+	assert.Equal(t, fresh, cache["prod"])
+	assert.True(t, time.Until(a.Expires()) > 24*time.Hour)
+}
@@ -0,0 +1,159 @@
+// Line based diffing of rendered descriptors
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Number of unchanged lines shown around each change
+	DefaultContext = 3
+)
+
+type opType int
+
+const (
+	opEqual opType = iota
+	opDelete
+	opInsert
+)
+
+type lineOp struct {
+	op   opType
+	text string
+	// line numbers (1 based) within a and b
+	aLine int
+	bLine int
+}
+
+// Unified returns a unified diff (as produced by diff -u) between {a} and {b}.  An empty string
+// is returned when both inputs are identical
+// {fromName} - label for the original content
+// {toName}   - label for the new content
+// {context}  - number of unchanged lines to show around each change
+func Unified(a, b, fromName, toName string, context int) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+
+	for _, h := range hunks(ops, context) {
+		writeHunk(&buf, ops[h[0]:h[1]])
+	}
+	return buf.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Computes the line operations transforming a into b using the longest common subsequence
+func diffLines(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := []lineOp{}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, lineOp{op: opEqual, text: a[i], aLine: i + 1, bLine: j + 1})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, lineOp{op: opDelete, text: a[i], aLine: i + 1, bLine: j})
+			i++
+		default:
+			ops = append(ops, lineOp{op: opInsert, text: b[j], aLine: i, bLine: j + 1})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, lineOp{op: opDelete, text: a[i], aLine: i + 1, bLine: j})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, lineOp{op: opInsert, text: b[j], aLine: i, bLine: j + 1})
+	}
+	return ops
+}
+
+// Groups changed operations with surrounding context into [start, end) ranges
+func hunks(ops []lineOp, context int) [][2]int {
+	ranges := [][2]int{}
+	for idx, o := range ops {
+		if o.op == opEqual {
+			continue
+		}
+		start := idx - context
+		if start < 0 {
+			start = 0
+		}
+		end := idx + context + 1
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if len(ranges) > 0 && start <= ranges[len(ranges)-1][1] {
+			ranges[len(ranges)-1][1] = end
+		} else {
+			ranges = append(ranges, [2]int{start, end})
+		}
+	}
+	return ranges
+}
+
+func writeHunk(buf *bytes.Buffer, ops []lineOp) {
+	aStart, bStart, aCount, bCount := 0, 0, 0, 0
+	for _, o := range ops {
+		if o.op != opInsert {
+			if aCount == 0 {
+				aStart = o.aLine
+			}
+			aCount++
+		}
+		if o.op != opDelete {
+			if bCount == 0 {
+				bStart = o.bLine
+			}
+			bCount++
+		}
+	}
+	if aCount == 0 {
+		aStart = ops[0].aLine
+	}
+	if bCount == 0 {
+		bStart = ops[0].bLine
+	}
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+	for _, o := range ops {
+		switch o.op {
+		case opEqual:
+			buf.WriteString(" " + o.text + "\n")
+		case opDelete:
+			buf.WriteString("-" + o.text + "\n")
+		case opInsert:
+			buf.WriteString("+" + o.text + "\n")
+		}
+	}
+}
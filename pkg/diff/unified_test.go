@@ -0,0 +1,34 @@
+package diff
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUnifiedIdentical(t *testing.T) {
+	assert.Equal(t, "", Unified("a\nb\n", "a\nb\n", "a", "b", DefaultContext))
+}
+
+func TestUnifiedChangedLine(t *testing.T) {
+	a := "id: /app\nmem: 200\ninstances: 1\n"
+	b := "id: /app\nmem: 300\ninstances: 1\n"
+
+	expected := `--- staging
++++ prod
+@@ -1,3 +1,3 @@
+ id: /app
+-mem: 200
++mem: 300
+ instances: 1
+`
+	assert.Equal(t, expected, Unified(a, b, "staging", "prod", DefaultContext))
+}
+
+func TestUnifiedSeparateHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n"
+
+	out := Unified(a, b, "a", "b", 1)
+	assert.Contains(t, out, "@@ -1,2 +1,2 @@\n-1\n+one\n 2\n")
+	assert.Contains(t, out, "@@ -9,2 +9,2 @@\n 9\n-10\n+ten\n")
+}
@@ -0,0 +1,209 @@
+// Parses params files: .env style KEY=VALUE files along with YAML and JSON documents of params
+package dotenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envcrypt"
+	"github.com/ghodss/yaml"
+)
+
+var (
+	keyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	// ${VAR}, ${VAR:-default} or $VAR
+	reference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+// ParseFile parses the params file {filename}.  Files ending with .json, .yaml or .yml are a document of params
+// and others are parsed as Parse.  Encrypted files are decrypted in memory
+func ParseFile(filename string) (map[string]string, error) {
+	data, err := envcrypt.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var params map[string]string
+	if et, extErr := encoding.EncoderTypeFromExt(filename); extErr == nil && et != encoding.TOML {
+		params, err = ParseDocument(et, data)
+	} else {
+		params, err = Parse(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return params, nil
+}
+
+// ParseDocument parses the params of a JSON or YAML object.  Values must be strings, numbers or booleans
+func ParseDocument(et encoding.EncoderType, data []byte) (map[string]string, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return map[string]string{}, nil
+	}
+	if et == encoding.YAML {
+		var err error
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, err
+		}
+	} else {
+		data = encoding.StripJSONC(data)
+	}
+	doc := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// numbers are kept as written rather than as floats (eg. 1234567890123 not 1.234567890123e+12)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	params := make(map[string]string, len(doc))
+	for k, v := range doc {
+		switch v := v.(type) {
+		case string:
+			params[k] = v
+		case nil:
+			params[k] = ""
+		case bool, json.Number:
+			params[k] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("the value of '%s' must be a string, number or boolean", k)
+		}
+	}
+	return params, nil
+}
+
+// Parse parses the KEY=VALUE lines of {data}.  Blank lines and comments (#) are skipped and an 'export ' prefix
+// is permitted.  Values may be
+//
+//   - unquoted: the rest of the line without a trailing comment (whitespace followed by #)
+//   - single quoted: taken literally and may span lines
+//   - double quoted: may span lines with \n, \t, \", \\ and \$ escapes
+//
+// References to params defined earlier or environment variables ($VAR, ${VAR} or ${VAR:-default}) are
+// expanded within unquoted and double quoted values.  References which can't be resolved are kept as is
+func Parse(data []byte) (map[string]string, error) {
+	params := map[string]string{}
+	lookup := func(name string) (string, bool) {
+		if v, ok := params[name]; ok {
+			return v, true
+		}
+		return os.LookupEnv(name)
+	}
+
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		start := i + 1
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d must be KEY=VALUE", start)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !keyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: '%s' is not a valid key", start, key)
+		}
+		value := strings.TrimLeft(line[eq+1:], " \t")
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if c := strings.Index(value, " #"); c >= 0 {
+				value = value[:c]
+			} else if c := strings.Index(value, "\t#"); c >= 0 {
+				value = value[:c]
+			}
+			params[key] = expand(strings.TrimSpace(value), lookup)
+			continue
+		}
+
+		quote := value[0]
+		text := value[1:]
+		for {
+			end := closingQuote(text, quote)
+			if end >= 0 {
+				if rest := strings.TrimSpace(text[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+					return nil, fmt.Errorf("line %d: unexpected '%s' after the closing quote", i+1, rest)
+				}
+				text = text[:end]
+				break
+			}
+			// the value continues on the next line
+			i++
+			if i >= len(lines) {
+				return nil, fmt.Errorf("line %d: the value of '%s' has no closing quote", start, key)
+			}
+			text += "\n" + lines[i]
+		}
+		if quote == '\'' {
+			params[key] = text
+		} else {
+			params[key] = unescape(text, lookup)
+		}
+	}
+	return params, nil
+}
+
+// Returns the index of the first {quote} of {s} which isn't escaped or -1.  Single quoted values have no escapes
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// Replaces the escapes of the double quoted value {s} and expands its references
+func unescape(s string, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+	plain := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			continue
+		}
+		b.WriteString(expand(s[plain:i], lookup))
+		switch c := s[i+1]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\', '$':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+		i++
+		plain = i + 1
+	}
+	b.WriteString(expand(s[plain:], lookup))
+	return b.String()
+}
+
+// Expands the references of {s} resolved by {lookup}
+func expand(s string, lookup func(string) (string, bool)) string {
+	return reference.ReplaceAllStringFunc(s, func(ref string) string {
+		m := reference.FindStringSubmatch(ref)
+		name, def, hasDefault := m[1], m[2], strings.Contains(ref, ":-")
+		if name == "" {
+			name = m[3]
+		}
+		if v, ok := lookup(name); ok && (v != "" || !hasDefault) {
+			return v
+		}
+		if hasDefault {
+			return def
+		}
+		return ref
+	})
+}
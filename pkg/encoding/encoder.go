@@ -1,12 +1,14 @@
-// YAML and JSON encoding
+// YAML, JSON and TOML encoding
 package encoding
 
 import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 )
 
 type EncoderType int
@@ -14,12 +16,15 @@ type EncoderType int
 const (
 	JSON EncoderType = 1 + iota
 	YAML
+	TOML
 )
 
 var (
-	ErrorInvalidExtension = errors.New("File extension must be [.json | .yml | .yaml]")
+	ErrorInvalidExtension = errors.New("File extension must be [.json | .jsonc | .yml | .yaml | .toml]")
+	ErrorMultiDocument    = errors.New("Files containing multiple documents can only be converted to yaml")
 	defaultJSONEncoder    = newJSONEncoder()
 	defaultYAMLEncoder    = newYAMLEncoder()
+	defaultTOMLEncoder    = newTOMLEncoder()
 )
 
 type Encoder interface {
@@ -38,6 +43,8 @@ func NewEncoder(encoder EncoderType) (Encoder, error) {
 		return newJSONEncoder(), nil
 	case YAML:
 		return newYAMLEncoder(), nil
+	case TOML:
+		return newTOMLEncoder(), nil
 	default:
 		panic(fmt.Errorf("Unsupported encoder type"))
 	}
@@ -51,6 +58,10 @@ func DefaultYAMLEncoder() Encoder {
 	return defaultYAMLEncoder
 }
 
+func DefaultTOMLEncoder() Encoder {
+	return defaultTOMLEncoder
+}
+
 func NewEncoderFromFileExt(filename string) (Encoder, error) {
 
 	if et, err := EncoderTypeFromExt(filename); err != nil {
@@ -64,13 +75,18 @@ func EncoderTypeFromExt(filename string) (EncoderType, error) {
 	switch filepath.Ext(filename) {
 	case ".yml", ".yaml":
 		return YAML, nil
-	case ".json":
+	case ".json", ".jsonc":
 		return JSON, nil
+	case ".toml":
+		return TOML, nil
 	}
 	return JSON, ErrorInvalidExtension
 
 }
 
+// Converts {infile} into the format of {outfile}.  The document is validated against {dataType} while
+// conversions between JSON and YAML retain the original key order and YAML comments.  Multi-document
+// YAML input is converted document by document and may only be written as YAML
 func ConvertFile(infile, outfile string, dataType interface{}) error {
 	var fromEnc, toEnc Encoder
 	var encErr error
@@ -83,14 +99,44 @@ func ConvertFile(infile, outfile string, dataType interface{}) error {
 		return encErr
 	}
 
-	file, err := os.Open(infile)
+	b, err := ioutil.ReadFile(infile)
 	if err != nil {
 		return err
 	}
 
-	if err := fromEnc.UnMarshal(file, dataType); err != nil {
-		return err
+	fromType, _ := EncoderTypeFromExt(infile)
+	toType, _ := EncoderTypeFromExt(outfile)
+
+	docs := SplitDocuments(fromType, string(b))
+	if len(docs) > 1 && toType != YAML {
+		return ErrorMultiDocument
 	}
+
+	results := []string{}
+	for i, doc := range docs {
+		v := dataType
+		if i > 0 {
+			v = reflect.New(reflect.TypeOf(dataType).Elem()).Interface()
+		}
+		// unmarshal into the data type to validate the document even when the output is
+		// produced from the ordered representation
+		if err := fromEnc.UnMarshalStr(doc, v); err != nil {
+			return err
+		}
+
+		var data string
+		var err error
+		if orderedSupported(fromType, toType) {
+			data, err = convertOrdered(doc, fromType, toType)
+		} else {
+			data, err = toEnc.MarshalIndent(v)
+		}
+		if err != nil {
+			return err
+		}
+		results = append(results, terminate(data))
+	}
+
 	if err := os.MkdirAll(filepath.Dir(outfile), 0700); err != nil {
 		return err
 	}
@@ -99,10 +145,10 @@ func ConvertFile(infile, outfile string, dataType interface{}) error {
 		return err
 	}
 	defer f.Close()
-	if data, err := toEnc.MarshalIndent(dataType); err != nil {
-		return err
+	if len(results) > 1 {
+		_, err = f.WriteString(JoinDocuments(results))
 	} else {
-		f.WriteString(data)
+		_, err = f.WriteString(results[0])
 	}
-	return nil
+	return err
 }
@@ -1,12 +1,15 @@
 package encoding
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"strings"
 )
 
-// An encoder that marshal's and unmarshal's Json which implements the Encoder interface
+// An encoder that marshal's and unmarshal's Json which implements the Encoder interface.  Output is
+// always strict JSON
 type JSONEncoder struct{}
 
 func newJSONEncoder() *JSONEncoder {
@@ -29,8 +32,14 @@ func (e *JSONEncoder) Marshal(data interface{}) (string, error) {
 	}
 }
 
+// Unmarshals JSON from {r} into {result}.  Input is lenient and may contain comments and trailing
+// commas (see StripJSONC)
 func (e *JSONEncoder) UnMarshal(r io.Reader, result interface{}) error {
-	decoder := json.NewDecoder(r)
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(StripJSONC(b)))
 	if err := decoder.Decode(result); err != nil {
 		return err
 	}
@@ -0,0 +1,32 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLenientJSON(t *testing.T) {
+	input := `{
+  // the application id
+  "id": "/app", /* inline */
+  "cmd": "echo // not a comment, /* nor this */",
+  "args": ["-v", "-x",],
+  "labels": {
+    "env": "qa",
+  },
+}`
+	result := struct {
+		ID     string            `json:"id"`
+		Cmd    string            `json:"cmd"`
+		Args   []string          `json:"args"`
+		Labels map[string]string `json:"labels"`
+	}{}
+
+	assert.NoError(t, DefaultJSONEncoder().UnMarshalStr(input, &result))
+	assert.Equal(t, "/app", result.ID)
+	assert.Equal(t, "echo // not a comment, /* nor this */", result.Cmd)
+	assert.Equal(t, []string{"-v", "-x"}, result.Args)
+	assert.Equal(t, "qa", result.Labels["env"])
+	assert.Equal(t, len(input), len(StripJSONC([]byte(input))))
+}
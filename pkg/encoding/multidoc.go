@@ -0,0 +1,69 @@
+package encoding
+
+import (
+	"bufio"
+	"strings"
+)
+
+const yamlDocumentSeparator = "---"
+
+// SplitDocuments splits {data} into the documents it contains.  Only YAML supports multiple documents
+// (separated by '---'); other encoder types are always returned as a single document.  Documents
+// containing only whitespace or comments are dropped
+func SplitDocuments(et EncoderType, data string) []string {
+	if et != YAML {
+		return []string{data}
+	}
+
+	docs := []string{}
+	var current []string
+
+	flush := func() {
+		doc := strings.Join(current, "\n")
+		if !isEmptyYAML(doc) {
+			docs = append(docs, doc+"\n")
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if isDocumentSeparator(line) {
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	if len(docs) == 0 {
+		return []string{data}
+	}
+	return docs
+}
+
+// JoinDocuments joins documents previously marshalled as YAML into a single multi-document stream
+func JoinDocuments(docs []string) string {
+	trimmed := make([]string, len(docs))
+	for i, d := range docs {
+		trimmed[i] = strings.TrimRight(d, "\n") + "\n"
+	}
+	return strings.Join(trimmed, yamlDocumentSeparator+"\n")
+}
+
+func isDocumentSeparator(line string) bool {
+	line = strings.TrimRight(line, " \t\r")
+	return line == yamlDocumentSeparator || strings.HasPrefix(line, yamlDocumentSeparator+" #")
+}
+
+func isEmptyYAML(doc string) bool {
+	for _, line := range strings.Split(doc, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,19 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitDocuments(t *testing.T) {
+	input := "---\nid: /app\n# comment only\n---\n# nothing here\n---  \nid: /job\ncmd: echo ---\n"
+
+	docs := SplitDocuments(YAML, input)
+	assert.Equal(t, 2, len(docs))
+	assert.Equal(t, "id: /app\n# comment only\n", docs[0])
+	assert.Equal(t, "id: /job\ncmd: echo ---\n", docs[1])
+
+	assert.Equal(t, []string{input}, SplitDocuments(JSON, input))
+	assert.Equal(t, "id: /app\n---\nid: /job\n", JoinDocuments([]string{"id: /app\n", "id: /job"}))
+}
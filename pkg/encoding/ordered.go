@@ -0,0 +1,120 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Converts a single JSON or YAML document into {to} without unmarshalling into a struct so
+// key order (and YAML comments) are retained.  JSON is a subset of YAML so both are
+// read as a YAML node tree
+func convertOrdered(doc string, from, to EncoderType) (string, error) {
+	if from == JSON {
+		doc = string(StripJSONC([]byte(doc)))
+	}
+
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal([]byte(doc), &root); err != nil {
+		return "", err
+	}
+
+	switch to {
+	case JSON:
+		var buf bytes.Buffer
+		if err := writeJSONNode(&buf, &root); err != nil {
+			return "", err
+		}
+		var out bytes.Buffer
+		if err := json.Indent(&out, buf.Bytes(), "", "   "); err != nil {
+			return "", err
+		}
+		return out.String(), nil
+	case YAML:
+		if from == JSON {
+			resetStyle(&root)
+		}
+		var out bytes.Buffer
+		enc := yamlv3.NewEncoder(&out)
+		enc.SetIndent(2)
+		if err := enc.Encode(&root); err != nil {
+			return "", err
+		}
+		enc.Close()
+		return out.String(), nil
+	}
+	return "", fmt.Errorf("Unsupported encoder type")
+}
+
+// clears flow and quoting styles carried over from JSON so output is idiomatic block YAML
+func resetStyle(n *yamlv3.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		resetStyle(c)
+	}
+}
+
+func writeJSONNode(buf *bytes.Buffer, n *yamlv3.Node) error {
+	switch n.Kind {
+	case yamlv3.DocumentNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSONNode(buf, n.Content[0])
+	case yamlv3.AliasNode:
+		return writeJSONNode(buf, n.Alias)
+	case yamlv3.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(n.Content[i].Value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeJSONNode(buf, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yamlv3.SequenceNode:
+		buf.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONNode(buf, c); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yamlv3.ScalarNode:
+		var v interface{} = n.Value
+		switch n.ShortTag() {
+		case "!!int", "!!float", "!!bool", "!!null":
+			if err := n.Decode(&v); err != nil {
+				return err
+			}
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %s", n.Line, err.Error())
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+// true if conversion between the types can be performed while preserving order
+func orderedSupported(from, to EncoderType) bool {
+	return from != TOML && to != TOML
+}
+
+// trims trailing newlines so converted documents are consistently terminated
+func terminate(s string) string {
+	return strings.TrimRight(s, "\n") + "\n"
+}
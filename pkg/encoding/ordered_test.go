@@ -0,0 +1,40 @@
+package encoding
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type orderedApp struct {
+	ID        string            `json:"id"`
+	Instances int               `json:"instances"`
+	Labels    map[string]string `json:"labels"`
+}
+
+func TestConvertPreservesOrderAndComments(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "convert")
+	defer os.RemoveAll(dir)
+
+	yml := filepath.Join(dir, "app.yml")
+	ioutil.WriteFile(yml, []byte("# service\nlabels:\n  zone: b\n  app: a\ninstances: 2 # scaled\nid: /app\n"), 0600)
+
+	out := filepath.Join(dir, "app.json")
+	assert.NoError(t, ConvertFile(yml, out, &orderedApp{}))
+	b, _ := ioutil.ReadFile(out)
+	assert.Equal(t, "{\n   \"labels\": {\n      \"zone\": \"b\",\n      \"app\": \"a\"\n   },\n   \"instances\": 2,\n   \"id\": \"/app\"\n}\n", string(b))
+
+	back := filepath.Join(dir, "back.yaml")
+	assert.NoError(t, ConvertFile(out, back, &orderedApp{}))
+	b, _ = ioutil.ReadFile(back)
+	assert.Equal(t, "labels:\n  zone: b\n  app: a\ninstances: 2\nid: /app\n", string(b))
+
+	same := filepath.Join(dir, "same.yml")
+	assert.NoError(t, ConvertFile(yml, same, &orderedApp{}))
+	b, _ = ioutil.ReadFile(same)
+	assert.Contains(t, string(b), "# service")
+	assert.Contains(t, string(b), "instances: 2 # scaled")
+}
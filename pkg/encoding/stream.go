@@ -0,0 +1,172 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Encodes values directly onto a writer without buffering the complete output
+type StreamEncoder interface {
+	// Encodes {v} onto the stream.  Each value written to a YAML stream becomes a separate document
+	Encode(v interface{}) error
+}
+
+// Decodes values from a reader without buffering the complete input
+type StreamDecoder interface {
+	// Decodes the next value from the stream into {v}.  Returns io.EOF when no values remain
+	Decode(v interface{}) error
+}
+
+// NewStreamEncoder returns a StreamEncoder writing values of type {et} to {w}
+func NewStreamEncoder(et EncoderType, w io.Writer) StreamEncoder {
+	switch et {
+	case YAML:
+		return &yamlStreamEncoder{w: w}
+	case TOML:
+		return &bufferedStreamEncoder{w: w, enc: newTOMLEncoder()}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "   ")
+	return enc
+}
+
+// NewStreamDecoder returns a StreamDecoder reading values of type {et} from {r}.  JSON values are read
+// one at a time, YAML is read one document at a time and TOML (which has no notion of multiple
+// documents) is read as a single value
+func NewStreamDecoder(et EncoderType, r io.Reader) StreamDecoder {
+	switch et {
+	case YAML:
+		return &yamlStreamDecoder{dec: yamlv3.NewDecoder(r)}
+	case TOML:
+		return &bufferedStreamDecoder{r: r, enc: newTOMLEncoder()}
+	}
+	return json.NewDecoder(r)
+}
+
+// DecodeArray streams the elements of the JSON array found under {key} within the top level object read from {r}
+// (eg. "apps" for {"apps": [...]}), invoking {fn} with a decoder positioned at each element.  Only a single
+// element is held in memory at a time
+func DecodeArray(r io.Reader, key string, fn func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(r)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if name, ok := t.(string); !ok || name != key {
+			// skip the value of any other key
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if t, err = dec.Token(); err != nil {
+			return err
+		}
+		if t == nil {
+			return nil
+		}
+		if d, ok := t.(json.Delim); !ok || d != '[' {
+			return fmt.Errorf("Expected an array for '%s'", key)
+		}
+		for dec.More() {
+			if err := fn(dec); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("Expected '%s' but found '%v'", delim, t)
+	}
+	return nil
+}
+
+type yamlStreamEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *yamlStreamEncoder) Encode(v interface{}) error {
+	data, err := defaultYAMLEncoder.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, yamlDocumentSeparator+"\n"); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = io.WriteString(e.w, data)
+	return err
+}
+
+// YAML documents are converted to JSON so json struct tags are honored like the YAMLEncoder
+type yamlStreamDecoder struct {
+	dec *yamlv3.Decoder
+}
+
+func (d *yamlStreamDecoder) Decode(v interface{}) error {
+	var node yamlv3.Node
+	if err := d.dec.Decode(&node); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := writeJSONNode(&buf, &node); err != nil {
+		return err
+	}
+	return json.Unmarshal(buf.Bytes(), v)
+}
+
+type bufferedStreamEncoder struct {
+	w   io.Writer
+	enc Encoder
+}
+
+func (e *bufferedStreamEncoder) Encode(v interface{}) error {
+	data, err := e.enc.MarshalIndent(v)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(e.w, data)
+	return err
+}
+
+type bufferedStreamDecoder struct {
+	r    io.Reader
+	enc  Encoder
+	done bool
+}
+
+func (d *bufferedStreamDecoder) Decode(v interface{}) error {
+	if d.done {
+		return io.EOF
+	}
+	d.done = true
+	b, err := ioutil.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+	return d.enc.UnMarshalStr(string(b), v)
+}
@@ -0,0 +1,47 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamYAMLDocuments(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewStreamEncoder(YAML, &buf)
+	assert.NoError(t, enc.Encode(&orderedApp{ID: "/a", Instances: 1}))
+	assert.NoError(t, enc.Encode(&orderedApp{ID: "/b", Instances: 2}))
+
+	dec := NewStreamDecoder(YAML, &buf)
+	ids := []string{}
+	for {
+		app := &orderedApp{}
+		if err := dec.Decode(app); err == io.EOF {
+			break
+		} else {
+			assert.NoError(t, err)
+		}
+		ids = append(ids, app.ID)
+	}
+	assert.Equal(t, []string{"/a", "/b"}, ids)
+}
+
+func TestDecodeArray(t *testing.T) {
+	input := `{"meta": {"x": [1, 2]}, "apps": [{"id": "/a"}, {"id": "/b"}], "after": true}`
+
+	ids := []string{}
+	err := DecodeArray(strings.NewReader(input), "apps", func(dec *json.Decoder) error {
+		app := &orderedApp{}
+		if err := dec.Decode(app); err != nil {
+			return err
+		}
+		ids = append(ids, app.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/a", "/b"}, ids)
+}
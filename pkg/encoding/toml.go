@@ -0,0 +1,95 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// An encoder that marshal's and unmarshal's TOML which implements the Encoder interface.  Like the
+// YAML encoder, data is converted to/from JSON so existing json struct tags are honored
+type TOMLEncoder struct{}
+
+func newTOMLEncoder() *TOMLEncoder {
+	return &TOMLEncoder{}
+}
+
+func (e *TOMLEncoder) MarshalIndent(data interface{}) (string, error) {
+	return e.Marshal(data)
+}
+
+func (e *TOMLEncoder) Marshal(data interface{}) (string, error) {
+	j, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	var m map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(j))
+	decoder.UseNumber()
+	if err := decoder.Decode(&m); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(tomlValue(m)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func (e *TOMLEncoder) UnMarshal(r io.Reader, result interface{}) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	var m map[string]interface{}
+	if err := toml.Unmarshal(b, &m); err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(j, result)
+}
+
+func (e *TOMLEncoder) UnMarshalStr(data string, result interface{}) error {
+	return e.UnMarshal(strings.NewReader(data), result)
+}
+
+// TOML has no notion of null so nil values are dropped and JSON numbers are
+// restored to their integer or float representation
+func tomlValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			if val != nil {
+				m[k] = tomlValue(val)
+			}
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, 0, len(t))
+		for _, val := range t {
+			if val != nil {
+				s = append(s, tomlValue(val))
+			}
+		}
+		return s
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	}
+	return v
+}
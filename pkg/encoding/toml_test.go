@@ -0,0 +1,31 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tomlApp struct {
+	ID        string            `json:"id"`
+	Instances int               `json:"instances"`
+	CPUs      float64           `json:"cpus"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Cmd       *string           `json:"cmd"`
+}
+
+func TestTOMLRoundTrip(t *testing.T) {
+	app := &tomlApp{ID: "/app", Instances: 2, CPUs: 0.5, Labels: map[string]string{"env": "qa"}, Args: []string{"-v"}}
+
+	enc := DefaultTOMLEncoder()
+	data, err := enc.Marshal(app)
+	assert.NoError(t, err)
+	assert.Contains(t, data, `id = "/app"`)
+	assert.Contains(t, data, "instances = 2")
+	assert.NotContains(t, data, "cmd")
+
+	result := &tomlApp{}
+	assert.NoError(t, enc.UnMarshalStr(data, result))
+	assert.Equal(t, app, result)
+}
@@ -0,0 +1,172 @@
+// Encrypts params and env files with a passphrase so secret values used for substitution never sit in
+// plaintext on disk.  Files are decrypted in memory as they're read.  Files encrypted by sops (eg. with a
+// KMS key) are decrypted by sops
+package envcrypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ContainX/depcon/pkg/sops"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// PassphraseEnv holds the passphrase of encrypted files (eg. a CI secret)
+	PassphraseEnv = "DEPCON_ENV_PASSPHRASE"
+
+	// first line of an encrypted file, followed by the base64 encoded salt, nonce and ciphertext
+	header    = "$DEPCON_ENCRYPTED;1;scrypt"
+	saltSize  = 16
+	lineWidth = 76
+	// scrypt cost parameters
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+)
+
+var (
+	ErrorNoPassphrase   = fmt.Errorf("A passphrase is required to decrypt encrypted env files (set %s)", PassphraseEnv)
+	ErrorDecryptFailed  = errors.New("the passphrase is incorrect or the file has been modified")
+	ErrorInvalidContent = errors.New("the encrypted content is invalid")
+)
+
+// Prompt is called for the passphrase when PassphraseEnv isn't set.  nil when passphrases can't be prompted for
+var Prompt func() (string, error)
+
+var (
+	mu         sync.Mutex
+	passphrase string
+)
+
+// IsEncrypted returns true if {data} was written by Encrypt
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(header))
+}
+
+// Encrypt returns {plain} encrypted with a key derived from {passphrase}
+func Encrypt(plain []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrorNoPassphrase
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := append(append(salt, nonce...), gcm.Seal(nil, nonce, plain, nil)...)
+
+	encoded := base64.StdEncoding.EncodeToString(sealed)
+	var buf bytes.Buffer
+	buf.WriteString(header + "\n")
+	for len(encoded) > 0 {
+		n := lineWidth
+		if len(encoded) < n {
+			n = len(encoded)
+		}
+		buf.WriteString(encoded[:n] + "\n")
+		encoded = encoded[n:]
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt returns the content of {data} (written by Encrypt) decrypted with {passphrase}
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, ErrorInvalidContent
+	}
+	body := strings.TrimPrefix(strings.TrimSpace(string(data)), header)
+	sealed, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(body), ""))
+	if err != nil {
+		return nil, ErrorInvalidContent
+	}
+	if len(sealed) < saltSize {
+		return nil, ErrorInvalidContent
+	}
+	gcm, err := newCipher(passphrase, sealed[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	sealed = sealed[saltSize:]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrorInvalidContent
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrorDecryptFailed
+	}
+	return plain, nil
+}
+
+// ReadFile returns the content of {filename} decrypted in memory when it's encrypted by Encrypt or sops and
+// otherwise as is
+func ReadFile(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if sops.IsEncrypted(data) {
+		return sops.ReadFile(filename)
+	}
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	pass, err := Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	plain, err := Decrypt(data, pass)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt %s: %s", filename, err.Error())
+	}
+	return plain, nil
+}
+
+// Passphrase returns the passphrase from PassphraseEnv or Prompt.  Prompted passphrases are asked for once
+func Passphrase() (string, error) {
+	if pass := os.Getenv(PassphraseEnv); pass != "" {
+		return pass, nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if passphrase != "" {
+		return passphrase, nil
+	}
+	if Prompt == nil {
+		return "", ErrorNoPassphrase
+	}
+	pass, err := Prompt()
+	if err != nil || pass == "" {
+		return "", ErrorNoPassphrase
+	}
+	passphrase = pass
+	return pass, nil
+}
+
+func newCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
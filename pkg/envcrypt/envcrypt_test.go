@@ -0,0 +1,81 @@
+package envcrypt
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	plain := []byte("DB_PASSWORD=s3cret\nAPI_KEY=abc=123\n")
+	data, err := Encrypt(plain, "correct horse")
+	assert.Nil(t, err)
+	assert.True(t, IsEncrypted(data))
+	assert.NotContains(t, string(data), "s3cret")
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		assert.True(t, len(line) <= lineWidth)
+	}
+
+	b, err := Decrypt(data, "correct horse")
+	assert.Nil(t, err)
+	assert.Equal(t, plain, b)
+
+	_, err = Decrypt(data, "wrong")
+	assert.Equal(t, ErrorDecryptFailed, err)
+	_, err = Decrypt([]byte(header+"\nnot base64!\n"), "correct horse")
+	assert.Equal(t, ErrorInvalidContent, err)
+	_, err = Decrypt(plain, "correct horse")
+	assert.Equal(t, ErrorInvalidContent, err)
+	_, err = Encrypt(plain, "")
+	assert.Equal(t, ErrorNoPassphrase, err)
+}
+
+func TestReadFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "envcrypt")
+	defer os.RemoveAll(dir)
+	plainFile := filepath.Join(dir, "params.env")
+	encFile := filepath.Join(dir, "secrets.env.enc")
+	ioutil.WriteFile(plainFile, []byte("TAG=1.0\n"), 0644)
+	data, _ := Encrypt([]byte("DB_PASSWORD=s3cret\n"), "pass")
+	ioutil.WriteFile(encFile, data, 0600)
+
+	defer os.Setenv(PassphraseEnv, os.Getenv(PassphraseEnv))
+	os.Unsetenv(PassphraseEnv)
+	defer func() { Prompt, passphrase = nil, "" }()
+
+	b, err := ReadFile(plainFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "TAG=1.0\n", string(b))
+
+	_, err = ReadFile(encFile)
+	assert.Equal(t, ErrorNoPassphrase, err)
+
+	os.Setenv(PassphraseEnv, "wrong")
+	_, err = ReadFile(encFile)
+	assert.Contains(t, err.Error(), ErrorDecryptFailed.Error())
+
+	os.Setenv(PassphraseEnv, "pass")
+	b, err = ReadFile(encFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "DB_PASSWORD=s3cret\n", string(b))
+
+	// prompted passphrases are asked for once
+	os.Unsetenv(PassphraseEnv)
+	prompts := 0
+	Prompt = func() (string, error) { prompts++; return "pass", nil }
+	ReadFile(encFile)
+	b, err = ReadFile(encFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "DB_PASSWORD=s3cret\n", string(b))
+	assert.Equal(t, 1, prompts)
+
+	passphrase = ""
+	Prompt = func() (string, error) { return "", errors.New("not a terminal") }
+	_, err = ReadFile(encFile)
+	assert.Equal(t, ErrorNoPassphrase, err)
+}
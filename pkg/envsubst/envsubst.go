@@ -29,6 +29,7 @@ const (
 	initial state = iota
 	readingVarName
 	readingBracedVarName
+	readingTransforms
 )
 
 type varNameTokenStatus int
@@ -48,9 +49,20 @@ const (
 type envsubst struct {
 	state             state
 	buffer            bytes.Buffer
+	transforms        bytes.Buffer
 	target            runeWriter
 	undefinedBehavior undefinedVariableBehavior
 	resolver          func(string) string
+	// invoked with the variable name and position when a variable could not be resolved
+	undefined func(name string, line, column int)
+	// only finds the undefined variables without applying transforms to the values
+	scan bool
+	// position of the rune currently being processed
+	line   int
+	column int
+	// position of the '$' starting the current variable token
+	tokenLine   int
+	tokenColumn int
 }
 
 func isVarNameCharacter(char rune, isFirstLetter bool) bool {
@@ -87,16 +99,26 @@ func writeRune(char rune, target runeWriter) error {
 }
 
 func substituteVariableReferences(source runeReader, target runeWriter, undefinedBehavior undefinedVariableBehavior, resolver func(string) string) error {
-	et := envsubst{
+	return runSubstitution(source, &envsubst{
 		target:            target,
 		undefinedBehavior: undefinedBehavior,
 		resolver:          resolver,
-	}
+	})
+}
+
+func runSubstitution(source runeReader, et *envsubst) error {
+	et.line, et.column = 1, 1
 
 	for char, size, _ := source.ReadRune(); size != 0; char, size, _ = source.ReadRune() {
 		if err := et.processRune(char); err != nil {
 			return err
 		}
+		if char == '\n' {
+			et.line++
+			et.column = 1
+		} else {
+			et.column++
+		}
 	}
 
 	return et.endOfInput()
@@ -108,6 +130,7 @@ func (et *envsubst) processRune(char rune) error {
 		switch {
 		case char == '$':
 			et.state = readingVarName
+			et.tokenLine, et.tokenColumn = et.line, et.column
 		default:
 			return writeRune(char, et.target)
 		}
@@ -124,11 +147,22 @@ func (et *envsubst) processRune(char rune) error {
 		switch {
 		case isVarNameCharacter(char, et.buffer.Len() == 0):
 			return writeRune(char, &et.buffer)
+		case char == '|' && et.buffer.Len() > 0:
+			et.state = readingTransforms
 		case char == '}':
 			return et.flushBuffer(complete)
 		default:
 			return et.flushBufferAndProcessNextRune(incomplete, char)
 		}
+	case readingTransforms:
+		switch {
+		case char == '}':
+			return et.flushBuffer(complete)
+		case char == '\n':
+			return et.flushBufferAndProcessNextRune(incomplete, char)
+		default:
+			return writeRune(char, &et.transforms)
+		}
 	}
 
 	return nil
@@ -158,27 +192,49 @@ func (et *envsubst) flushBuffer(bufferStatus varNameTokenStatus) error {
 		err = writeString(standaloneDollarString(bufferStatus, et.state), et.target)
 	case et.state == readingBracedVarName && bufferStatus == incomplete:
 		err = writeString("${"+et.buffer.String(), et.target)
+	case et.state == readingTransforms && bufferStatus == incomplete:
+		err = writeString("${"+et.buffer.String()+"|"+et.transforms.String(), et.target)
 	default:
 		err = writeString(et.resolve(et.buffer.String()), et.target)
 	}
 
 	et.state = initial
 	et.buffer.Reset()
+	et.transforms.Reset()
 
 	return err
 }
 
 func (et *envsubst) resolve(variableName string) string {
 	resolvedValue := et.resolver(variableName)
+	if len(resolvedValue) == 0 && et.undefined != nil {
+		et.undefined(variableName, et.tokenLine, et.tokenColumn)
+	}
 	if len(resolvedValue) == 0 && et.undefinedBehavior == preserve {
-		if et.state == readingBracedVarName {
-			return "${" + variableName + "}"
+		return et.token(variableName)
+	}
+	if et.state == readingTransforms && len(resolvedValue) > 0 && !et.scan {
+		transformed, err := applyTransforms(resolvedValue, et.transforms.String())
+		if err != nil {
+			log.Warning("%s: %s", et.token(variableName), err.Error())
+			return et.token(variableName)
 		}
-		return "$" + variableName
+		return transformed
 	}
 	return resolvedValue
 }
 
+// returns the original token for the variable currently being processed
+func (et *envsubst) token(variableName string) string {
+	switch et.state {
+	case readingBracedVarName:
+		return "${" + variableName + "}"
+	case readingTransforms:
+		return "${" + variableName + "|" + et.transforms.String() + "}"
+	}
+	return "$" + variableName
+}
+
 func Substitute(in io.Reader, preserveUndef bool, resolver func(string) string) string {
 	undefinedBehavior := remove
 	if preserveUndef {
@@ -193,15 +249,33 @@ func Substitute(in io.Reader, preserveUndef bool, resolver func(string) string)
 }
 
 func SubstFileTokens(in io.Reader, params map[string]string) (parsed string, missing bool) {
-	parsed = Substitute(in, true, func(s string) string {
-		if params != nil && params[s] != "" {
-			return params[s]
-		}
-		if os.Getenv(s) == "" {
-			log.Warning("Cannot find a value for varible ${%s} in template", s)
-			missing = true
-		}
-		return os.Getenv(s)
-	})
-	return parsed, missing
+	parsed, unresolved := SubstTokens(in, params)
+	return parsed, len(unresolved) > 0
+}
+
+// SubstTokens replaces ${PARAM} tokens with values from {params} falling back to environment variables.
+// Values referencing secrets are resolved with the SecretResolver.  Tokens which could not be resolved are
+// preserved and returned along with their positions
+func SubstTokens(in io.Reader, params map[string]string) (string, []MissingParam) {
+	missing := []MissingParam{}
+
+	buf := new(bytes.Buffer)
+	et := &envsubst{
+		target:            buf,
+		undefinedBehavior: preserve,
+		resolver: func(s string) string {
+			if params != nil && params[s] != "" {
+				return resolveSecret(s, params[s])
+			}
+			return resolveSecret(s, os.Getenv(s))
+		},
+		undefined: func(name string, line, column int) {
+			log.Warning("Cannot find a value for varible ${%s} in template (line %d, column %d)", name, line, column)
+			missing = append(missing, MissingParam{Name: name, Line: line, Column: column})
+		},
+	}
+	if err := runSubstitution(bufio.NewReader(in), et); err != nil {
+		log.Fatal(err)
+	}
+	return buf.String(), missing
 }
@@ -207,3 +207,38 @@ func TestVariableNamesAllowDigitsAfterFirstCharacter(t *testing.T) {
 	result = subst("no ${FUN2}", funWithDigits)
 	assert.Equal(t, "no ", result)
 }
+
+func TestSubstTokensReportsAllMissingWithPositions(t *testing.T) {
+	input := "{\n  \"id\": \"${APP_ID}\",\n  \"image\": \"repo:${TAG}\", \"host\": \"${WORD}\"\n}"
+
+	parsed, missing := SubstTokens(strings.NewReader(input), map[string]string{"WORD": "go"})
+
+	assert.Contains(t, parsed, `"host": "go"`)
+	assert.Contains(t, parsed, "${APP_ID}")
+	assert.Equal(t, 2, len(missing))
+	assert.Equal(t, MissingParam{Name: "APP_ID", Line: 2, Column: 10}, missing[0])
+	assert.Equal(t, MissingParam{Name: "TAG", Line: 3, Column: 18}, missing[1])
+
+	err := &MissingParamsError{Filename: "app.json", Params: missing}
+	assert.Contains(t, err.Error(), "app.json: ${APP_ID} at line 2, column 10")
+	assert.Contains(t, err.Error(), "app.json: ${TAG} at line 3, column 18")
+}
+
+func TestFindMissingSkipsTransforms(t *testing.T) {
+	input := "port: ${PORT|quote}\nhost: ${HOST}\ntoken: ${TOKEN}"
+
+	missing := FindMissing(strings.NewReader(input), map[string]string{"PORT": "secret://vault/port", "TOKEN": "abc"})
+	assert.Equal(t, []MissingParam{{Name: "HOST", Line: 2, Column: 7}}, missing)
+}
+
+func TestTransforms(t *testing.T) {
+	vars := map[string]string{"CONFIG": "a: \"1\"\nb: 2", "USER": "go"}
+
+	assert.Equal(t, `{"cfg": "a: \"1\"\nb: 2"}`, subst(`{"cfg": "${CONFIG|jsonEscape}"}`, vars))
+	assert.Equal(t, `{"cfg": "a: \"1\"\nb: 2"}`, subst(`{"cfg": ${CONFIG|toJson}}`, vars))
+	assert.Equal(t, "Z28=", subst("${USER|b64enc}", vars))
+	assert.Equal(t, `"Z28="`, subst("${USER|b64enc|quote}", vars))
+	assert.Equal(t, "  a: \"1\"\n  b: 2", subst("${CONFIG|indent:2}", vars))
+	assert.Equal(t, "${USER|bogus}", subst("${USER|bogus}", vars))
+	assert.Equal(t, "${MISSING|quote}", substitute("${MISSING|quote}", true, vars))
+}
@@ -0,0 +1,77 @@
+package envsubst
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MissingParam describes a ${PARAM} or template field which could not be resolved
+type MissingParam struct {
+	// Variable name (eg. DB_HOST) or template field (eg. .app.mem)
+	Name string
+	// Line within the source (1 based)
+	Line int
+	// Column within the line (1 based)
+	Column int
+	// True if this is a template field vs. a ${PARAM}
+	Template bool
+}
+
+func (p MissingParam) String() string {
+	token := fmt.Sprintf("${%s}", p.Name)
+	if p.Template {
+		token = fmt.Sprintf("{{ %s }}", p.Name)
+	}
+	return fmt.Sprintf("%s at line %d, column %d", token, p.Line, p.Column)
+}
+
+// MissingParamsError is returned when params or template fields could not be resolved and
+// missing values are not being ignored.  All unresolved references are reported at once
+type MissingParamsError struct {
+	Filename string
+	Params   []MissingParam
+}
+
+func (e *MissingParamsError) Error() string {
+	var b bytes.Buffer
+	b.WriteString("One or more ${PARAMS} that were defined")
+	if e.Filename != "" {
+		fmt.Fprintf(&b, " in %s", e.Filename)
+	}
+	b.WriteString(" could not be resolved:")
+	for _, p := range e.Params {
+		b.WriteString("\n  ")
+		if e.Filename != "" {
+			fmt.Fprintf(&b, "%s: ", e.Filename)
+		}
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
+
+// FindMissing returns the ${PARAM} tokens of {in} which can't be resolved from {params} or environment
+// variables along with their positions.  Values aren't resolved so no secrets are read
+func FindMissing(in io.Reader, params map[string]string) []MissingParam {
+	missing := []MissingParam{}
+	et := &envsubst{
+		target:            new(bytes.Buffer),
+		undefinedBehavior: preserve,
+		scan:              true,
+		resolver: func(s string) string {
+			if params != nil && params[s] != "" {
+				return params[s]
+			}
+			return os.Getenv(s)
+		},
+		undefined: func(name string, line, column int) {
+			missing = append(missing, MissingParam{Name: name, Line: line, Column: column})
+		},
+	}
+	if err := runSubstitution(bufio.NewReader(in), et); err != nil {
+		log.Fatal(err)
+	}
+	return missing
+}
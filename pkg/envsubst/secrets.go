@@ -0,0 +1,28 @@
+package envsubst
+
+import "github.com/ContainX/depcon/pkg/mask"
+
+// SecretResolver resolves param values which reference secrets (eg. secret://vault/secret/data/db#password)
+// returning other values as is.  Param values are used as is when nil
+var SecretResolver func(value string) (string, error)
+
+// Resolves {value} with the SecretResolver.  A secret which can't be resolved is logged and has no value.
+// The values of params named like a secret (eg. DB_PASSWORD) are masked within output
+func resolveSecret(name, value string) string {
+	if SecretResolver == nil || value == "" {
+		return maskParam(name, value)
+	}
+	resolved, err := SecretResolver(value)
+	if err != nil {
+		log.Error("Cannot resolve the secret of ${%s}: %s", name, err.Error())
+		return ""
+	}
+	return maskParam(name, resolved)
+}
+
+func maskParam(name, value string) string {
+	if value != "" && mask.IsSecretName(name) {
+		mask.AddValues(value)
+	}
+	return value
+}
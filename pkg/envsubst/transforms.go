@@ -0,0 +1,87 @@
+package envsubst
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TransformFunc converts a resolved value.  Arguments follow the transform name separated
+// by ':' (eg. ${CONFIG|indent:4})
+type TransformFunc func(value string, args ...string) (string, error)
+
+// Transforms which may be applied to a resolved param using the ${PARAM|transform} syntax.  Transforms
+// can be chained (eg. ${CONFIG|b64enc|quote}) and are applied left to right
+var Transforms = map[string]TransformFunc{
+	"toJson": func(value string, args ...string) (string, error) {
+		return ToJSON(value)
+	},
+	"jsonEscape": func(value string, args ...string) (string, error) {
+		return JSONEscape(value), nil
+	},
+	"b64enc": func(value string, args ...string) (string, error) {
+		return Base64Encode(value), nil
+	},
+	"quote": func(value string, args ...string) (string, error) {
+		return Quote(value), nil
+	},
+	"indent": func(value string, args ...string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("indent requires the number of spaces (eg. indent:4)")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return "", fmt.Errorf("indent: invalid number of spaces '%s'", args[0])
+		}
+		return Indent(n, value), nil
+	},
+}
+
+// ToJSON encodes {v} as JSON
+func ToJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// JSONEscape escapes {s} so it can be placed within a JSON string literal
+func JSONEscape(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b[1 : len(b)-1])
+}
+
+// Base64Encode returns the standard base64 encoding of {s}
+func Base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// Quote wraps {s} in double quotes escaping any special characters
+func Quote(s string) string {
+	return strconv.Quote(s)
+}
+
+// Indent prefixes every line within {s} with {spaces} spaces
+func Indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+// applies the '|' separated transforms found in {spec} to {value}
+func applyTransforms(value, spec string) (string, error) {
+	for _, t := range strings.Split(spec, "|") {
+		parts := strings.Split(strings.TrimSpace(t), ":")
+		fn, ok := Transforms[parts[0]]
+		if !ok {
+			return "", fmt.Errorf("Unknown transform '%s'", parts[0])
+		}
+		var err error
+		if value, err = fn(value, parts[1:]...); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
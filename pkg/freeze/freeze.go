@@ -0,0 +1,166 @@
+// Deployment freeze windows during which changes to an environment are refused unless the freeze is
+// explicitly broken (eg. for an emergency fix)
+package freeze
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// layout of the times of one-off windows (eg. 2026-12-24 00:00)
+	DateLayout = "2006-01-02 15:04"
+
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+var ErrorInvalidWindow = errors.New("Freeze windows must start and end with a weekly time (eg. Fri 16:00) or a date (eg. 2026-12-24 00:00)")
+
+// Window is a period changes are refused.  Start and End are either both weekly times (eg. Fri 16:00 to
+// Mon 08:00 repeating every week) or both dates (a one-off freeze)
+type Window struct {
+	// Optional name shown when a change is refused (eg. weekend)
+	Name  string `json:"name,omitempty"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Optional IANA time zone of Start and End (eg. Europe/London).  Default: local time
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// FrozenError is returned for changes attempted during a freeze
+type FrozenError struct {
+	Environment string
+	Window      *Window
+	Until       time.Time
+}
+
+func (e *FrozenError) Error() string {
+	name := ""
+	if e.Window.Name != "" {
+		name = fmt.Sprintf(" (%s)", e.Window.Name)
+	}
+	return fmt.Sprintf("Environment '%s' is frozen%s until %s - use --break-glass REASON to make changes anyway",
+		e.Environment, name, e.Until.Format("Mon 2006-01-02 15:04 MST"))
+}
+
+// Validate returns an error if the times or time zone of the window can't be parsed
+func (w *Window) Validate() error {
+	_, _, err := w.bounds(time.Now())
+	return err
+}
+
+// String describes the window (eg. weekend: Fri 16:00 - Mon 08:00)
+func (w *Window) String() string {
+	s := fmt.Sprintf("%s - %s", w.Start, w.End)
+	if w.Timezone != "" {
+		s += " " + w.Timezone
+	}
+	if w.Name != "" {
+		s = w.Name + ": " + s
+	}
+	return s
+}
+
+// Active returns true and the time the freeze ends if {now} is within the window
+func (w *Window) Active(now time.Time) (bool, time.Time, error) {
+	start, end, err := w.bounds(now)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if !now.Before(start) && now.Before(end) {
+		return true, end, nil
+	}
+	return false, time.Time{}, nil
+}
+
+// Check returns a FrozenError if {now} is within any of the {windows} of environment {env}
+func Check(env string, windows []*Window, now time.Time) error {
+	for _, w := range windows {
+		active, until, err := w.Active(now)
+		if err != nil {
+			return fmt.Errorf("Invalid freeze window '%s' of environment '%s': %s", w, env, err.Error())
+		}
+		if active {
+			return &FrozenError{Environment: env, Window: w, Until: until}
+		}
+	}
+	return nil
+}
+
+// Returns the start and end of the window.  For weekly windows this is the occurrence which contains {now}
+// or otherwise the next occurrence
+func (w *Window) bounds(now time.Time) (time.Time, time.Time, error) {
+	loc := time.Local
+	if w.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	now = now.In(loc)
+
+	if start, err := time.ParseInLocation(DateLayout, w.Start, loc); err == nil {
+		end, err := time.ParseInLocation(DateLayout, w.End, loc)
+		if err != nil || !end.After(start) {
+			return time.Time{}, time.Time{}, ErrorInvalidWindow
+		}
+		return start, end, nil
+	}
+
+	startMin, err := minuteOfWeek(w.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	endMin, err := minuteOfWeek(w.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	length := (endMin - startMin + minutesPerWeek) % minutesPerWeek
+	if length == 0 {
+		return time.Time{}, time.Time{}, ErrorInvalidWindow
+	}
+
+	// the most recent start of the window was {since} minutes ago which is {days} relative to today
+	nowOfDay := now.Hour()*60 + now.Minute()
+	since := (int(now.Weekday())*minutesPerDay + nowOfDay - startMin + minutesPerWeek) % minutesPerWeek
+	days := floorDiv(nowOfDay-since, minutesPerDay)
+	startOfDay := startMin % minutesPerDay
+	start := time.Date(now.Year(), now.Month(), now.Day()+days, startOfDay/60, startOfDay%60, 0, 0, loc)
+	if since >= length {
+		start = start.AddDate(0, 0, 7)
+	}
+	endOfDay := endMin % minutesPerDay
+	end := time.Date(start.Year(), start.Month(), start.Day()+(startOfDay+length)/minutesPerDay, endOfDay/60, endOfDay%60, 0, 0, loc)
+	return start, end, nil
+}
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Returns the minutes since Sunday 00:00 of the weekly time {s} (eg. Fri 16:00)
+func minuteOfWeek(s string) (int, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) != 2 || len(fields[0]) < 3 {
+		return 0, ErrorInvalidWindow
+	}
+	t, err := time.Parse("15:04", fields[1])
+	if err != nil {
+		return 0, ErrorInvalidWindow
+	}
+	for day, name := range weekdays {
+		if strings.HasPrefix(fields[0], name) {
+			return day*minutesPerDay + t.Hour()*60 + t.Minute(), nil
+		}
+	}
+	return 0, ErrorInvalidWindow
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
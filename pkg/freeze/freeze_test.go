@@ -0,0 +1,98 @@
+package freeze
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var weekend = &Window{Name: "weekend", Start: "Fri 16:00", End: "Mon 08:00", Timezone: "UTC"}
+
+func at(s string) time.Time {
+	t, _ := time.ParseInLocation(DateLayout, s, time.UTC)
+	return t
+}
+
+func TestWeeklyWindow(t *testing.T) {
+	// 2026-10-16 is a Friday
+	for _, now := range []string{"2026-10-16 16:00", "2026-10-17 12:00", "2026-10-19 07:59"} {
+		active, until, err := weekend.Active(at(now))
+		assert.Nil(t, err)
+		assert.True(t, active, now)
+		assert.Equal(t, at("2026-10-19 08:00"), until, now)
+	}
+	for _, now := range []string{"2026-10-16 15:59", "2026-10-19 08:00", "2026-10-21 12:00"} {
+		active, _, err := weekend.Active(at(now))
+		assert.Nil(t, err)
+		assert.False(t, active, now)
+	}
+
+	// windows within a day and starting on Sunday
+	nightly := &Window{Start: "wed 22:00", End: "Wed 23:30", Timezone: "UTC"}
+	active, until, _ := nightly.Active(at("2026-10-21 23:00"))
+	assert.True(t, active)
+	assert.Equal(t, at("2026-10-21 23:30"), until)
+	sunday := &Window{Start: "Sunday 00:00", End: "Tue 00:00", Timezone: "UTC"}
+	active, until, _ = sunday.Active(at("2026-10-19 10:00"))
+	assert.True(t, active)
+	assert.Equal(t, at("2026-10-20 00:00"), until)
+}
+
+func TestDateWindow(t *testing.T) {
+	holidays := &Window{Start: "2026-12-23 18:00", End: "2027-01-04 08:00", Timezone: "UTC"}
+	active, until, err := holidays.Active(at("2026-12-25 09:00"))
+	assert.Nil(t, err)
+	assert.True(t, active)
+	assert.Equal(t, at("2027-01-04 08:00"), until)
+	active, _, _ = holidays.Active(at("2026-12-23 17:00"))
+	assert.False(t, active)
+}
+
+func TestCheck(t *testing.T) {
+	assert.Nil(t, Check("prod", []*Window{weekend}, at("2026-10-14 12:00")))
+	err := Check("prod", []*Window{weekend}, at("2026-10-17 12:00"))
+	assert.IsType(t, &FrozenError{}, err)
+	assert.Equal(t, "Environment 'prod' is frozen (weekend) until Mon 2026-10-19 08:00 UTC - use --break-glass REASON to make changes anyway", err.Error())
+}
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, weekend.Validate())
+	assert.Equal(t, ErrorInvalidWindow, (&Window{Start: "Fri 16:00", End: "2026-10-19 08:00"}).Validate())
+	assert.Equal(t, ErrorInvalidWindow, (&Window{Start: "Fri", End: "Mon 08:00"}).Validate())
+	assert.Equal(t, ErrorInvalidWindow, (&Window{Start: "2026-10-19 08:00", End: "2026-10-18 08:00"}).Validate())
+	assert.NotNil(t, (&Window{Start: "Fri 16:00", End: "Mon 08:00", Timezone: "Mars/Olympus"}).Validate())
+}
+
+func TestChangeWindows(t *testing.T) {
+	windows := &ChangeWindows{Windows: []*Schedule{
+		{Name: "business-hours", Cron: "* 9-16 * * mon-fri", Timezone: "UTC"},
+		{Cron: "0-29 10 1,15 * *", Timezone: "UTC"},
+	}}
+	assert.Nil(t, windows.Validate())
+	// 2026-10-16 is a Friday
+	for _, now := range []string{"2026-10-16 09:00", "2026-10-16 16:59", "2026-11-01 10:29"} {
+		assert.Nil(t, windows.Check("prod", at(now)), now)
+	}
+
+	err := windows.Check("prod", at("2026-10-16 17:00"))
+	outside, ok := err.(*OutsideWindowError)
+	assert.True(t, ok)
+	assert.Equal(t, at("2026-10-19 09:00"), outside.Next)
+	assert.Contains(t, err.Error(), "business-hours: * 9-16 * * mon-fri UTC")
+	outside = windows.Check("prod", at("2026-10-31 23:00")).(*OutsideWindowError)
+	assert.Equal(t, at("2026-11-01 10:00"), outside.Next)
+
+	assert.Nil(t, (*ChangeWindows)(nil).Check("prod", at("2026-10-16 17:00")))
+	assert.False(t, (*ChangeWindows)(nil).Warns())
+	assert.True(t, (&ChangeWindows{Policy: PolicyWarn}).Warns())
+}
+
+func TestInvalidChangeWindows(t *testing.T) {
+	for _, cron := range []string{"* 9-17 * *", "60 * * * *", "* 17-9 * * *", "* * * * funday", "*/0 * * * *"} {
+		assert.NotNil(t, (&ChangeWindows{Windows: []*Schedule{{Cron: cron}}}).Validate(), cron)
+	}
+	assert.NotNil(t, (&ChangeWindows{Policy: "deny"}).Validate())
+	assert.NotNil(t, (&ChangeWindows{Windows: []*Schedule{{Cron: "* * * * *", Timezone: "Mars/Olympus"}}}).Validate())
+	assert.Nil(t, (&ChangeWindows{Windows: []*Schedule{{Cron: "*/15 0,12 1-7 jan-mar 7"}}}).Validate())
+}
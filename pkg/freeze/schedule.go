@@ -0,0 +1,251 @@
+package freeze
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Changes outside the change windows are refused unless the windows are explicitly broken
+	PolicyBlock = "block"
+	// Changes outside the change windows are made with a warning
+	PolicyWarn = "warn"
+
+	// how far ahead the next change window is looked for
+	nextWindowSearch = 366 * 24 * time.Hour
+)
+
+var ErrorInvalidCron = errors.New("Change windows must be cron expressions of 5 fields: minute hour day-of-month month day-of-week (eg. * 9-16 * * mon-fri)")
+
+// ChangeWindows are the recurring periods changes to an environment are allowed.  Unlike freeze windows
+// (periods changes are refused) changes are only allowed at the minutes matched by one of the windows
+type ChangeWindows struct {
+	// block (default) or warn
+	Policy  string      `json:"policy,omitempty"`
+	Windows []*Schedule `json:"windows"`
+}
+
+// Schedule is a change window matching the minutes of a cron expression (eg. * 9-16 * * mon-fri for
+// weekdays from 09:00 to 16:59)
+type Schedule struct {
+	// Optional name shown when a change is outside the windows (eg. business-hours)
+	Name string `json:"name,omitempty"`
+	// minute hour day-of-month month day-of-week with *, ranges (1-5), steps (*/15) lists (1,3) and the
+	// names of months and days (jan, mon)
+	Cron string `json:"cron"`
+	// Optional IANA time zone of the expression (eg. Europe/London).  Default: local time
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// OutsideWindowError is returned for changes attempted outside the change windows
+type OutsideWindowError struct {
+	Environment string
+	Windows     *ChangeWindows
+	// start of the next change window (zero when there is none within a year)
+	Next time.Time
+}
+
+func (e *OutsideWindowError) Error() string {
+	next := "no change window opens within a year"
+	if !e.Next.IsZero() {
+		next = "the next opens " + e.Next.Format("Mon 2006-01-02 15:04 MST")
+	}
+	return fmt.Sprintf("Environment '%s' only allows changes during its change windows (%s) and %s - use --break-glass REASON to make changes anyway",
+		e.Environment, e.Windows, next)
+}
+
+// Validate returns an error if the policy, an expression or a time zone can't be parsed
+func (c *ChangeWindows) Validate() error {
+	if c.Policy != "" && c.Policy != PolicyBlock && c.Policy != PolicyWarn {
+		return fmt.Errorf("Unknown policy '%s': must be %s or %s", c.Policy, PolicyBlock, PolicyWarn)
+	}
+	for _, s := range c.Windows {
+		if s == nil {
+			continue
+		}
+		if _, err := s.parse(); err != nil {
+			return fmt.Errorf("%s: %s", s, err.Error())
+		}
+	}
+	return nil
+}
+
+// String describes the windows (eg. business-hours: * 9-16 * * mon-fri Europe/London)
+func (c *ChangeWindows) String() string {
+	s := []string{}
+	for _, w := range c.Windows {
+		if w != nil {
+			s = append(s, w.String())
+		}
+	}
+	return strings.Join(s, ", ")
+}
+
+// Warns returns true if changes outside the windows are made with a warning rather than refused
+func (c *ChangeWindows) Warns() bool {
+	return c != nil && c.Policy == PolicyWarn
+}
+
+// Check returns an OutsideWindowError if {c} declares windows and {now} is outside all of them.  {c} may
+// be nil
+func (c *ChangeWindows) Check(env string, now time.Time) error {
+	if c == nil || len(c.Windows) == 0 {
+		return nil
+	}
+	schedules := []*cron{}
+	for _, s := range c.Windows {
+		if s == nil {
+			continue
+		}
+		parsed, err := s.parse()
+		if err != nil {
+			return fmt.Errorf("Invalid change window '%s' of environment '%s': %s", s, env, err.Error())
+		}
+		schedules = append(schedules, parsed)
+	}
+	open := func(t time.Time) bool {
+		for _, s := range schedules {
+			if s.matches(t) {
+				return true
+			}
+		}
+		return false
+	}
+	if open(now) {
+		return nil
+	}
+	next := now.Truncate(time.Minute).Add(time.Minute)
+	for end := now.Add(nextWindowSearch); next.Before(end); next = next.Add(time.Minute) {
+		if open(next) {
+			return &OutsideWindowError{Environment: env, Windows: c, Next: next}
+		}
+	}
+	return &OutsideWindowError{Environment: env, Windows: c}
+}
+
+// String describes the window (eg. business-hours: * 9-16 * * mon-fri Europe/London)
+func (s *Schedule) String() string {
+	str := s.Cron
+	if s.Timezone != "" {
+		str += " " + s.Timezone
+	}
+	if s.Name != "" {
+		str = s.Name + ": " + str
+	}
+	return str
+}
+
+// Matches returns true if the minute of {now} is matched by the expression
+func (s *Schedule) Matches(now time.Time) (bool, error) {
+	parsed, err := s.parse()
+	if err != nil {
+		return false, err
+	}
+	return parsed.matches(now), nil
+}
+
+// cron is a parsed expression: the values matched by each field and the location
+type cron struct {
+	fields []map[int]bool
+	loc    *time.Location
+}
+
+func (c *cron) matches(now time.Time) bool {
+	now = now.In(c.loc)
+	if !c.fields[0][now.Minute()] || !c.fields[1][now.Hour()] || !c.fields[3][int(now.Month())] {
+		return false
+	}
+	dom, dow := c.fields[2][now.Day()], c.fields[4][int(now.Weekday())]
+	// as with cron a day matches either field when both are restricted
+	if len(c.fields[2]) < 31 && len(c.fields[4]) < 7 {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+var (
+	cronBounds = [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	cronNames  = []map[string]int{nil, nil, nil,
+		{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12},
+		{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6},
+	}
+)
+
+func (s *Schedule) parse() (*cron, error) {
+	c := &cron{loc: time.Local}
+	if s.Timezone != "" {
+		var err error
+		if c.loc, err = time.LoadLocation(s.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	parts := strings.Fields(strings.ToLower(s.Cron))
+	if len(parts) != len(cronBounds) {
+		return nil, ErrorInvalidCron
+	}
+	for i, part := range parts {
+		values, err := parseCronField(part, cronBounds[i][0], cronBounds[i][1], cronNames[i])
+		if err != nil {
+			return nil, err
+		}
+		c.fields = append(c.fields, values)
+	}
+	// Sunday is 0 or 7
+	if c.fields[4][7] {
+		delete(c.fields[4], 7)
+		c.fields[4][0] = true
+	}
+	return c, nil
+}
+
+// Returns the values between {min} and {max} matched by the comma separated {field}
+func parseCronField(field string, min, max int, names map[string]int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n < 1 {
+				return nil, ErrorInvalidCron
+			}
+			step, item = n, item[:i]
+		}
+		from, to := min, max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if from, err = cronValue(bounds[0], names); err != nil {
+				return nil, err
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = cronValue(bounds[1], names); err != nil {
+					return nil, err
+				}
+			} else if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, ErrorInvalidCron
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func cronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[s]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, ErrorInvalidCron
+	}
+	return v, nil
+}
@@ -0,0 +1,107 @@
+// Shallow checkouts of git repositories kept up to date with their remote using the git executable
+package gitrepo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+var ErrorGitNotFound = errors.New("The git executable could not be found on the PATH")
+
+// Repo is a checkout of branch Branch (the remote's default when empty) of URL within Dir
+type Repo struct {
+	URL    string
+	Branch string
+	Dir    string
+}
+
+// Sync clones the repository when Dir isn't a checkout and otherwise fetches the branch discarding any
+// local changes.  The revision checked out is returned
+func (r *Repo) Sync() (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", ErrorGitNotFound
+	}
+
+	if _, err := os.Stat(filepath.Join(r.Dir, ".git")); os.IsNotExist(err) {
+		args := []string{"clone", "--depth", "1"}
+		if r.Branch != "" {
+			args = append(args, "--branch", r.Branch)
+		}
+		if _, err := git("", append(args, r.URL, r.Dir)...); err != nil {
+			return "", err
+		}
+	} else {
+		ref := r.Branch
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if _, err := git(r.Dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return "", err
+		}
+		if _, err := git(r.Dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+	return r.Revision()
+}
+
+// Revision returns the commit checked out
+func (r *Repo) Revision() (string, error) {
+	return git(r.Dir, "rev-parse", "HEAD")
+}
+
+// Commit commits every change within the checkout with {message} and pushes it to Branch of the remote
+// (the branch checked out when empty).  Nothing is committed when only the files {ignore} (relative to Dir)
+// changed.  The revision checked out and whether a commit was made are returned
+func (r *Repo) Commit(message string, ignore ...string) (string, bool, error) {
+	if _, err := git(r.Dir, "add", "-A"); err != nil {
+		return "", false, err
+	}
+	args := []string{"diff", "--cached", "--name-only", "--", "."}
+	for _, f := range ignore {
+		args = append(args, ":(exclude)"+filepath.ToSlash(f))
+	}
+	changed, err := git(r.Dir, args...)
+	if err != nil {
+		return "", false, err
+	}
+	if changed == "" {
+		rev, err := r.Revision()
+		return rev, false, err
+	}
+
+	commit := []string{"commit", "-q", "-m", message}
+	if _, err := git(r.Dir, "config", "user.email"); err != nil {
+		commit = append([]string{"-c", "user.name=depcon", "-c", "user.email=depcon@localhost"}, commit...)
+	}
+	if _, err := git(r.Dir, commit...); err != nil {
+		return "", false, err
+	}
+	ref := "HEAD"
+	if r.Branch != "" {
+		ref = "HEAD:refs/heads/" + r.Branch
+	}
+	if _, err := git(r.Dir, "push", "origin", ref); err != nil {
+		return "", false, err
+	}
+	rev, err := r.Revision()
+	return rev, true, err
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	// fail rather than prompt for credentials
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
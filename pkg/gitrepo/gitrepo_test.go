@@ -0,0 +1,86 @@
+package gitrepo
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Creates a repository within {dir} committing {filename}
+func commit(t *testing.T, dir, filename, content string) string {
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, filename), []byte(content), 0644))
+	for _, args := range [][]string{{"add", "-A"}, {"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-qm", filename}} {
+		_, err := git(dir, args...)
+		assert.Nil(t, err)
+	}
+	rev, _ := git(dir, "rev-parse", "HEAD")
+	return rev
+}
+
+func TestSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, _ := ioutil.TempDir("", "gitrepo")
+	defer os.RemoveAll(dir)
+
+	origin := filepath.Join(dir, "origin")
+	os.Mkdir(origin, 0755)
+	_, err := git(origin, "init", "-q")
+	assert.Nil(t, err)
+	first := commit(t, origin, "app.json", `{"id": "/web"}`)
+
+	r := &Repo{URL: "file://" + origin, Dir: filepath.Join(dir, "checkout")}
+	rev, err := r.Sync()
+	assert.Nil(t, err)
+	assert.Equal(t, first, rev)
+
+	second := commit(t, origin, "app.json", `{"id": "/web", "instances": 2}`)
+	// local changes are discarded
+	ioutil.WriteFile(filepath.Join(r.Dir, "app.json"), []byte("changed"), 0644)
+	rev, err = r.Sync()
+	assert.Nil(t, err)
+	assert.Equal(t, second, rev)
+	b, _ := ioutil.ReadFile(filepath.Join(r.Dir, "app.json"))
+	assert.Equal(t, `{"id": "/web", "instances": 2}`, string(b))
+
+	_, err = (&Repo{URL: "file://" + filepath.Join(dir, "missing"), Dir: filepath.Join(dir, "other")}).Sync()
+	assert.NotNil(t, err)
+}
+
+func TestCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, _ := ioutil.TempDir("", "gitrepo")
+	defer os.RemoveAll(dir)
+
+	origin := filepath.Join(dir, "origin")
+	os.Mkdir(origin, 0755)
+	git(origin, "init", "-q")
+	git(origin, "config", "receive.denyCurrentBranch", "ignore")
+	commit(t, origin, "app.json", `{"id": "/web"}`)
+
+	r := &Repo{URL: "file://" + origin, Dir: filepath.Join(dir, "checkout")}
+	first, err := r.Sync()
+	assert.Nil(t, err)
+
+	ioutil.WriteFile(filepath.Join(r.Dir, "manifest.yaml"), []byte("created: now"), 0644)
+	rev, committed, err := r.Commit("nightly", "manifest.yaml")
+	assert.Nil(t, err)
+	assert.False(t, committed)
+	assert.Equal(t, first, rev)
+
+	ioutil.WriteFile(filepath.Join(r.Dir, "app.json"), []byte(`{"id": "/web", "instances": 2}`), 0644)
+	rev, committed, err = r.Commit("nightly", "manifest.yaml")
+	assert.Nil(t, err)
+	assert.True(t, committed)
+	pushed, _ := git(origin, "rev-parse", "HEAD")
+	assert.Equal(t, pushed, rev)
+	message, _ := git(origin, "log", "-1", "--format=%s")
+	assert.Equal(t, "nightly", message)
+}
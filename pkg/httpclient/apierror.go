@@ -0,0 +1,54 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// APIError is an error response from the remote which wasn't otherwise classified (eg. Marathon
+// rejecting an invalid application with 422).  Marathon describes the failure with a message and
+// the offending fields
+type APIError struct {
+	Status  int          `json:"status"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// FieldError is a field of the request (eg. /container/docker/image) and why it was rejected
+type FieldError struct {
+	Path   string   `json:"path"`
+	Errors []string `json:"errors"`
+}
+
+// NewAPIError parses the error response {content} returned with {status}.  When the content isn't a
+// Marathon error document it becomes the message
+func NewAPIError(status int, content string) *APIError {
+	e := &APIError{}
+	if err := json.Unmarshal([]byte(content), e); err != nil || e.Message == "" {
+		e.Message = strings.TrimSpace(content)
+	}
+	e.Status = status
+	return e
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = ErrorMessage.Error()
+	}
+	msg = fmt.Sprintf("%s (Status %d)", msg, e.Status)
+	for _, d := range e.Details {
+		msg = fmt.Sprintf("%s\n  %s: %s", msg, d.Path, strings.Join(d.Errors, ", "))
+	}
+	return msg
+}
+
+// Err returns the error of the response.  Responses failing with a status which isn't otherwise
+// classified (ErrorMessage) return an APIError describing the remote's response
+func (r *Response) Err() error {
+	if r.Error == ErrorMessage {
+		return NewAPIError(r.Status, r.Content)
+	}
+	return r.Error
+}
@@ -0,0 +1,27 @@
+package httpclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIError(t *testing.T) {
+	e := NewAPIError(422, `{"message":"Object is not valid","details":[{"path":"/id","errors":["error.path.invalid"]}]}`)
+	assert.Equal(t, "Object is not valid", e.Message)
+	assert.Equal(t, []FieldError{{Path: "/id", Errors: []string{"error.path.invalid"}}}, e.Details)
+	assert.Equal(t, "Object is not valid (Status 422)\n  /id: error.path.invalid", e.Error())
+
+	e = NewAPIError(400, "bad request\n")
+	assert.Equal(t, "bad request", e.Message)
+	assert.Nil(t, e.Details)
+}
+
+func TestResponseErr(t *testing.T) {
+	resp := NewResponse(409, 0, `{"message":"App is locked by one or more deployments"}`, ErrorMessage)
+	assert.Equal(t, &APIError{Status: 409, Message: "App is locked by one or more deployments"}, resp.Err())
+
+	resp = NewResponse(404, 0, `{"message":"App '/web' does not exist"}`, ErrorNotFound)
+	assert.Equal(t, ErrorNotFound, resp.Err())
+	assert.Nil(t, NewResponse(200, 0, "", nil).Err())
+}
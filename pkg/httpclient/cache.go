@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultCacheTTL is how long responses without an ETag are served from the cache
+	DefaultCacheTTL = 5 * time.Second
+
+	// Responses larger than this are passed through without being cached
+	cacheMaxSize = 16 * 1024 * 1024
+)
+
+// CacheConfig caches the JSON responses of GET requests within {Dir} so repeated invocations (eg. from
+// scripts or shell completion) don't query the server each time.  Responses with an ETag are revalidated
+// with If-None-Match and others are served for {TTL}.  Any successful write to a server discards the
+// responses cached for it
+type CacheConfig struct {
+	Dir string
+	// Default: DefaultCacheTTL
+	TTL time.Duration
+}
+
+// cacheEntry is a cached response stored as JSON
+type cacheEntry struct {
+	URL    string      `json:"url"`
+	ETag   string      `json:"etag,omitempty"`
+	Stored time.Time   `json:"stored"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+type cacheTransport struct {
+	sync.Mutex
+	next   http.RoundTripper
+	config CacheConfig
+	// files stored by this transport.  Repeating a request within a process is polling for a change (eg.
+	// waiting on a deployment) so these are only ever revalidated
+	stored map[string]bool
+}
+
+func newCacheTransport(next http.RoundTripper, config *CacheConfig) *cacheTransport {
+	t := &cacheTransport{next: next, config: *config, stored: map[string]bool{}}
+	if t.config.TTL <= 0 {
+		t.config.TTL = DefaultCacheTTL
+	}
+	return t
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		resp, err := t.next.RoundTrip(req)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			t.invalidate(req)
+		}
+		return resp, err
+	}
+
+	filename := t.filename(req)
+	entry := t.load(filename)
+	if entry != nil {
+		if entry.ETag == "" && time.Since(entry.Stored) < t.config.TTL && !t.storedHere(filename) {
+			log.Debug("Cache hit - %s", req.URL)
+			return entry.response(req), nil
+		}
+		if entry.ETag != "" {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil && entry.ETag != "" {
+		drainAndClose(resp.Body)
+		log.Debug("Cache revalidated - %s", req.URL)
+		entry.Stored = time.Now()
+		t.store(filename, entry)
+		return entry.response(req), nil
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		return resp, nil
+	}
+	return t.save(filename, req, resp)
+}
+
+// Reads the body of {resp} caching it unless it's larger than cacheMaxSize in which case the body read so
+// far and the remainder are passed through
+func (t *cacheTransport) save(filename string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, cacheMaxSize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > cacheMaxSize {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	header := resp.Header.Clone()
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	t.store(filename, &cacheEntry{
+		URL:    req.URL.String(),
+		ETag:   resp.Header.Get("ETag"),
+		Stored: time.Now(),
+		Status: resp.StatusCode,
+		Header: header,
+		Body:   body,
+	})
+	return resp, nil
+}
+
+// Discards the responses cached for the server {req} was sent to
+func (t *cacheTransport) invalidate(req *http.Request) {
+	if err := os.RemoveAll(t.originDir(req)); err != nil {
+		log.Debug("Error clearing cache: %s", err.Error())
+	}
+}
+
+func (t *cacheTransport) load(filename string) *cacheEntry {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(b, entry); err != nil {
+		return nil
+	}
+	return entry
+}
+
+func (t *cacheTransport) store(filename string, entry *cacheEntry) {
+	t.Lock()
+	t.stored[filename] = true
+	t.Unlock()
+
+	b, err := json.Marshal(entry)
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(filename), 0700); err == nil {
+			err = ioutil.WriteFile(filename, b, 0600)
+		}
+	}
+	if err != nil {
+		log.Debug("Error caching response: %s", err.Error())
+	}
+}
+
+func (t *cacheTransport) storedHere(filename string) bool {
+	t.Lock()
+	defer t.Unlock()
+	return t.stored[filename]
+}
+
+// Responses are cached per server and keyed by the URL and credentials so users sharing a cache
+// directory never see each other's responses
+func (t *cacheTransport) filename(req *http.Request) string {
+	return filepath.Join(t.originDir(req), hash(req.URL.String(), req.Header.Get("Authorization")))
+}
+
+func (t *cacheTransport) originDir(req *http.Request) string {
+	return filepath.Join(t.config.Dir, hash(req.URL.Scheme, req.URL.Host))
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(e.Status),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func hash(values ...string) string {
+	h := sha256.New()
+	for _, v := range values {
+		h.Write([]byte(v))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheResult struct {
+	Version int `json:"version"`
+}
+
+func cacheServer(etag string, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if etag != "" {
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"version": 1}`))
+	}))
+}
+
+// Returns a func creating a new client (as each invocation of depcon would) sharing a cache directory
+func cacheClients(t *testing.T) (func() *HttpClient, func()) {
+	dir, err := ioutil.TempDir("", "cache")
+	assert.Nil(t, err)
+	newClient := func() *HttpClient {
+		return NewHttpClient(HttpClientConfig{RequestTimeout: 30, Cache: &CacheConfig{Dir: dir}})
+	}
+	return newClient, func() { os.RemoveAll(dir) }
+}
+
+func TestCacheTTL(t *testing.T) {
+	hits := 0
+	s := cacheServer("", &hits)
+	defer s.Close()
+	newClient, cleanup := cacheClients(t)
+	defer cleanup()
+
+	client := newClient()
+	result := &cacheResult{}
+	assert.Nil(t, client.HttpGet(s.URL+"/v2/apps", result).Error)
+	assert.Equal(t, 1, result.Version)
+
+	// a second invocation (a new client) is served from the cache
+	other := newClient()
+	result = &cacheResult{}
+	assert.Nil(t, other.HttpGet(s.URL+"/v2/apps", result).Error)
+	assert.Equal(t, 1, result.Version)
+	assert.Equal(t, 1, hits)
+
+	// requests repeated by the same client are polling so they always reach the server
+	assert.Nil(t, client.HttpGet(s.URL+"/v2/apps", nil).Error)
+	assert.Equal(t, 2, hits)
+
+	// writes discard the cached responses of the server
+	assert.Nil(t, other.HttpPost(s.URL+"/v2/apps", nil, nil).Error)
+	assert.Nil(t, newClient().HttpGet(s.URL+"/v2/apps", nil).Error)
+	assert.Equal(t, 4, hits)
+}
+
+func TestCacheETag(t *testing.T) {
+	hits := 0
+	s := cacheServer(`"v1"`, &hits)
+	defer s.Close()
+	newClient, cleanup := cacheClients(t)
+	defer cleanup()
+
+	assert.Nil(t, newClient().HttpGet(s.URL+"/v2/apps", nil).Error)
+	result := &cacheResult{}
+	resp := newClient().HttpGet(s.URL+"/v2/apps", result)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, 200, resp.Status)
+	assert.Equal(t, 1, result.Version)
+	// responses with an ETag are always revalidated
+	assert.Equal(t, 2, hits)
+}
+
+func TestNoCache(t *testing.T) {
+	hits := 0
+	s := cacheServer("", &hits)
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, NewHttpClient(HttpClientConfig{RequestTimeout: 30}).HttpGet(s.URL, nil).Error)
+	}
+	assert.Equal(t, 2, hits)
+}
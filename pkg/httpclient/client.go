@@ -2,19 +2,21 @@
 package httpclient
 
 import (
-	"crypto/tls"
+	"context"
 	"errors"
 	"github.com/ContainX/depcon/pkg/encoding"
 	"github.com/ContainX/depcon/pkg/logger"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 )
 
-var log = logger.GetLogger("client")
+// LogCategory is the logger of the client.  API calls are logged with their timing at INFO
+const LogCategory = "client"
+
+var log = logger.GetLogger(LogCategory)
 
 type Response struct {
 	Status  int
@@ -24,6 +26,8 @@ type Response struct {
 }
 
 type Request struct {
+	// Optional context cancelling the request.  Default: context.Background()
+	ctx context.Context
 	// Http Method type
 	method Method
 	// Complete URL including params
@@ -34,6 +38,8 @@ type Request struct {
 	result interface{}
 	// encoding type (optional : default JSON)
 	encodingType encoding.EncoderType
+	// Optional headers of this request set before authentication
+	headers map[string]string
 }
 
 type HttpClientConfig struct {
@@ -46,11 +52,55 @@ type HttpClientConfig struct {
 	RequestTimeout int
 	// TLS Insecure Skip Verify
 	TLSInsecureSkipVerify bool
+	// Optional token based authentication used in place of Http Basic Auth
+	Authenticator Authenticator
+	// Optional proxies used in place of the proxy environment variables
+	Proxy *ProxyConfig
+	// Optional client certificate and CA bundle for mutual TLS
+	TLS *TLSConfig
+	// If true only GET requests are permitted
+	ReadOnly bool
+	// Optional policy for retrying failed requests
+	Retry *RetryPolicy
+	// Optional connect, TLS handshake, response header and overall timeouts.  Timeouts.Request
+	// takes precedence over RequestTimeout when set
+	Timeouts *Timeouts
+	// Optional base URLs of equivalent servers (eg. each node of an HA cluster).  Requests made to any of
+	// them are sent to the active endpoint failing over to the next when a connection can't be established
+	Endpoints []string
+	// Optional maximum number of requests sent per second.  Zero is unlimited
+	RateLimit float64
+	// If true request bodies of 8KB or more are gzip compressed.  Responses are always requested with
+	// gzip and decompressed transparently
+	Compress bool
+	// Optional static headers added to every request (eg. gateway or tenant headers)
+	Headers map[string]string
+	// Optional cache of GET responses.  Nothing is cached when nil
+	Cache *CacheConfig
+	// If true requests are RPC style calls sent as POST whether or not they make changes (eg. the Mesos
+	// operator API) so the client checks writes with CheckWrite rather than every POST being checked
+	RPC bool
+	// Optional callbacks receiving every request and its status and latency (eg. to export metrics)
+	Hooks *Hooks
+}
+
+// Authenticator supplies tokens for token based authentication schemes (eg. DC/OS ACS)
+type Authenticator interface {
+	// Returns the current token.  If {refresh} is true any cached token must be discarded and
+	// a new one obtained
+	Token(refresh bool) (string, error)
+
+	// Applies the token to the request
+	Apply(req *http.Request, token string)
 }
 
 type HttpClient struct {
 	config HttpClientConfig
 	http   *http.Client
+	// error raised while configuring the client (eg. unreadable certificates) returned by every request
+	err error
+	// set when multiple endpoints are configured
+	failover *failoverTransport
 }
 
 var (
@@ -64,6 +114,8 @@ var (
 	ErrorNotAuthorized = errors.New("Not Authorized to perform this action - Status: 403")
 	// Not Authenticated
 	ErrorNotAuthenticated = errors.New("Not Authenticated to perform this action - Status: 401")
+	// Write request against a read-only client
+	ErrorReadOnly = errors.New("The environment is read-only - use --allow-write to make changes")
 )
 
 func NewDefaultConfig() *HttpClientConfig {
@@ -78,45 +130,108 @@ func NewHttpClient(config HttpClientConfig) *HttpClient {
 	hc := &HttpClient{
 		config: config,
 		http: &http.Client{
-			Timeout: (time.Duration(config.RequestTimeout) * time.Second),
+			Timeout: requestTimeout(&config),
 		},
 	}
-	if config.TLSInsecureSkipVerify {
-		tr := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	if tr, err := sharedTransport(&config); err != nil {
+		hc.err = err
+	} else {
+		var rt http.RoundTripper = tr
+		if TracingEnabled() {
+			rt = &tracingTransport{next: rt}
+		}
+		if config.RateLimit > 0 {
+			rt = newRateLimitTransport(rt, config.RateLimit)
+		}
+		rt = newLeaderTransport(rt)
+		if len(config.Endpoints) > 1 {
+			hc.failover = newFailoverTransport(rt, config.Endpoints)
+			rt = hc.failover
+		}
+		if config.Cache != nil && config.Cache.Dir != "" {
+			rt = newCacheTransport(rt, config.Cache)
 		}
-		hc.http.Transport = tr
+		if config.Hooks != nil {
+			rt = newHooksTransport(rt, config.Hooks)
+		}
+		hc.http.Transport = rt
 	}
 	return hc
 }
 
+// Returns the overall timeout for a request.  Timeouts.Request takes precedence over RequestTimeout
+// and NoTimeout disables the limit
+func requestTimeout(config *HttpClientConfig) time.Duration {
+	if t := config.Timeouts; t != nil && t.Request != 0 {
+		if t.Request < 0 {
+			return 0
+		}
+		return time.Duration(t.Request)
+	}
+	return time.Duration(config.RequestTimeout) * time.Second
+}
+
 func NewResponse(status int, elapsed time.Duration, content string, err error) *Response {
 	return &Response{Status: status, Elapsed: elapsed, Content: content, Error: err}
 }
 
 func (h *HttpClient) HttpGet(url string, result interface{}) *Response {
-	return h.invoke(&Request{method: GET, url: url, result: result})
+	return h.HttpGetCtx(context.Background(), url, result)
 }
 
 func (h *HttpClient) HttpPut(url string, data interface{}, result interface{}) *Response {
-	return h.httpCall(PUT, url, data, result)
+	return h.HttpPutCtx(context.Background(), url, data, result)
 }
 
 func (h *HttpClient) HttpDelete(url string, data interface{}, result interface{}) *Response {
-	return h.httpCall(DELETE, url, data, result)
+	return h.HttpDeleteCtx(context.Background(), url, data, result)
 }
 
 func (h *HttpClient) HttpPost(url string, data interface{}, result interface{}) *Response {
-	return h.httpCall(POST, url, data, result)
+	return h.HttpPostCtx(context.Background(), url, data, result)
+}
+
+// HttpGetCtx performs a GET request which is abandoned (along with any retries) when {ctx} is done
+func (h *HttpClient) HttpGetCtx(ctx context.Context, url string, result interface{}) *Response {
+	return h.invoke(&Request{ctx: ctx, method: GET, url: url, result: result})
 }
 
-func (h *HttpClient) httpCall(method Method, url string, data interface{}, result interface{}) *Response {
+// HttpPutCtx performs a PUT request which is abandoned (along with any retries) when {ctx} is done
+func (h *HttpClient) HttpPutCtx(ctx context.Context, url string, data interface{}, result interface{}) *Response {
+	return h.httpCall(ctx, PUT, url, data, result)
+}
+
+// HttpDeleteCtx performs a DELETE request which is abandoned (along with any retries) when {ctx} is done
+func (h *HttpClient) HttpDeleteCtx(ctx context.Context, url string, data interface{}, result interface{}) *Response {
+	return h.httpCall(ctx, DELETE, url, data, result)
+}
+
+// HttpPostCtx performs a POST request which is abandoned (along with any retries) when {ctx} is done
+func (h *HttpClient) HttpPostCtx(ctx context.Context, url string, data interface{}, result interface{}) *Response {
+	return h.httpCall(ctx, POST, url, data, result)
+}
+
+// Performs a POST request with additional {headers} (eg. the action of RPC style APIs such as AWS).  The
+// headers are set before authentication so they may be signed
+func (h *HttpClient) HttpPostWithHeaders(url string, headers map[string]string, data interface{}, result interface{}) *Response {
+	r := &Request{
+		method:  POST,
+		url:     url,
+		data:    h.convertBody(data),
+		result:  result,
+		headers: headers,
+	}
+	return h.invoke(r)
+}
+
+func (h *HttpClient) httpCall(ctx context.Context, method Method, url string, data interface{}, result interface{}) *Response {
 	var body string
 	if data != nil {
 		body = h.convertBody(data)
 	}
 
 	r := &Request{
+		ctx:    ctx,
 		method: method,
 		url:    url,
 		data:   body,
@@ -129,39 +244,98 @@ func (h *HttpClient) httpCall(method Method, url string, data interface{}, resul
 // Creates a net/http Request and associates default headers and authentication
 // parameters
 func (h *HttpClient) CreateHttpRequest(method, urlStr string, body io.Reader) (*http.Request, error) {
-	request, err := http.NewRequest(method, urlStr, body)
+	return h.createHttpRequest(context.Background(), method, urlStr, body, nil)
+}
+
+func (h *HttpClient) createHttpRequest(ctx context.Context, method, urlStr string, body io.Reader, headers map[string]string) (*http.Request, error) {
+	if h.err != nil {
+		return nil, h.err
+	}
+	request, err := http.NewRequestWithContext(ctx, method, urlStr, body)
 	if err != nil {
 		return nil, err
 	}
 
 	AddDefaultHeaders(request)
-	AddAuthentication(h.config, request)
+	for name, value := range h.config.Headers {
+		request.Header.Set(name, value)
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	if err := AddAuthentication(h.config, request); err != nil {
+		return nil, err
+	}
 
 	return request, nil
 }
 
 func (h *HttpClient) invoke(r *Request) *Response {
+	if h.config.ReadOnly && r.method != GET {
+		return &Response{Error: ErrorReadOnly}
+	}
+	if r.method != GET && !h.config.RPC {
+		if err := CheckWrite(r.method.String(), r.url); err != nil {
+			return &Response{Error: err}
+		}
+	}
+	if r.ctx == nil {
+		r.ctx = context.Background()
+	}
+	return h.retry(r.ctx, r.method, func() *Response { return h.invokeAuthenticated(r) })
+}
+
+func (h *HttpClient) invokeAuthenticated(r *Request) *Response {
+	resp := h.invokeOnce(r)
+
+	// tokens may expire mid-session so a single retry is made with a fresh token
+	if resp.Status == 401 && h.config.Authenticator != nil {
+		log.Debug("Received 401 - refreshing authentication token")
+		if _, err := h.config.Authenticator.Token(true); err != nil {
+			return NewResponse(resp.Status, resp.Elapsed, resp.Content, err)
+		}
+		return h.invokeOnce(r)
+	}
+	return resp
+}
+
+func (h *HttpClient) invokeOnce(r *Request) *Response {
 
 	log.Debug("%s - %s, Body:\n%s", r.method.String(), r.url, r.data)
 
-	request, err := h.CreateHttpRequest(r.method.String(), r.url, strings.NewReader(r.data))
+	body, contentEncoding := h.requestBody(r.data)
+	request, err := h.createHttpRequest(r.ctx, r.method.String(), r.url, body, r.headers)
 
 	if err != nil {
 		return &Response{Error: err}
 	}
+	if contentEncoding != "" {
+		request.Header.Set("Content-Encoding", contentEncoding)
+	}
 
 	req_start := time.Now()
 	response, err := h.http.Do(request)
 	req_elapsed := time.Now().Sub(req_start)
+	logCall(request, response, err, req_elapsed)
 
 	if err != nil {
 		return NewResponse(0, req_elapsed, "", err)
 	}
 
 	status := response.StatusCode
+	defer drainAndClose(response.Body)
+
+	// decode successful responses directly from the body so large documents (eg. group trees)
+	// are not held in memory twice.  When debugging the raw content is buffered so it can be logged
+	if status >= 200 && status < 300 && r.result != nil && !logger.IsEnabled(logger.DEBUG, "client") {
+		if err := h.decode(r, response.Body); err != nil && err != io.EOF {
+			log.Debug("Error decoding response: %s", err.Error())
+		}
+		return NewResponse(status, req_elapsed, "", nil)
+	}
+
 	var content string
 	if response.ContentLength != 0 {
-		defer response.Body.Close()
 		rc, err := ioutil.ReadAll(response.Body)
 		if err != nil {
 			return NewResponse(status, req_elapsed, "", err)
@@ -178,18 +352,150 @@ func (h *HttpClient) invoke(r *Request) *Response {
 		return NewResponse(status, req_elapsed, content, nil)
 	}
 
+	return NewResponse(status, req_elapsed, content, errorForStatus(status))
+}
+
+// Performs a GET request handing the response body for successful requests to {fn} as it is read
+// from the wire.  Useful for rendering large collections incrementally
+func (h *HttpClient) HttpGetStream(url string, fn func(body io.Reader) error) *Response {
+	return h.HttpGetStreamCtx(context.Background(), url, fn)
+}
+
+// HttpGetStreamCtx performs HttpGetStream abandoning the request when {ctx} is done
+func (h *HttpClient) HttpGetStreamCtx(ctx context.Context, url string, fn func(body io.Reader) error) *Response {
+	return h.HttpGetStreamWithHeadersCtx(ctx, url, nil, fn)
+}
+
+// HttpGetStreamWithHeadersCtx performs HttpGetStreamCtx with additional {headers} (eg. an Accept header
+// for content other than JSON)
+func (h *HttpClient) HttpGetStreamWithHeadersCtx(ctx context.Context, url string, headers map[string]string, fn func(body io.Reader) error) *Response {
+	return h.retry(ctx, GET, func() *Response { return h.httpGetStreamAuthenticated(ctx, url, headers, fn) })
+}
+
+// HttpPutRawCtx performs a PUT request sending {body} as is (eg. a multipart upload) along with additional
+// {headers} such as its Content-Type
+func (h *HttpClient) HttpPutRawCtx(ctx context.Context, url string, headers map[string]string, body string, result interface{}) *Response {
+	r := &Request{
+		ctx:     ctx,
+		method:  PUT,
+		url:     url,
+		data:    body,
+		result:  result,
+		headers: headers,
+	}
+	return h.invoke(r)
+}
+
+// HttpGetEventStreamCtx opens the server-sent event stream at {url} handing the body to {fn} as it's
+// received.  Unlike other requests the stream isn't limited by the request timeout and lasts until {fn}
+// returns, the server closes it or {ctx} is done.  {lastEventID} asks the server to resume the stream
+// after that event
+func (h *HttpClient) HttpGetEventStreamCtx(ctx context.Context, url, lastEventID string, fn func(body io.Reader) error) *Response {
+	resp := h.httpGetEventStream(ctx, url, lastEventID, fn)
+	if resp.Status == 401 && h.config.Authenticator != nil {
+		if _, err := h.config.Authenticator.Token(true); err != nil {
+			return NewResponse(resp.Status, resp.Elapsed, resp.Content, err)
+		}
+		return h.httpGetEventStream(ctx, url, lastEventID, fn)
+	}
+	return resp
+}
+
+func (h *HttpClient) httpGetEventStream(ctx context.Context, url, lastEventID string, fn func(body io.Reader) error) *Response {
+	log.Debug("%s - %s (event stream)", GET.String(), url)
+
+	headers := map[string]string{"Accept": "text/event-stream"}
+	if lastEventID != "" {
+		headers["Last-Event-ID"] = lastEventID
+	}
+	request, err := h.createHttpRequest(ctx, GET.String(), url, nil, headers)
+	if err != nil {
+		return &Response{Error: err}
+	}
+
+	client := *h.http
+	client.Timeout = 0
+	response, err := client.Do(request)
+	if err != nil {
+		return NewResponse(0, 0, "", err)
+	}
+	defer response.Body.Close()
+
+	status := response.StatusCode
+	if status >= 200 && status < 300 {
+		return NewResponse(status, 0, "", fn(response.Body))
+	}
+	rc, _ := ioutil.ReadAll(response.Body)
+	return NewResponse(status, 0, string(rc), errorForStatus(status))
+}
+
+func (h *HttpClient) httpGetStreamAuthenticated(ctx context.Context, url string, headers map[string]string, fn func(body io.Reader) error) *Response {
+	resp := h.httpGetStream(ctx, url, headers, fn)
+	if resp.Status == 401 && h.config.Authenticator != nil {
+		if _, err := h.config.Authenticator.Token(true); err != nil {
+			return NewResponse(resp.Status, resp.Elapsed, resp.Content, err)
+		}
+		return h.httpGetStream(ctx, url, headers, fn)
+	}
+	return resp
+}
+
+func (h *HttpClient) httpGetStream(ctx context.Context, url string, headers map[string]string, fn func(body io.Reader) error) *Response {
+	log.Debug("%s - %s (stream)", GET.String(), url)
+
+	request, err := h.createHttpRequest(ctx, GET.String(), url, nil, headers)
+	if err != nil {
+		return &Response{Error: err}
+	}
+
+	req_start := time.Now()
+	response, err := h.http.Do(request)
+	req_elapsed := time.Now().Sub(req_start)
+	logCall(request, response, err, req_elapsed)
+	if err != nil {
+		return NewResponse(0, req_elapsed, "", err)
+	}
+	defer drainAndClose(response.Body)
+
+	status := response.StatusCode
+	if status >= 200 && status < 300 {
+		return NewResponse(status, req_elapsed, "", fn(response.Body))
+	}
+
+	rc, _ := ioutil.ReadAll(response.Body)
+	return NewResponse(status, req_elapsed, string(rc), errorForStatus(status))
+}
+
+// Logs the API call {req} with its status and timing
+func logCall(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	elapsed = elapsed.Round(time.Millisecond)
+	if err != nil {
+		log.Info("%s %s failed after %s: %s", req.Method, redactURL(req.URL), elapsed, err.Error())
+		return
+	}
+	log.Info("%s %s %d (%s)", req.Method, redactURL(req.URL), resp.StatusCode, elapsed)
+}
+
+func errorForStatus(status int) error {
 	switch status {
 	case 500:
-		return NewResponse(status, req_elapsed, content, ErrorInvalidResponse)
+		return ErrorInvalidResponse
 	case 404:
-		return NewResponse(status, req_elapsed, content, ErrorNotFound)
+		return ErrorNotFound
 	case 403:
-		return NewResponse(status, req_elapsed, content, ErrorNotAuthorized)
+		return ErrorNotAuthorized
 	case 401:
-		return NewResponse(status, req_elapsed, content, ErrorNotAuthenticated)
+		return ErrorNotAuthenticated
 	}
+	return ErrorMessage
+}
 
-	return NewResponse(status, req_elapsed, content, ErrorMessage)
+func (h *HttpClient) decode(r *Request, body io.Reader) error {
+	et := encoding.JSON
+	if r.encodingType != 0 {
+		et = r.encodingType
+	}
+	return encoding.NewStreamDecoder(et, body).Decode(r.result)
 }
 
 func (h *HttpClient) convertBody(data interface{}) string {
@@ -216,10 +522,19 @@ func AddDefaultHeaders(req *http.Request) {
 	req.Header.Add("Accept", "application/json")
 }
 
-func AddAuthentication(c HttpClientConfig, req *http.Request) {
+func AddAuthentication(c HttpClientConfig, req *http.Request) error {
+	if c.Authenticator != nil {
+		token, err := c.Authenticator.Token(false)
+		if err != nil {
+			return err
+		}
+		c.Authenticator.Apply(req, token)
+		return nil
+	}
 	if c.HttpUser != "" {
 		req.SetBasicAuth(c.HttpUser, c.HttpPass)
 	}
+	return nil
 }
 
 func (h *HttpClient) Unwrap() *http.Client {
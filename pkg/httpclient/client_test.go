@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyRefusesWrites(t *testing.T) {
+	writes := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			writes++
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, ReadOnly: true})
+	result := map[string]interface{}{}
+
+	assert.Nil(t, client.HttpGet(s.URL+"/v2/apps", &result).Error)
+	assert.Equal(t, ErrorReadOnly, client.HttpPost(s.URL+"/v2/apps", map[string]string{"id": "app"}, &result).Error)
+	assert.Equal(t, ErrorReadOnly, client.HttpDelete(s.URL+"/v2/apps/app", nil, &result).Error)
+	assert.Equal(t, 0, writes)
+}
+
+func TestCustomHeaders(t *testing.T) {
+	var tenant string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Tenant")
+	}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Headers: map[string]string{"X-Tenant": "payments"}})
+	assert.Nil(t, client.HttpGet(s.URL, nil).Error)
+	assert.Equal(t, "payments", tenant)
+}
+
+type headerRecorder struct {
+	target string
+}
+
+func (a *headerRecorder) Token(refresh bool) (string, error) {
+	return "token", nil
+}
+
+func (a *headerRecorder) Apply(req *http.Request, token string) {
+	a.target = req.Header.Get("X-Amz-Target")
+}
+
+func TestRequestHeadersPrecedeAuthentication(t *testing.T) {
+	var contentType string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		fmt.Fprint(w, `{}`)
+	}))
+	defer s.Close()
+
+	auth := &headerRecorder{}
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Authenticator: auth})
+	headers := map[string]string{"X-Amz-Target": "Service.Action", "Content-Type": "application/x-amz-json-1.1"}
+	assert.Nil(t, client.HttpPostWithHeaders(s.URL, headers, map[string]string{}, nil).Error)
+	assert.Equal(t, "Service.Action", auth.target)
+	assert.Equal(t, "application/x-amz-json-1.1", contentType)
+}
+
+func TestWriteGuard(t *testing.T) {
+	frozen := errors.New("frozen")
+	SetWriteGuard(func(method, url string) error { return frozen })
+	defer SetWriteGuard(nil)
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30})
+	assert.Nil(t, client.HttpGet(s.URL, nil).Error)
+	assert.Equal(t, frozen, client.HttpPost(s.URL, nil, nil).Error)
+	assert.Equal(t, frozen, client.HttpDelete(s.URL, nil, nil).Error)
+
+	// RPC clients check writes themselves
+	rpc := NewHttpClient(HttpClientConfig{RequestTimeout: 30, RPC: true})
+	assert.Nil(t, rpc.HttpPost(s.URL, nil, nil).Error)
+	assert.Equal(t, frozen, CheckWrite("POST", s.URL))
+}
+
+func TestAddWriteGuard(t *testing.T) {
+	frozen, denied := errors.New("frozen"), errors.New("denied")
+	SetWriteGuard(func(method, url string) error { return frozen })
+	defer SetWriteGuard(nil)
+
+	AddWriteGuard(func(method, url string) error {
+		if url == "http://prod" {
+			return denied
+		}
+		return nil
+	})
+	assert.Equal(t, denied, CheckWrite("POST", "http://prod"))
+	assert.Equal(t, frozen, CheckWrite("POST", "http://dev"))
+}
+
+func TestContextStopsRetries(t *testing.T) {
+	attempts := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Retry: DefaultRetryPolicy()})
+	resp := client.HttpGetCtx(ctx, s.URL, nil)
+	assert.Equal(t, 1, attempts)
+	assert.True(t, errors.Is(resp.Error, context.Canceled))
+
+	resp = client.HttpGetCtx(ctx, s.URL, nil)
+	assert.Equal(t, 1, attempts, "a cancelled request should not be sent")
+	assert.True(t, errors.Is(resp.Error, context.Canceled))
+}
@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SchemeUnix     = "unix"
+	SchemeSSHHTTP  = "ssh+http"
+	SchemeSSHHTTPS = "ssh+https"
+)
+
+var (
+	ErrInvalidSSHEndpoint = errors.New("SSH endpoints must be ssh+http://[user@]bastion[:port]/host:port[/path]")
+
+	// command used to open SSH tunnels.  The user's ssh configuration, agent and known hosts apply
+	sshCommand = "ssh"
+
+	// dialers for hosts which are not reached over TCP, keyed by host:port
+	dialers = struct {
+		sync.RWMutex
+		hosts map[string]dialFunc
+	}{hosts: make(map[string]dialFunc)}
+)
+
+type dialFunc func(ctx context.Context) (net.Conn, error)
+
+// ResolveEndpoint converts endpoints which aren't reached over TCP into an http(s) URL and registers how
+// connections to it are made.  Supported schemes:
+//
+//	unix:///var/run/marathon.sock                      - unix domain socket
+//	ssh+http://user@bastion:22/marathon.internal:8080  - tunneled through the bastion with ssh
+//	ssh+https://bastion/marathon.internal:8443/prefix  - as above using https to Marathon
+//
+// Other URLs are returned unchanged
+func ResolveEndpoint(rawurl string) (string, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl, nil
+	}
+
+	switch u.Scheme {
+	case SchemeUnix:
+		if u.Path == "" {
+			return "", fmt.Errorf("'%s' must include the socket path (eg. unix:///var/run/marathon.sock)", rawurl)
+		}
+		socket := u.Path
+		host := socketHost(socket)
+		registerDialer(host+":80", func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		})
+		return "http://" + host, nil
+
+	case SchemeSSHHTTP, SchemeSSHHTTPS:
+		parts := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 2)
+		target := parts[0]
+		if u.Host == "" || target == "" {
+			return "", ErrInvalidSSHEndpoint
+		}
+		scheme := strings.TrimPrefix(u.Scheme, "ssh+")
+		if _, _, err := net.SplitHostPort(target); err != nil {
+			if scheme == "https" {
+				target += ":443"
+			} else {
+				target += ":80"
+			}
+		}
+		args := sshArgs(u, target)
+		registerDialer(target, func(ctx context.Context) (net.Conn, error) {
+			return dialSSH(ctx, args, target)
+		})
+		base := scheme + "://" + target
+		if len(parts) == 2 && parts[1] != "" {
+			base += "/" + parts[1]
+		}
+		return base, nil
+	}
+	return rawurl, nil
+}
+
+// Returns the host and port requests for {u} are sent to
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
+
+// Synthetic host name used for requests sent over the unix socket {path}
+func socketHost(path string) string {
+	h := fnv.New32a()
+	h.Write([]byte(path))
+	return fmt.Sprintf("unix-%08x.sock", h.Sum32())
+}
+
+// Returns the ssh arguments forwarding stdin/stdout to {target} via the bastion within {u}
+func sshArgs(u *url.URL, target string) []string {
+	args := []string{"-o", "BatchMode=yes", "-W", target}
+	if port := u.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	if u.User != nil && u.User.Username() != "" {
+		args = append(args, "-l", u.User.Username())
+	}
+	return append(args, u.Hostname())
+}
+
+func registerDialer(hostport string, dial dialFunc) {
+	dialers.Lock()
+	defer dialers.Unlock()
+	dialers.hosts[hostport] = dial
+}
+
+func lookupDialer(hostport string) dialFunc {
+	dialers.RLock()
+	defer dialers.RUnlock()
+	return dialers.hosts[hostport]
+}
+
+// Wraps {next} so hosts with a registered dialer are connected to using it
+func withDialers(next func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if dial := lookupDialer(addr); dial != nil {
+			return dial(ctx)
+		}
+		return next(ctx, network, addr)
+	}
+}
+
+// Opens a connection to {target} through an ssh process
+func dialSSH(ctx context.Context, args []string, target string) (net.Conn, error) {
+	cmd := exec.Command(sshCommand, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &strings.Builder{}
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return nil, &net.OpError{Op: "dial", Net: "ssh", Err: err}
+	}
+	log.Debug("Opened SSH tunnel: %s %s", sshCommand, strings.Join(args, " "))
+	return &commandConn{cmd: cmd, stdin: stdin, stdout: stdout, stderr: stderr, target: target}, nil
+}
+
+// commandConn is a net.Conn over the stdin and stdout of a process
+type commandConn struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	stderr *strings.Builder
+	target string
+	once   sync.Once
+}
+
+func (c *commandConn) Read(b []byte) (int, error) {
+	n, err := c.stdout.Read(b)
+	if err == io.EOF && n == 0 && c.stderr.Len() > 0 {
+		return 0, fmt.Errorf("ssh: %s", strings.TrimSpace(c.stderr.String()))
+	}
+	return n, err
+}
+
+func (c *commandConn) Write(b []byte) (int, error) {
+	return c.stdin.Write(b)
+}
+
+func (c *commandConn) Close() error {
+	c.once.Do(func() {
+		c.stdin.Close()
+		c.cmd.Process.Kill()
+		c.cmd.Wait()
+	})
+	return nil
+}
+
+func (c *commandConn) LocalAddr() net.Addr  { return commandAddr("ssh") }
+func (c *commandConn) RemoteAddr() net.Addr { return commandAddr(c.target) }
+
+// Deadlines are not supported by pipes.  Timeouts are enforced by the transport closing the connection
+func (c *commandConn) SetDeadline(t time.Time) error      { return nil }
+func (c *commandConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *commandConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type commandAddr string
+
+func (a commandAddr) Network() string { return "ssh" }
+func (a commandAddr) String() string  { return string(a) }
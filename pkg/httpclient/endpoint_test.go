@@ -0,0 +1,74 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnixSocketEndpoint(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon")
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "marathon.sock")
+
+	l, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+	s := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"path": "%s"}`, r.URL.Path)
+	})}
+	go s.Serve(l)
+	defer s.Close()
+
+	base, err := ResolveEndpoint("unix://" + socket)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://"+socketHost(socket), base)
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30})
+	result := map[string]string{}
+	assert.Nil(t, client.HttpGet(base+"/v2/apps", &result).Error)
+	assert.Equal(t, "/v2/apps", result["path"])
+}
+
+func TestSSHEndpoint(t *testing.T) {
+	base, err := ResolveEndpoint("ssh+https://ops@bastion:2222/marathon.internal/prefix")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://marathon.internal:443/prefix", base)
+	assert.NotNil(t, lookupDialer("marathon.internal:443"))
+
+	u, _ := url.Parse("ssh+http://ops@bastion:2222/marathon.internal:8080")
+	assert.Equal(t, []string{"-o", "BatchMode=yes", "-W", "marathon.internal:8080", "-p", "2222", "-l", "ops", "bastion"}, sshArgs(u, "marathon.internal:8080"))
+
+	_, err = ResolveEndpoint("ssh+http://bastion")
+	assert.Equal(t, ErrInvalidSSHEndpoint, err)
+
+	base, err = ResolveEndpoint("http://marathon:8080")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://marathon:8080", base)
+}
+
+func TestSSHTunnelCommand(t *testing.T) {
+	// cat stands in for ssh echoing back whatever is written to the tunnel
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+	sshCommand = "cat"
+	defer func() { sshCommand = "ssh" }()
+
+	conn, err := dialSSH(context.Background(), []string{}, "marathon:8080")
+	assert.Nil(t, err)
+	defer conn.Close()
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	n, _ := conn.Read(buf)
+	assert.Equal(t, "ping", string(buf[:n]))
+	assert.Equal(t, "marathon:8080", conn.RemoteAddr().String())
+}
@@ -0,0 +1,147 @@
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// failoverTransport sends requests made to any of the endpoints to the active endpoint.  When a
+// connection can not be established the next endpoint is tried and becomes active if it succeeds
+type failoverTransport struct {
+	sync.Mutex
+	next      http.RoundTripper
+	endpoints []string
+	active    int
+}
+
+func newFailoverTransport(next http.RoundTripper, endpoints []string) *failoverTransport {
+	t := &failoverTransport{next: next}
+	for _, e := range endpoints {
+		t.endpoints = append(t.endpoints, strings.TrimRight(e, "/"))
+	}
+	return t
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path, ok := t.relative(req.URL.String())
+	if !ok {
+		return t.next.RoundTrip(req)
+	}
+
+	start := t.current()
+	var lastErr error
+	for i := 0; i < len(t.endpoints); i++ {
+		idx := (start + i) % len(t.endpoints)
+		r, err := rewriteRequest(req, t.endpoints[idx]+path, i > 0)
+		if err != nil {
+			if lastErr != nil {
+				// the body can't be replayed so the connection error is reported
+				return nil, lastErr
+			}
+			return nil, err
+		}
+		resp, err := t.next.RoundTrip(r)
+		if err == nil {
+			if idx != start {
+				log.Warning("Failed over to %s", t.endpoints[idx])
+				t.setActive(idx)
+			}
+			return resp, nil
+		}
+		if !canFailover(req.Method, err) {
+			return nil, err
+		}
+		log.Debug("%s is unavailable: %s", t.endpoints[idx], err.Error())
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// Returns the remainder of {rawurl} following the endpoint it targets
+func (t *failoverTransport) relative(rawurl string) (string, bool) {
+	for _, e := range t.endpoints {
+		if strings.HasPrefix(rawurl, e) {
+			rest := rawurl[len(e):]
+			if rest == "" || rest[0] == '/' || rest[0] == '?' {
+				return rest, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (t *failoverTransport) current() int {
+	t.Lock()
+	defer t.Unlock()
+	return t.active
+}
+
+func (t *failoverTransport) setActive(idx int) {
+	t.Lock()
+	defer t.Unlock()
+	t.active = idx
+}
+
+// Makes {endpoint} the active endpoint.  Returns false if it is not one of the endpoints
+func (t *failoverTransport) activate(endpoint string) bool {
+	endpoint = strings.TrimRight(endpoint, "/")
+	for idx, e := range t.endpoints {
+		if e == endpoint {
+			t.setActive(idx)
+			return true
+		}
+	}
+	return false
+}
+
+// Copies {req} targeting {rawurl}.  When {replay} is true the body is recreated since the original
+// was consumed by a previous attempt
+func rewriteRequest(req *http.Request, rawurl string, replay bool) (*http.Request, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.URL = u
+	r.Host = ""
+	if replay && req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, http.ErrBodyNotAllowed
+		}
+		if r.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// A different endpoint may be tried if the connection could not be established (the request was never
+// sent) or, for methods other than POST, the connection failed
+func canFailover(method string, err error) bool {
+	_, ok := err.(*net.OpError)
+	return ok && (isConnectError(err) || method != POST.String())
+}
+
+// Determines if {err} was returned by a transport because the connection could not be established
+func isConnectError(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "dial"
+}
+
+// SetActiveEndpoint sends subsequent requests to {endpoint} (eg. the current leader).  Returns false if
+// {endpoint} is not one of the configured endpoints
+func (h *HttpClient) SetActiveEndpoint(endpoint string) bool {
+	return h.failover != nil && h.failover.activate(endpoint)
+}
+
+// ActiveEndpoint returns the endpoint requests are currently sent to or an empty string if endpoints
+// have not been configured
+func (h *HttpClient) ActiveEndpoint() string {
+	if h.failover == nil {
+		return ""
+	}
+	return h.failover.endpoints[h.failover.current()]
+}
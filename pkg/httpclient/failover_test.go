@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func closedURL() string {
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	l.Close()
+	return "http://" + l.Addr().String()
+}
+
+func TestFailover(t *testing.T) {
+	var body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer s.Close()
+
+	down := closedURL()
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Endpoints: []string{down, s.URL + "/"}})
+	assert.Equal(t, down, client.ActiveEndpoint())
+
+	resp := client.HttpPost(down+"/v2/apps", map[string]string{"id": "/app"}, nil)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, `{"id":"/app"}`, body)
+	assert.Equal(t, s.URL, client.ActiveEndpoint())
+
+	assert.True(t, client.SetActiveEndpoint(down))
+	assert.False(t, client.SetActiveEndpoint("http://unknown:8080"))
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	down := closedURL()
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Endpoints: []string{down, closedURL()}})
+	resp := client.HttpGet(down+"/v2/apps", nil)
+	assert.NotNil(t, resp.Error)
+	assert.Equal(t, down, client.ActiveEndpoint())
+}
+
+func TestFailoverNotConfigured(t *testing.T) {
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30})
+	assert.Equal(t, "", client.ActiveEndpoint())
+	assert.False(t, client.SetActiveEndpoint("http://localhost:8080"))
+}
@@ -0,0 +1,46 @@
+package httpclient
+
+import "sync"
+
+var (
+	guardMu    sync.RWMutex
+	writeGuard func(method, url string) error
+)
+
+// SetWriteGuard sets {fn} to be consulted before every request which may make a change (eg. to refuse
+// changes during a deployment freeze).  The request fails with the error {fn} returns.  nil removes the guard
+func SetWriteGuard(fn func(method, url string) error) {
+	guardMu.Lock()
+	defer guardMu.Unlock()
+	writeGuard = fn
+}
+
+// AddWriteGuard sets {fn} to be consulted before the write guard already set so requests are only made
+// when both permit them
+func AddWriteGuard(fn func(method, url string) error) {
+	guardMu.Lock()
+	defer guardMu.Unlock()
+	next := writeGuard
+	if next == nil {
+		writeGuard = fn
+		return
+	}
+	writeGuard = func(method, url string) error {
+		if err := fn(method, url); err != nil {
+			return err
+		}
+		return next(method, url)
+	}
+}
+
+// CheckWrite returns the error of the write guard for a {method} request to {url} or nil when permitted.
+// Used by clients of RPC style APIs which know which calls make changes
+func CheckWrite(method, url string) error {
+	guardMu.RLock()
+	fn := writeGuard
+	guardMu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	return fn(method, url)
+}
@@ -0,0 +1,28 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+)
+
+// Request bodies smaller than this are sent uncompressed since the saving doesn't outweigh the cost
+const compressMinSize = 8 * 1024
+
+// Returns the body for a request with {data} and the Content-Encoding to send.  Responses are always
+// requested and decompressed with gzip by the transport
+func (h *HttpClient) requestBody(data string) (io.Reader, string) {
+	if !h.config.Compress || len(data) < compressMinSize {
+		return strings.NewReader(data), ""
+	}
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		return strings.NewReader(data), ""
+	}
+	if err := zw.Close(); err != nil {
+		return strings.NewReader(data), ""
+	}
+	return bytes.NewReader(buf.Bytes()), "gzip"
+}
@@ -0,0 +1,56 @@
+package httpclient
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzipResponse(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(`{"id": "/app"}`))
+		zw.Close()
+	}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30})
+	result := map[string]string{}
+	assert.Nil(t, client.HttpGet(s.URL, &result).Error)
+	assert.Equal(t, "/app", result["id"])
+}
+
+func TestGzipRequest(t *testing.T) {
+	var encodings []string
+	var bodies []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body, _ = gzip.NewReader(r.Body)
+		}
+		data, _ := ioutil.ReadAll(body)
+		bodies = append(bodies, string(data))
+	}))
+	defer s.Close()
+
+	large := map[string]string{"cmd": strings.Repeat("x", compressMinSize)}
+	small := map[string]string{"cmd": "x"}
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Compress: true})
+	assert.Nil(t, client.HttpPut(s.URL, large, nil).Error)
+	assert.Nil(t, client.HttpPut(s.URL, small, nil).Error)
+	client = NewHttpClient(HttpClientConfig{RequestTimeout: 30})
+	assert.Nil(t, client.HttpPut(s.URL, large, nil).Error)
+
+	assert.Equal(t, []string{"gzip", "", ""}, encodings)
+	assert.Equal(t, bodies[0], bodies[2])
+	assert.Equal(t, `{"cmd":"x"}`, bodies[1])
+}
@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Hooks observe every request sent by a client so embedding applications can export metrics or traces
+// (eg. OpenTelemetry spans) without wrapping the transport.  Each attempt of a retried request is
+// reported separately.  Either callback may be nil
+type Hooks struct {
+	// Called before the request is sent.  The headers of {req} may be modified (eg. to propagate a trace)
+	// and a non-nil context returned becomes the context of the request
+	OnRequest func(req *RequestInfo) context.Context
+	// Called once the response headers are received or the request failed
+	OnResponse func(req *RequestInfo, resp *ResponseInfo)
+}
+
+// RequestInfo describes a request about to be sent
+type RequestInfo struct {
+	Context context.Context
+	Method  string
+	// Host the request is sent to (eg. marathon.local:8080)
+	Host string
+	// Path of the API called (eg. /v2/apps/web)
+	Path   string
+	Header http.Header
+}
+
+// ResponseInfo describes the outcome of a request
+type ResponseInfo struct {
+	// HTTP status or zero if no response was received
+	Status int
+	// Time taken until the response headers were received.  The body of streamed responses (eg. events)
+	// is read afterwards
+	Latency time.Duration
+	// Error raised when no response was received (eg. a connection failure)
+	Err error
+}
+
+// hooksTransport reports each request passing through {next} to the hooks
+type hooksTransport struct {
+	next  http.RoundTripper
+	hooks *Hooks
+}
+
+func newHooksTransport(next http.RoundTripper, hooks *Hooks) *hooksTransport {
+	return &hooksTransport{next: next, hooks: hooks}
+}
+
+func (t *hooksTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	info := &RequestInfo{
+		Context: req.Context(),
+		Method:  req.Method,
+		Host:    req.URL.Host,
+		Path:    req.URL.Path,
+		Header:  req.Header,
+	}
+	if t.hooks.OnRequest != nil {
+		if ctx := t.hooks.OnRequest(info); ctx != nil {
+			info.Context = ctx
+			req = req.WithContext(ctx)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if t.hooks.OnResponse != nil {
+		result := &ResponseInfo{Latency: time.Since(start), Err: err}
+		if resp != nil {
+			result.Status = resp.StatusCode
+		}
+		t.hooks.OnResponse(info, result)
+	}
+	return resp, err
+}
@@ -0,0 +1,60 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type hookKey struct{}
+
+func TestHooks(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "span-1", r.Header.Get("traceparent"))
+		if r.URL.Path == "/v2/apps/missing" {
+			w.WriteHeader(404)
+		}
+	}))
+	defer s.Close()
+
+	requests := []*RequestInfo{}
+	responses := []*ResponseInfo{}
+	hooks := &Hooks{
+		OnRequest: func(req *RequestInfo) context.Context {
+			req.Header.Set("traceparent", "span-1")
+			return context.WithValue(req.Context, hookKey{}, "span-1")
+		},
+		OnResponse: func(req *RequestInfo, resp *ResponseInfo) {
+			assert.Equal(t, "span-1", req.Context.Value(hookKey{}))
+			requests = append(requests, req)
+			responses = append(responses, resp)
+		},
+	}
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Hooks: hooks})
+
+	assert.Nil(t, client.HttpGet(s.URL+"/v2/apps", nil).Error)
+	assert.Equal(t, ErrorNotFound, client.HttpDelete(s.URL+"/v2/apps/missing", nil, nil).Error)
+
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "GET", requests[0].Method)
+	assert.Equal(t, "/v2/apps", requests[0].Path)
+	assert.Equal(t, 200, responses[0].Status)
+	assert.Equal(t, "DELETE", requests[1].Method)
+	assert.Equal(t, "/v2/apps/missing", requests[1].Path)
+	assert.Equal(t, 404, responses[1].Status)
+	assert.True(t, responses[1].Latency > 0)
+	assert.Nil(t, responses[1].Err)
+}
+
+func TestHooksConnectionError(t *testing.T) {
+	var result *ResponseInfo
+	hooks := &Hooks{OnResponse: func(req *RequestInfo, resp *ResponseInfo) { result = resp }}
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Hooks: hooks})
+
+	assert.NotNil(t, client.HttpGet("http://127.0.0.1:1/v2/info", nil).Error)
+	assert.Equal(t, 0, result.Status)
+	assert.NotNil(t, result.Err)
+}
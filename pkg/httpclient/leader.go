@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+const maxLeaderRedirects = 5
+
+// leaderTransport follows the redirects (307/308) issued by Marathon followers to the leader, replaying
+// the request body, and sends subsequent requests for the same host directly to the leader
+type leaderTransport struct {
+	sync.Mutex
+	next http.RoundTripper
+	// origin (scheme://host) of the requested host to the origin of its leader
+	leaders map[string]string
+}
+
+func newLeaderTransport(next http.RoundTripper) *leaderTransport {
+	return &leaderTransport{next: next, leaders: make(map[string]string)}
+}
+
+func (t *leaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	origin := originOf(req.URL)
+	r := req
+	cached := t.leader(origin)
+	if cached != "" {
+		var err error
+		if r, err = rewriteRequest(req, cached+requestURI(req.URL), false); err != nil {
+			return nil, err
+		}
+	}
+
+	for hops := 0; ; hops++ {
+		resp, err := t.next.RoundTrip(r)
+		if err != nil {
+			if cached == "" || hops > 0 || !isConnectError(err) {
+				return nil, err
+			}
+			// the leader may have changed since it was cached
+			log.Debug("Leader %s is unavailable: %s", cached, err.Error())
+			t.forget(origin)
+			cached = ""
+			if r, err = rewriteRequest(req, req.URL.String(), true); err != nil {
+				return nil, err
+			}
+			hops--
+			continue
+		}
+
+		if resp.StatusCode != http.StatusTemporaryRedirect && resp.StatusCode != http.StatusPermanentRedirect {
+			return resp, nil
+		}
+		location, err := resp.Location()
+		if err != nil || hops >= maxLeaderRedirects || !replayable(req) {
+			return resp, nil
+		}
+		drainAndClose(resp.Body)
+
+		log.Debug("Redirected to leader: %s", location)
+		t.remember(origin, originOf(location))
+		if r, err = rewriteRequest(req, location.String(), true); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *leaderTransport) leader(origin string) string {
+	t.Lock()
+	defer t.Unlock()
+	return t.leaders[origin]
+}
+
+func (t *leaderTransport) remember(origin, leader string) {
+	t.Lock()
+	defer t.Unlock()
+	if origin != leader {
+		t.leaders[origin] = leader
+	}
+}
+
+func (t *leaderTransport) forget(origin string) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.leaders, origin)
+}
+
+func originOf(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// Returns the path and query of {u}
+func requestURI(u *url.URL) string {
+	uri := u.EscapedPath()
+	if u.RawQuery != "" {
+		uri += "?" + u.RawQuery
+	}
+	return uri
+}
+
+// Determines if the body of {req} can be sent again
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
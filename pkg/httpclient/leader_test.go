@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderRedirect(t *testing.T) {
+	var bodies []string
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, r.Method+" "+r.URL.RequestURI()+" "+string(data))
+		user, _, _ := r.BasicAuth()
+		assert.Equal(t, "user", user)
+	}))
+	defer leader.Close()
+
+	followerHits := 0
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		followerHits++
+		http.Redirect(w, r, leader.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	}))
+	defer follower.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, HttpUser: "user", HttpPass: "pass"})
+	assert.Nil(t, client.HttpPost(follower.URL+"/v2/apps?force=true", map[string]string{"id": "/a"}, nil).Error)
+	assert.Nil(t, client.HttpPut(follower.URL+"/v2/apps/b", map[string]string{"id": "/b"}, nil).Error)
+
+	assert.Equal(t, 1, followerHits)
+	assert.Equal(t, []string{`POST /v2/apps?force=true {"id":"/a"}`, `PUT /v2/apps/b {"id":"/b"}`}, bodies)
+}
+
+func TestCachedLeaderUnavailable(t *testing.T) {
+	hits := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer s.Close()
+
+	tr := newLeaderTransport(http.DefaultTransport)
+	tr.remember(s.URL, closedURL())
+	client := &http.Client{Transport: tr}
+	resp, err := client.Get(s.URL + "/v2/apps")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, hits)
+	assert.Equal(t, "", tr.leader(s.URL))
+}
@@ -21,3 +21,8 @@ var methods = [...]string{
 func (method Method) String() string {
 	return methods[method-1]
 }
+
+// Determines if repeating the request has the same effect as sending it once
+func (method Method) idempotent() bool {
+	return method != POST
+}
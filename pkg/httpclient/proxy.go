@@ -0,0 +1,83 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidProxy = errors.New("Proxy must be a URL with a scheme of http, https or socks5")
+
+// ProxyConfig defines the proxies used for a client in place of the HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY environment variables
+type ProxyConfig struct {
+	// Proxy for http requests (eg. http://proxy:3128 or socks5://proxy:1080)
+	HTTP string `json:"http,omitempty"`
+	// Proxy for https requests.  Defaults to the HTTP proxy when empty
+	HTTPS string `json:"https,omitempty"`
+	// Comma separated hosts, domains (eg. .example.com), IPs or CIDR ranges which bypass the proxy
+	NoProxy string `json:"noproxy,omitempty"`
+}
+
+// IsEmpty returns true if no proxy has been defined
+func (p *ProxyConfig) IsEmpty() bool {
+	return p == nil || (p.HTTP == "" && p.HTTPS == "")
+}
+
+// ValidateProxyURL ensures {proxy} is a supported proxy URL
+func ValidateProxyURL(proxy string) error {
+	u, err := url.Parse(proxy)
+	if err != nil || u.Host == "" {
+		return ErrInvalidProxy
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+		return nil
+	}
+	return ErrInvalidProxy
+}
+
+// ProxyFunc returns a function suitable for http.Transport.Proxy
+func (p *ProxyConfig) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxy := p.HTTP
+		if req.URL.Scheme == "https" && p.HTTPS != "" {
+			proxy = p.HTTPS
+		}
+		if proxy == "" || p.bypass(req.URL) {
+			return nil, nil
+		}
+		if err := ValidateProxyURL(proxy); err != nil {
+			return nil, fmt.Errorf("%s: '%s'", err.Error(), proxy)
+		}
+		return url.Parse(proxy)
+	}
+}
+
+// Determines if {u} matches an entry within the NoProxy list
+func (p *ProxyConfig) bypass(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	ip := net.ParseIP(host)
+
+	for _, entry := range strings.Split(p.NoProxy, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case ip != nil && strings.Contains(entry, "/"):
+			if _, cidr, err := net.ParseCIDR(entry); err == nil && cidr.Contains(ip) {
+				return true
+			}
+		case entry == host || entry == u.Host:
+			return true
+		case strings.HasSuffix(host, "."+strings.TrimPrefix(entry, ".")):
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package httpclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyFunc(t *testing.T) {
+	p := &ProxyConfig{HTTP: "http://proxy:3128", HTTPS: "socks5://socks:1080", NoProxy: ".internal, 10.0.0.0/8, marathon:8080"}
+	proxy := p.ProxyFunc()
+
+	cases := map[string]string{
+		"http://cloud.example.com/v2/apps":  "http://proxy:3128",
+		"https://cloud.example.com/v2/apps": "socks5://socks:1080",
+		"http://mesos.internal/v2/apps":     "",
+		"http://10.1.2.3:8080/v2/apps":      "",
+		"http://marathon:8080/v2/apps":      "",
+		"http://marathon:9090/v2/apps":      "http://proxy:3128",
+	}
+	for target, expected := range cases {
+		req, _ := http.NewRequest("GET", target, nil)
+		u, err := proxy(req)
+		assert.NoError(t, err)
+		if expected == "" {
+			assert.Nil(t, u, target)
+		} else {
+			assert.Equal(t, expected, u.String(), target)
+		}
+	}
+}
+
+func TestValidateProxyURL(t *testing.T) {
+	assert.NoError(t, ValidateProxyURL("socks5://proxy:1080"))
+	assert.Equal(t, ErrInvalidProxy, ValidateProxyURL("proxy:3128"))
+	assert.Equal(t, ErrInvalidProxy, ValidateProxyURL("ftp://proxy:21"))
+}
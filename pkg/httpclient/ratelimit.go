@@ -0,0 +1,43 @@
+package httpclient
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimitTransport spaces requests evenly so no more than the configured number of requests per
+// second are sent.  Used to avoid overwhelming small Marathon masters during bulk operations
+type rateLimitTransport struct {
+	sync.Mutex
+	next     http.RoundTripper
+	interval time.Duration
+	// earliest time the next request may be sent
+	slot time.Time
+}
+
+func newRateLimitTransport(next http.RoundTripper, requestsPerSecond float64) *rateLimitTransport {
+	return &rateLimitTransport{next: next, interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.wait()
+	return t.next.RoundTrip(req)
+}
+
+// Reserves the next slot and sleeps until it arrives
+func (t *rateLimitTransport) wait() {
+	t.Lock()
+	now := time.Now()
+	if t.slot.Before(now) {
+		t.slot = now
+	}
+	delay := t.slot.Sub(now)
+	t.slot = t.slot.Add(t.interval)
+	t.Unlock()
+
+	if delay > 0 {
+		log.Debug("Rate limited - waiting %s", delay)
+		sleep(delay)
+	}
+}
@@ -0,0 +1,29 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	delays := []time.Duration{}
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	defer func() { sleep = time.Sleep }()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, RateLimit: 10})
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, client.HttpGet(s.URL, nil).Error)
+	}
+	// the first request is sent immediately and each subsequent request is scheduled 100ms after the
+	// previous one.  Since sleep doesn't advance the clock the delays accumulate
+	assert.Len(t, delays, 4)
+	last := delays[len(delays)-1]
+	assert.True(t, last > 300*time.Millisecond && last <= 400*time.Millisecond, last.String())
+}
@@ -0,0 +1,141 @@
+package httpclient
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Replaced by tests to avoid waiting between attempts
+var sleep = time.Sleep
+
+// RetryPolicy controls how failed requests are retried.  Requests are retried when no response was
+// received or the server responds with one of the retried statuses.  Non-idempotent requests (POST) are
+// only retried when the connection could not be established since the server may have acted on them.  A
+// policy attached to the context of a request with WithRetryPolicy overrides the policy of the client
+type RetryPolicy struct {
+	// Total attempts including the initial request.  Values below 2 disable retries
+	MaxAttempts int
+	// Delay before the first retry which doubles for each subsequent retry
+	BaseDelay time.Duration
+	// Upper bound for the delay between attempts
+	MaxDelay time.Duration
+	// Fraction (0 - 1) of each delay which is randomized so concurrent clients don't retry in lockstep
+	Jitter float64
+	// Optional response statuses which are retried.  Default: 429 and 5xx other than 501
+	Statuses []int
+}
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy returns a context which retries the requests made with it according to {policy} in
+// place of the policy of the client (eg. more attempts for a long operation or MaxAttempts 1 to disable
+// retries for a single call)
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+// DefaultRetryPolicy returns the policy applied to Marathon requests unless configured otherwise
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second, Jitter: 0.5}
+}
+
+// Returns the delay before retry number {retry} (starting at 1)
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(retry-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay -= delay * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+func (p *RetryPolicy) shouldRetry(method Method, resp *Response) bool {
+	if resp.Status == 0 {
+		if !isNetworkError(resp.Error) {
+			return false
+		}
+		return method.idempotent() || isDialError(resp.Error)
+	}
+	if p.retriedStatus(resp.Status) {
+		return method.idempotent()
+	}
+	return false
+}
+
+func (p *RetryPolicy) retriedStatus(status int) bool {
+	if p.Statuses == nil {
+		return status == 429 || (status >= 500 && status != 501)
+	}
+	for _, s := range p.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Invokes {call} retrying according to the configured RetryPolicy until {ctx} is done
+func (h *HttpClient) retry(ctx context.Context, method Method, call func() *Response) *Response {
+	resp := call()
+	policy := h.config.Retry
+	if override, ok := ctx.Value(retryPolicyKey{}).(*RetryPolicy); ok && override != nil {
+		policy = override
+	}
+	if policy == nil {
+		return resp
+	}
+
+	for attempt := 1; attempt < policy.MaxAttempts && ctx.Err() == nil && policy.shouldRetry(method, resp); attempt++ {
+		delay := policy.backoff(attempt)
+		reason := ""
+		if resp.Error != nil {
+			reason = resp.Error.Error()
+		}
+		log.Warning("Request failed (status: %d %s) - retrying in %s (attempt %d of %d)", resp.Status, reason, delay, attempt+1, policy.MaxAttempts)
+		if err := Sleep(ctx, delay); err != nil {
+			return NewResponse(resp.Status, resp.Elapsed, resp.Content, err)
+		}
+		resp = call()
+	}
+	return resp
+}
+
+// Sleep pauses for {d} returning the error of {ctx} if it's done first
+func Sleep(ctx context.Context, d time.Duration) error {
+	if ctx.Done() == nil {
+		sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func isNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	_, ok := err.(*url.Error)
+	return ok
+}
+
+// Determines if the connection could not be established in which case the request was never sent
+func isDialError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		if opErr, ok := urlErr.Err.(*net.OpError); ok {
+			return opErr.Op == "dial"
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func noSleep() func() {
+	sleep = func(time.Duration) {}
+	return func() { sleep = time.Sleep }
+}
+
+func TestRetryIdempotentOn5xx(t *testing.T) {
+	defer noSleep()()
+
+	attempts := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(503)
+			return
+		}
+		fmt.Fprint(w, `{"ok": "yes"}`)
+	}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Retry: DefaultRetryPolicy()})
+	result := map[string]string{}
+	resp := client.HttpGet(s.URL, &result)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, "yes", result["ok"])
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	defer noSleep()()
+
+	attempts := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(503)
+	}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Retry: DefaultRetryPolicy()})
+	resp := client.HttpPost(s.URL, map[string]string{"id": "app"}, nil)
+	assert.Equal(t, 503, resp.Status)
+	assert.Equal(t, 1, attempts)
+
+	// a POST which could not connect was never sent so it is safe to retry
+	l, _ := net.Listen("tcp", "127.0.0.1:0")
+	closed := "http://" + l.Addr().String()
+	l.Close()
+
+	dials := 0
+	sleep = func(time.Duration) { dials++ }
+	resp = client.HttpPost(closed, map[string]string{"id": "app"}, nil)
+	assert.NotNil(t, resp.Error)
+	assert.Equal(t, 2, dials)
+}
+
+func TestBackoff(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: time.Second, MaxDelay: 3 * time.Second}
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 3*time.Second, p.backoff(3))
+
+	p.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		d := p.backoff(2)
+		assert.True(t, d > time.Second-1 && d <= 2*time.Second)
+	}
+}
+
+func TestRetryStatusesAndOverride(t *testing.T) {
+	defer noSleep()()
+
+	attempts := 0
+	status := 503
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(status)
+	}))
+	defer s.Close()
+
+	policy := DefaultRetryPolicy()
+	policy.Statuses = []int{409}
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Retry: policy})
+	client.HttpGet(s.URL, nil)
+	assert.Equal(t, 1, attempts, "503 isn't one of the retried statuses")
+
+	attempts, status = 0, 409
+	client.HttpGet(s.URL, nil)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	ctx := WithRetryPolicy(context.Background(), &RetryPolicy{MaxAttempts: 5, Statuses: []int{409}})
+	client.HttpGetCtx(ctx, s.URL, nil)
+	assert.Equal(t, 5, attempts, "the policy of the context should override the client")
+}
@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	defaultConnectTimeout      = 30 * time.Second
+	defaultTLSHandshakeTimeout = 10 * time.Second
+	defaultKeepAlive           = 30 * time.Second
+
+	// Request timeout which disables the overall timeout (any negative value)
+	NoTimeout Duration = -1
+)
+
+// Duration is a time.Duration which is serialized as a string (eg. "30s")
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		// plain numbers are treated as seconds
+		var secs float64
+		if err := json.Unmarshal(b, &secs); err != nil {
+			return err
+		}
+		*d = Duration(secs * float64(time.Second))
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Timeouts for the phases of a request.  Zero values use the defaults
+type Timeouts struct {
+	// Establishing the TCP connection
+	Connect Duration `json:"connect,omitempty"`
+	// Completing the TLS handshake
+	TLSHandshake Duration `json:"tlshandshake,omitempty"`
+	// Waiting for the response headers once the request has been written
+	ResponseHeader Duration `json:"responseheader,omitempty"`
+	// The entire request including reading the body.  NoTimeout disables the limit
+	Request Duration `json:"request,omitempty"`
+}
+
+// IsEmpty returns true if no timeouts have been defined
+func (t *Timeouts) IsEmpty() bool {
+	return t == nil || *t == Timeouts{}
+}
+
+// Merge returns a copy of {t} with any timeouts set within {overrides} applied
+func (t *Timeouts) Merge(overrides *Timeouts) *Timeouts {
+	merged := Timeouts{}
+	if t != nil {
+		merged = *t
+	}
+	if overrides != nil {
+		if overrides.Connect != 0 {
+			merged.Connect = overrides.Connect
+		}
+		if overrides.TLSHandshake != 0 {
+			merged.TLSHandshake = overrides.TLSHandshake
+		}
+		if overrides.ResponseHeader != 0 {
+			merged.ResponseHeader = overrides.ResponseHeader
+		}
+		if overrides.Request != 0 {
+			merged.Request = overrides.Request
+		}
+	}
+	return &merged
+}
+
+func orDefault(d Duration, def time.Duration) time.Duration {
+	if d > 0 {
+		return time.Duration(d)
+	}
+	return def
+}
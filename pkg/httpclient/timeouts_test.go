@@ -0,0 +1,58 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutsJSON(t *testing.T) {
+	timeouts := &Timeouts{}
+	err := json.Unmarshal([]byte(`{"connect": "5s", "responseheader": 90, "request": "2m"}`), timeouts)
+	assert.Nil(t, err)
+	assert.Equal(t, Duration(5*time.Second), timeouts.Connect)
+	assert.Equal(t, Duration(90*time.Second), timeouts.ResponseHeader)
+	assert.Equal(t, Duration(2*time.Minute), timeouts.Request)
+
+	data, _ := json.Marshal(timeouts)
+	assert.Equal(t, `{"connect":"5s","responseheader":"1m30s","request":"2m0s"}`, string(data))
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"connect": "soon"}`), timeouts))
+}
+
+func TestTimeoutsMerge(t *testing.T) {
+	env := &Timeouts{Connect: Duration(time.Second), Request: Duration(time.Minute)}
+	merged := env.Merge(&Timeouts{Request: NoTimeout})
+	assert.Equal(t, Duration(time.Second), merged.Connect)
+	assert.Equal(t, NoTimeout, merged.Request)
+	assert.Equal(t, Duration(time.Minute), env.Request)
+
+	var none *Timeouts
+	assert.True(t, none.IsEmpty())
+	assert.True(t, none.Merge(nil).IsEmpty())
+}
+
+func TestResponseHeaderTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, Timeouts: &Timeouts{ResponseHeader: Duration(50 * time.Millisecond)}})
+	resp := client.HttpGet(s.URL, nil)
+	assert.NotNil(t, resp.Error)
+
+	client = NewHttpClient(HttpClientConfig{RequestTimeout: 30, Timeouts: &Timeouts{ResponseHeader: Duration(time.Second)}})
+	resp = client.HttpGet(s.URL, nil)
+	assert.Nil(t, resp.Error)
+}
+
+func TestRequestTimeoutPrecedence(t *testing.T) {
+	assert.Equal(t, 30*time.Second, requestTimeout(&HttpClientConfig{RequestTimeout: 30}))
+	assert.Equal(t, time.Minute, requestTimeout(&HttpClientConfig{RequestTimeout: 30, Timeouts: &Timeouts{Request: Duration(time.Minute)}}))
+	assert.Equal(t, time.Duration(0), requestTimeout(&HttpClientConfig{RequestTimeout: 30, Timeouts: &Timeouts{Request: NoTimeout}}))
+}
@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+)
+
+var ErrTLSKeyPair = errors.New("Both a client certificate and key must be specified")
+
+// TLSConfig defines the client certificate and CA bundle used for mutual TLS
+type TLSConfig struct {
+	// PEM encoded client certificate
+	CertFile string `json:"cert,omitempty"`
+	// PEM encoded client private key
+	KeyFile string `json:"key,omitempty"`
+	// Optional PEM encoded CA bundle used to verify the server in place of the system roots
+	CAFile string `json:"ca,omitempty"`
+	// Skips verification of the server certificate for this environment only.  Prefer CAFile
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// PEM encoded client certificate, key and CA bundle supplied in memory (eg. embedded within a
+	// kubeconfig) which take precedence over the files
+	CertData []byte `json:"-"`
+	KeyData  []byte `json:"-"`
+	CAData   []byte `json:"-"`
+}
+
+// IsEmpty returns true if no certificates have been defined
+func (t *TLSConfig) IsEmpty() bool {
+	return t == nil || (t.CertFile == "" && t.KeyFile == "" && t.CAFile == "" && !t.InsecureSkipVerify &&
+		len(t.CertData) == 0 && len(t.KeyData) == 0 && len(t.CAData) == 0)
+}
+
+// SkipsVerify returns true if verification of the server certificate is disabled by the configuration
+func (t *TLSConfig) SkipsVerify() bool {
+	return t != nil && t.InsecureSkipVerify
+}
+
+// Load reads the certificates and returns the resulting tls.Config.  Verification of the server certificate
+// is skipped when {insecure} (eg. --insecure) or InsecureSkipVerify is set
+func (t *TLSConfig) Load(insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure || t.SkipsVerify()}
+	if t.IsEmpty() {
+		return config, nil
+	}
+
+	if len(t.CertData) > 0 || len(t.KeyData) > 0 {
+		if len(t.CertData) == 0 || len(t.KeyData) == 0 {
+			return nil, ErrTLSKeyPair
+		}
+		cert, err := tls.X509KeyPair(t.CertData, t.KeyData)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load client certificate: %s", err.Error())
+		}
+		config.Certificates = []tls.Certificate{cert}
+	} else if t.CertFile != "" || t.KeyFile != "" {
+		if t.CertFile == "" || t.KeyFile == "" {
+			return nil, ErrTLSKeyPair
+		}
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load client certificate: %s", err.Error())
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(t.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(t.CAData) {
+			return nil, errors.New("No certificates found within the CA data")
+		}
+		config.RootCAs = pool
+	} else if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found within CA bundle '%s'", t.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
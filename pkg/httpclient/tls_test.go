@@ -0,0 +1,114 @@
+package httpclient
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMutualTLS(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon-tls")
+	defer os.RemoveAll(dir)
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "depcon"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	clientCert, _ := x509.ParseCertificate(der)
+
+	certs := &TLSConfig{
+		CertFile: filepath.Join(dir, "client.pem"),
+		KeyFile:  filepath.Join(dir, "client-key.pem"),
+		CAFile:   filepath.Join(dir, "ca.pem"),
+	}
+	ioutil.WriteFile(certs.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(certs.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0600)
+
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"cn": "%s"}`, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCert)
+	s.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	s.StartTLS()
+	defer s.Close()
+	ioutil.WriteFile(certs.CAFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600)
+
+	result := map[string]string{}
+	resp := NewHttpClient(HttpClientConfig{RequestTimeout: 30, TLS: certs}).HttpGet(s.URL, &result)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, "depcon", result["cn"])
+
+	// certificates supplied in memory are equivalent to the files
+	inMemory := &TLSConfig{}
+	inMemory.CertData, _ = ioutil.ReadFile(certs.CertFile)
+	inMemory.KeyData, _ = ioutil.ReadFile(certs.KeyFile)
+	inMemory.CAData, _ = ioutil.ReadFile(certs.CAFile)
+	result = map[string]string{}
+	resp = NewHttpClient(HttpClientConfig{RequestTimeout: 30, TLS: inMemory}).HttpGet(s.URL, &result)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, "depcon", result["cn"])
+
+	// without the client certificate the handshake is rejected
+	resp = NewHttpClient(HttpClientConfig{RequestTimeout: 30, TLS: &TLSConfig{CAFile: certs.CAFile}}).HttpGet(s.URL, &result)
+	assert.NotNil(t, resp.Error)
+}
+
+func TestTLSKeyPairRequired(t *testing.T) {
+	resp := NewHttpClient(HttpClientConfig{TLS: &TLSConfig{CertFile: "client.pem"}}).HttpGet("https://localhost", nil)
+	assert.Equal(t, ErrTLSKeyPair, resp.Error)
+}
+
+func TestEnvironmentScopedTLS(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "depcon-tls")
+	defer os.RemoveAll(dir)
+
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ok": "yes"}`)
+	}))
+	defer s.Close()
+	bundle := filepath.Join(dir, "ca.pem")
+	ioutil.WriteFile(bundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.Certificate().Raw}), 0600)
+
+	// the internal CA of another cluster
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	template := &x509.Certificate{SerialNumber: big.NewInt(2), Subject: pkix.Name{CommonName: "other-ca"}, IsCA: true,
+		NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour), BasicConstraintsValid: true}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	other := filepath.Join(dir, "other-ca.pem")
+	ioutil.WriteFile(other, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+
+	get := func(certs *TLSConfig) error {
+		return NewHttpClient(HttpClientConfig{RequestTimeout: 30, TLS: certs}).HttpGet(s.URL, &map[string]string{}).Error
+	}
+	assert.NotNil(t, get(nil))
+	assert.Nil(t, get(&TLSConfig{CAFile: bundle}))
+	// an environment only trusts its own CA
+	assert.NotNil(t, get(&TLSConfig{CAFile: other}))
+
+	insecure := &TLSConfig{InsecureSkipVerify: true}
+	assert.False(t, insecure.IsEmpty())
+	assert.True(t, insecure.SkipsVerify())
+	assert.Nil(t, get(insecure))
+	var none *TLSConfig
+	assert.False(t, none.SkipsVerify())
+}
@@ -0,0 +1,183 @@
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/mask"
+)
+
+const (
+	redacted = mask.Placeholder
+	// Bodies larger than this are truncated within the trace
+	maxTraceBody = 64 * 1024
+)
+
+var (
+	traceMu     sync.Mutex
+	traceWriter io.Writer
+
+	// Headers whose values are never written to the trace
+	secretHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Auth-Token"}
+)
+
+// EnableTracing writes the method, URL, headers, body, status and timing of every request made by
+// clients created afterwards to {w}.  Credentials and known secret fields are redacted
+func EnableTracing(w io.Writer) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	traceWriter = w
+}
+
+// TracingEnabled returns true if requests are being traced
+func TracingEnabled() bool {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	return traceWriter != nil
+}
+
+// tracingTransport writes each request and response passing through {next} to the trace writer
+type tracingTransport struct {
+	next http.RoundTripper
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--> %s %s\n", req.Method, redactURL(req.URL))
+	writeHeaders(buf, req.Header)
+	writeBody(buf, decodeBody(req.Header, reqBody))
+
+	if err != nil {
+		fmt.Fprintf(buf, "<-- ERROR %s (%s)\n\n", err.Error(), elapsed)
+		writeTrace(buf.Bytes())
+		return resp, err
+	}
+
+	fmt.Fprintf(buf, "<-- %s %s (%s)\n", resp.Proto, resp.Status, elapsed)
+	writeHeaders(buf, resp.Header)
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		buf.WriteString("(event stream not traced)\n")
+	} else {
+		respBody, rerr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+		writeBody(buf, respBody)
+		if rerr != nil {
+			fmt.Fprintf(buf, "(error reading body: %s)\n", rerr.Error())
+		}
+	}
+	buf.WriteString("\n")
+	writeTrace(buf.Bytes())
+	return resp, nil
+}
+
+func writeTrace(data []byte) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceWriter != nil {
+		traceWriter.Write(data)
+	}
+}
+
+func writeHeaders(w io.Writer, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := strings.Join(header[k], ", ")
+		if isSecretHeader(k) {
+			value = redacted
+		}
+		fmt.Fprintf(w, "%s: %s\n", k, value)
+	}
+}
+
+func writeBody(w io.Writer, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	truncated := len(body) > maxTraceBody
+	if truncated {
+		body = body[:maxTraceBody]
+	}
+	fmt.Fprintf(w, "\n%s\n", RedactBody(string(body)))
+	if truncated {
+		fmt.Fprintf(w, "(truncated to %d bytes)\n", maxTraceBody)
+	}
+}
+
+// Decompresses gzip encoded request bodies so they are readable within the trace
+func decodeBody(header http.Header, body []byte) []byte {
+	if header.Get("Content-Encoding") != "gzip" || len(body) == 0 {
+		return body
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	decoded, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+// RedactBody replaces the values of JSON string members named like a secret (eg. password, token)
+// and the values of secret params within {body}
+func RedactBody(body string) string {
+	return mask.Text(body)
+}
+
+func redactURL(u *url.URL) string {
+	c := *u
+	if c.User != nil {
+		c.User = url.User(c.User.Username())
+	}
+	if c.RawQuery != "" {
+		query := c.Query()
+		for key := range query {
+			if isSecretField(key) {
+				query.Set(key, redacted)
+			}
+		}
+		c.RawQuery = query.Encode()
+	}
+	return c.String()
+}
+
+// Determines if header {name} holds credentials.  Custom headers named like a secret (eg. X-WAF-Token)
+// are also redacted
+func isSecretHeader(name string) bool {
+	for _, h := range secretHeaders {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return isSecretField(name)
+}
+
+func isSecretField(name string) bool {
+	return mask.IsSecretName(name)
+}
@@ -0,0 +1,44 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactBody(t *testing.T) {
+	body := `{"uid": "admin", "password": "s3cret", "env": {"DB_PASSWORD": "abc\"def", "PORT": "80"}, "token":"xyz"}`
+	redactedBody := RedactBody(body)
+	assert.Equal(t, `{"uid": "admin", "password": "[REDACTED]", "env": {"DB_PASSWORD": "[REDACTED]", "PORT": "80"}, "token":"[REDACTED]"}`, redactedBody)
+}
+
+func TestTracing(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "/app", "token": "server-token"}`)
+	}))
+	defer s.Close()
+
+	out := &bytes.Buffer{}
+	EnableTracing(out)
+	defer EnableTracing(nil)
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30, HttpUser: "user", HttpPass: "pass"})
+	result := map[string]string{}
+	resp := client.HttpPost(s.URL+"/v2/apps?access_token=abc", map[string]string{"password": "hunter2", "id": "/app"}, &result)
+	assert.Nil(t, resp.Error)
+	assert.Equal(t, "/app", result["id"])
+
+	trace := out.String()
+	assert.Contains(t, trace, "--> POST "+s.URL+"/v2/apps?access_token=%5BREDACTED%5D")
+	assert.Contains(t, trace, "Authorization: [REDACTED]")
+	assert.Contains(t, trace, `"id":"/app"`)
+	assert.Contains(t, trace, "<-- HTTP/1.1 200 OK")
+	for _, secret := range []string{"hunter2", "server-token", "abc", "dXNlcjpwYXNz"} {
+		assert.False(t, strings.Contains(trace, secret), secret)
+	}
+}
@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// Idle connections kept across all hosts
+	maxIdleConns = 100
+	// Idle connections kept per host.  Bulk operations issue many requests against the same host so
+	// this is well above the net/http default of 2
+	maxIdleConnsPerHost = 32
+	idleConnTimeout     = 90 * time.Second
+)
+
+// Transports shared by clients with identical connection settings so connections are pooled across
+// clients (eg. the Marathon client and DC/OS login) rather than opened per client
+var transports = struct {
+	sync.Mutex
+	cache map[string]*http.Transport
+}{cache: make(map[string]*http.Transport)}
+
+// Connection level settings which identify a shared transport
+type transportKey struct {
+	Insecure       bool
+	Proxy          *ProxyConfig
+	TLS            *TLSConfig
+	Connect        Duration
+	TLSHandshake   Duration
+	ResponseHeader Duration
+}
+
+// sharedTransport returns the transport for the connection settings within {config} creating it on
+// first use
+func sharedTransport(config *HttpClientConfig) (*http.Transport, error) {
+	timeouts := config.Timeouts.Merge(nil)
+	data, _ := json.Marshal(transportKey{config.TLSInsecureSkipVerify, config.Proxy, config.TLS,
+		timeouts.Connect, timeouts.TLSHandshake, timeouts.ResponseHeader})
+	key := string(data)
+
+	transports.Lock()
+	defer transports.Unlock()
+	if tr, ok := transports.cache[key]; ok {
+		return tr, nil
+	}
+
+	tr, err := newTransport(config, timeouts)
+	if err != nil {
+		return nil, err
+	}
+	transports.cache[key] = tr
+	return tr, nil
+}
+
+func newTransport(config *HttpClientConfig, timeouts *Timeouts) (*http.Transport, error) {
+	dialer := &net.Dialer{
+		Timeout:   orDefault(timeouts.Connect, defaultConnectTimeout),
+		KeepAlive: defaultKeepAlive,
+	}
+	tr := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           withDialers(dialer.DialContext),
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   maxIdleConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   orDefault(timeouts.TLSHandshake, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: orDefault(timeouts.ResponseHeader, 0),
+		ExpectContinueTimeout: time.Second,
+	}
+	tlsConfig, err := config.TLS.Load(config.TLSInsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	tr.TLSClientConfig = tlsConfig
+	if !config.Proxy.IsEmpty() {
+		tr.Proxy = config.Proxy.ProxyFunc()
+	}
+	// unix sockets and ssh tunnels are never proxied
+	proxy := tr.Proxy
+	tr.Proxy = func(req *http.Request) (*url.URL, error) {
+		if lookupDialer(hostPort(req.URL)) != nil {
+			return nil, nil
+		}
+		return proxy(req)
+	}
+	return tr, nil
+}
+
+// Reads any remaining content from {body} before closing it so the connection can be reused
+func drainAndClose(body io.ReadCloser) {
+	io.Copy(ioutil.Discard, body)
+	body.Close()
+}
@@ -0,0 +1,51 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedTransport(t *testing.T) {
+	a, _ := sharedTransport(&HttpClientConfig{RequestTimeout: 30, HttpUser: "a"})
+	b, _ := sharedTransport(&HttpClientConfig{RequestTimeout: 10, HttpUser: "b"})
+	assert.Same(t, a, b)
+
+	insecure, _ := sharedTransport(&HttpClientConfig{RequestTimeout: 30, TLSInsecureSkipVerify: true})
+	assert.NotSame(t, a, insecure)
+
+	assert.True(t, a.ForceAttemptHTTP2)
+	assert.Equal(t, maxIdleConnsPerHost, a.MaxIdleConnsPerHost)
+}
+
+func TestConnectionsReused(t *testing.T) {
+	var conns int32
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"message": "not found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok": "yes"}  `)
+	}))
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+
+	client := NewHttpClient(HttpClientConfig{RequestTimeout: 30})
+	for i := 0; i < 20; i++ {
+		result := map[string]string{}
+		assert.Nil(t, client.HttpGet(s.URL, &result).Error)
+		assert.Equal(t, ErrorNotFound, client.HttpGet(s.URL+"/missing", nil).Error)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+}
@@ -0,0 +1,302 @@
+// Selects the entries of application logs by time, level and pattern and normalizes their timestamps so the
+// logs of several tasks can be read as one
+package logfilter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Levels from the least to the most severe
+var Levels = []string{"TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// TimeLayout is the layout of normalized timestamps
+const TimeLayout = "2006-01-02T15:04:05.000Z"
+
+var (
+	// a timestamp such as 2024-05-01T10:00:00.123Z, 2024-05-01 10:00:00,123 or 2024/05/01 10:00:00
+	timestamp   = `(\d{4}[-/]\d{2}[-/]\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?)`
+	leadingTime = regexp.MustCompile(`^\[?` + timestamp + `\]?\s*`)
+	fieldTime   = regexp.MustCompile(`(?i)\b(?:time|ts|timestamp)"?\s*[=:]\s*"?` + timestamp)
+	fieldLevel  = regexp.MustCompile(`(?i)\b(?:level|lvl|severity)"?\s*[=:]\s*"?([a-z]+)`)
+	wordLevel   = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|ERR|FATAL|CRITICAL|PANIC)\b|(?i)\[(trace|debug|info|warn|warning|error|crit|fatal)\]`)
+
+	timeLayouts = []string{"2006-01-02T15:04:05.999999999Z07:00", "2006-01-02T15:04:05.999999999Z0700", "2006-01-02T15:04:05.999999999"}
+	synonyms    = map[string]string{"WARNING": "WARN", "ERR": "ERROR", "CRIT": "FATAL", "CRITICAL": "FATAL", "PANIC": "FATAL"}
+)
+
+// Entry is a log line along with the lines continuing it (eg. a stack trace)
+type Entry struct {
+	// Source of the entry (eg. the task id)
+	Source string
+	// Time of the entry or the zero time when it has no timestamp
+	Time time.Time
+	// Level of the entry (one of Levels) or empty when it has none
+	Level string
+	Lines []string
+	// the text of the first line following a leading timestamp
+	message string
+}
+
+// Filter selects log entries.  Zero values select every entry
+type Filter struct {
+	// Entries before this time are dropped.  Logs without timestamps are never dropped by time
+	Since time.Time
+	// Only the last Tail entries of each source are kept
+	Tail int
+	// Entries with a line matching the expression are kept
+	Grep *regexp.Regexp
+	// Entries at this level or more severe are kept
+	Level string
+}
+
+// New returns a filter keeping the entries of the last {since} before {now}, the last {tail} entries, those
+// matching {grep} and those at {level} or more severe
+func New(since time.Duration, tail int, grep, level string, now time.Time) (*Filter, error) {
+	f := &Filter{Tail: tail}
+	if since < 0 || tail < 0 {
+		return nil, fmt.Errorf("--since and --tail must not be negative")
+	}
+	if since > 0 {
+		f.Since = now.Add(-since)
+	}
+	if grep != "" {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid --grep expression: %s", err.Error())
+		}
+		f.Grep = re
+	}
+	if level != "" {
+		f.Level = normalizeLevel(level)
+		if rank(f.Level) < 0 {
+			return nil, fmt.Errorf("'%s' is not a valid level - must be one of %s", level, strings.Join(Levels, ", "))
+		}
+	}
+	return f, nil
+}
+
+// IsZero returns true if the filter selects every entry
+func (f *Filter) IsZero() bool {
+	return f == nil || (f.Since.IsZero() && f.Tail == 0 && f.Grep == nil && f.Level == "")
+}
+
+// Apply returns the entries of {entries} selected by the filter
+func (f *Filter) Apply(entries []*Entry) []*Entry {
+	if f.IsZero() {
+		return entries
+	}
+	timed := false
+	for _, e := range entries {
+		timed = timed || !e.Time.IsZero()
+	}
+	selected := []*Entry{}
+	for _, e := range entries {
+		if f.Keeps(e, timed) {
+			selected = append(selected, e)
+		}
+	}
+	if f.Tail > 0 && len(selected) > f.Tail {
+		selected = selected[len(selected)-f.Tail:]
+	}
+	return selected
+}
+
+// Keeps returns true if entry {e} is selected by time, level and pattern.  {timed} is whether the log has
+// timestamps, entries without one are dropped by --since when it has
+func (f *Filter) Keeps(e *Entry, timed bool) bool {
+	if f == nil {
+		return true
+	}
+	if !f.Since.IsZero() && timed && (e.Time.IsZero() || e.Time.Before(f.Since)) {
+		return false
+	}
+	if f.Level != "" && rank(e.Level) < rank(f.Level) {
+		return false
+	}
+	if f.Grep != nil {
+		for _, line := range e.Lines {
+			if f.Grep.MatchString(line) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Parse splits {log} of {source} into entries.  A line with a timestamp starts an entry continued by the
+// following lines without one.  Logs without timestamps start an entry at each line with a level or at
+// every line when they have none
+func Parse(source, log string) []*Entry {
+	log = strings.TrimRight(log, "\n")
+	if log == "" {
+		return []*Entry{}
+	}
+	lines := []*Entry{}
+	timed, leveled := false, false
+	for _, line := range strings.Split(log, "\n") {
+		e := parseLine(source, strings.TrimRight(line, "\r"))
+		timed = timed || !e.Time.IsZero()
+		leveled = leveled || e.Level != ""
+		lines = append(lines, e)
+	}
+
+	entries := []*Entry{}
+	for i, e := range lines {
+		starts := !e.Time.IsZero()
+		switch {
+		case timed:
+		case leveled:
+			starts = e.Level != ""
+		default:
+			starts = true
+		}
+		if starts || i == 0 || (timed && entries[len(entries)-1].Time.IsZero()) {
+			entries = append(entries, e)
+			continue
+		}
+		last := entries[len(entries)-1]
+		last.Lines = append(last.Lines, e.Lines...)
+	}
+	return entries
+}
+
+func parseLine(source, line string) *Entry {
+	e := &Entry{Source: source, Lines: []string{line}, message: line}
+	if m := leadingTime.FindStringSubmatchIndex(line); m != nil {
+		e.Time = parseTime(line[m[2]:m[3]])
+		if !e.Time.IsZero() {
+			e.message = line[m[1]:]
+		}
+	} else if m := fieldTime.FindStringSubmatch(line); m != nil {
+		e.Time = parseTime(m[1])
+	}
+	if m := fieldLevel.FindStringSubmatch(line); m != nil && rank(normalizeLevel(m[1])) >= 0 {
+		e.Level = normalizeLevel(m[1])
+	} else if m := wordLevel.FindStringSubmatch(line); m != nil {
+		e.Level = normalizeLevel(m[1] + m[2])
+	}
+	return e
+}
+
+// Parses timestamp {s} returning the zero time when it's invalid.  Timestamps without a zone are UTC
+func parseTime(s string) time.Time {
+	s = strings.Replace(s, ",", ".", 1)
+	if len(s) >= 19 {
+		s = strings.Replace(s[:10], "/", "-", -1) + "T" + s[11:]
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func normalizeLevel(level string) string {
+	level = strings.ToUpper(level)
+	if s, ok := synonyms[level]; ok {
+		return s
+	}
+	return level
+}
+
+// Returns the severity of {level} or -1 when it isn't a level
+func rank(level string) int {
+	for i, l := range Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// Stream selects the entries of a log read in chunks (eg. while it's followed)
+type Stream struct {
+	Source string
+	// the incomplete last line of the previous chunk
+	partial string
+	read    bool
+	timed   bool
+	// whether the last entry was kept so the lines continuing it in the next chunk are
+	kept bool
+}
+
+// Read returns the entries of the complete lines read so far with {chunk} which are selected by {f}.  Tail
+// applies to the first chunk only
+func (s *Stream) Read(chunk string, f *Filter) []*Entry {
+	text := s.partial + chunk
+	end := strings.LastIndex(text, "\n")
+	if end < 0 {
+		s.partial = text
+		return []*Entry{}
+	}
+	s.partial = text[end+1:]
+
+	entries := Parse(s.Source, text[:end])
+	for _, e := range entries {
+		s.timed = s.timed || !e.Time.IsZero()
+	}
+	selected := []*Entry{}
+	for i, e := range entries {
+		// lines without a timestamp continue the previous entry (eg. a stack trace read in the next chunk)
+		keep := s.kept
+		if !s.timed || !e.Time.IsZero() || (i == 0 && !s.read) {
+			keep = f.Keeps(e, s.timed)
+			s.kept = keep
+		}
+		if keep {
+			selected = append(selected, e)
+		}
+	}
+	if !s.read && f != nil && f.Tail > 0 && len(selected) > f.Tail {
+		selected = selected[len(selected)-f.Tail:]
+	}
+	s.read = true
+	return selected
+}
+
+// Merge interleaves the entries of {logs} (each in the order logged) by time.  Entries without a timestamp
+// follow the entry they were logged after
+func Merge(logs ...[]*Entry) []*Entry {
+	merged := []*Entry{}
+	next := make([]int, len(logs))
+	for {
+		pick := -1
+		for i, entries := range logs {
+			if next[i] >= len(entries) {
+				continue
+			}
+			e := entries[next[i]]
+			if e.Time.IsZero() {
+				pick = i
+				break
+			}
+			if pick < 0 || e.Time.Before(logs[pick][next[pick]].Time) {
+				pick = i
+			}
+		}
+		if pick < 0 {
+			return merged
+		}
+		merged = append(merged, logs[pick][next[pick]])
+		next[pick]++
+	}
+}
+
+// Format returns the lines of entry {e}.  Normalized entries start with their timestamp in UTC (TimeLayout)
+// and source in place of the timestamp they were logged with
+func Format(e *Entry, normalize bool) string {
+	if !normalize {
+		return strings.Join(e.Lines, "\n")
+	}
+	stamp := strings.Repeat(" ", len(TimeLayout))
+	if !e.Time.IsZero() {
+		stamp = e.Time.Format(TimeLayout)
+	}
+	lines := append([]string{fmt.Sprintf("%s %s %s", stamp, e.Source, e.message)}, e.Lines[1:]...)
+	return strings.Join(lines, "\n")
+}
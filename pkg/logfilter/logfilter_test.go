@@ -0,0 +1,111 @@
+package logfilter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const javaLog = `2024-05-01 10:00:00,120 INFO  Starting server
+2024-05-01 10:04:00,300 ERROR Request failed
+java.lang.IllegalStateException: closed
+	at com.example.Server.handle(Server.java:42)
+2024-05-01 10:05:00,000 WARN  Slow request /orders
+`
+
+func messages(entries []*Entry) []string {
+	result := []string{}
+	for _, e := range entries {
+		result = append(result, e.Lines[0])
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	entries := Parse("web.1", javaLog)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 4, 0, 300000000, time.UTC), entries[1].Time)
+	assert.Equal(t, "ERROR", entries[1].Level)
+	assert.Len(t, entries[1].Lines, 3, "the stack trace continues the entry")
+	assert.Equal(t, "WARN", entries[2].Level)
+
+	entries = Parse("web.1", `{"level":"warning","ts":"2024-05-01T10:00:00+02:00","msg":"retrying"}
+time="2024-05-01T08:30:00Z" level=error msg="gave up"
+2024/05/01 08:31:00 [error] 12#12: upstream timed out`)
+	assert.Equal(t, "WARN", entries[0].Level)
+	assert.Equal(t, time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), entries[0].Time)
+	assert.Equal(t, "ERROR", entries[1].Level)
+	assert.Equal(t, time.Date(2024, 5, 1, 8, 31, 0, 0, time.UTC), entries[2].Time)
+	assert.Equal(t, "ERROR", entries[2].Level)
+
+	// logs without timestamps start entries at lines with a level
+	entries = Parse("web.1", "INFO ready\nERROR failed\n  caused by timeout\nplain")
+	assert.Equal(t, []string{"INFO ready", "ERROR failed"}, messages(entries))
+	assert.Len(t, entries[1].Lines, 3)
+
+	assert.Len(t, Parse("web.1", "one\ntwo\n"), 2)
+	assert.Empty(t, Parse("web.1", ""))
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 10, 0, 0, time.UTC)
+	entries := Parse("web.1", javaLog)
+
+	f, err := New(8*time.Minute, 0, "", "", now)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2024-05-01 10:04:00,300 ERROR Request failed", "2024-05-01 10:05:00,000 WARN  Slow request /orders"}, messages(f.Apply(entries)))
+
+	f, _ = New(0, 0, "", "warning", now)
+	assert.Len(t, f.Apply(entries), 2)
+	f, _ = New(0, 0, "", "ERROR", now)
+	assert.Len(t, f.Apply(entries), 1)
+
+	f, _ = New(0, 0, "IllegalState", "", now)
+	assert.Len(t, f.Apply(entries), 1, "continuation lines are matched")
+	f, _ = New(0, 2, "", "", now)
+	assert.Equal(t, "2024-05-01 10:04:00,300 ERROR Request failed", f.Apply(entries)[0].Lines[0])
+
+	// --since doesn't apply to logs without timestamps
+	f, _ = New(time.Minute, 0, "", "", now)
+	assert.Len(t, f.Apply(Parse("web.1", "one\ntwo")), 2)
+
+	_, err = New(0, 0, "(", "", now)
+	assert.Error(t, err)
+	_, err = New(0, 0, "", "LOUD", now)
+	assert.EqualError(t, err, "'LOUD' is not a valid level - must be one of TRACE, DEBUG, INFO, WARN, ERROR, FATAL")
+	f, _ = New(0, 0, "", "", now)
+	assert.True(t, f.IsZero())
+}
+
+func TestMergeAndFormat(t *testing.T) {
+	a := Parse("web.1", "2024-05-01T10:00:00Z first\n2024-05-01T10:00:02Z third\n  detail")
+	b := Parse("web.2", "[2024-05-01T12:00:01+02:00] second")
+	merged := Merge(a, b)
+
+	lines := []string{}
+	for _, e := range merged {
+		lines = append(lines, Format(e, true))
+	}
+	assert.Equal(t, `2024-05-01T10:00:00.000Z web.1 first
+2024-05-01T10:00:01.000Z web.2 second
+2024-05-01T10:00:02.000Z web.1 third
+  detail`, strings.Join(lines, "\n"))
+	assert.Equal(t, "2024-05-01T10:00:02Z third\n  detail", Format(merged[2], false))
+}
+
+func TestStream(t *testing.T) {
+	f, _ := New(0, 1, "", "ERROR", time.Now())
+	s := &Stream{Source: "web.1"}
+	entries := s.Read("2024-05-01T10:00:00Z ERROR one\n2024-05-01T10:00:01Z ERROR two\n2024-05-01T10:00:02Z INFO", f)
+	assert.Equal(t, []string{"2024-05-01T10:00:01Z ERROR two"}, messages(entries), "the incomplete line is held back")
+
+	entries = s.Read(" three\n2024-05-01T10:00:03Z ERROR four\n  at Main.java\n", f)
+	assert.Equal(t, []string{"2024-05-01T10:00:03Z ERROR four"}, messages(entries))
+	assert.Len(t, entries[0].Lines, 2)
+
+	// the stack trace of an entry kept continues in the next chunk
+	entries = s.Read("  at Thread.java\n2024-05-01T10:00:04Z ERROR five\n", f)
+	assert.Equal(t, []string{"  at Thread.java", "2024-05-01T10:00:04Z ERROR five"}, messages(entries), "tail applies to the first chunk only")
+}
@@ -0,0 +1,122 @@
+package logforward
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logfilter"
+)
+
+// DefaultMaxPending is the number of records kept while the sink is unavailable
+const DefaultMaxPending = 10000
+
+// TaskLog is the log of a stream (stdout or stderr) of a task as read from its sandbox
+type TaskLog struct {
+	Task   string
+	Stream string
+	Log    string
+}
+
+// Forwarder polls the logs of the tasks of an application and ships the entries logged since the previous
+// poll to a sink
+type Forwarder struct {
+	App  string
+	Sink Sink
+	// Fetch returns the logs of the running tasks
+	Fetch func() ([]*TaskLog, error)
+	// Ships the logs written before forwarding started.  Otherwise only the entries logged afterwards are
+	FromStart bool
+	// Records kept while the sink is unavailable before the oldest are dropped.  Default: DefaultMaxPending
+	MaxPending int
+
+	streams map[string]*logfilter.Stream
+	read    map[string]int
+	pending []*Record
+	polled  bool
+}
+
+// Run polls the logs every {interval} until {ctx} is done.  Failures to fetch or ship the logs are logged and
+// retried with the next poll
+func (f *Forwarder) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := f.Poll(time.Now()); err != nil {
+			log.Warning("Forwarding the logs of '%s' failed: %s (%d records pending)", f.App, err.Error(), len(f.pending))
+		} else if n > 0 {
+			log.Debug("Forwarded %d records of '%s'", n, f.App)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the logs and ships the entries logged since the previous poll along with any records a
+// failed poll left pending.  Returns the number of records shipped.  Entries without a timestamp are given
+// {now}
+func (f *Forwarder) Poll(now time.Time) (int, error) {
+	if f.streams == nil {
+		f.streams, f.read = map[string]*logfilter.Stream{}, map[string]int{}
+	}
+	logs, err := f.Fetch()
+	if err != nil {
+		return 0, err
+	}
+
+	seen := map[string]bool{}
+	for _, l := range logs {
+		key := l.Task + "/" + l.Stream
+		seen[key] = true
+		s, ok := f.streams[key]
+		if !ok || len(l.Log) < f.read[key] {
+			// a new task or a rotated log
+			s = &logfilter.Stream{Source: l.Task}
+			f.streams[key], f.read[key] = s, 0
+			if !ok && !f.polled && !f.FromStart {
+				// skip to the end of the last complete line
+				f.read[key] = strings.LastIndex(l.Log, "\n") + 1
+			}
+		}
+		for _, e := range s.Read(l.Log[f.read[key]:], nil) {
+			f.pending = append(f.pending, f.record(l, e, now))
+		}
+		f.read[key] = len(l.Log)
+	}
+	for key := range f.streams {
+		if !seen[key] {
+			delete(f.streams, key)
+			delete(f.read, key)
+		}
+	}
+	f.polled = true
+
+	max := f.MaxPending
+	if max <= 0 {
+		max = DefaultMaxPending
+	}
+	if dropped := len(f.pending) - max; dropped > 0 {
+		log.Warning("Dropped %d records of '%s' the sink hasn't accepted", dropped, f.App)
+		f.pending = f.pending[dropped:]
+	}
+	if len(f.pending) == 0 {
+		return 0, nil
+	}
+	if err := f.Sink.Send(f.pending); err != nil {
+		return 0, err
+	}
+	n := len(f.pending)
+	f.pending = nil
+	return n, nil
+}
+
+func (f *Forwarder) record(l *TaskLog, e *logfilter.Entry, now time.Time) *Record {
+	t := e.Time
+	if t.IsZero() {
+		t = now
+	}
+	return &Record{Time: t, App: f.App, Task: l.Task, Stream: l.Stream, Level: e.Level, Message: logfilter.Format(e, false)}
+}
@@ -0,0 +1,226 @@
+// Ships the logs of tasks to a central sink (syslog or Elasticsearch) along with the application and task
+// they were logged by
+package logforward
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	SchemeSyslog    = "syslog"
+	SchemeSyslogTCP = "syslog+tcp"
+	SchemeES        = "es"
+	SchemeESHTTPS   = "es+https"
+
+	// DefaultIndex is the Elasticsearch index records are written to when the url has no path
+	DefaultIndex = "depcon-logs"
+)
+
+var log = logger.GetLogger("depcon.logforward")
+
+// Record is a log entry of a task along with its metadata
+type Record struct {
+	Time time.Time `json:"@timestamp"`
+	// Application the task belongs to
+	App  string `json:"app"`
+	Task string `json:"task"`
+	// stdout or stderr
+	Stream  string `json:"stream"`
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message"`
+}
+
+// Sink receives the records of the tasks
+type Sink interface {
+	// Send ships {records}.  Records are kept by the caller and sent again when an error is returned
+	Send(records []*Record) error
+	Close() error
+}
+
+// New returns the sink of {rawurl}: syslog://host:514 (UDP), syslog+tcp://host:514, es://host:9200/index
+// or es+https://host:9200/index
+func New(rawurl string) (Sink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("'%s' has no host", rawurl)
+	}
+	switch u.Scheme {
+	case SchemeSyslog:
+		return &syslogSink{network: "udp", addr: withPort(u.Host, "514")}, nil
+	case SchemeSyslogTCP:
+		return &syslogSink{network: "tcp", addr: withPort(u.Host, "514")}, nil
+	case SchemeES, SchemeESHTTPS:
+		return newESSink(u), nil
+	}
+	return nil, fmt.Errorf("'%s' is not a log sink - must be %s://, %s://, %s:// or %s://", rawurl, SchemeSyslog, SchemeSyslogTCP, SchemeES, SchemeESHTTPS)
+}
+
+func withPort(host, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// syslogSink writes RFC 5424 messages to a syslog server
+type syslogSink struct {
+	network string
+	addr    string
+	conn    net.Conn
+}
+
+func (s *syslogSink) Send(records []*Record) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.addr, 10*time.Second)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	for _, r := range records {
+		msg := SyslogMessage(r)
+		if s.network == "tcp" {
+			// octet counting framing (RFC 6587)
+			msg = fmt.Sprintf("%d %s", len(msg), msg)
+		}
+		if _, err := s.conn.Write([]byte(msg)); err != nil {
+			// reconnect with the next send
+			s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// syslog severities of the levels.  Entries without a level are notices on stderr and informational on stdout
+var severities = map[string]int{"FATAL": 2, "ERROR": 3, "WARN": 4, "INFO": 6, "DEBUG": 7, "TRACE": 7}
+
+// facility user-level messages
+const facilityUser = 1
+
+// SyslogMessage returns {r} as an RFC 5424 message.  The application is the APP-NAME, the task the PROCID
+// and the metadata is also carried as structured data
+func SyslogMessage(r *Record) string {
+	severity, ok := severities[r.Level]
+	if !ok {
+		severity = 6
+		if r.Stream == "stderr" {
+			severity = 5
+		}
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("<%d>1 %s %s %s %s - [depcon app=\"%s\" task=\"%s\" stream=\"%s\"] %s",
+		facilityUser*8+severity, r.Time.UTC().Format(time.RFC3339Nano), header(host, 255), header(r.App, 48),
+		header(r.Task, 128), param(r.App), param(r.Task), param(r.Stream), r.Message)
+}
+
+// Returns {s} as a header field: printable characters without spaces of at most {max} or - when empty
+func header(s string, max int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= 32 || r >= 127 {
+			return -1
+		}
+		return r
+	}, strings.TrimPrefix(s, "/"))
+	if len(s) > max {
+		s = s[:max]
+	}
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Escapes {s} as a structured data param value
+func param(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// esSink indexes records with the Elasticsearch bulk API
+type esSink struct {
+	url    string
+	index  string
+	user   *url.Userinfo
+	client *http.Client
+}
+
+func newESSink(u *url.URL) *esSink {
+	scheme := "http"
+	if u.Scheme == SchemeESHTTPS {
+		scheme = "https"
+	}
+	index := strings.Trim(u.Path, "/")
+	if index == "" {
+		index = DefaultIndex
+	}
+	return &esSink{
+		url:    fmt.Sprintf("%s://%s/_bulk", scheme, u.Host),
+		index:  index,
+		user:   u.User,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *esSink) Send(records []*Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range records {
+		enc.Encode(map[string]map[string]string{"index": {"_index": s.index}})
+		enc.Encode(r)
+	}
+	req, err := http.NewRequest("POST", s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.user != nil {
+		password, _ := s.user.Password()
+		req.SetBasicAuth(s.user.Username(), password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Elasticsearch responded with %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	result := struct {
+		Errors bool `json:"errors"`
+	}{}
+	if json.Unmarshal(data, &result) == nil && result.Errors {
+		// the accepted records would be indexed twice if the batch was sent again
+		log.Warning("Elasticsearch rejected some of the %d records", len(records))
+	}
+	return nil
+}
+
+func (s *esSink) Close() error {
+	return nil
+}
@@ -16,6 +16,7 @@ var format = logging.MustStringFormatter(
 )
 
 func init() {
+	terminalFormat = format
 	backend := logging.NewLogBackend(os.Stdout, "", 0)
 	backendFmt := logging.NewBackendFormatter(backend, format)
 	logging.SetBackend(backendFmt)
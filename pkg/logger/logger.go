@@ -48,3 +48,8 @@ func GetLogger(module string) *logging.Logger {
 func Logger() *logging.Logger {
 	return dlog
 }
+
+// Determines if the specified level is enabled for the module
+func IsEnabled(level LogLevel, module string) bool {
+	return logging.GetLevel(module) >= level.unWrap()
+}
@@ -0,0 +1,205 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/mask"
+	"github.com/op/go-logging"
+)
+
+const (
+	// FormatText writes each record as a line of text with the fields appended as key=value
+	FormatText = "text"
+	// FormatJSON writes each record as a JSON object per line with the fields as properties
+	FormatJSON = "json"
+
+	// Common field names
+	FieldEnv        = "env"
+	FieldApp        = "app"
+	FieldDeployment = "deployment"
+	FieldDuration   = "duration"
+)
+
+var ErrorUnknownFormat = errors.New("Unknown log format - must be text or json")
+
+// Formatter of text written to files which must not contain colors
+var plainFormat = logging.MustStringFormatter(
+	"%{time:2006-01-02 15:04:05} %{level:.7s} [%{module}]: %{message}",
+)
+
+// Formatter of text written to stdout.  Overridden on platforms supporting colors
+var terminalFormat = plainFormat
+
+var (
+	mu         sync.RWMutex
+	jsonOutput bool
+	// fields added to every JSON record (eg. the environment)
+	globalFields = Fields{}
+)
+
+// Fields are the properties of a structured log record.  When passed as the last argument of a log call
+// they are written as JSON properties or appended to the text of the message
+type Fields map[string]interface{}
+
+// Entry logs to a logger with fields
+type Entry struct {
+	log    *logging.Logger
+	fields Fields
+}
+
+// Configure writes log records to {w} as {format} (text or json) with secrets masked.  Levels must be set
+// afterwards since changing the output resets them
+func Configure(format string, w io.Writer) error {
+	var backend logging.Backend
+	switch format {
+	case FormatText, "":
+		f := plainFormat
+		if w == os.Stdout {
+			f = terminalFormat
+		}
+		backend = logging.NewBackendFormatter(logging.NewLogBackend(maskingWriter{w}, "", 0), f)
+	case FormatJSON:
+		backend = &jsonBackend{w: maskingWriter{w}}
+	default:
+		return ErrorUnknownFormat
+	}
+	mu.Lock()
+	jsonOutput = format == FormatJSON
+	mu.Unlock()
+	logging.SetBackend(backend)
+	return nil
+}
+
+// SetGlobalFields sets the fields added to every JSON record (eg. the environment)
+func SetGlobalFields(fields Fields) {
+	mu.Lock()
+	defer mu.Unlock()
+	globalFields = fields
+}
+
+// With returns an entry logging to {log} with {fields}
+func With(log *logging.Logger, fields Fields) *Entry {
+	return &Entry{log: log, fields: fields}
+}
+
+// With returns an entry with {fields} in addition to the fields of the entry
+func (e *Entry) With(fields Fields) *Entry {
+	merged := Fields{}
+	for k, v := range e.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Entry{log: e.log, fields: merged}
+}
+
+func (e *Entry) Debug(format string, args ...interface{}) {
+	e.log.Debug(format+"%v", append(args, e.fields)...)
+}
+
+func (e *Entry) Info(format string, args ...interface{}) {
+	e.log.Info(format+"%v", append(args, e.fields)...)
+}
+
+func (e *Entry) Notice(format string, args ...interface{}) {
+	e.log.Notice(format+"%v", append(args, e.fields)...)
+}
+
+func (e *Entry) Warning(format string, args ...interface{}) {
+	e.log.Warning(format+"%v", append(args, e.fields)...)
+}
+
+func (e *Entry) Error(format string, args ...interface{}) {
+	e.log.Error(format+"%v", append(args, e.fields)...)
+}
+
+// String renders the fields as key=value pairs with a leading space for text output and nothing for JSON
+// output where they are written as properties
+func (f Fields) String() string {
+	mu.RLock()
+	asJSON := jsonOutput
+	mu.RUnlock()
+	if asJSON || len(f) == 0 {
+		return ""
+	}
+	pairs := []string{}
+	for _, k := range f.keys() {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, fieldValue(f[k])))
+	}
+	return " " + strings.Join(pairs, " ")
+}
+
+func (f Fields) keys() []string {
+	keys := []string{}
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type jsonBackend struct {
+	sync.Mutex
+	w io.Writer
+}
+
+func (b *jsonBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	record := map[string]interface{}{}
+	mu.RLock()
+	for k, v := range globalFields {
+		record[k] = v
+	}
+	mu.RUnlock()
+	for _, arg := range rec.Args {
+		if fields, ok := arg.(Fields); ok {
+			for k, v := range fields {
+				record[k] = fieldValue(v)
+			}
+		}
+	}
+	record["time"] = rec.Time.Format(time.RFC3339)
+	record["level"] = strings.ToLower(level.String())
+	record["module"] = rec.Module
+	record["msg"] = rec.Message()
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	b.Lock()
+	defer b.Unlock()
+	_, err = b.w.Write(append(line, '\n'))
+	return err
+}
+
+// maskingWriter masks the secrets within each record written to {w}
+type maskingWriter struct {
+	w io.Writer
+}
+
+func (m maskingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(m.w, mask.Text(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Durations are written as strings rounded to milliseconds (eg. 1m30.25s) and errors as their message
+func fieldValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case time.Duration:
+		return t.Round(time.Millisecond).String()
+	case error:
+		return t.Error()
+	}
+	return v
+}
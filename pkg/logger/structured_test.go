@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, Configure(FormatJSON, buf))
+	defer Configure(FormatText, os.Stdout)
+	SetGlobalFields(Fields{FieldEnv: "prod"})
+	defer SetGlobalFields(Fields{})
+	SetLevel(INFO, "test.json")
+
+	log := GetLogger("test.json")
+	With(log, Fields{FieldApp: "/web"}).With(Fields{FieldDuration: 90 * time.Second}).Info("Deployment of %s has completed", "/web")
+	log.Debug("not enabled")
+
+	record := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "Deployment of /web has completed", record["msg"])
+	assert.Equal(t, "info", record["level"])
+	assert.Equal(t, "test.json", record["module"])
+	assert.Equal(t, "prod", record[FieldEnv])
+	assert.Equal(t, "/web", record[FieldApp])
+	assert.Equal(t, "1m30s", record[FieldDuration])
+}
+
+func TestTextFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	assert.Nil(t, Configure(FormatText, buf))
+	defer Configure(FormatText, os.Stdout)
+	SetLevel(INFO, "test.text")
+
+	With(GetLogger("test.text"), Fields{FieldDeployment: "d-1", FieldApp: "/web"}).Warning("Deployment failed")
+	assert.Contains(t, buf.String(), "WARNING [test.text]: Deployment failed app=/web deployment=d-1\n")
+
+	assert.Equal(t, ErrorUnknownFormat, Configure("xml", buf))
+}
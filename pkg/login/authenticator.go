@@ -0,0 +1,97 @@
+package login
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// suffix of the cache key of refresh tokens
+	refreshSuffix = ".refresh"
+	// tokens expiring within this margin are refreshed before they're used
+	refreshMargin = time.Minute
+)
+
+// Cache persists tokens and their expiry between invocations (eg. within the OS keyring)
+type Cache interface {
+	Load(key string) string
+	Store(key, token string) error
+	LoadExpiry(key string) time.Time
+	StoreExpiry(key string, expires time.Time) error
+}
+
+// LoginRequiredError is returned when an environment has no valid token and it can't be refreshed
+type LoginRequiredError struct {
+	Environment string
+	Expired     bool
+}
+
+func (e *LoginRequiredError) Error() string {
+	if e.Expired {
+		return fmt.Sprintf("The login to environment '%s' has expired - run 'depcon login -e %s'", e.Environment, e.Environment)
+	}
+	return fmt.Sprintf("Environment '%s' requires a login - run 'depcon login -e %s'", e.Environment, e.Environment)
+}
+
+// Save stores {token} of the environment {env} within {cache}
+func Save(cache Cache, env string, token *Token) error {
+	if err := cache.Store(env, token.AccessToken); err != nil {
+		return err
+	}
+	if !token.Expires.IsZero() {
+		if err := cache.StoreExpiry(env, token.Expires); err != nil {
+			return err
+		}
+	}
+	return cache.Store(env+refreshSuffix, token.RefreshToken)
+}
+
+// Authenticator sends the token of the last login as a bearer token refreshing OIDC tokens as they expire.
+// Implements httpclient.Authenticator
+type Authenticator struct {
+	sync.Mutex
+	Environment string
+	Cache       Cache
+	// Refreshes the tokens of OIDC logins.  nil for logins whose tokens can't be refreshed (eg. LDAP)
+	OIDC    *OIDC
+	token   string
+	expires time.Time
+}
+
+func (a *Authenticator) Token(refresh bool) (string, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.token == "" {
+		a.token = a.Cache.Load(a.Environment)
+		a.expires = a.Cache.LoadExpiry(a.Environment)
+	}
+	if a.token == "" {
+		return "", &LoginRequiredError{Environment: a.Environment}
+	}
+	if !refresh && (a.expires.IsZero() || time.Until(a.expires) > refreshMargin) {
+		return a.token, nil
+	}
+
+	refreshToken := a.Cache.Load(a.Environment + refreshSuffix)
+	if a.OIDC == nil || refreshToken == "" {
+		return "", &LoginRequiredError{Environment: a.Environment, Expired: true}
+	}
+	log.Debug("Refreshing the token of environment '%s'", a.Environment)
+	token, err := a.OIDC.Refresh(refreshToken)
+	if err != nil {
+		log.Debug("Unable to refresh the token of environment '%s': %s", a.Environment, err.Error())
+		return "", &LoginRequiredError{Environment: a.Environment, Expired: true}
+	}
+	if err := Save(a.Cache, a.Environment, token); err != nil {
+		log.Warning("Unable to cache the token of environment '%s': %s", a.Environment, err.Error())
+	}
+	a.token, a.expires = token.AccessToken, token.Expires
+	return a.token, nil
+}
+
+func (a *Authenticator) Apply(req *http.Request, token string) {
+	req.Header.Set("Authorization", "Bearer "+token)
+}
@@ -0,0 +1,44 @@
+package login
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// LDAP exchanges a username and password for a token at the login endpoint of an identity-aware proxy
+type LDAP struct {
+	Config *Config
+	// Optional client used to reach the proxy (eg. with the CA of the environment)
+	Client *http.Client
+}
+
+// Login posts {username} and {password} to the login URL returning the token issued.  Responses holding
+// either an access_token (with expires_in) or a token are accepted
+func (l *LDAP) Login(username, password string) (*Token, error) {
+	body, _ := json.Marshal(map[string]string{"username": username, "password": password})
+	resp, err := httpClient(l.Client).Post(l.Config.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, ErrorLoginRejected
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("The login failed (%d)", resp.StatusCode)
+	}
+	result := &tokenResponse{}
+	if err := decodeJSON(resp.Body, result); err != nil {
+		return nil, err
+	}
+	token := result.token()
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("%s returned no token", l.Config.URL)
+	}
+	// the credentials aren't kept so the token can't be refreshed
+	token.RefreshToken = ""
+	return token, nil
+}
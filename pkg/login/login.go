@@ -0,0 +1,153 @@
+// Interactive logins against clusters fronted by an identity-aware proxy: the OIDC device code flow and the
+// exchange of LDAP credentials for a token.  The tokens obtained are sent as bearer tokens and OIDC tokens
+// are refreshed with their refresh token until it expires
+package login
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	MethodOIDC = "oidc"
+	MethodLDAP = "ldap"
+
+	// discovery document of an OIDC issuer
+	wellKnownPath = "/.well-known/openid-configuration"
+	// lifetime assumed for tokens which don't state it
+	defaultTokenLifetime = time.Hour
+)
+
+var (
+	log = logger.GetLogger("client")
+
+	ErrorNoIssuer      = errors.New("OIDC logins require the issuer and client id of the identity provider")
+	ErrorNoLoginURL    = errors.New("LDAP logins require the URL credentials are exchanged for a token at")
+	ErrorNoDeviceFlow  = errors.New("The identity provider does not support the device code flow")
+	ErrorAccessDenied  = errors.New("The login was denied")
+	ErrorLoginExpired  = errors.New("The login code expired before it was approved - run the login again")
+	ErrorLoginRejected = errors.New("The login was rejected - verify the username and password")
+)
+
+// Config is the identity provider of an environment
+type Config struct {
+	// OIDC issuer URL (eg. https://login.example.com/realms/ops)
+	Issuer string `json:"issuer,omitempty"`
+	// OIDC client id registered for depcon (a public client allowing the device code grant)
+	ClientID string `json:"clientId,omitempty"`
+	// OIDC scopes requested.  Default: openid offline_access
+	Scopes []string `json:"scopes,omitempty"`
+	// Optional audience of the token required by some proxies
+	Audience string `json:"audience,omitempty"`
+	// URL LDAP credentials are posted to in exchange for a token (eg. https://proxy.example.com/login)
+	URL string `json:"url,omitempty"`
+}
+
+// Validate returns an error unless the settings required by the login {method} are present
+func (c *Config) Validate(method string) error {
+	switch method {
+	case MethodOIDC:
+		if c == nil || c.Issuer == "" || c.ClientID == "" {
+			return ErrorNoIssuer
+		}
+	case MethodLDAP:
+		if c == nil || c.URL == "" {
+			return ErrorNoLoginURL
+		}
+	default:
+		return fmt.Errorf("Unsupported login method '%s' - must be '%s' or '%s'", method, MethodOIDC, MethodLDAP)
+	}
+	return nil
+}
+
+// Token is the outcome of a login
+type Token struct {
+	AccessToken  string
+	RefreshToken string
+	Expires      time.Time
+}
+
+// tokenResponse is the token endpoint's response (RFC 6749 5.1) or error (5.2)
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	IDToken          string `json:"id_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	// token of proxies exchanging LDAP credentials which don't follow RFC 6749
+	Token string `json:"token"`
+}
+
+func (r *tokenResponse) token() *Token {
+	t := &Token{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken}
+	if t.AccessToken == "" {
+		t.AccessToken = r.Token
+	}
+	if r.ExpiresIn > 0 {
+		t.Expires = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	} else if t.Expires = expiryOf(t.AccessToken); t.Expires.IsZero() {
+		t.Expires = time.Now().Add(defaultTokenLifetime)
+	}
+	return t
+}
+
+// Returns the exp claim of the JWT {token} or the zero time when it isn't a JWT
+func expiryOf(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	claims := struct {
+		Exp int64 `json:"exp"`
+	}{}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// Posts {form} to {endpoint} decoding the response into {result}.  Error responses of the token endpoint are
+// decoded as well so the caller can act on the error code
+func postForm(client *http.Client, endpoint string, form url.Values, result interface{}) (int, error) {
+	resp, err := httpClient(client).PostForm(endpoint, form)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, decodeJSON(resp.Body, result)
+}
+
+func decodeJSON(body io.Reader, result interface{}) error {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("Invalid response from the identity provider: %s", err.Error())
+	}
+	return nil
+}
+
+func httpClient(client *http.Client) *http.Client {
+	if client == nil {
+		return http.DefaultClient
+	}
+	return client
+}
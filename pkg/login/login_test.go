@@ -0,0 +1,138 @@
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryCache struct {
+	tokens   map[string]string
+	expiries map[string]time.Time
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{tokens: map[string]string{}, expiries: map[string]time.Time{}}
+}
+
+func (m *memoryCache) Load(key string) string { return m.tokens[key] }
+func (m *memoryCache) Store(key, token string) error {
+	m.tokens[key] = token
+	return nil
+}
+func (m *memoryCache) LoadExpiry(key string) time.Time { return m.expiries[key] }
+func (m *memoryCache) StoreExpiry(key string, expires time.Time) error {
+	m.expiries[key] = expires
+	return nil
+}
+
+// Returns an identity provider approving device codes on the second poll and refreshing "refresh-1"
+func newProvider(t *testing.T) *httptest.Server {
+	polls := 0
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case wellKnownPath:
+			json.NewEncoder(w).Encode(&discovery{DeviceAuthorizationEndpoint: s.URL + "/device", TokenEndpoint: s.URL + "/token"})
+		case "/device":
+			assert.Equal(t, "depcon", r.Form.Get("client_id"))
+			fmt.Fprint(w, `{"device_code": "dc", "user_code": "ABCD-EFGH", "verification_uri": "https://login/device", "interval": 1}`)
+		case "/token":
+			switch r.Form.Get("grant_type") {
+			case grantDeviceCode:
+				if polls++; polls == 1 {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprint(w, `{"error": "authorization_pending"}`)
+					return
+				}
+				fmt.Fprint(w, `{"access_token": "access-1", "refresh_token": "refresh-1", "expires_in": 300}`)
+			case grantRefreshToken:
+				if r.Form.Get("refresh_token") != "refresh-1" {
+					w.WriteHeader(http.StatusBadRequest)
+					fmt.Fprint(w, `{"error": "invalid_grant"}`)
+					return
+				}
+				fmt.Fprint(w, `{"access_token": "access-2", "expires_in": 300}`)
+			}
+		}
+	}))
+	return s
+}
+
+func TestDeviceFlow(t *testing.T) {
+	s := newProvider(t)
+	defer s.Close()
+
+	var prompted *DeviceCode
+	o := &OIDC{Config: &Config{Issuer: s.URL, ClientID: "depcon"}, Prompt: func(code *DeviceCode) { prompted = code }}
+	token, err := o.Login(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "ABCD-EFGH", prompted.UserCode)
+	assert.Equal(t, "access-1", token.AccessToken)
+	assert.Equal(t, "refresh-1", token.RefreshToken)
+	assert.True(t, time.Until(token.Expires) > 4*time.Minute)
+}
+
+func TestAuthenticatorRefreshesExpiredTokens(t *testing.T) {
+	s := newProvider(t)
+	defer s.Close()
+
+	cache := newMemoryCache()
+	a := &Authenticator{Environment: "prod", Cache: cache, OIDC: &OIDC{Config: &Config{Issuer: s.URL, ClientID: "depcon"}}}
+	_, err := a.Token(false)
+	assert.Equal(t, &LoginRequiredError{Environment: "prod"}, err)
+
+	Save(cache, "prod", &Token{AccessToken: "access-1", RefreshToken: "refresh-1", Expires: time.Now().Add(10 * time.Second)})
+	token, err := a.Token(false)
+	assert.Nil(t, err)
+	assert.Equal(t, "access-2", token)
+	assert.Equal(t, "access-2", cache.tokens["prod"])
+	// the refresh token is kept when a new one isn't issued
+	assert.Equal(t, "refresh-1", cache.tokens["prod"+refreshSuffix])
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	a.Apply(req, token)
+	assert.Equal(t, "Bearer access-2", req.Header.Get("Authorization"))
+
+	// tokens which can't be refreshed require a new login
+	a = &Authenticator{Environment: "stage", Cache: cache}
+	Save(cache, "stage", &Token{AccessToken: "ldap", Expires: time.Now().Add(-time.Minute)})
+	_, err = a.Token(false)
+	assert.EqualError(t, err, "The login to environment 'stage' has expired - run 'depcon login -e stage'")
+}
+
+func TestLDAPLogin(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		creds := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&creds)
+		if creds["username"] != "ops" || creds["password"] != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"token": "ldap-token"}`)
+	}))
+	defer s.Close()
+
+	l := &LDAP{Config: &Config{URL: s.URL}}
+	token, err := l.Login("ops", "s3cret")
+	assert.Nil(t, err)
+	assert.Equal(t, "ldap-token", token.AccessToken)
+	assert.False(t, token.Expires.IsZero())
+
+	_, err = l.Login("ops", "wrong")
+	assert.Equal(t, ErrorLoginRejected, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.Equal(t, ErrorNoIssuer, (&Config{Issuer: "https://login"}).Validate(MethodOIDC))
+	assert.Nil(t, (&Config{Issuer: "https://login", ClientID: "depcon"}).Validate(MethodOIDC))
+	var none *Config
+	assert.Equal(t, ErrorNoLoginURL, none.Validate(MethodLDAP))
+}
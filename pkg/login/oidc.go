@@ -0,0 +1,185 @@
+package login
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	grantDeviceCode   = "urn:ietf:params:oauth:grant-type:device_code"
+	grantRefreshToken = "refresh_token"
+
+	// errors returned while polling for the approval of a device code (RFC 8628 3.5)
+	errorPending   = "authorization_pending"
+	errorSlowDown  = "slow_down"
+	errorDenied    = "access_denied"
+	errorExpired   = "expired_token"
+	defaultScopes  = "openid offline_access"
+	defaultPolling = 5 * time.Second
+)
+
+// DeviceCode is the code the user approves the login with at VerificationURI
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval"`
+}
+
+// endpoints of the discovery document of an issuer
+type discovery struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+}
+
+// OIDC logs in with the device code flow (RFC 8628) of the identity provider of Config
+type OIDC struct {
+	Config *Config
+	// Optional client used to reach the identity provider (eg. with the proxy and CA of the environment)
+	Client *http.Client
+	// Called with the code the user must approve before polling for the approval starts
+	Prompt func(code *DeviceCode)
+}
+
+// Login requests a device code, hands it to Prompt and waits until the user approves (or denies) the login
+// or {ctx} is done
+func (o *OIDC) Login(ctx context.Context) (*Token, error) {
+	endpoints, err := o.discover()
+	if err != nil {
+		return nil, err
+	}
+	if endpoints.DeviceAuthorizationEndpoint == "" {
+		return nil, ErrorNoDeviceFlow
+	}
+
+	form := url.Values{"client_id": {o.Config.ClientID}, "scope": {o.scopes()}}
+	if o.Config.Audience != "" {
+		form.Set("audience", o.Config.Audience)
+	}
+	code := &DeviceCode{}
+	status, err := postForm(o.Client, endpoints.DeviceAuthorizationEndpoint, form, code)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || code.DeviceCode == "" {
+		return nil, fmt.Errorf("The identity provider refused the device code request (%d)", status)
+	}
+	if o.Prompt != nil {
+		o.Prompt(code)
+	}
+	return o.poll(ctx, endpoints.TokenEndpoint, code)
+}
+
+// Polls the token endpoint at the interval requested by the identity provider until the login is approved
+func (o *OIDC) poll(ctx context.Context, endpoint string, code *DeviceCode) (*Token, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPolling
+	}
+	var deadline <-chan time.Time
+	if code.ExpiresIn > 0 {
+		deadline = time.After(time.Duration(code.ExpiresIn) * time.Second)
+	}
+	form := url.Values{"grant_type": {grantDeviceCode}, "device_code": {code.DeviceCode}, "client_id": {o.Config.ClientID}}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline:
+			return nil, ErrorLoginExpired
+		case <-time.After(interval):
+		}
+
+		result := &tokenResponse{}
+		status, err := postForm(o.Client, endpoint, form, result)
+		if err != nil {
+			return nil, err
+		}
+		switch result.Error {
+		case "":
+			if status == http.StatusOK && result.AccessToken != "" {
+				return result.token(), nil
+			}
+			return nil, fmt.Errorf("The identity provider returned no token (%d)", status)
+		case errorPending:
+		case errorSlowDown:
+			interval += defaultPolling
+		case errorDenied:
+			return nil, ErrorAccessDenied
+		case errorExpired:
+			return nil, ErrorLoginExpired
+		default:
+			return nil, providerError(result)
+		}
+	}
+}
+
+// Refresh exchanges {refreshToken} for a new token.  The refresh token is kept when a new one isn't issued
+func (o *OIDC) Refresh(refreshToken string) (*Token, error) {
+	endpoints, err := o.discover()
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{"grant_type": {grantRefreshToken}, "refresh_token": {refreshToken}, "client_id": {o.Config.ClientID}}
+	result := &tokenResponse{}
+	status, err := postForm(o.Client, endpoints.TokenEndpoint, form, result)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK || result.AccessToken == "" {
+		return nil, providerError(result)
+	}
+	token := result.token()
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+	return token, nil
+}
+
+func (o *OIDC) discover() (*discovery, error) {
+	endpoints := &discovery{}
+	resp, err := httpClient(o.Client).Get(strings.TrimRight(o.Config.Issuer, "/") + wellKnownPath)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to read the OIDC configuration of %s (%d)", o.Config.Issuer, resp.StatusCode)
+	}
+	if err := decodeJSON(resp.Body, endpoints); err != nil {
+		return nil, err
+	}
+	if endpoints.TokenEndpoint == "" {
+		return nil, fmt.Errorf("The OIDC configuration of %s has no token endpoint", o.Config.Issuer)
+	}
+	return endpoints, nil
+}
+
+func (o *OIDC) scopes() string {
+	if len(o.Config.Scopes) == 0 {
+		return defaultScopes
+	}
+	return strings.Join(o.Config.Scopes, " ")
+}
+
+func providerError(r *tokenResponse) error {
+	if r.ErrorDescription != "" {
+		return fmt.Errorf("%s: %s", r.Error, r.ErrorDescription)
+	}
+	if r.Error != "" {
+		return fmt.Errorf("The identity provider returned %s", r.Error)
+	}
+	return fmt.Errorf("The identity provider returned no token")
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
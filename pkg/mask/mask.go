@@ -0,0 +1,177 @@
+// Masks the values of secrets (env vars and fields named like a secret and the values of secret params)
+// within output, diffs, logs and HTTP traces
+package mask
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces masked values
+const Placeholder = "[REDACTED]"
+
+// Values shorter than this are not masked wherever they appear since they would mask unrelated text
+const minValueLength = 4
+
+// DefaultPatterns are matched (case insensitive) against the names of env vars, fields, params and
+// headers.  A name containing any of the patterns holds a secret
+var DefaultPatterns = []string{"password", "passwd", "secret", "token", "private_key", "privatekey", "apikey", "api_key", "credential"}
+
+var (
+	mu       sync.RWMutex
+	enabled  = true
+	patterns = DefaultPatterns
+	values   = map[string]bool{}
+	// values sorted longest first so a value containing another is masked whole
+	sorted []string
+
+	// "name": "value"
+	jsonMember = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// name: value (a YAML mapping entry)
+	yamlEntry = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?)([\w.-]+)(:[ \t]+)(\S.*?)[ \t]*$`)
+	// NAME=value (eg. rendered env vars and docker parameters)
+	assignment = regexp.MustCompile(`\b([A-Za-z_][\w.-]*)=([^\s"',]+)`)
+)
+
+// SetEnabled turns masking on (the default) or off (eg. when secrets are explicitly requested)
+func SetEnabled(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = on
+}
+
+// Enabled returns true if secrets are masked
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enabled
+}
+
+// AddPatterns adds {p} to the patterns names holding secrets are matched with
+func AddPatterns(p ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	all := append([]string{}, patterns...)
+	for _, s := range p {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			all = append(all, s)
+		}
+	}
+	patterns = all
+}
+
+// AddValues declares {v} as secrets (eg. resolved secret references and the values of secret params) which
+// are masked wherever they appear
+func AddValues(v ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+	changed := false
+	for _, s := range v {
+		if len(s) >= minValueLength && !values[s] {
+			values[s] = true
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+	sorted = sorted[:0]
+	for s := range values {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+}
+
+// Reset restores the default patterns, forgets the declared values and enables masking
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled, patterns, values, sorted = true, DefaultPatterns, map[string]bool{}, nil
+}
+
+// IsSecretName returns true if {name} (eg. DB_PASSWORD) matches any of the patterns
+func IsSecretName(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	name = strings.ToLower(name)
+	for _, p := range patterns {
+		if strings.Contains(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value returns {value} masked when {name} is named like a secret or {value} is a declared secret and
+// otherwise with the secrets within it masked (eg. a formatted map of env vars)
+func Value(name, value string) string {
+	if !Enabled() || value == "" {
+		return value
+	}
+	if IsSecretName(name) || isValue(value) {
+		return Placeholder
+	}
+	return Redact(value)
+}
+
+// Text masks the secrets within {s}: the values of JSON members, YAML entries and NAME=value assignments
+// named like a secret along with the declared secret values.  {s} is returned as is when masking is off
+func Text(s string) string {
+	if !Enabled() {
+		return s
+	}
+	return Redact(s)
+}
+
+// Redact masks the secrets within {s} as Text does whether or not masking is enabled (eg. for the audit log
+// which outlives the command)
+func Redact(s string) string {
+	s = redactValues(s)
+	s = jsonMember.ReplaceAllStringFunc(s, func(member string) string {
+		m := jsonMember.FindStringSubmatch(member)
+		if !IsSecretName(m[1]) {
+			return member
+		}
+		return `"` + m[1] + `"` + m[2] + `"` + Placeholder + `"`
+	})
+	s = yamlEntry.ReplaceAllStringFunc(s, func(entry string) string {
+		m := yamlEntry.FindStringSubmatch(entry)
+		if !IsSecretName(m[2]) || strings.HasPrefix(m[4], Placeholder) || strings.HasPrefix(m[4], `"`+Placeholder) {
+			return entry
+		}
+		return m[1] + m[2] + m[3] + Placeholder
+	})
+	return assignment.ReplaceAllStringFunc(s, func(a string) string {
+		m := assignment.FindStringSubmatch(a)
+		if !IsSecretName(m[1]) {
+			return a
+		}
+		return m[1] + "=" + Placeholder
+	})
+}
+
+// Values masks the declared secret values within {s} (eg. values selected from output by a query).  {s} is
+// returned as is when masking is off
+func Values(s string) string {
+	if !Enabled() {
+		return s
+	}
+	return redactValues(s)
+}
+
+func redactValues(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, v := range sorted {
+		s = strings.Replace(s, v, Placeholder, -1)
+	}
+	return s
+}
+
+func isValue(s string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return values[s]
+}
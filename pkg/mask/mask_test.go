@@ -0,0 +1,37 @@
+package mask
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestText(t *testing.T) {
+	defer Reset()
+	AddValues("s3cr3t-value", "abc")
+
+	json := `{"env": {"DB_PASSWORD": "hunter22", "PORT": "80", "DSN": "user:s3cr3t-value@db"}}`
+	assert.Equal(t, `{"env": {"DB_PASSWORD": "[REDACTED]", "PORT": "80", "DSN": "user:[REDACTED]@db"}}`, Text(json))
+
+	yaml := "env:\n  API_TOKEN: abcdef\n  PORT: \"80\"\n"
+	assert.Equal(t, "env:\n  API_TOKEN: [REDACTED]\n  PORT: \"80\"\n", Text(yaml))
+
+	assert.Equal(t, "docker run -e GITHUB_TOKEN=[REDACTED] -e PORT=80", Text("docker run -e GITHUB_TOKEN=ghp_123 -e PORT=80"))
+	// values too short to mask safely are left
+	assert.Equal(t, "abc", Text("abc"))
+}
+
+func TestPatternsAndShowSecrets(t *testing.T) {
+	defer Reset()
+	assert.False(t, IsSecretName("DATABASE_DSN"))
+	AddPatterns("dsn")
+	assert.True(t, IsSecretName("DATABASE_DSN"))
+	assert.Equal(t, Placeholder, Value("env.DATABASE_DSN", "postgres://db"))
+	assert.Equal(t, "80", Value("env.PORT", "80"))
+
+	SetEnabled(false)
+	assert.Equal(t, "postgres://db", Value("env.DATABASE_DSN", "postgres://db"))
+	assert.Equal(t, `{"password": "x"}`, Text(`{"password": "x"}`))
+	// the audit log is always redacted
+	assert.Equal(t, `{"password": "[REDACTED]"}`, Redact(`{"password": "x"}`))
+}
@@ -0,0 +1,57 @@
+package mockrest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+// RecordedRequest is a request received by a Recorder with its JSON body decoded
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// Recorder is a server answering with a handler and recording every request.  Used by the tests of the
+// cluster clients to assert the requests they send
+type Recorder struct {
+	URL      string
+	Requests []RecordedRequest
+	server   *httptest.Server
+}
+
+// StartRecorder starts a server answering with {handler} and recording every request
+func StartRecorder(handler http.HandlerFunc) *Recorder {
+	rec := &Recorder{}
+	rec.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := RecordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header}
+		if b, _ := ioutil.ReadAll(r.Body); len(b) > 0 {
+			json.Unmarshal(b, &req.Body)
+		}
+		rec.Requests = append(rec.Requests, req)
+		handler(w, r)
+	}))
+	rec.URL = rec.server.URL
+	return rec
+}
+
+func (rec *Recorder) Close() {
+	rec.server.Close()
+}
+
+// RecordClient starts a Recorder answering with {handler} and has {connect} create the client under test
+// against its URL.  The {retry} policy sends each request once so failures reach the test without backoff.
+// Returns the recorded requests and the function stopping the server
+func RecordClient(handler http.HandlerFunc, connect func(url string, retry *httpclient.RetryPolicy)) (*[]RecordedRequest, func()) {
+	rec := StartRecorder(handler)
+	retry := httpclient.DefaultRetryPolicy()
+	retry.MaxAttempts = 1
+	connect(rec.URL, retry)
+	return &rec.Requests, rec.Close
+}
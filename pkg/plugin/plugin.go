@@ -0,0 +1,99 @@
+// Discovery and execution of depcon-<name> executables found on the PATH
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+const (
+	// executables named {Prefix}{name} become the command 'depcon {name}'
+	Prefix = "depcon-"
+)
+
+// Plugin is an executable extending depcon with the command Name
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// List returns the plugins found within the directories of $PATH sorted by name.  When several
+// executables share a name the first on the PATH wins as it does for the shell
+func List() []*Plugin {
+	return ListDirs(filepath.SplitList(os.Getenv("PATH")))
+}
+
+// ListDirs returns the plugins found within {dirs} sorted by name
+func ListDirs(dirs []string) []*Plugin {
+	found := map[string]*Plugin{}
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name, ok := pluginName(f)
+			if !ok {
+				continue
+			}
+			if _, exists := found[name]; !exists {
+				found[name] = &Plugin{Name: name, Path: filepath.Join(dir, f.Name())}
+			}
+		}
+	}
+
+	plugins := []*Plugin{}
+	for _, p := range found {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// Find returns the plugin providing the command {name}
+func Find(name string) (*Plugin, bool) {
+	for _, p := range List() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Run executes the plugin with {args} attached to the standard streams.  {env} (KEY=VALUE) is added to the
+// environment of depcon.  An *exec.ExitError is returned when the plugin exits with a non-zero status
+func (p *Plugin) Run(args []string, env []string) error {
+	cmd := exec.Command(p.Path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), env...)
+	return cmd.Run()
+}
+
+// Returns the command name of {f} when it is an executable named depcon-<name>
+func pluginName(f os.FileInfo) (string, bool) {
+	name := f.Name()
+	if f.IsDir() || !strings.HasPrefix(name, Prefix) {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(name))
+		if ext != ".exe" && ext != ".bat" && ext != ".cmd" {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	} else if f.Mode()&0111 == 0 {
+		return "", false
+	}
+	name = strings.TrimPrefix(name, Prefix)
+	return name, name != ""
+}
@@ -0,0 +1,52 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDirs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are found by extension on windows")
+	}
+	first, _ := ioutil.TempDir("", "plugins")
+	second, _ := ioutil.TempDir("", "plugins")
+	defer os.RemoveAll(first)
+	defer os.RemoveAll(second)
+
+	ioutil.WriteFile(filepath.Join(first, "depcon-verify"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(filepath.Join(first, "depcon-notes"), []byte("not executable"), 0644)
+	ioutil.WriteFile(filepath.Join(first, "kubectl-foo"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(filepath.Join(second, "depcon-verify"), []byte("#!/bin/sh\n"), 0755)
+	ioutil.WriteFile(filepath.Join(second, "depcon-release-notes"), []byte("#!/bin/sh\n"), 0755)
+	os.Mkdir(filepath.Join(second, "depcon-dir"), 0755)
+
+	plugins := ListDirs([]string{first, "", filepath.Join(first, "missing"), second})
+	assert.Equal(t, []*Plugin{
+		{Name: "release-notes", Path: filepath.Join(second, "depcon-release-notes")},
+		{Name: "verify", Path: filepath.Join(first, "depcon-verify")},
+	}, plugins)
+}
+
+func TestRunPassesEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+	dir, _ := ioutil.TempDir("", "plugins")
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	script := filepath.Join(dir, "depcon-echo")
+	ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"$DEPCON_ENV $@\" > "+out+"\nexit $1\n"), 0755)
+
+	p := &Plugin{Name: "echo", Path: script}
+	assert.Nil(t, p.Run([]string{"0", "--flag"}, []string{"DEPCON_ENV=prod"}))
+	data, _ := ioutil.ReadFile(out)
+	assert.Equal(t, "prod 0 --flag\n", string(data))
+
+	assert.NotNil(t, p.Run([]string{"3"}, nil))
+}
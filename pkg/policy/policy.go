@@ -0,0 +1,211 @@
+// Evaluates descriptors against rego policies using the opa executable so platform teams can enforce
+// guardrails (eg. no :latest images in production) before anything is deployed
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// Query evaluated when none is configured.  Policies add messages to the deny set:
+	//
+	//     package depcon
+	//     deny[msg] { endswith(input.app.container.docker.image, ":latest"); msg := "images must be pinned" }
+	DefaultQuery = "data.depcon.deny"
+
+	KindApp = "app"
+)
+
+var (
+	ErrorOPANotFound = errors.New("The opa executable could not be found on the PATH - see https://www.openpolicyagent.org/docs/latest/#running-opa")
+	ErrorViolations  = errors.New("The descriptor violates one or more policies")
+)
+
+// Input is the document policies are evaluated against (input.app, input.environment and input.kind)
+type Input struct {
+	Kind        string      `json:"kind"`
+	Environment string      `json:"environment"`
+	App         interface{} `json:"app,omitempty"`
+}
+
+// Checker evaluates the rego files or directories Policies and the bundle Bundle (a path or http(s)
+// URL of a bundle tarball)
+type Checker struct {
+	Policies []string
+	Bundle   string
+	// Default: DefaultQuery
+	Query string
+	// Default: opa
+	Executable string
+
+	bundleFile string
+}
+
+// ViolationsError lists the messages of the policies violated
+type ViolationsError struct {
+	Violations []string
+}
+
+func (e *ViolationsError) Error() string {
+	return fmt.Sprintf("%s:\n  - %s", ErrorViolations.Error(), strings.Join(e.Violations, "\n  - "))
+}
+
+// Enabled returns true if any policies are configured
+func (c *Checker) Enabled() bool {
+	return c != nil && (len(c.Policies) > 0 || c.Bundle != "")
+}
+
+// Check evaluates {input} returning a *ViolationsError listing the violations if there are any
+func (c *Checker) Check(input *Input) error {
+	violations, err := c.Violations(input)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &ViolationsError{Violations: violations}
+	}
+	return nil
+}
+
+// Violations evaluates {input} returning the messages of the policies violated
+func (c *Checker) Violations(input *Input) ([]string, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	exe := c.Executable
+	if exe == "" {
+		exe = "opa"
+	}
+	if _, err := exec.LookPath(exe); err != nil {
+		return nil, ErrorOPANotFound
+	}
+
+	args := []string{"eval", "--format", "json", "--stdin-input"}
+	for _, p := range c.Policies {
+		args = append(args, "--data", p)
+	}
+	if c.Bundle != "" {
+		bundle, err := c.bundle()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--bundle", bundle)
+	}
+	query := c.Query
+	if query == "" {
+		query = DefaultQuery
+	}
+
+	b, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(exe, append(args, query)...)
+	cmd.Stdin = bytes.NewReader(b)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("opa eval: %s", strings.TrimSpace(stderr.String()))
+	}
+	return parseResult(stdout.Bytes())
+}
+
+// Close removes the bundle downloaded by the checker
+func (c *Checker) Close() {
+	if c.bundleFile != "" {
+		os.Remove(c.bundleFile)
+		c.bundleFile = ""
+	}
+}
+
+// Returns the path of the bundle downloading it once when it's a URL
+func (c *Checker) bundle() (string, error) {
+	if !strings.HasPrefix(c.Bundle, "http://") && !strings.HasPrefix(c.Bundle, "https://") {
+		return c.Bundle, nil
+	}
+	if c.bundleFile != "" {
+		return c.bundleFile, nil
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(c.Bundle)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Unable to download the policy bundle %s: %s", c.Bundle, resp.Status)
+	}
+
+	f, err := ioutil.TempFile("", "depcon-bundle-*.tar.gz")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	c.bundleFile = f.Name()
+	return c.bundleFile, nil
+}
+
+type evalResult struct {
+	Result []struct {
+		Expressions []struct {
+			Value interface{} `json:"value"`
+		} `json:"expressions"`
+	} `json:"result"`
+}
+
+// Returns the messages of the opa eval output {b}.  The query's value may be a set (array) of messages, a
+// single message or a boolean where true is a violation.  Objects with a msg field use it as the message
+func parseResult(b []byte) ([]string, error) {
+	result := &evalResult{}
+	if err := json.Unmarshal(b, result); err != nil {
+		return nil, fmt.Errorf("Unable to parse the opa eval output: %s", err.Error())
+	}
+
+	violations := []string{}
+	for _, r := range result.Result {
+		for _, e := range r.Expressions {
+			switch v := e.Value.(type) {
+			case []interface{}:
+				for _, m := range v {
+					violations = append(violations, message(m))
+				}
+			case bool:
+				if v {
+					violations = append(violations, "denied by policy")
+				}
+			case nil:
+			default:
+				violations = append(violations, message(v))
+			}
+		}
+	}
+	return violations, nil
+}
+
+func message(v interface{}) string {
+	switch m := v.(type) {
+	case string:
+		return m
+	case map[string]interface{}:
+		if msg, ok := m["msg"].(string); ok {
+			return msg
+		}
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
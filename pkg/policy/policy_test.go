@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// A stand in for opa which records its arguments and denies inputs mentioning :latest
+const fakeOPA = `#!/bin/sh
+echo "$@" > "$(dirname "$0")/args"
+case "$(cat)" in
+  *:latest*) echo '{"result": [{"expressions": [{"value": ["images must be pinned", {"msg": "no latest in prod"}]}]}]}' ;;
+  *) echo '{"result": [{"expressions": [{"value": []}]}]}' ;;
+esac
+`
+
+func newChecker(t *testing.T, dir string) *Checker {
+	exe := filepath.Join(dir, "opa")
+	assert.Nil(t, ioutil.WriteFile(exe, []byte(fakeOPA), 0755))
+	return &Checker{Policies: []string{"policies/"}, Executable: exe}
+}
+
+func args(dir string) string {
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "args"))
+	return strings.TrimSpace(string(b))
+}
+
+func TestCheck(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "policy")
+	defer os.RemoveAll(dir)
+	c := newChecker(t, dir)
+
+	app := map[string]interface{}{"id": "/web", "container": map[string]interface{}{"docker": map[string]string{"image": "web:1.2"}}}
+	assert.Nil(t, c.Check(&Input{Kind: KindApp, Environment: "prod", App: app}))
+	assert.Equal(t, "eval --format json --stdin-input --data policies/ "+DefaultQuery, args(dir))
+
+	app["container"] = map[string]interface{}{"docker": map[string]string{"image": "web:latest"}}
+	err := c.Check(&Input{Kind: KindApp, Environment: "prod", App: app})
+	verr, ok := err.(*ViolationsError)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"images must be pinned", "no latest in prod"}, verr.Violations)
+	assert.Contains(t, err.Error(), "  - no latest in prod")
+}
+
+func TestCheckDownloadsBundle(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("bundle"))
+	}))
+	defer server.Close()
+	dir, _ := ioutil.TempDir("", "policy")
+	defer os.RemoveAll(dir)
+
+	c := newChecker(t, dir)
+	c.Policies, c.Bundle, c.Query = nil, server.URL+"/bundle.tar.gz", "data.platform.deny"
+	assert.Nil(t, c.Check(&Input{Kind: KindApp}))
+	assert.Nil(t, c.Check(&Input{Kind: KindApp}))
+	assert.Equal(t, 1, requests)
+	assert.Contains(t, args(dir), "--bundle "+c.bundleFile+" data.platform.deny")
+
+	bundle := c.bundleFile
+	c.Close()
+	_, err := os.Stat(bundle)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestCheckDisabledAndMissingExecutable(t *testing.T) {
+	assert.Nil(t, (&Checker{}).Check(&Input{Kind: KindApp}))
+	c := &Checker{Policies: []string{"policy.rego"}, Executable: "depcon-no-such-opa"}
+	assert.Equal(t, ErrorOPANotFound, c.Check(&Input{Kind: KindApp}))
+}
+
+func TestParseResult(t *testing.T) {
+	v, err := parseResult([]byte(`{}`))
+	assert.Nil(t, err)
+	assert.Empty(t, v)
+
+	v, _ = parseResult([]byte(`{"result": [{"expressions": [{"value": true}]}]}`))
+	assert.Equal(t, []string{"denied by policy"}, v)
+
+	v, _ = parseResult([]byte(`{"result": [{"expressions": [{"value": "memory must be set"}]}]}`))
+	assert.Equal(t, []string{"memory must be set"}, v)
+
+	_, err = parseResult([]byte(`not json`))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,25 @@
+// Status reported by the cluster clients while they wait (eg. on a deployment) so the commands can draw it
+// on the terminal in place of logging every check
+package progress
+
+// Reporter receives the status while waiting.  Status returns false if the status wasn't reported in which
+// case the client logs it
+type Reporter interface {
+	// {message} with {done} of {total} (eg. healthy instances, tasks or replicas).  {total} is zero when not
+	// applicable
+	Status(message string, done, total int) bool
+	// Clears the status before other output is written
+	Clear()
+}
+
+// Report passes the status to {r} returning false when there is no reporter or it didn't report the status
+func Report(r Reporter, message string, done, total int) bool {
+	return r != nil && r.Status(message, done, total)
+}
+
+// Clear clears the status drawn by {r} unless there is no reporter
+func Clear(r Reporter) {
+	if r != nil {
+		r.Clear()
+	}
+}
@@ -0,0 +1,32 @@
+package progress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	messages []string
+	cleared  bool
+}
+
+func (r *recorder) Status(message string, done, total int) bool {
+	r.messages = append(r.messages, message)
+	return true
+}
+
+func (r *recorder) Clear() {
+	r.cleared = true
+}
+
+func TestReport(t *testing.T) {
+	assert.False(t, Report(nil, "waiting", 0, 0))
+	Clear(nil)
+
+	r := &recorder{}
+	assert.True(t, Report(r, "waiting", 1, 3))
+	Clear(r)
+	assert.Equal(t, []string{"waiting"}, r.messages)
+	assert.True(t, r.cleared)
+}
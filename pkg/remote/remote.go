@@ -0,0 +1,232 @@
+// Fetches descriptors published over HTTP(S) (eg. to an artifact repository) sending the credentials configured
+// for their server and verifies them against the checksum they were released with
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/secrets"
+)
+
+// maximum size of a descriptor
+const maxSize = 10 << 20
+
+var ErrorChecksumMismatch = errors.New("The checksum of the descriptor does not match")
+
+// Source is a server descriptors are fetched from along with the credentials sent to it
+type Source struct {
+	// Scheme, host and leading path segments of the URLs the credentials are sent to (eg.
+	// https://artifacts.example.com/releases/)
+	URL string `json:"url"`
+	// Basic auth credentials.  Either may be a secret reference
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Bearer token in place of basic auth.  May be a secret reference
+	Token string `json:"token,omitempty"`
+	// Headers sent along with the credentials (eg. {"X-JFrog-Art-Api": "secret://vault/ci/artifactory#key"}).
+	// Values may be secret references
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Validate returns an error unless the source's URL is an http(s) URL
+func (s *Source) Validate() error {
+	if !IsURL(s.URL) {
+		return fmt.Errorf("'%s' must be an http:// or https:// URL", s.URL)
+	}
+	if s.Token != "" && (s.Username != "" || s.Password != "") {
+		return errors.New("Specify a token or a username and password, not both")
+	}
+	return nil
+}
+
+// IsURL returns true if {name} is an http:// or https:// URL rather than a file
+func IsURL(name string) bool {
+	return strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://")
+}
+
+// Match returns the source of {sources} with the longest URL matching {rawurl} or nil when none does.  The
+// scheme and host must be the same and the path of the source's URL a prefix of whole path segments (eg.
+// https://a.com/releases matches https://a.com/releases/app.yaml but neither https://a.com.evil.io/ nor
+// https://a.com/releases-old/)
+func Match(sources []*Source, rawurl string) *Source {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil
+	}
+	var match *Source
+	longest := -1
+	for _, s := range sources {
+		if s == nil {
+			continue
+		}
+		if n := matchLength(s.URL, u); n > longest {
+			match, longest = s, n
+		}
+	}
+	return match
+}
+
+// Returns the length of the path of {prefix} when it matches {u} or -1 when it doesn't
+func matchLength(prefix string, u *url.URL) int {
+	p, err := url.Parse(prefix)
+	if err != nil || p.Host == "" || !strings.EqualFold(p.Scheme, u.Scheme) || !strings.EqualFold(p.Host, u.Host) {
+		return -1
+	}
+	dir := strings.TrimSuffix(p.Path, "/")
+	if dir != "" && u.Path != dir && !strings.HasPrefix(u.Path, dir+"/") {
+		return -1
+	}
+	return len(dir)
+}
+
+// Fetcher downloads descriptors
+type Fetcher struct {
+	Client  *http.Client
+	Sources []*Source
+	// Resolves the secret references of the sources.  Default: secrets.Default()
+	Secrets *secrets.Resolver
+}
+
+// Fetch returns the content at {rawurl}
+func (f *Fetcher) Fetch(rawurl string) ([]byte, error) {
+	req, err := http.NewRequest("GET", rawurl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authenticate(req, Match(f.Sources, rawurl)); err != nil {
+		return nil, err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetching %s failed: %s", rawurl, resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", rawurl, maxSize>>20)
+	}
+	return data, nil
+}
+
+func (f *Fetcher) authenticate(req *http.Request, s *Source) error {
+	if s == nil {
+		return nil
+	}
+	resolver := f.Secrets
+	if resolver == nil {
+		resolver = secrets.Default()
+	}
+	resolve := func(values ...*string) error {
+		for _, v := range values {
+			r, err := resolver.ResolveValue(*v)
+			if err != nil {
+				return err
+			}
+			*v = r
+		}
+		return nil
+	}
+	username, password, token := s.Username, s.Password, s.Token
+	if err := resolve(&username, &password, &token); err != nil {
+		return err
+	}
+	switch {
+	case token != "":
+		req.Header.Set("Authorization", "Bearer "+token)
+	case username != "":
+		req.SetBasicAuth(username, password)
+	}
+	for name, value := range s.Headers {
+		if err := resolve(&value); err != nil {
+			return err
+		}
+		req.Header.Set(name, value)
+	}
+	return nil
+}
+
+// Download saves the descriptor at {rawurl} within {dir} under the name it's published with and returns its
+// path.  The descriptor is verified against {digest} unless it's empty
+func (f *Fetcher) Download(rawurl, digest, dir string) (string, error) {
+	data, err := f.Fetch(rawurl)
+	if err != nil {
+		return "", err
+	}
+	if digest != "" {
+		if err := Verify(data, digest); err != nil {
+			return "", fmt.Errorf("%s: %w", rawurl, err)
+		}
+	}
+	filename := filepath.Join(dir, Filename(rawurl))
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
+
+// Filename returns the name of the file published at {rawurl} (eg. app-1.4.2.yaml)
+func Filename(rawurl string) string {
+	name := "descriptor.json"
+	if u, err := url.Parse(rawurl); err == nil {
+		if base := path.Base(u.Path); base != "/" && base != "." {
+			name = base
+		}
+	}
+	return name
+}
+
+// Verify returns ErrorChecksumMismatch unless the SHA-256 of {data} is {digest} (hex, optionally prefixed with
+// sha256:)
+func Verify(data []byte, digest string) error {
+	expected := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(digest), "sha256:"))
+	if b, err := hex.DecodeString(expected); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("'%s' is not a SHA-256 digest", digest)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return fmt.Errorf("%w (expected %s, got %s)", ErrorChecksumMismatch, expected, actual)
+	}
+	return nil
+}
+
+// VerifyFile verifies the file {filename} against {digest} as Verify
+func VerifyFile(filename, digest string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if err := Verify(data, digest); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
+
+// TempDir returns a new directory descriptors are downloaded to and a function removing it
+func TempDir() (string, func(), error) {
+	dir, err := ioutil.TempDir("", "depcon-remote-")
+	if err != nil {
+		return "", nil, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
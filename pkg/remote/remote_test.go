@@ -0,0 +1,96 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+const descriptor = "id: /web\ninstances: 2\n"
+
+func digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+type staticProvider map[string]string
+
+func (p staticProvider) Get(path, key string) (string, error) {
+	return p[path], nil
+}
+
+func TestVerify(t *testing.T) {
+	assert.NoError(t, Verify([]byte(descriptor), digest(descriptor)))
+	assert.NoError(t, Verify([]byte(descriptor), "sha256:"+digest(descriptor)))
+
+	err := Verify([]byte(descriptor+"\n"), digest(descriptor))
+	assert.True(t, errors.Is(err, ErrorChecksumMismatch))
+	assert.Error(t, Verify([]byte(descriptor), "abc"))
+}
+
+func TestMatchAndValidate(t *testing.T) {
+	sources := []*Source{{URL: "https://artifacts.example.com/"}, {URL: "https://artifacts.example.com/releases/"}}
+	assert.Equal(t, sources[1], Match(sources, "https://artifacts.example.com/releases/app-1.4.2.yaml"))
+	assert.Equal(t, sources[0], Match(sources, "https://artifacts.example.com/snapshots/app.yaml"))
+	assert.Nil(t, Match(sources, "https://other.example.com/app.yaml"))
+	assert.Nil(t, Match(sources, "https://artifacts.example.com.evil.io/app.yaml"), "hosts are matched exactly")
+	assert.Nil(t, Match(sources, "http://artifacts.example.com/app.yaml"))
+	assert.Nil(t, Match(sources, "https://user@artifacts.example.com.evil.io/app.yaml"))
+
+	bare := []*Source{{URL: "https://artifacts.example.com"}, {URL: "https://artifacts.example.com/releases"}}
+	assert.Equal(t, bare[0], Match(bare, "https://ARTIFACTS.example.com/app.yaml"))
+	assert.Equal(t, bare[1], Match(bare, "https://artifacts.example.com/releases/app.yaml"))
+	assert.Equal(t, bare[0], Match(bare, "https://artifacts.example.com/releases-old/app.yaml"), "paths are matched by segment")
+
+	assert.NoError(t, sources[0].Validate())
+	assert.Error(t, (&Source{URL: "artifacts.example.com"}).Validate())
+	assert.Error(t, (&Source{URL: "https://a/", Token: "t", Username: "u"}).Validate())
+}
+
+func TestFilename(t *testing.T) {
+	assert.Equal(t, "app-1.4.2.yaml", Filename("https://artifacts.example.com/releases/app-1.4.2.yaml?token=x"))
+	assert.Equal(t, "descriptor.json", Filename("https://artifacts.example.com/"))
+}
+
+func TestDownload(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "ci" || password != "s3cret" || r.Header.Get("X-Repo") != "releases" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(descriptor))
+	}))
+	defer server.Close()
+
+	resolver := secrets.NewResolver(nil)
+	resolver.Register("vault", staticProvider{"ci/artifacts": "s3cret"})
+	f := &Fetcher{
+		Sources: []*Source{{URL: server.URL + "/releases/", Username: "ci", Password: "secret://vault/ci/artifacts", Headers: map[string]string{"X-Repo": "releases"}}},
+		Secrets: resolver,
+	}
+	dir := t.TempDir()
+
+	filename, err := f.Download(server.URL+"/releases/app-1.4.2.yaml", digest(descriptor), dir)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "app-1.4.2.yaml"), filename)
+	data, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, descriptor, string(data))
+	assert.NoError(t, VerifyFile(filename, digest(descriptor)))
+
+	_, err = f.Download(server.URL+"/releases/app-1.4.2.yaml", digest("tampered"), dir)
+	assert.True(t, errors.Is(err, ErrorChecksumMismatch))
+
+	// credentials are only sent to the URLs of their source
+	_, err = f.Download(server.URL+"/snapshots/app.yaml", "", dir)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
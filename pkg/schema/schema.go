@@ -0,0 +1,111 @@
+// JSON Schema generation and validation for descriptor types
+package schema
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	Draft = "http://json-schema.org/draft-07/schema#"
+	// Struct tag listing the allowed values for a field separated by '|' (eg. enum:"tcp|udp")
+	EnumTag = "enum"
+)
+
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+}
+
+// Generate builds a JSON Schema for the type of {v} using the json struct tags to determine
+// property names.  Struct types are placed within definitions and referenced so recursive
+// types (eg. groups within groups) can be described
+func Generate(v interface{}) *Schema {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	g := &generator{definitions: make(map[string]*Schema)}
+	root := g.schemaFor(t)
+	root.Schema = Draft
+	root.Title = t.Name()
+	root.Definitions = g.definitions
+	return root
+}
+
+type generator struct {
+	definitions map[string]*Schema
+}
+
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schemaFor(t.Elem())
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, exists := g.definitions[t.Name()]; !exists {
+			// register before walking fields to terminate recursion
+			g.definitions[t.Name()] = &Schema{}
+			*g.definitions[t.Name()] = *g.structSchema(t)
+		}
+		return &Schema{Ref: "#/definitions/" + t.Name()}
+	}
+	return &Schema{}
+}
+
+// Properties other than the fields are allowed since the types needn't declare every field of the documents
+// they describe (eg. Marathon fields depcon doesn't use).  Validate reports them as warnings
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := fieldName(f)
+		if name == "-" {
+			continue
+		}
+		fs := g.schemaFor(f.Type)
+		if enum := f.Tag.Get(EnumTag); enum != "" {
+			fs.Enum = strings.Split(enum, "|")
+		}
+		s.Properties[name] = fs
+	}
+	return s
+}
+
+func fieldName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if tag == "" {
+		return f.Name
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
@@ -0,0 +1,74 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type portMapping struct {
+	ContainerPort int    `json:"containerPort,omitempty"`
+	Protocol      string `json:"protocol" enum:"tcp|udp"`
+}
+
+type docker struct {
+	Image        string         `json:"image,omitempty"`
+	PortMappings []*portMapping `json:"portMappings,omitempty"`
+}
+
+type container struct {
+	Docker *docker `json:"docker,omitempty"`
+}
+
+type app struct {
+	ID        string            `json:"id,omitempty"`
+	Instances int               `json:"instances,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Container *container        `json:"container,omitempty"`
+}
+
+type group struct {
+	ID     string   `json:"id"`
+	Groups []*group `json:"groups,omitempty"`
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(&app{})
+	assert.Equal(t, Draft, s.Schema)
+	assert.Equal(t, "#/definitions/app", s.Ref)
+	assert.Equal(t, []string{"tcp", "udp"}, s.Definitions["portMapping"].Properties["protocol"].Enum)
+
+	g := Generate(&group{})
+	assert.Equal(t, "#/definitions/group", g.Definitions["group"].Properties["groups"].Items.Ref)
+}
+
+func TestValidateReportsPaths(t *testing.T) {
+	doc := `{
+		"id": "/app",
+		"instances": 1.5,
+		"labels": {"a": 1},
+		"contianer": {},
+		"container": {"docker": {"image": "nginx", "portMappings": [
+			{"containerPort": 80, "protocol": "tcp"},
+			{"containerPort": "443", "protocol": "http"}
+		]}}
+	}`
+	var data interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &data))
+
+	errs := Generate(&app{}).Validate(data)
+	msgs := []string{}
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		"container.docker.portMappings[1].containerPort: expected an integer but found a string",
+		"container.docker.portMappings[1].protocol: 'http' is not one of [tcp, udp]",
+		"contianer: unknown property",
+		"instances: expected an integer but found 1.5",
+		"labels.a: expected a string but found a number",
+	}, msgs)
+	assert.True(t, errs[2].Warning, "unknown properties are warnings")
+	assert.Len(t, Errors(errs), 4)
+}
@@ -0,0 +1,172 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ValidationError describes a value within a document which does not conform to the schema
+type ValidationError struct {
+	// Location of the value (eg. container.docker.portMappings[1].protocol)
+	Path    string
+	Message string
+	// If true the value is permitted but likely a mistake (eg. a misspelt property)
+	Warning bool
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate checks {data} (as decoded into interface{} from JSON or YAML) against {s} and returns
+// every violation found.  Null values are always accepted.  Properties the schema doesn't declare are
+// reported as warnings unless its additionalProperties describes or forbids them
+func (s *Schema) Validate(data interface{}) []ValidationError {
+	v := &validator{root: s}
+	v.validate(s, data, "")
+	return v.errors
+}
+
+type validator struct {
+	root   *Schema
+	errors []ValidationError
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warn(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, ValidationError{Path: path, Message: fmt.Sprintf(format, args...), Warning: true})
+}
+
+func (v *validator) resolve(s *Schema) *Schema {
+	for s.Ref != "" {
+		s = v.root.Definitions[strings.TrimPrefix(s.Ref, "#/definitions/")]
+	}
+	return s
+}
+
+func (v *validator) validate(s *Schema, data interface{}, path string) {
+	if data == nil {
+		return
+	}
+	s = v.resolve(s)
+
+	switch s.Type {
+	case "object":
+		m, ok := data.(map[string]interface{})
+		if !ok {
+			v.fail(path, "expected an object but found %s", typeName(data))
+			return
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			child := join(path, k)
+			if ps, ok := s.Properties[k]; ok {
+				v.validate(ps, m[k], child)
+				continue
+			}
+			switch ap := s.AdditionalProperties.(type) {
+			case nil:
+				if s.Properties != nil {
+					v.warn(child, "unknown property")
+				}
+			case *Schema:
+				v.validate(ap, m[k], child)
+			case bool:
+				if !ap {
+					v.fail(child, "unknown property")
+				}
+			}
+		}
+	case "array":
+		arr, ok := data.([]interface{})
+		if !ok {
+			v.fail(path, "expected an array but found %s", typeName(data))
+			return
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case "string":
+		str, ok := data.(string)
+		if !ok {
+			v.fail(path, "expected a string but found %s", typeName(data))
+			return
+		}
+		if len(s.Enum) > 0 && !contains(s.Enum, str) {
+			v.fail(path, "'%s' is not one of [%s]", str, strings.Join(s.Enum, ", "))
+		}
+	case "integer":
+		f, ok := data.(float64)
+		if !ok {
+			v.fail(path, "expected an integer but found %s", typeName(data))
+		} else if f != math.Trunc(f) {
+			v.fail(path, "expected an integer but found %v", f)
+		}
+	case "number":
+		if _, ok := data.(float64); !ok {
+			v.fail(path, "expected a number but found %s", typeName(data))
+		}
+	case "boolean":
+		if _, ok := data.(bool); !ok {
+			v.fail(path, "expected a boolean but found %s", typeName(data))
+		}
+	}
+}
+
+// Errors returns the violations of {errs} which aren't warnings
+func Errors(errs []ValidationError) []ValidationError {
+	failed := []ValidationError{}
+	for _, e := range errs {
+		if !e.Warning {
+			failed = append(failed, e)
+		}
+	}
+	return failed
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(data interface{}) string {
+	switch data.(type) {
+	case map[string]interface{}:
+		return "an object"
+	case []interface{}:
+		return "an array"
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return fmt.Sprintf("%T", data)
+}
@@ -0,0 +1,189 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ContainX/depcon/ecs"
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+const (
+	EnvVaultAddr  = "VAULT_ADDR"
+	EnvVaultToken = "VAULT_TOKEN"
+
+	awsContentType = "application/x-amz-json-1.1"
+)
+
+// envProvider returns environment variables.  The path is the variable name
+type envProvider struct{}
+
+func (p *envProvider) Get(path, key string) (string, error) {
+	value, ok := os.LookupEnv(path)
+	if !ok {
+		return "", ErrorNotFound
+	}
+	return selectKey(value, key)
+}
+
+// fileProvider returns the contents of files without the trailing newline
+type fileProvider struct {
+	dir string
+}
+
+func (p *fileProvider) Get(path, key string) (string, error) {
+	if !filepath.IsAbs(path) && p.dir != "" {
+		path = filepath.Join(p.dir, path)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrorNotFound
+		}
+		return "", err
+	}
+	return selectKey(strings.TrimRight(string(b), "\r\n"), key)
+}
+
+// vaultProvider reads secrets from HashiCorp Vault.  The path is the API path of the secret (eg.
+// secret/data/db for a KV version 2 engine)
+type vaultProvider struct {
+	http    *httpclient.HttpClient
+	address string
+}
+
+type vaultSecret struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+func newVaultProvider(config *Config) (Provider, error) {
+	address, token := config.Address, config.Token
+	if address == "" {
+		address = os.Getenv(EnvVaultAddr)
+	}
+	if token == "" {
+		token = os.Getenv(EnvVaultToken)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("The Vault address is not configured - set the provider's address or $%s", EnvVaultAddr)
+	}
+
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	httpConfig.Headers = map[string]string{"X-Vault-Token": token}
+	if config.Namespace != "" {
+		httpConfig.Headers["X-Vault-Namespace"] = config.Namespace
+	}
+	return &vaultProvider{http: httpclient.NewHttpClient(*httpConfig), address: strings.TrimRight(address, "/")}, nil
+}
+
+func (p *vaultProvider) Get(path, key string) (string, error) {
+	fields, err := p.read(path)
+	if err != nil {
+		return "", err
+	}
+	return field(fields, key)
+}
+
+func (p *vaultProvider) Fields(path string) (map[string]string, error) {
+	fields, err := p.read(path)
+	if err != nil {
+		return nil, err
+	}
+	return fieldStrings(fields)
+}
+
+func (p *vaultProvider) read(path string) (map[string]interface{}, error) {
+	secret := &vaultSecret{}
+	if resp := p.http.HttpGet(fmt.Sprintf("%s/v1/%s", p.address, strings.TrimLeft(path, "/")), secret); resp.Error != nil {
+		if resp.Error == httpclient.ErrorNotFound {
+			return nil, ErrorNotFound
+		}
+		return nil, resp.Err()
+	}
+	fields := secret.Data
+	// KV version 2 engines nest the fields beneath data.data alongside the metadata
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, versioned := fields["metadata"]; versioned {
+			fields = nested
+		}
+	}
+	if fields == nil {
+		return nil, ErrorNotFound
+	}
+	return fields, nil
+}
+
+type awsService struct {
+	name   string
+	target string
+}
+
+var (
+	secretsManager = awsService{name: "secretsmanager", target: "secretsmanager.GetSecretValue"}
+	parameterStore = awsService{name: "ssm", target: "AmazonSSM.GetParameter"}
+)
+
+// awsProvider reads secrets from AWS Secrets Manager (the path is the secret id) or SSM Parameter Store
+// (the path is the parameter name which is made absolute when hierarchical)
+type awsProvider struct {
+	http    *httpclient.HttpClient
+	host    string
+	service awsService
+}
+
+func newAWSProvider(config *Config, service awsService) (Provider, error) {
+	region := config.Region
+	if region == "" {
+		region = ecs.DefaultRegion()
+	}
+	if region == "" {
+		return nil, errors.New("The AWS region is not configured - set the provider's region or $AWS_REGION")
+	}
+	creds, err := ecs.LoadCredentials(config.Profile)
+	if err != nil {
+		return nil, err
+	}
+
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	httpConfig.Authenticator = &ecs.SigV4Signer{Credentials: creds, Region: region, Service: service.name}
+	// lookups are POSTs which make no changes so they aren't refused by freezes
+	httpConfig.RPC = true
+	host := fmt.Sprintf("https://%s.%s.amazonaws.com", service.name, region)
+	if config.Endpoint != "" {
+		host = strings.TrimRight(config.Endpoint, "/")
+	}
+	return &awsProvider{http: httpclient.NewHttpClient(*httpConfig), host: host, service: service}, nil
+}
+
+func (p *awsProvider) Get(path, key string) (string, error) {
+	headers := map[string]string{"X-Amz-Target": p.service.target, "Content-Type": awsContentType}
+	if p.service == parameterStore {
+		if strings.Contains(path, "/") && !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+		result := &struct {
+			Parameter struct {
+				Value string `json:"Value"`
+			} `json:"Parameter"`
+		}{}
+		input := map[string]interface{}{"Name": path, "WithDecryption": true}
+		if resp := p.http.HttpPostWithHeaders(p.host+"/", headers, input, result); resp.Error != nil {
+			return "", resp.Err()
+		}
+		return selectKey(result.Parameter.Value, key)
+	}
+
+	result := &struct {
+		SecretString string `json:"SecretString"`
+	}{}
+	if resp := p.http.HttpPostWithHeaders(p.host+"/", headers, map[string]string{"SecretId": path}, result); resp.Error != nil {
+		return "", resp.Err()
+	}
+	return selectKey(result.SecretString, key)
+}
@@ -0,0 +1,311 @@
+// Resolves secret://provider/path#key references within params and templates using the secrets providers
+// (Vault, AWS Secrets Manager, SSM Parameter Store, environment variables and files) configured per
+// environment
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/mask"
+)
+
+const (
+	// Prefix of a secret reference: secret://provider/path#key
+	Scheme = "secret://"
+
+	TypeEnv               = "env"
+	TypeFile              = "file"
+	TypeVault             = "vault"
+	TypeAWSSecretsManager = "aws-secrets-manager"
+	TypeSSM               = "ssm"
+)
+
+var log = logger.GetLogger("depcon.secrets")
+
+var (
+	ErrorInvalidReference = errors.New("Secret references must be of the form secret://provider/path[#key]")
+	ErrorNotFound         = errors.New("The secret does not exist")
+)
+
+// Provider returns the secrets of a secrets store (eg. Vault)
+type Provider interface {
+	// Get returns the secret at {path}.  {key} selects a field of a structured secret (eg. the fields of a
+	// Vault secret or a JSON document) and is empty when the whole secret is wanted
+	Get(path, key string) (string, error)
+}
+
+// FieldsProvider is implemented by providers reading every field of a structured secret at once.  Secrets
+// issued per read (eg. Vault dynamic credentials) must be read once so their fields belong together
+type FieldsProvider interface {
+	Fields(path string) (map[string]string, error)
+}
+
+// Config configures a secrets provider of an environment
+type Config struct {
+	// env, file, vault, aws-secrets-manager or ssm.  Default: the name the provider is configured under
+	Type string `json:"type,omitempty"`
+	// Vault address.  Default: $VAULT_ADDR
+	Address string `json:"address,omitempty"`
+	// Vault token.  Default: $VAULT_TOKEN
+	Token string `json:"token,omitempty"`
+	// Vault Enterprise namespace
+	Namespace string `json:"namespace,omitempty"`
+	// AWS region.  Default: $AWS_REGION
+	Region string `json:"region,omitempty"`
+	// Named profile within the AWS shared credentials file
+	Profile string `json:"profile,omitempty"`
+	// Optional AWS endpoint used in place of the regional endpoint (eg. a VPC endpoint)
+	Endpoint string `json:"endpoint,omitempty"`
+	// Directory the paths of a file provider are relative to.  Default: the working directory
+	Dir string `json:"dir,omitempty"`
+}
+
+// Validate returns an error if the provider configured under {name} has an unknown type
+func (c *Config) Validate(name string) error {
+	t := c.Type
+	if t == "" {
+		t = name
+	}
+	switch t {
+	case TypeEnv, TypeFile, TypeVault, TypeAWSSecretsManager, TypeSSM:
+		return nil
+	}
+	return fmt.Errorf("'%s' is not a secrets provider type - must be %s, %s, %s, %s or %s", t, TypeEnv, TypeFile, TypeVault, TypeAWSSecretsManager, TypeSSM)
+}
+
+// NewProvider creates the provider configured by {config} under {name}
+func NewProvider(name string, config *Config) (Provider, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	t := config.Type
+	if t == "" {
+		t = name
+	}
+	switch t {
+	case TypeEnv:
+		return &envProvider{}, nil
+	case TypeFile:
+		return &fileProvider{dir: config.Dir}, nil
+	case TypeVault:
+		return newVaultProvider(config)
+	case TypeAWSSecretsManager:
+		return newAWSProvider(config, secretsManager)
+	case TypeSSM:
+		return newAWSProvider(config, parameterStore)
+	}
+	return nil, fmt.Errorf("Secrets provider '%s': %s", name, config.Validate(name).Error())
+}
+
+// IsReference returns true if {value} is a secret reference
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// ParseReference splits the reference secret://provider/path#key into its parts
+func ParseReference(ref string) (provider, path, key string, err error) {
+	if !IsReference(ref) {
+		return "", "", "", ErrorInvalidReference
+	}
+	parts := strings.SplitN(strings.TrimPrefix(ref, Scheme), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", ErrorInvalidReference
+	}
+	provider, path = parts[0], parts[1]
+	if i := strings.LastIndex(path, "#"); i >= 0 {
+		path, key = path[:i], path[i+1:]
+	}
+	return provider, path, key, nil
+}
+
+// Resolver resolves secret references with the providers of an environment.  The env and file providers are
+// available without configuration.  Providers are created when first used and secrets are cached
+type Resolver struct {
+	configs   map[string]*Config
+	providers map[string]Provider
+	cache     map[string]string
+	mu        sync.Mutex
+}
+
+// NewResolver creates a resolver of the providers {configs} keyed by the name used within references
+func NewResolver(configs map[string]*Config) *Resolver {
+	all := map[string]*Config{TypeEnv: {Type: TypeEnv}, TypeFile: {Type: TypeFile}}
+	for name, c := range configs {
+		all[name] = c
+	}
+	return &Resolver{configs: all, providers: map[string]Provider{}, cache: map[string]string{}}
+}
+
+// Register adds {provider} under {name} replacing any provider configured with that name
+func (r *Resolver) Register(name string, provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = provider
+}
+
+// Resolve returns the secret referenced by {ref} (secret://provider/path#key)
+func (r *Resolver) Resolve(ref string) (string, error) {
+	name, path, key, err := ParseReference(ref)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if v, ok := r.cache[ref]; ok {
+		return v, nil
+	}
+	provider, err := r.provider(name)
+	if err != nil {
+		return "", err
+	}
+	log.Debug("Resolving secret %s", ref)
+	v, err := provider.Get(path, key)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", ref, err.Error())
+	}
+	r.cache[ref] = v
+	// resolved secrets are masked wherever they appear in output
+	mask.AddValues(v)
+	return v, nil
+}
+
+// ResolveFields returns every field of the structured secret referenced by {ref} (secret://provider/path)
+// read at once.  Providers without structured secrets must hold a JSON object
+func (r *Resolver) ResolveFields(ref string) (map[string]string, error) {
+	name, path, key, err := ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		return nil, fmt.Errorf("%s: the reference selects the field '%s' rather than the whole secret", ref, key)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	provider, err := r.provider(name)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug("Resolving the fields of secret %s", ref)
+	var fields map[string]string
+	if fp, ok := provider.(FieldsProvider); ok {
+		fields, err = fp.Fields(path)
+	} else {
+		fields, err = jsonFields(provider, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", ref, err.Error())
+	}
+	for _, v := range fields {
+		mask.AddValues(v)
+	}
+	return fields, nil
+}
+
+// ResolveValue returns the secret when {value} is a reference and otherwise {value} as is
+func (r *Resolver) ResolveValue(value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	return r.Resolve(value)
+}
+
+func (r *Resolver) provider(name string) (Provider, error) {
+	if p, ok := r.providers[name]; ok {
+		return p, nil
+	}
+	config, ok := r.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("No secrets provider named '%s' is configured for this environment", name)
+	}
+	p, err := NewProvider(name, config)
+	if err != nil {
+		return nil, err
+	}
+	r.providers[name] = p
+	return p, nil
+}
+
+var (
+	defaultResolver = NewResolver(nil)
+	defaultMu       sync.RWMutex
+)
+
+// SetDefault replaces the resolver used by params and templates (eg. with the providers of the selected
+// environment)
+func SetDefault(r *Resolver) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultResolver = r
+}
+
+// Default returns the resolver used by params and templates
+func Default() *Resolver {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultResolver
+}
+
+// Returns the field {key} of the JSON object {value} or {value} when {key} is empty
+func selectKey(value, key string) (string, error) {
+	if key == "" {
+		return value, nil
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("#%s requires a JSON object secret", key)
+	}
+	return field(fields, key)
+}
+
+// Returns the field {key} of {fields}.  When {key} is empty {fields} must have a single field
+// Returns the fields of the secret at {path} which {provider} holds as a JSON object
+func jsonFields(provider Provider, path string) (map[string]string, error) {
+	v, err := provider.Get(path, "")
+	if err != nil {
+		return nil, err
+	}
+	object := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(v), &object); err != nil {
+		return nil, errors.New("the secret is not a JSON object of fields")
+	}
+	return fieldStrings(object)
+}
+
+func fieldStrings(fields map[string]interface{}) (map[string]string, error) {
+	result := make(map[string]string, len(fields))
+	for k := range fields {
+		v, err := field(fields, k)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+func field(fields map[string]interface{}, key string) (string, error) {
+	if key == "" {
+		if len(fields) != 1 {
+			return "", fmt.Errorf("the secret has %d fields - select one with #key", len(fields))
+		}
+		for k := range fields {
+			key = k
+		}
+	}
+	v, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("the secret has no field '%s'", key)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
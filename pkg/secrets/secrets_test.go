@@ -0,0 +1,156 @@
+package secrets
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	provider, path, key, err := ParseReference("secret://vault/secret/data/db#password")
+	assert.Nil(t, err)
+	assert.Equal(t, "vault", provider)
+	assert.Equal(t, "secret/data/db", path)
+	assert.Equal(t, "password", key)
+
+	for _, ref := range []string{"vault/secret", "secret://vault", "secret:///path"} {
+		_, _, _, err := ParseReference(ref)
+		assert.Equal(t, ErrorInvalidReference, err, ref)
+	}
+}
+
+func TestEnvAndFileProviders(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "secrets")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "db.json"), []byte(`{"user": "app", "password": "s3cret"}`+"\n"), 0600)
+	os.Setenv("DEPCON_TEST_SECRET", "from-env")
+	defer os.Unsetenv("DEPCON_TEST_SECRET")
+
+	r := NewResolver(map[string]*Config{"files": {Type: TypeFile, Dir: dir}})
+	v, err := r.Resolve("secret://env/DEPCON_TEST_SECRET")
+	assert.Nil(t, err)
+	assert.Equal(t, "from-env", v)
+
+	v, err = r.Resolve("secret://files/db.json#password")
+	assert.Nil(t, err)
+	assert.Equal(t, "s3cret", v)
+
+	fields, err := r.ResolveFields("secret://files/db.json")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"user": "app", "password": "s3cret"}, fields)
+
+	_, err = r.Resolve("secret://env/DEPCON_TEST_UNSET")
+	assert.NotNil(t, err)
+	_, err = r.Resolve("secret://unknown/path")
+	assert.NotNil(t, err)
+
+	v, err = r.ResolveValue("plain")
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", v)
+}
+
+func TestVaultProvider(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "token", r.Header.Get("X-Vault-Token"))
+		switch r.URL.Path {
+		case "/v1/secret/data/db":
+			w.Write([]byte(`{"data": {"data": {"password": "kv2"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/db":
+			w.Write([]byte(`{"data": {"password": "kv1", "user": "app"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r := NewResolver(map[string]*Config{"vault": {Address: server.URL, Token: "token"}})
+	v, err := r.Resolve("secret://vault/secret/data/db")
+	assert.Nil(t, err)
+	assert.Equal(t, "kv2", v)
+
+	v, err = r.Resolve("secret://vault/kv/db#user")
+	assert.Nil(t, err)
+	assert.Equal(t, "app", v)
+
+	_, err = r.Resolve("secret://vault/kv/db")
+	assert.NotNil(t, err)
+	_, err = r.Resolve("secret://vault/kv/missing#password")
+	assert.NotNil(t, err)
+
+	fields, err := r.ResolveFields("secret://vault/kv/db")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"password": "kv1", "user": "app"}, fields)
+	_, err = r.ResolveFields("secret://vault/kv/db#user")
+	assert.NotNil(t, err)
+
+	r.Resolve("secret://vault/secret/data/db")
+	assert.Equal(t, 5, requests)
+}
+
+func TestAWSProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Contains(t, r.Header.Get("Authorization"), "AWS4-HMAC-SHA256")
+		body, _ := ioutil.ReadAll(r.Body)
+		switch r.Header.Get("X-Amz-Target") {
+		case "secretsmanager.GetSecretValue":
+			assert.Contains(t, string(body), `"SecretId":"prod/db"`)
+			w.Write([]byte(`{"SecretString": "{\"password\": \"sm\"}"}`))
+		case "AmazonSSM.GetParameter":
+			assert.Contains(t, string(body), `"Name":"/prod/db/password"`)
+			w.Write([]byte(`{"Parameter": {"Value": "ssm"}}`))
+		}
+	}))
+	defer server.Close()
+	os.Setenv("AWS_ACCESS_KEY_ID", "id")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "key")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	httpclient.SetWriteGuard(func(method, url string) error { return errors.New("frozen") })
+	defer httpclient.SetWriteGuard(nil)
+
+	r := NewResolver(map[string]*Config{
+		"aws": {Type: TypeAWSSecretsManager, Region: "us-east-1", Endpoint: server.URL},
+		"ssm": {Region: "us-east-1", Endpoint: server.URL},
+	})
+	v, err := r.Resolve("secret://aws/prod/db#password")
+	assert.Nil(t, err)
+	assert.Equal(t, "sm", v)
+
+	v, err = r.Resolve("secret://ssm/prod/db/password")
+	assert.Nil(t, err)
+	assert.Equal(t, "ssm", v)
+}
+
+func TestSubstTokensResolvesSecrets(t *testing.T) {
+	os.Setenv("DEPCON_TEST_SECRET", "from-env")
+	defer os.Unsetenv("DEPCON_TEST_SECRET")
+	envsubst.SecretResolver = NewResolver(nil).ResolveValue
+	defer func() { envsubst.SecretResolver = nil }()
+
+	parsed, missing := envsubst.SubstTokens(strings.NewReader(`{"a": "${A}", "b": "${B}", "c": "${C}"}`), map[string]string{
+		"A": "secret://env/DEPCON_TEST_SECRET",
+		"B": "plain",
+		"C": "secret://env/DEPCON_TEST_UNSET",
+	})
+	assert.Equal(t, `{"a": "from-env", "b": "plain", "c": "${C}"}`, parsed)
+	assert.Len(t, missing, 1)
+	assert.Equal(t, "C", missing[0].Name)
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.Nil(t, (&Config{}).Validate("vault"))
+	assert.Nil(t, (&Config{Type: TypeSSM}).Validate("params"))
+	assert.NotNil(t, (&Config{}).Validate("keepass"))
+}
@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Completer completes the subcommands, flags and arguments of the commands of Root.  Arguments and flag
+// values are completed with the ValidArgs and completion functions registered for shell completion (eg.
+// application ids)
+type Completer struct {
+	Root *cobra.Command
+}
+
+// Complete returns the candidates for the word being typed at the end of {line} along with that word
+func (c *Completer) Complete(line string) ([]string, string) {
+	words, err := Split(line)
+	if err != nil {
+		return nil, ""
+	}
+	partial := ""
+	if len(words) > 0 && !endsWithSpace(line) {
+		partial, words = words[len(words)-1], words[:len(words)-1]
+	}
+	cmd, rest, err := c.Root.Find(words)
+	if err != nil {
+		return nil, partial
+	}
+
+	candidates := []string{}
+	args, pending := positional(cmd, rest)
+	switch {
+	case pending != nil:
+		// the value of a flag
+		if fn, ok := cmd.GetFlagCompletionFunc(pending.Name); ok {
+			values, _ := fn(cmd, args, partial)
+			candidates = append(candidates, values...)
+		}
+	case strings.HasPrefix(partial, "-"):
+		add := func(f *pflag.Flag) {
+			if !f.Hidden {
+				candidates = append(candidates, "--"+f.Name)
+			}
+		}
+		cmd.Flags().VisitAll(add)
+		cmd.InheritedFlags().VisitAll(add)
+	default:
+		if len(args) == 0 {
+			for _, sub := range cmd.Commands() {
+				if sub.IsAvailableCommand() {
+					candidates = append(candidates, sub.Name())
+				}
+			}
+		}
+		candidates = append(candidates, cmd.ValidArgs...)
+		if cmd.ValidArgsFunction != nil {
+			values, _ := cmd.ValidArgsFunction(cmd, args, partial)
+			candidates = append(candidates, values...)
+		}
+	}
+	return matching(candidates, partial), partial
+}
+
+// Do completes the word before {pos} within {line} returning the remainder of each candidate (readline's
+// AutoCompleter)
+func (c *Completer) Do(line []rune, pos int) ([][]rune, int) {
+	candidates, partial := c.Complete(string(line[:pos]))
+	suffixes := [][]rune{}
+	for _, candidate := range candidates {
+		suffixes = append(suffixes, []rune(candidate[len(partial):]+" "))
+	}
+	return suffixes, len([]rune(partial))
+}
+
+// Returns the positional arguments within {words} following command {cmd} and the flag whose value is
+// expected next (nil when none is)
+func positional(cmd *cobra.Command, words []string) ([]string, *pflag.Flag) {
+	args := []string{}
+	var pending *pflag.Flag
+	for _, w := range words {
+		if pending != nil {
+			pending = nil
+			continue
+		}
+		if !strings.HasPrefix(w, "-") || w == "-" {
+			args = append(args, w)
+			continue
+		}
+		pending = flagExpectingValue(cmd, w)
+	}
+	return args, pending
+}
+
+// Returns the flag named by {word} (eg. --env or -e) when its value is the next word
+func flagExpectingValue(cmd *cobra.Command, word string) *pflag.Flag {
+	name := strings.TrimLeft(word, "-")
+	if strings.Contains(name, "=") {
+		return nil
+	}
+	var f *pflag.Flag
+	switch {
+	case strings.HasPrefix(word, "--"):
+		f = cmd.Flag(name)
+	case len(name) == 1:
+		if f = cmd.Flags().ShorthandLookup(name); f == nil {
+			f = cmd.InheritedFlags().ShorthandLookup(name)
+		}
+	}
+	if f == nil || f.NoOptDefVal != "" {
+		return nil
+	}
+	return f
+}
+
+// Returns the distinct {candidates} starting with {prefix} without their descriptions, sorted
+func matching(candidates []string, prefix string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, c := range candidates {
+		// completion functions may describe a candidate after a tab
+		c = strings.SplitN(c, "\t", 2)[0]
+		if strings.HasPrefix(c, prefix) && !seen[c] {
+			seen[c] = true
+			result = append(result, c)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func endsWithSpace(line string) bool {
+	return line != "" && unicode.IsSpace(rune(line[len(line)-1]))
+}
@@ -0,0 +1,202 @@
+// Interactive sessions running the commands of a cobra command tree line by line with history and tab
+// completion
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/chzyer/readline"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var ErrorUnterminatedQuote = errors.New("Unterminated quote")
+
+// commands ending the session
+var exitCommands = []string{"exit", "quit"}
+
+// Shell reads command lines and runs them as commands of Root until it's exited (exit, quit or Ctrl-D)
+type Shell struct {
+	Root   *cobra.Command
+	Prompt string
+	// Optional file the command history is kept in between sessions
+	HistoryFile string
+	// Optional check of the arguments of each line before they're run (eg. refusing commands which can't
+	// run within the shell)
+	Check func(args []string) error
+}
+
+// exit of a command within the shell, recovered so the session continues
+type exitCode int
+
+// Run reads and runs command lines until the session is exited
+func (s *Shell) Run() error {
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:            s.Prompt,
+		HistoryFile:       s.HistoryFile,
+		HistorySearchFold: true,
+		AutoComplete:      &Completer{Root: s.Root},
+		InterruptPrompt:   "^C",
+		EOFPrompt:         "exit",
+	})
+	if err != nil {
+		return err
+	}
+	defer rl.Close()
+	// interrupts while a command runs cancel the command rather than the session
+	cli.CancelOnInterrupt()
+
+	for {
+		line, err := rl.Readline()
+		switch {
+		case err == readline.ErrInterrupt:
+			continue
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+		args, err := Split(line)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if len(args) == 1 && contains(exitCommands, args[0]) {
+			return nil
+		}
+		if s.Check != nil {
+			if err := s.Check(args); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				continue
+			}
+		}
+		if code := s.Execute(args); code != cli.ExitSuccess {
+			fmt.Fprintf(os.Stderr, "(exit %d)\n", code)
+		}
+	}
+}
+
+// Execute runs the command line {args} returning its exit code.  Commands exiting end only themselves and
+// the flags they set are restored to their defaults for the next command
+func (s *Shell) Execute(args []string) (code int) {
+	defer ResetFlags(s.Root)
+	defer cli.ResetInterrupt()
+	defer cli.StopProgress()
+	defer cli.SetExitHandler(nil)
+	// commands (eg. plugins) reading the command line see the line being run
+	defer func(saved []string) { os.Args = saved }(os.Args)
+	defer func() {
+		if r := recover(); r != nil {
+			exit, ok := r.(exitCode)
+			if !ok {
+				panic(r)
+			}
+			code = int(exit)
+		}
+	}()
+
+	os.Args = append([]string{os.Args[0]}, args...)
+	cli.SetExitHandler(func(code int) {
+		panic(exitCode(code))
+	})
+	s.Root.SetArgs(args)
+	if err := s.Root.Execute(); err != nil {
+		return cli.ExitUsage
+	}
+	return cli.ExitSuccess
+}
+
+// Split splits {line} into arguments as a POSIX shell does: whitespace separates arguments, single quotes
+// keep their text literally, double quotes keep whitespace and a backslash escapes the next character
+func Split(line string) ([]string, error) {
+	args := []string{}
+	var current strings.Builder
+	inArg, escaped := false, false
+	var quote rune
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, ErrorUnterminatedQuote
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// ResetFlags restores the flags of {root} and its subcommands set by a command to their defaults
+func ResetFlags(root *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(defaultSlice(f.DefValue))
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	root.Flags().VisitAll(reset)
+	root.PersistentFlags().VisitAll(reset)
+	for _, c := range root.Commands() {
+		ResetFlags(c)
+	}
+}
+
+// Returns the values of the default {value} of a slice flag (eg. [a,b])
+func defaultSlice(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/cli"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	args, err := Split(`app list  --format '{{ .ID }}' -p "A=b c" x\ y`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app", "list", "--format", "{{ .ID }}", "-p", "A=b c", "x y"}, args)
+
+	args, err = Split(`  `)
+	assert.Nil(t, err)
+	assert.Empty(t, args)
+
+	args, err = Split(`a "" b`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "", "b"}, args)
+
+	_, err = Split(`app get "/web`)
+	assert.Equal(t, ErrorUnterminatedQuote, err)
+}
+
+// returns a tree of app commands completing application ids and the ids of the last app get
+func testTree() (*cobra.Command, *string) {
+	root := &cobra.Command{Use: "depcon"}
+	root.PersistentFlags().StringP("env", "e", "", "")
+	root.RegisterFlagCompletionFunc("env", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"prod", "test"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	app := &cobra.Command{Use: "app"}
+	got := ""
+	get := &cobra.Command{Use: "get", Run: func(cmd *cobra.Command, args []string) {
+		got = args[0]
+		if args[0] == "/missing" {
+			cli.Exit(cli.WithExitCode(cli.ExitNotFound, errors.New("missing")))
+		}
+	}}
+	get.Flags().Bool("wait", false, "")
+	get.Flags().StringSlice("param", []string{}, "")
+	get.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return []string{"/web\tweb server", "/worker", "/db"}, cobra.ShellCompDirectiveNoFileComp
+	}
+	list := &cobra.Command{Use: "list", Run: func(cmd *cobra.Command, args []string) {}}
+	hidden := &cobra.Command{Use: "internal", Hidden: true, Run: func(cmd *cobra.Command, args []string) {}}
+	app.AddCommand(get, list, hidden)
+	root.AddCommand(app)
+	return root, &got
+}
+
+func TestComplete(t *testing.T) {
+	root, _ := testTree()
+	c := &Completer{Root: root}
+
+	candidates, partial := c.Complete("ap")
+	assert.Equal(t, []string{"app"}, candidates)
+	assert.Equal(t, "ap", partial)
+
+	candidates, _ = c.Complete("app ")
+	assert.Equal(t, []string{"get", "list"}, candidates)
+
+	candidates, partial = c.Complete("app get /w")
+	assert.Equal(t, []string{"/web", "/worker"}, candidates)
+	assert.Equal(t, "/w", partial)
+
+	candidates, _ = c.Complete("app get --wait ")
+	assert.Equal(t, []string{"/db", "/web", "/worker"}, candidates)
+
+	candidates, _ = c.Complete("app get /web ")
+	assert.Empty(t, candidates)
+
+	candidates, _ = c.Complete("app get --")
+	assert.Equal(t, []string{"--env", "--param", "--wait"}, candidates)
+
+	candidates, _ = c.Complete("app get -e ")
+	assert.Equal(t, []string{"prod", "test"}, candidates)
+
+	candidates, _ = c.Complete("app get --param ")
+	assert.Empty(t, candidates)
+
+	suffixes, length := c.Do([]rune("app get /wo"), len("app get /wo"))
+	assert.Equal(t, [][]rune{[]rune("rker ")}, suffixes)
+	assert.Equal(t, 3, length)
+}
+
+func TestExecute(t *testing.T) {
+	root, got := testTree()
+	s := &Shell{Root: root}
+
+	assert.Equal(t, cli.ExitSuccess, s.Execute([]string{"app", "get", "/web", "--wait", "--param", "A=1"}))
+	assert.Equal(t, "/web", *got)
+	get, _, _ := root.Find([]string{"app", "get"})
+	wait, _ := get.Flags().GetBool("wait")
+	params, _ := get.Flags().GetStringSlice("param")
+	assert.False(t, wait)
+	assert.Empty(t, params)
+
+	// commands exiting end only themselves
+	assert.Equal(t, cli.ExitNotFound, s.Execute([]string{"app", "get", "/missing"}))
+	assert.Equal(t, cli.ExitUsage, s.Execute([]string{"app", "get", "--unknown"}))
+	assert.Equal(t, cli.ExitSuccess, s.Execute([]string{"app", "list"}))
+}
@@ -0,0 +1,183 @@
+// Detached ed25519 signatures of descriptors checked against trusted keys before deploying so an
+// environment only accepts descriptors signed by the release pipeline
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+const (
+	// appended to the name of a descriptor to name its signature (eg. app.json.sig)
+	Extension = ".sig"
+	Algorithm = "ed25519"
+
+	pemPrivateKey = "PRIVATE KEY"
+	pemPublicKey  = "PUBLIC KEY"
+)
+
+var (
+	ErrorNotSigned        = errors.New("The descriptor has no signature")
+	ErrorUntrustedKey     = errors.New("The descriptor is signed by a key which isn't trusted")
+	ErrorInvalidSignature = errors.New("The signature doesn't match the descriptor (it changed after it was signed)")
+	ErrorInvalidKey       = errors.New("Keys must be PEM encoded ed25519 keys (eg. created with depcon sign --generate-key)")
+)
+
+// Signature is the detached signature of a descriptor
+type Signature struct {
+	Algorithm string `json:"algorithm"`
+	// identifies the public key verifying the signature
+	KeyID string `json:"keyId"`
+	// base64 encoded signature of the descriptor's content
+	Signature string `json:"signature"`
+}
+
+// GenerateKey returns a new PEM encoded key pair
+func GenerateKey() (public, private []byte, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemPublicKey, Bytes: pubDER}),
+		pem.EncodeToMemory(&pem.Block{Type: pemPrivateKey, Bytes: privDER}), nil
+}
+
+// ParsePrivateKey parses the PEM encoded private key {data}
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemPrivateKey {
+		return nil, ErrorInvalidKey
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrorInvalidKey
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, ErrorInvalidKey
+	}
+	return priv, nil
+}
+
+// ParsePublicKey parses the PEM encoded public key {data}
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemPublicKey {
+		return nil, ErrorInvalidKey
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrorInvalidKey
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, ErrorInvalidKey
+	}
+	return pub, nil
+}
+
+// LoadPublicKeys reads the PEM encoded public keys {filenames}
+func LoadPublicKeys(filenames []string) ([]ed25519.PublicKey, error) {
+	keys := []ed25519.PublicKey{}
+	for _, f := range filenames {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", f, err.Error())
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// KeyID returns the identifier of the public key {key}: the start of the hex encoded SHA-256 of the key
+func KeyID(key ed25519.PublicKey) string {
+	return fmt.Sprintf("%x", sha256.Sum256(key))[:16]
+}
+
+// Sign returns the signature of {data} by {key}
+func Sign(data []byte, key ed25519.PrivateKey) *Signature {
+	return &Signature{
+		Algorithm: Algorithm,
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, data)),
+	}
+}
+
+// Verify returns nil if {sig} is a signature of {data} by one of the {trusted} keys
+func Verify(data []byte, sig *Signature, trusted []ed25519.PublicKey) error {
+	if sig.Algorithm != Algorithm {
+		return fmt.Errorf("Unsupported signature algorithm '%s'", sig.Algorithm)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return ErrorInvalidSignature
+	}
+	for _, key := range trusted {
+		if KeyID(key) != sig.KeyID {
+			continue
+		}
+		if !ed25519.Verify(key, data, raw) {
+			return ErrorInvalidSignature
+		}
+		return nil
+	}
+	return ErrorUntrustedKey
+}
+
+// SignFile writes the signature of the file {filename} by {key} to {filename}.sig returning its name
+func SignFile(filename string, key ed25519.PrivateKey) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(Sign(data, key), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	sigfile := filename + Extension
+	return sigfile, ioutil.WriteFile(sigfile, append(b, '\n'), 0644)
+}
+
+// VerifyFile returns nil if the file {filename} has a signature ({filename}.sig) by one of the {trusted} keys
+func VerifyFile(filename string, trusted []ed25519.PublicKey) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(filename + Extension)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%s: %w", filename, ErrorNotSigned)
+	}
+	if err != nil {
+		return err
+	}
+	sig := &Signature{}
+	if err := json.Unmarshal(b, sig); err != nil {
+		return fmt.Errorf("%s: %s", filename+Extension, err.Error())
+	}
+	if err := Verify(data, sig, trusted); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	return nil
+}
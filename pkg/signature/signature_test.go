@@ -0,0 +1,48 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerifyFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "signature")
+	defer os.RemoveAll(dir)
+	pubPEM, privPEM, err := GenerateKey()
+	assert.Nil(t, err)
+	priv, err := ParsePrivateKey(privPEM)
+	assert.Nil(t, err)
+	pub, err := ParsePublicKey(pubPEM)
+	assert.Nil(t, err)
+	otherPEM, _, _ := GenerateKey()
+	other, _ := ParsePublicKey(otherPEM)
+
+	filename := filepath.Join(dir, "app.json")
+	ioutil.WriteFile(filename, []byte(`{"id": "/web"}`), 0644)
+	assert.True(t, errors.Is(VerifyFile(filename, []ed25519.PublicKey{pub}), ErrorNotSigned))
+
+	sigfile, err := SignFile(filename, priv)
+	assert.Nil(t, err)
+	assert.Equal(t, filename+".sig", sigfile)
+	assert.Nil(t, VerifyFile(filename, []ed25519.PublicKey{other, pub}))
+	assert.True(t, errors.Is(VerifyFile(filename, []ed25519.PublicKey{other}), ErrorUntrustedKey))
+
+	ioutil.WriteFile(filename, []byte(`{"id": "/web", "instances": 9}`), 0644)
+	assert.True(t, errors.Is(VerifyFile(filename, []ed25519.PublicKey{pub}), ErrorInvalidSignature))
+}
+
+func TestParseInvalidKeys(t *testing.T) {
+	pubPEM, privPEM, _ := GenerateKey()
+	_, err := ParsePublicKey(privPEM)
+	assert.Equal(t, ErrorInvalidKey, err)
+	_, err = ParsePrivateKey(pubPEM)
+	assert.Equal(t, ErrorInvalidKey, err)
+	_, err = ParsePublicKey([]byte("not a key"))
+	assert.Equal(t, ErrorInvalidKey, err)
+}
@@ -0,0 +1,70 @@
+// Transparently decrypts descriptors and template contexts encrypted by sops (age, PGP or a KMS) using the
+// sops executable so secret-bearing files can be kept in git and still be deployed directly
+package sops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var ErrorSopsNotFound = errors.New("The sops executable could not be found on the PATH (required to decrypt files encrypted by sops)")
+
+var (
+	// sops encrypts values as ENC[AES256_GCM,data:...] and adds its metadata (keys and mac) under a
+	// top-level sops key (or sops_ prefixed keys within env files)
+	encryptedValue = regexp.MustCompile(`ENC\[AES256_GCM,data:`)
+	yamlMetadata   = regexp.MustCompile(`(?m)^sops:\s*$`)
+	jsonMetadata   = regexp.MustCompile(`"sops"\s*:\s*\{`)
+	dotenvMetadata = regexp.MustCompile(`(?m)^sops_mac=`)
+)
+
+// IsEncrypted returns true if {data} is a JSON, YAML or KEY=VALUE (dotenv) document encrypted by sops
+func IsEncrypted(data []byte) bool {
+	return encryptedValue.Match(data) && (yamlMetadata.Match(data) || jsonMetadata.Match(data) || dotenvMetadata.Match(data))
+}
+
+// ReadFile returns the content of {filename} decrypted by sops when it's encrypted and otherwise as is
+func ReadFile(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil || !IsEncrypted(data) {
+		return data, err
+	}
+	if dotenvMetadata.Match(data) {
+		return decrypt(filename, "dotenv")
+	}
+	return Decrypt(filename)
+}
+
+// Decrypt returns the content of the file {filename} decrypted by sops.  The keys are found as sops finds
+// them (eg. $SOPS_AGE_KEY_FILE, the gpg agent or the AWS, GCP and Azure credentials)
+func Decrypt(filename string) ([]byte, error) {
+	return decrypt(filename, fileFormat(filename))
+}
+
+func decrypt(filename, format string) ([]byte, error) {
+	if _, err := exec.LookPath("sops"); err != nil {
+		return nil, ErrorSopsNotFound
+	}
+	cmd := exec.Command("sops", "--decrypt", "--input-type", format, "--output-type", format, filename)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Unable to decrypt %s with sops: %s", filename, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// Returns the sops format of {filename}.  Descriptors without a YAML extension are JSON (eg. .jsonc)
+func fileFormat(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return "json"
+}
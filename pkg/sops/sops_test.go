@@ -0,0 +1,66 @@
+package sops
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const encryptedYAML = `env:
+    DB_PASSWORD: ENC[AES256_GCM,data:2Kx9,iv:aa,tag:bb,type:str]
+sops:
+    age:
+        - recipient: age1qyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqszqgpqyqs3290gq
+    mac: ENC[AES256_GCM,data:cc,iv:dd,tag:ee,type:str]
+    version: 3.8.1
+`
+
+const encryptedEnv = `DB_PASSWORD=ENC[AES256_GCM,data:2Kx9,iv:aa,tag:bb,type:str]
+sops_kms__list_0__map_arn=arn:aws:kms:us-east-1:111122223333:key/0f2a
+sops_mac=ENC[AES256_GCM,data:cc,iv:dd,tag:ee,type:str]
+sops_version=3.8.1
+`
+
+func TestIsEncrypted(t *testing.T) {
+	assert.True(t, IsEncrypted([]byte(encryptedYAML)))
+	assert.True(t, IsEncrypted([]byte(`{"env": {"A": "ENC[AES256_GCM,data:2Kx9,iv:aa,tag:bb,type:str]"}, "sops": {"mac": "x"}}`)))
+	assert.False(t, IsEncrypted([]byte(`{"id": "/web", "env": {"A": "1"}}`)))
+	assert.False(t, IsEncrypted([]byte("id: /web\nlabels:\n  sops: managed\n")))
+	assert.True(t, IsEncrypted([]byte(encryptedEnv)))
+	assert.False(t, IsEncrypted([]byte("DB_PASSWORD=s3cret\nsops_mac=managed\n")))
+}
+
+func TestReadFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script in place of sops")
+	}
+	dir, _ := ioutil.TempDir("", "sops")
+	defer os.RemoveAll(dir)
+	plain := filepath.Join(dir, "plain.json")
+	encrypted := filepath.Join(dir, "secret.yaml")
+	ioutil.WriteFile(plain, []byte(`{"id": "/web"}`), 0644)
+	ioutil.WriteFile(encrypted, []byte(encryptedYAML), 0644)
+	env := filepath.Join(dir, "secrets.env.enc")
+	ioutil.WriteFile(env, []byte(encryptedEnv), 0644)
+
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir)
+	b, err := ReadFile(plain)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"id": "/web"}`, string(b))
+	_, err = ReadFile(encrypted)
+	assert.Equal(t, ErrorSopsNotFound, err)
+
+	// a stand-in for sops printing its arguments
+	ioutil.WriteFile(filepath.Join(dir, "sops"), []byte("#!/bin/sh\necho \"$@\"\n"), 0755)
+	b, err = ReadFile(encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, "--decrypt --input-type yaml --output-type yaml "+encrypted+"\n", string(b))
+	b, err = ReadFile(env)
+	assert.Nil(t, err)
+	assert.Equal(t, "--decrypt --input-type dotenv --output-type dotenv "+env+"\n", string(b))
+}
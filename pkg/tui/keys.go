@@ -0,0 +1,94 @@
+package tui
+
+import "unicode/utf8"
+
+// Names of the keys which aren't characters
+const (
+	KeyUp        = "up"
+	KeyDown      = "down"
+	KeyLeft      = "left"
+	KeyRight     = "right"
+	KeyPageUp    = "pgup"
+	KeyPageDown  = "pgdn"
+	KeyHome      = "home"
+	KeyEnd       = "end"
+	KeyEnter     = "enter"
+	KeyEscape    = "esc"
+	KeyBackspace = "backspace"
+	KeyTab       = "tab"
+	KeyCtrlC     = "ctrl-c"
+)
+
+// Key is a key pressed: a character (Rune) or a named key (Name)
+type Key struct {
+	Rune rune
+	Name string
+}
+
+// Is returns true if the key is the character or named key {name}
+func (k Key) Is(name string) bool {
+	if k.Name != "" {
+		return k.Name == name
+	}
+	return string(k.Rune) == name
+}
+
+// escape sequences of the named keys as terminals send them
+var sequences = map[string]string{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[5~": KeyPageUp, "[6~": KeyPageDown,
+	"[H": KeyHome, "[F": KeyEnd, "OH": KeyHome, "OF": KeyEnd,
+	"[1~": KeyHome, "[4~": KeyEnd, "[7~": KeyHome, "[8~": KeyEnd,
+}
+
+// ParseKeys returns the keys within the input {p} read from a terminal in raw mode.  An escape which
+// doesn't start a known sequence is the escape key
+func ParseKeys(p []byte) []Key {
+	keys := []Key{}
+	for len(p) > 0 {
+		switch b := p[0]; {
+		case b == 0x1b:
+			name, size := parseSequence(p)
+			if name != "" {
+				keys = append(keys, Key{Name: name})
+			}
+			p = p[size:]
+			continue
+		case b == '\r' || b == '\n':
+			keys = append(keys, Key{Name: KeyEnter})
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, Key{Name: KeyBackspace})
+		case b == '\t':
+			keys = append(keys, Key{Name: KeyTab})
+		case b == 0x03:
+			keys = append(keys, Key{Name: KeyCtrlC})
+		case b < 0x20:
+			// other control characters are ignored
+		default:
+			r, size := utf8.DecodeRune(p)
+			keys = append(keys, Key{Rune: r})
+			p = p[size:]
+			continue
+		}
+		p = p[1:]
+	}
+	return keys
+}
+
+// Returns the named key of the escape sequence starting {p} and its length
+func parseSequence(p []byte) (string, int) {
+	if len(p) < 2 || (p[1] != '[' && p[1] != 'O') {
+		return KeyEscape, 1
+	}
+	for i := 2; i < len(p); i++ {
+		if p[i] >= 0x40 && p[i] <= 0x7e {
+			if name, ok := sequences[string(p[1:i+1])]; ok {
+				return name, i + 1
+			}
+			// unknown sequences are skipped
+			return "", i + 1
+		}
+	}
+	return KeyEscape, 1
+}
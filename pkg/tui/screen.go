@@ -0,0 +1,152 @@
+// Full-screen terminal views: the terminal is switched to its alternate screen in raw mode, frames of lines
+// are drawn over it and key presses are delivered as they're typed
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+)
+
+var ErrorNotTerminal = errors.New("A full-screen view requires a terminal")
+
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+	home           = "\x1b[H"
+	clearLine      = "\x1b[K"
+	clearBelow     = "\x1b[J"
+	reverseSGR     = "\x1b[7m"
+	resetSGR       = "\x1b[0m"
+)
+
+// Screen is a terminal switched to a full-screen view until it's closed
+type Screen struct {
+	in    *os.File
+	out   *bufio.Writer
+	state *readline.State
+	keys  chan Key
+}
+
+// Open switches the terminal of stdin and stdout to a full-screen view reading keys from stdin.  Returns
+// ErrorNotTerminal when either isn't a terminal
+func Open() (*Screen, error) {
+	in, out := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !readline.IsTerminal(in) || !readline.IsTerminal(out) {
+		return nil, ErrorNotTerminal
+	}
+	state, err := readline.MakeRaw(in)
+	if err != nil {
+		return nil, err
+	}
+	s := &Screen{in: os.Stdin, out: bufio.NewWriter(os.Stdout), state: state, keys: make(chan Key, 16)}
+	s.out.WriteString(enterAltScreen)
+	s.out.Flush()
+	go s.readKeys()
+	return s, nil
+}
+
+// Keys returns the keys pressed.  The channel is closed when stdin is
+func (s *Screen) Keys() <-chan Key {
+	return s.keys
+}
+
+// Size returns the width and height of the terminal
+func (s *Screen) Size() (int, int) {
+	w, h, err := readline.GetSize(int(s.in.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// Draw replaces the screen with {lines} cut to the width and height of the terminal
+func (s *Screen) Draw(lines []string) {
+	w, h := s.Size()
+	s.out.WriteString(home)
+	for i, line := range lines {
+		if i >= h {
+			break
+		}
+		if i > 0 {
+			s.out.WriteString("\r\n")
+		}
+		s.out.WriteString(Truncate(line, w))
+		s.out.WriteString(resetSGR + clearLine)
+	}
+	s.out.WriteString(clearBelow)
+	s.out.Flush()
+}
+
+// Close restores the terminal as it was before the screen was opened
+func (s *Screen) Close() {
+	s.out.WriteString(leaveAltScreen)
+	s.out.Flush()
+	readline.Restore(int(s.in.Fd()), s.state)
+}
+
+func (s *Screen) readKeys() {
+	defer close(s.keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := s.in.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range ParseKeys(buf[:n]) {
+			s.keys <- k
+		}
+	}
+}
+
+// Reverse renders {text} in reverse video (eg. the selected row)
+func Reverse(text string) string {
+	return reverseSGR + text + resetSGR
+}
+
+// Truncate cuts {line} to {width} visible characters.  Escape sequences within the line aren't counted
+func Truncate(line string, width int) string {
+	var b strings.Builder
+	visible := 0
+	for i := 0; i < len(line); {
+		if line[i] == '\x1b' {
+			end := escapeEnd(line, i)
+			b.WriteString(line[i:end])
+			i = end
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		if r == '\t' || r == '\r' || r == '\n' {
+			r, size = ' ', 1
+		}
+		if visible >= width {
+			i += size
+			continue
+		}
+		b.WriteRune(r)
+		visible++
+		i += size
+	}
+	return b.String()
+}
+
+// Returns the index following the escape sequence starting at {i} of {s}
+func escapeEnd(s string, i int) int {
+	j := i + 1
+	if j < len(s) && s[j] == '[' {
+		for j++; j < len(s); j++ {
+			if s[j] >= 0x40 && s[j] <= 0x7e {
+				return j + 1
+			}
+		}
+		return len(s)
+	}
+	if j < len(s) {
+		return j + 1
+	}
+	return j
+}
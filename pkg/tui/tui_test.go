@@ -0,0 +1,27 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeys(t *testing.T) {
+	keys := ParseKeys([]byte("s3\r\x1b[A\x1bOB\x1b[5~\x1b\x7f\x03é\x1b[99z"))
+	assert.Equal(t, []Key{
+		{Rune: 's'}, {Rune: '3'}, {Name: KeyEnter}, {Name: KeyUp}, {Name: KeyDown}, {Name: KeyPageUp},
+		{Name: KeyEscape}, {Name: KeyBackspace}, {Name: KeyCtrlC}, {Rune: 'é'},
+	}, keys)
+	assert.True(t, keys[0].Is("s"))
+	assert.True(t, keys[3].Is(KeyUp))
+	assert.False(t, keys[3].Is("u"))
+
+	assert.Equal(t, []Key{{Name: KeyEscape}}, ParseKeys([]byte("\x1b")))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", Truncate("abcdef", 3))
+	assert.Equal(t, "a b", Truncate("a\tb", 5))
+	assert.Equal(t, "\x1b[7mab\x1b[0m", Truncate("\x1b[7mabcd\x1b[0m", 2), "escape sequences aren't counted or cut")
+	assert.Equal(t, "héllo", Truncate("héllo world", 5))
+}
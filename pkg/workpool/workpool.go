@@ -0,0 +1,101 @@
+// Runs independent operations (eg. deploying many apps) on a bounded number of workers collecting the
+// outcome of every operation so a partial failure doesn't hide the operations which succeeded
+package workpool
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+)
+
+// Task is an operation named Name (eg. the id of the app it touches)
+type Task struct {
+	Name string
+	Run  func() error
+}
+
+// Result is the outcome of a task
+type Result struct {
+	Name     string        `json:"name"`
+	Result   string        `json:"result"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Err      error         `json:"-"`
+}
+
+// Run runs {tasks} on at most {parallel} workers (1 when less) and returns their results in the order of
+// {tasks}.  Every task is run regardless of failures.  {done} is called as each task completes with the
+// number completed so far and may be nil
+func Run(parallel int, tasks []*Task, done func(completed, total int, r *Result)) []*Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]*Result, len(tasks))
+	indexes := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	completed := 0
+
+	for w := 0; w < parallel && w < len(tasks); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				r := run(tasks[i])
+				mu.Lock()
+				results[i] = r
+				completed++
+				if done != nil {
+					done(completed, len(tasks), r)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range tasks {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+func run(t *Task) *Result {
+	start := time.Now()
+	r := &Result{Name: t.Name, Result: ResultSucceeded}
+	if err := t.Run(); err != nil {
+		r.Result, r.Error, r.Err = ResultFailed, err.Error(), err
+	}
+	r.Duration = time.Since(start).Round(time.Millisecond)
+	return r
+}
+
+// Failed returns the results of the tasks which failed
+func Failed(results []*Result) []*Result {
+	failed := []*Result{}
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing the tasks which failed or nil when every task succeeded
+func Err(results []*Result) error {
+	failed := Failed(results)
+	if len(failed) == 0 {
+		return nil
+	}
+	lines := []string{}
+	for _, r := range failed {
+		lines = append(lines, fmt.Sprintf("  %s: %s", r.Name, r.Error))
+	}
+	return fmt.Errorf("%d of %d operation(s) failed:\n%s", len(failed), len(results), strings.Join(lines, "\n"))
+}
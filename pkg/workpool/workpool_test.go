@@ -0,0 +1,55 @@
+package workpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunBoundsWorkers(t *testing.T) {
+	var running, peak int32
+	tasks := []*Task{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		tasks = append(tasks, &Task{Name: name, Run: func() error {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil
+		}})
+	}
+
+	completed := []int{}
+	results := Run(2, tasks, func(done, total int, r *Result) {
+		assert.Equal(t, 6, total)
+		completed = append(completed, done)
+	})
+	assert.Equal(t, int32(2), peak)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6}, completed)
+	for i, r := range results {
+		assert.Equal(t, tasks[i].Name, r.Name)
+		assert.Equal(t, ResultSucceeded, r.Result)
+	}
+	assert.Nil(t, Err(results))
+}
+
+func TestRunAggregatesFailures(t *testing.T) {
+	tasks := []*Task{
+		{Name: "/web", Run: func() error { return nil }},
+		{Name: "/api", Run: func() error { return errors.New("409 conflict") }},
+		{Name: "/db", Run: func() error { return errors.New("timed out") }},
+	}
+	results := Run(0, tasks, nil)
+	assert.Equal(t, ResultSucceeded, results[0].Result)
+	assert.Equal(t, ResultFailed, results[1].Result)
+	assert.Len(t, Failed(results), 2)
+	assert.Equal(t, "2 of 3 operation(s) failed:\n  /api: 409 conflict\n  /db: timed out", Err(results).Error())
+}
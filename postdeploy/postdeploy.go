@@ -0,0 +1,190 @@
+// Smoke tests run once a deployment has converged: a shell command or an HTTP request which must succeed
+// for the deployment to be considered successful
+package postdeploy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	DefaultRetries  = 3
+	DefaultInterval = 5 * time.Second
+)
+
+var log = logger.GetLogger("depcon.postdeploy")
+
+var ErrorNoCheck = errors.New("A post-deploy check requires a command or a URL")
+
+// Check is a command and/or URL which must succeed after a deployment.  Both are templates rendered with the
+// Target (eg. ./smoke.sh {{.Endpoint}}) and are retried until they succeed or run out of retries
+type Check struct {
+	// Command run by the shell.  DEPCON_APP_ID, DEPCON_ENV, DEPCON_VERSION and DEPCON_ENDPOINT are set
+	Command string
+	// URL requested with GET which must respond with a 2xx status
+	URL string
+	// Attempts before the check fails.  Default: DefaultRetries
+	Retries int
+	// Time between attempts.  Default: DefaultInterval
+	Interval time.Duration
+	// Client used to request the URL.  http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Target is the deployed application the templates of a check are rendered with
+type Target struct {
+	ID          string
+	Version     string
+	Environment string
+	// host:port of the first running task or empty when no task exposes a port
+	Endpoint string
+	// host:port of every running task
+	Endpoints []string
+}
+
+// TargetOf returns the target of the application {app} deployed to the environment {env}
+func TargetOf(app *marathon.Application, env string) *Target {
+	t := &Target{ID: app.ID, Version: app.Version, Environment: env, Endpoints: []string{}}
+	for _, task := range app.Tasks {
+		if task == nil || task.StartedAt == "" || len(task.Ports) == 0 {
+			continue
+		}
+		t.Endpoints = append(t.Endpoints, task.Host+":"+strconv.Itoa(task.Ports[0]))
+	}
+	if len(t.Endpoints) > 0 {
+		t.Endpoint = t.Endpoints[0]
+	}
+	return t
+}
+
+// Enabled returns true if the check has a command or URL to run
+func (c *Check) Enabled() bool {
+	return c != nil && (c.Command != "" || c.URL != "")
+}
+
+// Result is the outcome of running a check
+type Result struct {
+	Attempts int
+	// Output of the command and the status of the URL on the last attempt
+	Output string
+}
+
+// Run renders the command and URL for {target} and attempts them until both succeed or the check runs
+// out of retries
+func (c *Check) Run(target *Target) (*Result, error) {
+	if !c.Enabled() {
+		return nil, ErrorNoCheck
+	}
+	command, err := render("command", c.Command, target)
+	if err != nil {
+		return nil, err
+	}
+	url, err := render("URL", c.URL, target)
+	if err != nil {
+		return nil, err
+	}
+
+	retries, interval := c.Retries, c.Interval
+	if retries <= 0 {
+		retries = DefaultRetries
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	result := &Result{}
+	for {
+		result.Attempts++
+		result.Output, err = c.attempt(command, url, target)
+		if err == nil {
+			log.Info("Post-deploy check of '%s' passed", target.ID)
+			return result, nil
+		}
+		if result.Attempts >= retries {
+			return result, fmt.Errorf("post-deploy check of '%s' failed after %d attempt(s): %s", target.ID, retries, err.Error())
+		}
+		log.Debug("Post-deploy check of '%s' failed (attempt %d of %d): %s", target.ID, result.Attempts, retries, err.Error())
+		time.Sleep(interval)
+	}
+}
+
+// Runs the command and requests the URL returning their combined output
+func (c *Check) attempt(command, url string, target *Target) (string, error) {
+	output := []string{}
+	if command != "" {
+		out, err := runCommand(command, target)
+		if out != "" {
+			output = append(output, out)
+		}
+		if err != nil {
+			return strings.Join(output, "\n"), err
+		}
+	}
+	if url != "" {
+		status, err := c.get(url)
+		if status != "" {
+			output = append(output, status)
+		}
+		if err != nil {
+			return strings.Join(output, "\n"), err
+		}
+	}
+	return strings.Join(output, "\n"), nil
+}
+
+func runCommand(command string, target *Target) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), "DEPCON_APP_ID="+target.ID, "DEPCON_ENV="+target.Environment,
+		"DEPCON_VERSION="+target.Version, "DEPCON_ENDPOINT="+target.Endpoint)
+	b, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(b))
+	if err != nil {
+		return out, fmt.Errorf("'%s' failed: %s %s", command, err.Error(), out)
+	}
+	return out, nil
+}
+
+// Requests {url} returning its status line
+func (c *Check) get(url string) (string, error) {
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	status := fmt.Sprintf("GET %s returned %d", url, resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return status, errors.New(status)
+	}
+	return status, nil
+}
+
+// Renders the template {text} of the check's {field} with {target}
+func render(field, text string, target *Target) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	t, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("Invalid post-deploy %s '%s': %s", field, text, err.Error())
+	}
+	b := &bytes.Buffer{}
+	if err := t.Execute(b, target); err != nil {
+		return "", fmt.Errorf("Invalid post-deploy %s '%s': %s", field, text, err.Error())
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,63 @@
+package postdeploy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTargetOf(t *testing.T) {
+	app := &marathon.Application{ID: "/web", Version: "v2", Tasks: []*marathon.Task{
+		{Host: "staged", Ports: []int{31000}},
+		{Host: "no-ports", StartedAt: "x"},
+		{Host: "agent-1", Ports: []int{31001, 31002}, StartedAt: "x"},
+		{Host: "agent-2", Ports: []int{31003}, StartedAt: "x"},
+	}}
+	target := TargetOf(app, "prod")
+	assert.Equal(t, &Target{ID: "/web", Version: "v2", Environment: "prod", Endpoint: "agent-1:31001",
+		Endpoints: []string{"agent-1:31001", "agent-2:31003"}}, target)
+}
+
+func TestRunCommand(t *testing.T) {
+	target := &Target{ID: "/web", Environment: "prod", Endpoint: "agent-1:31001"}
+
+	check := &Check{Command: `test "{{.Endpoint}}" = agent-1:31001 && test "$DEPCON_ENV" = prod && echo ok`, Retries: 1}
+	result, err := check.Run(target)
+	assert.Nil(t, err)
+	assert.Equal(t, &Result{Attempts: 1, Output: "ok"}, result)
+
+	check = &Check{Command: "echo down && exit 3", Retries: 2, Interval: time.Millisecond}
+	result, err = check.Run(target)
+	assert.EqualError(t, err, "post-deploy check of '/web' failed after 2 attempt(s): 'echo down && exit 3' failed: exit status 3 down")
+	assert.Equal(t, &Result{Attempts: 2, Output: "down"}, result)
+
+	check = &Check{Command: "./smoke.sh {{.Missing}}"}
+	_, err = check.Run(target)
+	assert.Contains(t, err.Error(), "Invalid post-deploy command")
+}
+
+func TestRunURL(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/health/web", r.URL.Path)
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	check := &Check{URL: s.URL + "/health{{.ID}}", Interval: time.Millisecond}
+	result, err := check.Run(&Target{ID: "/web"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, "GET "+s.URL+"/health/web returned 200", result.Output)
+	assert.Equal(t, 2, requests)
+
+	_, err = (&Check{}).Run(&Target{})
+	assert.Equal(t, ErrorNoCheck, err)
+}
@@ -0,0 +1,71 @@
+package reconcile
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ContainX/depcon/marathon"
+)
+
+const (
+	// the application differs from its descriptor
+	DriftChanged = "changed"
+	// the application of a descriptor isn't running
+	DriftMissing = "missing"
+	// the application is running but no descriptor defines it
+	DriftUnmanaged = "unmanaged"
+)
+
+// Drift is an application which differs from the descriptors
+type Drift struct {
+	ID     string                 `json:"id"`
+	File   string                 `json:"file,omitempty"`
+	Type   string                 `json:"type"`
+	Fields []marathon.FieldChange `json:"fields,omitempty"`
+}
+
+// Detect compares the apps of the descriptors (including those of groups) with the {live} applications.
+// Live applications beneath {prefix} which no descriptor defines are unmanaged.  Unmanaged apps aren't
+// reported when {prefix} is empty
+func Detect(descs []*Descriptor, live []marathon.Application, prefix string) []*Drift {
+	apps := map[string]*marathon.Application{}
+	for i := range live {
+		apps[live[i].ID] = &live[i]
+	}
+
+	drift := []*Drift{}
+	managed := map[string]bool{}
+	for _, d := range descs {
+		desired := map[string]*marathon.Application{d.ID: d.App}
+		if d.Group != nil {
+			desired = GroupApps(d.Group)
+		}
+		for _, id := range sortedIDs(desired) {
+			managed[id] = true
+			current, ok := apps[id]
+			if !ok {
+				drift = append(drift, &Drift{ID: id, File: d.File, Type: DriftMissing})
+			} else if fields := marathon.DiffApplication(desired[id], current); len(fields) > 0 {
+				drift = append(drift, &Drift{ID: id, File: d.File, Type: DriftChanged, Fields: fields})
+			}
+		}
+	}
+
+	if prefix == "" {
+		return drift
+	}
+	unmanaged := []*Drift{}
+	for id := range apps {
+		if !managed[id] && withinPrefix(id, prefix) {
+			unmanaged = append(unmanaged, &Drift{ID: id, Type: DriftUnmanaged})
+		}
+	}
+	sort.Slice(unmanaged, func(i, j int) bool { return unmanaged[i].ID < unmanaged[j].ID })
+	return append(drift, unmanaged...)
+}
+
+// Returns true if the app {id} is {prefix} or within the group {prefix}
+func withinPrefix(id, prefix string) bool {
+	prefix = absoluteID("/", prefix)
+	return prefix == "/" || id == prefix || strings.HasPrefix(id, prefix+"/")
+}
@@ -0,0 +1,167 @@
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/encoding"
+)
+
+const (
+	// label of the apps deployed by a manifest holding the manifest's name
+	LabelManifest = "DEPCON_MANIFEST"
+	// label of the apps deployed by a group descriptor of a manifest holding the group's id
+	LabelManifestGroup = "DEPCON_MANIFEST_GROUP"
+)
+
+var (
+	ErrorManifestName      = errors.New("The manifest requires a name identifying the resources it manages")
+	ErrorManifestResources = errors.New("The manifest declares no resources")
+)
+
+// Manifest declares the apps and groups (by descriptor file) managed together
+type Manifest struct {
+	// Identifies the resources managed by the manifest.  Resources labeled with the name which are no longer
+	// declared are pruned
+	Name string `json:"name"`
+	// Template context the descriptors are rendered with relative to the manifest
+	TemplateContext string `json:"tempctx,omitempty"`
+	// Values of the ${PARAMS} within the descriptors
+	Params    map[string]string `json:"params,omitempty"`
+	Resources []*Resource       `json:"resources"`
+	dir       string
+}
+
+// Resource is an app or group descriptor of a manifest
+type Resource struct {
+	// Descriptor relative to the manifest
+	File string `json:"file"`
+	// Params of this descriptor overriding those of the manifest
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// LoadManifest parses the manifest {filename} (.yaml, .yml or .json)
+func LoadManifest(filename string) (*Manifest, error) {
+	enc, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Manifest{dir: filepath.Dir(filename)}
+	if err := enc.UnMarshal(f, m); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	if m.Name == "" {
+		return nil, ErrorManifestName
+	}
+	if len(m.Resources) == 0 {
+		return nil, ErrorManifestResources
+	}
+	for i, r := range m.Resources {
+		if r.File == "" {
+			return nil, fmt.Errorf("%s: resources[%d] requires a file", filename, i)
+		}
+	}
+	return m, nil
+}
+
+// Path returns {name} relative to the manifest unless it is absolute
+func (m *Manifest) Path(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(m.dir, name)
+}
+
+// Descriptors loads the descriptors of the manifest's resources labeling their apps with the manifest's
+// name.  The params of {opts} override those of the manifest and its resources
+func (m *Manifest) Descriptors(opts *LoadOptions) ([]*Descriptor, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	descs := []*Descriptor{}
+	for _, r := range m.Resources {
+		o := *opts
+		o.Params = merge(m.Params, r.Params, opts.Params)
+		d, err := LoadFile(m.Path(r.File), &o)
+		if err != nil {
+			return nil, err
+		}
+		descs = append(descs, d...)
+	}
+	for _, d := range descs {
+		m.label(d)
+	}
+	return descs, nil
+}
+
+func (m *Manifest) label(d *Descriptor) {
+	if d.App != nil {
+		d.App.Labels = withLabel(d.App.Labels, LabelManifest, m.Name)
+		return
+	}
+	for _, app := range GroupApps(d.Group) {
+		app.Labels = withLabel(app.Labels, LabelManifest, m.Name)
+		app.Labels[LabelManifestGroup] = d.ID
+	}
+}
+
+// PlanPrune returns the deletes of the {live} apps labeled with manifest {name} which none of {descs}
+// declare.  Groups a manifest declared are deleted whole once none of their apps are declared
+func PlanPrune(name string, descs []*Descriptor, live []marathon.Application) []*Change {
+	declared := map[string]bool{}
+	for _, d := range descs {
+		declared[d.ID] = true
+		if d.Group != nil {
+			for id := range GroupApps(d.Group) {
+				declared[id] = true
+			}
+		}
+	}
+
+	groups := map[string]bool{}
+	changes := []*Change{}
+	for _, app := range live {
+		if app.Labels[LabelManifest] != name || declared[app.ID] {
+			continue
+		}
+		if group := app.Labels[LabelManifestGroup]; group != "" && !declared[group] {
+			if !groups[group] {
+				groups[group] = true
+				changes = append(changes, &Change{ID: group, Kind: KindGroup, Action: ActionDelete})
+			}
+			continue
+		}
+		changes = append(changes, &Change{ID: app.ID, Kind: KindApp, Action: ActionDelete})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ID < changes[j].ID })
+	return changes
+}
+
+func withLabel(labels map[string]string, key, value string) map[string]string {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[key] = value
+	return labels
+}
+
+// Returns the union of {maps} where later maps override earlier maps
+func merge(maps ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,76 @@
+package reconcile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManifestDescriptors(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "manifest")
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"manifest.yaml":  "name: shop\nparams:\n  COUNT: \"1\"\nresources:\n  - file: apps/web.json\n    params:\n      COUNT: \"3\"\n  - file: apps/shop.yaml\n",
+		"apps/web.json":  `{"id": "/web", "instances": ${COUNT}, "labels": {"team": "web"}}`,
+		"apps/shop.yaml": "id: /shop\napps:\n  - id: cart\n    instances: ${COUNT}\n",
+	})
+
+	m, err := LoadManifest(filepath.Join(dir, "manifest.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "shop", m.Name)
+
+	descs, err := m.Descriptors(nil)
+	assert.Nil(t, err)
+	assert.Len(t, descs, 2)
+	assert.Equal(t, 3, descs[0].App.Instances)
+	assert.Equal(t, map[string]string{"team": "web", LabelManifest: "shop"}, descs[0].App.Labels)
+	cart := GroupApps(descs[1].Group)["/shop/cart"]
+	assert.Equal(t, 1, cart.Instances)
+	assert.Equal(t, "/shop", cart.Labels[LabelManifestGroup])
+
+	descs, err = m.Descriptors(&LoadOptions{Params: map[string]string{"COUNT": "5"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, descs[0].App.Instances)
+
+	writeFiles(t, dir, map[string]string{"invalid.yaml": "resources:\n  - file: apps/web.json\n"})
+	_, err = LoadManifest(filepath.Join(dir, "invalid.yaml"))
+	assert.Equal(t, ErrorManifestName, err)
+}
+
+func TestPlanPrune(t *testing.T) {
+	descs := []*Descriptor{
+		{ID: "/web", App: &marathon.Application{ID: "/web"}},
+		{ID: "/shop", Group: &marathon.Group{GroupID: "/shop", Apps: []*marathon.Application{{ID: "cart"}}}},
+	}
+	labels := func(group string) map[string]string {
+		l := map[string]string{LabelManifest: "shop"}
+		if group != "" {
+			l[LabelManifestGroup] = group
+		}
+		return l
+	}
+	client := &fakeMarathon{apps: []marathon.Application{
+		{ID: "/web", Labels: labels("")},
+		{ID: "/worker", Labels: labels("")},
+		{ID: "/shop/cart", Labels: labels("/shop")},
+		{ID: "/shop/search", Labels: labels("/shop")},
+		{ID: "/billing/api", Labels: labels("/billing")},
+		{ID: "/billing/db", Labels: labels("/billing")},
+		{ID: "/other", Labels: map[string]string{LabelManifest: "other"}},
+		{ID: "/unlabeled"},
+	}}
+
+	changes := PlanPrune("shop", descs, client.apps)
+	assert.Len(t, changes, 3)
+	assert.Equal(t, Change{ID: "/billing", Kind: KindGroup, Action: ActionDelete}, *changes[0])
+	assert.Equal(t, Change{ID: "/shop/search", Kind: KindApp, Action: ActionDelete}, *changes[1])
+	assert.Equal(t, Change{ID: "/worker", Kind: KindApp, Action: ActionDelete}, *changes[2])
+
+	assert.Equal(t, 0, Apply(client, changes, false, time.Second))
+	assert.Equal(t, []string{"/billing", "/shop/search", "/worker"}, client.deleted)
+}
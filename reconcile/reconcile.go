@@ -0,0 +1,333 @@
+// Reconciles a directory of Marathon descriptors with the applications running in a cluster by planning
+// the creates and updates which bring the cluster in line with the descriptors and applying them
+package reconcile
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/sops"
+	"github.com/ContainX/depcon/pkg/workpool"
+)
+
+const (
+	KindApp   = "app"
+	KindGroup = "group"
+
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionNone   = "none"
+	ActionDelete = "delete"
+
+	ResultApplied = "applied"
+	ResultFailed  = "failed"
+)
+
+var log = logger.GetLogger("depcon.reconcile")
+
+// RenderFunc returns the contents of descriptor {filename} (eg. rendered with a template context)
+type RenderFunc func(filename string) (string, error)
+
+type LoadOptions struct {
+	// Renders each descriptor.  The file is read as is when nil
+	Render RenderFunc
+	// Values of the ${PARAMS} within the descriptors
+	Params map[string]string
+	// Leave ${PARAMS} without a value unresolved rather than failing
+	IgnoreMissing bool
+	// Files within the directory which aren't descriptors (eg. the template context)
+	Exclude []string
+	// Checks each descriptor file before it's rendered (eg. its signature).  Nothing is checked when nil
+	Verify func(filename string) error
+}
+
+// Descriptor is an application or group defined by a file (or a document of a multi-document file)
+type Descriptor struct {
+	File  string
+	ID    string
+	App   *marathon.Application
+	Group *marathon.Group
+}
+
+// Change is the action bringing the cluster in line with a descriptor
+type Change struct {
+	File   string `json:"file"`
+	ID     string `json:"id"`
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	// The fields which differ.  Fields of a group's apps are prefixed with the app id (eg. [/shop/web] instances)
+	Fields []marathon.FieldChange `json:"fields,omitempty"`
+	Result string                 `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+	desc   *Descriptor
+}
+
+// Load renders and parses the descriptors (.json, .yaml and .yml) within {dir} and its sub directories.
+// Hidden files and directories are skipped
+func Load(dir string, opts *LoadOptions) ([]*Descriptor, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	exclude := map[string]bool{}
+	for _, f := range opts.Exclude {
+		if abs, err := filepath.Abs(f); err == nil {
+			exclude[abs] = true
+		}
+	}
+
+	files := []string{}
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && p != dir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if abs, _ := filepath.Abs(p); info.IsDir() || exclude[abs] {
+			return nil
+		}
+		if _, err := encoding.EncoderTypeFromExt(p); err == nil {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	descs := []*Descriptor{}
+	for _, f := range files {
+		d, err := LoadFile(f, opts)
+		if err != nil {
+			return nil, err
+		}
+		descs = append(descs, d...)
+	}
+	return descs, nil
+}
+
+// LoadFile renders and parses the descriptor {filename} returning a descriptor per document
+func LoadFile(filename string, opts *LoadOptions) ([]*Descriptor, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	if opts.Verify != nil {
+		if err := opts.Verify(filename); err != nil {
+			return nil, err
+		}
+	}
+	var content string
+	if opts.Render != nil {
+		rendered, err := opts.Render(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err.Error())
+		}
+		content = rendered
+	} else {
+		b, err := sops.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		content = string(b)
+	}
+
+	et, _ := encoding.EncoderTypeFromExt(filename)
+	enc, _ := encoding.NewEncoder(et)
+	descs := []*Descriptor{}
+	for _, doc := range encoding.SplitDocuments(et, content) {
+		parsed, missing := envsubst.SubstTokens(strings.NewReader(doc), opts.Params)
+		if len(missing) > 0 && !opts.IgnoreMissing {
+			return nil, &envsubst.MissingParamsError{Filename: filename, Params: missing}
+		}
+
+		ag := &marathon.AppOrGroup{}
+		if err := enc.UnMarshalStr(parsed, ag); err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err.Error())
+		}
+		d := &Descriptor{File: filename, ID: absoluteID("/", ag.ID)}
+		var err error
+		if ag.IsApplication() {
+			d.App = new(marathon.Application)
+			err = enc.UnMarshalStr(parsed, d.App)
+		} else {
+			d.Group = new(marathon.Group)
+			err = enc.UnMarshalStr(parsed, d.Group)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err.Error())
+		}
+		if ag.ID == "" {
+			return nil, fmt.Errorf("%s: the id is required", filename)
+		}
+		descs = append(descs, d)
+	}
+	return descs, nil
+}
+
+// Plan compares the descriptors with the {live} applications of the cluster returning a change per descriptor
+func Plan(descs []*Descriptor, live []marathon.Application) []*Change {
+	apps := map[string]*marathon.Application{}
+	for i := range live {
+		apps[live[i].ID] = &live[i]
+	}
+
+	changes := []*Change{}
+	for _, d := range descs {
+		c := &Change{File: d.File, ID: d.ID, Action: ActionNone, desc: d}
+		if d.App != nil {
+			c.Kind = KindApp
+			if current, ok := apps[d.ID]; !ok {
+				c.Action = ActionCreate
+			} else if c.Fields = marathon.DiffApplication(d.App, current); len(c.Fields) > 0 {
+				c.Action = ActionUpdate
+			}
+		} else {
+			c.Kind = KindGroup
+			found := false
+			groupApps := GroupApps(d.Group)
+			for _, id := range sortedIDs(groupApps) {
+				current, ok := apps[id]
+				if !ok {
+					c.Fields = append(c.Fields, marathon.FieldChange{Path: "[" + id + "]", Desired: ActionCreate})
+					continue
+				}
+				found = true
+				for _, f := range marathon.DiffApplication(groupApps[id], current) {
+					f.Path = "[" + id + "] " + f.Path
+					c.Fields = append(c.Fields, f)
+				}
+			}
+			switch {
+			case !found:
+				c.Action = ActionCreate
+			case len(c.Fields) > 0:
+				c.Action = ActionUpdate
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes
+}
+
+// Changed returns the changes of {changes} which have an action
+func Changed(changes []*Change) []*Change {
+	changed := []*Change{}
+	for _, c := range changes {
+		if c.Action != ActionNone {
+			changed = append(changed, c)
+		}
+	}
+	return changed
+}
+
+// Apply makes the creates, updates and deletes of {changes} in order waiting for each when {wait} is true.
+// Every change is attempted and the number which failed is returned
+func Apply(client marathon.Marathon, changes []*Change, wait bool, timeout time.Duration) int {
+	return ApplyParallel(client, changes, wait, timeout, 1)
+}
+
+// ApplyParallel is Apply making up to {parallel} changes at once.  Changes are started in order but may
+// complete in any order when {parallel} is greater than 1
+func ApplyParallel(client marathon.Marathon, changes []*Change, wait bool, timeout time.Duration, parallel int) int {
+	tasks := []*workpool.Task{}
+	for _, c := range changes {
+		if c.Action == ActionNone {
+			continue
+		}
+		c := c
+		tasks = append(tasks, &workpool.Task{Name: c.ID, Run: func() error {
+			return apply(client, c, wait, timeout)
+		}})
+	}
+	return len(workpool.Failed(workpool.Run(parallel, tasks, nil)))
+}
+
+// Makes the change {c} recording its result
+func apply(client marathon.Marathon, c *Change, wait bool, timeout time.Duration) error {
+	logger.With(log, logger.Fields{logger.FieldApp: c.ID}).Info("Applying %s of %s '%s' (%s)", c.Action, c.Kind, c.ID, c.File)
+	var err error
+	ids := []string{c.ID}
+	switch {
+	case c.Action == ActionDelete:
+		err = destroy(client, c, wait, timeout)
+		ids = nil
+	case c.Kind == KindApp:
+		_, err = client.CreateApplication(c.desc.App, false, c.Action == ActionUpdate)
+	default:
+		_, err = client.CreateGroup(c.desc.Group, false, true)
+		ids = sortedIDs(GroupApps(c.desc.Group))
+	}
+	if err == nil && wait {
+		for _, id := range ids {
+			if err = client.WaitForApplication(id, timeout); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		c.Result, c.Error = ResultFailed, err.Error()
+		return err
+	}
+	c.Result = ResultApplied
+	return nil
+}
+
+func destroy(client marathon.Marathon, c *Change, wait bool, timeout time.Duration) error {
+	var deployment *marathon.DeploymentID
+	var err error
+	if c.Kind == KindApp {
+		deployment, err = client.DestroyApplication(c.ID)
+	} else {
+		deployment, err = client.DestroyGroup(c.ID)
+	}
+	if err != nil || !wait || deployment == nil {
+		return err
+	}
+	return client.WaitForDeployment(deployment.DeploymentID, timeout)
+}
+
+// GroupApps returns the apps of {group} and its sub groups keyed by their absolute ids
+func GroupApps(group *marathon.Group) map[string]*marathon.Application {
+	apps := map[string]*marathon.Application{}
+	collectApps(absoluteID("/", group.GroupID), group, apps)
+	return apps
+}
+
+func sortedIDs(apps map[string]*marathon.Application) []string {
+	ids := []string{}
+	for id := range apps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func collectApps(groupID string, group *marathon.Group, apps map[string]*marathon.Application) {
+	for _, app := range group.Apps {
+		apps[absoluteID(groupID, app.ID)] = app
+	}
+	for _, g := range group.Groups {
+		collectApps(absoluteID(groupID, g.GroupID), g, apps)
+	}
+}
+
+// Returns {id} relative to {parent} unless it is already absolute
+func absoluteID(parent, id string) string {
+	if strings.HasPrefix(id, "/") {
+		return path.Clean(id)
+	}
+	return path.Join(parent, id)
+}
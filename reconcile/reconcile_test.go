@@ -0,0 +1,191 @@
+package reconcile
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/gitrepo"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMarathon records the changes applied.  Calls it doesn't implement panic
+type fakeMarathon struct {
+	marathon.Marathon
+	apps    []marathon.Application
+	created []string
+	updated []string
+	groups  []string
+	deleted []string
+}
+
+func (f *fakeMarathon) ListApplications() (*marathon.Applications, error) {
+	return &marathon.Applications{Apps: f.apps}, nil
+}
+
+func (f *fakeMarathon) CreateApplication(app *marathon.Application, wait, force bool) (*marathon.Application, error) {
+	if force {
+		f.updated = append(f.updated, app.ID)
+	} else {
+		f.created = append(f.created, app.ID)
+	}
+	return app, nil
+}
+
+func (f *fakeMarathon) CreateGroup(group *marathon.Group, wait, force bool) (*marathon.Group, error) {
+	f.groups = append(f.groups, group.GroupID)
+	return group, nil
+}
+
+func (f *fakeMarathon) DestroyApplication(id string) (*marathon.DeploymentID, error) {
+	f.deleted = append(f.deleted, id)
+	return &marathon.DeploymentID{}, nil
+}
+
+func (f *fakeMarathon) DestroyGroup(id string) (*marathon.DeploymentID, error) {
+	f.deleted = append(f.deleted, id)
+	return &marathon.DeploymentID{}, nil
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "reconcile")
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"web.json":          `{"id": "web", "instances": ${COUNT}}`,
+		"shop/group.yaml":   "id: /shop\napps:\n  - id: cart\n---\nid: /api\ninstances: 1\n",
+		"context.json":      `{}`,
+		".hidden/skip.json": `{"id": "skip"}`,
+		"README.md":         "docs",
+	})
+
+	descs, err := Load(dir, &LoadOptions{Params: map[string]string{"COUNT": "2"}, Exclude: []string{filepath.Join(dir, "context.json")}})
+	assert.Nil(t, err)
+	assert.Len(t, descs, 3)
+	assert.Equal(t, "/shop", descs[0].ID)
+	assert.NotNil(t, descs[0].Group)
+	assert.Equal(t, "/api", descs[1].ID)
+	assert.Equal(t, "/web", descs[2].ID)
+	assert.Equal(t, 2, descs[2].App.Instances)
+
+	_, err = Load(dir, &LoadOptions{Exclude: []string{filepath.Join(dir, "context.json")}})
+	assert.NotNil(t, err)
+}
+
+func TestLoadVerifiesEachFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "reconcile")
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"signed.json":   `{"id": "signed"}`,
+		"unsigned.json": `{"id": "unsigned"}`,
+	})
+
+	verified := []string{}
+	_, err := Load(dir, &LoadOptions{Verify: func(filename string) error {
+		verified = append(verified, filepath.Base(filename))
+		if filepath.Base(filename) == "unsigned.json" {
+			return errors.New("unsigned")
+		}
+		return nil
+	}})
+	assert.EqualError(t, err, "unsigned")
+	assert.Equal(t, []string{"signed.json", "unsigned.json"}, verified)
+}
+
+func TestPlanAndApply(t *testing.T) {
+	descs := []*Descriptor{
+		{File: "a.json", ID: "/a", App: &marathon.Application{ID: "/a", Instances: 2}},
+		{File: "b.json", ID: "/b", App: &marathon.Application{ID: "/b", Instances: 1}},
+		{File: "c.json", ID: "/c", App: &marathon.Application{ID: "/c", Instances: 1}},
+		{File: "shop.json", ID: "/shop", Group: &marathon.Group{GroupID: "/shop", Apps: []*marathon.Application{{ID: "cart", Instances: 1}, {ID: "web", Instances: 1}}}},
+	}
+	client := &fakeMarathon{apps: []marathon.Application{
+		{ID: "/a", Instances: 1},
+		{ID: "/b", Instances: 1},
+		{ID: "/shop/cart", Instances: 1},
+	}}
+
+	changes := Plan(descs, client.apps)
+	assert.Equal(t, ActionUpdate, changes[0].Action)
+	assert.Equal(t, "instances", changes[0].Fields[0].Path)
+	assert.Equal(t, ActionNone, changes[1].Action)
+	assert.Equal(t, ActionCreate, changes[2].Action)
+	assert.Equal(t, ActionUpdate, changes[3].Action)
+	assert.Equal(t, "[/shop/web]", changes[3].Fields[0].Path)
+
+	assert.Equal(t, 0, Apply(client, changes, false, time.Second))
+	assert.Equal(t, []string{"/c"}, client.created)
+	assert.Len(t, client.updated, 1)
+	assert.Equal(t, []string{"/shop"}, client.groups)
+	assert.Equal(t, ResultApplied, changes[0].Result)
+	assert.Equal(t, "", changes[1].Result)
+}
+
+func TestSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir, _ := ioutil.TempDir("", "reconcile")
+	defer os.RemoveAll(dir)
+
+	origin := filepath.Join(dir, "origin")
+	writeFiles(t, origin, map[string]string{"prod/web.json": `{"id": "/web", "instances": 3}`, "test/web.json": `{"id": "/web"}`})
+	for _, args := range [][]string{{"init", "-q"}, {"add", "-A"}, {"-c", "user.name=t", "-c", "user.email=t@t", "commit", "-qm", "init"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = origin
+		assert.Nil(t, cmd.Run())
+	}
+
+	client := &fakeMarathon{apps: []marathon.Application{{ID: "/web", Instances: 1}}}
+	log := audit.New(filepath.Join(dir, "audit.log"))
+	s := &Syncer{Repo: &gitrepo.Repo{URL: "file://" + origin, Dir: filepath.Join(dir, "checkout")}, Path: "prod", Environment: "prod", Client: client, Audit: log}
+
+	result, err := s.Sync()
+	assert.Nil(t, err)
+	assert.Len(t, result.Revision, 40)
+	assert.Len(t, result.Changed(), 1)
+	assert.Equal(t, []string{"/web"}, client.updated)
+
+	entries, _ := log.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, ActionUpdate, entries[0].Action)
+	assert.Equal(t, ActionSync, entries[1].Action)
+	assert.Equal(t, result.Revision, entries[1].Details["revision"])
+}
+
+func TestDetect(t *testing.T) {
+	descs := []*Descriptor{
+		{File: "a.json", ID: "/prod/a", App: &marathon.Application{ID: "/prod/a", Instances: 2}},
+		{File: "shop.json", ID: "/prod/shop", Group: &marathon.Group{GroupID: "/prod/shop", Apps: []*marathon.Application{{ID: "cart", Instances: 1}, {ID: "web", Instances: 1}}}},
+	}
+	live := []marathon.Application{
+		{ID: "/prod/a", Instances: 1},
+		{ID: "/prod/shop/cart", Instances: 1},
+		{ID: "/prod/legacy", Instances: 1},
+		{ID: "/test/a", Instances: 1},
+	}
+
+	drift := Detect(descs, live, "/prod")
+	assert.Len(t, drift, 3)
+	assert.Equal(t, DriftChanged, drift[0].Type)
+	assert.Equal(t, "instances", drift[0].Fields[0].Path)
+	assert.Equal(t, "/prod/shop/web", drift[1].ID)
+	assert.Equal(t, DriftMissing, drift[1].Type)
+	assert.Equal(t, "/prod/legacy", drift[2].ID)
+	assert.Equal(t, DriftUnmanaged, drift[2].Type)
+
+	assert.Len(t, Detect(descs, live, ""), 2)
+	assert.Len(t, Detect(descs, live, "/"), 4)
+}
@@ -0,0 +1,139 @@
+package reconcile
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/gitrepo"
+)
+
+const ActionSync = "sync"
+
+// Syncer applies the descriptors within a path of a git repository to an environment
+type Syncer struct {
+	Repo *gitrepo.Repo
+	// Directory of the descriptors relative to the root of the repository
+	Path        string
+	Environment string
+	Client      marathon.Marathon
+	Load        *LoadOptions
+	// Plan the changes without applying them
+	DryRun bool
+	// Wait for each change to complete before the next
+	Wait bool
+	// Maximum time waited on each change.  marathon.WaitForever disables the limit.  Default:
+	// marathon.DefaultTimeout
+	Timeout time.Duration
+	// Number of changes made at once.  Default: 1
+	Parallel int
+	// Records each sync making changes or failing.  Nothing is recorded when nil
+	Audit *audit.Log
+}
+
+// SyncResult is the outcome of a sync of a revision
+type SyncResult struct {
+	Time     time.Time `json:"time"`
+	Revision string    `json:"revision"`
+	Changes  []*Change `json:"changes"`
+	Failed   int       `json:"failed"`
+}
+
+// Changed returns the changes which weren't no-ops
+func (r *SyncResult) Changed() []*Change {
+	return Changed(r.Changes)
+}
+
+// Sync pulls the repository, plans the changes bringing the environment in line with its descriptors and
+// applies them unless this is a dry run
+func (s *Syncer) Sync() (*SyncResult, error) {
+	result := &SyncResult{Time: time.Now().UTC()}
+	err := s.sync(result)
+	s.record(result, err)
+	if err == nil && result.Failed > 0 {
+		err = fmt.Errorf("%d of %d change(s) of revision %s failed", result.Failed, len(result.Changed()), result.Revision)
+	}
+	return result, err
+}
+
+func (s *Syncer) sync(result *SyncResult) error {
+	var err error
+	if result.Revision, err = s.Repo.Sync(); err != nil {
+		return err
+	}
+	descs, err := Load(filepath.Join(s.Repo.Dir, s.Path), s.Load)
+	if err != nil {
+		return err
+	}
+	apps, err := s.Client.ListApplications()
+	if err != nil {
+		return err
+	}
+	result.Changes = Plan(descs, apps.Apps)
+	for _, c := range result.Changes {
+		if rel, err := filepath.Rel(s.Repo.Dir, c.File); err == nil {
+			c.File = rel
+		}
+	}
+	if !s.DryRun {
+		timeout := s.Timeout
+		if timeout == 0 {
+			timeout = marathon.DefaultTimeout
+		}
+		result.Failed = ApplyParallel(s.Client, result.Changes, s.Wait, timeout, s.Parallel)
+	}
+	return nil
+}
+
+// Run syncs every {interval} until {stop} is closed handing each result to {report}
+func (s *Syncer) Run(interval time.Duration, stop <-chan struct{}, report func(*SyncResult, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report(s.Sync())
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Records a sync which applied changes or failed along with each change it made
+func (s *Syncer) record(result *SyncResult, err error) {
+	if s.Audit == nil || s.DryRun || (err == nil && len(result.Changed()) == 0) {
+		return
+	}
+	details := map[string]string{"repo": s.Repo.URL, "path": s.Path, "revision": result.Revision}
+	entries := AuditEntries(s.Environment, result.Changed(), details)
+
+	summary := &audit.Entry{Environment: s.Environment, Action: ActionSync, Target: s.Repo.URL, Result: audit.ResultSuccess, Details: details,
+		Message: fmt.Sprintf("%d change(s), %d failed", len(result.Changed()), result.Failed)}
+	if err != nil || result.Failed > 0 {
+		summary.Result = audit.ResultFailed
+	}
+	if err != nil {
+		summary.Message = err.Error()
+	}
+	for _, e := range append(entries, summary) {
+		if err := s.Audit.Record(e); err != nil {
+			log.Error("Unable to write the audit log %s: %s", s.Audit.Filename(), err.Error())
+			return
+		}
+	}
+}
+
+// AuditEntries returns an audit entry per change of {changes} made to environment {env}
+func AuditEntries(env string, changes []*Change, details map[string]string) []*audit.Entry {
+	entries := []*audit.Entry{}
+	for _, c := range changes {
+		e := &audit.Entry{Environment: env, Action: c.Action, Target: c.ID, Result: audit.ResultSuccess, Details: details}
+		if c.Result == ResultFailed {
+			e.Result, e.Message = audit.ResultFailed, c.Error
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
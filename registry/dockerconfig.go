@@ -0,0 +1,129 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ContainX/depcon/pkg/userdir"
+)
+
+// DockerConfig is the subset of a docker client config (~/.docker/config.json) read by the docker containerizer
+type DockerConfig struct {
+	Auths map[string]*DockerAuth `json:"auths"`
+	// credential helpers are local to the host the config was written on and aren't bundled
+	CredsStore  string            `json:"credsStore,omitempty"`
+	CredHelpers map[string]string `json:"credHelpers,omitempty"`
+}
+
+// DockerAuth is the base64 encoded username:password of a registry within a docker config
+type DockerAuth struct {
+	Auth string `json:"auth"`
+}
+
+// DefaultDockerConfig returns the path of the docker client config of the current user
+func DefaultDockerConfig() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	return filepath.Join(userdir.Get(), ".docker", "config.json")
+}
+
+// LoadDockerConfig reads the docker client config {filename}
+func LoadDockerConfig(filename string) (*DockerConfig, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	config := &DockerConfig{}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("%s is not a valid docker config: %s", filename, err.Error())
+	}
+	if config.Auths == nil {
+		config.Auths = map[string]*DockerAuth{}
+	}
+	return config, nil
+}
+
+// Registries returns the sorted registries the config holds credentials or a credential helper for
+func (c *DockerConfig) Registries() []string {
+	seen := map[string]bool{}
+	for reg := range c.Auths {
+		seen[reg] = true
+	}
+	for reg := range c.CredHelpers {
+		seen[reg] = true
+	}
+	names := []string{}
+	for reg := range seen {
+		names = append(names, reg)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Portable returns a config usable on the agents holding just the auths of {registries} (all registries when
+// empty).  Registries without an auth are asked of their credential helper or the config's credsStore
+func (c *DockerConfig) Portable(registries ...string) (*DockerConfig, error) {
+	if len(registries) == 0 {
+		registries = c.Registries()
+	}
+	portable := &DockerConfig{Auths: map[string]*DockerAuth{}}
+	for _, reg := range registries {
+		key := authKey(reg)
+		if auth, ok := c.Auths[key]; ok && auth != nil && auth.Auth != "" {
+			portable.Auths[key] = auth
+			continue
+		}
+		creds, err := c.Credentials(reg)
+		if err != nil {
+			return nil, err
+		}
+		if creds == nil {
+			return nil, fmt.Errorf("The docker config holds no credentials for registry '%s' (run docker login %s)", reg, reg)
+		}
+		portable.Auths[key] = creds.auth()
+	}
+	if len(portable.Auths) == 0 {
+		return nil, fmt.Errorf("The docker config holds no registry credentials (run docker login)")
+	}
+	return portable, nil
+}
+
+// Credentials returns the credentials of {registry} from its auth or by asking its credential helper (or the
+// config's credsStore).  Returns nil when the config holds no credentials for the registry
+func (c *DockerConfig) Credentials(registry string) (*Credentials, error) {
+	key := authKey(registry)
+	if auth, ok := c.Auths[key]; ok && auth != nil && auth.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("The docker config holds an invalid auth for registry '%s'", registry)
+		}
+		userpass := strings.SplitN(string(decoded), ":", 2)
+		if len(userpass) != 2 {
+			return nil, fmt.Errorf("The docker config holds an invalid auth for registry '%s'", registry)
+		}
+		return &Credentials{Username: userpass[0], Password: userpass[1]}, nil
+	}
+	helper := c.CredHelpers[key]
+	if helper == "" {
+		helper = c.CredsStore
+	}
+	if helper == "" {
+		return nil, nil
+	}
+	return helperCredentials(helper, key)
+}
+
+// Returns the key of {registry} within the auths of a docker config
+func authKey(registry string) string {
+	if registry == DockerHub {
+		return dockerHubAuth
+	}
+	return registry
+}
@@ -0,0 +1,338 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// registry serving the images of Docker Hub
+	dockerHubRegistry = "registry-1.docker.io"
+
+	mediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest  = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex     = "application/vnd.oci.image.index.v1+json"
+
+	// platform whose manifest is read from multi-platform images (the platform of Mesos agents)
+	defaultOS           = "linux"
+	defaultArchitecture = "amd64"
+)
+
+// parameters of a WWW-Authenticate challenge (eg. Bearer realm="...",service="...")
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// Reference is an image reference split into the registry, repository and tag (or digest)
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference splits {image} (eg. nginx:1.25, registry.example.com/shop/web@sha256:...) into its parts.
+// Images without a tag are the latest tag
+func ParseReference(image string) (*Reference, error) {
+	ref := &Reference{Registry: RegistryOf(image)}
+	name := image
+	if ref.Registry != DockerHub || strings.HasPrefix(image, DockerHub+"/") {
+		name = image[strings.Index(image, "/")+1:]
+	}
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+	} else if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	if ref.Registry == DockerHub && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name == "" || strings.ToLower(name) != name {
+		return nil, fmt.Errorf("'%s' is not a valid image reference", image)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// ImageConfig is the configuration of an image (the config blob of its manifest) relevant to running it
+type ImageConfig struct {
+	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	Entrypoint   []string            `json:"Entrypoint"`
+	Cmd          []string            `json:"Cmd"`
+	Env          []string            `json:"Env"`
+	Labels       map[string]string   `json:"Labels"`
+	User         string              `json:"User"`
+	WorkingDir   string              `json:"WorkingDir"`
+	Healthcheck  *struct {
+		Test []string `json:"Test"`
+	} `json:"Healthcheck"`
+}
+
+// Port is a port exposed by an image
+type Port struct {
+	Port     int
+	Protocol string
+}
+
+// Ports returns the exposed ports of the image sorted by port
+func (c *ImageConfig) Ports() []Port {
+	ports := []Port{}
+	for exposed := range c.ExposedPorts {
+		parts := strings.SplitN(exposed, "/", 2)
+		port, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		protocol := "tcp"
+		if len(parts) == 2 {
+			protocol = parts[1]
+		}
+		ports = append(ports, Port{Port: port, Protocol: protocol})
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Port < ports[j].Port || (ports[i].Port == ports[j].Port && ports[i].Protocol < ports[j].Protocol)
+	})
+	return ports
+}
+
+// Inspector reads the configuration of images from their registry with the Docker Registry HTTP API v2
+type Inspector struct {
+	// Optional client used to reach the registries
+	Client *http.Client
+	// Returns the credentials of a registry or nil for anonymous pulls
+	Credentials func(registry string) (*Credentials, error)
+	// Reaches registries over http rather than https (eg. a local registry)
+	PlainHTTP bool
+}
+
+type manifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// Inspect returns the configuration of {image}.  The linux/amd64 image is read from multi-platform images
+func (i *Inspector) Inspect(image string) (*ImageConfig, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return nil, err
+	}
+	session := &registrySession{inspector: i, ref: ref}
+
+	m, err := session.manifest(ref.reference())
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Manifests) > 0 {
+		digest := m.Manifests[0].Digest
+		for _, entry := range m.Manifests {
+			if entry.Platform.OS == defaultOS && entry.Platform.Architecture == defaultArchitecture {
+				digest = entry.Digest
+				break
+			}
+		}
+		if m, err = session.manifest(digest); err != nil {
+			return nil, err
+		}
+	}
+	if m.Config.Digest == "" {
+		return nil, fmt.Errorf("The manifest of %s has no config (schema 1 manifests aren't supported)", image)
+	}
+
+	resp, err := session.get("/blobs/"+m.Config.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	blob := struct {
+		Config *ImageConfig `json:"config"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&blob); err != nil {
+		return nil, fmt.Errorf("Invalid config of %s: %s", image, err.Error())
+	}
+	if blob.Config == nil {
+		blob.Config = &ImageConfig{}
+	}
+	return blob.Config, nil
+}
+
+func (r *Reference) reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+func (r *Reference) String() string {
+	if r.Digest != "" {
+		return r.Registry + "/" + r.Repository + "@" + r.Digest
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// requests against the repository of an image authorizing them as challenged by the registry
+type registrySession struct {
+	inspector     *Inspector
+	ref           *Reference
+	authorization string
+}
+
+func (s *registrySession) manifest(reference string) (*manifest, error) {
+	accept := strings.Join([]string{mediaTypeManifest, mediaTypeManifestList, mediaTypeOCIManifest, mediaTypeOCIIndex}, ", ")
+	resp, err := s.get("/manifests/"+reference, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	m := &manifest{}
+	if err := json.NewDecoder(resp.Body).Decode(m); err != nil {
+		return nil, fmt.Errorf("Invalid manifest of %s: %s", s.ref.Repository, err.Error())
+	}
+	return m, nil
+}
+
+// Gets {path} of the repository answering an authentication challenge once
+func (s *registrySession) get(path, accept string) (*http.Response, error) {
+	host := s.ref.Registry
+	if host == DockerHub {
+		host = dockerHubRegistry
+	}
+	scheme := "https"
+	if s.inspector.PlainHTTP {
+		scheme = "http"
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s%s", scheme, host, s.ref.Repository, path)
+
+	for attempt := 0; ; attempt++ {
+		req, _ := http.NewRequest(http.MethodGet, u, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if s.authorization != "" {
+			req.Header.Set("Authorization", s.authorization)
+		}
+		resp, err := s.client().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			challenge := resp.Header.Get("WWW-Authenticate")
+			drain(resp)
+			if err := s.authorize(challenge); err != nil {
+				return nil, err
+			}
+			continue
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			drain(resp)
+			return nil, fmt.Errorf("Access to %s/%s was denied (%d) - run docker login %s", s.ref.Registry, s.ref.Repository, resp.StatusCode, s.ref.Registry)
+		case resp.StatusCode == http.StatusNotFound:
+			drain(resp)
+			return nil, fmt.Errorf("%s was not found", s.ref)
+		case resp.StatusCode != http.StatusOK:
+			drain(resp)
+			return nil, fmt.Errorf("%s returned %d for %s", s.ref.Registry, resp.StatusCode, u)
+		}
+		return resp, nil
+	}
+}
+
+// Sets the authorization answering the WWW-Authenticate {challenge} of the registry (Basic or Bearer)
+func (s *registrySession) authorize(challenge string) error {
+	var creds *Credentials
+	if s.inspector.Credentials != nil {
+		c, err := s.inspector.Credentials(s.ref.Registry)
+		if err != nil {
+			return err
+		}
+		creds = c
+	}
+
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	switch scheme {
+	case "basic":
+		if creds == nil {
+			return fmt.Errorf("%s requires credentials - run docker login %s", s.ref.Registry, s.ref.Registry)
+		}
+		s.authorization = "Basic " + creds.auth().Auth
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("%s requested unsupported authentication '%s'", s.ref.Registry, challenge)
+	}
+
+	params := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+	if params["realm"] == "" {
+		return fmt.Errorf("%s sent a bearer challenge without a realm", s.ref.Registry)
+	}
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + s.ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequest(http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	resp, err := s.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("The token service of %s refused the pull of %s (%d)", s.ref.Registry, s.ref.Repository, resp.StatusCode)
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	s.authorization = "Bearer " + token.Token
+	return nil
+}
+
+func (s *registrySession) client() *http.Client {
+	if s.inspector.Client != nil {
+		return s.inspector.Client
+	}
+	return http.DefaultClient
+}
+
+func drain(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
@@ -0,0 +1,74 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	ref, _ := ParseReference("nginx")
+	assert.Equal(t, &Reference{Registry: DockerHub, Repository: "library/nginx", Tag: "latest"}, ref)
+	ref, _ = ParseReference("docker.io/bitnami/redis:7.2")
+	assert.Equal(t, &Reference{Registry: DockerHub, Repository: "bitnami/redis", Tag: "7.2"}, ref)
+	ref, _ = ParseReference("localhost:5000/shop/web@sha256:abc")
+	assert.Equal(t, &Reference{Registry: "localhost:5000", Repository: "shop/web", Digest: "sha256:abc"}, ref)
+	_, err := ParseReference("Shop/Web")
+	assert.NotNil(t, err)
+}
+
+func TestInspect(t *testing.T) {
+	var host string
+	tokens := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			user, pass, _ := r.BasicAuth()
+			assert.Equal(t, "deploy:s3cret", user+":"+pass)
+			assert.Equal(t, "repository:shop/web:pull", r.URL.Query().Get("scope"))
+			tokens++
+			fmt.Fprint(w, `{"token": "t0k3n"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="registry",scope="repository:shop/web:pull"`, host))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/shop/web/manifests/1.2":
+			assert.Contains(t, r.Header.Get("Accept"), mediaTypeOCIIndex)
+			fmt.Fprint(w, `{"mediaType": "`+mediaTypeOCIIndex+`", "manifests": [
+				{"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64"}},
+				{"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}}]}`)
+		case "/v2/shop/web/manifests/sha256:amd":
+			fmt.Fprint(w, `{"mediaType": "`+mediaTypeOCIManifest+`", "config": {"digest": "sha256:cfg"}}`)
+		case "/v2/shop/web/blobs/sha256:cfg":
+			fmt.Fprint(w, `{"config": {"ExposedPorts": {"8080/tcp": {}, "53/udp": {}}, "Entrypoint": ["/entrypoint.sh"],
+				"Cmd": ["serve"], "Env": ["PATH=/bin", "MODE=prod"], "Labels": {"team": "shop"}}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+	host = strings.TrimPrefix(s.URL, "http://")
+
+	inspector := &Inspector{PlainHTTP: true, Credentials: func(registry string) (*Credentials, error) {
+		assert.Equal(t, host, registry)
+		return &Credentials{Username: "deploy", Password: "s3cret"}, nil
+	}}
+	config, err := inspector.Inspect(host + "/shop/web:1.2")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, tokens)
+	assert.Equal(t, []Port{{53, "udp"}, {8080, "tcp"}}, config.Ports())
+	assert.Equal(t, []string{"/entrypoint.sh"}, config.Entrypoint)
+	assert.Equal(t, []string{"serve"}, config.Cmd)
+	assert.Equal(t, []string{"PATH=/bin", "MODE=prod"}, config.Env)
+	assert.Equal(t, "shop", config.Labels["team"])
+
+	_, err = inspector.Inspect(host + "/shop/web:missing")
+	assert.Contains(t, err.Error(), "was not found")
+}
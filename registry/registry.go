@@ -0,0 +1,278 @@
+// Packages short-lived Docker registry credentials (from Vault or a docker credential helper) into the
+// docker.tar.gz bundles fetched by applications and adds the bundles to the fetch URIs of the applications
+// pulling from the registries so rotating credentials doesn't mean editing every application
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/secrets"
+)
+
+const (
+	DockerHub = "docker.io"
+	// key of Docker Hub within a docker config
+	dockerHubAuth = "https://index.docker.io/v1/"
+	// file within the bundle read by the docker containerizer
+	dockerConfig = ".docker/config.json"
+)
+
+var log = logger.GetLogger("depcon.registry")
+
+var ErrorNoCredentials = errors.New("A registry must configure credentials (a secret reference), a username and password or a credential helper")
+
+// Config is a registry of an environment whose credentials are packaged for the applications pulling from it
+type Config struct {
+	// Host of the registry (eg. registry.example.com:5000).  Images without a registry are pulled from docker.io
+	Registry string `json:"registry"`
+	// Reference of a secret with username and password fields read at once (eg. the Vault dynamic secret
+	// secret://vault/registry/creds/deploy)
+	Credentials string `json:"credentials,omitempty"`
+	// Username and password when the fields are separate secrets.  Either may be a secret reference
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Docker credential helper asked for the credentials (eg. ecr-login runs docker-credential-ecr-login)
+	Helper string `json:"helper,omitempty"`
+	// Path of the bundle within Marathon's artifact store.  Default: /credentials/<registry>/docker.tar.gz
+	Artifact string `json:"artifact,omitempty"`
+	// URI the agents fetch the bundle from.  Default: the URL of the artifact within Marathon's artifact store
+	URI string `json:"uri,omitempty"`
+}
+
+// Credentials authenticate against a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+func (c *Credentials) auth() *DockerAuth {
+	return &DockerAuth{Auth: base64.StdEncoding.EncodeToString([]byte(c.Username + ":" + c.Password))}
+}
+
+// Validate returns an error unless the registry and a source of credentials are configured
+func (c *Config) Validate() error {
+	if c.Registry == "" {
+		return errors.New("The registry's host is required (eg. registry.example.com)")
+	}
+	if c.Credentials == "" && c.Helper == "" && (c.Username == "" || c.Password == "") {
+		return ErrorNoCredentials
+	}
+	if c.Credentials != "" && !secrets.IsReference(c.Credentials) {
+		return fmt.Errorf("credentials must be a secret reference (eg. secret://vault/registry/creds/deploy) not '%s'", c.Credentials)
+	}
+	return nil
+}
+
+// ArtifactPath returns the path of the registry's bundle within the artifact store
+func (c *Config) ArtifactPath() string {
+	if c.Artifact != "" {
+		return c.Artifact
+	}
+	return path.Join("/credentials", strings.Replace(c.Registry, ":", "_", -1), "docker.tar.gz")
+}
+
+// Fetch returns the current credentials of the registry using {resolver} for secret references
+func (c *Config) Fetch(resolver *secrets.Resolver) (*Credentials, error) {
+	switch {
+	case c.Credentials != "":
+		fields, err := resolver.ResolveFields(c.Credentials)
+		if err != nil {
+			return nil, err
+		}
+		if fields["username"] == "" || fields["password"] == "" {
+			return nil, fmt.Errorf("%s: the secret must have username and password fields", c.Credentials)
+		}
+		return &Credentials{Username: fields["username"], Password: fields["password"]}, nil
+	case c.Helper != "":
+		return helperCredentials(c.Helper, c.Registry)
+	case c.Username != "" && c.Password != "":
+		username, err := resolver.ResolveValue(c.Username)
+		if err != nil {
+			return nil, err
+		}
+		password, err := resolver.ResolveValue(c.Password)
+		if err != nil {
+			return nil, err
+		}
+		return &Credentials{Username: username, Password: password}, nil
+	}
+	return nil, ErrorNoCredentials
+}
+
+// Asks the docker credential helper {helper} for the credentials of {registry}
+func helperCredentials(helper, registry string) (*Credentials, error) {
+	name := "docker-credential-" + helper
+	cmd := exec.Command(name, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s get %s failed: %s", name, registry, strings.TrimSpace(stderr.String()+" "+stdout.String()))
+	}
+	result := struct {
+		Username string
+		Secret   string
+	}{}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("%s returned invalid credentials: %s", name, err.Error())
+	}
+	return &Credentials{Username: result.Username, Password: result.Secret}, nil
+}
+
+// Bundle returns the docker.tar.gz fetched by applications holding a docker config which authenticates
+// against {registry} with {creds}
+func Bundle(registry string, creds *Credentials) ([]byte, error) {
+	return BundleConfig(&DockerConfig{Auths: map[string]*DockerAuth{authKey(registry): creds.auth()}})
+}
+
+// BundleConfig returns the docker.tar.gz fetched by applications holding {config} as .docker/config.json
+func BundleConfig(config *DockerConfig) ([]byte, error) {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	if err := tw.WriteHeader(&tar.Header{Name: ".docker/", Typeflag: tar.TypeDir, Mode: 0700, ModTime: now}); err != nil {
+		return nil, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: dockerConfig, Typeflag: tar.TypeReg, Mode: 0600, Size: int64(len(data)), ModTime: now}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RegistryOf returns the registry {image} is pulled from (eg. registry.example.com for
+// registry.example.com/shop/web:1.2 and docker.io for nginx:1.25)
+func RegistryOf(image string) string {
+	i := strings.Index(image, "/")
+	if i < 0 {
+		return DockerHub
+	}
+	host := image[:i]
+	if strings.ContainsAny(host, ".:") || host == "localhost" {
+		return host
+	}
+	return DockerHub
+}
+
+// Find returns the registry of {configs} {image} is pulled from or nil
+func Find(configs []*Config, image string) *Config {
+	registry := RegistryOf(image)
+	for _, c := range configs {
+		if c != nil && c.Registry == registry {
+			return c
+		}
+	}
+	return nil
+}
+
+// Packager uploads fresh bundles of the registries to the artifact store and adds them to the fetch URIs of
+// the applications pulling from the registries.  Each bundle is uploaded once per packager
+type Packager struct {
+	Registries []*Config
+	Secrets    *secrets.Resolver
+	// uploads the bundles to the artifact store
+	Client marathon.Marathon
+	// URL the agents fetch artifacts of the store beneath (eg. http://marathon:8080/v2/artifacts)
+	ArtifactURL string
+
+	mu   sync.Mutex
+	uris map[string]string
+}
+
+// Prepare adds the bundle of the registry {app} pulls its image from to the application's fetch URIs
+// refreshing the bundle when it's the first application pulling from the registry.  Applications pulling
+// from registries which aren't configured are left as is
+func (p *Packager) Prepare(app *marathon.Application) error {
+	if app.Container == nil || app.Container.Docker == nil || app.Container.Docker.Image == "" {
+		return nil
+	}
+	c := Find(p.Registries, app.Container.Docker.Image)
+	if c == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	uri, ok := p.uris[c.Registry]
+	if !ok {
+		var err error
+		if uri, err = p.refresh(c); err != nil {
+			return err
+		}
+		if p.uris == nil {
+			p.uris = map[string]string{}
+		}
+		p.uris[c.Registry] = uri
+	}
+	AddURI(app, uri)
+	return nil
+}
+
+// Refresh uploads a bundle of the current credentials of the registry {c} returning the URI it's fetched from
+func (p *Packager) Refresh(c *Config) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.refresh(c)
+}
+
+func (p *Packager) refresh(c *Config) (string, error) {
+	creds, err := c.Fetch(p.Secrets)
+	if err != nil {
+		return "", fmt.Errorf("Unable to fetch the credentials of registry '%s': %s", c.Registry, err.Error())
+	}
+	bundle, err := Bundle(c.Registry, creds)
+	if err != nil {
+		return "", err
+	}
+	log.Info("Refreshing the credentials of registry '%s' (%s)", c.Registry, c.ArtifactPath())
+	if err := p.Client.UploadArtifact(c.ArtifactPath(), bytes.NewReader(bundle)); err != nil {
+		return "", err
+	}
+	return p.uri(c), nil
+}
+
+func (p *Packager) uri(c *Config) string {
+	if c.URI != "" {
+		return c.URI
+	}
+	return strings.TrimRight(p.ArtifactURL, "/") + "/" + strings.TrimLeft(c.ArtifactPath(), "/")
+}
+
+// AddURI adds {uri} to the fetch URIs of {app} (extracted into the sandbox) unless it's already fetched.
+// Returns true if it was added
+func AddURI(app *marathon.Application, uri string) bool {
+	for _, f := range app.Fetch {
+		if f.URI == uri {
+			return false
+		}
+	}
+	app.FetchURI(uri)
+	return true
+}
@@ -0,0 +1,118 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathontest"
+	"github.com/ContainX/depcon/pkg/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryOf(t *testing.T) {
+	assert.Equal(t, DockerHub, RegistryOf("nginx:1.25"))
+	assert.Equal(t, DockerHub, RegistryOf("library/nginx"))
+	assert.Equal(t, "registry.example.com", RegistryOf("registry.example.com/shop/web:1.2"))
+	assert.Equal(t, "localhost:5000", RegistryOf("localhost:5000/web"))
+}
+
+func TestPackagerPrepare(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "registry")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "registry.json"), []byte(`{"username": "deploy", "password": "s3cret"}`), 0600)
+	resolver := secrets.NewResolver(map[string]*secrets.Config{"files": {Type: secrets.TypeFile, Dir: dir}})
+
+	fake := marathontest.New()
+	p := &Packager{
+		Registries:  []*Config{{Registry: "registry.example.com", Credentials: "secret://files/registry.json"}},
+		Secrets:     resolver,
+		Client:      fake,
+		ArtifactURL: "http://marathon:8080/v2/artifacts/",
+	}
+	web := &marathon.Application{ID: "/web", Container: &marathon.Container{Docker: &marathon.Docker{Image: "registry.example.com/web:1.2"}}}
+	api := &marathon.Application{ID: "/api", Container: &marathon.Container{Docker: &marathon.Docker{Image: "registry.example.com/api:3"}}}
+	hub := &marathon.Application{ID: "/proxy", Container: &marathon.Container{Docker: &marathon.Docker{Image: "nginx"}}}
+	for _, app := range []*marathon.Application{web, api, web, hub} {
+		assert.Nil(t, p.Prepare(app))
+	}
+
+	uri := "http://marathon:8080/v2/artifacts/credentials/registry.example.com/docker.tar.gz"
+	assert.Len(t, web.Fetch, 1)
+	assert.Equal(t, uri, web.Fetch[0].URI)
+	assert.True(t, web.Fetch[0].Extract)
+	assert.Equal(t, uri, api.Fetch[0].URI)
+	assert.Empty(t, hub.Fetch)
+	assert.Len(t, fake.Artifacts, 1)
+
+	config := readBundle(t, fake.Artifacts["/credentials/registry.example.com/docker.tar.gz"])
+	auth, _ := base64.StdEncoding.DecodeString(config["registry.example.com"])
+	assert.Equal(t, "deploy:s3cret", string(auth))
+}
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, (&Config{Registry: "r.example.com", Helper: "ecr-login"}).Validate())
+	assert.Equal(t, ErrorNoCredentials, (&Config{Registry: "r.example.com", Username: "u"}).Validate())
+	assert.NotNil(t, (&Config{Registry: "r.example.com", Credentials: "plain"}).Validate())
+	assert.NotNil(t, (&Config{Helper: "ecr-login"}).Validate())
+}
+
+// Returns the auths of the docker config within {bundle}
+func readBundle(t *testing.T, bundle []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	assert.Nil(t, err)
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err != nil {
+			t.Fatalf("%s not found within the bundle", dockerConfig)
+		}
+		if h.Name != dockerConfig {
+			continue
+		}
+		config := struct {
+			Auths map[string]map[string]string `json:"auths"`
+		}{}
+		assert.Nil(t, json.NewDecoder(tr).Decode(&config))
+		auths := map[string]string{}
+		for k, v := range config.Auths {
+			auths[k] = v["auth"]
+		}
+		return auths
+	}
+}
+
+func TestDockerConfigPortable(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "registry")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "config.json")
+	ioutil.WriteFile(filename, []byte(`{
+		"auths": {"registry.example.com": {"auth": "ZGVwbG95OnMzY3JldA=="}, "https://index.docker.io/v1/": {"auth": "aHViOnB3"}},
+		"credsStore": "desktop"
+	}`), 0600)
+
+	config, err := LoadDockerConfig(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://index.docker.io/v1/", "registry.example.com"}, config.Registries())
+
+	portable, err := config.Portable("registry.example.com")
+	assert.Nil(t, err)
+	assert.Empty(t, portable.CredsStore)
+	bundle, err := BundleConfig(portable)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"registry.example.com": "ZGVwbG95OnMzY3JldA=="}, readBundle(t, bundle))
+
+	portable, err = config.Portable(DockerHub)
+	assert.Nil(t, err)
+	assert.Contains(t, portable.Auths, "https://index.docker.io/v1/")
+
+	_, err = (&DockerConfig{Auths: map[string]*DockerAuth{}}).Portable("other.example.com")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,220 @@
+// Runs releases: ordered steps deploying Marathon apps and groups and running Metronome jobs, where steps
+// may run in parallel and a failed release resumes after the steps which completed
+package release
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/reconcile"
+)
+
+const (
+	KindApp      = "app"
+	KindJob      = "job"
+	KindParallel = "parallel"
+)
+
+var log = logger.GetLogger("depcon.release")
+
+var (
+	ErrorName    = errors.New("The release requires a name")
+	ErrorNoSteps = errors.New("The release declares no steps")
+)
+
+// Release is the ordered steps of a deployment spanning applications and jobs
+type Release struct {
+	Name string `json:"name"`
+	// Template context the descriptors are rendered with relative to the release
+	TemplateContext string `json:"tempctx,omitempty"`
+	// Values of the ${PARAMS} within the descriptors
+	Params map[string]string `json:"params,omitempty"`
+	// Run in order.  The release stops at the first step which fails
+	Steps    []*Step `json:"steps"`
+	dir      string
+	checksum string
+}
+
+// Step deploys an app or group descriptor, runs a job or runs its parallel steps concurrently
+type Step struct {
+	// Identifies the step within the release (and the state of a failed release)
+	Name string `json:"name"`
+	// Marathon app or group descriptor relative to the release
+	App string `json:"app,omitempty"`
+	// Metronome job descriptor relative to the release which is created or updated and then run
+	Job string `json:"job,omitempty"`
+	// Steps run concurrently.  The step completes once all of them have
+	Parallel []*Step `json:"parallel,omitempty"`
+	// Wait for the apps to be healthy or the job run to succeed before the next step.  Default: true
+	Wait *bool `json:"wait,omitempty"`
+	// Max duration of the wait (eg. 90s, 5m)
+	Timeout string `json:"timeout,omitempty"`
+	// Params of the step's descriptor overriding those of the release
+	Params  map[string]string `json:"params,omitempty"`
+	timeout time.Duration
+}
+
+// Kind returns whether the step deploys an app, runs a job or runs parallel steps
+func (s *Step) Kind() string {
+	switch {
+	case s.App != "":
+		return KindApp
+	case s.Job != "":
+		return KindJob
+	}
+	return KindParallel
+}
+
+// Waits returns true unless the step disables waiting
+func (s *Step) Waits() bool {
+	return s.Wait == nil || *s.Wait
+}
+
+// Load parses and validates the release {filename} (.yaml, .yml or .json)
+func Load(filename string) (*Release, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	r := &Release{dir: filepath.Dir(filename), checksum: fmt.Sprintf("%x", sha256.Sum256(b))}
+	if err := enc.UnMarshalStr(string(b), r); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	if err := r.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return r, nil
+}
+
+func (r *Release) validate() error {
+	if r.Name == "" {
+		return ErrorName
+	}
+	if len(r.Steps) == 0 {
+		return ErrorNoSteps
+	}
+	names := map[string]bool{}
+	var validate func(steps []*Step, parallel bool) error
+	validate = func(steps []*Step, parallel bool) error {
+		for _, s := range steps {
+			if s.Name == "" {
+				return errors.New("every step requires a name")
+			}
+			if names[s.Name] {
+				return fmt.Errorf("step '%s' is declared more than once", s.Name)
+			}
+			names[s.Name] = true
+
+			declared := 0
+			for _, set := range []bool{s.App != "", s.Job != "", len(s.Parallel) > 0} {
+				if set {
+					declared++
+				}
+			}
+			if declared != 1 {
+				return fmt.Errorf("step '%s' requires exactly one of app, job or parallel", s.Name)
+			}
+			if parallel && len(s.Parallel) > 0 {
+				return fmt.Errorf("step '%s': parallel steps can't be nested", s.Name)
+			}
+			if s.Timeout != "" {
+				d, err := time.ParseDuration(s.Timeout)
+				if err != nil {
+					return fmt.Errorf("step '%s': invalid timeout '%s'", s.Name, s.Timeout)
+				}
+				s.timeout = d
+			}
+			if err := validate(s.Parallel, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return validate(r.Steps, false)
+}
+
+// Path returns {name} relative to the release unless it is absolute
+func (r *Release) Path(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(r.dir, name)
+}
+
+// HasJobs returns true if a step of the release runs a job
+func (r *Release) HasJobs() bool {
+	for _, s := range r.Steps {
+		for _, p := range append([]*Step{s}, s.Parallel...) {
+			if p.Job != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Checksum identifies the contents of the release file
+func (r *Release) Checksum() string {
+	return r.checksum
+}
+
+// Returns the options loading the descriptor of step {s} whose params override those of the release but
+// not those of {opts}
+func (r *Release) loadOptions(s *Step, opts *reconcile.LoadOptions) *reconcile.LoadOptions {
+	o := reconcile.LoadOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	params := map[string]string{}
+	for _, m := range []map[string]string{r.Params, s.Params, o.Params} {
+		for k, v := range m {
+			params[k] = v
+		}
+	}
+	o.Params = params
+	return &o
+}
+
+// Renders and parses the Metronome job descriptor {filename}
+func loadJob(filename string, opts *reconcile.LoadOptions) (map[string]interface{}, error) {
+	var content string
+	if opts.Render != nil {
+		rendered, err := opts.Render(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err.Error())
+		}
+		content = rendered
+	} else {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		content = string(b)
+	}
+
+	parsed, missing := envsubst.SubstTokens(strings.NewReader(content), opts.Params)
+	if len(missing) > 0 && !opts.IgnoreMissing {
+		return nil, &envsubst.MissingParamsError{Filename: filename, Params: missing}
+	}
+	enc, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	if err := enc.UnMarshalStr(parsed, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	return doc, nil
+}
@@ -0,0 +1,171 @@
+package release
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/metronome"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeMarathon records the apps deployed failing those listed in fail.  Calls it doesn't implement panic
+type fakeMarathon struct {
+	marathon.Marathon
+	mu       sync.Mutex
+	fail     map[string]bool
+	deployed []string
+}
+
+func (f *fakeMarathon) ListApplications() (*marathon.Applications, error) {
+	return &marathon.Applications{}, nil
+}
+
+func (f *fakeMarathon) CreateApplication(app *marathon.Application, wait, force bool) (*marathon.Application, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deployed = append(f.deployed, app.ID)
+	return app, nil
+}
+
+func (f *fakeMarathon) WaitForApplication(id string, timeout time.Duration) error {
+	if f.fail[id] {
+		return marathon.ErrorTimeout
+	}
+	return nil
+}
+
+// fakeMetronome records the jobs run
+type fakeMetronome struct {
+	metronome.Metronome
+	runs []string
+}
+
+func (f *fakeMetronome) ApplyJob(doc map[string]interface{}, force bool) (*metronome.ApplyResult, error) {
+	return &metronome.ApplyResult{JobID: doc["id"].(string)}, nil
+}
+
+func (f *fakeMetronome) StartRun(id string) (*metronome.Run, error) {
+	f.runs = append(f.runs, id)
+	return &metronome.Run{ID: "1", JobID: id}, nil
+}
+
+func (f *fakeMetronome) WaitForRun(id, runID string, timeout time.Duration) error {
+	return nil
+}
+
+const testRelease = `
+name: shop
+params:
+  TAG: "1.0"
+steps:
+  - name: migrate
+    job: migrate.json
+    timeout: 5m
+  - name: api
+    app: api.json
+  - name: workers
+    parallel:
+      - name: worker
+        app: worker.json
+      - name: scheduler
+        app: scheduler.json
+        wait: false
+`
+
+func writeRelease(t *testing.T, dir string) string {
+	files := map[string]string{
+		"release.yaml":   testRelease,
+		"migrate.json":   `{"id": "migrate", "run": {"cmd": "migrate ${TAG}"}}`,
+		"api.json":       `{"id": "/api", "container": {"docker": {"image": "api:${TAG}"}}}`,
+		"worker.json":    `{"id": "/worker"}`,
+		"scheduler.json": `{"id": "/scheduler"}`,
+	}
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return filepath.Join(dir, "release.yaml")
+}
+
+func TestLoad(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "release")
+	defer os.RemoveAll(dir)
+
+	rel, err := Load(writeRelease(t, dir))
+	assert.Nil(t, err)
+	assert.Equal(t, "shop", rel.Name)
+	assert.Len(t, rel.Steps, 3)
+	assert.Equal(t, KindJob, rel.Steps[0].Kind())
+	assert.Equal(t, 5*time.Minute, rel.Steps[0].timeout)
+	assert.Equal(t, KindParallel, rel.Steps[2].Kind())
+	assert.True(t, rel.Steps[2].Parallel[0].Waits())
+	assert.False(t, rel.Steps[2].Parallel[1].Waits())
+
+	invalid := map[string]string{
+		"noname.yaml":    "steps:\n  - name: a\n    app: a.json\n",
+		"duplicate.yaml": "name: r\nsteps:\n  - name: a\n    app: a.json\n  - name: a\n    job: a.json\n",
+		"both.yaml":      "name: r\nsteps:\n  - name: a\n    app: a.json\n    job: a.json\n",
+		"nested.yaml":    "name: r\nsteps:\n  - name: a\n    parallel:\n      - name: b\n        parallel:\n          - name: c\n            app: c.json\n",
+		"timeout.yaml":   "name: r\nsteps:\n  - name: a\n    app: a.json\n    timeout: soon\n",
+	}
+	for name, content := range invalid {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		_, err := Load(filepath.Join(dir, name))
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestRunResumes(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "release")
+	defer os.RemoveAll(dir)
+	rel, err := Load(writeRelease(t, dir))
+	assert.Nil(t, err)
+
+	client := &fakeMarathon{fail: map[string]bool{"/worker": true}}
+	jobs := &fakeMetronome{}
+	runner := &Runner{Environment: "prod", Marathon: client, Metronome: jobs, StateFile: filepath.Join(dir, "state.json")}
+
+	results, err := runner.Run(rel, false)
+	assert.NotNil(t, err)
+	assert.Len(t, results, 4)
+	assert.Equal(t, ResultSucceeded, results[0].Result)
+	assert.Equal(t, "migrate", results[0].Target)
+	assert.Equal(t, "/api", results[1].Target)
+	assert.Equal(t, "create", results[1].Action)
+	assert.Equal(t, ResultFailed, results[2].Result)
+	assert.Equal(t, ResultSucceeded, results[3].Result)
+
+	state, err := LoadState(runner.StateFile)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"migrate", "api", "scheduler"}, state.Completed)
+
+	client.fail = nil
+	client.deployed = nil
+	results, err = runner.Run(rel, false)
+	assert.Nil(t, err)
+	assert.Equal(t, ResultSkipped, results[0].Result)
+	assert.Equal(t, ResultSkipped, results[1].Result)
+	assert.Equal(t, ResultSucceeded, results[2].Result)
+	assert.Equal(t, ResultSkipped, results[3].Result)
+	assert.Equal(t, []string{"/worker"}, client.deployed)
+	assert.Equal(t, []string{"migrate"}, jobs.runs)
+
+	_, err = os.Stat(runner.StateFile)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRunWithoutMetronome(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "release")
+	defer os.RemoveAll(dir)
+	rel, _ := Load(writeRelease(t, dir))
+
+	runner := &Runner{Marathon: &fakeMarathon{}}
+	results, err := runner.Run(rel, false)
+	assert.NotNil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, ErrorNoMetronome.Error(), results[0].Error)
+}
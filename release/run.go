@@ -0,0 +1,268 @@
+package release
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/metronome"
+	"github.com/ContainX/depcon/reconcile"
+)
+
+const (
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+	// the step completed during a previous run of the release
+	ResultSkipped = "skipped"
+)
+
+var ErrorNoMetronome = errors.New("The release runs jobs but no Metronome client is configured")
+
+// StepResult is the outcome of a step deploying an app or running a job
+type StepResult struct {
+	Step string `json:"step"`
+	Kind string `json:"kind"`
+	// The app, group or job ids of the step's descriptor
+	Target string `json:"target,omitempty"`
+	// The changes made (eg. create, update, none) or the job run
+	Action   string        `json:"action,omitempty"`
+	Result   string        `json:"result"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// State records the steps of a release which completed so a failed release resumes after them
+type State struct {
+	Release     string    `json:"release"`
+	Environment string    `json:"environment"`
+	Checksum    string    `json:"checksum"`
+	Completed   []string  `json:"completed"`
+	Updated     time.Time `json:"updated"`
+}
+
+// Runner runs releases against an environment
+type Runner struct {
+	Environment string
+	Marathon    marathon.Marathon
+	// Runs the job steps.  Releases without job steps don't require one
+	Metronome metronome.Metronome
+	// Renders the descriptors and supplies the params overriding those of the release
+	Load *reconcile.LoadOptions
+	// File the state of a failed release is kept in.  The release isn't resumable when empty
+	StateFile string
+	// Receives the result of each step as it completes.  Optional
+	Report func(*StepResult)
+
+	mu    sync.Mutex
+	state *State
+}
+
+// Run runs the steps of {rel} in order stopping at the first which fails.  Steps which completed during a
+// failed run of the same release are skipped unless {restart} is true.  The state is removed once every
+// step has completed
+func (r *Runner) Run(rel *Release, restart bool) ([]*StepResult, error) {
+	r.state = r.loadState(rel, restart)
+	completed := map[string]bool{}
+	for _, name := range r.state.Completed {
+		completed[name] = true
+	}
+
+	results := []*StepResult{}
+	for _, step := range rel.Steps {
+		steps := []*Step{step}
+		if step.Kind() == KindParallel {
+			steps = step.Parallel
+		}
+		stepResults := make([]*StepResult, len(steps))
+		var wg sync.WaitGroup
+		for i, s := range steps {
+			if completed[s.Name] {
+				stepResults[i] = &StepResult{Step: s.Name, Kind: s.Kind(), Result: ResultSkipped}
+				r.report(stepResults[i])
+				continue
+			}
+			wg.Add(1)
+			go func(i int, s *Step) {
+				defer wg.Done()
+				stepResults[i] = r.runStep(rel, s)
+				r.report(stepResults[i])
+			}(i, s)
+		}
+		wg.Wait()
+
+		results = append(results, stepResults...)
+		failed := []string{}
+		for _, sr := range stepResults {
+			if sr.Result == ResultFailed {
+				failed = append(failed, sr.Step)
+			}
+		}
+		if len(failed) > 0 {
+			return results, fmt.Errorf("Release '%s' failed at step(s) %s.  Run it again to resume from them", rel.Name, strings.Join(failed, ", "))
+		}
+	}
+
+	if r.StateFile != "" {
+		os.Remove(r.StateFile)
+	}
+	return results, nil
+}
+
+func (r *Runner) runStep(rel *Release, s *Step) *StepResult {
+	log.Info("Running step '%s' of release '%s'", s.Name, rel.Name)
+	started := time.Now()
+	result := &StepResult{Step: s.Name, Kind: s.Kind()}
+	var err error
+	if s.Kind() == KindApp {
+		err = r.deploy(rel, s, result)
+	} else {
+		err = r.runJob(rel, s, result)
+	}
+	result.Duration = time.Since(started).Round(time.Millisecond)
+	if err != nil {
+		result.Result, result.Error = ResultFailed, err.Error()
+		return result
+	}
+	result.Result = ResultSucceeded
+	r.complete(s.Name)
+	return result
+}
+
+// Creates or updates the apps of the step's descriptor when they differ from those running
+func (r *Runner) deploy(rel *Release, s *Step, result *StepResult) error {
+	descs, err := reconcile.LoadFile(rel.Path(s.App), rel.loadOptions(s, r.Load))
+	if err != nil {
+		return err
+	}
+	apps, err := r.Marathon.ListApplications()
+	if err != nil {
+		return err
+	}
+
+	changes := reconcile.Plan(descs, apps.Apps)
+	ids, actions := []string{}, []string{}
+	for _, c := range changes {
+		ids = append(ids, c.ID)
+		actions = append(actions, c.Action)
+	}
+	result.Target, result.Action = strings.Join(ids, ","), strings.Join(actions, ",")
+
+	timeout := s.timeout
+	if timeout == 0 {
+		timeout = marathon.DefaultTimeout
+	}
+	if reconcile.Apply(r.Marathon, changes, s.Waits(), timeout) > 0 {
+		for _, c := range changes {
+			if c.Result == reconcile.ResultFailed {
+				return fmt.Errorf("%s: %s", c.ID, c.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// Creates or updates the step's job and runs it
+func (r *Runner) runJob(rel *Release, s *Step, result *StepResult) error {
+	if r.Metronome == nil {
+		return ErrorNoMetronome
+	}
+	doc, err := loadJob(rel.Path(s.Job), rel.loadOptions(s, r.Load))
+	if err != nil {
+		return err
+	}
+	applied, err := r.Metronome.ApplyJob(doc, true)
+	if err != nil {
+		return err
+	}
+	result.Target = applied.JobID
+	run, err := r.Metronome.StartRun(applied.JobID)
+	if err != nil {
+		return err
+	}
+	result.Action = "run " + run.ID
+	if !s.Waits() {
+		return nil
+	}
+
+	timeout := s.timeout
+	if timeout == 0 {
+		timeout = metronome.DefaultTimeout
+	}
+	return r.Metronome.WaitForRun(applied.JobID, run.ID, timeout)
+}
+
+func (r *Runner) report(result *StepResult) {
+	if r.Report != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.Report(result)
+	}
+}
+
+// Records step {name} as completed within the state file
+func (r *Runner) complete(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.Completed = append(r.state.Completed, name)
+	r.state.Updated = time.Now().UTC()
+	if r.StateFile == "" {
+		return
+	}
+	if err := SaveState(r.StateFile, r.state); err != nil {
+		log.Error("Unable to save the state of release '%s' to %s: %s", r.state.Release, r.StateFile, err.Error())
+	}
+}
+
+// Returns the state of the failed run of {rel} or a new state when there is none, {restart} is true or the
+// release has changed since
+func (r *Runner) loadState(rel *Release, restart bool) *State {
+	fresh := &State{Release: rel.Name, Environment: r.Environment, Checksum: rel.Checksum(), Completed: []string{}}
+	if r.StateFile == "" || restart {
+		return fresh
+	}
+	state, err := LoadState(r.StateFile)
+	switch {
+	case err != nil:
+		if !os.IsNotExist(err) {
+			log.Warning("Ignoring the unreadable state %s: %s", r.StateFile, err.Error())
+		}
+		return fresh
+	case state.Checksum != rel.Checksum():
+		log.Warning("Release '%s' has changed since it failed - running every step", rel.Name)
+		return fresh
+	}
+	log.Info("Resuming release '%s' after %d completed step(s)", rel.Name, len(state.Completed))
+	return state
+}
+
+// LoadState reads the state saved to {filename}
+func LoadState(filename string) (*State, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// SaveState writes {state} to {filename} creating its directory when needed
+func SaveState(filename string, state *State) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0600)
+}
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/envsubst"
+	"github.com/ContainX/depcon/pkg/schema"
+	"github.com/ContainX/depcon/pkg/signature"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	StatusValid      = "valid"
+	StatusDeployed   = "deployed"
+	StatusFailed     = "failed"
+	StatusRolledBack = "rolled back"
+	StatusScheduled  = "scheduled"
+	StatusCancelled  = "cancelled"
+
+	// audit actions of scheduled deployments
+	ActionSchedule = "schedule"
+	ActionDeploy   = "deploy"
+)
+
+var (
+	ErrorNoDescriptor = errors.New("A descriptor is required")
+	ErrorNoAppID      = errors.New("The application id is required")
+	ErrorInvalid      = errors.New("The descriptor is invalid")
+	ErrorNoVersion    = errors.New("The application has no previous version to roll back to")
+	ErrorNoSchedule   = errors.New("The scheduled deployment does not exist")
+)
+
+// DeployRequest deploys an application descriptor to an environment
+type DeployRequest struct {
+	// Application descriptor which may contain ${PARAMS}
+	Descriptor string `json:"descriptor"`
+	// Format of the descriptor: json (default) or yaml
+	Format string `json:"format,omitempty"`
+	// Values of the ${PARAMS} within the descriptor
+	Params map[string]string `json:"params,omitempty"`
+	// Leave ${PARAMS} without a value unresolved rather than failing
+	IgnoreMissing bool `json:"ignoreMissing,omitempty"`
+	// Update the application when it exists
+	Force bool `json:"force,omitempty"`
+	// Wait for the application to be running and healthy
+	Wait bool `json:"wait,omitempty"`
+	// Max duration to wait (eg. 90s, 5m).  Default: marathon.DefaultTimeout
+	Timeout string `json:"timeout,omitempty"`
+	// Restore the previous version (or remove a new application) when it doesn't become healthy.  Implies wait
+	Rollback bool `json:"rollback,omitempty"`
+	// Render and validate the descriptor without deploying it
+	DryRun bool `json:"dryRun,omitempty"`
+	// Deploy at this time (eg. 2024-06-01T02:00Z) rather than immediately.  The descriptor is rendered and
+	// validated when requested
+	At string `json:"at,omitempty"`
+	// Deploy after this delay (eg. 4h) rather than immediately
+	In string `json:"in,omitempty"`
+	// Detached signature of the descriptor (see depcon sign) required by environments trusting keys
+	Signature *signature.Signature `json:"signature,omitempty"`
+}
+
+// RollbackRequest restores a previous version of an application
+type RollbackRequest struct {
+	AppID string `json:"appId"`
+	// Version to restore.  Default: the version prior to the current one
+	Version string `json:"version,omitempty"`
+	Wait    bool   `json:"wait,omitempty"`
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Deployment is the outcome of a deploy or rollback request
+type Deployment struct {
+	// Id of a scheduled deployment
+	ID              string `json:"id,omitempty"`
+	Environment     string `json:"environment"`
+	AppID           string `json:"appId"`
+	Status          string `json:"status"`
+	Version         string `json:"version,omitempty"`
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	// Descriptor with ${PARAMS} resolved (dry runs)
+	Rendered string `json:"rendered,omitempty"`
+	// Time a scheduled deployment is planned for (RFC3339)
+	ScheduledAt string `json:"scheduledAt,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Renders, validates and deploys the descriptor of {req} to environment {env}.  Deployments planned for later
+// (at or in) are scheduled on behalf of {user}
+func (s *Server) deploy(env, user string, req *DeployRequest) (*Deployment, error) {
+	timeout, err := parseTimeout(req.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	at, err := utils.ScheduledTime(req.At, req.In, time.Now())
+	if err != nil {
+		return nil, &apiError{status: http.StatusBadRequest, err: err}
+	}
+	if err := s.verify(env, req); err != nil {
+		return nil, err
+	}
+	rendered, app, err := Render(req)
+	if err != nil {
+		return nil, err
+	}
+	result := &Deployment{Environment: env, AppID: app.ID}
+	if !at.IsZero() {
+		result.ScheduledAt = at.UTC().Format(time.RFC3339)
+	}
+	if req.DryRun {
+		result.Status, result.Rendered = StatusValid, rendered
+		return result, nil
+	}
+	if !at.IsZero() {
+		return s.schedule(user, req, app, timeout, at, result), nil
+	}
+	return s.deployApp(req, app, timeout, result)
+}
+
+// Checks the descriptor of {req} is signed by one of the keys environment {env} trusts (see Config.TrustedKeys)
+func (s *Server) verify(env string, req *DeployRequest) error {
+	if s.config.TrustedKeys == nil {
+		return nil
+	}
+	trusted, err := s.config.TrustedKeys(env)
+	if err != nil || len(trusted) == 0 {
+		return err
+	}
+	if req.Signature == nil {
+		return &apiError{status: http.StatusForbidden, err: signature.ErrorNotSigned}
+	}
+	if err := signature.Verify([]byte(req.Descriptor), req.Signature, trusted); err != nil {
+		return &apiError{status: http.StatusForbidden, err: err}
+	}
+	return nil
+}
+
+// Deploys {app} as requested by {req} filling in {result}
+func (s *Server) deployApp(req *DeployRequest, app *marathon.Application, timeout time.Duration, result *Deployment) (*Deployment, error) {
+	env := result.Environment
+	if !s.acquire(env, app.ID) {
+		return nil, &apiError{status: http.StatusConflict, err: ErrorInProgress}
+	}
+	defer s.release(env, app.ID)
+
+	client, err := s.config.Client(env)
+	if err != nil {
+		return nil, err
+	}
+	if current, err := client.GetApplication(app.ID); err == nil {
+		result.PreviousVersion = current.Version
+	}
+
+	log.Info("Deploying '%s' to '%s' (requested force: %v, wait: %v, rollback: %v)", app.ID, env, req.Force, req.Wait, req.Rollback)
+	// the client clears the id of an updated application
+	id := app.ID
+	if _, err := client.CreateApplication(app, false, req.Force); err != nil {
+		return nil, err
+	}
+
+	if req.Wait || req.Rollback {
+		if err := client.WaitForApplication(id, timeout); err != nil {
+			result.Status, result.Error = StatusFailed, err.Error()
+			if req.Rollback {
+				restoreVersion(client, result, timeout)
+			}
+			return result, err
+		}
+	}
+	result.Status = StatusDeployed
+	if deployed, err := client.GetApplication(id); err == nil {
+		result.Version = deployed.Version
+	}
+	return result, nil
+}
+
+// Restores the previous version of a failed deployment or removes an application which didn't exist
+func restoreVersion(client marathon.Marathon, result *Deployment, timeout time.Duration) {
+	var err error
+	if result.PreviousVersion == "" {
+		log.Warning("Deployment of '%s' failed, removing the application", result.AppID)
+		_, err = client.DestroyApplication(result.AppID)
+	} else {
+		log.Warning("Deployment of '%s' failed, rolling back to %s", result.AppID, result.PreviousVersion)
+		update := marathon.NewApplication(result.AppID).RollbackVersion(result.PreviousVersion)
+		if _, err = client.UpdateApplication(update, false); err == nil {
+			err = client.WaitForApplication(result.AppID, timeout)
+		}
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("%s (rollback failed: %s)", result.Error, err.Error())
+		return
+	}
+	result.Status, result.Version = StatusRolledBack, result.PreviousVersion
+}
+
+// Restores the version of {req} (the prior version when unspecified) of an application in environment {env}
+func (s *Server) rollback(env string, req *RollbackRequest) (*Deployment, error) {
+	if req.AppID == "" {
+		return nil, &apiError{status: http.StatusBadRequest, err: ErrorNoAppID}
+	}
+	timeout, err := parseTimeout(req.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if !s.acquire(env, req.AppID) {
+		return nil, &apiError{status: http.StatusConflict, err: ErrorInProgress}
+	}
+	defer s.release(env, req.AppID)
+
+	client, err := s.config.Client(env)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := client.ListVersions(req.AppID)
+	if err != nil {
+		return nil, err
+	}
+	result := &Deployment{Environment: env, AppID: req.AppID, Version: req.Version}
+	if len(versions.Versions) > 0 {
+		result.PreviousVersion = versions.Versions[0]
+	}
+	if result.Version == "" {
+		if len(versions.Versions) < 2 {
+			return nil, &apiError{status: http.StatusConflict, err: ErrorNoVersion}
+		}
+		result.Version = versions.Versions[1]
+	}
+
+	log.Info("Rolling back '%s' in '%s' to %s", req.AppID, env, result.Version)
+	update := marathon.NewApplication(req.AppID).RollbackVersion(result.Version)
+	if _, err := client.UpdateApplication(update, false); err != nil {
+		return nil, err
+	}
+	if req.Wait {
+		if err := client.WaitForApplication(req.AppID, timeout); err != nil {
+			result.Status, result.Error = StatusFailed, err.Error()
+			return result, err
+		}
+	}
+	result.Status = StatusDeployed
+	return result, nil
+}
+
+// Render resolves the ${PARAMS} of the descriptor within {req} and validates the result against the
+// application schema returning the rendered descriptor and the application it defines
+func Render(req *DeployRequest) (string, *marathon.Application, error) {
+	if strings.TrimSpace(req.Descriptor) == "" {
+		return "", nil, &apiError{status: http.StatusBadRequest, err: ErrorNoDescriptor}
+	}
+	format := req.Format
+	if format == "" {
+		format = "json"
+	}
+	et, err := encoding.EncoderTypeFromExt("descriptor." + format)
+	if err != nil {
+		return "", nil, &apiError{status: http.StatusBadRequest, err: fmt.Errorf("Unsupported format '%s' (json or yaml)", format)}
+	}
+	enc, _ := encoding.NewEncoder(et)
+
+	rendered, missing := envsubst.SubstTokens(strings.NewReader(req.Descriptor), req.Params)
+	if len(missing) > 0 && !req.IgnoreMissing {
+		return "", nil, &apiError{status: http.StatusUnprocessableEntity, err: marathon.ErrorAppParamsMissing, errors: toStrings(missing)}
+	}
+
+	var data interface{}
+	if err := enc.UnMarshalStr(rendered, &data); err != nil {
+		return "", nil, &apiError{status: http.StatusUnprocessableEntity, err: ErrorInvalid, errors: []string{err.Error()}}
+	}
+	// unknown properties are refused too since the application deployed only has the fields depcon knows
+	if verrs := schema.Generate(&marathon.Application{}).Validate(data); len(verrs) > 0 {
+		return "", nil, &apiError{status: http.StatusUnprocessableEntity, err: ErrorInvalid, errors: toStrings(verrs)}
+	}
+
+	app := new(marathon.Application)
+	if err := enc.UnMarshalStr(rendered, app); err != nil {
+		return "", nil, &apiError{status: http.StatusUnprocessableEntity, err: ErrorInvalid, errors: []string{err.Error()}}
+	}
+	if app.ID == "" {
+		return "", nil, &apiError{status: http.StatusUnprocessableEntity, err: ErrorNoAppID}
+	}
+	return rendered, app, nil
+}
+
+func parseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return marathon.DefaultTimeout, nil
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d <= 0 {
+		return 0, &apiError{status: http.StatusBadRequest, err: fmt.Errorf("Invalid timeout '%s' (eg. 90s, 5m)", timeout)}
+	}
+	return d, nil
+}
+
+func toStrings(values interface{}) []string {
+	s := []string{}
+	switch v := values.(type) {
+	case []envsubst.MissingParam:
+		for _, p := range v {
+			s = append(s, p.String())
+		}
+	case []schema.ValidationError:
+		for _, e := range v {
+			s = append(s, e.Error())
+		}
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/audit"
+)
+
+// deployment deferred to a planned time
+type scheduledDeployment struct {
+	deployment *Deployment
+	user       string
+	timer      *time.Timer
+}
+
+// Defers the deployment of {app} requested by {user} until {at} returning the scheduled deployment {result}.
+// Scheduled deployments are held in memory so they are lost when the server stops
+func (s *Server) schedule(user string, req *DeployRequest, app *marathon.Application, timeout time.Duration, at time.Time, result *Deployment) *Deployment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastID++
+	result.ID, result.Status = strconv.Itoa(s.lastID), StatusScheduled
+
+	sd := &scheduledDeployment{deployment: result, user: user}
+	sd.timer = time.AfterFunc(time.Until(at), func() {
+		if !s.unschedule(result.Environment, result.ID) {
+			return
+		}
+		deployment := *result
+		deployment.Status = ""
+		_, err := s.deployApp(req, app, timeout, &deployment)
+		if err != nil && deployment.Status == "" {
+			deployment.Status, deployment.Error = StatusFailed, err.Error()
+		}
+		log.Info("Scheduled deployment %s of '%s' to '%s': %s", deployment.ID, deployment.AppID, deployment.Environment, deployment.Status)
+		s.record(ActionDeploy, user, &deployment, err)
+	})
+	s.scheduled[result.ID] = sd
+
+	log.Info("Deployment %s of '%s' to '%s' scheduled at %s", result.ID, result.AppID, result.Environment, result.ScheduledAt)
+	s.record(ActionSchedule, user, result, nil)
+	scheduled := *result
+	return &scheduled
+}
+
+// Returns the deployments scheduled in environment {env} in the order they're planned
+func (s *Server) scheduledDeployments(env string) []*Deployment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deployments := []*Deployment{}
+	for _, sd := range s.scheduled {
+		if sd.deployment.Environment == env {
+			d := *sd.deployment
+			deployments = append(deployments, &d)
+		}
+	}
+	sort.Slice(deployments, func(i, j int) bool {
+		if deployments[i].ScheduledAt == deployments[j].ScheduledAt {
+			return deployments[i].ID < deployments[j].ID
+		}
+		return deployments[i].ScheduledAt < deployments[j].ScheduledAt
+	})
+	return deployments
+}
+
+// Cancels the deployment {id} scheduled in environment {env} on behalf of {user}
+func (s *Server) cancelScheduled(env, id, user string) (*Deployment, error) {
+	s.mu.Lock()
+	sd, ok := s.scheduled[id]
+	if ok && sd.deployment.Environment == env {
+		sd.timer.Stop()
+		delete(s.scheduled, id)
+	}
+	s.mu.Unlock()
+	if !ok || sd.deployment.Environment != env {
+		return nil, &apiError{status: http.StatusNotFound, err: ErrorNoSchedule}
+	}
+
+	cancelled := *sd.deployment
+	cancelled.Status = StatusCancelled
+	log.Info("Scheduled deployment %s of '%s' to '%s' cancelled", id, cancelled.AppID, env)
+	s.record(ActionSchedule, user, &cancelled, nil)
+	return &cancelled, nil
+}
+
+// Removes the scheduled deployment {id} returning false when it was cancelled
+func (s *Server) unschedule(env, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sd, ok := s.scheduled[id]; !ok || sd.deployment.Environment != env {
+		return false
+	}
+	delete(s.scheduled, id)
+	return true
+}
+
+// Records {action} of deployment {d} made on behalf of {user} in the audit log of the config
+func (s *Server) record(action, user string, d *Deployment, err error) {
+	if s.config.Audit == nil {
+		return
+	}
+	e := &audit.Entry{User: user, Environment: d.Environment, Action: action, Target: d.AppID, Result: audit.ResultSuccess,
+		Details: map[string]string{"id": d.ID, "scheduledAt": d.ScheduledAt}}
+	switch {
+	case err != nil:
+		e.Result, e.Message = audit.ResultFailed, err.Error()
+	case d.Status == StatusScheduled:
+		e.Result = audit.ResultScheduled
+	case d.Status == StatusCancelled:
+		e.Result = audit.ResultCancelled
+	}
+	if d.Version != "" {
+		e.Details["version"] = d.Version
+	}
+	if err := s.config.Audit.Record(e); err != nil {
+		log.Error("Unable to write the audit log %s: %s", s.config.Audit.Filename(), err.Error())
+	}
+}
@@ -0,0 +1,341 @@
+// REST API exposing the deployment pipeline of depcon (render, validate, deploy, wait and rollback) against
+// the configured environments so CI systems and bots can deploy without holding cluster credentials
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/access"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	DefaultAddress = ":8080"
+	// Prefix of the versioned API paths
+	PathPrefix       = "/v1"
+	PathHealth       = PathPrefix + "/health"
+	PathEnvironments = PathPrefix + "/environments"
+	PathDeployments  = "deployments"
+	PathRollbacks    = "rollbacks"
+
+	// commands the access policy is checked against for deployments and rollbacks
+	CommandDeploy   = "deploy"
+	CommandRollback = "rollback"
+)
+
+var (
+	ErrorNoTokens           = errors.New("At least one API token is required")
+	ErrorNoEnvironments     = errors.New("At least one environment must be served")
+	ErrorUnauthorized       = errors.New("A valid bearer token is required")
+	ErrorUnknownEnvironment = errors.New("The environment is not served")
+	ErrorNotFound           = errors.New("The resource does not exist")
+	ErrorMethodNotAllowed   = errors.New("The method is not allowed")
+	ErrorInProgress         = errors.New("A deployment of the application is already in progress")
+)
+
+var log = logger.GetLogger("depcon.server")
+
+// ClientFactory returns the Marathon client of environment {env}
+type ClientFactory func(env string) (marathon.Marathon, error)
+
+type Config struct {
+	// Address to listen on (eg. :8080)
+	Address string
+	// Bearer tokens which are accepted
+	Tokens []string
+	// Optional user of each token checked against Access.  Tokens without a user are checked as the user ""
+	Users map[string]string
+	// Optional roles restricting which users may deploy to and roll back which environments
+	Access *access.Policy
+	// Environments deployments may target
+	Environments []string
+	// TLS certificate and key.  Plain HTTP is served when unspecified
+	CertFile string
+	KeyFile  string
+	// Returns the client of an environment
+	Client ClientFactory
+	// Optional log the scheduled deployments and their outcome are recorded in
+	Audit *audit.Log
+	// Optional keys one of which must have signed the descriptors deployed to an environment.  Descriptors
+	// aren't checked for environments without keys
+	TrustedKeys func(env string) ([]ed25519.PublicKey, error)
+}
+
+type Server struct {
+	config       *Config
+	environments map[string]bool
+	mu           sync.Mutex
+	// apps with a deployment in progress keyed by environment and app id
+	inProgress map[string]bool
+	// deployments planned for later keyed by id
+	scheduled map[string]*scheduledDeployment
+	lastID    int
+}
+
+// Error response of the API
+type Message struct {
+	Message string   `json:"message"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// apiError is an error with the HTTP status it is responded with
+type apiError struct {
+	status int
+	err    error
+	errors []string
+}
+
+func (e *apiError) Error() string {
+	return e.err.Error()
+}
+
+// key of the user of the request's token within its context
+type userKey struct{}
+
+func New(config *Config) (*Server, error) {
+	if len(config.Tokens) == 0 {
+		return nil, ErrorNoTokens
+	}
+	if len(config.Environments) == 0 {
+		return nil, ErrorNoEnvironments
+	}
+	if config.Access != nil {
+		if err := config.Access.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	s := &Server{config: config, environments: map[string]bool{}, inProgress: map[string]bool{}, scheduled: map[string]*scheduledDeployment{}}
+	for _, env := range config.Environments {
+		s.environments[env] = true
+	}
+	return s, nil
+}
+
+// Serves the API until the listener fails
+func (s *Server) ListenAndServe() error {
+	address := s.config.Address
+	if address == "" {
+		address = DefaultAddress
+	}
+	log.Info("Serving environments %s on %s", strings.Join(s.Environments(), ", "), address)
+	if s.config.CertFile != "" {
+		return http.ListenAndServeTLS(address, s.config.CertFile, s.config.KeyFile, s.Handler())
+	}
+	return http.ListenAndServe(address, s.Handler())
+}
+
+// Returns the handler of the API.  Everything other than the health check requires a bearer token
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathHealth, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle(PathEnvironments, s.authenticated(s.listEnvironments))
+	mux.Handle(PathEnvironments+"/", s.authenticated(s.environmentAction))
+	return mux
+}
+
+// Returns the names of the environments being served
+func (s *Server) Environments() []string {
+	names := []string{}
+	for env := range s.environments {
+		names = append(names, env)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) authenticated(fn http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := s.validToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="depcon"`)
+			writeError(w, &apiError{status: http.StatusUnauthorized, err: ErrorUnauthorized})
+			return
+		}
+		fn(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// Returns the user of the request's token and true if the token is accepted
+func (s *Server) validToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return "", false
+	}
+	token := []byte(strings.TrimSpace(strings.TrimPrefix(auth, "Bearer ")))
+	valid, user := false, ""
+	// every token is compared so the time taken doesn't reveal which one matched
+	for _, t := range s.config.Tokens {
+		if subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+			valid, user = true, s.config.Users[t]
+		}
+	}
+	return user, valid
+}
+
+// Returns an error with the forbidden status unless the access policy permits the user of {r} to make
+// changes to {env} with {command}
+func (s *Server) authorize(r *http.Request, env, command string) error {
+	user := userOf(r)
+	if err := s.config.Access.Check(user, env, command); err != nil {
+		log.Warning("%s", err.Error())
+		return &apiError{status: http.StatusForbidden, err: err}
+	}
+	return nil
+}
+
+// Returns the user of the token of {r}
+func userOf(r *http.Request) string {
+	user, _ := r.Context().Value(userKey{}).(string)
+	return user
+}
+
+func (s *Server) listEnvironments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, &apiError{status: http.StatusMethodNotAllowed, err: ErrorMethodNotAllowed})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string][]string{"environments": s.Environments()})
+}
+
+// Routes /v1/environments/{env}/(deployments|rollbacks) and /v1/environments/{env}/deployments/{id} (scheduled
+// deployments)
+func (s *Server) environmentAction(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, PathEnvironments), "/"), "/")
+	scheduled := len(parts) == 3 && parts[1] == PathDeployments
+	if !scheduled && (len(parts) != 2 || (parts[1] != PathDeployments && parts[1] != PathRollbacks)) {
+		writeError(w, &apiError{status: http.StatusNotFound, err: ErrorNotFound})
+		return
+	}
+	listing := len(parts) == 2 && parts[1] == PathDeployments && r.Method == http.MethodGet
+	if (scheduled && r.Method != http.MethodDelete) || (!scheduled && !listing && r.Method != http.MethodPost) {
+		writeError(w, &apiError{status: http.StatusMethodNotAllowed, err: ErrorMethodNotAllowed})
+		return
+	}
+	env := parts[0]
+	if !s.environments[env] {
+		writeError(w, &apiError{status: http.StatusNotFound, err: ErrorUnknownEnvironment})
+		return
+	}
+	if listing {
+		writeJSON(w, http.StatusOK, map[string][]*Deployment{"scheduled": s.scheduledDeployments(env)})
+		return
+	}
+	if scheduled {
+		err := s.authorize(r, env, CommandDeploy)
+		var cancelled *Deployment
+		if err == nil {
+			cancelled, err = s.cancelScheduled(env, parts[2], userOf(r))
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, cancelled)
+		return
+	}
+
+	var result *Deployment
+	var err error
+	if parts[1] == PathDeployments {
+		req := &DeployRequest{}
+		if err = decode(r, req); err == nil && !req.DryRun {
+			err = s.authorize(r, env, CommandDeploy)
+		}
+		if err == nil {
+			result, err = s.deploy(env, userOf(r), req)
+		}
+	} else {
+		req := &RollbackRequest{}
+		if err = decode(r, req); err == nil {
+			err = s.authorize(r, env, CommandRollback)
+		}
+		if err == nil {
+			result, err = s.rollback(env, req)
+		}
+	}
+
+	status := http.StatusOK
+	if err != nil {
+		if result == nil {
+			writeError(w, err)
+			return
+		}
+		// the deployment was attempted so its outcome is returned along with the failure status
+		status = statusOf(err)
+	}
+	writeJSON(w, status, result)
+}
+
+// Marks the app as being deployed returning false when a deployment of it is already in progress
+func (s *Server) acquire(env, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := env + ":" + id
+	if s.inProgress[key] {
+		return false
+	}
+	s.inProgress[key] = true
+	return true
+}
+
+func (s *Server) release(env, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inProgress, env+":"+id)
+}
+
+func decode(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return &apiError{status: http.StatusBadRequest, err: errors.New("Invalid request body: " + err.Error())}
+	}
+	return nil
+}
+
+func statusOf(err error) int {
+	if e, ok := err.(*apiError); ok {
+		return e.status
+	}
+	switch {
+	case errors.Is(err, marathon.ErrorAppExists):
+		return http.StatusConflict
+	case errors.Is(err, marathon.ErrorNoAppExists):
+		return http.StatusNotFound
+	case errors.Is(err, marathon.ErrorTimeout):
+		return http.StatusGatewayTimeout
+	}
+	switch marathon.ErrorCodeOf(err) {
+	case marathon.CodeNotFound:
+		return http.StatusNotFound
+	case marathon.CodeConflict:
+		return http.StatusConflict
+	case marathon.CodeValidation:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadGateway
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	m := &Message{Message: err.Error()}
+	if e, ok := err.(*apiError); ok {
+		m.Errors = e.errors
+	}
+	writeJSON(w, statusOf(err), m)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,334 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/access"
+	"github.com/ContainX/depcon/pkg/audit"
+	"github.com/ContainX/depcon/pkg/signature"
+	"github.com/stretchr/testify/assert"
+)
+
+const token = "secret"
+
+// fakeMarathon records the calls of the server.  Calls it doesn't implement panic
+type fakeMarathon struct {
+	marathon.Marathon
+	current   *marathon.Application
+	created   *marathon.Application
+	updated   *marathon.Application
+	destroyed string
+	waitErr   error
+}
+
+func (f *fakeMarathon) GetApplication(id string) (*marathon.Application, error) {
+	if f.current == nil {
+		return nil, marathon.ErrorNoAppExists
+	}
+	return f.current, nil
+}
+
+func (f *fakeMarathon) CreateApplication(app *marathon.Application, wait, force bool) (*marathon.Application, error) {
+	if f.current != nil && !force {
+		return nil, marathon.ErrorAppExists
+	}
+	f.created = app
+	return app, nil
+}
+
+func (f *fakeMarathon) UpdateApplication(app *marathon.Application, wait bool) (*marathon.Application, error) {
+	f.updated = app
+	return app, nil
+}
+
+func (f *fakeMarathon) DestroyApplication(id string) (*marathon.DeploymentID, error) {
+	f.destroyed = id
+	return &marathon.DeploymentID{}, nil
+}
+
+func (f *fakeMarathon) WaitForApplication(id string, timeout time.Duration) error {
+	err := f.waitErr
+	// the rollback succeeds
+	f.waitErr = nil
+	return err
+}
+
+func (f *fakeMarathon) ListVersions(id string) (*marathon.Versions, error) {
+	return &marathon.Versions{Versions: []string{"v2", "v1"}}, nil
+}
+
+func newTestServer(t *testing.T, client *fakeMarathon) *httptest.Server {
+	s, err := New(&Config{Tokens: []string{token}, Environments: []string{"prod", "test"}, Client: func(env string) (marathon.Marathon, error) {
+		return client, nil
+	}})
+	assert.Nil(t, err)
+	return httptest.NewServer(s.Handler())
+}
+
+func post(t *testing.T, url, body string) (*http.Response, map[string]interface{}) {
+	return send(t, http.MethodPost, url, body)
+}
+
+func send(t *testing.T, method, url, body string) (*http.Response, map[string]interface{}) {
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	result := map[string]interface{}{}
+	json.NewDecoder(resp.Body).Decode(&result)
+	return resp, result
+}
+
+func TestNewRequiresTokens(t *testing.T) {
+	_, err := New(&Config{Environments: []string{"prod"}})
+	assert.Equal(t, ErrorNoTokens, err)
+}
+
+func TestAuthentication(t *testing.T) {
+	ts := newTestServer(t, &fakeMarathon{})
+	defer ts.Close()
+
+	resp, _ := http.Get(ts.URL + PathHealth)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = http.Get(ts.URL + PathEnvironments)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+PathEnvironments, nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, _ = http.DefaultClient.Do(req)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, _ = http.DefaultClient.Do(req)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	envs := map[string][]string{}
+	json.NewDecoder(resp.Body).Decode(&envs)
+	assert.Equal(t, []string{"prod", "test"}, envs["environments"])
+}
+
+func TestDeploy(t *testing.T) {
+	client := &fakeMarathon{}
+	ts := newTestServer(t, client)
+	defer ts.Close()
+
+	resp, result := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\", \"instances\": ${COUNT}}", "params": {"COUNT": "3"}, "wait": true}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, StatusDeployed, result["status"])
+	assert.Equal(t, 3, client.created.Instances)
+}
+
+func TestDeployRejectsInvalidDescriptors(t *testing.T) {
+	client := &fakeMarathon{}
+	ts := newTestServer(t, client)
+	defer ts.Close()
+
+	resp, result := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "id: /web\ninstances: ${COUNT}", "format": "yaml"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, marathon.ErrorAppParamsMissing.Error(), result["message"])
+
+	resp, result = post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\", \"instances\": \"three\"}"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	assert.Equal(t, ErrorInvalid.Error(), result["message"])
+	assert.Nil(t, client.created)
+
+	resp, _ = post(t, ts.URL+"/v1/environments/stage/deployments", `{"descriptor": "{\"id\": \"/web\"}"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestDeployDryRun(t *testing.T) {
+	client := &fakeMarathon{}
+	ts := newTestServer(t, client)
+	defer ts.Close()
+
+	resp, result := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/${APP}\"}", "params": {"APP": "web"}, "dryRun": true}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, StatusValid, result["status"])
+	assert.Equal(t, `{"id": "/web"}`, result["rendered"])
+	assert.Nil(t, client.created)
+}
+
+func TestDeployExistingRequiresForce(t *testing.T) {
+	ts := newTestServer(t, &fakeMarathon{current: &marathon.Application{ID: "/web", Version: "v1"}})
+	defer ts.Close()
+
+	resp, _ := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\"}"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestDeployRequiresTrustedSignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	_, other, _ := ed25519.GenerateKey(nil)
+	client := &fakeMarathon{}
+	s, err := New(&Config{Tokens: []string{token}, Environments: []string{"prod", "test"}, Client: func(env string) (marathon.Marathon, error) {
+		return client, nil
+	}, TrustedKeys: func(env string) ([]ed25519.PublicKey, error) {
+		if env == "prod" {
+			return []ed25519.PublicKey{pub}, nil
+		}
+		return nil, nil
+	}})
+	assert.Nil(t, err)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	descriptor := `{"id": "/web"}`
+	request := func(key ed25519.PrivateKey) string {
+		req := &DeployRequest{Descriptor: descriptor}
+		if key != nil {
+			req.Signature = signature.Sign([]byte(descriptor), key)
+		}
+		b, _ := json.Marshal(req)
+		return string(b)
+	}
+
+	resp, result := post(t, ts.URL+"/v1/environments/prod/deployments", request(nil))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, signature.ErrorNotSigned.Error(), result["message"])
+
+	resp, result = post(t, ts.URL+"/v1/environments/prod/deployments", request(other))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, signature.ErrorUntrustedKey.Error(), result["message"])
+	assert.Nil(t, client.created)
+
+	resp, _ = post(t, ts.URL+"/v1/environments/prod/deployments", request(priv))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "/web", client.created.ID)
+
+	// environments without keys accept unsigned descriptors
+	resp, _ = post(t, ts.URL+"/v1/environments/test/deployments", request(nil))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestDeployRollsBackFailures(t *testing.T) {
+	client := &fakeMarathon{current: &marathon.Application{ID: "/web", Version: "v1"}, waitErr: marathon.ErrorDeploymentFailed}
+	ts := newTestServer(t, client)
+	defer ts.Close()
+
+	resp, result := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\"}", "force": true, "rollback": true}`)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, StatusRolledBack, result["status"])
+	assert.Equal(t, "v1", result["version"])
+	assert.Equal(t, "v1", client.updated.Version)
+
+	// new applications are removed
+	client = &fakeMarathon{waitErr: marathon.ErrorTimeout}
+	ts2 := newTestServer(t, client)
+	defer ts2.Close()
+
+	resp, result = post(t, ts2.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\"}", "rollback": true}`)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Equal(t, StatusRolledBack, result["status"])
+	assert.Equal(t, "/web", client.destroyed)
+}
+
+func TestRollback(t *testing.T) {
+	client := &fakeMarathon{}
+	ts := newTestServer(t, client)
+	defer ts.Close()
+
+	resp, result := post(t, ts.URL+"/v1/environments/test/rollbacks", `{"appId": "/web"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "v1", result["version"])
+	assert.Equal(t, "v2", result["previousVersion"])
+	assert.Equal(t, "v1", client.updated.Version)
+
+	resp, _ = post(t, ts.URL+"/v1/environments/test/rollbacks", `{}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestAccessPolicy(t *testing.T) {
+	client := &fakeMarathon{current: &marathon.Application{ID: "/web", Version: "v2"}}
+	s, err := New(&Config{
+		Tokens:       []string{token},
+		Users:        map[string]string{token: "alice"},
+		Access:       &access.Policy{Roles: map[string]*access.Role{"developer": {Environments: []string{"test"}}}, Users: map[string][]string{"alice": {"developer"}}},
+		Environments: []string{"prod", "test"},
+		Client:       func(env string) (marathon.Marathon, error) { return client, nil },
+	})
+	assert.Nil(t, err)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, result := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\"}", "force": true}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, result["message"], "alice")
+	assert.Nil(t, client.updated)
+
+	resp, _ = post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\"}", "dryRun": true}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = post(t, ts.URL+"/v1/environments/test/rollbacks", `{"appId": "/web"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestScheduledDeploy(t *testing.T) {
+	client := &fakeMarathon{}
+	ts := newTestServer(t, client)
+	defer ts.Close()
+
+	resp, _ := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\"}", "at": "2020-01-01T02:00Z"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\", \"instances\": ${COUNT}}", "in": "4h"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	resp, result := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\"}", "in": "4h"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, StatusScheduled, result["status"])
+	assert.Equal(t, "1", result["id"])
+	assert.NotEmpty(t, result["scheduledAt"])
+	assert.Nil(t, client.created)
+
+	resp, result = send(t, http.MethodGet, ts.URL+"/v1/environments/prod/deployments", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, result["scheduled"], 1)
+	_, result = send(t, http.MethodGet, ts.URL+"/v1/environments/test/deployments", "")
+	assert.Len(t, result["scheduled"], 0)
+
+	resp, _ = send(t, http.MethodDelete, ts.URL+"/v1/environments/test/deployments/1", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, result = send(t, http.MethodDelete, ts.URL+"/v1/environments/prod/deployments/1", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, StatusCancelled, result["status"])
+	_, result = send(t, http.MethodGet, ts.URL+"/v1/environments/prod/deployments", "")
+	assert.Len(t, result["scheduled"], 0)
+}
+
+func TestScheduledDeployRuns(t *testing.T) {
+	client := &fakeMarathon{}
+	filename := filepath.Join(t.TempDir(), audit.DefaultFilename)
+	s, err := New(&Config{Tokens: []string{token}, Users: map[string]string{token: "alice"}, Environments: []string{"prod"}, Audit: audit.New(filename),
+		Client: func(env string) (marathon.Marathon, error) { return client, nil }})
+	assert.Nil(t, err)
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	resp, _ := post(t, ts.URL+"/v1/environments/prod/deployments", `{"descriptor": "{\"id\": \"/web\"}", "in": "10ms"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	deployed := func() bool {
+		data, _ := ioutil.ReadFile(filename)
+		return strings.Contains(string(data), `"action":"deploy"`)
+	}
+	assert.Eventually(t, deployed, 5*time.Second, 10*time.Millisecond)
+	data, _ := ioutil.ReadFile(filename)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"result":"scheduled"`)
+	assert.Contains(t, lines[0], `"user":"alice"`)
+	assert.Contains(t, lines[1], `"result":"success"`)
+	assert.Contains(t, lines[1], `"scheduledAt"`)
+	assert.Equal(t, "/web", client.created.ID)
+}
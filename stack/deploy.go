@@ -0,0 +1,183 @@
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/reconcile"
+)
+
+const (
+	ResultDeployed  = "deployed"
+	ResultUnchanged = "unchanged"
+	ResultFailed    = "failed"
+	// not deployed since an application before it failed
+	ResultSkipped = "skipped"
+	// deployed and then restored to its previous version (or removed) after the stack failed
+	ResultRolledBack     = "rolled back"
+	ResultRollbackFailed = "rollback failed"
+)
+
+// Result is the change made to an application of a stack and its outcome
+type Result struct {
+	ID   string `json:"id"`
+	File string `json:"file"`
+	// create, update or none
+	Action    string                 `json:"action"`
+	DependsOn []string               `json:"dependsOn,omitempty"`
+	Fields    []marathon.FieldChange `json:"fields,omitempty"`
+	// Version running before the stack was deployed.  Empty when the application is created
+	Previous string        `json:"previous,omitempty"`
+	Result   string        `json:"result,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	app      *App
+}
+
+// Deployer deploys the applications of stacks
+type Deployer struct {
+	Marathon marathon.Marathon
+	// Max duration of the wait for each application to be healthy.  Default: marathon.DefaultTimeout
+	Timeout time.Duration
+	// Leaves the applications changed by a failed deploy in place rather than rolling them back
+	NoRollback bool
+	// Receives each result as the application is deployed or rolled back.  Optional
+	Report func(*Result)
+}
+
+// Plan compares the ordered {apps} with those deployed returning the change each requires
+func (d *Deployer) Plan(apps []*App) ([]*Result, error) {
+	results := []*Result{}
+	for _, a := range apps {
+		r := &Result{ID: a.ID, File: a.File, Action: reconcile.ActionNone, DependsOn: a.DependsOn, app: a}
+		live, err := d.Marathon.GetApplication(a.ID)
+		switch {
+		case errors.Is(err, httpclient.ErrorNotFound):
+			r.Action = reconcile.ActionCreate
+		case err != nil:
+			return nil, err
+		default:
+			r.Previous = live.Version
+			if r.Fields = marathon.DiffApplication(a.App, live); len(r.Fields) > 0 {
+				r.Action = reconcile.ActionUpdate
+			}
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// Deploy creates or updates the ordered {apps} one at a time waiting for each to be healthy before the
+// next.  Once one fails the rest are skipped and the applications changed by the deploy (including the one
+// which failed) are rolled back in reverse order unless NoRollback is set
+func (d *Deployer) Deploy(apps []*App) ([]*Result, error) {
+	results, err := d.Plan(apps)
+	if err != nil {
+		return nil, err
+	}
+	changed := []*Result{}
+	for i, r := range results {
+		if r.Action == reconcile.ActionNone {
+			r.Result = ResultUnchanged
+			d.report(r)
+			continue
+		}
+		started := time.Now()
+		deployed, err := d.deploy(r)
+		r.Duration = time.Since(started).Round(time.Millisecond)
+		if deployed {
+			changed = append(changed, r)
+		}
+		if err == nil {
+			r.Result = ResultDeployed
+			d.report(r)
+			continue
+		}
+
+		r.Result, r.Error = ResultFailed, err.Error()
+		d.report(r)
+		for _, skipped := range results[i+1:] {
+			skipped.Result = ResultSkipped
+			d.report(skipped)
+		}
+		cause := fmt.Errorf("Deploying '%s' failed: %w", r.ID, err)
+		if d.NoRollback || len(changed) == 0 {
+			return results, cause
+		}
+		if failed := d.rollback(changed); failed > 0 {
+			return results, fmt.Errorf("%w (%d of %d rollback(s) failed)", cause, failed, len(changed))
+		}
+		return results, fmt.Errorf("%w (rolled back %d application(s))", cause, len(changed))
+	}
+	return results, nil
+}
+
+// Creates or updates the application of {r} and waits for it returning whether the application was changed
+func (d *Deployer) deploy(r *Result) (bool, error) {
+	logger.With(log, logger.Fields{logger.FieldApp: r.ID}).Info("Deploying %s of '%s' (%s)", r.Action, r.ID, r.File)
+	if _, err := d.Marathon.CreateApplication(r.app.App, false, r.Action == reconcile.ActionUpdate); err != nil {
+		return false, err
+	}
+	return true, d.Marathon.WaitForApplication(r.ID, d.timeout(r.app.Timeout))
+}
+
+// Restores the applications of {changed} in reverse order, cancelling any deployment still in progress,
+// returning the number which failed
+func (d *Deployer) rollback(changed []*Result) int {
+	failed := 0
+	for i := len(changed) - 1; i >= 0; i-- {
+		r := changed[i]
+		l := logger.With(log, logger.Fields{logger.FieldApp: r.ID})
+		// a deployment which timed out still holds the application so Marathon refuses changes to it until the
+		// deployment is cancelled.  It's deleted without Marathon's own rollback which the one below replaces
+		_, err := d.Marathon.CancelAppDeployment(r.ID, false)
+		if err != nil {
+			l.Warning("Unable to cancel the deployment of '%s': %s", r.ID, err.Error())
+		}
+		if r.Previous == "" {
+			l.Warning("Rolling back '%s' by removing it", r.ID)
+			var deployment *marathon.DeploymentID
+			if deployment, err = d.Marathon.DestroyApplication(r.ID); err == nil && deployment != nil {
+				err = d.Marathon.WaitForDeployment(deployment.DeploymentID, d.timeout(r.app.Timeout))
+			}
+		} else {
+			l.Warning("Rolling back '%s' to %s", r.ID, r.Previous)
+			update := marathon.NewApplication(r.ID).RollbackVersion(r.Previous)
+			if _, err = d.Marathon.UpdateApplication(update, false); err == nil {
+				err = d.Marathon.WaitForApplication(r.ID, d.timeout(r.app.Timeout))
+			}
+		}
+		if err != nil {
+			l.Error("Unable to roll back '%s': %s", r.ID, err.Error())
+			if r.Error != "" {
+				r.Error += "; "
+			}
+			r.Result, r.Error = ResultRollbackFailed, r.Error+"rollback: "+err.Error()
+			failed++
+		} else {
+			r.Result = ResultRolledBack
+		}
+		d.report(r)
+	}
+	return failed
+}
+
+func (d *Deployer) timeout(t time.Duration) time.Duration {
+	switch {
+	case t != 0:
+		return t
+	case d.Timeout != 0:
+		return d.Timeout
+	}
+	return marathon.DefaultTimeout
+}
+
+func (d *Deployer) report(r *Result) {
+	if d.Report != nil {
+		d.Report(r)
+	}
+}
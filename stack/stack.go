@@ -0,0 +1,278 @@
+// Deploys stacks: sets of application descriptors sharing a template context and params which are deployed
+// one at a time in dependency order, rolling back the applications changed by a deploy which fails
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/reconcile"
+)
+
+// LabelDependsOn is the label of a descriptor listing the ids of the applications of the stack deployed
+// before it separated by commas (eg. /shop/db,/shop/cache)
+const LabelDependsOn = "DEPCON_DEPENDS_ON"
+
+var log = logger.GetLogger("depcon.stack")
+
+var ErrorNoApps = errors.New("The stack declares no applications")
+
+// Stack is a set of application descriptors deployed together
+type Stack struct {
+	Name string `json:"name"`
+	// Template context the descriptors are rendered with relative to the stack
+	TemplateContext string `json:"tempctx,omitempty"`
+	// Values of the ${PARAMS} within the descriptors
+	Params map[string]string `json:"params,omitempty"`
+	Apps   []*Member         `json:"apps"`
+	dir    string
+	// the descriptors are every file within dir rather than the members
+	all bool
+}
+
+// Member is an application descriptor of a stack manifest
+type Member struct {
+	// Descriptor relative to the stack
+	File string `json:"file"`
+	// Ids of the applications of the stack deployed (and healthy) before this one in addition to those of the
+	// descriptor's DEPCON_DEPENDS_ON label and Marathon dependencies
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// Params of this descriptor overriding those of the stack
+	Params map[string]string `json:"params,omitempty"`
+	// Max duration of the wait for the application to be healthy (eg. 90s, 5m)
+	Timeout string `json:"timeout,omitempty"`
+	timeout time.Duration
+}
+
+// App is an application of a stack along with the applications it depends on
+type App struct {
+	ID   string
+	File string
+	App  *marathon.Application
+	// Absolute ids of the applications of the stack deployed before this one
+	DependsOn []string
+	// Max duration of the wait.  Zero uses the deployer's timeout
+	Timeout time.Duration
+}
+
+// Load returns the stack {filename} which is either a directory whose descriptors (.json, .yaml and .yml)
+// are the stack or a stack manifest (.yaml, .yml or .json) listing the descriptors
+func Load(filename string) (*Stack, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		abs, _ := filepath.Abs(filename)
+		return &Stack{Name: filepath.Base(abs), dir: filename, all: true}, nil
+	}
+
+	enc, err := encoding.NewEncoderFromFileExt(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &Stack{dir: filepath.Dir(filename)}
+	if err := enc.UnMarshal(f, s); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+	if s.Name == "" {
+		s.Name = strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	}
+	if len(s.Apps) == 0 {
+		return nil, fmt.Errorf("%s: %s", filename, ErrorNoApps.Error())
+	}
+	for i, m := range s.Apps {
+		if m.File == "" {
+			return nil, fmt.Errorf("%s: apps[%d] requires a file", filename, i)
+		}
+		if m.Timeout != "" {
+			if m.timeout, err = time.ParseDuration(m.Timeout); err != nil {
+				return nil, fmt.Errorf("%s: invalid timeout '%s' of %s", filename, m.Timeout, m.File)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Path returns {name} relative to the stack unless it is absolute
+func (s *Stack) Path(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(s.dir, name)
+}
+
+// Files returns the descriptors of a stack manifest or nil for a directory
+func (s *Stack) Files() []string {
+	if s.all {
+		return nil
+	}
+	files := []string{}
+	for _, m := range s.Apps {
+		files = append(files, s.Path(m.File))
+	}
+	return files
+}
+
+// Load renders and parses the descriptors of the stack returning its applications in the order they're
+// deployed.  The params of {opts} override those of the stack and its members.  Groups, dependencies on
+// applications outside the stack and dependency cycles are rejected
+func (s *Stack) Load(opts *reconcile.LoadOptions) ([]*App, error) {
+	if opts == nil {
+		opts = &reconcile.LoadOptions{}
+	}
+	apps := []*App{}
+	add := func(descs []*reconcile.Descriptor, m *Member) error {
+		for _, d := range descs {
+			if d.App == nil {
+				return fmt.Errorf("%s: '%s' is a group - stacks deploy applications", d.File, d.ID)
+			}
+			a := &App{ID: d.ID, File: d.File, App: d.App}
+			deps := append(append([]string{}, d.App.Dependencies...), splitLabel(d.App.Labels[LabelDependsOn])...)
+			if m != nil {
+				deps = append(deps, m.DependsOn...)
+				a.Timeout = m.timeout
+			}
+			a.DependsOn = resolveIDs(d.ID, deps)
+			apps = append(apps, a)
+		}
+		return nil
+	}
+
+	if s.all {
+		o := *opts
+		o.Params = merge(s.Params, opts.Params)
+		descs, err := reconcile.Load(s.dir, &o)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(descs, nil); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, m := range s.Apps {
+			o := *opts
+			o.Params = merge(s.Params, m.Params, opts.Params)
+			descs, err := reconcile.LoadFile(s.Path(m.File), &o)
+			if err != nil {
+				return nil, err
+			}
+			if err := add(descs, m); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(apps) == 0 {
+		return nil, ErrorNoApps
+	}
+	return Order(apps)
+}
+
+// Order returns {apps} ordered so each follows the applications it depends on.  Applications without an
+// ordering between them keep the order they were declared in
+func Order(apps []*App) ([]*App, error) {
+	declared := map[string]*App{}
+	for _, a := range apps {
+		if other, ok := declared[a.ID]; ok {
+			return nil, fmt.Errorf("'%s' is declared by both %s and %s", a.ID, other.File, a.File)
+		}
+		declared[a.ID] = a
+	}
+	for _, a := range apps {
+		for _, dep := range a.DependsOn {
+			if dep == a.ID {
+				return nil, fmt.Errorf("'%s' (%s) depends on itself", a.ID, a.File)
+			}
+			if declared[dep] == nil {
+				return nil, fmt.Errorf("'%s' (%s) depends on '%s' which isn't part of the stack", a.ID, a.File, dep)
+			}
+		}
+	}
+
+	ordered := []*App{}
+	placed := map[string]bool{}
+	for len(ordered) < len(apps) {
+		progressed := false
+		for _, a := range apps {
+			if placed[a.ID] || !dependenciesPlaced(a, placed) {
+				continue
+			}
+			ordered = append(ordered, a)
+			placed[a.ID] = true
+			progressed = true
+			break
+		}
+		if !progressed {
+			cycle := []string{}
+			for _, a := range apps {
+				if !placed[a.ID] {
+					cycle = append(cycle, a.ID)
+				}
+			}
+			return nil, fmt.Errorf("The dependencies of %s form a cycle", strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+func dependenciesPlaced(a *App, placed map[string]bool) bool {
+	for _, dep := range a.DependsOn {
+		if !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the absolute, sorted and distinct ids of {ids} where relative ids are relative to the group of
+// application {id} as with Marathon's dependencies
+func resolveIDs(id string, ids []string) []string {
+	seen := map[string]bool{}
+	resolved := []string{}
+	for _, dep := range ids {
+		if dep = strings.TrimSpace(dep); dep == "" {
+			continue
+		}
+		if !strings.HasPrefix(dep, "/") {
+			dep = path.Join(path.Dir(id), dep)
+		}
+		if dep = path.Clean(dep); !seen[dep] {
+			seen[dep] = true
+			resolved = append(resolved, dep)
+		}
+	}
+	sort.Strings(resolved)
+	return resolved
+}
+
+func splitLabel(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func merge(maps ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,211 @@
+package stack
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/marathon/marathontest"
+	"github.com/ContainX/depcon/reconcile"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingWait fails the waits on the applications listed in fail
+type failingWait struct {
+	*marathontest.Fake
+	fail map[string]bool
+}
+
+func (f *failingWait) WaitForApplication(id string, timeout time.Duration) error {
+	if f.fail[id] {
+		return marathon.ErrorTimeout
+	}
+	return f.Fake.WaitForApplication(id, timeout)
+}
+
+// lockedDeploy leaves the deployment of {stuck} in progress so the application is locked as it is by
+// Marathon: waits time out and changes are refused until the deployment is cancelled
+type lockedDeploy struct {
+	*marathontest.Fake
+	stuck string
+}
+
+func (f *lockedDeploy) CreateApplication(app *marathon.Application, wait, force bool) (*marathon.Application, error) {
+	if f.locked(app.ID) {
+		return nil, marathon.NewError(marathon.CodeConflict, 409, errors.New("App is locked by one or more deployments"))
+	}
+	result, err := f.Fake.CreateApplication(app, wait, force)
+	if err == nil && app.ID == f.stuck {
+		f.Deployments = append(f.Deployments, &marathon.Deploy{DeployID: "stuck", AffectedApps: []string{app.ID}})
+	}
+	return result, err
+}
+
+func (f *lockedDeploy) UpdateApplication(app *marathon.Application, wait bool) (*marathon.Application, error) {
+	if f.locked(app.ID) {
+		return nil, marathon.NewError(marathon.CodeConflict, 409, errors.New("App is locked by one or more deployments"))
+	}
+	return f.Fake.UpdateApplication(app, wait)
+}
+
+func (f *lockedDeploy) WaitForApplication(id string, timeout time.Duration) error {
+	if f.locked(id) {
+		return marathon.ErrorTimeout
+	}
+	return f.Fake.WaitForApplication(id, timeout)
+}
+
+func (f *lockedDeploy) locked(id string) bool {
+	for _, d := range f.Deployments {
+		for _, app := range d.AffectedApps {
+			if app == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, _ := ioutil.TempDir("", "stack")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, content := range files {
+		assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	return dir
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"stack.yaml": `name: shop
+params:
+  TAG: "1.0"
+apps:
+  - file: web.yaml
+    dependsOn: [api]
+    timeout: 5m
+  - file: api.yaml
+    params:
+      TAG: "2.0"
+  - file: db.yaml
+`,
+		"web.yaml": "id: /shop/web\nlabels:\n  VERSION: \"${TAG}\"\n",
+		"api.yaml": "id: /shop/api\nlabels:\n  DEPCON_DEPENDS_ON: /shop/db\n  VERSION: \"${TAG}\"\n",
+		"db.yaml":  "id: /shop/db\n",
+	})
+
+	s, err := Load(filepath.Join(dir, "stack.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "shop", s.Name)
+	assert.Len(t, s.Files(), 3)
+
+	apps, err := s.Load(&reconcile.LoadOptions{Params: map[string]string{"UNUSED": "x"}})
+	assert.Nil(t, err)
+	ids := []string{}
+	for _, a := range apps {
+		ids = append(ids, a.ID)
+	}
+	assert.Equal(t, []string{"/shop/db", "/shop/api", "/shop/web"}, ids)
+	assert.Equal(t, []string{"/shop/api"}, apps[2].DependsOn, "relative ids are relative to the app's group")
+	assert.Equal(t, 5*time.Minute, apps[2].Timeout)
+	assert.Equal(t, "2.0", apps[1].App.Labels["VERSION"])
+	assert.Equal(t, "1.0", apps[2].App.Labels["VERSION"])
+}
+
+func TestLoadDirectory(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a-web.json": `{"id": "/web", "dependencies": ["/db"]}`,
+		"b-db.json":  `{"id": "/db"}`,
+	})
+	s, err := Load(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Files())
+
+	apps, err := s.Load(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "/db", apps[0].ID)
+	assert.Equal(t, "/web", apps[1].ID)
+}
+
+func TestOrderErrors(t *testing.T) {
+	_, err := Order([]*App{{ID: "/a", DependsOn: []string{"/b"}}, {ID: "/b", DependsOn: []string{"/a"}}})
+	assert.EqualError(t, err, "The dependencies of /a, /b form a cycle")
+
+	_, err = Order([]*App{{ID: "/a", File: "a.json", DependsOn: []string{"/missing"}}})
+	assert.EqualError(t, err, "'/a' (a.json) depends on '/missing' which isn't part of the stack")
+
+	_, err = Order([]*App{{ID: "/a", File: "a.json"}, {ID: "/a", File: "b.json"}})
+	assert.EqualError(t, err, "'/a' is declared by both a.json and b.json")
+}
+
+func TestDeploy(t *testing.T) {
+	fake := marathontest.New().WithApps(&marathon.Application{ID: "/db", Instances: 1}, &marathon.Application{ID: "/cache", Instances: 1})
+	apps := []*App{
+		{ID: "/db", App: &marathon.Application{ID: "/db", Instances: 2}},
+		{ID: "/cache", App: &marathon.Application{ID: "/cache", Instances: 1}},
+		{ID: "/api", App: &marathon.Application{ID: "/api", Instances: 1}, DependsOn: []string{"/db"}},
+	}
+	reported := []string{}
+	d := &Deployer{Marathon: fake, Report: func(r *Result) { reported = append(reported, r.ID+" "+r.Result) }}
+
+	plan, err := d.Plan(apps)
+	assert.Nil(t, err)
+	assert.Equal(t, reconcile.ActionUpdate, plan[0].Action)
+	assert.Equal(t, reconcile.ActionNone, plan[1].Action)
+	assert.Equal(t, reconcile.ActionCreate, plan[2].Action)
+	assert.Empty(t, reported, "planning doesn't deploy")
+
+	results, err := d.Deploy(apps)
+	assert.Nil(t, err)
+	assert.Len(t, results, 3)
+	assert.Equal(t, []string{"/db deployed", "/cache unchanged", "/api deployed"}, reported)
+	assert.Equal(t, 2, fake.Apps["/db"].Instances)
+	assert.NotNil(t, fake.Apps["/api"])
+}
+
+func TestDeployRollsBack(t *testing.T) {
+	fake := marathontest.New().WithApps(&marathon.Application{ID: "/db", Instances: 1})
+	previous := fake.Apps["/db"].Version
+	client := &failingWait{Fake: fake, fail: map[string]bool{"/web": true}}
+	apps := []*App{
+		{ID: "/db", App: &marathon.Application{ID: "/db", Instances: 3}},
+		{ID: "/api", App: &marathon.Application{ID: "/api", Instances: 1}},
+		{ID: "/web", App: &marathon.Application{ID: "/web", Instances: 1}},
+		{ID: "/worker", App: &marathon.Application{ID: "/worker", Instances: 1}},
+	}
+	reported := []string{}
+	d := &Deployer{Marathon: client, Report: func(r *Result) { reported = append(reported, r.ID+" "+r.Result) }}
+
+	results, err := d.Deploy(apps)
+	assert.EqualError(t, err, "Deploying '/web' failed: "+marathon.ErrorTimeout.Error()+" (rolled back 3 application(s))")
+	assert.Equal(t, []string{"/db deployed", "/api deployed", "/web failed", "/worker skipped",
+		"/web rolled back", "/api rolled back", "/db rolled back"}, reported)
+	assert.Equal(t, previous, results[0].Previous)
+	assert.Nil(t, fake.Apps["/api"], "created apps are removed")
+	assert.Nil(t, fake.Apps["/web"])
+	assert.Nil(t, fake.Apps["/worker"], "skipped apps aren't deployed")
+	assert.True(t, fake.Called("UpdateApplication"), "updated apps are restored to their previous version")
+
+	reported = reported[:0]
+	fake.WithApps(&marathon.Application{ID: "/db", Instances: 1})
+	d.NoRollback = true
+	_, err = d.Deploy(apps)
+	assert.EqualError(t, err, "Deploying '/web' failed: "+marathon.ErrorTimeout.Error())
+	assert.Equal(t, []string{"/db deployed", "/api deployed", "/web failed", "/worker skipped"}, reported)
+}
+
+func TestRollbackCancelsDeploymentInProgress(t *testing.T) {
+	fake := marathontest.New().WithApps(&marathon.Application{ID: "/api", Instances: 1})
+	d := &Deployer{Marathon: &lockedDeploy{Fake: fake, stuck: "/api"}}
+
+	results, err := d.Deploy([]*App{{ID: "/api", App: &marathon.Application{ID: "/api", Instances: 3}}})
+	assert.EqualError(t, err, "Deploying '/api' failed: "+marathon.ErrorTimeout.Error()+" (rolled back 1 application(s))")
+	assert.Equal(t, ResultRolledBack, results[0].Result)
+	assert.Empty(t, fake.Deployments, "the deployment which timed out is cancelled")
+	assert.True(t, fake.Called("CancelAppDeployment"))
+	assert.True(t, fake.Called("UpdateApplication"), "the previous version is restored")
+}
@@ -0,0 +1,535 @@
+package swarm
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// label identifying the stack of services and networks (shared with 'docker stack')
+	LabelNamespace = "com.docker.stack.namespace"
+	defaultNetwork = "default"
+)
+
+// Service keys of the compose file which swarm services support
+var supportedKeys = map[string]bool{
+	"image": true, "command": true, "entrypoint": true, "environment": true, "labels": true, "deploy": true,
+	"ports": true, "networks": true, "volumes": true, "healthcheck": true, "hostname": true, "user": true,
+	"working_dir": true, "stop_grace_period": true,
+}
+
+// Stack is a compose file converted to the networks and service specs of the Docker Engine API
+type Stack struct {
+	Name string
+	// network specs keyed by the name within the stack
+	Networks map[string]map[string]interface{}
+	// names of external networks which must already exist
+	External []string
+	Services []map[string]interface{}
+	// keys of the compose file which were ignored
+	Warnings []string
+}
+
+// ConvertCompose converts the compose file {doc} to the services and networks of stack {name}.  Services and
+// networks are named {name}_{service} and labeled with the stack as 'docker stack deploy' does
+func ConvertCompose(name string, doc map[string]interface{}) (*Stack, error) {
+	services, _ := doc["services"].(map[string]interface{})
+	if len(services) == 0 {
+		return nil, ErrorNoServices
+	}
+
+	stack := &Stack{Name: name, Networks: map[string]map[string]interface{}{}}
+	networks, _ := doc["networks"].(map[string]interface{})
+	for key, v := range networks {
+		n, _ := v.(map[string]interface{})
+		if external, _ := n["external"].(bool); external {
+			stack.External = append(stack.External, key)
+			continue
+		}
+		spec := map[string]interface{}{
+			"Name":   stack.scoped(key),
+			"Driver": stringOr(n["driver"], "overlay"),
+			"Labels": stack.labels(n["labels"]),
+		}
+		if attachable, _ := n["attachable"].(bool); attachable {
+			spec["Attachable"] = true
+		}
+		stack.Networks[key] = spec
+	}
+
+	names := []string{}
+	for svc := range services {
+		names = append(names, svc)
+	}
+	sort.Strings(names)
+	for _, svc := range names {
+		s, _ := services[svc].(map[string]interface{})
+		spec, err := stack.convertService(svc, s)
+		if err != nil {
+			return nil, fmt.Errorf("service '%s': %s", svc, err.Error())
+		}
+		stack.Services = append(stack.Services, spec)
+	}
+	return stack, nil
+}
+
+// Returns {key} prefixed by the stack name
+func (stack *Stack) scoped(key string) string {
+	return stack.Name + "_" + key
+}
+
+// Returns the labels {v} (a map or list of key=value) with the stack namespace label
+func (stack *Stack) labels(v interface{}) map[string]string {
+	labels := toStringMap(v)
+	labels[LabelNamespace] = stack.Name
+	return labels
+}
+
+func (stack *Stack) convertService(name string, s map[string]interface{}) (map[string]interface{}, error) {
+	for key := range s {
+		if !supportedKeys[key] {
+			stack.Warnings = append(stack.Warnings, fmt.Sprintf("services.%s.%s is not supported and was ignored", name, key))
+		}
+	}
+	image, _ := s["image"].(string)
+	if image == "" {
+		return nil, ErrorMissingImage
+	}
+	deploy, _ := s["deploy"].(map[string]interface{})
+
+	container := map[string]interface{}{
+		"Image":  image,
+		"Labels": toStringMap(s["labels"]),
+		"Env":    toEnv(s["environment"]),
+	}
+	if v := toArgs(s["entrypoint"]); len(v) > 0 {
+		container["Command"] = v
+	}
+	if v := toArgs(s["command"]); len(v) > 0 {
+		container["Args"] = v
+	}
+	for key, field := range map[string]string{"hostname": "Hostname", "user": "User", "working_dir": "Dir"} {
+		if v, _ := s[key].(string); v != "" {
+			container[field] = v
+		}
+	}
+	if v, ok := s["stop_grace_period"]; ok {
+		d, err := toDuration(v)
+		if err != nil {
+			return nil, err
+		}
+		container["StopGracePeriod"] = d
+	}
+	if hc, ok := s["healthcheck"].(map[string]interface{}); ok {
+		healthcheck, err := convertHealthcheck(hc)
+		if err != nil {
+			return nil, err
+		}
+		container["Healthcheck"] = healthcheck
+	}
+	mounts, err := stack.convertVolumes(s["volumes"])
+	if err != nil {
+		return nil, err
+	}
+	if len(mounts) > 0 {
+		container["Mounts"] = mounts
+	}
+
+	task := map[string]interface{}{"ContainerSpec": container}
+	spec := map[string]interface{}{
+		"Name":         stack.scoped(name),
+		"Labels":       stack.labels(deploy["labels"]),
+		"TaskTemplate": task,
+	}
+
+	if deploy["mode"] == "global" {
+		spec["Mode"] = map[string]interface{}{"Global": map[string]interface{}{}}
+	} else {
+		replicas := 1
+		if v, ok := deploy["replicas"]; ok {
+			replicas = toInt(v)
+		}
+		spec["Mode"] = map[string]interface{}{"Replicated": map[string]interface{}{"Replicas": replicas}}
+	}
+	if resources, ok := deploy["resources"].(map[string]interface{}); ok {
+		r, err := convertResources(resources)
+		if err != nil {
+			return nil, err
+		}
+		task["Resources"] = r
+	}
+	if restart, ok := deploy["restart_policy"].(map[string]interface{}); ok {
+		r, err := convertRestartPolicy(restart)
+		if err != nil {
+			return nil, err
+		}
+		task["RestartPolicy"] = r
+	}
+	if placement, ok := deploy["placement"].(map[string]interface{}); ok {
+		task["Placement"] = map[string]interface{}{"Constraints": toArgs(placement["constraints"])}
+	}
+	if update, ok := deploy["update_config"].(map[string]interface{}); ok {
+		u, err := convertUpdateConfig(update)
+		if err != nil {
+			return nil, err
+		}
+		spec["UpdateConfig"] = u
+	}
+
+	ports, err := convertPorts(s["ports"])
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) > 0 {
+		spec["EndpointSpec"] = map[string]interface{}{"Ports": ports}
+	}
+	task["Networks"] = stack.convertNetworks(name, s["networks"])
+	return spec, nil
+}
+
+// Attaches the service to the listed networks or the stack's default network when none are listed
+func (stack *Stack) convertNetworks(service string, v interface{}) []map[string]interface{} {
+	keys := []string{}
+	switch n := v.(type) {
+	case []interface{}:
+		for _, key := range n {
+			keys = append(keys, fmt.Sprint(key))
+		}
+	case map[string]interface{}:
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+	}
+	if len(keys) == 0 {
+		keys = []string{defaultNetwork}
+	}
+
+	attachments := []map[string]interface{}{}
+	for _, key := range keys {
+		target := stack.scoped(key)
+		if contains(stack.External, key) {
+			target = key
+		} else if _, ok := stack.Networks[key]; !ok {
+			// the default network and networks referenced without a definition are created as overlays
+			stack.Networks[key] = map[string]interface{}{"Name": target, "Driver": "overlay", "Labels": stack.labels(nil)}
+		}
+		attachments = append(attachments, map[string]interface{}{"Target": target, "Aliases": []string{service}})
+	}
+	return attachments
+}
+
+// Converts the short syntax (source:target[:ro]) of volumes.  Sources which are paths are bind mounted and
+// others are volumes named within the stack
+func (stack *Stack) convertVolumes(v interface{}) ([]map[string]interface{}, error) {
+	list, _ := v.([]interface{})
+	mounts := []map[string]interface{}{}
+	for _, item := range list {
+		spec, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("volume '%v' must use the short syntax (source:target[:ro])", item)
+		}
+		parts := strings.Split(spec, ":")
+		mount := map[string]interface{}{"Type": "volume", "Target": parts[0]}
+		if len(parts) > 1 {
+			mount["Target"] = parts[1]
+			if strings.HasPrefix(parts[0], "/") || strings.HasPrefix(parts[0], ".") || strings.HasPrefix(parts[0], "~") {
+				mount["Type"] = "bind"
+				mount["Source"] = parts[0]
+			} else {
+				mount["Source"] = stack.scoped(parts[0])
+				mount["VolumeOptions"] = map[string]interface{}{"Labels": stack.labels(nil)}
+			}
+		}
+		if len(parts) > 2 && parts[2] == "ro" {
+			mount["ReadOnly"] = true
+		}
+		mounts = append(mounts, mount)
+	}
+	return mounts, nil
+}
+
+// Converts ports in the short (published:target[/protocol]) or long syntax
+func convertPorts(v interface{}) ([]map[string]interface{}, error) {
+	list, _ := v.([]interface{})
+	ports := []map[string]interface{}{}
+	for _, item := range list {
+		port := map[string]interface{}{"Protocol": "tcp", "PublishMode": "ingress"}
+		switch p := item.(type) {
+		case map[string]interface{}:
+			port["TargetPort"] = toInt(p["target"])
+			if published, ok := p["published"]; ok {
+				port["PublishedPort"] = toInt(published)
+			}
+			port["Protocol"] = stringOr(p["protocol"], "tcp")
+			port["PublishMode"] = stringOr(p["mode"], "ingress")
+		default:
+			spec := fmt.Sprint(p)
+			if i := strings.Index(spec, "/"); i > 0 {
+				port["Protocol"] = spec[i+1:]
+				spec = spec[:i]
+			}
+			parts := strings.Split(spec, ":")
+			target, err := strconv.Atoi(parts[len(parts)-1])
+			if err != nil {
+				return nil, fmt.Errorf("port '%v' must be [published:]target[/protocol]", item)
+			}
+			port["TargetPort"] = target
+			if len(parts) > 1 {
+				published, err := strconv.Atoi(parts[len(parts)-2])
+				if err != nil {
+					return nil, fmt.Errorf("port '%v' must be [published:]target[/protocol]", item)
+				}
+				port["PublishedPort"] = published
+			}
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+func convertResources(r map[string]interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for key, field := range map[string]string{"limits": "Limits", "reservations": "Reservations"} {
+		values, ok := r[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		resources := map[string]interface{}{}
+		if cpus, ok := values["cpus"]; ok {
+			n, err := strconv.ParseFloat(fmt.Sprint(cpus), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cpus '%v' must be a number", cpus)
+			}
+			resources["NanoCPUs"] = int64(n * 1e9)
+		}
+		if memory, ok := values["memory"]; ok {
+			b, err := toBytes(memory)
+			if err != nil {
+				return nil, err
+			}
+			resources["MemoryBytes"] = b
+		}
+		result[field] = resources
+	}
+	return result, nil
+}
+
+func convertRestartPolicy(r map[string]interface{}) (map[string]interface{}, error) {
+	policy := map[string]interface{}{}
+	if v, ok := r["condition"]; ok {
+		policy["Condition"] = v
+	}
+	if v, ok := r["max_attempts"]; ok {
+		policy["MaxAttempts"] = toInt(v)
+	}
+	for key, field := range map[string]string{"delay": "Delay", "window": "Window"} {
+		if v, ok := r[key]; ok {
+			d, err := toDuration(v)
+			if err != nil {
+				return nil, err
+			}
+			policy[field] = d
+		}
+	}
+	return policy, nil
+}
+
+func convertUpdateConfig(u map[string]interface{}) (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+	if v, ok := u["parallelism"]; ok {
+		config["Parallelism"] = toInt(v)
+	}
+	for key, field := range map[string]string{"failure_action": "FailureAction", "order": "Order"} {
+		if v, ok := u[key]; ok {
+			config[field] = v
+		}
+	}
+	if v, ok := u["max_failure_ratio"]; ok {
+		config["MaxFailureRatio"] = v
+	}
+	for key, field := range map[string]string{"delay": "Delay", "monitor": "Monitor"} {
+		if v, ok := u[key]; ok {
+			d, err := toDuration(v)
+			if err != nil {
+				return nil, err
+			}
+			config[field] = d
+		}
+	}
+	return config, nil
+}
+
+func convertHealthcheck(hc map[string]interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	switch test := hc["test"].(type) {
+	case string:
+		result["Test"] = []string{"CMD-SHELL", test}
+	case []interface{}:
+		result["Test"] = toArgs(test)
+	}
+	if disable, _ := hc["disable"].(bool); disable {
+		result["Test"] = []string{"NONE"}
+	}
+	if v, ok := hc["retries"]; ok {
+		result["Retries"] = toInt(v)
+	}
+	for key, field := range map[string]string{"interval": "Interval", "timeout": "Timeout", "start_period": "StartPeriod"} {
+		if v, ok := hc[key]; ok {
+			d, err := toDuration(v)
+			if err != nil {
+				return nil, err
+			}
+			result[field] = d
+		}
+	}
+	return result, nil
+}
+
+// Returns {v} (a map or list of KEY=VALUE) as a sorted list of KEY=VALUE
+func toEnv(v interface{}) []string {
+	env := []string{}
+	switch e := v.(type) {
+	case []interface{}:
+		for _, item := range e {
+			env = append(env, fmt.Sprint(item))
+		}
+	case map[string]interface{}:
+		for k, value := range e {
+			if value == nil {
+				env = append(env, k)
+			} else {
+				env = append(env, k+"="+fmt.Sprint(value))
+			}
+		}
+	}
+	sort.Strings(env)
+	return env
+}
+
+// Returns {v} (a map or list of key=value) as a map
+func toStringMap(v interface{}) map[string]string {
+	m := map[string]string{}
+	switch l := v.(type) {
+	case []interface{}:
+		for _, item := range l {
+			kv := strings.SplitN(fmt.Sprint(item), "=", 2)
+			if len(kv) == 2 {
+				m[kv[0]] = kv[1]
+			} else {
+				m[kv[0]] = ""
+			}
+		}
+	case map[string]interface{}:
+		for k, value := range l {
+			m[k] = fmt.Sprint(value)
+		}
+	}
+	return m
+}
+
+// Returns {v} (a list or a string split as the shell would) as a list of arguments
+func toArgs(v interface{}) []string {
+	switch a := v.(type) {
+	case string:
+		return splitArgs(a)
+	case []interface{}:
+		args := []string{}
+		for _, item := range a {
+			args = append(args, fmt.Sprint(item))
+		}
+		return args
+	}
+	return nil
+}
+
+// Splits {s} on whitespace outside of single or double quotes removing the quotes
+func splitArgs(s string) []string {
+	args := []string{}
+	var current bytes.Buffer
+	var quote rune
+	inArg := false
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// Converts a compose duration (eg. 10s, 1m30s) to nanoseconds
+func toDuration(v interface{}) (int64, error) {
+	d, err := time.ParseDuration(fmt.Sprint(v))
+	if err != nil {
+		return 0, fmt.Errorf("'%v' must be a duration (eg. 10s, 1m30s)", v)
+	}
+	return int64(d), nil
+}
+
+// Converts a compose byte value (eg. 512M, 1g, 1024) to bytes
+func toBytes(v interface{}) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(fmt.Sprint(v)))
+	multiplier := int64(1)
+	for suffix, m := range map[string]int64{"k": 1 << 10, "m": 1 << 20, "g": 1 << 30} {
+		if strings.HasSuffix(s, suffix) || strings.HasSuffix(s, suffix+"b") {
+			s = strings.TrimSuffix(strings.TrimSuffix(s, "b"), suffix)
+			multiplier = m
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSuffix(s, "b"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%v' must be a size in bytes (eg. 512M, 1G)", v)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case float64:
+		return int(n)
+	case int:
+		return n
+	}
+	i, _ := strconv.Atoi(fmt.Sprint(v))
+	return i
+}
+
+func stringOr(v interface{}, def string) string {
+	if s, ok := v.(string); ok && s != "" {
+		return s
+	}
+	return def
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
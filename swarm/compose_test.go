@@ -0,0 +1,130 @@
+package swarm
+
+import (
+	"testing"
+
+	"github.com/ContainX/depcon/pkg/encoding"
+	"github.com/stretchr/testify/assert"
+)
+
+const testCompose = `
+version: "3.8"
+services:
+  web:
+    image: nginx:1.19
+    command: nginx -g "daemon off;"
+    environment:
+      MODE: prod
+      DEBUG:
+    ports:
+      - "8080:80"
+      - 443
+      - target: 53
+        published: 5353
+        protocol: udp
+        mode: host
+    volumes:
+      - ./conf:/etc/nginx/conf.d:ro
+      - data:/var/cache
+    networks: [front]
+    deploy:
+      replicas: 3
+      labels: [tier=web]
+      resources:
+        limits: { cpus: "0.5", memory: 512M }
+      update_config: { parallelism: 1, delay: 10s, order: start-first }
+      restart_policy: { condition: on-failure, max_attempts: 3 }
+      placement:
+        constraints: [node.role == worker]
+  agent:
+    image: agent
+    build: ./agent
+    deploy:
+      mode: global
+networks:
+  front:
+    driver: overlay
+    attachable: true
+  shared:
+    external: true
+`
+
+func convertTestCompose(t *testing.T) *Stack {
+	doc := map[string]interface{}{}
+	encoder, _ := encoding.NewEncoder(encoding.YAML)
+	assert.Nil(t, encoder.UnMarshalStr(testCompose, &doc))
+	stack, err := ConvertCompose("shop", doc)
+	assert.Nil(t, err)
+	return stack
+}
+
+func TestConvertComposeServices(t *testing.T) {
+	stack := convertTestCompose(t)
+	assert.Len(t, stack.Services, 2)
+	assert.Equal(t, []string{"services.agent.build is not supported and was ignored"}, stack.Warnings)
+
+	agent := stack.Services[0]
+	assert.Equal(t, "shop_agent", agent["Name"])
+	assert.Equal(t, map[string]interface{}{"Global": map[string]interface{}{}}, agent["Mode"])
+
+	web := stack.Services[1]
+	assert.Equal(t, "shop_web", web["Name"])
+	assert.Equal(t, map[string]string{"tier": "web", LabelNamespace: "shop"}, web["Labels"])
+	assert.Equal(t, map[string]interface{}{"Replicated": map[string]interface{}{"Replicas": 3}}, web["Mode"])
+
+	task := web["TaskTemplate"].(map[string]interface{})
+	container := task["ContainerSpec"].(map[string]interface{})
+	assert.Equal(t, "nginx:1.19", container["Image"])
+	assert.Equal(t, []string{"DEBUG", "MODE=prod"}, container["Env"])
+	assert.Equal(t, []string{"nginx", "-g", "daemon off;"}, container["Args"])
+	assert.Equal(t, []map[string]interface{}{
+		{"Type": "bind", "Source": "./conf", "Target": "/etc/nginx/conf.d", "ReadOnly": true},
+		{"Type": "volume", "Source": "shop_data", "Target": "/var/cache", "VolumeOptions": map[string]interface{}{"Labels": map[string]string{LabelNamespace: "shop"}}},
+	}, container["Mounts"])
+
+	resources := task["Resources"].(map[string]interface{})["Limits"].(map[string]interface{})
+	assert.Equal(t, int64(5e8), resources["NanoCPUs"])
+	assert.Equal(t, int64(512<<20), resources["MemoryBytes"])
+	assert.Equal(t, map[string]interface{}{"Condition": "on-failure", "MaxAttempts": 3}, task["RestartPolicy"])
+	assert.Equal(t, []string{"node.role == worker"}, task["Placement"].(map[string]interface{})["Constraints"])
+	assert.Equal(t, map[string]interface{}{"Parallelism": 1, "Delay": int64(10e9), "Order": "start-first"}, web["UpdateConfig"])
+	assert.Equal(t, []map[string]interface{}{{"Target": "shop_front", "Aliases": []string{"web"}}}, task["Networks"])
+
+	assert.Equal(t, []map[string]interface{}{
+		{"Protocol": "tcp", "PublishMode": "ingress", "TargetPort": 80, "PublishedPort": 8080},
+		{"Protocol": "tcp", "PublishMode": "ingress", "TargetPort": 443},
+		{"Protocol": "udp", "PublishMode": "host", "TargetPort": 53, "PublishedPort": 5353},
+	}, web["EndpointSpec"].(map[string]interface{})["Ports"])
+}
+
+func TestConvertComposeNetworks(t *testing.T) {
+	stack := convertTestCompose(t)
+	assert.Equal(t, []string{"shared"}, stack.External)
+	assert.Len(t, stack.Networks, 2)
+	assert.Equal(t, true, stack.Networks["front"]["Attachable"])
+	// the agent attaches to the stack's default network which is created implicitly
+	assert.Equal(t, "shop_default", stack.Networks["default"]["Name"])
+	assert.Equal(t, "overlay", stack.Networks["default"]["Driver"])
+}
+
+func TestConvertComposeErrors(t *testing.T) {
+	_, err := ConvertCompose("shop", map[string]interface{}{"version": "3"})
+	assert.Equal(t, ErrorNoServices, err)
+
+	_, err = ConvertCompose("shop", map[string]interface{}{"services": map[string]interface{}{"web": map[string]interface{}{}}})
+	assert.EqualError(t, err, "service 'web': An image must be specified")
+
+	doc := map[string]interface{}{"services": map[string]interface{}{"web": map[string]interface{}{"image": "nginx", "ports": []interface{}{"http"}}}}
+	_, err = ConvertCompose("shop", doc)
+	assert.EqualError(t, err, "service 'web': port 'http' must be [published:]target[/protocol]")
+}
+
+func TestToBytes(t *testing.T) {
+	for in, expected := range map[interface{}]int64{"512M": 512 << 20, "1g": 1 << 30, "64kb": 64 << 10, float64(1024): 1024, "100b": 100} {
+		b, err := toBytes(in)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, b, "%v", in)
+	}
+	_, err := toBytes("lots")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,59 @@
+package swarm
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+)
+
+// Environment variables read by the docker CLI which are honoured so both tools target the same engine
+const (
+	EnvHost      = "DOCKER_HOST"
+	EnvCertPath  = "DOCKER_CERT_PATH"
+	EnvTLSVerify = "DOCKER_TLS_VERIFY"
+
+	DefaultHost = "unix:///var/run/docker.sock"
+)
+
+// Config is the address and TLS settings of a swarm manager's Docker engine
+type Config struct {
+	// unix:///path/to.sock, tcp://host:port, http(s)://host:port or ssh+http:// tunnels
+	Host string
+	// Optional client certificate and CA bundle.  tcp:// hosts use https when set
+	TLS      *httpclient.TLSConfig
+	Insecure bool
+}
+
+// DefaultConfig returns the engine described by the docker CLI environment variables falling back to the
+// local socket
+func DefaultConfig() *Config {
+	config := &Config{Host: os.Getenv(EnvHost)}
+	if config.Host == "" {
+		config.Host = DefaultHost
+	}
+	if dir := os.Getenv(EnvCertPath); dir != "" {
+		config.TLS = &httpclient.TLSConfig{
+			CAFile:   filepath.Join(dir, "ca.pem"),
+			CertFile: filepath.Join(dir, "cert.pem"),
+			KeyFile:  filepath.Join(dir, "key.pem"),
+		}
+		// as with the docker CLI the certificates are only verified with DOCKER_TLS_VERIFY
+		config.Insecure = os.Getenv(EnvTLSVerify) == ""
+	}
+	return config
+}
+
+// Returns the URL of the engine converting tcp:// to http or https depending on whether TLS is configured
+func (c *Config) address() (string, error) {
+	u, err := url.Parse(c.Host)
+	if err != nil || u.Scheme != "tcp" {
+		return httpclient.ResolveEndpoint(c.Host)
+	}
+	u.Scheme = "http"
+	if !c.TLS.IsEmpty() {
+		u.Scheme = "https"
+	}
+	return u.String(), nil
+}
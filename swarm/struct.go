@@ -0,0 +1,92 @@
+package swarm
+
+// The subset of the Docker Engine API objects displayed and managed by depcon
+
+type Service struct {
+	ID      string `json:"ID"`
+	Version struct {
+		Index uint64 `json:"Index"`
+	} `json:"Version"`
+	CreatedAt    string        `json:"CreatedAt"`
+	UpdatedAt    string        `json:"UpdatedAt"`
+	Spec         ServiceSpec   `json:"Spec"`
+	Endpoint     *Endpoint     `json:"Endpoint,omitempty"`
+	UpdateStatus *UpdateStatus `json:"UpdateStatus,omitempty"`
+}
+
+type ServiceSpec struct {
+	Name         string            `json:"Name"`
+	Labels       map[string]string `json:"Labels,omitempty"`
+	TaskTemplate struct {
+		ContainerSpec struct {
+			Image string `json:"Image"`
+		} `json:"ContainerSpec"`
+	} `json:"TaskTemplate"`
+	Mode ServiceMode `json:"Mode"`
+}
+
+// ServiceMode is either replicated with a number of replicas or global (one task on every node)
+type ServiceMode struct {
+	Replicated *struct {
+		Replicas int `json:"Replicas"`
+	} `json:"Replicated,omitempty"`
+	Global *struct{} `json:"Global,omitempty"`
+}
+
+type Endpoint struct {
+	Ports []*PortConfig `json:"Ports,omitempty"`
+}
+
+type PortConfig struct {
+	Protocol      string `json:"Protocol"`
+	TargetPort    int    `json:"TargetPort"`
+	PublishedPort int    `json:"PublishedPort"`
+	PublishMode   string `json:"PublishMode"`
+}
+
+// UpdateStatus is the state of the latest rolling update of a service (eg. updating, completed, paused,
+// rollback_completed)
+type UpdateStatus struct {
+	State   string `json:"State"`
+	Message string `json:"Message"`
+}
+
+type Task struct {
+	ID           string     `json:"ID"`
+	ServiceID    string     `json:"ServiceID"`
+	NodeID       string     `json:"NodeID"`
+	Slot         int        `json:"Slot"`
+	DesiredState string     `json:"DesiredState"`
+	Status       TaskStatus `json:"Status"`
+}
+
+type TaskStatus struct {
+	State   string `json:"State"`
+	Message string `json:"Message"`
+	Err     string `json:"Err,omitempty"`
+}
+
+type Network struct {
+	ID     string            `json:"Id"`
+	Name   string            `json:"Name"`
+	Labels map[string]string `json:"Labels"`
+}
+
+// StackSummary is a stack and the number of services within it
+type StackSummary struct {
+	Name     string `json:"name"`
+	Services int    `json:"services"`
+}
+
+// DeployResult is the outcome of deploying a service of a stack
+type DeployResult struct {
+	Service string `json:"service"`
+	ID      string `json:"id"`
+	// created or updated
+	Action string `json:"action"`
+}
+
+type createResponse struct {
+	ID       string `json:"ID"`
+	Warnings []string
+}
@@ -0,0 +1,327 @@
+// Docker Swarm (Docker Engine API)
+package swarm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+const (
+	/* --- api related constants --- */
+	// oldest engine API supporting the fields depcon sends (Docker 19.03)
+	API_VERSION  = "v1.40"
+	API_SERVICES = "services"
+	API_TASKS    = "tasks"
+	API_NETWORKS = "networks"
+
+	DefaultTimeout = time.Duration(5) * time.Minute
+)
+
+// Common package logger
+var log = logger.GetLogger("depcon.swarm")
+
+var (
+	ErrorTimeout      = errors.New("The operation has timed out")
+	ErrorNoServices   = errors.New("The compose file does not define any services")
+	ErrorMissingImage = errors.New("An image must be specified")
+	ErrorUpdateFailed = errors.New("The service update failed")
+	ErrorGlobalScale  = errors.New("Global services can't be scaled")
+)
+
+type Swarm interface {
+
+	/** Stack API */
+
+	// Creates the networks and creates or updates the services of {stack}
+	// {stack} - the converted compose file (see ConvertCompose)
+	DeployStack(stack *Stack) ([]*DeployResult, error)
+
+	// List the stacks and the number of services within them
+	ListStacks() ([]*StackSummary, error)
+
+	// Removes the services and networks of a stack
+	// {name} - stack name
+	RemoveStack(name string) error
+
+	/** Service API */
+
+	// List services.  If {stack} is not empty only the services of the stack are returned
+	ListServices(stack string) ([]*Service, error)
+
+	// Get a Service by name or ID
+	// {name} - service name or ID
+	GetService(name string) (*Service, error)
+
+	// Scale a replicated Service
+	// {name} - service name or ID
+	// {replicas} - replicas to scale to
+	ScaleService(name string, replicas int) (*Service, error)
+
+	// Removes a Service
+	// {name} - service name or ID
+	RemoveService(name string) error
+
+	// List the tasks of a Service
+	// {name} - service name or ID
+	ListTasks(name string) ([]*Task, error)
+
+	// Waits until the update of a Service has completed and every desired task is running
+	// {name} - service name or ID
+	// {timeout} - the max time to wait
+	WaitForService(name string, timeout time.Duration) error
+}
+
+type SwarmClient struct {
+	http httpclient.HttpClient
+	host string
+	opts *SwarmOptions
+}
+
+type SwarmOptions struct {
+	// Refuses deploying, scaling and removing services (every request other than a GET) with
+	// httpclient.ErrorReadOnly
+	ReadOnly bool
+	// Policy for retrying failed requests.  httpclient.DefaultRetryPolicy is used when nil
+	Retry *httpclient.RetryPolicy
+	// Optional connect, TLS handshake, response header and overall request timeouts
+	Timeouts *httpclient.Timeouts
+	// Optional reporter drawing the status (running tasks of the total) while waiting on services.  Status is logged when nil
+	Progress progress.Reporter
+}
+
+// NewSwarmClient creates a client for the swarm manager of {config}
+func NewSwarmClient(config *Config, opts *SwarmOptions) (Swarm, error) {
+	host, err := config.address()
+	if err != nil {
+		return nil, err
+	}
+	httpConfig := httpclient.NewDefaultConfig()
+	httpConfig.TLSInsecureSkipVerify = config.Insecure
+	httpConfig.TLS = config.TLS
+	httpConfig.Retry = httpclient.DefaultRetryPolicy()
+	if opts != nil {
+		httpConfig.ReadOnly = opts.ReadOnly
+		httpConfig.Timeouts = opts.Timeouts
+		if opts.Retry != nil {
+			httpConfig.Retry = opts.Retry
+		}
+	}
+
+	c := new(SwarmClient)
+	c.http = *httpclient.NewHttpClient(*httpConfig)
+	c.host = host
+	c.opts = opts
+	return c, nil
+}
+
+// Returns the URL of {resource} followed by {elements} with the optional query {params}
+func (c *SwarmClient) swarmUrl(params url.Values, resource string, elements ...string) string {
+	for i, e := range elements {
+		elements[i] = url.PathEscape(e)
+	}
+	uri := utils.BuildPath(c.host, append([]string{API_VERSION, resource}, elements...))
+	if len(params) > 0 {
+		uri += "?" + params.Encode()
+	}
+	return uri
+}
+
+// Returns the filters query parameter matching {filters} (eg. label=com.docker.stack.namespace=web)
+func filterParams(filters map[string][]string) url.Values {
+	f := map[string]map[string]bool{}
+	for key, values := range filters {
+		f[key] = map[string]bool{}
+		for _, v := range values {
+			f[key][v] = true
+		}
+	}
+	b, _ := json.Marshal(f)
+	return url.Values{"filters": []string{string(b)}}
+}
+
+func stackFilter(stack string) url.Values {
+	return filterParams(map[string][]string{"label": {LabelNamespace + "=" + stack}})
+}
+
+func (c *SwarmClient) DeployStack(stack *Stack) ([]*DeployResult, error) {
+	existing, err := c.listNetworks(stack.Name)
+	if err != nil {
+		return nil, err
+	}
+	keys := []string{}
+	for key := range stack.Networks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		spec := stack.Networks[key]
+		name := spec["Name"].(string)
+		if _, ok := existing[name]; ok {
+			continue
+		}
+		log.Info("Creating network '%s'", name)
+		if resp := c.http.HttpPost(c.swarmUrl(nil, API_NETWORKS, "create"), spec, nil); resp.Error != nil {
+			return nil, resp.Err()
+		}
+	}
+
+	results := []*DeployResult{}
+	for _, spec := range stack.Services {
+		name := spec["Name"].(string)
+		current, err := c.GetService(name)
+		switch err {
+		case httpclient.ErrorNotFound:
+			log.Info("Creating service '%s'", name)
+			created := new(createResponse)
+			if resp := c.http.HttpPost(c.swarmUrl(nil, API_SERVICES, "create"), spec, created); resp.Error != nil {
+				return nil, resp.Err()
+			}
+			results = append(results, &DeployResult{Service: name, ID: created.ID, Action: "created"})
+		case nil:
+			log.Info("Updating service '%s'", name)
+			if err := c.updateService(current.ID, current.Version.Index, spec); err != nil {
+				return nil, err
+			}
+			results = append(results, &DeployResult{Service: name, ID: current.ID, Action: "updated"})
+		default:
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (c *SwarmClient) updateService(id string, version uint64, spec interface{}) error {
+	params := url.Values{"version": []string{fmt.Sprintf("%d", version)}}
+	if resp := c.http.HttpPost(c.swarmUrl(params, API_SERVICES, id, "update"), spec, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *SwarmClient) ListStacks() ([]*StackSummary, error) {
+	services, err := c.ListServices("")
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, s := range services {
+		if stack := s.Spec.Labels[LabelNamespace]; stack != "" {
+			counts[stack]++
+		}
+	}
+	stacks := []*StackSummary{}
+	for name, count := range counts {
+		stacks = append(stacks, &StackSummary{Name: name, Services: count})
+	}
+	sort.Slice(stacks, func(i, j int) bool { return stacks[i].Name < stacks[j].Name })
+	return stacks, nil
+}
+
+func (c *SwarmClient) RemoveStack(name string) error {
+	services, err := c.ListServices(name)
+	if err != nil {
+		return err
+	}
+	networks, err := c.listNetworks(name)
+	if err != nil {
+		return err
+	}
+	if len(services) == 0 && len(networks) == 0 {
+		return httpclient.ErrorNotFound
+	}
+	for _, s := range services {
+		if err := c.RemoveService(s.ID); err != nil {
+			return err
+		}
+	}
+	for _, n := range networks {
+		log.Info("Removing network '%s'", n.Name)
+		if resp := c.http.HttpDelete(c.swarmUrl(nil, API_NETWORKS, n.ID), nil, nil); resp.Error != nil {
+			return resp.Err()
+		}
+	}
+	return nil
+}
+
+// Returns the networks of {stack} keyed by name
+func (c *SwarmClient) listNetworks(stack string) (map[string]*Network, error) {
+	networks := []*Network{}
+	if resp := c.http.HttpGet(c.swarmUrl(stackFilter(stack), API_NETWORKS), &networks); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	byName := map[string]*Network{}
+	for _, n := range networks {
+		byName[n.Name] = n
+	}
+	return byName, nil
+}
+
+func (c *SwarmClient) ListServices(stack string) ([]*Service, error) {
+	var params url.Values
+	if stack != "" {
+		params = stackFilter(stack)
+	}
+	services := []*Service{}
+	if resp := c.http.HttpGet(c.swarmUrl(params, API_SERVICES), &services); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Spec.Name < services[j].Spec.Name })
+	return services, nil
+}
+
+func (c *SwarmClient) GetService(name string) (*Service, error) {
+	service := new(Service)
+	if resp := c.http.HttpGet(c.swarmUrl(nil, API_SERVICES, name), service); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return service, nil
+}
+
+func (c *SwarmClient) ScaleService(name string, replicas int) (*Service, error) {
+	// the spec is updated as a whole so it's read without discarding the fields depcon doesn't model
+	raw := map[string]interface{}{}
+	if resp := c.http.HttpGet(c.swarmUrl(nil, API_SERVICES, name), &raw); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	spec, _ := raw["Spec"].(map[string]interface{})
+	mode, _ := spec["Mode"].(map[string]interface{})
+	if _, ok := mode["Replicated"]; !ok {
+		return nil, ErrorGlobalScale
+	}
+	mode["Replicated"] = map[string]interface{}{"Replicas": replicas}
+
+	version, _ := raw["Version"].(map[string]interface{})
+	index, _ := version["Index"].(float64)
+	id, _ := raw["ID"].(string)
+	log.Info("Scaling service '%s' to %d replicas", name, replicas)
+	if err := c.updateService(id, uint64(index), spec); err != nil {
+		return nil, err
+	}
+	return c.GetService(id)
+}
+
+func (c *SwarmClient) RemoveService(name string) error {
+	log.Info("Removing service '%s'", name)
+	if resp := c.http.HttpDelete(c.swarmUrl(nil, API_SERVICES, name), nil, nil); resp.Error != nil {
+		return resp.Err()
+	}
+	return nil
+}
+
+func (c *SwarmClient) ListTasks(name string) ([]*Task, error) {
+	tasks := []*Task{}
+	params := filterParams(map[string][]string{"service": {name}})
+	if resp := c.http.HttpGet(c.swarmUrl(params, API_TASKS), &tasks); resp.Error != nil {
+		return nil, resp.Err()
+	}
+	return tasks, nil
+}
@@ -0,0 +1,139 @@
+package swarm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/ContainX/depcon/pkg/mockrest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestClient(handler http.HandlerFunc) (c Swarm, requests *[]mockrest.RecordedRequest, stop func()) {
+	requests, stop = mockrest.RecordClient(handler, func(url string, retry *httpclient.RetryPolicy) {
+		c, _ = NewSwarmClient(&Config{Host: url}, &SwarmOptions{Retry: retry})
+	})
+	return
+}
+
+func testStack() *Stack {
+	return &Stack{
+		Name:     "shop",
+		Networks: map[string]map[string]interface{}{"default": {"Name": "shop_default", "Driver": "overlay"}},
+		Services: []map[string]interface{}{{"Name": "shop_web"}, {"Name": "shop_api"}},
+	}
+}
+
+func TestDeployStack(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1.40/networks":
+			fmt.Fprint(w, `[]`)
+		case "/v1.40/services/shop_web":
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"message": "service shop_web not found"}`)
+		case "/v1.40/services/shop_api":
+			fmt.Fprint(w, `{"ID": "s2", "Version": {"Index": 17}}`)
+		case "/v1.40/services/create":
+			fmt.Fprint(w, `{"ID": "s1"}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	})
+	defer stop()
+
+	results, err := c.DeployStack(testStack())
+	assert.Nil(t, err)
+	assert.Equal(t, []*DeployResult{
+		{Service: "shop_web", ID: "s1", Action: "created"},
+		{Service: "shop_api", ID: "s2", Action: "updated"},
+	}, results)
+
+	assert.Equal(t, `filters={"label":{"com.docker.stack.namespace=shop":true}}`, mustUnescape((*requests)[0].Query))
+	assert.Equal(t, "/v1.40/networks/create", (*requests)[1].Path)
+	assert.Equal(t, "shop_default", (*requests)[1].Body["Name"])
+	assert.Equal(t, "/v1.40/services/create", (*requests)[3].Path)
+	update := (*requests)[5]
+	assert.Equal(t, "/v1.40/services/s2/update", update.Path)
+	assert.Equal(t, "version=17", update.Query)
+}
+
+func TestScaleService(t *testing.T) {
+	c, requests, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `{"ID": "s1", "Version": {"Index": 3}, "Spec": {"Name": "shop_web", "Mode": {"Replicated": {"Replicas": 1}}, "TaskTemplate": {"ContainerSpec": {"Image": "nginx", "Env": ["A=1"]}}}}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	})
+	defer stop()
+
+	_, err := c.ScaleService("shop_web", 4)
+	assert.Nil(t, err)
+	update := (*requests)[1]
+	assert.Equal(t, "/v1.40/services/s1/update", update.Path)
+	assert.Equal(t, float64(4), update.Body["Mode"].(map[string]interface{})["Replicated"].(map[string]interface{})["Replicas"])
+	// fields which aren't modelled are retained
+	assert.Equal(t, []interface{}{"A=1"}, update.Body["TaskTemplate"].(map[string]interface{})["ContainerSpec"].(map[string]interface{})["Env"])
+}
+
+func TestScaleGlobalService(t *testing.T) {
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ID": "s1", "Spec": {"Mode": {"Global": {}}}}`)
+	})
+	defer stop()
+
+	_, err := c.ScaleService("agent", 2)
+	assert.Equal(t, ErrorGlobalScale, err)
+}
+
+func TestWaitForService(t *testing.T) {
+	defer func(i time.Duration) { waitInterval = i }(waitInterval)
+	waitInterval = time.Millisecond
+
+	polls := 0
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1.40/tasks" {
+			polls++
+			state := "starting"
+			if polls > 1 {
+				state = "running"
+			}
+			fmt.Fprintf(w, `[{"DesiredState": "running", "Status": {"State": "running"}}, {"DesiredState": "running", "Status": {"State": "%s"}},
+				{"DesiredState": "shutdown", "Status": {"State": "running"}}]`, state)
+			return
+		}
+		fmt.Fprint(w, `{"ID": "s1", "Spec": {"Mode": {"Replicated": {"Replicas": 2}}}, "UpdateStatus": {"State": "completed"}}`)
+	})
+	defer stop()
+
+	assert.Nil(t, c.WaitForService("web", time.Minute))
+	assert.Equal(t, 2, polls)
+	assert.Equal(t, ErrorTimeout, c.WaitForService("web", 0))
+}
+
+func TestWaitForServiceRolledBack(t *testing.T) {
+	c, _, stop := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ID": "s1", "UpdateStatus": {"State": "rollback_completed", "Message": "rolled back"}}`)
+	})
+	defer stop()
+
+	assert.Equal(t, ErrorUpdateFailed, c.WaitForService("web", time.Minute))
+}
+
+func TestConfigAddress(t *testing.T) {
+	address, _ := (&Config{Host: "tcp://docker:2376"}).address()
+	assert.Equal(t, "http://docker:2376", address)
+	address, _ = (&Config{Host: "tcp://docker:2376", TLS: &httpclient.TLSConfig{CAFile: "ca.pem"}}).address()
+	assert.Equal(t, "https://docker:2376", address)
+	address, _ = (&Config{Host: "unix:///var/run/docker.sock"}).address()
+	assert.Contains(t, address, "http://unix-")
+}
+
+func mustUnescape(s string) string {
+	u, _ := url.QueryUnescape(s)
+	return u
+}
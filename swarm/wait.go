@@ -0,0 +1,94 @@
+package swarm
+
+import (
+	"time"
+
+	"github.com/ContainX/depcon/pkg/logger"
+	"github.com/ContainX/depcon/pkg/progress"
+	"github.com/ContainX/depcon/utils"
+)
+
+var logWait = logger.GetLogger("depcon.deploy.wait")
+
+// interval between checks of the service tasks
+var waitInterval = time.Duration(2) * time.Second
+
+// update states of a service which will not converge without intervention
+var failedUpdateStates = map[string]bool{"paused": true, "rollback_completed": true, "rollback_paused": true}
+
+func (c *SwarmClient) WaitForService(name string, timeout time.Duration) error {
+	t_now := time.Now()
+	t_stop := t_now.Add(timeout)
+
+	for {
+		if time.Now().After(t_stop) {
+			c.clearWaitStatus()
+			return ErrorTimeout
+		}
+		s, err := c.GetService(name)
+		if err != nil {
+			c.clearWaitStatus()
+			return err
+		}
+		if s.UpdateStatus != nil && failedUpdateStates[s.UpdateStatus.State] {
+			c.clearWaitStatus()
+			logWait.Error("Update of service '%s' is %s: %s", name, s.UpdateStatus.State, s.UpdateStatus.Message)
+			return ErrorUpdateFailed
+		}
+		tasks, err := c.ListTasks(s.ID)
+		if err != nil {
+			c.clearWaitStatus()
+			return err
+		}
+		running, desired := s.TaskCounts(tasks)
+		if s.IsConverged(running, desired) {
+			c.clearWaitStatus()
+			logWait.Info("Service '%s' has converged, elapsed time %s", name, utils.ElapsedStr(time.Since(t_now)))
+			return nil
+		}
+		if !c.reportWaitStatus("Waiting for service "+name+" to converge", running, desired) {
+			logWait.Info("%v of %v tasks of '%s' are running.  Retrying check in %v", running, desired, name, waitInterval)
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+// TaskCounts returns the running tasks of the service and the tasks it should be running.  Replicated
+// services should run their replicas and global services every task which is desired to be running
+func (s *Service) TaskCounts(tasks []*Task) (running, desired int) {
+	for _, t := range tasks {
+		if t.DesiredState != "running" {
+			continue
+		}
+		desired++
+		if t.Status.State == "running" {
+			running++
+		}
+	}
+	if s.Spec.Mode.Replicated != nil {
+		desired = s.Spec.Mode.Replicated.Replicas
+	}
+	return
+}
+
+// IsConverged determines if any update has completed and the desired tasks are running
+func (s *Service) IsConverged(running, desired int) bool {
+	if s.UpdateStatus != nil && s.UpdateStatus.State != "" && s.UpdateStatus.State != "completed" {
+		return false
+	}
+	if s.Spec.Mode.Global != nil && desired == 0 {
+		// the tasks of a new global service haven't been scheduled yet
+		return false
+	}
+	return running == desired
+}
+
+func (c *SwarmClient) reportWaitStatus(message string, done, total int) bool {
+	return c.opts != nil && progress.Report(c.opts.Progress, message, done, total)
+}
+
+func (c *SwarmClient) clearWaitStatus() {
+	if c.opts != nil {
+		progress.Clear(c.opts.Progress)
+	}
+}
@@ -0,0 +1,198 @@
+// Compares the resources allocated to applications with the resources their containers use
+package utilization
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/pkg/logger"
+)
+
+const (
+	// Apps flagged as over-provisioned by default
+	DefaultTop = 5
+	// Time between the two samples the cpus used are derived from
+	DefaultInterval = 5 * time.Second
+)
+
+var log = logger.GetLogger("depcon.utilization")
+
+// TaskUsage is the cpus and memory (MB) used by the container of a task
+type TaskUsage struct {
+	CPUs float64
+	Mem  float64
+}
+
+// AppUsage is the resources allocated to the instances of an app and the resources they use.  Usage is
+// only known for the sampled instances so percentages compare the usage with their allocation
+type AppUsage struct {
+	ID            string  `json:"id"`
+	Instances     int     `json:"instances"`
+	Sampled       int     `json:"sampled"`
+	CPUsAllocated float64 `json:"cpusAllocated"`
+	CPUsUsed      float64 `json:"cpusUsed"`
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemAllocated  float64 `json:"memAllocated"`
+	MemUsed       float64 `json:"memUsed"`
+	MemPercent    float64 `json:"memPercent"`
+	// Allocation of every instance which is unused, projected from the sampled instances
+	UnusedCPUs      float64 `json:"unusedCpus"`
+	UnusedMem       float64 `json:"unusedMem"`
+	OverProvisioned bool    `json:"overProvisioned"`
+
+	// allocation of the sampled instances
+	sampledCPUs, sampledMem float64
+	score                   float64
+}
+
+// Report is the usage of a set of applications ordered by the share of the cluster's allocation they
+// leave unused, most over-provisioned first
+type Report struct {
+	Apps  []*AppUsage `json:"apps"`
+	Total *AppUsage   `json:"total"`
+}
+
+// Sample reads the containers of every active agent twice {interval} apart and returns the usage of each
+// executor (the task id of Marathon's tasks).  Agents which can't be read are logged and skipped
+func Sample(m mesos.Mesos, interval time.Duration) (map[string]*TaskUsage, error) {
+	agents, err := m.ListAgents()
+	if err != nil {
+		return nil, err
+	}
+	active := []*mesos.Agent{}
+	for _, a := range agents {
+		if a.Active {
+			active = append(active, a)
+		}
+	}
+
+	first := readContainers(m, active)
+	time.Sleep(interval)
+	second := readContainers(m, active)
+
+	usage := map[string]*TaskUsage{}
+	for id, after := range second {
+		u := &TaskUsage{Mem: float64(after.MemRSSBytes) / (1024 * 1024)}
+		if before, ok := first[id]; ok {
+			u.CPUs = mesos.CPUsUsed(before, after)
+		}
+		usage[id] = u
+	}
+	return usage, nil
+}
+
+// Returns the statistics of the containers of {agents} by executor id reading the agents in parallel
+func readContainers(m mesos.Mesos, agents []*mesos.Agent) map[string]*mesos.ResourceStatistics {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	stats := map[string]*mesos.ResourceStatistics{}
+	for _, a := range agents {
+		wg.Add(1)
+		go func(a *mesos.Agent) {
+			defer wg.Done()
+			containers, err := m.ListContainers(a)
+			if err != nil {
+				log.Warning("Unable to read the containers of agent %s: %s", a.Hostname(), err.Error())
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, c := range containers {
+				if c.ExecutorID != nil && c.Statistics != nil {
+					stats[c.ExecutorID.Value] = c.Statistics
+				}
+			}
+		}(a)
+	}
+	wg.Wait()
+	return stats
+}
+
+// Summarize returns the usage of {apps} from the {usage} of their {tasks} flagging the {top} apps leaving
+// the largest share of the allocated cpus and memory unused as over-provisioned
+func Summarize(apps []*marathon.Application, tasks []*marathon.Task, usage map[string]*TaskUsage, top int) *Report {
+	byApp := map[string][]*TaskUsage{}
+	for _, t := range tasks {
+		if u, ok := usage[t.ID]; ok {
+			byApp[t.AppID] = append(byApp[t.AppID], u)
+		}
+	}
+
+	report := &Report{Apps: []*AppUsage{}, Total: &AppUsage{ID: "TOTAL"}}
+	for _, app := range apps {
+		a := &AppUsage{ID: app.ID, Instances: app.Instances}
+		a.CPUsAllocated = app.CPUs * float64(app.Instances)
+		a.MemAllocated = app.Mem * float64(app.Instances)
+		for _, u := range byApp[app.ID] {
+			a.Sampled++
+			a.CPUsUsed += u.CPUs
+			a.MemUsed += u.Mem
+		}
+		a.sampledCPUs = app.CPUs * float64(a.Sampled)
+		a.sampledMem = app.Mem * float64(a.Sampled)
+		if a.Sampled > 0 {
+			projection := float64(app.Instances) / float64(a.Sampled)
+			a.UnusedCPUs = math.Max(0, a.sampledCPUs-a.CPUsUsed) * projection
+			a.UnusedMem = math.Max(0, a.sampledMem-a.MemUsed) * projection
+		}
+		report.Apps = append(report.Apps, a)
+		report.Total.add(a)
+	}
+
+	for _, a := range report.Apps {
+		if report.Total.CPUsAllocated > 0 {
+			a.score += a.UnusedCPUs / report.Total.CPUsAllocated
+		}
+		if report.Total.MemAllocated > 0 {
+			a.score += a.UnusedMem / report.Total.MemAllocated
+		}
+	}
+	sort.SliceStable(report.Apps, func(i, j int) bool {
+		if report.Apps[i].score != report.Apps[j].score {
+			return report.Apps[i].score > report.Apps[j].score
+		}
+		return report.Apps[i].ID < report.Apps[j].ID
+	})
+	for i, a := range report.Apps {
+		a.OverProvisioned = i < top && a.score > 0
+	}
+	for _, a := range append(report.Apps, report.Total) {
+		a.finish()
+	}
+	return report
+}
+
+func (a *AppUsage) add(o *AppUsage) {
+	a.Instances += o.Instances
+	a.Sampled += o.Sampled
+	a.CPUsAllocated += o.CPUsAllocated
+	a.CPUsUsed += o.CPUsUsed
+	a.MemAllocated += o.MemAllocated
+	a.MemUsed += o.MemUsed
+	a.UnusedCPUs += o.UnusedCPUs
+	a.UnusedMem += o.UnusedMem
+	a.sampledCPUs += o.sampledCPUs
+	a.sampledMem += o.sampledMem
+}
+
+// Computes the percentages and rounds the values
+func (a *AppUsage) finish() {
+	if a.sampledCPUs > 0 {
+		a.CPUPercent = round(a.CPUsUsed / a.sampledCPUs * 100)
+	}
+	if a.sampledMem > 0 {
+		a.MemPercent = round(a.MemUsed / a.sampledMem * 100)
+	}
+	a.CPUsUsed = round(a.CPUsUsed)
+	a.MemUsed = round(a.MemUsed)
+	a.UnusedCPUs = round(a.UnusedCPUs)
+	a.UnusedMem = round(a.UnusedMem)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,84 @@
+package utilization
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ContainX/depcon/marathon"
+	"github.com/ContainX/depcon/mesos"
+	"github.com/ContainX/depcon/pkg/httpclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	apps := []*marathon.Application{
+		{ID: "/web", Instances: 4, CPUs: 1, Mem: 1024},
+		{ID: "/api", Instances: 2, CPUs: 0.5, Mem: 512},
+		{ID: "/batch", Instances: 1, CPUs: 2, Mem: 256},
+	}
+	tasks := []*marathon.Task{
+		{ID: "web.1", AppID: "/web"}, {ID: "web.2", AppID: "/web"},
+		{ID: "api.1", AppID: "/api"}, {ID: "api.2", AppID: "/api"},
+		{ID: "batch.1", AppID: "/batch"},
+	}
+	usage := map[string]*TaskUsage{
+		"web.1": {CPUs: 0.1, Mem: 256}, "web.2": {CPUs: 0.1, Mem: 256},
+		"api.1": {CPUs: 0.5, Mem: 500}, "api.2": {CPUs: 0.5, Mem: 500},
+	}
+
+	report := Summarize(apps, tasks, usage, 1)
+	assert.Equal(t, []string{"/web", "/api", "/batch"}, []string{report.Apps[0].ID, report.Apps[1].ID, report.Apps[2].ID})
+	web := report.Apps[0]
+	assert.Equal(t, 2, web.Sampled)
+	assert.Equal(t, 4.0, web.CPUsAllocated)
+	assert.Equal(t, 0.2, web.CPUsUsed)
+	assert.Equal(t, 10.0, web.CPUPercent)
+	assert.Equal(t, 25.0, web.MemPercent)
+	// the unsampled instances are projected from the sampled ones
+	assert.Equal(t, 3.6, web.UnusedCPUs)
+	assert.Equal(t, 3072.0, web.UnusedMem)
+	assert.True(t, web.OverProvisioned)
+	assert.False(t, report.Apps[1].OverProvisioned)
+
+	batch := report.Apps[2]
+	assert.Equal(t, 0, batch.Sampled)
+	assert.Equal(t, 0.0, batch.CPUPercent)
+
+	assert.Equal(t, 7, report.Total.Instances)
+	assert.Equal(t, 4, report.Total.Sampled)
+	assert.Equal(t, 7.0, report.Total.CPUsAllocated)
+	assert.Equal(t, 40.0, report.Total.CPUPercent)
+}
+
+func TestSample(t *testing.T) {
+	samples := 0
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(b), "GET_AGENTS"):
+			fmt.Fprintf(w, `{"type": "GET_AGENTS", "get_agents": {"agents": [
+				{"agent_info": {"id": {"value": "a1"}, "hostname": "agent-1"}, "active": true, "pid": "slave(1)@%s"},
+				{"agent_info": {"id": {"value": "a2"}, "hostname": "agent-2"}, "active": false, "pid": "slave(1)@unreachable:5051"}]}}`,
+				strings.TrimPrefix(s.URL, "http://"))
+		case strings.Contains(string(b), "GET_CONTAINERS"):
+			samples++
+			fmt.Fprintf(w, `{"type": "GET_CONTAINERS", "get_containers": {"containers": [
+				{"executor_id": {"value": "web.1"}, "resource_statistics": {"timestamp": %d, "cpus_user_time_secs": %d,
+				 "cpus_system_time_secs": 0, "mem_rss_bytes": 268435456}}]}}`, samples*10, samples*5)
+		}
+	}))
+	defer s.Close()
+	retry := httpclient.DefaultRetryPolicy()
+	retry.MaxAttempts = 1
+	m := mesos.NewMesosClient(s.URL, "", "", &mesos.MesosOptions{Retry: retry})
+
+	usage, err := Sample(m, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, samples)
+	assert.Equal(t, &TaskUsage{CPUs: 0.5, Mem: 256}, usage["web.1"])
+}
@@ -10,7 +10,9 @@ import (
 )
 
 var (
-	urlPrefix []string = []string{"http://", "https://"}
+	urlPrefix []string = []string{"http://", "https://", "unix://", "ssh+http://", "ssh+https://"}
+	// layouts of a scheduled time: RFC3339 with optional seconds
+	scheduleLayouts = []string{time.RFC3339, "2006-01-02T15:04Z07:00"}
 )
 
 func TrimRootPath(id string) string {
@@ -82,6 +84,32 @@ func ElapsedStr(d time.Duration) string {
 	return fmt.Sprintf("%0.2f sec(s)", d.Seconds())
 }
 
+// ScheduledTime returns the time a deployment is planned for: {at} (eg. 2024-06-01T02:00Z) or {in} after {now}
+// (eg. 4h).  Returns the zero time when neither is given and an error when both are or the time isn't after {now}
+func ScheduledTime(at, in string, now time.Time) (time.Time, error) {
+	switch {
+	case at == "" && in == "":
+		return time.Time{}, nil
+	case at != "" && in != "":
+		return time.Time{}, fmt.Errorf("Specify either a time (at) or a delay (in), not both")
+	case in != "":
+		d, err := time.ParseDuration(in)
+		if err != nil || d <= 0 {
+			return time.Time{}, fmt.Errorf("Invalid delay '%s' (eg. 30m, 4h)", in)
+		}
+		return now.Add(d), nil
+	}
+	for _, layout := range scheduleLayouts {
+		if t, err := time.Parse(layout, at); err == nil {
+			if !t.After(now) {
+				return time.Time{}, fmt.Errorf("The scheduled time %s has passed", at)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid time '%s' (eg. 2024-06-01T02:00Z, 2024-06-01T02:00:00+02:00)", at)
+}
+
 func IntInSlice(a int, list []int) bool {
 	for _, b := range list {
 		if b == a {